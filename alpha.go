@@ -0,0 +1,58 @@
+package mediadevices
+
+import (
+	"errors"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+var errNotVideoTrack = errors.New("not a video track")
+
+// alphaSource is NewAlphaTrack's track source. Closing it ends the alpha, but not the color
+// track.
+type alphaSource struct {
+	video.Reader
+	id        string
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *alphaSource) ID() string {
+	return s.id
+}
+
+func (s *alphaSource) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+// NewAlphaTrack creates a video track of track's frame alpha, see video.AlphaPlane, e.g. of a person
+// segmented from the background. It's sent as an alpha side stream, typically with VP9 in selector, so
+// receivers blend track's frames over their own background. track's frames should have
+// straight alpha, see video.Unpremultiply, and both tracks have the same capture times, so they're
+// paired by RTP timestamps if the tracks share a WithMediaClock clock. track is kept open when the
+// returned track is closed.
+func NewAlphaTrack(selector *CodecSelector, track Track) (Track, error) {
+	videoTrack, ok := track.(*VideoTrack)
+	if !ok {
+		return nil, errNotVideoTrack
+	}
+
+	source := &alphaSource{
+		id:     track.ID() + "-alpha",
+		closed: make(chan struct{}),
+	}
+	reader := videoTrack.NewReader(false)
+	source.Reader = video.AlphaPlane(video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+		select {
+		case <-source.closed:
+			return nil, func() {}, io.EOF
+		default:
+		}
+		return reader.Read()
+	}), reader))
+	return NewVideoTrack(source, selector), nil
+}
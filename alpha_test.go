@@ -0,0 +1,50 @@
+package mediadevices
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNewAlphaTrack(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	img.SetNRGBA(1, 2, color.NRGBA{R: 255, A: 128})
+	track := NewVideoTrack(&testVideoSource{img: img}, nil)
+	defer track.Close()
+
+	alpha, err := NewAlphaTrack(nil, track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alpha.ID() != "test-alpha" {
+		t.Fatalf("expected the ID to be test-alpha, but got %s", alpha.ID())
+	}
+
+	frame, _, err := alpha.(*VideoTrack).NewReader(false).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv, ok := frame.(*image.YCbCr)
+	if !ok {
+		t.Fatalf("expected an I420 frame of the alpha, but got %T", frame)
+	}
+	if a := yuv.YCbCrAt(1, 2); a.Y != 128 || a.Cb != 128 || a.Cr != 128 {
+		t.Fatalf("expected the alpha in the luma with the neutral chroma, but got %v", a)
+	}
+	if a := yuv.YCbCrAt(0, 0).Y; a != 0 {
+		t.Fatalf("expected the transparent pixels to be 0, but got %d", a)
+	}
+
+	if err := alpha.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := track.(*VideoTrack).NewReader(false).Read(); err != nil {
+		t.Fatalf("expected the track of the colors to be kept, but got %v", err)
+	}
+
+	audio := NewAudioTrack(&testAudioSource{id: "mic"}, nil)
+	defer audio.Close()
+	if _, err := NewAlphaTrack(nil, audio); err != errNotVideoTrack {
+		t.Fatalf("expected %v, but got %v", errNotVideoTrack, err)
+	}
+}
@@ -0,0 +1,198 @@
+package mediadevices
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/pion/rtcp"
+)
+
+var errUnsupportedTrack = errors.New("the track doesn't support the bandwidth allocation")
+
+// TrackBandwidth is a track's share of a connection's bandwidth.
+type TrackBandwidth struct {
+	// MaxBitRate caps the track's bit rate in bps. It's unlimited if it's 0.
+	MaxBitRate int
+	// MinBitRate is given to the track before the remaining bandwidth is split, as long as bandwidth allows.
+	MinBitRate int
+	// Priority is the track's weight relative to other tracks, e.g. 2 for a screen share that gets
+	// twice the bandwidth of a camera with 1. It's 1 if it's 0.
+	Priority float64
+}
+
+func (b TrackBandwidth) weight() float64 {
+	if b.Priority <= 0 {
+		return 1
+	}
+	return b.Priority
+}
+
+// bitRateTrack is a track whose encoders follow the bit rate allocated by BandwidthAllocator.
+type bitRateTrack interface {
+	Track
+	setBitRate(bps int)
+}
+
+type allocatedTrack struct {
+	track      bitRateTrack
+	bandwidth  TrackBandwidth
+	allocation int
+}
+
+// BandwidthAllocator splits a connection's estimated bandwidth between its tracks, e.g. a camera and a screen
+// share, by priority and caps, and sets the tracks' encoder bit rates. The estimate comes from
+// SetEstimate, or from receiver REMB packets via HandleRTCP.
+type BandwidthAllocator struct {
+	mu       sync.Mutex
+	estimate int
+	tracks   []*allocatedTrack
+}
+
+// NewBandwidthAllocator creates a BandwidthAllocator without tracks.
+func NewBandwidthAllocator() *BandwidthAllocator {
+	return &BandwidthAllocator{}
+}
+
+// SetTrackBandwidth adds track to the allocation, or updates its share if it's already added.
+func (a *BandwidthAllocator) SetTrackBandwidth(track Track, bandwidth TrackBandwidth) error {
+	t, ok := track.(bitRateTrack)
+	if !ok {
+		return errUnsupportedTrack
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, allocated := range a.tracks {
+		if allocated.track == t {
+			allocated.bandwidth = bandwidth
+			a.allocate()
+			return nil
+		}
+	}
+	a.tracks = append(a.tracks, &allocatedTrack{track: t, bandwidth: bandwidth})
+	a.allocate()
+	return nil
+}
+
+// RemoveTrack removes track from the allocation, and splits its bandwidth between other tracks. track's
+// encoders keep the last bit rate.
+func (a *BandwidthAllocator) RemoveTrack(track Track) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, allocated := range a.tracks {
+		if allocated.track == track {
+			allocated.track.setBitRate(0)
+			a.tracks = append(a.tracks[:i], a.tracks[i+1:]...)
+			a.allocate()
+			return
+		}
+	}
+}
+
+// SetEstimate sets the connection's estimated bandwidth in bps, and allocates it to tracks.
+func (a *BandwidthAllocator) SetEstimate(bps int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.estimate = bps
+	a.allocate()
+}
+
+// HandleRTCP sets the estimate from REMB packets in pkts, e.g. packets read by webrtc.RTPSender.ReadRTCP.
+func (a *BandwidthAllocator) HandleRTCP(pkts []rtcp.Packet) {
+	for _, pkt := range pkts {
+		if remb, ok := pkt.(*rtcp.ReceiverEstimatedMaximumBitrate); ok {
+			a.SetEstimate(int(remb.Bitrate))
+		}
+	}
+}
+
+// Allocation returns the bit rate allocated to track in bps, or 0 if it's not allocated yet.
+func (a *BandwidthAllocator) Allocation(track Track) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, allocated := range a.tracks {
+		if allocated.track == track {
+			return allocated.allocation
+		}
+	}
+	return 0
+}
+
+// allocate splits the estimate between tracks. Nothing is allocated until the estimate is known.
+func (a *BandwidthAllocator) allocate() {
+	if a.estimate <= 0 {
+		return
+	}
+
+	bandwidths := make([]TrackBandwidth, len(a.tracks))
+	for i, allocated := range a.tracks {
+		bandwidths[i] = allocated.bandwidth
+	}
+	for i, bps := range allocateBandwidth(a.estimate, bandwidths) {
+		a.tracks[i].allocation = bps
+		a.tracks[i].track.setBitRate(bps)
+	}
+}
+
+// allocateBandwidth returns bit rates for bandwidths' tracks within estimate. Minimums are given
+// by priority, highest first, and the rest is split by priority. A track's share above its cap
+// is split between other tracks.
+func allocateBandwidth(estimate int, bandwidths []TrackBandwidth) []int {
+	allocations := make([]int, len(bandwidths))
+	capOf := func(i int) int {
+		if max := bandwidths[i].MaxBitRate; max > 0 {
+			return max
+		}
+		return estimate
+	}
+
+	order := make([]int, len(bandwidths))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bandwidths[order[i]].weight() > bandwidths[order[j]].weight()
+	})
+
+	remaining := estimate
+	for _, i := range order {
+		min := bandwidths[i].MinBitRate
+		if c := capOf(i); min > c {
+			min = c
+		}
+		if min > remaining {
+			min = remaining
+		}
+		allocations[i] = min
+		remaining -= min
+	}
+
+	active := order
+	for remaining > 0 && len(active) > 0 {
+		var total float64
+		for _, i := range active {
+			total += bandwidths[i].weight()
+		}
+
+		var next []int
+		spent := 0
+		for _, i := range active {
+			share := int(float64(remaining) * bandwidths[i].weight() / total)
+			if c := capOf(i); allocations[i]+share >= c {
+				share = c - allocations[i]
+			} else {
+				next = append(next, i)
+			}
+			allocations[i] += share
+			spent += share
+		}
+		remaining -= spent
+		if len(next) == len(active) {
+			// No track reached its cap, so the rest is rounding error.
+			break
+		}
+		active = next
+	}
+	return allocations
+}
@@ -0,0 +1,147 @@
+package mediadevices
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+)
+
+func TestAllocateBandwidth(t *testing.T) {
+	testCases := map[string]struct {
+		estimate   int
+		bandwidths []TrackBandwidth
+		expected   []int
+	}{
+		"Equal": {
+			estimate:   1000,
+			bandwidths: []TrackBandwidth{{}, {}},
+			expected:   []int{500, 500},
+		},
+		"Priority": {
+			estimate:   1200,
+			bandwidths: []TrackBandwidth{{Priority: 1}, {Priority: 2}},
+			expected:   []int{400, 800},
+		},
+		"Capped": {
+			estimate:   1000,
+			bandwidths: []TrackBandwidth{{MaxBitRate: 200}, {Priority: 4}},
+			expected:   []int{200, 800},
+		},
+		"AllCapped": {
+			estimate:   1000,
+			bandwidths: []TrackBandwidth{{MaxBitRate: 200}, {MaxBitRate: 300}},
+			expected:   []int{200, 300},
+		},
+		"Minimum": {
+			estimate:   1000,
+			bandwidths: []TrackBandwidth{{MinBitRate: 600}, {Priority: 4}},
+			expected:   []int{680, 320},
+		},
+		"MinimumByPriority": {
+			estimate:   500,
+			bandwidths: []TrackBandwidth{{MinBitRate: 400}, {MinBitRate: 400, Priority: 2}},
+			expected:   []int{100, 400},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if allocations := allocateBandwidth(c.estimate, c.bandwidths); !reflect.DeepEqual(c.expected, allocations) {
+				t.Fatalf("expected %v, but got %v", c.expected, allocations)
+			}
+		})
+	}
+}
+
+type testBitRateEncoder struct {
+	r       video.Reader
+	bitRate int
+}
+
+func (e *testBitRateEncoder) Read() ([]byte, func(), error) {
+	if _, _, err := e.r.Read(); err != nil {
+		return nil, func() {}, err
+	}
+	return []byte{0xff}, func() {}, nil
+}
+
+func (e *testBitRateEncoder) SetBitRate(b int) error {
+	e.bitRate = b
+	return nil
+}
+
+func (e *testBitRateEncoder) Close() error         { return nil }
+func (e *testBitRateEncoder) ForceKeyFrame() error { return nil }
+
+type testBitRateEncoderBuilder struct {
+	encoders []*testBitRateEncoder
+}
+
+func (b *testBitRateEncoderBuilder) RTPCodec() *codec.RTPCodec { return codec.NewRTPVP8Codec(90000) }
+
+func (b *testBitRateEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	e := &testBitRateEncoder{r: r}
+	b.encoders = append(b.encoders, e)
+	return e, nil
+}
+
+func TestBandwidthAllocator(t *testing.T) {
+	builder := &testBitRateEncoderBuilder{}
+	selector := NewCodecSelector(WithVideoEncoders(builder))
+	img := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	camera := NewVideoTrack(&testVideoSource{img: img}, selector)
+	screen := NewVideoTrack(&testVideoSource{img: img}, selector)
+	defer camera.Close()
+	defer screen.Close()
+
+	a := NewBandwidthAllocator()
+	if err := a.SetTrackBandwidth(camera, TrackBandwidth{MaxBitRate: 300000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetTrackBandwidth(screen, TrackBandwidth{Priority: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if bps := a.Allocation(camera); bps != 0 {
+		t.Fatalf("expected nothing to be allocated without the estimate, but got %d", bps)
+	}
+
+	var readers []EncodedReadCloser
+	for _, track := range []Track{camera, screen} {
+		r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		readers = append(readers, r)
+	}
+
+	a.HandleRTCP([]rtcp.Packet{&rtcp.ReceiverEstimatedMaximumBitrate{Bitrate: 1200000}})
+	for _, r := range readers {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, expected := range []int{300000, 900000} {
+		if bps := builder.encoders[i].bitRate; bps != expected {
+			t.Fatalf("expected the bit rate of encoder %d to be %d, but got %d", i, expected, bps)
+		}
+	}
+
+	a.RemoveTrack(camera)
+	if _, _, err := readers[1].Read(); err != nil {
+		t.Fatal(err)
+	}
+	if bps := builder.encoders[1].bitRate; bps != 1200000 {
+		t.Fatalf("expected the bandwidth of the removed track to be reallocated, but got %d", bps)
+	}
+
+	if err := a.SetTrackBandwidth(&mockMediaStreamTrack{}, TrackBandwidth{}); err != errUnsupportedTrack {
+		t.Fatalf("expected %v, but got %v", errUnsupportedTrack, err)
+	}
+}
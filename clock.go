@@ -0,0 +1,117 @@
+package mediadevices
+
+import (
+	"image"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const defaultMaxSkew = 20 * time.Millisecond
+
+// MediaClock is a capture clock shared by audio and video tracks. Samplers on the same MediaClock produce
+// RTP timestamps from the same time source, so sender reports, which map RTP timestamps to NTP time, keep
+// audio and video in sync.
+type MediaClock struct {
+	now     func() time.Time
+	maxSkew time.Duration
+
+	once  sync.Once
+	start time.Time
+}
+
+// MediaClockOption is a type for specifying MediaClock options
+type MediaClockOption func(*MediaClock)
+
+// WithMaxSkew sets the most skew allowed between audio samples and the clock before the audio is
+// resampled to correct the drift. The default is 20 ms.
+func WithMaxSkew(skew time.Duration) MediaClockOption {
+	return func(c *MediaClock) {
+		c.maxSkew = skew
+	}
+}
+
+// WithTimeSource replaces the clock's time source. The default is time.Now.
+func WithTimeSource(now func() time.Time) MediaClockOption {
+	return func(c *MediaClock) {
+		c.now = now
+	}
+}
+
+// NewMediaClock constructs MediaClock with given variadic options
+func NewMediaClock(opts ...MediaClockOption) *MediaClock {
+	c := MediaClock{
+		now:     time.Now,
+		maxSkew: defaultMaxSkew,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c
+}
+
+// Now returns the current time from the clock's time source. The first call also marks the clock's
+// start.
+func (c *MediaClock) Now() time.Time {
+	now := c.now()
+	c.once.Do(func() {
+		c.start = now
+	})
+	return now
+}
+
+// Elapsed returns the time elapsed since the clock started.
+func (c *MediaClock) Elapsed() time.Duration {
+	now := c.Now()
+	return now.Sub(c.start)
+}
+
+// clockCaptureTimes moves the wall-clock capture times r's driver sets onto clock, by their age at wall when
+// they're read. Kernel timestamps then share a time domain with clock, which may be offset from the wall
+// clock, e.g. with clocksync. r is returned as is if it doesn't report metadata.
+func clockCaptureTimes(r video.Reader, clock *MediaClock, wall func() time.Time) video.Reader {
+	if _, ok := r.(video.MetadataReader); !ok {
+		return r
+	}
+	var m video.Metadata
+	return video.NewMetadataReader(video.ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		m, _ = video.MetadataOf(r)
+		if !m.CaptureTime.IsZero() {
+			m.CaptureTime = clock.Now().Add(-wall().Sub(m.CaptureTime))
+		}
+		return img, release, nil
+	}), func() video.Metadata { return m })
+}
+
+// newClockAudioReader resamples r's audio to follow clock, so it stays in sync with video on the same clock
+// while its RTP timestamps keep nominal durations, as RFC 7587 requires for Opus. Drift is measured from when
+// audio.NewDriftBuffer's goroutine reads chunks from r, i.e. at the device rate rather than the encoder's. The
+// returned func stops the goroutine after the chunk it's reading.
+func newClockAudioReader(r audio.Reader, clock *MediaClock, sampleRate int) (audio.Reader, func()) {
+	var stopped uint32
+	src := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		if atomic.LoadUint32(&stopped) != 0 {
+			return nil, func() {}, io.EOF
+		}
+		return r.Read()
+	})
+
+	// Encoders buffer the 10 ms chunks again for their frame sizes
+	nSamples := sampleRate / 100
+	if nSamples <= 0 {
+		nSamples = 480
+	}
+	buffer := audio.NewDriftBuffer(nSamples, audio.WithDriftClock(clock.Now, clock.maxSkew))
+	return buffer(src), func() { atomic.StoreUint32(&stopped, 1) }
+}
@@ -0,0 +1,260 @@
+package mediadevices
+
+import (
+	"image"
+	"io"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/webrtc/v3"
+)
+
+func TestMediaClock(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	clock := NewMediaClock(WithTimeSource(func() time.Time { return now }))
+
+	if elapsed := clock.Elapsed(); elapsed != 0 {
+		t.Fatalf("expected the clock to start at 0, but got %v", elapsed)
+	}
+
+	now = now.Add(time.Second)
+	if elapsed := clock.Elapsed(); elapsed != time.Second {
+		t.Fatalf("expected 1s after a second, but got %v", elapsed)
+	}
+}
+
+func TestClockAudioReader(t *testing.T) {
+	const sampleRate = 48000
+	const maxSkew = 10 * time.Millisecond
+
+	testCases := map[string]time.Duration{
+		// Clock intervals of the 10 ms chunks
+		"DeviceRunsFast": 9980 * time.Microsecond,
+		"DeviceRunsSlow": 10020 * time.Microsecond,
+		"NoDrift":        10 * time.Millisecond,
+	}
+
+	for name, interval := range testCases {
+		interval := interval
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			now := time.Unix(0, 0)
+			clock := NewMediaClock(
+				WithTimeSource(func() time.Time {
+					mu.Lock()
+					defer mu.Unlock()
+					return now
+				}),
+				WithMaxSkew(maxSkew),
+			)
+
+			// The clock reads chunks at the device's 100 Hz. The source waits for the consumer, so the buffer doesn't
+			// overflow.
+			const chunks = 2000
+			var read int
+			ready := make(chan struct{}, chunks+10)
+			for i := 0; i < 10; i++ {
+				ready <- struct{}{}
+			}
+			src := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+				if read == chunks {
+					return nil, func() {}, io.EOF
+				}
+				<-ready
+				mu.Lock()
+				now = time.Unix(0, 0).Add(time.Duration(read) * interval)
+				mu.Unlock()
+				read++
+				return wave.NewInt16Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: sampleRate}), func() {}, nil
+			})
+
+			r, stop := newClockAudioReader(src, clock, sampleRate)
+			defer stop()
+			var total int
+			for {
+				chunk, _, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				total += chunk.ChunkInfo().Len
+				ready <- struct{}{}
+			}
+
+			// Output follows the clock from the first chunk, which was captured 10 ms before it was read
+			elapsed := float64(sampleRate)*(time.Duration(chunks-1)*interval).Seconds() + 480
+			skew := math.Abs(float64(total) - elapsed)
+			tolerance := float64(sampleRate)*maxSkew.Seconds() + 480
+			if skew > tolerance {
+				t.Fatalf("expected skew to be within %f samples, but got %f", tolerance, skew)
+			}
+		})
+	}
+}
+
+func TestCaptureVideoSampler(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := NewMediaClock(WithTimeSource(func() time.Time { return now }))
+
+	var captureTime time.Time
+	sample := newCaptureVideoSampler(clock, 90000, func() time.Time { return captureTime })
+
+	// Encoded 50 ms after capture, but the duration is measured between capture times
+	captureTime = now.Add(100 * time.Millisecond)
+	now = captureTime.Add(50 * time.Millisecond)
+	if samples := sample(); samples != 9000 {
+		t.Fatalf("expected 9000 samples, but got %d", samples)
+	}
+	captureTime = captureTime.Add(33 * time.Millisecond)
+	now = captureTime.Add(10 * time.Millisecond)
+	if samples := sample(); samples != 2970 {
+		t.Fatalf("expected 2970 samples, but got %d", samples)
+	}
+
+	// Falls back to the clock
+	captureTime = time.Time{}
+	if samples := sample(); samples != 900 {
+		t.Fatalf("expected 900 samples, but got %d", samples)
+	}
+}
+
+func TestCaptureVideoSamplerOffsetClock(t *testing.T) {
+	for _, offset := range []time.Duration{time.Hour, -time.Hour} {
+		wall := time.Unix(1000, 0)
+		clock := NewMediaClock(WithTimeSource(func() time.Time { return wall.Add(offset) }))
+
+		// The driver reports kernel timestamps on the wall clock, 5ms before frames are read
+		src := video.NewMetadataReader(video.ReaderFunc(func() (image.Image, func(), error) {
+			return image.NewGray(image.Rect(0, 0, 1, 1)), func() {}, nil
+		}), func() video.Metadata {
+			return video.Metadata{CaptureTime: wall.Add(-5 * time.Millisecond)}
+		})
+		r := clockCaptureTimes(src, clock, func() time.Time { return wall })
+		sample := newCaptureVideoSampler(clock, 90000, func() time.Time {
+			m, _ := video.MetadataOf(r)
+			return m.CaptureTime
+		})
+
+		for i := 0; i < 10; i++ {
+			wall = wall.Add(33 * time.Millisecond)
+			if _, _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+			expected := uint32(2970)
+			if i == 0 {
+				expected = 2520
+			}
+			if samples := sample(); samples != expected {
+				t.Fatalf("%v: expected %d samples for the frame %d, but got %d", offset, expected, i, samples)
+			}
+		}
+	}
+}
+
+func TestCaptureVideoSamplerVariableRate(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := NewMediaClock(WithTimeSource(func() time.Time { return now }))
+	sample := newCaptureVideoSampler(clock, 90000, func() time.Time { return now })
+
+	// A screen capture that only delivers frames on damage
+	intervals := []time.Duration{16667 * time.Microsecond, 5 * time.Second, 41667 * time.Microsecond, 250 * time.Millisecond}
+	var elapsed time.Duration
+	var samples uint32
+	for i := 0; i < 100; i++ {
+		interval := intervals[i%len(intervals)]
+		now = now.Add(interval)
+		elapsed += interval
+		n := sample()
+		if expected := uint32(interval * 90000 / time.Second); n < expected-1 || n > expected+1 {
+			t.Fatalf("expected %d samples for %v, but got %d", expected, interval, n)
+		}
+		samples += n
+	}
+	if expected := uint32(math.Round(elapsed.Seconds() * 90000)); samples != expected {
+		t.Fatalf("expected %d samples without the drift, but got %d", expected, samples)
+	}
+}
+
+type reorderingTestEncoder struct {
+	testPreparedEncoder
+	frame codec.EncodedFrame
+}
+
+func (e *reorderingTestEncoder) EncodedFrame() (codec.EncodedFrame, bool) { return e.frame, true }
+
+func TestPresentationClock(t *testing.T) {
+	base := time.Unix(1600000000, 0)
+	var c presentationClock
+	if _, pts, dts := c.times(&testPreparedEncoder{}, time.Time{}); pts != 0 || dts != 0 {
+		t.Fatalf("expected no times without the capture time, but got %v and %v", pts, dts)
+	}
+	if captured, pts, dts := c.times(&testPreparedEncoder{}, base); !captured.Equal(base) || pts != 0 || dts != 0 {
+		t.Fatalf("expected the first frame at 0, but got %v and %v", pts, dts)
+	}
+
+	// Frame 3 was reordered, so it comes out before frame 1, which was read last
+	e := &reorderingTestEncoder{frame: codec.EncodedFrame{
+		Metadata: video.Metadata{CaptureTime: base.Add(100 * time.Millisecond)},
+		PTS:      100 * time.Millisecond,
+		DTS:      34 * time.Millisecond,
+	}}
+	captured, pts, dts := c.times(e, base.Add(34*time.Millisecond))
+	if !captured.Equal(e.frame.Metadata.CaptureTime) {
+		t.Fatalf("expected the capture time of the reported frame, but got %v", captured)
+	}
+	if pts != 100*time.Millisecond || dts != 34*time.Millisecond {
+		t.Fatalf("expected the PTS 100ms and the DTS 34ms, but got %v and %v", pts, dts)
+	}
+}
+
+func TestNominalSampler(t *testing.T) {
+	// 1501.5 samples per frame at 59.94 fps and 90kHz
+	sample := newVideoSampler(TimestampNominal, NewMediaClock(), 90000, 60000.0/1001, nil)
+	var total uint32
+	for i := 0; i < 1000; i++ {
+		total += sample()
+	}
+	if total != 1501500 {
+		t.Fatalf("expected 1501500 samples without the drift, but got %d", total)
+	}
+}
+
+func TestClockSampler(t *testing.T) {
+	now := time.Unix(0, 0)
+	clock := NewMediaClock(WithTimeSource(func() time.Time { return now }))
+
+	captureTime := now
+	video := newVideoSampler(TimestampClock, clock, 90000, 30, func() time.Time { return captureTime })
+	audio := newSourceAudioSampler(TimestampClock, clock, 48000, 20*time.Millisecond)
+
+	// Capture time and latency are ignored
+	now = now.Add(50 * time.Millisecond)
+	if samples := video(); samples != 4500 {
+		t.Fatalf("expected 4500 samples, but got %d", samples)
+	}
+	if samples := audio(); samples != 2400 {
+		t.Fatalf("expected 2400 samples, but got %d", samples)
+	}
+}
+
+func TestWithClockRate(t *testing.T) {
+	encoder := &testBitRateEncoderBuilder{}
+	selector := NewCodecSelector(WithVideoEncoders(encoder), WithClockRate("video/VP8", 48000))
+
+	var m webrtc.MediaEngine
+	selector.Populate(&m)
+	if c := selector.rtpCodec(encoder.RTPCodec()); c.ClockRate != 48000 {
+		t.Fatalf("expected the clock rate of 48000, but got %d", c.ClockRate)
+	}
+	if c := NewCodecSelector().rtpCodec(encoder.RTPCodec()); c.ClockRate != 90000 {
+		t.Fatalf("expected the clock rate of the codec, but got %d", c.ClockRate)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/overuse"
 	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/webrtc/v3"
 )
@@ -17,6 +18,14 @@ import (
 type CodecSelector struct {
 	videoEncoders []codec.VideoEncoderBuilder
 	audioEncoders []codec.AudioEncoderBuilder
+	clock         *MediaClock
+	overuseOpts   []overuse.Option
+	// timestamps is the RTP timestamp source, and clockRates are clock rates by lower case codec
+	// mime type.
+	timestamps TimestampSource
+	clockRates map[string]uint32
+
+	preparedVideoCodecs []string
 }
 
 // CodecSelectorOption is a type for specifying CodecSelector options
@@ -36,6 +45,52 @@ func WithAudioEncoders(encoders ...codec.AudioEncoderBuilder) CodecSelectorOptio
 	}
 }
 
+// WithMediaClock shares clock between all tracks using this CodecSelector. Their RTP timestamps are
+// generated from the same clock, and audio is resampled to follow it.
+func WithMediaClock(clock *MediaClock) CodecSelectorOption {
+	return func(t *CodecSelector) {
+		t.clock = clock
+	}
+}
+
+// WithTimestampSource selects the time that tracks' RTP timestamps are generated from. The default is
+// TimestampDefault. Use TimestampClock with a MediaClock of an external time source, e.g. PTP, to timestamp
+// buffers by the external clock.
+func WithTimestampSource(source TimestampSource) CodecSelectorOption {
+	return func(t *CodecSelector) {
+		t.timestamps = source
+	}
+}
+
+// WithClockRate replaces the codec's RTP clock rate, where the codec is formatted as "video/<codecName>" or
+// "audio/<codecName>", e.g. to follow the clock rate of a receiver that isn't negotiated by WebRTC. Populate
+// registers codecs to the MediaEngine with the clock rate.
+func WithClockRate(mimeType string, clockRate uint32) CodecSelectorOption {
+	return func(t *CodecSelector) {
+		if t.clockRates == nil {
+			t.clockRates = make(map[string]uint32)
+		}
+		t.clockRates[strings.ToLower(mimeType)] = clockRate
+	}
+}
+
+// WithOveruseDetectorOptions configures the video encoders' overuse detectors, which emit adaptation
+// signals to follow VideoTrack's degradation preference.
+func WithOveruseDetectorOptions(opts ...overuse.Option) CodecSelectorOption {
+	return func(t *CodecSelector) {
+		t.overuseOpts = opts
+	}
+}
+
+// WithPreparedVideoEncoders builds the codecs' video encoders ahead of time when GetUserMedia or GetDisplayMedia
+// creates video tracks, so encoder initialization doesn't delay the first frame. The codec's first reader,
+// e.g. the one created on Bind, uses the prepared encoder.
+func WithPreparedVideoEncoders(codecNames ...string) CodecSelectorOption {
+	return func(t *CodecSelector) {
+		t.preparedVideoCodecs = codecNames
+	}
+}
+
 // NewCodecSelector constructs CodecSelector with given variadic options
 func NewCodecSelector(opts ...CodecSelectorOption) *CodecSelector {
 	var track CodecSelector
@@ -50,12 +105,35 @@ func NewCodecSelector(opts ...CodecSelectorOption) *CodecSelector {
 // Populate lets the webrtc engine be aware of supported codecs that are contained in CodecSelector
 func (selector *CodecSelector) Populate(setting *webrtc.MediaEngine) {
 	for _, encoder := range selector.videoEncoders {
-		setting.RegisterCodec(encoder.RTPCodec().RTPCodecParameters, webrtc.RTPCodecTypeVideo)
+		rtpCodec := selector.rtpCodec(encoder.RTPCodec())
+		setting.RegisterCodec(rtpCodec.RTPCodecParameters, webrtc.RTPCodecTypeVideo)
+		for _, redundancyCodec := range rtpCodec.RedundancyCodecs() {
+			setting.RegisterCodec(redundancyCodec, webrtc.RTPCodecTypeVideo)
+		}
 	}
 
 	for _, encoder := range selector.audioEncoders {
-		setting.RegisterCodec(encoder.RTPCodec().RTPCodecParameters, webrtc.RTPCodecTypeAudio)
+		rtpCodec := selector.rtpCodec(encoder.RTPCodec())
+		if strings.EqualFold(rtpCodec.MimeType, codec.MimeTypeAAC) {
+			// Encoded readers only, since browsers don't support AAC
+			continue
+		}
+		setting.RegisterCodec(rtpCodec.RTPCodecParameters, webrtc.RTPCodecTypeAudio)
+		for _, redundancyCodec := range rtpCodec.RedundancyCodecs() {
+			setting.RegisterCodec(redundancyCodec, webrtc.RTPCodecTypeAudio)
+		}
+	}
+}
+
+// rtpCodec returns a copy of c with WithClockRate's clock rate, or c if the clock rate isn't replaced.
+func (selector *CodecSelector) rtpCodec(c *codec.RTPCodec) *codec.RTPCodec {
+	clockRate, ok := selector.clockRates[strings.ToLower(c.MimeType)]
+	if !ok {
+		return c
 	}
+	replaced := *c
+	replaced.ClockRate = clockRate
+	return &replaced
 }
 
 // selectVideoCodecByNames selects a single codec that can be built and matched. codecNames can be formatted as "video/<codecName>" or "<codecName>"
@@ -86,7 +164,7 @@ outer:
 		return nil, nil, errors.New(strings.Join(errReasons, "\n\n"))
 	}
 
-	return encodedReader, selectedEncoder.RTPCodec(), nil
+	return encodedReader, selector.rtpCodec(selectedEncoder.RTPCodec()), nil
 }
 
 func (selector *CodecSelector) selectVideoCodec(reader video.Reader, inputProp prop.Media, codecs ...webrtc.RTPCodecParameters) (codec.ReadCloser, *codec.RTPCodec, error) {
@@ -127,7 +205,7 @@ outer:
 		return nil, nil, errors.New(strings.Join(errReasons, "\n\n"))
 	}
 
-	return encodedReader, selectedEncoder.RTPCodec(), nil
+	return encodedReader, selector.rtpCodec(selectedEncoder.RTPCodec()), nil
 }
 
 func (selector *CodecSelector) selectAudioCodec(reader audio.Reader, inputProp prop.Media, codecs ...webrtc.RTPCodecParameters) (codec.ReadCloser, *codec.RTPCodec, error) {
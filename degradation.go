@@ -0,0 +1,240 @@
+package mediadevices
+
+import (
+	"image"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/overuse"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// DegradationPreference represents how a video track is degraded when the encoder can't keep up
+// with the source.
+// Reference: https://w3c.github.io/webrtc-pc/#dom-rtcdegradationpreference
+type DegradationPreference int
+
+// DegradationPreference values.
+const (
+	// DegradationPreferenceDisabled keeps the original resolution and frame rate even if the encoder can't keep up.
+	DegradationPreferenceDisabled DegradationPreference = iota
+	// DegradationPreferenceMaintainFramerate lowers the resolution to keep the frame rate.
+	DegradationPreferenceMaintainFramerate
+	// DegradationPreferenceMaintainResolution drops frames to keep the resolution.
+	DegradationPreferenceMaintainResolution
+	// DegradationPreferenceBalanced drops some frames first, and lowers the resolution after that.
+	DegradationPreferenceBalanced
+)
+
+func (p DegradationPreference) String() string {
+	switch p {
+	case DegradationPreferenceDisabled:
+		return "disabled"
+	case DegradationPreferenceMaintainFramerate:
+		return "maintain-framerate"
+	case DegradationPreferenceMaintainResolution:
+		return "maintain-resolution"
+	case DegradationPreferenceBalanced:
+		return "balanced"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	// degradationStep is the per-dimension frame size ratio, or the frame rate ratio, between two levels.
+	degradationStep = 0.75
+	// maxDegradationLevel limits degradation to 0.75^4, about 1/3 of the original
+	maxDegradationLevel = 4
+	// balancedDropLevel is how many frame rate levels DegradationPreferenceBalanced uses before lowering
+	// the resolution.
+	balancedDropLevel = 2
+	// minDegradedSize is the minimum width and height of degraded frames
+	minDegradedSize = 16
+
+	defaultDegradationFrameRate = 30
+)
+
+// degradationLevel is the degradation state. Each level multiplies the original value by degradationStep.
+type degradationLevel struct {
+	scale int
+	drop  int
+}
+
+// degradationController consumes one encoder's overuse detector signals, and decides which frames to drop
+// and what resolution to use.
+type degradationController struct {
+	preference func() DegradationPreference
+	budget     time.Duration
+	width      int
+	height     int
+
+	mu       sync.Mutex
+	detector *overuse.Detector
+	level    degradationLevel
+	credit   float64
+}
+
+func newDegradationController(preference func() DegradationPreference, inputProp prop.Media, opts ...overuse.Option) *degradationController {
+	frameRate := float64(inputProp.FrameRate)
+	if frameRate <= 0 {
+		frameRate = defaultDegradationFrameRate
+	}
+	budget := time.Duration(float64(time.Second) / frameRate)
+
+	return &degradationController{
+		preference: preference,
+		budget:     budget,
+		width:      inputProp.Width,
+		height:     inputProp.Height,
+		detector:   overuse.NewDetector(budget, opts...),
+	}
+}
+
+// wrap returns a reader that drops frames according to the current level, and marks the capture time
+// of frames delivered to the encoder.
+func (c *degradationController) wrap(r video.Reader) video.Reader {
+	return video.ReaderFunc(func() (image.Image, func(), error) {
+		for {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			c.mu.Lock()
+			keep := c.keepFrame()
+			if keep {
+				c.detector.FrameCaptured()
+			}
+			c.mu.Unlock()
+
+			if keep {
+				return img, release, nil
+			}
+			release()
+		}
+	})
+}
+
+// keepFrame spreads dropped frames evenly. c.mu must be held.
+func (c *degradationController) keepFrame() bool {
+	c.credit += math.Pow(degradationStep, float64(c.level.drop))
+	if c.credit < 1 {
+		return false
+	}
+	c.credit--
+	return true
+}
+
+// resize changes the original frame size, e.g. after Reconfigure. The level is kept.
+func (c *degradationController) resize(width, height int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.width, c.height = width, height
+}
+
+// resolution returns the current frame size. If the original frame size is unknown, frames aren't scaled.
+func (c *degradationController) resolution() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.resolutionLocked(c.level.scale)
+}
+
+func (c *degradationController) resolutionLocked(scale int) (int, int) {
+	if scale == 0 || c.width <= 0 || c.height <= 0 {
+		return c.width, c.height
+	}
+
+	ratio := math.Pow(degradationStep, float64(scale))
+	width := int(float64(c.width)*ratio) &^ 1
+	height := int(float64(c.height)*ratio) &^ 1
+	if width < minDegradedSize {
+		width = minDegradedSize
+	}
+	if height < minDegradedSize {
+		height = minDegradedSize
+	}
+	return width, height
+}
+
+// onEncoded marks that the last delivered frame has been encoded, and adapts the level with the detector's signal.
+// It returns true if the resolution changed and the encoder needs a reset.
+func (c *degradationController) onEncoded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	next := c.level
+	switch c.detector.FrameEncoded() {
+	case overuse.SignalAdaptDown:
+		next = c.degrade(c.level)
+	case overuse.SignalAdaptUp:
+		next = c.restore(c.level)
+	}
+	next = c.constrain(next)
+
+	if next == c.level {
+		return false
+	}
+
+	scaleChanged := next.scale != c.level.scale
+	logger.Debugf("adapting video from %+v to %+v with %s, usage: %.2f", c.level, next, c.preference(), c.detector.Usage())
+	c.level = next
+	// Dropped frames give the encoder more time
+	c.detector.SetBudget(time.Duration(float64(c.budget) / math.Pow(degradationStep, float64(next.drop))))
+	return scaleChanged
+}
+
+func (c *degradationController) canScale() bool {
+	return c.width > 0 && c.height > 0
+}
+
+func (c *degradationController) degrade(l degradationLevel) degradationLevel {
+	switch c.preference() {
+	case DegradationPreferenceMaintainFramerate:
+		if l.scale < maxDegradationLevel && c.canScale() {
+			l.scale++
+		}
+	case DegradationPreferenceMaintainResolution:
+		if l.drop < maxDegradationLevel {
+			l.drop++
+		}
+	case DegradationPreferenceBalanced:
+		switch {
+		case l.drop < balancedDropLevel:
+			l.drop++
+		case l.scale < maxDegradationLevel && c.canScale():
+			l.scale++
+		case l.drop < maxDegradationLevel:
+			l.drop++
+		}
+	}
+	return l
+}
+
+func (c *degradationController) restore(l degradationLevel) degradationLevel {
+	switch {
+	case l.drop > balancedDropLevel:
+		l.drop--
+	case l.scale > 0:
+		l.scale--
+	case l.drop > 0:
+		l.drop--
+	}
+	return l
+}
+
+// constrain resets levels the current preference doesn't allow, since it can change anytime.
+func (c *degradationController) constrain(l degradationLevel) degradationLevel {
+	switch c.preference() {
+	case DegradationPreferenceDisabled:
+		return degradationLevel{}
+	case DegradationPreferenceMaintainFramerate:
+		l.drop = 0
+	case DegradationPreferenceMaintainResolution:
+		l.scale = 0
+	}
+	return l
+}
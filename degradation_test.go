@@ -0,0 +1,129 @@
+package mediadevices
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/overuse"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func TestDegradationController(t *testing.T) {
+	inputProp := prop.Media{
+		Video: prop.Video{
+			Width:     640,
+			Height:    480,
+			FrameRate: 30,
+		},
+	}
+
+	testCases := map[string]struct {
+		preference     DegradationPreference
+		expectedScaled bool
+		expectedDrop   bool
+	}{
+		"Disabled": {
+			preference: DegradationPreferenceDisabled,
+		},
+		"MaintainFramerate": {
+			preference:     DegradationPreferenceMaintainFramerate,
+			expectedScaled: true,
+		},
+		"MaintainResolution": {
+			preference:   DegradationPreferenceMaintainResolution,
+			expectedDrop: true,
+		},
+		"Balanced": {
+			preference:     DegradationPreferenceBalanced,
+			expectedScaled: true,
+			expectedDrop:   true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			preference := testCase.preference
+			c := newDegradationController(func() DegradationPreference { return preference }, inputProp,
+				overuse.WithTimeSource(func() time.Time { return now }),
+				overuse.WithCPUMonitor(nil),
+			)
+
+			var frames int
+			reader := c.wrap(video.ReaderFunc(func() (image.Image, func(), error) {
+				frames++
+				return nil, func() {}, nil
+			}))
+
+			// Encode every frame in 60ms while the budget is 33ms
+			var rebuilt bool
+			for i := 0; i < 300; i++ {
+				reader.Read()
+				now = now.Add(60 * time.Millisecond)
+				if c.onEncoded() {
+					rebuilt = true
+				}
+			}
+
+			width, height := c.resolution()
+			if scaled := width < 640 && height < 480; scaled != testCase.expectedScaled {
+				t.Fatalf("expected scaled to be %v, but got %dx%d", testCase.expectedScaled, width, height)
+			}
+			if rebuilt != testCase.expectedScaled {
+				t.Fatalf("expected rebuilt to be %v, but got %v", testCase.expectedScaled, rebuilt)
+			}
+			if dropped := frames > 300; dropped != testCase.expectedDrop {
+				t.Fatalf("expected dropped to be %v, but read %d frames from the source", testCase.expectedDrop, frames)
+			}
+
+			// The encoder is fast enough now, so the original quality should be restored
+			for i := 0; i < 1000; i++ {
+				reader.Read()
+				now = now.Add(time.Millisecond)
+				c.onEncoded()
+			}
+
+			if width, height := c.resolution(); width != 640 || height != 480 {
+				t.Fatalf("expected resolution to be restored, but got %dx%d", width, height)
+			}
+			if c.level != (degradationLevel{}) {
+				t.Fatalf("expected level to be restored, but got %+v", c.level)
+			}
+		})
+	}
+}
+
+func TestDegradationControllerPreferenceChange(t *testing.T) {
+	now := time.Unix(0, 0)
+	preference := DegradationPreferenceMaintainFramerate
+	c := newDegradationController(func() DegradationPreference { return preference }, prop.Media{
+		Video: prop.Video{Width: 640, Height: 480},
+	},
+		overuse.WithTimeSource(func() time.Time { return now }),
+		overuse.WithCPUMonitor(nil),
+	)
+	reader := c.wrap(video.ReaderFunc(func() (image.Image, func(), error) {
+		return nil, func() {}, nil
+	}))
+
+	for i := 0; i < 10; i++ {
+		reader.Read()
+		now = now.Add(time.Second)
+		c.onEncoded()
+	}
+	if c.level.scale != 1 {
+		t.Fatalf("expected scale level to be 1, but got %d", c.level.scale)
+	}
+
+	preference = DegradationPreferenceDisabled
+	reader.Read()
+	if !c.onEncoded() {
+		t.Fatal("expected the encoder to be rebuilt after disabling the degradation")
+	}
+	if c.level != (degradationLevel{}) {
+		t.Fatalf("expected level to be reset, but got %+v", c.level)
+	}
+}
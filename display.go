@@ -0,0 +1,120 @@
+package mediadevices
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+var errDisplayInUse = errors.New("the screen is being captured")
+
+// DisplaySource is a screen GetDisplayMedia can share.
+type DisplaySource struct {
+	MediaDeviceInfo
+	// Media is the screen properties that fit the constraints best, e.g. size and display surface.
+	Media  prop.Media
+	driver driver.Driver
+}
+
+// Thumbnail captures a screen frame and scales it to width, keeping the aspect ratio. If the driver
+// can't capture thumbnails itself, the screen is opened only while the frame is captured, so it fails if
+// the screen is already being captured.
+func (s DisplaySource) Thumbnail(width int) (image.Image, error) {
+	if t, ok := s.driver.(driver.Thumbnailer); ok {
+		return t.Thumbnail(width)
+	}
+	if s.driver.Status() != driver.StateClosed {
+		return nil, errDisplayInUse
+	}
+	recorder, ok := s.driver.(driver.VideoRecorder)
+	if !ok {
+		return nil, errInvalidDriverType
+	}
+
+	if err := s.driver.Open(); err != nil {
+		return nil, err
+	}
+	defer s.driver.Close()
+
+	r, err := recorder.VideoRecord(s.Media)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := video.Scale(width, -1, nil)(r).Read()
+	return img, err
+}
+
+// DisplayChoice is the screen DisplayPicker chose to share.
+type DisplayChoice struct {
+	// DeviceID is the DeviceID of one of the DisplaySources.
+	DeviceID string
+	// CaptureArea is the screen region to capture, which overrides the constraints' CaptureArea
+	// if it's not empty.
+	CaptureArea image.Rectangle
+}
+
+// DisplayPicker is called by GetDisplayMedia with screens that fit the constraints, so applications
+// can implement their own dialog to choose what to share, e.g. with screen thumbnails. Returning
+// an error cancels GetDisplayMedia, which returns the error.
+type DisplayPicker func(sources []DisplaySource) (DisplayChoice, error)
+
+// displaySources returns screens that fit the constraints, by priority.
+func displaySources(filter driver.FilterFn, constraints MediaTrackConstraints) []DisplaySource {
+	var sources []DisplaySource
+	for d, props := range queryDriverProperties(filter) {
+		info, ok := deviceInfo(d)
+		if !ok {
+			continue
+		}
+
+		var best prop.Media
+		found := false
+		minFitnessDist := 0.0
+		for _, p := range props {
+			fitnessDist, ok := constraints.MediaConstraints.FitnessDistance(p)
+			if ok && (!found || fitnessDist < minFitnessDist) {
+				best, found, minFitnessDist = p, true, fitnessDist
+			}
+		}
+		if found {
+			sources = append(sources, DisplaySource{MediaDeviceInfo: info, Media: best, driver: d})
+		}
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		pi, pj := sources[i].driver.Info().Priority, sources[j].driver.Info().Priority
+		if pi != pj {
+			return pi > pj
+		}
+		return sources[i].Label < sources[j].Label
+	})
+	return sources
+}
+
+// pickDisplay narrows the constraints down to the screen chosen by pick.
+func pickDisplay(filter driver.FilterFn, constraints MediaTrackConstraints, pick DisplayPicker) (driver.FilterFn, MediaTrackConstraints, error) {
+	sources := displaySources(filter, constraints)
+	if len(sources) == 0 {
+		return nil, MediaTrackConstraints{}, errNotFound
+	}
+
+	choice, err := pick(sources)
+	if err != nil {
+		return nil, MediaTrackConstraints{}, err
+	}
+
+	for _, s := range sources {
+		if s.DeviceID == choice.DeviceID {
+			if !choice.CaptureArea.Empty() {
+				constraints.CaptureArea = choice.CaptureArea
+			}
+			return driver.FilterAnd(filter, driver.FilterID(choice.DeviceID)), constraints, nil
+		}
+	}
+	return nil, MediaTrackConstraints{}, fmt.Errorf("failed to share the screen: %s is not one of the sources", choice.DeviceID)
+}
@@ -0,0 +1,125 @@
+package mediadevices
+
+import (
+	"errors"
+	"image"
+	"io"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+type screenAdapterMock struct {
+	done chan struct{}
+}
+
+func (a *screenAdapterMock) Open() error {
+	a.done = make(chan struct{})
+	return nil
+}
+
+func (a *screenAdapterMock) Close() error {
+	close(a.done)
+	return nil
+}
+
+func (a *screenAdapterMock) Properties() []prop.Media {
+	return []prop.Media{{
+		Video: prop.Video{
+			Width:          64,
+			Height:         32,
+			FrameFormat:    frame.FormatRGBA,
+			DisplaySurface: prop.DisplaySurfaceMonitor,
+		},
+	}}
+}
+
+func (a *screenAdapterMock) VideoRecord(p prop.Media) (video.Reader, error) {
+	img := image.NewRGBA(image.Rect(0, 0, p.Width, p.Height))
+	return video.ReaderFunc(func() (image.Image, func(), error) {
+		select {
+		case <-a.done:
+			return nil, func() {}, io.EOF
+		default:
+		}
+		return img, func() {}, nil
+	}), nil
+}
+
+func TestGetDisplayMediaPicker(t *testing.T) {
+	primary, secondary := &screenAdapterMock{}, &screenAdapterMock{}
+	for a, info := range map[*screenAdapterMock]driver.Info{
+		primary:   {Label: "primary", DeviceType: driver.Screen, Priority: driver.PriorityHigh},
+		secondary: {Label: "secondary", DeviceType: driver.Screen},
+	} {
+		if err := RegisterDriverAdapter(a, info); err != nil {
+			t.Fatal(err)
+		}
+		defer driver.GetManager().Unregister(a)
+	}
+
+	t.Run("Choose", func(t *testing.T) {
+		s, err := GetDisplayMedia(MediaStreamConstraints{
+			Video: func(c *MediaTrackConstraints) {},
+			PickDisplay: func(sources []DisplaySource) (DisplayChoice, error) {
+				if len(sources) != 2 || sources[0].Label != "primary" || sources[1].Label != "secondary" {
+					t.Fatalf("expected the primary and the secondary screens in order, but got %v", sources)
+				}
+				thumbnail, err := sources[1].Thumbnail(16)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if expected := image.Rect(0, 0, 16, 8); thumbnail.Bounds() != expected {
+					t.Fatalf("expected the thumbnail to be %v, but got %v", expected, thumbnail.Bounds())
+				}
+				return DisplayChoice{DeviceID: sources[1].DeviceID, CaptureArea: image.Rect(8, 8, 40, 24)}, nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		track := s.GetVideoTracks()[0].(*VideoTrack)
+		defer track.Close()
+
+		if track.ID() != driver.GetManager().Query(driver.FilterAnd(
+			driver.FilterVideoRecorder(), func(d driver.Driver) bool { return d.Info().Label == "secondary" },
+		))[0].ID() {
+			t.Fatal("expected the secondary screen to be shared")
+		}
+		img, _, err := track.NewReader(false).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := image.Rect(0, 0, 32, 16); img.Bounds() != expected {
+			t.Fatalf("expected the frame to be cropped to %v, but got %v", expected, img.Bounds())
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		errCancelled := errors.New("cancelled")
+		_, err := GetDisplayMedia(MediaStreamConstraints{
+			Video: func(c *MediaTrackConstraints) {},
+			PickDisplay: func(sources []DisplaySource) (DisplayChoice, error) {
+				return DisplayChoice{}, errCancelled
+			},
+		})
+		if err != errCancelled {
+			t.Fatalf("expected %v, but got %v", errCancelled, err)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := GetDisplayMedia(MediaStreamConstraints{
+			Video: func(c *MediaTrackConstraints) {},
+			PickDisplay: func(sources []DisplaySource) (DisplayChoice, error) {
+				return DisplayChoice{DeviceID: "unknown"}, nil
+			},
+		})
+		if err == nil {
+			t.Fatal("expected an error for an unknown screen")
+		}
+	})
+}
@@ -8,8 +8,10 @@ require (
 	github.com/google/uuid v1.2.0
 	github.com/kbinani/screenshot v0.0.0-20210326165202-b96eb3309bb0
 	github.com/pion/logging v0.2.2
+	github.com/pion/rtcp v1.2.6
 	github.com/pion/rtp v1.6.5
 	github.com/pion/webrtc/v3 v3.0.29
 	golang.org/x/image v0.0.0-20210622092929-e6eecd499c2c
+	golang.org/x/net v0.0.0-20210420210106-798c2154c571
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 )
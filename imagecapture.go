@@ -0,0 +1,71 @@
+package mediadevices
+
+import (
+	"image"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// PhotoSettings are settings for a photo taken by VideoTrack.TakePhoto.
+// Reference: https://w3c.github.io/mediacapture-image/#photosettings-section
+type PhotoSettings struct {
+	// ImageWidth and ImageHeight are the photo's ideal size. The largest photo is taken if both are 0.
+	ImageWidth, ImageHeight int
+}
+
+// PhotoCapabilities returns the properties of photos the track's device can take, or nil if
+// photos are video frames.
+func (track *VideoTrack) PhotoCapabilities() []prop.Media {
+	source, _, _ := track.switcher.current()
+	if taker, ok := source.(driver.PhotoTaker); ok {
+		return taker.PhotoProperties()
+	}
+	return nil
+}
+
+// TakePhoto takes a still photo, like browsers' ImageCapture.takePhoto. If the driver supports it,
+// e.g. V4L2, the photo is taken at the settings' resolution, e.g. the camera's full native resolution,
+// while the video keeps its resolution. Otherwise, the next video frame is returned.
+func (track *VideoTrack) TakePhoto(settings PhotoSettings) (image.Image, error) {
+	source, _, _ := track.switcher.current()
+	taker, ok := source.(driver.PhotoTaker)
+	var props []prop.Media
+	if ok {
+		props = taker.PhotoProperties()
+	}
+	if len(props) == 0 {
+		img, _, err := track.NewReader(true).Read()
+		return img, err
+	}
+	return taker.TakePhoto(selectPhotoProperty(props, settings))
+}
+
+// selectPhotoProperty returns the property in props that fits settings best.
+func selectPhotoProperty(props []prop.Media, settings PhotoSettings) prop.Media {
+	best := props[0]
+	if settings.ImageWidth == 0 && settings.ImageHeight == 0 {
+		for _, p := range props[1:] {
+			if p.Width*p.Height > best.Width*best.Height {
+				best = p
+			}
+		}
+		return best
+	}
+
+	var constraints prop.MediaConstraints
+	if settings.ImageWidth > 0 {
+		constraints.Width = prop.Int(settings.ImageWidth)
+	}
+	if settings.ImageHeight > 0 {
+		constraints.Height = prop.Int(settings.ImageHeight)
+	}
+	minFitnessDist, _ := constraints.FitnessDistance(best)
+	for _, p := range props[1:] {
+		if fitnessDist, _ := constraints.FitnessDistance(p); fitnessDist < minFitnessDist {
+			minFitnessDist = fitnessDist
+			best = p
+		}
+	}
+	return best
+}
@@ -0,0 +1,91 @@
+package mediadevices
+
+import (
+	"image"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func photoMedia(width, height int) prop.Media {
+	return prop.Media{Video: prop.Video{Width: width, Height: height, FrameFormat: frame.FormatI420}}
+}
+
+type photoCameraMock struct {
+	screenAdapterMock
+	taken []prop.Media
+}
+
+func (a *photoCameraMock) PhotoProperties() []prop.Media {
+	return []prop.Media{photoMedia(1920, 1080), photoMedia(4032, 3024)}
+}
+
+func (a *photoCameraMock) TakePhoto(p prop.Media) (image.Image, error) {
+	a.taken = append(a.taken, p)
+	return image.NewRGBA(image.Rect(0, 0, p.Width, p.Height)), nil
+}
+
+func TestTakePhoto(t *testing.T) {
+	a := &photoCameraMock{}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "photo", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	d := driver.GetManager().Query(func(d driver.Driver) bool { return d.Info().Label == "photo" })[0]
+	c := MediaTrackConstraints{selectedMedia: photoMedia(64, 32)}
+	track, err := newTrackFromDriver(d, c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer track.Close()
+	videoTrack := track.(*VideoTrack)
+
+	if n := len(videoTrack.PhotoCapabilities()); n != 2 {
+		t.Fatalf("expected 2 capabilities, but got %d", n)
+	}
+
+	img, err := videoTrack.TakePhoto(PhotoSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := image.Rect(0, 0, 4032, 3024); img.Bounds() != expected {
+		t.Fatalf("expected the largest photo %v, but got %v", expected, img.Bounds())
+	}
+
+	img, err = videoTrack.TakePhoto(PhotoSettings{ImageWidth: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := image.Rect(0, 0, 1920, 1080); img.Bounds() != expected {
+		t.Fatalf("expected the closest photo %v, but got %v", expected, img.Bounds())
+	}
+
+	// The video keeps its resolution
+	videoFrame, _, err := videoTrack.NewReader(false).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := image.Rect(0, 0, 64, 32); videoFrame.Bounds() != expected {
+		t.Fatalf("expected the frame of the video %v, but got %v", expected, videoFrame.Bounds())
+	}
+}
+
+func TestTakePhotoFromVideo(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	track := NewVideoTrack(&testVideoSource{img: img}, nil).(*VideoTrack)
+	defer track.Close()
+
+	if capabilities := track.PhotoCapabilities(); capabilities != nil {
+		t.Fatalf("expected no capabilities, but got %v", capabilities)
+	}
+	photo, err := track.TakePhoto(PhotoSettings{ImageWidth: 1920})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if photo.Bounds() != img.Bounds() {
+		t.Fatalf("expected the frame of the video %v, but got %v", img.Bounds(), photo.Bounds())
+	}
+}
@@ -0,0 +1,70 @@
+// Package privacy reads Windows privacy settings, which allow or deny applications use of cameras
+// and microphones. Desktop applications are never prompted, so permission is only granted or denied.
+package privacy
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// Consent store capabilities.
+const (
+	Webcam     = "webcam"
+	Microphone = "microphone"
+)
+
+const consentStore = `Software\Microsoft\Windows\CurrentVersion\CapabilityAccessManager\ConsentStore\`
+
+// Permission returns desktop applications' permission to use capability. Access is denied if
+// any switch is off: device access in HKLM, user access, or desktop application access.
+// It's unknown on versions before the consent store, i.e. Windows 10 1803.
+func Permission(capability string) driver.Permission {
+	keys := []struct {
+		root syscall.Handle
+		path string
+	}{
+		{syscall.HKEY_LOCAL_MACHINE, consentStore + capability},
+		{syscall.HKEY_CURRENT_USER, consentStore + capability},
+		{syscall.HKEY_CURRENT_USER, consentStore + capability + `\NonPackaged`},
+	}
+
+	permission := driver.PermissionUnknown
+	for _, k := range keys {
+		v, ok := readString(k.root, k.path, "Value")
+		switch {
+		case !ok:
+		case v == "Deny":
+			return driver.PermissionDenied
+		case v == "Allow":
+			permission = driver.PermissionGranted
+		}
+	}
+	return permission
+}
+
+// readString returns key's string value, or false if it doesn't exist.
+func readString(root syscall.Handle, path, name string) (string, bool) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return "", false
+	}
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(root, p, 0, syscall.KEY_READ, &key); err != nil {
+		return "", false
+	}
+	defer syscall.RegCloseKey(key)
+
+	n, err := syscall.UTF16PtrFromString(name)
+	if err != nil {
+		return "", false
+	}
+	var typ uint32
+	buf := make([]uint16, 64)
+	size := uint32(len(buf) * 2)
+	if err := syscall.RegQueryValueEx(key, n, nil, &typ, (*byte)(unsafe.Pointer(&buf[0])), &size); err != nil || typ != syscall.REG_SZ {
+		return "", false
+	}
+	return syscall.UTF16ToString(buf[:size/2]), true
+}
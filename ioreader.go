@@ -1,8 +1,16 @@
 package mediadevices
 
+import "time"
+
 type EncodedBuffer struct {
 	Data    []byte
 	Samples uint32
+	// CaptureTime is when the encoded frame was captured, or zero if it's unknown.
+	CaptureTime time.Time
+	// PTS and DTS are a video frame's presentation and decoding times since the track's first captured frame. DTS
+	// is before PTS when the encoder reorders frames, e.g. x264 with B-frames, and is negative for the first frames
+	// of a reordered stream. Both are zero for audio and when capture times are unknown.
+	PTS, DTS time.Duration
 }
 
 type EncodedReadCloser interface {
@@ -16,7 +24,11 @@ type encodedReadCloserImpl struct {
 }
 
 func (r *encodedReadCloserImpl) Read() (EncodedBuffer, func(), error) {
-	return r.readFn()
+	buffer, release, err := r.readFn()
+	if err == nil {
+		release = resources.trackBuffer("encoded buffer", release)
+	}
+	return buffer, release, err
 }
 
 func (r *encodedReadCloserImpl) Close() error {
@@ -0,0 +1,128 @@
+package mediadevices
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencyWindow is how many recent frames the latency distributions cover.
+const latencyWindow = 512
+
+// LatencyDistribution is the latency distribution of recent frames.
+type LatencyDistribution struct {
+	// Count is the number of frames in the distribution.
+	Count int
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// LatencyStats is a track's frame latency, from when frames are captured to when they're packetized into
+// RTP packets. Each stage starts where the previous one ends.
+type LatencyStats struct {
+	// Process is from capture to encoder input, which includes transforms and time spent waiting
+	// for the encoder.
+	Process LatencyDistribution
+	// Encode is from encoder input to the encoded frame, which includes scaling for degradation.
+	Encode LatencyDistribution
+	// Packetize is from the encoded frame to RTP packets with FEC. Only RTP readers measure it.
+	Packetize LatencyDistribution
+	// Total is from capture to RTP packets. Only RTP readers measure it.
+	Total LatencyDistribution
+}
+
+// latencyRing keeps the latency of the last latencyWindow frames.
+type latencyRing struct {
+	samples []time.Duration
+	next    int
+}
+
+func (r *latencyRing) add(d time.Duration) {
+	if d < 0 {
+		// The capture time is on another clock
+		d = 0
+	}
+	if len(r.samples) < latencyWindow {
+		r.samples = append(r.samples, d)
+		return
+	}
+	r.samples[r.next] = d
+	r.next = (r.next + 1) % latencyWindow
+}
+
+func (r *latencyRing) distribution() LatencyDistribution {
+	n := len(r.samples)
+	if n == 0 {
+		return LatencyDistribution{}
+	}
+
+	sorted := make([]time.Duration, n)
+	copy(sorted, r.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	// Nearest-rank percentile
+	percentile := func(p float64) time.Duration {
+		return sorted[int(math.Ceil(p*float64(n)))-1]
+	}
+	return LatencyDistribution{
+		Count: n,
+		P50:   percentile(0.5),
+		P95:   percentile(0.95),
+		P99:   percentile(0.99),
+		Max:   sorted[n-1],
+	}
+}
+
+// latencyTracer collects frame latency from all of a track's readers.
+type latencyTracer struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	process   latencyRing
+	encode    latencyRing
+	packetize latencyRing
+	total     latencyRing
+}
+
+// newLatencyTracer creates a tracer with now, which has to be the capture times' clock.
+func newLatencyTracer(now func() time.Time) *latencyTracer {
+	return &latencyTracer{now: now}
+}
+
+// encoded records a frame's stages up to the encoder. The frame is skipped if its capture time is unknown.
+func (t *latencyTracer) encoded(captured, input, encoded time.Time) {
+	if captured.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.process.add(input.Sub(captured))
+	t.encode.add(encoded.Sub(input))
+}
+
+// packetized records a frame's packetization stage and total latency.
+func (t *latencyTracer) packetized(captured, encoded, packetized time.Time) {
+	if captured.IsZero() {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.packetize.add(packetized.Sub(encoded))
+	t.total.add(packetized.Sub(captured))
+}
+
+func (t *latencyTracer) stats() LatencyStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return LatencyStats{
+		Process:   t.process.distribution(),
+		Encode:    t.encode.distribution(),
+		Packetize: t.packetize.distribution(),
+		Total:     t.total.distribution(),
+	}
+}
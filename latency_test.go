@@ -0,0 +1,72 @@
+package mediadevices
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestLatencyDistribution(t *testing.T) {
+	var r latencyRing
+	if d := r.distribution(); d.Count != 0 {
+		t.Fatalf("expected an empty distribution, but got %+v", d)
+	}
+
+	for i := 100; i > 0; i-- {
+		r.add(time.Duration(i) * time.Millisecond)
+	}
+	expected := LatencyDistribution{
+		Count: 100,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+		Max:   100 * time.Millisecond,
+	}
+	if d := r.distribution(); d != expected {
+		t.Fatalf("expected %+v, but got %+v", expected, d)
+	}
+
+	// Only recent frames are kept
+	for i := 0; i < latencyWindow; i++ {
+		r.add(time.Millisecond)
+	}
+	if d := r.distribution(); d.Count != latencyWindow || d.Max != time.Millisecond {
+		t.Fatalf("expected %d frames of 1ms, but got %+v", latencyWindow, d)
+	}
+}
+
+func TestVideoTrackLatencyStats(t *testing.T) {
+	source := &testVideoSource{
+		img: image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio420),
+	}
+	track := NewVideoTrack(source, NewCodecSelector(WithVideoEncoders(&testPreparedEncoderBuilder{}))).(*VideoTrack)
+	defer track.Close()
+
+	r, err := track.NewRTPReader("vp8", 1, 1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	const frames = 3
+	for i := 0; i < frames; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := track.LatencyStats()
+	for name, d := range map[string]LatencyDistribution{
+		"process":   stats.Process,
+		"encode":    stats.Encode,
+		"packetize": stats.Packetize,
+		"total":     stats.Total,
+	} {
+		if d.Count != frames {
+			t.Errorf("expected %d frames in %s latency, but got %d", frames, name, d.Count)
+		}
+	}
+	if stats.Total.Max < stats.Encode.Max {
+		t.Fatalf("expected the total latency to include the encoding, but got %+v", stats)
+	}
+}
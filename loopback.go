@@ -0,0 +1,279 @@
+package mediadevices
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/mediadevices/pkg/wave/mixer"
+)
+
+const (
+	// loopbackSamplingRate is the tone's sampling rate, which the Player resamples.
+	loopbackSamplingRate = 48000
+	// loopbackBlock is detection resolution, and the tone's chunk duration.
+	loopbackBlock = 10 * time.Millisecond
+	// loopbackNoiseDuration is how long input is measured before the tone is played.
+	loopbackNoiseDuration = 200 * time.Millisecond
+	// loopbackOnsetBlocks is how many consecutive tone blocks detect it, so a click isn't
+	// taken as the tone.
+	loopbackOnsetBlocks = 3
+	// loopbackMargin is how much louder than noise the tone has to be in dB.
+	loopbackMargin = 10
+
+	defaultLoopbackFrequency = 1000
+	defaultLoopbackLevel     = -12
+	defaultLoopbackDuration  = 500 * time.Millisecond
+	defaultLoopbackMaxDelay  = time.Second
+	defaultLoopbackMinLevel  = -60
+)
+
+// LoopbackResult is AudioLoopbackTest's result. Levels are in dBFS, where 0 dBFS is a full scale sine
+// wave, and measured at the tone frequency.
+type LoopbackResult struct {
+	// Detected is whether the tone was captured within the maximum delay.
+	Detected bool
+	// Delay is from when the tone was played to when it was captured, which is the sum of output and input
+	// latencies, with 10ms resolution.
+	Delay time.Duration
+	// Level is the captured tone's level, e.g. to warn about low input volume.
+	Level float64
+	// NoiseLevel is the input level before the tone was played.
+	NoiseLevel float64
+}
+
+type loopbackConfig struct {
+	frequency float64
+	level     float64
+	duration  time.Duration
+	maxDelay  time.Duration
+	minLevel  float64
+}
+
+// LoopbackOption configures AudioLoopbackTest.
+type LoopbackOption func(*loopbackConfig)
+
+// WithToneFrequency sets tone frequency in Hz. The default is 1kHz.
+func WithToneFrequency(hz float64) LoopbackOption {
+	return func(c *loopbackConfig) {
+		c.frequency = hz
+	}
+}
+
+// WithToneLevel sets tone level in dBFS. The default is -12 dBFS.
+func WithToneLevel(dbfs float64) LoopbackOption {
+	return func(c *loopbackConfig) {
+		c.level = dbfs
+	}
+}
+
+// WithToneDuration sets how long the tone is played. The default is 500ms.
+func WithToneDuration(d time.Duration) LoopbackOption {
+	return func(c *loopbackConfig) {
+		c.duration = d
+	}
+}
+
+// WithMaxLoopbackDelay sets the captured tone's maximum delay. The default is 1 second.
+func WithMaxLoopbackDelay(d time.Duration) LoopbackOption {
+	return func(c *loopbackConfig) {
+		c.maxDelay = d
+	}
+}
+
+// WithMinLoopbackLevel sets the captured tone's minimum level in dBFS. The default is -60 dBFS.
+func WithMinLoopbackLevel(dbfs float64) LoopbackOption {
+	return func(c *loopbackConfig) {
+		c.minLevel = dbfs
+	}
+}
+
+// AudioLoopbackTest plays a tone through the output device that fits output best, and verifies that the
+// input device that fits input best captures it, e.g. for a "test my devices" check before joining a call.
+// Devices are selected like NewPlayer and GetUserMedia do. Input is measured for 200ms before
+// the tone to know its noise, and the tone is detected when it's 10dB louder than noise for 30ms. It returns
+// an error only if the devices can't be opened, and the result tells whether the tone was heard.
+//
+// Since delay is measured by the wall clock, input echo cancellation should be disabled, and
+// other Players should be stopped while testing.
+func AudioLoopbackTest(output, input MediaOption, opts ...LoopbackOption) (LoopbackResult, error) {
+	c := loopbackConfig{
+		frequency: defaultLoopbackFrequency,
+		level:     defaultLoopbackLevel,
+		duration:  defaultLoopbackDuration,
+		maxDelay:  defaultLoopbackMaxDelay,
+		minLevel:  defaultLoopbackMinLevel,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	var constraints MediaTrackConstraints
+	if input != nil {
+		input(&constraints)
+	}
+	track, err := selectAudio(constraints, nil)
+	if err != nil {
+		return LoopbackResult{}, fmt.Errorf("failed to open the input device: %s", err)
+	}
+	defer track.Close()
+
+	mic := track.(*AudioTrack).NewReader(false)
+	mic = audio.NewConverter(false, wave.TypeFloat32Interleaved)(audio.NewChannelMixer(1, &mixer.MonoMixer{})(mic))
+	d := newToneDetector(c.frequency)
+
+	// Noise is measured before the tone is played
+	result := LoopbackResult{NoiseLevel: math.Inf(-1)}
+	for d.elapsed < loopbackNoiseDuration {
+		chunk, _, err := mic.Read()
+		if err != nil {
+			return LoopbackResult{}, fmt.Errorf("failed to read the input device: %s", err)
+		}
+		d.write(chunk.(*wave.Float32Interleaved), time.Now(), func(_ time.Time, level float64) {
+			result.NoiseLevel = math.Max(result.NoiseLevel, level)
+		})
+	}
+	threshold := math.Max(c.minLevel, result.NoiseLevel+loopbackMargin)
+
+	played := make(chan time.Time, 1)
+	player, err := NewPlayer(newTone(c, played), output)
+	if err != nil {
+		return LoopbackResult{}, fmt.Errorf("failed to open the output device: %s", err)
+	}
+	defer player.Close()
+
+	var (
+		start   = time.Now()
+		started bool
+		onset   time.Time
+		// consecutive is how many tone blocks there are from onset, and power is their total power.
+		consecutive int
+		power       float64
+		// left is how many tone blocks remain to measure, without the last one, which may be cut.
+		left = int(c.duration/loopbackBlock) - 1
+	)
+	for {
+		chunk, _, err := mic.Read()
+		if err != nil {
+			return LoopbackResult{}, fmt.Errorf("failed to read the input device: %s", err)
+		}
+		now := time.Now()
+		if !started {
+			select {
+			case start = <-played:
+				started = true
+			default:
+			}
+		}
+
+		d.write(chunk.(*wave.Float32Interleaved), now, func(t time.Time, level float64) {
+			switch {
+			case result.Detected:
+				if left > 0 {
+					consecutive++
+					power += math.Pow(10, level/10)
+					left--
+				}
+			case !started || t.Before(start):
+			case level < threshold:
+				consecutive, power = 0, 0
+			default:
+				if consecutive == 0 {
+					onset = t
+				}
+				consecutive++
+				power += math.Pow(10, level/10)
+				if consecutive == loopbackOnsetBlocks {
+					result.Detected = true
+					result.Delay = onset.Sub(start)
+					left -= consecutive
+				}
+			}
+		})
+
+		switch {
+		case result.Detected && left <= 0:
+			result.Level = 10 * math.Log10(power/float64(consecutive))
+			return result, nil
+		case !result.Detected && now.Sub(start) > c.maxDelay+loopbackBlock*loopbackOnsetBlocks:
+			// The tone wasn't heard in time
+			return result, nil
+		}
+	}
+}
+
+// newTone returns a tone reader, which sends its first chunk's time to played.
+func newTone(c loopbackConfig, played chan<- time.Time) audio.Reader {
+	amplitude := math.Pow(10, c.level/20)
+	blockLen := int(loopbackSamplingRate * loopbackBlock / time.Second)
+	total := int(int64(loopbackSamplingRate) * int64(c.duration) / int64(time.Second))
+	var n int
+	return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		if n >= total {
+			return nil, func() {}, io.EOF
+		}
+		if n == 0 {
+			played <- time.Now()
+		}
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: blockLen, Channels: 1, SamplingRate: loopbackSamplingRate})
+		for i := range chunk.Data {
+			phase := 2 * math.Pi * c.frequency * float64(n+i) / loopbackSamplingRate
+			chunk.Data[i] = float32(amplitude * math.Sin(phase))
+		}
+		n += blockLen
+		return chunk, func() {}, nil
+	})
+}
+
+// toneDetector measures a frequency's level in input blocks with the Goertzel algorithm.
+type toneDetector struct {
+	frequency float64
+	block     []float32
+	// elapsed is the input duration so far.
+	elapsed time.Duration
+}
+
+func newToneDetector(frequency float64) *toneDetector {
+	return &toneDetector{frequency: frequency}
+}
+
+// write appends the samples of chunk, which was read at now, and calls onBlock with each complete block's
+// time and level. A sample's time is estimated from now, since the chunk ends when it's read.
+func (d *toneDetector) write(chunk *wave.Float32Interleaved, now time.Time, onBlock func(time.Time, float64)) {
+	info := chunk.ChunkInfo()
+	rate := info.SamplingRate
+	if rate <= 0 {
+		rate = loopbackSamplingRate
+	}
+	blockLen := int(int64(rate) * int64(loopbackBlock) / int64(time.Second))
+	for i, v := range chunk.Data {
+		d.block = append(d.block, v)
+		if len(d.block) < blockLen {
+			continue
+		}
+		// The block starts blockLen-1 samples before sample i
+		t := now.Add(-time.Duration(len(chunk.Data)-i+blockLen-1) * time.Second / time.Duration(rate))
+		onBlock(t, goertzelLevel(d.block, d.frequency, rate))
+		d.block = d.block[:0]
+	}
+	d.elapsed += time.Duration(len(chunk.Data)) * time.Second / time.Duration(rate)
+}
+
+// goertzelLevel returns the frequency's level in block in dBFS, where 0 dBFS is a full scale sine wave.
+func goertzelLevel(block []float32, frequency float64, rate int) float64 {
+	k := 2 * math.Cos(2*math.Pi*frequency/float64(rate))
+	var s1, s2 float64
+	for _, v := range block {
+		s1, s2 = float64(v)+k*s1-s2, s1
+	}
+	power := s1*s1 + s2*s2 - k*s1*s2
+	// A sine wave of amplitude A has power (A*N/2)^2
+	amplitude := 2 * math.Sqrt(math.Max(power, 0)) / float64(len(block))
+	if amplitude == 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(amplitude)
+}
@@ -0,0 +1,124 @@
+package mediadevices
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// loopbackMock captures what it plays after a delay, like a speaker next to a microphone.
+type loopbackMock struct {
+	played chan audio.Reader
+	// delay is how many samples there are between speaker and microphone.
+	delay int
+	gain  float32
+}
+
+func (m *loopbackMock) Open() error  { return nil }
+func (m *loopbackMock) Close() error { return nil }
+func (m *loopbackMock) Properties() []prop.Media {
+	return []prop.Media{{
+		Audio: prop.Audio{
+			ChannelCount:  1,
+			SampleRate:    48000,
+			SampleSize:    4,
+			IsFloat:       true,
+			IsInterleaved: true,
+		},
+	}}
+}
+
+type loopbackSpeakerMock struct{ *loopbackMock }
+
+func (s loopbackSpeakerMock) AudioPlay(p prop.Media, r audio.Reader) error {
+	s.played <- r
+	return nil
+}
+
+type loopbackMicMock struct{ *loopbackMock }
+
+func (m loopbackMicMock) AudioRecord(p prop.Media) (audio.Reader, error) {
+	const chunkLen = 480
+	queue := make([]float32, m.delay)
+	var speaker audio.Reader
+	return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		time.Sleep(10 * time.Millisecond)
+		if speaker == nil {
+			select {
+			case speaker = <-m.played:
+			default:
+			}
+		}
+
+		in := make([]float32, chunkLen)
+		if speaker != nil {
+			if chunk, _, err := speaker.Read(); err == nil {
+				for i, v := range chunk.(*wave.Float32Interleaved).Data {
+					in[i] = v * m.gain
+				}
+			}
+		}
+		queue = append(queue, in...)
+
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 48000})
+		copy(chunk.Data, queue)
+		queue = queue[chunkLen:]
+		return chunk, func() {}, nil
+	}), nil
+}
+
+func registerLoopbackMock(t *testing.T, m *loopbackMock) func() {
+	speaker, mic := loopbackSpeakerMock{m}, loopbackMicMock{m}
+	if err := RegisterDriverAdapter(speaker, driver.Info{Label: "loopback-speaker", DeviceType: driver.Speaker}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterDriverAdapter(mic, driver.Info{Label: "loopback-mic", DeviceType: driver.Microphone, Priority: driver.PriorityHigh}); err != nil {
+		t.Fatal(err)
+	}
+	return func() {
+		driver.GetManager().Unregister(speaker)
+		driver.GetManager().Unregister(mic)
+	}
+}
+
+func TestAudioLoopbackTest(t *testing.T) {
+	m := &loopbackMock{played: make(chan audio.Reader, 1), delay: 2400, gain: 0.5}
+	defer registerLoopbackMock(t, m)()
+
+	result, err := AudioLoopbackTest(nil, nil, WithToneDuration(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Detected {
+		t.Fatalf("expected the tone to be detected, but got %+v", result)
+	}
+	// The tone is captured from 40ms after it's played
+	if result.Delay < 20*time.Millisecond || result.Delay > 150*time.Millisecond {
+		t.Fatalf("expected the delay of about 40ms, but got %v", result.Delay)
+	}
+	// -12 dBFS with -6 dB gain
+	if math.Abs(result.Level-(-18)) > 1 {
+		t.Fatalf("expected the level of -18 dBFS, but got %f", result.Level)
+	}
+	if result.NoiseLevel > -100 {
+		t.Fatalf("expected the silent noise, but got %f dBFS", result.NoiseLevel)
+	}
+}
+
+func TestAudioLoopbackTestSilent(t *testing.T) {
+	m := &loopbackMock{played: make(chan audio.Reader, 1), delay: 2400, gain: 0}
+	defer registerLoopbackMock(t, m)()
+
+	result, err := AudioLoopbackTest(nil, nil, WithToneDuration(100*time.Millisecond), WithMaxLoopbackDelay(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Detected {
+		t.Fatalf("expected the tone not to be detected, but got %+v", result)
+	}
+}
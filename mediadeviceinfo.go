@@ -10,6 +10,7 @@ const (
 	VideoInput MediaDeviceType = iota + 1
 	AudioInput
 	AudioOutput
+	VideoOutput
 )
 
 // MediaDeviceInfo represents https://w3c.github.io/mediacapture-main/#dom-mediadeviceinfo
@@ -2,10 +2,12 @@ package mediadevices
 
 import (
 	"fmt"
+	"image"
 	"math"
 	"strings"
 
 	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
 	"github.com/pion/mediadevices/pkg/prop"
 )
 
@@ -26,7 +28,7 @@ func GetDisplayMedia(constraints MediaStreamConstraints) (MediaStream, error) {
 	var videoConstraints MediaTrackConstraints
 	if constraints.Video != nil {
 		constraints.Video(&videoConstraints)
-		tracker, err := selectScreen(videoConstraints, constraints.Codec)
+		tracker, err := selectScreen(videoConstraints, constraints.Codec, constraints.PickDisplay)
 		if err != nil {
 			cleanTrackers()
 			return nil, err
@@ -161,39 +163,85 @@ func selectBestDriver(filter driver.FilterFn, constraints MediaTrackConstraints)
 }
 
 func selectAudio(constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
-	typeFilter := driver.FilterAudioRecorder()
-
-	d, c, err := selectBestDriver(typeFilter, constraints)
-	if err != nil {
-		return nil, err
-	}
-
-	return newTrackFromDriver(d, c, selector)
+	return selectInput(AudioInput, constraints, selector)
 }
+
 func selectVideo(constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
-	typeFilter := driver.FilterVideoRecorder()
-	notScreenFilter := driver.FilterNot(driver.FilterDeviceType(driver.Screen))
-	filter := driver.FilterAnd(typeFilter, notScreenFilter)
+	return selectInput(VideoInput, constraints, selector)
+}
 
+// selectInput opens the kind driver that fits the constraints best. If none fits, it returns a
+// *PermissionError if the OS denied any of them, since denied devices can't be opened to query their properties.
+func selectInput(kind MediaDeviceType, constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
+	filter := kindFilter(kind)
 	d, c, err := selectBestDriver(filter, constraints)
 	if err != nil {
+		if denied := deniedError(kind, driver.GetManager().Query(filter)...); denied != nil {
+			return nil, denied
+		}
 		return nil, err
 	}
 
 	return newTrackFromDriver(d, c, selector)
 }
 
-func selectScreen(constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
+func selectScreen(constraints MediaTrackConstraints, selector *CodecSelector, pick DisplayPicker) (Track, error) {
 	typeFilter := driver.FilterVideoRecorder()
 	screenFilter := driver.FilterDeviceType(driver.Screen)
 	filter := driver.FilterAnd(typeFilter, screenFilter)
 
+	if pick != nil {
+		var err error
+		filter, constraints, err = pickDisplay(filter, constraints, pick)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	d, c, err := selectBestDriver(filter, constraints)
 	if err != nil {
 		return nil, err
 	}
 
-	return newTrackFromDriver(d, c, selector)
+	area := constraints.CaptureArea
+	if !area.Empty() {
+		bounds := image.Rect(0, 0, c.selectedMedia.Width, c.selectedMedia.Height)
+		if !area.In(bounds) {
+			return nil, fmt.Errorf("failed to capture the area: %v is out of the screen %v", area, bounds)
+		}
+	}
+	c.selectedMedia.FrameRate = capFrameRate(c.selectedMedia.FrameRate, constraints.FrameRate)
+
+	track, err := newTrackFromDriver(d, c, selector)
+	if err != nil {
+		return nil, err
+	}
+	if !area.Empty() {
+		track.(*VideoTrack).Transform(video.Crop(area))
+	}
+	return track, nil
+}
+
+// capFrameRate limits a screen's frame rate to c's max. Since screens can be captured at any
+// frame rate, the max, or the ideal value if given, is used instead of the driver default.
+func capFrameRate(frameRate float32, c prop.FloatConstraint) float32 {
+	var r prop.FloatRanged
+	switch c := c.(type) {
+	case prop.FloatRanged:
+		r = c
+	case *prop.FloatRanged:
+		r = *c
+	default:
+		return frameRate
+	}
+
+	if frameRate == 0 {
+		frameRate = r.Ideal
+	}
+	if r.Max > 0 && (frameRate == 0 || frameRate > r.Max) {
+		frameRate = r.Max
+	}
+	return frameRate
 }
 
 func EnumerateDevices() []MediaDeviceInfo {
@@ -201,22 +249,47 @@ func EnumerateDevices() []MediaDeviceInfo {
 		driver.FilterFn(func(driver.Driver) bool { return true }))
 	info := make([]MediaDeviceInfo, 0, len(drivers))
 	for _, d := range drivers {
-		var kind MediaDeviceType
-		switch {
-		case driver.FilterVideoRecorder()(d):
-			kind = VideoInput
-		case driver.FilterAudioRecorder()(d):
-			kind = AudioInput
-		default:
-			continue
+		if i, ok := deviceInfo(d); ok {
+			info = append(info, i)
 		}
-		driverInfo := d.Info()
-		info = append(info, MediaDeviceInfo{
-			DeviceID:   d.ID(),
-			Kind:       kind,
-			Label:      driverInfo.Label,
-			DeviceType: driverInfo.DeviceType,
-		})
 	}
 	return info
 }
+
+// ResolveDeviceID returns the current device that had id, e.g. a DeviceID stored as a user preference.
+// IDs are generated from stable device attributes like serial numbers, and the device is found even if
+// some of them change, e.g. when it's plugged into another port of a USB hub.
+func ResolveDeviceID(id string) (MediaDeviceInfo, error) {
+	d, err := driver.GetManager().Resolve(id)
+	if err != nil {
+		return MediaDeviceInfo{}, err
+	}
+	info, ok := deviceInfo(d)
+	if !ok {
+		return MediaDeviceInfo{}, errNotFound
+	}
+	return info, nil
+}
+
+func deviceInfo(d driver.Driver) (MediaDeviceInfo, bool) {
+	var kind MediaDeviceType
+	switch {
+	case driver.FilterVideoRecorder()(d):
+		kind = VideoInput
+	case driver.FilterAudioRecorder()(d):
+		kind = AudioInput
+	case driver.FilterAudioPlayer()(d):
+		kind = AudioOutput
+	case driver.FilterVideoPlayer()(d):
+		kind = VideoOutput
+	default:
+		return MediaDeviceInfo{}, false
+	}
+	driverInfo := d.Info()
+	return MediaDeviceInfo{
+		DeviceID:   d.ID(),
+		Kind:       kind,
+		Label:      driverInfo.Label,
+		DeviceType: driverInfo.DeviceType,
+	}, true
+}
@@ -131,3 +131,93 @@ func TestSelectBestDriverConstraintsResultIsSetProperly(t *testing.T) {
 		t.Fatalf("failed to return best constraints\nexpected:\n%v\n\ngot:\n%v", expectedProp, bestConstraints.selectedMedia)
 	}
 }
+
+func TestResolveDeviceID(t *testing.T) {
+	info := driver.Info{
+		Label:      "usb-speaker",
+		DeviceType: driver.Speaker,
+		Hardware:   driver.Hardware{Serial: "0001", VendorID: "0d8c", ProductID: "0014", PortPath: "1-2"},
+	}
+	register := func(info driver.Info) (*playerAdapterMock, string) {
+		a := &playerAdapterMock{}
+		if err := RegisterDriverAdapter(a, info); err != nil {
+			t.Fatal(err)
+		}
+		for _, device := range EnumerateDevices() {
+			if device.Label == info.Label {
+				return a, device.DeviceID
+			}
+		}
+		t.Fatal("expected the device to be enumerated")
+		return nil, ""
+	}
+
+	a, id := register(info)
+	driver.GetManager().Unregister(a)
+
+	// Plugged into another port of the hub
+	info.Hardware.PortPath = "1-3.2"
+	a, current := register(info)
+	defer driver.GetManager().Unregister(a)
+	if current != id {
+		t.Fatalf("expected the ID %s to be kept, but got %s", id, current)
+	}
+
+	device, err := ResolveDeviceID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.DeviceID != id || device.Kind != AudioOutput {
+		t.Fatalf("expected the speaker of %s, but got %v", id, device)
+	}
+
+	if _, err := ResolveDeviceID("unknown"); err == nil {
+		t.Fatal("expected an error for the unknown ID")
+	}
+}
+
+func TestCapFrameRate(t *testing.T) {
+	testCases := map[string]struct {
+		frameRate  float32
+		constraint prop.FloatConstraint
+		expected   float32
+	}{
+		"NoConstraint": {
+			frameRate: 0,
+			expected:  0,
+		},
+		"Ideal": {
+			frameRate:  30,
+			constraint: prop.Float(15),
+			expected:   30,
+		},
+		"MaxWithoutFrameRate": {
+			frameRate:  0,
+			constraint: prop.FloatRanged{Max: 5},
+			expected:   5,
+		},
+		"MaxAboveFrameRate": {
+			frameRate:  10,
+			constraint: &prop.FloatRanged{Max: 15},
+			expected:   10,
+		},
+		"MaxBelowFrameRate": {
+			frameRate:  30,
+			constraint: prop.FloatRanged{Max: 15},
+			expected:   15,
+		},
+		"RangedIdeal": {
+			frameRate:  0,
+			constraint: prop.FloatRanged{Max: 15, Ideal: 10},
+			expected:   10,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if actual := capFrameRate(c.frameRate, c.constraint); actual != c.expected {
+				t.Fatalf("expected %f, but got %f", c.expected, actual)
+			}
+		})
+	}
+}
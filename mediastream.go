@@ -1,6 +1,8 @@
 package mediadevices
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/pion/webrtc/v3"
@@ -18,6 +20,8 @@ type MediaStream interface {
 	AddTrack(t Track)
 	// RemoveTrack implements https://w3c.github.io/mediacapture-main/#dom-mediastream-removetrack
 	RemoveTrack(t Track)
+	// Close closes all of the stream's tracks, and removes them from it.
+	Close() error
 }
 
 type mediaStream struct {
@@ -86,3 +90,21 @@ func (m *mediaStream) RemoveTrack(t Track) {
 
 	delete(m.tracks, t)
 }
+
+func (m *mediaStream) Close() error {
+	m.l.Lock()
+	tracks := m.tracks
+	m.tracks = make(map[Track]struct{})
+	m.l.Unlock()
+
+	var errs []string
+	for track := range tracks {
+		if err := track.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", track.ID(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close the tracks: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
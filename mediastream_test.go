@@ -57,6 +57,10 @@ func (track *mockMediaStreamTrack) NewEncodedIOReader(codecName string) (io.Read
 	return nil, nil
 }
 
+func (track *mockMediaStreamTrack) Stats() TrackStats {
+	return TrackStats{}
+}
+
 func TestMediaStreamFilters(t *testing.T) {
 	audioTracks := []Track{
 		&mockMediaStreamTrack{AudioInput},
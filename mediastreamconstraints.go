@@ -1,6 +1,9 @@
 package mediadevices
 
 import (
+	"image"
+
+	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/prop"
 )
 
@@ -8,11 +11,23 @@ type MediaStreamConstraints struct {
 	Audio MediaOption
 	Video MediaOption
 	Codec *CodecSelector
+	// PickDisplay lets the user choose which screen GetDisplayMedia shares. If it's nil, the screen that fits
+	// the constraints best is shared.
+	PickDisplay DisplayPicker
 }
 
 // MediaTrackConstraints represents https://w3c.github.io/mediacapture-main/#dom-mediatrackconstraints
 type MediaTrackConstraints struct {
 	prop.MediaConstraints
+	// DriverOptions are passed to the driver selected for the track, e.g. camera.V4L2Options, to tune it
+	// when defaults don't fit, like at high resolutions or for low latency.
+	DriverOptions []driver.Option
+	// Controls are low level camera properties, e.g. torch and exposure time, which are
+	// applied when the track is created. VideoTrack.ApplyControls can change them later.
+	Controls driver.Controls
+	// CaptureArea is the screen region GetDisplayMedia captures, e.g. part of a large monitor.
+	// The whole screen is captured if it's empty.
+	CaptureArea   image.Rectangle
 	selectedMedia prop.Media
 }
 
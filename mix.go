@@ -0,0 +1,71 @@
+package mediadevices
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+var (
+	errNoTracksToMix = errors.New("no tracks to mix")
+	errNotAudioTrack = errors.New("not an audio track")
+)
+
+// mixedAudioSource is MixAudioTracks' track source. Closing it ends the mix, but not the mixed
+// tracks.
+type mixedAudioSource struct {
+	audio.Reader
+	id        string
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *mixedAudioSource) ID() string {
+	return s.id
+}
+
+func (s *mixedAudioSource) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+// MixAudioTracks creates an audio track that mixes tracks' audio, e.g. a microphone and speaker
+// loopback, so they're sent to a peer connection or recorded by NewEncodedReader as a single track.
+// Audio is mixed before selector's codecs encode it. The first track paces the mix and decides
+// its format, see audio.Mix. Mixed tracks are kept open when the returned track is closed.
+func MixAudioTracks(selector *CodecSelector, tracks ...Track) (Track, error) {
+	if len(tracks) == 0 {
+		return nil, errNoTracksToMix
+	}
+
+	readers := make([]audio.Reader, len(tracks))
+	ids := make([]string, len(tracks))
+	for i, track := range tracks {
+		audioTrack, ok := track.(*AudioTrack)
+		if !ok {
+			return nil, errNotAudioTrack
+		}
+		readers[i] = audioTrack.NewReader(false)
+		ids[i] = track.ID()
+	}
+
+	source := &mixedAudioSource{
+		id:     strings.Join(ids, "+"),
+		closed: make(chan struct{}),
+	}
+	first := readers[0]
+	readers[0] = audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		select {
+		case <-source.closed:
+			return nil, func() {}, io.EOF
+		default:
+		}
+		return first.Read()
+	})
+	source.Reader = audio.Mix(readers...)
+	return NewAudioTrack(source, selector), nil
+}
@@ -0,0 +1,81 @@
+package mediadevices
+
+import (
+	"image"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+type testAudioSource struct {
+	id    string
+	value float32
+}
+
+func (s *testAudioSource) Read() (wave.Audio, func(), error) {
+	time.Sleep(time.Millisecond)
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: 80, Channels: 1, SamplingRate: 8000})
+	for i := range chunk.Data {
+		chunk.Data[i] = s.value
+	}
+	return chunk, func() {}, nil
+}
+
+func (s *testAudioSource) ID() string   { return s.id }
+func (s *testAudioSource) Close() error { return nil }
+
+func TestMixAudioTracks(t *testing.T) {
+	mic := NewAudioTrack(&testAudioSource{id: "mic", value: 0.25}, nil)
+	loopback := NewAudioTrack(&testAudioSource{id: "loopback", value: 0.5}, nil)
+	defer mic.Close()
+	defer loopback.Close()
+
+	track, err := MixAudioTracks(nil, mic, loopback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.ID() != "mic+loopback" {
+		t.Fatalf("expected the ID to be mic+loopback, but got %s", track.ID())
+	}
+
+	r := track.(*AudioTrack).NewReader(false)
+	for i := 0; ; i++ {
+		if i == 100 {
+			t.Fatal("expected the tracks to be mixed")
+		}
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := float64(chunk.At(0, 0).(wave.Float32Sample)); math.Abs(v-0.75) < 0.001 {
+			break
+		}
+	}
+
+	ended := make(chan error, 1)
+	track.OnEnded(func(err error) { ended <- err })
+	if err := track.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, _, err := r.Read(); err != nil {
+			break
+		}
+	}
+	select {
+	case <-ended:
+	case <-time.After(time.Second):
+		t.Fatal("expected the mixed track to end when it's closed")
+	}
+
+	if _, _, err := mic.(*AudioTrack).NewReader(false).Read(); err != nil {
+		t.Fatalf("expected the mixed tracks to be kept, but got %v", err)
+	}
+
+	video := NewVideoTrack(&testVideoSource{img: image.NewRGBA(image.Rect(0, 0, 4, 4))}, nil)
+	if _, err := MixAudioTracks(nil, mic, video); err != errNotAudioTrack {
+		t.Fatalf("expected %v, but got %v", errNotAudioTrack, err)
+	}
+}
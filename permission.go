@@ -0,0 +1,80 @@
+package mediadevices
+
+import (
+	"fmt"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// PermissionError is returned when the OS doesn't allow the application to use devices, e.g. the user denied
+// the prompt or turned off access in privacy settings, so applications can guide the user to
+// settings instead of showing a driver failure.
+type PermissionError struct {
+	Kind MediaDeviceType
+	// Permission is driver.PermissionDenied or driver.PermissionRestricted. The user can't change restricted
+	// permission.
+	Permission driver.Permission
+}
+
+func (e *PermissionError) Error() string {
+	kind := "audio input"
+	if e.Kind == VideoInput {
+		kind = "video input"
+	}
+	return fmt.Sprintf("the permission to use the %s devices is %s", kind, e.Permission)
+}
+
+// RequestPermission asks the OS for permission to use devices of kind, VideoInput or AudioInput, and waits
+// until the user answers, e.g. a macOS prompt. It's answered at once if the user already answered. It
+// returns a *PermissionError if permission isn't granted, or nil if it's granted or the OS doesn't have
+// permissions. Desktop applications aren't prompted on Windows, so it only checks privacy settings.
+//
+// It's called by the application's main thread before GetUserMedia, e.g. in an onboarding flow, since
+// GetUserMedia would block while the prompt is shown.
+func RequestPermission(kind MediaDeviceType) error {
+	drivers := driver.GetManager().Query(kindFilter(kind))
+	if len(drivers) == 0 {
+		return errNotFound
+	}
+	for _, d := range drivers {
+		p, err := d.RequestPermission()
+		if err != nil {
+			return fmt.Errorf("failed to request the permission of %s: %s", d.Info().Label, err)
+		}
+		if err := permissionError(kind, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kindFilter returns a filter of drivers GetUserMedia selects for kind.
+func kindFilter(kind MediaDeviceType) driver.FilterFn {
+	if kind == VideoInput {
+		return driver.FilterAnd(driver.FilterVideoRecorder(), driver.FilterNot(driver.FilterDeviceType(driver.Screen)))
+	}
+	return driver.FilterAudioRecorder()
+}
+
+// permissionError returns a *PermissionError if p doesn't allow kind devices to be opened.
+func permissionError(kind MediaDeviceType, p driver.Permission) error {
+	if p == driver.PermissionDenied || p == driver.PermissionRestricted {
+		return &PermissionError{Kind: kind, Permission: p}
+	}
+	return nil
+}
+
+// deniedError returns a *PermissionError if any driver is denied, which is why finding
+// or opening them failed.
+func deniedError(kind MediaDeviceType, drivers ...driver.Driver) error {
+	for _, d := range drivers {
+		p, err := d.Probe()
+		if err != nil {
+			continue
+		}
+		if err := permissionError(kind, p.Permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package mediadevices
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// permissionCameraMock can't be opened until its permission is granted, like macOS cameras.
+type permissionCameraMock struct {
+	screenAdapterMock
+	permission driver.Permission
+	// answer is the permission after the prompt.
+	answer    driver.Permission
+	requested int
+}
+
+func (a *permissionCameraMock) Open() error {
+	if a.permission != driver.PermissionGranted {
+		return errors.New("failed to init device")
+	}
+	return a.screenAdapterMock.Open()
+}
+
+func (a *permissionCameraMock) Probe() (driver.Probe, error) {
+	return driver.Probe{Permission: a.permission}, nil
+}
+
+func (a *permissionCameraMock) RequestPermission() (driver.Permission, error) {
+	a.requested++
+	if a.permission == driver.PermissionPrompt {
+		a.permission = a.answer
+	}
+	return a.permission, nil
+}
+
+func TestRequestPermission(t *testing.T) {
+	a := &permissionCameraMock{permission: driver.PermissionPrompt, answer: driver.PermissionGranted}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "prompt", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	if err := RequestPermission(VideoInput); err != nil {
+		t.Fatal(err)
+	}
+	if a.requested != 1 || a.permission != driver.PermissionGranted {
+		t.Fatalf("expected the permission to be requested, but got %d requests and %s", a.requested, a.permission)
+	}
+
+	denied := &permissionCameraMock{permission: driver.PermissionPrompt, answer: driver.PermissionDenied}
+	if err := RegisterDriverAdapter(denied, driver.Info{Label: "denied", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(denied)
+
+	err := RequestPermission(VideoInput)
+	if e, ok := err.(*PermissionError); !ok || e.Kind != VideoInput || e.Permission != driver.PermissionDenied {
+		t.Fatalf("expected the permission to be denied, but got %v", err)
+	}
+}
+
+func TestGetUserMediaPermissionError(t *testing.T) {
+	a := &permissionCameraMock{permission: driver.PermissionRestricted}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "restricted", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	// Only the restricted camera could have the size
+	_, err := GetUserMedia(MediaStreamConstraints{
+		Video: func(c *MediaTrackConstraints) {
+			c.Width = prop.IntExact(12345)
+		},
+	})
+	if e, ok := err.(*PermissionError); !ok || e.Permission != driver.PermissionRestricted {
+		t.Fatalf("expected the permission to be restricted, but got %v", err)
+	}
+
+	d := driver.GetManager().Query(func(d driver.Driver) bool { return d.Info().Label == "restricted" })[0]
+	if _, err := newTrackFromDriver(d, MediaTrackConstraints{selectedMedia: photoMedia(64, 32)}, nil); err == nil {
+		t.Fatal("expected the track to fail")
+	} else if _, ok := err.(*PermissionError); !ok {
+		t.Fatalf("expected a *PermissionError, but got %v", err)
+	}
+}
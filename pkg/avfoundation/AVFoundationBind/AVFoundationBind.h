@@ -36,12 +36,20 @@ static STATUS STATUS_UNSUPPORTED_FRAME_FORMAT = (STATUS) "Unsupported frame form
 static STATUS STATUS_UNSUPPORTED_MEDIA_TYPE   = (STATUS) "Unsupported media type";
 static STATUS STATUS_FAILED_TO_ACQUIRE_LOCK   = (STATUS) "Failed to acquire a lock";
 static STATUS STATUS_UNSUPPORTED_FORMAT       = (STATUS) "Unsupported device format";
+static STATUS STATUS_DEVICE_NOT_FOUND         = (STATUS) "Device not found";
 
 typedef enum AVBindMediaType {
     AVBindMediaTypeVideo,
     AVBindMediaTypeAudio,
 } AVBindMediaType;
 
+typedef enum AVBindAuthorizationStatus {
+    AVBindAuthorizationStatusNotDetermined,
+    AVBindAuthorizationStatusRestricted,
+    AVBindAuthorizationStatusDenied,
+    AVBindAuthorizationStatusAuthorized,
+} AVBindAuthorizationStatus;
+
 typedef enum AVBindFrameFormat {
     AVBindFrameFormatI420,
     AVBindFrameFormatNV21,
@@ -72,6 +80,15 @@ typedef struct {
 // Everytime this function gets called, the array will be overwritten and the memory will be reused.
 STATUS AVBindDevices(AVBindMediaType, PAVBindDevice*, int*);
 
+// AVBindAuthorization returns the application's permission to capture the media type, which doesn't
+// prompt the user.
+STATUS AVBindAuthorization(AVBindMediaType, AVBindAuthorizationStatus*);
+// AVBindRequestAuthorization prompts the user for permission to capture the media type if they haven't been
+// asked yet, and waits for the answer. It sets 1 if permission is granted, or 0 otherwise.
+STATUS AVBindRequestAuthorization(AVBindMediaType, int*);
+// AVBindDeviceInUse returns 1 if another application uses the device exclusively, or 0 otherwise.
+STATUS AVBindDeviceInUse(AVBindDevice, int*);
+
 STATUS AVBindSessionInit(AVBindDevice, PAVBindSession*);
 STATUS AVBindSessionFree(PAVBindSession*);
 STATUS AVBindSessionOpen(PAVBindSession, AVBindMediaProperty, AVBindDataCallback, void*);
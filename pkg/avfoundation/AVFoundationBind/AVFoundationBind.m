@@ -212,6 +212,67 @@ cleanup:
     return retStatus;
 }
 
+STATUS AVBindAuthorization(AVBindMediaType mediaType, AVBindAuthorizationStatus *pStatus) {
+    STATUS retStatus = STATUS_OK;
+    CHK(mediaType == AVBindMediaTypeVideo || mediaType == AVBindMediaTypeAudio, STATUS_UNSUPPORTED_MEDIA_TYPE);
+    CHK(pStatus != NULL, STATUS_NULL_ARG);
+
+    AVMediaType _mediaType = mediaType == AVBindMediaTypeVideo ? AVMediaTypeVideo : AVMediaTypeAudio;
+    switch ([AVCaptureDevice authorizationStatusForMediaType: _mediaType]) {
+    case AVAuthorizationStatusAuthorized:
+        *pStatus = AVBindAuthorizationStatusAuthorized;
+        break;
+    case AVAuthorizationStatusDenied:
+        *pStatus = AVBindAuthorizationStatusDenied;
+        break;
+    case AVAuthorizationStatusRestricted:
+        *pStatus = AVBindAuthorizationStatusRestricted;
+        break;
+    default:
+        *pStatus = AVBindAuthorizationStatusNotDetermined;
+        break;
+    }
+
+cleanup:
+    return retStatus;
+}
+
+STATUS AVBindRequestAuthorization(AVBindMediaType mediaType, int *pGranted) {
+    STATUS retStatus = STATUS_OK;
+    CHK(mediaType == AVBindMediaTypeVideo || mediaType == AVBindMediaTypeAudio, STATUS_UNSUPPORTED_MEDIA_TYPE);
+    CHK(pGranted != NULL, STATUS_NULL_ARG);
+
+    AVMediaType _mediaType = mediaType == AVBindMediaTypeVideo ? AVMediaTypeVideo : AVMediaTypeAudio;
+    // The handler is called on an arbitrary queue, so the calling thread just waits for it.
+    __block BOOL granted = NO;
+    dispatch_semaphore_t semaphore = dispatch_semaphore_create(0);
+    [AVCaptureDevice requestAccessForMediaType: _mediaType completionHandler: ^(BOOL answer) {
+        granted = answer;
+        dispatch_semaphore_signal(semaphore);
+    }];
+    dispatch_semaphore_wait(semaphore, DISPATCH_TIME_FOREVER);
+    dispatch_release(semaphore);
+    *pGranted = granted ? 1 : 0;
+
+cleanup:
+    return retStatus;
+}
+
+STATUS AVBindDeviceInUse(AVBindDevice device, int *pInUse) {
+    STATUS retStatus = STATUS_OK;
+    NSAutoreleasePool *refPool = [[NSAutoreleasePool alloc] init];
+    CHK(pInUse != NULL, STATUS_NULL_ARG);
+
+    NSString *refUID = [NSString stringWithUTF8String: device.uid];
+    AVCaptureDevice *refDevice = [AVCaptureDevice deviceWithUniqueID: refUID];
+    CHK(refDevice != nil, STATUS_DEVICE_NOT_FOUND);
+    *pInUse = refDevice.inUseByAnotherApplication ? 1 : 0;
+
+cleanup:
+    [refPool drain];
+    return retStatus;
+}
+
 struct AVBindSession {
     AVBindDevice device;
     AVCaptureSession *refCaptureSession;
@@ -26,6 +26,28 @@ const (
 	Audio = MediaType(C.AVBindMediaTypeAudio)
 )
 
+// AuthorizationStatus is the application's permission to capture a media type, which the user grants
+// in macOS privacy settings.
+type AuthorizationStatus C.AVBindAuthorizationStatus
+
+const (
+	// AuthorizationNotDetermined is that the user hasn't been asked yet. They're asked when a session is opened.
+	AuthorizationNotDetermined = AuthorizationStatus(C.AVBindAuthorizationStatusNotDetermined)
+	// AuthorizationRestricted is that system policy, e.g. parental controls, doesn't allow capture.
+	AuthorizationRestricted = AuthorizationStatus(C.AVBindAuthorizationStatusRestricted)
+	AuthorizationDenied     = AuthorizationStatus(C.AVBindAuthorizationStatusDenied)
+	AuthorizationAuthorized = AuthorizationStatus(C.AVBindAuthorizationStatusAuthorized)
+)
+
+// Authorization returns the application's permission to capture the media type without prompting the user
+func Authorization(mediaType MediaType) (AuthorizationStatus, error) {
+	var status C.AVBindAuthorizationStatus
+	if errStatus := C.AVBindAuthorization(C.AVBindMediaType(mediaType), &status); errStatus != nil {
+		return AuthorizationNotDetermined, fmt.Errorf("%s", C.GoString(errStatus))
+	}
+	return AuthorizationStatus(status), nil
+}
+
 // Device represents a metadata that later can be used to retrieve back the
 // underlying device given by AVFoundation
 type Device struct {
@@ -90,6 +112,29 @@ func Devices(mediaType MediaType) ([]Device, error) {
 	return devices, nil
 }
 
+// RequestAuthorization prompts the user for permission to capture the media type if they haven't been asked
+// yet, and blocks until they answer. It returns the permission without prompting if they already answered.
+func RequestAuthorization(mediaType MediaType) (AuthorizationStatus, error) {
+	var granted C.int
+	if status := C.AVBindRequestAuthorization(C.AVBindMediaType(mediaType), &granted); status != nil {
+		return AuthorizationNotDetermined, fmt.Errorf("%s", C.GoString(status))
+	}
+	if granted == 0 {
+		// Denied or restricted
+		return Authorization(mediaType)
+	}
+	return AuthorizationAuthorized, nil
+}
+
+// InUse reports whether another application uses the device exclusively
+func (device Device) InUse() (bool, error) {
+	var inUse C.int
+	if status := C.AVBindDeviceInUse(device.cDevice, &inUse); status != nil {
+		return false, fmt.Errorf("%s", C.GoString(status))
+	}
+	return inUse != 0, nil
+}
+
 // ReadCloser is a wrapper around the data callback from AVFoundation. The data received from the
 // the underlying callback can be retrieved by calling Read.
 type ReadCloser struct {
@@ -0,0 +1,200 @@
+// Package bench measures video pipeline performance, e.g. capture, scale, convert and encode, with
+// synthetic sources. Results can be written as CSV or JSON to compare codecs and transforms across
+// hardware, or to catch performance regressions in CI. ProbeLadder measures an encoder's quality at
+// bitrate ladder rungs instead.
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	defaultFrames    = 300
+	defaultWarmUp    = 10
+	defaultFrameRate = 30
+)
+
+var errNoFrames = errors.New("no frames to measure")
+
+// VideoPipeline is a pipeline to measure. Source frames are transformed in order, and encoded if
+// Encoder is set.
+type VideoPipeline struct {
+	Name       string
+	Width      int
+	Height     int
+	Transforms []video.TransformFunc
+	Encoder    codec.VideoEncoderBuilder
+	// Seed is the source's seed.
+	Seed int64
+}
+
+// Options configures Run.
+type Options struct {
+	// Frames is how many frames are measured. If it's 0, 300 is used.
+	Frames int
+	// WarmUp is how many frames are processed before measuring, e.g. to fill caches and
+	// encoder lookahead. If it's 0, 10 is used. A negative value disables it.
+	WarmUp int
+	// FrameRate is the frame rate told to the encoder. If it's 0, 30 is used.
+	FrameRate float32
+	// CPUProfile receives the measurement's CPU profile in pprof format if it's not nil.
+	CPUProfile io.Writer
+}
+
+// Result is a pipeline's measurement.
+type Result struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Frames int    `json:"frames"`
+	// Duration is the time to process all frames.
+	Duration time.Duration `json:"duration_ns"`
+	// FPS is how many frames are processed per second.
+	FPS float64 `json:"fps"`
+	// LatencyP50 and LatencyP99 are percentiles of per-frame processing time.
+	LatencyP50 time.Duration `json:"latency_p50_ns"`
+	LatencyP99 time.Duration `json:"latency_p99_ns"`
+	// BitRate is the encoded stream's bit rate at the frame rate, or 0 if it's not encoded.
+	BitRate float64 `json:"bitrate"`
+	// AllocsPerFrame and BytesPerFrame are heap allocations per frame.
+	AllocsPerFrame float64 `json:"allocs_per_frame"`
+	BytesPerFrame  float64 `json:"bytes_per_frame"`
+}
+
+// Run measures the pipeline.
+func Run(p VideoPipeline, opts Options) (Result, error) {
+	if opts.Frames <= 0 {
+		opts.Frames = defaultFrames
+	}
+	if opts.WarmUp == 0 {
+		opts.WarmUp = defaultWarmUp
+	}
+	if opts.FrameRate <= 0 {
+		opts.FrameRate = defaultFrameRate
+	}
+
+	read, closer, err := p.build(opts.FrameRate)
+	if err != nil {
+		return Result{}, err
+	}
+	defer closer()
+
+	for i := 0; i < opts.WarmUp; i++ {
+		if _, err := read(); err != nil {
+			return Result{}, err
+		}
+	}
+
+	if opts.CPUProfile != nil {
+		if err := pprof.StartCPUProfile(opts.CPUProfile); err != nil {
+			return Result{}, fmt.Errorf("failed to start the CPU profile: %s", err)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	latencies := make([]time.Duration, opts.Frames)
+	var size int
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := range latencies {
+		frameStart := time.Now()
+		n, err := read()
+		if err != nil {
+			return Result{}, err
+		}
+		latencies[i] = time.Since(frameStart)
+		size += n
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	return newResult(p, latencies, elapsed, size, opts.FrameRate, after.Mallocs-before.Mallocs, after.TotalAlloc-before.TotalAlloc)
+}
+
+// build returns a function that reads a frame through the pipeline and returns the encoded frame's size.
+func (p VideoPipeline) build(frameRate float32) (func() (int, error), func(), error) {
+	r := video.Merge(p.Transforms...)(NewVideoSource(p.Width, p.Height, p.Seed))
+	if p.Encoder == nil {
+		return func() (int, error) {
+			_, release, err := r.Read()
+			if err != nil {
+				return 0, err
+			}
+			release()
+			return 0, nil
+		}, func() {}, nil
+	}
+
+	// Encoders are told the transformed frame size.
+	first, releaseFirst, err := r.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	bounds := first.Bounds()
+	src := r
+	r = video.ReaderFunc(func() (image.Image, func(), error) {
+		if img := first; img != nil {
+			first = nil
+			return img, releaseFirst, nil
+		}
+		return src.Read()
+	})
+
+	encoder, err := p.Encoder.BuildVideoEncoder(r, prop.Media{
+		Video: prop.Video{
+			Width:     bounds.Dx(),
+			Height:    bounds.Dy(),
+			FrameRate: frameRate,
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build the encoder: %s", err)
+	}
+	return func() (int, error) {
+		b, release, err := encoder.Read()
+		if err != nil {
+			return 0, err
+		}
+		n := len(b)
+		release()
+		return n, nil
+	}, func() { encoder.Close() }, nil
+}
+
+func newResult(p VideoPipeline, latencies []time.Duration, elapsed time.Duration, size int, frameRate float32, allocs, bytes uint64) (Result, error) {
+	frames := len(latencies)
+	if frames == 0 {
+		return Result{}, errNoFrames
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	res := Result{
+		Name:           p.Name,
+		Width:          p.Width,
+		Height:         p.Height,
+		Frames:         frames,
+		Duration:       elapsed,
+		LatencyP50:     sorted[(frames-1)*50/100],
+		LatencyP99:     sorted[(frames-1)*99/100],
+		BitRate:        float64(size) * 8 * float64(frameRate) / float64(frames),
+		AllocsPerFrame: float64(allocs) / float64(frames),
+		BytesPerFrame:  float64(bytes) / float64(frames),
+	}
+	if elapsed > 0 {
+		res.FPS = float64(frames) / elapsed.Seconds()
+	}
+	return res, nil
+}
@@ -0,0 +1,130 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/codec/vp8"
+)
+
+func TestNewVideoSource(t *testing.T) {
+	read := func(seed int64) *image.YCbCr {
+		img, _, err := NewVideoSource(64, 32, seed).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img.(*image.YCbCr)
+	}
+
+	a, b := read(1), read(1)
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("expected the frames of the same seed to be the same")
+	}
+	if a.Rect != image.Rect(0, 0, 64, 32) {
+		t.Fatalf("expected the size to be 64x32, but got %v", a.Rect)
+	}
+	if reflect.DeepEqual(a, read(2)) {
+		t.Fatal("expected the frames of the different seeds to be different")
+	}
+}
+
+func TestRun(t *testing.T) {
+	params, err := vp8.NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.BitRate = 100000
+	pipelines := StandardPipelines(64, 32, map[string]codec.VideoEncoderBuilder{"vp8": &params})
+
+	results, err := RunAll(pipelines, Options{Frames: 5, WarmUp: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedNames := []string{"capture", "capture+scale", "capture+scale+convert", "capture+scale+convert+vp8"}
+	if len(results) != len(expectedNames) {
+		t.Fatalf("expected %d results, but got %d", len(expectedNames), len(results))
+	}
+	for i, res := range results {
+		if res.Name != expectedNames[i] {
+			t.Fatalf("expected %s, but got %s", expectedNames[i], res.Name)
+		}
+		if res.Frames != 5 || res.FPS <= 0 || res.LatencyP99 < res.LatencyP50 {
+			t.Fatalf("expected valid measurement, but got %+v", res)
+		}
+	}
+	if results[2].BitRate != 0 {
+		t.Fatalf("expected no bit rate without the encoder, but got %f", results[2].BitRate)
+	}
+	if results[3].BitRate <= 0 {
+		t.Fatalf("expected the bit rate of the encoder, but got %f", results[3].BitRate)
+	}
+
+	t.Run("CPUProfile", func(t *testing.T) {
+		var profile bytes.Buffer
+		if _, err := Run(pipelines[0], Options{Frames: 2, CPUProfile: &profile}); err != nil {
+			t.Fatal(err)
+		}
+		if profile.Len() == 0 {
+			t.Fatal("expected the CPU profile to be written")
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	results := []Result{
+		{Name: "capture", Width: 64, Height: 32, Frames: 2, Duration: 1000, FPS: 2000000, LatencyP50: 400, LatencyP99: 600},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		csvHeader,
+		{"capture", "64", "32", "2", "1000", "2000000", "400", "600", "0", "0", "0"},
+	}
+	if !reflect.DeepEqual(expected, records) {
+		t.Fatalf("expected %v, but got %v", expected, records)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []Result
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(results, decoded) {
+		t.Fatalf("expected %+v, but got %+v", results, decoded)
+	}
+}
+
+func BenchmarkCapture(b *testing.B) {
+	Benchmark(b, VideoPipeline{Width: 640, Height: 480})
+}
+
+// BenchmarkPipelines reports allocations per stage, which should stay near zero per frame since frames and
+// encoded buffers are pooled.
+func BenchmarkPipelines(b *testing.B) {
+	vp8Params, err := vp8.NewParams()
+	if err != nil {
+		b.Fatal(err)
+	}
+	encoders := map[string]codec.VideoEncoderBuilder{"vp8": &vp8Params}
+	for _, p := range StandardPipelines(640, 480, encoders) {
+		p := p
+		b.Run(p.Name, func(b *testing.B) {
+			Benchmark(b, p)
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package bench
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	xvp8 "golang.org/x/image/vp8"
+)
+
+var errEmptySample = errors.New("the sample has no frames")
+
+// Rung is a bitrate ladder rung, i.e. a simulcast layer.
+type Rung struct {
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+	BitRate int `json:"bitrate"`
+}
+
+// RungQuality is a rung's quality measured by ProbeLadder.
+type RungQuality struct {
+	Rung
+	// EncodedBitRate is the encoded frames' bit rate at the frame rate, which may differ from BitRate
+	// while the encoder's rate control converges.
+	EncodedBitRate float64 `json:"encoded_bitrate"`
+	// PSNR is mean luma PSNR in dB, and SSIM is mean luma SSIM. Decoded frames are
+	// scaled back to sample size, so rungs of different sizes are compared as they're
+	// displayed.
+	PSNR float64 `json:"psnr"`
+	SSIM float64 `json:"ssim"`
+}
+
+// FrameDecoder decodes encoder frames for ProbeLadder.
+type FrameDecoder interface {
+	Decode(frame []byte) (image.Image, error)
+}
+
+// LadderOptions configures ProbeLadder.
+type LadderOptions struct {
+	// FrameRate is the frame rate told to the encoder. If it's 0, 30 is used.
+	FrameRate float32
+	// Decoder returns a new decoder for encoder frames for each rung. If it's nil, frames are
+	// decoded as VP8 key frames, e.g. from pkg/codec/vp8.
+	Decoder func() (FrameDecoder, error)
+}
+
+// CaptureSample reads n frames from r for ProbeLadder. Frames are copied, since readers reuse their buffers.
+func CaptureSample(r video.Reader, n int) ([]*image.YCbCr, error) {
+	r = video.ToI420(r)
+	sample := make([]*image.YCbCr, 0, n)
+	for len(sample) < n {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, err
+		}
+		yuv := img.(*image.YCbCr)
+		copied := image.NewYCbCr(image.Rect(0, 0, yuv.Rect.Dx(), yuv.Rect.Dy()), image.YCbCrSubsampleRatio420)
+		for y := 0; y < copied.Rect.Dy(); y++ {
+			copy(copied.Y[y*copied.YStride:(y+1)*copied.YStride], yuv.Y[yuv.YOffset(yuv.Rect.Min.X, yuv.Rect.Min.Y+y):])
+		}
+		for y := 0; y < (copied.Rect.Dy()+1)/2; y++ {
+			c := yuv.COffset(yuv.Rect.Min.X, yuv.Rect.Min.Y+2*y)
+			copy(copied.Cb[y*copied.CStride:(y+1)*copied.CStride], yuv.Cb[c:])
+			copy(copied.Cr[y*copied.CStride:(y+1)*copied.CStride], yuv.Cr[c:])
+		}
+		release()
+		sample = append(sample, copied)
+	}
+	return sample, nil
+}
+
+// ProbeLadder encodes sample at each rung with encoder, and measures decoded frame quality,
+// so applications can choose simulcast rungs for a camera and scene, e.g. by ChooseLadder.
+// Frames are scaled to rung size, and bit rate is set by the encoder's SetBitRate. A few seconds
+// of sample are enough, while the first frames are encoded before rate control converges.
+func ProbeLadder(sample []*image.YCbCr, encoder codec.VideoEncoderBuilder, rungs []Rung, opts LadderOptions) ([]RungQuality, error) {
+	if len(sample) == 0 {
+		return nil, errEmptySample
+	}
+	if opts.FrameRate <= 0 {
+		opts.FrameRate = defaultFrameRate
+	}
+	if opts.Decoder == nil {
+		opts.Decoder = newVP8Decoder
+	}
+
+	results := make([]RungQuality, 0, len(rungs))
+	for _, rung := range rungs {
+		q, err := probeRung(sample, encoder, rung, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to probe %dx%d at %d bps: %s", rung.Width, rung.Height, rung.BitRate, err)
+		}
+		results = append(results, q)
+	}
+	return results, nil
+}
+
+func probeRung(sample []*image.YCbCr, builder codec.VideoEncoderBuilder, rung Rung, opts LadderOptions) (RungQuality, error) {
+	if rung.Width <= 0 || rung.Height <= 0 {
+		return RungQuality{}, fmt.Errorf("invalid size")
+	}
+	var n int
+	src := video.ReaderFunc(func() (image.Image, func(), error) {
+		img := sample[n%len(sample)]
+		n++
+		return img, func() {}, nil
+	})
+	r := video.Merge(video.Scale(rung.Width, rung.Height, video.ScalerBiLinear), video.ToI420)(src)
+	encoder, err := builder.BuildVideoEncoder(r, prop.Media{
+		Video: prop.Video{
+			Width:       rung.Width,
+			Height:      rung.Height,
+			FrameRate:   opts.FrameRate,
+			FrameFormat: frame.FormatI420,
+		},
+	})
+	if err != nil {
+		return RungQuality{}, fmt.Errorf("failed to build the encoder: %s", err)
+	}
+	defer encoder.Close()
+	if err := encoder.SetBitRate(rung.BitRate); err != nil {
+		return RungQuality{}, fmt.Errorf("failed to set the bit rate: %s", err)
+	}
+	decoder, err := opts.Decoder()
+	if err != nil {
+		return RungQuality{}, fmt.Errorf("failed to create the decoder: %s", err)
+	}
+
+	bounds := sample[0].Rect
+	upscale := video.Scale(bounds.Dx(), bounds.Dy(), video.ScalerBiLinear)
+	var decoded image.Image
+	display := video.Merge(upscale, video.ToI420)(video.ReaderFunc(func() (image.Image, func(), error) {
+		return decoded, func() {}, nil
+	}))
+
+	q := RungQuality{Rung: rung}
+	var size int
+	for i := range sample {
+		b, release, err := encoder.Read()
+		if err != nil {
+			return RungQuality{}, err
+		}
+		size += len(b)
+		decoded, err = decoder.Decode(b)
+		release()
+		if err != nil {
+			return RungQuality{}, fmt.Errorf("failed to decode a frame: %s", err)
+		}
+		img, _, err := display.Read()
+		if err != nil {
+			return RungQuality{}, err
+		}
+		yuv := img.(*image.YCbCr)
+		ref := sample[i]
+		q.PSNR += video.PSNR(ref, yuv)
+		q.SSIM += video.SSIM(ref, yuv)
+	}
+	frames := float64(len(sample))
+	q.PSNR /= frames
+	q.SSIM /= frames
+	q.EncodedBitRate = float64(size) * 8 * float64(opts.FrameRate) / frames
+	return q, nil
+}
+
+// ChooseLadder chooses a rung for each size from ProbeLadder results: the lowest bit rate whose PSNR
+// is at least minPSNR, or the best one if none reaches it. Rungs are sorted from the largest size.
+func ChooseLadder(results []RungQuality, minPSNR float64) []RungQuality {
+	type size struct{ width, height int }
+	chosen := make(map[size]RungQuality)
+	for _, q := range results {
+		s := size{q.Width, q.Height}
+		c, ok := chosen[s]
+		switch {
+		case !ok:
+			chosen[s] = q
+		case c.PSNR < minPSNR && q.PSNR > c.PSNR:
+			chosen[s] = q
+		case q.PSNR >= minPSNR && (c.PSNR < minPSNR || q.BitRate < c.BitRate):
+			chosen[s] = q
+		}
+	}
+	ladder := make([]RungQuality, 0, len(chosen))
+	for _, q := range chosen {
+		ladder = append(ladder, q)
+	}
+	sort.Slice(ladder, func(i, j int) bool {
+		return ladder[i].Width*ladder[i].Height > ladder[j].Width*ladder[j].Height
+	})
+	return ladder
+}
+
+// vp8Decoder decodes VP8 key frames with golang.org/x/image/vp8.
+type vp8Decoder struct {
+	d *xvp8.Decoder
+}
+
+func newVP8Decoder() (FrameDecoder, error) {
+	return &vp8Decoder{d: xvp8.NewDecoder()}, nil
+}
+
+func (v *vp8Decoder) Decode(b []byte) (image.Image, error) {
+	v.d.Init(bytes.NewReader(b), len(b))
+	if _, err := v.d.DecodeFrameHeader(); err != nil {
+		return nil, err
+	}
+	return v.d.DecodeFrame()
+}
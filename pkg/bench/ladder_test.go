@@ -0,0 +1,48 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec/vp8"
+)
+
+func TestProbeLadder(t *testing.T) {
+	sample, err := CaptureSample(NewVideoSource(128, 96, 1), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := vp8.NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rungs := []Rung{
+		{Width: 128, Height: 96, BitRate: 100000},
+		{Width: 128, Height: 96, BitRate: 2000000},
+		{Width: 64, Height: 48, BitRate: 100000},
+	}
+	results, err := ProbeLadder(sample, &params, rungs, LadderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(rungs) {
+		t.Fatalf("expected %d results, but got %d", len(rungs), len(results))
+	}
+	for i, q := range results {
+		if q.Rung != rungs[i] || q.EncodedBitRate <= 0 || q.PSNR <= 0 || q.SSIM <= 0 || q.SSIM > 1 {
+			t.Fatalf("expected valid quality, but got %+v", q)
+		}
+	}
+	if results[1].PSNR <= results[0].PSNR {
+		t.Fatalf("expected the higher bit rate to be better, but got %.2fdB and %.2fdB", results[1].PSNR, results[0].PSNR)
+	}
+
+	ladder := ChooseLadder(results, results[1].PSNR-0.01)
+	if len(ladder) != 2 || ladder[0].Rung != rungs[1] || ladder[1].Rung != rungs[2] {
+		t.Fatalf("expected a rung for each size, but got %+v", ladder)
+	}
+
+	if _, err := ProbeLadder(nil, &params, rungs, LadderOptions{}); err != errEmptySample {
+		t.Fatalf("expected %v, but got %v", errEmptySample, err)
+	}
+}
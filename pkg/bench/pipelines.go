@@ -0,0 +1,52 @@
+package bench
+
+import (
+	"sort"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// StandardPipelines returns pipelines that add a typical camera pipeline's stages one by one, i.e.
+// capture, scale to half size, convert to I420, and encode with each of encoders. Differences
+// between results are the stages' costs. encoders' keys are used as pipeline names.
+func StandardPipelines(width, height int, encoders map[string]codec.VideoEncoderBuilder) []VideoPipeline {
+	scale := video.Scale(width/2, height/2, nil)
+	pipelines := []VideoPipeline{
+		{Name: "capture", Width: width, Height: height},
+		{Name: "capture+scale", Width: width, Height: height, Transforms: []video.TransformFunc{scale}},
+		{Name: "capture+scale+convert", Width: width, Height: height, Transforms: []video.TransformFunc{scale, video.ToI420}},
+	}
+	for _, name := range sortedNames(encoders) {
+		pipelines = append(pipelines, VideoPipeline{
+			Name:       "capture+scale+convert+" + name,
+			Width:      width,
+			Height:     height,
+			Transforms: []video.TransformFunc{scale, video.ToI420},
+			Encoder:    encoders[name],
+		})
+	}
+	return pipelines
+}
+
+// RunAll measures the pipelines in order.
+func RunAll(pipelines []VideoPipeline, opts Options) ([]Result, error) {
+	results := make([]Result, 0, len(pipelines))
+	for _, p := range pipelines {
+		res, err := Run(p, opts)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func sortedNames(encoders map[string]codec.VideoEncoderBuilder) []string {
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
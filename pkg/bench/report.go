@@ -0,0 +1,85 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var csvHeader = []string{
+	"name", "width", "height", "frames", "duration_ns", "fps",
+	"latency_p50_ns", "latency_p99_ns", "bitrate", "allocs_per_frame", "bytes_per_frame",
+}
+
+// WriteCSV writes results as CSV with a header row.
+func WriteCSV(w io.Writer, results []Result) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, res := range results {
+		err := cw.Write([]string{
+			res.Name,
+			strconv.Itoa(res.Width),
+			strconv.Itoa(res.Height),
+			strconv.Itoa(res.Frames),
+			strconv.FormatInt(int64(res.Duration), 10),
+			formatFloat(res.FPS),
+			strconv.FormatInt(int64(res.LatencyP50), 10),
+			strconv.FormatInt(int64(res.LatencyP99), 10),
+			formatFloat(res.BitRate),
+			formatFloat(res.AllocsPerFrame),
+			formatFloat(res.BytesPerFrame),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes results as a JSON array.
+func WriteJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// Benchmark runs the pipeline as a Go benchmark, so standard tools like benchstat can compare it,
+// e.g. from a user's BenchmarkXxx function. An iteration processes a frame.
+func Benchmark(b *testing.B, p VideoPipeline) {
+	read, closer, err := p.build(defaultFrameRate)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer closer()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var size int
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		n, err := read()
+		if err != nil {
+			b.Fatal(err)
+		}
+		size += n
+	}
+	if elapsed := time.Since(start); elapsed > 0 {
+		b.ReportMetric(float64(b.N)/elapsed.Seconds(), "fps")
+	}
+	if size > 0 {
+		b.ReportMetric(float64(size)*8*defaultFrameRate/float64(b.N), "bit/s")
+	}
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
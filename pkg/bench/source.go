@@ -0,0 +1,77 @@
+package bench
+
+import (
+	"image"
+	"math/rand"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// sourceFrames is how many distinct frames NewVideoSource generates. Frames are generated once
+// and repeated, so generating them isn't measured.
+const sourceFrames = 30
+
+// NewVideoSource returns a reader that emulates a camera capturing YUYV frames of size. Frames are
+// moving color bars with noise, which are the same for the same seed, so results are reproducible
+// across runs and machines. Each frame is decoded by the camera drivers' decoder, like a real
+// capture.
+func NewVideoSource(width, height int, seed int64) video.Reader {
+	decoder, err := frame.NewDecoder(frame.FormatYUYV)
+	if err != nil {
+		panic(err)
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	frames := make([][]byte, sourceFrames)
+	for i := range frames {
+		frames[i] = yuyvFrame(width, height, i, rnd)
+	}
+
+	var n int
+	return video.ReaderFunc(func() (image.Image, func(), error) {
+		raw := frames[n%len(frames)]
+		n++
+		return decoder.Decode(raw, width, height)
+	})
+}
+
+// barColors are the bars' YCbCr colors, i.e. white, yellow, cyan, green, magenta, red and blue.
+var barColors = [][3]uint8{
+	{235, 128, 128},
+	{210, 16, 146},
+	{170, 166, 16},
+	{145, 54, 34},
+	{106, 202, 222},
+	{81, 90, 240},
+	{41, 240, 110},
+}
+
+// yuyvFrame returns frame i, whose bars are shifted by i pixels.
+func yuyvFrame(width, height, i int, rnd *rand.Rand) []byte {
+	buf := make([]byte, width*height*2)
+	barWidth := width/len(barColors) + 1
+	for y := 0; y < height; y++ {
+		for x := 0; x+1 < width; x += 2 {
+			c := barColors[((x+i)/barWidth)%len(barColors)]
+			addr := (y*width + x) * 2
+			buf[addr+0] = noisy(c[0], rnd)
+			buf[addr+1] = c[1]
+			buf[addr+2] = noisy(c[0], rnd)
+			buf[addr+3] = c[2]
+		}
+	}
+	return buf
+}
+
+func noisy(v uint8, rnd *rand.Rand) uint8 {
+	n := int(v) + rnd.Intn(9) - 4
+	switch {
+	case n < 0:
+		return 0
+	case n > 255:
+		return 255
+	default:
+		return uint8(n)
+	}
+}
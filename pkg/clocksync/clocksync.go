@@ -0,0 +1,275 @@
+// Package clocksync disciplines a local clock against an external time source, e.g. an NTP server or a PTP
+// hardware clock, so machines capturing the same event produce timestamps that can be aligned.
+// Pass Clock.Now to mediadevices.WithTimeSource to timestamp media by the disciplined clock.
+package clocksync
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPollInterval  = 16 * time.Second
+	defaultFilterSize    = 8
+	defaultStepThreshold = 128 * time.Millisecond
+	// defaultMaxSlew is the maximum correction rate in seconds per second, same as ntpd.
+	defaultMaxSlew = 500e-6
+)
+
+var errClosed = errors.New("clocksync: clock is closed")
+
+// Sample measures the local clock's offset from the source.
+type Sample struct {
+	// Offset is source time minus local clock time.
+	Offset time.Duration
+	// Delay is the measurement's round trip delay. Samples with shorter delay have more accurate
+	// offsets, since offset error is up to half the delay.
+	Delay time.Duration
+}
+
+// Source measures the local clock's offset, from time.Now, from an external clock.
+type Source interface {
+	Query() (Sample, error)
+}
+
+// SourceFunc is an adapter to use an ordinary function as Source.
+type SourceFunc func() (Sample, error)
+
+// Query calls f().
+func (f SourceFunc) Query() (Sample, error) {
+	return f()
+}
+
+// Stats is Clock's synchronization state.
+type Stats struct {
+	// Synced is whether the clock has synchronized with the source.
+	Synced bool
+	// Offset is the correction currently applied to the local clock.
+	Offset time.Duration
+	// TargetOffset is the best sample's offset, which Offset is slewing to.
+	TargetOffset time.Duration
+	// Delay is the best sample's round trip delay.
+	Delay time.Duration
+	// Jitter is the root mean square of differences between recent sample offsets and the best
+	// sample's. Machines' timestamps are aligned within about the sum of their jitters.
+	Jitter time.Duration
+	// Samples is how many measurements succeeded.
+	Samples int
+	// LastSync is the local time of the last successful measurement.
+	LastSync time.Time
+	// LastError is the last measurement's error, which is nil if it succeeded.
+	LastError error
+}
+
+// Clock is a local clock disciplined against Source. Offset is corrected gradually so the clock
+// is monotonic, except when offset changes more than the step threshold, e.g. on the first measurement. Clock
+// is safe for concurrent use.
+type Clock struct {
+	source        Source
+	pollInterval  time.Duration
+	filterSize    int
+	stepThreshold time.Duration
+	maxSlew       float64
+	now           func() time.Time
+
+	mu      sync.Mutex
+	filter  []Sample
+	stats   Stats
+	from    time.Duration
+	fromAt  time.Time
+	closed  bool
+	closing chan struct{}
+	done    chan struct{}
+}
+
+// Option configures Clock.
+type Option func(*Clock)
+
+// WithPollInterval sets the interval between measurements. The default is 16 seconds.
+func WithPollInterval(interval time.Duration) Option {
+	return func(c *Clock) {
+		c.pollInterval = interval
+	}
+}
+
+// WithFilterSize sets how many recent samples the best sample is selected from. The default
+// is 8, like NTP's clock filter.
+func WithFilterSize(size int) Option {
+	return func(c *Clock) {
+		c.filterSize = size
+	}
+}
+
+// WithStepThreshold sets the offset change over which the clock is stepped instead of slewed. The default is
+// 128 ms, same as ntpd.
+func WithStepThreshold(threshold time.Duration) Option {
+	return func(c *Clock) {
+		c.stepThreshold = threshold
+	}
+}
+
+// WithMaxSlew sets the maximum correction rate in seconds per second. The default is 500 ppm.
+func WithMaxSlew(rate float64) Option {
+	return func(c *Clock) {
+		c.maxSlew = rate
+	}
+}
+
+// WithLocalTimeSource replaces the disciplined local clock, which defaults to time.Now. The source has to
+// measure offset from the same clock.
+func WithLocalTimeSource(now func() time.Time) Option {
+	return func(c *Clock) {
+		c.now = now
+	}
+}
+
+// NewClock creates a Clock that's disciplined against source. It measures offset once before returning, and
+// then every poll interval in background until Close is called. The first measurement's error is returned
+// to find an unreachable source early, but the clock keeps polling it.
+func NewClock(source Source, opts ...Option) (*Clock, error) {
+	c := &Clock{
+		source:        source,
+		pollInterval:  defaultPollInterval,
+		filterSize:    defaultFilterSize,
+		stepThreshold: defaultStepThreshold,
+		maxSlew:       defaultMaxSlew,
+		now:           time.Now,
+		closing:       make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.filterSize < 1 {
+		c.filterSize = 1
+	}
+
+	err := c.Sync()
+	go c.poll()
+	return c, err
+}
+
+func (c *Clock) poll() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closing:
+			return
+		case <-ticker.C:
+			c.Sync()
+		}
+	}
+}
+
+// Sync measures offset from the source now, e.g. after the network changed.
+func (c *Clock) Sync() error {
+	sample, err := c.source.Query()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+	c.stats.LastError = err
+	if err != nil {
+		return err
+	}
+	c.update(sample)
+	return nil
+}
+
+func (c *Clock) update(sample Sample) {
+	now := c.now()
+	c.filter = append(c.filter, sample)
+	if len(c.filter) > c.filterSize {
+		c.filter = c.filter[len(c.filter)-c.filterSize:]
+	}
+
+	best := c.filter[0]
+	for _, s := range c.filter[1:] {
+		if s.Delay < best.Delay {
+			best = s
+		}
+	}
+
+	var sum float64
+	for _, s := range c.filter {
+		d := float64(s.Offset - best.Offset)
+		sum += d * d
+	}
+
+	offset := c.offset(now)
+	if !c.stats.Synced || absDuration(best.Offset-offset) > c.stepThreshold {
+		offset = best.Offset
+	}
+	c.from = offset
+	c.fromAt = now
+
+	c.stats.Synced = true
+	c.stats.TargetOffset = best.Offset
+	c.stats.Delay = best.Delay
+	c.stats.Jitter = time.Duration(math.Sqrt(sum / float64(len(c.filter))))
+	c.stats.Samples++
+	c.stats.LastSync = now
+}
+
+// offset returns the correction at local time now, which slews from the last update's correction to the
+// target offset.
+func (c *Clock) offset(now time.Time) time.Duration {
+	diff := c.stats.TargetOffset - c.from
+	max := time.Duration(float64(now.Sub(c.fromAt)) * c.maxSlew)
+	switch {
+	case max < 0:
+		return c.from
+	case diff > max:
+		return c.from + max
+	case diff < -max:
+		return c.from - max
+	default:
+		return c.stats.TargetOffset
+	}
+}
+
+// Now returns the disciplined time. It's the local time until the clock is synchronized.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	return now.Add(c.offset(now))
+}
+
+// Stats returns synchronization state, e.g. to monitor offset and jitter.
+func (c *Clock) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Offset = c.offset(c.now())
+	return stats
+}
+
+// Close stops polling the source. The clock keeps the last correction.
+func (c *Clock) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.closing)
+	<-c.done
+	return nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,148 @@
+package clocksync
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	local := time.Unix(1000, 0)
+	samples := []Sample{
+		{Offset: time.Second, Delay: 10 * time.Millisecond},
+		// The best sample has the shortest delay
+		{Offset: time.Second + 5*time.Millisecond, Delay: 2 * time.Millisecond},
+		{Offset: time.Second + 40*time.Millisecond, Delay: 50 * time.Millisecond},
+	}
+	var i int
+	source := SourceFunc(func() (Sample, error) {
+		s := samples[i]
+		i++
+		return s, nil
+	})
+
+	c, err := NewClock(source,
+		WithPollInterval(time.Hour),
+		WithLocalTimeSource(func() time.Time { return local }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The first sample steps the clock
+	if now := c.Now(); !now.Equal(local.Add(time.Second)) {
+		t.Fatalf("expected the clock to be stepped to %v, but got %v", local.Add(time.Second), now)
+	}
+
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	stats := c.Stats()
+	if stats.TargetOffset != time.Second+5*time.Millisecond || stats.Delay != 2*time.Millisecond {
+		t.Fatalf("expected the sample of the shortest delay, but got %v and %v", stats.TargetOffset, stats.Delay)
+	}
+	if stats.Offset != time.Second {
+		t.Fatalf("expected the offset not to be slewed yet, but got %v", stats.Offset)
+	}
+	// sqrt((5^2 + 0^2 + 35^2) / 3) ms
+	if stats.Jitter < 20*time.Millisecond || stats.Jitter > 21*time.Millisecond {
+		t.Fatalf("expected the jitter to be about 20.4ms, but got %v", stats.Jitter)
+	}
+	if stats.Samples != 3 || !stats.Synced {
+		t.Fatalf("expected 3 samples, but got %d", stats.Samples)
+	}
+
+	// 500 ppm over 4 seconds is 2ms
+	local = local.Add(4 * time.Second)
+	if offset := c.Stats().Offset; offset != time.Second+2*time.Millisecond {
+		t.Fatalf("expected the offset to be slewed by 2ms, but got %v", offset)
+	}
+	local = local.Add(10 * time.Second)
+	if offset := c.Stats().Offset; offset != time.Second+5*time.Millisecond {
+		t.Fatalf("expected the offset to reach the target, but got %v", offset)
+	}
+}
+
+func TestClockStep(t *testing.T) {
+	local := time.Unix(1000, 0)
+	offset := time.Second
+	c, err := NewClock(SourceFunc(func() (Sample, error) { return Sample{Offset: offset}, nil }),
+		WithPollInterval(time.Hour),
+		WithFilterSize(1),
+		WithLocalTimeSource(func() time.Time { return local }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	offset = -time.Second
+	if err := c.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Stats().Offset; got != offset {
+		t.Fatalf("expected the clock to be stepped to %v, but got %v", offset, got)
+	}
+}
+
+func TestClockError(t *testing.T) {
+	errSource := errors.New("unreachable")
+	c, err := NewClock(SourceFunc(func() (Sample, error) { return Sample{}, errSource }), WithPollInterval(time.Hour))
+	if err != errSource {
+		t.Fatalf("expected the error of the source, but got %v", err)
+	}
+	defer c.Close()
+
+	if stats := c.Stats(); stats.Synced || stats.LastError != errSource {
+		t.Fatalf("expected the clock not to be synced, but got %+v", stats)
+	}
+}
+
+func TestNTPSource(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	const serverOffset = 3 * time.Second
+	go func() {
+		req := make([]byte, ntpPacketSize)
+		n, addr, err := conn.ReadFrom(req)
+		if err != nil || n != ntpPacketSize {
+			return
+		}
+		res := make([]byte, ntpPacketSize)
+		res[0] = ntpVersion<<3 | ntpModeServer
+		res[1] = 1
+		copy(res[24:32], req[40:48])
+		now := toNTPTime(time.Now().Add(serverOffset))
+		binary.BigEndian.PutUint64(res[32:], now)
+		binary.BigEndian.PutUint64(res[40:], now)
+		conn.WriteTo(res, addr)
+	}()
+
+	sample, err := NewNTPSource(conn.LocalAddr().String(), time.Second).Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := absDuration(sample.Offset - serverOffset); diff > sample.Delay+time.Millisecond {
+		t.Fatalf("expected the offset to be %v, but got %v", serverOffset, sample.Offset)
+	}
+	if sample.Delay < 0 || sample.Delay > time.Second {
+		t.Fatalf("expected a short delay, but got %v", sample.Delay)
+	}
+}
+
+func TestNTPTime(t *testing.T) {
+	now := time.Unix(1600000000, 123456789)
+	if got := fromNTPTime(toNTPTime(now)); absDuration(got.Sub(now)) > time.Nanosecond {
+		t.Fatalf("expected %v, but got %v", now, got)
+	}
+}
@@ -0,0 +1,116 @@
+package clocksync
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+const (
+	ntpPacketSize     = 48
+	ntpDefaultPort    = "123"
+	ntpDefaultTimeout = 5 * time.Second
+	// ntpEpochOffset is seconds between the NTP epoch (1900) and the Unix epoch (1970)
+	ntpEpochOffset = 2208988800
+
+	ntpVersion    = 4
+	ntpModeClient = 3
+	ntpModeServer = 4
+	ntpModeBcast  = 5
+	ntpLeapAlarm  = 3
+)
+
+var (
+	errNTPInvalidResponse = errors.New("clocksync: invalid NTP response")
+	errNTPUnsynchronized  = errors.New("clocksync: NTP server is not synchronized")
+)
+
+// NTPSource measures offset from an NTP server in SNTP client mode.
+// Reference: https://tools.ietf.org/html/rfc4330
+type NTPSource struct {
+	address string
+	timeout time.Duration
+}
+
+// NewNTPSource creates an NTPSource for server, which is a host with an optional port, e.g. "pool.ntp.org" or
+// "192.168.1.1:123". Each query times out after timeout, or 5 seconds if it's zero.
+func NewNTPSource(server string, timeout time.Duration) *NTPSource {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, ntpDefaultPort)
+	}
+	if timeout == 0 {
+		timeout = ntpDefaultTimeout
+	}
+	return &NTPSource{address: server, timeout: timeout}
+}
+
+// Query sends a request to the server and measures offset from its response.
+func (s *NTPSource) Query() (Sample, error) {
+	conn, err := net.DialTimeout("udp", s.address, s.timeout)
+	if err != nil {
+		return Sample{}, fmt.Errorf("failed to connect to %s: %s", s.address, err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return Sample{}, err
+	}
+
+	req := make([]byte, ntpPacketSize)
+	req[0] = ntpVersion<<3 | ntpModeClient
+	t1 := time.Now()
+	// The server copies our transmit timestamp into its originate timestamp, which identifies the response.
+	xmt := toNTPTime(t1)
+	binary.BigEndian.PutUint64(req[40:], xmt)
+	if _, err := conn.Write(req); err != nil {
+		return Sample{}, fmt.Errorf("failed to send the NTP request: %s", err)
+	}
+
+	res := make([]byte, ntpPacketSize)
+	for {
+		n, err := conn.Read(res)
+		if err != nil {
+			return Sample{}, fmt.Errorf("failed to receive the NTP response: %s", err)
+		}
+		t4 := time.Now()
+		if n < ntpPacketSize || binary.BigEndian.Uint64(res[24:]) != xmt {
+			// A late response to another request, or a spoofed packet
+			continue
+		}
+		return parseNTPResponse(res, t1, t4)
+	}
+}
+
+func parseNTPResponse(res []byte, t1, t4 time.Time) (Sample, error) {
+	leap := res[0] >> 6
+	mode := res[0] & 0x7
+	stratum := res[1]
+	if mode != ntpModeServer && mode != ntpModeBcast {
+		return Sample{}, errNTPInvalidResponse
+	}
+	// Stratum 0 is a kiss-o'-death packet
+	if leap == ntpLeapAlarm || stratum == 0 {
+		return Sample{}, errNTPUnsynchronized
+	}
+
+	t2 := fromNTPTime(binary.BigEndian.Uint64(res[32:]))
+	t3 := fromNTPTime(binary.BigEndian.Uint64(res[40:]))
+	return Sample{
+		Offset: (t2.Sub(t1) + t3.Sub(t4)) / 2,
+		Delay:  t4.Sub(t1) - t3.Sub(t2),
+	}, nil
+}
+
+func toNTPTime(t time.Time) uint64 {
+	nsec := uint64(t.UnixNano())
+	sec := nsec/uint64(time.Second) + ntpEpochOffset
+	frac := (nsec % uint64(time.Second)) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+func fromNTPTime(ntp uint64) time.Time {
+	sec := int64(ntp>>32) - ntpEpochOffset
+	nsec := int64((ntp & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(sec, nsec)
+}
@@ -0,0 +1,31 @@
+package clocksync
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPHCSourceTAI(t *testing.T) {
+	const tai = 37 * time.Second
+	s := &PHCSource{
+		// The hardware clock is on TAI, and 2ms ahead of the local clock
+		read: func() (time.Time, error) {
+			return time.Now().Add(tai + 2*time.Millisecond), nil
+		},
+		taiOffset: func() (time.Duration, error) { return tai, nil },
+	}
+	sample, err := s.Query()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := sample.Offset - 2*time.Millisecond; d < -time.Millisecond || d > time.Millisecond {
+		t.Fatalf("expected an offset of about 2ms from UTC, but got %v", sample.Offset)
+	}
+
+	errTAI := errors.New("no adjtimex")
+	s.taiOffset = func() (time.Duration, error) { return 0, errTAI }
+	if _, err := s.Query(); err == nil {
+		t.Fatal("expected an error without the TAI offset")
+	}
+}
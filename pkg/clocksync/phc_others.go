@@ -0,0 +1,30 @@
+//go:build !linux
+// +build !linux
+
+package clocksync
+
+import (
+	"errors"
+)
+
+var errPHCUnsupported = errors.New("clocksync: PTP hardware clocks are only supported on Linux")
+
+// PHCSource measures offset from a PTP hardware clock, e.g. a network interface's /dev/ptp0
+// synchronized by ptp4l.
+type PHCSource struct{}
+
+// OpenPHCSource opens device's PTP hardware clock, e.g. "/dev/ptp0". It's only supported on Linux, and
+// returns an error on other platforms.
+func OpenPHCSource(device string) (*PHCSource, error) {
+	return nil, errPHCUnsupported
+}
+
+// Query returns an error on platforms other than Linux.
+func (s *PHCSource) Query() (Sample, error) {
+	return Sample{}, errPHCUnsupported
+}
+
+// Close does nothing on platforms other than Linux.
+func (s *PHCSource) Close() error {
+	return nil
+}
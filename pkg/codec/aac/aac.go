@@ -0,0 +1,180 @@
+// +build fdkaac
+
+package aac
+
+// #cgo pkg-config: fdk-aac
+// #include <fdk-aac/aacenc_lib.h>
+//
+// AACENC_ERROR enc_new(HANDLE_AACENCODER *h, int sample_rate, int channels, int bitrate, int adts) {
+//   AACENC_ERROR err;
+//   if ((err = aacEncOpen(h, 0, channels)) != AACENC_OK)
+//     return err;
+//   if ((err = aacEncoder_SetParam(*h, AACENC_AOT, AOT_AAC_LC)) != AACENC_OK ||
+//       (err = aacEncoder_SetParam(*h, AACENC_SAMPLERATE, sample_rate)) != AACENC_OK ||
+//       (err = aacEncoder_SetParam(*h, AACENC_CHANNELMODE, channels == 1 ? MODE_1 : MODE_2)) != AACENC_OK ||
+//       (err = aacEncoder_SetParam(*h, AACENC_CHANNELORDER, 1)) != AACENC_OK ||
+//       (err = aacEncoder_SetParam(*h, AACENC_BITRATE, bitrate)) != AACENC_OK ||
+//       (err = aacEncoder_SetParam(*h, AACENC_TRANSMUX, adts ? TT_MP4_ADTS : TT_MP4_RAW)) != AACENC_OK ||
+//       (err = aacEncoder_SetParam(*h, AACENC_AFTERBURNER, 1)) != AACENC_OK ||
+//       (err = aacEncEncode(*h, NULL, NULL, NULL, NULL)) != AACENC_OK) {
+//     aacEncClose(h);
+//     return err;
+//   }
+//   return AACENC_OK;
+// }
+//
+// // enc_encode encodes interleaved samples and returns the frame size, which is 0 while the encoder is
+// // holding frames back, or -1 on error.
+// int enc_encode(HANDLE_AACENCODER h, INT_PCM *samples, int n, unsigned char *out, int out_size) {
+//   AACENC_BufDesc in_buf = {0}, out_buf = {0};
+//   AACENC_InArgs in_args = {0};
+//   AACENC_OutArgs out_args = {0};
+//   void *in_ptr = samples, *out_ptr = out;
+//   int in_id = IN_AUDIO_DATA, in_size = n * sizeof(INT_PCM), in_el_size = sizeof(INT_PCM);
+//   int out_id = OUT_BITSTREAM_DATA, out_el_size = 1;
+//
+//   in_buf.numBufs = 1;
+//   in_buf.bufs = &in_ptr;
+//   in_buf.bufferIdentifiers = &in_id;
+//   in_buf.bufSizes = &in_size;
+//   in_buf.bufElSizes = &in_el_size;
+//   out_buf.numBufs = 1;
+//   out_buf.bufs = &out_ptr;
+//   out_buf.bufferIdentifiers = &out_id;
+//   out_buf.bufSizes = &out_size;
+//   out_buf.bufElSizes = &out_el_size;
+//   in_args.numInSamples = n;
+//
+//   if (aacEncEncode(h, &in_buf, &out_buf, &in_args, &out_args) != AACENC_OK)
+//     return -1;
+//   return out_args.numOutBytes;
+// }
+import "C"
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/mediadevices/pkg/wave/mixer"
+)
+
+// maxFrameSize is the largest 2-channel frame, at 6144 bits per channel.
+const maxFrameSize = 2 * 6144 / 8
+
+type encoder struct {
+	engine C.HANDLE_AACENCODER
+	reader audio.Reader
+	frame  []byte
+	out    codec.BufferPool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
+	if p.SampleRate == 0 {
+		return nil, fmt.Errorf("aac: inProp.SampleRate is required")
+	}
+	if p.ChannelCount == 0 {
+		return nil, fmt.Errorf("aac: inProp.ChannelCount is required")
+	}
+
+	channels := p.ChannelCount
+	if channels > 2 {
+		channels = 2
+	}
+	if params.ChannelMixer == nil {
+		params.ChannelMixer = &mixer.ChannelSelector{Channels: []int{0, 1}[:channels]}
+	}
+
+	sampleRate := p.SampleRate
+	rResample := func(r audio.Reader) audio.Reader { return r }
+	if sampleRateIndex(sampleRate) < 0 {
+		sampleRate = 48000
+		rResample = audio.NewResampler(sampleRate)
+	}
+
+	var adts C.int
+	if params.ADTS {
+		adts = 1
+	}
+	var engine C.HANDLE_AACENCODER
+	if err := C.enc_new(&engine, C.int(sampleRate), C.int(channels), C.int(params.BitRate), adts); err != C.AACENC_OK {
+		return nil, fmt.Errorf("failed to create the encoder (%d)", err)
+	}
+
+	// fdk-aac takes a frame of interleaved Int16 samples
+	rConv := audio.NewConverter(true, wave.TypeInt16Interleaved)
+	rMix := audio.NewChannelMixer(channels, params.ChannelMixer)
+	rBuf := audio.NewBuffer(FrameSize)
+	return &encoder{
+		engine: engine,
+		reader: rBuf(rConv(rMix(rResample(r)))),
+		frame:  make([]byte, maxFrameSize),
+	}, nil
+}
+
+func (e *encoder) Read() ([]byte, func(), error) {
+	buff, _, err := e.reader.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	b, ok := buff.(*wave.Int16Interleaved)
+	if !ok {
+		return nil, func() {}, fmt.Errorf("unknown type of audio buffer")
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil, func() {}, io.EOF
+	}
+
+	n := C.enc_encode(
+		e.engine,
+		(*C.INT_PCM)(unsafe.Pointer(&b.Data[0])),
+		C.int(len(b.Data)),
+		(*C.uchar)(&e.frame[0]),
+		C.int(len(e.frame)),
+	)
+	if n < 0 {
+		return nil, func() {}, fmt.Errorf("failed to encode")
+	}
+	encoded, release := e.out.Get(int(n))
+	copy(encoded, e.frame[:n])
+	return encoded, release, nil
+}
+
+func (e *encoder) SetBitRate(bitRate int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return io.EOF
+	}
+	if err := C.aacEncoder_SetParam(e.engine, C.AACENC_BITRATE, C.UINT(bitRate)); err != C.AACENC_OK {
+		return fmt.Errorf("failed to set encoder's bitrate to %d", bitRate)
+	}
+	return nil
+}
+
+// ForceKeyFrame does nothing since every frame is independent.
+func (e *encoder) ForceKeyFrame() error {
+	return nil
+}
+
+func (e *encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	C.aacEncClose(&e.engine)
+	return nil
+}
@@ -0,0 +1,26 @@
+package aac
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAudioSpecificConfig(t *testing.T) {
+	testCases := map[string]struct {
+		sampleRate, channels int
+		expected             []byte
+	}{
+		"48kHzStereo": {sampleRate: 48000, channels: 2, expected: []byte{0x11, 0x90}},
+		"44.1kHzMono": {sampleRate: 44100, channels: 1, expected: []byte{0x12, 0x08}},
+		// Resampled to 48kHz and mixed down to 2 channels
+		"Unsupported": {sampleRate: 50000, channels: 6, expected: []byte{0x11, 0x90}},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			if config := AudioSpecificConfig(testCase.sampleRate, testCase.channels); !bytes.Equal(config, testCase.expected) {
+				t.Fatalf("expected %v, but got %v", testCase.expected, config)
+			}
+		})
+	}
+}
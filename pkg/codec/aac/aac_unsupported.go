@@ -0,0 +1,13 @@
+// +build !fdkaac
+
+package aac
+
+import (
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
+	return nil, errNotSupported
+}
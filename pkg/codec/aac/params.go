@@ -0,0 +1,79 @@
+// Package aac implements an AAC-LC encoder with fdk-aac, e.g. for MP4 recordings, which more players handle with
+// AAC than with Opus. The encoder needs the fdkaac build tag, since most Linux distributions don't ship fdk-aac
+// because of its license, and WebRTC can't negotiate AAC anyway.
+package aac
+
+import (
+	"errors"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave/mixer"
+)
+
+// FrameSize is the number of samples per channel in an AAC-LC frame.
+const FrameSize = 1024
+
+// sampleRates are the AudioSpecificConfig sampling frequencies, by index.
+var sampleRates = []int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350}
+
+var errNotSupported = errors.New("aac: the encoder requires the fdkaac build tag")
+
+// Params stores AAC specific encoding parameters.
+type Params struct {
+	codec.BaseParams
+	// ChannelMixer is the mixer used when the source has more than 2 channels. By default the first 2 channels are
+	// kept.
+	ChannelMixer mixer.ChannelMixer
+
+	// ADTS prefixes each frame with an ADTS header, e.g. to write .aac files with record.Recorder. Without it, frames
+	// are raw for MP4 muxers, which take AudioSpecificConfig instead.
+	ADTS bool
+}
+
+// NewParams returns default AAC codec specific parameters.
+func NewParams() (Params, error) {
+	return Params{
+		BaseParams: codec.BaseParams{
+			BitRate: 128000,
+		},
+	}, nil
+}
+
+// RTPCodec represents the codec metadata
+func (p *Params) RTPCodec() *codec.RTPCodec {
+	return codec.NewRTPAACCodec(48000, 2)
+}
+
+// BuildAudioEncoder builds AAC encoder with given params
+func (p *Params) BuildAudioEncoder(r audio.Reader, property prop.Media) (codec.ReadCloser, error) {
+	return newEncoder(r, property, *p)
+}
+
+// AudioSpecificConfig returns the MP4 decoder configuration for AAC-LC frames at sampleRate with channels. The
+// encoder resamples unsupported rates to 48kHz and mixes more than 2 channels down to 2, and the config matches.
+func AudioSpecificConfig(sampleRate, channels int) []byte {
+	index := sampleRateIndex(sampleRate)
+	if index < 0 {
+		index = sampleRateIndex(48000)
+	}
+	if channels > 2 {
+		channels = 2
+	}
+	// audioObjectType 2 (AAC LC), samplingFrequencyIndex, channelConfiguration and a zeroed GASpecificConfig
+	const objectType = 2
+	return []byte{
+		objectType<<3 | byte(index>>1),
+		byte(index&1)<<7 | byte(channels)<<3,
+	}
+}
+
+func sampleRateIndex(sampleRate int) int {
+	for i, r := range sampleRates {
+		if r == sampleRate {
+			return i
+		}
+	}
+	return -1
+}
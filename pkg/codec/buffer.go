@@ -0,0 +1,29 @@
+package codec
+
+import "sync"
+
+// BufferPool reuses encoded frame buffers once they're released, so encoders don't allocate a buffer per frame.
+// Each buffer comes with a release function, used as the release function of ReadCloser.Read, and the buffer
+// must not be used after it's called. The zero value is ready to use, and it's safe for concurrent use.
+type BufferPool struct {
+	pool sync.Pool
+}
+
+type pooledBuffer struct {
+	b       []byte
+	release func()
+}
+
+// Get returns a buffer of n bytes with undefined content, and a function that releases it to p.
+func (p *BufferPool) Get(n int) ([]byte, func()) {
+	buf, _ := p.pool.Get().(*pooledBuffer)
+	if buf == nil {
+		buf = &pooledBuffer{}
+		buf.release = func() { p.pool.Put(buf) }
+	}
+	if cap(buf.b) < n {
+		buf.b = make([]byte, n)
+	}
+	buf.b = buf.b[:n]
+	return buf.b, buf.release
+}
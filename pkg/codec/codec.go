@@ -18,6 +18,12 @@ type RTPCodec struct {
 
 	// Latency of static frame size codec.
 	Latency time.Duration
+
+	// MTU overrides the maximum RTP payload size of the track if it's not 0.
+	MTU int
+
+	// FEC configures forward error correction for outgoing packets.
+	FEC FECParams
 }
 
 // NewRTPH264Codec is a helper to create an H264 codec
@@ -37,6 +43,26 @@ func NewRTPH264Codec(clockrate uint32) *RTPCodec {
 	}
 }
 
+// MimeTypeH265 is H265's MIME type. The webrtc package doesn't define it yet.
+const MimeTypeH265 = "video/H265"
+
+// NewRTPH265Codec is a helper to create an H265 codec
+func NewRTPH265Codec(clockrate uint32) *RTPCodec {
+	return &RTPCodec{
+		RTPCodecParameters: webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:     MimeTypeH265,
+				ClockRate:    90000,
+				Channels:     0,
+				SDPFmtpLine:  "",
+				RTCPFeedback: nil,
+			},
+			PayloadType: 116,
+		},
+		Payloader: &h265Payloader{},
+	}
+}
+
 // NewRTPVP8Codec is a helper to create an VP8 codec
 func NewRTPVP8Codec(clockrate uint32) *RTPCodec {
 	return &RTPCodec{
@@ -88,6 +114,27 @@ func NewRTPOpusCodec(clockrate uint32) *RTPCodec {
 	}
 }
 
+// MimeTypeAAC is the MIME type of AAC in RFC 3640. WebRTC doesn't support AAC, so AAC encoders are only used by
+// encoded readers, e.g. for MP4 recordings.
+const MimeTypeAAC = "audio/mpeg4-generic"
+
+// NewRTPAACCodec is a helper to create an AAC codec in the AAC-hbr mode of RFC 3640
+func NewRTPAACCodec(clockrate uint32, channels uint16) *RTPCodec {
+	return &RTPCodec{
+		RTPCodecParameters: webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:     MimeTypeAAC,
+				ClockRate:    clockrate,
+				Channels:     channels,
+				SDPFmtpLine:  "streamtype=5;profile-level-id=1;mode=AAC-hbr;sizelength=13;indexlength=3;indexdeltalength=3",
+				RTCPFeedback: nil,
+			},
+			PayloadType: 97,
+		},
+		Payloader: &aacPayloader{},
+	}
+}
+
 // AudioEncoderBuilder is the interface that wraps basic operations that are
 // necessary to build the audio encoder.
 //
@@ -123,6 +170,55 @@ type ReadCloser interface {
 	ForceKeyFrame() error
 }
 
+// Preparer is an optional ReadCloser interface for encoders that can allocate their internal state
+// before the first Read. It's used to avoid first-frame latency.
+type Preparer interface {
+	// Prepare initializes the encoder, and returns the codec configuration that has to be sent
+	// before the first frame, e.g. H.264's SPS and PPS. It returns nil if the codec has none.
+	Prepare() ([]byte, error)
+}
+
+// Resetter is an optional interface of ReadCloser for encoders that can reinitialize in place, e.g. for a new
+// resolution, so they don't have to be closed and rebuilt while the track is being sent.
+type Resetter interface {
+	// Reset reinitializes the encoder for frames matching p, read after it returns. The next frame is a keyframe
+	// with the new codec configuration, e.g. H.264 SPS and PPS. The bitrate set by SetBitRate is kept.
+	Reset(p prop.Media) error
+}
+
+// IntraRefresher is an optional ReadCloser interface for encoders that can replace periodic keyframes with a
+// column of intra coded macroblocks that sweeps across the picture over KeyFrameInterval frames. Decoders recover
+// from a loss within the interval, and the refresh cost is spread over many frames instead of arriving in bursts.
+type IntraRefresher interface {
+	// SetIntraRefresh enables or disables periodic intra refresh.
+	SetIntraRefresh(enabled bool) error
+}
+
+// LongTermReferencer is an optional ReadCloser interface for encoders that can keep long-term reference frames,
+// so frames after a loss can refer to an older frame that was received instead of requiring a keyframe.
+type LongTermReferencer interface {
+	// SetLongTermReference enables or disables long-term reference frames.
+	SetLongTermReference(enabled bool) error
+}
+
+// FrameReporter is an optional ReadCloser interface for encoders that delay or reorder frames, e.g.
+// x264 with lookahead and B-frames. Other encoders output the frame they read in each Read, or nothing
+// if they skipped it.
+type FrameReporter interface {
+	// EncodedFrame returns the source frame of the data the last Read returned, or false if no frame was
+	// returned.
+	EncodedFrame() (EncodedFrame, bool)
+}
+
+// EncodedFrame describes the source frame of an encoded frame.
+type EncodedFrame struct {
+	// Metadata is the source frame's metadata when the encoder read it.
+	Metadata video.Metadata
+	// PTS and DTS are the frame's presentation and decoding times from the encoder's first frame. DTS is
+	// less than PTS when frames are reordered.
+	PTS, DTS time.Duration
+}
+
 // BaseParams represents an codec's encoding properties
 type BaseParams struct {
 	// Target bitrate in bps.
@@ -130,4 +226,72 @@ type BaseParams struct {
 
 	// Expected interval of the keyframes in frames.
 	KeyFrameInterval int
+
+	// Packetization configures how the encoded data is packetized into RTP packets.
+	Packetization PacketizationParams
+
+	// ColorMatrix and ColorRange are the colorspace frames are encoded in, e.g. prop.ColorMatrixBT709 and
+	// prop.ColorRangeLimited, which encoders that support it signal to decoders. They default to
+	// the source colorspace, and frames are encoded as they are if it's unknown.
+	ColorMatrix string
+	ColorRange  string
+
+	// Content selects screen content coding tools on encoders that have them, e.g. to keep text edges sharp. The
+	// default, ContentAuto, uses them for GetDisplayMedia screens.
+	Content Content
+}
+
+// Content is the kind of frames the encoders are tuned for.
+type Content int
+
+const (
+	// ContentAuto encodes screens, i.e. sources with prop.Video.DisplaySurface, as ContentScreen, and everything
+	// else as ContentCamera.
+	ContentAuto Content = iota
+	// ContentCamera is natural camera content.
+	ContentCamera
+	// ContentScreen is screen content, e.g. text and slides, with sharp edges and repeating patterns.
+	ContentScreen
+)
+
+// ScreenContent reports whether a source with property is encoded as ContentScreen.
+func (p *BaseParams) ScreenContent(property prop.Media) bool {
+	switch p.Content {
+	case ContentCamera:
+		return false
+	case ContentScreen:
+		return true
+	}
+	return property.DisplaySurface != ""
+}
+
+// ColorSpace returns the colorspace that frames of a source with property are encoded in.
+func (p *BaseParams) ColorSpace(property prop.Media) video.ColorSpace {
+	if cs := video.ColorSpaceOf(prop.Video{ColorMatrix: p.ColorMatrix, ColorRange: p.ColorRange}); !cs.IsZero() {
+		return cs
+	}
+	return video.ColorSpaceOf(property.Video)
+}
+
+// ToI420 converts r's frames to I420 in cs for encoders, or like video.ToI420 if cs is unknown.
+// The planes are compacted, since encoders expect contiguous buffers. The metadata of r is kept, e.g. for
+// FrameClock.
+func ToI420(r video.Reader, cs video.ColorSpace) video.Reader {
+	if cs.IsZero() {
+		return video.KeepMetadata(video.Compact(video.ToI420(r)), r)
+	}
+	return video.KeepMetadata(video.Compact(video.ToI420In(cs)(r)), r)
+}
+
+// H264ColorDescription returns the H.264 and H.265 VUI colour_primaries, transfer_characteristics and
+// matrix_coefficients for cs, which are 0 if it's unknown.
+func H264ColorDescription(cs video.ColorSpace) (primaries, transfer, matrix int) {
+	switch {
+	case cs.IsZero():
+		return 0, 0, 0
+	case cs.Matrix == prop.ColorMatrixBT709:
+		return 1, 1, 1
+	}
+	// SMPTE 170M, which is BT.601 for NTSC sources
+	return 6, 6, 6
 }
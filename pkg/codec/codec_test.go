@@ -0,0 +1,24 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func TestScreenContent(t *testing.T) {
+	camera := prop.Media{}
+	screen := prop.Media{Video: prop.Video{DisplaySurface: prop.DisplaySurfaceWindow}}
+
+	testCases := map[Content][2]bool{
+		ContentAuto:   {false, true},
+		ContentCamera: {false, false},
+		ContentScreen: {true, true},
+	}
+	for content, expected := range testCases {
+		p := BaseParams{Content: content}
+		if actual := [2]bool{p.ScreenContent(camera), p.ScreenContent(screen)}; actual != expected {
+			t.Errorf("expected %v for the content %d of the camera and the screen, but got %v", expected, content, actual)
+		}
+	}
+}
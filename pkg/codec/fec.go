@@ -0,0 +1,163 @@
+package codec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Default payload types for redundancy codecs. They're chosen not to collide with the media codecs
+// defined in this package.
+const (
+	DefaultVideoREDPayloadType webrtc.PayloadType = 123
+	DefaultAudioREDPayloadType webrtc.PayloadType = 63
+	DefaultULPFECPayloadType   webrtc.PayloadType = 127
+)
+
+// Redundancy codec MIME types.
+const (
+	MimeTypeVideoRED = "video/red"
+	MimeTypeAudioRED = "audio/red"
+	MimeTypeULPFEC   = "video/ulpfec"
+)
+
+// FECMode represents a forward error correction scheme.
+type FECMode int
+
+// FECMode values.
+const (
+	// FECModeNone disables forward error correction.
+	FECModeNone FECMode = iota
+	// FECModeRED sends redundant copies of previous payloads in RED packets. It's mostly useful
+	// for audio codecs with small payloads, e.g. Opus.
+	// Reference: https://tools.ietf.org/html/rfc2198
+	FECModeRED
+	// FECModeULPFEC encapsulates media packets in RED, and sends ULPFEC packets that can recover
+	// one lost media packet per group. Only video codecs support it.
+	// Reference: https://tools.ietf.org/html/rfc5109
+	FECModeULPFEC
+)
+
+// FECParams configures forward error correction for the outgoing RTP stream.
+type FECParams struct {
+	// Mode is the FEC scheme. The default, FECModeNone, disables FEC.
+	Mode FECMode
+
+	// Overhead is the ratio of redundant to media data. In FECModeULPFEC, one FEC packet is generated
+	// per 1/Overhead media packets, e.g. 0.25 protects every 4 packets. In FECModeRED, Overhead is how
+	// many previous payloads are resent in each packet, e.g. 1 doubles the bandwidth. The default is
+	// 0.25 for FECModeULPFEC and 1 for FECModeRED.
+	Overhead float64
+
+	// REDPayloadType is the RED packets' payload type. If it's 0, DefaultVideoREDPayloadType or
+	// DefaultAudioREDPayloadType is used depending on the codec type.
+	REDPayloadType webrtc.PayloadType
+
+	// ULPFECPayloadType is the ULPFEC packets' payload type. If it's 0, DefaultULPFECPayloadType
+	// is used.
+	ULPFECPayloadType webrtc.PayloadType
+}
+
+func isAudioMimeType(mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(mimeType), "audio/")
+}
+
+// setFEC fills in p's defaults, and stores it in c
+func (c *RTPCodec) setFEC(p FECParams) {
+	if p.Mode == FECModeNone {
+		c.FEC = FECParams{}
+		return
+	}
+
+	if p.Overhead <= 0 {
+		switch p.Mode {
+		case FECModeULPFEC:
+			p.Overhead = 0.25
+		default:
+			p.Overhead = 1
+		}
+	}
+
+	if p.REDPayloadType == 0 {
+		if isAudioMimeType(c.MimeType) {
+			p.REDPayloadType = DefaultAudioREDPayloadType
+		} else {
+			p.REDPayloadType = DefaultVideoREDPayloadType
+		}
+	}
+
+	if p.ULPFECPayloadType == 0 {
+		p.ULPFECPayloadType = DefaultULPFECPayloadType
+	}
+
+	c.FEC = p
+}
+
+// RedundancyCodecs returns the codecs to negotiate along with c to send FEC packets.
+func (c *RTPCodec) RedundancyCodecs() []webrtc.RTPCodecParameters {
+	var codecs []webrtc.RTPCodecParameters
+
+	switch c.FEC.Mode {
+	case FECModeRED, FECModeULPFEC:
+		red := webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:  MimeTypeVideoRED,
+				ClockRate: c.ClockRate,
+			},
+			PayloadType: c.FEC.REDPayloadType,
+		}
+		if isAudioMimeType(c.MimeType) {
+			red.MimeType = MimeTypeAudioRED
+			red.Channels = c.Channels
+			red.SDPFmtpLine = fmt.Sprintf("%d/%d", c.PayloadType, c.PayloadType)
+		}
+		codecs = append(codecs, red)
+	}
+
+	if c.FEC.Mode == FECModeULPFEC && !isAudioMimeType(c.MimeType) {
+		codecs = append(codecs, webrtc.RTPCodecParameters{
+			RTPCodecCapability: webrtc.RTPCodecCapability{
+				MimeType:  MimeTypeULPFEC,
+				ClockRate: c.ClockRate,
+			},
+			PayloadType: c.FEC.ULPFECPayloadType,
+		})
+	}
+
+	return codecs
+}
+
+// NegotiatedFEC returns c.FEC limited to the redundancy codecs in negotiated, using their negotiated payload
+// types. FEC is disabled if RED wasn't negotiated, and ULPFEC falls back to RED if ulpfec wasn't, so the remote
+// only gets payload types it can decode.
+func (c *RTPCodec) NegotiatedFEC(negotiated []webrtc.RTPCodecParameters) FECParams {
+	p := FECParams{}
+	for _, want := range c.RedundancyCodecs() {
+		for _, got := range negotiated {
+			if !strings.EqualFold(got.MimeType, want.MimeType) {
+				continue
+			}
+			switch strings.ToLower(want.MimeType) {
+			case MimeTypeULPFEC:
+				p.ULPFECPayloadType = got.PayloadType
+			default:
+				p.REDPayloadType = got.PayloadType
+			}
+			break
+		}
+	}
+
+	switch {
+	case p.REDPayloadType == 0:
+		return FECParams{}
+	case c.FEC.Mode == FECModeULPFEC && p.ULPFECPayloadType == 0:
+		p.Mode = FECModeRED
+		// ULPFEC's overhead is a ratio, so RED uses its default
+		p.Overhead = 1
+	default:
+		p.Mode = c.FEC.Mode
+		p.Overhead = c.FEC.Overhead
+	}
+	return p
+}
@@ -0,0 +1,53 @@
+package codec
+
+import (
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// FrameClock derives encoder frame timestamps from the capture times in frame metadata. Variable frame rate
+// sources, e.g. screen captures that only deliver frames on damage, are then encoded with real timestamps, and
+// each frame gets bits for the interval it covers instead of assuming a constant frame rate.
+type FrameClock struct {
+	r       video.Reader
+	nominal time.Duration
+	now     func() time.Time
+	start   time.Time
+	last    time.Time
+}
+
+// NewFrameClock creates a FrameClock for frames read from r, which must be the encoder's reader or keep its
+// metadata, see ToI420. Frames without a capture time are spaced frameRate apart, or timed when they're encoded
+// if frameRate is 0.
+func NewFrameClock(r video.Reader, frameRate float32) *FrameClock {
+	c := &FrameClock{r: r, now: time.Now}
+	if frameRate > 0 {
+		c.nominal = time.Duration(float64(time.Second) / float64(frameRate))
+	}
+	return c
+}
+
+// Tick returns the timestamp of the frame last read from r, relative to the first frame, and the interval since
+// the previous frame, which is 0 for the first one. Call it once after each frame is read.
+func (c *FrameClock) Tick() (timestamp, interval time.Duration) {
+	m, _ := video.MetadataOf(c.r)
+	t := m.CaptureTime
+	if t.IsZero() {
+		if c.nominal > 0 && !c.start.IsZero() {
+			t = c.last.Add(c.nominal)
+		} else {
+			t = c.now()
+		}
+	}
+	if c.start.IsZero() {
+		c.start, c.last = t, t
+	}
+	if t.Before(c.last) {
+		// The capture time went backwards, e.g. the source was replaced, so continue from the last frame
+		t = c.last
+	}
+	interval = t.Sub(c.last)
+	c.last = t
+	return t.Sub(c.start), interval
+}
@@ -0,0 +1,51 @@
+package codec
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+func TestFrameClock(t *testing.T) {
+	now := time.Unix(1000, 0)
+	intervals := []time.Duration{0, 33 * time.Millisecond, 2 * time.Second, 10 * time.Millisecond}
+	var n int
+	src := video.Stamp(video.ReaderFunc(func() (image.Image, func(), error) {
+		now = now.Add(intervals[n])
+		n++
+		return image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420), func() {}, nil
+	}), func() time.Time { return now })
+
+	// Capture times survive the encoder's conversion
+	r := ToI420(src, video.ColorSpace{})
+	clock := NewFrameClock(r, 30)
+	var expected time.Duration
+	for _, interval := range intervals {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+		expected += interval
+		timestamp, d := clock.Tick()
+		if timestamp != expected || d != interval {
+			t.Fatalf("expected the timestamp %v after %v, but got %v after %v", expected, interval, timestamp, d)
+		}
+	}
+}
+
+func TestFrameClockNominal(t *testing.T) {
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		return image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420), func() {}, nil
+	})
+	clock := NewFrameClock(r, 25)
+	for i := 0; i < 3; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+		// Frames without a capture time follow the frame rate
+		if timestamp, _ := clock.Tick(); timestamp != time.Duration(i)*40*time.Millisecond {
+			t.Fatalf("expected the timestamp %v, but got %v", time.Duration(i)*40*time.Millisecond, timestamp)
+		}
+	}
+}
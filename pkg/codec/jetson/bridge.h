@@ -0,0 +1,299 @@
+// +build jetson
+
+#include <errno.h>
+#include <fcntl.h>
+#include <libv4l2.h>
+#include <linux/videodev2.h>
+#include <nvbuf_utils.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/mman.h>
+#include <v4l2_nv_extensions.h>
+
+#define NUM_OUTPUT_BUFFERS 1
+#define NUM_CAPTURE_BUFFERS 4
+#define CAPTURE_BUFFER_SIZE (2 * 1024 * 1024)
+#define NUM_PLANES 3
+
+#define CHK(__expr, __msg)                                                                                             \
+  do {                                                                                                                 \
+    if ((__expr) < 0) {                                                                                                \
+      status.code = errno;                                                                                             \
+      status.msg = __msg;                                                                                              \
+      goto CleanUp;                                                                                                    \
+    }                                                                                                                  \
+  } while (0)
+
+typedef struct Status {
+  int code;
+  const char *msg;
+} Status;
+
+typedef struct Slice {
+  uint8_t *data;
+  int len;
+} Slice;
+
+typedef struct Params {
+  const char *device;
+  int width, height;
+  uint32_t bitrate;
+  uint32_t key_frame_interval;
+  uint32_t frame_rate;
+} Params;
+
+typedef struct Buffer {
+  void *data;
+  uint32_t len;
+} Buffer;
+
+typedef struct Encoder {
+  int fd;
+  int width, height;
+  // staging is an NVMM buffer for frames in CPU memory
+  int staging;
+  Buffer capture[NUM_CAPTURE_BUFFERS];
+  int num_capture;
+  // dequeued is the index of the capture buffer the caller owns, or -1
+  int dequeued;
+} Encoder;
+
+Status enc_new(Params, Encoder *);
+Status enc_encode_dmabuf(Encoder *, int dmabuf, int force_key_frame, Slice *encoded);
+Status enc_encode(Encoder *, Slice y, Slice cb, Slice cr, int force_key_frame, Slice *encoded);
+Status enc_set_bitrate(Encoder *, uint32_t);
+Status enc_close(Encoder *);
+
+static int enc_set_ctrl(Encoder *encoder, uint32_t id, int32_t value) {
+  struct v4l2_ext_control ctrl = {0};
+  struct v4l2_ext_controls ctrls = {0};
+
+  ctrl.id = id;
+  ctrl.value = value;
+  ctrls.ctrl_class = V4L2_CTRL_CLASS_MPEG;
+  ctrls.count = 1;
+  ctrls.controls = &ctrl;
+  return v4l2_ioctl(encoder->fd, VIDIOC_S_EXT_CTRLS, &ctrls);
+}
+
+Status enc_new(Params params, Encoder *encoder) {
+  Status status = {0};
+  struct v4l2_format fmt = {0};
+  struct v4l2_streamparm parm = {0};
+  struct v4l2_requestbuffers reqbufs = {0};
+  struct v4l2_buffer buf;
+  struct v4l2_plane planes[NUM_PLANES];
+  NvBufferCreateParams staging = {0};
+  enum v4l2_buf_type type;
+  int i;
+
+  memset(encoder, 0, sizeof(Encoder));
+  encoder->dequeued = -1;
+  encoder->staging = -1;
+  encoder->width = params.width;
+  encoder->height = params.height;
+
+  encoder->fd = v4l2_open(params.device, O_RDWR);
+  CHK(encoder->fd, "Failed to open the device");
+
+  // The encoder requires the capture plane to be configured first
+  fmt.type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  fmt.fmt.pix_mp.width = params.width;
+  fmt.fmt.pix_mp.height = params.height;
+  fmt.fmt.pix_mp.pixelformat = V4L2_PIX_FMT_H264;
+  fmt.fmt.pix_mp.num_planes = 1;
+  fmt.fmt.pix_mp.plane_fmt[0].sizeimage = CAPTURE_BUFFER_SIZE;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_S_FMT, &fmt), "Failed to set the output format");
+
+  memset(&fmt, 0, sizeof(fmt));
+  fmt.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  fmt.fmt.pix_mp.width = params.width;
+  fmt.fmt.pix_mp.height = params.height;
+  fmt.fmt.pix_mp.pixelformat = V4L2_PIX_FMT_YUV420M;
+  fmt.fmt.pix_mp.num_planes = NUM_PLANES;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_S_FMT, &fmt), "Failed to set the input format");
+
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_BITRATE, params.bitrate), "Failed to set bitrate");
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_H264_PROFILE, V4L2_MPEG_VIDEO_H264_PROFILE_BASELINE),
+      "Failed to set profile");
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_GOP_SIZE, params.key_frame_interval), "Failed to set intra period");
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_IDR_INTERVAL, params.key_frame_interval), "Failed to set IDR period");
+  // Some decoders expect SPS/PPS headers to be added to every key frame
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEOENC_INSERT_SPS_PPS_AT_IDR, 1), "Failed to set inline header");
+
+  if (params.frame_rate > 0) {
+    parm.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+    parm.parm.output.timeperframe.numerator = 1;
+    parm.parm.output.timeperframe.denominator = params.frame_rate;
+    CHK(v4l2_ioctl(encoder->fd, VIDIOC_S_PARM, &parm), "Failed to set frame rate");
+  }
+
+  // Raw frames are always passed as NVMM buffers
+  reqbufs.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  reqbufs.memory = V4L2_MEMORY_DMABUF;
+  reqbufs.count = NUM_OUTPUT_BUFFERS;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_REQBUFS, &reqbufs), "Failed to request input buffers");
+
+  memset(&reqbufs, 0, sizeof(reqbufs));
+  reqbufs.type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  reqbufs.memory = V4L2_MEMORY_MMAP;
+  reqbufs.count = NUM_CAPTURE_BUFFERS;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_REQBUFS, &reqbufs), "Failed to request output buffers");
+  if (reqbufs.count > NUM_CAPTURE_BUFFERS) {
+    reqbufs.count = NUM_CAPTURE_BUFFERS;
+  }
+  for (i = 0; i < (int)reqbufs.count; i++) {
+    memset(&buf, 0, sizeof(buf));
+    memset(planes, 0, sizeof(planes));
+    buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+    buf.memory = V4L2_MEMORY_MMAP;
+    buf.index = i;
+    buf.m.planes = planes;
+    buf.length = 1;
+    CHK(v4l2_ioctl(encoder->fd, VIDIOC_QUERYBUF, &buf), "Failed to query output buffer");
+
+    encoder->capture[i].len = planes[0].length;
+    encoder->capture[i].data =
+        v4l2_mmap(NULL, planes[0].length, PROT_READ | PROT_WRITE, MAP_SHARED, encoder->fd, planes[0].m.mem_offset);
+    if (encoder->capture[i].data == MAP_FAILED) {
+      encoder->capture[i].data = NULL;
+      CHK(-1, "Failed to map output buffer");
+    }
+    encoder->num_capture++;
+    CHK(v4l2_ioctl(encoder->fd, VIDIOC_QBUF, &buf), "Failed to queue output buffer");
+  }
+
+  staging.width = params.width;
+  staging.height = params.height;
+  staging.layout = NvBufferLayout_Pitch;
+  staging.payloadType = NvBufferPayload_SurfArray;
+  staging.colorFormat = NvBufferColorFormat_YUV420;
+  staging.nvbuf_tag = NvBufferTag_VIDEO_ENC;
+  CHK(NvBufferCreateEx(&encoder->staging, &staging), "Failed to create staging buffer");
+
+  type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_STREAMON, &type), "Failed to start input stream");
+  type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_STREAMON, &type), "Failed to start output stream");
+
+CleanUp:
+
+  if (status.code != 0 && encoder->fd >= 0) {
+    enc_close(encoder);
+  }
+
+  return status;
+}
+
+// enc_encode_dmabuf encodes the NVMM buffer. The encoded data in the capture buffer is stored in encoded,
+// and it's valid until the next encode or enc_close. The encoder releases the NVMM buffer when this returns.
+Status enc_encode_dmabuf(Encoder *encoder, int dmabuf, int force_key_frame, Slice *encoded) {
+  Status status = {0};
+  struct v4l2_buffer buf = {0};
+  struct v4l2_plane planes[NUM_PLANES] = {0};
+  int i;
+
+  if (encoder->dequeued >= 0) {
+    buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+    buf.memory = V4L2_MEMORY_MMAP;
+    buf.index = encoder->dequeued;
+    buf.m.planes = planes;
+    buf.length = 1;
+    CHK(v4l2_ioctl(encoder->fd, VIDIOC_QBUF, &buf), "Failed to requeue output buffer");
+    encoder->dequeued = -1;
+  }
+
+  if (force_key_frame) {
+    CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_MFC51_VIDEO_FORCE_FRAME_TYPE, V4L2_MPEG_MFC51_VIDEO_FORCE_FRAME_TYPE_I_FRAME),
+        "Failed to force key frame");
+  }
+
+  // All planes of an NVMM buffer share the fd. bytesused has to be non-zero, otherwise it's treated as EOS.
+  memset(&buf, 0, sizeof(buf));
+  memset(planes, 0, sizeof(planes));
+  buf.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  buf.memory = V4L2_MEMORY_DMABUF;
+  buf.index = 0;
+  buf.m.planes = planes;
+  buf.length = NUM_PLANES;
+  for (i = 0; i < NUM_PLANES; i++) {
+    planes[i].m.fd = dmabuf;
+    planes[i].bytesused = 1;
+  }
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_QBUF, &buf), "Failed to queue input buffer");
+
+  memset(&buf, 0, sizeof(buf));
+  memset(planes, 0, sizeof(planes));
+  buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  buf.memory = V4L2_MEMORY_MMAP;
+  buf.m.planes = planes;
+  buf.length = 1;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_DQBUF, &buf), "Failed to dequeue output buffer");
+  encoder->dequeued = buf.index;
+  encoded->data = (uint8_t *)encoder->capture[buf.index].data;
+  encoded->len = planes[0].bytesused;
+
+  // The encoder has produced the frame, so the input buffer has been consumed
+  memset(&buf, 0, sizeof(buf));
+  memset(planes, 0, sizeof(planes));
+  buf.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  buf.memory = V4L2_MEMORY_DMABUF;
+  buf.m.planes = planes;
+  buf.length = NUM_PLANES;
+  CHK(v4l2_ioctl(encoder->fd, VIDIOC_DQBUF, &buf), "Failed to dequeue input buffer");
+
+CleanUp:
+
+  return status;
+}
+
+// enc_encode copies y, cb, cr to the staging buffer, and encodes it
+Status enc_encode(Encoder *encoder, Slice y, Slice cb, Slice cr, int force_key_frame, Slice *encoded) {
+  Status status = {0};
+  int width = encoder->width, height = encoder->height;
+
+  CHK(Raw2NvBuffer(y.data, 0, width, height, encoder->staging), "Failed to copy luma plane");
+  CHK(Raw2NvBuffer(cb.data, 1, width / 2, height / 2, encoder->staging), "Failed to copy cb plane");
+  CHK(Raw2NvBuffer(cr.data, 2, width / 2, height / 2, encoder->staging), "Failed to copy cr plane");
+
+  return enc_encode_dmabuf(encoder, encoder->staging, force_key_frame, encoded);
+
+CleanUp:
+
+  return status;
+}
+
+Status enc_set_bitrate(Encoder *encoder, uint32_t bitrate) {
+  Status status = {0};
+
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_BITRATE, bitrate), "Failed to set bitrate");
+
+CleanUp:
+
+  return status;
+}
+
+Status enc_close(Encoder *encoder) {
+  Status status = {0};
+  enum v4l2_buf_type type;
+  int i;
+
+  type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  v4l2_ioctl(encoder->fd, VIDIOC_STREAMOFF, &type);
+  type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  v4l2_ioctl(encoder->fd, VIDIOC_STREAMOFF, &type);
+
+  for (i = 0; i < encoder->num_capture; i++) {
+    v4l2_munmap(encoder->capture[i].data, encoder->capture[i].len);
+  }
+  if (encoder->staging >= 0) {
+    NvBufferDestroy(encoder->staging);
+  }
+
+  CHK(v4l2_close(encoder->fd), "Failed to close the device");
+
+CleanUp:
+
+  return status;
+}
@@ -0,0 +1,8 @@
+package jetson
+
+// DMABufImage is an image stored in an NVMM buffer, e.g. frames from pkg/driver/argus.
+// The encoder reads the buffer directly instead of copying the pixels.
+type DMABufImage interface {
+	// DMABuf returns the NVMM buffer's dmabuf fd
+	DMABuf() int
+}
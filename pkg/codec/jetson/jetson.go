@@ -0,0 +1,161 @@
+// +build jetson
+
+// Package jetson implements a hardware accelerated H264 encoder for NVIDIA Jetson.
+// Frames from pkg/driver/argus are encoded from their NVMM buffers without copying to CPU memory.
+// Other frames are copied to an NVMM buffer before encoding.
+// Building this package requires the Jetson Multimedia API, and it's only built with the jetson build tag.
+// Reference: https://docs.nvidia.com/jetson/l4t-multimedia/group__V4L2Enc.html
+package jetson
+
+// #cgo CFLAGS: -I/usr/src/jetson_multimedia_api/include
+// #cgo LDFLAGS: -L/usr/lib/aarch64-linux-gnu/tegra -lv4l2 -lnvbuf_utils
+// #include "bridge.h"
+import "C"
+import (
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+type encoder struct {
+	engine        C.Encoder
+	r             video.Reader
+	frame         image.Image
+	toI420        video.Reader
+	mu            sync.Mutex
+	closed        bool
+	forceKeyFrame bool
+}
+
+func statusToErr(status *C.Status) error {
+	return fmt.Errorf("(errno = %d) %s", int(status.code), C.GoString(status.msg))
+}
+
+func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
+	if params.KeyFrameInterval == 0 {
+		params.KeyFrameInterval = 60
+	}
+
+	if params.BitRate == 0 {
+		params.BitRate = 300000
+	}
+
+	if params.Device == "" {
+		params.Device = DefaultDevice
+	}
+
+	device := C.CString(params.Device)
+	defer C.free(unsafe.Pointer(device))
+
+	e := encoder{r: r}
+	// Only frames in CPU memory are converted, so the converter reads the current frame
+	e.toI420 = video.Compact(video.ToI420(video.ReaderFunc(func() (image.Image, func(), error) {
+		return e.frame, func() {}, nil
+	})))
+	status := C.enc_new(C.Params{
+		device:             device,
+		width:              C.int(p.Width),
+		height:             C.int(p.Height),
+		bitrate:            C.uint(params.BitRate),
+		key_frame_interval: C.uint(params.KeyFrameInterval),
+		frame_rate:         C.uint(p.FrameRate),
+	}, &e.engine)
+	if status.code != 0 {
+		return nil, statusToErr(&status)
+	}
+
+	return &e, nil
+}
+
+func (e *encoder) Read() ([]byte, func(), error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, func() {}, io.EOF
+	}
+
+	img, release, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	defer release()
+
+	var forceKeyFrame C.int
+	if e.forceKeyFrame {
+		forceKeyFrame = 1
+		e.forceKeyFrame = false
+	}
+
+	var encoded C.Slice
+	var status C.Status
+	if buf, ok := img.(DMABufImage); ok {
+		status = C.enc_encode_dmabuf(&e.engine, C.int(buf.DMABuf()), forceKeyFrame, &encoded)
+	} else {
+		e.frame = img
+		i420, _, err := e.toI420.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		imgReal := i420.(*image.YCbCr)
+		var y, cb, cr C.Slice
+		y.data = (*C.uchar)(&imgReal.Y[0])
+		y.len = C.int(len(imgReal.Y))
+		cb.data = (*C.uchar)(&imgReal.Cb[0])
+		cb.len = C.int(len(imgReal.Cb))
+		cr.data = (*C.uchar)(&imgReal.Cr[0])
+		cr.len = C.int(len(imgReal.Cr))
+		status = C.enc_encode(&e.engine, y, cb, cr, forceKeyFrame, &encoded)
+	}
+	if status.code != 0 {
+		return nil, func() {}, statusToErr(&status)
+	}
+
+	// GoBytes copies the C array to a Go slice. After this, it's safe to requeue the capture buffer
+	return C.GoBytes(unsafe.Pointer(encoded.data), encoded.len), func() {}, nil
+}
+
+func (e *encoder) SetBitRate(b int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+
+	status := C.enc_set_bitrate(&e.engine, C.uint(b))
+	if status.code != 0 {
+		return statusToErr(&status)
+	}
+	return nil
+}
+
+func (e *encoder) ForceKeyFrame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.forceKeyFrame = true
+	return nil
+}
+
+func (e *encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+	status := C.enc_close(&e.engine)
+	if status.code != 0 {
+		return statusToErr(&status)
+	}
+	return nil
+}
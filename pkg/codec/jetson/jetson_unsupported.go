@@ -0,0 +1,17 @@
+// +build !jetson
+
+package jetson
+
+import (
+	"errors"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+var errNotSupported = errors.New("jetson encoder requires the jetson build tag")
+
+func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
+	return nil, errNotSupported
+}
@@ -0,0 +1,19 @@
+// +build !jetson
+
+package jetson
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func TestBuildVideoEncoderUnsupported(t *testing.T) {
+	p, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.BuildVideoEncoder(nil, prop.Media{}); err != errNotSupported {
+		t.Fatalf("expected %v without the jetson build tag, but got %v", errNotSupported, err)
+	}
+}
@@ -0,0 +1,39 @@
+package jetson
+
+import (
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// DefaultDevice is Jetson Linux's hardware encoder
+const DefaultDevice = "/dev/nvhost-msenc"
+
+// Params stores Jetson encoder specific encoding parameters.
+type Params struct {
+	codec.BaseParams
+	// Device is the path to the encoder device
+	Device string
+}
+
+// NewParams returns default Jetson encoder specific parameters.
+func NewParams() (Params, error) {
+	return Params{
+		BaseParams: codec.BaseParams{
+			KeyFrameInterval: 60,
+		},
+		Device: DefaultDevice,
+	}, nil
+}
+
+// RTPCodec represents the codec metadata
+func (p *Params) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPH264Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
+}
+
+// BuildVideoEncoder builds Jetson encoder with given params
+func (p *Params) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
+	return newEncoder(r, property, *p)
+}
@@ -0,0 +1,20 @@
+package jetson
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestParams(t *testing.T) {
+	p, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Device != DefaultDevice || p.KeyFrameInterval != 60 {
+		t.Fatalf("expected the default device and the keyframe interval, but got %+v", p)
+	}
+	if c := p.RTPCodec(); c.MimeType != webrtc.MimeTypeH264 || c.ClockRate != 90000 {
+		t.Fatalf("expected H264 at 90kHz, but got %s at %d", c.MimeType, c.ClockRate)
+	}
+}
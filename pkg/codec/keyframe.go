@@ -0,0 +1,58 @@
+package codec
+
+import (
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// IsKeyFrame reports whether an encoded mimeType frame can be decoded without previous frames. Audio codec
+// and unknown codec frames are all keyframes.
+func IsKeyFrame(mimeType string, data []byte) bool {
+	switch {
+	case strings.EqualFold(mimeType, webrtc.MimeTypeH264):
+		return hasNALU(data, func(header byte) bool { return header&0x1f == 5 })
+	case strings.EqualFold(mimeType, MimeTypeH265):
+		// IRAP pictures
+		return hasNALU(data, func(header byte) bool { t := header >> 1 & 0x3f; return t >= 16 && t <= 21 })
+	case strings.EqualFold(mimeType, webrtc.MimeTypeVP8):
+		return len(data) > 0 && data[0]&0x01 == 0
+	case strings.EqualFold(mimeType, webrtc.MimeTypeVP9):
+		return isVP9KeyFrame(data)
+	}
+	return true
+}
+
+// hasNALU reports whether Annex B data has a NAL unit whose first header byte matches.
+func hasNALU(data []byte, match func(header byte) bool) bool {
+	for i := 0; i+3 < len(data); i++ {
+		if data[i] == 0 && data[i+1] == 0 && data[i+2] == 1 && match(data[i+3]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isVP9KeyFrame parses the frame type from a VP9 frame's uncompressed header.
+// Reference: VP9 Bitstream Specification, 6.2 Uncompressed header syntax
+func isVP9KeyFrame(data []byte) bool {
+	if len(data) == 0 || data[0]>>6 != 2 {
+		return false
+	}
+	bit := 2
+	readBit := func() byte {
+		v := data[0] >> (7 - bit) & 1
+		bit++
+		return v
+	}
+	profile := readBit() | readBit()<<1
+	if profile == 3 {
+		// reserved_zero
+		readBit()
+	}
+	if readBit() == 1 {
+		// show_existing_frame
+		return false
+	}
+	return readBit() == 0
+}
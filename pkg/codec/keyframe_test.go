@@ -0,0 +1,34 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+)
+
+func TestIsKeyFrame(t *testing.T) {
+	testCases := map[string]struct {
+		mimeType string
+		data     []byte
+		expected bool
+	}{
+		"H264IDR":     {webrtc.MimeTypeH264, []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 1, 0x65, 0x88}, true},
+		"H264NonIDR":  {webrtc.MimeTypeH264, []byte{0, 0, 0, 1, 0x41, 0x9a}, false},
+		"H265IDR":     {MimeTypeH265, []byte{0, 0, 0, 1, 0x26, 0x01}, true},
+		"H265Trail":   {MimeTypeH265, []byte{0, 0, 0, 1, 0x02, 0x01}, false},
+		"VP8Key":      {webrtc.MimeTypeVP8, []byte{0x10, 0x02}, true},
+		"VP8Inter":    {webrtc.MimeTypeVP8, []byte{0x31}, false},
+		"VP9Key":      {webrtc.MimeTypeVP9, []byte{0x82}, true},
+		"VP9Inter":    {webrtc.MimeTypeVP9, []byte{0x86}, false},
+		"VP9Profile3": {webrtc.MimeTypeVP9, []byte{0xb0}, true},
+		"Opus":        {webrtc.MimeTypeOpus, []byte{0xfc}, true},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			if IsKeyFrame(testCase.mimeType, testCase.data) != testCase.expected {
+				t.Fatalf("expected %v, but got %v", testCase.expected, !testCase.expected)
+			}
+		})
+	}
+}
@@ -41,7 +41,7 @@ func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser,
 	}
 
 	e := encoder{
-		r: video.ToI420(r),
+		r: video.Compact(video.ToI420(r)),
 	}
 	status := C.enc_new(C.Params{
 		width:              C.int(p.Width),
@@ -91,12 +91,16 @@ func (e *encoder) Read() ([]byte, func(), error) {
 	return encoded, func() {}, err
 }
 
+// errNotImplemented is returned by controls mmal doesn't support yet, so track adaptation
+// is skipped instead of crashing the application.
+var errNotImplemented = fmt.Errorf("not implemented")
+
 func (e *encoder) SetBitRate(b int) error {
-	panic("SetBitRate is not implemented")
+	return errNotImplemented
 }
 
 func (e *encoder) ForceKeyFrame() error {
-	panic("ForceKeyFrame is not implemented")
+	return errNotImplemented
 }
 
 func (e *encoder) Close() error {
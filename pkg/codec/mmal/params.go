@@ -22,7 +22,9 @@ func NewParams() (Params, error) {
 
 // RTPCodec represents the codec metadata
 func (p *Params) RTPCodec() *codec.RTPCodec {
-	return codec.NewRTPH264Codec(90000)
+	c := codec.NewRTPH264Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
 }
 
 // BuildVideoEncoder builds mmal encoder with given params
@@ -22,7 +22,7 @@ Encoder *enc_new(const EncoderOptions opts, int *eresult) {
   }
 
   // TODO: Remove hardcoded values
-  params.iUsageType = CAMERA_VIDEO_REAL_TIME;
+  params.iUsageType = opts.screen_content ? SCREEN_CONTENT_REAL_TIME : CAMERA_VIDEO_REAL_TIME;
   params.iPicWidth = opts.width;
   params.iPicHeight = opts.height;
   params.iTargetBitrate = opts.target_bitrate;
@@ -31,7 +31,12 @@ Encoder *enc_new(const EncoderOptions opts, int *eresult) {
   params.fMaxFrameRate = opts.max_fps;
   params.bEnableFrameSkip = true;
   params.uiMaxNalSize = 0;
-  params.uiIntraPeriod = 30;
+  params.uiIntraPeriod = opts.key_frame_interval;
+  // Frames in the higher temporal layers are not referred by the lower layers
+  params.iTemporalLayerNum = opts.temporal_layers;
+  // The number of the long-term reference frames can't be changed from 2 yet
+  params.bEnableLongTermReference = opts.long_term_reference;
+  params.iLTRRefNum = opts.long_term_reference ? 2 : 0;
   // set to 0, so that it'll automatically use multi threads when needed
   params.iMultipleThreadIdc = 0;
   // The base spatial layer 0 is the only one we use.
@@ -44,6 +49,15 @@ Encoder *enc_new(const EncoderOptions opts, int *eresult) {
   params.sSpatialLayers[0].sSliceArgument.uiSliceNum = 1;
   params.sSpatialLayers[0].sSliceArgument.uiSliceMode = SM_SIZELIMITED_SLICE;
   params.sSpatialLayers[0].sSliceArgument.uiSliceSizeConstraint = 12800;
+  if (opts.color_matrix != 0) {
+    params.sSpatialLayers[0].bVideoSignalTypePresent = true;
+    params.sSpatialLayers[0].uiVideoFormat = VF_UNDEF;
+    params.sSpatialLayers[0].bFullRange = opts.full_range;
+    params.sSpatialLayers[0].bColorDescriptionPresent = true;
+    params.sSpatialLayers[0].uiColorPrimaries = opts.color_primaries;
+    params.sSpatialLayers[0].uiTransferCharacteristics = opts.transfer_characteristics;
+    params.sSpatialLayers[0].uiColorMatrix = opts.color_matrix;
+  }
 
   rv = engine->InitializeExt(&params);
   if (rv != 0) {
@@ -56,6 +70,7 @@ Encoder *enc_new(const EncoderOptions opts, int *eresult) {
   encoder->params = params;
   encoder->buff = (unsigned char *)malloc(opts.width * opts.height);
   encoder->buff_size = opts.width * opts.height;
+  encoder->force_key_frame = 0;
   return encoder;
 }
 
@@ -72,6 +87,35 @@ void enc_free(Encoder *e, int *eresult) {
   free(e);
 }
 
+// copy_layers concatenates the NAL units of all layers into the encoder's buffer.
+static Slice copy_layers(Encoder *e, SFrameBSInfo *info) {
+  Slice payload = {0};
+  int *layer_size = (int *)calloc(sizeof(int), info->iLayerNum);
+  int size = 0;
+  for (int layer = 0; layer < info->iLayerNum; layer++) {
+    for (int i = 0; i < info->sLayerInfo[layer].iNalCount; i++)
+      layer_size[layer] += info->sLayerInfo[layer].pNalLengthInByte[i];
+
+    size += layer_size[layer];
+  }
+
+  if (e->buff_size < size) {
+    free(e->buff);
+    e->buff = (unsigned char *)malloc(size);
+    e->buff_size = size;
+  }
+  size = 0;
+  for (int layer = 0; layer < info->iLayerNum; layer++) {
+    memcpy(e->buff + size, info->sLayerInfo[layer].pBsBuf, layer_size[layer]);
+    size += layer_size[layer];
+  }
+  free(layer_size);
+
+  payload.data = e->buff;
+  payload.data_len = size;
+  return payload;
+}
+
 // There's a good reference from ffmpeg in using the encode_frame
 // Reference: https://ffmpeg.org/doxygen/2.6/libopenh264enc_8c_source.html
 Slice enc_encode(Encoder *e, Frame f, int *eresult) {
@@ -80,8 +124,12 @@ Slice enc_encode(Encoder *e, Frame f, int *eresult) {
   SFrameBSInfo info = {0};
   Slice payload = {0};
 
-  if(e->force_key_frame == 1) {
-    info.eFrameType = videoFrameTypeI;
+  if (e->force_key_frame == 1) {
+    rv = e->engine->ForceIntraFrame(true);
+    if (rv != 0) {
+      *eresult = rv;
+      return payload;
+    }
     e->force_key_frame = 0;
   }
 
@@ -101,27 +149,101 @@ Slice enc_encode(Encoder *e, Frame f, int *eresult) {
     return payload;
   }
 
-  int *layer_size = (int *)calloc(sizeof(int), info.iLayerNum);
-  int size = 0;
-  for (int layer = 0; layer < info.iLayerNum; layer++) {
-    for (int i = 0; i < info.sLayerInfo[layer].iNalCount; i++)
-      layer_size[layer] += info.sLayerInfo[layer].pNalLengthInByte[i];
+  return copy_layers(e, &info);
+}
 
-    size += layer_size[layer];
+Slice enc_encode_parameter_sets(Encoder *e, int *eresult) {
+  SFrameBSInfo info = {0};
+  Slice payload = {0};
+
+  int rv = e->engine->EncodeParameterSets(&info);
+  if (rv != 0) {
+    *eresult = rv;
+    return payload;
   }
+  return copy_layers(e, &info);
+}
 
-  if (e->buff_size < size) {
-    e->buff = (unsigned char *)malloc(size);
-    e->buff_size = size;
+void enc_set_bitrate(Encoder *e, int bitrate, int *eresult) {
+  int rv;
+  SBitrateInfo info;
+
+  info.iLayer = SPATIAL_LAYER_ALL;
+  info.iBitrate = bitrate;
+  rv = e->engine->SetOption(ENCODER_OPTION_BITRATE, &info);
+  if (rv != 0) {
+    *eresult = rv;
+    return;
   }
-  size = 0;
-  for (int layer = 0; layer < info.iLayerNum; layer++) {
-    memcpy(e->buff + size, info.sLayerInfo[layer].pBsBuf, layer_size[layer]);
-    size += layer_size[layer];
+
+  // The maximum bitrate has to follow the target, since it's set to the same value at the initialization
+  rv = e->engine->SetOption(ENCODER_OPTION_MAX_BITRATE, &info);
+  if (rv != 0) {
+    *eresult = rv;
+    return;
   }
-  free(layer_size);
+  e->params.iTargetBitrate = bitrate;
+  e->params.iMaxBitrate = bitrate;
+}
 
-  payload.data = e->buff;
-  payload.data_len = size;
-  return payload;
+// enc_reset changes the resolution and the frame rate of the encoder in place. The encoder resets itself when the
+// resolution changes, so that the next frame is an IDR frame with the new SPS and PPS.
+void enc_reset(Encoder *e, int width, int height, float max_fps, int *eresult) {
+  int rv;
+  SEncParamExt params = e->params;
+
+  params.iPicWidth = width;
+  params.iPicHeight = height;
+  if (max_fps > 0)
+    params.fMaxFrameRate = max_fps;
+  params.sSpatialLayers[0].iVideoWidth = params.iPicWidth;
+  params.sSpatialLayers[0].iVideoHeight = params.iPicHeight;
+  params.sSpatialLayers[0].fFrameRate = params.fMaxFrameRate;
+  rv = e->engine->SetOption(ENCODER_OPTION_SVC_ENCODE_PARAM_EXT, &params);
+  if (rv != 0) {
+    *eresult = rv;
+    return;
+  }
+  e->params = params;
+
+  // The frame rate alone doesn't reset the encoder
+  rv = e->engine->ForceIntraFrame(true);
+  if (rv != 0) {
+    *eresult = rv;
+    return;
+  }
+  e->force_key_frame = 0;
+}
+
+void enc_set_long_term_reference(Encoder *e, int enabled, int *eresult) {
+  SLTRConfig config;
+
+  config.bEnableLongTermReference = enabled;
+  config.iLTRRefNum = enabled ? 2 : 0;
+  int rv = e->engine->SetOption(ENCODER_OPTION_LTR, &config);
+  if (rv != 0) {
+    *eresult = rv;
+    return;
+  }
+  e->params.bEnableLongTermReference = config.bEnableLongTermReference;
+  e->params.iLTRRefNum = config.iLTRRefNum;
+}
+
+void enc_get_stats(Encoder *e, EncoderStats *stats, int *eresult) {
+  int rv;
+  SEncoderStatistics s = {0};
+
+  rv = e->engine->GetOption(ENCODER_OPTION_GET_STATISTICS, &s);
+  if (rv != 0) {
+    *eresult = rv;
+    return;
+  }
+
+  stats->input_frames = s.uiInputFrameCount;
+  stats->skipped_frames = s.uiSkippedFrameCount;
+  stats->idr_requests = s.uiIDRReqNum;
+  stats->idr_sent = s.uiIDRSentNum;
+  stats->bitrate = s.uiBitRate;
+  stats->average_qp = s.uiAverageFrameQP;
+  stats->average_fps = s.fAverageFrameRate;
 }
@@ -20,8 +20,25 @@ typedef struct EncoderOptions {
   int width, height;
   int target_bitrate;
   float max_fps;
+  int key_frame_interval;
+  int temporal_layers;
+  int long_term_reference;
+  int screen_content;
+  // The VUI of the colorspace, which isn't written if color_matrix is 0
+  int full_range;
+  int color_primaries, transfer_characteristics, color_matrix;
 } EncoderOptions;
 
+typedef struct EncoderStats {
+  unsigned int input_frames;
+  unsigned int skipped_frames;
+  unsigned int idr_requests;
+  unsigned int idr_sent;
+  unsigned int bitrate;
+  unsigned int average_qp;
+  float average_fps;
+} EncoderStats;
+
 typedef struct Encoder {
   SEncParamExt params;
   ISVCEncoder *engine;
@@ -33,6 +50,11 @@ typedef struct Encoder {
 Encoder *enc_new(const EncoderOptions params, int *eresult);
 void enc_free(Encoder *e, int *eresult);
 Slice enc_encode(Encoder *e, Frame f, int *eresult);
+Slice enc_encode_parameter_sets(Encoder *e, int *eresult);
+void enc_set_bitrate(Encoder *e, int bitrate, int *eresult);
+void enc_reset(Encoder *e, int width, int height, float max_fps, int *eresult);
+void enc_set_long_term_reference(Encoder *e, int enabled, int *eresult);
+void enc_get_stats(Encoder *e, EncoderStats *stats, int *eresult);
 #ifdef __cplusplus
 }
 #endif
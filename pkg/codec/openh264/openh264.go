@@ -7,6 +7,7 @@ package openh264
 import "C"
 
 import (
+	"errors"
 	"fmt"
 	"image"
 	"io"
@@ -18,9 +19,35 @@ import (
 	"github.com/pion/mediadevices/pkg/prop"
 )
 
+const maxTemporalLayers = 4
+
+// Stats represents encoder statistics.
+type Stats struct {
+	// InputFrames is the number of frames passed to the encoder.
+	InputFrames int
+	// SkippedFrames is the number of frames skipped by rate control.
+	SkippedFrames int
+	// IDRRequests is the number of key frame requests, and IDRSent is the number of encoded key frames.
+	IDRRequests int
+	IDRSent     int
+	// BitRate is the output bitrate in bps within the rate control window.
+	BitRate int
+	// AverageQP is the last encoded frame's average QP.
+	AverageQP int
+	// AverageFrameRate is the input frame rate since the encoder started.
+	AverageFrameRate float32
+}
+
+// StatsReader is implemented by encoders built by Params. Stats can be read by asserting
+// codec.ReadCloser to StatsReader.
+type StatsReader interface {
+	Stats() (Stats, error)
+}
+
 type encoder struct {
 	engine *C.Encoder
 	r      video.Reader
+	out    codec.BufferPool
 
 	mu     sync.Mutex
 	closed bool
@@ -30,13 +57,46 @@ func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser,
 	if params.BitRate == 0 {
 		params.BitRate = 100000
 	}
+	if params.KeyFrameInterval == 0 {
+		params.KeyFrameInterval = 30
+	}
+	if params.TemporalLayers == 0 {
+		params.TemporalLayers = 1
+	}
+	if params.TemporalLayers < 1 || params.TemporalLayers > maxTemporalLayers {
+		return nil, errors.New("the number of temporal layers must be between 1 and 4")
+	}
+
+	cs := params.ColorSpace(p)
+	primaries, transfer, matrix := codec.H264ColorDescription(cs)
+	var fullRange C.int
+	if cs.Range == prop.ColorRangeFull {
+		fullRange = 1
+	}
+
+	var longTermReference C.int
+	if params.LongTermReference {
+		longTermReference = 1
+	}
+	var screenContent C.int
+	if params.ScreenContent(p) {
+		screenContent = 1
+	}
 
 	var rv C.int
 	cEncoder := C.enc_new(C.EncoderOptions{
-		width:          C.int(p.Width),
-		height:         C.int(p.Height),
-		target_bitrate: C.int(params.BitRate),
-		max_fps:        C.float(p.FrameRate),
+		width:                    C.int(p.Width),
+		height:                   C.int(p.Height),
+		target_bitrate:           C.int(params.BitRate),
+		max_fps:                  C.float(p.FrameRate),
+		key_frame_interval:       C.int(params.KeyFrameInterval),
+		temporal_layers:          C.int(params.TemporalLayers),
+		long_term_reference:      longTermReference,
+		screen_content:           screenContent,
+		full_range:               fullRange,
+		color_primaries:          C.int(primaries),
+		transfer_characteristics: C.int(transfer),
+		color_matrix:             C.int(matrix),
 	}, &rv)
 	if err := errResult(rv); err != nil {
 		return nil, fmt.Errorf("failed in creating encoder: %v", err)
@@ -44,7 +104,7 @@ func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser,
 
 	return &encoder{
 		engine: cEncoder,
-		r:      video.ToI420(r),
+		r:      codec.ToI420(r, cs),
 	}, nil
 }
 
@@ -56,10 +116,11 @@ func (e *encoder) Read() ([]byte, func(), error) {
 		return nil, func() {}, io.EOF
 	}
 
-	img, _, err := e.r.Read()
+	img, releaseImg, err := e.r.Read()
 	if err != nil {
 		return nil, func() {}, err
 	}
+	defer releaseImg()
 
 	yuvImg := img.(*image.YCbCr)
 	bounds := yuvImg.Bounds()
@@ -75,12 +136,110 @@ func (e *encoder) Read() ([]byte, func(), error) {
 		return nil, func() {}, fmt.Errorf("failed in encoding: %v", err)
 	}
 
-	encoded := C.GoBytes(unsafe.Pointer(s.data), s.data_len)
-	return encoded, func() {}, nil
+	encoded, release := e.out.Get(int(s.data_len))
+	if len(encoded) > 0 {
+		copy(encoded, (*[1 << 30]byte)(unsafe.Pointer(s.data))[:len(encoded):len(encoded)])
+	}
+	return encoded, release, nil
+}
+
+// Prepare implements codec.Preparer. It returns SPS and PPS so they can be sent before the first frame.
+func (e *encoder) Prepare() ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, io.EOF
+	}
+
+	var rv C.int
+	s := C.enc_encode_parameter_sets(e.engine, &rv)
+	if err := errResult(rv); err != nil {
+		return nil, fmt.Errorf("failed in encoding parameter sets: %v", err)
+	}
+
+	return C.GoBytes(unsafe.Pointer(s.data), s.data_len), nil
 }
 
 func (e *encoder) SetBitRate(b int) error {
-	panic("SetBitRate is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+
+	var rv C.int
+	C.enc_set_bitrate(e.engine, C.int(b), &rv)
+	if err := errResult(rv); err != nil {
+		return fmt.Errorf("failed in setting bitrate: %v", err)
+	}
+	return nil
+}
+
+// Reset implements codec.Resetter. It resets the encoder in place to the resolution and frame rate of p, and the
+// next frame is an IDR frame with new SPS and PPS.
+func (e *encoder) Reset(p prop.Media) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+
+	var rv C.int
+	C.enc_reset(e.engine, C.int(p.Width), C.int(p.Height), C.float(p.FrameRate), &rv)
+	if err := errResult(rv); err != nil {
+		return fmt.Errorf("failed in resetting: %v", err)
+	}
+	return nil
+}
+
+// SetLongTermReference implements codec.LongTermReferencer.
+func (e *encoder) SetLongTermReference(enabled bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+
+	var cEnabled C.int
+	if enabled {
+		cEnabled = 1
+	}
+	var rv C.int
+	C.enc_set_long_term_reference(e.engine, cEnabled, &rv)
+	if err := errResult(rv); err != nil {
+		return fmt.Errorf("failed in setting long-term reference: %v", err)
+	}
+	return nil
+}
+
+func (e *encoder) Stats() (Stats, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return Stats{}, io.EOF
+	}
+
+	var rv C.int
+	var s C.EncoderStats
+	C.enc_get_stats(e.engine, &s, &rv)
+	if err := errResult(rv); err != nil {
+		return Stats{}, fmt.Errorf("failed in getting statistics: %v", err)
+	}
+
+	return Stats{
+		InputFrames:      int(s.input_frames),
+		SkippedFrames:    int(s.skipped_frames),
+		IDRRequests:      int(s.idr_requests),
+		IDRSent:          int(s.idr_sent),
+		BitRate:          int(s.bitrate),
+		AverageQP:        int(s.average_qp),
+		AverageFrameRate: float32(s.average_fps),
+	}, nil
 }
 
 func (e *encoder) ForceKeyFrame() error {
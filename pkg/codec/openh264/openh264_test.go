@@ -0,0 +1,372 @@
+package openh264
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func newTestEncoder(t *testing.T, params Params) codec.ReadCloser {
+	img := image.NewYCbCr(image.Rect(0, 0, 320, 240), image.YCbCrSubsampleRatio420)
+	e, err := params.BuildVideoEncoder(
+		video.ReaderFunc(func() (image.Image, func(), error) {
+			return img, func() {}, nil
+		}),
+		prop.Media{
+			Video: prop.Video{
+				Width:       320,
+				Height:      240,
+				FrameRate:   30,
+				FrameFormat: frame.FormatI420,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRuntimeControl(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TemporalLayers = 2
+	// Keep rate control from skipping frames
+	params.BitRate = 1000000
+
+	e := newTestEncoder(t, params)
+	defer e.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, _, err := e.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.SetBitRate(500000); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if err := e.ForceKeyFrame(); err != nil {
+		t.Fatalf("expected no error, but got %v", err)
+	}
+	if _, _, err := e.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := e.(StatsReader).Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.InputFrames != 5 {
+		t.Fatalf("expected 5 input frames, but got %d", stats.InputFrames)
+	}
+	if stats.IDRSent < 2 {
+		t.Fatalf("expected at least 2 IDR frames, but got %d", stats.IDRSent)
+	}
+}
+
+func TestTemporalLayersValidation(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TemporalLayers = 5
+
+	_, err = params.BuildVideoEncoder(
+		video.ReaderFunc(func() (image.Image, func(), error) {
+			return nil, func() {}, nil
+		}),
+		prop.Media{Video: prop.Video{Width: 320, Height: 240, FrameRate: 30}},
+	)
+	if err == nil {
+		t.Fatal("expected an error with 5 temporal layers")
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newTestEncoder(t, params)
+	defer e.Close()
+
+	config, err := e.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The configuration must be SPS followed by PPS, with start codes
+	var nalTypes []byte
+	for i := 0; i+3 < len(config); i++ {
+		if config[i] == 0 && config[i+1] == 0 && config[i+2] == 1 {
+			nalTypes = append(nalTypes, config[i+3]&0x1f)
+		}
+	}
+	if len(nalTypes) != 2 || nalTypes[0] != 7 || nalTypes[1] != 8 {
+		t.Fatalf("expected SPS and PPS, but got NAL unit types %v", nalTypes)
+	}
+
+	if _, _, err := e.Read(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) u(n int) int {
+	var v int
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(r.data[r.pos/8]>>(7-uint(r.pos%8))&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) ue() int {
+	zeros := 0
+	for r.u(1) == 0 {
+		zeros++
+	}
+	return 1<<uint(zeros) - 1 + r.u(zeros)
+}
+
+// videoSignalType parses config's baseline profile SPS up to the VUI video signal type.
+func videoSignalType(t *testing.T, config []byte) (present bool, fullRange, matrix int) {
+	start := -1
+	for i := 0; i+3 < len(config); i++ {
+		if config[i] == 0 && config[i+1] == 0 && config[i+2] == 1 && config[i+3]&0x1f == 7 {
+			start = i + 4
+			break
+		}
+	}
+	if start < 0 {
+		t.Fatal("expected SPS in the configuration")
+	}
+	// Removes emulation prevention bytes
+	var sps []byte
+	for i := start; i < len(config); i++ {
+		if i >= start+2 && config[i] == 3 && config[i-1] == 0 && config[i-2] == 0 {
+			continue
+		}
+		sps = append(sps, config[i])
+	}
+
+	r := &bitReader{data: sps}
+	if profile := r.u(8); profile != 66 {
+		t.Fatalf("expected the baseline profile, but got %d", profile)
+	}
+	r.u(16)
+	r.ue()
+	r.ue()
+	if pocType := r.ue(); pocType == 0 {
+		r.ue()
+	}
+	r.ue()
+	r.u(1)
+	r.ue()
+	r.ue()
+	if frameMbsOnly := r.u(1); frameMbsOnly == 0 {
+		r.u(1)
+	}
+	r.u(1)
+	if cropping := r.u(1); cropping == 1 {
+		r.ue()
+		r.ue()
+		r.ue()
+		r.ue()
+	}
+	if vui := r.u(1); vui == 0 {
+		return false, 0, 0
+	}
+	if aspectRatio := r.u(1); aspectRatio == 1 {
+		if r.u(8) == 255 {
+			r.u(32)
+		}
+	}
+	if overscan := r.u(1); overscan == 1 {
+		r.u(1)
+	}
+	if r.u(1) == 0 {
+		return false, 0, 0
+	}
+	r.u(3)
+	fullRange = r.u(1)
+	if r.u(1) == 0 {
+		return true, fullRange, 0
+	}
+	r.u(16)
+	return true, fullRange, r.u(8)
+}
+
+func TestColorSpace(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := newTestEncoder(t, params)
+	config, err := e.(codec.Preparer).Prepare()
+	e.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if present, _, _ := videoSignalType(t, config); present {
+		t.Fatal("expected no video signal type without the colorspace")
+	}
+
+	params.ColorMatrix = prop.ColorMatrixBT709
+	params.ColorRange = prop.ColorRangeFull
+	e = newTestEncoder(t, params)
+	defer e.Close()
+	config, err = e.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	present, fullRange, matrix := videoSignalType(t, config)
+	if !present || fullRange != 1 || matrix != 1 {
+		t.Fatalf("expected the full range of BT.709, but got present=%v, full_range=%d, matrix=%d", present, fullRange, matrix)
+	}
+	if _, _, err := e.Read(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReset(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.BitRate = 1000000
+
+	img := image.NewYCbCr(image.Rect(0, 0, 320, 240), image.YCbCrSubsampleRatio420)
+	e, err := params.BuildVideoEncoder(
+		video.ReaderFunc(func() (image.Image, func(), error) {
+			return img, func() {}, nil
+		}),
+		prop.Media{Video: prop.Video{Width: 320, Height: 240, FrameRate: 30, FrameFormat: frame.FormatI420}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := e.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := e.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img = image.NewYCbCr(image.Rect(0, 0, 160, 120), image.YCbCrSubsampleRatio420)
+	if err := e.(codec.Resetter).Reset(prop.Media{Video: prop.Video{Width: 160, Height: 120, FrameRate: 15}}); err != nil {
+		t.Fatalf("failed to reset the encoder: %s", err)
+	}
+	data, _, err := e.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first frame after a reset must be an IDR frame with new parameter sets
+	var nalTypes []byte
+	for i := 0; i+3 < len(data); i++ {
+		if data[i] == 0 && data[i+1] == 0 && data[i+2] == 1 {
+			nalTypes = append(nalTypes, data[i+3]&0x1f)
+		}
+	}
+	if !bytes.Contains(nalTypes, []byte{7}) || !bytes.Contains(nalTypes, []byte{5}) {
+		t.Fatalf("expected SPS and an IDR frame, but got NAL unit types %v", nalTypes)
+	}
+	newConfig, err := e.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(config, newConfig) {
+		t.Fatal("expected the parameter sets of the new resolution")
+	}
+}
+
+func TestLongTermReference(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.BitRate = 1000000
+
+	e := newTestEncoder(t, params)
+	defer e.Close()
+	config, err := e.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params.LongTermReference = true
+	ltr := newTestEncoder(t, params)
+	defer ltr.Close()
+	ltrConfig, err := ltr.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Long-term references raise max_num_ref_frames in the SPS
+	if bytes.Equal(config, ltrConfig) {
+		t.Fatal("expected the parameter sets with the long-term reference frames")
+	}
+
+	for _, enabled := range []bool{false, true} {
+		if err := ltr.(codec.LongTermReferencer).SetLongTermReference(enabled); err != nil {
+			t.Fatalf("expected no error, but got %v", err)
+		}
+		for i := 0; i < 30; i++ {
+			if _, _, err := ltr.Read(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestScreenContent(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.BitRate = 1000000
+	// Screen content coding uses long-term references differently
+	params.LongTermReference = true
+
+	e := newTestEncoder(t, params)
+	defer e.Close()
+	config, err := e.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params.Content = codec.ContentScreen
+	screen := newTestEncoder(t, params)
+	defer screen.Close()
+	screenConfig, err := screen.(codec.Preparer).Prepare()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(config, screenConfig) {
+		t.Fatal("expected the parameter sets of the screen content")
+	}
+	for i := 0; i < 30; i++ {
+		if _, _, err := screen.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -9,20 +9,32 @@ import (
 // Params stores libopenh264 specific encoding parameters.
 type Params struct {
 	codec.BaseParams
+
+	// TemporalLayers is the number of temporal scalability layers, from 1 to 4. Lower layers don't refer to
+	// frames in higher layers, so receivers or SFUs can drop those frames to lower the frame rate.
+	TemporalLayers int
+
+	// LongTermReference enables long-term reference frames, see codec.LongTermReferencer. It can also be toggled
+	// while encoding. openh264 always keeps 2 long-term reference frames.
+	LongTermReference bool
 }
 
 // NewParams returns default openh264 codec specific parameters.
 func NewParams() (Params, error) {
 	return Params{
 		BaseParams: codec.BaseParams{
-			BitRate: 100000,
+			BitRate:          100000,
+			KeyFrameInterval: 30,
 		},
+		TemporalLayers: 1,
 	}, nil
 }
 
 // RTPCodec represents the codec metadata
 func (p *Params) RTPCodec() *codec.RTPCodec {
-	return codec.NewRTPH264Codec(90000)
+	c := codec.NewRTPH264Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
 }
 
 // BuildVideoEncoder builds openh264 encoder with given params
@@ -25,6 +25,7 @@ type encoder struct {
 	inBuff wave.Audio
 	reader audio.Reader
 	engine *C.OpusEncoder
+	out    codec.BufferPool
 }
 
 func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
@@ -48,8 +49,17 @@ func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser,
 
 	channels := p.ChannelCount
 
+	// The encoder only supports Opus bandwidth sampling rates, so others, e.g. 44.1kHz or 96kHz, are resampled
+	// to fullband.
+	sampleRate := p.SampleRate
+	rResample := func(r audio.Reader) audio.Reader { return r }
+	if !isSupportedSampleRate(sampleRate) {
+		sampleRate = 48000
+		rResample = audio.NewResampler(sampleRate)
+	}
+
 	engine := C.opus_encoder_create(
-		C.opus_int32(p.SampleRate),
+		C.opus_int32(sampleRate),
 		C.int(channels),
 		C.OPUS_APPLICATION_VOIP,
 		&cerror,
@@ -58,11 +68,13 @@ func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser,
 		return nil, errors.New("failed to create encoder engine")
 	}
 
+	// The encoder and buffer only support interleaved Int16 and Float32.
+	rConv := audio.NewConverter(false, wave.TypeFloat32Interleaved, wave.TypeInt16Interleaved)
 	rMix := audio.NewChannelMixer(channels, params.ChannelMixer)
-	rBuf := audio.NewBuffer(params.Latency.samples(p.SampleRate))
+	rBuf := audio.NewBuffer(params.Latency.samples(sampleRate))
 	e := encoder{
 		engine: engine,
-		reader: rMix(rBuf(r)),
+		reader: rMix(rBuf(rConv(rResample(r)))),
 	}
 
 	err := e.SetBitRate(params.BitRate)
@@ -73,13 +85,23 @@ func newEncoder(r audio.Reader, p prop.Media, params Params) (codec.ReadCloser,
 	return &e, nil
 }
 
+func isSupportedSampleRate(sampleRate int) bool {
+	switch sampleRate {
+	case 8000, 12000, 16000, 24000, 48000:
+		return true
+	default:
+		return false
+	}
+}
+
 func (e *encoder) Read() ([]byte, func(), error) {
-	buff, _, err := e.reader.Read()
+	buff, releaseBuff, err := e.reader.Read()
 	if err != nil {
 		return nil, func() {}, err
 	}
+	defer releaseBuff()
 
-	encoded := make([]byte, 1024)
+	encoded, release := e.out.Get(1024)
 	var n C.opus_int32
 	switch b := buff.(type) {
 	case *wave.Int16Interleaved:
@@ -105,8 +127,12 @@ func (e *encoder) Read() ([]byte, func(), error) {
 	if n < 0 {
 		err = errors.New("failed to encode")
 	}
+	if err != nil {
+		release()
+		return nil, func() {}, err
+	}
 
-	return encoded[:n:n], func() {}, err
+	return encoded[:n:n], release, nil
 }
 
 func (e *encoder) SetBitRate(bitRate int) error {
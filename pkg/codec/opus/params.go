@@ -48,7 +48,9 @@ type Params struct {
 	// ChannelMixer is a mixer to be used if number of given and expected channels differ.
 	ChannelMixer mixer.ChannelMixer
 
-	// Expected latency of the codec.
+	// Latency is the frame duration, which is also the RTP packets' packet time (ptime).
+	// 10ms reduces latency for intercoms, and 60ms reduces packet overhead on limited bandwidth.
+	// Chunks of any size from the source are repacketized to this duration.
 	Latency Latency
 }
 
@@ -63,6 +65,7 @@ func NewParams() (Params, error) {
 func (p *Params) RTPCodec() *codec.RTPCodec {
 	c := codec.NewRTPOpusCodec(48000)
 	c.Latency = time.Duration(p.Latency)
+	c.SetPacketization(p.Packetization)
 	return c
 }
 
@@ -0,0 +1,545 @@
+package codec
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// PacketizationParams represents how encoded frames are packetized into RTP packets.
+// The zero value keeps the default packetization of the codec.
+type PacketizationParams struct {
+	// MTU is the maximum RTP payload size in bytes. If it's 0, the track's default MTU is used.
+	MTU int
+
+	// H264Mode is the H264 packetization-mode that's announced in SDP and used to packetize NAL units.
+	H264Mode H264PacketizationMode
+
+	// H264STAPA aggregates consecutive small NAL units, e.g. SPS and PPS, into one STAP-A packet.
+	// It's only used in non-interleaved mode.
+	H264STAPA bool
+
+	// PictureID configures the picture ID field in the VP8/VP9 payload descriptor.
+	PictureID PictureIDMode
+
+	// FEC configures forward error correction for outgoing packets. FEC is disabled by default.
+	FEC FECParams
+}
+
+// H264PacketizationMode represents H264 packetization-mode.
+// Reference: https://tools.ietf.org/html/rfc6184#section-5.2
+type H264PacketizationMode int
+
+// H264PacketizationMode values.
+const (
+	// H264PacketizationNonInterleaved allows FU-A fragmentation and STAP-A aggregation (packetization-mode=1).
+	H264PacketizationNonInterleaved H264PacketizationMode = iota
+	// H264PacketizationSingleNAL sends every NAL unit in its own packet (packetization-mode=0). NAL units
+	// larger than the MTU are not fragmented, so the encoder is expected to limit the slice size.
+	H264PacketizationSingleNAL
+)
+
+// SDPValue returns the packetization-mode value that's used in the SDP fmtp line.
+func (m H264PacketizationMode) SDPValue() int {
+	switch m {
+	case H264PacketizationSingleNAL:
+		return 0
+	default:
+		return 1
+	}
+}
+
+// PictureIDMode represents the size of the picture ID in VP8/VP9 payload descriptors.
+type PictureIDMode int
+
+// PictureIDMode values.
+const (
+	// PictureIDDefault keeps the payloader's default behaviour.
+	PictureIDDefault PictureIDMode = iota
+	// PictureIDNone omits the picture ID. VP9 requires a picture ID in flexible mode, so a
+	// 15-bit picture ID is used for VP9 instead.
+	PictureIDNone
+	// PictureID7Bit uses a 7-bit picture ID.
+	PictureID7Bit
+	// PictureID15Bit uses a 15-bit picture ID.
+	PictureID15Bit
+)
+
+// SetPacketization configures the codec's payloader and SDP parameters to follow p.
+func (c *RTPCodec) SetPacketization(p PacketizationParams) {
+	c.MTU = p.MTU
+	c.setFEC(p.FEC)
+
+	switch strings.ToLower(c.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeH264):
+		if p.H264Mode == H264PacketizationNonInterleaved && !p.H264STAPA {
+			return
+		}
+
+		c.Payloader = &h264Payloader{mode: p.H264Mode, stapA: p.H264STAPA}
+		c.SDPFmtpLine = strings.Replace(c.SDPFmtpLine, "packetization-mode=1",
+			fmt.Sprintf("packetization-mode=%d", p.H264Mode.SDPValue()), 1)
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		if p.PictureID != PictureIDDefault {
+			c.Payloader = newVP8Payloader(p.PictureID)
+		}
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		if p.PictureID != PictureIDDefault {
+			c.Payloader = newVP9Payloader(p.PictureID)
+		}
+	}
+}
+
+const (
+	h264NALUTypeMask  = 0x1F
+	h264NALURefMask   = 0x60
+	h264NALUTypeAUD   = 9
+	h264NALUTypeFill  = 12
+	h264NALUTypeSTAPA = 24
+	h264NALUTypeFUA   = 28
+
+	h264FUAHeaderSize      = 2
+	h264STAPAHeaderSize    = 1
+	h264STAPALengthSize    = 2
+	h264STAPAMaxNALUSize   = 0xFFFF
+	h264FUAStartBit        = 0x80
+	h264FUAEndBit          = 0x40
+	h265NALUHeaderSize     = 2
+	h265NALUTypeAUD        = 35
+	h265NALUTypeFU         = 49
+	h265FUHeaderSize       = 3
+	vp8DescriptorSize      = 1
+	vp9DescriptorSize      = 1
+	pictureIDMask7Bit      = 0x7F
+	pictureIDMask15Bit     = 0x7FFF
+	pictureIDExtendedBit   = 0x80
+	vp8ExtendedControlBit  = 0x80
+	vp8StartOfPartitionBit = 0x10
+	vp8PictureIDPresentBit = 0x80
+	vp8TL0PicIdxPresentBit = 0x40
+	vp8TIDPresentBit       = 0x20
+	vp8LayerSyncBit        = 0x20
+	vp9PictureIDPresentBit = 0x80
+	vp9LayerIndicesBit     = 0x20
+	vp9SwitchingUpBit      = 0x10
+	vp9FlexibleModeBit     = 0x10
+	vp9StartOfFrameBit     = 0x08
+	vp9EndOfFrameBit       = 0x04
+)
+
+// h264Payloader packetizes H264 Annex B streams with a configurable packetization mode
+type h264Payloader struct {
+	mode  H264PacketizationMode
+	stapA bool
+}
+
+// splitNALUs splits an Annex B stream into NAL units without start codes
+func splitNALUs(stream []byte) [][]byte {
+	var nalus [][]byte
+	start := -1
+	zeros := 0
+	for i, b := range stream {
+		switch {
+		case b == 0:
+			zeros++
+			continue
+		case b == 1 && zeros >= 2:
+			if start >= 0 {
+				nalus = append(nalus, stream[start:i-zeros])
+			}
+			start = i + 1
+		}
+		zeros = 0
+	}
+
+	if start < 0 {
+		// No start code found, so the whole stream is one NAL unit
+		return [][]byte{stream}
+	}
+
+	return append(nalus, stream[start:])
+}
+
+func (p *h264Payloader) Payload(mtu int, payload []byte) [][]byte {
+	var payloads [][]byte
+	var aggregated [][]byte
+
+	flushAggregated := func() {
+		switch len(aggregated) {
+		case 0:
+			return
+		case 1:
+			payloads = append(payloads, aggregated[0])
+		default:
+			var nri byte
+			size := h264STAPAHeaderSize
+			for _, nalu := range aggregated {
+				size += h264STAPALengthSize + len(nalu)
+				if ref := nalu[0] & h264NALURefMask; ref > nri {
+					nri = ref
+				}
+			}
+
+			out := make([]byte, h264STAPAHeaderSize, size)
+			out[0] = h264NALUTypeSTAPA | nri
+			for _, nalu := range aggregated {
+				var length [h264STAPALengthSize]byte
+				binary.BigEndian.PutUint16(length[:], uint16(len(nalu)))
+				out = append(out, length[:]...)
+				out = append(out, nalu...)
+			}
+			payloads = append(payloads, out)
+		}
+		aggregated = nil
+	}
+
+	aggregatedSize := h264STAPAHeaderSize
+	for _, nalu := range splitNALUs(payload) {
+		if len(nalu) == 0 {
+			continue
+		}
+
+		naluType := nalu[0] & h264NALUTypeMask
+		if naluType == h264NALUTypeAUD || naluType == h264NALUTypeFill {
+			continue
+		}
+
+		if p.mode == H264PacketizationSingleNAL {
+			out := make([]byte, len(nalu))
+			copy(out, nalu)
+			payloads = append(payloads, out)
+			continue
+		}
+
+		if p.stapA {
+			needed := h264STAPALengthSize + len(nalu)
+			if aggregatedSize+needed > mtu {
+				flushAggregated()
+				aggregatedSize = h264STAPAHeaderSize
+			}
+
+			if aggregatedSize+needed <= mtu && len(nalu) <= h264STAPAMaxNALUSize {
+				out := make([]byte, len(nalu))
+				copy(out, nalu)
+				aggregated = append(aggregated, out)
+				aggregatedSize += needed
+				continue
+			}
+		}
+
+		if len(nalu) <= mtu {
+			out := make([]byte, len(nalu))
+			copy(out, nalu)
+			payloads = append(payloads, out)
+			continue
+		}
+
+		payloads = append(payloads, fragmentFUA(mtu, nalu)...)
+	}
+
+	flushAggregated()
+	return payloads
+}
+
+// fragmentFUA fragments nalu into FU-A packets.
+// Reference: https://tools.ietf.org/html/rfc6184#section-5.8
+func fragmentFUA(mtu int, nalu []byte) [][]byte {
+	maxFragmentSize := mtu - h264FUAHeaderSize
+	if maxFragmentSize <= 0 {
+		return nil
+	}
+
+	var payloads [][]byte
+	naluType := nalu[0] & h264NALUTypeMask
+	naluRef := nalu[0] & h264NALURefMask
+	// The first octet is skipped since it's conveyed in the FU indicator and FU header
+	data := nalu[1:]
+	for i := 0; i < len(data); i += maxFragmentSize {
+		end := i + maxFragmentSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		out := make([]byte, h264FUAHeaderSize+end-i)
+		out[0] = h264NALUTypeFUA | naluRef
+		out[1] = naluType
+		if i == 0 {
+			out[1] |= h264FUAStartBit
+		}
+		if end == len(data) {
+			out[1] |= h264FUAEndBit
+		}
+		copy(out[h264FUAHeaderSize:], data[i:end])
+		payloads = append(payloads, out)
+	}
+
+	return payloads
+}
+
+// h265Payloader packetizes H265 Annex B streams into single NAL unit packets and fragmentation units.
+// Reference: https://tools.ietf.org/html/rfc7798#section-4.4
+type h265Payloader struct{}
+
+func (p *h265Payloader) Payload(mtu int, payload []byte) [][]byte {
+	var payloads [][]byte
+	for _, nalu := range splitNALUs(payload) {
+		if len(nalu) < h265NALUHeaderSize {
+			continue
+		}
+		if (nalu[0]>>1)&0x3F == h265NALUTypeAUD {
+			continue
+		}
+
+		if len(nalu) <= mtu {
+			out := make([]byte, len(nalu))
+			copy(out, nalu)
+			payloads = append(payloads, out)
+			continue
+		}
+
+		payloads = append(payloads, fragmentH265FU(mtu, nalu)...)
+	}
+	return payloads
+}
+
+// fragmentH265FU fragments nalu into FU packets.
+// Reference: https://tools.ietf.org/html/rfc7798#section-4.4.3
+func fragmentH265FU(mtu int, nalu []byte) [][]byte {
+	maxFragmentSize := mtu - h265FUHeaderSize
+	if maxFragmentSize <= 0 {
+		return nil
+	}
+
+	var payloads [][]byte
+	naluType := (nalu[0] >> 1) & 0x3F
+	// The NAL unit header is carried in the payload header and FU header
+	data := nalu[h265NALUHeaderSize:]
+	for i := 0; i < len(data); i += maxFragmentSize {
+		end := i + maxFragmentSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		out := make([]byte, h265FUHeaderSize+end-i)
+		// Keep F bit, LayerId and TID, and replace the type
+		out[0] = nalu[0]&0x81 | h265NALUTypeFU<<1
+		out[1] = nalu[1]
+		out[2] = naluType
+		if i == 0 {
+			out[2] |= h264FUAStartBit
+		}
+		if end == len(data) {
+			out[2] |= h264FUAEndBit
+		}
+		copy(out[h265FUHeaderSize:], data[i:end])
+		payloads = append(payloads, out)
+	}
+
+	return payloads
+}
+
+// pictureIDSize returns the picture ID field's size in bytes
+func pictureIDSize(mode PictureIDMode) int {
+	switch mode {
+	case PictureID7Bit:
+		return 1
+	case PictureID15Bit:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// putPictureID writes pictureID to b. b must be large enough to hold the field.
+func putPictureID(b []byte, mode PictureIDMode, pictureID uint16) {
+	switch mode {
+	case PictureID7Bit:
+		b[0] = byte(pictureID & pictureIDMask7Bit)
+	case PictureID15Bit:
+		b[0] = pictureIDExtendedBit | byte((pictureID>>8)&pictureIDMask7Bit)
+		b[1] = byte(pictureID)
+	}
+}
+
+func nextPictureID(mode PictureIDMode, pictureID uint16) uint16 {
+	if mode == PictureID7Bit {
+		return (pictureID + 1) & pictureIDMask7Bit
+	}
+	return (pictureID + 1) & pictureIDMask15Bit
+}
+
+// vp8Payloader packetizes VP8 frames with a configurable payload descriptor.
+// Reference: https://tools.ietf.org/html/rfc7741#section-4.2
+type vp8Payloader struct {
+	mode      PictureIDMode
+	pictureID uint16
+	// layers, if set, signals temporal layers in TL0PICIDX and TID.
+	layers *temporalLayers
+}
+
+func newVP8Payloader(mode PictureIDMode) *vp8Payloader {
+	return &vp8Payloader{
+		mode:      mode,
+		pictureID: uint16(rand.Intn(pictureIDMask15Bit)),
+	}
+}
+
+func (p *vp8Payloader) Payload(mtu int, payload []byte) [][]byte {
+	idSize := pictureIDSize(p.mode)
+	headerSize := vp8DescriptorSize
+	if idSize > 0 || p.layers != nil {
+		// Extended control bits and picture ID
+		headerSize += 1 + idSize
+	}
+	if p.layers != nil {
+		// TL0PICIDX, TID and Y
+		headerSize += 2
+	}
+
+	maxFragmentSize := mtu - headerSize
+	if maxFragmentSize <= 0 || len(payload) == 0 {
+		return nil
+	}
+
+	var layer TemporalLayer
+	var tl0PicIdx uint8
+	if p.layers != nil {
+		layer, tl0PicIdx = p.layers.next(payload[0]&0x01 == 0)
+	}
+
+	var payloads [][]byte
+	for i := 0; i < len(payload); i += maxFragmentSize {
+		end := i + maxFragmentSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+
+		out := make([]byte, headerSize+end-i)
+		if i == 0 {
+			out[0] = vp8StartOfPartitionBit
+		}
+		if headerSize > vp8DescriptorSize {
+			out[0] |= vp8ExtendedControlBit
+			if idSize > 0 {
+				out[1] = vp8PictureIDPresentBit
+				putPictureID(out[2:], p.mode, p.pictureID)
+			}
+		}
+		if p.layers != nil {
+			out[1] |= vp8TL0PicIdxPresentBit | vp8TIDPresentBit
+			out[2+idSize] = tl0PicIdx
+			out[3+idSize] = byte(layer.ID) << 6
+			if layer.Sync {
+				out[3+idSize] |= vp8LayerSyncBit
+			}
+		}
+		copy(out[headerSize:], payload[i:end])
+		payloads = append(payloads, out)
+	}
+
+	p.pictureID = nextPictureID(p.mode, p.pictureID)
+	return payloads
+}
+
+// vp9Payloader packetizes VP9 frames in flexible mode with a configurable picture ID.
+// Reference: https://datatracker.ietf.org/doc/html/draft-ietf-payload-vp9-10#section-4.2
+type vp9Payloader struct {
+	mode      PictureIDMode
+	pictureID uint16
+	// layers, if set, signals temporal layers in the layer indices.
+	layers *temporalLayers
+}
+
+func newVP9Payloader(mode PictureIDMode) *vp9Payloader {
+	if mode != PictureID7Bit {
+		mode = PictureID15Bit
+	}
+
+	return &vp9Payloader{
+		mode:      mode,
+		pictureID: uint16(rand.Intn(pictureIDMask15Bit)),
+	}
+}
+
+func (p *vp9Payloader) Payload(mtu int, payload []byte) [][]byte {
+	idSize := pictureIDSize(p.mode)
+	headerSize := vp9DescriptorSize + idSize
+	if p.layers != nil {
+		headerSize++
+	}
+	maxFragmentSize := mtu - headerSize
+	if maxFragmentSize <= 0 || len(payload) == 0 {
+		return nil
+	}
+
+	var layerIndices byte
+	if p.layers != nil {
+		// TID, U, SID and D. There's a single spatial layer, so frames never depend on another one
+		layer, _ := p.layers.next(isVP9KeyFrame(payload))
+		layerIndices = byte(layer.ID) << 5
+		if !p.layers.top(layer) {
+			// Higher layers only refer to this frame and later ones
+			layerIndices |= vp9SwitchingUpBit
+		}
+	}
+
+	var payloads [][]byte
+	for i := 0; i < len(payload); i += maxFragmentSize {
+		end := i + maxFragmentSize
+		if end > len(payload) {
+			end = len(payload)
+		}
+
+		out := make([]byte, headerSize+end-i)
+		out[0] = vp9PictureIDPresentBit | vp9FlexibleModeBit
+		if i == 0 {
+			out[0] |= vp9StartOfFrameBit
+		}
+		if end == len(payload) {
+			out[0] |= vp9EndOfFrameBit
+		}
+		putPictureID(out[vp9DescriptorSize:], p.mode, p.pictureID)
+		if p.layers != nil {
+			out[0] |= vp9LayerIndicesBit
+			out[vp9DescriptorSize+idSize] = layerIndices
+		}
+		copy(out[headerSize:], payload[i:end])
+		payloads = append(payloads, out)
+	}
+
+	p.pictureID = nextPictureID(p.mode, p.pictureID)
+	return payloads
+}
+
+// aacAUHeaderSize is the size of AU-headers-length plus one AU header with a 13-bit AU-size and 3-bit AU-Index.
+const aacAUHeaderSize = 4
+
+// aacPayloader packetizes AAC frames in AAC-hbr mode. Each packet carries one frame, or a fragment of a frame
+// larger than mtu, with an AU header giving the size of the whole frame.
+// Reference: https://tools.ietf.org/html/rfc3640#section-3.3.6
+type aacPayloader struct{}
+
+func (p *aacPayloader) Payload(mtu int, payload []byte) [][]byte {
+	maxFragmentSize := mtu - aacAUHeaderSize
+	if len(payload) == 0 || maxFragmentSize <= 0 {
+		return nil
+	}
+
+	header := []byte{0x00, 0x10, byte(len(payload) >> 5), byte(len(payload)&0x1f) << 3}
+	var payloads [][]byte
+	for len(payload) > 0 {
+		n := len(payload)
+		if n > maxFragmentSize {
+			n = maxFragmentSize
+		}
+		out := make([]byte, 0, aacAUHeaderSize+n)
+		out = append(out, header...)
+		out = append(out, payload[:n]...)
+		payloads = append(payloads, out)
+		payload = payload[n:]
+	}
+	return payloads
+}
@@ -0,0 +1,296 @@
+package codec
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestH264Payloader(t *testing.T) {
+	sps := []byte{0x67, 0x42, 0x00, 0x1f}
+	pps := []byte{0x68, 0xce, 0x3c, 0x80}
+	idr := append([]byte{0x65}, bytes.Repeat([]byte{0xAA}, 20)...)
+	aud := []byte{0x09, 0xf0}
+
+	var stream []byte
+	for _, nalu := range [][]byte{aud, sps, pps, idr} {
+		stream = append(stream, 0x00, 0x00, 0x00, 0x01)
+		stream = append(stream, nalu...)
+	}
+
+	t.Run("SingleNAL", func(t *testing.T) {
+		p := &h264Payloader{mode: H264PacketizationSingleNAL}
+		payloads := p.Payload(10, stream)
+		expected := [][]byte{sps, pps, idr}
+		if !reflect.DeepEqual(payloads, expected) {
+			t.Fatalf("expected %v, but got %v", expected, payloads)
+		}
+	})
+
+	t.Run("STAPA", func(t *testing.T) {
+		p := &h264Payloader{stapA: true}
+		payloads := p.Payload(13, stream)
+		if len(payloads) < 2 {
+			t.Fatalf("expected at least 2 payloads, but got %d", len(payloads))
+		}
+
+		stapA := []byte{0x78, 0x00, 0x04}
+		stapA = append(stapA, sps...)
+		stapA = append(stapA, 0x00, 0x04)
+		stapA = append(stapA, pps...)
+		if !bytes.Equal(payloads[0], stapA) {
+			t.Fatalf("expected the first payload to be STAP-A %v, but got %v", stapA, payloads[0])
+		}
+
+		// The IDR is larger than the MTU, so it has to be split into FU-A packets
+		var reassembled []byte
+		for i, payload := range payloads[1:] {
+			if len(payload) > 13 {
+				t.Fatalf("payload size %d exceeds mtu", len(payload))
+			}
+			if payload[0]&h264NALUTypeMask != h264NALUTypeFUA {
+				t.Fatalf("expected FU-A, but got type %d", payload[0]&h264NALUTypeMask)
+			}
+			if start := payload[1]&h264FUAStartBit != 0; start != (i == 0) {
+				t.Fatalf("unexpected start bit in fragment %d", i)
+			}
+			if end := payload[1]&h264FUAEndBit != 0; end != (i == len(payloads)-2) {
+				t.Fatalf("unexpected end bit in fragment %d", i)
+			}
+			reassembled = append(reassembled, payload[2:]...)
+		}
+
+		if !bytes.Equal(reassembled, idr[1:]) {
+			t.Fatalf("expected reassembled nalu to be %v, but got %v", idr[1:], reassembled)
+		}
+	})
+}
+
+func TestH265Payloader(t *testing.T) {
+	vps := []byte{0x40, 0x01, 0x0c}
+	aud := []byte{0x46, 0x01, 0x50}
+	idr := append([]byte{0x26, 0x01}, bytes.Repeat([]byte{0xAA}, 20)...)
+
+	var stream []byte
+	for _, nalu := range [][]byte{aud, vps, idr} {
+		stream = append(stream, 0x00, 0x00, 0x00, 0x01)
+		stream = append(stream, nalu...)
+	}
+
+	payloads := (&h265Payloader{}).Payload(10, stream)
+	if len(payloads) < 2 {
+		t.Fatalf("expected at least 2 payloads, but got %d", len(payloads))
+	}
+	if !bytes.Equal(payloads[0], vps) {
+		t.Fatalf("expected the first payload to be VPS %v, but got %v", vps, payloads[0])
+	}
+
+	var reassembled []byte
+	for i, payload := range payloads[1:] {
+		if len(payload) > 10 {
+			t.Fatalf("payload size %d exceeds mtu", len(payload))
+		}
+		if typ := (payload[0] >> 1) & 0x3F; typ != h265NALUTypeFU {
+			t.Fatalf("expected FU, but got type %d", typ)
+		}
+		if typ := payload[2] & 0x3F; typ != 19 {
+			t.Fatalf("expected fragmented type 19, but got %d", typ)
+		}
+		if start := payload[2]&h264FUAStartBit != 0; start != (i == 0) {
+			t.Fatalf("unexpected start bit in fragment %d", i)
+		}
+		if end := payload[2]&h264FUAEndBit != 0; end != (i == len(payloads)-2) {
+			t.Fatalf("unexpected end bit in fragment %d", i)
+		}
+		reassembled = append(reassembled, payload[3:]...)
+	}
+
+	if !bytes.Equal(reassembled, idr[2:]) {
+		t.Fatalf("expected reassembled nalu to be %v, but got %v", idr[2:], reassembled)
+	}
+}
+
+func TestAACPayloader(t *testing.T) {
+	frame := bytes.Repeat([]byte{0xAA}, 300)
+
+	payloads := (&aacPayloader{}).Payload(1200, frame)
+	if len(payloads) != 1 {
+		t.Fatalf("expected 1 payload, but got %d", len(payloads))
+	}
+	// 16-bit AU-headers-length, then AU-size 300 with AU-Index 0
+	if header := payloads[0][:4]; !bytes.Equal(header, []byte{0x00, 0x10, 0x09, 0x60}) {
+		t.Fatalf("expected the AU header of the size 300, but got %v", header)
+	}
+	if !bytes.Equal(payloads[0][4:], frame) {
+		t.Fatal("expected the frame after the AU header")
+	}
+
+	payloads = (&aacPayloader{}).Payload(104, frame)
+	if len(payloads) != 3 {
+		t.Fatalf("expected 3 fragments, but got %d", len(payloads))
+	}
+	var reassembled []byte
+	for _, payload := range payloads {
+		if len(payload) > 104 {
+			t.Fatalf("payload size %d exceeds mtu", len(payload))
+		}
+		if !bytes.Equal(payload[:4], payloads[0][:4]) {
+			t.Fatal("expected the fragments to have the AU header of the whole frame")
+		}
+		reassembled = append(reassembled, payload[4:]...)
+	}
+	if !bytes.Equal(reassembled, frame) {
+		t.Fatal("expected the fragments to be reassembled to the frame")
+	}
+}
+
+func TestSetPacketization(t *testing.T) {
+	c := NewRTPH264Codec(90000)
+	c.SetPacketization(PacketizationParams{MTU: 1000, H264Mode: H264PacketizationSingleNAL})
+	if c.MTU != 1000 {
+		t.Fatalf("expected mtu to be 1000, but got %d", c.MTU)
+	}
+	if !strings.Contains(c.SDPFmtpLine, "packetization-mode=0") {
+		t.Fatalf("expected packetization-mode=0 in fmtp line, but got %s", c.SDPFmtpLine)
+	}
+	if _, ok := c.Payloader.(*h264Payloader); !ok {
+		t.Fatalf("expected payloader to be replaced, but got %T", c.Payloader)
+	}
+
+	c = NewRTPVP8Codec(90000)
+	defaultPayloader := c.Payloader
+	c.SetPacketization(PacketizationParams{})
+	if c.Payloader != defaultPayloader {
+		t.Fatal("expected default payloader to be kept with the zero value params")
+	}
+}
+
+func TestVPXPayloaderPictureID(t *testing.T) {
+	payload := bytes.Repeat([]byte{0x01}, 10)
+
+	testCases := map[string]struct {
+		payloads   func() [][]byte
+		headerSize int
+	}{
+		"VP8None": {
+			payloads:   func() [][]byte { return newVP8Payloader(PictureIDNone).Payload(6, payload) },
+			headerSize: 1,
+		},
+		"VP87Bit": {
+			payloads:   func() [][]byte { return newVP8Payloader(PictureID7Bit).Payload(6, payload) },
+			headerSize: 3,
+		},
+		"VP815Bit": {
+			payloads:   func() [][]byte { return newVP8Payloader(PictureID15Bit).Payload(6, payload) },
+			headerSize: 4,
+		},
+		"VP97Bit": {
+			payloads:   func() [][]byte { return newVP9Payloader(PictureID7Bit).Payload(6, payload) },
+			headerSize: 2,
+		},
+		"VP9None": {
+			payloads:   func() [][]byte { return newVP9Payloader(PictureIDNone).Payload(6, payload) },
+			headerSize: 3,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			var total int
+			for _, p := range testCase.payloads() {
+				if len(p) > 6 {
+					t.Fatalf("payload size %d exceeds mtu", len(p))
+				}
+				total += len(p) - testCase.headerSize
+			}
+			if total != len(payload) {
+				t.Fatalf("expected %d bytes of payload, but got %d", len(payload), total)
+			}
+		})
+	}
+}
+
+func TestVPXPayloaderTemporalLayers(t *testing.T) {
+	vp8KeyFrame := []byte{0x10, 0x02, 0x00, 0x9d, 0x01, 0x2a}
+	vp8Frame := []byte{0x11, 0x02, 0x00}
+	// frame_marker, profile 0, show_existing_frame and frame_type
+	vp9KeyFrame := []byte{0x82, 0x49, 0x83, 0x42}
+	vp9Frame := []byte{0x86, 0x00}
+
+	testCases := map[string]struct {
+		codec    *RTPCodec
+		keyFrame []byte
+		frame    []byte
+		// layer returns the descriptor's TID and sync bit
+		layer func(t *testing.T, p []byte) (int, bool)
+	}{
+		"VP8": {
+			codec:    NewRTPVP8Codec(90000),
+			keyFrame: vp8KeyFrame,
+			frame:    vp8Frame,
+			layer: func(t *testing.T, p []byte) (int, bool) {
+				if p[0]&vp8ExtendedControlBit == 0 || p[1] != vp8PictureIDPresentBit|vp8TL0PicIdxPresentBit|vp8TIDPresentBit {
+					t.Fatalf("expected the picture ID, TL0PICIDX and TID, but got %x", p[:2])
+				}
+				return int(p[5] >> 6), p[5]&vp8LayerSyncBit != 0
+			},
+		},
+		"VP9": {
+			codec:    NewRTPVP9Codec(90000),
+			keyFrame: vp9KeyFrame,
+			frame:    vp9Frame,
+			layer: func(t *testing.T, p []byte) (int, bool) {
+				if p[0]&vp9LayerIndicesBit == 0 {
+					t.Fatalf("expected the layer indices, but got %x", p[0])
+				}
+				return int(p[3] >> 5), p[3]&vp9SwitchingUpBit != 0
+			},
+		},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			c := testCase.codec
+			c.SetTemporalLayers(3)
+
+			expected := []int{0, 2, 1, 2, 0, 2, 0, 2}
+			expectedSync := []bool{false, true, true, false, false, true, false, true}
+			var layers []int
+			var tl0PicIdx []byte
+			for i := range expected {
+				frame := testCase.frame
+				if i == 0 || i == 6 {
+					// The pattern restarts at each keyframe
+					frame = testCase.keyFrame
+				}
+				payloads := c.Payload(1200, frame)
+				if len(payloads) != 1 {
+					t.Fatalf("expected 1 payload, but got %d", len(payloads))
+				}
+				tid, sync := testCase.layer(t, payloads[0])
+				if name == "VP8" {
+					if sync != expectedSync[i] {
+						t.Errorf("expected the layer sync bit of the frame %d to be %v", i, expectedSync[i])
+					}
+					tl0PicIdx = append(tl0PicIdx, payloads[0][4])
+				}
+				layers = append(layers, tid)
+			}
+			if !reflect.DeepEqual(layers, expected) {
+				t.Fatalf("expected the layers %v, but got %v", expected, layers)
+			}
+			if name == "VP8" && (tl0PicIdx[1] != tl0PicIdx[0] || tl0PicIdx[4] != tl0PicIdx[0]+1) {
+				t.Fatalf("expected TL0PICIDX to increase at the base layer frames, but got %v", tl0PicIdx)
+			}
+		})
+	}
+
+	c := NewRTPVP8Codec(90000)
+	defaultPayloader := c.Payloader
+	c.SetTemporalLayers(1)
+	if c.Payloader != defaultPayloader {
+		t.Fatal("expected default payloader to be kept with a single layer")
+	}
+}
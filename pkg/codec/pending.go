@@ -0,0 +1,69 @@
+package codec
+
+import (
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// maxPendingFrames is the maximum number of frames PendingFrames keeps. It's more than encoders
+// delay, e.g. 250 frames of x264 lookahead and its B-frames.
+const maxPendingFrames = 300
+
+// PendingFrames keeps a value for each frame a wrapped encoder reads, e.g. its content hash, until the
+// encoder outputs the frame. A FrameReporter's frames are matched by sequence number. Other
+// encoders output the last frame they read, so older frames were skipped. The zero value is ready to use,
+// and it's safe for concurrent use.
+type PendingFrames struct {
+	mu     sync.Mutex
+	frames []pendingFrame
+}
+
+type pendingFrame struct {
+	sequence uint64
+	value    interface{}
+}
+
+// Push adds the value for m's frame, which the encoder read. The oldest frame is dropped when there
+// are too many frames.
+func (p *PendingFrames) Push(m video.Metadata, value interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.frames) == maxPendingFrames {
+		copy(p.frames, p.frames[1:])
+		p.frames = p.frames[:len(p.frames)-1]
+	}
+	p.frames = append(p.frames, pendingFrame{sequence: m.Sequence, value: value})
+}
+
+// Pop returns the value for data's frame, which encoder's last Read returned. It returns false if
+// data doesn't have a frame, or its frame wasn't pushed.
+func (p *PendingFrames) Pop(encoder ReadCloser, data []byte) (interface{}, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if reporter, ok := encoder.(FrameReporter); ok {
+		f, ok := reporter.EncodedFrame()
+		if !ok || len(data) == 0 {
+			return nil, false
+		}
+		for i, pending := range p.frames {
+			if pending.sequence == f.Metadata.Sequence {
+				p.frames = append(p.frames[:i], p.frames[i+1:]...)
+				return pending.value, true
+			}
+		}
+		return nil, false
+	}
+
+	if len(p.frames) == 0 {
+		return nil, false
+	}
+	last := p.frames[len(p.frames)-1]
+	p.frames = p.frames[:0]
+	if len(data) == 0 {
+		// The frame was skipped
+		return nil, false
+	}
+	return last.value, true
+}
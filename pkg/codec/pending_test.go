@@ -0,0 +1,65 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+type reportingEncoder struct {
+	ReadCloser
+	frame EncodedFrame
+	ok    bool
+}
+
+func (e *reportingEncoder) EncodedFrame() (EncodedFrame, bool) {
+	return e.frame, e.ok
+}
+
+func TestPendingFrames(t *testing.T) {
+	var p PendingFrames
+	p.Push(video.Metadata{Sequence: 0}, 0)
+	p.Push(video.Metadata{Sequence: 1}, 1)
+	// Encoders without FrameReporter output the last frame, so older frames were skipped
+	if v, ok := p.Pop(nil, []byte{1}); !ok || v != 1 {
+		t.Fatalf("expected the last frame, but got %v", v)
+	}
+	p.Push(video.Metadata{Sequence: 2}, 2)
+	if _, ok := p.Pop(nil, nil); ok {
+		t.Fatal("expected no frame with the empty data")
+	}
+	if _, ok := p.Pop(nil, []byte{1}); ok {
+		t.Fatal("expected the skipped frame to be dropped")
+	}
+
+	e := &reportingEncoder{}
+	for i := 3; i < 6; i++ {
+		p.Push(video.Metadata{Sequence: uint64(i)}, i)
+	}
+	if _, ok := p.Pop(e, nil); ok {
+		t.Fatal("expected no frame while the frames are delayed")
+	}
+	e.frame, e.ok = EncodedFrame{Metadata: video.Metadata{Sequence: 5}}, true
+	if v, ok := p.Pop(e, []byte{1}); !ok || v != 5 {
+		t.Fatalf("expected the frame 5, but got %v", v)
+	}
+	e.frame.Metadata.Sequence = 3
+	if v, ok := p.Pop(e, []byte{1}); !ok || v != 3 {
+		t.Fatalf("expected the frame 3, but got %v", v)
+	}
+	if _, ok := p.Pop(e, []byte{1}); ok {
+		t.Fatal("expected the frame 3 to be popped once")
+	}
+
+	for i := 0; i < maxPendingFrames+10; i++ {
+		p.Push(video.Metadata{Sequence: uint64(100 + i)}, i)
+	}
+	if len(p.frames) != maxPendingFrames {
+		t.Fatalf("expected %d frames, but got %d", maxPendingFrames, len(p.frames))
+	}
+	// Frame 4 was dropped with the oldest frames
+	e.frame.Metadata.Sequence = 4
+	if _, ok := p.Pop(e, []byte{1}); ok {
+		t.Fatal("expected the oldest frame to be dropped")
+	}
+}
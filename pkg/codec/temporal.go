@@ -0,0 +1,97 @@
+package codec
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// MaxTemporalLayers is the most layers TemporalLayerPattern supports.
+const MaxTemporalLayers = 3
+
+// TemporalLayer is one frame of a temporal layer pattern.
+type TemporalLayer struct {
+	// ID is the frame's temporal layer, where 0 is the base layer.
+	ID int
+	// Sync is set if the frame only refers to the base layer, so receivers can switch up to its layer there.
+	Sync bool
+}
+
+// TemporalLayerPattern returns the repeating frame pattern for the given number of temporal layers, or nil if
+// layers isn't between 1 and MaxTemporalLayers. With 3 layers the pattern is 0, 2, 1, 2, and each layer doubles
+// the frame rate. Base layer frames refer to the previous base frame, middle layer frames refer to the base layer,
+// and nothing refers to top layer frames, so SFUs drop those first.
+func TemporalLayerPattern(layers int) []TemporalLayer {
+	switch layers {
+	case 1:
+		return []TemporalLayer{{ID: 0}}
+	case 2:
+		return []TemporalLayer{{ID: 0}, {ID: 1, Sync: true}}
+	case 3:
+		return []TemporalLayer{{ID: 0}, {ID: 2, Sync: true}, {ID: 1, Sync: true}, {ID: 2}}
+	}
+	return nil
+}
+
+// SetTemporalLayers makes the VP8/VP9 payloader signal TemporalLayerPattern(layers) in the payload descriptors.
+// The encoder must follow the pattern without dropping frames, and keyframes must start a pattern, since the
+// payloader restarts it on each keyframe.
+func (c *RTPCodec) SetTemporalLayers(layers int) {
+	pattern := TemporalLayerPattern(layers)
+	if len(pattern) <= 1 {
+		return
+	}
+
+	switch strings.ToLower(c.MimeType) {
+	case strings.ToLower(webrtc.MimeTypeVP8):
+		p, ok := c.Payloader.(*vp8Payloader)
+		if !ok {
+			// SFUs need picture IDs to detect losses in the layers they forward
+			p = newVP8Payloader(PictureID15Bit)
+		}
+		p.layers = newTemporalLayers(layers, pattern)
+		c.Payloader = p
+	case strings.ToLower(webrtc.MimeTypeVP9):
+		p, ok := c.Payloader.(*vp9Payloader)
+		if !ok {
+			p = newVP9Payloader(PictureIDDefault)
+		}
+		p.layers = newTemporalLayers(layers, pattern)
+		c.Payloader = p
+	}
+}
+
+// temporalLayers tracks the position in the pattern as frames are encoded.
+type temporalLayers struct {
+	layers    int
+	pattern   []TemporalLayer
+	index     int
+	tl0PicIdx uint8
+}
+
+func newTemporalLayers(layers int, pattern []TemporalLayer) *temporalLayers {
+	return &temporalLayers{
+		layers:    layers,
+		pattern:   pattern,
+		tl0PicIdx: uint8(rand.Intn(256)),
+	}
+}
+
+// next returns the next frame's layer and the TL0PICIDX of the latest base layer frame, including this one.
+func (l *temporalLayers) next(keyFrame bool) (TemporalLayer, uint8) {
+	if keyFrame {
+		l.index = 0
+	}
+	layer := l.pattern[l.index]
+	l.index = (l.index + 1) % len(l.pattern)
+	if layer.ID == 0 {
+		l.tl0PicIdx++
+	}
+	return layer, l.tl0PicIdx
+}
+
+// top reports whether layer is the highest layer in the pattern.
+func (l *temporalLayers) top(layer TemporalLayer) bool {
+	return layer.ID == l.layers-1
+}
@@ -0,0 +1,312 @@
+#include <errno.h>
+#include <fcntl.h>
+#include <linux/videodev2.h>
+#include <poll.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#include <sys/ioctl.h>
+#include <sys/mman.h>
+#include <unistd.h>
+
+#define NUM_CAPTURE_BUFFERS 4
+#define CAPTURE_BUFFER_SIZE (1024 * 1024)
+#define POLL_TIMEOUT_MS 1000
+
+#define CHK(__expr, __msg)                                                                                             \
+  do {                                                                                                                 \
+    if ((__expr) < 0) {                                                                                                \
+      status.code = errno;                                                                                             \
+      status.msg = __msg;                                                                                              \
+      goto CleanUp;                                                                                                    \
+    }                                                                                                                  \
+  } while (0)
+
+typedef struct Status {
+  int code;
+  const char *msg;
+} Status;
+
+typedef struct Slice {
+  uint8_t *data;
+  int len;
+} Slice;
+
+typedef struct Params {
+  const char *device;
+  int width, height;
+  uint32_t bitrate;
+  uint32_t key_frame_interval;
+  uint32_t frame_rate;
+} Params;
+
+typedef struct Buffer {
+  void *data;
+  uint32_t len;
+} Buffer;
+
+typedef struct Encoder {
+  int fd;
+  int width, height;
+  // Layout of the input buffer, which the driver can pad
+  uint32_t bytes_per_line, size_image;
+  Buffer output;
+  Buffer capture[NUM_CAPTURE_BUFFERS];
+  int num_capture;
+  // dequeued is the index of the capture buffer the caller owns, or -1
+  int dequeued;
+} Encoder;
+
+Status enc_new(Params, Encoder *);
+Status enc_encode(Encoder *, Slice y, Slice cb, Slice cr, int force_key_frame, Slice *encoded);
+Status enc_set_bitrate(Encoder *, uint32_t);
+Status enc_close(Encoder *);
+
+// In V4L2 M2M, OUTPUT is the raw frame queue, and CAPTURE is the encoded frame queue
+static int enc_set_ctrl(Encoder *encoder, uint32_t id, int32_t value) {
+  struct v4l2_control ctrl = {0};
+  ctrl.id = id;
+  ctrl.value = value;
+  return ioctl(encoder->fd, VIDIOC_S_CTRL, &ctrl);
+}
+
+static int enc_map(Encoder *encoder, uint32_t type, uint32_t index, Buffer *buffer) {
+  struct v4l2_buffer buf = {0};
+  struct v4l2_plane planes[VIDEO_MAX_PLANES] = {0};
+
+  buf.type = type;
+  buf.memory = V4L2_MEMORY_MMAP;
+  buf.index = index;
+  buf.m.planes = planes;
+  buf.length = VIDEO_MAX_PLANES;
+  if (ioctl(encoder->fd, VIDIOC_QUERYBUF, &buf) < 0) {
+    return -1;
+  }
+
+  buffer->len = planes[0].length;
+  buffer->data = mmap(NULL, planes[0].length, PROT_READ | PROT_WRITE, MAP_SHARED, encoder->fd, planes[0].m.mem_offset);
+  if (buffer->data == MAP_FAILED) {
+    buffer->data = NULL;
+    return -1;
+  }
+  return 0;
+}
+
+static int enc_queue(Encoder *encoder, uint32_t type, uint32_t index, uint32_t bytes_used) {
+  struct v4l2_buffer buf = {0};
+  struct v4l2_plane planes[1] = {0};
+
+  buf.type = type;
+  buf.memory = V4L2_MEMORY_MMAP;
+  buf.index = index;
+  buf.m.planes = planes;
+  buf.length = 1;
+  planes[0].bytesused = bytes_used;
+  return ioctl(encoder->fd, VIDIOC_QBUF, &buf);
+}
+
+// enc_dequeue waits until one of the queue's buffers is available. It fails with ETIMEDOUT if the driver doesn't
+// return a buffer within POLL_TIMEOUT_MS.
+static int enc_dequeue(Encoder *encoder, uint32_t type, uint32_t *index, uint32_t *bytes_used) {
+  struct v4l2_buffer buf = {0};
+  struct v4l2_plane planes[1] = {0};
+  struct pollfd fds = {0};
+  int ret;
+
+  fds.fd = encoder->fd;
+  fds.events = type == V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE ? POLLIN : POLLOUT;
+  ret = poll(&fds, 1, POLL_TIMEOUT_MS);
+  if (ret < 0) {
+    return -1;
+  }
+  if (ret == 0) {
+    errno = ETIMEDOUT;
+    return -1;
+  }
+
+  buf.type = type;
+  buf.memory = V4L2_MEMORY_MMAP;
+  buf.m.planes = planes;
+  buf.length = 1;
+  if (ioctl(encoder->fd, VIDIOC_DQBUF, &buf) < 0) {
+    return -1;
+  }
+
+  *index = buf.index;
+  *bytes_used = planes[0].bytesused;
+  return 0;
+}
+
+Status enc_new(Params params, Encoder *encoder) {
+  Status status = {0};
+  struct v4l2_format fmt = {0};
+  struct v4l2_streamparm parm = {0};
+  struct v4l2_requestbuffers reqbufs = {0};
+  enum v4l2_buf_type type;
+  int i;
+
+  memset(encoder, 0, sizeof(Encoder));
+  encoder->dequeued = -1;
+  encoder->width = params.width;
+  encoder->height = params.height;
+
+  encoder->fd = open(params.device, O_RDWR | O_NONBLOCK);
+  CHK(encoder->fd, "Failed to open the device");
+
+  fmt.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  fmt.fmt.pix_mp.width = params.width;
+  fmt.fmt.pix_mp.height = params.height;
+  fmt.fmt.pix_mp.pixelformat = V4L2_PIX_FMT_YUV420;
+  fmt.fmt.pix_mp.field = V4L2_FIELD_ANY;
+  fmt.fmt.pix_mp.num_planes = 1;
+  fmt.fmt.pix_mp.plane_fmt[0].bytesperline = params.width;
+  CHK(ioctl(encoder->fd, VIDIOC_S_FMT, &fmt), "Failed to set the input format");
+  // The driver might pad lines and planes to meet hardware alignment
+  encoder->bytes_per_line = fmt.fmt.pix_mp.plane_fmt[0].bytesperline;
+  encoder->size_image = fmt.fmt.pix_mp.plane_fmt[0].sizeimage;
+
+  memset(&fmt, 0, sizeof(fmt));
+  fmt.type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  fmt.fmt.pix_mp.width = params.width;
+  fmt.fmt.pix_mp.height = params.height;
+  fmt.fmt.pix_mp.pixelformat = V4L2_PIX_FMT_H264;
+  fmt.fmt.pix_mp.field = V4L2_FIELD_ANY;
+  fmt.fmt.pix_mp.num_planes = 1;
+  fmt.fmt.pix_mp.plane_fmt[0].sizeimage = CAPTURE_BUFFER_SIZE;
+  CHK(ioctl(encoder->fd, VIDIOC_S_FMT, &fmt), "Failed to set the output format");
+
+  if (params.frame_rate > 0) {
+    parm.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+    parm.parm.output.timeperframe.numerator = 1;
+    parm.parm.output.timeperframe.denominator = params.frame_rate;
+    CHK(ioctl(encoder->fd, VIDIOC_S_PARM, &parm), "Failed to set frame rate");
+  }
+
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_BITRATE, params.bitrate), "Failed to set bitrate");
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_H264_I_PERIOD, params.key_frame_interval),
+      "Failed to set intra period");
+  // Some decoders expect SPS/PPS headers to be added to every key frame
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_REPEAT_SEQ_HEADER, 1), "Failed to set inline header");
+  // Not all drivers support the profile and level. The driver defaults are used in that case.
+  enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_H264_PROFILE, V4L2_MPEG_VIDEO_H264_PROFILE_CONSTRAINED_BASELINE);
+  enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_H264_LEVEL, V4L2_MPEG_VIDEO_H264_LEVEL_4_0);
+
+  reqbufs.type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  reqbufs.memory = V4L2_MEMORY_MMAP;
+  reqbufs.count = 1;
+  CHK(ioctl(encoder->fd, VIDIOC_REQBUFS, &reqbufs), "Failed to request input buffers");
+  CHK(enc_map(encoder, V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE, 0, &encoder->output), "Failed to map input buffer");
+
+  memset(&reqbufs, 0, sizeof(reqbufs));
+  reqbufs.type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  reqbufs.memory = V4L2_MEMORY_MMAP;
+  reqbufs.count = NUM_CAPTURE_BUFFERS;
+  CHK(ioctl(encoder->fd, VIDIOC_REQBUFS, &reqbufs), "Failed to request output buffers");
+  if (reqbufs.count > NUM_CAPTURE_BUFFERS) {
+    reqbufs.count = NUM_CAPTURE_BUFFERS;
+  }
+  for (i = 0; i < (int)reqbufs.count; i++) {
+    CHK(enc_map(encoder, V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE, i, &encoder->capture[i]), "Failed to map output buffer");
+    encoder->num_capture++;
+    CHK(enc_queue(encoder, V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE, i, 0), "Failed to queue output buffer");
+  }
+
+  type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  CHK(ioctl(encoder->fd, VIDIOC_STREAMON, &type), "Failed to start input stream");
+  type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  CHK(ioctl(encoder->fd, VIDIOC_STREAMON, &type), "Failed to start output stream");
+
+CleanUp:
+
+  if (status.code != 0 && encoder->fd >= 0) {
+    enc_close(encoder);
+  }
+
+  return status;
+}
+
+// enc_encode encodes y, cb, cr. The encoded data in the capture buffer is stored in encoded, and it's valid
+// until the next enc_encode or enc_close. The encoder might return headers in a separate buffer,
+// so the caller should call enc_encode with empty planes until a picture is returned.
+Status enc_encode(Encoder *encoder, Slice y, Slice cb, Slice cr, int force_key_frame, Slice *encoded) {
+  Status status = {0};
+  uint8_t *dst;
+  uint32_t index, bytes_used, luma_size, chroma_size;
+  int row, chroma_width, chroma_height;
+
+  if (encoder->dequeued >= 0) {
+    CHK(enc_queue(encoder, V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE, encoder->dequeued, 0), "Failed to requeue output buffer");
+    encoder->dequeued = -1;
+  }
+
+  if (y.len > 0) {
+    if (force_key_frame) {
+      CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_FORCE_KEY_FRAME, 1), "Failed to force key frame");
+    }
+
+    // Lay out the planes with the driver's padding. The chroma planes follow the luma plane,
+    // which might have extra rows for alignment.
+    luma_size = encoder->size_image * 2 / 3;
+    chroma_size = encoder->size_image / 6;
+    chroma_width = encoder->width / 2;
+    chroma_height = encoder->height / 2;
+    dst = (uint8_t *)encoder->output.data;
+    for (row = 0; row < encoder->height; row++) {
+      memcpy(dst + row * encoder->bytes_per_line, y.data + row * encoder->width, encoder->width);
+    }
+    for (row = 0; row < chroma_height; row++) {
+      memcpy(dst + luma_size + row * encoder->bytes_per_line / 2, cb.data + row * chroma_width, chroma_width);
+      memcpy(dst + luma_size + chroma_size + row * encoder->bytes_per_line / 2, cr.data + row * chroma_width,
+             chroma_width);
+    }
+    CHK(enc_queue(encoder, V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE, 0, encoder->size_image), "Failed to queue input buffer");
+  }
+
+  CHK(enc_dequeue(encoder, V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE, &index, &bytes_used), "Failed to dequeue output buffer");
+  encoder->dequeued = index;
+  encoded->data = (uint8_t *)encoder->capture[index].data;
+  encoded->len = bytes_used;
+
+  if (y.len > 0) {
+    // The encoder has produced the frame, so the input buffer has been consumed
+    CHK(enc_dequeue(encoder, V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE, &index, &bytes_used), "Failed to dequeue input buffer");
+  }
+
+CleanUp:
+
+  return status;
+}
+
+Status enc_set_bitrate(Encoder *encoder, uint32_t bitrate) {
+  Status status = {0};
+
+  CHK(enc_set_ctrl(encoder, V4L2_CID_MPEG_VIDEO_BITRATE, bitrate), "Failed to set bitrate");
+
+CleanUp:
+
+  return status;
+}
+
+Status enc_close(Encoder *encoder) {
+  Status status = {0};
+  enum v4l2_buf_type type;
+  int i;
+
+  type = V4L2_BUF_TYPE_VIDEO_OUTPUT_MPLANE;
+  ioctl(encoder->fd, VIDIOC_STREAMOFF, &type);
+  type = V4L2_BUF_TYPE_VIDEO_CAPTURE_MPLANE;
+  ioctl(encoder->fd, VIDIOC_STREAMOFF, &type);
+
+  if (encoder->output.data != NULL) {
+    munmap(encoder->output.data, encoder->output.len);
+  }
+  for (i = 0; i < encoder->num_capture; i++) {
+    munmap(encoder->capture[i].data, encoder->capture[i].len);
+  }
+
+  CHK(close(encoder->fd), "Failed to close the device");
+
+CleanUp:
+
+  return status;
+}
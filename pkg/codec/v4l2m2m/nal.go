@@ -0,0 +1,21 @@
+package v4l2m2m
+
+// hasPicture reports whether the annex B stream b contains a coded slice. Some encoders return
+// parameter sets in a separate buffer before the first picture.
+func hasPicture(b []byte) bool {
+	zeros := 0
+	for i, v := range b {
+		switch {
+		case v == 0:
+			zeros++
+			continue
+		case v == 1 && zeros >= 2 && i+1 < len(b):
+			// Reference: ITU-T H.264 Table 7-1, 1 is a non-IDR slice and 5 is an IDR slice
+			if naluType := b[i+1] & 0x1F; naluType >= 1 && naluType <= 5 {
+				return true
+			}
+		}
+		zeros = 0
+	}
+	return false
+}
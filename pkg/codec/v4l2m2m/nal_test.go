@@ -0,0 +1,30 @@
+package v4l2m2m
+
+import "testing"
+
+func TestHasPicture(t *testing.T) {
+	sps := []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x1f}
+	pps := []byte{0x00, 0x00, 0x00, 0x01, 0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0x00, 0x00, 0x01, 0x65, 0x88, 0x84}
+	nonIDR := []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9a}
+
+	testCases := map[string]struct {
+		stream   []byte
+		expected bool
+	}{
+		"Empty":        {nil, false},
+		"HeadersOnly":  {append(append([]byte{}, sps...), pps...), false},
+		"HeadersIDR":   {append(append(append([]byte{}, sps...), pps...), idr...), true},
+		"NonIDR":       {nonIDR, true},
+		"StartCodeEnd": {[]byte{0x00, 0x00, 0x01}, false},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			if actual := hasPicture(testCase.stream); actual != testCase.expected {
+				t.Fatalf("expected %v, but got %v", testCase.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package v4l2m2m
+
+import (
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// DefaultDevice is the Raspberry Pi H264 encoder
+const DefaultDevice = "/dev/video11"
+
+// Params stores V4L2 M2M specific encoding parameters.
+type Params struct {
+	codec.BaseParams
+	// Device is the path to the V4L2 memory-to-memory encoder device
+	Device string
+}
+
+// NewParams returns default V4L2 M2M codec specific parameters.
+func NewParams() (Params, error) {
+	return Params{
+		BaseParams: codec.BaseParams{
+			KeyFrameInterval: 60,
+		},
+		Device: DefaultDevice,
+	}, nil
+}
+
+// RTPCodec represents the codec metadata
+func (p *Params) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPH264Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
+}
+
+// BuildVideoEncoder builds V4L2 M2M encoder with given params
+func (p *Params) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
+	return newEncoder(r, property, *p)
+}
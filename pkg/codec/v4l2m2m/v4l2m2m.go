@@ -0,0 +1,157 @@
+// +build linux
+
+// Package v4l2m2m implements a hardware accelerated H264 encoder using V4L2 memory-to-memory devices,
+// e.g. Raspberry Pi's h264_v4l2m2m, which replaces MMAL in the libcamera-based camera stack.
+// Reference: https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/dev-encoder.html
+package v4l2m2m
+
+// #include "bridge.h"
+import "C"
+import (
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// maxHeaderBuffers limits how many buffers without a picture are accepted, e.g. SPS/PPS-only buffers
+const maxHeaderBuffers = 4
+
+type encoder struct {
+	engine        C.Encoder
+	r             video.Reader
+	mu            sync.Mutex
+	closed        bool
+	forceKeyFrame bool
+}
+
+func statusToErr(status *C.Status) error {
+	return fmt.Errorf("(errno = %d) %s", int(status.code), C.GoString(status.msg))
+}
+
+func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
+	if params.KeyFrameInterval == 0 {
+		params.KeyFrameInterval = 60
+	}
+
+	if params.BitRate == 0 {
+		params.BitRate = 300000
+	}
+
+	if params.Device == "" {
+		params.Device = DefaultDevice
+	}
+
+	device := C.CString(params.Device)
+	defer C.free(unsafe.Pointer(device))
+
+	e := encoder{
+		r: video.Compact(video.ToI420(r)),
+	}
+	status := C.enc_new(C.Params{
+		device:             device,
+		width:              C.int(p.Width),
+		height:             C.int(p.Height),
+		bitrate:            C.uint(params.BitRate),
+		key_frame_interval: C.uint(params.KeyFrameInterval),
+		frame_rate:         C.uint(p.FrameRate),
+	}, &e.engine)
+	if status.code != 0 {
+		return nil, statusToErr(&status)
+	}
+
+	return &e, nil
+}
+
+func (e *encoder) Read() ([]byte, func(), error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, func() {}, io.EOF
+	}
+
+	img, _, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	imgReal := img.(*image.YCbCr)
+	var y, cb, cr C.Slice
+	y.data = (*C.uchar)(&imgReal.Y[0])
+	y.len = C.int(len(imgReal.Y))
+	cb.data = (*C.uchar)(&imgReal.Cb[0])
+	cb.len = C.int(len(imgReal.Cb))
+	cr.data = (*C.uchar)(&imgReal.Cr[0])
+	cr.len = C.int(len(imgReal.Cr))
+
+	var forceKeyFrame C.int
+	if e.forceKeyFrame {
+		forceKeyFrame = 1
+		e.forceKeyFrame = false
+	}
+
+	var encoded []byte
+	for i := 0; i < maxHeaderBuffers; i++ {
+		var buf C.Slice
+		status := C.enc_encode(&e.engine, y, cb, cr, forceKeyFrame, &buf)
+		if status.code != 0 {
+			return nil, func() {}, statusToErr(&status)
+		}
+
+		// GoBytes copies the C array to a Go slice. After this, it's safe to requeue the capture buffer
+		encoded = append(encoded, C.GoBytes(unsafe.Pointer(buf.data), buf.len)...)
+		if hasPicture(encoded) {
+			return encoded, func() {}, nil
+		}
+
+		// The frame has been queued, so only the remaining capture buffers are dequeued.
+		y, cb, cr = C.Slice{}, C.Slice{}, C.Slice{}
+		forceKeyFrame = 0
+	}
+
+	return encoded, func() {}, nil
+}
+
+func (e *encoder) SetBitRate(b int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+
+	status := C.enc_set_bitrate(&e.engine, C.uint(b))
+	if status.code != 0 {
+		return statusToErr(&status)
+	}
+	return nil
+}
+
+func (e *encoder) ForceKeyFrame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.forceKeyFrame = true
+	return nil
+}
+
+func (e *encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil
+	}
+
+	e.closed = true
+	status := C.enc_close(&e.engine)
+	if status.code != 0 {
+		return statusToErr(&status)
+	}
+	return nil
+}
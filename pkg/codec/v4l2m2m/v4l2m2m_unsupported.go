@@ -0,0 +1,20 @@
+// +build !linux
+
+package v4l2m2m
+
+// // Dummy CGO import to avoid `C source files not allowed when not using cgo or SWIG`
+import "C"
+
+import (
+	"errors"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+var errNotSupported = errors.New("v4l2m2m is only supported on linux")
+
+func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
+	return nil, errNotSupported
+}
@@ -0,0 +1,76 @@
+package vaapi
+
+// bitWriter writes H264 and H265 parameter set syntax elements, MSB first
+type bitWriter struct {
+	buf  []byte
+	cur  byte
+	nBit uint
+}
+
+// u writes the lowest n bits of v
+func (w *bitWriter) u(n uint, v uint32) {
+	for i := n; i > 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>(i-1)&1)
+		w.nBit++
+		if w.nBit == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.nBit = 0, 0
+		}
+	}
+}
+
+func (w *bitWriter) flag(b bool) {
+	if b {
+		w.u(1, 1)
+	} else {
+		w.u(1, 0)
+	}
+}
+
+// ue writes v in unsigned Exp-Golomb code
+func (w *bitWriter) ue(v uint32) {
+	v++
+	var n uint
+	for x := v; x > 1; x >>= 1 {
+		n++
+	}
+	w.u(n, 0)
+	w.u(n+1, v)
+}
+
+// se writes v in signed Exp-Golomb code
+func (w *bitWriter) se(v int32) {
+	if v > 0 {
+		w.ue(uint32(v)*2 - 1)
+	} else {
+		w.ue(uint32(-v) * 2)
+	}
+}
+
+// trailing writes rbsp_trailing_bits, and returns the RBSP
+func (w *bitWriter) trailing() []byte {
+	w.u(1, 1)
+	for w.nBit != 0 {
+		w.u(1, 0)
+	}
+	return w.buf
+}
+
+// nalu returns the NAL unit in Annex B byte stream format, with emulation prevention bytes inserted into the RBSP.
+func nalu(header []byte, rbsp []byte) []byte {
+	b := append([]byte{0x00, 0x00, 0x00, 0x01}, header...)
+	zeros := 0
+	for _, v := range rbsp {
+		if zeros == 2 && v <= 0x03 {
+			b = append(b, 0x03)
+			zeros = 0
+		}
+		b = append(b, v)
+		if v == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return b
+}
@@ -0,0 +1,129 @@
+package vaapi
+
+import (
+	"bytes"
+	"testing"
+)
+
+// bitReader reads syntax elements written by bitWriter
+type bitReader struct {
+	buf []byte
+	pos uint
+}
+
+func (r *bitReader) u(n uint) uint32 {
+	var v uint32
+	for i := uint(0); i < n; i++ {
+		v = v<<1 | uint32(r.buf[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) ue() uint32 {
+	var n uint
+	for r.u(1) == 0 {
+		n++
+	}
+	return 1<<n - 1 + r.u(n)
+}
+
+// unescape removes the start code and emulation prevention bytes
+func unescape(nalu []byte) []byte {
+	return bytes.ReplaceAll(nalu[4:], []byte{0x00, 0x00, 0x03}, []byte{0x00, 0x00})
+}
+
+func TestBitWriter(t *testing.T) {
+	var w bitWriter
+	w.ue(0)  // 1
+	w.ue(1)  // 010
+	w.ue(2)  // 011
+	w.ue(3)  // 00100
+	w.se(1)  // 010
+	w.se(-1) // 011
+	w.u(4, 0xA)
+
+	// 1010 0110 0100 0100 1110 10, then the stop bit and a zero bit
+	expected := []byte{0xA6, 0x44, 0xEA}
+	if b := w.trailing(); !bytes.Equal(b, expected) {
+		t.Fatalf("expected %x, but got %x", expected, b)
+	}
+}
+
+func TestNALUEmulationPrevention(t *testing.T) {
+	b := nalu([]byte{0x67}, []byte{0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x04})
+	expected := []byte{
+		0x00, 0x00, 0x00, 0x01, 0x67,
+		0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x03, 0x00, 0x00, 0x03, 0x00, 0x04,
+	}
+	if !bytes.Equal(b, expected) {
+		t.Fatalf("expected %x, but got %x", expected, b)
+	}
+}
+
+func TestH264SequenceSPS(t *testing.T) {
+	s := &h264Sequence{
+		width:                 1278,
+		height:                718,
+		levelIDC:              h264Level(1278, 718),
+		log2MaxFrameNumMinus4: 4,
+		initQP:                26,
+		frameRate:             30,
+	}
+
+	sps := s.sps()
+	if !bytes.Equal(sps[:5], []byte{0x00, 0x00, 0x00, 0x01, 0x67}) {
+		t.Fatalf("expected SPS NAL unit, but got %x", sps[:5])
+	}
+
+	r := &bitReader{buf: unescape(sps)[1:]}
+	if profile := r.u(8); profile != h264ProfileIDCConstrainedBaseline {
+		t.Fatalf("expected profile_idc %d, but got %d", h264ProfileIDCConstrainedBaseline, profile)
+	}
+	if flags := r.u(8); flags != h264ConstraintFlags {
+		t.Fatalf("expected constraint flags %x, but got %x", h264ConstraintFlags, flags)
+	}
+	if level := r.u(8); level != 31 {
+		t.Fatalf("expected level_idc 31, but got %d", level)
+	}
+	r.ue() // seq_parameter_set_id
+	if v := r.ue(); v != 4 {
+		t.Fatalf("expected log2_max_frame_num_minus4 4, but got %d", v)
+	}
+	if v := r.ue(); v != 2 {
+		t.Fatalf("expected pic_order_cnt_type 2, but got %d", v)
+	}
+	r.ue() // max_num_ref_frames
+	r.u(1) // gaps_in_frame_num_value_allowed_flag
+	if w, h := r.ue()+1, r.ue()+1; w != 80 || h != 45 {
+		t.Fatalf("expected 80x45 macroblocks, but got %dx%d", w, h)
+	}
+	r.u(2) // frame_mbs_only_flag, direct_8x8_inference_flag
+	if cropping := r.u(1); cropping != 1 {
+		t.Fatal("expected frame_cropping_flag to be set")
+	}
+	if left, right, top, bottom := r.ue(), r.ue(), r.ue(), r.ue(); left != 0 || right != 1 || top != 0 || bottom != 1 {
+		t.Fatalf("expected crop offsets (0, 1, 0, 1), but got (%d, %d, %d, %d)", left, right, top, bottom)
+	}
+}
+
+func TestH264Level(t *testing.T) {
+	testCases := map[string]struct {
+		width, height int
+		expected      uint8
+	}{
+		"VGA":   {640, 480, 30},
+		"720p":  {1280, 720, 31},
+		"1080p": {1920, 1080, 40},
+		"4K":    {3840, 2160, 51},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			if level := h264Level(testCase.width, testCase.height); level != testCase.expected {
+				t.Fatalf("expected level %d, but got %d", testCase.expected, level)
+			}
+		})
+	}
+}
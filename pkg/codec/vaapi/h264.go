@@ -0,0 +1,491 @@
+// +build dragonfly freebsd linux netbsd openbsd solaris
+
+package vaapi
+
+// reference: https://github.com/intel/libva-utils/blob/master/encode/h264encode.c
+
+// #include <fcntl.h>
+// #include <stdint.h>
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <string.h>
+//
+// #include <va/va.h>
+// #include <va/va_enc_h264.h>
+//
+// #include "helper.h"
+//
+// void setSeqFieldsH264(VAEncSequenceParameterBufferH264 *p, uint32_t log2MaxFrameNumMinus4) {
+//   p->seq_fields.bits.chroma_format_idc = 1;
+//   p->seq_fields.bits.frame_mbs_only_flag = 1;
+//   p->seq_fields.bits.direct_8x8_inference_flag = 1;
+//   p->seq_fields.bits.log2_max_frame_num_minus4 = log2MaxFrameNumMinus4;
+//   p->seq_fields.bits.pic_order_cnt_type = 2;
+//   p->vui_fields.bits.timing_info_present_flag = 1;
+//   p->vui_fields.bits.bitstream_restriction_flag = 1;
+//   p->vui_fields.bits.motion_vectors_over_pic_boundaries_flag = 1;
+//   p->vui_fields.bits.log2_max_mv_length_horizontal = 16;
+//   p->vui_fields.bits.log2_max_mv_length_vertical = 16;
+// }
+// void setPicFieldsH264(VAEncPictureParameterBufferH264 *p, uint32_t idr) {
+//   p->pic_fields.bits.idr_pic_flag = idr;
+//   p->pic_fields.bits.reference_pic_flag = 1;
+//   p->pic_fields.bits.deblocking_filter_control_present_flag = 1;
+// }
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	surfaceH264Ref0 = iota
+	surfaceH264Ref1
+	surfaceH264Input
+	surfaceH264Num
+)
+
+const (
+	sliceTypeH264P = 0
+	sliceTypeH264I = 2
+
+	h264Log2MaxFrameNumMinus4 = 4
+)
+
+type encoderH264 struct {
+	frames *frameSource
+	frame  []byte
+
+	fdDRI      C.int
+	display    C.VADisplay
+	confID     C.VAConfigID
+	surfs      [surfaceH264Num]C.VASurfaceID
+	ctxID      C.VAContextID
+	seqParam   C.VAEncSequenceParameterBufferH264
+	picParam   C.VAEncPictureParameterBufferH264
+	sliceParam C.VAEncSliceParameterBufferH264
+	hrdParam   hrdParam
+	frParam    frParam
+	rcParam    rcParam
+
+	seq           h264Sequence
+	packedHeaders C.uint
+
+	slotCurr int
+	frameNum int
+	poc      int
+	idrPicID int
+
+	frameCnt int
+	prop     prop.Media
+	params   ParamsH264
+
+	rate      *framerateDetector
+	rcUpdated bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+var invalidPictureH264 = C.VAPictureH264{
+	picture_id: C.VA_INVALID_SURFACE,
+	flags:      C.VA_PICTURE_H264_INVALID,
+}
+
+// newH264Encoder creates new H264 encoder
+func newH264Encoder(r video.Reader, p prop.Media, params ParamsH264) (codec.ReadCloser, error) {
+	if p.Width <= 0 || p.Height <= 0 {
+		return nil, errors.New("width and height must be positive")
+	}
+	if p.Width%2 != 0 || p.Height%2 != 0 {
+		return nil, errors.New("width and height must be 2*n")
+	}
+	if params.KeyFrameInterval == 0 {
+		params.KeyFrameInterval = 60
+	}
+	if p.FrameRate == 0 {
+		p.FrameRate = 30
+	}
+
+	params.RateControl.setBitRate(params.BitRate)
+
+	e := &encoderH264{
+		frames: newFrameSource(r),
+		prop:   p,
+		params: params,
+		rate:   newFramerateDetector(uint32(p.FrameRate)),
+		seq: h264Sequence{
+			width:                 p.Width,
+			height:                p.Height,
+			levelIDC:              h264Level(p.Width, p.Height),
+			log2MaxFrameNumMinus4: h264Log2MaxFrameNumMinus4,
+			initQP:                int32(params.RateControl.InitialQP),
+			frameRate:             uint32(p.FrameRate),
+		},
+		hrdParam: hrdParam{
+			hdr: C.VAEncMiscParameterBuffer{
+				_type: C.VAEncMiscParameterTypeHRD,
+			},
+		},
+		frParam: frParam{
+			hdr: C.VAEncMiscParameterBuffer{
+				_type: C.VAEncMiscParameterTypeFrameRate,
+			},
+			data: C.VAEncMiscParameterFrameRate{
+				framerate: C.uint(p.FrameRate),
+			},
+		},
+		rcParam: rcParam{
+			hdr: C.VAEncMiscParameterBuffer{
+				_type: C.VAEncMiscParameterTypeRateControl,
+			},
+		},
+	}
+	e.hrdParam.setRateControl(params.RateControl)
+	e.rcParam.setRateControl(params.RateControl)
+
+	right, bottom := e.seq.crop()
+	e.seqParam = C.VAEncSequenceParameterBufferH264{
+		level_idc:                   C.uchar(e.seq.levelIDC),
+		intra_period:                C.uint(params.KeyFrameInterval),
+		intra_idr_period:            C.uint(params.KeyFrameInterval),
+		ip_period:                   1,
+		bits_per_second:             C.uint(params.RateControl.bitsPerSecond),
+		max_num_ref_frames:          1,
+		picture_width_in_mbs:        C.ushort(e.seq.widthInMbs()),
+		picture_height_in_mbs:       C.ushort(e.seq.heightInMbs()),
+		vui_parameters_present_flag: 1,
+		num_units_in_tick:           1,
+		time_scale:                  C.uint(2 * p.FrameRate),
+	}
+	if right != 0 || bottom != 0 {
+		e.seqParam.frame_cropping_flag = 1
+		e.seqParam.frame_crop_right_offset = C.uint(right)
+		e.seqParam.frame_crop_bottom_offset = C.uint(bottom)
+	}
+	C.setSeqFieldsH264(&e.seqParam, h264Log2MaxFrameNumMinus4)
+
+	e.picParam = C.VAEncPictureParameterBufferH264{
+		pic_init_qp: C.uchar(params.RateControl.InitialQP),
+	}
+	for i := range e.picParam.ReferenceFrames {
+		e.picParam.ReferenceFrames[i] = invalidPictureH264
+	}
+	e.sliceParam = C.VAEncSliceParameterBufferH264{
+		num_macroblocks: C.uint(e.seq.widthInMbs() * e.seq.heightInMbs()),
+	}
+	for i := range e.sliceParam.RefPicList0 {
+		e.sliceParam.RefPicList0[i] = invalidPictureH264
+		e.sliceParam.RefPicList1[i] = invalidPictureH264
+	}
+
+	var err error
+	e.display, e.fdDRI, err = openDisplay("/dev/dri/card0")
+	if err != nil {
+		// TODO: try another graphic card and display via X11
+		return nil, err
+	}
+
+	var vaMajor, vaMinor C.int
+	if s := C.vaInitialize(e.display, &vaMajor, &vaMinor); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to init libva: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	ep, err := selectEntrypoint(e.display, C.VAProfileH264ConstrainedBaseline, params.LowPower)
+	if err != nil {
+		return nil, err
+	}
+
+	confAttrs := []C.VAConfigAttrib{
+		{_type: C.VAConfigAttribRTFormat},
+		{_type: C.VAConfigAttribRateControl},
+		{_type: C.VAConfigAttribEncPackedHeaders},
+	}
+	if s := C.vaGetConfigAttributes(
+		e.display,
+		C.VAProfileH264ConstrainedBaseline,
+		ep,
+		&confAttrs[0], C.int(len(confAttrs)),
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to get config attrs: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if (confAttrs[0].value & C.VA_RT_FORMAT_YUV420) == 0 {
+		return nil, errors.New("the hardware encoder doesn't support YUV420")
+	}
+	if (confAttrs[1].value & C.uint(params.RateControlMode)) == 0 {
+		return nil, errors.New("the hardware encoder doesn't support specified rate control mode")
+	}
+	confAttrs[0].value = C.VA_RT_FORMAT_YUV420
+	confAttrs[1].value = C.uint(params.RateControlMode)
+	if confAttrs[2].value == C.VA_ATTRIB_NOT_SUPPORTED {
+		// The driver generates the parameter sets
+		confAttrs = confAttrs[:2]
+	} else {
+		e.packedHeaders = confAttrs[2].value & (C.VA_ENC_PACKED_HEADER_SEQUENCE | C.VA_ENC_PACKED_HEADER_PICTURE)
+		confAttrs[2].value = e.packedHeaders
+	}
+
+	if s := C.vaCreateConfig(
+		e.display,
+		C.VAProfileH264ConstrainedBaseline,
+		ep,
+		&confAttrs[0], C.int(len(confAttrs)),
+		&e.confID,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to create config: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	surfAttr := C.VASurfaceAttrib{
+		_type: C.VASurfaceAttribPixelFormat,
+		flags: C.VA_SURFACE_ATTRIB_SETTABLE,
+		value: C.genValInt(C.VA_FOURCC_NV12),
+	}
+	width, height := alignUp16(p.Width), alignUp16(p.Height)
+	if s := C.vaCreateSurfaces(
+		e.display,
+		C.VA_RT_FORMAT_YUV420,
+		C.uint(width), C.uint(height),
+		&e.surfs[0], surfaceH264Num,
+		&surfAttr, 1,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to create surfaces: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	if s := C.vaCreateContext(
+		e.display,
+		e.confID,
+		C.int(width), C.int(height),
+		C.VA_PROGRESSIVE,
+		&e.surfs[0], surfaceH264Num,
+		&e.ctxID,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to create context: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	return e, nil
+}
+
+func (e *encoderH264) Read() ([]byte, func(), error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, func() {}, io.EOF
+	}
+
+	input, releaseInput, err := e.frames.read(e.display, e.surfs[surfaceH264Input])
+	if err != nil {
+		return nil, func() {}, err
+	}
+	defer releaseInput()
+
+	idr := e.frameCnt%e.params.KeyFrameInterval == 0
+	e.frameCnt++
+
+	e.frParam.data.framerate = C.uint(e.rate.Calc())
+
+	if idr {
+		e.frameNum = 0
+		e.poc = 0
+	}
+	ref := e.picParam.CurrPic
+	recon := C.VAPictureH264{
+		picture_id:          e.surfs[e.slotCurr],
+		frame_idx:           C.uint(e.frameNum),
+		TopFieldOrderCnt:    C.int(e.poc * 2),
+		BottomFieldOrderCnt: C.int(e.poc * 2),
+	}
+
+	e.picParam.CurrPic = recon
+	e.picParam.frame_num = C.ushort(e.frameNum)
+	C.setPicFieldsH264(&e.picParam, boolToUint(idr))
+	if idr {
+		e.picParam.ReferenceFrames[0] = invalidPictureH264
+		e.sliceParam.RefPicList0[0] = invalidPictureH264
+		e.sliceParam.slice_type = sliceTypeH264I
+		e.sliceParam.idr_pic_id = C.ushort(e.idrPicID)
+		e.idrPicID = (e.idrPicID + 1) & 0xFFFF
+	} else {
+		ref.flags = C.VA_PICTURE_H264_SHORT_TERM_REFERENCE
+		e.picParam.ReferenceFrames[0] = ref
+		e.sliceParam.RefPicList0[0] = ref
+		e.sliceParam.slice_type = sliceTypeH264P
+	}
+
+	// Prepare buffers
+	buffs := make([]C.VABufferID, 0, bufferNum)
+	type buffParam struct {
+		typ  C.VABufferType
+		n    uint
+		num  uint
+		src  unsafe.Pointer
+		hook func()
+	}
+	buffParams := []buffParam{
+		{
+			typ: C.VAEncCodedBufferType,
+			n:   uint(alignUp16(e.prop.Width) * alignUp16(e.prop.Height) * 3 / 2), num: 1, src: nil,
+		},
+	}
+	if idr {
+		buffParams = append(buffParams, buffParam{
+			typ: C.VAEncSequenceParameterBufferType,
+			n:   uint(unsafe.Sizeof(e.seqParam)), num: 1, src: unsafe.Pointer(&e.seqParam),
+		})
+	}
+	buffParams = append(buffParams,
+		buffParam{
+			typ: C.VAEncPictureParameterBufferType,
+			n:   uint(unsafe.Sizeof(e.picParam)), num: 1, src: unsafe.Pointer(&e.picParam),
+			hook: func() {
+				e.picParam.coded_buf = buffs[0]
+			},
+		},
+	)
+	var packed []packedHeader
+	if idr && e.packedHeaders&C.VA_ENC_PACKED_HEADER_SEQUENCE != 0 {
+		packed = append(packed, newPackedHeader(C.VAEncPackedHeaderSequence, e.seq.sps()))
+	}
+	if idr && e.packedHeaders&C.VA_ENC_PACKED_HEADER_PICTURE != 0 {
+		packed = append(packed, newPackedHeader(C.VAEncPackedHeaderPicture, e.seq.pps()))
+	}
+	for i := range packed {
+		buffParams = append(buffParams,
+			buffParam{
+				typ: C.VAEncPackedHeaderParameterBufferType,
+				n:   uint(unsafe.Sizeof(packed[i].param)), num: 1, src: unsafe.Pointer(&packed[i].param),
+			},
+			buffParam{
+				typ: C.VAEncPackedHeaderDataBufferType,
+				n:   uint(len(packed[i].data)), num: 1, src: unsafe.Pointer(&packed[i].data[0]),
+			},
+		)
+	}
+	if idr || e.rcUpdated {
+		e.rcUpdated = false
+		buffParams = append(buffParams,
+			buffParam{
+				typ: C.VAEncMiscParameterBufferType,
+				n:   uint(unsafe.Sizeof(e.hrdParam)), num: 1, src: unsafe.Pointer(&e.hrdParam),
+			},
+			buffParam{
+				typ: C.VAEncMiscParameterBufferType,
+				n:   uint(unsafe.Sizeof(e.frParam)), num: 1, src: unsafe.Pointer(&e.frParam),
+			},
+			buffParam{
+				typ: C.VAEncMiscParameterBufferType,
+				n:   uint(unsafe.Sizeof(e.rcParam)), num: 1, src: unsafe.Pointer(&e.rcParam),
+			},
+		)
+	}
+	buffParams = append(buffParams,
+		buffParam{
+			typ: C.VAEncSliceParameterBufferType,
+			n:   uint(unsafe.Sizeof(e.sliceParam)), num: 1, src: unsafe.Pointer(&e.sliceParam),
+		},
+	)
+	for _, p := range buffParams {
+		if p.hook != nil {
+			p.hook()
+		}
+		var id C.VABufferID
+		if s := C.vaCreateBufferPtr(
+			e.display, e.ctxID,
+			p.typ, C.uint(p.n), C.uint(p.num),
+			C.size_t(uintptr(p.src)),
+			&id,
+		); s != C.VA_STATUS_SUCCESS {
+			e.destroyBuffers(buffs)
+			return nil, func() {}, fmt.Errorf("failed to create buffer: %s", C.GoString(C.vaErrorStr(s)))
+		}
+		buffs = append(buffs, id)
+	}
+	defer e.destroyBuffers(buffs)
+
+	// Render picture
+	if s := C.vaBeginPicture(
+		e.display, e.ctxID,
+		input,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to begin picture: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if s := C.vaRenderPicture(
+		e.display, e.ctxID,
+		&buffs[1], // 0 is for ouput
+		C.int(len(buffs)-1),
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to render picture: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if s := C.vaEndPicture(
+		e.display, e.ctxID,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to end picture: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	// Load encoded data
+	if s := C.vaSyncSurface(e.display, input); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to sync surface: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	e.frame, err = copyCodedBuffer(e.display, buffs[0], e.frame)
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	// Update reference
+	e.slotCurr = 1 - e.slotCurr
+	e.frameNum = (e.frameNum + 1) % (1 << (h264Log2MaxFrameNumMinus4 + 4))
+	e.poc++
+
+	encoded := make([]byte, len(e.frame))
+	copy(encoded, e.frame)
+	return encoded, func() {}, nil
+}
+
+func (e *encoderH264) destroyBuffers(buffs []C.VABufferID) {
+	for _, b := range buffs {
+		C.vaDestroyBuffer(e.display, b)
+	}
+}
+
+func (e *encoderH264) SetBitRate(b int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.params.BitRate = b
+	e.params.RateControl.setBitRate(b)
+	e.seqParam.bits_per_second = C.uint(e.params.RateControl.bitsPerSecond)
+	e.hrdParam.setRateControl(e.params.RateControl)
+	e.rcParam.setRateControl(e.params.RateControl)
+	// Rate control parameters are sent with the next frame
+	e.rcUpdated = true
+	return nil
+}
+
+func (e *encoderH264) ForceKeyFrame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.frameCnt = 0
+	return nil
+}
+
+func (e *encoderH264) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	C.vaDestroySurfaces(e.display, &e.surfs[0], C.int(len(e.surfs)))
+	C.vaDestroyContext(e.display, e.ctxID)
+	C.vaDestroyConfig(e.display, e.confID)
+	closeDisplay(e.display, e.fdDRI)
+
+	e.closed = true
+	return nil
+}
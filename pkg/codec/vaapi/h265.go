@@ -0,0 +1,474 @@
+// +build dragonfly freebsd linux netbsd openbsd solaris
+
+package vaapi
+
+// reference: https://github.com/intel/libva-utils/blob/master/encode/hevcencode.c
+
+// #include <fcntl.h>
+// #include <stdint.h>
+// #include <stdio.h>
+// #include <stdlib.h>
+// #include <string.h>
+//
+// #include <va/va.h>
+// #include <va/va_enc_hevc.h>
+//
+// #include "helper.h"
+//
+// void setSeqFieldsHEVC(VAEncSequenceParameterBufferHEVC *p, uint32_t amp) {
+//   p->seq_fields.bits.chroma_format_idc = 1;
+//   p->seq_fields.bits.amp_enabled_flag = amp;
+//   p->seq_fields.bits.low_delay_seq = 1;
+//   p->vui_fields.bits.vui_timing_info_present_flag = 1;
+//   p->vui_fields.bits.bitstream_restriction_flag = 1;
+//   p->vui_fields.bits.motion_vectors_over_pic_boundaries_flag = 1;
+//   p->vui_fields.bits.restricted_ref_pic_lists_flag = 1;
+//   p->vui_fields.bits.log2_max_mv_length_horizontal = 15;
+//   p->vui_fields.bits.log2_max_mv_length_vertical = 15;
+// }
+// void setPicFieldsHEVC(VAEncPictureParameterBufferHEVC *p, uint32_t idr, uint32_t codingType) {
+//   p->pic_fields.bits.idr_pic_flag = idr;
+//   p->pic_fields.bits.coding_type = codingType;
+//   p->pic_fields.bits.reference_pic_flag = 1;
+//   p->pic_fields.bits.cu_qp_delta_enabled_flag = 1;
+// }
+// void setSliceFieldsHEVC(VAEncSliceParameterBufferHEVC *p) {
+//   p->slice_fields.bits.last_slice_of_pic_flag = 1;
+// }
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	surfaceH265Ref0 = iota
+	surfaceH265Ref1
+	surfaceH265Input
+	surfaceH265Num
+)
+
+const (
+	sliceTypeH265B = 0
+	sliceTypeH265P = 1
+	sliceTypeH265I = 2
+
+	codingTypeH265I = 1
+	codingTypeH265P = 2
+
+	nalUnitTypeH265TrailR   = 1
+	nalUnitTypeH265IDRWRADL = 19
+)
+
+type encoderH265 struct {
+	frames *frameSource
+	frame  []byte
+
+	fdDRI      C.int
+	display    C.VADisplay
+	confID     C.VAConfigID
+	surfs      [surfaceH265Num]C.VASurfaceID
+	ctxID      C.VAContextID
+	seqParam   C.VAEncSequenceParameterBufferHEVC
+	picParam   C.VAEncPictureParameterBufferHEVC
+	sliceParam C.VAEncSliceParameterBufferHEVC
+	hrdParam   hrdParam
+	frParam    frParam
+	rcParam    rcParam
+
+	// lowDelayB uses B slices that refer to the same picture in both lists instead of P slices,
+	// since low power encoders don't support P slices.
+	lowDelayB bool
+
+	slotCurr int
+	poc      int
+
+	frameCnt int
+	prop     prop.Media
+	params   ParamsH265
+
+	rate      *framerateDetector
+	rcUpdated bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+var invalidPictureH265 = C.VAPictureHEVC{
+	picture_id: C.VA_INVALID_SURFACE,
+	flags:      C.VA_PICTURE_HEVC_INVALID,
+}
+
+// newH265Encoder creates new H265 encoder
+func newH265Encoder(r video.Reader, p prop.Media, params ParamsH265) (codec.ReadCloser, error) {
+	if p.Width%16 != 0 || p.Width == 0 {
+		return nil, errors.New("width must be 16*n")
+	}
+	if p.Height%16 != 0 || p.Height == 0 {
+		return nil, errors.New("height must be 16*n")
+	}
+	if params.KeyFrameInterval == 0 {
+		params.KeyFrameInterval = 60
+	}
+	if p.FrameRate == 0 {
+		p.FrameRate = 30
+	}
+
+	params.RateControl.setBitRate(params.BitRate)
+
+	e := &encoderH265{
+		frames: newFrameSource(r),
+		prop:   p,
+		params: params,
+		rate:   newFramerateDetector(uint32(p.FrameRate)),
+		hrdParam: hrdParam{
+			hdr: C.VAEncMiscParameterBuffer{
+				_type: C.VAEncMiscParameterTypeHRD,
+			},
+		},
+		frParam: frParam{
+			hdr: C.VAEncMiscParameterBuffer{
+				_type: C.VAEncMiscParameterTypeFrameRate,
+			},
+			data: C.VAEncMiscParameterFrameRate{
+				framerate: C.uint(p.FrameRate),
+			},
+		},
+		rcParam: rcParam{
+			hdr: C.VAEncMiscParameterBuffer{
+				_type: C.VAEncMiscParameterTypeRateControl,
+			},
+		},
+	}
+	e.hrdParam.setRateControl(params.RateControl)
+	e.rcParam.setRateControl(params.RateControl)
+
+	var err error
+	e.display, e.fdDRI, err = openDisplay("/dev/dri/card0")
+	if err != nil {
+		// TODO: try another graphic card and display via X11
+		return nil, err
+	}
+
+	var vaMajor, vaMinor C.int
+	if s := C.vaInitialize(e.display, &vaMajor, &vaMinor); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to init libva: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	ep, err := selectEntrypoint(e.display, C.VAProfileHEVCMain, params.LowPower)
+	if err != nil {
+		return nil, err
+	}
+	e.lowDelayB = ep == C.VAEntrypointEncSliceLP
+
+	// Low power encoders use 64x64 CTUs, and don't support asymmetric motion partitioning
+	log2DiffMaxMinCb, amp := 2, true
+	if e.lowDelayB {
+		log2DiffMaxMinCb, amp = 3, false
+	}
+	ctuSize := 8 << uint(log2DiffMaxMinCb)
+	numCtu := ((p.Width + ctuSize - 1) / ctuSize) * ((p.Height + ctuSize - 1) / ctuSize)
+
+	e.seqParam = C.VAEncSequenceParameterBufferHEVC{
+		general_profile_idc:                      1,
+		general_level_idc:                        C.uchar(h265Level(p.Width, p.Height)),
+		intra_period:                             C.uint(params.KeyFrameInterval),
+		intra_idr_period:                         C.uint(params.KeyFrameInterval),
+		ip_period:                                1,
+		bits_per_second:                          C.uint(params.RateControl.bitsPerSecond),
+		pic_width_in_luma_samples:                C.ushort(p.Width),
+		pic_height_in_luma_samples:               C.ushort(p.Height),
+		log2_min_luma_coding_block_size_minus3:   0,
+		log2_diff_max_min_luma_coding_block_size: C.uchar(log2DiffMaxMinCb),
+		log2_min_transform_block_size_minus2:     0,
+		log2_diff_max_min_transform_block_size:   3,
+		max_transform_hierarchy_depth_inter:      3,
+		max_transform_hierarchy_depth_intra:      3,
+		vui_parameters_present_flag:              1,
+		vui_num_units_in_tick:                    1,
+		vui_time_scale:                           C.uint(p.FrameRate),
+	}
+	C.setSeqFieldsHEVC(&e.seqParam, boolToUint(amp))
+
+	e.picParam = C.VAEncPictureParameterBufferHEVC{
+		collocated_ref_pic_index: 0xFF,
+		pic_init_qp:              C.uchar(params.RateControl.InitialQP),
+	}
+	for i := range e.picParam.reference_frames {
+		e.picParam.reference_frames[i] = invalidPictureH265
+	}
+	e.sliceParam = C.VAEncSliceParameterBufferHEVC{
+		num_ctu_in_slice:   C.uint(numCtu),
+		max_num_merge_cand: 5,
+	}
+	for i := range e.sliceParam.ref_pic_list0 {
+		e.sliceParam.ref_pic_list0[i] = invalidPictureH265
+		e.sliceParam.ref_pic_list1[i] = invalidPictureH265
+	}
+	C.setSliceFieldsHEVC(&e.sliceParam)
+
+	confAttrs := []C.VAConfigAttrib{
+		{_type: C.VAConfigAttribRTFormat},
+		{_type: C.VAConfigAttribRateControl},
+	}
+	if s := C.vaGetConfigAttributes(
+		e.display,
+		C.VAProfileHEVCMain,
+		ep,
+		&confAttrs[0], 2,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to get config attrs: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if (confAttrs[0].value & C.VA_RT_FORMAT_YUV420) == 0 {
+		return nil, errors.New("the hardware encoder doesn't support YUV420")
+	}
+	if (confAttrs[1].value & C.uint(params.RateControlMode)) == 0 {
+		return nil, errors.New("the hardware encoder doesn't support specified rate control mode")
+	}
+	confAttrs[0].value = C.VA_RT_FORMAT_YUV420
+	confAttrs[1].value = C.uint(params.RateControlMode)
+
+	if s := C.vaCreateConfig(
+		e.display,
+		C.VAProfileHEVCMain,
+		ep,
+		&confAttrs[0], 2,
+		&e.confID,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to create config: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	surfAttr := C.VASurfaceAttrib{
+		_type: C.VASurfaceAttribPixelFormat,
+		flags: C.VA_SURFACE_ATTRIB_SETTABLE,
+		value: C.genValInt(C.VA_FOURCC_NV12),
+	}
+	if s := C.vaCreateSurfaces(
+		e.display,
+		C.VA_RT_FORMAT_YUV420,
+		C.uint(p.Width), C.uint(p.Height),
+		&e.surfs[0], surfaceH265Num,
+		&surfAttr, 1,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to create surfaces: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	if s := C.vaCreateContext(
+		e.display,
+		e.confID,
+		C.int(p.Width), C.int(p.Height),
+		C.VA_PROGRESSIVE,
+		&e.surfs[0], surfaceH265Num,
+		&e.ctxID,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to create context: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	return e, nil
+}
+
+func (e *encoderH265) Read() ([]byte, func(), error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, func() {}, io.EOF
+	}
+
+	input, releaseInput, err := e.frames.read(e.display, e.surfs[surfaceH265Input])
+	if err != nil {
+		return nil, func() {}, err
+	}
+	defer releaseInput()
+
+	idr := e.frameCnt%e.params.KeyFrameInterval == 0
+	e.frameCnt++
+
+	e.frParam.data.framerate = C.uint(e.rate.Calc())
+
+	if idr {
+		e.poc = 0
+	}
+	ref := e.picParam.decoded_curr_pic
+	e.picParam.decoded_curr_pic = C.VAPictureHEVC{
+		picture_id:    e.surfs[e.slotCurr],
+		pic_order_cnt: C.int(e.poc),
+	}
+
+	if idr {
+		C.setPicFieldsHEVC(&e.picParam, 1, codingTypeH265I)
+		e.picParam.nal_unit_type = nalUnitTypeH265IDRWRADL
+		e.picParam.reference_frames[0] = invalidPictureH265
+		e.sliceParam.slice_type = sliceTypeH265I
+		e.sliceParam.ref_pic_list0[0] = invalidPictureH265
+		e.sliceParam.ref_pic_list1[0] = invalidPictureH265
+	} else {
+		C.setPicFieldsHEVC(&e.picParam, 0, codingTypeH265P)
+		e.picParam.nal_unit_type = nalUnitTypeH265TrailR
+		ref.flags = C.VA_PICTURE_HEVC_RPS_ST_CURR_BEFORE
+		e.picParam.reference_frames[0] = ref
+		e.sliceParam.slice_type = sliceTypeH265P
+		e.sliceParam.ref_pic_list0[0] = ref
+		if e.lowDelayB {
+			e.sliceParam.slice_type = sliceTypeH265B
+			e.sliceParam.ref_pic_list1[0] = ref
+		}
+	}
+
+	// Prepare buffers
+	buffs := make([]C.VABufferID, 0, bufferNum)
+	type buffParam struct {
+		typ  C.VABufferType
+		n    uint
+		num  uint
+		src  unsafe.Pointer
+		hook func()
+	}
+	buffParams := []buffParam{
+		{
+			typ: C.VAEncCodedBufferType,
+			n:   uint(e.prop.Width * e.prop.Height * 3 / 2), num: 1, src: nil,
+		},
+	}
+	if idr {
+		buffParams = append(buffParams, buffParam{
+			typ: C.VAEncSequenceParameterBufferType,
+			n:   uint(unsafe.Sizeof(e.seqParam)), num: 1, src: unsafe.Pointer(&e.seqParam),
+		})
+	}
+	buffParams = append(buffParams,
+		buffParam{
+			typ: C.VAEncPictureParameterBufferType,
+			n:   uint(unsafe.Sizeof(e.picParam)), num: 1, src: unsafe.Pointer(&e.picParam),
+			hook: func() {
+				e.picParam.coded_buf = buffs[0]
+			},
+		},
+	)
+	if idr || e.rcUpdated {
+		e.rcUpdated = false
+		buffParams = append(buffParams,
+			buffParam{
+				typ: C.VAEncMiscParameterBufferType,
+				n:   uint(unsafe.Sizeof(e.hrdParam)), num: 1, src: unsafe.Pointer(&e.hrdParam),
+			},
+			buffParam{
+				typ: C.VAEncMiscParameterBufferType,
+				n:   uint(unsafe.Sizeof(e.frParam)), num: 1, src: unsafe.Pointer(&e.frParam),
+			},
+			buffParam{
+				typ: C.VAEncMiscParameterBufferType,
+				n:   uint(unsafe.Sizeof(e.rcParam)), num: 1, src: unsafe.Pointer(&e.rcParam),
+			},
+		)
+	}
+	buffParams = append(buffParams,
+		buffParam{
+			typ: C.VAEncSliceParameterBufferType,
+			n:   uint(unsafe.Sizeof(e.sliceParam)), num: 1, src: unsafe.Pointer(&e.sliceParam),
+		},
+	)
+	for _, p := range buffParams {
+		if p.hook != nil {
+			p.hook()
+		}
+		var id C.VABufferID
+		if s := C.vaCreateBufferPtr(
+			e.display, e.ctxID,
+			p.typ, C.uint(p.n), C.uint(p.num),
+			C.size_t(uintptr(p.src)),
+			&id,
+		); s != C.VA_STATUS_SUCCESS {
+			e.destroyBuffers(buffs)
+			return nil, func() {}, fmt.Errorf("failed to create buffer: %s", C.GoString(C.vaErrorStr(s)))
+		}
+		buffs = append(buffs, id)
+	}
+	defer e.destroyBuffers(buffs)
+
+	// Render picture
+	if s := C.vaBeginPicture(
+		e.display, e.ctxID,
+		input,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to begin picture: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if s := C.vaRenderPicture(
+		e.display, e.ctxID,
+		&buffs[1], // 0 is for ouput
+		C.int(len(buffs)-1),
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to render picture: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if s := C.vaEndPicture(
+		e.display, e.ctxID,
+	); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to end picture: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	// Load encoded data
+	if s := C.vaSyncSurface(e.display, input); s != C.VA_STATUS_SUCCESS {
+		return nil, func() {}, fmt.Errorf("failed to sync surface: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	e.frame, err = copyCodedBuffer(e.display, buffs[0], e.frame)
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	// Update reference
+	e.slotCurr = 1 - e.slotCurr
+	e.poc++
+
+	encoded := make([]byte, len(e.frame))
+	copy(encoded, e.frame)
+	return encoded, func() {}, nil
+}
+
+func (e *encoderH265) destroyBuffers(buffs []C.VABufferID) {
+	for _, b := range buffs {
+		C.vaDestroyBuffer(e.display, b)
+	}
+}
+
+func (e *encoderH265) SetBitRate(b int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.params.BitRate = b
+	e.params.RateControl.setBitRate(b)
+	e.seqParam.bits_per_second = C.uint(e.params.RateControl.bitsPerSecond)
+	e.hrdParam.setRateControl(e.params.RateControl)
+	e.rcParam.setRateControl(e.params.RateControl)
+	// Rate control parameters are sent with the next frame
+	e.rcUpdated = true
+	return nil
+}
+
+func (e *encoderH265) ForceKeyFrame() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.frameCnt = 0
+	return nil
+}
+
+func (e *encoderH265) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	C.vaDestroySurfaces(e.display, &e.surfs[0], C.int(len(e.surfs)))
+	C.vaDestroyContext(e.display, e.ctxID)
+	C.vaDestroyConfig(e.display, e.confID)
+	closeDisplay(e.display, e.fdDRI)
+
+	e.closed = true
+	return nil
+}
@@ -0,0 +1,142 @@
+package vaapi
+
+// The encoder writes H264 parameter sets and passes them to the driver as packed headers, since some drivers
+// generate them without VUI, and decoders buffer frames without bitstream_restriction.
+
+const (
+	h264ProfileIDCConstrainedBaseline = 66
+	// h264ConstraintFlags sets constraint_set0_flag, constraint_set1_flag and constraint_set2_flag,
+	// i.e. profile-level-id 42e0xx
+	h264ConstraintFlags = 0xE0
+)
+
+func alignUp16(v int) int {
+	return (v + 15) &^ 15
+}
+
+// h264Level returns level_idc for the frame size, assuming up to 30 fps.
+func h264Level(width, height int) uint8 {
+	switch mbs := alignUp16(width) * alignUp16(height) / 256; {
+	case mbs <= 1620:
+		return 30
+	case mbs <= 3600:
+		return 31
+	case mbs <= 8192:
+		return 40
+	default:
+		return 51
+	}
+}
+
+// h265Level returns general_level_idc (level * 30) for the frame size, assuming up to 30 fps.
+func h265Level(width, height int) uint8 {
+	switch samples := width * height; {
+	case samples <= 552960:
+		return 90
+	case samples <= 983040:
+		return 93
+	case samples <= 2228224:
+		return 120
+	default:
+		return 150
+	}
+}
+
+// h264Sequence stores SPS and PPS fields
+type h264Sequence struct {
+	width, height int
+	levelIDC      uint8
+	// log2MaxFrameNumMinus4 is log2_max_frame_num_minus4. pic_order_cnt_type is always 2 since there are
+	// no B frames, so picture order count is derived from frame_num.
+	log2MaxFrameNumMinus4 uint32
+	initQP                int32
+	frameRate             uint32
+}
+
+func (s *h264Sequence) widthInMbs() int {
+	return alignUp16(s.width) / 16
+}
+
+func (s *h264Sequence) heightInMbs() int {
+	return alignUp16(s.height) / 16
+}
+
+// crop returns frame_crop_right_offset and frame_crop_bottom_offset in units of 2 samples for 4:2:0
+func (s *h264Sequence) crop() (uint32, uint32) {
+	return uint32(alignUp16(s.width)-s.width) / 2, uint32(alignUp16(s.height)-s.height) / 2
+}
+
+// sps returns seq_parameter_set_rbsp as a NAL unit
+// Reference: ITU-T H.264 7.3.2.1.1
+func (s *h264Sequence) sps() []byte {
+	var w bitWriter
+	w.u(8, h264ProfileIDCConstrainedBaseline)
+	w.u(8, h264ConstraintFlags)
+	w.u(8, uint32(s.levelIDC))
+	w.ue(0) // seq_parameter_set_id
+	w.ue(s.log2MaxFrameNumMinus4)
+	w.ue(2)       // pic_order_cnt_type
+	w.ue(1)       // max_num_ref_frames
+	w.flag(false) // gaps_in_frame_num_value_allowed_flag
+	w.ue(uint32(s.widthInMbs() - 1))
+	w.ue(uint32(s.heightInMbs() - 1))
+	w.flag(true) // frame_mbs_only_flag
+	w.flag(true) // direct_8x8_inference_flag
+
+	right, bottom := s.crop()
+	w.flag(right != 0 || bottom != 0)
+	if right != 0 || bottom != 0 {
+		w.ue(0)
+		w.ue(right)
+		w.ue(0)
+		w.ue(bottom)
+	}
+
+	// VUI
+	w.flag(true)
+	w.flag(false) // aspect_ratio_info_present_flag
+	w.flag(false) // overscan_info_present_flag
+	w.flag(false) // video_signal_type_present_flag
+	w.flag(false) // chroma_loc_info_present_flag
+	w.flag(true)  // timing_info_present_flag
+	w.u(32, 1)    // num_units_in_tick
+	w.u(32, 2*s.frameRate)
+	w.flag(false) // fixed_frame_rate_flag
+	w.flag(false) // nal_hrd_parameters_present_flag
+	w.flag(false) // vcl_hrd_parameters_present_flag
+	w.flag(false) // pic_struct_present_flag
+	// bitstream_restriction tells decoders that frames can be output immediately
+	w.flag(true)
+	w.flag(true) // motion_vectors_over_pic_boundaries_flag
+	w.ue(0)      // max_bytes_per_pic_denom
+	w.ue(0)      // max_bits_per_mb_denom
+	w.ue(16)     // log2_max_mv_length_horizontal
+	w.ue(16)     // log2_max_mv_length_vertical
+	w.ue(0)      // max_num_reorder_frames
+	w.ue(1)      // max_dec_frame_buffering
+
+	return nalu([]byte{0x67}, w.trailing())
+}
+
+// pps returns pic_parameter_set_rbsp as a NAL unit
+// Reference: ITU-T H.264 7.3.2.2
+func (s *h264Sequence) pps() []byte {
+	var w bitWriter
+	w.ue(0)       // pic_parameter_set_id
+	w.ue(0)       // seq_parameter_set_id
+	w.flag(false) // entropy_coding_mode_flag, CAVLC in constrained baseline
+	w.flag(false) // bottom_field_pic_order_in_frame_present_flag
+	w.ue(0)       // num_slice_groups_minus1
+	w.ue(0)       // num_ref_idx_l0_default_active_minus1
+	w.ue(0)       // num_ref_idx_l1_default_active_minus1
+	w.flag(false) // weighted_pred_flag
+	w.u(2, 0)     // weighted_bipred_idc
+	w.se(s.initQP - 26)
+	w.se(0)       // pic_init_qs_minus26
+	w.se(0)       // chroma_qp_index_offset
+	w.flag(true)  // deblocking_filter_control_present_flag
+	w.flag(false) // constrained_intra_pred_flag
+	w.flag(false) // redundant_pic_cnt_present_flag
+
+	return nalu([]byte{0x68}, w.trailing())
+}
@@ -6,6 +6,7 @@
 #include <unistd.h>
 #include <va/va.h>
 #include <va/va_drm.h>
+#include <va/va_drmcommon.h>
 
 #include "helper.h"
 
@@ -36,19 +37,85 @@ VAStatus vaMapBufferSeg(VADisplay d, VABufferID buf_id, VACodedBufferSegment **s
   return vaMapBuffer(d, buf_id, (void **)seg);
 }
 
-void copyI420toNV12(
-    void *nv12,
+int copyI420toImage(
+    uint8_t *dst, const VAImage *img,
     const uint8_t *y, const uint8_t *cb, const uint8_t *cr,
-    const unsigned int n)
+    const int yStride, const int cStride,
+    const int width, const int height)
 {
-  unsigned int i, j;
-  memcpy(nv12, y, n);
-  uint8_t *p = &((uint8_t *)nv12)[n];
-  for (i = 0; i < n / 4; i++)
+  int i, j;
+  const uint8_t *u = cb;
+  const uint8_t *v = cr;
+  uint8_t *p;
+
+  for (j = 0; j < height; j++)
   {
-    p[i * 2] = cb[i];
-    p[i * 2 + 1] = cr[i];
+    memcpy(&dst[img->offsets[0] + j * img->pitches[0]], &y[j * yStride], width);
   }
+
+  switch (img->format.fourcc)
+  {
+  case VA_FOURCC_NV12:
+    for (j = 0; j < height / 2; j++)
+    {
+      p = &dst[img->offsets[1] + j * img->pitches[1]];
+      for (i = 0; i < width / 2; i++)
+      {
+        p[i * 2] = cb[j * cStride + i];
+        p[i * 2 + 1] = cr[j * cStride + i];
+      }
+    }
+    return 0;
+  case VA_FOURCC_YV12:
+    u = cr;
+    v = cb;
+    // fallthrough
+  case VA_FOURCC_I420:
+    for (j = 0; j < height / 2; j++)
+    {
+      memcpy(&dst[img->offsets[1] + j * img->pitches[1]], &u[j * cStride], width / 2);
+      memcpy(&dst[img->offsets[2] + j * img->pitches[2]], &v[j * cStride], width / 2);
+    }
+    return 0;
+  default:
+    return -1;
+  }
+}
+
+VAStatus importDMABufNV12(
+    VADisplay d, int fd, unsigned int size,
+    unsigned int width, unsigned int height,
+    const uint32_t *offsets, const uint32_t *pitches,
+    VASurfaceID *surf)
+{
+  uintptr_t handle = (uintptr_t)fd;
+  VASurfaceAttribExternalBuffers ext;
+  VASurfaceAttrib attrs[2];
+
+  memset(&ext, 0, sizeof(ext));
+  ext.pixel_format = VA_FOURCC_NV12;
+  ext.width = width;
+  ext.height = height;
+  ext.data_size = size;
+  ext.num_planes = 2;
+  ext.pitches[0] = pitches[0];
+  ext.pitches[1] = pitches[1];
+  ext.offsets[0] = offsets[0];
+  ext.offsets[1] = offsets[1];
+  ext.buffers = &handle;
+  ext.num_buffers = 1;
+
+  memset(attrs, 0, sizeof(attrs));
+  attrs[0].type = VASurfaceAttribMemoryType;
+  attrs[0].flags = VA_SURFACE_ATTRIB_SETTABLE;
+  attrs[0].value.type = VAGenericValueTypeInteger;
+  attrs[0].value.value.i = VA_SURFACE_ATTRIB_MEM_TYPE_DRM_PRIME;
+  attrs[1].type = VASurfaceAttribExternalBufferDescriptor;
+  attrs[1].flags = VA_SURFACE_ATTRIB_SETTABLE;
+  attrs[1].value.type = VAGenericValueTypePointer;
+  attrs[1].value.value.p = &ext;
+
+  return vaCreateSurfaces(d, VA_RT_FORMAT_YUV420, width, height, surf, 1, attrs, 2);
 }
 
 #endif // HAS_VAAPI
@@ -13,9 +13,15 @@ VAStatus vaCreateBufferPtr(
     size_t dataptr,
     VABufferID *buf_id);
 VAStatus vaMapBufferSeg(VADisplay d, VABufferID buf_id, VACodedBufferSegment **seg);
-void copyI420toNV12(
-    void *nv12,
+int copyI420toImage(
+    uint8_t *dst, const VAImage *img,
     const uint8_t *y, const uint8_t *cb, const uint8_t *cr,
-    const unsigned int n);
+    const int yStride, const int cStride,
+    const int width, const int height);
+VAStatus importDMABufNV12(
+    VADisplay d, int fd, unsigned int size,
+    unsigned int width, unsigned int height,
+    const uint32_t *offsets, const uint32_t *pitches,
+    VASurfaceID *surf);
 
 #endif // HAS_VAAPI
@@ -12,6 +12,7 @@ type ParamsVP8 struct {
 	Sequence        SequenceParamVP8
 	RateControlMode RateControlMode
 	RateControl     RateControlParam
+	LowPower        LowPowerMode
 }
 
 // SequenceParamVP8 represents VAEncSequenceParameterBufferVP8 and other parameter buffers.
@@ -45,7 +46,9 @@ func NewVP8Params() (ParamsVP8, error) {
 
 // RTPCodec represents the codec metadata
 func (p *ParamsVP8) RTPCodec() *codec.RTPCodec {
-	return codec.NewRTPVP8Codec(90000)
+	c := codec.NewRTPVP8Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
 }
 
 // BuildVideoEncoder builds VP8 encoder with given params
@@ -58,6 +61,7 @@ type ParamsVP9 struct {
 	codec.BaseParams
 	RateControlMode RateControlMode
 	RateControl     RateControlParam
+	LowPower        LowPowerMode
 }
 
 // RateControlParam represents VAEncMiscParameterRateControl.
@@ -75,6 +79,11 @@ type RateControlParam struct {
 	MaxQP      uint
 }
 
+// setBitRate calculates the maximum bit-rate from the target bit-rate.
+func (p *RateControlParam) setBitRate(bitRate int) {
+	p.bitsPerSecond = uint(float32(bitRate) / (0.01 * float32(p.TargetPercentage)))
+}
+
 // RateControlMode represents rate control mode.
 // Note that supported mode depends on the codec and acceleration hardware.
 type RateControlMode uint
@@ -94,6 +103,21 @@ const (
 	RateControlAVBR           RateControlMode = 0x00000800
 )
 
+// LowPowerMode represents whether the low power entrypoint, VAEntrypointEncSliceLP, is used.
+// The low power entrypoint uses the hardware's fixed function encoder, e.g. VDEnc on Intel GPUs,
+// and it's the only entrypoint on some hardware.
+type LowPowerMode int
+
+// LowPowerMode values.
+const (
+	// LowPowerAuto uses VAEntrypointEncSlice if available, and falls back to VAEntrypointEncSliceLP.
+	LowPowerAuto LowPowerMode = iota
+	// LowPowerDisabled uses VAEntrypointEncSlice only.
+	LowPowerDisabled
+	// LowPowerEnabled uses VAEntrypointEncSliceLP if available, and falls back to VAEntrypointEncSlice.
+	LowPowerEnabled
+)
+
 // NewVP9Params returns default parameters of VP9 codec.
 func NewVP9Params() (ParamsVP9, error) {
 	return ParamsVP9{
@@ -114,10 +138,90 @@ func NewVP9Params() (ParamsVP9, error) {
 
 // RTPCodec represents the codec metadata
 func (p *ParamsVP9) RTPCodec() *codec.RTPCodec {
-	return codec.NewRTPVP9Codec(90000)
+	c := codec.NewRTPVP9Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
 }
 
 // BuildVideoEncoder builds VP9 encoder with given params
 func (p *ParamsVP9) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
 	return newVP9Encoder(r, property, *p)
 }
+
+// ParamsH264 stores H264 encoding parameters.
+// The stream is encoded in constrained baseline profile without B frames.
+type ParamsH264 struct {
+	codec.BaseParams
+	RateControlMode RateControlMode
+	RateControl     RateControlParam
+	LowPower        LowPowerMode
+}
+
+// NewH264Params returns default parameters of H264 codec.
+func NewH264Params() (ParamsH264, error) {
+	return ParamsH264{
+		BaseParams: codec.BaseParams{
+			BitRate:          1000000,
+			KeyFrameInterval: 60,
+		},
+		RateControlMode: RateControlVBR,
+		RateControl: RateControlParam{
+			TargetPercentage: 80,
+			WindowSize:       1500,
+			InitialQP:        26,
+			MinQP:            10,
+			MaxQP:            51,
+		},
+	}, nil
+}
+
+// RTPCodec represents the codec metadata
+func (p *ParamsH264) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPH264Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
+}
+
+// BuildVideoEncoder builds H264 encoder with given params
+func (p *ParamsH264) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
+	return newH264Encoder(r, property, *p)
+}
+
+// ParamsH265 stores H265 encoding parameters.
+// The stream is encoded in main profile without frame reordering.
+type ParamsH265 struct {
+	codec.BaseParams
+	RateControlMode RateControlMode
+	RateControl     RateControlParam
+	LowPower        LowPowerMode
+}
+
+// NewH265Params returns default parameters of H265 codec.
+func NewH265Params() (ParamsH265, error) {
+	return ParamsH265{
+		BaseParams: codec.BaseParams{
+			BitRate:          800000,
+			KeyFrameInterval: 60,
+		},
+		RateControlMode: RateControlVBR,
+		RateControl: RateControlParam{
+			TargetPercentage: 80,
+			WindowSize:       1500,
+			InitialQP:        26,
+			MinQP:            10,
+			MaxQP:            51,
+		},
+	}, nil
+}
+
+// RTPCodec represents the codec metadata
+func (p *ParamsH265) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPH265Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
+}
+
+// BuildVideoEncoder builds H265 encoder with given params
+func (p *ParamsH265) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
+	return newH265Encoder(r, property, *p)
+}
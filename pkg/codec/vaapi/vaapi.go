@@ -6,8 +6,13 @@
 package vaapi
 
 import (
+	"errors"
 	"fmt"
+	"image"
 	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
 )
 
 // #cgo pkg-config: libva libva-drm
@@ -60,3 +65,209 @@ func closeDisplay(d C.VADisplay, fd C.int) {
 	C.vaTerminate(d)
 	C.close(fd)
 }
+
+// selectEntrypoint returns the profile's encoder entrypoint according to the low power mode.
+func selectEntrypoint(d C.VADisplay, profile C.VAProfile, mode LowPowerMode) (C.VAEntrypoint, error) {
+	var numEntrypoints C.int
+	entrypoints := make([]C.VAEntrypoint, int(C.vaMaxNumEntrypoints(d)))
+
+	if s := C.vaQueryConfigEntrypoints(
+		d,
+		profile,
+		&entrypoints[0],
+		&numEntrypoints,
+	); s != C.VA_STATUS_SUCCESS {
+		return 0, fmt.Errorf("failed to query libva entrypoints: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	var slice, lowPower bool
+	for _, ep := range entrypoints[:numEntrypoints] {
+		switch ep {
+		case C.VAEntrypointEncSlice:
+			slice = true
+		case C.VAEntrypointEncSliceLP:
+			lowPower = true
+		}
+	}
+
+	switch {
+	case mode == LowPowerEnabled && lowPower:
+		return C.VAEntrypointEncSliceLP, nil
+	case mode != LowPowerEnabled && slice:
+		return C.VAEntrypointEncSlice, nil
+	case mode != LowPowerDisabled && lowPower:
+		return C.VAEntrypointEncSliceLP, nil
+	case mode == LowPowerEnabled && slice:
+		return C.VAEntrypointEncSlice, nil
+	}
+	return 0, errors.New("libva entrypoint not found")
+}
+
+// uploadImage copies img to the surface following the surface's plane layout and pitches.
+func uploadImage(d C.VADisplay, surf C.VASurfaceID, img *image.YCbCr) error {
+	var vaImg C.VAImage
+	var rawBuf unsafe.Pointer
+	if s := C.vaDeriveImage(d, surf, &vaImg); s != C.VA_STATUS_SUCCESS {
+		return fmt.Errorf("failed to derive image: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if s := C.vaMapBuffer(d, vaImg.buf, &rawBuf); s != C.VA_STATUS_SUCCESS {
+		C.vaDestroyImage(d, vaImg.image_id)
+		return fmt.Errorf("failed to map buffer: %s", C.GoString(C.vaErrorStr(s)))
+	}
+
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if w := int(vaImg.width); w < width {
+		width = w
+	}
+	if h := int(vaImg.height); h < height {
+		height = h
+	}
+	ret := C.copyI420toImage(
+		(*C.uint8_t)(rawBuf), &vaImg,
+		(*C.uint8_t)(&img.Y[0]),
+		(*C.uint8_t)(&img.Cb[0]),
+		(*C.uint8_t)(&img.Cr[0]),
+		C.int(img.YStride), C.int(img.CStride),
+		C.int(width), C.int(height),
+	)
+
+	if s := C.vaUnmapBuffer(d, vaImg.buf); s != C.VA_STATUS_SUCCESS {
+		C.vaDestroyImage(d, vaImg.image_id)
+		return fmt.Errorf("failed to unmap buffer: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if s := C.vaDestroyImage(d, vaImg.image_id); s != C.VA_STATUS_SUCCESS {
+		return fmt.Errorf("failed to destroy image: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	if ret != 0 {
+		return fmt.Errorf("unsupported surface format: %x", uint32(vaImg.format.fourcc))
+	}
+	return nil
+}
+
+var errDMABufFormat = errors.New("only NV12 can be imported from DMA-BUF")
+
+// frameSource reads encoder input frames. DMA-BUF frames, e.g. from a V4L2 camera with
+// V4L2Options.ExportDMABuf, are imported as surfaces without copies. Other frames are converted into I420
+// and uploaded to the encoder's input surface.
+type frameSource struct {
+	r      video.Reader
+	frame  image.Image
+	toI420 video.Reader
+	// noImport is set once the driver fails to import a DMA-BUF, so later frames are uploaded.
+	noImport bool
+}
+
+func newFrameSource(r video.Reader) *frameSource {
+	s := &frameSource{r: r}
+	s.toI420 = video.ToI420(video.ReaderFunc(func() (image.Image, func(), error) {
+		return s.frame, func() {}, nil
+	}))
+	return s
+}
+
+// read reads a frame and returns its surface, which is either imported or surf. release has to be
+// called after the frame is encoded.
+func (s *frameSource) read(d C.VADisplay, surf C.VASurfaceID) (C.VASurfaceID, func(), error) {
+	img, _, err := s.r.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if buf, ok := img.(video.DMABufImage); ok && !s.noImport {
+		imported, err := importDMABuf(d, buf.ExportDMABuf())
+		if err == nil {
+			return imported, func() { C.vaDestroySurfaces(d, &imported, 1) }, nil
+		}
+		// Fall back to copying frames
+		s.noImport = true
+	}
+
+	s.frame = img
+	i420, _, err := s.toI420.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := uploadImage(d, surf, i420.(*image.YCbCr)); err != nil {
+		return 0, nil, err
+	}
+	return surf, func() {}, nil
+}
+
+// importDMABuf creates a surface that refers to the frame in buf.
+func importDMABuf(d C.VADisplay, buf video.DMABuf) (C.VASurfaceID, error) {
+	if buf.Format != frame.FormatNV12 || len(buf.Planes) != 2 {
+		return 0, errDMABufFormat
+	}
+	var offsets, pitches [2]C.uint32_t
+	for i, plane := range buf.Planes {
+		offsets[i] = C.uint32_t(plane.Offset)
+		pitches[i] = C.uint32_t(plane.Pitch)
+	}
+
+	var surf C.VASurfaceID
+	if s := C.importDMABufNV12(
+		d, C.int(buf.FD), C.uint(buf.Size),
+		C.uint(buf.Width), C.uint(buf.Height),
+		&offsets[0], &pitches[0],
+		&surf,
+	); s != C.VA_STATUS_SUCCESS {
+		return 0, fmt.Errorf("failed to import DMA-BUF: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	return surf, nil
+}
+
+// copyCodedBuffer returns the data of every segment in the coded buffer.
+func copyCodedBuffer(d C.VADisplay, buf C.VABufferID, dst []byte) ([]byte, error) {
+	var seg *C.VACodedBufferSegment
+	if s := C.vaMapBufferSeg(d, buf, &seg); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to map buffer: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	dst = dst[:0]
+	for ; seg != nil; seg = (*C.VACodedBufferSegment)(seg.next) {
+		dst = append(dst, C.GoBytes(seg.buf, C.int(seg.size))...)
+	}
+	if s := C.vaUnmapBuffer(d, buf); s != C.VA_STATUS_SUCCESS {
+		return nil, fmt.Errorf("failed to unmap buffer: %s", C.GoString(C.vaErrorStr(s)))
+	}
+	return dst, nil
+}
+
+func (p *hrdParam) setRateControl(rc RateControlParam) {
+	p.data.initial_buffer_fullness = C.uint(rc.bitsPerSecond * rc.WindowSize / 2000)
+	p.data.buffer_size = C.uint(rc.bitsPerSecond * rc.WindowSize / 1000)
+}
+
+func (p *rcParam) setRateControl(rc RateControlParam) {
+	p.data.window_size = C.uint(rc.WindowSize)
+	p.data.initial_qp = C.uint(rc.InitialQP)
+	p.data.min_qp = C.uint(rc.MinQP)
+	p.data.max_qp = C.uint(rc.MaxQP)
+	p.data.bits_per_second = C.uint(rc.bitsPerSecond)
+	p.data.target_percentage = C.uint(rc.TargetPercentage)
+}
+
+// packedHeader is a header written by the encoder. It's passed to the driver with
+// VAEncPackedHeaderParameterBuffer and VAEncPackedHeaderDataBuffer.
+type packedHeader struct {
+	param C.VAEncPackedHeaderParameterBuffer
+	data  []byte
+}
+
+// newPackedHeader returns the NAL unit's packed header. The NAL unit already has emulation prevention bytes.
+func newPackedHeader(typ C.uint, nalu []byte) packedHeader {
+	return packedHeader{
+		param: C.VAEncPackedHeaderParameterBuffer{
+			_type:               typ,
+			bit_length:          C.uint(len(nalu) * 8),
+			has_emulation_bytes: 1,
+		},
+		data: nalu,
+	}
+}
+
+func boolToUint(b bool) C.uint {
+	if b {
+		return 1
+	}
+	return 0
+}
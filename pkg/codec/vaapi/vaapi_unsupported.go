@@ -22,3 +22,11 @@ func newVP8Encoder(r video.Reader, p prop.Media, params ParamsVP8) (codec.ReadCl
 func newVP9Encoder(r video.Reader, p prop.Media, params ParamsVP9) (codec.ReadCloser, error) {
 	return nil, errNotSupported
 }
+
+func newH264Encoder(r video.Reader, p prop.Media, params ParamsH264) (codec.ReadCloser, error) {
+	return nil, errNotSupported
+}
+
+func newH265Encoder(r video.Reader, p prop.Media, params ParamsH265) (codec.ReadCloser, error) {
+	return nil, errNotSupported
+}
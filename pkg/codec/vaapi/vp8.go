@@ -97,7 +97,8 @@ type encoderVP8 struct {
 	prop     prop.Media
 	params   ParamsVP8
 
-	rate *framerateDetector
+	rate      *framerateDetector
+	rcUpdated bool
 
 	mu     sync.Mutex
 	closed bool
@@ -118,8 +119,7 @@ func newVP8Encoder(r video.Reader, p prop.Media, params ParamsVP8) (codec.ReadCl
 		p.FrameRate = 30
 	}
 
-	params.RateControl.bitsPerSecond =
-		uint(float32(params.BitRate) / (0.01 * float32(params.RateControl.TargetPercentage)))
+	params.RateControl.setBitRate(params.BitRate)
 
 	var error_resilient_value uint
 	if params.Sequence.ErrorResilient {
@@ -213,26 +213,9 @@ func newVP8Encoder(r video.Reader, p prop.Media, params ParamsVP8) (codec.ReadCl
 		return nil, fmt.Errorf("failed to init libva: %s", C.GoString(C.vaErrorStr(s)))
 	}
 
-	var numEntrypoints C.int
-	entrypoints := make([]C.VAEntrypoint, int(C.vaMaxNumEntrypoints(e.display)))
-
-	if s := C.vaQueryConfigEntrypoints(
-		e.display,
-		C.VAProfileVP8Version0_3,
-		&entrypoints[0],
-		&numEntrypoints,
-	); s != C.VA_STATUS_SUCCESS {
-		return nil, fmt.Errorf("failed to query libva entrypoints: %s", C.GoString(C.vaErrorStr(s)))
-	}
-
-	var epFound bool
-	for i := 0; i < int(numEntrypoints); i++ {
-		if entrypoints[i] == C.VAEntrypointEncSlice {
-			epFound = true
-		}
-	}
-	if !epFound {
-		return nil, errors.New("libva entrypoint not found")
+	ep, err := selectEntrypoint(e.display, C.VAProfileVP8Version0_3, params.LowPower)
+	if err != nil {
+		return nil, err
 	}
 
 	confAttrs := []C.VAConfigAttrib{
@@ -242,7 +225,7 @@ func newVP8Encoder(r video.Reader, p prop.Media, params ParamsVP8) (codec.ReadCl
 	if s := C.vaGetConfigAttributes(
 		e.display,
 		C.VAProfileVP8Version0_3,
-		C.VAEntrypointEncSlice,
+		ep,
 		&confAttrs[0], 2,
 	); s != C.VA_STATUS_SUCCESS {
 		return nil, fmt.Errorf("failed to get config attrs: %s", C.GoString(C.vaErrorStr(s)))
@@ -259,7 +242,7 @@ func newVP8Encoder(r video.Reader, p prop.Media, params ParamsVP8) (codec.ReadCl
 	if s := C.vaCreateConfig(
 		e.display,
 		C.VAProfileVP8Version0_3,
-		C.VAEntrypointEncSlice,
+		ep,
 		&confAttrs[0], 2,
 		&e.confID,
 	); s != C.VA_STATUS_SUCCESS {
@@ -392,7 +375,8 @@ func (e *encoderVP8) Read() ([]byte, func(), error) {
 			n:   uint(unsafe.Sizeof(e.qMat)), num: 1, src: unsafe.Pointer(&e.qMat),
 		},
 	}
-	if kf {
+	if kf || e.rcUpdated {
+		e.rcUpdated = false
 		buffParams = append(buffParams,
 			buffParam{
 				typ: C.VAEncMiscParameterBufferType,
@@ -429,32 +413,8 @@ func (e *encoderVP8) Read() ([]byte, func(), error) {
 	}
 
 	// Upload image
-	var vaImg C.VAImage
-	var rawBuf unsafe.Pointer
-	if s := C.vaDeriveImage(e.display, e.surfs[surfaceVP8Input], &vaImg); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to derive image: %s", C.GoString(C.vaErrorStr(s)))
-	}
-	if s := C.vaMapBuffer(e.display, vaImg.buf, &rawBuf); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to map buffer: %s", C.GoString(C.vaErrorStr(s)))
-	}
-	// TODO: use vaImg.pitches to support padding
-	C.memcpy(
-		unsafe.Pointer(uintptr(rawBuf)+uintptr(vaImg.offsets[0])),
-		unsafe.Pointer(&yuvImg.Y[0]), C.size_t(len(yuvImg.Y)),
-	)
-	C.memcpy(
-		unsafe.Pointer(uintptr(rawBuf)+uintptr(vaImg.offsets[1])),
-		unsafe.Pointer(&yuvImg.Cb[0]), C.size_t(len(yuvImg.Cb)),
-	)
-	C.memcpy(
-		unsafe.Pointer(uintptr(rawBuf)+uintptr(vaImg.offsets[2])),
-		unsafe.Pointer(&yuvImg.Cr[0]), C.size_t(len(yuvImg.Cr)),
-	)
-	if s := C.vaUnmapBuffer(e.display, vaImg.buf); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to unmap buffer: %s", C.GoString(C.vaErrorStr(s)))
-	}
-	if s := C.vaDestroyImage(e.display, vaImg.image_id); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to destroy image: %s", C.GoString(C.vaErrorStr(s)))
+	if err := uploadImage(e.display, e.surfs[surfaceVP8Input], yuvImg); err != nil {
+		return nil, func() {}, err
 	}
 
 	if s := C.vaRenderPicture(
@@ -541,11 +501,25 @@ func (e *encoderVP8) Read() ([]byte, func(), error) {
 }
 
 func (e *encoderVP8) SetBitRate(b int) error {
-	panic("SetBitRate is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.params.BitRate = b
+	e.params.RateControl.setBitRate(b)
+	e.seqParam.bits_per_second = C.uint(e.params.RateControl.bitsPerSecond)
+	e.hrdParam.setRateControl(e.params.RateControl)
+	e.rcParam.setRateControl(e.params.RateControl)
+	// Rate control parameters are sent with the next frame
+	e.rcUpdated = true
+	return nil
 }
 
 func (e *encoderVP8) ForceKeyFrame() error {
-	panic("ForceKeyFrame is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.frameCnt = 0
+	return nil
 }
 
 func (e *encoderVP8) Close() error {
@@ -89,7 +89,8 @@ type encoderVP9 struct {
 	prop     prop.Media
 	params   ParamsVP9
 
-	rate *framerateDetector
+	rate      *framerateDetector
+	rcUpdated bool
 
 	mu     sync.Mutex
 	closed bool
@@ -110,8 +111,7 @@ func newVP9Encoder(r video.Reader, p prop.Media, params ParamsVP9) (codec.ReadCl
 		p.FrameRate = 30
 	}
 
-	params.RateControl.bitsPerSecond =
-		uint(float32(params.BitRate) / (0.01 * float32(params.RateControl.TargetPercentage)))
+	params.RateControl.setBitRate(params.BitRate)
 
 	// Parameters are from https://github.com/intel/libva-utils/blob/master/encode/vp9enc.c
 	e := &encoderVP9{
@@ -202,26 +202,9 @@ func newVP9Encoder(r video.Reader, p prop.Media, params ParamsVP9) (codec.ReadCl
 		return nil, fmt.Errorf("failed to init libva: %s", C.GoString(C.vaErrorStr(s)))
 	}
 
-	var numEntrypoints C.int
-	entrypoints := make([]C.VAEntrypoint, int(C.vaMaxNumEntrypoints(e.display)))
-
-	if s := C.vaQueryConfigEntrypoints(
-		e.display,
-		C.VAProfileVP9Profile0,
-		&entrypoints[0],
-		&numEntrypoints,
-	); s != C.VA_STATUS_SUCCESS {
-		return nil, fmt.Errorf("failed to query libva entrypoints: %s", C.GoString(C.vaErrorStr(s)))
-	}
-
-	var epFound bool
-	for i := 0; i < int(numEntrypoints); i++ {
-		if entrypoints[i] == C.VAEntrypointEncSlice {
-			epFound = true
-		}
-	}
-	if !epFound {
-		return nil, errors.New("libva entrypoint not found")
+	ep, err := selectEntrypoint(e.display, C.VAProfileVP9Profile0, params.LowPower)
+	if err != nil {
+		return nil, err
 	}
 
 	confAttrs := []C.VAConfigAttrib{
@@ -231,7 +214,7 @@ func newVP9Encoder(r video.Reader, p prop.Media, params ParamsVP9) (codec.ReadCl
 	if s := C.vaGetConfigAttributes(
 		e.display,
 		C.VAProfileVP9Profile0,
-		C.VAEntrypointEncSlice,
+		ep,
 		&confAttrs[0], 2,
 	); s != C.VA_STATUS_SUCCESS {
 		return nil, fmt.Errorf("failed to get config attrs: %s", C.GoString(C.vaErrorStr(s)))
@@ -248,7 +231,7 @@ func newVP9Encoder(r video.Reader, p prop.Media, params ParamsVP9) (codec.ReadCl
 	if s := C.vaCreateConfig(
 		e.display,
 		C.VAProfileVP9Profile0,
-		C.VAEntrypointEncSlice,
+		ep,
 		&confAttrs[0], 2,
 		&e.confID,
 	); s != C.VA_STATUS_SUCCESS {
@@ -355,7 +338,8 @@ func (e *encoderVP9) Read() ([]byte, func(), error) {
 			n:   uint(unsafe.Sizeof(e.qMat)), num: 1, src: unsafe.Pointer(&e.qMat),
 		},
 	}
-	if kf {
+	if kf || e.rcUpdated {
+		e.rcUpdated = false
 		buffParams = append(buffParams,
 			buffParam{
 				typ: C.VAEncMiscParameterBufferType,
@@ -392,27 +376,8 @@ func (e *encoderVP9) Read() ([]byte, func(), error) {
 	}
 
 	// Upload image
-	var vaImg C.VAImage
-	var rawBuf unsafe.Pointer
-	if s := C.vaDeriveImage(e.display, e.surfs[surfaceVP9Input], &vaImg); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to derive image: %s", C.GoString(C.vaErrorStr(s)))
-	}
-	if s := C.vaMapBuffer(e.display, vaImg.buf, &rawBuf); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to map buffer: %s", C.GoString(C.vaErrorStr(s)))
-	}
-	// TODO: use vaImg.pitches to support padding
-	C.copyI420toNV12(
-		rawBuf,
-		(*C.uchar)(&yuvImg.Y[0]),
-		(*C.uchar)(&yuvImg.Cb[0]),
-		(*C.uchar)(&yuvImg.Cr[0]),
-		C.uint(len(yuvImg.Y)),
-	)
-	if s := C.vaUnmapBuffer(e.display, vaImg.buf); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to unmap buffer: %s", C.GoString(C.vaErrorStr(s)))
-	}
-	if s := C.vaDestroyImage(e.display, vaImg.image_id); s != C.VA_STATUS_SUCCESS {
-		return nil, func() {}, fmt.Errorf("failed to destroy image: %s", C.GoString(C.vaErrorStr(s)))
+	if err := uploadImage(e.display, e.surfs[surfaceVP9Input], yuvImg); err != nil {
+		return nil, func() {}, err
 	}
 
 	if s := C.vaRenderPicture(
@@ -476,11 +441,25 @@ func (e *encoderVP9) Read() ([]byte, func(), error) {
 }
 
 func (e *encoderVP9) SetBitRate(b int) error {
-	panic("SetBitRate is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.params.BitRate = b
+	e.params.RateControl.setBitRate(b)
+	e.seqParam.bits_per_second = C.uint(e.params.RateControl.bitsPerSecond)
+	e.hrdParam.setRateControl(e.params.RateControl)
+	e.rcParam.setRateControl(e.params.RateControl)
+	// Rate control parameters are sent with the next frame
+	e.rcUpdated = true
+	return nil
 }
 
 func (e *encoderVP9) ForceKeyFrame() error {
-	panic("ForceKeyFrame is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.frameCnt = 0
+	return nil
 }
 
 func (e *encoderVP9) Close() error {
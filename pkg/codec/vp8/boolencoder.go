@@ -0,0 +1,73 @@
+package vp8
+
+// boolEncoder is the boolean entropy encoder specified in RFC 6386 section 7.
+// prob is always the probability of a 0 bit, scaled to 1..255.
+type boolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+// reset starts a new partition, reusing the previous partition's buffer.
+func (e *boolEncoder) reset() {
+	*e = boolEncoder{
+		buf:      e.buf[:0],
+		rng:      255,
+		bitCount: 24,
+	}
+}
+
+// addOneToOutput propagates the carry into bytes already written.
+func (e *boolEncoder) addOneToOutput() {
+	i := len(e.buf) - 1
+	for ; i >= 0 && e.buf[i] == 0xff; i-- {
+		e.buf[i] = 0
+	}
+	e.buf[i]++
+}
+
+func (e *boolEncoder) writeBool(prob uint8, b bool) {
+	split := 1 + (((e.rng - 1) * uint32(prob)) >> 8)
+	if b {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			e.addOneToOutput()
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+// writeUint writes n bits of v, most significant first, with the given probability.
+func (e *boolEncoder) writeUint(prob uint8, v uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.writeBool(prob, (v>>uint(i))&1 == 1)
+	}
+}
+
+// writeLiteral writes an unsigned n-bit literal, L(n) in the spec.
+func (e *boolEncoder) writeLiteral(v uint32, n int) {
+	e.writeUint(uniformProb, v, n)
+}
+
+// flush pads the remaining bits so the decoder can read every written bit,
+// and returns the encoded data.
+func (e *boolEncoder) flush() []byte {
+	for i := 0; i < 32; i++ {
+		e.writeBool(uniformProb, false)
+	}
+	return e.buf
+}
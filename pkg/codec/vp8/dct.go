@@ -0,0 +1,134 @@
+package vp8
+
+// The forward transforms are libvpx's, and the inverse transforms are the ones specified in
+// sections 14.3 and 14.4. The encoder must reconstruct frames exactly like the decoder does,
+// since reconstructed pixels are used for prediction.
+
+// fdct4 transforms a 4x4 residual block in raster order.
+func fdct4(in *[16]int32, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		ip := in[4*i : 4*i+4]
+		a := (ip[0] + ip[3]) * 8
+		b := (ip[1] + ip[2]) * 8
+		c := (ip[1] - ip[2]) * 8
+		d := (ip[0] - ip[3]) * 8
+		tmp[4*i+0] = a + b
+		tmp[4*i+2] = a - b
+		tmp[4*i+1] = (c*2217 + d*5352 + 14500) >> 12
+		tmp[4*i+3] = (d*2217 - c*5352 + 7500) >> 12
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[12+i]
+		b := tmp[4+i] + tmp[8+i]
+		c := tmp[4+i] - tmp[8+i]
+		d := tmp[i] - tmp[12+i]
+		out[i] = (a + b + 7) >> 4
+		out[8+i] = (a - b + 7) >> 4
+		out[4+i] = (c*2217+d*5352+12000)>>16 + btoi(d != 0)
+		out[12+i] = (d*2217 - c*5352 + 51000) >> 16
+	}
+}
+
+// fwht4 transforms the DC coefficients of 16 luma blocks in raster order.
+func fwht4(in *[16]int32, out *[16]int32) {
+	var tmp [16]int32
+	for i := 0; i < 4; i++ {
+		ip := in[4*i : 4*i+4]
+		a := (ip[0] + ip[2]) * 4
+		d := (ip[1] + ip[3]) * 4
+		c := (ip[1] - ip[3]) * 4
+		b := (ip[0] - ip[2]) * 4
+		tmp[4*i+0] = a + d + btoi(a != 0)
+		tmp[4*i+1] = b + c
+		tmp[4*i+2] = b - c
+		tmp[4*i+3] = a - d
+	}
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[8+i]
+		d := tmp[4+i] + tmp[12+i]
+		c := tmp[4+i] - tmp[12+i]
+		b := tmp[i] - tmp[8+i]
+		v := [4]int32{a + d, b + c, b - c, a - d}
+		for j := range v {
+			if v[j] < 0 {
+				v[j]++
+			}
+			out[4*j+i] = (v[j] + 3) >> 3
+		}
+	}
+}
+
+// idct4 adds the inverse transformed coefficients to the 4x4 prediction at dst.
+func idct4(coeff *[16]int32, dst []uint8, stride int) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2).
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2).
+	)
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := coeff[i] + coeff[8+i]
+		b := coeff[i] - coeff[8+i]
+		c := (coeff[4+i]*c2)>>16 - (coeff[12+i]*c1)>>16
+		d := (coeff[4+i]*c1)>>16 + (coeff[12+i]*c2)>>16
+		m[i][0] = a + d
+		m[i][1] = b + c
+		m[i][2] = b - c
+		m[i][3] = a - d
+	}
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		row := dst[j*stride : j*stride+4]
+		row[0] = clip8(int32(row[0]) + (a+d)>>3)
+		row[1] = clip8(int32(row[1]) + (b+c)>>3)
+		row[2] = clip8(int32(row[2]) + (b-c)>>3)
+		row[3] = clip8(int32(row[3]) + (a-d)>>3)
+	}
+}
+
+// iwht4 restores the DC coefficients of 16 luma blocks in raster order.
+func iwht4(in *[16]int32, out *[16]int32) {
+	var m [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := in[i] + in[12+i]
+		a1 := in[4+i] + in[8+i]
+		a2 := in[4+i] - in[8+i]
+		a3 := in[i] - in[12+i]
+		m[i] = a0 + a1
+		m[8+i] = a0 - a1
+		m[4+i] = a3 + a2
+		m[12+i] = a3 - a2
+	}
+	for i := 0; i < 4; i++ {
+		dc := m[4*i] + 3
+		a0 := dc + m[4*i+3]
+		a1 := m[4*i+1] + m[4*i+2]
+		a2 := m[4*i+1] - m[4*i+2]
+		a3 := dc - m[4*i+3]
+		out[4*i+0] = (a0 + a1) >> 3
+		out[4*i+1] = (a3 + a2) >> 3
+		out[4*i+2] = (a0 - a1) >> 3
+		out[4*i+3] = (a3 - a2) >> 3
+	}
+}
+
+func clip8(i int32) uint8 {
+	if i < 0 {
+		return 0
+	}
+	if i > 255 {
+		return 255
+	}
+	return uint8(i)
+}
+
+func btoi(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,353 @@
+package vp8
+
+// Borders used to predict macroblocks on the frame edges, section 12.2.
+const (
+	borderTop  = 0x7f
+	borderLeft = 0x81
+)
+
+// edge stores the reconstructed pixels above and left of a block, which are used for prediction.
+type edge struct {
+	top     [16]uint8
+	left    [16]uint8
+	corner  uint8
+	hasTop  bool
+	hasLeft bool
+}
+
+func loadEdge(pix []uint8, stride, x, y, n int) edge {
+	e := edge{hasTop: y > 0, hasLeft: x > 0}
+	switch {
+	case y == 0:
+		e.corner = borderTop
+	case x == 0:
+		e.corner = borderLeft
+	default:
+		e.corner = pix[(y-1)*stride+x-1]
+	}
+	for i := 0; i < n; i++ {
+		if e.hasTop {
+			e.top[i] = pix[(y-1)*stride+x+i]
+		} else {
+			e.top[i] = borderTop
+		}
+		if e.hasLeft {
+			e.left[i] = pix[(y+i)*stride+x-1]
+		} else {
+			e.left[i] = borderLeft
+		}
+	}
+	return e
+}
+
+// predict fills the n x n block with mode's prediction. DC prediction only uses the available
+// edges, as specified in section 12.2.
+func predict(dst []uint8, n int, mode uint8, e *edge) {
+	switch mode {
+	case predDC:
+		var sum, shift uint32
+		if e.hasTop {
+			for i := 0; i < n; i++ {
+				sum += uint32(e.top[i])
+			}
+			shift++
+		}
+		if e.hasLeft {
+			for i := 0; i < n; i++ {
+				sum += uint32(e.left[i])
+			}
+			shift++
+		}
+		dc := uint8(0x80)
+		if shift > 0 {
+			if n == 16 {
+				shift += 3
+			} else {
+				shift += 2
+			}
+			dc = uint8((sum + 1<<(shift-1)) >> shift)
+		}
+		for i := range dst[:n*n] {
+			dst[i] = dc
+		}
+	case predTM:
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				dst[j*n+i] = clip8(int32(e.left[j]) + int32(e.top[i]) - int32(e.corner))
+			}
+		}
+	case predVE:
+		for j := 0; j < n; j++ {
+			copy(dst[j*n:j*n+n], e.top[:n])
+		}
+	case predHE:
+		for j := 0; j < n; j++ {
+			for i := 0; i < n; i++ {
+				dst[j*n+i] = e.left[j]
+			}
+		}
+	}
+}
+
+func sad(pred []uint8, n int, src []uint8, stride int) int {
+	s := 0
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			d := int(pred[j*n+i]) - int(src[j*stride+i])
+			if d < 0 {
+				d = -d
+			}
+			s += d
+		}
+	}
+	return s
+}
+
+// encodeMacroblock chooses prediction modes, writes residual tokens and reconstructs the
+// macroblock.
+func (e *encoder) encodeMacroblock(mbx, mby int) macroblock {
+	var (
+		mb           macroblock
+		predY        [256]uint8
+		predU, predV [64]uint8
+		tmp          [256]uint8
+	)
+
+	// Choose the modes with the smallest SAD.
+	ys, cs := e.src.YStride, e.src.CStride
+	yOff, cOff := 16*mby*ys+16*mbx, 8*mby*cs+8*mbx
+	yEdge := loadEdge(e.rec.Y, ys, 16*mbx, 16*mby, 16)
+	uEdge := loadEdge(e.rec.Cb, cs, 8*mbx, 8*mby, 8)
+	vEdge := loadEdge(e.rec.Cr, cs, 8*mbx, 8*mby, 8)
+	bestY, bestC := -1, -1
+	for mode := uint8(0); mode < nPredMode; mode++ {
+		predict(tmp[:], 16, mode, &yEdge)
+		if s := sad(tmp[:], 16, e.src.Y[yOff:], ys); bestY < 0 || s < bestY {
+			bestY, mb.predY = s, mode
+			predY = tmp
+		}
+	}
+	for mode := uint8(0); mode < nPredMode; mode++ {
+		var u, v [64]uint8
+		predict(u[:], 8, mode, &uEdge)
+		predict(v[:], 8, mode, &vEdge)
+		s := sad(u[:], 8, e.src.Cb[cOff:], cs) + sad(v[:], 8, e.src.Cr[cOff:], cs)
+		if bestC < 0 || s < bestC {
+			bestC, mb.predC = s, mode
+			predU, predV = u, v
+		}
+	}
+
+	// Transform and quantize residuals.
+	var (
+		yCoeff, yLevels [16][16]int32
+		uvCoeff         [8][16]int32
+		uvLevels        [8][16]int32
+		dc, y2, y2Level [16]int32
+		nonZero         bool
+	)
+	for n := 0; n < 16; n++ {
+		x, y := 4*(n%4), 4*(n/4)
+		residual(&yCoeff[n], predY[y*16+x:], 16, e.src.Y[yOff+y*ys+x:], ys)
+		dc[n] = yCoeff[n][0]
+		nonZero = quantizeBlock(&yCoeff[n], &yLevels[n], e.quant.y1, 1) || nonZero
+	}
+	fwht4(&dc, &y2)
+	nonZero = quantizeBlock(&y2, &y2Level, e.quant.y2, 0) || nonZero
+	for n := 0; n < 8; n++ {
+		pred, src := predU[:], e.src.Cb[cOff:]
+		if n >= 4 {
+			pred, src = predV[:], e.src.Cr[cOff:]
+		}
+		x, y := 4*(n%2), 4*(n%4/2)
+		residual(&uvCoeff[n], pred[y*8+x:], 8, src[y*cs+x:], cs)
+		nonZero = quantizeBlock(&uvCoeff[n], &uvLevels[n], e.quant.uv, 0) || nonZero
+	}
+
+	// Write tokens in section 13's order, and update the contexts.
+	up, left := &e.upNz[mbx], &e.leftNz
+	mb.skip = !nonZero
+	if mb.skip {
+		*up, *left = nzContext{}, nzContext{}
+	} else {
+		probs := &defaultTokenProb
+		nz := writeTokens(&e.tokens, &probs[planeY2], left.y2+up.y2, &y2Level, 0)
+		left.y2, up.y2 = nz, nz
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				nz := writeTokens(&e.tokens, &probs[planeY1WithY2], left.y[y]+up.y[x], &yLevels[4*y+x], 1)
+				left.y[y], up.y[x] = nz, nz
+			}
+		}
+		for c := 0; c < 4; c += 2 {
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 2; x++ {
+					nz := writeTokens(&e.tokens, &probs[planeUV], left.uv[y+c]+up.uv[x+c], &uvLevels[2*c+2*y+x], 0)
+					left.uv[y+c], up.uv[x+c] = nz, nz
+				}
+			}
+		}
+	}
+
+	// Reconstruct the macroblock the same way the decoder does.
+	iwht4(&y2, &dc)
+	for n := 0; n < 16; n++ {
+		yCoeff[n][0] = dc[n]
+		x, y := 4*(n%4), 4*(n/4)
+		reconstruct(e.rec.Y[yOff+y*ys+x:], ys, predY[y*16+x:], 16, &yCoeff[n])
+	}
+	for n := 0; n < 8; n++ {
+		pred, dst := predU[:], e.rec.Cb[cOff:]
+		if n >= 4 {
+			pred, dst = predV[:], e.rec.Cr[cOff:]
+		}
+		x, y := 4*(n%2), 4*(n%4/2)
+		reconstruct(dst[y*cs+x:], cs, pred[y*8+x:], 8, &uvCoeff[n])
+	}
+	return mb
+}
+
+// residual computes the transformed 4x4 residual between the source and the prediction.
+func residual(coeff *[16]int32, pred []uint8, predStride int, src []uint8, stride int) {
+	var r [16]int32
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			r[4*j+i] = int32(src[j*stride+i]) - int32(pred[j*predStride+i])
+		}
+	}
+	fdct4(&r, coeff)
+}
+
+// quantizeBlock quantizes coefficients from first on into levels in zigzag order, and replaces
+// the coefficients with the dequantized values the decoder will see.
+// It reports whether any level is non-zero.
+func quantizeBlock(coeff *[16]int32, levels *[16]int32, q [2]int32, first int) bool {
+	nonZero := false
+	for n := first; n < 16; n++ {
+		z := zigzag[n]
+		qi, rounding := q[1], int32(quantRoundingAC)
+		if z == 0 {
+			qi, rounding = q[0], quantRoundingDC
+		}
+		v := coeff[z]
+		neg := v < 0
+		if neg {
+			v = -v
+		}
+		level := (v + qi*rounding>>7) / qi
+		if max := 0x7fff / qi; level > max {
+			level = max
+		}
+		if level > maxLevel {
+			level = maxLevel
+		}
+		if neg {
+			level = -level
+		}
+		levels[n] = level
+		coeff[z] = level * qi
+		nonZero = nonZero || level != 0
+	}
+	return nonZero
+}
+
+func reconstruct(dst []uint8, stride int, pred []uint8, predStride int, coeff *[16]int32) {
+	for j := 0; j < 4; j++ {
+		copy(dst[j*stride:j*stride+4], pred[j*predStride:j*predStride+4])
+	}
+	idct4(coeff, dst, stride)
+}
+
+// writeTokens writes levels from first on in zigzag order with section 13.2's token tree,
+// and returns 1 if any token other than EOB is written.
+func writeTokens(w *boolEncoder, probs *[nBand][nContext][nProb]uint8, context uint8, levels *[16]int32, first int) uint8 {
+	last := -1
+	for n := 15; n >= first; n-- {
+		if levels[n] != 0 {
+			last = n
+			break
+		}
+	}
+
+	p := &probs[bands[first]][context]
+	if last < 0 {
+		w.writeBool(p[0], false)
+		return 0
+	}
+	w.writeBool(p[0], true)
+	for n := first; n <= last; n++ {
+		v := levels[n]
+		if v < 0 {
+			v = -v
+		}
+		if v == 0 {
+			w.writeBool(p[1], false)
+			p = &probs[bands[n+1]][0]
+			continue
+		}
+		w.writeBool(p[1], true)
+		writeTokenValue(w, p, v)
+		if v == 1 {
+			p = &probs[bands[n+1]][1]
+		} else {
+			p = &probs[bands[n+1]][2]
+		}
+		w.writeBool(uniformProb, levels[n] < 0)
+		if n == 15 {
+			break
+		}
+		w.writeBool(p[0], n != last)
+	}
+	return 1
+}
+
+// writeTokenValue writes the absolute value of a non-zero level.
+func writeTokenValue(w *boolEncoder, p *[nProb]uint8, v int32) {
+	if v == 1 {
+		w.writeBool(p[2], false)
+		return
+	}
+	w.writeBool(p[2], true)
+	switch {
+	case v <= 4:
+		w.writeBool(p[3], false)
+		if v == 2 {
+			w.writeBool(p[4], false)
+			return
+		}
+		w.writeBool(p[4], true)
+		w.writeBool(p[5], v == 4)
+	case v <= 10:
+		w.writeBool(p[3], true)
+		w.writeBool(p[6], false)
+		if v <= 6 {
+			// DCT_CAT1
+			w.writeBool(p[7], false)
+			w.writeBool(159, v == 6)
+			return
+		}
+		// DCT_CAT2
+		w.writeBool(p[7], true)
+		w.writeBool(165, (v-7)&2 != 0)
+		w.writeBool(145, (v-7)&1 != 0)
+	default:
+		// DCT_CAT3 to DCT_CAT6
+		w.writeBool(p[3], true)
+		w.writeBool(p[6], true)
+		cat := 0
+		for cat < 3 && v >= 3+(8<<uint(cat+1)) {
+			cat++
+		}
+		w.writeBool(p[8], cat>>1 == 1)
+		w.writeBool(p[9+(cat>>1)], cat&1 == 1)
+		extra := uint32(v - 3 - 8<<uint(cat))
+		tab := &cat3456[cat]
+		bits := 0
+		for tab[bits] != 0 {
+			bits++
+		}
+		for i := 0; i < bits; i++ {
+			w.writeBool(tab[i], (extra>>uint(bits-1-i))&1 == 1)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package vp8
+
+import (
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// Params stores the pure Go VP8 encoder's encoding parameters.
+type Params struct {
+	codec.BaseParams
+
+	// Quantizer is the initial quantizer index from 0 (best quality) to 127.
+	// If BitRate is 0, the quantizer is kept constant.
+	Quantizer int
+	// MinQuantizer and MaxQuantizer limit the quantizer index rate control can choose.
+	MinQuantizer int
+	MaxQuantizer int
+}
+
+// NewParams returns the pure Go VP8 encoder's default parameters.
+func NewParams() (Params, error) {
+	return Params{
+		BaseParams: codec.BaseParams{
+			BitRate: 1000000,
+		},
+		Quantizer:    40,
+		MinQuantizer: 4,
+		MaxQuantizer: 127,
+	}, nil
+}
+
+// RTPCodec represents the codec metadata
+func (p *Params) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPVP8Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
+}
+
+// BuildVideoEncoder builds VP8 encoder with given params
+func (p *Params) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
+	return newEncoder(r, property, *p)
+}
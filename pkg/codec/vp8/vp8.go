@@ -0,0 +1,375 @@
+// Package vp8 implements a VP8 encoder in pure Go.
+// It doesn't require cgo, so it can be used in cross-compiled or static binaries
+// where libvpx isn't available. All frames are encoded as intra frames with 16x16 luma
+// prediction, so it uses more bandwidth and CPU than libvpx for the same quality.
+// Use pkg/codec/vpx if possible.
+// Reference: https://tools.ietf.org/html/rfc6386
+package vp8
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// Prediction modes, in decoder order.
+const (
+	predDC = iota
+	predTM
+	predVE
+	predHE
+	nPredMode
+)
+
+const (
+	maxQuantizer = 127
+	// maxFrameSize is the largest width and height the 14-bit frame header fields can store.
+	maxFrameSize = 1<<14 - 1
+	// maxLevel is the largest quantized coefficient DCT_CAT6 can represent.
+	maxLevel = 2048
+
+	// Quantization rounding in 1/128 of the quantizer. Smaller AC rounding removes small
+	// high frequency coefficients, which are expensive to code.
+	quantRoundingDC = 64
+	quantRoundingAC = 44
+
+	// rateControlGain is how much the quantizer index changes when the frame size is twice the target.
+	rateControlGain = 8
+
+	defaultFrameRate = 30
+	// maxFrameInterval is the longest interval budgeted to a single frame, so the first frame after a variable frame
+	// rate pause, e.g. on a static screen, doesn't burst.
+	maxFrameInterval = time.Second
+)
+
+type quantizer struct {
+	y1, y2, uv [2]int32
+}
+
+func newQuantizer(qi int) quantizer {
+	q := quantizer{
+		y1: [2]int32{int32(dequantTableDC[qi]), int32(dequantTableAC[qi])},
+		y2: [2]int32{int32(dequantTableDC[qi]) * 2, int32(dequantTableAC[qi]) * 155 / 100},
+		uv: [2]int32{int32(dequantTableDC[clampInt(qi, 0, 117)]), int32(dequantTableAC[qi])},
+	}
+	if q.y2[1] < 8 {
+		q.y2[1] = 8
+	}
+	return q
+}
+
+// nzContext stores whether neighboring blocks have non-zero coefficients.
+type nzContext struct {
+	y2 uint8
+	y  [4]uint8
+	uv [4]uint8
+}
+
+type macroblock struct {
+	predY, predC uint8
+	skip         bool
+}
+
+type encoder struct {
+	r         video.Reader
+	clock     *codec.FrameClock
+	frameRate float64
+	// propWidth and propHeight are the expected frame size, used to prepare buffers.
+	propWidth, propHeight int
+
+	mu      sync.Mutex
+	closed  bool
+	bitRate int
+	qi      int
+	minQI   int
+	maxQI   int
+
+	width, height int
+	mbw, mbh      int
+	// src and rec are the source and reconstructed frames, aligned to macroblocks.
+	src, rec *image.YCbCr
+	mbs      []macroblock
+	quant    quantizer
+	upNz     []nzContext
+	leftNz   nzContext
+
+	// tokens and first are the partitions, whose buffers are reused across frames. Frames are written to buffers
+	// from out.
+	tokens, first boolEncoder
+	out           codec.BufferPool
+}
+
+func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
+	if params.MaxQuantizer == 0 {
+		params.MaxQuantizer = maxQuantizer
+	}
+	if params.MinQuantizer < 0 || params.MaxQuantizer > maxQuantizer || params.MinQuantizer > params.MaxQuantizer {
+		return nil, fmt.Errorf("invalid quantizer range: %d-%d", params.MinQuantizer, params.MaxQuantizer)
+	}
+	if p.Width > maxFrameSize || p.Height > maxFrameSize {
+		return nil, fmt.Errorf("frame size %dx%d is too large", p.Width, p.Height)
+	}
+
+	frameRate := float64(p.FrameRate)
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
+
+	i420 := video.KeepMetadata(video.ToI420(r), r)
+	return &encoder{
+		r:          i420,
+		clock:      codec.NewFrameClock(i420, float32(frameRate)),
+		propWidth:  p.Width,
+		propHeight: p.Height,
+		frameRate:  frameRate,
+		bitRate:    params.BitRate,
+		qi:         clampInt(params.Quantizer, params.MinQuantizer, params.MaxQuantizer),
+		minQI:      params.MinQuantizer,
+		maxQI:      params.MaxQuantizer,
+	}, nil
+}
+
+func (e *encoder) Read() ([]byte, func(), error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, func() {}, io.EOF
+	}
+
+	img, release, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	err = e.loadSource(img.(*image.YCbCr))
+	release()
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	_, interval := e.clock.Tick()
+	encoded, releaseEncoded := e.encodeFrame()
+	e.updateQuantizer(len(encoded), interval)
+	return encoded, releaseEncoded, nil
+}
+
+// Prepare implements codec.Preparer. VP8 has no codec configuration, so it only allocates buffers.
+func (e *encoder) Prepare() ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, io.EOF
+	}
+	if e.propWidth > 0 && e.propHeight > 0 {
+		e.allocate(e.propWidth, e.propHeight)
+	}
+	return nil, nil
+}
+
+func (e *encoder) SetBitRate(b int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.bitRate = b
+	return nil
+}
+
+// ForceKeyFrame does nothing since all frames are key frames.
+func (e *encoder) ForceKeyFrame() error {
+	return nil
+}
+
+func (e *encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	return nil
+}
+
+// allocate allocates buffers for the frame size if it changed.
+func (e *encoder) allocate(width, height int) {
+	if width == e.width && height == e.height {
+		return
+	}
+	e.width, e.height = width, height
+	e.mbw, e.mbh = (width+15)/16, (height+15)/16
+	rect := image.Rect(0, 0, e.mbw*16, e.mbh*16)
+	e.src = image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	e.rec = image.NewYCbCr(rect, image.YCbCrSubsampleRatio420)
+	e.mbs = make([]macroblock, e.mbw*e.mbh)
+	e.upNz = make([]nzContext, e.mbw)
+}
+
+// loadSource copies the frame into the macroblock-aligned source buffer, and repeats edge
+// pixels to fill the padding.
+func (e *encoder) loadSource(img *image.YCbCr) error {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if width == 0 || height == 0 || width > maxFrameSize || height > maxFrameSize {
+		return fmt.Errorf("invalid frame size %dx%d", width, height)
+	}
+	e.allocate(width, height)
+
+	min := img.Rect.Min
+	copyPlane(e.src.Y, e.src.YStride, e.mbh*16, img.Y[img.YOffset(min.X, min.Y):], img.YStride, width, height)
+	cw, ch := (width+1)/2, (height+1)/2
+	cOffset := img.COffset(min.X, min.Y)
+	copyPlane(e.src.Cb, e.src.CStride, e.mbh*8, img.Cb[cOffset:], img.CStride, cw, ch)
+	copyPlane(e.src.Cr, e.src.CStride, e.mbh*8, img.Cr[cOffset:], img.CStride, cw, ch)
+	return nil
+}
+
+func copyPlane(dst []uint8, dstStride, dstHeight int, src []uint8, srcStride, width, height int) {
+	for y := 0; y < dstHeight; y++ {
+		sy := y
+		if sy >= height {
+			sy = height - 1
+		}
+		row := dst[y*dstStride : (y+1)*dstStride]
+		copy(row, src[sy*srcStride:sy*srcStride+width])
+		for x := width; x < dstStride; x++ {
+			row[x] = row[width-1]
+		}
+	}
+}
+
+// updateQuantizer moves the frame size toward the target bitrate's budget for the interval since the previous
+// frame, or the nominal interval for the first frame.
+func (e *encoder) updateQuantizer(size int, interval time.Duration) {
+	if e.bitRate <= 0 || size == 0 {
+		return
+	}
+	seconds := 1 / e.frameRate
+	if interval > 0 {
+		seconds = math.Min(interval.Seconds(), maxFrameInterval.Seconds())
+	}
+	target := float64(e.bitRate) / 8 * seconds
+	delta := int(math.Round(math.Log2(float64(size)/target) * rateControlGain))
+	e.qi = clampInt(e.qi+delta, e.minQI, e.maxQI)
+}
+
+func (e *encoder) encodeFrame() ([]byte, func()) {
+	e.quant = newQuantizer(e.qi)
+	e.tokens.reset()
+	for i := range e.upNz {
+		e.upNz[i] = nzContext{}
+	}
+	nonSkip := 0
+	for mby := 0; mby < e.mbh; mby++ {
+		e.leftNz = nzContext{}
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			mb := e.encodeMacroblock(mbx, mby)
+			if !mb.skip {
+				nonSkip++
+			}
+			e.mbs[mby*e.mbw+mbx] = mb
+		}
+	}
+	tokens := e.tokens.flush()
+	first := e.writeFirstPartition(nonSkip)
+
+	// Frame tag and key frame header, section 9.1.
+	size := len(first)
+	b, release := e.out.Get(10 + len(first) + len(tokens))
+	b = append(b[:0],
+		// key frame, version 0, show frame
+		byte(size<<5)|1<<4,
+		byte(size>>3),
+		byte(size>>11),
+		0x9d, 0x01, 0x2a,
+		byte(e.width), byte(e.width>>8),
+		byte(e.height), byte(e.height>>8),
+	)
+	b = append(b, first...)
+	return append(b, tokens...), release
+}
+
+// writeFirstPartition writes the frame header and macroblock headers, section 19.2.
+func (e *encoder) writeFirstPartition(nonSkip int) []byte {
+	w := &e.first
+	w.reset()
+	w.writeLiteral(0, 1) // color space
+	w.writeLiteral(0, 1) // clamping type
+	w.writeLiteral(0, 1) // segmentation enabled
+	w.writeLiteral(0, 1) // filter type
+	w.writeLiteral(uint32(loopFilterLevel(e.qi)), 6)
+	w.writeLiteral(0, 3) // sharpness
+	w.writeLiteral(0, 1) // loop filter adjustments
+	w.writeLiteral(0, 2) // one token partition
+	w.writeLiteral(uint32(e.qi), 7)
+	for i := 0; i < 5; i++ {
+		w.writeLiteral(0, 1) // no quantizer delta
+	}
+	w.writeLiteral(1, 1) // refresh entropy probs
+	for i := range tokenProbUpdateProb {
+		for j := range tokenProbUpdateProb[i] {
+			for k := range tokenProbUpdateProb[i][j] {
+				for l := range tokenProbUpdateProb[i][j][k] {
+					w.writeBool(tokenProbUpdateProb[i][j][k][l], false)
+				}
+			}
+		}
+	}
+
+	probSkipFalse := uint8(clampInt(nonSkip*256/len(e.mbs), 1, 255))
+	w.writeLiteral(1, 1) // mb_no_coeff_skip
+	w.writeLiteral(uint32(probSkipFalse), 8)
+
+	for _, mb := range e.mbs {
+		w.writeBool(probSkipFalse, mb.skip)
+		w.writeBool(145, true) // 16x16 luma prediction
+		switch mb.predY {
+		case predDC:
+			w.writeBool(156, false)
+			w.writeBool(163, false)
+		case predVE:
+			w.writeBool(156, false)
+			w.writeBool(163, true)
+		case predHE:
+			w.writeBool(156, true)
+			w.writeBool(128, false)
+		case predTM:
+			w.writeBool(156, true)
+			w.writeBool(128, true)
+		}
+		switch mb.predC {
+		case predDC:
+			w.writeBool(142, false)
+		case predVE:
+			w.writeBool(142, true)
+			w.writeBool(114, false)
+		case predHE:
+			w.writeBool(142, true)
+			w.writeBool(114, true)
+			w.writeBool(183, false)
+		case predTM:
+			w.writeBool(142, true)
+			w.writeBool(114, true)
+			w.writeBool(183, true)
+		}
+	}
+	return w.flush()
+}
+
+// loopFilterLevel returns the loop filter strength, stronger for coarser quantizers.
+func loopFilterLevel(qi int) int {
+	return qi / 3
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
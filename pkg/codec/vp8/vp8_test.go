@@ -0,0 +1,225 @@
+package vp8
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	xvp8 "golang.org/x/image/vp8"
+)
+
+// testImage returns a frame with gradients and edges, which uses every prediction mode.
+func testImage(width, height, offset int) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, width, height), image.YCbCrSubsampleRatio420)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v := (x*3 + y*2 + offset) % 256
+			if (x/24+y/20)%2 == 0 {
+				v = 255 - v/2
+			}
+			img.Y[y*img.YStride+x] = uint8(v)
+		}
+	}
+	for y := 0; y < (height+1)/2; y++ {
+		for x := 0; x < (width+1)/2; x++ {
+			img.Cb[y*img.CStride+x] = uint8(64 + (x*2+offset)%128)
+			img.Cr[y*img.CStride+x] = uint8(192 - (y*3)%128)
+		}
+	}
+	return img
+}
+
+func psnr(a, b []uint8, aStride, bStride, width, height int) float64 {
+	var sse float64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			d := float64(a[y*aStride+x]) - float64(b[y*bStride+x])
+			sse += d * d
+		}
+	}
+	if sse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255*float64(width*height)/sse)
+}
+
+func newTestEncoder(t *testing.T, params Params, width, height int) (codec.ReadCloser, *int) {
+	var cnt int
+	e, err := params.BuildVideoEncoder(
+		video.ReaderFunc(func() (image.Image, func(), error) {
+			cnt++
+			return testImage(width, height, cnt), func() {}, nil
+		}),
+		prop.Media{
+			Video: prop.Video{
+				Width:       width,
+				Height:      height,
+				FrameRate:   30,
+				FrameFormat: frame.FormatI420,
+			},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, &cnt
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for name, size := range map[string]struct{ width, height int }{
+		"Aligned":   {64, 48},
+		"Unaligned": {99, 57},
+	} {
+		size := size
+		t.Run(name, func(t *testing.T) {
+			params, err := NewParams()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params.BitRate = 0
+			params.Quantizer = 10
+
+			e, cnt := newTestEncoder(t, params, size.width, size.height)
+			defer e.Close()
+
+			for i := 0; i < 3; i++ {
+				b, _, err := e.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				d := xvp8.NewDecoder()
+				d.Init(bytes.NewReader(b), len(b))
+				fh, err := d.DecodeFrameHeader()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !fh.KeyFrame || fh.Width != size.width || fh.Height != size.height {
+					t.Fatalf("expected %dx%d key frame, but got %+v", size.width, size.height, fh)
+				}
+				decoded, err := d.DecodeFrame()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				src := testImage(size.width, size.height, *cnt)
+				if p := psnr(src.Y, decoded.Y, src.YStride, decoded.YStride, size.width, size.height); p < 35 {
+					t.Errorf("expected luma PSNR to be at least 35dB, but got %.2fdB", p)
+				}
+				cw, ch := (size.width+1)/2, (size.height+1)/2
+				if p := psnr(src.Cb, decoded.Cb, src.CStride, decoded.CStride, cw, ch); p < 35 {
+					t.Errorf("expected Cb PSNR to be at least 35dB, but got %.2fdB", p)
+				}
+				if p := psnr(src.Cr, decoded.Cr, src.CStride, decoded.CStride, cw, ch); p < 35 {
+					t.Errorf("expected Cr PSNR to be at least 35dB, but got %.2fdB", p)
+				}
+			}
+		})
+	}
+}
+
+func TestRateControl(t *testing.T) {
+	const (
+		width, height = 160, 120
+		frames        = 30
+	)
+
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	averageSize := func(e codec.ReadCloser) float64 {
+		var total int
+		for i := 0; i < frames; i++ {
+			b, _, err := e.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += len(b)
+		}
+		return float64(total) / frames
+	}
+
+	params.BitRate = 1000000
+	e, _ := newTestEncoder(t, params, width, height)
+	defer e.Close()
+	high := averageSize(e)
+
+	if err := e.SetBitRate(200000); err != nil {
+		t.Fatal(err)
+	}
+	averageSize(e)
+	low := averageSize(e)
+
+	// 200 kbps at 30 fps
+	const target = 200000 / 8 / 30
+	if low >= high {
+		t.Fatalf("expected the frames to be smaller after lowering the bitrate, but got %.0f bytes from %.0f bytes", low, high)
+	}
+	if low > target*1.5 || low < target*0.5 {
+		t.Errorf("expected the frame size to be close to %d bytes, but got %.0f bytes", target, low)
+	}
+}
+
+func TestRateControlVariableFrameRate(t *testing.T) {
+	const width, height = 160, 120
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.BitRate = 200000
+
+	// Frames are captured at 10 fps, slower than the nominal frame rate, e.g. by a screen capture
+	now := time.Unix(1000, 0)
+	var cnt int
+	src := video.Stamp(video.ReaderFunc(func() (image.Image, func(), error) {
+		now = now.Add(100 * time.Millisecond)
+		cnt++
+		return testImage(width, height, cnt), func() {}, nil
+	}), func() time.Time { return now })
+	e, err := params.BuildVideoEncoder(src, prop.Media{
+		Video: prop.Video{Width: width, Height: height, FrameRate: 30, FrameFormat: frame.FormatI420},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	var total int
+	for i := 0; i < 60; i++ {
+		b, _, err := e.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i >= 30 {
+			total += len(b)
+		}
+	}
+	// 200 kbps at 10 fps
+	const target = 200000 / 8 / 10
+	if size := float64(total) / 30; size > target*1.5 || size < target*0.5 {
+		t.Errorf("expected the frame size to be close to %d bytes, but got %.0f bytes", target, size)
+	}
+}
+
+func TestClose(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, _ := newTestEncoder(t, params, 32, 32)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := e.Read(); err != io.EOF {
+		t.Fatalf("expected %v, but got %v", io.EOF, err)
+	}
+}
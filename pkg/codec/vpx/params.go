@@ -18,8 +18,35 @@ type Params struct {
 	RateControlMaxQuantizer      uint
 	LagInFrames                  uint
 	ErrorResilient               ErrorResilientMode
+
+	// CPUUsed controls encoder speed. Larger values make the encoder faster and lower the quality.
+	// The range is -16 to 16 in VP8 and -9 to 9 in VP9. Negative values are only valid in realtime mode,
+	// i.e. when Deadline is DeadlineRealtime.
+	CPUUsed int
+	// Threads is how many threads the encoder uses. If it's 0, video.Workers() is used.
+	Threads uint
+	// CQLevel is the quality for RateControlCQ and RateControlQ, from 0 to 63, where lower is better. Recordings can
+	// use RateControlQ to keep a constant quality instead of a real-time bitrate, e.g. with DeadlineGoodQuality. The
+	// default, 0, keeps the libvpx default.
+	CQLevel uint
+
+	// TemporalLayers is the number of temporal scalability layers, from 1 to 3. Frames are encoded and signaled in
+	// the codec.TemporalLayerPattern pattern, so SFUs can drop higher layers for slow receivers. With layers, the
+	// encoder doesn't delay or drop frames, so LagInFrames is ignored and error resilient mode is on.
+	TemporalLayers int
+	// TemporalLayerBitRates is the target bitrate of each temporal layer in bps, on top of the layers below it.
+	// BitRate is ignored, unless this is nil, in which case BitRate is split between the layers.
+	TemporalLayerBitRates []int
 }
 
+// Deadline presets. The realtime deadline suits live streaming, and the others are
+// much slower but give better quality at the same bitrate.
+const (
+	DeadlineRealtime    = time.Microsecond
+	DeadlineGoodQuality = time.Second
+	DeadlineBestQuality = time.Duration(0)
+)
+
 // RateControlMode represents rate control mode.
 type RateControlMode int
 
@@ -27,7 +54,10 @@ type RateControlMode int
 const (
 	RateControlVBR RateControlMode = iota
 	RateControlCBR
+	// RateControlCQ is constrained quality: CQLevel, capped by the bitrate.
 	RateControlCQ
+	// RateControlQ is constant quality at CQLevel, ignoring the bitrate.
+	RateControlQ
 )
 
 // ErrorResilientMode represents error resilient mode.
@@ -35,6 +65,7 @@ type ErrorResilientMode int
 
 // ErrorResilientMode values.
 const (
+	ErrorResilientDisabled   ErrorResilientMode = 0x00
 	ErrorResilientDefault    ErrorResilientMode = 0x01
 	ErrorResilientPartitions ErrorResilientMode = 0x02
 )
@@ -35,6 +35,11 @@ package vpx
 //   return malloc(sizeof(vpx_image_t));
 // }
 //
+// // cgo can't call variadic functions
+// vpx_codec_err_t codecControl(vpx_codec_ctx_t *ctx, int id, int v) {
+//   return vpx_codec_control_(ctx, id, v);
+// }
+//
 // // Wrap encode function to keep Go memory safe
 // vpx_codec_err_t encode_wrapper(
 //     vpx_codec_ctx_t* codec, vpx_image_t* raw,
@@ -69,17 +74,34 @@ type encoder struct {
 	cfg             *C.vpx_codec_enc_cfg_t
 	r               video.Reader
 	frameIndex      int
-	tStart          int
+	clock           *codec.FrameClock
 	tLastFrame      int
 	frame           []byte
+	out             codec.BufferPool
 	deadline        int
 	requireKeyFrame bool
 	isKeyFrame      bool
+	controls        []control
+
+	// layers is the temporal layer pattern, and layerIndex is the next frame's position in it. Keyframes only
+	// start a pattern, so the payloader stays in step.
+	layers           []codec.TemporalLayer
+	layerIndex       int
+	layerShares      []float64
+	setLayerID       bool
+	keyFrameInterval int
+	sinceKeyFrame    int
 
 	mu     sync.Mutex
 	closed bool
 }
 
+// control is a codec control applied after codec initialization
+type control struct {
+	id    C.int
+	value C.int
+}
+
 // VP8Params is codec specific paramaters
 type VP8Params struct {
 	Params
@@ -99,17 +121,32 @@ func NewVP8Params() (VP8Params, error) {
 
 // RTPCodec represents the codec metadata
 func (p *VP8Params) RTPCodec() *codec.RTPCodec {
-	return codec.NewRTPVP8Codec(90000)
+	c := codec.NewRTPVP8Codec(90000)
+	c.SetPacketization(p.Packetization)
+	c.SetTemporalLayers(p.TemporalLayers)
+	return c
 }
 
 // BuildVideoEncoder builds VP8 encoder with given params
 func (p *VP8Params) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
-	return newEncoder(r, property, p.Params, C.ifaceVP8())
+	var controls []control
+	if p.ScreenContent(property) {
+		// Mode 2 drops frames on overshoot, which breaks temporal layer patterns
+		controls = append(controls, control{C.VP8E_SET_SCREEN_CONTENT_MODE, 1})
+	}
+	return newEncoder(r, property, p.Params, C.ifaceVP8(), controls)
 }
 
 // VP9Params is codec specific paramaters
 type VP9Params struct {
 	Params
+
+	// RowMultiThreading enables row-based multi-threading. It speeds up encoding if Threads is
+	// larger than TileColumns.
+	RowMultiThreading bool
+	// TileColumns is the log2 number of tile columns, e.g. 2 divides the frame into 4 columns.
+	// Tiles can be encoded and decoded in parallel. The frame width limits the number.
+	TileColumns int
 }
 
 // NewVP9Params returns default VP9 codec specific parameters.
@@ -121,17 +158,47 @@ func NewVP9Params() (VP9Params, error) {
 
 	return VP9Params{
 		Params: p,
+		// Same as libvpx's default, it's clamped by the frame width
+		TileColumns: 6,
 	}, nil
 }
 
 // RTPCodec represents the codec metadata
 func (p *VP9Params) RTPCodec() *codec.RTPCodec {
-	return codec.NewRTPVP9Codec(90000)
+	c := codec.NewRTPVP9Codec(90000)
+	c.SetPacketization(p.Packetization)
+	c.SetTemporalLayers(p.TemporalLayers)
+	return c
 }
 
 // BuildVideoEncoder builds VP9 encoder with given params
 func (p *VP9Params) BuildVideoEncoder(r video.Reader, property prop.Media) (codec.ReadCloser, error) {
-	return newEncoder(r, property, p.Params, C.ifaceVP9())
+	var rowMT C.int
+	if p.RowMultiThreading {
+		rowMT = 1
+	}
+	controls := []control{
+		{C.VP9E_SET_ROW_MT, rowMT},
+		{C.VP9E_SET_TILE_COLUMNS, C.int(p.TileColumns)},
+	}
+	// VP8 doesn't signal colorspace, so VP8 frames are only converted to it.
+	if cs := p.ColorSpace(property); !cs.IsZero() {
+		colorSpace, colorRange := C.int(C.VPX_CS_BT_601), C.int(C.VPX_CR_STUDIO_RANGE)
+		if cs.Matrix == prop.ColorMatrixBT709 {
+			colorSpace = C.VPX_CS_BT_709
+		}
+		if cs.Range == prop.ColorRangeFull {
+			colorRange = C.VPX_CR_FULL_RANGE
+		}
+		controls = append(controls, control{C.VP9E_SET_COLOR_SPACE, colorSpace}, control{C.VP9E_SET_COLOR_RANGE, colorRange})
+	}
+	if p.TemporalLayers > 1 {
+		controls = append(controls, control{C.VP9E_SET_SVC, 1})
+	}
+	if p.ScreenContent(property) {
+		controls = append(controls, control{C.VP9E_SET_TUNE_CONTENT, C.VP9E_CONTENT_SCREEN})
+	}
+	return newEncoder(r, property, p.Params, C.ifaceVP9(), controls)
 }
 
 func newParams(codecIface *C.vpx_codec_iface_t) (Params, error) {
@@ -148,10 +215,11 @@ func newParams(codecIface *C.vpx_codec_iface_t) (Params, error) {
 		RateControlMaxQuantizer:      uint(cfg.rc_max_quantizer),
 		LagInFrames:                  uint(cfg.g_lag_in_frames),
 		ErrorResilient:               ErrorResilientMode(cfg.g_error_resilient),
+		Threads:                      uint(cfg.g_threads),
 	}, nil
 }
 
-func newEncoder(r video.Reader, p prop.Media, params Params, codecIface *C.vpx_codec_iface_t) (codec.ReadCloser, error) {
+func newEncoder(r video.Reader, p prop.Media, params Params, codecIface *C.vpx_codec_iface_t, controls []control) (codec.ReadCloser, error) {
 	if params.BitRate == 0 {
 		params.BitRate = 100000
 	}
@@ -159,6 +227,25 @@ func newEncoder(r video.Reader, p prop.Media, params Params, codecIface *C.vpx_c
 	if params.KeyFrameInterval == 0 {
 		params.KeyFrameInterval = 60
 	}
+	if params.Threads == 0 {
+		params.Threads = uint(video.Workers())
+	}
+	if params.TemporalLayers == 0 {
+		params.TemporalLayers = 1
+	}
+	layers := codec.TemporalLayerPattern(params.TemporalLayers)
+	if layers == nil {
+		return nil, fmt.Errorf("the number of temporal layers must be between 1 and %d", codec.MaxTemporalLayers)
+	}
+	shares, bitRate, err := layerShares(params)
+	if err != nil {
+		return nil, err
+	}
+	params.BitRate = bitRate
+	cq, err := cqLevel(params)
+	if err != nil {
+		return nil, err
+	}
 
 	cfg := &C.vpx_codec_enc_cfg_t{}
 	if ec := C.vpx_codec_enc_config_default(codecIface, cfg, 0); ec != 0 {
@@ -178,12 +265,19 @@ func newEncoder(r video.Reader, p prop.Media, params Params, codecIface *C.vpx_c
 	cfg.g_timebase.num = 1
 	cfg.g_timebase.den = 1000
 	cfg.g_lag_in_frames = C.uint(params.LagInFrames)
+	cfg.g_threads = C.uint(params.Threads)
 	cfg.rc_target_bitrate = C.uint(params.BitRate) / 1000
 	cfg.kf_max_dist = C.uint(params.KeyFrameInterval)
 
 	cfg.rc_resize_allowed = 0
 	cfg.g_pass = C.VPX_RC_ONE_PASS
 
+	if len(shares) > 1 {
+		setTemporalLayers(cfg, layers, shares)
+		// Keyframes are forced at the start of a pattern instead
+		cfg.kf_mode = C.VPX_KF_DISABLED
+	}
+
 	raw := &C.vpx_image_t{}
 	if C.vpx_img_alloc(raw, C.VPX_IMG_FMT_I420, cfg.g_w, cfg.g_h, 1) == nil {
 		return nil, errors.New("vpx_img_alloc failed")
@@ -192,23 +286,148 @@ func newEncoder(r video.Reader, p prop.Media, params Params, codecIface *C.vpx_c
 	*rawNoBuffer = *raw // Copy only parameters
 	C.vpx_img_free(raw) // Pointers will be overwritten by the raw buffer
 
+	toI420 := codec.ToI420(r, params.ColorSpace(p))
+	clock := codec.NewFrameClock(toI420, 0)
+	controls = append([]control{{C.VP8E_SET_CPUUSED, C.int(params.CPUUsed)}}, controls...)
+	if cq > 0 {
+		controls = append(controls, control{C.VP8E_SET_CQ_LEVEL, C.int(cq)})
+	}
+	codec, err := newCodec(codecIface, cfg, controls)
+	if err != nil {
+		return nil, err
+	}
+	return &encoder{
+		r:                toI420,
+		codec:            codec,
+		raw:              rawNoBuffer,
+		cfg:              cfg,
+		clock:            clock,
+		deadline:         int(params.Deadline / time.Microsecond),
+		frame:            make([]byte, 1024),
+		controls:         controls,
+		layers:           layers,
+		layerShares:      shares,
+		setLayerID:       codecIface == C.ifaceVP8(),
+		keyFrameInterval: int(params.KeyFrameInterval),
+	}, nil
+}
+
+// cqLevel returns the CQ level of params, or 0 for the libvpx default. Only RateControlCQ and RateControlQ use
+// it.
+func cqLevel(params Params) (int, error) {
+	if params.CQLevel > 63 {
+		return 0, errors.New("the CQ level must be between 0 and 63")
+	}
+	if params.RateControlEndUsage != RateControlCQ && params.RateControlEndUsage != RateControlQ {
+		return 0, nil
+	}
+	return int(params.CQLevel), nil
+}
+
+// layerShares returns each temporal layer's share of the total bitrate, along with the total.
+func layerShares(params Params) ([]float64, int, error) {
+	layers := params.TemporalLayers
+	if params.TemporalLayerBitRates == nil {
+		switch layers {
+		case 2:
+			return []float64{0.6, 0.4}, params.BitRate, nil
+		case 3:
+			return []float64{0.4, 0.2, 0.4}, params.BitRate, nil
+		}
+		return []float64{1}, params.BitRate, nil
+	}
+
+	if len(params.TemporalLayerBitRates) != layers {
+		return nil, 0, fmt.Errorf("expected %d temporal layer bitrates, but got %d", layers, len(params.TemporalLayerBitRates))
+	}
+	var total int
+	for _, b := range params.TemporalLayerBitRates {
+		if b <= 0 {
+			return nil, 0, errors.New("the temporal layer bitrates must be positive")
+		}
+		total += b
+	}
+	shares := make([]float64, layers)
+	for i, b := range params.TemporalLayerBitRates {
+		shares[i] = float64(b) / float64(total)
+	}
+	return shares, total, nil
+}
+
+// setTemporalLayers configures temporal layers in cfg. Each layer's bitrate includes the layers below it, and
+// each layer doubles the frame rate.
+func setTemporalLayers(cfg *C.vpx_codec_enc_cfg_t, layers []codec.TemporalLayer, shares []float64) {
+	cfg.ts_number_layers = C.uint(len(shares))
+	cfg.ts_periodicity = C.uint(len(layers))
+	for i, l := range layers {
+		cfg.ts_layer_id[i] = C.uint(l.ID)
+	}
+	for i := range shares {
+		cfg.ts_rate_decimator[i] = C.uint(1 << uint(len(shares)-1-i))
+	}
+	setLayerBitRates(cfg, shares)
+
+	if len(shares) == 2 {
+		cfg.temporal_layering_mode = C.VP9E_TEMPORAL_LAYERING_MODE_0101
+	} else {
+		cfg.temporal_layering_mode = C.VP9E_TEMPORAL_LAYERING_MODE_0212
+	}
+	// SFUs drop higher layers, so no frame depends on them
+	cfg.g_lag_in_frames = 0
+	cfg.rc_dropframe_thresh = 0
+	if cfg.g_error_resilient == 0 {
+		cfg.g_error_resilient = C.VPX_ERROR_RESILIENT_DEFAULT
+	}
+}
+
+// setLayerBitRates splits the rc_target_bitrate of cfg between temporal layers by shares.
+func setLayerBitRates(cfg *C.vpx_codec_enc_cfg_t, shares []float64) {
+	var sum float64
+	for i, share := range shares {
+		sum += share
+		cfg.ts_target_bitrate[i] = C.uint(float64(cfg.rc_target_bitrate) * sum)
+		// VP9 reads layer bitrates from layer_target_bitrate
+		cfg.layer_target_bitrate[i] = cfg.ts_target_bitrate[i]
+	}
+}
+
+// vp8LayerFlags returns the reference flags for a VP8 frame in layer. The base layer refers to and updates the
+// last frame, the middle layer updates the golden frame, and the top layer updates nothing. Sync frames only
+// refer to the last frame; others also refer to the golden frame.
+func vp8LayerFlags(layer codec.TemporalLayer, layers int) int {
+	flags := C.VP8_EFLAG_NO_REF_ARF | C.VP8_EFLAG_NO_UPD_ARF
+	if layer.ID == 0 || layer.Sync {
+		flags |= C.VP8_EFLAG_NO_REF_GF
+	}
+	switch layer.ID {
+	case 0:
+		flags |= C.VP8_EFLAG_NO_UPD_GF
+	case layers - 1:
+		flags |= C.VP8_EFLAG_NO_UPD_LAST | C.VP8_EFLAG_NO_UPD_GF | C.VP8_EFLAG_NO_UPD_ENTROPY
+	default:
+		flags |= C.VP8_EFLAG_NO_UPD_LAST | C.VP8_EFLAG_NO_UPD_ENTROPY
+	}
+	return flags
+}
+
+// newCodec initializes the codec context and applies the controls.
+func newCodec(codecIface *C.vpx_codec_iface_t, cfg *C.vpx_codec_enc_cfg_t, controls []control) (*C.vpx_codec_ctx_t, error) {
 	codec := C.newCtx()
 	if ec := C.vpx_codec_enc_init_ver(
 		codec, codecIface, cfg, 0, C.VPX_ENCODER_ABI_VERSION,
 	); ec != 0 {
+		C.free(unsafe.Pointer(codec))
 		return nil, fmt.Errorf("vpx_codec_enc_init failed (%d)", ec)
 	}
-	t0 := time.Now().Nanosecond() / 1000000
-	return &encoder{
-		r:          video.ToI420(r),
-		codec:      codec,
-		raw:        rawNoBuffer,
-		cfg:        cfg,
-		tStart:     t0,
-		tLastFrame: t0,
-		deadline:   int(params.Deadline / time.Microsecond),
-		frame:      make([]byte, 1024),
-	}, nil
+
+	for _, c := range controls {
+		if ec := C.codecControl(codec, c.id, c.value); ec != 0 {
+			C.vpx_codec_destroy(codec)
+			C.free(unsafe.Pointer(codec))
+			return nil, fmt.Errorf("vpx_codec_control(%d) failed (%d)", c.id, ec)
+		}
+	}
+	return codec, nil
 }
 
 func (e *encoder) Read() ([]byte, func(), error) {
@@ -219,10 +438,11 @@ func (e *encoder) Read() ([]byte, func(), error) {
 		return nil, func() {}, io.EOF
 	}
 
-	img, _, err := e.r.Read()
+	img, releaseImg, err := e.r.Read()
 	if err != nil {
 		return nil, func() {}, err
 	}
+	defer releaseImg()
 	yuvImg := img.(*image.YCbCr)
 	bounds := yuvImg.Bounds()
 	height := C.int(bounds.Dy())
@@ -232,23 +452,27 @@ func (e *encoder) Read() ([]byte, func(), error) {
 	e.raw.stride[1] = C.int(yuvImg.CStride)
 	e.raw.stride[2] = C.int(yuvImg.CStride)
 
-	t := time.Now().Nanosecond() / 1000000
+	// Timestamps use a 1ms time base and come from capture times, so rate control follows variable frame rates,
+	// e.g. from screen captures that only deliver frames on damage.
+	timestamp, _ := e.clock.Tick()
+	t := int(timestamp / time.Millisecond)
 
 	if e.cfg.g_w != C.uint(width) || e.cfg.g_h != C.uint(height) {
 		e.cfg.g_w, e.cfg.g_h = C.uint(width), C.uint(height)
 
-		newCodec := C.newCtx()
-		if ec := C.vpx_codec_enc_init_ver(
-			newCodec, e.codec.iface, e.cfg, 0, C.VPX_ENCODER_ABI_VERSION,
-		); ec != 0 {
-			return nil, func() {}, fmt.Errorf("vpx_codec_enc_init failed (%d)", ec)
+		c, err := newCodec(e.codec.iface, e.cfg, e.controls)
+		if err != nil {
+			return nil, func() {}, err
 		}
+		C.vpx_codec_destroy(e.codec)
 		C.free(unsafe.Pointer(e.codec))
-		e.codec = newCodec
+		e.codec = c
 
 		e.raw.w, e.raw.h = C.uint(width), C.uint(height)
 		e.raw.r_w, e.raw.r_h = C.uint(width), C.uint(height)
 		e.raw.d_w, e.raw.d_h = C.uint(width), C.uint(height)
+		// The new codec starts with a keyframe
+		e.layerIndex = 0
 	}
 
 	duration := t - e.tLastFrame
@@ -261,20 +485,32 @@ func (e *encoder) Read() ([]byte, func(), error) {
 		duration = 1
 	}
 	var flags int
-	if e.requireKeyFrame {
+	forceKeyFrame := e.requireKeyFrame && e.layerIndex == 0
+	if forceKeyFrame {
 		flags = flags | C.VPX_EFLAG_FORCE_KF
 	}
+	layer := e.layers[e.layerIndex]
+	if e.setLayerID && len(e.layers) > 1 {
+		// VP8 is given each frame's layer; VP9 follows the same pattern via temporal_layering_mode
+		flags |= vp8LayerFlags(layer, len(e.layerShares))
+		if ec := C.codecControl(e.codec, C.VP8E_SET_TEMPORAL_LAYER_ID, C.int(layer.ID)); ec != 0 {
+			return nil, func() {}, fmt.Errorf("vpx_codec_control(VP8E_SET_TEMPORAL_LAYER_ID) failed (%d)", ec)
+		}
+	}
 	if ec := C.encode_wrapper(
 		e.codec, e.raw,
-		C.long(t-e.tStart), C.ulong(duration), C.long(flags), C.ulong(e.deadline),
+		C.long(t), C.ulong(duration), C.long(flags), C.ulong(e.deadline),
 		(*C.uchar)(&yuvImg.Y[0]), (*C.uchar)(&yuvImg.Cb[0]), (*C.uchar)(&yuvImg.Cr[0]),
 	); ec != C.VPX_CODEC_OK {
 		return nil, func() {}, fmt.Errorf("vpx_codec_encode failed (%d)", ec)
 	}
 
-	e.requireKeyFrame = false
+	if forceKeyFrame {
+		e.requireKeyFrame = false
+	}
 	e.frameIndex++
 	e.tLastFrame = t
+	e.layerIndex = (e.layerIndex + 1) % len(e.layers)
 
 	e.frame = e.frame[:0]
 	var iter C.vpx_codec_iter_t
@@ -285,18 +521,37 @@ func (e *encoder) Read() ([]byte, func(), error) {
 		}
 		if pkt.kind == C.VPX_CODEC_CX_FRAME_PKT {
 			e.isKeyFrame = C.pktFrameFlags(pkt)&C.VPX_FRAME_IS_KEY == C.VPX_FRAME_IS_KEY
-			encoded := C.GoBytes(unsafe.Pointer(C.pktBuf(pkt)), C.pktSz(pkt))
-			e.frame = append(e.frame, encoded...)
+			if n := int(C.pktSz(pkt)); n > 0 {
+				e.frame = append(e.frame, (*[1 << 30]byte)(C.pktBuf(pkt))[:n:n]...)
+			}
 		}
 	}
 
-	encoded := make([]byte, len(e.frame))
+	e.sinceKeyFrame++
+	if e.isKeyFrame {
+		e.sinceKeyFrame = 0
+	}
+	if len(e.layers) > 1 && e.sinceKeyFrame >= e.keyFrameInterval {
+		e.requireKeyFrame = true
+	}
+
+	encoded, release := e.out.Get(len(e.frame))
 	copy(encoded, e.frame)
-	return encoded, func() {}, err
+	return encoded, release, err
 }
 
 func (e *encoder) SetBitRate(b int) error {
-	panic("SetBitRate is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.cfg.rc_target_bitrate = C.uint(b) / 1000
+	if len(e.layerShares) > 1 {
+		setLayerBitRates(e.cfg, e.layerShares)
+	}
+	if ec := C.vpx_codec_enc_config_set(e.codec, e.cfg); ec != C.VPX_CODEC_OK {
+		return fmt.Errorf("vpx_codec_enc_config_set failed (%d)", ec)
+	}
+	return nil
 }
 
 func (e *encoder) ForceKeyFrame() error {
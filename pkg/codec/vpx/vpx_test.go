@@ -158,3 +158,186 @@ func TestRequestKeyFrame(t *testing.T) {
 
 	}
 }
+
+func TestTemporalLayers(t *testing.T) {
+	for name, factory := range map[string]func() (codec.VideoEncoderBuilder, error){
+		"VP8": func() (codec.VideoEncoderBuilder, error) {
+			p, err := NewVP8Params()
+			p.TemporalLayers = 3
+			p.TemporalLayerBitRates = []int{200000, 100000, 100000}
+			p.KeyFrameInterval = 3
+			return &p, err
+		},
+		"VP9": func() (codec.VideoEncoderBuilder, error) {
+			p, err := NewVP9Params()
+			p.TemporalLayers = 3
+			p.KeyFrameInterval = 3
+			return &p, err
+		},
+	} {
+		factory := factory
+		t.Run(name, func(t *testing.T) {
+			param, err := factory()
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := param.BuildVideoEncoder(
+				video.ReaderFunc(func() (image.Image, func(), error) {
+					return image.NewYCbCr(image.Rect(0, 0, 320, 240), image.YCbCrSubsampleRatio420), func() {}, nil
+				}),
+				prop.Media{
+					Video: prop.Video{
+						Width:       320,
+						Height:      240,
+						FrameRate:   30,
+						FrameFormat: frame.FormatI420,
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			// Keyframes are delayed to the start of a pattern
+			for i := 0; i < 9; i++ {
+				b, rel, err := r.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				rel()
+				if len(b) == 0 {
+					t.Fatalf("expected the frame %d not to be dropped", i)
+				}
+				if keyFrame := r.(*encoder).isKeyFrame; keyFrame != (i%4 == 0) {
+					t.Errorf("expected the keyframes at the frames 0, 4 and 8, but got %v at the frame %d", keyFrame, i)
+				}
+			}
+		})
+	}
+}
+
+func TestLayerShares(t *testing.T) {
+	shares, bitRate, err := layerShares(Params{TemporalLayers: 2, TemporalLayerBitRates: []int{300000, 100000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bitRate != 400000 || shares[0] != 0.75 || shares[1] != 0.25 {
+		t.Fatalf("expected the shares of 400000 bps to be [0.75 0.25], but got %v of %d bps", shares, bitRate)
+	}
+	if _, _, err := layerShares(Params{TemporalLayers: 3, TemporalLayerBitRates: []int{1, 2}}); err == nil {
+		t.Fatal("expected an error with the bitrates of 2 layers")
+	}
+}
+
+func TestCQLevel(t *testing.T) {
+	for mode, expected := range map[RateControlMode]int{
+		RateControlVBR: 0,
+		RateControlCBR: 0,
+		RateControlCQ:  20,
+		RateControlQ:   20,
+	} {
+		if cq, err := cqLevel(Params{RateControlEndUsage: mode, CQLevel: 20}); err != nil || cq != expected {
+			t.Fatalf("expected the CQ level %d of the mode %d, but got %d, %v", expected, mode, cq, err)
+		}
+	}
+	if _, err := cqLevel(Params{RateControlEndUsage: RateControlQ, CQLevel: 64}); err == nil {
+		t.Fatal("expected an error with the CQ level 64")
+	}
+
+	size := func(cq uint) int {
+		p, err := NewVP8Params()
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.RateControlEndUsage = RateControlQ
+		p.RateControlMinQuantizer, p.RateControlMaxQuantizer = 0, 63
+		p.CQLevel = cq
+		var i int
+		r, err := p.BuildVideoEncoder(
+			video.ReaderFunc(func() (image.Image, func(), error) {
+				img := image.NewYCbCr(image.Rect(0, 0, 320, 240), image.YCbCrSubsampleRatio420)
+				for j := range img.Y {
+					img.Y[j] = uint8(j%320 + i*4)
+				}
+				i++
+				return img, func() {}, nil
+			}),
+			prop.Media{
+				Video: prop.Video{
+					Width:       320,
+					Height:      240,
+					FrameRate:   30,
+					FrameFormat: frame.FormatI420,
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		var n int
+		for i := 0; i < 10; i++ {
+			b, rel, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			n += len(b)
+			rel()
+		}
+		return n
+	}
+	// Lower levels mean higher quality
+	if high, low := size(4), size(60); high <= low {
+		t.Fatalf("expected more bytes for the higher quality, but got %d and %d bytes", high, low)
+	}
+}
+
+func TestEncoderControls(t *testing.T) {
+	property := prop.Media{
+		Video: prop.Video{
+			Width:       320,
+			Height:      240,
+			FrameRate:   30,
+			FrameFormat: frame.FormatI420,
+		},
+	}
+	width := 320
+	reader := video.ReaderFunc(func() (image.Image, func(), error) {
+		return image.NewYCbCr(image.Rect(0, 0, width, 240), image.YCbCrSubsampleRatio420), func() {}, nil
+	})
+
+	p, err := NewVP9Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Deadline = DeadlineRealtime
+	p.CPUUsed = 8
+	p.Threads = 4
+	p.RowMultiThreading = true
+	p.TileColumns = 2
+	e, err := p.BuildVideoEncoder(reader, property)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	// Controls are applied again when the codec is initialized for the new size
+	for _, w := range []int{320, 640} {
+		width = w
+		_, release, err := e.Read()
+		if err != nil {
+			t.Fatalf("failed to encode %d pixels wide frame with the controls: %v", w, err)
+		}
+		release()
+	}
+
+	vp8, err := NewVP8Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// VP8's range is -16 to 16
+	vp8.CPUUsed = 100
+	if _, err := vp8.BuildVideoEncoder(reader, property); err == nil {
+		t.Fatal("expected an error with CPUUsed out of the range")
+	}
+}
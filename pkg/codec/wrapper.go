@@ -0,0 +1,57 @@
+package codec
+
+import (
+	"errors"
+
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// ErrNotSupported is returned by EncoderWrapper when the wrapped encoder lacks an optional interface.
+var ErrNotSupported = errors.New("codec: not supported by the wrapped encoder")
+
+// EncoderWrapper is embedded by encoders that wrap another encoder, e.g. to modify its output. It forwards the
+// optional ReadCloser interfaces to the wrapped encoder so the wrapper doesn't hide them. Prepare returns no
+// configuration if the wrapped encoder isn't a Preparer; the other methods return ErrNotSupported.
+type EncoderWrapper struct {
+	ReadCloser
+}
+
+// Prepare implements Preparer.
+func (w EncoderWrapper) Prepare() ([]byte, error) {
+	if p, ok := w.ReadCloser.(Preparer); ok {
+		return p.Prepare()
+	}
+	return nil, nil
+}
+
+// Reset implements Resetter.
+func (w EncoderWrapper) Reset(p prop.Media) error {
+	if r, ok := w.ReadCloser.(Resetter); ok {
+		return r.Reset(p)
+	}
+	return ErrNotSupported
+}
+
+// SetIntraRefresh implements IntraRefresher.
+func (w EncoderWrapper) SetIntraRefresh(enabled bool) error {
+	if r, ok := w.ReadCloser.(IntraRefresher); ok {
+		return r.SetIntraRefresh(enabled)
+	}
+	return ErrNotSupported
+}
+
+// SetLongTermReference implements LongTermReferencer.
+func (w EncoderWrapper) SetLongTermReference(enabled bool) error {
+	if r, ok := w.ReadCloser.(LongTermReferencer); ok {
+		return r.SetLongTermReference(enabled)
+	}
+	return ErrNotSupported
+}
+
+// EncodedFrame implements FrameReporter.
+func (w EncoderWrapper) EncodedFrame() (EncodedFrame, bool) {
+	if r, ok := w.ReadCloser.(FrameReporter); ok {
+		return r.EncodedFrame()
+	}
+	return EncodedFrame{}, false
+}
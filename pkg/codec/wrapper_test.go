@@ -0,0 +1,70 @@
+package codec
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+type plainEncoder struct{}
+
+func (e *plainEncoder) Read() ([]byte, func(), error) { return nil, func() {}, nil }
+func (e *plainEncoder) Close() error                  { return nil }
+func (e *plainEncoder) SetBitRate(int) error          { return nil }
+func (e *plainEncoder) ForceKeyFrame() error          { return nil }
+
+type optionalEncoder struct {
+	plainEncoder
+	width        int
+	intraRefresh bool
+	longTerm     bool
+}
+
+func (e *optionalEncoder) Prepare() ([]byte, error)                { return []byte{1}, nil }
+func (e *optionalEncoder) Reset(p prop.Media) error                { e.width = p.Width; return nil }
+func (e *optionalEncoder) SetIntraRefresh(enabled bool) error      { e.intraRefresh = enabled; return nil }
+func (e *optionalEncoder) SetLongTermReference(enabled bool) error { e.longTerm = enabled; return nil }
+func (e *optionalEncoder) EncodedFrame() (EncodedFrame, bool)      { return EncodedFrame{PTS: 1}, true }
+
+// wrappingEncoder wraps another encoder.
+type wrappingEncoder struct {
+	EncoderWrapper
+}
+
+func TestEncoderWrapper(t *testing.T) {
+	e := &optionalEncoder{}
+	var rc ReadCloser = &wrappingEncoder{EncoderWrapper{e}}
+
+	if config, err := rc.(Preparer).Prepare(); err != nil || len(config) != 1 {
+		t.Fatalf("expected the configuration of the wrapped encoder, but got %v, %v", config, err)
+	}
+	if err := rc.(Resetter).Reset(prop.Media{Video: prop.Video{Width: 640}}); err != nil || e.width != 640 {
+		t.Fatalf("expected the wrapped encoder to be reset, but got %v", err)
+	}
+	if err := rc.(IntraRefresher).SetIntraRefresh(true); err != nil || !e.intraRefresh {
+		t.Fatalf("expected the intra refresh to be enabled, but got %v", err)
+	}
+	if err := rc.(LongTermReferencer).SetLongTermReference(true); err != nil || !e.longTerm {
+		t.Fatalf("expected the long-term reference to be enabled, but got %v", err)
+	}
+	if f, ok := rc.(FrameReporter).EncodedFrame(); !ok || f.PTS != 1 {
+		t.Fatalf("expected the frame of the wrapped encoder, but got %+v", f)
+	}
+
+	rc = &wrappingEncoder{EncoderWrapper{&plainEncoder{}}}
+	if config, err := rc.(Preparer).Prepare(); err != nil || config != nil {
+		t.Fatalf("expected no configuration, but got %v, %v", config, err)
+	}
+	if err := rc.(Resetter).Reset(prop.Media{}); err != ErrNotSupported {
+		t.Fatalf("expected %v, but got %v", ErrNotSupported, err)
+	}
+	if err := rc.(IntraRefresher).SetIntraRefresh(true); err != ErrNotSupported {
+		t.Fatalf("expected %v, but got %v", ErrNotSupported, err)
+	}
+	if err := rc.(LongTermReferencer).SetLongTermReference(true); err != ErrNotSupported {
+		t.Fatalf("expected %v, but got %v", ErrNotSupported, err)
+	}
+	if _, ok := rc.(FrameReporter).EncodedFrame(); ok {
+		t.Fatal("expected no frame")
+	}
+}
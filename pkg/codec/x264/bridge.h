@@ -12,18 +12,32 @@
 typedef struct Slice {
   unsigned char *data;
   int data_len;
+  // Frame pts and dts, where pts is the one passed to enc_encode
+  int64_t pts;
+  int64_t dts;
 } Slice;
 
 typedef struct Encoder {
   x264_t *h;
   x264_picture_t pic_in;
   x264_param_t param;
+  // stats is the path of the two-pass statistics file
+  char *stats;
 } Encoder;
 
-Encoder *enc_new(x264_param_t param, char *preset, int *rc) {
+// enc_new opens an encoder with preset and stats. pass is 1 or 2 for the two-pass passes, which write and read
+// the statistics in stats, or 0 for single-pass encoding.
+Encoder *enc_new(x264_param_t param, char *preset, int screen_content, int zero_latency, int pass, char *stats, int *rc) {
   Encoder *e = (Encoder *)malloc(sizeof(Encoder));
-
-  if (x264_param_default_preset(&e->param, preset, "zerolatency") < 0) {
+  e->stats = stats;
+
+  // stillimage keeps text edges sharp, which the camera-oriented psy optimizations would blur
+  const char *tune = NULL;
+  if (zero_latency)
+    tune = screen_content ? "zerolatency,stillimage" : "zerolatency";
+  else if (screen_content)
+    tune = "stillimage";
+  if (x264_param_default_preset(&e->param, preset, tune) < 0) {
     free(preset);
     *rc = ERR_DEFAULT_PRESET;
     goto fail;
@@ -36,16 +50,39 @@ Encoder *enc_new(x264_param_t param, char *preset, int *rc) {
   e->param.i_height = param.i_height;
   e->param.i_fps_num = param.i_fps_num;
   e->param.i_fps_den = 1;
+  e->param.i_threads = param.i_threads;
   // Intra refres:
   e->param.i_keyint_max = param.i_keyint_max;
+  e->param.b_intra_refresh = param.b_intra_refresh;
+  // GOP structure; the preset picks the number of reference frames if it's unset:
+  e->param.i_bframe = param.i_bframe;
+  if (param.i_frame_reference > 0)
+    e->param.i_frame_reference = param.i_frame_reference;
   // Rate control:
-  e->param.rc.i_rc_method = X264_RC_ABR;
+  e->param.rc.i_rc_method = param.rc.i_rc_method;
+  e->param.rc.f_rf_constant = param.rc.f_rf_constant;
   e->param.rc.i_bitrate = param.rc.i_bitrate;
   e->param.rc.i_vbv_max_bitrate = param.rc.i_vbv_max_bitrate;
   e->param.rc.i_vbv_buffer_size = param.rc.i_vbv_buffer_size;
+  // Colorspace, which is left at defaults if it's unknown:
+  if (param.vui.i_colmatrix != 0) {
+    e->param.vui.b_fullrange = param.vui.b_fullrange;
+    e->param.vui.i_colorprim = param.vui.i_colorprim;
+    e->param.vui.i_transfer = param.vui.i_transfer;
+    e->param.vui.i_colmatrix = param.vui.i_colmatrix;
+  }
   // For streaming:
   e->param.b_repeat_headers = 1;
   e->param.b_annexb = 1;
+  // Two-pass:
+  if (pass == 1) {
+    e->param.rc.b_stat_write = 1;
+    e->param.rc.psz_stat_out = stats;
+    x264_param_apply_fastfirstpass(&e->param);
+  } else if (pass == 2) {
+    e->param.rc.b_stat_read = 1;
+    e->param.rc.psz_stat_in = stats;
+  }
 
   if (x264_param_apply_profile(&e->param, "high") < 0) {
     *rc = ERR_APPLY_PROFILE;
@@ -73,32 +110,87 @@ Encoder *enc_new(x264_param_t param, char *preset, int *rc) {
   return e;
 
 fail:
+  free(stats);
   free(e);
   return NULL;
 }
 
-Slice enc_encode(Encoder *e, uint8_t *y, uint8_t *cb, uint8_t *cr, int *rc) {
+// enc_output encodes pic_in, or the next delayed frame if pic_in is NULL.
+static Slice enc_output(Encoder *e, x264_picture_t *pic_in, int *rc) {
   x264_nal_t *nal;
   int i_nal;
 
   x264_picture_t pic_out;
-  e->pic_in.img.plane[0] = y;
-  e->pic_in.img.plane[1] = cb;
-  e->pic_in.img.plane[2] = cr;
-
-  int frame_size = x264_encoder_encode(e->h, &nal, &i_nal, &e->pic_in, &pic_out);
+  int frame_size = x264_encoder_encode(e->h, &nal, &i_nal, pic_in, &pic_out);
   Slice s = {.data_len = frame_size};
-  if (frame_size <= 0) {
+  if (frame_size < 0) {
     *rc = ERR_ENCODE;
     return s;
   }
+  if (frame_size == 0) {
+    // The lookahead or B-frames are holding the frame back
+    s.data_len = 0;
+    return s;
+  }
 
-  // e->pic_in.i_pts++;
   s.data = nal->p_payload;
+  s.pts = pic_out.i_pts;
+  s.dts = pic_out.i_dts;
   return s;
 }
 
+Slice enc_encode(Encoder *e, uint8_t *y, uint8_t *cb, uint8_t *cr, int64_t pts, int *rc) {
+  e->pic_in.img.plane[0] = y;
+  e->pic_in.img.plane[1] = cb;
+  e->pic_in.img.plane[2] = cr;
+  // B-frames reorder frames by pts
+  e->pic_in.i_pts = pts;
+
+  Slice s = enc_output(e, &e->pic_in, rc);
+  e->pic_in.i_type = X264_TYPE_AUTO;
+  return s;
+}
+
+// enc_flush outputs the next frame held back by the lookahead or B-frames, while enc_delayed_frames is nonzero.
+// The encoder accepts no new frames after a flush.
+Slice enc_flush(Encoder *e, int *rc) {
+  return enc_output(e, NULL, rc);
+}
+
+int enc_delayed_frames(Encoder *e) {
+  return x264_encoder_delayed_frames(e->h);
+}
+
+int enc_set_bitrate(Encoder *e, int bitrate) {
+  e->param.rc.i_bitrate = bitrate;
+  e->param.rc.i_vbv_max_bitrate = bitrate;
+  e->param.rc.i_vbv_buffer_size = bitrate * 2;
+  return x264_encoder_reconfig(e->h, &e->param);
+}
+
+// enc_set_intra_refresh reopens the encoder with or without intra refresh, since x264 can't reconfigure it.
+int enc_set_intra_refresh(Encoder *e, int enabled) {
+  if (e->param.b_intra_refresh == enabled)
+    return 0;
+
+  e->param.b_intra_refresh = enabled;
+  x264_t *h = x264_encoder_open(&e->param);
+  if (!h) {
+    e->param.b_intra_refresh = !enabled;
+    return -1;
+  }
+  x264_encoder_close(e->h);
+  e->h = h;
+  return 0;
+}
+
+// enc_force_key_frame makes the next frame an IDR frame.
+void enc_force_key_frame(Encoder *e) {
+  e->pic_in.i_type = X264_TYPE_IDR;
+}
+
 void enc_close(Encoder *e, int *rc) {
   x264_encoder_close(e->h);
+  free(e->stats);
   free(e);
 }
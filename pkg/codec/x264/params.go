@@ -12,6 +12,29 @@ type Params struct {
 
 	// Faster preset has lower CPU usage but lower quality
 	Preset Preset
+
+	// IntraRefresh replaces periodic IDR frames with intra refresh over KeyFrameInterval frames, see
+	// codec.IntraRefresher. Toggling it while encoding restarts the encoder with an IDR frame.
+	IntraRefresh bool
+
+	// ZeroLatency outputs each frame as soon as it's encoded, without lookahead or B-frames. NewParams enables it
+	// for real-time streams; disable it to compress recordings better.
+	ZeroLatency bool
+	// BFrames is the maximum number of consecutive B-frames, which delay output for reordering. It requires
+	// ZeroLatency to be disabled. The default, 0, uses no B-frames. Delayed frames are output when the source
+	// returns an error or the encoder is closed, and codec.FrameReporter reports the source frame of each output.
+	BFrames int
+	// RefFrames is the maximum number of reference frames. The default, 0, uses the Preset's value.
+	RefFrames int
+
+	// CRF encodes at a constant rate factor from 0 to 51 instead of at BitRate, where lower is better, e.g. 23 for
+	// recordings. If BitRate is set, it caps the bitrate. The default, 0, encodes at BitRate for real-time streams.
+	CRF float32
+	// TwoPass encodes each GOP of KeyFrameInterval frames in two passes: the first analyzes the frames so the second
+	// can distribute BitRate between them, e.g. for recordings of a target size. A GOP is buffered until it's
+	// complete, or until ForceKeyFrame, a source error or Close, so output is delayed by a GOP. It requires
+	// ZeroLatency to be disabled, and CRF is ignored.
+	TwoPass bool
 }
 
 // Preset represents a set of default configurations from libx264
@@ -36,12 +59,15 @@ func NewParams() (Params, error) {
 		BaseParams: codec.BaseParams{
 			KeyFrameInterval: 60,
 		},
+		ZeroLatency: true,
 	}, nil
 }
 
 // RTPCodec represents the codec metadata
 func (p *Params) RTPCodec() *codec.RTPCodec {
-	return codec.NewRTPH264Codec(90000)
+	c := codec.NewRTPH264Codec(90000)
+	c.SetPacketization(p.Packetization)
+	return c
 }
 
 // BuildVideoEncoder builds x264 encoder with given params
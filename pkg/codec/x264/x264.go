@@ -10,7 +10,10 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"io/ioutil"
+	"os"
 	"sync"
+	"time"
 	"unsafe"
 
 	"github.com/pion/mediadevices/pkg/codec"
@@ -18,11 +21,49 @@ import (
 	"github.com/pion/mediadevices/pkg/prop"
 )
 
+// defaultFrameRate sets the timestamp rate when the source frame rate is unknown.
+const defaultFrameRate = 30
+
 type encoder struct {
 	engine *C.Encoder
 	r      video.Reader
+	out    codec.BufferPool
 	mu     sync.Mutex
 	closed bool
+
+	// param, preset, screenContent and zeroLatency are used to open the engine of each TwoPass pass, in which case
+	// engine is nil. gop holds the current GOP, which is encoded once it has KeyFrameInterval frames.
+	param         C.x264_param_t
+	preset        string
+	screenContent C.int
+	zeroLatency   C.int
+	gop           []gopFrame
+
+	// interval is the nominal frame interval, and first is when the first frame was captured.
+	interval time.Duration
+	first    time.Time
+	// pts is the next frame's pts. frames holds frames not yet output, and times maps their pts to presentation
+	// times relative to the dts of the last output frame.
+	pts    int64
+	frames map[int64]codec.EncodedFrame
+	times  map[int64]time.Duration
+	// encoded is the frame from the last Read, and delayed holds frames flushed from the encoder, which are
+	// returned before err.
+	encoded codec.EncodedFrame
+	ok      bool
+	delayed []delayedFrame
+	err     error
+}
+
+type gopFrame struct {
+	img *image.YCbCr
+	pts int64
+}
+
+type delayedFrame struct {
+	data    []byte
+	release func()
+	frame   codec.EncodedFrame
 }
 
 type cerror int
@@ -58,6 +99,11 @@ var (
 	errAllocPicture  = fmt.Errorf("failed to alloc picture")
 	errOpenEngine    = fmt.Errorf("failed to open x264")
 	errEncode        = fmt.Errorf("failed to encode")
+	errSetBitRate    = fmt.Errorf("failed to set bitrate")
+	errIntraRefresh  = fmt.Errorf("failed to set intra refresh")
+	errBFrames       = fmt.Errorf("failed to use B-frames with zero latency")
+	errTwoPass       = fmt.Errorf("failed to use two-pass with zero latency")
+	errCRF           = fmt.Errorf("failed to set CRF out of the range from 0 to 51")
 )
 
 func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser, error) {
@@ -65,71 +111,397 @@ func newEncoder(r video.Reader, p prop.Media, params Params) (codec.ReadCloser,
 		params.KeyFrameInterval = 60
 	}
 
-	// Convert from bit/s to kbit/s because x264 uses kbit/s instead.
-	// Reference: https://code.videolan.org/videolan/x264/-/blob/7923c5818b50a3d8816eed222a7c43b418a73b36/encoder/ratecontrol.c#L657
-	params.BitRate /= 1000
-
 	param := C.x264_param_t{
 		i_csp:        C.X264_CSP_I420,
 		i_width:      C.int(p.Width),
 		i_height:     C.int(p.Height),
 		i_keyint_max: C.int(params.KeyFrameInterval),
+		i_threads:    C.int(video.Workers()),
 	}
-	param.rc.i_bitrate = C.int(params.BitRate)
-	param.rc.i_vbv_max_bitrate = param.rc.i_bitrate
-	param.rc.i_vbv_buffer_size = param.rc.i_vbv_max_bitrate * 2
-
-	var rc C.int
-	// cPreset will be freed in C.enc_new
-	cPreset := C.CString(fmt.Sprint(params.Preset))
-	engine := C.enc_new(param, cPreset, &rc)
-	if err := errFromC(rc); err != nil {
+	if params.IntraRefresh {
+		param.b_intra_refresh = 1
+	}
+	if params.BFrames > 0 && params.ZeroLatency {
+		return nil, errBFrames
+	}
+	param.i_bframe = C.int(params.BFrames)
+	param.i_frame_reference = C.int(params.RefFrames)
+	cs := params.ColorSpace(p)
+	primaries, transfer, matrix := codec.H264ColorDescription(cs)
+	param.vui.i_colorprim = C.int(primaries)
+	param.vui.i_transfer = C.int(transfer)
+	param.vui.i_colmatrix = C.int(matrix)
+	if cs.Range == prop.ColorRangeFull {
+		param.vui.b_fullrange = 1
+	}
+	if params.TwoPass && params.ZeroLatency {
+		return nil, errTwoPass
+	}
+	rateControl, err := newRateControl(params, params.BitRate)
+	if err != nil {
 		return nil, err
 	}
+	rateControl.apply(&param)
+
+	var screenContent C.int
+	if params.ScreenContent(p) {
+		screenContent = 1
+	}
+	var zeroLatency C.int
+	if params.ZeroLatency {
+		zeroLatency = 1
+	}
 
+	frameRate := p.FrameRate
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
 	e := encoder{
-		engine: engine,
-		r:      video.ToI420(r),
+		r:             codec.ToI420(r, cs),
+		param:         param,
+		preset:        fmt.Sprint(params.Preset),
+		screenContent: screenContent,
+		zeroLatency:   zeroLatency,
+		interval:      time.Duration(float64(time.Second) / float64(frameRate)),
+		frames:        make(map[int64]codec.EncodedFrame),
+		times:         make(map[int64]time.Duration),
 	}
+	if params.TwoPass {
+		// Engines are opened per GOP
+		return &e, nil
+	}
+	engine, err := e.open(0, "")
+	if err != nil {
+		return nil, err
+	}
+	e.engine = engine
 	return &e, nil
 }
 
+// rateControl holds the x264_param_t rate control settings, with bitrates in kbit/s.
+type rateControl struct {
+	crf           bool
+	rateFactor    float32
+	bitRate       int
+	vbvMaxBitRate int
+	vbvBufferSize int
+}
+
+// newRateControl returns the rate control for params at bitRate in bit/s. The VBV caps each frame's bitrate for
+// real-time streams and CRF; the second TwoPass pass distributes the bitrate across a GOP without it.
+func newRateControl(params Params, bitRate int) (rateControl, error) {
+	if params.CRF < 0 || params.CRF > 51 {
+		return rateControl{}, errCRF
+	}
+	// Convert from bit/s to kbit/s because x264 uses kbit/s instead.
+	// Reference: https://code.videolan.org/videolan/x264/-/blob/7923c5818b50a3d8816eed222a7c43b418a73b36/encoder/ratecontrol.c#L657
+	rc := rateControl{bitRate: bitRate / 1000}
+	if !params.TwoPass {
+		rc.vbvMaxBitRate, rc.vbvBufferSize = rc.bitRate, rc.bitRate*2
+		if params.CRF > 0 {
+			rc.crf, rc.rateFactor = true, params.CRF
+		}
+	}
+	return rc, nil
+}
+
+func (rc rateControl) apply(param *C.x264_param_t) {
+	param.rc.i_rc_method = C.X264_RC_ABR
+	if rc.crf {
+		param.rc.i_rc_method = C.X264_RC_CRF
+		param.rc.f_rf_constant = C.float(rc.rateFactor)
+	}
+	param.rc.i_bitrate = C.int(rc.bitRate)
+	param.rc.i_vbv_max_bitrate = C.int(rc.vbvMaxBitRate)
+	param.rc.i_vbv_buffer_size = C.int(rc.vbvBufferSize)
+}
+
+// open opens an engine with e.param. pass is the TwoPass pass, which writes or reads the statistics at stats, or
+// 0 for single-pass encoding.
+func (e *encoder) open(pass int, stats string) (*C.Encoder, error) {
+	var rc C.int
+	// cPreset will be freed in C.enc_new, and cStats in C.enc_close
+	cPreset := C.CString(e.preset)
+	var cStats *C.char
+	if pass > 0 {
+		cStats = C.CString(stats)
+	}
+	engine := C.enc_new(e.param, cPreset, e.screenContent, e.zeroLatency, C.int(pass), cStats, &rc)
+	if err := errFromC(rc); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
 func (e *encoder) Read() ([]byte, func(), error) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
+	e.ok = false
+	if len(e.delayed) > 0 {
+		return e.nextDelayed()
+	}
+	if e.err != nil {
+		return nil, func() {}, e.err
+	}
 	if e.closed {
 		return nil, func() {}, io.EOF
 	}
 
-	img, _, err := e.r.Read()
+	img, releaseImg, err := e.r.Read()
 	if err != nil {
-		return nil, func() {}, err
+		// Frames held back by the lookahead and B-frames are output before the error. The encoder accepts no new
+		// frames once they're flushed.
+		if err := e.flush(); err != nil {
+			return nil, func() {}, err
+		}
+		if len(e.delayed) == 0 {
+			return nil, func() {}, err
+		}
+		e.err = err
+		return e.nextDelayed()
 	}
+	defer releaseImg()
 	yuvImg := img.(*image.YCbCr)
+	pts := e.push()
+
+	if e.engine == nil {
+		// GOP frames are copied until they're encoded, since the source may reuse them
+		cp := *yuvImg
+		cp.Y = append([]byte{}, yuvImg.Y...)
+		cp.Cb = append([]byte{}, yuvImg.Cb...)
+		cp.Cr = append([]byte{}, yuvImg.Cr...)
+		e.gop = append(e.gop, gopFrame{img: &cp, pts: pts})
+		if len(e.gop) == int(e.param.i_keyint_max) {
+			if err := e.encodeGOP(); err != nil {
+				return nil, func() {}, err
+			}
+		}
+		if len(e.delayed) > 0 {
+			return e.nextDelayed()
+		}
+		return nil, func() {}, nil
+	}
 
+	s, err := encode(e.engine, yuvImg, pts)
+	if err != nil {
+		return nil, func() {}, err
+	}
+	encoded, release := e.output(s)
+	if len(encoded) > 0 {
+		e.encoded, e.ok = e.pop(s), true
+	}
+	return encoded, release, nil
+}
+
+func encode(engine *C.Encoder, img *image.YCbCr, pts int64) (C.Slice, error) {
 	var rc C.int
 	s := C.enc_encode(
-		e.engine,
-		(*C.uchar)(&yuvImg.Y[0]),
-		(*C.uchar)(&yuvImg.Cb[0]),
-		(*C.uchar)(&yuvImg.Cr[0]),
+		engine,
+		(*C.uchar)(&img.Y[0]),
+		(*C.uchar)(&img.Cb[0]),
+		(*C.uchar)(&img.Cr[0]),
+		C.int64_t(pts),
 		&rc,
 	)
-	if err := errFromC(rc); err != nil {
-		return nil, func() {}, err
+	return s, errFromC(rc)
+}
+
+// encodeGOP encodes the TwoPass GOP to e.delayed. The first pass writes frame statistics to a temporary file,
+// which the second pass reads to encode them.
+func (e *encoder) encodeGOP() error {
+	if len(e.gop) == 0 {
+		return nil
 	}
+	gop := e.gop
+	e.gop = nil
 
-	encoded := C.GoBytes(unsafe.Pointer(s.data), s.data_len)
-	return encoded, func() {}, err
+	f, err := ioutil.TempFile("", "x264-stats")
+	if err != nil {
+		return fmt.Errorf("failed to create the statistics of the first pass: %s", err)
+	}
+	stats := f.Name()
+	f.Close()
+	defer func() {
+		// x264 writes the statistics to a temporary file first, with the macroblock tree next to it
+		for _, suffix := range []string{"", ".temp", ".mbtree", ".mbtree.temp"} {
+			os.Remove(stats + suffix)
+		}
+	}()
+
+	for pass := 1; pass <= 2; pass++ {
+		engine, err := e.open(pass, stats)
+		if err != nil {
+			return err
+		}
+		output := pass == 2
+		for _, frame := range gop {
+			s, err := encode(engine, frame.img, frame.pts)
+			if err == nil && output && s.data_len > 0 {
+				data, release := e.output(s)
+				e.delayed = append(e.delayed, delayedFrame{data: data, release: release, frame: e.pop(s)})
+			}
+			if err != nil {
+				var rc C.int
+				C.enc_close(engine, &rc)
+				return err
+			}
+		}
+		err = e.drain(engine, output)
+		var rc C.int
+		C.enc_close(engine, &rc)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextDelayed returns the oldest frame flushed from the encoder.
+func (e *encoder) nextDelayed() ([]byte, func(), error) {
+	f := e.delayed[0]
+	e.delayed = e.delayed[1:]
+	e.encoded, e.ok = f.frame, true
+	return f.data, f.release, nil
+}
+
+// push records the frame last read from the source as pending output, and returns its pts.
+func (e *encoder) push() int64 {
+	m, _ := video.MetadataOf(e.r)
+	f := codec.EncodedFrame{Metadata: m, PTS: time.Duration(e.pts) * e.interval}
+	if !m.CaptureTime.IsZero() {
+		if e.first.IsZero() {
+			e.first = m.CaptureTime
+		}
+		f.PTS = m.CaptureTime.Sub(e.first)
+	}
+	pts := e.pts
+	e.frames[pts], e.times[pts] = f, f.PTS
+	e.pts++
+	return pts
+}
+
+// pop returns the frame for s and removes it from the pending frames.
+func (e *encoder) pop(s C.Slice) codec.EncodedFrame {
+	pts, dts := int64(s.pts), int64(s.dts)
+	f := e.frames[pts]
+	delete(e.frames, pts)
+
+	// dts is the pts of an earlier frame, or before the first pts for the first reordered frames
+	if t, ok := e.times[dts]; ok {
+		f.DTS = t
+	} else {
+		f.DTS = f.PTS - time.Duration(pts-dts)*e.interval
+	}
+	for pts := range e.times {
+		if pts < dts {
+			delete(e.times, pts)
+		}
+	}
+	return f
+}
+
+// output copies the data of s to a buffer, since the encoder reuses its memory.
+func (e *encoder) output(s C.Slice) ([]byte, func()) {
+	encoded, release := e.out.Get(int(s.data_len))
+	if len(encoded) > 0 {
+		copy(encoded, (*[1 << 30]byte)(unsafe.Pointer(s.data))[:len(encoded):len(encoded)])
+	}
+	return encoded, release
+}
+
+// flush outputs the encoder's delayed frames, or the buffered TwoPass GOP, to e.delayed.
+func (e *encoder) flush() error {
+	if e.engine == nil {
+		return e.encodeGOP()
+	}
+	return e.drain(e.engine, true)
+}
+
+// drain flushes engine's delayed frames, appending them to e.delayed if output is true.
+func (e *encoder) drain(engine *C.Encoder, output bool) error {
+	for C.enc_delayed_frames(engine) > 0 {
+		var rc C.int
+		s := C.enc_flush(engine, &rc)
+		if err := errFromC(rc); err != nil {
+			return err
+		}
+		if s.data_len == 0 || !output {
+			continue
+		}
+		data, release := e.output(s)
+		e.delayed = append(e.delayed, delayedFrame{data: data, release: release, frame: e.pop(s)})
+	}
+	return nil
+}
+
+// EncodedFrame implements codec.FrameReporter, since frames are delayed by the lookahead and reordered by
+// B-frames when ZeroLatency is disabled.
+func (e *encoder) EncodedFrame() (codec.EncodedFrame, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.encoded, e.ok
 }
 
 func (e *encoder) SetBitRate(b int) error {
-	panic("SetBitRate is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+	// x264 uses kbit/s
+	if e.engine == nil {
+		// TwoPass applies the new bitrate from the next GOP
+		rateControl, _ := newRateControl(Params{TwoPass: true}, b)
+		rateControl.apply(&e.param)
+		return nil
+	}
+	if C.enc_set_bitrate(e.engine, C.int(b/1000)) < 0 {
+		return errSetBitRate
+	}
+	return nil
+}
+
+// SetIntraRefresh implements codec.IntraRefresher.
+func (e *encoder) SetIntraRefresh(enabled bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+	var cEnabled C.int
+	if enabled {
+		cEnabled = 1
+	}
+	if e.engine == nil {
+		e.param.b_intra_refresh = cEnabled
+		return nil
+	}
+	// Delayed frames are output before the encoder is reopened
+	if err := e.flush(); err != nil {
+		return err
+	}
+	if C.enc_set_intra_refresh(e.engine, cEnabled) < 0 {
+		return errIntraRefresh
+	}
+	return nil
 }
 
 func (e *encoder) ForceKeyFrame() error {
-	panic("ForceKeyFrame is not implemented")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return io.EOF
+	}
+	if e.engine == nil {
+		// The next frame starts a new GOP
+		return e.encodeGOP()
+	}
+	C.enc_force_key_frame(e.engine)
+	return nil
 }
 
 func (e *encoder) Close() error {
@@ -140,8 +512,12 @@ func (e *encoder) Close() error {
 		return nil
 	}
 
-	var rc C.int
-	C.enc_close(e.engine, &rc)
+	// Read returns the delayed frames before io.EOF
+	err := e.flush()
+	if e.engine != nil {
+		var rc C.int
+		C.enc_close(e.engine, &rc)
+	}
 	e.closed = true
-	return nil
+	return err
 }
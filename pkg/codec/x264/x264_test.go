@@ -0,0 +1,324 @@
+package x264
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+var testProp = prop.Media{
+	Video: prop.Video{
+		Width:       320,
+		Height:      240,
+		FrameRate:   30,
+		FrameFormat: frame.FormatI420,
+	},
+}
+
+// testFrames returns n moving frames captured at 30 fps, then io.EOF. A negative n never ends.
+func testFrames(n int) video.Reader {
+	base := time.Unix(1600000000, 0)
+	var i int
+	return video.Stamp(video.ReaderFunc(func() (image.Image, func(), error) {
+		if i == n {
+			return nil, func() {}, io.EOF
+		}
+		img := image.NewYCbCr(image.Rect(0, 0, 320, 240), image.YCbCrSubsampleRatio420)
+		for j := range img.Y {
+			img.Y[j] = uint8(j%320 + i*4)
+		}
+		i++
+		return img, func() {}, nil
+	}), func() time.Time {
+		// Frame i-1 was just read
+		return base.Add(time.Duration(i-1) * time.Second / 30)
+	})
+}
+
+func newTestEncoder(t *testing.T, params Params, r video.Reader) codec.ReadCloser {
+	params.BitRate = 1000000
+	e, err := params.BuildVideoEncoder(r, testProp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// readAll reads until err, and returns the non-empty frames with their source frames.
+func readAll(t *testing.T, e codec.ReadCloser, err error) ([][]byte, []codec.EncodedFrame) {
+	var frames [][]byte
+	var encoded []codec.EncodedFrame
+	for {
+		b, release, readErr := e.Read()
+		if readErr == err {
+			return frames, encoded
+		}
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		f, ok := e.(codec.FrameReporter).EncodedFrame()
+		if len(b) == 0 {
+			if ok {
+				t.Fatal("expected no frame for the empty data")
+			}
+			release()
+			continue
+		}
+		if !ok {
+			t.Fatal("expected the frame of the data")
+		}
+		frames = append(frames, append([]byte{}, b...))
+		encoded = append(encoded, f)
+		release()
+	}
+}
+
+func TestZeroLatency(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newTestEncoder(t, params, testFrames(10))
+	defer e.Close()
+
+	for i := 0; i < 10; i++ {
+		b, release, err := e.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		// Each frame is output as soon as it's read
+		f, ok := e.(codec.FrameReporter).EncodedFrame()
+		if len(b) == 0 || !ok {
+			t.Fatalf("expected the frame %d to be output, but got %d bytes", i, len(b))
+		}
+		if f.Metadata.Sequence != uint64(i) || f.PTS != f.DTS {
+			t.Fatalf("expected the frame %d in order, but got %+v", i, f)
+		}
+		if expected := time.Duration(i) * time.Second / 30; f.PTS != expected {
+			t.Fatalf("expected the PTS %v, but got %v", expected, f.PTS)
+		}
+	}
+}
+
+func TestBFrames(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.BFrames = 2
+	if _, err := params.BuildVideoEncoder(testFrames(1), testProp); !errors.Is(err, errBFrames) {
+		t.Fatalf("expected %v with ZeroLatency, but got %v", errBFrames, err)
+	}
+
+	params.ZeroLatency = false
+	e := newTestEncoder(t, params, testFrames(60))
+	defer e.Close()
+
+	// Frames held back by the lookahead and B-frames are output before the source error
+	_, encoded := readAll(t, e, io.EOF)
+	if len(encoded) != 60 {
+		t.Fatalf("expected 60 frames, but got %d", len(encoded))
+	}
+	seen := make(map[uint64]bool)
+	var reordered bool
+	for i, f := range encoded {
+		seen[f.Metadata.Sequence] = true
+		if f.PTS != time.Duration(f.Metadata.Sequence)*time.Second/30 {
+			t.Fatalf("expected the PTS of the frame %d, but got %v", f.Metadata.Sequence, f.PTS)
+		}
+		if f.DTS > f.PTS {
+			t.Fatalf("expected the DTS before the PTS, but got %+v", f)
+		}
+		if i > 0 && f.DTS <= encoded[i-1].DTS {
+			t.Fatalf("expected the increasing DTS, but got %v after %v", f.DTS, encoded[i-1].DTS)
+		}
+		reordered = reordered || f.DTS != f.PTS
+	}
+	if len(seen) != 60 {
+		t.Fatalf("expected each frame to be output once, but got %d frames", len(seen))
+	}
+	if !reordered {
+		t.Fatal("expected the frames to be reordered by the B-frames")
+	}
+}
+
+func TestCloseFlush(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.ZeroLatency = false
+	params.BFrames = 2
+	e := newTestEncoder(t, params, testFrames(-1))
+
+	var n int
+	for i := 0; i < 30; i++ {
+		b, release, err := e.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		if len(b) > 0 {
+			n++
+		}
+	}
+	if n == 30 {
+		t.Fatal("expected the frames to be delayed by the lookahead")
+	}
+
+	// Delayed frames are returned before io.EOF
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	frames, _ := readAll(t, e, io.EOF)
+	if n+len(frames) != 30 {
+		t.Fatalf("expected 30 frames, but got %d", n+len(frames))
+	}
+}
+
+// sps returns the SPS of an Annex B frame.
+func sps(frame []byte) []byte {
+	for _, nal := range bytes.Split(frame, []byte{0, 0, 1}) {
+		if len(nal) > 0 && nal[0]&0x1f == 7 {
+			return bytes.TrimRight(nal, "\x00")
+		}
+	}
+	return nil
+}
+
+func TestRefFrames(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.Preset = PresetMedium
+	params.RefFrames = 1
+	e := newTestEncoder(t, params, testFrames(1))
+	defer e.Close()
+	frames, _ := readAll(t, e, io.EOF)
+
+	// The SPS max_num_ref_frames is the number of reference frames, which is 3 in the medium preset
+	params.RefFrames = 0
+	preset := newTestEncoder(t, params, testFrames(1))
+	defer preset.Close()
+	presetFrames, _ := readAll(t, preset, io.EOF)
+
+	if len(frames) != 1 || len(presetFrames) != 1 {
+		t.Fatalf("expected a frame each, but got %d and %d", len(frames), len(presetFrames))
+	}
+	if s := sps(frames[0]); s == nil || bytes.Equal(s, sps(presetFrames[0])) {
+		t.Fatal("expected the SPS with the other number of the reference frames")
+	}
+}
+
+func TestTwoPass(t *testing.T) {
+	params, err := NewParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.TwoPass = true
+	if _, err := params.BuildVideoEncoder(testFrames(1), testProp); !errors.Is(err, errTwoPass) {
+		t.Fatalf("expected %v with ZeroLatency, but got %v", errTwoPass, err)
+	}
+
+	params.ZeroLatency = false
+	params.KeyFrameInterval = 10
+	e := newTestEncoder(t, params, testFrames(25))
+	defer e.Close()
+
+	// Frames are output once each GOP of 10 frames has been read
+	for i := 0; i < 9; i++ {
+		b, _, err := e.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != 0 {
+			t.Fatalf("expected the frame %d to be buffered, but got %d bytes", i, len(b))
+		}
+	}
+	b, _, err := e.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := e.(codec.FrameReporter).EncodedFrame(); len(b) == 0 || !ok || f.Metadata.Sequence != 0 {
+		t.Fatalf("expected the first frame after the GOP, but got %d bytes of %+v", len(b), f)
+	}
+
+	// The last GOP of 5 frames is encoded when the source ends
+	_, encoded := readAll(t, e, io.EOF)
+	if len(encoded) != 24 {
+		t.Fatalf("expected 24 more frames, but got %d", len(encoded))
+	}
+	for i, f := range encoded {
+		if f.Metadata.Sequence != uint64(i+1) {
+			t.Fatalf("expected the frame %d, but got %d", i+1, f.Metadata.Sequence)
+		}
+	}
+}
+
+func TestRateControl(t *testing.T) {
+	for name, c := range map[string]struct {
+		params   Params
+		expected rateControl
+	}{
+		"BitRate": {
+			Params{},
+			rateControl{bitRate: 1000, vbvMaxBitRate: 1000, vbvBufferSize: 2000},
+		},
+		"CRF": {
+			Params{CRF: 23},
+			rateControl{crf: true, rateFactor: 23, bitRate: 1000, vbvMaxBitRate: 1000, vbvBufferSize: 2000},
+		},
+		"TwoPass": {
+			// CRF is ignored, and the bitrate isn't capped by the VBV
+			Params{CRF: 23, TwoPass: true},
+			rateControl{bitRate: 1000},
+		},
+	} {
+		rc, err := newRateControl(c.params, 1000000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rc != c.expected {
+			t.Fatalf("%s: expected %+v, but got %+v", name, c.expected, rc)
+		}
+	}
+	if _, err := newRateControl(Params{CRF: 52}, 0); !errors.Is(err, errCRF) {
+		t.Fatalf("expected %v, but got %v", errCRF, err)
+	}
+}
+
+func TestCRF(t *testing.T) {
+	size := func(crf float32) int {
+		params, err := NewParams()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The bitrate doesn't cap the quality
+		params.CRF = crf
+		e, err := params.BuildVideoEncoder(testFrames(10), testProp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer e.Close()
+		frames, _ := readAll(t, e, io.EOF)
+		var n int
+		for _, frame := range frames {
+			n += len(frame)
+		}
+		return n
+	}
+	// Lower rate factors mean higher quality
+	if high, low := size(10), size(45); high <= low {
+		t.Fatalf("expected more bytes for the higher quality, but got %d and %d bytes", high, low)
+	}
+}
@@ -0,0 +1,359 @@
+// +build alsa
+
+package alsa
+
+/*
+#cgo pkg-config: alsa
+#include <stdlib.h>
+#include <alsa/asoundlib.h>
+
+// alsaTestFormat returns 0 if the device supports the sample format.
+static int alsaTestFormat(snd_pcm_t *pcm, snd_pcm_format_t format)
+{
+	snd_pcm_hw_params_t *hw;
+	int err;
+
+	snd_pcm_hw_params_alloca(&hw);
+	if ((err = snd_pcm_hw_params_any(pcm, hw)) < 0)
+		return err;
+	return snd_pcm_hw_params_test_format(pcm, hw, format);
+}
+
+// alsaTestRate returns 0 if the device supports the sampling rate without resampling.
+static int alsaTestRate(snd_pcm_t *pcm, unsigned int rate)
+{
+	snd_pcm_hw_params_t *hw;
+	int err;
+
+	snd_pcm_hw_params_alloca(&hw);
+	if ((err = snd_pcm_hw_params_any(pcm, hw)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_rate_resample(pcm, hw, 0)) < 0)
+		return err;
+	return snd_pcm_hw_params_test_rate(pcm, hw, rate, 0);
+}
+
+// alsaChannels returns the channel count range.
+static int alsaChannels(snd_pcm_t *pcm, unsigned int *min, unsigned int *max)
+{
+	snd_pcm_hw_params_t *hw;
+	int err;
+
+	snd_pcm_hw_params_alloca(&hw);
+	if ((err = snd_pcm_hw_params_any(pcm, hw)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_get_channels_min(hw, min)) < 0)
+		return err;
+	return snd_pcm_hw_params_get_channels_max(hw, max);
+}
+
+// alsaConfigure sets the device's parameters. period and buffer are the requested sizes in frames,
+// and they're updated to the sizes the device chose.
+static int alsaConfigure(snd_pcm_t *pcm, snd_pcm_format_t format, unsigned int channels, unsigned int rate,
+	snd_pcm_uframes_t *period, snd_pcm_uframes_t *buffer)
+{
+	snd_pcm_hw_params_t *hw;
+	snd_pcm_sw_params_t *sw;
+	int err;
+
+	snd_pcm_hw_params_alloca(&hw);
+	if ((err = snd_pcm_hw_params_any(pcm, hw)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_rate_resample(pcm, hw, 0)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_access(pcm, hw, SND_PCM_ACCESS_RW_INTERLEAVED)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_format(pcm, hw, format)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_channels(pcm, hw, channels)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_rate(pcm, hw, rate, 0)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_period_size_near(pcm, hw, period, NULL)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_set_buffer_size_near(pcm, hw, buffer)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params(pcm, hw)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_get_period_size(hw, period, NULL)) < 0)
+		return err;
+	if ((err = snd_pcm_hw_params_get_buffer_size(hw, buffer)) < 0)
+		return err;
+
+	snd_pcm_sw_params_alloca(&sw);
+	if ((err = snd_pcm_sw_params_current(pcm, sw)) < 0)
+		return err;
+	if ((err = snd_pcm_sw_params_set_avail_min(pcm, sw, *period)) < 0)
+		return err;
+	if ((err = snd_pcm_sw_params_set_start_threshold(pcm, sw, 1)) < 0)
+		return err;
+	return snd_pcm_sw_params(pcm, sw);
+}
+*/
+import "C"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/pion/mediadevices/internal/logging"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// maxChannels limits the properties of devices with many channels, e.g. snd-aloop.
+const maxChannels = 8
+
+var logger = logging.NewLogger("mediadevices/driver/alsa")
+
+var (
+	errUnsupportedFormat = errors.New("the provided audio format is not supported")
+	errClosed            = errors.New("the device is closed")
+)
+
+// sampleRates are the sampling rates to negotiate with the device. The first one is the default.
+var sampleRates = []int{48000, 44100, 88200, 96000, 16000}
+
+// frameDurations are the device's period times, which are common audio codec frame durations (ptime).
+// The first one is the default.
+var frameDurations = []time.Duration{
+	20 * time.Millisecond,
+	10 * time.Millisecond,
+	40 * time.Millisecond,
+	60 * time.Millisecond,
+}
+
+// formats are the little endian sample formats the decoder supports, in order of
+// preference.
+var formats = []struct {
+	format     C.snd_pcm_format_t
+	sampleSize int
+	isFloat    bool
+}{
+	{C.SND_PCM_FORMAT_S16_LE, 2, false},
+	{C.SND_PCM_FORMAT_FLOAT_LE, 4, true},
+	{C.SND_PCM_FORMAT_S32_LE, 4, false},
+	{C.SND_PCM_FORMAT_S24_3LE, 3, false},
+}
+
+type microphone struct {
+	device pcmDevice
+	pcm    *C.snd_pcm_t
+	mutex  sync.Mutex
+	// options overrides package level BufferOptions if it's not nil.
+	options *BufferOptions
+}
+
+func init() {
+	devices, err := captureDevices()
+	if err != nil {
+		// ALSA isn't available
+		logger.Debugf("failed to enumerate devices: %s\n", err)
+		return
+	}
+
+	for i, device := range devices {
+		priority := driver.PriorityNormal
+		if i == 0 {
+			// The first card's first device is ALSA's default device.
+			priority = driver.PriorityHigh
+		}
+		driver.GetManager().Register(&microphone{device: device}, driver.Info{
+			Label:      device.label(),
+			DeviceType: driver.Microphone,
+			Priority:   priority,
+			Hardware:   device.hardware(),
+		})
+	}
+}
+
+func alsaError(op string, err C.int) error {
+	return fmt.Errorf("failed to %s: %s", op, C.GoString(C.snd_strerror(err)))
+}
+
+func (m *microphone) Configure(opts []driver.Option) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.options = nil
+	if options, ok := bufferOptionsOf(opts); ok {
+		m.options = &options
+	}
+	return nil
+}
+
+func (m *microphone) Open() error {
+	name := C.CString(m.device.hw())
+	defer C.free(unsafe.Pointer(name))
+
+	var pcm *C.snd_pcm_t
+	if err := C.snd_pcm_open(&pcm, name, C.SND_PCM_STREAM_CAPTURE, 0); err < 0 {
+		return alsaError("open "+m.device.hw(), err)
+	}
+
+	m.mutex.Lock()
+	m.pcm = pcm
+	m.mutex.Unlock()
+	return nil
+}
+
+func (m *microphone) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.pcm != nil {
+		C.snd_pcm_drop(m.pcm)
+		C.snd_pcm_close(m.pcm)
+		m.pcm = nil
+	}
+	return nil
+}
+
+func (m *microphone) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
+	decoder, err := wave.NewDecoder(&wave.RawFormat{
+		SampleSize:  inputProp.SampleSize,
+		IsFloat:     inputProp.IsFloat,
+		Interleaved: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	format, ok := sampleFormat(inputProp.SampleSize, inputProp.IsFloat)
+	if !ok {
+		return nil, errUnsupportedFormat
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.pcm == nil {
+		return nil, errClosed
+	}
+
+	options := currentBufferOptions()
+	if m.options != nil {
+		options = *m.options
+	}
+	period, buffer := options.sizes(inputProp.SampleRate, inputProp.Latency)
+	cPeriod, cBuffer := C.snd_pcm_uframes_t(period), C.snd_pcm_uframes_t(buffer)
+	if err := C.alsaConfigure(
+		m.pcm, format, C.uint(inputProp.ChannelCount), C.uint(inputProp.SampleRate), &cPeriod, &cBuffer,
+	); err < 0 {
+		return nil, alsaError("configure "+m.device.hw(), err)
+	}
+	logger.Debugf("%s: period %d frames, buffer %d frames\n", m.device.hw(), cPeriod, cBuffer)
+
+	if err := C.snd_pcm_prepare(m.pcm); err < 0 {
+		return nil, alsaError("prepare "+m.device.hw(), err)
+	}
+
+	frameSize := inputProp.SampleSize * inputProp.ChannelCount
+	buf := make([]byte, int(cPeriod)*frameSize)
+	var xruns int
+	// Decode into the pool, so chunks are reused once readers release them.
+	var pool wave.Pool
+
+	reader := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+
+		var n int
+		for n < int(cPeriod) {
+			if m.pcm == nil {
+				return nil, func() {}, io.EOF
+			}
+
+			ret := C.snd_pcm_readi(m.pcm, unsafe.Pointer(&buf[n*frameSize]), cPeriod-C.snd_pcm_uframes_t(n))
+			if ret >= 0 {
+				n += int(ret)
+				continue
+			}
+
+			// Overruns (-EPIPE) and suspends (-ESTRPIPE) are recovered by preparing the device again,
+			// and the period's samples are discarded since they're no longer continuous.
+			code := C.int(ret)
+			xruns++
+			logger.Debugf("%s: xrun %d: %s\n", m.device.hw(), xruns, C.GoString(C.snd_strerror(code)))
+			if err := C.snd_pcm_recover(m.pcm, code, 1); err < 0 {
+				return nil, func() {}, alsaError("recover "+m.device.hw(), err)
+			}
+			n = 0
+		}
+
+		chunk, release, err := pool.Decode(decoder, binary.LittleEndian, buf, inputProp.ChannelCount)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		switch chunk := chunk.(type) {
+		case *wave.Int16Interleaved:
+			chunk.Size.SamplingRate = inputProp.SampleRate
+		case *wave.Int32Interleaved:
+			chunk.Size.SamplingRate = inputProp.SampleRate
+		case *wave.Float32Interleaved:
+			chunk.Size.SamplingRate = inputProp.SampleRate
+		}
+		return chunk, release, nil
+	})
+	return reader, nil
+}
+
+func (m *microphone) Properties() []prop.Media {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.pcm == nil {
+		return nil
+	}
+
+	var minCh, maxCh C.uint
+	if err := C.alsaChannels(m.pcm, &minCh, &maxCh); err < 0 {
+		logger.Debugf("%s\n", alsaError("query channels", err))
+		return nil
+	}
+	if maxCh > maxChannels {
+		maxCh = maxChannels
+	}
+
+	var props []prop.Media
+	for ch := int(minCh); ch <= int(maxCh); ch++ {
+		for _, sampleRate := range sampleRates {
+			if C.alsaTestRate(m.pcm, C.uint(sampleRate)) < 0 {
+				continue
+			}
+			for _, latency := range frameDurations {
+				for _, f := range formats {
+					if C.alsaTestFormat(m.pcm, f.format) < 0 {
+						continue
+					}
+					props = append(props, prop.Media{
+						Audio: prop.Audio{
+							ChannelCount:  ch,
+							SampleRate:    sampleRate,
+							SampleSize:    f.sampleSize,
+							IsFloat:       f.isFloat,
+							IsBigEndian:   false,
+							IsInterleaved: true,
+							Latency:       latency,
+						},
+					})
+				}
+			}
+		}
+	}
+	return props
+}
+
+func sampleFormat(sampleSize int, isFloat bool) (C.snd_pcm_format_t, bool) {
+	for _, f := range formats {
+		if f.sampleSize == sampleSize && f.isFloat == isFloat {
+			return f.format, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,14 @@
+// Package alsa registers ALSA capture devices as audio drivers. Devices are opened directly through the hw
+// plugin, so it works on headless Linux systems that don't run PulseAudio or PipeWire, and samples don't go
+// through a sound server.
+//
+// Devices are enumerated from /proc/asound. The device period is the selected property's frame duration
+// (Latency), and SetBufferOptions tunes the ring buffer size. Ring buffer overruns are recovered by preparing
+// the device again.
+//
+// ALSA is linked with cgo, and the package is only built with the alsa build tag:
+//
+//	go build -tags alsa
+//
+// Reference: https://www.alsa-project.org/alsa-doc/alsa-lib/pcm.html
+package alsa
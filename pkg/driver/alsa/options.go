@@ -0,0 +1,71 @@
+package alsa
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+const (
+	defaultPeriods    = 4
+	defaultPeriodTime = 20 * time.Millisecond
+)
+
+// BufferOptions controls device ring buffers. It's also accepted in GetUserMedia constraints'
+// DriverOptions, where it overrides SetBufferOptions for the device.
+type BufferOptions struct {
+	// Periods is the number of periods in the ring buffer. A larger buffer tolerates longer reader stalls without
+	// overruns, but samples are delayed more after a stall. If it's 0, 4 is used.
+	Periods int
+}
+
+var (
+	bufferOptionsMu sync.Mutex
+	bufferOptions   BufferOptions
+)
+
+// SetBufferOptions configures the ALSA drivers. The options apply to devices recorded
+// after this call.
+func SetBufferOptions(options BufferOptions) {
+	bufferOptionsMu.Lock()
+	defer bufferOptionsMu.Unlock()
+	bufferOptions = options
+}
+
+func currentBufferOptions() BufferOptions {
+	bufferOptionsMu.Lock()
+	defer bufferOptionsMu.Unlock()
+	return bufferOptions
+}
+
+// bufferOptionsOf returns BufferOptions in opts, or false if there's none. The last one takes precedence.
+func bufferOptionsOf(opts []driver.Option) (BufferOptions, bool) {
+	var options BufferOptions
+	var found bool
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case BufferOptions:
+			options, found = o, true
+		case *BufferOptions:
+			options, found = *o, true
+		}
+	}
+	return options, found
+}
+
+// sizes returns the period and buffer sizes in frames for the sampling rate and period time.
+func (o BufferOptions) sizes(sampleRate int, periodTime time.Duration) (period, buffer int) {
+	if periodTime <= 0 {
+		periodTime = defaultPeriodTime
+	}
+	periods := o.Periods
+	if periods <= 0 {
+		periods = defaultPeriods
+	}
+	period = int(int64(sampleRate) * int64(periodTime) / int64(time.Second))
+	if period < 1 {
+		period = 1
+	}
+	return period, period * periods
+}
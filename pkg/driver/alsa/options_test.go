@@ -0,0 +1,39 @@
+package alsa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+func TestBufferOptionsSizes(t *testing.T) {
+	testCases := map[string]struct {
+		options        BufferOptions
+		periodTime     time.Duration
+		period, buffer int
+	}{
+		"Default":    {BufferOptions{}, 0, 960, 3840},
+		"PeriodTime": {BufferOptions{}, 10 * time.Millisecond, 480, 1920},
+		"Periods":    {BufferOptions{Periods: 2}, 20 * time.Millisecond, 960, 1920},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			period, buffer := c.options.sizes(48000, c.periodTime)
+			if period != c.period || buffer != c.buffer {
+				t.Fatalf("expected %d and %d frames, but got %d and %d", c.period, c.buffer, period, buffer)
+			}
+		})
+	}
+}
+
+func TestBufferOptionsOf(t *testing.T) {
+	if _, ok := bufferOptionsOf([]driver.Option{"unknown"}); ok {
+		t.Fatal("expected no BufferOptions")
+	}
+	options, ok := bufferOptionsOf([]driver.Option{BufferOptions{Periods: 2}, &BufferOptions{Periods: 8}})
+	if !ok || options.Periods != 8 {
+		t.Fatalf("expected the last BufferOptions, but got %v", options)
+	}
+}
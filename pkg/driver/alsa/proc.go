@@ -0,0 +1,132 @@
+package alsa
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+const (
+	procCards = "/proc/asound/cards"
+	procPCM   = "/proc/asound/pcm"
+)
+
+// e.g. " 0 [PCH            ]: HDA-Intel - HDA Intel PCH"
+var cardPattern = regexp.MustCompile(`^\s*(\d+)\s+\[(\S+)\s*\]:`)
+
+// pcmDevice is a sound card's capture device.
+type pcmDevice struct {
+	card   int
+	device int
+	// cardID is the card's identifier, e.g. PCH.
+	cardID string
+	name   string
+}
+
+// hw returns the device's name for the hw plugin.
+func (d pcmDevice) hw() string {
+	return fmt.Sprintf("hw:%d,%d", d.card, d.device)
+}
+
+// label returns a label that's stable regardless of card order.
+func (d pcmDevice) label() string {
+	return fmt.Sprintf("hw:CARD=%s,DEV=%d", d.cardID, d.device)
+}
+
+// hardware returns the attributes of the device's sound card, e.g. a USB audio interface's serial
+// number, which identify it even if another card of the same model takes its card ID.
+func (d pcmDevice) hardware() driver.Hardware {
+	hw, _ := driver.USBHardware(fmt.Sprintf("/sys/class/sound/card%d/device", d.card))
+	hw.Index = d.device
+	return hw
+}
+
+// captureDevices enumerates capture devices from /proc/asound.
+func captureDevices() ([]pcmDevice, error) {
+	cards, err := os.Open(procCards)
+	if err != nil {
+		return nil, err
+	}
+	defer cards.Close()
+
+	ids, err := parseCards(cards)
+	if err != nil {
+		return nil, err
+	}
+
+	pcm, err := os.Open(procPCM)
+	if err != nil {
+		return nil, err
+	}
+	defer pcm.Close()
+
+	return parsePCM(pcm, ids)
+}
+
+// parseCards returns card identifiers from /proc/asound/cards.
+func parseCards(r io.Reader) (map[int]string, error) {
+	ids := make(map[int]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := cardPattern.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			// Each card's second line is its long name.
+			continue
+		}
+		card, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, err
+		}
+		ids[card] = m[2]
+	}
+	return ids, scanner.Err()
+}
+
+// parsePCM returns capture devices from /proc/asound/pcm. ids are the card identifiers, and the card
+// number is used for cards missing from ids.
+func parsePCM(r io.Reader, ids map[int]string) ([]pcmDevice, error) {
+	var devices []pcmDevice
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// e.g. "00-00: ALC892 Analog : ALC892 Analog : playback 1 : capture 1"
+		fields := strings.Split(scanner.Text(), " : ")
+		if len(fields) < 3 {
+			continue
+		}
+		var card, device int
+		if _, err := fmt.Sscanf(fields[0], "%d-%d:", &card, &device); err != nil {
+			return nil, fmt.Errorf("failed to parse pcm: %s", err)
+		}
+		if !hasCapture(fields[2:]) {
+			continue
+		}
+
+		cardID, ok := ids[card]
+		if !ok {
+			cardID = strconv.Itoa(card)
+		}
+		devices = append(devices, pcmDevice{
+			card:   card,
+			device: device,
+			cardID: cardID,
+			name:   strings.TrimSpace(fields[1]),
+		})
+	}
+	return devices, scanner.Err()
+}
+
+// hasCapture reports whether a pcm's streams, e.g. "playback 1" and "capture 1", include capture.
+func hasCapture(streams []string) bool {
+	for _, stream := range streams {
+		if strings.HasPrefix(strings.TrimSpace(stream), "capture") {
+			return true
+		}
+	}
+	return false
+}
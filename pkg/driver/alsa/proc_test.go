@@ -0,0 +1,49 @@
+package alsa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testCards = ` 0 [PCH            ]: HDA-Intel - HDA Intel PCH
+                      HDA Intel PCH at 0xf7f10000 irq 130
+ 1 [Device         ]: USB-Audio - USB Audio Device
+                      C-Media Electronics Inc. USB Audio Device at usb-0000:00:14.0-2, full speed
+`
+	testPCM = `00-00: ALC892 Analog : ALC892 Analog : playback 1 : capture 1
+00-01: ALC892 Digital : ALC892 Digital : playback 1
+00-02: ALC892 Alt Analog : ALC892 Alt Analog : capture 1
+01-00: USB Audio : USB Audio : playback 1 : capture 1
+02-00: Loopback PCM : Loopback PCM : playback 8 : capture 8
+`
+)
+
+func TestCaptureDevices(t *testing.T) {
+	ids, err := parseCards(strings.NewReader(testCards))
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices, err := parsePCM(strings.NewReader(testPCM), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []pcmDevice{
+		{card: 0, device: 0, cardID: "PCH", name: "ALC892 Analog"},
+		{card: 0, device: 2, cardID: "PCH", name: "ALC892 Alt Analog"},
+		{card: 1, device: 0, cardID: "Device", name: "USB Audio"},
+		{card: 2, device: 0, cardID: "2", name: "Loopback PCM"},
+	}
+	if !reflect.DeepEqual(expected, devices) {
+		t.Fatalf("expected %v, but got %v", expected, devices)
+	}
+
+	if hw := devices[1].hw(); hw != "hw:0,2" {
+		t.Errorf("expected hw:0,2, but got %s", hw)
+	}
+	if label := devices[2].label(); label != "hw:CARD=Device,DEV=0" {
+		t.Errorf("expected hw:CARD=Device,DEV=0, but got %s", label)
+	}
+}
@@ -0,0 +1,168 @@
+// +build jetson
+
+package argus
+
+// #cgo CXXFLAGS: -std=c++11 -I/usr/src/jetson_multimedia_api/include
+// #cgo LDFLAGS: -L/usr/lib/aarch64-linux-gnu/tegra -lnvargus_socketclient -lnvbuf_utils
+// #include "bridge.hpp"
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	maxModes    = 32
+	readTimeout = 5000000000 // 5 seconds in nanoseconds
+)
+
+var errReadTimeout = errors.New("read timeout")
+
+type camera struct {
+	index  int
+	cam    *C.ArgusCamera
+	mutex  sync.Mutex
+	cancel func()
+}
+
+func init() {
+	if C.argusInit() != C.ARGUS_OK {
+		return
+	}
+
+	n := int(C.argusCameraCount())
+	for i := 0; i < n; i++ {
+		priority := driver.PriorityNormal
+		if i == 0 {
+			priority = driver.PriorityHigh
+		}
+		driver.GetManager().Register(&camera{index: i}, driver.Info{
+			Label:      fmt.Sprintf("argus%d", i),
+			DeviceType: driver.Camera,
+			Priority:   priority,
+		})
+	}
+}
+
+func (c *camera) Open() error {
+	// The capture session is created in VideoRecord since it needs the resolution
+	return nil
+}
+
+func (c *camera) Close() error {
+	if c.cancel != nil {
+		// Let the reader know the caller has closed the camera
+		c.cancel()
+		// Wait until the reader is done with the current frame
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.cancel = nil
+	}
+
+	if c.cam != nil {
+		C.argusCameraClose(c.cam)
+		c.cam = nil
+	}
+	return nil
+}
+
+func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
+	cam := C.argusCameraOpen(C.int(c.index), C.int(p.Width), C.int(p.Height), C.int(p.FrameRate))
+	if cam == nil {
+		return nil, fmt.Errorf("failed to start argus%d with %dx%d", c.index, p.Width, p.Height)
+	}
+	c.cam = cam
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	rect := image.Rect(0, 0, p.Width, p.Height)
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		if ctx.Err() != nil {
+			// Return EOF if the camera is already closed.
+			return nil, func() {}, io.EOF
+		}
+
+		fd := C.argusCameraRead(cam, readTimeout)
+		switch {
+		case fd == C.ARGUS_TIMEOUT:
+			return nil, func() {}, errReadTimeout
+		case fd < 0:
+			return nil, func() {}, fmt.Errorf("failed to acquire a frame from argus%d", c.index)
+		}
+
+		return &nvmmImage{fd: int(fd), rect: rect}, func() {}, nil
+	})
+
+	return r, nil
+}
+
+func (c *camera) Properties() []prop.Media {
+	var modes [maxModes * 3]C.int
+	n := int(C.argusCameraModes(C.int(c.index), &modes[0], maxModes))
+
+	properties := make([]prop.Media, 0, n)
+	for i := 0; i < n; i++ {
+		// NVMM buffers are laid out as I420
+		properties = append(properties, prop.Media{
+			Video: prop.Video{
+				Width:       int(modes[i*3]),
+				Height:      int(modes[i*3+1]),
+				FrameRate:   float32(modes[i*3+2]),
+				FrameFormat: frame.FormatI420,
+			},
+		})
+	}
+	return properties
+}
+
+// nvmmImage is a frame in an NVMM buffer. The buffer is only copied to CPU memory when the pixels
+// are accessed, e.g. by a software encoder or a transform.
+type nvmmImage struct {
+	fd   int
+	rect image.Rectangle
+
+	once sync.Once
+	img  *image.YCbCr
+}
+
+// DMABuf returns the NVMM buffer's dmabuf fd
+func (i *nvmmImage) DMABuf() int {
+	return i.fd
+}
+
+func (i *nvmmImage) ColorModel() color.Model {
+	return color.YCbCrModel
+}
+
+func (i *nvmmImage) Bounds() image.Rectangle {
+	return i.rect
+}
+
+func (i *nvmmImage) At(x, y int) color.Color {
+	return i.YCbCr().At(x, y)
+}
+
+// YCbCr returns a copy of the frame in CPU memory
+func (i *nvmmImage) YCbCr() *image.YCbCr {
+	i.once.Do(func() {
+		img := image.NewYCbCr(i.rect, image.YCbCrSubsampleRatio420)
+		C.argusBufferToI420(C.int(i.fd), C.int(i.rect.Dx()), C.int(i.rect.Dy()),
+			(*C.uint8_t)(&img.Y[0]), (*C.uint8_t)(&img.Cb[0]), (*C.uint8_t)(&img.Cr[0]))
+		i.img = img
+	})
+	return i.img
+}
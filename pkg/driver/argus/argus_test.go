@@ -0,0 +1,27 @@
+// +build jetson
+
+package argus
+
+import (
+	"image"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec/jetson"
+)
+
+func TestNVMMImage(t *testing.T) {
+	rect := image.Rect(0, 0, 1280, 720)
+	var img image.Image = &nvmmImage{fd: 42, rect: rect}
+
+	// Frames are passed to the encoder as NVMM buffers
+	buf, ok := img.(jetson.DMABufImage)
+	if !ok {
+		t.Fatal("expected the frame to be a jetson.DMABufImage")
+	}
+	if fd := buf.DMABuf(); fd != 42 {
+		t.Fatalf("expected the dmabuf fd 42, but got %d", fd)
+	}
+	if bounds := img.Bounds(); bounds != rect {
+		t.Fatalf("expected the bounds %v, but got %v", rect, bounds)
+	}
+}
@@ -0,0 +1,197 @@
+// +build jetson
+
+#include "bridge.hpp"
+
+#include <Argus/Argus.h>
+#include <EGLStream/EGLStream.h>
+#include <EGLStream/NV/ImageNativeBuffer.h>
+#include <nvbuf_utils.h>
+
+#include <vector>
+
+using namespace Argus;
+using namespace EGLStream;
+
+// NUM_BUFFERS is the number of NVMM buffers that can be referred by the frames in Go at the same time
+#define NUM_BUFFERS 4
+
+static UniqueObj<CameraProvider> provider;
+static ICameraProvider *iProvider = nullptr;
+static std::vector<CameraDevice *> devices;
+
+struct ArgusCamera {
+  UniqueObj<CaptureSession> session;
+  UniqueObj<OutputStream> stream;
+  UniqueObj<FrameConsumer> consumer;
+  UniqueObj<Request> request;
+  int fds[NUM_BUFFERS];
+  int next;
+};
+
+extern "C" {
+
+int argusInit(void) {
+  if (iProvider != nullptr) {
+    return ARGUS_OK;
+  }
+
+  provider.reset(CameraProvider::create());
+  iProvider = interface_cast<ICameraProvider>(provider);
+  if (iProvider == nullptr || iProvider->getCameraDevices(&devices) != STATUS_OK) {
+    provider.reset();
+    iProvider = nullptr;
+    return ARGUS_ERROR;
+  }
+  return ARGUS_OK;
+}
+
+int argusCameraCount(void) { return devices.size(); }
+
+int argusCameraModes(int index, int *modes, int max) {
+  if (index < 0 || index >= (int)devices.size()) {
+    return 0;
+  }
+
+  ICameraProperties *iProperties = interface_cast<ICameraProperties>(devices[index]);
+  std::vector<SensorMode *> sensorModes;
+  if (iProperties == nullptr || iProperties->getAllSensorModes(&sensorModes) != STATUS_OK) {
+    return 0;
+  }
+
+  int n = 0;
+  for (SensorMode *mode : sensorModes) {
+    ISensorMode *iMode = interface_cast<ISensorMode>(mode);
+    if (iMode == nullptr || n >= max) {
+      continue;
+    }
+    modes[n * 3] = iMode->getResolution().width();
+    modes[n * 3 + 1] = iMode->getResolution().height();
+    modes[n * 3 + 2] = 1000000000ULL / iMode->getFrameDurationRange().min();
+    n++;
+  }
+  return n;
+}
+
+ArgusCamera *argusCameraOpen(int index, int width, int height, int frameRate) {
+  if (index < 0 || index >= (int)devices.size()) {
+    return nullptr;
+  }
+
+  ArgusCamera *c = new ArgusCamera();
+  for (int i = 0; i < NUM_BUFFERS; i++) {
+    c->fds[i] = -1;
+  }
+
+  c->session.reset(iProvider->createCaptureSession(devices[index]));
+  ICaptureSession *iSession = interface_cast<ICaptureSession>(c->session);
+  if (iSession == nullptr) {
+    argusCameraClose(c);
+    return nullptr;
+  }
+
+  UniqueObj<OutputStreamSettings> settings(iSession->createOutputStreamSettings(STREAM_TYPE_EGL));
+  IEGLOutputStreamSettings *iSettings = interface_cast<IEGLOutputStreamSettings>(settings);
+  if (iSettings == nullptr) {
+    argusCameraClose(c);
+    return nullptr;
+  }
+  iSettings->setPixelFormat(PIXEL_FMT_YCbCr_420_888);
+  iSettings->setResolution(Size2D<uint32_t>(width, height));
+  iSettings->setMetadataEnable(false);
+
+  c->stream.reset(iSession->createOutputStream(settings.get()));
+  c->consumer.reset(FrameConsumer::create(c->stream.get()));
+  c->request.reset(iSession->createRequest(CAPTURE_INTENT_VIDEO_RECORD));
+  IRequest *iRequest = interface_cast<IRequest>(c->request);
+  if (interface_cast<IFrameConsumer>(c->consumer) == nullptr || iRequest == nullptr ||
+      iRequest->enableOutputStream(c->stream.get()) != STATUS_OK) {
+    argusCameraClose(c);
+    return nullptr;
+  }
+
+  if (frameRate > 0) {
+    ISourceSettings *iSource = interface_cast<ISourceSettings>(iRequest->getSourceSettings());
+    if (iSource != nullptr) {
+      iSource->setFrameDurationRange(Range<uint64_t>(1000000000ULL / frameRate));
+    }
+  }
+
+  // The frames are copied from the EGLStream to the buffers by the hardware, so they stay in NVMM
+  NvBufferCreateParams params = {0};
+  params.width = width;
+  params.height = height;
+  params.layout = NvBufferLayout_Pitch;
+  params.payloadType = NvBufferPayload_SurfArray;
+  params.colorFormat = NvBufferColorFormat_YUV420;
+  params.nvbuf_tag = NvBufferTag_CAMERA;
+  for (int i = 0; i < NUM_BUFFERS; i++) {
+    if (NvBufferCreateEx(&c->fds[i], &params) != 0) {
+      argusCameraClose(c);
+      return nullptr;
+    }
+  }
+
+  if (iSession->repeat(c->request.get()) != STATUS_OK) {
+    argusCameraClose(c);
+    return nullptr;
+  }
+  return c;
+}
+
+int argusCameraRead(ArgusCamera *c, uint64_t timeoutNs) {
+  IFrameConsumer *iConsumer = interface_cast<IFrameConsumer>(c->consumer);
+  Argus::Status status;
+  UniqueObj<Frame> frame(iConsumer->acquireFrame(timeoutNs, &status));
+  if (status == STATUS_TIMEOUT) {
+    return ARGUS_TIMEOUT;
+  }
+
+  IFrame *iFrame = interface_cast<IFrame>(frame);
+  if (iFrame == nullptr) {
+    return ARGUS_ERROR;
+  }
+  NV::IImageNativeBuffer *iNativeBuffer = interface_cast<NV::IImageNativeBuffer>(iFrame->getImage());
+  if (iNativeBuffer == nullptr) {
+    return ARGUS_ERROR;
+  }
+
+  int fd = c->fds[c->next];
+  if (iNativeBuffer->copyToNvBuffer(fd) != STATUS_OK) {
+    return ARGUS_ERROR;
+  }
+  c->next = (c->next + 1) % NUM_BUFFERS;
+  return fd;
+}
+
+void argusCameraClose(ArgusCamera *c) {
+  ICaptureSession *iSession = interface_cast<ICaptureSession>(c->session);
+  if (iSession != nullptr) {
+    iSession->stopRepeat();
+    iSession->waitForIdle();
+  }
+  IEGLOutputStream *iStream = interface_cast<IEGLOutputStream>(c->stream);
+  if (iStream != nullptr) {
+    iStream->disconnect();
+  }
+
+  c->consumer.reset();
+  c->request.reset();
+  c->stream.reset();
+  c->session.reset();
+
+  for (int i = 0; i < NUM_BUFFERS; i++) {
+    if (c->fds[i] >= 0) {
+      NvBufferDestroy(c->fds[i]);
+    }
+  }
+  delete c;
+}
+
+int argusBufferToI420(int fd, int width, int height, uint8_t *y, uint8_t *cb, uint8_t *cr) {
+  if (NvBuffer2Raw(fd, 0, width, height, y) != 0 || NvBuffer2Raw(fd, 1, width / 2, height / 2, cb) != 0 ||
+      NvBuffer2Raw(fd, 2, width / 2, height / 2, cr) != 0) {
+    return ARGUS_ERROR;
+  }
+  return ARGUS_OK;
+}
+}
@@ -0,0 +1,34 @@
+// +build jetson
+
+#pragma once
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define ARGUS_OK 0
+#define ARGUS_ERROR -1
+#define ARGUS_TIMEOUT -2
+
+typedef struct ArgusCamera ArgusCamera;
+
+int argusInit(void);
+int argusCameraCount(void);
+// argusCameraModes stores the sensor modes of the camera at index as triples of width, height and
+// the maximum frame rate, and returns the number of modes
+int argusCameraModes(int index, int *modes, int max);
+
+ArgusCamera *argusCameraOpen(int index, int width, int height, int frameRate);
+// argusCameraRead copies the next frame to one of the NVMM buffers of the camera, and returns the dmabuf fd of
+// the buffer. The buffer is reused after all the other buffers are used.
+int argusCameraRead(ArgusCamera *camera, uint64_t timeoutNs);
+void argusCameraClose(ArgusCamera *camera);
+
+// argusBufferToI420 copies the planes of the NVMM buffer to the CPU memory
+int argusBufferToI420(int fd, int width, int height, uint8_t *y, uint8_t *cb, uint8_t *cr);
+
+#ifdef __cplusplus
+}
+#endif
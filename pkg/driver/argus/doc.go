@@ -0,0 +1,11 @@
+// Package argus registers NVIDIA Jetson's CSI cameras as video drivers using the Argus camera API.
+//
+// Frames are kept in NVMM buffers, and they're only copied to CPU memory when the pixels are accessed.
+// pkg/codec/jetson encodes the buffers directly, so raw frames don't go through Go.
+//
+// Argus is linked with cgo from the Jetson Multimedia API, and the package is only built with the jetson build tag:
+//
+//	go build -tags jetson
+//
+// Reference: https://docs.nvidia.com/jetson/l4t-multimedia/group__LibargusAPI.html
+package argus
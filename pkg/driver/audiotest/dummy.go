@@ -51,6 +51,7 @@ func (d *dummy) AudioRecord(p prop.Media) (audio.Reader, error) {
 	var phase int
 
 	closed := d.closed
+	var pool wave.Pool
 
 	reader := audio.ReaderFunc(func() (wave.Audio, func(), error) {
 		select {
@@ -62,13 +63,15 @@ func (d *dummy) AudioRecord(p prop.Media) (audio.Reader, error) {
 		time.Sleep(nextReadTime.Sub(time.Now()))
 		nextReadTime = nextReadTime.Add(p.Latency)
 
-		a := wave.NewFloat32Interleaved(
-			wave.ChunkInfo{
-				Channels:     p.ChannelCount,
-				Len:          nSample,
-				SamplingRate: p.SampleRate,
-			},
-		)
+		chunk, release, err := pool.Get(wave.TypeFloat32Interleaved, wave.ChunkInfo{
+			Channels:     p.ChannelCount,
+			Len:          nSample,
+			SamplingRate: p.SampleRate,
+		})
+		if err != nil {
+			return nil, func() {}, err
+		}
+		a := chunk.(*wave.Float32Interleaved)
 
 		for i := 0; i < nSample; i++ {
 			phase++
@@ -79,7 +82,7 @@ func (d *dummy) AudioRecord(p prop.Media) (audio.Reader, error) {
 				a.SetFloat32(i, ch, wave.Float32Sample(sin[phase]))
 			}
 		}
-		return a, func() {}, nil
+		return a, release, nil
 	})
 	return reader, nil
 }
@@ -69,3 +69,39 @@ func (cam *camera) VideoRecord(property prop.Media) (video.Reader, error) {
 func (cam *camera) Properties() []prop.Media {
 	return cam.session.Properties()
 }
+
+// Probe returns the application's camera permission, and whether another application uses the
+// camera. It doesn't prompt the user.
+func (cam *camera) Probe() (driver.Probe, error) {
+	status, err := avfoundation.Authorization(avfoundation.Video)
+	if err != nil {
+		return driver.Probe{}, err
+	}
+	inUse, err := cam.device.InUse()
+	if err != nil {
+		return driver.Probe{}, err
+	}
+	return driver.Probe{Permission: permission(status), Busy: inUse}, nil
+}
+
+// RequestPermission prompts the user for camera permission if they haven't been asked yet.
+func (cam *camera) RequestPermission() (driver.Permission, error) {
+	status, err := avfoundation.RequestAuthorization(avfoundation.Video)
+	if err != nil {
+		return driver.PermissionUnknown, err
+	}
+	return permission(status), nil
+}
+
+func permission(status avfoundation.AuthorizationStatus) driver.Permission {
+	switch status {
+	case avfoundation.AuthorizationAuthorized:
+		return driver.PermissionGranted
+	case avfoundation.AuthorizationDenied:
+		return driver.PermissionDenied
+	case avfoundation.AuthorizationRestricted:
+		return driver.PermissionRestricted
+	default:
+		return driver.PermissionPrompt
+	}
+}
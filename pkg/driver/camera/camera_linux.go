@@ -1,18 +1,218 @@
 package camera
 
+// #include <errno.h>
+// #include <fcntl.h>
 // #include <linux/videodev2.h>
+// #include <poll.h>
+// #include <string.h>
+// #include <sys/ioctl.h>
+// #include <sys/mman.h>
+// #include <time.h>
+// #include <unistd.h>
+//
+// // bufferAge returns the nanoseconds since the kernel captured the frame in the buffer at index,
+// // or -1 if the buffer has no monotonic timestamp.
+// static long long bufferAge(int fd, unsigned int index) {
+//   struct v4l2_buffer buf;
+//   struct timespec now;
+//   memset(&buf, 0, sizeof(buf));
+//   buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   buf.memory = V4L2_MEMORY_MMAP;
+//   buf.index = index;
+//   if (ioctl(fd, VIDIOC_QUERYBUF, &buf) < 0) {
+//     return -1;
+//   }
+//   if ((buf.flags & V4L2_BUF_FLAG_TIMESTAMP_MASK) != V4L2_BUF_FLAG_TIMESTAMP_MONOTONIC) {
+//     return -1;
+//   }
+//   if (clock_gettime(CLOCK_MONOTONIC, &now) < 0) {
+//     return -1;
+//   }
+//   return (long long)(now.tv_sec - buf.timestamp.tv_sec) * 1000000000LL +
+//     now.tv_nsec - (long long)buf.timestamp.tv_usec * 1000LL;
+// }
+//
+// // bufferSequence returns the sequence number of the frame in the buffer at index, or -1 if it's unknown.
+// static long long bufferSequence(int fd, unsigned int index) {
+//   struct v4l2_buffer buf;
+//   memset(&buf, 0, sizeof(buf));
+//   buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   buf.memory = V4L2_MEMORY_MMAP;
+//   buf.index = index;
+//   if (ioctl(fd, VIDIOC_QUERYBUF, &buf) < 0) {
+//     return -1;
+//   }
+//   return buf.sequence;
+// }
+//
+// // // bufferIncomplete returns 1 if the device flagged the frame in the buffer at index as corrupted, e.g. because its
+// // // transfer was cut off, 0 if it didn't, or -1 if it's unknown.
+// static int bufferIncomplete(int fd, unsigned int index) {
+//   struct v4l2_buffer buf;
+//   memset(&buf, 0, sizeof(buf));
+//   buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   buf.memory = V4L2_MEMORY_MMAP;
+//   buf.index = index;
+//   if (ioctl(fd, VIDIOC_QUERYBUF, &buf) < 0) {
+//     return -1;
+//   }
+//   return (buf.flags & V4L2_BUF_FLAG_ERROR) ? 1 : 0;
+// }
+//
+// // dmabufStop stops streaming and frees dmabufStart's n buffers.
+// static void dmabufStop(int fd, int n, int *fds, void **ptrs, unsigned int *lengths) {
+//   int type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   struct v4l2_requestbuffers req;
+//   ioctl(fd, VIDIOC_STREAMOFF, &type);
+//   for (int i = 0; i < n; i++) {
+//     if (ptrs[i] != NULL) {
+//       munmap(ptrs[i], lengths[i]);
+//       ptrs[i] = NULL;
+//     }
+//     if (fds[i] >= 0) {
+//       close(fds[i]);
+//       fds[i] = -1;
+//     }
+//   }
+//   memset(&req, 0, sizeof(req));
+//   req.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   req.memory = V4L2_MEMORY_MMAP;
+//   ioctl(fd, VIDIOC_REQBUFS, &req);
+// }
+//
+// // dmabufStart requests count buffers, exports them as DMA-BUF into fds, maps them to ptrs and starts streaming.
+// // bytesPerLine is the frame pitch. It returns the buffer count, or -1 if the device can't export
+// // buffers.
+// static int dmabufStart(int fd, unsigned int count, unsigned int max, int *fds, void **ptrs, unsigned int *lengths,
+//     unsigned int *bytesPerLine) {
+//   struct v4l2_format fmt;
+//   struct v4l2_requestbuffers req;
+//   int type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   int n = 0;
+//   memset(&fmt, 0, sizeof(fmt));
+//   fmt.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   if (ioctl(fd, VIDIOC_G_FMT, &fmt) < 0) {
+//     return -1;
+//   }
+//   *bytesPerLine = fmt.fmt.pix.bytesperline;
+//   memset(&req, 0, sizeof(req));
+//   req.count = count;
+//   req.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   req.memory = V4L2_MEMORY_MMAP;
+//   if (ioctl(fd, VIDIOC_REQBUFS, &req) < 0 || req.count == 0) {
+//     return -1;
+//   }
+//   if (req.count > max) {
+//     req.count = max;
+//   }
+//   for (; n < (int)req.count; n++) {
+//     struct v4l2_buffer buf;
+//     struct v4l2_exportbuffer exp;
+//     fds[n] = -1;
+//     ptrs[n] = NULL;
+//     memset(&buf, 0, sizeof(buf));
+//     buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//     buf.memory = V4L2_MEMORY_MMAP;
+//     buf.index = n;
+//     if (ioctl(fd, VIDIOC_QUERYBUF, &buf) < 0) {
+//       goto fail;
+//     }
+//     lengths[n] = buf.length;
+//     ptrs[n] = mmap(NULL, buf.length, PROT_READ, MAP_SHARED, fd, buf.m.offset);
+//     if (ptrs[n] == MAP_FAILED) {
+//       ptrs[n] = NULL;
+//       goto fail;
+//     }
+//     memset(&exp, 0, sizeof(exp));
+//     exp.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//     exp.index = n;
+//     exp.flags = O_RDONLY | O_CLOEXEC;
+//     if (ioctl(fd, VIDIOC_EXPBUF, &exp) < 0) {
+//       goto fail;
+//     }
+//     fds[n] = exp.fd;
+//     if (ioctl(fd, VIDIOC_QBUF, &buf) < 0) {
+//       goto fail;
+//     }
+//   }
+//   if (ioctl(fd, VIDIOC_STREAMON, &type) < 0) {
+//     dmabufStop(fd, n, fds, ptrs, lengths);
+//     return -1;
+//   }
+//   return n;
+// fail:
+//   // The buffer at n is partially initialized.
+//   dmabufStop(fd, n + 1, fds, ptrs, lengths);
+//   return -1;
+// }
+//
+// // dmabufDequeue waits up to timeout milliseconds for a frame. It returns the buffer index, -1 on errors
+// // or -2 on timeout.
+// static int dmabufDequeue(int fd, int timeout, unsigned int *bytesUsed) {
+//   struct pollfd pfd = {fd, POLLIN, 0};
+//   struct v4l2_buffer buf;
+//   int ret = poll(&pfd, 1, timeout);
+//   if (ret == 0) {
+//     return -2;
+//   }
+//   if (ret < 0) {
+//     return -1;
+//   }
+//   memset(&buf, 0, sizeof(buf));
+//   buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   buf.memory = V4L2_MEMORY_MMAP;
+//   if (ioctl(fd, VIDIOC_DQBUF, &buf) < 0) {
+//     return -1;
+//   }
+//   *bytesUsed = buf.bytesused;
+//   return buf.index;
+// }
+//
+// // // buffersBusy returns 1 if another handle owns the device's buffers, e.g. another process streaming from
+// // // it, or 0 otherwise. Buffers requested here are freed at once.
+// static int buffersBusy(int fd) {
+//   struct v4l2_requestbuffers req;
+//   memset(&req, 0, sizeof(req));
+//   req.count = 1;
+//   req.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   req.memory = V4L2_MEMORY_MMAP;
+//   if (ioctl(fd, VIDIOC_REQBUFS, &req) < 0) {
+//     return errno == EBUSY ? 1 : 0;
+//   }
+//   req.count = 0;
+//   ioctl(fd, VIDIOC_REQBUFS, &req);
+//   return 0;
+// }
+//
+// static int dmabufQueue(int fd, unsigned int index) {
+//   struct v4l2_buffer buf;
+//   memset(&buf, 0, sizeof(buf));
+//   buf.type = V4L2_BUF_TYPE_VIDEO_CAPTURE;
+//   buf.memory = V4L2_MEMORY_MMAP;
+//   buf.index = index;
+//   return ioctl(fd, VIDIOC_QBUF, &buf);
+// }
 import "C"
 
 import (
 	"context"
 	"errors"
+	"fmt"
 	"image"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
 
 	"github.com/blackjack/webcam"
+	"github.com/pion/mediadevices/internal/logging"
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/frame"
 	"github.com/pion/mediadevices/pkg/io/video"
@@ -22,11 +222,21 @@ import (
 const (
 	maxEmptyFrameCount = 5
 	prioritizedDevice  = "video0"
+	// maxDMABufBuffers is the maximum number of buffers exported as DMA-BUF.
+	maxDMABufBuffers = 32
+	// photoWarmUpFrames is how many frames are discarded after switching to a photo resolution,
+	// since exposure and white balance of the first frames are often wrong.
+	photoWarmUpFrames = 3
 )
 
+var logger = logging.NewLogger("mediadevices/driver/camera")
+
 var (
-	errReadTimeout = errors.New("read timeout")
-	errEmptyFrame  = errors.New("empty frame")
+	errReadTimeout       = errors.New("read timeout")
+	errEmptyFrame        = errors.New("empty frame")
+	errDMABufUnsupported = errors.New("the buffers can't be exported as DMA-BUF")
+	errPhotoUnsupported  = errors.New("photos can't be taken while the buffers are exported as DMA-BUF")
+	errNotStreaming      = errors.New("the camera is not streaming")
 	// Reference: https://commons.wikimedia.org/wiki/File:Vector_Video_Standards2.svg
 	supportedResolutions = [][2]int{
 		{320, 240},
@@ -69,6 +279,27 @@ type camera struct {
 	started         bool
 	mutex           sync.Mutex
 	cancel          func()
+	// queryFile is a second handle to the device for querying buffer timestamps, since the webcam
+	// package discards them. It's nil if the device can't be opened twice.
+	queryFile *os.File
+	options   V4L2Options
+	// dmabuf streams buffers exported as DMA-BUF, or is nil if frames are copied.
+	dmabuf *dmabufStream
+	// recording is the video property, which is restored after taking a photo.
+	recording prop.Media
+}
+
+// dmabufStream is the device's capture buffers exported as DMA-BUF. Buffers are mapped to read pixels,
+// and streamed with queryFile since the webcam package doesn't expose its handle.
+type dmabufStream struct {
+	fd           C.int
+	n            C.int
+	fds          [maxDMABufBuffers]C.int
+	ptrs         [maxDMABufBuffers]unsafe.Pointer
+	lengths      [maxDMABufBuffers]C.uint
+	bytesPerLine C.uint
+	// held is the index of the buffer returned by the reader, or -1.
+	held C.int
 }
 
 func init() {
@@ -105,10 +336,25 @@ func discover(discovered map[string]struct{}, pattern string) {
 			Label:      label + LabelSeparator + reallink,
 			DeviceType: driver.Camera,
 			Priority:   priority,
+			Hardware:   hardware(reallink),
 		})
 	}
 }
 
+// hardware returns the attributes of the video node's device, e.g. video0's, to identify it across reboots.
+// A USB camera may have several video nodes, like the metadata node, which are told apart by index.
+func hardware(node string) driver.Hardware {
+	sysfs := filepath.Join("/sys/class/video4linux", node)
+	hw, err := driver.USBHardware(filepath.Join(sysfs, "device"))
+	if err != nil {
+		return driver.Hardware{}
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(sysfs, "index")); err == nil {
+		hw.Index, _ = strconv.Atoi(strings.TrimSpace(string(b)))
+	}
+	return hw
+}
+
 func newCamera(path string) *camera {
 	formats := map[webcam.PixelFormat]frame.Format{
 		webcam.PixelFormat(C.V4L2_PIX_FMT_YUV420): frame.FormatI420,
@@ -134,22 +380,61 @@ func newCamera(path string) *camera {
 }
 
 func (c *camera) Open() error {
-	cam, err := webcam.Open(c.path)
+	cam, err := c.openWebcam()
 	if err != nil {
 		return err
 	}
+	c.cam = cam
+
+	// VIDIOC_QUERYBUF is allowed from any device handle. Fall back to the read time if it fails.
+	if f, err := os.OpenFile(c.path, os.O_RDWR, 0); err == nil {
+		c.queryFile = f
+	}
+	return nil
+}
+
+func (c *camera) openWebcam() (*webcam.Webcam, error) {
+	cam, err := webcam.Open(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Late frames should be discarded by default. Buffering should be handled in higher level.
+	bufferCount := c.options.BufferCount
+	if bufferCount <= 0 {
+		bufferCount = defaultBufferCount
+	}
+	if err := cam.SetBufferCount(uint32(bufferCount)); err != nil {
+		cam.Close()
+		return nil, err
+	}
+	return cam, nil
+}
+
+// reopen closes and reopens the device, since it's the only way to free the webcam package's buffers,
+// which have to be freed to change format. c.cam is nil if it fails.
+func (c *camera) reopen() error {
+	c.cam.Close()
+	c.cam = nil
 
-	// Late frames should be discarded. Buffering should be handled in higher level.
-	cam.SetBufferCount(1)
+	cam, err := c.openWebcam()
+	if err != nil {
+		return err
+	}
 	c.cam = cam
 	return nil
 }
 
-func (c *camera) Close() error {
-	if c.cam == nil {
-		return nil
+func (c *camera) Configure(opts []driver.Option) error {
+	options, err := v4l2Options(opts)
+	if err != nil {
+		return err
 	}
+	c.options = options
+	return nil
+}
 
+func (c *camera) Close() error {
 	if c.cancel != nil {
 		// Let the reader knows that the caller has closed the camera
 		c.cancel()
@@ -160,13 +445,83 @@ func (c *camera) Close() error {
 		// Note: StopStreaming frees frame buffers even if they are still used in Go code.
 		//       There is currently no convenient way to do this safely.
 		//       So, consumer of this stream must close camera after unusing all images.
-		c.cam.StopStreaming()
+		if s := c.dmabuf; s != nil {
+			C.dmabufStop(s.fd, s.n, &s.fds[0], &s.ptrs[0], &s.lengths[0])
+			c.dmabuf = nil
+		} else if c.cam != nil {
+			c.cam.StopStreaming()
+		}
 		c.cancel = nil
 	}
-	c.cam.Close()
+	if c.cam != nil {
+		// The camera is lost if reopening it after a photo failed.
+		c.cam.Close()
+		c.cam = nil
+	}
+	if c.queryFile != nil {
+		c.queryFile.Close()
+		c.queryFile = nil
+	}
 	return nil
 }
 
+// Probe checks device node permission, e.g. whether the user is in the video group, and whether another
+// process is streaming from the device, which owns its buffers until it stops.
+func (c *camera) Probe() (driver.Probe, error) {
+	if c.cam != nil {
+		// The buffers belong to the device this driver opened
+		return driver.Probe{Permission: driver.PermissionGranted}, nil
+	}
+
+	f, err := os.OpenFile(c.path, os.O_RDWR|syscall.O_NONBLOCK, 0)
+	switch {
+	case os.IsPermission(err):
+		return driver.Probe{Permission: driver.PermissionDenied}, nil
+	case errors.Is(err, syscall.EBUSY):
+		return driver.Probe{Permission: driver.PermissionGranted, Busy: true}, nil
+	case err != nil:
+		return driver.Probe{}, err
+	}
+	defer f.Close()
+	return driver.Probe{
+		Permission: driver.PermissionGranted,
+		Busy:       C.buffersBusy(C.int(f.Fd())) == 1,
+	}, nil
+}
+
+// captureTime returns when the kernel captured the frame in the buffer at index, on the wall clock. Tracks move it
+// onto their MediaClock. It returns the zero time if there's no timestamp, so the read time is used instead.
+func (c *camera) captureTime(index uint32) time.Time {
+	if c.queryFile == nil {
+		return time.Time{}
+	}
+	age := int64(C.bufferAge(C.int(c.queryFile.Fd()), C.uint(index)))
+	if age < 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(-time.Duration(age))
+}
+
+// sequence returns the sequence number of the frame in the buffer at index, or false if it's unknown.
+func (c *camera) sequence(index uint32) (uint32, bool) {
+	if c.queryFile == nil {
+		return 0, false
+	}
+	seq := int64(C.bufferSequence(C.int(c.queryFile.Fd()), C.uint(index)))
+	if seq < 0 {
+		return 0, false
+	}
+	return uint32(seq), true
+}
+
+// incomplete reports whether the device flagged the frame in the buffer at index as incomplete.
+func (c *camera) incomplete(index uint32) bool {
+	if c.queryFile == nil {
+		return false
+	}
+	return C.bufferIncomplete(C.int(c.queryFile.Fd()), C.uint(index)) == 1
+}
+
 func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 	decoder, err := frame.NewDecoder(p.FrameFormat)
 	if err != nil {
@@ -179,15 +534,27 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 		return nil, err
 	}
 
+	if c.options.ExportDMABuf {
+		r, err := c.recordDMABuf(p, decoder)
+		if err == nil {
+			return r, nil
+		}
+		logger.Debugf("failed to export the buffers of %s, falling back to copying the frames: %s\n", c.path, err)
+	}
+
 	if err := c.cam.StartStreaming(); err != nil {
 		return nil, err
 	}
-
-	cam := c.cam
+	c.recording = p
 
 	ctx, cancel := context.WithCancel(context.Background())
 	c.cancel = cancel
-	var buf []byte
+	// bufs are Go frame buffers, which are used in turn with DoubleBuffer.
+	var bufs [2][]byte
+	var next int
+	// lastCapture is the last frame's capture time in nanoseconds, which the track reads after Read.
+	var lastCapture int64
+	var drops dropCounter
 	r := video.ReaderFunc(func() (img image.Image, release func(), err error) {
 		// Lock to avoid accessing the buffer after StopStreaming()
 		c.mutex.Lock()
@@ -199,6 +566,11 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 				// Return EOF if the camera is already closed.
 				return nil, func() {}, io.EOF
 			}
+			// The device is reopened to take a photo.
+			cam := c.cam
+			if cam == nil {
+				return nil, func() {}, errNotStreaming
+			}
 
 			err := cam.WaitForFrame(5) // 5 seconds
 			switch err.(type) {
@@ -210,7 +582,7 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 				return nil, func() {}, err
 			}
 
-			b, err := cam.ReadFrame()
+			b, index, err := cam.GetFrame()
 			if err != nil {
 				// Camera has been stopped.
 				return nil, func() {}, err
@@ -219,24 +591,313 @@ func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
 			// Frame is empty.
 			// Retry reading and return errEmptyFrame if it exceeds maxEmptyFrameCount.
 			if len(b) == 0 {
+				cam.ReleaseFrame(index)
 				continue
 			}
 
-			if len(b) > len(buf) {
+			if c.options.DoubleBuffer && c.incomplete(index) {
+				if seq, ok := c.sequence(index); ok {
+					drops.next(seq)
+				}
+				drops.drop()
+				cam.ReleaseFrame(index)
+				// Incomplete frames aren't counted as empty ones, since the next frame is usually fine.
+				i--
+				continue
+			}
+
+			if len(b) > len(bufs[next]) {
 				// Grow the intermediate buffer
-				buf = make([]byte, len(b))
+				bufs[next] = make([]byte, len(b))
+			}
+			buf := bufs[next]
+			if c.options.DoubleBuffer {
+				next = 1 - next
 			}
 
 			// move the memory from mmap to Go. This will guarantee that any data that's going out
 			// from this reader will be Go safe. Otherwise, it's possible that outside of this reader
 			// that this memory is still being used even after we close it.
 			n := copy(buf, b)
+			var captured int64
+			if t := c.captureTime(index); !t.IsZero() {
+				captured = t.UnixNano()
+			}
+			atomic.StoreInt64(&lastCapture, captured)
+			if seq, ok := c.sequence(index); ok {
+				drops.next(seq)
+			}
+			cam.ReleaseFrame(index)
 			return decoder.Decode(buf[:n], p.Width, p.Height)
 		}
 		return nil, func() {}, errEmptyFrame
 	})
 
-	return r, nil
+	return video.NewMetadataReader(r, func() video.Metadata {
+		var m video.Metadata
+		if captured := atomic.LoadInt64(&lastCapture); captured != 0 {
+			m.CaptureTime = time.Unix(0, captured)
+		}
+		m.Dropped = drops.dropped()
+		return m
+	}), nil
+}
+
+// recordDMABuf starts streaming buffers exported as DMA-BUF. The reader returns DMABufImage, and
+// a frame's buffer is queued again when the next frame is read.
+func (c *camera) recordDMABuf(p prop.Media, decoder frame.Decoder) (video.Reader, error) {
+	if c.queryFile == nil {
+		return nil, errDMABufUnsupported
+	}
+	s := &dmabufStream{fd: C.int(c.queryFile.Fd()), held: -1}
+	n := C.dmabufStart(s.fd, C.uint(c.options.BufferCount), maxDMABufBuffers,
+		&s.fds[0], &s.ptrs[0], &s.lengths[0], &s.bytesPerLine)
+	if n < 0 {
+		return nil, errDMABufUnsupported
+	}
+	s.n = n
+
+	planes, ok := dmabufPlanes(p.FrameFormat, int(s.bytesPerLine), p.Height)
+	if !ok {
+		C.dmabufStop(s.fd, s.n, &s.fds[0], &s.ptrs[0], &s.lengths[0])
+		return nil, errDMABufUnsupported
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.dmabuf = s
+	// lastCapture is the last frame's capture time in nanoseconds, which the track reads after Read.
+	var lastCapture int64
+	var drops dropCounter
+	r := video.ReaderFunc(func() (img image.Image, release func(), err error) {
+		// Lock to avoid accessing buffers after Close() frees them
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		for i := 0; i < maxEmptyFrameCount; i++ {
+			if ctx.Err() != nil {
+				// Return EOF if the camera is already closed.
+				return nil, func() {}, io.EOF
+			}
+
+			// The last read's frame isn't used anymore.
+			if s.held >= 0 {
+				C.dmabufQueue(s.fd, C.uint(s.held))
+				s.held = -1
+			}
+
+			var used C.uint
+			index := C.dmabufDequeue(s.fd, 5000, &used) // 5 seconds
+			switch {
+			case index == -2:
+				return nil, func() {}, errReadTimeout
+			case index < 0:
+				// Camera has been stopped.
+				return nil, func() {}, fmt.Errorf("failed to dequeue a buffer of %s", c.path)
+			}
+
+			// Frame is empty.
+			// Retry reading and return errEmptyFrame if it exceeds maxEmptyFrameCount.
+			if used == 0 {
+				C.dmabufQueue(s.fd, C.uint(index))
+				continue
+			}
+
+			s.held = index
+			var captured int64
+			if t := c.captureTime(uint32(index)); !t.IsZero() {
+				captured = t.UnixNano()
+			}
+			atomic.StoreInt64(&lastCapture, captured)
+			if seq, ok := c.sequence(uint32(index)); ok {
+				drops.next(seq)
+			}
+			return &dmabufImage{
+				buf: video.DMABuf{
+					FD:     int(s.fds[index]),
+					Format: p.FrameFormat,
+					Width:  p.Width,
+					Height: p.Height,
+					Planes: planes,
+					Size:   int(s.lengths[index]),
+				},
+				data:    (*[1 << 30]byte)(s.ptrs[index])[:used:used],
+				decoder: decoder,
+			}, func() {}, nil
+		}
+		return nil, func() {}, errEmptyFrame
+	})
+
+	return video.NewMetadataReader(r, func() video.Metadata {
+		var m video.Metadata
+		if captured := atomic.LoadInt64(&lastCapture); captured != 0 {
+			m.CaptureTime = time.Unix(0, captured)
+		}
+		m.Dropped = drops.dropped()
+		return m
+	}), nil
+}
+
+// v4l2Control is a value of a V4L2 control.
+type v4l2Control struct {
+	id    webcam.ControlID
+	value int32
+}
+
+// v4l2Controls returns V4L2 controls to apply c in the order to set them, since automatic modes have to be
+// turned off before setting manual values. ISO isn't supported since V4L2 exposes it as a value menu
+// that the webcam package can't query.
+func v4l2Controls(c driver.Controls) ([]v4l2Control, error) {
+	var controls []v4l2Control
+	if c.ISO != nil {
+		return nil, driver.ErrControlUnsupported
+	}
+	if c.Torch != nil {
+		mode := v4l2Control{webcam.ControlID(C.V4L2_CID_FLASH_LED_MODE), C.V4L2_FLASH_LED_MODE_NONE}
+		if *c.Torch {
+			mode.value = C.V4L2_FLASH_LED_MODE_TORCH
+		}
+		controls = append(controls, mode)
+	}
+	if c.ExposureTime != nil {
+		if *c.ExposureTime == 0 {
+			// UVC cameras' auto mode
+			controls = append(controls, v4l2Control{webcam.ControlID(C.V4L2_CID_EXPOSURE_AUTO), C.V4L2_EXPOSURE_APERTURE_PRIORITY})
+		} else {
+			controls = append(controls,
+				v4l2Control{webcam.ControlID(C.V4L2_CID_EXPOSURE_AUTO), C.V4L2_EXPOSURE_MANUAL},
+				// In 100 microseconds
+				v4l2Control{webcam.ControlID(C.V4L2_CID_EXPOSURE_ABSOLUTE), int32(*c.ExposureTime / (100 * time.Microsecond))},
+			)
+		}
+	}
+	if c.ColorTemperature != nil {
+		if *c.ColorTemperature == 0 {
+			controls = append(controls, v4l2Control{webcam.ControlID(C.V4L2_CID_AUTO_WHITE_BALANCE), 1})
+		} else {
+			controls = append(controls,
+				v4l2Control{webcam.ControlID(C.V4L2_CID_AUTO_WHITE_BALANCE), 0},
+				v4l2Control{webcam.ControlID(C.V4L2_CID_WHITE_BALANCE_TEMPERATURE), int32(*c.ColorTemperature)},
+			)
+		}
+	}
+	return controls, nil
+}
+
+// SetControls sets c's V4L2 controls. Values are clamped to device ranges.
+func (c *camera) SetControls(controls driver.Controls) error {
+	values, err := v4l2Controls(controls)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.cam == nil {
+		return errNotStreaming
+	}
+	supported := c.cam.GetControls()
+	for i, v := range values {
+		ctrl, ok := supported[v.id]
+		if !ok {
+			return driver.ErrControlUnsupported
+		}
+		if v.value < ctrl.Min {
+			values[i].value = ctrl.Min
+		}
+		if v.value > ctrl.Max {
+			values[i].value = ctrl.Max
+		}
+	}
+	for _, v := range values {
+		if err := c.cam.SetControl(v.id, v.value); err != nil {
+			return fmt.Errorf("failed to set the control %s of %s: %s", supported[v.id].Name, c.path, err)
+		}
+	}
+	return nil
+}
+
+func (c *camera) PhotoProperties() []prop.Media {
+	return photoProperties(c.Properties())
+}
+
+// TakePhoto briefly switches the device to the photo resolution, since V4L2 can't stream
+// at two resolutions at once. Video is paused while the photo is taken, then resumed in the same format.
+func (c *camera) TakePhoto(p prop.Media) (image.Image, error) {
+	decoder, err := frame.NewDecoder(p.FrameFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.cam == nil {
+		return nil, errNotStreaming
+	}
+	if c.dmabuf != nil {
+		return nil, errPhotoUnsupported
+	}
+	recording := c.cancel != nil
+	if recording {
+		if err := c.reopen(); err != nil {
+			return nil, err
+		}
+	}
+
+	img, err := c.capturePhoto(p, decoder)
+	// Photo buffers are freed even if video isn't recorded, to change format later.
+	if err := c.reopen(); err != nil {
+		return nil, err
+	}
+	if recording {
+		r := c.recording
+		if _, _, _, err := c.cam.SetImageFormat(c.reversedFormats[r.FrameFormat], uint32(r.Width), uint32(r.Height)); err != nil {
+			return nil, err
+		}
+		if err := c.cam.StartStreaming(); err != nil {
+			return nil, err
+		}
+	}
+	return img, err
+}
+
+func (c *camera) capturePhoto(p prop.Media, decoder frame.Decoder) (image.Image, error) {
+	_, width, height, err := c.cam.SetImageFormat(c.reversedFormats[p.FrameFormat], uint32(p.Width), uint32(p.Height))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.cam.StartStreaming(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < photoWarmUpFrames+maxEmptyFrameCount; i++ {
+		err := c.cam.WaitForFrame(5) // 5 seconds
+		switch err.(type) {
+		case nil:
+		case *webcam.Timeout:
+			return nil, errReadTimeout
+		default:
+			return nil, err
+		}
+
+		b, index, err := c.cam.GetFrame()
+		if err != nil {
+			return nil, err
+		}
+		if i < photoWarmUpFrames || len(b) == 0 {
+			c.cam.ReleaseFrame(index)
+			continue
+		}
+
+		buf := make([]byte, len(b))
+		copy(buf, b)
+		c.cam.ReleaseFrame(index)
+		img, _, err := decoder.Decode(buf, int(width), int(height))
+		return img, err
+	}
+	return nil, errEmptyFrame
 }
 
 func (c *camera) Properties() []prop.Media {
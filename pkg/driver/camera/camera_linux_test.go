@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pion/mediadevices/pkg/driver"
 )
@@ -70,3 +71,36 @@ func TestDiscover(t *testing.T) {
 		t.Errorf("Expected label: %s, got: %s", expectedNoLink, label)
 	}
 }
+
+func TestV4L2Controls(t *testing.T) {
+	torch := true
+	exposure := 33 * time.Millisecond
+	auto := 0
+	temperature := 4500
+
+	controls, err := v4l2Controls(driver.Controls{ExposureTime: &exposure, ColorTemperature: &temperature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []int32
+	for _, c := range controls {
+		values = append(values, c.value)
+	}
+	// Manual modes are set before values: manual exposure, 330 * 100us, auto white balance off, 4500K
+	if len(values) != 4 || values[1] != 330 || values[2] != 0 || values[3] != 4500 {
+		t.Fatalf("expected the manual modes and the values, but got %v", values)
+	}
+
+	controls, err = v4l2Controls(driver.Controls{Torch: &torch, ColorTemperature: &auto})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(controls) != 2 || controls[1].value != 1 {
+		t.Fatalf("expected the torch and the auto white balance, but got %v", controls)
+	}
+
+	iso := 400
+	if _, err := v4l2Controls(driver.Controls{ISO: &iso}); err != driver.ErrControlUnsupported {
+		t.Fatalf("expected %v, but got %v", driver.ErrControlUnsupported, err)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"unsafe"
 
+	"github.com/pion/mediadevices/internal/privacy"
 	"github.com/pion/mediadevices/pkg/driver"
 	"github.com/pion/mediadevices/pkg/frame"
 	"github.com/pion/mediadevices/pkg/io/video"
@@ -154,3 +155,9 @@ func (c *camera) Properties() []prop.Media {
 const (
 	fourccYUY2 = 0x32595559
 )
+
+// Probe returns the permission from Windows privacy settings. Whether another application uses the camera
+// isn't known until it's opened.
+func (c *camera) Probe() (driver.Probe, error) {
+	return driver.Probe{Permission: privacy.Permission(privacy.Webcam)}, nil
+}
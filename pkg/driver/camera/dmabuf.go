@@ -0,0 +1,80 @@
+package camera
+
+import (
+	"image"
+	"image/color"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// dmabufPlanes returns the plane layout of a frame in a V4L2 buffer, or false if the format isn't
+// a raw frame that encoders can import, e.g. MJPEG.
+func dmabufPlanes(format frame.Format, bytesPerLine, height int) ([]video.DMABufPlane, bool) {
+	switch format {
+	case frame.FormatYUYV, frame.FormatUYVY:
+		return []video.DMABufPlane{{Offset: 0, Pitch: bytesPerLine}}, true
+	case frame.FormatNV12, frame.FormatNV21:
+		return []video.DMABufPlane{
+			{Offset: 0, Pitch: bytesPerLine},
+			{Offset: bytesPerLine * height, Pitch: bytesPerLine},
+		}, true
+	case frame.FormatI420:
+		cHeight := (height + 1) / 2
+		return []video.DMABufPlane{
+			{Offset: 0, Pitch: bytesPerLine},
+			{Offset: bytesPerLine * height, Pitch: bytesPerLine / 2},
+			{Offset: bytesPerLine*height + bytesPerLine/2*cHeight, Pitch: bytesPerLine / 2},
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+// dmabufImage is a frame in a V4L2 capture buffer exported as a DMA-BUF. The buffer is copied into
+// Go memory only when pixels are accessed.
+type dmabufImage struct {
+	buf video.DMABuf
+	// data is the mapped buffer, which is valid until the buffer is queued again.
+	data    []byte
+	decoder frame.Decoder
+
+	once sync.Once
+	img  image.Image
+}
+
+func (i *dmabufImage) ExportDMABuf() video.DMABuf {
+	return i.buf
+}
+
+func (i *dmabufImage) ColorModel() color.Model {
+	return i.decoded().ColorModel()
+}
+
+func (i *dmabufImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, i.buf.Width, i.buf.Height)
+}
+
+func (i *dmabufImage) At(x, y int) color.Color {
+	return i.decoded().At(x, y)
+}
+
+// YCbCr returns a copy of the frame in Go memory, or nil if it's not decoded to YCbCr.
+func (i *dmabufImage) YCbCr() *image.YCbCr {
+	img, _ := i.decoded().(*image.YCbCr)
+	return img
+}
+
+func (i *dmabufImage) decoded() image.Image {
+	i.once.Do(func() {
+		data := make([]byte, len(i.data))
+		copy(data, i.data)
+		img, _, err := i.decoder.Decode(data, i.buf.Width, i.buf.Height)
+		if err != nil {
+			img = image.NewYCbCr(i.Bounds(), image.YCbCrSubsampleRatio420)
+		}
+		i.img = img
+	})
+	return i.img
+}
@@ -0,0 +1,78 @@
+package camera
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+func TestDMABufPlanes(t *testing.T) {
+	testCases := map[frame.Format][]video.DMABufPlane{
+		frame.FormatYUYV: {{Offset: 0, Pitch: 1280}},
+		frame.FormatNV12: {{Offset: 0, Pitch: 640}, {Offset: 640 * 480, Pitch: 640}},
+		frame.FormatI420: {{Offset: 0, Pitch: 640}, {Offset: 640 * 480, Pitch: 320}, {Offset: 640*480 + 320*240, Pitch: 320}},
+	}
+	for format, expected := range testCases {
+		pitch := 640
+		if format == frame.FormatYUYV {
+			pitch = 1280
+		}
+		planes, ok := dmabufPlanes(format, pitch, 480)
+		if !ok {
+			t.Fatalf("expected %s to be exported", format)
+		}
+		if !reflect.DeepEqual(expected, planes) {
+			t.Errorf("%s: expected %v, but got %v", format, expected, planes)
+		}
+	}
+
+	if _, ok := dmabufPlanes(frame.FormatMJPEG, 0, 480); ok {
+		t.Fatal("expected MJPEG not to be exported")
+	}
+}
+
+func TestDMABufImage(t *testing.T) {
+	decoder, err := frame.NewDecoder(frame.FormatYUYV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2x1 YUYV
+	data := []byte{10, 20, 30, 40}
+	img := &dmabufImage{
+		buf:     video.DMABuf{FD: 3, Format: frame.FormatYUYV, Width: 2, Height: 1},
+		data:    data,
+		decoder: decoder,
+	}
+
+	var _ video.DMABufImage = img
+	if img.ExportDMABuf().FD != 3 {
+		t.Fatalf("expected the fd 3, but got %d", img.ExportDMABuf().FD)
+	}
+	if img.Bounds() != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("expected 2x1, but got %v", img.Bounds())
+	}
+
+	yuv := img.YCbCr()
+	if yuv == nil {
+		t.Fatal("expected the frame to be decoded")
+	}
+	// Pixels are copied, so the buffer can be reused.
+	data[0] = 0
+	if yuv.Y[0] != 10 || yuv.Y[1] != 30 || yuv.Cb[0] != 20 || yuv.Cr[0] != 40 {
+		t.Fatalf("expected Y [10 30] Cb 20 Cr 40, but got %v %v %v", yuv.Y, yuv.Cb, yuv.Cr)
+	}
+
+	// video.ToI420 uses the decoded frame.
+	out, _, err := video.ToI420(video.ReaderFunc(func() (image.Image, func(), error) {
+		return img, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i420 := out.(*image.YCbCr); i420.SubsampleRatio != image.YCbCrSubsampleRatio420 || i420.Y[0] != 10 {
+		t.Fatalf("expected I420 of the frame, but got %v", i420)
+	}
+}
@@ -0,0 +1,29 @@
+package camera
+
+import "sync/atomic"
+
+// dropCounter counts frames the device dropped from gaps in buffer sequence numbers.
+type dropCounter struct {
+	total   uint64
+	last    uint32
+	started bool
+}
+
+// next records a read frame's sequence number. Sequence numbers restart from 0 when
+// streaming restarts, e.g. to take a photo, which isn't counted.
+func (d *dropCounter) next(seq uint32) {
+	if d.started && seq > d.last+1 {
+		atomic.AddUint64(&d.total, uint64(seq-d.last-1))
+	}
+	d.last, d.started = seq, true
+}
+
+// drop records a frame that was read but that the reader dropped, e.g. because it's incomplete.
+func (d *dropCounter) drop() {
+	atomic.AddUint64(&d.total, 1)
+}
+
+// dropped returns how many frames were dropped. It's safe to call from other goroutines.
+func (d *dropCounter) dropped() uint64 {
+	return atomic.LoadUint64(&d.total)
+}
@@ -0,0 +1,16 @@
+package camera
+
+import (
+	"testing"
+)
+
+func TestDropCounter(t *testing.T) {
+	var d dropCounter
+	for _, seq := range []uint32{10, 11, 14, 15, 0, 1, 3} {
+		d.next(seq)
+	}
+	// 12, 13 and 2 are dropped, and the restart from 0 isn't counted.
+	if n := d.dropped(); n != 3 {
+		t.Fatalf("expected 3 dropped frames, but got %d", n)
+	}
+}
@@ -0,0 +1,79 @@
+package camera
+
+import (
+	"errors"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+var errUnsupportedIOMethod = errors.New("unsupported io method: only IOMethodMMAP is supported")
+
+// IOMethod is how frames are exchanged with a V4L2 device.
+type IOMethod int
+
+// IOMethod values.
+const (
+	// IOMethodMMAP maps driver buffers into process memory.
+	IOMethodMMAP IOMethod = iota
+	// IOMethodUserPtr lets the driver write into process buffers. It isn't supported yet.
+	IOMethodUserPtr
+	// IOMethodDMABuf exports buffers as DMA-BUF file descriptors, to pass them to other devices without copies.
+	// It isn't supported yet.
+	IOMethodDMABuf
+)
+
+// V4L2Options tunes the V4L2 camera driver on Linux. Pass it in GetUserMedia constraints' DriverOptions.
+// It's ignored on other platforms.
+type V4L2Options struct {
+	// BufferCount is how many buffers are requested from the device. More buffers avoid dropped frames
+	// when the reader stalls, e.g. at high resolutions, but add delay. If it's 0, 1 is used to always
+	// read the latest frame.
+	BufferCount int
+	// IOMethod is how frames are read. Only IOMethodMMAP is supported.
+	IOMethod IOMethod
+	// ExportDMABuf exports buffers as DMA-BUF, so hardware encoders, e.g. pkg/codec/vaapi, can import
+	// frames without copying them into Go memory. Frames are still copied when other readers access
+	// pixels. It falls back to copying frames if the device or format doesn't support it. The reader holds
+	// a buffer until the next frame, so at least 2 buffers are used, and 4 by default.
+	ExportDMABuf bool
+	// DoubleBuffer copies frames into 2 buffers in turn, so a frame the reader returned isn't overwritten
+	// by the next one while it's still encoded, e.g. by a broadcaster's other readers. At least 2 buffers are
+	// requested from the device, and frames the device flags as incomplete, e.g. when a slow USB bus
+	// can't keep up with an uncompressed format, are dropped and counted in video.Metadata.Dropped instead of
+	// being returned half-updated. Use video.DetectTearing for devices that don't flag them.
+	DoubleBuffer bool
+}
+
+const (
+	defaultBufferCount       = 1
+	defaultDMABufBufferCount = 4
+	minDMABufBufferCount     = 2
+	minDoubleBufferCount     = 2
+)
+
+// v4l2Options returns V4L2Options in opts. The last one takes precedence.
+func v4l2Options(opts []driver.Option) (V4L2Options, error) {
+	var options V4L2Options
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case V4L2Options:
+			options = o
+		case *V4L2Options:
+			options = *o
+		}
+	}
+	if options.IOMethod != IOMethodMMAP {
+		return V4L2Options{}, errUnsupportedIOMethod
+	}
+	switch {
+	case options.ExportDMABuf && options.BufferCount <= 0:
+		options.BufferCount = defaultDMABufBufferCount
+	case options.ExportDMABuf && options.BufferCount < minDMABufBufferCount:
+		options.BufferCount = minDMABufBufferCount
+	case options.DoubleBuffer && options.BufferCount < minDoubleBufferCount:
+		options.BufferCount = minDoubleBufferCount
+	case options.BufferCount <= 0:
+		options.BufferCount = defaultBufferCount
+	}
+	return options, nil
+}
@@ -0,0 +1,48 @@
+package camera
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+func TestV4L2Options(t *testing.T) {
+	options, err := v4l2Options(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.BufferCount != defaultBufferCount {
+		t.Fatalf("expected %d buffers by default, but got %d", defaultBufferCount, options.BufferCount)
+	}
+
+	options, err = v4l2Options([]driver.Option{"unknown", &V4L2Options{BufferCount: 2}, V4L2Options{BufferCount: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.BufferCount != 4 {
+		t.Fatalf("expected the last option to be used, but got %d buffers", options.BufferCount)
+	}
+
+	testCases := map[string]struct {
+		options V4L2Options
+		count   int
+	}{
+		"DMABufDefault": {V4L2Options{ExportDMABuf: true}, defaultDMABufBufferCount},
+		"DMABufMin":     {V4L2Options{ExportDMABuf: true, BufferCount: 1}, minDMABufBufferCount},
+		"DMABuf":        {V4L2Options{ExportDMABuf: true, BufferCount: 8}, 8},
+		"DoubleBuffer":  {V4L2Options{DoubleBuffer: true}, minDoubleBufferCount},
+	}
+	for name, c := range testCases {
+		options, err := v4l2Options([]driver.Option{c.options})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if options.BufferCount != c.count {
+			t.Errorf("%s: expected %d buffers, but got %d", name, c.count, options.BufferCount)
+		}
+	}
+
+	if _, err := v4l2Options([]driver.Option{V4L2Options{IOMethod: IOMethodDMABuf}}); err != errUnsupportedIOMethod {
+		t.Fatalf("expected %v, but got %v", errUnsupportedIOMethod, err)
+	}
+}
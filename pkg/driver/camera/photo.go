@@ -0,0 +1,25 @@
+package camera
+
+import (
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// photoProperties returns the largest size of each format in props, since photos are taken at
+// the device's full resolution.
+func photoProperties(props []prop.Media) []prop.Media {
+	var photos []prop.Media
+	index := make(map[frame.Format]int)
+	for _, p := range props {
+		i, ok := index[p.FrameFormat]
+		if !ok {
+			index[p.FrameFormat] = len(photos)
+			photos = append(photos, p)
+			continue
+		}
+		if p.Width*p.Height > photos[i].Width*photos[i].Height {
+			photos[i] = p
+		}
+	}
+	return photos
+}
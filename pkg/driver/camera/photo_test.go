@@ -0,0 +1,29 @@
+package camera
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func TestPhotoProperties(t *testing.T) {
+	media := func(width, height int, format frame.Format) prop.Media {
+		return prop.Media{Video: prop.Video{Width: width, Height: height, FrameFormat: format}}
+	}
+	props := []prop.Media{
+		media(640, 480, frame.FormatYUYV),
+		media(1920, 1080, frame.FormatMJPEG),
+		media(1280, 720, frame.FormatYUYV),
+		media(320, 240, frame.FormatMJPEG),
+		media(3840, 2160, frame.FormatMJPEG),
+	}
+	expected := []prop.Media{
+		media(1280, 720, frame.FormatYUYV),
+		media(3840, 2160, frame.FormatMJPEG),
+	}
+	if actual := photoProperties(props); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, but got %v", expected, actual)
+	}
+}
@@ -11,4 +11,8 @@ const (
 	Microphone = "microphone"
 	// Screen represents screen devices
 	Screen = "screen"
+	// Speaker represents audio output devices
+	Speaker = "speaker"
+	// VirtualCamera represents video output devices that other applications see as cameras
+	VirtualCamera = "virtualcamera"
 )
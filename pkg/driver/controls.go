@@ -0,0 +1,35 @@
+package driver
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrControlUnsupported is returned when the device or driver doesn't support a control.
+var ErrControlUnsupported = errors.New("the control is not supported by the device")
+
+// Controls are low level camera properties, which mirror browsers' image capture constrainable
+// properties. Nil controls are kept as they are.
+// Reference: https://w3c.github.io/mediacapture-image/#constrainable-properties
+type Controls struct {
+	// Torch turns the camera's fill light on or off, e.g. a phone's flash LED.
+	Torch *bool
+	// ISO is sensor sensitivity, or 0 to make sensitivity automatic.
+	ISO *int
+	// ExposureTime is manual exposure time, or 0 to make exposure automatic.
+	ExposureTime *time.Duration
+	// ColorTemperature is manual white balance in Kelvin, or 0 to make white balance automatic.
+	ColorTemperature *int
+}
+
+// Empty reports whether no control is set.
+func (c Controls) Empty() bool {
+	return c.Torch == nil && c.ISO == nil && c.ExposureTime == nil && c.ColorTemperature == nil
+}
+
+// Controller is implemented by adapters that support Controls. SetControls is called after Open, and
+// can be called again while recording. If any control isn't supported, it returns
+// ErrControlUnsupported without applying the others.
+type Controller interface {
+	SetControls(c Controls) error
+}
@@ -1,6 +1,8 @@
 package driver
 
 import (
+	"image"
+
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/io/video"
 	"github.com/pion/mediadevices/pkg/prop"
@@ -10,10 +12,36 @@ type VideoRecorder interface {
 	VideoRecord(p prop.Media) (r video.Reader, err error)
 }
 
+// Thumbnailer is implemented by video recorders that can capture a single frame without recording,
+// e.g. screens, so share pickers can show previews. Thumbnail can be called in any driver state,
+// and returns the frame scaled to width, keeping the aspect ratio.
+type Thumbnailer interface {
+	Thumbnail(width int) (image.Image, error)
+}
+
+// PhotoTaker is implemented by video recorders that can take a still photo at a higher resolution than
+// the video while recording. TakePhoto takes a photo in p, which has to be one of PhotoProperties.
+type PhotoTaker interface {
+	PhotoProperties() []prop.Media
+	TakePhoto(p prop.Media) (image.Image, error)
+}
+
 type AudioRecorder interface {
 	AudioRecord(p prop.Media) (r audio.Reader, err error)
 }
 
+// AudioPlayer renders audio to an output device. AudioPlay starts reading r in the background, and r's samples
+// have to be in p's format. Playback ends when r returns an error or the driver is closed.
+type AudioPlayer interface {
+	AudioPlay(p prop.Media, r audio.Reader) error
+}
+
+// VideoPlayer renders video to an output device, e.g. a virtual camera. VideoPlay starts reading r in the background,
+// and r's images have to be p's size. Playback ends when r returns an error or the driver is closed.
+type VideoPlayer interface {
+	VideoPlay(p prop.Media, r video.Reader) error
+}
+
 // Priority represents device selection priority level
 type Priority float32
 
@@ -30,6 +58,8 @@ type Info struct {
 	Label      string
 	DeviceType DeviceType
 	Priority   Priority
+	// Hardware is used to generate the device's ID, which is stable across reboots.
+	Hardware Hardware
 }
 
 type Adapter interface {
@@ -43,4 +73,14 @@ type Driver interface {
 	ID() string
 	Info() Info
 	Status() State
+	// Configure passes driver specific options to the adapter if it's Configurable, otherwise they're ignored.
+	Configure(opts []Option) error
+	// SetControls applies controls to the adapter if it's a Controller. Otherwise, it returns
+	// ErrControlUnsupported unless controls are empty.
+	SetControls(c Controls) error
+	// Probe returns the device's status if the adapter is a Prober, otherwise permission is unknown.
+	Probe() (Probe, error)
+	// RequestPermission asks the OS for permission if the adapter is a PermissionRequester, otherwise it
+	// returns Probe's permission.
+	RequestPermission() (Permission, error)
 }
@@ -0,0 +1,64 @@
+// Package drivertest provides a camera and a microphone whose frames are delivered by a fake clock, so
+// application pipelines, e.g. transforms and encoders, can be tested deterministically
+// without sleeping in tests.
+package drivertest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock that only moves forward when Advance is called. This package's drivers deliver
+// a frame when the clock reaches its time. Pass Now to mediadevices.WithTimeSource to make
+// capture times and RTP timestamps deterministic.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	changed chan struct{}
+}
+
+// NewClock creates a clock that starts at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{
+		now:     start,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d, and wakes up readers whose frames are due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d <= 0 {
+		return
+	}
+	c.now = c.now.Add(d)
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// waitUntil blocks until the clock reaches t. It returns false if done is closed before that.
+func (c *Clock) waitUntil(t time.Time, done <-chan struct{}) bool {
+	for {
+		c.mu.Lock()
+		reached := !c.now.Before(t)
+		changed := c.changed
+		c.mu.Unlock()
+		if reached {
+			return true
+		}
+
+		select {
+		case <-changed:
+		case <-done:
+			return false
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package drivertest
+
+import (
+	"context"
+	"image"
+	"io"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	defaultFrameRate = 30
+	defaultLatency   = 20 * time.Millisecond
+)
+
+// device is what Camera and Microphone have in common.
+type device struct {
+	clock *Clock
+
+	mu     sync.Mutex
+	closed <-chan struct{}
+	cancel func()
+}
+
+func (d *device) Open() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.mu.Lock()
+	d.closed, d.cancel = ctx.Done(), cancel
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *device) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cancel != nil {
+		d.cancel()
+	}
+	return nil
+}
+
+func (d *device) done() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.closed
+}
+
+// Camera is a camera that delivers a frame every clock frame interval. Frame n is delivered when
+// the clock reaches n frame intervals after recording started, and that's its capture time.
+// Due frames are delivered at once, so no frame is dropped however late they're read.
+type Camera struct {
+	device
+	properties []prop.Media
+	frame      func(n int, p prop.Media) image.Image
+}
+
+// CameraOption configures Camera.
+type CameraOption func(*Camera)
+
+// WithCameraProperties sets camera properties. The default is 640x480 I420 at 30 fps.
+func WithCameraProperties(properties ...prop.Media) CameraOption {
+	return func(c *Camera) {
+		c.properties = properties
+	}
+}
+
+// WithFrames sets a function that returns recording frame n with properties p. Default
+// frames are I420 images whose luma is n.
+func WithFrames(frame func(n int, p prop.Media) image.Image) CameraOption {
+	return func(c *Camera) {
+		c.frame = frame
+	}
+}
+
+// NewCamera creates a camera driven by clock.
+func NewCamera(clock *Clock, opts ...CameraOption) *Camera {
+	c := &Camera{
+		device: device{clock: clock},
+		properties: []prop.Media{{
+			Video: prop.Video{
+				Width:       640,
+				Height:      480,
+				FrameFormat: frame.FormatI420,
+				FrameRate:   defaultFrameRate,
+			},
+		}},
+		frame: lumaFrame,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register registers the camera with label to the driver manager, so GetUserMedia selects it.
+// The returned function unregisters it.
+func (c *Camera) Register(label string) (func(), error) {
+	return register(c, label, driver.Camera)
+}
+
+func (c *Camera) Properties() []prop.Media {
+	return c.properties
+}
+
+func (c *Camera) VideoRecord(p prop.Media) (video.Reader, error) {
+	interval := frameInterval(p.FrameRate)
+	start := c.clock.Now()
+	done := c.done()
+
+	var n int
+	var captured time.Time
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		due := start.Add(time.Duration(n) * interval)
+		if !c.clock.waitUntil(due, done) {
+			return nil, func() {}, io.EOF
+		}
+
+		img := c.frame(n, p)
+		captured = due
+		n++
+		return img, func() {}, nil
+	})
+	// Frames are read synchronously, so metadata is from the last frame.
+	return video.NewMetadataReader(r, func() video.Metadata {
+		return video.Metadata{CaptureTime: captured}
+	}), nil
+}
+
+// Microphone is a microphone that delivers a chunk of latency length every clock latency, like
+// Camera.
+type Microphone struct {
+	device
+	properties []prop.Media
+	chunk      func(n int, p prop.Media) wave.Audio
+}
+
+// MicrophoneOption configures Microphone.
+type MicrophoneOption func(*Microphone)
+
+// WithMicrophoneProperties sets microphone properties. The default is 48 kHz mono float32 with
+// 20 ms latency.
+func WithMicrophoneProperties(properties ...prop.Media) MicrophoneOption {
+	return func(m *Microphone) {
+		m.properties = properties
+	}
+}
+
+// WithChunks sets a function that returns recording chunk n with properties p. Default
+// chunks are a 480 Hz sine wave.
+func WithChunks(chunk func(n int, p prop.Media) wave.Audio) MicrophoneOption {
+	return func(m *Microphone) {
+		m.chunk = chunk
+	}
+}
+
+// NewMicrophone creates a microphone driven by clock.
+func NewMicrophone(clock *Clock, opts ...MicrophoneOption) *Microphone {
+	m := &Microphone{
+		device: device{clock: clock},
+		properties: []prop.Media{{
+			Audio: prop.Audio{
+				ChannelCount:  1,
+				Latency:       defaultLatency,
+				SampleRate:    48000,
+				SampleSize:    4,
+				IsFloat:       true,
+				IsInterleaved: true,
+			},
+		}},
+		chunk: sineChunk,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Register registers the microphone with label to the driver manager, so GetUserMedia selects it.
+// The returned function unregisters it.
+func (m *Microphone) Register(label string) (func(), error) {
+	return register(m, label, driver.Microphone)
+}
+
+func (m *Microphone) Properties() []prop.Media {
+	return m.properties
+}
+
+func (m *Microphone) AudioRecord(p prop.Media) (audio.Reader, error) {
+	latency := p.Latency
+	if latency <= 0 {
+		latency = defaultLatency
+	}
+	start := m.clock.Now()
+	done := m.done()
+
+	var n int
+	return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		// A chunk is delivered after all its samples are captured.
+		due := start.Add(time.Duration(n+1) * latency)
+		if !m.clock.waitUntil(due, done) {
+			return nil, func() {}, io.EOF
+		}
+
+		chunk := m.chunk(n, p)
+		n++
+		return chunk, func() {}, nil
+	}), nil
+}
+
+func register(a driver.Adapter, label string, t driver.DeviceType) (func(), error) {
+	if err := driver.GetManager().Register(a, driver.Info{Label: label, DeviceType: t}); err != nil {
+		return nil, err
+	}
+	return func() { driver.GetManager().Unregister(a) }, nil
+}
+
+func frameInterval(frameRate float32) time.Duration {
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
+	return time.Duration(float64(time.Second) / float64(frameRate))
+}
+
+// lumaFrame returns an I420 image whose luma is n, so tests can tell frames apart.
+func lumaFrame(n int, p prop.Media) image.Image {
+	img := image.NewYCbCr(image.Rect(0, 0, p.Width, p.Height), image.YCbCrSubsampleRatio420)
+	for i := range img.Y {
+		img.Y[i] = uint8(n)
+	}
+	for i := range img.Cb {
+		img.Cb[i] = 128
+		img.Cr[i] = 128
+	}
+	return img
+}
+
+// sineChunk returns chunk n of a 480 Hz sine wave, which continues from the previous chunk.
+func sineChunk(n int, p prop.Media) wave.Audio {
+	channels := p.ChannelCount
+	if channels <= 0 {
+		channels = 1
+	}
+	latency := p.Latency
+	if latency <= 0 {
+		latency = defaultLatency
+	}
+	size := int(int64(p.SampleRate) * int64(latency) / int64(time.Second))
+
+	chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: size, Channels: channels, SamplingRate: p.SampleRate})
+	for i := 0; i < size; i++ {
+		t := float64(n*size+i) / float64(p.SampleRate)
+		v := wave.Float32Sample(0.25 * math.Sin(2*math.Pi*480*t))
+		for ch := 0; ch < channels; ch++ {
+			chunk.SetFloat32(i, ch, v)
+		}
+	}
+	return chunk
+}
@@ -0,0 +1,141 @@
+package drivertest
+
+import (
+	"image"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestCamera(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := NewClock(start)
+	cam := NewCamera(clock)
+	unregister, err := cam.Register("drivertest-camera")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregister()
+
+	stream, err := mediadevices.GetUserMedia(mediadevices.MediaStreamConstraints{
+		Video: func(c *mediadevices.MediaTrackConstraints) {
+			c.Width = prop.Int(64)
+			c.Height = prop.Int(32)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	track := stream.GetVideoTracks()[0].(*mediadevices.VideoTrack)
+	defer track.Close()
+	r := track.NewReader(false)
+
+	read := func() (image.Image, time.Time) {
+		frames := make(chan image.Image)
+		go func() {
+			img, _, err := r.Read()
+			if err != nil {
+				t.Error(err)
+			}
+			frames <- img
+		}()
+		img := <-frames
+		m, _ := video.MetadataOf(r)
+		return img, m.CaptureTime
+	}
+
+	// The first frame is due when recording starts.
+	img, captured := read()
+	if y := img.(*image.YCbCr).Y[0]; y != 0 {
+		t.Fatalf("expected frame 0, but got %d", y)
+	}
+	if !captured.Equal(start) {
+		t.Fatalf("expected the capture time to be %v, but got %v", start, captured)
+	}
+
+	// Due frames are delivered at once.
+	clock.Advance(2 * time.Second / 30)
+	for i := 1; i <= 2; i++ {
+		img, captured := read()
+		if y := img.(*image.YCbCr).Y[0]; int(y) != i {
+			t.Fatalf("expected frame %d, but got %d", i, y)
+		}
+		if expected := start.Add(time.Duration(i) * (time.Second / 30)); !captured.Equal(expected) {
+			t.Fatalf("expected the capture time to be %v, but got %v", expected, captured)
+		}
+	}
+}
+
+func TestCameraBlocksUntilDue(t *testing.T) {
+	clock := NewClock(time.Unix(0, 0))
+	cam := NewCamera(clock, WithCameraProperties(prop.Media{Video: prop.Video{Width: 4, Height: 4, FrameRate: 10}}))
+	if err := cam.Open(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := cam.VideoRecord(cam.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	read := make(chan error)
+	go func() {
+		_, _, err := r.Read()
+		read <- err
+	}()
+	clock.Advance(50 * time.Millisecond)
+	clock.Advance(50 * time.Millisecond)
+	if err := <-read; err != nil {
+		t.Fatal(err)
+	}
+
+	// Close wakes up the blocked reader.
+	go func() {
+		_, _, err := r.Read()
+		read <- err
+	}()
+	cam.Close()
+	if err := <-read; err != io.EOF {
+		t.Fatalf("expected %v, but got %v", io.EOF, err)
+	}
+}
+
+func TestMicrophone(t *testing.T) {
+	clock := NewClock(time.Unix(0, 0))
+	mic := NewMicrophone(clock)
+	if err := mic.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer mic.Close()
+	r, err := mic.AudioRecord(mic.Properties()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chunks := make(chan wave.Audio)
+	go func() {
+		for i := 0; i < 2; i++ {
+			chunk, _, err := r.Read()
+			if err != nil {
+				t.Error(err)
+			}
+			chunks <- chunk
+		}
+	}()
+
+	// A chunk is delivered after its latency.
+	clock.Advance(40 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		chunk := <-chunks
+		if info := chunk.ChunkInfo(); info.Len != 960 || info.SamplingRate != 48000 {
+			t.Fatalf("expected 20ms at 48kHz, but got %+v", info)
+		}
+	}
+}
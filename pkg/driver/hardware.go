@@ -0,0 +1,84 @@
+package driver
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// idNamespace is the namespace of device IDs generated from stable device attributes.
+var idNamespace = uuid.MustParse("5b1ea1c6-8a4f-4b8e-9a43-3c3d35c5e1a7")
+
+// Hardware represents the hardware attributes that identify a device across reboots and bus
+// re-enumeration, e.g. by USB hubs. Empty fields are unknown.
+type Hardware struct {
+	Serial    string
+	VendorID  string
+	ProductID string
+	// PortPath is the device's physical location on the bus, e.g. "1-2.3" for USB, which is kept as long as
+	// the device is plugged into the same port.
+	PortPath string
+	// Index tells apart devices on the same hardware, e.g. a sound card's PCM devices.
+	Index int
+}
+
+// USBHardware returns the device's attributes in sysfs, e.g. /sys/class/video4linux/video0/device. The USB
+// device's attributes are returned if the device belongs to one, otherwise PortPath is the device's location
+// under /sys/devices.
+func USBHardware(sysfsDevice string) (Hardware, error) {
+	path, err := filepath.EvalSymlinks(sysfsDevice)
+	if err != nil {
+		return Hardware{}, err
+	}
+
+	for dir := path; dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		vendor, err := readAttribute(dir, "idVendor")
+		if err != nil {
+			continue
+		}
+		product, _ := readAttribute(dir, "idProduct")
+		serial, _ := readAttribute(dir, "serial")
+		return Hardware{
+			Serial:    serial,
+			VendorID:  vendor,
+			ProductID: product,
+			PortPath:  filepath.Base(dir),
+		}, nil
+	}
+	return Hardware{PortPath: strings.TrimPrefix(path, "/sys/devices/")}, nil
+}
+
+func readAttribute(dir, name string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// stableIDs returns info's device IDs, most stable first. A device is identified by its serial number if
+// it's known, then by the port it's plugged into, and finally by its label. The IDs stay the same as long
+// as the attributes do, so they can be stored as user preferences.
+func stableIDs(info Info) []string {
+	var keys []string
+	hw := info.Hardware
+	product := strings.Join([]string{string(info.DeviceType), hw.VendorID, hw.ProductID, strconv.Itoa(hw.Index)}, "\x00")
+	if hw.Serial != "" {
+		keys = append(keys, product+"\x00serial\x00"+hw.Serial)
+	}
+	if hw.PortPath != "" {
+		keys = append(keys, product+"\x00port\x00"+hw.PortPath)
+	}
+	if info.Label != "" {
+		keys = append(keys, string(info.DeviceType)+"\x00label\x00"+info.Label)
+	}
+
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = uuid.NewSHA1(idNamespace, []byte(key)).String()
+	}
+	return ids
+}
@@ -0,0 +1,46 @@
+package driver
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUSBHardware(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// sysfs layout, e.g. /sys/devices/pci0000:00/0000:00:14.0/usb1/1-2/1-2.3/1-2.3:1.0/video4linux/video0
+	usb := filepath.Join(dir, "usb1", "1-2", "1-2.3")
+	device := filepath.Join(usb, "1-2.3:1.0")
+	if err := os.MkdirAll(filepath.Join(device, "video4linux", "video0"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range map[string]string{"idVendor": "046d\n", "idProduct": "0825\n", "serial": "ABCD\n"} {
+		if err := ioutil.WriteFile(filepath.Join(usb, name), []byte(value), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The class directory links to the device.
+	link := filepath.Join(dir, "device")
+	if err := os.Symlink(device, link); err != nil {
+		t.Fatal(err)
+	}
+
+	hw, err := USBHardware(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Hardware{Serial: "ABCD", VendorID: "046d", ProductID: "0825", PortPath: "1-2.3"}
+	if hw != expected {
+		t.Fatalf("expected %v, but got %v", expected, hw)
+	}
+
+	if _, err := USBHardware(filepath.Join(dir, "missing")); err == nil {
+		t.Fatal("expected an error for the missing device")
+	}
+}
@@ -0,0 +1,228 @@
+// +build jack
+
+#include "bridge.h"
+
+#include <errno.h>
+#include <pthread.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+
+#include <jack/jack.h>
+#include <jack/ringbuffer.h>
+
+// Ring buffer size in periods
+#define RING_PERIODS 16
+
+static const char *clientName = "mediadevices";
+
+struct jkCapture {
+	jack_client_t *client;
+	jack_port_t **ports;
+	int n;
+	jack_ringbuffer_t *ring;
+
+	pthread_mutex_t mutex;
+	pthread_cond_t cond;
+	volatile int ended;
+};
+
+typedef struct {
+	uint32_t frameTime;
+	uint32_t frames;
+} jkHeader;
+
+const char **jkPorts()
+{
+	jack_client_t *client = jack_client_open(clientName, JackNoStartServer, NULL);
+	if (!client)
+		return NULL;
+	const char **ports = jack_get_ports(client, NULL, JACK_DEFAULT_AUDIO_TYPE, JackPortIsOutput);
+	jack_client_close(client);
+	return ports;
+}
+
+void jkFreePorts(const char **ports)
+{
+	jack_free(ports);
+}
+
+int jkServerInfo(int *rate, int *bufferSize)
+{
+	jack_client_t *client = jack_client_open(clientName, JackNoStartServer, NULL);
+	if (!client)
+		return JK_ERROR;
+	*rate = jack_get_sample_rate(client);
+	*bufferSize = jack_get_buffer_size(client);
+	jack_client_close(client);
+	return 0;
+}
+
+// ringCopy copies src to offset in the ring buffer's write vector.
+static void ringCopy(jack_ringbuffer_data_t *vec, size_t offset, const void *src, size_t size)
+{
+	const char *p = src;
+	if (offset < vec[0].len) {
+		size_t n = vec[0].len - offset < size ? vec[0].len - offset : size;
+		memcpy(vec[0].buf + offset, p, n);
+		p += n;
+		size -= n;
+		offset = vec[0].len;
+	}
+	if (size > 0)
+		memcpy(vec[1].buf + (offset - vec[0].len), p, size);
+}
+
+static int onProcess(jack_nframes_t nframes, void *arg)
+{
+	jkCapture *c = arg;
+	size_t size = sizeof(jkHeader) + (size_t)nframes * c->n * sizeof(float);
+
+	// The period is lost if the reader is behind, and the frame time fills the gap.
+	jack_ringbuffer_data_t vec[2];
+	jack_ringbuffer_get_write_vector(c->ring, vec);
+	if (vec[0].len + vec[1].len >= size) {
+		jkHeader header = {jack_last_frame_time(c->client), nframes};
+		size_t offset = 0;
+		ringCopy(vec, offset, &header, sizeof(header));
+		offset += sizeof(header);
+		for (int ch = 0; ch < c->n; ch++) {
+			const float *in = jack_port_get_buffer(c->ports[ch], nframes);
+			ringCopy(vec, offset, in, nframes * sizeof(float));
+			offset += nframes * sizeof(float);
+		}
+		// The whole period becomes readable at once.
+		jack_ringbuffer_write_advance(c->ring, size);
+	}
+
+	// The process thread is real-time, so it never waits for the reader.
+	if (pthread_mutex_trylock(&c->mutex) == 0) {
+		pthread_cond_signal(&c->cond);
+		pthread_mutex_unlock(&c->mutex);
+	}
+	return 0;
+}
+
+static void onShutdown(void *arg)
+{
+	jkCapture *c = arg;
+	pthread_mutex_lock(&c->mutex);
+	c->ended = 1;
+	pthread_cond_signal(&c->cond);
+	pthread_mutex_unlock(&c->mutex);
+}
+
+jkCapture *jkCaptureOpen(const char **ports, int n)
+{
+	jkCapture *c = calloc(1, sizeof(jkCapture));
+	if (!c)
+		return NULL;
+	pthread_mutex_init(&c->mutex, NULL);
+	pthread_cond_init(&c->cond, NULL);
+	c->n = n;
+
+	c->client = jack_client_open(clientName, JackNoStartServer, NULL);
+	if (!c->client)
+		goto fail;
+
+	c->ports = calloc(n, sizeof(jack_port_t *));
+	if (!c->ports)
+		goto fail;
+	for (int ch = 0; ch < n; ch++) {
+		char name[32];
+		snprintf(name, sizeof(name), "in_%d", ch + 1);
+		c->ports[ch] = jack_port_register(c->client, name, JACK_DEFAULT_AUDIO_TYPE, JackPortIsInput, 0);
+		if (!c->ports[ch])
+			goto fail;
+	}
+
+	size_t period = sizeof(jkHeader) + (size_t)jack_get_buffer_size(c->client) * n * sizeof(float);
+	c->ring = jack_ringbuffer_create(period * RING_PERIODS);
+	if (!c->ring)
+		goto fail;
+
+	jack_set_process_callback(c->client, onProcess, c);
+	jack_on_shutdown(c->client, onShutdown, c);
+	if (jack_activate(c->client) != 0)
+		goto fail;
+
+	for (int ch = 0; ch < n; ch++) {
+		if (jack_connect(c->client, ports[ch], jack_port_name(c->ports[ch])) != 0) {
+			jack_deactivate(c->client);
+			goto fail;
+		}
+	}
+	return c;
+
+fail:
+	if (c->client)
+		jack_client_close(c->client);
+	if (c->ring)
+		jack_ringbuffer_free(c->ring);
+	free(c->ports);
+	pthread_cond_destroy(&c->cond);
+	pthread_mutex_destroy(&c->mutex);
+	free(c);
+	return NULL;
+}
+
+int jkSampleRate(jkCapture *c)
+{
+	return jack_get_sample_rate(c->client);
+}
+
+int jkBufferSize(jkCapture *c)
+{
+	return jack_get_buffer_size(c->client);
+}
+
+int jkCaptureRead(jkCapture *c, float *buf, int maxFrames, uint32_t *frameTime, int timeoutMs)
+{
+	struct timespec deadline;
+	clock_gettime(CLOCK_REALTIME, &deadline);
+	deadline.tv_sec += timeoutMs / 1000;
+	deadline.tv_nsec += (long)(timeoutMs % 1000) * 1000000;
+	if (deadline.tv_nsec >= 1000000000) {
+		deadline.tv_sec++;
+		deadline.tv_nsec -= 1000000000;
+	}
+
+	pthread_mutex_lock(&c->mutex);
+	while (jack_ringbuffer_read_space(c->ring) < sizeof(jkHeader) && !c->ended) {
+		if (pthread_cond_timedwait(&c->cond, &c->mutex, &deadline) == ETIMEDOUT)
+			break;
+	}
+	pthread_mutex_unlock(&c->mutex);
+
+	if (jack_ringbuffer_read_space(c->ring) < sizeof(jkHeader))
+		return c->ended ? JK_ENDED : JK_TIMEOUT;
+
+	// Periods are committed at once, so the samples are available after the header.
+	jkHeader header;
+	jack_ringbuffer_read(c->ring, (char *)&header, sizeof(header));
+	*frameTime = header.frameTime;
+	for (int ch = 0; ch < c->n; ch++) {
+		char *dst = (char *)(buf + (size_t)ch * maxFrames);
+		size_t size = (size_t)header.frames * sizeof(float);
+		if (header.frames > (uint32_t)maxFrames) {
+			// The server's buffer size changed.
+			jack_ringbuffer_read(c->ring, dst, (size_t)maxFrames * sizeof(float));
+			jack_ringbuffer_read_advance(c->ring, size - (size_t)maxFrames * sizeof(float));
+		} else {
+			jack_ringbuffer_read(c->ring, dst, size);
+		}
+	}
+	return header.frames > (uint32_t)maxFrames ? maxFrames : (int)header.frames;
+}
+
+void jkCaptureClose(jkCapture *c)
+{
+	jack_deactivate(c->client);
+	jack_client_close(c->client);
+	jack_ringbuffer_free(c->ring);
+	free(c->ports);
+	pthread_cond_destroy(&c->cond);
+	pthread_mutex_destroy(&c->mutex);
+	free(c);
+}
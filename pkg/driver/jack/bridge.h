@@ -0,0 +1,28 @@
+// +build jack
+
+#pragma once
+
+#include <stdint.h>
+
+#define JK_ERROR -1
+#define JK_TIMEOUT -2
+#define JK_ENDED -3
+
+typedef struct jkCapture jkCapture;
+
+// jkPorts returns the audio type's physical and client output ports as a NULL terminated array,
+// which has to be freed by jkFreePorts. NULL is returned if the server isn't running.
+const char **jkPorts();
+void jkFreePorts(const char **ports);
+
+// jkServerInfo stores the server's sample rate and buffer size. It returns 0 on success.
+int jkServerInfo(int *rate, int *bufferSize);
+
+// jkCaptureOpen creates a client with input ports connected to ports.
+jkCapture *jkCaptureOpen(const char **ports, int n);
+int jkSampleRate(jkCapture *c);
+int jkBufferSize(jkCapture *c);
+// jkCaptureRead reads a period into buf, where channel ch's samples start at buf + ch * maxFrames.
+// It returns the number of frames, and stores the period's frame time in frameTime.
+int jkCaptureRead(jkCapture *c, float *buf, int maxFrames, uint32_t *frameTime, int timeoutMs);
+void jkCaptureClose(jkCapture *c);
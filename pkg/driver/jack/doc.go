@@ -0,0 +1,15 @@
+// Package jack registers JACK clients' output ports as audio drivers, so feeds from mixers and audio
+// interfaces in pro audio setups can be streamed. Each client's ports, e.g. system:capture_1 and
+// system:capture_2, are registered as a device whose channels are the ports, and RegisterPorts can
+// register any combination of ports.
+//
+// Chunks are the JACK server's periods in Float32, without conversion. Timing is sample accurate:
+// periods lost to reader overruns are filled with silence, so every sample's position in the track
+// matches the server's frame time.
+//
+// JACK is linked with cgo, and the package is only built with the jack build tag:
+//
+//	go build -tags jack
+//
+// Reference: https://jackaudio.org/api/
+package jack
@@ -0,0 +1,196 @@
+// +build jack
+
+package jack
+
+// #cgo pkg-config: jack
+// #include <stdlib.h>
+// #include "bridge.h"
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/pion/mediadevices/internal/logging"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const readTimeoutMs = 5000
+
+var logger = logging.NewLogger("mediadevices/driver/jack")
+
+var (
+	errServerUnavailable = errors.New("JACK server is not running")
+	errOpenFailed        = errors.New("failed to open JACK client")
+	errReadTimeout       = errors.New("read timeout")
+	errNoPorts           = errors.New("no ports are selected")
+)
+
+type microphone struct {
+	group   portGroup
+	capture *C.jkCapture
+	mutex   sync.Mutex
+}
+
+func init() {
+	ports, err := outputPorts()
+	if err != nil {
+		logger.Debugf("%s\n", err)
+		return
+	}
+
+	for _, group := range groupPorts(ports) {
+		priority := driver.PriorityNormal
+		if group.label == "jack:system" {
+			// The audio interface's physical inputs
+			priority = driver.PriorityHigh
+		}
+		driver.GetManager().Register(&microphone{group: group}, driver.Info{
+			Label:      group.label,
+			DeviceType: driver.Microphone,
+			Priority:   priority,
+		})
+	}
+}
+
+func outputPorts() ([]string, error) {
+	cPorts := C.jkPorts()
+	if cPorts == nil {
+		return nil, errServerUnavailable
+	}
+	defer C.jkFreePorts(cPorts)
+
+	var ports []string
+	for _, p := range (*[1 << 16]*C.char)(unsafe.Pointer(cPorts)) {
+		if p == nil {
+			break
+		}
+		ports = append(ports, C.GoString(p))
+	}
+	return ports, nil
+}
+
+// RegisterPorts registers a device whose channels are ports, e.g. a mixer's stereo feed from
+// "mixer:out_L" and "mixer:out_R", and returns its device ID.
+func RegisterPorts(ports ...string) (string, error) {
+	if len(ports) == 0 {
+		return "", errNoPorts
+	}
+
+	group := selectPorts(ports)
+	manager := driver.GetManager()
+	registered := manager.Query(func(d driver.Driver) bool { return d.Info().Label == group.label })
+	if len(registered) == 0 {
+		manager.Register(&microphone{group: group}, driver.Info{
+			Label:      group.label,
+			DeviceType: driver.Microphone,
+			Priority:   driver.PriorityNormal,
+		})
+		registered = manager.Query(func(d driver.Driver) bool { return d.Info().Label == group.label })
+	}
+	return registered[0].ID(), nil
+}
+
+func (m *microphone) Open() error {
+	return nil
+}
+
+func (m *microphone) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.capture != nil {
+		C.jkCaptureClose(m.capture)
+		m.capture = nil
+	}
+	return nil
+}
+
+func (m *microphone) AudioRecord(p prop.Media) (audio.Reader, error) {
+	cPorts := make([]*C.char, len(m.group.ports))
+	for i, port := range m.group.ports {
+		cPorts[i] = C.CString(port)
+		defer C.free(unsafe.Pointer(cPorts[i]))
+	}
+
+	capture := C.jkCaptureOpen(&cPorts[0], C.int(len(cPorts)))
+	if capture == nil {
+		return nil, errOpenFailed
+	}
+	if rate := int(C.jkSampleRate(capture)); rate != p.SampleRate {
+		C.jkCaptureClose(capture)
+		return nil, fmt.Errorf("the sample rate of the server is %d instead of %d", rate, p.SampleRate)
+	}
+
+	m.mutex.Lock()
+	m.capture = capture
+	m.mutex.Unlock()
+
+	channels := len(m.group.ports)
+	maxFrames := int(C.jkBufferSize(capture))
+	buf := make([]float32, maxFrames*channels)
+	tl := timeline{maxGap: uint32(p.SampleRate)}
+
+	reader := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		m.mutex.Lock()
+		if m.capture == nil {
+			m.mutex.Unlock()
+			return nil, func() {}, io.EOF
+		}
+		var frameTime C.uint32_t
+		n := C.jkCaptureRead(m.capture, (*C.float)(&buf[0]), C.int(maxFrames), &frameTime, readTimeoutMs)
+		m.mutex.Unlock()
+
+		switch {
+		case n == C.JK_ENDED:
+			return nil, func() {}, io.EOF
+		case n == C.JK_TIMEOUT:
+			return nil, func() {}, errReadTimeout
+		case n < 0:
+			return nil, func() {}, errOpenFailed
+		}
+
+		// Lost periods are filled with silence before the period.
+		gap := int(tl.advance(uint32(frameTime), uint32(n)))
+		if gap > 0 {
+			logger.Debugf("%s: %d frames are lost\n", m.group.label, gap)
+		}
+
+		chunk := wave.NewFloat32NonInterleaved(wave.ChunkInfo{
+			Len:          gap + int(n),
+			Channels:     channels,
+			SamplingRate: p.SampleRate,
+		})
+		for ch := range chunk.Data {
+			copy(chunk.Data[ch][gap:], buf[ch*maxFrames:ch*maxFrames+int(n)])
+		}
+		return chunk, func() {}, nil
+	})
+	return reader, nil
+}
+
+func (m *microphone) Properties() []prop.Media {
+	var rate, bufferSize C.int
+	if C.jkServerInfo(&rate, &bufferSize) != 0 {
+		return nil
+	}
+
+	// The server fixes the format.
+	return []prop.Media{{
+		Audio: prop.Audio{
+			ChannelCount:  len(m.group.ports),
+			SampleRate:    int(rate),
+			SampleSize:    4,
+			IsFloat:       true,
+			IsInterleaved: false,
+			Latency:       time.Duration(bufferSize) * time.Second / time.Duration(rate),
+		},
+	}}
+}
@@ -0,0 +1,62 @@
+package jack
+
+import "strings"
+
+// portGroup is a set of ports captured as a device's channels.
+type portGroup struct {
+	label string
+	ports []string
+}
+
+// groupPorts groups ports, e.g. system:capture_1, by client. The order of clients, and of ports within
+// each client, is kept.
+func groupPorts(ports []string) []portGroup {
+	var groups []portGroup
+	index := make(map[string]int)
+	for _, port := range ports {
+		client := port
+		if i := strings.Index(port, ":"); i >= 0 {
+			client = port[:i]
+		}
+		i, ok := index[client]
+		if !ok {
+			i = len(groups)
+			index[client] = i
+			groups = append(groups, portGroup{label: "jack:" + client})
+		}
+		groups[i].ports = append(groups[i].ports, port)
+	}
+	return groups
+}
+
+// selectPorts returns the group of ports the user selected.
+func selectPorts(ports []string) portGroup {
+	return portGroup{
+		label: "jack:" + strings.Join(ports, ","),
+		ports: append([]string(nil), ports...),
+	}
+}
+
+// timeline keeps chunks aligned with the server's frame time.
+type timeline struct {
+	next    uint32
+	started bool
+	// maxGap is the longest gap to fill. The timeline starts over after a longer gap, e.g. when
+	// the server was in freewheel mode.
+	maxGap uint32
+}
+
+// advance returns the number of frames missed before the period at frameTime.
+func (t *timeline) advance(frameTime, frames uint32) uint32 {
+	var gap uint32
+	if t.started {
+		// The frame time wraps around, which uint32 arithmetic handles.
+		gap = frameTime - t.next
+		if int32(gap) < 0 || gap > t.maxGap {
+			gap = 0
+		}
+	}
+	t.started = true
+	t.next = frameTime + frames
+	return gap
+}
@@ -0,0 +1,51 @@
+package jack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupPorts(t *testing.T) {
+	groups := groupPorts([]string{
+		"system:capture_1",
+		"mixer:out_L",
+		"system:capture_2",
+		"mixer:out_R",
+	})
+	expected := []portGroup{
+		{label: "jack:system", ports: []string{"system:capture_1", "system:capture_2"}},
+		{label: "jack:mixer", ports: []string{"mixer:out_L", "mixer:out_R"}},
+	}
+	if !reflect.DeepEqual(expected, groups) {
+		t.Fatalf("expected %v, but got %v", expected, groups)
+	}
+
+	if label := selectPorts([]string{"mixer:out_L", "system:capture_2"}).label; label != "jack:mixer:out_L,system:capture_2" {
+		t.Fatalf("expected jack:mixer:out_L,system:capture_2, but got %s", label)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	tl := timeline{maxGap: 48000}
+	testCases := []struct {
+		frameTime uint32
+		gap       uint32
+	}{
+		{1000, 0},
+		{1256, 0},
+		// 2 periods are lost
+		{2024, 512},
+		// Out of order, which isn't filled
+		{1000, 0},
+		// Longer than maxGap
+		{200000, 0},
+		// Wraps around
+		{0xFFFFFF00, 0},
+		{0x00000100, 256},
+	}
+	for _, c := range testCases {
+		if gap := tl.advance(c.frameTime, 256); gap != c.gap {
+			t.Fatalf("expected the gap of %d frames at %d, but got %d", c.gap, c.frameTime, gap)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// +build libcamera
+
+#include "bridge.hpp"
+
+#include <chrono>
+#include <condition_variable>
+#include <cstdlib>
+#include <cstring>
+#include <deque>
+#include <map>
+#include <memory>
+#include <mutex>
+#include <vector>
+
+#include <libcamera/libcamera.h>
+#include <sys/mman.h>
+#include <time.h>
+
+using namespace libcamera;
+
+#define NUM_BUFFERS 4
+
+static std::unique_ptr<CameraManager> manager;
+
+struct lcCamera {
+  std::shared_ptr<Camera> camera;
+  std::unique_ptr<CameraConfiguration> config;
+  std::unique_ptr<FrameBufferAllocator> allocator;
+  std::vector<std::unique_ptr<Request>> requests;
+  // planes stores the mapped address of the Y, U and V planes of each buffer
+  std::map<const FrameBuffer *, std::vector<const uint8_t *>> planes;
+  std::vector<std::pair<void *, size_t>> mappings;
+  Stream *stream = nullptr;
+  unsigned int stride = 0;
+  int width = 0, height = 0;
+  bool started = false;
+
+  std::mutex mu;
+  std::condition_variable cond;
+  std::deque<Request *> completed;
+
+  void requestComplete(Request *request) {
+    if (request->status() == Request::RequestCancelled) {
+      return;
+    }
+
+    std::lock_guard<std::mutex> lock(mu);
+    completed.push_back(request);
+    cond.notify_one();
+  }
+
+  bool mapBuffer(const FrameBuffer *buffer) {
+    // The planes usually share a dmabuf, so every fd is only mapped once
+    std::map<int, size_t> sizes;
+    for (const FrameBuffer::Plane &plane : buffer->planes()) {
+      size_t end = plane.offset + plane.length;
+      if (sizes[plane.fd.get()] < end) {
+        sizes[plane.fd.get()] = end;
+      }
+    }
+
+    std::map<int, const uint8_t *> addresses;
+    for (const auto &size : sizes) {
+      void *address = mmap(nullptr, size.second, PROT_READ, MAP_SHARED, size.first, 0);
+      if (address == MAP_FAILED) {
+        return false;
+      }
+      mappings.push_back({address, size.second});
+      addresses[size.first] = static_cast<const uint8_t *>(address);
+    }
+
+    std::vector<const uint8_t *> &ptrs = planes[buffer];
+    for (const FrameBuffer::Plane &plane : buffer->planes()) {
+      ptrs.push_back(addresses[plane.fd.get()] + plane.offset);
+    }
+
+    // Some pipelines describe the whole frame as a single plane
+    if (ptrs.size() == 1) {
+      ptrs.push_back(ptrs[0] + stride * height);
+      ptrs.push_back(ptrs[1] + (stride / 2) * (height / 2));
+    }
+    return ptrs.size() == 3;
+  }
+
+  void release() {
+    if (started) {
+      camera->stop();
+      camera->requestCompleted.disconnect(this, &lcCamera::requestComplete);
+      started = false;
+    }
+
+    completed.clear();
+    requests.clear();
+    planes.clear();
+    for (const auto &mapping : mappings) {
+      munmap(mapping.first, mapping.second);
+    }
+    mappings.clear();
+    allocator.reset();
+    config.reset();
+    stream = nullptr;
+  }
+};
+
+extern "C" {
+
+int lcManagerStart(void) {
+  if (manager) {
+    return LC_OK;
+  }
+
+  manager = std::make_unique<CameraManager>();
+  if (manager->start() != 0) {
+    manager.reset();
+    return LC_ERROR;
+  }
+  return LC_OK;
+}
+
+int lcCameraCount(void) { return manager->cameras().size(); }
+
+char *lcCameraID(int index) {
+  std::vector<std::shared_ptr<Camera>> cameras = manager->cameras();
+  if (index < 0 || index >= (int)cameras.size()) {
+    return nullptr;
+  }
+  return strdup(cameras[index]->id().c_str());
+}
+
+lcCamera *lcCameraOpen(const char *id) {
+  std::shared_ptr<Camera> camera = manager->get(id);
+  if (!camera || camera->acquire() != 0) {
+    return nullptr;
+  }
+
+  lcCamera *c = new lcCamera();
+  c->camera = camera;
+  return c;
+}
+
+int lcCameraSizes(lcCamera *c, int *sizes, int max) {
+  std::unique_ptr<CameraConfiguration> config = c->camera->generateConfiguration({StreamRole::VideoRecording});
+  if (!config || config->empty()) {
+    return 0;
+  }
+
+  int n = 0;
+  for (const Size &size : config->at(0).formats().sizes(formats::YUV420)) {
+    if (n >= max) {
+      break;
+    }
+    sizes[n * 2] = size.width;
+    sizes[n * 2 + 1] = size.height;
+    n++;
+  }
+  return n;
+}
+
+int lcCameraStart(lcCamera *c, int width, int height, int frameRate) {
+  c->config = c->camera->generateConfiguration({StreamRole::VideoRecording});
+  if (!c->config || c->config->empty()) {
+    return LC_ERROR;
+  }
+
+  StreamConfiguration &cfg = c->config->at(0);
+  cfg.pixelFormat = formats::YUV420;
+  cfg.size.width = width;
+  cfg.size.height = height;
+  cfg.bufferCount = NUM_BUFFERS;
+  // The pipeline might adjust the configuration, but the frames have to be delivered as requested
+  if (c->config->validate() == CameraConfiguration::Invalid || cfg.pixelFormat != formats::YUV420 ||
+      (int)cfg.size.width != width || (int)cfg.size.height != height) {
+    c->release();
+    return LC_ERROR;
+  }
+  if (c->camera->configure(c->config.get()) != 0) {
+    c->release();
+    return LC_ERROR;
+  }
+
+  c->stream = cfg.stream();
+  c->stride = cfg.stride;
+  c->width = width;
+  c->height = height;
+
+  c->allocator = std::make_unique<FrameBufferAllocator>(c->camera);
+  if (c->allocator->allocate(c->stream) < 0) {
+    c->release();
+    return LC_ERROR;
+  }
+
+  for (const std::unique_ptr<FrameBuffer> &buffer : c->allocator->buffers(c->stream)) {
+    std::unique_ptr<Request> request = c->camera->createRequest();
+    if (!request || request->addBuffer(c->stream, buffer.get()) != 0 || !c->mapBuffer(buffer.get())) {
+      c->release();
+      return LC_ERROR;
+    }
+    c->requests.push_back(std::move(request));
+  }
+
+  ControlList controls(controls::controls);
+  if (frameRate > 0) {
+    int64_t duration = 1000000 / frameRate;
+    controls.set(controls::FrameDurationLimits, Span<const int64_t, 2>({duration, duration}));
+  }
+
+  c->camera->requestCompleted.connect(c, &lcCamera::requestComplete);
+  if (c->camera->start(&controls) != 0) {
+    c->camera->requestCompleted.disconnect(c, &lcCamera::requestComplete);
+    c->release();
+    return LC_ERROR;
+  }
+  c->started = true;
+
+  for (std::unique_ptr<Request> &request : c->requests) {
+    if (c->camera->queueRequest(request.get()) != 0) {
+      c->release();
+      return LC_ERROR;
+    }
+  }
+  return LC_OK;
+}
+
+int lcCameraRead(lcCamera *c, uint8_t *dst, int len, int timeoutMs, int64_t *age) {
+  *age = -1;
+  Request *request;
+  {
+    std::unique_lock<std::mutex> lock(c->mu);
+    if (!c->cond.wait_for(lock, std::chrono::milliseconds(timeoutMs), [c] { return !c->completed.empty(); })) {
+      return LC_TIMEOUT;
+    }
+    request = c->completed.front();
+    c->completed.pop_front();
+  }
+
+  int ySize = c->width * c->height;
+  int cWidth = c->width / 2, cHeight = c->height / 2;
+  if (len < ySize + cWidth * cHeight * 2) {
+    return LC_ERROR;
+  }
+
+  int ret = LC_ERROR;
+  FrameBuffer *buffer = request->findBuffer(c->stream);
+  if (request->status() == Request::RequestComplete && buffer != nullptr) {
+    // Remove the padding of the lines
+    const std::vector<const uint8_t *> &ptrs = c->planes[buffer];
+    for (int y = 0; y < c->height; y++) {
+      memcpy(dst + y * c->width, ptrs[0] + y * c->stride, c->width);
+    }
+    uint8_t *cb = dst + ySize, *cr = cb + cWidth * cHeight;
+    for (int y = 0; y < cHeight; y++) {
+      memcpy(cb + y * cWidth, ptrs[1] + y * (c->stride / 2), cWidth);
+      memcpy(cr + y * cWidth, ptrs[2] + y * (c->stride / 2), cWidth);
+    }
+    // The timestamp of the buffer is taken by the kernel in CLOCK_MONOTONIC
+    struct timespec now;
+    uint64_t timestamp = buffer->metadata().timestamp;
+    if (timestamp != 0 && clock_gettime(CLOCK_MONOTONIC, &now) == 0) {
+      *age = int64_t(now.tv_sec) * 1000000000 + now.tv_nsec - int64_t(timestamp);
+    }
+    ret = LC_OK;
+  }
+
+  request->reuse(Request::ReuseBuffers);
+  c->camera->queueRequest(request);
+  return ret;
+}
+
+void lcCameraClose(lcCamera *c) {
+  c->release();
+  c->camera->release();
+  delete c;
+}
+}
@@ -0,0 +1,33 @@
+// +build libcamera
+
+#pragma once
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define LC_OK 0
+#define LC_ERROR -1
+#define LC_TIMEOUT -2
+
+typedef struct lcCamera lcCamera;
+
+int lcManagerStart(void);
+int lcCameraCount(void);
+// lcCameraID returns the id of the camera at index. The caller has to free the returned string.
+char *lcCameraID(int index);
+
+lcCamera *lcCameraOpen(const char *id);
+// lcCameraSizes stores the supported frame sizes in I420 as pairs of width and height, and returns the number of sizes
+int lcCameraSizes(lcCamera *camera, int *sizes, int max);
+int lcCameraStart(lcCamera *camera, int width, int height, int frameRate);
+// lcCameraRead copies the next frame to dst in I420 without padding, and stores the nanoseconds elapsed since the
+// frame was captured to age, or -1 if the timestamp isn't available
+int lcCameraRead(lcCamera *camera, uint8_t *dst, int len, int timeoutMs, int64_t *age);
+void lcCameraClose(lcCamera *camera);
+
+#ifdef __cplusplus
+}
+#endif
@@ -0,0 +1,10 @@
+// Package libcamera registers libcamera's cameras as video drivers. libcamera is Raspberry Pi's camera
+// stack, which has replaced MMAL. Frames are delivered in I420, so pkg/codec/v4l2m2m can encode them
+// without conversion.
+//
+// libcamera is linked with cgo, and the package is only built with the libcamera build tag:
+//
+//	go build -tags libcamera
+//
+// Reference: https://libcamera.org
+package libcamera
@@ -0,0 +1,175 @@
+// +build libcamera
+
+package libcamera
+
+// #cgo pkg-config: libcamera
+// #cgo CXXFLAGS: -std=c++17
+// #include <stdlib.h>
+// #include "bridge.hpp"
+import "C"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	maxSizes         = 64
+	readTimeoutMs    = 5000
+	defaultFrameRate = 30
+)
+
+var (
+	errReadTimeout = errors.New("read timeout")
+	errEmptyFrame  = errors.New("empty frame")
+)
+
+type camera struct {
+	id     string
+	cam    *C.lcCamera
+	buf    []byte
+	mutex  sync.Mutex
+	cancel func()
+}
+
+func init() {
+	if C.lcManagerStart() != C.LC_OK {
+		return
+	}
+
+	n := int(C.lcCameraCount())
+	for i := 0; i < n; i++ {
+		cID := C.lcCameraID(C.int(i))
+		if cID == nil {
+			continue
+		}
+		id := C.GoString(cID)
+		C.free(unsafe.Pointer(cID))
+
+		priority := driver.PriorityNormal
+		if i == 0 {
+			priority = driver.PriorityHigh
+		}
+		driver.GetManager().Register(&camera{id: id}, driver.Info{
+			Label:      id,
+			DeviceType: driver.Camera,
+			Priority:   priority,
+		})
+	}
+}
+
+func (c *camera) Open() error {
+	cID := C.CString(c.id)
+	defer C.free(unsafe.Pointer(cID))
+
+	cam := C.lcCameraOpen(cID)
+	if cam == nil {
+		return fmt.Errorf("failed to acquire %s", c.id)
+	}
+	c.cam = cam
+	return nil
+}
+
+func (c *camera) Close() error {
+	if c.cam == nil {
+		return nil
+	}
+
+	if c.cancel != nil {
+		// Let the reader know the caller has closed the camera
+		c.cancel()
+		// Wait until the reader is done copying the frame
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		c.cancel = nil
+	}
+	C.lcCameraClose(c.cam)
+	c.cam = nil
+	return nil
+}
+
+func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
+	decoder, err := frame.NewDecoder(frame.FormatI420)
+	if err != nil {
+		return nil, err
+	}
+
+	frameRate := p.FrameRate
+	if frameRate <= 0 {
+		frameRate = defaultFrameRate
+	}
+	if C.lcCameraStart(c.cam, C.int(p.Width), C.int(p.Height), C.int(frameRate)) != C.LC_OK {
+		return nil, fmt.Errorf("failed to start %s with %dx%d", c.id, p.Width, p.Height)
+	}
+
+	cam := c.cam
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	buf := make([]byte, p.Width*p.Height+(p.Width/2)*(p.Height/2)*2)
+	// lastCapture is the last frame's capture time in nanoseconds, which the track reads after Read.
+	var lastCapture int64
+	r := video.ReaderFunc(func() (img image.Image, release func(), err error) {
+		// Lock to avoid accessing the camera after it's closed
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+
+		if ctx.Err() != nil {
+			// Return EOF if the camera is already closed.
+			return nil, func() {}, io.EOF
+		}
+
+		var age C.int64_t
+		switch C.lcCameraRead(cam, (*C.uint8_t)(&buf[0]), C.int(len(buf)), readTimeoutMs, &age) {
+		case C.LC_OK:
+		case C.LC_TIMEOUT:
+			return nil, func() {}, errReadTimeout
+		default:
+			return nil, func() {}, errEmptyFrame
+		}
+
+		var captured int64
+		if age >= 0 {
+			captured = time.Now().Add(-time.Duration(age)).UnixNano()
+		}
+		atomic.StoreInt64(&lastCapture, captured)
+		return decoder.Decode(buf, p.Width, p.Height)
+	})
+
+	return video.NewMetadataReader(r, func() video.Metadata {
+		var m video.Metadata
+		if captured := atomic.LoadInt64(&lastCapture); captured != 0 {
+			m.CaptureTime = time.Unix(0, captured)
+		}
+		return m
+	}), nil
+}
+
+func (c *camera) Properties() []prop.Media {
+	var sizes [maxSizes * 2]C.int
+	n := int(C.lcCameraSizes(c.cam, &sizes[0], maxSizes))
+
+	properties := make([]prop.Media, 0, n)
+	for i := 0; i < n; i++ {
+		properties = append(properties, prop.Media{
+			Video: prop.Video{
+				Width:       int(sizes[i*2]),
+				Height:      int(sizes[i*2+1]),
+				FrameFormat: frame.FormatI420,
+			},
+		})
+	}
+	return properties
+}
@@ -0,0 +1,151 @@
+package machinevision
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+var errEmptyFrame = errors.New("empty frame")
+
+// adapter exposes a Device as a driver.Adapter and driver.VideoRecorder
+type adapter struct {
+	backend Backend
+	id      string
+	device  Device
+	mutex   sync.Mutex
+	cancel  func()
+}
+
+func newAdapter(b Backend, id string) *adapter {
+	return &adapter{
+		backend: b,
+		id:      id,
+	}
+}
+
+func (a *adapter) Open() error {
+	device, err := a.backend.Open(a.id)
+	if err != nil {
+		return err
+	}
+
+	a.device = device
+	return nil
+}
+
+func (a *adapter) Close() error {
+	if a.device == nil {
+		return nil
+	}
+
+	if a.cancel != nil {
+		// Let the reader know the caller has closed the camera
+		a.cancel()
+		// Wait until the reader is done with the device buffer
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		a.device.Stop()
+		a.cancel = nil
+	}
+
+	err := a.device.Close()
+	a.device = nil
+	return err
+}
+
+func (a *adapter) VideoRecord(p prop.Media) (video.Reader, error) {
+	decoder, err := frame.NewDecoder(p.FrameFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := a.findFormat(p)
+	if !ok {
+		return nil, fmt.Errorf("%dx%d %s is not supported by the device", p.Width, p.Height, p.FrameFormat)
+	}
+
+	if err := a.device.Start(f); err != nil {
+		return nil, err
+	}
+
+	device := a.device
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.cancel = cancel
+	var buf []byte
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		// Lock to avoid accessing the buffer after Stop()
+		a.mutex.Lock()
+		defer a.mutex.Unlock()
+
+		if ctx.Err() != nil {
+			// Return EOF if the camera is already closed.
+			return nil, func() {}, io.EOF
+		}
+
+		b, err := device.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		if len(b) == 0 {
+			return nil, func() {}, errEmptyFrame
+		}
+
+		if len(b) > len(buf) {
+			// Grow the intermediate buffer
+			buf = make([]byte, len(b))
+		}
+
+		// The next acquisition reuses the device buffer, so the frame has to be moved to Go memory
+		n := copy(buf, b)
+		return decoder.Decode(buf[:n], f.Width, f.Height)
+	})
+
+	return r, nil
+}
+
+// findFormat returns the format that matches p. Industrial cameras usually accept any frame rate up to
+// their maximum, so the requested frame rate is used if it's below the maximum.
+func (a *adapter) findFormat(p prop.Media) (Format, bool) {
+	for _, f := range a.device.Formats() {
+		if f.Width == p.Width && f.Height == p.Height && f.FrameFormat == p.FrameFormat {
+			if p.FrameRate > 0 && (f.FrameRate == 0 || p.FrameRate < f.FrameRate) {
+				f.FrameRate = p.FrameRate
+			}
+			return f, true
+		}
+	}
+
+	return Format{}, false
+}
+
+func (a *adapter) Properties() []prop.Media {
+	properties := make([]prop.Media, 0)
+	for _, f := range a.device.Formats() {
+		// Formats that can't be decoded aren't selectable
+		if _, err := frame.NewDecoder(f.FrameFormat); err != nil {
+			continue
+		}
+
+		properties = append(properties, prop.Media{
+			Video: prop.Video{
+				Width:       f.Width,
+				Height:      f.Height,
+				FrameFormat: f.FrameFormat,
+				FrameRate:   f.FrameRate,
+			},
+		})
+	}
+
+	return properties
+}
@@ -0,0 +1,135 @@
+package machinevision
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+type fakeDevice struct {
+	formats []Format
+	started *Format
+	closed  bool
+	buf     []byte
+}
+
+func (d *fakeDevice) Formats() []Format {
+	return d.formats
+}
+
+func (d *fakeDevice) Start(f Format) error {
+	d.started = &f
+	d.buf = bytes.Repeat([]byte{0x80}, f.Width*f.Height)
+	return nil
+}
+
+func (d *fakeDevice) Read() ([]byte, error) {
+	if d.started == nil {
+		return nil, errors.New("not started")
+	}
+	return d.buf, nil
+}
+
+func (d *fakeDevice) Stop() error {
+	d.started = nil
+	return nil
+}
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+type fakeBackend struct {
+	device *fakeDevice
+}
+
+func (b *fakeBackend) Name() string {
+	return "Fake"
+}
+
+func (b *fakeBackend) Devices() ([]DeviceInfo, error) {
+	return []DeviceInfo{{ID: "0001", Label: "Camera"}}, nil
+}
+
+func (b *fakeBackend) Open(id string) (Device, error) {
+	return b.device, nil
+}
+
+func TestRegister(t *testing.T) {
+	b := &fakeBackend{device: &fakeDevice{}}
+	if err := Register(b); err != nil {
+		t.Fatal(err)
+	}
+
+	drivers := driver.GetManager().Query(func(d driver.Driver) bool {
+		return d.Info().Label == "Fake:0001:Camera"
+	})
+	if len(drivers) != 1 {
+		t.Fatalf("expected 1 driver, but got %d", len(drivers))
+	}
+	if drivers[0].Info().DeviceType != driver.Camera {
+		t.Fatalf("expected device type to be %s, but got %s", driver.Camera, drivers[0].Info().DeviceType)
+	}
+}
+
+func TestAdapter(t *testing.T) {
+	device := &fakeDevice{
+		formats: []Format{
+			{Width: 4, Height: 2, FrameFormat: frame.FormatBayerRGGB8, FrameRate: 60},
+			// Formats without decoders are hidden
+			{Width: 4, Height: 2, FrameFormat: "BG12"},
+		},
+	}
+	a := newAdapter(&fakeBackend{device: device}, "0001")
+	if err := a.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	properties := a.Properties()
+	if len(properties) != 1 {
+		t.Fatalf("expected 1 property, but got %d", len(properties))
+	}
+	if properties[0].FrameFormat != frame.FormatBayerRGGB8 {
+		t.Fatalf("expected frame format to be %s, but got %s", frame.FormatBayerRGGB8, properties[0].FrameFormat)
+	}
+
+	if _, err := a.VideoRecord(prop.Media{Video: prop.Video{Width: 8, Height: 8, FrameFormat: frame.FormatBayerRGGB8}}); err == nil {
+		t.Fatal("expected unsupported resolution to be rejected")
+	}
+
+	p := properties[0]
+	p.FrameRate = 30
+	r, err := a.VideoRecord(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if device.started == nil || device.started.FrameRate != 30 {
+		t.Fatalf("expected acquisition to be started at 30 fps, but got %+v", device.started)
+	}
+
+	img, release, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 4, 2) {
+		t.Fatalf("expected bounds to be %v, but got %v", image.Rect(0, 0, 4, 2), img.Bounds())
+	}
+	release()
+
+	if err := a.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !device.closed || device.started != nil {
+		t.Fatal("expected device to be stopped and closed")
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Fatalf("expected %v after close, but got %v", io.EOF, err)
+	}
+}
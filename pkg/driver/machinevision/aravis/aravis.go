@@ -0,0 +1,292 @@
+// +build aravis
+
+package aravis
+
+// #cgo pkg-config: aravis-0.8
+// #include <stdlib.h>
+// #include <arv.h>
+//
+// // Aravis reports errors with GError. The helpers below turn them into messages the caller has to
+// // free, since GError is awkward to handle from Go.
+// static char *takeError(GError *err) {
+//   char *msg;
+//   if (err == NULL) {
+//     return NULL;
+//   }
+//   msg = g_strdup(err->message);
+//   g_error_free(err);
+//   return msg;
+// }
+//
+// static ArvCamera *openCamera(const char *id, char **msg) {
+//   GError *err = NULL;
+//   ArvCamera *camera = arv_camera_new(id, &err);
+//   *msg = takeError(err);
+//   return camera;
+// }
+//
+// static gint64 *pixelFormats(ArvCamera *camera, guint *n, char **msg) {
+//   GError *err = NULL;
+//   gint64 *formats = arv_camera_dup_available_pixel_formats(camera, n, &err);
+//   *msg = takeError(err);
+//   return formats;
+// }
+//
+// static void sensorBounds(ArvCamera *camera, gint *width, gint *height, double *frameRate, char **msg) {
+//   GError *err = NULL;
+//   gint min;
+//   double minFrameRate;
+//   arv_camera_get_width_bounds(camera, &min, width, &err);
+//   if (err == NULL) {
+//     arv_camera_get_height_bounds(camera, &min, height, &err);
+//   }
+//   if (err == NULL) {
+//     arv_camera_get_frame_rate_bounds(camera, &minFrameRate, frameRate, &err);
+//   }
+//   *msg = takeError(err);
+// }
+//
+// static ArvStream *startStream(ArvCamera *camera, gint width, gint height, ArvPixelFormat format,
+//     double frameRate, guint nBuffers, char **msg) {
+//   GError *err = NULL;
+//   ArvStream *stream = NULL;
+//   guint payload, i;
+//   arv_camera_set_acquisition_mode(camera, ARV_ACQUISITION_MODE_CONTINUOUS, &err);
+//   if (err == NULL) {
+//     arv_camera_set_region(camera, 0, 0, width, height, &err);
+//   }
+//   if (err == NULL) {
+//     arv_camera_set_pixel_format(camera, format, &err);
+//   }
+//   if (err == NULL && frameRate > 0) {
+//     arv_camera_set_frame_rate(camera, frameRate, &err);
+//   }
+//   if (err == NULL) {
+//     payload = arv_camera_get_payload(camera, &err);
+//   }
+//   if (err == NULL) {
+//     stream = arv_camera_create_stream(camera, NULL, NULL, &err);
+//   }
+//   if (err == NULL) {
+//     for (i = 0; i < nBuffers; i++) {
+//       arv_stream_push_buffer(stream, arv_buffer_new(payload, NULL));
+//     }
+//     arv_camera_start_acquisition(camera, &err);
+//   }
+//   if (err != NULL && stream != NULL) {
+//     g_object_unref(stream);
+//     stream = NULL;
+//   }
+//   *msg = takeError(err);
+//   return stream;
+// }
+//
+// static void stopStream(ArvCamera *camera, ArvStream *stream) {
+//   arv_camera_stop_acquisition(camera, NULL);
+//   g_object_unref(stream);
+// }
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/driver/machinevision"
+	"github.com/pion/mediadevices/pkg/frame"
+)
+
+const (
+	// numBuffers is how many buffers are queued to the stream. Aravis drops frames when all buffers
+	// are in use, so late frames are discarded like in other camera drivers.
+	numBuffers = 4
+	// readTimeout is the frame acquisition timeout in microseconds
+	readTimeout = 5000000
+)
+
+var errReadTimeout = errors.New("read timeout")
+
+// Reference: https://github.com/AravisProject/aravis/blob/main/src/arvenums.h
+var pixelFormats = map[C.ArvPixelFormat]frame.Format{
+	C.ARV_PIXEL_FORMAT_MONO_8:              frame.FormatGRAY8,
+	C.ARV_PIXEL_FORMAT_BAYER_RG_8:          frame.FormatBayerRGGB8,
+	C.ARV_PIXEL_FORMAT_BAYER_BG_8:          frame.FormatBayerBGGR8,
+	C.ARV_PIXEL_FORMAT_BAYER_GR_8:          frame.FormatBayerGRBG8,
+	C.ARV_PIXEL_FORMAT_BAYER_GB_8:          frame.FormatBayerGBRG8,
+	C.ARV_PIXEL_FORMAT_YUV_422_PACKED:      frame.FormatUYVY,
+	C.ARV_PIXEL_FORMAT_YUV_422_YUYV_PACKED: frame.FormatYUY2,
+}
+
+func init() {
+	machinevision.Register(backend{})
+}
+
+func takeError(msg *C.char) error {
+	if msg == nil {
+		return nil
+	}
+	defer C.g_free(C.gpointer(unsafe.Pointer(msg)))
+	return errors.New(C.GoString(msg))
+}
+
+type backend struct{}
+
+func (backend) Name() string {
+	return "Aravis"
+}
+
+func (backend) Devices() ([]machinevision.DeviceInfo, error) {
+	C.arv_update_device_list()
+
+	n := int(C.arv_get_n_devices())
+	devices := make([]machinevision.DeviceInfo, 0, n)
+	for i := 0; i < n; i++ {
+		id := C.arv_get_device_id(C.guint(i))
+		if id == nil {
+			continue
+		}
+		devices = append(devices, machinevision.DeviceInfo{
+			ID:    C.GoString(id),
+			Label: C.GoString(C.arv_get_device_model(C.guint(i))),
+		})
+	}
+
+	return devices, nil
+}
+
+func (backend) Open(id string) (machinevision.Device, error) {
+	cID := C.CString(id)
+	defer C.free(unsafe.Pointer(cID))
+
+	var msg *C.char
+	camera := C.openCamera(cID, &msg)
+	if err := takeError(msg); err != nil {
+		return nil, err
+	}
+	if camera == nil {
+		return nil, fmt.Errorf("failed to open %s", id)
+	}
+
+	d := &device{camera: camera}
+	if err := d.queryFormats(); err != nil {
+		C.g_object_unref(C.gpointer(camera))
+		return nil, err
+	}
+	return d, nil
+}
+
+type device struct {
+	camera  *C.ArvCamera
+	stream  *C.ArvStream
+	buffer  *C.ArvBuffer
+	formats []machinevision.Format
+	// reversedFormats maps frame formats to Aravis pixel formats
+	reversedFormats map[frame.Format]C.ArvPixelFormat
+}
+
+// queryFormats lists the supported pixel formats at full sensor size, since the application usually
+// configures an industrial camera's region of interest.
+func (d *device) queryFormats() error {
+	var width, height C.gint
+	var frameRate C.double
+	var msg *C.char
+	C.sensorBounds(d.camera, &width, &height, &frameRate, &msg)
+	if err := takeError(msg); err != nil {
+		return err
+	}
+
+	var n C.guint
+	formats := C.pixelFormats(d.camera, &n, &msg)
+	if err := takeError(msg); err != nil {
+		return err
+	}
+	defer C.g_free(C.gpointer(unsafe.Pointer(formats)))
+
+	d.reversedFormats = make(map[frame.Format]C.ArvPixelFormat)
+	for _, pf := range (*[1 << 16]C.gint64)(unsafe.Pointer(formats))[:n:n] {
+		f, ok := pixelFormats[C.ArvPixelFormat(pf)]
+		if !ok {
+			continue
+		}
+		d.reversedFormats[f] = C.ArvPixelFormat(pf)
+		d.formats = append(d.formats, machinevision.Format{
+			Width:       int(width),
+			Height:      int(height),
+			FrameFormat: f,
+			FrameRate:   float32(frameRate),
+		})
+	}
+
+	return nil
+}
+
+func (d *device) Formats() []machinevision.Format {
+	return d.formats
+}
+
+func (d *device) Start(f machinevision.Format) error {
+	pf, ok := d.reversedFormats[f.FrameFormat]
+	if !ok {
+		return fmt.Errorf("%s is not supported", f.FrameFormat)
+	}
+
+	var msg *C.char
+	stream := C.startStream(d.camera, C.gint(f.Width), C.gint(f.Height), pf, C.double(f.FrameRate), numBuffers, &msg)
+	if err := takeError(msg); err != nil {
+		return err
+	}
+	d.stream = stream
+	return nil
+}
+
+func (d *device) Read() ([]byte, error) {
+	if d.stream == nil {
+		return nil, errors.New("acquisition is not started")
+	}
+
+	// The caller no longer uses the previous buffer
+	d.releaseBuffer()
+
+	for {
+		buffer := C.arv_stream_timeout_pop_buffer(d.stream, readTimeout)
+		if buffer == nil {
+			return nil, errReadTimeout
+		}
+
+		if C.arv_buffer_get_status(buffer) != C.ARV_BUFFER_STATUS_SUCCESS {
+			// Incomplete frames, e.g. packet loss on GigE, are requeued
+			C.arv_stream_push_buffer(d.stream, buffer)
+			continue
+		}
+
+		var size C.size_t
+		data := C.arv_buffer_get_data(buffer, &size)
+		d.buffer = buffer
+		// The next Read requeues the buffer, so it's not copied here
+		return (*[1 << 30]byte)(data)[:size:size], nil
+	}
+}
+
+func (d *device) releaseBuffer() {
+	if d.buffer != nil {
+		C.arv_stream_push_buffer(d.stream, d.buffer)
+		d.buffer = nil
+	}
+}
+
+func (d *device) Stop() error {
+	if d.stream == nil {
+		return nil
+	}
+
+	d.releaseBuffer()
+	C.stopStream(d.camera, d.stream)
+	d.stream = nil
+	return nil
+}
+
+func (d *device) Close() error {
+	d.Stop()
+	C.g_object_unref(C.gpointer(d.camera))
+	return nil
+}
@@ -0,0 +1,8 @@
+// Package aravis registers GigE Vision and USB3 Vision cameras as video drivers using Aravis.
+//
+// Aravis is linked with cgo, and the package is only built with the aravis build tag:
+//
+//	go build -tags aravis
+//
+// Reference: https://github.com/AravisProject/aravis
+package aravis
@@ -0,0 +1,76 @@
+// Package machinevision adapts industrial cameras, e.g. GigE Vision and USB3 Vision cameras, to video drivers.
+//
+// Cameras are controlled by a Backend, usually a binding of the vendor SDK or a GenICam library.
+// Bayer and monochrome frames are handled by pkg/frame's decoders like any other frame format.
+package machinevision
+
+import (
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+)
+
+// Format is a Device acquisition mode
+type Format struct {
+	Width       int
+	Height      int
+	FrameFormat frame.Format
+	// FrameRate is the acquisition's maximum frame rate. 0 means the device decides the frame rate.
+	FrameRate float32
+}
+
+// DeviceInfo describes a device discovered by a Backend
+type DeviceInfo struct {
+	// ID is a unique string passed to Backend.Open, e.g. a serial number
+	ID string
+	// Label is the device's human-readable name
+	Label string
+}
+
+// Device is a camera opened by a Backend
+type Device interface {
+	// Formats returns the acquisition modes the device supports
+	Formats() []Format
+	// Start starts the acquisition with f
+	Start(f Format) error
+	// Read blocks until the next frame is acquired. The returned buffer is only valid until the next Read or Stop.
+	Read() ([]byte, error)
+	// Stop stops the acquisition. Start might be called again after Stop.
+	Stop() error
+	// Close releases the device
+	Close() error
+}
+
+// Backend discovers and opens machine vision cameras
+type Backend interface {
+	// Name is used as the driver labels' prefix
+	Name() string
+	// Devices returns the cameras currently available
+	Devices() ([]DeviceInfo, error)
+	// Open opens the camera with id
+	Open(id string) (Device, error)
+}
+
+// Register discovers b's cameras, and registers them with the driver manager as cameras
+func Register(b Backend) error {
+	devices, err := b.Devices()
+	if err != nil {
+		return err
+	}
+
+	manager := driver.GetManager()
+	for _, info := range devices {
+		label := b.Name() + ":" + info.ID
+		if info.Label != "" {
+			label += ":" + info.Label
+		}
+		err := manager.Register(newAdapter(b, info.ID), driver.Info{
+			Label:      label,
+			DeviceType: driver.Camera,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
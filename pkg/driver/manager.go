@@ -1,5 +1,15 @@
 package driver
 
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	errNotRegistered = errors.New("adapter is not registered")
+	errNotFound      = errors.New("device is not found")
+)
+
 // FilterFn is being used to decide if a driver should be included in the
 // query result.
 type FilterFn func(Driver) bool
@@ -20,6 +30,22 @@ func FilterAudioRecorder() FilterFn {
 	}
 }
 
+// FilterAudioPlayer return a filter function to get a list of registered AudioPlayers
+func FilterAudioPlayer() FilterFn {
+	return func(d Driver) bool {
+		_, ok := d.(AudioPlayer)
+		return ok
+	}
+}
+
+// FilterVideoPlayer return a filter function to get a list of registered VideoPlayers
+func FilterVideoPlayer() FilterFn {
+	return func(d Driver) bool {
+		_, ok := d.(VideoPlayer)
+		return ok
+	}
+}
+
 // FilterID return a filter function to get registered drivers which have given ID
 func FilterID(id string) FilterFn {
 	return func(d Driver) bool {
@@ -55,11 +81,18 @@ func FilterNot(filter FilterFn) FilterFn {
 
 // Manager is a singleton to manage multiple drivers and their states
 type Manager struct {
+	mu      sync.RWMutex
 	drivers map[string]Driver
+	// adapters are the registered adapters, keyed by their drivers' IDs.
+	adapters map[string]Adapter
+	// aliases maps driver IDs to the drivers' other stable IDs, which Resolve uses.
+	aliases map[string][]string
 }
 
 var manager = &Manager{
-	drivers: make(map[string]Driver),
+	drivers:  make(map[string]Driver),
+	adapters: make(map[string]Adapter),
+	aliases:  make(map[string][]string),
 }
 
 // GetManager gets manager singleton instance
@@ -67,15 +100,75 @@ func GetManager() *Manager {
 	return manager
 }
 
-// Register registers adapter to be discoverable by Query
+// Register registers adapter to be discoverable by Query. The driver's ID is generated from info, so it stays
+// the same for the device across reboots and re-enumeration, see Hardware. A random ID is used if the device
+// can't be told apart from registered ones.
 func (m *Manager) Register(a Adapter, info Info) error {
-	d := wrapAdapter(a, info)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := stableIDs(info)
+	var d Driver
+	for i, id := range ids {
+		if _, ok := m.drivers[id]; !ok {
+			d = wrapAdapterWithID(a, info, id)
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if d == nil {
+		d = wrapAdapter(a, info)
+	}
+
 	m.drivers[d.ID()] = d
+	m.adapters[d.ID()] = a
+	if m.aliases == nil {
+		m.aliases = make(map[string][]string)
+	}
+	m.aliases[d.ID()] = ids
 	return nil
 }
 
+// Resolve returns the driver of the device that had id, e.g. a DeviceID stored as a user preference.
+// The device is found even if its ID changed because some attributes changed, e.g. the device is plugged
+// into another port, as long as one of the other attributes is kept.
+func (m *Manager) Resolve(id string) (Driver, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if d, ok := m.drivers[id]; ok {
+		return d, nil
+	}
+	for driverID, aliases := range m.aliases {
+		for _, alias := range aliases {
+			if alias == id {
+				return m.drivers[driverID], nil
+			}
+		}
+	}
+	return nil, errNotFound
+}
+
+// Unregister removes the adapter's driver, e.g. when the device is unplugged. a has to be the same value
+// passed to Register. Tracks already created from the driver are kept as is.
+func (m *Manager) Unregister(a Adapter) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, adapter := range m.adapters {
+		if adapter == a {
+			delete(m.drivers, id)
+			delete(m.adapters, id)
+			delete(m.aliases, id)
+			return nil
+		}
+	}
+	return errNotRegistered
+}
+
 // Query queries by using f to filter drivers, and simply return the filtered results.
 func (m *Manager) Query(f FilterFn) []Driver {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	results := make([]Driver, 0)
 	for _, d := range m.drivers {
 		if ok := f(d); ok {
@@ -40,3 +40,103 @@ func TestFilterAnd(t *testing.T) {
 		t.Error("FilterAnd(filterTrue, filterTrue, filterTrue)() must be true")
 	}
 }
+
+func TestManagerUnregister(t *testing.T) {
+	m := &Manager{
+		drivers:  make(map[string]Driver),
+		adapters: make(map[string]Adapter),
+	}
+	a, b := &audioAdapterMock{}, &audioAdapterBrokenMock{}
+	m.Register(a, Info{Label: "a"})
+	m.Register(b, Info{Label: "b"})
+
+	if err := m.Unregister(a); err != nil {
+		t.Fatal(err)
+	}
+	drivers := m.Query(func(Driver) bool { return true })
+	if len(drivers) != 1 || drivers[0].Info().Label != "b" {
+		t.Fatalf("expected only b to be registered, but got %v", drivers)
+	}
+
+	if err := m.Unregister(a); err != errNotRegistered {
+		t.Fatalf("expected %v, but got %v", errNotRegistered, err)
+	}
+}
+
+func TestManagerResolve(t *testing.T) {
+	newManager := func() *Manager {
+		return &Manager{
+			drivers:  make(map[string]Driver),
+			adapters: make(map[string]Adapter),
+		}
+	}
+	info := Info{
+		Label:      "video0",
+		DeviceType: Camera,
+		Hardware:   Hardware{Serial: "1234", VendorID: "046d", ProductID: "0825", PortPath: "1-2"},
+	}
+
+	// The ID is kept across reboots, where devices are registered again.
+	a := &videoAdapterMock{}
+	m := newManager()
+	m.Register(a, info)
+	drivers := m.Query(func(Driver) bool { return true })
+	if len(drivers) != 1 {
+		t.Fatalf("expected 1 driver, but got %d", len(drivers))
+	}
+	id := drivers[0].ID()
+
+	m = newManager()
+	moved := info
+	moved.Label = "video2"
+	moved.Hardware.PortPath = "1-3.1"
+	m.Register(a, moved)
+	d, err := m.Resolve(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID() != id {
+		t.Fatalf("expected the ID to be kept with the same serial, but got %s", d.ID())
+	}
+
+	// Devices without a serial are identified by port.
+	noSerial := info
+	noSerial.Hardware.Serial = ""
+	m = newManager()
+	m.Register(a, noSerial)
+	id = m.Query(func(Driver) bool { return true })[0].ID()
+
+	m = newManager()
+	renamed := noSerial
+	renamed.Label = "video4"
+	m.Register(a, renamed)
+	if d, err := m.Resolve(id); err != nil || d.ID() != id {
+		t.Fatalf("expected the ID to be kept with the same port, but got %v", err)
+	}
+
+	// The same devices get other IDs, which are resolved from the stored one.
+	m = newManager()
+	b := &videoAdapterBrokenMock{}
+	m.Register(a, info)
+	m.Register(b, info)
+	drivers = m.Query(func(Driver) bool { return true })
+	if len(drivers) != 2 || drivers[0].ID() == drivers[1].ID() {
+		t.Fatalf("expected 2 drivers with the different IDs, but got %v", drivers)
+	}
+	ids := stableIDs(info)
+	if err := m.Unregister(a); err != nil {
+		t.Fatal(err)
+	}
+	// b still has the serial, and is found by the ID given to a.
+	d, err = m.Resolve(ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ID() != ids[1] {
+		t.Fatalf("expected %s, but got %s", ids[1], d.ID())
+	}
+
+	if _, err := m.Resolve("unknown"); err != errNotFound {
+		t.Fatalf("expected %v, but got %v", errNotFound, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 	"unsafe"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/mediadevices/pkg/wave/mixer"
 )
 
 const (
@@ -21,17 +23,42 @@ const (
 	// TODO: should replace this with a more flexible approach
 	sampleRateStep    = 1000
 	initialBufferSize = 1024
+	// defaultLabel is the label of the device that follows the system's default device.
+	defaultLabel = "default"
 )
 
+// sampleRates are the sampling rates to negotiate with the device. The first one is the default.
+var sampleRates = []int{48000, 44100, 88200, 96000}
+
+// frameDurations are the durations of chunks from the device, which are common audio codec frame
+// durations (ptime). The first one is the default.
+var frameDurations = []time.Duration{
+	20 * time.Millisecond,
+	10 * time.Millisecond,
+	40 * time.Millisecond,
+	60 * time.Millisecond,
+}
+
 var logger = logging.NewLogger("mediadevices/driver/microphone")
 var ctx *malgo.AllocatedContext
 var hostEndian binary.ByteOrder
 var (
 	errUnsupportedFormat = errors.New("the provided audio format is not supported")
+	errDeviceNotFound    = errors.New("device not found")
 )
 
+// fallbackFormats are used for devices whose formats can't be queried. miniaudio converts samples
+// from the device's native format.
+var fallbackFormats = []malgo.FormatType{malgo.FormatF32, malgo.FormatS16}
+
 type microphone struct {
 	malgo.DeviceInfo
+	// isDefault is true for the device that follows the system's default device. The stream moves
+	// to the new default device when it changes, e.g. when a headset is plugged in.
+	isDefault bool
+	// channels are the 0-based indexes of the channels selected by RegisterChannels. Every channel is
+	// captured if it's nil.
+	channels  []int
 	chunkChan chan []byte
 }
 
@@ -51,15 +78,30 @@ func init() {
 
 	for _, device := range devices {
 		info, err := ctx.DeviceInfo(malgo.Capture, device.ID, malgo.Shared)
-		if err == nil {
-			priority := driver.PriorityNormal
-			if info.IsDefault > 0 {
-				priority = driver.PriorityHigh
-			}
-			driver.GetManager().Register(newMicrophone(info), driver.Info{
-				Label:      device.ID.String(),
+		if err != nil {
+			// Some backends, e.g. CoreAudio for aggregate devices, can't report every device's details.
+			// Those devices can still be captured with the fallback formats.
+			logger.Debugf("failed to query device %s: %s\n", device.ID.String(), err)
+			info = device
+		}
+
+		priority := driver.PriorityNormal
+		if info.IsDefault > 0 && !followsDefaultDevice {
+			priority = driver.PriorityHigh
+		}
+		driver.GetManager().Register(newMicrophone(info), driver.Info{
+			Label:      device.ID.String(),
+			DeviceType: driver.Microphone,
+			Priority:   priority,
+		})
+
+		if info.IsDefault > 0 && followsDefaultDevice {
+			m := newMicrophone(info)
+			m.isDefault = true
+			driver.GetManager().Register(m, driver.Info{
+				Label:      defaultLabel,
 				DeviceType: driver.Microphone,
-				Priority:   priority,
+				Priority:   driver.PriorityHigh,
 			})
 		}
 	}
@@ -81,6 +123,54 @@ func newMicrophone(info malgo.DeviceInfo) *microphone {
 	}
 }
 
+// RegisterChannels registers a device that captures channels of the device with label, e.g. {2, 3} for
+// the third and fourth inputs of a multichannel interface or aggregate device as stereo, and returns its
+// device ID. The channels are 0-based indexes.
+func RegisterChannels(label string, channels ...int) (string, error) {
+	if len(channels) == 0 {
+		return "", errors.New("no channels are selected")
+	}
+
+	devices, err := ctx.Devices(malgo.Capture)
+	if err != nil {
+		return "", err
+	}
+	var info *malgo.DeviceInfo
+	for i := range devices {
+		if devices[i].ID.String() != label {
+			continue
+		}
+		info = &devices[i]
+		if detail, err := ctx.DeviceInfo(malgo.Capture, devices[i].ID, malgo.Shared); err == nil {
+			info = &detail
+		}
+		break
+	}
+	if info == nil {
+		return "", errDeviceNotFound
+	}
+	for _, ch := range channels {
+		if ch < 0 || (info.MaxChannels > 0 && uint32(ch) >= info.MaxChannels) {
+			return "", fmt.Errorf("channel %d is out of range of %d channels", ch, info.MaxChannels)
+		}
+	}
+
+	selectedLabel := fmt.Sprintf("%s:%v", label, channels)
+	manager := driver.GetManager()
+	registered := manager.Query(func(d driver.Driver) bool { return d.Info().Label == selectedLabel })
+	if len(registered) == 0 {
+		m := newMicrophone(*info)
+		m.channels = append([]int(nil), channels...)
+		manager.Register(m, driver.Info{
+			Label:      selectedLabel,
+			DeviceType: driver.Microphone,
+			Priority:   driver.PriorityNormal,
+		})
+		registered = manager.Query(func(d driver.Driver) bool { return d.Info().Label == selectedLabel })
+	}
+	return registered[0].ID(), nil
+}
+
 func (m *microphone) Open() error {
 	m.chunkChan = make(chan []byte, 1)
 	return nil
@@ -94,6 +184,19 @@ func (m *microphone) Close() error {
 	return nil
 }
 
+// Probe returns the application's microphone permission, which privacy settings give
+// on macOS and Windows.
+func (m *microphone) Probe() (driver.Probe, error) {
+	permission, err := probePermission()
+	return driver.Probe{Permission: permission}, err
+}
+
+// RequestPermission prompts the user for microphone permission on macOS if they haven't been asked
+// yet.
+func (m *microphone) RequestPermission() (driver.Permission, error) {
+	return requestPermission()
+}
+
 func (m *microphone) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
 	var config malgo.DeviceConfig
 	var callbacks malgo.DeviceCallbacks
@@ -107,12 +210,38 @@ func (m *microphone) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
 		return nil, err
 	}
 
+	// The selected channels are picked from all of the device's channels.
+	captureChannels := inputProp.ChannelCount
+	var selector *mixer.ChannelSelector
+	if m.channels != nil {
+		captureChannels = int(m.MaxChannels)
+		for _, ch := range m.channels {
+			if ch >= captureChannels {
+				captureChannels = ch + 1
+			}
+		}
+		selector = &mixer.ChannelSelector{Channels: m.channels}
+	}
+
 	config.DeviceType = malgo.Capture
 	config.PerformanceProfile = malgo.LowLatency
-	config.Capture.Channels = uint32(inputProp.ChannelCount)
+	if !m.isDefault {
+		// The default device is opened without an ID, so miniaudio follows changes of the default.
+		config.Capture.DeviceID = m.ID.Pointer()
+	}
+	config.Capture.Channels = uint32(captureChannels)
 	config.SampleRate = uint32(inputProp.SampleRate)
+	if inputProp.Latency > 0 {
+		// Chunks from the device last one period.
+		config.PeriodSizeInMilliseconds = uint32(inputProp.Latency / time.Millisecond)
+	}
+	configureDevice(&config)
 	if inputProp.SampleSize == 4 && inputProp.IsFloat {
 		config.Capture.Format = malgo.FormatF32
+	} else if inputProp.SampleSize == 4 && !inputProp.IsFloat {
+		config.Capture.Format = malgo.FormatS32
+	} else if inputProp.SampleSize == 3 && !inputProp.IsFloat {
+		config.Capture.Format = malgo.FormatS24
 	} else if inputProp.SampleSize == 2 && !inputProp.IsFloat {
 		config.Capture.Format = malgo.FormatS16
 	} else {
@@ -124,6 +253,13 @@ func (m *microphone) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
 	}
 	callbacks.Data = onRecvChunk
 
+	// The system stops the device when it's unplugged or its format changes.
+	stopped := make(chan struct{})
+	var stopOnce sync.Once
+	callbacks.Stop = func() {
+		stopOnce.Do(func() { close(stopped) })
+	}
+
 	device, err := malgo.InitDevice(ctx.Context, config, callbacks)
 	if err != nil {
 		return nil, err
@@ -134,25 +270,54 @@ func (m *microphone) AudioRecord(inputProp prop.Media) (audio.Reader, error) {
 		return nil, err
 	}
 
+	// Decode into the pool, so chunks are reused once readers release them.
+	var pool wave.Pool
 	var reader audio.Reader = audio.ReaderFunc(func() (wave.Audio, func(), error) {
-		chunk, ok := <-m.chunkChan
+		var chunk []byte
+		var ok bool
+		select {
+		case chunk, ok = <-m.chunkChan:
+		case <-stopped:
+		}
 		if !ok {
 			device.Stop()
 			device.Uninit()
 			return nil, func() {}, io.EOF
 		}
 
-		decodedChunk, err := decoder.Decode(hostEndian, chunk, inputProp.ChannelCount)
+		decodedChunk, release, err := pool.Decode(decoder, hostEndian, chunk, captureChannels)
+		if err != nil {
+			return nil, func() {}, err
+		}
 		// FIXME: the decoder should also fill this information
 		switch decodedChunk := decodedChunk.(type) {
 		case *wave.Float32Interleaved:
 			decodedChunk.Size.SamplingRate = inputProp.SampleRate
 		case *wave.Int16Interleaved:
 			decodedChunk.Size.SamplingRate = inputProp.SampleRate
+		case *wave.Int32Interleaved:
+			decodedChunk.Size.SamplingRate = inputProp.SampleRate
 		default:
 			panic("unsupported format")
 		}
-		return decodedChunk, func() {}, err
+
+		if selector != nil {
+			info := decodedChunk.ChunkInfo()
+			info.Channels = len(m.channels)
+			selected, releaseSelected, err := pool.Get(wave.TypeOf(decodedChunk), info)
+			if err != nil {
+				release()
+				return nil, func() {}, err
+			}
+			err = selector.Mix(selected, decodedChunk)
+			release()
+			if err != nil {
+				releaseSelected()
+				return nil, func() {}, err
+			}
+			return selected, releaseSelected, nil
+		}
+		return decodedChunk, release, nil
 	})
 
 	return reader, nil
@@ -168,37 +333,81 @@ func (m *microphone) Properties() []prop.Media {
 		isBigEndian = true
 	}
 
-	for ch := m.MinChannels; ch <= m.MaxChannels; ch++ {
-		// FIXME: Currently support 48kHz only. We need to implement a resampler first.
-		// for sampleRate := m.MinSampleRate; sampleRate <= m.MaxSampleRate; sampleRate += sampleRateStep {
-		sampleRate := 48000
-		for i := 0; i < int(m.FormatCount); i++ {
-			format := m.Formats[i]
-
-			supportedProp := prop.Media{
-				Audio: prop.Audio{
-					ChannelCount: int(ch),
-					SampleRate:   int(sampleRate),
-					IsBigEndian:  isBigEndian,
-					// miniaudio only supports interleaved at the moment
-					IsInterleaved: true,
-					// FIXME: should change this to a less discrete value
-					Latency: time.Millisecond * 20,
-				},
-			}
+	info := m.DeviceInfo
+	if mode := deviceShareMode(); mode != malgo.Shared {
+		// Only the device's native formats are available in exclusive mode.
+		if detail, err := ctx.DeviceInfo(malgo.Capture, m.ID, mode); err == nil {
+			info = detail
+		}
+	}
+
+	minChannels, maxChannels := int(info.MinChannels), int(info.MaxChannels)
+	if m.channels != nil {
+		minChannels, maxChannels = len(m.channels), len(m.channels)
+	} else if maxChannels == 0 {
+		// The channels are unknown
+		minChannels, maxChannels = 1, 2
+	}
+	formats := make([]malgo.FormatType, info.FormatCount)
+	for i := range formats {
+		formats[i] = malgo.FormatType(info.Formats[i])
+	}
+	if len(formats) == 0 {
+		formats = fallbackFormats
+	}
 
-			switch malgo.FormatType(format) {
-			case malgo.FormatF32:
-				supportedProp.SampleSize = 4
-				supportedProp.IsFloat = true
-			case malgo.FormatS16:
-				supportedProp.SampleSize = 2
-				supportedProp.IsFloat = false
+	for ch := minChannels; ch <= maxChannels; ch++ {
+		for _, sampleRate := range sampleRates {
+			if !supportsSampleRate(info, sampleRate) {
+				continue
 			}
+			for _, latency := range frameDurations {
+				for _, format := range formats {
+					supportedProp := prop.Media{
+						Audio: prop.Audio{
+							ChannelCount: ch,
+							SampleRate:   sampleRate,
+							IsBigEndian:  isBigEndian,
+							// miniaudio only supports interleaved at the moment
+							IsInterleaved: true,
+							Latency:       latency,
+						},
+					}
+
+					switch format {
+					case malgo.FormatF32:
+						supportedProp.SampleSize = 4
+						supportedProp.IsFloat = true
+					case malgo.FormatS32:
+						supportedProp.SampleSize = 4
+						supportedProp.IsFloat = false
+					case malgo.FormatS24:
+						supportedProp.SampleSize = 3
+						supportedProp.IsFloat = false
+					case malgo.FormatS16:
+						supportedProp.SampleSize = 2
+						supportedProp.IsFloat = false
+					default:
+						// The decoder doesn't support the format
+						continue
+					}
 
-			supportedProps = append(supportedProps, supportedProp)
+					supportedProps = append(supportedProps, supportedProp)
+				}
+			}
 		}
-		// }
 	}
 	return supportedProps
 }
+
+// supportsSampleRate reports whether the sampling rate is within the device's range. The range is unknown
+// if the backend doesn't report it.
+func supportsSampleRate(info malgo.DeviceInfo, sampleRate int) bool {
+	if info.MinSampleRate > 0 && uint32(sampleRate) < info.MinSampleRate {
+		return false
+	}
+	if info.MaxSampleRate > 0 && uint32(sampleRate) > info.MaxSampleRate {
+		return false
+	}
+	return true
+}
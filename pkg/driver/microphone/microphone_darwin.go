@@ -0,0 +1,48 @@
+package microphone
+
+import (
+	"github.com/gen2brain/malgo"
+	"github.com/pion/mediadevices/pkg/avfoundation"
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// followsDefaultDevice is true since miniaudio's CoreAudio backend moves the default device's streams
+// to the new default device. The default device is registered as "default" with high priority, so
+// tracks follow the input selected in system settings.
+const followsDefaultDevice = true
+
+func configureDevice(config *malgo.DeviceConfig) {}
+
+func deviceShareMode() malgo.ShareMode {
+	return malgo.Shared
+}
+
+// probePermission returns AVFoundation's authorization, which also applies to CoreAudio.
+func probePermission() (driver.Permission, error) {
+	status, err := avfoundation.Authorization(avfoundation.Audio)
+	if err != nil {
+		return driver.PermissionUnknown, err
+	}
+	return permission(status), nil
+}
+
+func requestPermission() (driver.Permission, error) {
+	status, err := avfoundation.RequestAuthorization(avfoundation.Audio)
+	if err != nil {
+		return driver.PermissionUnknown, err
+	}
+	return permission(status), nil
+}
+
+func permission(status avfoundation.AuthorizationStatus) driver.Permission {
+	switch status {
+	case avfoundation.AuthorizationAuthorized:
+		return driver.PermissionGranted
+	case avfoundation.AuthorizationDenied:
+		return driver.PermissionDenied
+	case avfoundation.AuthorizationRestricted:
+		return driver.PermissionRestricted
+	default:
+		return driver.PermissionPrompt
+	}
+}
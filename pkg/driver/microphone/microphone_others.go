@@ -0,0 +1,28 @@
+// +build !darwin,!windows
+
+package microphone
+
+import (
+	"github.com/gen2brain/malgo"
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// followsDefaultDevice is false since other backends don't move streams when the default device
+// changes. The current default device gets high priority instead.
+const followsDefaultDevice = false
+
+func configureDevice(config *malgo.DeviceConfig) {}
+
+func deviceShareMode() malgo.ShareMode {
+	return malgo.Shared
+}
+
+// probePermission returns PermissionUnknown since other systems don't have microphone permissions
+// other than device file permissions.
+func probePermission() (driver.Permission, error) {
+	return driver.PermissionUnknown, nil
+}
+
+func requestPermission() (driver.Permission, error) {
+	return driver.PermissionUnknown, nil
+}
@@ -0,0 +1,22 @@
+package microphone
+
+import (
+	"testing"
+
+	"github.com/gen2brain/malgo"
+)
+
+func TestSupportsSampleRate(t *testing.T) {
+	unknown := malgo.DeviceInfo{}
+	if !supportsSampleRate(unknown, 8000) || !supportsSampleRate(unknown, 192000) {
+		t.Fatal("expected any sampling rate to be supported when the range is unknown")
+	}
+
+	// e.g. a device's native range in exclusive mode
+	native := malgo.DeviceInfo{MinSampleRate: 44100, MaxSampleRate: 48000}
+	for sampleRate, expected := range map[int]bool{16000: false, 44100: true, 48000: true, 96000: false} {
+		if supported := supportsSampleRate(native, sampleRate); supported != expected {
+			t.Fatalf("expected %d to be supported: %v, but got %v", sampleRate, expected, supported)
+		}
+	}
+}
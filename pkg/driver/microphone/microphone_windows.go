@@ -0,0 +1,82 @@
+package microphone
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gen2brain/malgo"
+	"github.com/pion/mediadevices/internal/privacy"
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// followsDefaultDevice is false to keep streams on the device selected at the start.
+const followsDefaultDevice = false
+
+// WASAPIOptions controls WASAPI capture streams, which give up shared mode's convenience for
+// lower latency and unprocessed samples.
+//
+// IAudioClient2 raw streams (AUDCLNT_STREAMOPTIONS_RAW) aren't available in malgo's miniaudio.
+// Use Exclusive to bypass the audio engine, which also bypasses system effects.
+type WASAPIOptions struct {
+	// Exclusive opens devices in exclusive mode. Samples are delivered from the device without the audio
+	// engine's mixing, conversions and effects, and other applications can't use the device while it's
+	// captured. Only the devices' native formats are listed in the properties.
+	Exclusive bool
+	// BufferDuration is the requested device buffer duration, which is rounded up to a multiple of
+	// the frame duration (Latency). A longer buffer tolerates longer reader stalls.
+	// If it's 0, miniaudio's default is used.
+	BufferDuration time.Duration
+	// NoAutoConvert disables WASAPI's sample rate conversion in shared mode, so miniaudio only
+	// converts samples when the device's sampling rate differs.
+	NoAutoConvert bool
+}
+
+var (
+	wasapiOptionsMu sync.Mutex
+	wasapiOptions   WASAPIOptions
+)
+
+// SetWASAPIOptions configures microphones on Windows. The options apply to microphones
+// recorded after this call.
+func SetWASAPIOptions(options WASAPIOptions) {
+	wasapiOptionsMu.Lock()
+	defer wasapiOptionsMu.Unlock()
+	wasapiOptions = options
+}
+
+func currentWASAPIOptions() WASAPIOptions {
+	wasapiOptionsMu.Lock()
+	defer wasapiOptionsMu.Unlock()
+	return wasapiOptions
+}
+
+func configureDevice(config *malgo.DeviceConfig) {
+	options := currentWASAPIOptions()
+	if options.Exclusive {
+		config.Capture.ShareMode = malgo.Exclusive
+	}
+	if options.BufferDuration > 0 && config.PeriodSizeInMilliseconds > 0 {
+		period := time.Duration(config.PeriodSizeInMilliseconds) * time.Millisecond
+		config.Periods = uint32((options.BufferDuration + period - 1) / period)
+	}
+	if options.NoAutoConvert {
+		config.Wasapi.NoAutoConvertSRC = 1
+		config.Wasapi.NoDefaultQualitySRC = 1
+	}
+}
+
+func deviceShareMode() malgo.ShareMode {
+	if currentWASAPIOptions().Exclusive {
+		return malgo.Exclusive
+	}
+	return malgo.Shared
+}
+
+func probePermission() (driver.Permission, error) {
+	return privacy.Permission(privacy.Microphone), nil
+}
+
+// requestPermission returns privacy settings, since desktop applications aren't prompted on Windows.
+func requestPermission() (driver.Permission, error) {
+	return privacy.Permission(privacy.Microphone), nil
+}
@@ -0,0 +1,32 @@
+package microphone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gen2brain/malgo"
+)
+
+func TestConfigureDevice(t *testing.T) {
+	defer SetWASAPIOptions(WASAPIOptions{})
+
+	config := malgo.DefaultDeviceConfig(malgo.Capture)
+	config.PeriodSizeInMilliseconds = 20
+	configureDevice(&config)
+	if config.Capture.ShareMode != malgo.Shared || deviceShareMode() != malgo.Shared {
+		t.Fatal("expected the shared mode by default")
+	}
+
+	SetWASAPIOptions(WASAPIOptions{Exclusive: true, BufferDuration: 50 * time.Millisecond, NoAutoConvert: true})
+	configureDevice(&config)
+	if config.Capture.ShareMode != malgo.Exclusive || deviceShareMode() != malgo.Exclusive {
+		t.Fatal("expected the exclusive mode")
+	}
+	// The buffer is rounded up to 3 periods of 20ms
+	if config.Periods != 3 {
+		t.Fatalf("expected 3 periods, but got %d", config.Periods)
+	}
+	if config.Wasapi.NoAutoConvertSRC != 1 || config.Wasapi.NoDefaultQualitySRC != 1 {
+		t.Fatal("expected the sample rate conversion of WASAPI to be disabled")
+	}
+}
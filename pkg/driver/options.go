@@ -0,0 +1,13 @@
+package driver
+
+// Option is a driver specific option, e.g. V4L2 buffer count, which the application passes to drivers
+// through MediaTrackConstraints.DriverOptions. Each driver defines its own option types and ignores
+// others'.
+type Option interface{}
+
+// Configurable is implemented by adapters that accept Options. Configure is called before Open, and
+// its options replace those of the previous call. An error is returned if the adapter knows an option
+// but can't support it, e.g. on this device.
+type Configurable interface {
+	Configure(opts []Option) error
+}
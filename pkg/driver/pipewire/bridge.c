@@ -0,0 +1,318 @@
+// +build pipewire
+
+#include "bridge.h"
+
+#include <errno.h>
+#include <pthread.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+#include <time.h>
+
+#include <pipewire/pipewire.h>
+#include <spa/param/audio/format-utils.h>
+
+#include "_cgo_export.h"
+
+// Capture stream ring buffer size, in periods
+#define RING_PERIODS 8
+
+static struct {
+	struct pw_thread_loop *loop;
+	struct pw_context *context;
+	struct pw_core *core;
+	struct pw_registry *registry;
+	struct spa_hook coreListener;
+	struct spa_hook registryListener;
+	int pending;
+	int synced;
+} pw;
+
+struct pwCapture {
+	struct pw_stream *stream;
+	struct spa_hook listener;
+
+	pthread_mutex_t mutex;
+	pthread_cond_t cond;
+	uint8_t *ring;
+	size_t cap, head, len;
+	int ended;
+};
+
+static const char *dictGet(const struct spa_dict *props, const char *key)
+{
+	const char *v = props ? spa_dict_lookup(props, key) : NULL;
+	return v ? v : "";
+}
+
+static void onGlobal(void *data, uint32_t id, uint32_t permissions, const char *type, uint32_t version,
+	const struct spa_dict *props)
+{
+	if (strcmp(type, PW_TYPE_INTERFACE_Node) != 0)
+		return;
+
+	goNodeAdded(id,
+		(char *)dictGet(props, PW_KEY_NODE_NAME),
+		(char *)dictGet(props, PW_KEY_NODE_DESCRIPTION),
+		(char *)dictGet(props, PW_KEY_MEDIA_CLASS),
+		(char *)dictGet(props, PW_KEY_APP_NAME));
+}
+
+static void onGlobalRemove(void *data, uint32_t id)
+{
+	goNodeRemoved(id);
+}
+
+static const struct pw_registry_events registryEvents = {
+	PW_VERSION_REGISTRY_EVENTS,
+	.global = onGlobal,
+	.global_remove = onGlobalRemove,
+};
+
+static void onCoreDone(void *data, uint32_t id, int seq)
+{
+	if (id == PW_ID_CORE && seq == pw.pending) {
+		pw.synced = 1;
+		pw_thread_loop_signal(pw.loop, false);
+	}
+}
+
+static void onCoreError(void *data, uint32_t id, int seq, int res, const char *message)
+{
+	if (id == PW_ID_CORE) {
+		// The connection to the daemon was lost.
+		pw.synced = 1;
+		pw_thread_loop_signal(pw.loop, false);
+	}
+}
+
+static const struct pw_core_events coreEvents = {
+	PW_VERSION_CORE_EVENTS,
+	.done = onCoreDone,
+	.error = onCoreError,
+};
+
+int pwStart()
+{
+	pw_init(NULL, NULL);
+
+	pw.loop = pw_thread_loop_new("mediadevices", NULL);
+	if (!pw.loop)
+		return PW_ERROR;
+
+	pw_thread_loop_lock(pw.loop);
+	pw.context = pw_context_new(pw_thread_loop_get_loop(pw.loop), NULL, 0);
+	if (!pw.context)
+		goto fail;
+	pw.core = pw_context_connect(pw.context, NULL, 0);
+	if (!pw.core)
+		goto fail;
+
+	pw_core_add_listener(pw.core, &pw.coreListener, &coreEvents, NULL);
+	pw.registry = pw_core_get_registry(pw.core, PW_VERSION_REGISTRY, 0);
+	pw_registry_add_listener(pw.registry, &pw.registryListener, &registryEvents, NULL);
+
+	if (pw_thread_loop_start(pw.loop) < 0)
+		goto fail;
+
+	// Wait for the initial nodes
+	pw.pending = pw_core_sync(pw.core, PW_ID_CORE, 0);
+	while (!pw.synced) {
+		if (pw_thread_loop_timed_wait(pw.loop, 5) != 0)
+			break;
+	}
+	pw_thread_loop_unlock(pw.loop);
+	return PW_OK;
+
+fail:
+	pw_thread_loop_unlock(pw.loop);
+	if (pw.context)
+		pw_context_destroy(pw.context);
+	pw_thread_loop_destroy(pw.loop);
+	pw.loop = NULL;
+	return PW_ERROR;
+}
+
+static void onProcess(void *data)
+{
+	pwCapture *c = data;
+	struct pw_buffer *b = pw_stream_dequeue_buffer(c->stream);
+	if (!b)
+		return;
+
+	struct spa_data *d = &b->buffer->datas[0];
+	if (d->data && d->chunk) {
+		uint32_t offset = SPA_MIN(d->chunk->offset, d->maxsize);
+		uint32_t size = SPA_MIN(d->chunk->size, d->maxsize - offset);
+		const uint8_t *src = (const uint8_t *)d->data + offset;
+
+		pthread_mutex_lock(&c->mutex);
+		if (size > c->cap) {
+			src += size - c->cap;
+			size = c->cap;
+		}
+		// The oldest samples are overwritten when the reader is behind.
+		if (c->len + size > c->cap) {
+			size_t drop = c->len + size - c->cap;
+			c->head = (c->head + drop) % c->cap;
+			c->len -= drop;
+		}
+		for (uint32_t i = 0; i < size;) {
+			size_t tail = (c->head + c->len) % c->cap;
+			size_t n = SPA_MIN(size - i, c->cap - tail);
+			memcpy(c->ring + tail, src + i, n);
+			c->len += n;
+			i += n;
+		}
+		pthread_cond_signal(&c->cond);
+		pthread_mutex_unlock(&c->mutex);
+	}
+
+	pw_stream_queue_buffer(c->stream, b);
+}
+
+static void onStateChanged(void *data, enum pw_stream_state old, enum pw_stream_state state, const char *error)
+{
+	pwCapture *c = data;
+	if (state != PW_STREAM_STATE_ERROR && state != PW_STREAM_STATE_UNCONNECTED)
+		return;
+
+	// The node was removed, or the daemon is gone.
+	pthread_mutex_lock(&c->mutex);
+	c->ended = 1;
+	pthread_cond_signal(&c->cond);
+	pthread_mutex_unlock(&c->mutex);
+}
+
+static const struct pw_stream_events streamEvents = {
+	PW_VERSION_STREAM_EVENTS,
+	.state_changed = onStateChanged,
+	.process = onProcess,
+};
+
+static enum spa_audio_format spaFormat(int format, int *sampleSize)
+{
+	switch (format) {
+	case PW_FORMAT_S16:
+		*sampleSize = 2;
+		return SPA_AUDIO_FORMAT_S16_LE;
+	case PW_FORMAT_S24:
+		*sampleSize = 3;
+		return SPA_AUDIO_FORMAT_S24_LE;
+	case PW_FORMAT_S32:
+		*sampleSize = 4;
+		return SPA_AUDIO_FORMAT_S32_LE;
+	case PW_FORMAT_F32:
+		*sampleSize = 4;
+		return SPA_AUDIO_FORMAT_F32_LE;
+	default:
+		return SPA_AUDIO_FORMAT_UNKNOWN;
+	}
+}
+
+pwCapture *pwCaptureOpen(const char *target, int captureSink, int format, int rate, int channels, int period)
+{
+	int sampleSize = 0;
+	enum spa_audio_format f = spaFormat(format, &sampleSize);
+	if (f == SPA_AUDIO_FORMAT_UNKNOWN || !pw.loop)
+		return NULL;
+
+	pwCapture *c = calloc(1, sizeof(pwCapture));
+	if (!c)
+		return NULL;
+	pthread_mutex_init(&c->mutex, NULL);
+	pthread_cond_init(&c->cond, NULL);
+	c->cap = (size_t)period * channels * sampleSize * RING_PERIODS;
+	c->ring = malloc(c->cap);
+	if (!c->ring) {
+		free(c);
+		return NULL;
+	}
+
+	char latency[32];
+	snprintf(latency, sizeof(latency), "%d/%d", period, rate);
+	struct pw_properties *props = pw_properties_new(
+		PW_KEY_MEDIA_TYPE, "Audio",
+		PW_KEY_MEDIA_CATEGORY, "Capture",
+		PW_KEY_MEDIA_ROLE, "Communication",
+		PW_KEY_NODE_LATENCY, latency,
+		// The stream ends with its node instead of moving to the default node.
+		PW_KEY_NODE_DONT_RECONNECT, "true",
+		"target.object", target,
+		NULL);
+	if (captureSink)
+		pw_properties_set(props, PW_KEY_STREAM_CAPTURE_SINK, "true");
+
+	uint8_t buffer[1024];
+	struct spa_pod_builder builder = SPA_POD_BUILDER_INIT(buffer, sizeof(buffer));
+	const struct spa_pod *params[1];
+	params[0] = spa_format_audio_raw_build(&builder, SPA_PARAM_EnumFormat,
+		&SPA_AUDIO_INFO_RAW_INIT(.format = f, .rate = rate, .channels = channels));
+
+	pw_thread_loop_lock(pw.loop);
+	c->stream = pw_stream_new(pw.core, "mediadevices", props);
+	if (!c->stream) {
+		pw_thread_loop_unlock(pw.loop);
+		free(c->ring);
+		free(c);
+		return NULL;
+	}
+	pw_stream_add_listener(c->stream, &c->listener, &streamEvents, c);
+	if (pw_stream_connect(c->stream, PW_DIRECTION_INPUT, PW_ID_ANY,
+		PW_STREAM_FLAG_AUTOCONNECT | PW_STREAM_FLAG_MAP_BUFFERS | PW_STREAM_FLAG_RT_PROCESS,
+		params, 1) < 0) {
+		pw_stream_destroy(c->stream);
+		pw_thread_loop_unlock(pw.loop);
+		free(c->ring);
+		free(c);
+		return NULL;
+	}
+	pw_thread_loop_unlock(pw.loop);
+	return c;
+}
+
+int pwCaptureRead(pwCapture *c, void *buf, int size, int timeoutMs)
+{
+	struct timespec deadline;
+	clock_gettime(CLOCK_REALTIME, &deadline);
+	deadline.tv_sec += timeoutMs / 1000;
+	deadline.tv_nsec += (long)(timeoutMs % 1000) * 1000000;
+	if (deadline.tv_nsec >= 1000000000) {
+		deadline.tv_sec++;
+		deadline.tv_nsec -= 1000000000;
+	}
+
+	uint8_t *dst = buf;
+	int ret = PW_OK;
+	pthread_mutex_lock(&c->mutex);
+	while (size > 0) {
+		while (c->len == 0 && !c->ended) {
+			if (pthread_cond_timedwait(&c->cond, &c->mutex, &deadline) == ETIMEDOUT)
+				break;
+		}
+		if (c->len == 0) {
+			ret = c->ended ? PW_ENDED : PW_TIMEOUT;
+			break;
+		}
+		size_t n = SPA_MIN((size_t)size, SPA_MIN(c->len, c->cap - c->head));
+		memcpy(dst, c->ring + c->head, n);
+		c->head = (c->head + n) % c->cap;
+		c->len -= n;
+		dst += n;
+		size -= n;
+	}
+	pthread_mutex_unlock(&c->mutex);
+	return ret;
+}
+
+void pwCaptureClose(pwCapture *c)
+{
+	pw_thread_loop_lock(pw.loop);
+	pw_stream_destroy(c->stream);
+	pw_thread_loop_unlock(pw.loop);
+
+	pthread_cond_destroy(&c->cond);
+	pthread_mutex_destroy(&c->mutex);
+	free(c->ring);
+	free(c);
+}
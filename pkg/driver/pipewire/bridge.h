@@ -0,0 +1,29 @@
+// +build pipewire
+
+#pragma once
+
+#include <stdint.h>
+
+#define PW_OK 0
+#define PW_ERROR -1
+#define PW_TIMEOUT -2
+#define PW_ENDED -3
+
+// Capture stream sample formats
+#define PW_FORMAT_S16 1
+#define PW_FORMAT_S24 2
+#define PW_FORMAT_S32 3
+#define PW_FORMAT_F32 4
+
+typedef struct pwCapture pwCapture;
+
+// pwStart connects to the PipeWire daemon and starts the thread loop. The graph's nodes are reported to
+// goNodeAdded before it returns, and changes are reported to goNodeAdded and goNodeRemoved from the loop.
+int pwStart();
+
+// pwCaptureOpen connects a capture stream to the node. If captureSink isn't 0, the sink's monitor is
+// captured. period is the number of frames processed at once.
+pwCapture *pwCaptureOpen(const char *target, int captureSink, int format, int rate, int channels, int period);
+// pwCaptureRead fills buf with the captured samples. It returns PW_ENDED after the node is removed.
+int pwCaptureRead(pwCapture *c, void *buf, int size, int timeoutMs);
+void pwCaptureClose(pwCapture *c);
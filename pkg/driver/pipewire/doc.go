@@ -0,0 +1,17 @@
+// Package pipewire registers PipeWire's audio nodes as audio drivers using the native API instead of the
+// PulseAudio compatibility layer. Three kinds of nodes can be captured:
+//
+//   - Audio/Source: microphones and other capture devices
+//   - Audio/Sink: speaker monitors, labeled with the ".monitor" suffix
+//   - Stream/Output/Audio: application audio, labeled with the "stream:" prefix
+//
+// The drivers follow the graph. They're registered when nodes appear, e.g. a USB headset is plugged in or
+// an application starts playing, and unregistered when the nodes are removed. Running tracks of removed
+// nodes end with io.EOF.
+//
+// PipeWire is linked with cgo, and the package is only built with the pipewire build tag:
+//
+//	go build -tags pipewire
+//
+// Reference: https://docs.pipewire.org
+package pipewire
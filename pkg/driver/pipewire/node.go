@@ -0,0 +1,117 @@
+package pipewire
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// Media classes of nodes to capture
+const (
+	classSource = "Audio/Source"
+	classSink   = "Audio/Sink"
+	classStream = "Stream/Output/Audio"
+)
+
+// node is an audio node in PipeWire's graph.
+type node struct {
+	id uint32
+	// name is node.name, which is unique in the graph.
+	name        string
+	description string
+	class       string
+	// application is the stream's application.name.
+	application string
+}
+
+// captured reports whether the node can be captured.
+func (n node) captured() bool {
+	switch n.class {
+	case classSource, classSink, classStream:
+		return n.name != ""
+	default:
+		return false
+	}
+}
+
+// label returns the driver's label.
+func (n node) label() string {
+	switch n.class {
+	case classSink:
+		return n.name + ".monitor"
+	case classStream:
+		// Applications can have several streams with the same name.
+		app := n.application
+		if app == "" {
+			app = n.name
+		}
+		return fmt.Sprintf("stream:%s:%d", app, n.id)
+	default:
+		return n.name
+	}
+}
+
+// captureSink reports whether the node has to be captured as a sink monitor.
+func (n node) captureSink() bool {
+	return n.class == classSink
+}
+
+func (n node) priority() driver.Priority {
+	if n.class == classSource {
+		return driver.PriorityNormal
+	}
+	// Monitors and applications shouldn't be chosen over microphones by default.
+	return driver.PriorityLow
+}
+
+// graph keeps the drivers registered for the graph's nodes.
+type graph struct {
+	mu         sync.Mutex
+	adapters   map[uint32]driver.Adapter
+	newAdapter func(n node) driver.Adapter
+	register   func(a driver.Adapter, info driver.Info) error
+	unregister func(a driver.Adapter) error
+}
+
+func newGraph(newAdapter func(n node) driver.Adapter) *graph {
+	m := driver.GetManager()
+	return &graph{
+		adapters:   make(map[uint32]driver.Adapter),
+		newAdapter: newAdapter,
+		register:   m.Register,
+		unregister: m.Unregister,
+	}
+}
+
+// add registers the node's driver. The node replaces any old one with the same id.
+func (g *graph) add(n node) {
+	if !n.captured() {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if old, ok := g.adapters[n.id]; ok {
+		g.unregister(old)
+	}
+
+	a := g.newAdapter(n)
+	g.adapters[n.id] = a
+	g.register(a, driver.Info{
+		Label:      n.label(),
+		DeviceType: driver.Microphone,
+		Priority:   n.priority(),
+	})
+}
+
+// remove unregisters the node's driver. Unknown ids are ignored, since events arrive for every object
+// in the graph.
+func (g *graph) remove(id uint32) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if a, ok := g.adapters[id]; ok {
+		g.unregister(a)
+		delete(g.adapters, id)
+	}
+}
@@ -0,0 +1,101 @@
+package pipewire
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+type adapterMock struct{ n node }
+
+func (a *adapterMock) Open() error              { return nil }
+func (a *adapterMock) Close() error             { return nil }
+func (a *adapterMock) Properties() []prop.Media { return nil }
+
+func TestNodeLabel(t *testing.T) {
+	testCases := map[string]struct {
+		node     node
+		captured bool
+		label    string
+	}{
+		"Source": {
+			node:     node{id: 40, name: "alsa_input.usb-headset", class: classSource},
+			captured: true,
+			label:    "alsa_input.usb-headset",
+		},
+		"Sink": {
+			node:     node{id: 41, name: "alsa_output.pci-analog-stereo", class: classSink},
+			captured: true,
+			label:    "alsa_output.pci-analog-stereo.monitor",
+		},
+		"Stream": {
+			node:     node{id: 42, name: "Playback", class: classStream, application: "Firefox"},
+			captured: true,
+			label:    "stream:Firefox:42",
+		},
+		"Video": {
+			node: node{id: 43, name: "v4l2_input.camera", class: "Video/Source"},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			if captured := c.node.captured(); captured != c.captured {
+				t.Fatalf("expected captured to be %v, but got %v", c.captured, captured)
+			}
+			if !c.captured {
+				return
+			}
+			if label := c.node.label(); label != c.label {
+				t.Fatalf("expected %s, but got %s", c.label, label)
+			}
+		})
+	}
+}
+
+func TestGraph(t *testing.T) {
+	registered := make(map[driver.Adapter]driver.Info)
+	g := &graph{
+		adapters:   make(map[uint32]driver.Adapter),
+		newAdapter: func(n node) driver.Adapter { return &adapterMock{n} },
+		register: func(a driver.Adapter, info driver.Info) error {
+			registered[a] = info
+			return nil
+		},
+		unregister: func(a driver.Adapter) error {
+			delete(registered, a)
+			return nil
+		},
+	}
+
+	g.add(node{id: 40, name: "mic", class: classSource})
+	g.add(node{id: 41, name: "speaker", class: classSink})
+	g.add(node{id: 43, name: "camera", class: "Video/Source"})
+	if len(registered) != 2 {
+		t.Fatalf("expected 2 drivers, but got %d", len(registered))
+	}
+	for a, info := range registered {
+		n := a.(*adapterMock).n
+		if n.id == 41 && info.Priority != driver.PriorityLow {
+			t.Errorf("expected the monitor to have the low priority, but got %v", info.Priority)
+		}
+	}
+
+	// The node's properties are updated
+	g.add(node{id: 40, name: "mic", description: "Microphone", class: classSource})
+	if len(registered) != 2 {
+		t.Fatalf("expected 2 drivers after the update, but got %d", len(registered))
+	}
+
+	g.remove(41)
+	g.remove(43)
+	if len(registered) != 1 {
+		t.Fatalf("expected 1 driver after the removal, but got %d", len(registered))
+	}
+	for a := range registered {
+		if n := a.(*adapterMock).n; n.description != "Microphone" {
+			t.Fatalf("expected the updated node, but got %v", n)
+		}
+	}
+}
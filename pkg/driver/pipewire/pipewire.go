@@ -0,0 +1,213 @@
+// +build pipewire
+
+package pipewire
+
+// #cgo pkg-config: libpipewire-0.3
+// #include <stdlib.h>
+// #include "bridge.h"
+import "C"
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/pion/mediadevices/internal/logging"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const readTimeoutMs = 5000
+
+var logger = logging.NewLogger("mediadevices/driver/pipewire")
+
+var (
+	errOpenFailed        = errors.New("failed to open capture stream")
+	errReadTimeout       = errors.New("read timeout")
+	errUnsupportedFormat = errors.New("the provided audio format is not supported")
+)
+
+// sampleRates are the streams' sampling rates. PipeWire converts the nodes' samples, so any combination
+// of the properties can be captured. The first one is the default.
+var sampleRates = []int{48000, 44100, 96000, 16000}
+
+// frameDurations are the streams' quantums, which are common audio codec frame durations (ptime).
+// The first one is the default.
+var frameDurations = []time.Duration{
+	20 * time.Millisecond,
+	10 * time.Millisecond,
+	40 * time.Millisecond,
+	60 * time.Millisecond,
+}
+
+var formats = []struct {
+	format     C.int
+	sampleSize int
+	isFloat    bool
+}{
+	{C.PW_FORMAT_F32, 4, true},
+	{C.PW_FORMAT_S16, 2, false},
+	{C.PW_FORMAT_S32, 4, false},
+	{C.PW_FORMAT_S24, 3, false},
+}
+
+var nodes *graph
+
+type microphone struct {
+	node    node
+	capture *C.pwCapture
+	mutex   sync.Mutex
+}
+
+func init() {
+	nodes = newGraph(func(n node) driver.Adapter {
+		return &microphone{node: n}
+	})
+	if C.pwStart() != C.PW_OK {
+		logger.Debug("failed to connect to PipeWire")
+	}
+}
+
+//export goNodeAdded
+func goNodeAdded(id C.uint32_t, name, description, class, application *C.char) {
+	nodes.add(node{
+		id:          uint32(id),
+		name:        C.GoString(name),
+		description: C.GoString(description),
+		class:       C.GoString(class),
+		application: C.GoString(application),
+	})
+}
+
+//export goNodeRemoved
+func goNodeRemoved(id C.uint32_t) {
+	nodes.remove(uint32(id))
+}
+
+func (m *microphone) Open() error {
+	return nil
+}
+
+func (m *microphone) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.capture != nil {
+		C.pwCaptureClose(m.capture)
+		m.capture = nil
+	}
+	return nil
+}
+
+func (m *microphone) AudioRecord(p prop.Media) (audio.Reader, error) {
+	decoder, err := wave.NewDecoder(&wave.RawFormat{
+		SampleSize:  p.SampleSize,
+		IsFloat:     p.IsFloat,
+		Interleaved: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	format, ok := sampleFormat(p.SampleSize, p.IsFloat)
+	if !ok {
+		return nil, errUnsupportedFormat
+	}
+
+	latency := p.Latency
+	if latency <= 0 {
+		latency = frameDurations[0]
+	}
+	period := int(int64(p.SampleRate) * int64(latency) / int64(time.Second))
+
+	target := C.CString(m.node.name)
+	defer C.free(unsafe.Pointer(target))
+	var captureSink C.int
+	if m.node.captureSink() {
+		captureSink = 1
+	}
+
+	capture := C.pwCaptureOpen(target, captureSink, format, C.int(p.SampleRate), C.int(p.ChannelCount), C.int(period))
+	if capture == nil {
+		return nil, errOpenFailed
+	}
+	m.mutex.Lock()
+	m.capture = capture
+	m.mutex.Unlock()
+
+	buf := make([]byte, period*p.ChannelCount*p.SampleSize)
+	// Decode into the pool, so chunks are reused once readers release them.
+	var pool wave.Pool
+	reader := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		m.mutex.Lock()
+		if m.capture == nil {
+			m.mutex.Unlock()
+			return nil, func() {}, io.EOF
+		}
+		ret := C.pwCaptureRead(m.capture, unsafe.Pointer(&buf[0]), C.int(len(buf)), readTimeoutMs)
+		m.mutex.Unlock()
+
+		switch ret {
+		case C.PW_OK:
+		case C.PW_ENDED:
+			// The node was removed from the graph.
+			return nil, func() {}, io.EOF
+		case C.PW_TIMEOUT:
+			return nil, func() {}, errReadTimeout
+		default:
+			return nil, func() {}, errOpenFailed
+		}
+
+		chunk, release, err := pool.Decode(decoder, binary.LittleEndian, buf, p.ChannelCount)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		switch chunk := chunk.(type) {
+		case *wave.Int16Interleaved:
+			chunk.Size.SamplingRate = p.SampleRate
+		case *wave.Int32Interleaved:
+			chunk.Size.SamplingRate = p.SampleRate
+		case *wave.Float32Interleaved:
+			chunk.Size.SamplingRate = p.SampleRate
+		}
+		return chunk, release, nil
+	})
+	return reader, nil
+}
+
+func (m *microphone) Properties() []prop.Media {
+	var props []prop.Media
+	for ch := 1; ch <= 2; ch++ {
+		for _, sampleRate := range sampleRates {
+			for _, latency := range frameDurations {
+				for _, f := range formats {
+					props = append(props, prop.Media{
+						Audio: prop.Audio{
+							ChannelCount:  ch,
+							SampleRate:    sampleRate,
+							SampleSize:    f.sampleSize,
+							IsFloat:       f.isFloat,
+							IsInterleaved: true,
+							Latency:       latency,
+						},
+					})
+				}
+			}
+		}
+	}
+	return props
+}
+
+func sampleFormat(sampleSize int, isFloat bool) (C.int, bool) {
+	for _, f := range formats {
+		if f.sampleSize == sampleSize && f.isFloat == isFloat {
+			return f.format, true
+		}
+	}
+	return 0, false
+}
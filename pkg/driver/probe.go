@@ -0,0 +1,55 @@
+package driver
+
+// Permission is whether the OS allows the application to use a device, e.g. macOS and Windows privacy
+// settings.
+type Permission int
+
+const (
+	// PermissionUnknown is that the driver can't tell permission without opening the device.
+	PermissionUnknown Permission = iota
+	// PermissionGranted is that the application is allowed to use the device.
+	PermissionGranted
+	// PermissionDenied is that the user denied the device, e.g. in the prompt or privacy settings.
+	PermissionDenied
+	// PermissionPrompt is that the user hasn't been asked yet, and the OS will ask when the device is opened.
+	PermissionPrompt
+	// PermissionRestricted is that system policy, e.g. parental controls or a device management
+	// profile, doesn't allow the application to use the device, and the user can't change it.
+	PermissionRestricted
+)
+
+func (p Permission) String() string {
+	switch p {
+	case PermissionGranted:
+		return "granted"
+	case PermissionDenied:
+		return "denied"
+	case PermissionPrompt:
+		return "prompt"
+	case PermissionRestricted:
+		return "restricted"
+	default:
+		return "unknown"
+	}
+}
+
+// Probe is a device's status, which is checked without opening it.
+type Probe struct {
+	Permission Permission
+	// Busy is whether another application uses the device exclusively, so it can't be opened now.
+	Busy bool
+}
+
+// Prober is implemented by adapters that can check their status without recording, e.g. before
+// asking the user for permission in an onboarding flow. Probe can be called in any driver state, and
+// it mustn't make the OS prompt the user.
+type Prober interface {
+	Probe() (Probe, error)
+}
+
+// PermissionRequester is implemented by adapters whose OS prompts the user for permission, e.g. cameras
+// and microphones on macOS. RequestPermission shows the prompt if the user hasn't been asked yet, and waits for
+// the answer. It returns the current permission without prompting if the user already answered.
+type PermissionRequester interface {
+	RequestPermission() (Permission, error)
+}
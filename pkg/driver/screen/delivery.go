@@ -0,0 +1,81 @@
+package screen
+
+import (
+	"bytes"
+	"image"
+	"sync"
+	"time"
+)
+
+const defaultMaxIdleInterval = time.Second
+
+// DeliveryOptions controls when screen drivers deliver captured frames.
+type DeliveryOptions struct {
+	// OnlyOnChange skips frames identical to the last delivered frame. Since a mostly static screen
+	// doesn't need to be encoded again, this saves a lot of CPU.
+	OnlyOnChange bool
+	// MaxIdleInterval is the longest interval between two delivered frames in OnlyOnChange mode. The last frame
+	// is delivered again after the interval, so receivers can recover from packet loss. If it's 0,
+	// 1 second is used.
+	MaxIdleInterval time.Duration
+}
+
+var (
+	deliveryOptionsMu sync.Mutex
+	deliveryOptions   DeliveryOptions
+)
+
+// SetDeliveryOptions configures the screen drivers. The options apply to screens recorded
+// after this call.
+func SetDeliveryOptions(options DeliveryOptions) {
+	deliveryOptionsMu.Lock()
+	defer deliveryOptionsMu.Unlock()
+	deliveryOptions = options
+}
+
+func currentDeliveryOptions() DeliveryOptions {
+	deliveryOptionsMu.Lock()
+	defer deliveryOptionsMu.Unlock()
+	return deliveryOptions
+}
+
+// changeDetector decides whether a captured frame has to be delivered by comparing it with the last delivered one.
+type changeDetector struct {
+	options       DeliveryOptions
+	now           func() time.Time
+	last          []byte
+	lastRect      image.Rectangle
+	lastDelivered time.Time
+}
+
+func newChangeDetector(options DeliveryOptions) *changeDetector {
+	if options.MaxIdleInterval <= 0 {
+		options.MaxIdleInterval = defaultMaxIdleInterval
+	}
+
+	return &changeDetector{
+		options: options,
+		now:     time.Now,
+	}
+}
+
+// shouldDeliver reports whether img has to be delivered. img is copied when it's delivered, so the caller can
+// reuse its buffer for the next frame.
+func (d *changeDetector) shouldDeliver(img *image.RGBA) bool {
+	if !d.options.OnlyOnChange {
+		return true
+	}
+
+	now := d.now()
+	unchanged := d.last != nil && img.Rect == d.lastRect && bytes.Equal(img.Pix, d.last)
+	if unchanged && now.Sub(d.lastDelivered) < d.options.MaxIdleInterval {
+		return false
+	}
+
+	if !unchanged {
+		d.last = append(d.last[:0], img.Pix...)
+		d.lastRect = img.Rect
+	}
+	d.lastDelivered = now
+	return true
+}
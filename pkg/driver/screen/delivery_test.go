@@ -0,0 +1,52 @@
+package screen
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestChangeDetector(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	t.Run("Disabled", func(t *testing.T) {
+		d := newChangeDetector(DeliveryOptions{})
+		for i := 0; i < 3; i++ {
+			if !d.shouldDeliver(img) {
+				t.Fatal("expected every frame to be delivered")
+			}
+		}
+	})
+
+	t.Run("OnlyOnChange", func(t *testing.T) {
+		now := time.Unix(0, 0)
+		d := newChangeDetector(DeliveryOptions{OnlyOnChange: true, MaxIdleInterval: time.Second})
+		d.now = func() time.Time { return now }
+
+		if !d.shouldDeliver(img) {
+			t.Fatal("expected the first frame to be delivered")
+		}
+
+		now = now.Add(100 * time.Millisecond)
+		if d.shouldDeliver(img) {
+			t.Fatal("expected the unchanged frame to be skipped")
+		}
+
+		// The detector should keep its own copy, since drivers reuse the buffer
+		img.Pix[0] = 0xFF
+		now = now.Add(100 * time.Millisecond)
+		if !d.shouldDeliver(img) {
+			t.Fatal("expected the changed frame to be delivered")
+		}
+
+		now = now.Add(999 * time.Millisecond)
+		if d.shouldDeliver(img) {
+			t.Fatal("expected the unchanged frame to be skipped before the max idle interval")
+		}
+
+		now = now.Add(time.Millisecond)
+		if !d.shouldDeliver(img) {
+			t.Fatal("expected the unchanged frame to be delivered after the max idle interval")
+		}
+	})
+}
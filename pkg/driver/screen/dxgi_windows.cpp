@@ -0,0 +1,313 @@
+#include <string.h>
+
+#include <d3d11.h>
+#include <dxgi1_6.h>
+
+#include "dxgi_windows.hpp"
+
+// utf16Decode converts the output device name to UTF-8.
+// returned pointer must be released by free() after use.
+static char* utf16Decode(const WCHAR* str)
+{
+  int size = WideCharToMultiByte(CP_UTF8, 0, str, -1, nullptr, 0, nullptr, nullptr);
+  char* ret = (char*)malloc(size);
+  WideCharToMultiByte(CP_UTF8, 0, str, -1, ret, size, nullptr, nullptr);
+  return ret;
+}
+
+// findOutput returns the adapter and the output at the given indexes.
+static int findOutput(int iAdapter, int iOutput, IDXGIAdapter1** adapter, IDXGIOutput** output)
+{
+  IDXGIFactory1* factory = nullptr;
+  if (FAILED(CreateDXGIFactory1(__uuidof(IDXGIFactory1), (void**)&factory)))
+    return 0;
+
+  if (FAILED(factory->EnumAdapters1(iAdapter, adapter)))
+  {
+    safeRelease(&factory);
+    return 0;
+  }
+  safeRelease(&factory);
+
+  if (FAILED((*adapter)->EnumOutputs(iOutput, output)))
+  {
+    safeRelease(adapter);
+    return 0;
+  }
+  return 1;
+}
+
+// listDXGIOutputs stores information of the outputs that are attached to the desktop to dxgiOutputList*.
+int listDXGIOutputs(dxgiOutputList* list, const char** errstr)
+{
+  IDXGIFactory1* factory = nullptr;
+  if (FAILED(CreateDXGIFactory1(__uuidof(IDXGIFactory1), (void**)&factory)))
+  {
+    *errstr = errCreateFactory;
+    return 1;
+  }
+
+  list->num = 0;
+  list->outputs = nullptr;
+
+  // Count the outputs first
+  IDXGIAdapter1* adapter;
+  for (UINT i = 0; factory->EnumAdapters1(i, &adapter) != DXGI_ERROR_NOT_FOUND; ++i)
+  {
+    IDXGIOutput* output;
+    for (UINT j = 0; adapter->EnumOutputs(j, &output) != DXGI_ERROR_NOT_FOUND; ++j)
+    {
+      DXGI_OUTPUT_DESC desc;
+      if (SUCCEEDED(output->GetDesc(&desc)) && desc.AttachedToDesktop)
+        list->num++;
+      safeRelease(&output);
+    }
+    safeRelease(&adapter);
+  }
+
+  list->outputs = new dxgiOutputInfo[list->num];
+
+  int n = 0;
+  for (UINT i = 0; factory->EnumAdapters1(i, &adapter) != DXGI_ERROR_NOT_FOUND; ++i)
+  {
+    IDXGIOutput* output;
+    for (UINT j = 0; adapter->EnumOutputs(j, &output) != DXGI_ERROR_NOT_FOUND && n < list->num; ++j)
+    {
+      DXGI_OUTPUT_DESC desc;
+      if (SUCCEEDED(output->GetDesc(&desc)) && desc.AttachedToDesktop)
+      {
+        dxgiOutputInfo* info = &list->outputs[n++];
+        info->adapter = i;
+        info->output = j;
+        info->name = utf16Decode(desc.DeviceName);
+        info->width = desc.DesktopCoordinates.right - desc.DesktopCoordinates.left;
+        info->height = desc.DesktopCoordinates.bottom - desc.DesktopCoordinates.top;
+        // The primary monitor is always placed at the origin of the virtual desktop
+        info->primary = desc.DesktopCoordinates.left == 0 && desc.DesktopCoordinates.top == 0;
+      }
+      safeRelease(&output);
+    }
+    safeRelease(&adapter);
+  }
+  list->num = n;
+
+  safeRelease(&factory);
+  return 0;
+}
+
+// freeDXGIOutputList frees all resources stored in dxgiOutputList*.
+void freeDXGIOutputList(dxgiOutputList* list)
+{
+  if (list->outputs != nullptr)
+  {
+    for (int i = 0; i < list->num; ++i)
+    {
+      free(list->outputs[i].name);
+    }
+    delete[] list->outputs;
+    list->outputs = nullptr;
+  }
+}
+
+// duplicate starts the desktop duplication. HDR outputs are duplicated in half float format to keep
+// the highlights, which are clipped in BGRA format.
+static int duplicate(dxgiCapture* capture, IDXGIOutput* output, ID3D11Device* device, IDXGIOutputDuplication** duplication)
+{
+  capture->hdr = 0;
+
+  IDXGIOutput6* output6 = nullptr;
+  if (SUCCEEDED(output->QueryInterface(__uuidof(IDXGIOutput6), (void**)&output6)))
+  {
+    DXGI_OUTPUT_DESC1 desc;
+    int hdr = SUCCEEDED(output6->GetDesc1(&desc)) &&
+              desc.ColorSpace == DXGI_COLOR_SPACE_RGB_FULL_G2084_NONE_P2020;
+
+    DXGI_FORMAT hdrFormats[] = {DXGI_FORMAT_R16G16B16A16_FLOAT};
+    DXGI_FORMAT sdrFormats[] = {DXGI_FORMAT_B8G8R8A8_UNORM};
+    HRESULT hr = output6->DuplicateOutput1(
+        device, 0, 1, hdr ? hdrFormats : sdrFormats, duplication);
+    safeRelease(&output6);
+    if (SUCCEEDED(hr))
+    {
+      capture->hdr = hdr;
+      return 1;
+    }
+  }
+
+  // DuplicateOutput1 is available since Windows 10 1703
+  IDXGIOutput1* output1 = nullptr;
+  if (FAILED(output->QueryInterface(__uuidof(IDXGIOutput1), (void**)&output1)))
+    return 0;
+
+  HRESULT hr = output1->DuplicateOutput(device, duplication);
+  safeRelease(&output1);
+  return SUCCEEDED(hr);
+}
+
+// openDXGICapture starts the desktop duplication of the output which is specified in dxgiCapture*.
+int openDXGICapture(dxgiCapture* capture, const char** errstr)
+{
+  IDXGIAdapter1* adapter = nullptr;
+  IDXGIOutput* output = nullptr;
+  ID3D11Device* device = nullptr;
+  ID3D11DeviceContext* context = nullptr;
+  IDXGIOutputDuplication* duplication = nullptr;
+  ID3D11Texture2D* staging = nullptr;
+
+  if (!findOutput(capture->adapter, capture->output, &adapter, &output))
+  {
+    *errstr = errFindOutput;
+    goto fail;
+  }
+
+  // The device has to be created on the adapter which owns the output
+  if (FAILED(D3D11CreateDevice(
+          adapter, D3D_DRIVER_TYPE_UNKNOWN, nullptr, 0, nullptr, 0,
+          D3D11_SDK_VERSION, &device, nullptr, &context)))
+  {
+    *errstr = errCreateDevice;
+    goto fail;
+  }
+
+  if (!duplicate(capture, output, device, &duplication))
+  {
+    *errstr = errDuplicateOutput;
+    goto fail;
+  }
+
+  {
+    DXGI_OUTDUPL_DESC duplDesc;
+    duplication->GetDesc(&duplDesc);
+    capture->width = duplDesc.ModeDesc.Width;
+    capture->height = duplDesc.ModeDesc.Height;
+
+    D3D11_TEXTURE2D_DESC desc;
+    memset(&desc, 0, sizeof(desc));
+    desc.Width = duplDesc.ModeDesc.Width;
+    desc.Height = duplDesc.ModeDesc.Height;
+    desc.MipLevels = 1;
+    desc.ArraySize = 1;
+    desc.Format = capture->hdr ? DXGI_FORMAT_R16G16B16A16_FLOAT : DXGI_FORMAT_B8G8R8A8_UNORM;
+    desc.SampleDesc.Count = 1;
+    desc.Usage = D3D11_USAGE_STAGING;
+    desc.CPUAccessFlags = D3D11_CPU_ACCESS_READ;
+    if (FAILED(device->CreateTexture2D(&desc, nullptr, &staging)))
+    {
+      *errstr = errCreateStaging;
+      goto fail;
+    }
+  }
+
+  safeRelease(&output);
+  safeRelease(&adapter);
+
+  capture->device = device;
+  capture->context = context;
+  capture->duplication = duplication;
+  capture->staging = staging;
+  return 0;
+
+fail:
+  safeRelease(&staging);
+  safeRelease(&duplication);
+  safeRelease(&context);
+  safeRelease(&device);
+  safeRelease(&output);
+  safeRelease(&adapter);
+  return 1;
+}
+
+// captureDXGIFrame waits for a new frame up to timeoutMs, and copies it to buf.
+// buf must have width*height*4 bytes for BGRA frames, which are stored in RGBA order,
+// or width*height*8 bytes for HDR frames, which are stored as they are.
+int captureDXGIFrame(dxgiCapture* capture, unsigned char* buf, int timeoutMs, const char** errstr)
+{
+  IDXGIOutputDuplication* duplication = (IDXGIOutputDuplication*)capture->duplication;
+  ID3D11DeviceContext* context = (ID3D11DeviceContext*)capture->context;
+  ID3D11Texture2D* staging = (ID3D11Texture2D*)capture->staging;
+
+  DXGI_OUTDUPL_FRAME_INFO info;
+  IDXGIResource* resource = nullptr;
+  HRESULT hr = duplication->AcquireNextFrame(timeoutMs, &info, &resource);
+  if (hr == DXGI_ERROR_WAIT_TIMEOUT)
+    return dxgiCaptureTimeout;
+  if (hr == DXGI_ERROR_ACCESS_LOST)
+    return dxgiCaptureAccessLost;
+  if (FAILED(hr))
+  {
+    *errstr = errAcquireFrame;
+    return dxgiCaptureError;
+  }
+
+  // Only the mouse pointer has been updated
+  if (info.LastPresentTime.QuadPart == 0)
+  {
+    safeRelease(&resource);
+    duplication->ReleaseFrame();
+    return dxgiCaptureTimeout;
+  }
+
+  ID3D11Texture2D* texture = nullptr;
+  hr = resource->QueryInterface(__uuidof(ID3D11Texture2D), (void**)&texture);
+  safeRelease(&resource);
+  if (FAILED(hr))
+  {
+    duplication->ReleaseFrame();
+    *errstr = errAcquireFrame;
+    return dxgiCaptureError;
+  }
+
+  context->CopyResource(staging, texture);
+  safeRelease(&texture);
+
+  D3D11_MAPPED_SUBRESOURCE mapped;
+  if (FAILED(context->Map(staging, 0, D3D11_MAP_READ, 0, &mapped)))
+  {
+    duplication->ReleaseFrame();
+    *errstr = errMapFrame;
+    return dxgiCaptureError;
+  }
+
+  const int bpp = capture->hdr ? 8 : 4;
+  const int stride = capture->width * bpp;
+  for (int y = 0; y < capture->height; ++y)
+  {
+    const unsigned char* src = (const unsigned char*)mapped.pData + y * mapped.RowPitch;
+    unsigned char* dst = buf + y * stride;
+    if (capture->hdr)
+    {
+      memcpy(dst, src, stride);
+      continue;
+    }
+    for (int x = 0; x < stride; x += 4)
+    {
+      dst[x + 0] = src[x + 2];
+      dst[x + 1] = src[x + 1];
+      dst[x + 2] = src[x + 0];
+      dst[x + 3] = 0xFF;
+    }
+  }
+
+  context->Unmap(staging, 0);
+  duplication->ReleaseFrame();
+  return dxgiCaptureOK;
+}
+
+// closeDXGICapture frees all resources stored in dxgiCapture*.
+void closeDXGICapture(dxgiCapture* capture)
+{
+  ID3D11Texture2D* staging = (ID3D11Texture2D*)capture->staging;
+  IDXGIOutputDuplication* duplication = (IDXGIOutputDuplication*)capture->duplication;
+  ID3D11DeviceContext* context = (ID3D11DeviceContext*)capture->context;
+  ID3D11Device* device = (ID3D11Device*)capture->device;
+
+  safeRelease(&staging);
+  safeRelease(&duplication);
+  safeRelease(&context);
+  safeRelease(&device);
+
+  capture->staging = nullptr;
+  capture->duplication = nullptr;
+  capture->context = nullptr;
+  capture->device = nullptr;
+}
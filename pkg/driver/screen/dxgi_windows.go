@@ -0,0 +1,195 @@
+package screen
+
+// #cgo LDFLAGS: -ld3d11 -ldxgi -luuid
+// #include <stdlib.h>
+// #include "dxgi_windows.hpp"
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"time"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	dxgiDefaultFrameRate = 30
+	// dxgiReopenInterval is how often desktop duplication is retried while the desktop isn't accessible,
+	// e.g. while the secure desktop is shown.
+	dxgiReopenInterval = 100 * time.Millisecond
+)
+
+// dxgiScreen captures a monitor with DXGI Desktop Duplication. Unlike GDI, it only copies updated frames,
+// and it keeps HDR monitors' highlights by tone mapping them to SDR.
+//
+// It doesn't capture thumbnails itself since desktop duplication has to be started anyway, so the screen
+// is opened to capture a thumbnail.
+//
+// Frames are copied to system memory. Handing GPU textures straight to hardware encoders isn't
+// supported yet, since none of this module's encoders accept them.
+type dxgiScreen struct {
+	name    string
+	adapter int
+	output  int
+
+	capture *C.dxgiCapture
+	doneCh  chan struct{}
+}
+
+// registerNativeScreens registers all monitors attached to the desktop. If DXGI isn't available,
+// e.g. in a remote desktop session on old Windows, it returns false to fall back to GDI.
+func registerNativeScreens() bool {
+	var list C.dxgiOutputList
+	var errStr *C.char
+	if C.listDXGIOutputs(&list, &errStr) != 0 {
+		return false
+	}
+	defer C.freeDXGIOutputList(&list)
+
+	if list.num == 0 {
+		return false
+	}
+
+	for i := 0; i < int(list.num); i++ {
+		info := C.getDXGIOutput(&list, C.int(i))
+		priority := driver.PriorityNormal
+		if info.primary != 0 {
+			priority = driver.PriorityHigh
+		}
+
+		s := &dxgiScreen{
+			name:    C.GoString(info.name),
+			adapter: int(info.adapter),
+			output:  int(info.output),
+		}
+		driver.GetManager().Register(s, driver.Info{
+			Label:      s.name,
+			DeviceType: driver.Screen,
+			Priority:   priority,
+		})
+	}
+
+	return true
+}
+
+func (s *dxgiScreen) Open() error {
+	s.capture = &C.dxgiCapture{
+		adapter: C.int(s.adapter),
+		output:  C.int(s.output),
+	}
+
+	var errStr *C.char
+	if C.openDXGICapture(s.capture, &errStr) != 0 {
+		return fmt.Errorf("failed to open %s: %s", s.name, C.GoString(errStr))
+	}
+
+	s.doneCh = make(chan struct{})
+	return nil
+}
+
+func (s *dxgiScreen) Close() error {
+	if s.doneCh != nil {
+		close(s.doneCh)
+	}
+	if s.capture != nil {
+		C.closeDXGICapture(s.capture)
+	}
+	return nil
+}
+
+// reopen restarts desktop duplication after access was lost, e.g. by a mode change.
+func (s *dxgiScreen) reopen() error {
+	for {
+		C.closeDXGICapture(s.capture)
+
+		var errStr *C.char
+		if C.openDXGICapture(s.capture, &errStr) == 0 {
+			return nil
+		}
+
+		select {
+		case <-s.doneCh:
+			return io.EOF
+		case <-time.After(dxgiReopenInterval):
+		}
+	}
+}
+
+func (s *dxgiScreen) VideoRecord(p prop.Media) (video.Reader, error) {
+	if p.FrameRate == 0 {
+		p.FrameRate = dxgiDefaultFrameRate
+	}
+	tick := time.NewTicker(time.Duration(float32(time.Second) / p.FrameRate))
+
+	detector := newChangeDetector(currentDeliveryOptions())
+	var img *image.RGBA
+	var buf []byte
+	var captured bool
+
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		for {
+			select {
+			case <-s.doneCh:
+				tick.Stop()
+				return nil, func() {}, io.EOF
+			case <-tick.C:
+			}
+
+			// The size can change after reopening
+			width, height := int(s.capture.width), int(s.capture.height)
+			if img == nil || img.Rect.Dx() != width || img.Rect.Dy() != height {
+				img = image.NewRGBA(image.Rect(0, 0, width, height))
+				buf = make([]byte, width*height*8)
+				captured = false
+			}
+
+			dst := img.Pix
+			if s.capture.hdr != 0 {
+				dst = buf
+			}
+
+			var errStr *C.char
+			// The ticker limits the frame rate, so updates are polled without blocking
+			switch C.captureDXGIFrame(s.capture, (*C.uchar)(unsafe.Pointer(&dst[0])), 0, &errStr) {
+			case C.dxgiCaptureOK:
+				if s.capture.hdr != 0 {
+					toneMapScRGB(img, buf)
+				}
+				captured = true
+			case C.dxgiCaptureTimeout:
+				// The desktop hasn't been updated, so deliver the last frame again
+			case C.dxgiCaptureAccessLost:
+				if err := s.reopen(); err != nil {
+					return nil, func() {}, err
+				}
+				continue
+			default:
+				return nil, func() {}, fmt.Errorf("failed to capture %s: %s", s.name, C.GoString(errStr))
+			}
+
+			if captured && detector.shouldDeliver(img) {
+				return img, func() {}, nil
+			}
+		}
+	})
+	return r, nil
+}
+
+func (s *dxgiScreen) Properties() []prop.Media {
+	return []prop.Media{
+		{
+			Video: prop.Video{
+				Width:          int(s.capture.width),
+				Height:         int(s.capture.height),
+				FrameFormat:    frame.FormatRGBA,
+				DisplaySurface: prop.DisplaySurfaceMonitor,
+			},
+		},
+	}
+}
@@ -0,0 +1,84 @@
+#pragma once
+
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+enum
+{
+  dxgiCaptureError = -1,
+  dxgiCaptureOK = 0,
+  dxgiCaptureTimeout = 1,
+  dxgiCaptureAccessLost = 2,
+};
+
+typedef struct
+{
+  int adapter;
+  int output;
+  char* name;
+  int width;
+  int height;
+  int primary;
+} dxgiOutputInfo;
+
+typedef struct
+{
+  int num;
+  dxgiOutputInfo* outputs;
+} dxgiOutputList;
+
+typedef struct
+{
+  int adapter;
+  int output;
+  int width;
+  int height;
+  // hdr is set when the frames are in linear scRGB half float format instead of BGRA
+  int hdr;
+
+  void* device;
+  void* context;
+  void* duplication;
+  void* staging;
+} dxgiCapture;
+
+int listDXGIOutputs(dxgiOutputList* list, const char** errstr);
+void freeDXGIOutputList(dxgiOutputList* list);
+int openDXGICapture(dxgiCapture* capture, const char** errstr);
+int captureDXGIFrame(dxgiCapture* capture, unsigned char* buf, int timeoutMs, const char** errstr);
+void closeDXGICapture(dxgiCapture* capture);
+
+inline dxgiOutputInfo* getDXGIOutput(dxgiOutputList* list, int i)
+{
+  return &list->outputs[i];
+}
+
+#ifdef __cplusplus
+}
+#endif
+
+#ifdef __cplusplus
+#include <windows.h>
+
+template <class T>
+void safeRelease(T** p)
+{
+  if (*p)
+  {
+    (*p)->Release();
+    *p = nullptr;
+  }
+}
+
+const static char* errCreateFactory = "failed to create dxgi factory";
+const static char* errCreateDevice = "failed to create d3d11 device";
+const static char* errFindOutput = "failed to find output";
+const static char* errDuplicateOutput = "failed to duplicate output";
+const static char* errCreateStaging = "failed to create staging texture";
+const static char* errAcquireFrame = "failed to acquire frame";
+const static char* errMapFrame = "failed to map frame";
+
+#endif
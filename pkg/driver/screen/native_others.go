@@ -0,0 +1,9 @@
+// +build !linux,!windows,!darwin !linux,!cgo
+
+package screen
+
+// registerNativeScreens returns false since there's no native screen driver for this platform,
+// or cgo is disabled.
+func registerNativeScreens() bool {
+	return false
+}
@@ -0,0 +1,372 @@
+// +build cgo
+
+package screen
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/screencapturekit"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	sckDefaultFrameRate  = 30
+	sckSystemAudioLabel  = "SCKSystemAudio"
+	sckAudioSampleRate   = 48000
+	sckAudioChannelCount = 2
+	sckAudioLatency      = 20 * time.Millisecond
+)
+
+var (
+	errSCKUnavailable  = errors.New("screen: ScreenCaptureKit is not available")
+	errContentNotFound = errors.New("screen: the content is not found")
+)
+
+// Exclusions are applications and windows hidden from captured displays and applications.
+type Exclusions struct {
+	// Applications are the applications' bundle identifiers
+	Applications []string
+	// Windows are the window IDs
+	Windows []uint32
+}
+
+var (
+	exclusionsMu sync.Mutex
+	exclusions   Exclusions
+)
+
+// SetExclusions hides applications and windows from screens recorded after this call.
+// It's only supported by ScreenCaptureKit on macOS 13 or later.
+func SetExclusions(e Exclusions) {
+	exclusionsMu.Lock()
+	defer exclusionsMu.Unlock()
+	exclusions = e
+}
+
+func currentExclusions() Exclusions {
+	exclusionsMu.Lock()
+	defer exclusionsMu.Unlock()
+	return exclusions
+}
+
+// sckScreen captures a display, an application, or a window with ScreenCaptureKit.
+type sckScreen struct {
+	content screencapturekit.Content
+	stream  *screencapturekit.Stream
+}
+
+// registerNativeScreens registers displays and system audio with ScreenCaptureKit. On macOS 12 or
+// earlier, it returns false to fall back to CGDisplayStream.
+func registerNativeScreens() bool {
+	if !screencapturekit.Available() {
+		return false
+	}
+
+	contents, err := screencapturekit.Contents()
+	if err != nil {
+		return false
+	}
+
+	var mainDisplay *screencapturekit.Content
+	for i, content := range contents {
+		if content.Type != screencapturekit.Display {
+			continue
+		}
+
+		priority := driver.PriorityNormal
+		if mainDisplay == nil {
+			mainDisplay = &contents[i]
+			priority = driver.PriorityHigh
+		}
+		driver.GetManager().Register(&sckScreen{content: content}, driver.Info{
+			Label:      sckLabel(content),
+			DeviceType: driver.Screen,
+			Priority:   priority,
+		})
+	}
+
+	if mainDisplay == nil {
+		return false
+	}
+
+	driver.GetManager().Register(&sckSystemAudio{display: *mainDisplay}, driver.Info{
+		Label:      sckSystemAudioLabel,
+		DeviceType: driver.Microphone,
+		Priority:   driver.PriorityLow,
+	})
+	return true
+}
+
+func sckLabel(content screencapturekit.Content) string {
+	switch content.Type {
+	case screencapturekit.Application:
+		return fmt.Sprintf("SCKApplication:%s", content.Name)
+	case screencapturekit.Window:
+		return fmt.Sprintf("SCKWindow:%d", content.ID)
+	default:
+		return fmt.Sprintf("SCKDisplay:%d", content.ID)
+	}
+}
+
+// RegisterApplication registers a screen that captures all of an application's windows on the main display,
+// and returns its device ID. Applications aren't registered by default since they come and go.
+// It's only supported by ScreenCaptureKit on macOS 13 or later.
+func RegisterApplication(bundleID string) (string, error) {
+	return registerContent(func(c screencapturekit.Content) bool {
+		return c.Type == screencapturekit.Application && c.Name == bundleID
+	})
+}
+
+// RegisterWindow registers a screen that captures one window, and returns its device ID. The window is
+// captured even when other windows cover it. It's only supported by ScreenCaptureKit on macOS 13 or later.
+func RegisterWindow(windowID uint32) (string, error) {
+	return registerContent(func(c screencapturekit.Content) bool {
+		return c.Type == screencapturekit.Window && c.ID == windowID
+	})
+}
+
+func registerContent(match func(screencapturekit.Content) bool) (string, error) {
+	if !screencapturekit.Available() {
+		return "", errSCKUnavailable
+	}
+
+	contents, err := screencapturekit.Contents()
+	if err != nil {
+		return "", err
+	}
+
+	for _, content := range contents {
+		if !match(content) {
+			continue
+		}
+
+		label := sckLabel(content)
+		manager := driver.GetManager()
+		registered := manager.Query(func(d driver.Driver) bool { return d.Info().Label == label })
+		if len(registered) == 0 {
+			manager.Register(&sckScreen{content: content}, driver.Info{
+				Label:      label,
+				DeviceType: driver.Screen,
+				Priority:   driver.PriorityLow,
+			})
+			registered = manager.Query(func(d driver.Driver) bool { return d.Info().Label == label })
+		}
+		return registered[0].ID(), nil
+	}
+
+	return "", errContentNotFound
+}
+
+func (s *sckScreen) Open() error {
+	return nil
+}
+
+func (s *sckScreen) Close() error {
+	if s.stream != nil {
+		return s.stream.Close()
+	}
+	return nil
+}
+
+func (s *sckScreen) VideoRecord(p prop.Media) (video.Reader, error) {
+	if p.FrameRate == 0 {
+		p.FrameRate = sckDefaultFrameRate
+	}
+
+	e := currentExclusions()
+	stream, err := screencapturekit.Open(screencapturekit.Config{
+		Content:              s.content,
+		ExcludedApplications: e.Applications,
+		ExcludedWindows:      e.Windows,
+		Width:                p.Width,
+		Height:               p.Height,
+		FrameRate:            int(p.FrameRate),
+		ShowsCursor:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.stream = stream
+
+	// ScreenCaptureKit only delivers frames when the content changes. Without OnlyOnChange,
+	// the last frame is delivered again to keep the frame rate.
+	options := currentDeliveryOptions()
+	maxIdle := time.Duration(float32(time.Second) / p.FrameRate)
+	if options.OnlyOnChange {
+		maxIdle = options.MaxIdleInterval
+		if maxIdle <= 0 {
+			maxIdle = defaultMaxIdleInterval
+		}
+	}
+
+	frames := make(chan *image.RGBA)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			img, err := stream.ReadVideo()
+			if err != nil {
+				errCh <- err
+				close(frames)
+				return
+			}
+			frames <- img
+		}
+	}()
+
+	var last *image.RGBA
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		timer := time.NewTimer(maxIdle)
+		defer timer.Stop()
+
+		for {
+			select {
+			case img, ok := <-frames:
+				if !ok {
+					return nil, func() {}, <-errCh
+				}
+				last = img
+				return img, func() {}, nil
+			case <-timer.C:
+				if last != nil {
+					return last, func() {}, nil
+				}
+				timer.Reset(maxIdle)
+			}
+		}
+	})
+	return r, nil
+}
+
+// Thumbnail captures a frame at thumbnail size, which ScreenCaptureKit scales.
+func (s *sckScreen) Thumbnail(width int) (image.Image, error) {
+	if width <= 0 {
+		width = s.content.Width
+	}
+
+	e := currentExclusions()
+	stream, err := screencapturekit.Open(screencapturekit.Config{
+		Content:              s.content,
+		ExcludedApplications: e.Applications,
+		ExcludedWindows:      e.Windows,
+		Width:                width,
+		Height:               thumbnailHeight(s.content.Width, s.content.Height, width),
+		FrameRate:            1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	img, err := stream.ReadVideo()
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func (s *sckScreen) Properties() []prop.Media {
+	return []prop.Media{
+		{
+			Video: prop.Video{
+				Width:          s.content.Width,
+				Height:         s.content.Height,
+				FrameFormat:    frame.FormatRGBA,
+				DisplaySurface: sckDisplaySurface(s.content),
+			},
+		},
+	}
+}
+
+func sckDisplaySurface(content screencapturekit.Content) string {
+	switch content.Type {
+	case screencapturekit.Application:
+		return prop.DisplaySurfaceApplication
+	case screencapturekit.Window:
+		return prop.DisplaySurfaceWindow
+	default:
+		return prop.DisplaySurfaceMonitor
+	}
+}
+
+// sckSystemAudio captures system audio with ScreenCaptureKit, excluding the current process's audio.
+type sckSystemAudio struct {
+	display screencapturekit.Content
+	stream  *screencapturekit.Stream
+}
+
+func (a *sckSystemAudio) Open() error {
+	return nil
+}
+
+func (a *sckSystemAudio) Close() error {
+	if a.stream != nil {
+		return a.stream.Close()
+	}
+	return nil
+}
+
+func (a *sckSystemAudio) AudioRecord(p prop.Media) (audio.Reader, error) {
+	// ScreenCaptureKit always captures video along with audio, so the smallest frames are requested
+	stream, err := screencapturekit.Open(screencapturekit.Config{
+		Content:       a.display,
+		Width:         2,
+		Height:        2,
+		FrameRate:     1,
+		CapturesAudio: true,
+		SampleRate:    p.SampleRate,
+		ChannelCount:  p.ChannelCount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.stream = stream
+
+	var reader audio.Reader = audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, err := stream.ReadAudio()
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		samples := 0
+		if len(chunk.Data) > 0 {
+			samples = len(chunk.Data[0])
+		}
+		decoded := &wave.Float32NonInterleaved{
+			Data: chunk.Data,
+			Size: wave.ChunkInfo{
+				Len:          samples,
+				Channels:     chunk.Channels,
+				SamplingRate: p.SampleRate,
+			},
+		}
+		return decoded, func() {}, nil
+	})
+
+	// ScreenCaptureKit's chunk size isn't configurable
+	nSamples := int(sckAudioLatency.Seconds() * float64(p.SampleRate))
+	return audio.NewBuffer(nSamples)(reader), nil
+}
+
+func (a *sckSystemAudio) Properties() []prop.Media {
+	return []prop.Media{
+		{
+			Audio: prop.Audio{
+				ChannelCount:  sckAudioChannelCount,
+				SampleRate:    sckAudioSampleRate,
+				SampleSize:    4,
+				IsFloat:       true,
+				IsInterleaved: false,
+				Latency:       sckAudioLatency,
+			},
+		},
+	}
+}
@@ -0,0 +1,39 @@
+// +build cgo
+
+package screen
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/screencapturekit"
+)
+
+func TestSCKContent(t *testing.T) {
+	for _, c := range []struct {
+		content        screencapturekit.Content
+		label, surface string
+	}{
+		{screencapturekit.Content{Type: screencapturekit.Display, ID: 1}, "SCKDisplay:1", prop.DisplaySurfaceMonitor},
+		{screencapturekit.Content{Type: screencapturekit.Application, Name: "com.apple.Safari"}, "SCKApplication:com.apple.Safari", prop.DisplaySurfaceApplication},
+		{screencapturekit.Content{Type: screencapturekit.Window, ID: 42}, "SCKWindow:42", prop.DisplaySurfaceWindow},
+	} {
+		if label := sckLabel(c.content); label != c.label {
+			t.Fatalf("expected the label %s, but got %s", c.label, label)
+		}
+		screen := &sckScreen{content: c.content}
+		if surface := screen.Properties()[0].DisplaySurface; surface != c.surface {
+			t.Fatalf("expected the display surface %s of %s, but got %s", c.surface, c.label, surface)
+		}
+	}
+}
+
+func TestSetExclusions(t *testing.T) {
+	defer SetExclusions(Exclusions{})
+
+	SetExclusions(Exclusions{Applications: []string{"com.apple.Terminal"}, Windows: []uint32{42}})
+	e := currentExclusions()
+	if len(e.Applications) != 1 || e.Applications[0] != "com.apple.Terminal" || len(e.Windows) != 1 || e.Windows[0] != 42 {
+		t.Fatalf("expected the exclusions to be kept for the next screens, but got %+v", e)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"time"
 
 	"github.com/kbinani/screenshot"
 	"github.com/pion/mediadevices/pkg/driver"
@@ -20,6 +21,10 @@ type screen struct {
 }
 
 func init() {
+	if registerNativeScreens() {
+		return
+	}
+
 	activeDisplays := screenshot.NumActiveDisplays()
 	for i := 0; i < activeDisplays; i++ {
 		priority := driver.PriorityNormal
@@ -54,27 +59,56 @@ func (s *screen) Close() error {
 }
 
 func (s *screen) VideoRecord(selectedProp prop.Media) (video.Reader, error) {
+	detector := newChangeDetector(currentDeliveryOptions())
+	// Unchanged frames are polled at the frame rate instead of being captured continuously
+	frameRate := selectedProp.FrameRate
+	if frameRate == 0 {
+		frameRate = 10
+	}
+	pollInterval := time.Duration(float32(time.Second) / frameRate)
+
 	r := video.ReaderFunc(func() (img image.Image, release func(), err error) {
-		select {
-		case <-s.doneCh:
-			return nil, nil, io.EOF
-		default:
-		}
+		for {
+			select {
+			case <-s.doneCh:
+				return nil, nil, io.EOF
+			default:
+			}
 
-		img, err = screenshot.CaptureDisplay(s.displayIndex)
-		release = func() {}
-		return
+			rgba, err := screenshot.CaptureDisplay(s.displayIndex)
+			if err != nil {
+				return nil, func() {}, err
+			}
+			if detector.shouldDeliver(rgba) {
+				return rgba, func() {}, nil
+			}
+
+			select {
+			case <-s.doneCh:
+				return nil, nil, io.EOF
+			case <-time.After(pollInterval):
+			}
+		}
 	})
 	return r, nil
 }
 
+func (s *screen) Thumbnail(width int) (image.Image, error) {
+	rgba, err := screenshot.CaptureDisplay(s.displayIndex)
+	if err != nil {
+		return nil, err
+	}
+	return thumbnail(rgba, width), nil
+}
+
 func (s *screen) Properties() []prop.Media {
 	resolution := screenshot.GetDisplayBounds(s.displayIndex)
 	supportedProp := prop.Media{
 		Video: prop.Video{
-			Width:       resolution.Dx(),
-			Height:      resolution.Dy(),
-			FrameFormat: frame.FormatRGBA,
+			Width:          resolution.Dx(),
+			Height:         resolution.Dy(),
+			FrameFormat:    frame.FormatRGBA,
+			DisplaySurface: prop.DisplaySurfaceMonitor,
 		},
 	}
 	return []prop.Media{supportedProp}
@@ -0,0 +1,32 @@
+package screen
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// thumbnail scales img to width, keeping the aspect ratio. img's size is kept if width isn't positive.
+func thumbnail(img image.Image, width int) *image.RGBA {
+	b := img.Bounds()
+	if width <= 0 {
+		width = b.Dx()
+	}
+	height := thumbnailHeight(b.Dx(), b.Dy(), width)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(dst, dst.Rect, img, b, draw.Src, nil)
+	return dst
+}
+
+// thumbnailHeight returns the height of a w x h screen scaled to width.
+func thumbnailHeight(w, h, width int) int {
+	if w <= 0 {
+		return 1
+	}
+	height := h * width / w
+	if height < 1 {
+		return 1
+	}
+	return height
+}
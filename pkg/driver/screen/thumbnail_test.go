@@ -0,0 +1,36 @@
+package screen
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for i := range src.Pix {
+		src.Pix[i] = 0x80
+	}
+
+	testCases := map[string]struct {
+		width    int
+		expected image.Rectangle
+	}{
+		"Scaled":   {width: 16, expected: image.Rect(0, 0, 16, 12)},
+		"Original": {width: 0, expected: image.Rect(0, 0, 64, 48)},
+		"Tiny":     {width: 1, expected: image.Rect(0, 0, 1, 1)},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			img := thumbnail(src, c.width)
+			if img.Bounds() != c.expected {
+				t.Fatalf("expected %v, but got %v", c.expected, img.Bounds())
+			}
+			expected := color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0x80}
+			if actual := img.RGBAAt(0, 0); actual != expected {
+				t.Fatalf("expected %v, but got %v", expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package screen
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+)
+
+const (
+	// sdrWhiteLevel is the scRGB value of SDR reference white. scRGB defines 1.0 as 80 nits, and Windows'
+	// default SDR content brightness is 200 nits.
+	sdrWhiteLevel = 200.0 / 80.0
+	// toneMapKnee is the value, relative to SDR white, where highlights start to be compressed
+	toneMapKnee   = 0.75
+	srgbTableSize = 4096
+)
+
+var srgbTable = func() [srgbTableSize + 1]uint8 {
+	var table [srgbTableSize + 1]uint8
+	for i := range table {
+		v := float64(i) / srgbTableSize
+		if v <= 0.0031308 {
+			v *= 12.92
+		} else {
+			v = 1.055*math.Pow(v, 1/2.4) - 0.055
+		}
+		table[i] = uint8(math.Round(v * 0xFF))
+	}
+	return table
+}()
+
+// halfToFloat decodes an IEEE 754 half-precision float.
+func halfToFloat(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1F
+	frac := uint32(h) & 0x3FF
+
+	switch exp {
+	case 0:
+		// Subnormal
+		v := float32(frac) / (1 << 24)
+		if sign != 0 {
+			v = -v
+		}
+		return v
+	case 0x1F:
+		return math.Float32frombits(sign | 0x7F800000 | frac<<13)
+	default:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | frac<<13)
+	}
+}
+
+// toneMap compresses a linear value relative to SDR white into [0, 1]. Values below the knee are kept
+// as is, and highlights above it are compressed smoothly with the Reinhard operator.
+func toneMap(v float32) float32 {
+	if v <= toneMapKnee {
+		if v < 0 {
+			// Out of sRGB gamut
+			return 0
+		}
+		return v
+	}
+
+	t := (v - toneMapKnee) / (1 - toneMapKnee)
+	return toneMapKnee + (1-toneMapKnee)*t/(1+t)
+}
+
+// toneMapScRGB converts a frame in linear scRGB half float, the format HDR outputs use, to sRGB.
+func toneMapScRGB(dst *image.RGBA, src []byte) {
+	for i, j := 0, 0; i+8 <= len(src) && j+4 <= len(dst.Pix); i, j = i+8, j+4 {
+		for c := 0; c < 3; c++ {
+			v := halfToFloat(binary.LittleEndian.Uint16(src[i+c*2:])) / sdrWhiteLevel
+			dst.Pix[j+c] = srgbTable[int(toneMap(v)*srgbTableSize)]
+		}
+		dst.Pix[j+3] = 0xFF
+	}
+}
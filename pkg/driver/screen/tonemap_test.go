@@ -0,0 +1,62 @@
+package screen
+
+import (
+	"encoding/binary"
+	"image"
+	"testing"
+)
+
+func TestHalfToFloat(t *testing.T) {
+	testCases := map[uint16]float32{
+		0x0000: 0,
+		0x3C00: 1,
+		0xC000: -2,
+		0x3800: 0.5,
+		0x0001: 1.0 / (1 << 24),
+		0x7BFF: 65504,
+	}
+
+	for h, expected := range testCases {
+		if v := halfToFloat(h); v != expected {
+			t.Errorf("expected %#04x to be %f, but got %f", h, expected, v)
+		}
+	}
+}
+
+func TestToneMapScRGB(t *testing.T) {
+	pixel := func(r, g, b uint16) []byte {
+		px := make([]byte, 8)
+		binary.LittleEndian.PutUint16(px[0:], r)
+		binary.LittleEndian.PutUint16(px[2:], g)
+		binary.LittleEndian.PutUint16(px[4:], b)
+		binary.LittleEndian.PutUint16(px[6:], 0x3C00)
+		return px
+	}
+
+	var src []byte
+	src = append(src, pixel(0x0000, 0x0000, 0x0000)...) // black
+	src = append(src, pixel(0x4100, 0x4100, 0x4100)...) // 2.5, SDR white
+	src = append(src, pixel(0x5640, 0x5640, 0x5640)...) // 100, very bright highlight
+	src = append(src, pixel(0xBC00, 0x0000, 0x0000)...) // out of sRGB gamut
+
+	dst := image.NewRGBA(image.Rect(0, 0, 4, 1))
+	toneMapScRGB(dst, src)
+
+	if px := dst.RGBAAt(0, 0); px.R != 0 || px.A != 0xFF {
+		t.Errorf("expected black, but got %v", px)
+	}
+
+	white := dst.RGBAAt(1, 0)
+	if white.R < 0xE0 || white.R == 0xFF {
+		t.Errorf("expected SDR white to be bright with some headroom, but got %v", white)
+	}
+
+	highlight := dst.RGBAAt(2, 0)
+	if highlight.R <= white.R {
+		t.Errorf("expected highlight to be brighter than SDR white, but got %v and %v", highlight, white)
+	}
+
+	if px := dst.RGBAAt(3, 0); px.R != 0 {
+		t.Errorf("expected negative value to be clamped, but got %v", px)
+	}
+}
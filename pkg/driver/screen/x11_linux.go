@@ -67,14 +67,32 @@ func (s *screen) VideoRecord(p prop.Media) (video.Reader, error) {
 
 	var dst image.RGBA
 	reader := s.reader
+	detector := newChangeDetector(currentDeliveryOptions())
 
 	r := video.ReaderFunc(func() (image.Image, func(), error) {
-		<-s.tick.C
-		return reader.Read().ToRGBA(&dst), func() {}, nil
+		for {
+			<-s.tick.C
+			img := reader.Read().ToRGBA(&dst)
+			if detector.shouldDeliver(img) {
+				return img, func() {}, nil
+			}
+		}
 	})
 	return r, nil
 }
 
+// Thumbnail captures the screen with its own display connection, so it works while recording.
+func (s *screen) Thumbnail(width int) (image.Image, error) {
+	r, err := newReader(s.num)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var dst image.RGBA
+	return thumbnail(r.Read().ToRGBA(&dst), width), nil
+}
+
 func (s *screen) Properties() []prop.Media {
 	rect := s.reader.img.Bounds()
 	w := rect.Dx()
@@ -83,9 +101,10 @@ func (s *screen) Properties() []prop.Media {
 		{
 			DeviceID: deviceID(s.num),
 			Video: prop.Video{
-				Width:       w,
-				Height:      h,
-				FrameFormat: frame.FormatRGBA,
+				Width:          w,
+				Height:         h,
+				FrameFormat:    frame.FormatRGBA,
+				DisplaySurface: prop.DisplaySurfaceMonitor,
 			},
 		},
 	}
@@ -0,0 +1,81 @@
+package speaker
+
+import "sync"
+
+// fifo passes samples from the track's reader to the device callback. write blocks while the fifo is
+// full, so the device clock paces the reader, and read never blocks since it's called from the audio
+// thread.
+type fifo struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	buf    []byte
+	head   int
+	len    int
+	closed bool
+	// underruns is the number of reads filled with silence.
+	underruns int
+}
+
+func newFIFO(size int) *fifo {
+	f := &fifo{buf: make([]byte, size)}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// write copies b into the fifo. It returns false if the fifo is closed.
+func (f *fifo) write(b []byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(b) > 0 {
+		for f.len == len(f.buf) && !f.closed {
+			f.cond.Wait()
+		}
+		if f.closed {
+			return false
+		}
+
+		tail := (f.head + f.len) % len(f.buf)
+		end := len(f.buf)
+		if tail < f.head {
+			end = f.head
+		}
+		n := copy(f.buf[tail:end], b)
+		f.len += n
+		b = b[n:]
+	}
+	return true
+}
+
+// read fills b with the fifo's samples, and the rest of b with silence.
+func (f *fifo) read(b []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for n < len(b) && f.len > 0 {
+		end := f.head + f.len
+		if end > len(f.buf) {
+			end = len(f.buf)
+		}
+		m := copy(b[n:], f.buf[f.head:end])
+		f.head = (f.head + m) % len(f.buf)
+		f.len -= m
+		n += m
+	}
+	if n < len(b) {
+		for i := n; i < len(b); i++ {
+			b[i] = 0
+		}
+		f.underruns++
+	}
+	f.cond.Broadcast()
+}
+
+// close unblocks the writer.
+func (f *fifo) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.cond.Broadcast()
+}
@@ -0,0 +1,56 @@
+package speaker
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFIFO(t *testing.T) {
+	f := newFIFO(4)
+
+	if !f.write([]byte{1, 2, 3}) {
+		t.Fatal("expected to write")
+	}
+	b := make([]byte, 2)
+	f.read(b)
+	if !bytes.Equal(b, []byte{1, 2}) {
+		t.Fatalf("expected [1 2], but got %v", b)
+	}
+
+	// Wraps around
+	if !f.write([]byte{4, 5, 6}) {
+		t.Fatal("expected to write")
+	}
+	b = make([]byte, 6)
+	f.read(b)
+	if !bytes.Equal(b, []byte{3, 4, 5, 6, 0, 0}) {
+		t.Fatalf("expected [3 4 5 6 0 0], but got %v", b)
+	}
+	if f.underruns != 1 {
+		t.Fatalf("expected 1 underrun, but got %d", f.underruns)
+	}
+
+	// The writer is blocked until samples are read.
+	done := make(chan bool)
+	go func() {
+		done <- f.write([]byte{1, 2, 3, 4, 5, 6})
+	}()
+	select {
+	case <-done:
+		t.Fatal("expected the writer to be blocked")
+	case <-time.After(10 * time.Millisecond):
+	}
+	f.read(make([]byte, 4))
+	if !<-done {
+		t.Fatal("expected to write")
+	}
+
+	go func() {
+		done <- f.write([]byte{1, 2, 3, 4})
+	}()
+	f.close()
+	if <-done {
+		t.Fatal("expected the write to fail after close")
+	}
+}
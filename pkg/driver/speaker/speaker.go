@@ -0,0 +1,237 @@
+// Package speaker registers audio output devices as drivers that render an audio.Reader, e.g. a remote
+// track's decoded samples, using miniaudio on every platform. Use mediadevices.NewPlayer to play.
+package speaker
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/gen2brain/malgo"
+	"github.com/pion/mediadevices/internal/logging"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	// fifoPeriods is the size, in periods, of the fifo between the reader and the device.
+	fifoPeriods = 4
+	// defaultLatency is the device period.
+	defaultLatency = 20 * time.Millisecond
+)
+
+// sampleRates are the sampling rates to negotiate with the device. The first one is the default.
+var sampleRates = []int{48000, 44100}
+
+var logger = logging.NewLogger("mediadevices/driver/speaker")
+var ctx *malgo.AllocatedContext
+
+var (
+	errUnsupportedFormat = errors.New("the provided audio format is not supported")
+	errUnexpectedType    = errors.New("the audio doesn't match the format of the device")
+)
+
+type speaker struct {
+	malgo.DeviceInfo
+	mu     sync.Mutex
+	device *malgo.Device
+	fifo   *fifo
+}
+
+func init() {
+	var err error
+	ctx, err = malgo.InitContext(nil, malgo.ContextConfig{}, func(message string) {
+		logger.Debugf("%v\n", message)
+	})
+	if err != nil {
+		logger.Debugf("failed to initialize context: %s\n", err)
+		return
+	}
+
+	devices, err := ctx.Devices(malgo.Playback)
+	if err != nil {
+		logger.Debugf("failed to enumerate devices: %s\n", err)
+		return
+	}
+
+	for _, device := range devices {
+		info, err := ctx.DeviceInfo(malgo.Playback, device.ID, malgo.Shared)
+		if err != nil {
+			info = device
+		}
+
+		priority := driver.PriorityNormal
+		if info.IsDefault > 0 {
+			priority = driver.PriorityHigh
+		}
+		driver.GetManager().Register(&speaker{DeviceInfo: info}, driver.Info{
+			Label:      device.ID.String(),
+			DeviceType: driver.Speaker,
+			Priority:   priority,
+		})
+	}
+}
+
+func (s *speaker) Open() error {
+	return nil
+}
+
+func (s *speaker) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fifo != nil {
+		s.fifo.close()
+		s.fifo = nil
+	}
+	if s.device != nil {
+		s.device.Uninit()
+		s.device = nil
+	}
+	return nil
+}
+
+func (s *speaker) AudioPlay(p prop.Media, r audio.Reader) error {
+	var config malgo.DeviceConfig
+	config.DeviceType = malgo.Playback
+	config.PerformanceProfile = malgo.LowLatency
+	config.Playback.DeviceID = s.ID.Pointer()
+	config.Playback.Channels = uint32(p.ChannelCount)
+	config.SampleRate = uint32(p.SampleRate)
+
+	latency := p.Latency
+	if latency <= 0 {
+		latency = defaultLatency
+	}
+	config.PeriodSizeInMilliseconds = uint32(latency / time.Millisecond)
+
+	switch {
+	case p.SampleSize == 4 && p.IsFloat:
+		config.Playback.Format = malgo.FormatF32
+	case p.SampleSize == 4 && !p.IsFloat:
+		config.Playback.Format = malgo.FormatS32
+	case p.SampleSize == 2 && !p.IsFloat:
+		config.Playback.Format = malgo.FormatS16
+	default:
+		return errUnsupportedFormat
+	}
+
+	frameSize := p.SampleSize * p.ChannelCount
+	periodSize := int(int64(p.SampleRate)*int64(latency)/int64(time.Second)) * frameSize
+	f := newFIFO(periodSize * fifoPeriods)
+
+	callbacks := malgo.DeviceCallbacks{
+		Data: func(out, _ []byte, _ uint32) {
+			f.read(out)
+		},
+	}
+	device, err := malgo.InitDevice(ctx.Context, config, callbacks)
+	if err != nil {
+		return err
+	}
+	if err := device.Start(); err != nil {
+		device.Uninit()
+		return err
+	}
+
+	s.mu.Lock()
+	s.device = device
+	s.fifo = f
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			chunk, release, err := r.Read()
+			if err != nil {
+				if err != io.EOF {
+					logger.Errorf("failed to read audio: %s", err)
+				}
+				return
+			}
+
+			b, err := chunkBytes(chunk, p)
+			if err != nil {
+				release()
+				logger.Errorf("failed to play %T: %s", chunk, err)
+				return
+			}
+			ok := f.write(b)
+			release()
+			if !ok {
+				// The speaker is closed.
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// chunkBytes returns the chunk's samples in the device's memory layout, which is interleaved
+// samples in host endianness.
+func chunkBytes(chunk wave.Audio, p prop.Media) ([]byte, error) {
+	info := chunk.ChunkInfo()
+	if info.Len == 0 {
+		return nil, nil
+	}
+	if info.Channels != p.ChannelCount {
+		return nil, errUnexpectedType
+	}
+
+	n := info.Len * info.Channels
+	switch c := chunk.(type) {
+	case *wave.Float32Interleaved:
+		if p.SampleSize == 4 && p.IsFloat {
+			return (*[1 << 30]byte)(unsafe.Pointer(&c.Data[0]))[: n*4 : n*4], nil
+		}
+	case *wave.Int32Interleaved:
+		if p.SampleSize == 4 && !p.IsFloat {
+			return (*[1 << 30]byte)(unsafe.Pointer(&c.Data[0]))[: n*4 : n*4], nil
+		}
+	case *wave.Int16Interleaved:
+		if p.SampleSize == 2 && !p.IsFloat {
+			return (*[1 << 30]byte)(unsafe.Pointer(&c.Data[0]))[: n*2 : n*2], nil
+		}
+	}
+	return nil, errUnexpectedType
+}
+
+func (s *speaker) Properties() []prop.Media {
+	minChannels, maxChannels := int(s.MinChannels), int(s.MaxChannels)
+	if maxChannels == 0 || maxChannels > 2 {
+		// Tracks are mono or stereo.
+		minChannels, maxChannels = 1, 2
+	}
+
+	var props []prop.Media
+	for ch := minChannels; ch <= maxChannels; ch++ {
+		for _, sampleRate := range sampleRates {
+			if s.MinSampleRate > 0 && uint32(sampleRate) < s.MinSampleRate {
+				continue
+			}
+			if s.MaxSampleRate > 0 && uint32(sampleRate) > s.MaxSampleRate {
+				continue
+			}
+			// miniaudio converts samples into the device's native format.
+			for _, f := range []struct {
+				sampleSize int
+				isFloat    bool
+			}{{4, true}, {2, false}, {4, false}} {
+				props = append(props, prop.Media{
+					Audio: prop.Audio{
+						ChannelCount:  ch,
+						SampleRate:    sampleRate,
+						SampleSize:    f.sampleSize,
+						IsFloat:       f.isFloat,
+						IsInterleaved: true,
+						Latency:       defaultLatency,
+					},
+				})
+			}
+		}
+	}
+	return props
+}
@@ -0,0 +1,211 @@
+package virtualcam
+
+// #include <linux/videodev2.h>
+// #include <string.h>
+// #include <sys/ioctl.h>
+//
+// // v4l2IsOutput returns 1 if the device at fd is a video output device like v4l2loopback.
+// static int v4l2IsOutput(int fd) {
+//   struct v4l2_capability cap;
+//   memset(&cap, 0, sizeof(cap));
+//   if (ioctl(fd, VIDIOC_QUERYCAP, &cap) < 0) {
+//     return 0;
+//   }
+//   unsigned int caps = cap.capabilities;
+//   if (caps & V4L2_CAP_DEVICE_CAPS) {
+//     caps = cap.device_caps;
+//   }
+//   return (caps & V4L2_CAP_VIDEO_OUTPUT) && (caps & V4L2_CAP_READWRITE);
+// }
+//
+// static int v4l2SetFormat(int fd, unsigned int width, unsigned int height, unsigned int fourcc,
+//     unsigned int bytesPerLine, unsigned int sizeImage) {
+//   struct v4l2_format fmt;
+//   memset(&fmt, 0, sizeof(fmt));
+//   fmt.type = V4L2_BUF_TYPE_VIDEO_OUTPUT;
+//   fmt.fmt.pix.width = width;
+//   fmt.fmt.pix.height = height;
+//   fmt.fmt.pix.pixelformat = fourcc;
+//   fmt.fmt.pix.field = V4L2_FIELD_NONE;
+//   fmt.fmt.pix.bytesperline = bytesPerLine;
+//   fmt.fmt.pix.sizeimage = sizeImage;
+//   fmt.fmt.pix.colorspace = V4L2_COLORSPACE_SRGB;
+//   if (ioctl(fd, VIDIOC_S_FMT, &fmt) < 0) {
+//     return -1;
+//   }
+//   if (fmt.fmt.pix.width != width || fmt.fmt.pix.height != height || fmt.fmt.pix.pixelformat != fourcc) {
+//     return -2;
+//   }
+//   return 0;
+// }
+//
+// // v4l2SetFrameRate tells the device's readers the frame rate.
+// static int v4l2SetFrameRate(int fd, unsigned int numerator, unsigned int denominator) {
+//   struct v4l2_streamparm parm;
+//   memset(&parm, 0, sizeof(parm));
+//   parm.type = V4L2_BUF_TYPE_VIDEO_OUTPUT;
+//   parm.parm.output.timeperframe.numerator = numerator;
+//   parm.parm.output.timeperframe.denominator = denominator;
+//   return ioctl(fd, VIDIOC_S_PARM, &parm);
+// }
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// fourccs are the V4L2 pixel formats of frameFormats.
+var fourccs = map[frame.Format]C.uint{
+	frame.FormatYUYV: C.V4L2_PIX_FMT_YUYV,
+	frame.FormatI420: C.V4L2_PIX_FMT_YUV420,
+}
+
+type virtualCamera struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+	done chan struct{}
+}
+
+func init() {
+	paths, _ := filepath.Glob("/dev/video*")
+	for _, path := range paths {
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			continue
+		}
+		isOutput := C.v4l2IsOutput(C.int(f.Fd())) != 0
+		f.Close()
+		if !isOutput {
+			continue
+		}
+
+		driver.GetManager().Register(&virtualCamera{path: path}, driver.Info{
+			Label:      path,
+			DeviceType: driver.VirtualCamera,
+			Priority:   driver.PriorityNormal,
+		})
+	}
+}
+
+func (c *virtualCamera) Open() error {
+	f, err := os.OpenFile(c.path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.file = f
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *virtualCamera) Close() error {
+	c.mu.Lock()
+	f, done := c.file, c.done
+	c.file, c.done = nil, nil
+	c.mu.Unlock()
+
+	if done != nil {
+		close(done)
+	}
+	if f == nil {
+		return nil
+	}
+	return f.Close()
+}
+
+func (c *virtualCamera) VideoPlay(p prop.Media, r video.Reader) error {
+	fourcc, ok := fourccs[p.FrameFormat]
+	if !ok {
+		return errUnsupportedFormat
+	}
+	size, err := frameSize(p.FrameFormat, p.Width, p.Height)
+	if err != nil {
+		return err
+	}
+	bytesPerLine := p.Width
+	if p.FrameFormat == frame.FormatYUYV {
+		bytesPerLine = size / p.Height
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f := c.file
+	fd := C.int(f.Fd())
+	switch C.v4l2SetFormat(fd, C.uint(p.Width), C.uint(p.Height), fourcc, C.uint(bytesPerLine), C.uint(size)) {
+	case 0:
+	case -2:
+		return fmt.Errorf("failed to set the format: %dx%d %s is not accepted by %s", p.Width, p.Height, p.FrameFormat, c.path)
+	default:
+		return fmt.Errorf("failed to set the format of %s", c.path)
+	}
+	if p.FrameRate > 0 {
+		if C.v4l2SetFrameRate(fd, 1000, C.uint(p.FrameRate*1000)) < 0 {
+			logger.Debugf("failed to set the frame rate of %s\n", c.path)
+		}
+	}
+
+	done := make(chan struct{})
+	c.done = done
+	go func() {
+		buf := make([]byte, size)
+		for {
+			img, release, err := r.Read()
+			if err != nil {
+				logger.Debugf("stopped playing to %s: %s\n", c.path, err)
+				return
+			}
+
+			yuv, ok := img.(*image.YCbCr)
+			if !ok || yuv.Rect.Dx() != p.Width || yuv.Rect.Dy() != p.Height {
+				release()
+				logger.Debugf("stopped playing to %s: %s\n", c.path, errUnexpectedSize)
+				return
+			}
+			err = pack(buf, p.FrameFormat, yuv)
+			release()
+			if err != nil {
+				return
+			}
+
+			select {
+			case <-done:
+				return
+			default:
+			}
+			// A frame has to be written in one go.
+			if _, err := f.Write(buf); err != nil {
+				logger.Debugf("failed to write a frame to %s: %s\n", c.path, err)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (c *virtualCamera) Properties() []prop.Media {
+	var properties []prop.Media
+	for _, format := range frameFormats {
+		for _, size := range sizes {
+			properties = append(properties, prop.Media{
+				Video: prop.Video{
+					Width:       size.X,
+					Height:      size.Y,
+					FrameFormat: format,
+					FrameRate:   30,
+				},
+			})
+		}
+	}
+	return properties
+}
@@ -0,0 +1,97 @@
+// Package virtualcam registers virtual camera devices as drivers that render a video.Reader, e.g. composited or
+// processed video, so other applications like video conferencing can use it as a camera. Use
+// mediadevices.NewVideoPlayer to play.
+//
+// On Linux, v4l2loopback (https://github.com/umlaeute/v4l2loopback) output devices are registered, e.g.
+//
+//	sudo modprobe v4l2loopback exclusive_caps=1 card_label="mediadevices"
+//
+// Virtual cameras on Windows (DirectShow filters like OBS Virtual Camera) and macOS (CoreMediaIO extensions)
+// are provided by plugins installed and signed together with their applications, and the protocols between
+// application and plugin are private. They aren't supported yet.
+package virtualcam
+
+import (
+	"errors"
+	"image"
+
+	"github.com/pion/mediadevices/internal/logging"
+	"github.com/pion/mediadevices/pkg/frame"
+)
+
+var logger = logging.NewLogger("mediadevices/driver/virtualcam")
+
+var (
+	errUnsupportedFormat = errors.New("the provided frame format is not supported")
+	errUnexpectedSize    = errors.New("the image doesn't match the size of the device")
+)
+
+// sizes are common camera resolutions to negotiate. The device accepts any size, and the first one is the
+// default.
+var sizes = []image.Point{{1280, 720}, {1920, 1080}, {640, 480}}
+
+// frameFormats are the formats written to the device. YUYV comes first since most applications
+// support it.
+var frameFormats = []frame.Format{frame.FormatYUYV, frame.FormatI420}
+
+// frameSize returns the frame's size in bytes.
+func frameSize(format frame.Format, width, height int) (int, error) {
+	switch format {
+	case frame.FormatYUYV:
+		return (width + 1) / 2 * 4 * height, nil
+	case frame.FormatI420:
+		return width*height + 2*((width+1)/2)*((height+1)/2), nil
+	default:
+		return 0, errUnsupportedFormat
+	}
+}
+
+// pack writes img into dst in format. dst has to be frameSize long.
+func pack(dst []byte, format frame.Format, img *image.YCbCr) error {
+	switch format {
+	case frame.FormatYUYV:
+		packYUYV(dst, img)
+	case frame.FormatI420:
+		packI420(dst, img)
+	default:
+		return errUnsupportedFormat
+	}
+	return nil
+}
+
+// packYUYV writes img as pixel pairs that share the left pixel's chroma samples.
+func packYUYV(dst []byte, img *image.YCbCr) {
+	r := img.Rect
+	i := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x += 2 {
+			x1 := x + 1
+			if x1 >= r.Max.X {
+				x1 = x
+			}
+			c := img.COffset(x, y)
+			dst[i] = img.Y[img.YOffset(x, y)]
+			dst[i+1] = img.Cb[c]
+			dst[i+2] = img.Y[img.YOffset(x1, y)]
+			dst[i+3] = img.Cr[c]
+			i += 4
+		}
+	}
+}
+
+// packI420 writes img as planes without padding. Chroma planes are subsampled from img if it isn't 4:2:0.
+func packI420(dst []byte, img *image.YCbCr) {
+	r := img.Rect
+	i := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		i += copy(dst[i:], img.Y[img.YOffset(r.Min.X, y):img.YOffset(r.Min.X, y)+r.Dx()])
+	}
+	for _, plane := range [][]uint8{img.Cb, img.Cr} {
+		for y := r.Min.Y; y < r.Max.Y; y += 2 {
+			for x := r.Min.X; x < r.Max.X; x += 2 {
+				dst[i] = plane[img.COffset(x, y)]
+				i++
+			}
+		}
+	}
+}
@@ -0,0 +1,7 @@
+// +build !linux
+
+package virtualcam
+
+func init() {
+	logger.Debugf("virtual cameras are not supported on this platform\n")
+}
@@ -0,0 +1,95 @@
+package virtualcam
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/frame"
+)
+
+// testImage returns a 4x2 image whose luma samples are 1, 2, ... and chroma samples are 100+i and 200+i.
+func testImage(ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, 4, 2), ratio)
+	for i := range img.Y {
+		img.Y[i] = uint8(i + 1)
+	}
+	for i := range img.Cb {
+		img.Cb[i] = uint8(100 + i)
+		img.Cr[i] = uint8(200 + i)
+	}
+	return img
+}
+
+func TestPack(t *testing.T) {
+	testCases := map[string]struct {
+		format   frame.Format
+		ratio    image.YCbCrSubsampleRatio
+		expected []byte
+	}{
+		"YUYVFrom420": {
+			format: frame.FormatYUYV,
+			ratio:  image.YCbCrSubsampleRatio420,
+			expected: []byte{
+				1, 100, 2, 200, 3, 101, 4, 201,
+				5, 100, 6, 200, 7, 101, 8, 201,
+			},
+		},
+		"YUYVFrom444": {
+			format: frame.FormatYUYV,
+			ratio:  image.YCbCrSubsampleRatio444,
+			expected: []byte{
+				1, 100, 2, 200, 3, 102, 4, 202,
+				5, 104, 6, 204, 7, 106, 8, 206,
+			},
+		},
+		"I420From420": {
+			format:   frame.FormatI420,
+			ratio:    image.YCbCrSubsampleRatio420,
+			expected: []byte{1, 2, 3, 4, 5, 6, 7, 8, 100, 101, 200, 201},
+		},
+		"I420From444": {
+			format:   frame.FormatI420,
+			ratio:    image.YCbCrSubsampleRatio444,
+			expected: []byte{1, 2, 3, 4, 5, 6, 7, 8, 100, 102, 200, 202},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			size, err := frameSize(c.format, 4, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size != len(c.expected) {
+				t.Fatalf("expected the frame size to be %d, but got %d", len(c.expected), size)
+			}
+
+			dst := make([]byte, size)
+			if err := pack(dst, c.format, testImage(c.ratio)); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(c.expected, dst) {
+				t.Fatalf("expected %v, but got %v", c.expected, dst)
+			}
+		})
+	}
+
+	t.Run("SubImage", func(t *testing.T) {
+		img := testImage(image.YCbCrSubsampleRatio420).SubImage(image.Rect(2, 0, 4, 2)).(*image.YCbCr)
+		dst := make([]byte, 6)
+		if err := pack(dst, frame.FormatI420, img); err != nil {
+			t.Fatal(err)
+		}
+		expected := []byte{3, 4, 7, 8, 101, 201}
+		if !reflect.DeepEqual(expected, dst) {
+			t.Fatalf("expected %v, but got %v", expected, dst)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		if _, err := frameSize(frame.FormatMJPEG, 4, 2); err != errUnsupportedFormat {
+			t.Fatalf("expected %v, but got %v", errUnsupportedFormat, err)
+		}
+	})
+}
@@ -12,8 +12,10 @@ func wrapAdapter(a Adapter, info Info) Driver {
 	if err != nil {
 		panic(err)
 	}
+	return wrapAdapterWithID(a, info, generator.String())
+}
 
-	id := generator.String()
+func wrapAdapterWithID(a Adapter, info Info, id string) Driver {
 	d := &adapterWrapper{
 		Adapter: a,
 		id:      id,
@@ -23,8 +25,31 @@ func wrapAdapter(a Adapter, info Info) Driver {
 
 	switch v := a.(type) {
 	case VideoRecorder:
-		// Only expose Driver and VideoRecorder interfaces
+		// Only expose Driver and VideoRecorder interfaces, and Thumbnailer and PhotoTaker if available
 		d.VideoRecorder = v
+		t, isThumbnailer := a.(Thumbnailer)
+		p, isPhotoTaker := a.(PhotoTaker)
+		switch {
+		case isThumbnailer && isPhotoTaker:
+			return &struct {
+				Driver
+				VideoRecorder
+				Thumbnailer
+				PhotoTaker
+			}{d, d, t, p}
+		case isThumbnailer:
+			return &struct {
+				Driver
+				VideoRecorder
+				Thumbnailer
+			}{d, d, t}
+		case isPhotoTaker:
+			return &struct {
+				Driver
+				VideoRecorder
+				PhotoTaker
+			}{d, d, p}
+		}
 		r := &struct {
 			Driver
 			VideoRecorder
@@ -37,8 +62,22 @@ func wrapAdapter(a Adapter, info Info) Driver {
 			Driver
 			AudioRecorder
 		}{d, d}
+	case AudioPlayer:
+		// Only expose Driver and AudioPlayer interfaces
+		d.AudioPlayer = v
+		return &struct {
+			Driver
+			AudioPlayer
+		}{d, d}
+	case VideoPlayer:
+		// Only expose Driver and VideoPlayer interfaces
+		d.VideoPlayer = v
+		return &struct {
+			Driver
+			VideoPlayer
+		}{d, d}
 	default:
-		panic("adapter has to be either VideoRecorder/AudioRecorder")
+		panic("adapter has to be either VideoRecorder/AudioRecorder/AudioPlayer/VideoPlayer")
 	}
 }
 
@@ -46,6 +85,8 @@ type adapterWrapper struct {
 	Adapter
 	VideoRecorder
 	AudioRecorder
+	AudioPlayer
+	VideoPlayer
 	id    string
 	info  Info
 	state State
@@ -63,6 +104,38 @@ func (w *adapterWrapper) Status() State {
 	return w.state
 }
 
+func (w *adapterWrapper) Configure(opts []Option) error {
+	if c, ok := w.Adapter.(Configurable); ok {
+		return c.Configure(opts)
+	}
+	return nil
+}
+
+func (w *adapterWrapper) SetControls(c Controls) error {
+	if controller, ok := w.Adapter.(Controller); ok {
+		return controller.SetControls(c)
+	}
+	if c.Empty() {
+		return nil
+	}
+	return ErrControlUnsupported
+}
+
+func (w *adapterWrapper) Probe() (Probe, error) {
+	if p, ok := w.Adapter.(Prober); ok {
+		return p.Probe()
+	}
+	return Probe{}, nil
+}
+
+func (w *adapterWrapper) RequestPermission() (Permission, error) {
+	if r, ok := w.Adapter.(PermissionRequester); ok {
+		return r.RequestPermission()
+	}
+	p, err := w.Probe()
+	return p.Permission, err
+}
+
 func (w *adapterWrapper) Open() error {
 	return w.state.Update(StateOpened, w.Adapter.Open)
 }
@@ -104,3 +177,23 @@ func (w *adapterWrapper) AudioRecord(p prop.Media) (r audio.Reader, err error) {
 	}
 	return
 }
+
+func (w *adapterWrapper) AudioPlay(p prop.Media, r audio.Reader) (err error) {
+	err = w.state.Update(StateRunning, func() error {
+		return w.AudioPlayer.AudioPlay(p, r)
+	})
+	if err != nil {
+		_ = w.Close()
+	}
+	return
+}
+
+func (w *adapterWrapper) VideoPlay(p prop.Media, r video.Reader) (err error) {
+	err = w.state.Update(StateRunning, func() error {
+		return w.VideoPlayer.VideoPlay(p, r)
+	})
+	if err != nil {
+		_ = w.Close()
+	}
+	return
+}
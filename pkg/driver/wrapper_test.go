@@ -2,6 +2,7 @@ package driver
 
 import (
 	"fmt"
+	"image"
 	"testing"
 
 	"github.com/pion/mediadevices/pkg/io/audio"
@@ -39,6 +40,14 @@ func (a *audioAdapterBrokenMock) AudioRecord(p prop.Media) (r audio.Reader, err
 	return nil, recordErr
 }
 
+type audioPlayerMock struct{ adapterMock }
+
+func (a *audioPlayerMock) AudioPlay(p prop.Media, r audio.Reader) error { return nil }
+
+type videoPlayerMock struct{ adapterMock }
+
+func (a *videoPlayerMock) VideoPlay(p prop.Media, r video.Reader) error { return nil }
+
 func TestVideoWrapperState(t *testing.T) {
 	var a videoAdapterMock
 	d := wrapAdapter(&a, Info{})
@@ -136,3 +145,210 @@ func TestAudioWrapperWithBrokenRecorderState(t *testing.T) {
 		t.Errorf("expected the status to be %v, but got %v", StateClosed, d.Status())
 	}
 }
+
+func TestAudioPlayerWrapperState(t *testing.T) {
+	var a audioPlayerMock
+	d := wrapAdapter(&a, Info{})
+
+	ap, ok := d.(AudioPlayer)
+	if !ok {
+		t.Fatalf("expected to be an AudioPlayer")
+	}
+	if _, ok := d.(AudioRecorder); ok {
+		t.Errorf("expected not to be an AudioRecorder")
+	}
+
+	if err := ap.AudioPlay(prop.Media{}, nil); err == nil {
+		t.Errorf("expected to get an invalid state")
+	}
+
+	if err := d.Open(); err != nil {
+		t.Errorf("expected to successfully open, but got %v", err)
+	}
+
+	if err := ap.AudioPlay(prop.Media{}, nil); err != nil {
+		t.Errorf("expected to successfully start playing, but got %v", err)
+	}
+	if d.Status() != StateRunning {
+		t.Errorf("expected the status to be %v, but got %v", StateRunning, d.Status())
+	}
+}
+
+func TestVideoPlayerWrapperState(t *testing.T) {
+	var a videoPlayerMock
+	d := wrapAdapter(&a, Info{})
+
+	vp, ok := d.(VideoPlayer)
+	if !ok {
+		t.Fatalf("expected to be a VideoPlayer")
+	}
+	if _, ok := d.(VideoRecorder); ok {
+		t.Errorf("expected not to be a VideoRecorder")
+	}
+
+	if err := vp.VideoPlay(prop.Media{}, nil); err == nil {
+		t.Errorf("expected to get an invalid state")
+	}
+
+	if err := d.Open(); err != nil {
+		t.Errorf("expected to successfully open, but got %v", err)
+	}
+
+	if err := vp.VideoPlay(prop.Media{}, nil); err != nil {
+		t.Errorf("expected to successfully start playing, but got %v", err)
+	}
+	if d.Status() != StateRunning {
+		t.Errorf("expected the status to be %v, but got %v", StateRunning, d.Status())
+	}
+}
+
+type configurableMock struct {
+	videoAdapterMock
+	opts []Option
+}
+
+func (a *configurableMock) Configure(opts []Option) error {
+	for _, opt := range opts {
+		if opt == "unsupported" {
+			return recordErr
+		}
+	}
+	a.opts = opts
+	return nil
+}
+
+func TestWrapperConfigure(t *testing.T) {
+	var a configurableMock
+	d := wrapAdapter(&a, Info{})
+	if err := d.Configure([]Option{1, "option"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.opts) != 2 {
+		t.Fatalf("expected the options to be passed, but got %v", a.opts)
+	}
+	if err := d.Configure([]Option{"unsupported"}); err != recordErr {
+		t.Fatalf("expected %v, but got %v", recordErr, err)
+	}
+
+	// Adapters that aren't Configurable ignore options.
+	var b videoAdapterMock
+	if err := wrapAdapter(&b, Info{}).Configure([]Option{1}); err != nil {
+		t.Fatalf("expected the options to be ignored, but got %v", err)
+	}
+}
+
+type thumbnailerMock struct{ videoAdapterMock }
+
+func (a *thumbnailerMock) Thumbnail(width int) (image.Image, error) {
+	return image.NewRGBA(image.Rect(0, 0, width, width/2)), nil
+}
+
+func TestWrapperThumbnailer(t *testing.T) {
+	d := wrapAdapter(&thumbnailerMock{}, Info{})
+	thumbnailer, ok := d.(Thumbnailer)
+	if !ok {
+		t.Fatal("expected the driver to be a Thumbnailer")
+	}
+	img, err := thumbnailer.Thumbnail(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := image.Rect(0, 0, 16, 8); img.Bounds() != expected {
+		t.Fatalf("expected %v, but got %v", expected, img.Bounds())
+	}
+	if _, ok := d.(VideoRecorder); !ok {
+		t.Fatal("expected the driver to be a VideoRecorder")
+	}
+	if d.Status() != StateClosed {
+		t.Fatalf("expected the state to be %s, but got %s", StateClosed, d.Status())
+	}
+
+	if _, ok := wrapAdapter(&videoAdapterMock{}, Info{}).(Thumbnailer); ok {
+		t.Fatal("expected the driver not to be a Thumbnailer")
+	}
+}
+
+type photoTakerMock struct{ videoAdapterMock }
+
+func (a *photoTakerMock) PhotoProperties() []prop.Media { return []prop.Media{{}} }
+func (a *photoTakerMock) TakePhoto(p prop.Media) (image.Image, error) {
+	return image.NewRGBA(image.Rect(0, 0, p.Width, p.Height)), nil
+}
+
+type photoThumbnailerMock struct{ photoTakerMock }
+
+func (a *photoThumbnailerMock) Thumbnail(width int) (image.Image, error) {
+	return image.NewRGBA(image.Rect(0, 0, width, width)), nil
+}
+
+func TestWrapperPhotoTaker(t *testing.T) {
+	d := wrapAdapter(&photoTakerMock{}, Info{})
+	if _, ok := d.(PhotoTaker); !ok {
+		t.Fatal("expected the driver to be a PhotoTaker")
+	}
+	if _, ok := d.(Thumbnailer); ok {
+		t.Fatal("expected the driver not to be a Thumbnailer")
+	}
+
+	d = wrapAdapter(&photoThumbnailerMock{}, Info{})
+	_, isPhotoTaker := d.(PhotoTaker)
+	_, isThumbnailer := d.(Thumbnailer)
+	if !isPhotoTaker || !isThumbnailer {
+		t.Fatal("expected the driver to be both a PhotoTaker and a Thumbnailer")
+	}
+}
+
+type controllerMock struct {
+	videoAdapterMock
+	controls []Controls
+}
+
+func (a *controllerMock) SetControls(c Controls) error {
+	a.controls = append(a.controls, c)
+	return nil
+}
+
+func TestWrapperSetControls(t *testing.T) {
+	torch := true
+	var a controllerMock
+	if err := wrapAdapter(&a, Info{}).SetControls(Controls{Torch: &torch}); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.controls) != 1 || *a.controls[0].Torch != torch {
+		t.Fatalf("expected the controls to be passed, but got %v", a.controls)
+	}
+
+	d := wrapAdapter(&videoAdapterMock{}, Info{})
+	if err := d.SetControls(Controls{}); err != nil {
+		t.Fatalf("expected the empty controls to be ignored, but got %v", err)
+	}
+	if err := d.SetControls(Controls{Torch: &torch}); err != ErrControlUnsupported {
+		t.Fatalf("expected %v, but got %v", ErrControlUnsupported, err)
+	}
+}
+
+type proberMock struct {
+	videoAdapterMock
+}
+
+func (a *proberMock) Probe() (Probe, error) {
+	return Probe{Permission: PermissionDenied}, nil
+}
+
+func TestWrapperProbe(t *testing.T) {
+	p, err := wrapAdapter(&proberMock{}, Info{}).Probe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Permission != PermissionDenied {
+		t.Fatalf("expected %s, but got %s", PermissionDenied, p.Permission)
+	}
+
+	p, err = wrapAdapter(&videoAdapterMock{}, Info{}).Probe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Permission != PermissionUnknown {
+		t.Fatalf("expected %s, but got %s", PermissionUnknown, p.Permission)
+	}
+}
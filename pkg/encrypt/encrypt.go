@@ -0,0 +1,81 @@
+// Package encrypt encrypts recordings at rest with AES-256-GCM. Files are encrypted in chunks as they're
+// written, so recordings of any length are encrypted without buffering them, and each file, e.g. each
+// recording segment, is encrypted with its own key derived from the master key.
+//
+// A file starts with a header of the magic, master key ID, file key salt and chunk
+// size. Each chunk is the sealed chunk's 4-byte length followed by the sealed chunk. A chunk's nonce is its index
+// and whether it's the last one, so chunks can't be reordered and the file can't be truncated.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	magic = "MDE1"
+	// DefaultChunkSize is the plain chunk size if WithChunkSize doesn't set it.
+	DefaultChunkSize = 64 * 1024
+	// maxChunkSize bounds memory readers allocate for chunks of untrusted files.
+	maxChunkSize = 16 * 1024 * 1024
+	saltLen      = 16
+	keyLen       = 32
+	maxKeyIDLen  = 255
+)
+
+var (
+	// ErrUnknownKey is returned by readers when a file is encrypted with a key that isn't in the key ring.
+	ErrUnknownKey = errors.New("encrypt: unknown key")
+	// ErrInvalidFile is returned by readers when a file isn't encrypted by this package.
+	ErrInvalidFile = errors.New("encrypt: invalid file")
+	// ErrAuthentication is returned by readers when a file is altered or truncated.
+	ErrAuthentication = errors.New("encrypt: message authentication failed")
+)
+
+// Key is a master key. ID is stored in files to find the key to decrypt them, so master keys can be
+// rotated without re-encrypting old files.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// KeyRing maps IDs to master keys to decrypt files.
+type KeyRing map[string][]byte
+
+// fileAEAD derives a file key from secret and salt with HKDF-SHA256, and returns its cipher.
+// Reference: https://tools.ietf.org/html/rfc5869
+func fileAEAD(secret []byte, keyID string, salt []byte) (cipher.AEAD, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("encrypt: empty key")
+	}
+
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	expand := hmac.New(sha256.New, prk)
+	expand.Write([]byte("mediadevices file key " + keyID))
+	expand.Write([]byte{1})
+	key := expand.Sum(nil)[:keyLen]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the cipher: %s", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce for the chunk at index.
+func chunkNonce(index uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], index)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
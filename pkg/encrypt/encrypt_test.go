@@ -0,0 +1,150 @@
+package encrypt
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+var testKey = Key{ID: "2021-07", Secret: []byte("0123456789abcdef0123456789abcdef")}
+
+func encrypt(t *testing.T, data []byte, opts ...Option) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, testKey, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Written in pieces that don't align with chunks
+	for len(data) > 0 {
+		n := 7
+		if n > len(data) {
+			n = len(data)
+		}
+		if _, err := w.Write(data[:n]); err != nil {
+			t.Fatal(err)
+		}
+		data = data[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(file []byte, keys KeyRing) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(file), keys)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestWriterReader(t *testing.T) {
+	keys := KeyRing{testKey.ID: testKey.Secret}
+	for _, size := range []int{0, 1, 32, 33, 100} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		file := encrypt(t, data, WithChunkSize(32))
+		if bytes.Contains(file, data[:size/2]) && size > 2 {
+			t.Fatalf("expected the data of %d bytes to be encrypted", size)
+		}
+
+		out, err := decrypt(file, keys)
+		if err != nil {
+			t.Fatalf("expected the data of %d bytes to be decrypted, but got %v", size, err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Fatalf("expected %v, but got %v", data, out)
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	keys := KeyRing{testKey.ID: testKey.Secret}
+	data := make([]byte, 100)
+	file := encrypt(t, data, WithChunkSize(32))
+
+	if _, err := decrypt(file, KeyRing{"other": testKey.Secret}); err != ErrUnknownKey {
+		t.Fatalf("expected %v, but got %v", ErrUnknownKey, err)
+	}
+	if _, err := decrypt([]byte("not encrypted"), keys); err != ErrInvalidFile {
+		t.Fatalf("expected %v, but got %v", ErrInvalidFile, err)
+	}
+
+	altered := append([]byte{}, file...)
+	altered[len(altered)-1]++
+	if _, err := decrypt(altered, keys); err != ErrAuthentication {
+		t.Fatalf("expected %v with the altered data, but got %v", ErrAuthentication, err)
+	}
+
+	// Truncated at the end of chunk 3
+	header := len(magic) + 1 + len(testKey.ID) + saltLen + 4
+	chunk := 4 + 32 + 16
+	if _, err := decrypt(file[:header+3*chunk], keys); err != ErrAuthentication {
+		t.Fatalf("expected %v with the truncated file, but got %v", ErrAuthentication, err)
+	}
+
+	// Chunks are swapped
+	swapped := append([]byte{}, file[:header]...)
+	swapped = append(swapped, file[header+chunk:header+2*chunk]...)
+	swapped = append(swapped, file[header:header+chunk]...)
+	swapped = append(swapped, file[header+2*chunk:]...)
+	if _, err := decrypt(swapped, keys); err != ErrAuthentication {
+		t.Fatalf("expected %v with the reordered chunks, but got %v", ErrAuthentication, err)
+	}
+}
+
+type testFile struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (f *testFile) Close() error {
+	f.closed = true
+	return nil
+}
+
+func TestSegments(t *testing.T) {
+	var files []*testFile
+	keys := []Key{testKey, {ID: "2021-08", Secret: []byte("another key")}}
+	s := NewSegments(func(segment int) (io.WriteCloser, error) {
+		f := &testFile{}
+		files = append(files, f)
+		return f, nil
+	}, func(segment int) Key {
+		return keys[segment%2]
+	})
+
+	for i := 0; i < 3; i++ {
+		w, err := s.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ring := KeyRing{keys[0].ID: keys[0].Secret, keys[1].ID: keys[1].Secret}
+	for i, f := range files {
+		if !f.closed {
+			t.Fatalf("expected the segment %d to be closed", i)
+		}
+		out, err := decrypt(f.Bytes(), ring)
+		if err != nil {
+			t.Fatalf("expected the segment %d to be decrypted, but got %v", i, err)
+		}
+		if !bytes.Equal(out, []byte{byte(i)}) {
+			t.Fatalf("expected %v, but got %v", []byte{byte(i)}, out)
+		}
+	}
+	if bytes.Equal(files[0].Bytes()[:30], files[2].Bytes()[:30]) {
+		t.Fatal("expected the segments with the same master key to have different file keys")
+	}
+}
@@ -0,0 +1,108 @@
+package encrypt
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"io"
+)
+
+type reader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	max    int
+	buf    []byte
+	index  uint64
+	last   bool
+}
+
+// NewReader returns a reader that decrypts a file written by NewWriter from r with a key in keys. Data
+// is only returned after its chunk is authenticated, and the reader returns ErrAuthentication if the file is
+// altered or truncated.
+func NewReader(r io.Reader, keys KeyRing) (io.Reader, error) {
+	prefix := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, ErrInvalidFile
+	}
+	if string(prefix[:len(magic)]) != magic {
+		return nil, ErrInvalidFile
+	}
+
+	rest := make([]byte, int(prefix[len(magic)])+saltLen+4)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, ErrInvalidFile
+	}
+	keyID := string(rest[:len(rest)-saltLen-4])
+	salt := rest[len(keyID) : len(keyID)+saltLen]
+	chunkSize := int(binary.BigEndian.Uint32(rest[len(rest)-4:]))
+	if chunkSize <= 0 || chunkSize > maxChunkSize {
+		return nil, ErrInvalidFile
+	}
+
+	secret, ok := keys[keyID]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	aead, err := fileAEAD(secret, keyID, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reader{
+		r:      r,
+		aead:   aead,
+		header: append(prefix, rest...),
+		max:    chunkSize + aead.Overhead(),
+	}, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.last {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *reader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(r.r, size[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// The last chunk is missing
+			return ErrAuthentication
+		}
+		return err
+	}
+	n := int(binary.BigEndian.Uint32(size[:]))
+	if n < r.aead.Overhead() || n > r.max {
+		return ErrAuthentication
+	}
+
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(r.r, sealed); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return ErrAuthentication
+		}
+		return err
+	}
+
+	// A chunk is the last one if it's sealed as the last one, which can't be forged without the key.
+	plain, err := r.aead.Open(nil, chunkNonce(r.index, false), sealed, r.header)
+	if err != nil {
+		plain, err = r.aead.Open(nil, chunkNonce(r.index, true), sealed, r.header)
+		if err != nil {
+			return ErrAuthentication
+		}
+		r.last = true
+	}
+	r.index++
+	r.buf = plain
+	return nil
+}
@@ -0,0 +1,66 @@
+package encrypt
+
+import (
+	"io"
+	"sync"
+)
+
+// Segments encrypts a recording's segments into separate files. Each segment has its own file key, and
+// a master key is chosen per segment, so keys are rotated at segment boundaries.
+type Segments struct {
+	mu      sync.Mutex
+	create  func(segment int) (io.WriteCloser, error)
+	key     func(segment int) Key
+	opts    []Option
+	index   int
+	file    io.WriteCloser
+	current io.WriteCloser
+}
+
+// NewSegments creates Segments, which creates each segment's file with create, and encrypts it with the
+// master key returned by key, e.g. the same key for all segments or a new key every day.
+func NewSegments(create func(segment int) (io.WriteCloser, error), key func(segment int) Key, opts ...Option) *Segments {
+	return &Segments{create: create, key: key, opts: opts}
+}
+
+// Next closes the current segment, and returns the next segment's writer.
+func (s *Segments) Next() (io.Writer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.closeCurrent(); err != nil {
+		return nil, err
+	}
+
+	file, err := s.create(s.index)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(file, s.key(s.index), s.opts...)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	s.index++
+	s.file, s.current = file, w
+	return w, nil
+}
+
+// Close closes the current segment.
+func (s *Segments) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeCurrent()
+}
+
+func (s *Segments) closeCurrent() error {
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	if errFile := s.file.Close(); err == nil {
+		err = errFile
+	}
+	s.file, s.current = nil, nil
+	return err
+}
@@ -0,0 +1,114 @@
+package encrypt
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Option configures NewWriter.
+type Option func(*writer)
+
+// WithChunkSize sets plain chunk size. Smaller chunks lose less of the recording when it's
+// interrupted, and larger ones have less overhead.
+func WithChunkSize(size int) Option {
+	return func(w *writer) {
+		w.chunkSize = size
+	}
+}
+
+type writer struct {
+	w         io.Writer
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	buf       []byte
+	index     uint64
+	closed    bool
+}
+
+// NewWriter returns a writer that encrypts data written to it with key, and writes it to w. The file is
+// only complete after Close, which doesn't close w.
+func NewWriter(w io.Writer, key Key, opts ...Option) (io.WriteCloser, error) {
+	if len(key.ID) > maxKeyIDLen {
+		return nil, fmt.Errorf("encrypt: the key ID is longer than %d bytes", maxKeyIDLen)
+	}
+
+	ew := &writer{w: w, chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		opt(ew)
+	}
+	if ew.chunkSize <= 0 || ew.chunkSize > maxChunkSize {
+		return nil, fmt.Errorf("encrypt: invalid chunk size %d", ew.chunkSize)
+	}
+
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate the salt: %s", err)
+	}
+	aead, err := fileAEAD(key.Secret, key.ID, salt)
+	if err != nil {
+		return nil, err
+	}
+	ew.aead = aead
+
+	header := append([]byte(magic), byte(len(key.ID)))
+	header = append(header, key.ID...)
+	header = append(header, salt...)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(ew.chunkSize))
+	ew.header = append(header, size[:]...)
+	if _, err := w.Write(ew.header); err != nil {
+		return nil, err
+	}
+	ew.buf = make([]byte, 0, ew.chunkSize)
+	return ew, nil
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("encrypt: write to a closed writer")
+	}
+
+	n := 0
+	for len(p) > 0 {
+		// A full chunk is kept until more data comes, since the last chunk is sealed differently.
+		if len(w.buf) == w.chunkSize {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):w.chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+m]
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+func (w *writer) flush(last bool) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.index, last), w.buf, w.header)
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := w.w.Write(size[:]); err != nil {
+		return err
+	}
+	if _, err := w.w.Write(sealed); err != nil {
+		return err
+	}
+	w.index++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close writes the last chunk.
+func (w *writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
@@ -0,0 +1,124 @@
+// Package event provides a track of timestamped application events, e.g. motion regions, speech segments and
+// detections, aligned with media capture times. Events are written to WebVTT or JSON sidecar files
+// next to recordings, and sent to peers over data channels.
+package event
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event is an application event on the media timeline.
+type Event struct {
+	// Kind is the event type, e.g. "motion" or "speech".
+	Kind string
+	// Time is when the event starts, e.g. the capture time of the frame it's detected in.
+	Time time.Time
+	// Duration is the event length, or 0 for an instant event.
+	Duration time.Duration
+	// Data is the event payload, which is encoded in JSON.
+	Data interface{}
+}
+
+// End returns when the event ends.
+func (e Event) End() time.Time {
+	return e.Time.Add(e.Duration)
+}
+
+// TrackOption configures NewTrack.
+type TrackOption func(*Track)
+
+// WithStart sets the timeline start, e.g. when recording started, from which event offsets
+// are written. The default is when the track is created.
+func WithStart(start time.Time) TrackOption {
+	return func(t *Track) {
+		t.start = start
+	}
+}
+
+// WithTimeSource sets the time source for events without a time, e.g. mediadevices.MediaClock.Now to share
+// the capture clock. The default is time.Now.
+func WithTimeSource(now func() time.Time) TrackOption {
+	return func(t *Track) {
+		t.now = now
+	}
+}
+
+// Track stores a session's events, and passes them to subscribers as they're emitted.
+type Track struct {
+	mu       sync.Mutex
+	start    time.Time
+	now      func() time.Time
+	events   []Event
+	handlers map[int]func(Event)
+	next     int
+}
+
+// NewTrack creates an empty Track.
+func NewTrack(opts ...TrackOption) *Track {
+	t := &Track{
+		now:      time.Now,
+		handlers: make(map[int]func(Event)),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	if t.start.IsZero() {
+		t.start = t.now()
+	}
+	return t
+}
+
+// Start returns the timeline start.
+func (t *Track) Start() time.Time {
+	return t.start
+}
+
+// Emit adds e to the track and calls subscribers. e's time is the current time if it's zero.
+func (t *Track) Emit(e Event) {
+	if e.Time.IsZero() {
+		e.Time = t.now()
+	}
+
+	t.mu.Lock()
+	t.events = append(t.events, e)
+	handlers := make([]func(Event), 0, len(t.handlers))
+	for _, handler := range t.handlers {
+		handlers = append(handlers, handler)
+	}
+	t.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
+
+// Events returns events emitted so far, ordered by time.
+func (t *Track) Events() []Event {
+	t.mu.Lock()
+	events := make([]Event, len(t.events))
+	copy(events, t.events)
+	t.mu.Unlock()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// Subscribe calls handler with events emitted after it. handler is called in Emit's goroutine.
+// The returned function stops the calls.
+func (t *Track) Subscribe(handler func(Event)) func() {
+	t.mu.Lock()
+	id := t.next
+	t.next++
+	t.handlers[id] = handler
+	t.mu.Unlock()
+
+	return func() {
+		t.mu.Lock()
+		delete(t.handlers, id)
+		t.mu.Unlock()
+	}
+}
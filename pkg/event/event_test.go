@@ -0,0 +1,179 @@
+package event
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+var testStart = time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+func TestTrack(t *testing.T) {
+	track := NewTrack(WithStart(testStart), WithTimeSource(func() time.Time { return testStart.Add(time.Second) }))
+
+	var received []Event
+	unsubscribe := track.Subscribe(func(e Event) { received = append(received, e) })
+	track.Emit(Event{Kind: "motion", Time: testStart.Add(2 * time.Second)})
+	track.Emit(Event{Kind: "qr"})
+	unsubscribe()
+	track.Emit(Event{Kind: "motion", Time: testStart.Add(3 * time.Second)})
+
+	if len(received) != 2 {
+		t.Fatalf("expected 2 events before unsubscribing, but got %d", len(received))
+	}
+	events := track.Events()
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, but got %d", len(events))
+	}
+	if events[0].Kind != "qr" || !events[0].Time.Equal(testStart.Add(time.Second)) {
+		t.Fatalf("expected the event without the time to be at the current time, but got %+v", events[0])
+	}
+}
+
+func testTrack() *Track {
+	track := NewTrack(WithStart(testStart))
+	track.Emit(Event{Kind: "speech", Time: testStart.Add(1500 * time.Millisecond), Duration: 2 * time.Second})
+	track.Emit(Event{Kind: "qr", Time: testStart.Add(time.Hour + 61*time.Second), Data: "hello"})
+	return track
+}
+
+func TestWriteWebVTT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteWebVTT(&buf, testTrack()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `WEBVTT
+
+1
+00:00:01.500 --> 00:00:03.500
+{"kind":"speech","start":1.5,"end":3.5,"time":"2021-07-01T12:00:01.5Z"}
+
+2
+01:01:01.000 --> 01:01:01.001
+{"kind":"qr","start":3661,"end":3661,"time":"2021-07-01T13:01:01Z","data":"hello"}
+`
+	if buf.String() != expected {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", expected, buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testTrack()); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []record
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Kind != "speech" || records[0].End != 3.5 || records[1].Data != "hello" {
+		t.Fatalf("unexpected records: %+v", records)
+	}
+}
+
+type testSender struct {
+	sent []string
+}
+
+func (s *testSender) SendText(text string) error {
+	s.sent = append(s.sent, text)
+	return nil
+}
+
+func TestSend(t *testing.T) {
+	track := NewTrack(WithStart(testStart))
+	s := &testSender{}
+	stop := Send(track, s)
+	track.Emit(Event{Kind: "motion", Time: testStart.Add(time.Second), Data: []int{1, 2}})
+	stop()
+	track.Emit(Event{Kind: "motion", Time: testStart.Add(2 * time.Second)})
+
+	expected := `{"kind":"motion","start":1,"end":1,"time":"2021-07-01T12:00:01Z","data":[1,2]}`
+	if len(s.sent) != 1 || s.sent[0] != expected {
+		t.Fatalf("expected %v, but got %v", []string{expected}, s.sent)
+	}
+}
+
+func TestFromVideo(t *testing.T) {
+	track := NewTrack(WithStart(testStart))
+	captured := testStart.Add(500 * time.Millisecond)
+	var n int
+	src := video.ReaderFunc(func() (image.Image, func(), error) {
+		n++
+		return image.NewGray(image.Rect(0, 0, n, 1)), func() {}, nil
+	})
+	r := FromVideo(track, "wide", func(img image.Image) (interface{}, bool) {
+		return img.Bounds().Dx(), img.Bounds().Dx() > 1
+	})(video.Stamp(src, func() time.Time { return captured }))
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := video.MetadataOf(r); !ok {
+		t.Fatal("expected the metadata of the frames to be kept")
+	}
+
+	events := track.Events()
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, but got %d", len(events))
+	}
+	if !events[0].Time.Equal(captured) || events[0].Data != 2 {
+		t.Fatalf("expected the event of the second frame at the capture time, but got %+v", events[0])
+	}
+}
+
+func TestSpeechSegments(t *testing.T) {
+	// 20ms of mono audio at 8kHz
+	const chunkLen = 160
+
+	now := testStart
+	track := NewTrack(WithStart(testStart), WithTimeSource(func() time.Time { return now }))
+	speech := false
+	var n int
+	src := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		now = now.Add(20 * time.Millisecond)
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			if speech {
+				chunk.Data[i] = float32(0.3 * math.Sin(float64(n)*2*math.Pi/80))
+			}
+			n++
+		}
+		return chunk, func() {}, nil
+	})
+	v := audio.NewVoiceActivity()
+	r := SpeechSegments(track, v)(v.Detect()(src))
+	read := func(chunks int) {
+		for i := 0; i < chunks; i++ {
+			if _, _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	read(10)
+	speech = true
+	read(25)
+	speech = false
+	read(25)
+
+	events := track.Events()
+	if len(events) != 1 {
+		t.Fatalf("expected a segment, but got %d", len(events))
+	}
+	// 25 speech chunks and a 15 chunk hangover, ending at the 15th chunk after speech
+	if !events[0].Time.Equal(testStart.Add(220*time.Millisecond)) || events[0].Duration != 780*time.Millisecond {
+		t.Fatalf("expected the segment from 220ms for 780ms, but got %+v", events[0])
+	}
+}
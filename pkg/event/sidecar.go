@@ -0,0 +1,98 @@
+package event
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// instantCueDuration is the WebVTT cue length for instant events, since a cue has to end after it starts.
+const instantCueDuration = time.Millisecond
+
+// record is an event in sidecar files and data channels. Start and End are offsets in seconds from
+// the timeline start, and Time is the absolute time to align the event with other streams.
+type record struct {
+	Kind  string      `json:"kind"`
+	Start float64     `json:"start"`
+	End   float64     `json:"end"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+func newRecord(start time.Time, e Event) record {
+	return record{
+		Kind:  e.Kind,
+		Start: e.Time.Sub(start).Seconds(),
+		End:   e.End().Sub(start).Seconds(),
+		Time:  e.Time,
+		Data:  e.Data,
+	}
+}
+
+// WriteJSON writes t's events to w as a JSON array.
+func WriteJSON(w io.Writer, t *Track) error {
+	events := t.Events()
+	records := make([]record, len(events))
+	for i, e := range events {
+		records[i] = newRecord(t.Start(), e)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
+
+// WriteWebVTT writes t's events to w as a WebVTT metadata track, whose cues are the events in JSON,
+// so players show them on the recording's timeline.
+// Reference: https://www.w3.org/TR/webvtt1/
+func WriteWebVTT(w io.Writer, t *Track) error {
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString("WEBVTT\n"); err != nil {
+		return err
+	}
+
+	for i, e := range t.Events() {
+		start := e.Time.Sub(t.Start())
+		if start < 0 {
+			start = 0
+		}
+		end := start + e.Duration
+		if end <= start {
+			end = start + instantCueDuration
+		}
+
+		payload, err := json.Marshal(newRecord(t.Start(), e))
+		if err != nil {
+			return fmt.Errorf("failed to encode the event %d: %s", i, err)
+		}
+		if _, err := fmt.Fprintf(bw, "\n%d\n%s --> %s\n%s\n", i+1, vttTime(start), vttTime(end), payload); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// vttTime formats d as a WebVTT timestamp, hh:mm:ss.ttt.
+func vttTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// TextSender sends text messages, e.g. *webrtc.DataChannel.
+type TextSender interface {
+	SendText(s string) error
+}
+
+// Send sends events emitted to t after it to s in JSON, e.g. over a data channel to show detections
+// on remote video. The returned function stops sending. Events that fail to send are dropped.
+func Send(t *Track, s TextSender) func() {
+	return t.Subscribe(func(e Event) {
+		payload, err := json.Marshal(newRecord(t.Start(), e))
+		if err != nil {
+			return
+		}
+		_ = s.SendText(string(payload))
+	})
+}
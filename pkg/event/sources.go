@@ -0,0 +1,63 @@
+package event
+
+import (
+	"image"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// KindSpeech is the kind of events SpeechSegments emits.
+const KindSpeech = "speech"
+
+// FromVideo returns a transform that emits an event of kind to t for each frame detect returns true for,
+// e.g. motion regions. The event is at the frame's capture time, so it's aligned with the
+// video, and its data is what detect returns. Frames are passed through.
+func FromVideo(t *Track, kind string, detect func(img image.Image) (data interface{}, ok bool)) video.TransformFunc {
+	return func(r video.Reader) video.Reader {
+		return video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			if data, ok := detect(img); ok {
+				m, _ := video.MetadataOf(r)
+				t.Emit(Event{Kind: kind, Time: m.CaptureTime, Data: data})
+			}
+			return img, release, nil
+		}), r)
+	}
+}
+
+// SpeechSegments returns a transform that emits a KindSpeech event to t for each speech segment v
+// detects. The event is emitted when the segment ends, and its duration includes v's hangover. It should be
+// applied after v.Detect.
+func SpeechSegments(t *Track, v *audio.VoiceActivity) audio.TransformFunc {
+	return func(r audio.Reader) audio.Reader {
+		var segment *Event
+		end := func() {
+			if segment != nil {
+				segment.Duration = t.now().Sub(segment.Time)
+				t.Emit(*segment)
+				segment = nil
+			}
+		}
+		return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				end()
+				return nil, func() {}, err
+			}
+
+			switch speaking := v.Speaking(); {
+			case speaking && segment == nil:
+				segment = &Event{Kind: KindSpeech, Time: t.now()}
+			case !speaking:
+				end()
+			}
+			return chunk, release, nil
+		})
+	}
+}
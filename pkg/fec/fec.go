@@ -0,0 +1,57 @@
+// Package fec implements forward error correction for outgoing RTP streams.
+//
+// Only schemes widely supported by browsers are implemented: RED (RFC 2198) and ULPFEC (RFC 5109)
+// encapsulated in RED. FlexFEC isn't supported yet.
+package fec
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/rtp"
+)
+
+const (
+	rtpHeaderSize = 12
+	// maxGroupSize is how many packets the short ULPFEC mask can cover.
+	maxGroupSize = 16
+)
+
+// Encoder adds redundancy to outgoing RTP packets.
+type Encoder interface {
+	// Encode takes one frame's packets from a packetizer, and returns the packets to send instead.
+	// The returned packets may have different payload types and sequence numbers.
+	Encode(pkts []*rtp.Packet) []*rtp.Packet
+}
+
+// NewEncoder creates an Encoder that follows c.FEC. Since redundancy is added on top of media packets,
+// media has to be packetized with mediaMTU for the encoded packets to fit in mtu. If FEC is disabled, enc
+// is nil and mediaMTU equals mtu.
+func NewEncoder(c *codec.RTPCodec, mtu int) (enc Encoder, mediaMTU int) {
+	params := c.FEC
+	isAudio := strings.HasPrefix(strings.ToLower(c.MimeType), "audio/")
+
+	switch {
+	case params.Mode == codec.FECModeNone:
+		return nil, mtu
+	case params.Mode == codec.FECModeULPFEC && !isAudio:
+		groupSize := int(math.Round(1 / params.Overhead))
+		if groupSize < 1 {
+			groupSize = 1
+		}
+		if groupSize > maxGroupSize {
+			groupSize = maxGroupSize
+		}
+		enc := NewULPFECEncoder(uint8(params.REDPayloadType), uint8(params.ULPFECPayloadType), groupSize)
+		return enc, mtu - redPrimaryHeaderSize - ulpfecHeaderSize - ulpfecLevelHeaderSize
+	default:
+		// ULPFEC isn't defined for audio, so fall back to RED
+		distance := int(math.Round(params.Overhead))
+		if distance < 1 {
+			distance = 1
+		}
+		enc := NewREDEncoder(uint8(params.REDPayloadType), distance, mtu-rtpHeaderSize)
+		return enc, mtu - redPrimaryHeaderSize
+	}
+}
@@ -0,0 +1,172 @@
+package fec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/rtp"
+)
+
+func newPacket(pt uint8, seq uint16, ts uint32, marker bool, payload []byte) *rtp.Packet {
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			Marker:         marker,
+			PayloadType:    pt,
+			SequenceNumber: seq,
+			Timestamp:      ts,
+			SSRC:           0x12345678,
+		},
+		Payload: payload,
+	}
+}
+
+func TestREDEncoder(t *testing.T) {
+	enc := NewREDEncoder(63, 1, 1200)
+
+	first := enc.Encode([]*rtp.Packet{newPacket(111, 1, 960, true, []byte{0x01, 0x02})})
+	if first[0].PayloadType != 63 {
+		t.Fatalf("expected payload type to be 63, but got %d", first[0].PayloadType)
+	}
+	expected := []byte{111, 0x01, 0x02}
+	if !bytes.Equal(first[0].Payload, expected) {
+		t.Fatalf("expected %v, but got %v", expected, first[0].Payload)
+	}
+
+	second := enc.Encode([]*rtp.Packet{newPacket(111, 2, 1920, true, []byte{0x03})})
+	expected = []byte{
+		redFollowBit | 111, 960 >> 6, (960 << 2) & 0xFF, 2, // redundant block header
+		111,        // primary block header
+		0x01, 0x02, // redundant block
+		0x03, // primary block
+	}
+	if !bytes.Equal(second[0].Payload, expected) {
+		t.Fatalf("expected %v, but got %v", expected, second[0].Payload)
+	}
+
+	// The redundant block should be dropped when it doesn't fit
+	enc = NewREDEncoder(63, 1, 8)
+	enc.Encode([]*rtp.Packet{newPacket(111, 1, 960, true, []byte{0x01, 0x02})})
+	third := enc.Encode([]*rtp.Packet{newPacket(111, 2, 1920, true, []byte{0x03, 0x04})})
+	expected = []byte{111, 0x03, 0x04}
+	if !bytes.Equal(third[0].Payload, expected) {
+		t.Fatalf("expected %v, but got %v", expected, third[0].Payload)
+	}
+}
+
+// recoverPacket restores one lost media packet from the rest of the group and the ULPFEC payload
+func recoverPacket(t *testing.T, received []*rtp.Packet, fec []byte) *rtp.Packet {
+	recovered := make([]byte, rtpHeaderSize+binary.BigEndian.Uint16(fec[ulpfecHeaderSize:]))
+	copy(recovered[:2], fec[:2])
+	copy(recovered[4:8], fec[4:8])
+	length := binary.BigEndian.Uint16(fec[8:])
+	copy(recovered[rtpHeaderSize:], fec[ulpfecHeaderSize+ulpfecLevelHeaderSize:])
+
+	for _, pkt := range received {
+		raw, err := pkt.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		recovered[0] ^= raw[0]
+		recovered[1] ^= raw[1]
+		for i := 4; i < 8; i++ {
+			recovered[i] ^= raw[i]
+		}
+		length ^= uint16(len(raw) - rtpHeaderSize)
+		for i, b := range raw[rtpHeaderSize:] {
+			recovered[rtpHeaderSize+i] ^= b
+		}
+	}
+	recovered[0] = recovered[0]&ulpfecRecoveryMask | 0x80
+
+	var pkt rtp.Packet
+	if err := pkt.Unmarshal(recovered[:rtpHeaderSize+int(length)]); err != nil {
+		t.Fatal(err)
+	}
+	return &pkt
+}
+
+func TestULPFECEncoder(t *testing.T) {
+	enc := NewULPFECEncoder(123, 127, 3)
+
+	payloads := [][]byte{{0x01, 0x02, 0x03}, {0x04}, {0x05, 0x06}}
+	var pkts []*rtp.Packet
+	for i, payload := range payloads {
+		pkts = append(pkts, newPacket(96, 0, 3000, i == len(payloads)-1, payload))
+	}
+
+	encoded := enc.Encode(pkts)
+	if len(encoded) != 4 {
+		t.Fatalf("expected 3 media packets and a FEC packet, but got %d packets", len(encoded))
+	}
+
+	var media []*rtp.Packet
+	for i, pkt := range encoded {
+		if pkt.PayloadType != 123 {
+			t.Fatalf("expected all packets to be encapsulated in RED, but got payload type %d", pkt.PayloadType)
+		}
+		if i > 0 && pkt.SequenceNumber != encoded[i-1].SequenceNumber+1 {
+			t.Fatalf("expected sequence numbers to be consecutive, but got %d after %d",
+				pkt.SequenceNumber, encoded[i-1].SequenceNumber)
+		}
+
+		// Decapsulate RED
+		header := pkt.Header
+		header.PayloadType = pkt.Payload[0]
+		media = append(media, &rtp.Packet{Header: header, Payload: pkt.Payload[1:]})
+	}
+
+	fec := media[3]
+	if fec.PayloadType != 127 {
+		t.Fatalf("expected the last packet to be ULPFEC, but got payload type %d", fec.PayloadType)
+	}
+	if mask := binary.BigEndian.Uint16(fec.Payload[ulpfecHeaderSize+2:]); mask != 0xE000 {
+		t.Fatalf("expected mask to be 0xE000, but got %#x", mask)
+	}
+
+	// Lose the second packet
+	lost := media[1]
+	recovered := recoverPacket(t, []*rtp.Packet{media[0], media[2]}, fec.Payload)
+	// Recovered headers don't carry the original sequence number, it's derived from the base sequence
+	// number and the mask
+	recovered.SequenceNumber = lost.SequenceNumber
+	if recovered.PayloadType != 96 || recovered.Timestamp != lost.Timestamp || recovered.Marker != lost.Marker {
+		t.Fatalf("expected recovered header to be %+v, but got %+v", lost.Header, recovered.Header)
+	}
+	if !bytes.Equal(recovered.Payload, lost.Payload) {
+		t.Fatalf("expected recovered payload to be %v, but got %v", lost.Payload, recovered.Payload)
+	}
+}
+
+func TestNewEncoder(t *testing.T) {
+	c := codec.NewRTPOpusCodec(48000)
+	if enc, mtu := NewEncoder(c, 1200); enc != nil || mtu != 1200 {
+		t.Fatalf("expected FEC to be disabled by default, but got %T with mtu %d", enc, mtu)
+	}
+
+	// ULPFEC isn't defined for audio, so RED should be used instead
+	c.SetPacketization(codec.PacketizationParams{FEC: codec.FECParams{Mode: codec.FECModeULPFEC}})
+	if enc, _ := NewEncoder(c, 1200); enc == nil {
+		t.Fatal("expected an encoder")
+	} else if _, ok := enc.(*redEncoder); !ok {
+		t.Fatalf("expected RED encoder for audio, but got %T", enc)
+	}
+
+	c = codec.NewRTPVP8Codec(90000)
+	c.SetPacketization(codec.PacketizationParams{FEC: codec.FECParams{Mode: codec.FECModeULPFEC}})
+	enc, _ := NewEncoder(c, 1200)
+	ulpfec, ok := enc.(*ulpfecEncoder)
+	if !ok {
+		t.Fatalf("expected ULPFEC encoder for video, but got %T", enc)
+	}
+	if ulpfec.groupSize != 4 {
+		t.Fatalf("expected group size to be 4 with the default overhead, but got %d", ulpfec.groupSize)
+	}
+
+	codecs := c.RedundancyCodecs()
+	if len(codecs) != 2 || codecs[0].MimeType != codec.MimeTypeVideoRED || codecs[1].MimeType != codec.MimeTypeULPFEC {
+		t.Fatalf("expected red and ulpfec codecs, but got %+v", codecs)
+	}
+}
@@ -0,0 +1,89 @@
+package fec
+
+import (
+	"github.com/pion/rtp"
+)
+
+const (
+	redPrimaryHeaderSize   = 1
+	redRedundantHeaderSize = 4
+	redMaxTimestampOffset  = 1<<14 - 1
+	redMaxBlockLength      = 1<<10 - 1
+	redFollowBit           = 0x80
+)
+
+type redBlock struct {
+	payloadType uint8
+	timestamp   uint32
+	payload     []byte
+}
+
+type redEncoder struct {
+	payloadType uint8
+	distance    int
+	maxPayload  int
+	history     []redBlock
+}
+
+// NewREDEncoder creates an Encoder that encapsulates packets in RED with payloadType. Each packet carries
+// up to distance previous payloads besides its own, as long as it doesn't grow beyond maxPayload bytes.
+// Reference: https://tools.ietf.org/html/rfc2198
+func NewREDEncoder(payloadType uint8, distance int, maxPayload int) Encoder {
+	return &redEncoder{
+		payloadType: payloadType,
+		distance:    distance,
+		maxPayload:  maxPayload,
+	}
+}
+
+func (e *redEncoder) Encode(pkts []*rtp.Packet) []*rtp.Packet {
+	for _, pkt := range pkts {
+		// Use the newest blocks that fit, and send them oldest first
+		size := redPrimaryHeaderSize + len(pkt.Payload)
+		var redundant []redBlock
+		for i := len(e.history) - 1; i >= 0; i-- {
+			block := e.history[i]
+			offset := pkt.Timestamp - block.timestamp
+			if offset > redMaxTimestampOffset || len(block.payload) > redMaxBlockLength {
+				continue
+			}
+
+			if size+redRedundantHeaderSize+len(block.payload) > e.maxPayload {
+				break
+			}
+
+			size += redRedundantHeaderSize + len(block.payload)
+			redundant = append([]redBlock{block}, redundant...)
+		}
+
+		payload := make([]byte, 0, size)
+		for _, block := range redundant {
+			offset := pkt.Timestamp - block.timestamp
+			payload = append(payload,
+				redFollowBit|block.payloadType,
+				byte(offset>>6),
+				byte(offset<<2)|byte(len(block.payload)>>8),
+				byte(len(block.payload)),
+			)
+		}
+		payload = append(payload, pkt.PayloadType)
+		for _, block := range redundant {
+			payload = append(payload, block.payload...)
+		}
+		payload = append(payload, pkt.Payload...)
+
+		e.history = append(e.history, redBlock{
+			payloadType: pkt.PayloadType,
+			timestamp:   pkt.Timestamp,
+			payload:     pkt.Payload,
+		})
+		if len(e.history) > e.distance {
+			e.history = e.history[len(e.history)-e.distance:]
+		}
+
+		pkt.PayloadType = e.payloadType
+		pkt.Payload = payload
+	}
+
+	return pkts
+}
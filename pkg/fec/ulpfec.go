@@ -0,0 +1,122 @@
+package fec
+
+import (
+	"encoding/binary"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	ulpfecHeaderSize      = 10
+	ulpfecLevelHeaderSize = 4
+	// ulpfecRecoveryMask clears the E and L bits, and keeps P, X and CC from the first byte of RTP headers.
+	ulpfecRecoveryMask = 0x3F
+)
+
+type ulpfecEncoder struct {
+	redPayloadType    uint8
+	ulpfecPayloadType uint8
+	groupSize         int
+	sequencer         rtp.Sequencer
+	group             []*rtp.Packet
+}
+
+// NewULPFECEncoder creates an Encoder that encapsulates packets in RED with redPayloadType, and generates
+// an ULPFEC packet with ulpfecPayloadType every groupSize packets or at the end of each frame. One lost
+// packet per group can be recovered with the FEC packet. groupSize can't be greater than 16.
+//
+// Since FEC packets are inserted in the stream, the encoder renumbers packets.
+// Reference: https://tools.ietf.org/html/rfc5109
+func NewULPFECEncoder(redPayloadType, ulpfecPayloadType uint8, groupSize int) Encoder {
+	if groupSize > maxGroupSize {
+		groupSize = maxGroupSize
+	}
+
+	return &ulpfecEncoder{
+		redPayloadType:    redPayloadType,
+		ulpfecPayloadType: ulpfecPayloadType,
+		groupSize:         groupSize,
+		sequencer:         rtp.NewRandomSequencer(),
+	}
+}
+
+func (e *ulpfecEncoder) Encode(pkts []*rtp.Packet) []*rtp.Packet {
+	encoded := make([]*rtp.Packet, 0, len(pkts)+len(pkts)/e.groupSize+1)
+
+	for _, pkt := range pkts {
+		pkt.SequenceNumber = e.sequencer.NextSequenceNumber()
+		// FEC protects media packets as they'll be after RED decapsulation
+		e.group = append(e.group, &rtp.Packet{Header: pkt.Header, Payload: pkt.Payload})
+
+		pkt.Payload = append([]byte{pkt.PayloadType}, pkt.Payload...)
+		pkt.PayloadType = e.redPayloadType
+		encoded = append(encoded, pkt)
+
+		if len(e.group) >= e.groupSize || pkt.Marker {
+			if fec := e.generate(); fec != nil {
+				encoded = append(encoded, fec)
+			}
+			e.group = e.group[:0]
+		}
+	}
+
+	return encoded
+}
+
+// generate creates a RED packet holding an ULPFEC packet with one protection level for the group
+func (e *ulpfecEncoder) generate() *rtp.Packet {
+	var protected [][]byte
+	var protectionLength int
+	for _, pkt := range e.group {
+		raw, err := pkt.Marshal()
+		if err != nil {
+			// The group can't be recovered without all its packets
+			return nil
+		}
+
+		protected = append(protected, raw)
+		if l := len(raw) - rtpHeaderSize; l > protectionLength {
+			protectionLength = l
+		}
+	}
+
+	base := e.group[0].SequenceNumber
+	fec := make([]byte, ulpfecHeaderSize+ulpfecLevelHeaderSize+protectionLength)
+	level := fec[ulpfecHeaderSize : ulpfecHeaderSize+ulpfecLevelHeaderSize]
+	payload := fec[ulpfecHeaderSize+ulpfecLevelHeaderSize:]
+
+	var mask uint16
+	var lengthRecovery uint16
+	for i, raw := range protected {
+		fec[0] ^= raw[0]
+		fec[1] ^= raw[1]
+		for j := 0; j < 4; j++ {
+			fec[4+j] ^= raw[4+j]
+		}
+		lengthRecovery ^= uint16(len(raw) - rtpHeaderSize)
+
+		for j, b := range raw[rtpHeaderSize:] {
+			payload[j] ^= b
+		}
+
+		mask |= 0x8000 >> (e.group[i].SequenceNumber - base)
+	}
+
+	fec[0] &= ulpfecRecoveryMask
+	binary.BigEndian.PutUint16(fec[2:], base)
+	binary.BigEndian.PutUint16(fec[8:], lengthRecovery)
+	binary.BigEndian.PutUint16(level[0:], uint16(protectionLength))
+	binary.BigEndian.PutUint16(level[2:], mask)
+
+	last := e.group[len(e.group)-1]
+	return &rtp.Packet{
+		Header: rtp.Header{
+			Version:        2,
+			PayloadType:    e.redPayloadType,
+			SequenceNumber: e.sequencer.NextSequenceNumber(),
+			Timestamp:      last.Timestamp,
+			SSRC:           last.SSRC,
+		},
+		Payload: append([]byte{e.ulpfecPayloadType}, fec...),
+	}
+}
@@ -0,0 +1,103 @@
+package frame
+
+import (
+	"fmt"
+	"image"
+)
+
+// bayerColor is the color channel index in image.RGBA's Pix
+type bayerColor int
+
+const (
+	bayerRed   bayerColor = 0
+	bayerGreen bayerColor = 1
+	bayerBlue  bayerColor = 2
+)
+
+// bayerPattern is a 2x2 block's color filter, ordered top-left, top-right, bottom-left, bottom-right
+type bayerPattern [4]bayerColor
+
+var (
+	bayerPatternRGGB = bayerPattern{bayerRed, bayerGreen, bayerGreen, bayerBlue}
+	bayerPatternBGGR = bayerPattern{bayerBlue, bayerGreen, bayerGreen, bayerRed}
+	bayerPatternGRBG = bayerPattern{bayerGreen, bayerRed, bayerBlue, bayerGreen}
+	bayerPatternGBRG = bayerPattern{bayerGreen, bayerBlue, bayerRed, bayerGreen}
+)
+
+func (p bayerPattern) at(x, y int) bayerColor {
+	return p[(y&1)<<1|(x&1)]
+}
+
+func decodeBayerRGGB(frame []byte, width, height int) (image.Image, func(), error) {
+	return demosaic(frame, width, height, bayerPatternRGGB)
+}
+
+func decodeBayerBGGR(frame []byte, width, height int) (image.Image, func(), error) {
+	return demosaic(frame, width, height, bayerPatternBGGR)
+}
+
+func decodeBayerGRBG(frame []byte, width, height int) (image.Image, func(), error) {
+	return demosaic(frame, width, height, bayerPatternGRBG)
+}
+
+func decodeBayerGBRG(frame []byte, width, height int) (image.Image, func(), error) {
+	return demosaic(frame, width, height, bayerPatternGBRG)
+}
+
+// demosaic interpolates each pixel's missing colors from neighbors in the 3x3 window that have the color
+// (bilinear interpolation). Pixels on the edges only use neighbors inside the frame.
+func demosaic(frame []byte, width, height int, pattern bayerPattern) (image.Image, func(), error) {
+	expectedSize := width * height
+	if expectedSize != len(frame) {
+		return nil, func() {}, fmt.Errorf("frame length (%d) not expected size (%d)", len(frame), expectedSize)
+	}
+
+	img, release := pool.RGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sum, count [3]int
+			for dy := -1; dy <= 1; dy++ {
+				ny := y + dy
+				if ny < 0 || ny >= height {
+					continue
+				}
+				for dx := -1; dx <= 1; dx++ {
+					nx := x + dx
+					if nx < 0 || nx >= width {
+						continue
+					}
+					c := pattern.at(nx, ny)
+					sum[c] += int(frame[ny*width+nx])
+					count[c]++
+				}
+			}
+
+			i := img.PixOffset(x, y)
+			for c := range sum {
+				if count[c] > 0 {
+					img.Pix[i+c] = uint8(sum[c] / count[c])
+				} else {
+					img.Pix[i+c] = 0
+				}
+			}
+			// The sensor value is more accurate than the neighbors' average
+			img.Pix[i+int(pattern.at(x, y))] = frame[y*width+x]
+			img.Pix[i+3] = 0xFF
+		}
+	}
+
+	return img, release, nil
+}
+
+func decodeGray(frame []byte, width, height int) (image.Image, func(), error) {
+	expectedSize := width * height
+	if expectedSize != len(frame) {
+		return nil, func() {}, fmt.Errorf("frame length (%d) not expected size (%d)", len(frame), expectedSize)
+	}
+
+	return &image.Gray{
+		Pix:    frame,
+		Stride: width,
+		Rect:   image.Rect(0, 0, width, height),
+	}, func() {}, nil
+}
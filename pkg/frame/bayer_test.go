@@ -0,0 +1,118 @@
+package frame
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDecodeBayer(t *testing.T) {
+	const (
+		width  = 4
+		height = 4
+	)
+	values := map[bayerColor]uint8{
+		bayerRed:   200,
+		bayerGreen: 100,
+		bayerBlue:  50,
+	}
+	expected := color.RGBA{R: 200, G: 100, B: 50, A: 0xFF}
+
+	testCases := map[Format]bayerPattern{
+		FormatBayerRGGB8: bayerPatternRGGB,
+		FormatBayerBGGR8: bayerPatternBGGR,
+		FormatBayerGRBG8: bayerPatternGRBG,
+		FormatBayerGBRG8: bayerPatternGBRG,
+	}
+
+	for format, pattern := range testCases {
+		format, pattern := format, pattern
+		t.Run(string(format), func(t *testing.T) {
+			decoder, err := NewDecoder(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, _, err := decoder.Decode([]byte{0x00}, width, height); err == nil {
+				t.Fatal("expected to get a frame length mismatch")
+			}
+
+			input := make([]byte, width*height)
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					input[y*width+x] = values[pattern.at(x, y)]
+				}
+			}
+
+			img, _, err := decoder.Decode(input, width, height)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for y := 0; y < height; y++ {
+				for x := 0; x < width; x++ {
+					if c := img.At(x, y); c != expected {
+						t.Fatalf("expected %v at (%d, %d), but got %v", expected, x, y, c)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDemosaicInterpolation(t *testing.T) {
+	// RGGB
+	input := []byte{
+		10, 20, 30, 40,
+		50, 60, 70, 80,
+		90, 100, 110, 120,
+		130, 140, 150, 160,
+	}
+	img, _, err := demosaic(input, 4, 4, bayerPatternRGGB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (1, 1) is blue, reds are on the corners, and greens are on the sides
+	expected := color.RGBA{
+		R: (10 + 30 + 90 + 110) / 4,
+		G: (20 + 50 + 70 + 100) / 4,
+		B: 60,
+		A: 0xFF,
+	}
+	if c := img.At(1, 1); c != expected {
+		t.Fatalf("expected %v, but got %v", expected, c)
+	}
+
+	// (2, 1) is green, reds are above and below, and blues are left and right
+	expected = color.RGBA{
+		R: (30 + 110) / 2,
+		G: 70,
+		B: (60 + 80) / 2,
+		A: 0xFF,
+	}
+	if c := img.At(2, 1); c != expected {
+		t.Fatalf("expected %v, but got %v", expected, c)
+	}
+}
+
+func TestDecodeGray(t *testing.T) {
+	decoder, err := NewDecoder(FormatGRAY8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := decoder.Decode([]byte{0x00}, 2, 2); err == nil {
+		t.Fatal("expected to get a frame length mismatch")
+	}
+
+	img, _, err := decoder.Decode([]byte{1, 2, 3, 4}, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 2, 2) {
+		t.Fatalf("expected bounds to be %v, but got %v", image.Rect(0, 0, 2, 2), img.Bounds())
+	}
+	if c := img.At(1, 1); c != (color.Gray{Y: 4}) {
+		t.Fatalf("expected %v, but got %v", color.Gray{Y: 4}, c)
+	}
+}
@@ -28,6 +28,19 @@ const (
 
 	// FormatZ16 https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/pixfmt-z16.html
 	FormatZ16 = "Z16"
+
+	// FormatGRAY8 is an 8-bit grayscale format, e.g. Mono8 on machine vision cameras
+	// https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/pixfmt-grey.html
+	FormatGRAY8 Format = "GREY"
+
+	// FormatBayerRGGB8 https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/pixfmt-srggb8.html
+	FormatBayerRGGB8 Format = "RGGB"
+	// FormatBayerBGGR8 https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/pixfmt-srggb8.html
+	FormatBayerBGGR8 Format = "BA81"
+	// FormatBayerGRBG8 https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/pixfmt-srggb8.html
+	FormatBayerGRBG8 Format = "GRBG"
+	// FormatBayerGBRG8 https://www.kernel.org/doc/html/v5.9/userspace-api/media/v4l/pixfmt-srggb8.html
+	FormatBayerGBRG8 Format = "GBRG"
 )
 
 const FormatYUYV = FormatYUY2
@@ -40,6 +53,13 @@ var decoderMap = map[Format]decoderFunc{
 	FormatUYVY:  decodeUYVY,
 	FormatMJPEG: decodeMJPEG,
 	FormatZ16:   decodeZ16,
+
+	FormatGRAY8: decodeGray,
+
+	FormatBayerRGGB8: decodeBayerRGGB,
+	FormatBayerBGGR8: decodeBayerBGGR,
+	FormatBayerGRBG8: decodeBayerGRBG,
+	FormatBayerGBRG8: decodeBayerGBRG,
 }
 
 func NewDecoder(f Format) (Decoder, error) {
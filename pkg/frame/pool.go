@@ -0,0 +1,99 @@
+package frame
+
+import (
+	"image"
+	"sync"
+)
+
+// Pool reuses frames and their headers once they're released, so decoders don't allocate every frame of a
+// stream. Each frame comes with a release function that must be called exactly once, and the frame must not be
+// used after that. The zero value is ready to use, and it's safe for concurrent use.
+type Pool struct {
+	pool sync.Pool
+}
+
+// pool is the Pool shared by the decoders.
+var pool Pool
+
+type pooledFrame struct {
+	yuv     image.YCbCr
+	rgba    image.RGBA
+	pix     []uint8
+	release func()
+}
+
+// get returns a frame whose pix has n bytes.
+func (p *Pool) get(n int) *pooledFrame {
+	f, _ := p.pool.Get().(*pooledFrame)
+	if f == nil {
+		f = &pooledFrame{}
+		f.release = func() { p.pool.Put(f) }
+	}
+	if cap(f.pix) < n {
+		f.pix = make([]uint8, n)
+	}
+	f.pix = f.pix[:n]
+	return f
+}
+
+// planes returns a YCbCr header with yn luma samples and cn samples per chroma plane, and a function that
+// releases it. The caller sets the other fields.
+func (p *Pool) planes(yn, cn int) (*image.YCbCr, func()) {
+	f := p.get(yn + 2*cn)
+	f.yuv = image.YCbCr{
+		Y:  f.pix[:yn:yn],
+		Cb: f.pix[yn : yn+cn : yn+cn],
+		Cr: f.pix[yn+cn : yn+2*cn : yn+2*cn],
+	}
+	return &f.yuv, f.release
+}
+
+// YCbCr returns a frame of r in ratio, like image.NewYCbCr, and a function that releases it to p. Reused
+// frames aren't cleared.
+func (p *Pool) YCbCr(r image.Rectangle, ratio image.YCbCrSubsampleRatio) (*image.YCbCr, func()) {
+	w, h, cw, ch := yCbCrSize(r, ratio)
+	img, release := p.planes(w*h, cw*ch)
+	img.SubsampleRatio = ratio
+	img.YStride = w
+	img.CStride = cw
+	img.Rect = r
+	return img, release
+}
+
+// RGBA returns a frame of r, like image.NewRGBA, and a function that releases it to p. Reused frames aren't
+// cleared.
+func (p *Pool) RGBA(r image.Rectangle) (*image.RGBA, func()) {
+	f := p.get(4 * r.Dx() * r.Dy())
+	f.rgba = image.RGBA{
+		Pix:    f.pix,
+		Stride: 4 * r.Dx(),
+		Rect:   r,
+	}
+	return &f.rgba, f.release
+}
+
+// yCbCrSize returns the luma and chroma sizes of r in ratio, as image.NewYCbCr allocates them.
+func yCbCrSize(r image.Rectangle, ratio image.YCbCrSubsampleRatio) (w, h, cw, ch int) {
+	w, h = r.Dx(), r.Dy()
+	switch ratio {
+	case image.YCbCrSubsampleRatio422:
+		cw = (r.Max.X+1)/2 - r.Min.X/2
+		ch = h
+	case image.YCbCrSubsampleRatio420:
+		cw = (r.Max.X+1)/2 - r.Min.X/2
+		ch = (r.Max.Y+1)/2 - r.Min.Y/2
+	case image.YCbCrSubsampleRatio440:
+		cw = w
+		ch = (r.Max.Y+1)/2 - r.Min.Y/2
+	case image.YCbCrSubsampleRatio411:
+		cw = (r.Max.X+3)/4 - r.Min.X/4
+		ch = h
+	case image.YCbCrSubsampleRatio410:
+		cw = (r.Max.X+3)/4 - r.Min.X/4
+		ch = (r.Max.Y+1)/2 - r.Min.Y/2
+	default:
+		cw = w
+		ch = h
+	}
+	return
+}
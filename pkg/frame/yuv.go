@@ -14,7 +14,9 @@ func decodeI420(frame []byte, width, height int) (image.Image, func(), error) {
 		return nil, func() {}, fmt.Errorf("frame length (%d) less than expected (%d)", len(frame), cri)
 	}
 
-	return &image.YCbCr{
+	// The planes point into the frame, so only the header comes from the pool
+	img, release := pool.planes(0, 0)
+	*img = image.YCbCr{
 		Y:              frame[:yi],
 		YStride:        width,
 		Cb:             frame[yi:cbi],
@@ -22,7 +24,8 @@ func decodeI420(frame []byte, width, height int) (image.Image, func(), error) {
 		CStride:        width / 2,
 		SubsampleRatio: image.YCbCrSubsampleRatio420,
 		Rect:           image.Rect(0, 0, width, height),
-	}, func() {}, nil
+	}
+	return img, release, nil
 }
 
 func decodeNV21(frame []byte, width, height int) (image.Image, func(), error) {
@@ -33,21 +36,18 @@ func decodeNV21(frame []byte, width, height int) (image.Image, func(), error) {
 		return nil, func() {}, fmt.Errorf("frame length (%d) less than expected (%d)", len(frame), ci)
 	}
 
-	var cb, cr []byte
-	for i := yi; i < ci; i += 2 {
-		cr = append(cr, frame[i])
-		cb = append(cb, frame[i+1])
+	// The luma points into the frame, and the chroma is deinterleaved into pooled planes
+	img, release := pool.planes(0, (ci-yi)/2)
+	for i, j := yi, 0; i+1 < ci; i, j = i+2, j+1 {
+		img.Cr[j] = frame[i]
+		img.Cb[j] = frame[i+1]
 	}
-
-	return &image.YCbCr{
-		Y:              frame[:yi],
-		YStride:        width,
-		Cb:             cb,
-		Cr:             cr,
-		CStride:        width / 2,
-		SubsampleRatio: image.YCbCrSubsampleRatio420,
-		Rect:           image.Rect(0, 0, width, height),
-	}, func() {}, nil
+	img.Y = frame[:yi]
+	img.YStride = width
+	img.CStride = width / 2
+	img.SubsampleRatio = image.YCbCrSubsampleRatio420
+	img.Rect = image.Rect(0, 0, width, height)
+	return img, release, nil
 }
 
 func decodeNV12(frame []byte, width, height int) (image.Image, func(), error) {
@@ -21,9 +21,8 @@ func decodeYUY2(frame []byte, width, height int) (image.Image, func(), error) {
 		return nil, func() {}, fmt.Errorf("frame length (%d) less than expected (%d)", len(frame), fi)
 	}
 
-	y := make([]byte, yi)
-	cb := make([]byte, ci)
-	cr := make([]byte, ci)
+	img, release := pool.planes(yi, ci)
+	y, cb, cr := img.Y, img.Cb, img.Cr
 
 	C.decodeYUY2CGO(
 		(*C.uchar)(&y[0]),
@@ -33,15 +32,11 @@ func decodeYUY2(frame []byte, width, height int) (image.Image, func(), error) {
 		C.int(width), C.int(height),
 	)
 
-	return &image.YCbCr{
-		Y:              y,
-		YStride:        width,
-		Cb:             cb,
-		Cr:             cr,
-		CStride:        width / 2,
-		SubsampleRatio: image.YCbCrSubsampleRatio422,
-		Rect:           image.Rect(0, 0, width, height),
-	}, func() {}, nil
+	img.YStride = width
+	img.CStride = width / 2
+	img.SubsampleRatio = image.YCbCrSubsampleRatio422
+	img.Rect = image.Rect(0, 0, width, height)
+	return img, release, nil
 }
 
 func decodeUYVY(frame []byte, width, height int) (image.Image, func(), error) {
@@ -53,9 +48,8 @@ func decodeUYVY(frame []byte, width, height int) (image.Image, func(), error) {
 		return nil, func() {}, fmt.Errorf("frame length (%d) less than expected (%d)", len(frame), fi)
 	}
 
-	y := make([]byte, yi)
-	cb := make([]byte, ci)
-	cr := make([]byte, ci)
+	img, release := pool.planes(yi, ci)
+	y, cb, cr := img.Y, img.Cb, img.Cr
 
 	C.decodeUYVYCGO(
 		(*C.uchar)(&y[0]),
@@ -65,13 +59,9 @@ func decodeUYVY(frame []byte, width, height int) (image.Image, func(), error) {
 		C.int(width), C.int(height),
 	)
 
-	return &image.YCbCr{
-		Y:              y,
-		YStride:        width,
-		Cb:             cb,
-		Cr:             cr,
-		CStride:        width / 2,
-		SubsampleRatio: image.YCbCrSubsampleRatio422,
-		Rect:           image.Rect(0, 0, width, height),
-	}, func() {}, nil
+	img.YStride = width
+	img.CStride = width / 2
+	img.SubsampleRatio = image.YCbCrSubsampleRatio422
+	img.Rect = image.Rect(0, 0, width, height)
+	return img, release, nil
 }
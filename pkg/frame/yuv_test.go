@@ -150,11 +150,13 @@ func BenchmarkDecodeYUY2(b *testing.B) {
 		sz := sz
 		b.Run(fmt.Sprintf("%dx%d", sz.width, sz.height), func(b *testing.B) {
 			input := make([]byte, sz.width*sz.height*2)
+			b.ReportAllocs()
 			for i := 0; i < b.N; i++ {
-				_, _, err := decodeYUY2(input, sz.width, sz.height)
+				_, release, err := decodeYUY2(input, sz.width, sz.height)
 				if err != nil {
 					b.Fatal(err)
 				}
+				release()
 			}
 		})
 	}
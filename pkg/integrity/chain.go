@@ -0,0 +1,69 @@
+package integrity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// chain computes a stream's seals. Each seal covers the previous one, so frames can't be removed,
+// reordered or replaced without breaking the chain.
+type chain struct {
+	key      []byte
+	sequence uint64
+	prev     Hash
+}
+
+func newChain(key []byte) *chain {
+	return &chain{key: key}
+}
+
+// mac returns the seal for the frame at sequence, whose content hashes to frameHash and whose encoded data hashes
+// to dataHash.
+func (c *chain) mac(sequence uint64, frameHash, dataHash Hash) Hash {
+	m := hmac.New(sha256.New, c.key)
+	m.Write(c.prev[:])
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], sequence)
+	m.Write(seq[:])
+	m.Write(frameHash[:])
+	m.Write(dataHash[:])
+
+	var sum Hash
+	copy(sum[:], m.Sum(nil))
+	return sum
+}
+
+// seal returns the next frame's seal, and advances the chain.
+func (c *chain) seal(frameHash, dataHash Hash) FrameRecord {
+	record := FrameRecord{
+		Sequence:  c.sequence,
+		FrameHash: frameHash,
+		MAC:       c.mac(c.sequence, frameHash, dataHash),
+	}
+	c.prev = record.MAC
+	c.sequence++
+	return record
+}
+
+// dataHash returns the encoded frame data's hash. H.264 frames are hashed by their NAL units without
+// seals, so hashes don't change when start codes are rewritten, e.g. by RTP packetization.
+func dataHash(h264 bool, data []byte) Hash {
+	if !h264 {
+		return sha256.Sum256(data)
+	}
+
+	h := sha256.New()
+	for _, nalu := range splitNALUs(data) {
+		if _, ok := parseSealSEI(nalu); ok {
+			continue
+		}
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(nalu)))
+		h.Write(size[:])
+		h.Write(nalu)
+	}
+	var sum Hash
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
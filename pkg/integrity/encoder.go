@@ -0,0 +1,119 @@
+package integrity
+
+import (
+	"image"
+	"strings"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+// Option configures NewEncoderBuilder.
+type Option func(*encoderBuilder)
+
+// WithKey sets the seal key. Streams are verified with the same key. Without a key, seals only
+// detect accidental changes, since anyone can compute them.
+func WithKey(key []byte) Option {
+	return func(b *encoderBuilder) {
+		b.key = append([]byte{}, key...)
+	}
+}
+
+// WithRecorder sets a function that's called with each encoded frame's seal, e.g. to log them next to the
+// recording. It's called in the encoder's reading goroutine, so it shouldn't block.
+func WithRecorder(record func(FrameRecord)) Option {
+	return func(b *encoderBuilder) {
+		b.record = record
+	}
+}
+
+type encoderBuilder struct {
+	codec.VideoEncoderBuilder
+	key    []byte
+	record func(FrameRecord)
+}
+
+// NewEncoderBuilder wraps builder to seal frames it encodes. Each frame's content is hashed before it's
+// encoded, and each encoded frame is sealed with a MAC of the frame hash, encoded data and previous seal.
+// Seals are passed to the recorder, and embedded into H.264 frames as SEI, which decoders ignore.
+// Other codecs' seals are only recorded.
+func NewEncoderBuilder(builder codec.VideoEncoderBuilder, opts ...Option) codec.VideoEncoderBuilder {
+	b := &encoderBuilder{VideoEncoderBuilder: builder}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *encoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	e := &encoder{
+		chain:  newChain(b.key),
+		record: b.record,
+		h264:   strings.EqualFold(b.RTPCodec().MimeType, webrtc.MimeTypeH264),
+	}
+
+	// Encoders read frames in their Read, and codec.PendingFrames matches hashes to encoded
+	// frames.
+	hashed := video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		m, _ := video.MetadataOf(r)
+		e.pending.Push(m, pendingFrame{hash: FrameHash(img), captureTime: m.CaptureTime})
+		return img, release, nil
+	}), r)
+
+	rc, err := b.VideoEncoderBuilder.BuildVideoEncoder(hashed, p)
+	if err != nil {
+		return nil, err
+	}
+	e.ReadCloser = rc
+
+	return e, nil
+}
+
+type pendingFrame struct {
+	hash        Hash
+	captureTime time.Time
+}
+
+type encoder struct {
+	codec.EncoderWrapper
+	chain  *chain
+	record func(FrameRecord)
+	h264   bool
+
+	pending codec.PendingFrames
+}
+
+func (e *encoder) Read() ([]byte, func(), error) {
+	b, release, err := e.ReadCloser.Read()
+	if err != nil {
+		return b, release, err
+	}
+	// Skipped frames aren't sealed, and frames without a hash are sealed with the zero hash
+	v, _ := e.pending.Pop(e.ReadCloser, b)
+	if len(b) == 0 {
+		return b, release, nil
+	}
+	f, _ := v.(pendingFrame)
+	record := e.chain.seal(f.hash, dataHash(e.h264, b))
+	record.CaptureTime = f.captureTime
+	if e.record != nil {
+		e.record(record)
+	}
+	if !e.h264 {
+		return b, release, nil
+	}
+
+	sei := sealSEI(record)
+	sealed := make([]byte, 0, len(sei)+len(b))
+	sealed = append(sealed, sei...)
+	sealed = append(sealed, b...)
+	release()
+	return sealed, func() {}, nil
+}
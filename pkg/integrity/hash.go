@@ -0,0 +1,82 @@
+// Package integrity proves that a recording's frames weren't altered. Each frame's content is hashed
+// before it's encoded, and each encoded frame is sealed with a MAC that chains the frame hash, encoded data
+// and previous seal. Seals are logged, and embedded into H.264 streams as SEI, so Verifier can verify
+// decoded streams.
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"image"
+	"image/draw"
+	"time"
+)
+
+// Hash is a SHA-256 hash.
+type Hash [sha256.Size]byte
+
+// FrameRecord is an encoded frame's seal.
+type FrameRecord struct {
+	// Sequence is the frame's index in the encoder's stream, from 0.
+	Sequence uint64
+	// CaptureTime is the frame's capture time if it's known.
+	CaptureTime time.Time
+	// FrameHash is the frame's content hash before it was encoded, see FrameHash.
+	FrameHash Hash
+	// MAC is the frame's seal, which chains the previous seal.
+	MAC Hash
+}
+
+// FrameHash returns the hash of img's pixels. YCbCr image planes are hashed as they are, and other
+// images are hashed in RGBA, with their formats and sizes.
+func FrameHash(img image.Image) Hash {
+	h := sha256.New()
+	bounds := img.Bounds()
+	w, ht := bounds.Dx(), bounds.Dy()
+
+	switch v := img.(type) {
+	case *image.YCbCr:
+		writeHeader(h, "YCbCr"+v.SubsampleRatio.String(), w, ht)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			i := v.YOffset(bounds.Min.X, y)
+			h.Write(v.Y[i : i+w])
+		}
+		// Chroma rows and columns that cover bounds
+		c0, c1 := v.COffset(bounds.Min.X, bounds.Min.Y), v.COffset(bounds.Max.X-1, bounds.Min.Y)
+		cw := c1 - c0 + 1
+		last := -1
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			i := v.COffset(bounds.Min.X, y)
+			if i == last {
+				continue
+			}
+			last = i
+			h.Write(v.Cb[i : i+cw])
+			h.Write(v.Cr[i : i+cw])
+		}
+	default:
+		rgba, ok := img.(*image.RGBA)
+		if !ok {
+			rgba = image.NewRGBA(image.Rect(0, 0, w, ht))
+			draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+		}
+		writeHeader(h, "RGBA", w, ht)
+		for y := 0; y < ht; y++ {
+			i := rgba.PixOffset(rgba.Rect.Min.X, rgba.Rect.Min.Y+y)
+			h.Write(rgba.Pix[i : i+4*w])
+		}
+	}
+
+	var sum Hash
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func writeHeader(h hash.Hash, format string, width, height int) {
+	h.Write([]byte(format))
+	var size [8]byte
+	binary.BigEndian.PutUint32(size[:4], uint32(width))
+	binary.BigEndian.PutUint32(size[4:], uint32(height))
+	h.Write(size[:])
+}
@@ -0,0 +1,276 @@
+package integrity
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+func TestFrameHash(t *testing.T) {
+	img := image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio420)
+	h := FrameHash(img)
+	if FrameHash(img.SubImage(image.Rect(0, 0, 4, 4))) != h {
+		t.Fatal("expected the same hash of the same pixels")
+	}
+
+	img.Cr[3] = 1
+	if FrameHash(img) == h {
+		t.Fatal("expected the hash to change with the chroma")
+	}
+	if FrameHash(image.NewGray(image.Rect(0, 0, 4, 4))) == FrameHash(image.NewGray(image.Rect(0, 0, 2, 8))) {
+		t.Fatal("expected the hash to include the size")
+	}
+}
+
+func TestEmulationPrevention(t *testing.T) {
+	rbsp := []byte{0, 0, 0, 0, 0, 1, 0, 0, 3, 0, 0}
+	ebsp := emulationPrevention(rbsp)
+	expected := []byte{0, 0, 3, 0, 0, 3, 0, 1, 0, 0, 3, 3, 0, 0}
+	if !bytes.Equal(ebsp, expected) {
+		t.Fatalf("expected %v, but got %v", expected, ebsp)
+	}
+	if out := removeEmulationPrevention(ebsp); !bytes.Equal(out, rbsp) {
+		t.Fatalf("expected %v, but got %v", rbsp, out)
+	}
+}
+
+// testEncoderBuilder encodes each frame to fake NAL units whose data is the first luma sample.
+type testEncoderBuilder struct {
+	mimeType string
+	prepared bool
+}
+
+func (b *testEncoderBuilder) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPH264Codec(90000)
+	c.MimeType = b.mimeType
+	return c
+}
+
+func (b *testEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	e := &testEncoder{r: r}
+	if b.prepared {
+		return &testPreparedEncoder{e}, nil
+	}
+	return e, nil
+}
+
+type testEncoder struct {
+	r video.Reader
+}
+
+func (e *testEncoder) Read() ([]byte, func(), error) {
+	img, release, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	defer release()
+	y := img.(*image.YCbCr).Y[0]
+	return []byte{0, 0, 0, 1, 0x67, y, 0, 0, 1, 0x65, y, y}, func() {}, nil
+}
+
+func (e *testEncoder) Close() error         { return nil }
+func (e *testEncoder) SetBitRate(int) error { return nil }
+func (e *testEncoder) ForceKeyFrame() error { return nil }
+
+type testPreparedEncoder struct {
+	*testEncoder
+}
+
+func (e *testPreparedEncoder) Prepare() ([]byte, error) { return nil, nil }
+
+func testFrames(n int) video.Reader {
+	var i int
+	return video.ReaderFunc(func() (image.Image, func(), error) {
+		if i == n {
+			return nil, func() {}, io.EOF
+		}
+		img := image.NewYCbCr(image.Rect(0, 0, 2, 2), image.YCbCrSubsampleRatio420)
+		img.Y[0] = uint8(i)
+		i++
+		return img, func() {}, nil
+	})
+}
+
+func encodeAll(t *testing.T, b codec.VideoEncoderBuilder, n int) ([][]byte, []FrameRecord) {
+	var records []FrameRecord
+	builder := NewEncoderBuilder(b, WithKey([]byte("secret")), WithRecorder(func(r FrameRecord) {
+		records = append(records, r)
+	}))
+	rc, err := builder.BuildVideoEncoder(testFrames(n), prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var frames [][]byte
+	for {
+		b, _, err := rc.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		frames = append(frames, b)
+	}
+	return frames, records
+}
+
+func TestVerifier(t *testing.T) {
+	frames, records := encodeAll(t, &testEncoderBuilder{mimeType: webrtc.MimeTypeH264}, 4)
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, but got %d", len(records))
+	}
+	for i, record := range records {
+		if record.Sequence != uint64(i) {
+			t.Fatalf("expected the sequence %d, but got %d", i, record.Sequence)
+		}
+	}
+
+	v := NewVerifier([]byte("secret"))
+	for i, frame := range frames {
+		record, err := v.Verify(frame)
+		if err != nil {
+			t.Fatalf("expected the frame %d to be verified, but got %v", i, err)
+		}
+		if record != records[i] {
+			t.Fatalf("expected the seal %+v, but got %+v", records[i], record)
+		}
+	}
+
+	// RTP packetization rewrites start codes
+	v = NewVerifier([]byte("secret"))
+	rewritten := bytes.Replace(frames[0], []byte{0, 0, 1, 0x65}, []byte{0, 0, 0, 1, 0x65}, 1)
+	if _, err := v.Verify(rewritten); err != nil {
+		t.Fatalf("expected the frame with the rewritten start codes to be verified, but got %v", err)
+	}
+
+	tampered := append([]byte{}, frames[1]...)
+	tampered[len(tampered)-1]++
+	if _, err := v.Verify(tampered); !errors.Is(err, ErrTampered) {
+		t.Fatalf("expected %v, but got %v", ErrTampered, err)
+	}
+	if _, err := v.Verify(frames[3]); !errors.Is(err, ErrBrokenChain) {
+		t.Fatalf("expected %v with a missing frame, but got %v", ErrBrokenChain, err)
+	}
+	if _, err := v.Verify([]byte{0, 0, 0, 1, 0x65, 1}); !errors.Is(err, ErrNoSeal) {
+		t.Fatalf("expected %v, but got %v", ErrNoSeal, err)
+	}
+
+	v = NewVerifier([]byte("other"))
+	if _, err := v.Verify(frames[0]); !errors.Is(err, ErrTampered) {
+		t.Fatalf("expected %v with the wrong key, but got %v", ErrTampered, err)
+	}
+}
+
+func TestEncoderBuilderRecordOnly(t *testing.T) {
+	frames, records := encodeAll(t, &testEncoderBuilder{mimeType: webrtc.MimeTypeVP8, prepared: true}, 2)
+	if len(frames[0]) != 12 {
+		t.Fatalf("expected the frames of the other codecs to be kept, but got %d bytes", len(frames[0]))
+	}
+
+	v := NewVerifier([]byte("secret"))
+	for i := range frames {
+		if err := v.VerifyRecord(records[i], frames[i]); err != nil {
+			t.Fatalf("expected the frame %d to be verified, but got %v", i, err)
+		}
+	}
+	if records[0].FrameHash == records[1].FrameHash {
+		t.Fatal("expected the frames to have different hashes")
+	}
+}
+
+func TestEncoderBuilderPreparer(t *testing.T) {
+	builder := NewEncoderBuilder(&testEncoderBuilder{mimeType: webrtc.MimeTypeH264, prepared: true})
+	rc, err := builder.BuildVideoEncoder(testFrames(1), prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rc.(codec.Preparer); !ok {
+		t.Fatal("expected the encoder to keep the preparer")
+	}
+}
+
+// reorderingEncoderBuilder outputs frames in outputs' order, in which -1 outputs nothing.
+type reorderingEncoderBuilder struct {
+	testEncoderBuilder
+	outputs []int
+}
+
+func (b *reorderingEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	return &reorderingEncoder{r: r, outputs: b.outputs, frames: map[int]video.Metadata{}}, nil
+}
+
+type reorderingEncoder struct {
+	testEncoder
+	r       video.Reader
+	outputs []int
+	frames  map[int]video.Metadata
+	read    int
+	last    int
+}
+
+func (e *reorderingEncoder) Read() ([]byte, func(), error) {
+	img, release, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	release()
+	e.frames[int(img.(*image.YCbCr).Y[0])], _ = video.MetadataOf(e.r)
+
+	e.last = e.outputs[e.read]
+	e.read++
+	if e.last < 0 {
+		return nil, func() {}, nil
+	}
+	return []byte{uint8(e.last)}, func() {}, nil
+}
+
+func (e *reorderingEncoder) EncodedFrame() (codec.EncodedFrame, bool) {
+	if e.last < 0 {
+		return codec.EncodedFrame{}, false
+	}
+	return codec.EncodedFrame{Metadata: e.frames[e.last]}, true
+}
+
+func TestEncoderBuilderReorder(t *testing.T) {
+	// Frame 1 is skipped, and frame 2 is delayed after frame 3
+	outputs := []int{0, -1, -1, 3, 2, 4}
+	var records []FrameRecord
+	builder := NewEncoderBuilder(&reorderingEncoderBuilder{
+		testEncoderBuilder: testEncoderBuilder{mimeType: webrtc.MimeTypeVP8},
+		outputs:            outputs,
+	}, WithRecorder(func(r FrameRecord) {
+		records = append(records, r)
+	}))
+	rc, err := builder.BuildVideoEncoder(video.Stamp(testFrames(len(outputs)), time.Now), prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		if _, _, err := rc.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []int{0, 3, 2, 4}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, but got %d", len(expected), len(records))
+	}
+	for i, frame := range expected {
+		img := image.NewYCbCr(image.Rect(0, 0, 2, 2), image.YCbCrSubsampleRatio420)
+		img.Y[0] = uint8(frame)
+		if records[i].FrameHash != FrameHash(img) {
+			t.Fatalf("expected the record %d to have the hash of the frame %d", i, frame)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package integrity
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	naluTypeSEI = 6
+	// seiUserDataUnregistered is the payload type of SEI whose data is identified by a UUID.
+	seiUserDataUnregistered = 5
+	sealLen                 = 8 + 2*len(Hash{})
+)
+
+// sealUUID identifies SEI that carries seals.
+var sealUUID = [16]byte{0x6d, 0x65, 0x64, 0x69, 0x61, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2d, 0x69, 0x6e, 0x74}
+
+var startCode = []byte{0, 0, 0, 1}
+
+// sealSEI returns record's SEI NAL unit in Annex B, with the start code.
+func sealSEI(record FrameRecord) []byte {
+	payload := make([]byte, 0, len(sealUUID)+sealLen)
+	payload = append(payload, sealUUID[:]...)
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], record.Sequence)
+	payload = append(payload, seq[:]...)
+	payload = append(payload, record.FrameHash[:]...)
+	payload = append(payload, record.MAC[:]...)
+
+	rbsp := []byte{seiUserDataUnregistered, byte(len(payload))}
+	rbsp = append(rbsp, payload...)
+	// rbsp_trailing_bits
+	rbsp = append(rbsp, 0x80)
+
+	nalu := append([]byte{}, startCode...)
+	nalu = append(nalu, naluTypeSEI)
+	return append(nalu, emulationPrevention(rbsp)...)
+}
+
+// parseSealSEI returns the seal in SEI NAL unit nalu without the start code, or false if it's not a seal.
+func parseSealSEI(nalu []byte) (FrameRecord, bool) {
+	if len(nalu) < 1 || nalu[0]&0x1f != naluTypeSEI {
+		return FrameRecord{}, false
+	}
+	rbsp := removeEmulationPrevention(nalu[1:])
+	if len(rbsp) < 2+len(sealUUID)+sealLen || rbsp[0] != seiUserDataUnregistered ||
+		int(rbsp[1]) != len(sealUUID)+sealLen {
+		return FrameRecord{}, false
+	}
+	payload := rbsp[2:]
+	if !bytes.Equal(payload[:len(sealUUID)], sealUUID[:]) {
+		return FrameRecord{}, false
+	}
+	payload = payload[len(sealUUID):]
+
+	var record FrameRecord
+	record.Sequence = binary.BigEndian.Uint64(payload)
+	copy(record.FrameHash[:], payload[8:])
+	copy(record.MAC[:], payload[8+len(Hash{}):])
+	return record, true
+}
+
+// emulationPrevention inserts 0x03 after each two zero bytes followed by a byte up to 0x03, so
+// the payload doesn't contain a start code.
+func emulationPrevention(rbsp []byte) []byte {
+	out := make([]byte, 0, len(rbsp)+len(rbsp)/2)
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros == 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+func removeEmulationPrevention(ebsp []byte) []byte {
+	out := make([]byte, 0, len(ebsp))
+	zeros := 0
+	for _, b := range ebsp {
+		if zeros == 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+// splitNALUs returns access unit au's NAL units in Annex B, without start codes.
+func splitNALUs(au []byte) [][]byte {
+	var nalus [][]byte
+	start := -1
+	for i := 0; i+2 < len(au); {
+		if au[i] == 0 && au[i+1] == 0 && au[i+2] == 1 {
+			if start >= 0 {
+				nalus = append(nalus, bytes.TrimRight(au[start:i], "\x00"))
+			}
+			i += 3
+			start = i
+			continue
+		}
+		i++
+	}
+	if start >= 0 {
+		nalus = append(nalus, au[start:])
+	}
+	return nalus
+}
@@ -0,0 +1,64 @@
+package integrity
+
+import (
+	"crypto/hmac"
+	"errors"
+)
+
+var (
+	// ErrNoSeal is returned by Verifier when a frame doesn't have a seal.
+	ErrNoSeal = errors.New("integrity: the frame isn't sealed")
+	// ErrBrokenChain is returned by Verifier when a frame is missing or reordered.
+	ErrBrokenChain = errors.New("integrity: the chain of the seals is broken")
+	// ErrTampered is returned by Verifier when a frame doesn't match its seal.
+	ErrTampered = errors.New("integrity: the frame doesn't match its seal")
+)
+
+// Verifier verifies a stream's sealed frames from its first frame, e.g. a recording's access units or
+// a depacketized RTP stream before decoding. Since decoding is deterministic, verified data proves
+// decoded frames, and frame hashes in seals identify frames before lossy encoding.
+type Verifier struct {
+	chain *chain
+}
+
+// NewVerifier creates a Verifier with the encoder's key, see WithKey.
+func NewVerifier(key []byte) *Verifier {
+	return &Verifier{chain: newChain(append([]byte{}, key...))}
+}
+
+// Verify verifies an H.264 access unit in Annex B, and returns its seal. After an error, the chain only continues
+// with following frames if the frame's seal was found.
+func (v *Verifier) Verify(au []byte) (FrameRecord, error) {
+	for _, nalu := range splitNALUs(au) {
+		if record, ok := parseSealSEI(nalu); ok {
+			return record, v.VerifyRecord(record, au)
+		}
+	}
+	return FrameRecord{}, ErrNoSeal
+}
+
+// VerifyRecord verifies encoded frame data against its recorded seal, e.g. for codecs whose seals aren't
+// embedded in the stream. Records have to be verified in order.
+func (v *Verifier) VerifyRecord(record FrameRecord, data []byte) error {
+	h264 := false
+	for _, nalu := range splitNALUs(data) {
+		if _, ok := parseSealSEI(nalu); ok {
+			h264 = true
+			break
+		}
+	}
+
+	sequence := v.chain.sequence
+	mac := v.chain.mac(record.Sequence, record.FrameHash, dataHash(h264, data))
+	// Continue from the seal, so following frames are verified after a broken frame.
+	v.chain.prev = record.MAC
+	v.chain.sequence = record.Sequence + 1
+
+	if record.Sequence != sequence {
+		return ErrBrokenChain
+	}
+	if !hmac.Equal(mac[:], record.MAC[:]) {
+		return ErrTampered
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	// agcTargetLevel is the RMS level AutoGainControl adjusts input to, i.e. -20 dBFS.
+	agcTargetLevel = 0.1
+	// agcMaxGain is AutoGainControl's maximum gain, i.e. 30 dB.
+	agcMaxGain = 31.6
+	agcMinGain = 0.1
+	// agcSilenceLevel is the RMS level below which gain isn't raised, so background noise
+	// in pauses isn't amplified.
+	agcSilenceLevel = 0.001
+	// Gain is lowered quickly to avoid clipping, and raised slowly to keep speech dynamics.
+	agcAttack  = 20 * time.Millisecond
+	agcRelease = time.Second
+)
+
+// AutoGainControl returns a transform that keeps input level around -20 dBFS, like browsers'
+// autoGainControl. Gain is up to 30 dB, and samples are clipped at full scale.
+func AutoGainControl() TransformFunc {
+	return func(r Reader) Reader {
+		gain := 1.0
+		var (
+			buf  []float64
+			pool wave.Pool
+		)
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			info := chunk.ChunkInfo()
+			if info.Len == 0 || info.Channels == 0 {
+				return chunk, release, nil
+			}
+			defer release()
+			buf = normalizedSamples(chunk, buf)
+
+			level := rms(buf)
+			target := gain
+			if level > agcSilenceLevel || level*gain > agcTargetLevel {
+				target = math.Max(agcMinGain, math.Min(agcMaxGain, agcTargetLevel/level))
+			}
+			tau := agcRelease
+			if target < gain {
+				tau = agcAttack
+			}
+			next := gain + (target-gain)*smoothingFactor(info.Len, info.SamplingRate, tau)
+
+			applyGain(buf, info.Channels, gain, next)
+			gain = next
+			out, releaseOut := denormalizedChunk(&pool, chunk, buf)
+			return out, releaseOut, nil
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package audio
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// Condition is a set of audio input problems detected by Analyze.
+type Condition uint
+
+// Condition flags. No flag means the input is normal.
+const (
+	// ConditionClipping means the signal keeps reaching the full scale, e.g. the input gain is too high.
+	ConditionClipping Condition = 1 << iota
+	// ConditionDCOffset means the signal is biased from zero, e.g. the microphone or its amplifier is broken.
+	ConditionDCOffset
+	// ConditionDeadMic means the input has been digitally silent, e.g. the microphone is muted by hardware
+	// or disconnected.
+	ConditionDeadMic
+)
+
+// Has reports whether c contains all of flag's flags.
+func (c Condition) Has(flag Condition) bool {
+	return c&flag == flag
+}
+
+func (c Condition) String() string {
+	if c == 0 {
+		return "normal"
+	}
+	var names []string
+	for _, f := range []struct {
+		flag Condition
+		name string
+	}{
+		{ConditionClipping, "clipping"},
+		{ConditionDCOffset, "dc-offset"},
+		{ConditionDeadMic, "dead-mic"},
+	} {
+		if c.Has(f.flag) {
+			names = append(names, f.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Analysis is the result of Analyze. Levels are normalized to [-1, 1], and measured over the last
+// analysis window.
+type Analysis struct {
+	Condition Condition
+	// Peak is the maximum absolute level.
+	Peak float64
+	// DCOffset is the mean level.
+	DCOffset float64
+	// ClippedRatio is the share of samples reaching the clipping level.
+	ClippedRatio float64
+}
+
+const (
+	// analysisWindow is how much audio the levels are measured over at once.
+	analysisWindow = 500 * time.Millisecond
+	// clippedRatioThreshold is the share of clipped samples at which a window counts as clipping.
+	clippedRatioThreshold = 0.001
+
+	defaultClippingLevel     = 0.99
+	defaultDCOffsetThreshold = 0.1
+	defaultSustainDuration   = 2 * time.Second
+	defaultDeadMicDuration   = 5 * time.Second
+	defaultSamplingRate      = 48000
+)
+
+type analyzeConfig struct {
+	clippingLevel     float64
+	dcOffsetThreshold float64
+	sustainDuration   time.Duration
+	deadMicDuration   time.Duration
+}
+
+// AnalyzeOption configures Analyze.
+type AnalyzeOption func(*analyzeConfig)
+
+// WithClippingLevel sets the absolute level of clipped samples. The default is 0.99.
+func WithClippingLevel(level float64) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.clippingLevel = level
+	}
+}
+
+// WithDCOffsetThreshold sets the absolute mean level to detect DC offset. The default is 0.1.
+func WithDCOffsetThreshold(offset float64) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.dcOffsetThreshold = offset
+	}
+}
+
+// WithSustainDuration sets how long clipping or DC offset has to continue before the condition is reported.
+// The default is 2 seconds.
+func WithSustainDuration(d time.Duration) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.sustainDuration = d
+	}
+}
+
+// WithDeadMicDuration sets how long the input has to be digitally silent before the condition is reported.
+// The default is 5 seconds.
+func WithDeadMicDuration(d time.Duration) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.deadMicDuration = d
+	}
+}
+
+// Analyze detects sustained clipping, DC offset and dead microphones, and calls onChange when the input's
+// condition changes. Durations are measured in samples instead of wall-clock time.
+// onChange is called in the reading goroutine, so it shouldn't block.
+func Analyze(onChange func(Analysis), opts ...AnalyzeOption) TransformFunc {
+	c := analyzeConfig{
+		clippingLevel:     defaultClippingLevel,
+		dcOffsetThreshold: defaultDCOffsetThreshold,
+		sustainDuration:   defaultSustainDuration,
+		deadMicDuration:   defaultDeadMicDuration,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(r Reader) Reader {
+		var (
+			condition Condition
+			// Current window's levels
+			n, clipped int
+			sum, peak  float64
+			silent     = true
+			// Durations of consecutive windows with each problem
+			clipping, dcOffset, deadMic time.Duration
+		)
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			info := chunk.ChunkInfo()
+			samplingRate := info.SamplingRate
+			if samplingRate <= 0 {
+				samplingRate = defaultSamplingRate
+			}
+			windowLen := int(int64(samplingRate) * int64(analysisWindow) / int64(time.Second))
+
+			for i := 0; i < info.Len; i++ {
+				for ch := 0; ch < info.Channels; ch++ {
+					v := normalize(chunk.At(i, ch))
+					abs := math.Abs(v)
+					sum += v
+					if abs > peak {
+						peak = abs
+					}
+					if abs >= c.clippingLevel {
+						clipped++
+					}
+					if v != 0 {
+						silent = false
+					}
+				}
+
+				n++
+				if n < windowLen {
+					continue
+				}
+
+				samples := float64(n * info.Channels)
+				analysis := Analysis{
+					Peak:         peak,
+					DCOffset:     sum / samples,
+					ClippedRatio: float64(clipped) / samples,
+				}
+				clipping = sustain(clipping, analysis.ClippedRatio >= clippedRatioThreshold)
+				dcOffset = sustain(dcOffset, math.Abs(analysis.DCOffset) >= c.dcOffsetThreshold)
+				deadMic = sustain(deadMic, silent)
+				n, clipped, sum, peak, silent = 0, 0, 0, 0, true
+
+				if clipping >= c.sustainDuration {
+					analysis.Condition |= ConditionClipping
+				}
+				if dcOffset >= c.sustainDuration {
+					analysis.Condition |= ConditionDCOffset
+				}
+				if deadMic >= c.deadMicDuration {
+					analysis.Condition |= ConditionDeadMic
+				}
+				if analysis.Condition != condition {
+					condition = analysis.Condition
+					onChange(analysis)
+				}
+			}
+
+			return chunk, release, nil
+		})
+	}
+}
+
+// sustain returns the duration of consecutive windows with a problem.
+func sustain(d time.Duration, detected bool) time.Duration {
+	if !detected {
+		return 0
+	}
+	return d + analysisWindow
+}
+
+// normalize converts the sample to [-1, 1].
+func normalize(s wave.Sample) float64 {
+	switch v := s.(type) {
+	case wave.Int16Sample:
+		return float64(v) / -math.MinInt16
+	case wave.Float32Sample:
+		return float64(v)
+	default:
+		return float64(s.Int()) / -math.MinInt32
+	}
+}
@@ -0,0 +1,94 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestAnalyze(t *testing.T) {
+	// 100ms of mono audio at 8kHz
+	const chunkLen = 800
+
+	var (
+		level    func(i int) float32
+		analyses []Analysis
+	)
+	r := Analyze(func(a Analysis) {
+		analyses = append(analyses, a)
+	})(ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			chunk.Data[i] = level(i)
+		}
+		return chunk, func() {}, nil
+	}))
+
+	read := func(chunks int, f func(i int) float32) {
+		level = f
+		for i := 0; i < chunks; i++ {
+			if _, _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	sine := func(amplitude, offset float64) func(int) float32 {
+		return func(i int) float32 {
+			return float32(amplitude*math.Sin(float64(i)*2*math.Pi/80) + offset)
+		}
+	}
+	clipped := func(i int) float32 {
+		return float32(math.Max(-1, math.Min(1, 2*math.Sin(float64(i)*2*math.Pi/80))))
+	}
+	silence := func(int) float32 { return 0 }
+
+	read(50, sine(0.5, 0))
+	if len(analyses) != 0 {
+		t.Fatalf("expected no event from the normal input, but got %+v", analyses)
+	}
+
+	// Clipping is reported after 2 seconds
+	read(19, clipped)
+	if len(analyses) != 0 {
+		t.Fatalf("expected no event before 2 seconds, but got %+v", analyses)
+	}
+	read(1, clipped)
+	if len(analyses) != 1 || analyses[0].Condition != ConditionClipping {
+		t.Fatalf("expected clipping, but got %+v", analyses)
+	}
+	if analyses[0].Peak != 1 {
+		t.Fatalf("expected the peak to be 1, but got %f", analyses[0].Peak)
+	}
+
+	read(5, sine(0.3, 0.2))
+	if len(analyses) != 2 || analyses[1].Condition != 0 {
+		t.Fatalf("expected to recover from clipping, but got %+v", analyses)
+	}
+	read(15, sine(0.3, 0.2))
+	if len(analyses) != 3 || analyses[2].Condition != ConditionDCOffset {
+		t.Fatalf("expected DC offset, but got %+v", analyses)
+	}
+	if math.Abs(analyses[2].DCOffset-0.2) > 0.001 {
+		t.Fatalf("expected the DC offset to be 0.2, but got %f", analyses[2].DCOffset)
+	}
+
+	// Dead mic is reported after 5 seconds
+	read(50, silence)
+	if len(analyses) != 5 || analyses[3].Condition != 0 || analyses[4].Condition != ConditionDeadMic {
+		t.Fatalf("expected dead mic, but got %+v", analyses)
+	}
+}
+
+func TestConditionString(t *testing.T) {
+	for c, expected := range map[Condition]string{
+		0:                                     "normal",
+		ConditionClipping:                     "clipping",
+		ConditionDCOffset | ConditionDeadMic:  "dc-offset|dead-mic",
+		ConditionClipping | ConditionDCOffset: "clipping|dc-offset",
+	} {
+		if s := c.String(); s != expected {
+			t.Errorf("expected %s, but got %s", expected, s)
+		}
+	}
+}
@@ -2,12 +2,48 @@ package audio
 
 import (
 	"errors"
+	"time"
 
 	"github.com/pion/mediadevices/pkg/wave"
 )
 
 var errUnsupported = errors.New("unsupported audio format")
 
+// NewFrameBuffer creates an audio transform that repacketizes chunks of any size from the source into chunks of
+// duration, like NewBuffer with the number of samples computed from the source's sampling rate. It's used
+// to match drivers' chunks to the packets' frame duration (ptime).
+func NewFrameBuffer(duration time.Duration) TransformFunc {
+	return func(r Reader) Reader {
+		var buffered Reader
+		// The source is peeked to learn the sampling rate, and the peeked chunk is fed to the buffer.
+		var peeked wave.Audio
+		source := ReaderFunc(func() (wave.Audio, func(), error) {
+			if peeked != nil {
+				chunk := peeked
+				peeked = nil
+				return chunk, func() {}, nil
+			}
+			return r.Read()
+		})
+
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			if buffered == nil {
+				chunk, _, err := r.Read()
+				if err != nil {
+					return nil, func() {}, err
+				}
+				samplingRate := chunk.ChunkInfo().SamplingRate
+				if samplingRate <= 0 {
+					return nil, func() {}, errors.New("unknown sampling rate")
+				}
+				peeked = chunk
+				buffered = NewBuffer(int(int64(samplingRate) * int64(duration) / int64(time.Second)))(source)
+			}
+			return buffered.Read()
+		})
+	}
+}
+
 // NewBuffer creates audio transform to buffer signal to have exact nSample samples.
 func NewBuffer(nSamples int) TransformFunc {
 	var inBuff wave.Audio
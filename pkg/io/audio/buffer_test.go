@@ -4,6 +4,7 @@ import (
 	"io"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/pion/mediadevices/pkg/wave"
 )
@@ -70,3 +71,33 @@ func TestBuffer(t *testing.T) {
 		}
 	}
 }
+
+func TestFrameBuffer(t *testing.T) {
+	// 25ms chunks at 1kHz are repacketized into 10ms frames
+	var n int
+	r := NewFrameBuffer(10 * time.Millisecond)(ReaderFunc(func() (wave.Audio, func(), error) {
+		if n >= 4 {
+			return nil, func() {}, io.EOF
+		}
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 25, Channels: 1, SamplingRate: 1000})
+		for i := range chunk.Data {
+			chunk.Data[i] = int16(n*25 + i)
+		}
+		n++
+		return chunk, func() {}, nil
+	}))
+
+	for i := 0; i < 10; i++ {
+		a, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunk := a.(*wave.Int16Interleaved)
+		if chunk.Size.Len != 10 || chunk.Data[0] != int16(i*10) {
+			t.Fatalf("expected frame %d to have 10 samples from %d, but got %v", i, i*10, chunk.Data)
+		}
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Fatalf("expected EOF, but got %v", err)
+	}
+}
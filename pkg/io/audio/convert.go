@@ -0,0 +1,37 @@
+package audio
+
+import (
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// NewConverter creates an audio transform that converts chunks into the first of types, unless they're already
+// one of types. It's used to feed a driver's chunks to a consumer that only supports some of the types.
+// If dither is true, bit depth reduction to Int16 is dithered.
+func NewConverter(dither bool, types ...wave.Type) TransformFunc {
+	return func(r Reader) Reader {
+		if len(types) == 0 {
+			return r
+		}
+		converter := wave.NewConverter(types[0], dither)
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			t := wave.TypeOf(chunk)
+			for _, typ := range types {
+				if t == typ {
+					return chunk, release, nil
+				}
+			}
+
+			converted, err := converter.Convert(chunk)
+			release()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			return converted, func() {}, nil
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestConverter(t *testing.T) {
+	input := []wave.Audio{
+		&wave.Int16Interleaved{
+			Size: wave.ChunkInfo{Len: 2, Channels: 2, SamplingRate: 1234},
+			Data: []int16{1, 2, 3, 4},
+		},
+		&wave.Float32NonInterleaved{
+			Size: wave.ChunkInfo{Len: 2, Channels: 2, SamplingRate: 1234},
+			Data: [][]float32{{0.5, -0.5}, {0.25, -0.25}},
+		},
+	}
+	expected := []wave.Audio{
+		input[0],
+		&wave.Float32Interleaved{
+			Size: wave.ChunkInfo{Len: 2, Channels: 2, SamplingRate: 1234},
+			Data: []float32{0.5, 0.25, -0.5, -0.25},
+		},
+	}
+
+	var i int
+	r := NewConverter(false, wave.TypeFloat32Interleaved, wave.TypeInt16Interleaved)(ReaderFunc(func() (wave.Audio, func(), error) {
+		i++
+		return input[i-1], func() {}, nil
+	}))
+	for j := range expected {
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected[j], chunk) {
+			t.Errorf("expected chunk %d to be %v, but got %v", j, expected[j], chunk)
+		}
+	}
+}
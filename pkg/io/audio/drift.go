@@ -0,0 +1,341 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	defaultDriftTarget        = 40 * time.Millisecond
+	defaultDriftCapacity      = 200 * time.Millisecond
+	defaultMaxDriftCorrection = 0.005
+)
+
+type driftBufferConfig struct {
+	target        time.Duration
+	capacity      time.Duration
+	maxCorrection float64
+	// now and maxSkew are set by WithDriftClock
+	now     func() time.Time
+	maxSkew time.Duration
+}
+
+// DriftBufferOption configures NewDriftBuffer.
+type DriftBufferOption func(*driftBufferConfig)
+
+// WithDriftTarget sets how much buffered audio is kept. It's the latency the buffer adds.
+// The default is 40ms.
+func WithDriftTarget(d time.Duration) DriftBufferOption {
+	return func(c *driftBufferConfig) {
+		c.target = d
+	}
+}
+
+// WithDriftCapacity sets the most audio that can be buffered. When it's exceeded, the oldest samples
+// are dropped down to the target. The default is 200ms.
+func WithDriftCapacity(d time.Duration) DriftBufferOption {
+	return func(c *driftBufferConfig) {
+		c.capacity = d
+	}
+}
+
+// WithMaxDriftCorrection sets the largest resampling ratio used to follow drift. The default is 0.005,
+// which changes the pitch by less than 9 cents.
+func WithMaxDriftCorrection(ratio float64) DriftBufferOption {
+	return func(c *driftBufferConfig) {
+		c.maxCorrection = ratio
+	}
+}
+
+// WithDriftClock makes the buffer follow now's clock instead of the consumer's rate, e.g. a clock shared by
+// audio and video tracks. Chunks are stamped with now when they're read from the source, and when the source's
+// samples differ from the clock's elapsed time by more than maxSkew, chunks are resampled by up to the max drift
+// correction until the output is back in sync. It's meant for consumers that read as fast as chunks arrive, e.g.
+// encoders, whose output has nominal durations.
+func WithDriftClock(now func() time.Time, maxSkew time.Duration) DriftBufferOption {
+	return func(c *driftBufferConfig) {
+		c.now, c.maxSkew = now, maxSkew
+	}
+}
+
+// NewDriftBuffer creates an audio transform that buffers signal to exactly nSamples samples like NewBuffer, but
+// reads the source in another goroutine and keeps the buffered audio bounded. When the device and consumer run at
+// slightly different rates, chunks are resampled by up to the max drift correction to bring the buffer back to
+// the target, so latency doesn't grow during a long capture. The goroutine stops when the source returns an
+// error.
+func NewDriftBuffer(nSamples int, opts ...DriftBufferOption) TransformFunc {
+	c := driftBufferConfig{
+		target:        defaultDriftTarget,
+		capacity:      defaultDriftCapacity,
+		maxCorrection: defaultMaxDriftCorrection,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(r Reader) Reader {
+		var (
+			once sync.Once
+			mu   sync.Mutex
+			cond = sync.NewCond(&mu)
+			q    = newDriftQueue(nSamples, c)
+			err  error
+		)
+
+		fill := func() {
+			for {
+				chunk, release, readErr := r.Read()
+				var captured time.Time
+				if c.now != nil {
+					captured = c.now()
+				}
+				mu.Lock()
+				if readErr == nil {
+					readErr = q.push(chunk, captured)
+					release()
+				}
+				if readErr != nil {
+					err = readErr
+				}
+				cond.Broadcast()
+				mu.Unlock()
+				if readErr != nil {
+					return
+				}
+			}
+		}
+
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			once.Do(func() { go fill() })
+
+			mu.Lock()
+			defer mu.Unlock()
+			for {
+				if chunk, ok := q.pop(err != nil); ok {
+					return chunk, func() {}, nil
+				}
+				if err != nil {
+					return nil, func() {}, err
+				}
+				cond.Wait()
+			}
+		})
+	}
+}
+
+// driftQueue is NewDriftBuffer's bounded queue, which resamples output chunks to keep the queue level
+// around the target.
+type driftQueue struct {
+	driftBufferConfig
+	nSamples int
+
+	info     wave.ChunkInfo
+	format   wave.SampleFormat
+	newChunk func(wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64))
+	// samples are interleaved samples normalized to [-1, 1]
+	samples []float64
+	// pos is the next output sample's position between samples, in frames
+	pos float64
+	// primed is false until the level reaches the target at the start or after an underrun
+	primed  bool
+	dropped int
+
+	// start is when the first chunk was read under WithDriftClock, and received counts frames read from the source
+	// since then. skew is received minus the frames in the clock's elapsed time, and resampled counts extra frames
+	// consumed by resampling to compensate for it.
+	start     time.Time
+	received  float64
+	skew      float64
+	resampled float64
+}
+
+func newDriftQueue(nSamples int, c driftBufferConfig) *driftQueue {
+	return &driftQueue{
+		driftBufferConfig: c,
+		nSamples:          nSamples,
+	}
+}
+
+// frames returns d in frames.
+func (q *driftQueue) frames(d time.Duration) float64 {
+	samplingRate := q.info.SamplingRate
+	if samplingRate <= 0 {
+		samplingRate = defaultSamplingRate
+	}
+	return float64(samplingRate) * d.Seconds()
+}
+
+func (q *driftQueue) available() int {
+	if q.info.Channels == 0 {
+		return 0
+	}
+	return len(q.samples) / q.info.Channels
+}
+
+// level returns the number of frames that haven't been read.
+func (q *driftQueue) level() float64 {
+	return float64(q.available()) - q.pos
+}
+
+func (q *driftQueue) discard(frames int) {
+	q.samples = q.samples[frames*q.info.Channels:]
+}
+
+// push adds chunk, which was read from the source at captured if the queue follows a clock.
+func (q *driftQueue) push(chunk wave.Audio, captured time.Time) error {
+	info := chunk.ChunkInfo()
+
+	var newChunk func(wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64))
+	switch chunk.(type) {
+	case *wave.Int16Interleaved:
+		newChunk = func(info wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64)) {
+			a := wave.NewInt16Interleaved(info)
+			return a, func(i, ch int, v float64) { a.SetInt16(i, ch, toInt16(v)) }
+		}
+	case *wave.Int16NonInterleaved:
+		newChunk = func(info wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64)) {
+			a := wave.NewInt16NonInterleaved(info)
+			return a, func(i, ch int, v float64) { a.SetInt16(i, ch, toInt16(v)) }
+		}
+	case *wave.Int32Interleaved:
+		newChunk = func(info wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64)) {
+			a := wave.NewInt32Interleaved(info)
+			return a, func(i, ch int, v float64) { a.SetInt32(i, ch, toInt32(v)) }
+		}
+	case *wave.Int32NonInterleaved:
+		newChunk = func(info wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64)) {
+			a := wave.NewInt32NonInterleaved(info)
+			return a, func(i, ch int, v float64) { a.SetInt32(i, ch, toInt32(v)) }
+		}
+	case *wave.Float32Interleaved:
+		newChunk = func(info wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64)) {
+			a := wave.NewFloat32Interleaved(info)
+			return a, func(i, ch int, v float64) { a.SetFloat32(i, ch, wave.Float32Sample(v)) }
+		}
+	case *wave.Float32NonInterleaved:
+		newChunk = func(info wave.ChunkInfo) (wave.Audio, func(i, ch int, v float64)) {
+			a := wave.NewFloat32NonInterleaved(info)
+			return a, func(i, ch int, v float64) { a.SetFloat32(i, ch, wave.Float32Sample(v)) }
+		}
+	default:
+		return errUnsupported
+	}
+
+	if info.Channels != q.info.Channels || info.SamplingRate != q.info.SamplingRate ||
+		chunk.SampleFormat() != q.format {
+		// The format changed, so buffered samples can't be mixed with new ones.
+		q.samples = q.samples[:0]
+		q.pos = 0
+		q.primed = false
+		q.start = time.Time{}
+	}
+	q.info = info
+	q.format = chunk.SampleFormat()
+	q.newChunk = newChunk
+
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			q.samples = append(q.samples, normalize(chunk.At(i, ch)))
+		}
+	}
+
+	if q.now != nil {
+		if q.start.IsZero() {
+			// The first chunk was captured before it was read, so skew is counted from the next chunk on
+			q.start, q.received, q.skew, q.resampled = captured, 0, 0, 0
+		} else {
+			q.received += float64(info.Len)
+			q.skew = q.received - q.frames(captured.Sub(q.start))
+		}
+	}
+
+	if q.level() > q.frames(q.capacity) {
+		// The consumer has stalled. Drop the oldest samples instead of growing latency.
+		drop := int(q.level() - q.frames(q.target))
+		q.discard(drop)
+		q.pos = 0
+		q.dropped += drop
+	}
+	return nil
+}
+
+// correction returns the ratio of extra frames to consume, proportional to how far the level is from the target.
+// A level within one chunk of the target is left alone. With WithDriftClock, it's the max drift correction until
+// the uncompensated skew is within maxSkew.
+func (q *driftQueue) correction() float64 {
+	if q.now != nil {
+		diff := q.skew - q.resampled
+		if math.Abs(diff) <= q.frames(q.maxSkew) {
+			return 0
+		}
+		return math.Copysign(q.maxCorrection, diff)
+	}
+
+	level, target, capacity := q.level(), q.frames(q.target), q.frames(q.capacity)
+	diff := level - target
+	if math.Abs(diff) <= float64(q.nSamples) {
+		return 0
+	}
+
+	var e float64
+	if diff > 0 {
+		e = diff / math.Max(1, capacity-target)
+	} else {
+		e = diff / math.Max(1, target)
+	}
+	return q.maxCorrection * math.Max(-1, math.Min(1, e))
+}
+
+// pop returns the next chunk of nSamples samples. It fails if there aren't enough samples, or the queue hasn't
+// been primed. The flush option skips priming to drain the queue.
+func (q *driftQueue) pop(flush bool) (wave.Audio, bool) {
+	if q.newChunk == nil {
+		return nil, false
+	}
+	if !q.primed && !flush {
+		if q.level() < q.frames(q.target) {
+			return nil, false
+		}
+		q.primed = true
+	}
+
+	step := 1 + q.correction()
+	last := q.pos + step*float64(q.nSamples-1)
+	if int(last)+2 > q.available() {
+		// Underrun: wait for the target again, so the next chunks aren't short of samples again.
+		q.primed = false
+		return nil, false
+	}
+
+	info := q.info
+	info.Len = q.nSamples
+	chunk, set := q.newChunk(info)
+	channels := info.Channels
+	for i := 0; i < q.nSamples; i++ {
+		p := q.pos + step*float64(i)
+		j := int(p)
+		f := p - float64(j)
+		for ch := 0; ch < channels; ch++ {
+			a, b := q.samples[j*channels+ch], q.samples[(j+1)*channels+ch]
+			set(i, ch, a+(b-a)*f)
+		}
+	}
+
+	q.pos += step * float64(q.nSamples)
+	q.resampled += (step - 1) * float64(q.nSamples)
+	consumed := int(q.pos)
+	q.discard(consumed)
+	q.pos -= float64(consumed)
+	return chunk, true
+}
+
+func toInt16(v float64) wave.Int16Sample {
+	return wave.Int16Sample(math.Max(math.MinInt16, math.Min(math.MaxInt16, math.Round(v*-math.MinInt16))))
+}
+
+func toInt32(v float64) wave.Int32Sample {
+	return wave.Int32Sample(math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Round(v*-math.MinInt32))))
+}
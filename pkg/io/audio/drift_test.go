@@ -0,0 +1,200 @@
+package audio
+
+import (
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// rampChunk returns a mono chunk at 1kHz whose samples are start, start+1, ...
+func rampChunk(start, n int) *wave.Int16Interleaved {
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: n, Channels: 1, SamplingRate: 1000})
+	for i := range chunk.Data {
+		chunk.Data[i] = int16(start + i)
+	}
+	return chunk
+}
+
+func TestDriftQueue(t *testing.T) {
+	config := driftBufferConfig{
+		target:        50 * time.Millisecond,
+		capacity:      200 * time.Millisecond,
+		maxCorrection: 0.1,
+	}
+
+	t.Run("Passthrough", func(t *testing.T) {
+		q := newDriftQueue(10, config)
+		if err := q.push(rampChunk(0, 40), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := q.pop(false); ok {
+			t.Fatal("expected to wait for the target")
+		}
+		if err := q.push(rampChunk(40, 20), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			chunk, ok := q.pop(false)
+			if !ok {
+				t.Fatal("expected a chunk")
+			}
+			data := chunk.(*wave.Int16Interleaved).Data
+			for j, v := range data {
+				if int(v) != i*10+j {
+					t.Fatalf("expected samples from %d, but got %v", i*10, data)
+				}
+			}
+		}
+	})
+
+	t.Run("Overflow", func(t *testing.T) {
+		q := newDriftQueue(10, config)
+		if err := q.push(rampChunk(0, 300), time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		if q.dropped != 250 {
+			t.Fatalf("expected to drop 250 samples, but dropped %d", q.dropped)
+		}
+		chunk, ok := q.pop(false)
+		if !ok {
+			t.Fatal("expected a chunk")
+		}
+		if v := chunk.(*wave.Int16Interleaved).Data[0]; v != 250 {
+			t.Fatalf("expected the chunk to start from 250, but got %d", v)
+		}
+	})
+
+	testCases := map[string]struct {
+		// frames pushed per 2 chunks of 10 frames
+		frames int
+		// expected level after the push
+		min, max float64
+	}{
+		"FastDevice": {frames: 21, min: 100, max: 150},
+		"SlowDevice": {frames: 19, min: 15, max: 40},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			q := newDriftQueue(10, config)
+			var next int
+			var level float64
+			for i := 0; i < 2000; i++ {
+				if err := q.push(rampChunk(next, c.frames), time.Time{}); err != nil {
+					t.Fatal(err)
+				}
+				next += c.frames
+				level = q.level()
+				for j := 0; j < 2; j++ {
+					if _, ok := q.pop(false); !ok && i > 100 {
+						t.Fatalf("unexpected underrun at %d with level %f", i, q.level())
+					}
+				}
+			}
+			if q.dropped != 0 {
+				t.Fatalf("expected no samples to be dropped, but dropped %d", q.dropped)
+			}
+			if level < c.min || level > c.max {
+				t.Fatalf("expected the level between %f and %f, but got %f", c.min, c.max, level)
+			}
+		})
+	}
+}
+
+func TestDriftQueueClock(t *testing.T) {
+	const maxSkew = 20 * time.Millisecond
+	testCases := map[string]time.Duration{
+		// Clock intervals of the 10-frame chunks, which are 10ms at the device rate
+		"FastDevice": 9980 * time.Microsecond,
+		"SlowDevice": 10020 * time.Microsecond,
+		"NoDrift":    10 * time.Millisecond,
+	}
+	for name, interval := range testCases {
+		interval := interval
+		t.Run(name, func(t *testing.T) {
+			config := driftBufferConfig{
+				target:        50 * time.Millisecond,
+				capacity:      200 * time.Millisecond,
+				maxCorrection: 0.005,
+				now:           time.Now,
+				maxSkew:       maxSkew,
+			}
+			q := newDriftQueue(10, config)
+			start := time.Unix(1600000000, 0)
+			var output int
+			for i := 0; i < 5000; i++ {
+				// The consumer keeps the queue level, since it doesn't follow the clock
+				if err := q.push(rampChunk(i*10, 10), start.Add(time.Duration(i)*interval)); err != nil {
+					t.Fatal(err)
+				}
+				for {
+					if _, ok := q.pop(false); !ok {
+						break
+					}
+					output += 10
+				}
+			}
+
+			// Output and buffered frames follow the clock from the first chunk
+			elapsed := q.frames(4999*interval) + 10
+			skew := float64(output) + q.level() - elapsed
+			if math.Abs(skew) > q.frames(maxSkew)+10 {
+				t.Fatalf("expected the output within %v of the clock, but the skew is %f frames", maxSkew, skew)
+			}
+			if interval != 10*time.Millisecond && q.resampled == 0 {
+				t.Fatal("expected the chunks to be resampled")
+			}
+			if interval == 10*time.Millisecond && q.resampled != 0 {
+				t.Fatalf("expected no resampling, but resampled %f frames", q.resampled)
+			}
+		})
+	}
+}
+
+func TestDriftBuffer(t *testing.T) {
+	input := make(chan wave.Audio, 2)
+	input <- &wave.Float32Interleaved{
+		Size: wave.ChunkInfo{Len: 3, Channels: 2, SamplingRate: 1000},
+		Data: []float32{0.1, -0.1, 0.2, -0.2, 0.3, -0.3},
+	}
+	input <- &wave.Float32Interleaved{
+		Size: wave.ChunkInfo{Len: 3, Channels: 2, SamplingRate: 1000},
+		Data: []float32{0.4, -0.4, 0.5, -0.5, 0.6, -0.6},
+	}
+	close(input)
+
+	r := NewDriftBuffer(2, WithDriftTarget(6*time.Millisecond))(ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, ok := <-input
+		if !ok {
+			return nil, func() {}, io.EOF
+		}
+		return chunk, func() {}, nil
+	}))
+
+	var n int
+	for {
+		chunk, _, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, ok := chunk.(*wave.Float32Interleaved)
+		if !ok {
+			t.Fatalf("expected *wave.Float32Interleaved, but got %T", chunk)
+		}
+		if f.Data[0] != -f.Data[1] {
+			t.Fatalf("expected the channels to be kept, but got %v", f.Data)
+		}
+		n++
+	}
+	// The remaining samples are drained after the source ends, and the last chunk is stretched since
+	// the level is below the target.
+	if n != 3 {
+		t.Fatalf("expected 3 chunks, but got %d", n)
+	}
+}
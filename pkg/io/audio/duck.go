@@ -0,0 +1,178 @@
+package audio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	// vadThreshold is the level to noise floor ratio detected as speech, i.e. +12 dB.
+	vadThreshold = 4
+	// vadMinLevel is the level below which input is never speech, i.e. -50 dBFS.
+	vadMinLevel = 0.003
+	// vadHangover keeps speech detected in short pauses between words.
+	vadHangover = 300 * time.Millisecond
+	// Noise floor follows quieter chunks quickly, and rises slowly like NoiseSuppression.
+	vadFloorFall = 100 * time.Millisecond
+	vadFloorRise = 5 * time.Second
+
+	// defaultDuckGain is -12 dB.
+	defaultDuckGain    = 0.25
+	defaultDuckAttack  = 50 * time.Millisecond
+	defaultDuckRelease = 500 * time.Millisecond
+)
+
+// VoiceActivity detects speech in an audio source, e.g. a microphone. Duck uses it to lower
+// other sources' gain while speech is detected. The source is fed by the transform Detect returns.
+type VoiceActivity struct {
+	mu       sync.Mutex
+	speaking bool
+}
+
+// NewVoiceActivity creates a VoiceActivity that hasn't detected speech.
+func NewVoiceActivity() *VoiceActivity {
+	return &VoiceActivity{}
+}
+
+// Speaking reports whether speech is detected.
+func (v *VoiceActivity) Speaking() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.speaking
+}
+
+func (v *VoiceActivity) set(speaking bool) {
+	v.mu.Lock()
+	v.speaking = speaking
+	v.mu.Unlock()
+}
+
+// Detect returns a transform that detects speech in audio passing through it. Speech is detected while
+// chunks are 12 dB louder than the noise floor, and detection is held for 300ms after that.
+func (v *VoiceActivity) Detect() TransformFunc {
+	return func(r Reader) Reader {
+		var (
+			started  bool
+			floor    float64
+			hangover time.Duration
+			buf      []float64
+		)
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				v.set(false)
+				return nil, func() {}, err
+			}
+
+			info := chunk.ChunkInfo()
+			if info.Len == 0 || info.Channels == 0 || info.SamplingRate <= 0 {
+				return chunk, release, nil
+			}
+			buf = normalizedSamples(chunk, buf)
+
+			level := rms(buf)
+			speech := level > vadMinLevel && level > floor*vadThreshold
+			switch {
+			case !started:
+				// The floor starts from the first chunk, which is rarely speech.
+				started, floor = true, level
+			case level < floor:
+				floor += (level - floor) * smoothingFactor(info.Len, info.SamplingRate, vadFloorFall)
+			default:
+				floor += (level - floor) * smoothingFactor(info.Len, info.SamplingRate, vadFloorRise)
+			}
+
+			if speech {
+				hangover = vadHangover
+			} else {
+				hangover -= time.Duration(int64(info.Len) * int64(time.Second) / int64(info.SamplingRate))
+			}
+			v.set(hangover > 0)
+			return chunk, release, nil
+		})
+	}
+}
+
+type duckConfig struct {
+	gain    float64
+	attack  time.Duration
+	release time.Duration
+}
+
+// DuckOption configures Duck.
+type DuckOption func(*duckConfig)
+
+// WithDuckGain sets ducked audio gain. The default is 0.25, i.e. -12 dB.
+func WithDuckGain(gain float64) DuckOption {
+	return func(c *duckConfig) {
+		c.gain = gain
+	}
+}
+
+// WithDuckAttack sets the time constant to lower gain when speech starts. The default is 50ms.
+func WithDuckAttack(d time.Duration) DuckOption {
+	return func(c *duckConfig) {
+		c.attack = d
+	}
+}
+
+// WithDuckRelease sets the time constant to restore gain after speech. The default is 500ms.
+func WithDuckRelease(d time.Duration) DuckOption {
+	return func(c *duckConfig) {
+		c.release = d
+	}
+}
+
+// Duck returns a transform that lowers audio gain, e.g. for system audio music, while v detects
+// speech in another source, e.g. the microphone. Chunks have the same type as the source.
+func Duck(v *VoiceActivity, opts ...DuckOption) TransformFunc {
+	c := duckConfig{
+		gain:    defaultDuckGain,
+		attack:  defaultDuckAttack,
+		release: defaultDuckRelease,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(r Reader) Reader {
+		var (
+			buf  []float64
+			pool wave.Pool
+		)
+		gain := 1.0
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			target := 1.0
+			if v.Speaking() {
+				target = c.gain
+			}
+			info := chunk.ChunkInfo()
+			if target == 1 && gain == 1 || info.Len == 0 || info.Channels == 0 {
+				return chunk, release, nil
+			}
+
+			tau := c.release
+			if target < gain {
+				tau = c.attack
+			}
+			next := gain + (target-gain)*smoothingFactor(info.Len, info.SamplingRate, tau)
+			if target == 1 && next > 0.999 {
+				next = 1
+			}
+
+			buf = normalizedSamples(chunk, buf)
+			applyGain(buf, info.Channels, gain, next)
+			gain = next
+			ducked, releaseDucked := denormalizedChunk(&pool, chunk, buf)
+			release()
+			return ducked, releaseDucked, nil
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestVoiceActivity(t *testing.T) {
+	// 20ms of mono audio at 8kHz
+	const chunkLen = 160
+
+	rnd := rand.New(rand.NewSource(1))
+	speech := false
+	var n int
+	v := NewVoiceActivity()
+	r := v.Detect()(ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			s := 0.005 * rnd.NormFloat64()
+			if speech {
+				s += 0.3 * math.Sin(float64(n)*2*math.Pi/80)
+			}
+			chunk.Data[i] = float32(s)
+			n++
+		}
+		return chunk, func() {}, nil
+	}))
+	read := func(chunks int) {
+		for i := 0; i < chunks; i++ {
+			if _, _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	read(50)
+	if v.Speaking() {
+		t.Fatal("expected the noise not to be detected as the speech")
+	}
+
+	speech = true
+	read(1)
+	if !v.Speaking() {
+		t.Fatal("expected the speech to be detected")
+	}
+
+	// Detection is held for 300ms, i.e. 15 chunks.
+	speech = false
+	read(10)
+	if !v.Speaking() {
+		t.Fatal("expected the detection to be held in the pauses")
+	}
+	read(10)
+	if v.Speaking() {
+		t.Fatal("expected the detection to end after the speech")
+	}
+}
+
+func TestDuck(t *testing.T) {
+	const chunkLen = 160
+
+	v := NewVoiceActivity()
+	r := Duck(v, WithDuckAttack(20*time.Millisecond), WithDuckRelease(100*time.Millisecond))(ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 2, SamplingRate: 8000})
+		for i := range chunk.Data {
+			chunk.Data[i] = math.MaxInt16 / 2
+		}
+		return chunk, func() {}, nil
+	}))
+	level := func(chunks int) float64 {
+		var chunk wave.Audio
+		for i := 0; i < chunks; i++ {
+			var err error
+			if chunk, _, err = r.Read(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, ok := chunk.(*wave.Int16Interleaved); !ok {
+			t.Fatalf("expected the type of the input to be kept, but got %T", chunk)
+		}
+		return rms(normalizedSamples(chunk, nil))
+	}
+
+	if l := level(5); math.Abs(l-0.5) > 0.001 {
+		t.Fatalf("expected the audio to be kept without the speech, but got %f", l)
+	}
+
+	v.set(true)
+	if l := level(1); l >= 0.5 || l <= defaultDuckGain*0.5 {
+		t.Fatalf("expected the gain to be lowered gradually, but got %f", l)
+	}
+	if l := level(20); math.Abs(l-defaultDuckGain*0.5) > 0.005 {
+		t.Fatalf("expected the audio to be ducked to %f, but got %f", defaultDuckGain*0.5, l)
+	}
+
+	v.set(false)
+	if l := level(50); math.Abs(l-0.5) > 0.001 {
+		t.Fatalf("expected the gain to be restored after the speech, but got %f", l)
+	}
+}
@@ -0,0 +1,206 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	// echoReferenceDuration is how much far-end audio EchoReference keeps.
+	echoReferenceDuration = time.Second
+	// echoTail is the longest echo delay EchoCancellation removes, i.e. the filter length.
+	echoTail = 64 * time.Millisecond
+	// echoStepSize is the NLMS filter's step size.
+	echoStepSize = 0.5
+	// echoDoubleTalkThreshold is the Geigel detector threshold. The filter isn't adapted while near-end
+	// input is louder than far-end audio multiplied by it, since near-end speech would disturb it.
+	echoDoubleTalkThreshold = 0.5
+	echoDoubleTalkHangover  = 30 * time.Millisecond
+)
+
+// EchoReference stores far-end audio played to the speakers, e.g. a remote track's audio, for
+// EchoCancellation. Audio is fed by the transform Tap returns.
+type EchoReference struct {
+	mu           sync.Mutex
+	samplingRate int
+	ring         []float64
+	written      int64
+}
+
+// NewEchoReference creates an empty EchoReference.
+func NewEchoReference() *EchoReference {
+	return &EchoReference{}
+}
+
+// Tap returns a transform that stores audio passing through it as far-end audio. The transform should be
+// applied right before audio is rendered, so the echo delay is as short as possible.
+func (e *EchoReference) Tap() TransformFunc {
+	return func(r Reader) Reader {
+		var buf []float64
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			info := chunk.ChunkInfo()
+			if info.Channels > 0 {
+				buf = normalizedSamples(chunk, buf)
+				e.write(info.SamplingRate, info.Channels, buf)
+			}
+			return chunk, release, nil
+		})
+	}
+}
+
+// write stores interleaved samples as mono.
+func (e *EchoReference) write(samplingRate, channels int, samples []float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if samplingRate != e.samplingRate || e.ring == nil {
+		e.samplingRate = samplingRate
+		e.ring = make([]float64, int64(samplingRate)*int64(echoReferenceDuration)/int64(time.Second)+1)
+		e.written = 0
+	}
+	for i := 0; i+channels <= len(samples); i += channels {
+		var v float64
+		for ch := 0; ch < channels; ch++ {
+			v += samples[i+ch]
+		}
+		e.ring[e.written%int64(len(e.ring))] = v / float64(channels)
+		e.written++
+	}
+}
+
+// read reads far-end samples from pos into dst, and returns the new position and sampling rate. pos starts
+// from the latest sample if it's negative or too old. dst is filled with zeroes when no far-end audio is
+// played.
+func (e *EchoReference) read(pos int64, dst []float64) (int64, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if pos < 0 || pos > e.written || pos < e.written-int64(len(e.ring)) {
+		pos = e.written
+	}
+	for i := range dst {
+		if pos < e.written {
+			dst[i] = e.ring[pos%int64(len(e.ring))]
+			pos++
+		} else {
+			dst[i] = 0
+		}
+	}
+	return pos, e.samplingRate
+}
+
+// echoCanceller is an NLMS filter that estimates far-end audio echo in a channel.
+type echoCanceller struct {
+	weights []float64
+}
+
+// EchoCancellation returns a transform that removes echo of ref's far-end audio from input, like
+// browsers' echoCancellation. Echo is estimated by an adaptive filter that models the path from
+// speakers to microphone up to 64 ms. Input is passed through while its sampling rate differs from
+// far-end audio's, so ref should be tapped after audio is resampled to the input rate.
+func EchoCancellation(ref *EchoReference) TransformFunc {
+	return func(r Reader) Reader {
+		var (
+			pos          int64 = -1
+			samplingRate int
+			// history is far-end samples, newest first.
+			history    []float64
+			energy     float64
+			cancellers []echoCanceller
+			hangover   int
+			far, buf   []float64
+			pool       wave.Pool
+		)
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			info := chunk.ChunkInfo()
+			if info.Len == 0 || info.Channels == 0 {
+				return chunk, release, nil
+			}
+			if cap(far) < info.Len {
+				far = make([]float64, info.Len)
+			}
+			far = far[:info.Len]
+			var refRate int
+			pos, refRate = ref.read(pos, far)
+			if refRate != info.SamplingRate {
+				return chunk, release, nil
+			}
+
+			taps := int(int64(info.SamplingRate) * int64(echoTail) / int64(time.Second))
+			if samplingRate != info.SamplingRate || len(cancellers) != info.Channels || taps == 0 {
+				if taps == 0 {
+					return chunk, release, nil
+				}
+				samplingRate = info.SamplingRate
+				history = make([]float64, taps)
+				energy = 0
+				cancellers = make([]echoCanceller, info.Channels)
+				for i := range cancellers {
+					cancellers[i].weights = make([]float64, taps)
+				}
+			}
+			hangoverLen := int(int64(info.SamplingRate) * int64(echoDoubleTalkHangover) / int64(time.Second))
+
+			defer release()
+			buf = normalizedSamples(chunk, buf)
+			for i := 0; i < info.Len; i++ {
+				// Push far-end sample
+				old := history[taps-1]
+				copy(history[1:], history)
+				history[0] = far[i]
+				energy += far[i]*far[i] - old*old
+				if energy < 0 {
+					energy = 0
+				}
+				x := history
+
+				var maxFar float64
+				for _, v := range x {
+					if a := math.Abs(v); a > maxFar {
+						maxFar = a
+					}
+				}
+				for ch := range cancellers {
+					if math.Abs(buf[i*info.Channels+ch]) > echoDoubleTalkThreshold*maxFar {
+						hangover = hangoverLen
+					}
+				}
+
+				for ch := range cancellers {
+					w := cancellers[ch].weights
+					var estimate float64
+					for k, v := range x {
+						estimate += w[k] * v
+					}
+					e := buf[i*info.Channels+ch] - estimate
+					buf[i*info.Channels+ch] = e
+
+					if hangover == 0 && energy > 0 {
+						step := echoStepSize * e / (energy + 1e-6)
+						for k, v := range x {
+							w[k] += step * v
+						}
+					}
+				}
+				if hangover > 0 {
+					hangover--
+				}
+			}
+			out, releaseOut := denormalizedChunk(&pool, chunk, buf)
+			return out, releaseOut, nil
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package audio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// mixCapacity is the maximum audio buffered per Mix input. When an input runs faster than the first
+// one and exceeds it, the oldest samples are dropped.
+const mixCapacity = 200 * time.Millisecond
+
+// Mix creates a reader that sums readers' audio, e.g. a microphone and speaker loopback, into
+// a single signal. The first reader paces the mix, and chunks have its format. Other readers are read in
+// background goroutines, then resampled and mixed to the first reader's sampling rate and channels.
+// An input with no samples in time is silent in that chunk. The sum is clipped to the sample range.
+// The goroutines are stopped when the first reader returns an error.
+func Mix(readers ...Reader) Reader {
+	if len(readers) == 1 {
+		return readers[0]
+	}
+
+	var (
+		once    sync.Once
+		inputs  []*mixInput
+		samples []float64
+		buf     []float64
+		pool    wave.Pool
+		done    = make(chan struct{})
+	)
+	return ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, release, err := readers[0].Read()
+		if err != nil {
+			select {
+			case <-done:
+			default:
+				close(done)
+			}
+			return nil, func() {}, err
+		}
+		defer release()
+
+		info := chunk.ChunkInfo()
+		once.Do(func() {
+			for _, r := range readers[1:] {
+				input := newMixInput(info.SamplingRate, info.Channels)
+				inputs = append(inputs, input)
+				go input.fill(NewResampler(info.SamplingRate)(r), done)
+			}
+		})
+
+		samples = normalizedSamples(chunk, samples)
+		for _, input := range inputs {
+			buf = input.pop(buf, info.Len)
+			for i, v := range buf {
+				samples[i] += v
+			}
+		}
+		out, releaseOut := denormalizedChunk(&pool, chunk, samples)
+		return out, releaseOut, nil
+	})
+}
+
+// mixInput queues a Mix input's samples, in the mix's channels.
+type mixInput struct {
+	mu       sync.Mutex
+	channels int
+	capacity int
+	queue    []float64
+	primed   bool
+	buf      []float64
+}
+
+func newMixInput(samplingRate, channels int) *mixInput {
+	return &mixInput{
+		channels: channels,
+		capacity: int(int64(samplingRate)*int64(mixCapacity)/int64(time.Second)) * channels,
+	}
+}
+
+func (m *mixInput) fill(r Reader, done <-chan struct{}) {
+	for {
+		chunk, release, err := r.Read()
+		if err != nil {
+			return
+		}
+		select {
+		case <-done:
+			release()
+			return
+		default:
+		}
+
+		m.buf = normalizedSamples(chunk, m.buf)
+		info := chunk.ChunkInfo()
+		release()
+
+		m.mu.Lock()
+		for i := 0; i < info.Len; i++ {
+			frame := m.buf[i*info.Channels : (i+1)*info.Channels]
+			for ch := 0; ch < m.channels; ch++ {
+				m.queue = append(m.queue, mixChannel(frame, ch, m.channels))
+			}
+		}
+		if over := len(m.queue) - m.capacity; over > 0 {
+			m.queue = append(m.queue[:0], m.queue[over:]...)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// mixChannel returns the sample for mix channel ch from frame in channels. Mono is copied to all
+// channels, and all channels are averaged to mono.
+func mixChannel(frame []float64, ch, channels int) float64 {
+	switch {
+	case len(frame) == channels:
+		return frame[ch]
+	case len(frame) == 1:
+		return frame[0]
+	case channels == 1:
+		var sum float64
+		for _, v := range frame {
+			sum += v
+		}
+		return sum / float64(len(frame))
+	default:
+		return frame[ch%len(frame)]
+	}
+}
+
+// pop returns n frames' interleaved samples into buf. Frames not read yet are silent.
+// The queue isn't read until a chunk is buffered, so input is mixed by chunks after a jitter.
+func (m *mixInput) pop(buf []float64, n int) []float64 {
+	size := n * m.channels
+	if cap(buf) < size {
+		buf = make([]float64, size)
+	}
+	buf = buf[:size]
+	for i := range buf {
+		buf[i] = 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.primed {
+		if len(m.queue) < size {
+			return buf
+		}
+		m.primed = true
+	}
+	k := copy(buf, m.queue)
+	m.queue = append(m.queue[:0], m.queue[k:]...)
+	return buf
+}
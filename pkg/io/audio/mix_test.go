@@ -0,0 +1,88 @@
+package audio
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func TestMix(t *testing.T) {
+	const chunkLen = 80
+
+	errDone := errors.New("done")
+	reads := 0
+	mic := ReaderFunc(func() (wave.Audio, func(), error) {
+		if reads++; reads > 200 {
+			return nil, func() {}, errDone
+		}
+		// Mix pace
+		time.Sleep(time.Millisecond)
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			chunk.Data[i] = math.MaxInt16 / 4
+		}
+		return chunk, func() {}, nil
+	})
+	loopback := ReaderFunc(func() (wave.Audio, func(), error) {
+		time.Sleep(time.Millisecond)
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 2, SamplingRate: 8000})
+		for i := 0; i < chunkLen; i++ {
+			chunk.Data[2*i], chunk.Data[2*i+1] = 0.2, 0.4
+		}
+		return chunk, func() {}, nil
+	})
+
+	r := Mix(mic, loopback)
+	for {
+		chunk, _, err := r.Read()
+		if err == errDone {
+			t.Fatal("expected the loopback to be mixed")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := chunk.(*wave.Int16Interleaved); !ok {
+			t.Fatalf("expected the type of the first reader to be kept, but got %T", chunk)
+		}
+		if info := chunk.ChunkInfo(); info.Len != chunkLen || info.Channels != 1 {
+			t.Fatalf("expected %d mono samples, but got %d samples of %d channels", chunkLen, info.Len, info.Channels)
+		}
+
+		samples := normalizedSamples(chunk, nil)
+		if math.Abs(samples[0]-0.25) < 0.01 {
+			// Loopback isn't buffered yet
+			continue
+		}
+		for i, v := range samples {
+			if math.Abs(v-0.55) > 0.01 {
+				t.Fatalf("expected sample %d to be the mic and the average of the loopback, 0.55, but got %f", i, v)
+			}
+		}
+		break
+	}
+}
+
+func TestMixInputPop(t *testing.T) {
+	m := newMixInput(1000, 1)
+	if m.capacity != 200 {
+		t.Fatalf("expected the capacity to be 200 samples, but got %d", m.capacity)
+	}
+
+	m.queue = []float64{0.1, 0.2}
+	if buf := m.pop(nil, 3); buf[0] != 0 || m.primed {
+		t.Fatal("expected the input to be silent until a chunk is buffered")
+	}
+
+	m.queue = append(m.queue, 0.3, 0.4)
+	buf := m.pop(nil, 3)
+	if buf[0] != 0.1 || buf[2] != 0.3 || len(m.queue) != 1 {
+		t.Fatalf("expected the first 3 samples, but got %v", buf)
+	}
+	buf = m.pop(buf, 3)
+	if buf[0] != 0.4 || buf[1] != 0 || buf[2] != 0 {
+		t.Fatalf("expected the rest of the samples and silence, but got %v", buf)
+	}
+}
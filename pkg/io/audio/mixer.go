@@ -20,16 +20,9 @@ func NewChannelMixer(channels int, mixer mixer.ChannelMixer) TransformFunc {
 
 			ci.Channels = channels
 
-			var mixed wave.Audio
-			switch buff.(type) {
-			case *wave.Int16Interleaved:
-				mixed = wave.NewInt16Interleaved(ci)
-			case *wave.Int16NonInterleaved:
-				mixed = wave.NewInt16NonInterleaved(ci)
-			case *wave.Float32Interleaved:
-				mixed = wave.NewFloat32Interleaved(ci)
-			case *wave.Float32NonInterleaved:
-				mixed = wave.NewFloat32NonInterleaved(ci)
+			mixed, err := wave.TypeOf(buff).New(ci)
+			if err != nil {
+				return nil, func() {}, err
 			}
 			if err := mixer.Mix(mixed, buff); err != nil {
 				return nil, func() {}, err
@@ -0,0 +1,76 @@
+package audio
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	// noiseMinGain is noise attenuation, i.e. -20 dB.
+	noiseMinGain = 0.1
+	// Noise floor follows quieter chunks quickly, and rises slowly so speech isn't
+	// mistaken for noise.
+	noiseFloorFall = 100 * time.Millisecond
+	noiseFloorRise = 5 * time.Second
+	// Gain opens quickly at speech start, and closes slowly to keep word tails.
+	noiseGainAttack  = 5 * time.Millisecond
+	noiseGainRelease = 100 * time.Millisecond
+)
+
+// NoiseSuppression returns a transform that suppresses stationary background noise, e.g. from fans, like
+// browsers' noiseSuppression. The noise floor is estimated from the input's quietest parts, and
+// chunks close to the floor are attenuated by up to 20 dB. Since gain is applied to all frequencies,
+// noise mixed with speech is kept.
+func NoiseSuppression() TransformFunc {
+	return func(r Reader) Reader {
+		var (
+			floor float64
+			gain  = 1.0
+			buf   []float64
+			pool  wave.Pool
+		)
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			info := chunk.ChunkInfo()
+			if info.Len == 0 || info.Channels == 0 {
+				return chunk, release, nil
+			}
+			defer release()
+			buf = normalizedSamples(chunk, buf)
+
+			level := rms(buf)
+			switch {
+			case floor == 0:
+				floor = level
+			case level < floor:
+				floor += (level - floor) * smoothingFactor(info.Len, info.SamplingRate, noiseFloorFall)
+			default:
+				floor += (level - floor) * smoothingFactor(info.Len, info.SamplingRate, noiseFloorRise)
+			}
+
+			// Spectral subtraction over whole band
+			target := 1.0
+			if level > 0 {
+				target = math.Max(noiseMinGain, 1-(floor*floor)/(level*level))
+			} else {
+				target = noiseMinGain
+			}
+			tau := noiseGainRelease
+			if target > gain {
+				tau = noiseGainAttack
+			}
+			next := gain + (target-gain)*smoothingFactor(info.Len, info.SamplingRate, tau)
+
+			applyGain(buf, info.Channels, gain, next)
+			gain = next
+			out, releaseOut := denormalizedChunk(&pool, chunk, buf)
+			return out, releaseOut, nil
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"math"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// normalizedSamples returns chunk's samples normalized to [-1, 1] in interleaved order. buf is reused
+// if it's large enough.
+func normalizedSamples(chunk wave.Audio, buf []float64) []float64 {
+	info := chunk.ChunkInfo()
+	n := info.Len * info.Channels
+	if cap(buf) < n {
+		buf = make([]float64, n)
+	}
+	buf = buf[:n]
+	// Read interleaved chunks directly, since At boxes every sample
+	switch c := chunk.(type) {
+	case *wave.Int16Interleaved:
+		for i, v := range c.Data[:n] {
+			buf[i] = float64(v) / -math.MinInt16
+		}
+		return buf
+	case *wave.Int32Interleaved:
+		for i, v := range c.Data[:n] {
+			buf[i] = float64(v) / -math.MinInt32
+		}
+		return buf
+	case *wave.Float32Interleaved:
+		for i, v := range c.Data[:n] {
+			buf[i] = float64(v)
+		}
+		return buf
+	}
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			buf[i*info.Channels+ch] = normalize(chunk.At(i, ch))
+		}
+	}
+	return buf
+}
+
+// denormalizedChunk returns a pooled chunk of the same type as src holding the interleaved samples in [-1, 1],
+// and a function that releases it. Out of range samples are clipped.
+func denormalizedChunk(pool *wave.Pool, src wave.Audio, samples []float64) (wave.Audio, func()) {
+	info := src.ChunkInfo()
+	dst, release, err := pool.Get(wave.TypeOf(src), info)
+	if err != nil {
+		dst, release, _ = pool.Get(wave.TypeFloat32Interleaved, info)
+	}
+
+	n := info.Len * info.Channels
+	switch d := dst.(type) {
+	case *wave.Int16Interleaved:
+		for i, v := range samples[:n] {
+			d.Data[i] = int16(int32(clip(v)*math.MaxInt32) >> 16)
+		}
+		return dst, release
+	case *wave.Int32Interleaved:
+		for i, v := range samples[:n] {
+			d.Data[i] = int32(clip(v) * math.MaxInt32)
+		}
+		return dst, release
+	case *wave.Float32Interleaved:
+		for i, v := range samples[:n] {
+			d.Data[i] = float32(clip(v))
+		}
+		return dst, release
+	}
+
+	floatDst, isFloat := dst.(interface {
+		SetFloat32(i, ch int, s wave.Float32Sample)
+	})
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			v := clip(samples[i*info.Channels+ch])
+			if isFloat {
+				floatDst.SetFloat32(i, ch, wave.Float32Sample(v))
+			} else {
+				dst.Set(i, ch, wave.Int32Sample(v*math.MaxInt32))
+			}
+		}
+	}
+	return dst, release
+}
+
+// clip clips v to [-1, 1].
+func clip(v float64) float64 {
+	return math.Max(-1, math.Min(1, v))
+}
+
+// smoothingFactor returns the exponential smoothing factor with time constant tau for n samples.
+func smoothingFactor(n, samplingRate int, tau time.Duration) float64 {
+	if samplingRate <= 0 {
+		samplingRate = defaultSamplingRate
+	}
+	d := time.Duration(int64(n) * int64(time.Second) / int64(samplingRate))
+	return 1 - math.Exp(-float64(d)/float64(tau))
+}
+
+// rms returns samples' root mean square.
+func rms(samples []float64) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range samples {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+// applyGain multiplies samples by a gain that changes linearly from g0 to g1 over the chunk, to avoid
+// zipper noise.
+func applyGain(samples []float64, channels int, g0, g1 float64) {
+	frames := len(samples) / channels
+	for i := 0; i < frames; i++ {
+		g := g0 + (g1-g0)*float64(i+1)/float64(frames)
+		for ch := 0; ch < channels; ch++ {
+			samples[i*channels+ch] *= g
+		}
+	}
+}
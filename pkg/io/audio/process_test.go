@@ -0,0 +1,148 @@
+package audio
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// chunkRMS returns a mono chunk's RMS level.
+func chunkRMS(chunk wave.Audio) float64 {
+	return rms(normalizedSamples(chunk, nil))
+}
+
+func TestAutoGainControl(t *testing.T) {
+	// 20ms of mono audio at 8kHz
+	const chunkLen = 160
+
+	var n int
+	r := AutoGainControl()(ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			chunk.Data[i] = int16(300 * math.Sin(float64(n)*2*math.Pi/80))
+			n++
+		}
+		return chunk, func() {}, nil
+	}))
+
+	var chunk wave.Audio
+	for i := 0; i < 300; i++ {
+		var err error
+		if chunk, _, err = r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := chunk.(*wave.Int16Interleaved); !ok {
+		t.Fatalf("expected the type of the input to be kept, but got %T", chunk)
+	}
+	if level := chunkRMS(chunk); math.Abs(level-agcTargetLevel) > 0.02 {
+		t.Fatalf("expected the level to be around %f, but got %f", agcTargetLevel, level)
+	}
+}
+
+func TestNoiseSuppression(t *testing.T) {
+	const chunkLen = 160
+
+	rnd := rand.New(rand.NewSource(1))
+	speech := false
+	var n int
+	r := NoiseSuppression()(ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			v := 0.01 * rnd.NormFloat64()
+			if speech {
+				v += 0.3 * math.Sin(float64(n)*2*math.Pi/80)
+			}
+			chunk.Data[i] = float32(v)
+			n++
+		}
+		return chunk, func() {}, nil
+	}))
+	read := func(chunks int) float64 {
+		var level float64
+		for i := 0; i < chunks; i++ {
+			chunk, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			level = chunkRMS(chunk)
+		}
+		return level
+	}
+
+	if level := read(100); level > 0.002 {
+		t.Fatalf("expected the noise to be attenuated, but got %f", level)
+	}
+	speech = true
+	if level := read(10); level < 0.2 {
+		t.Fatalf("expected the speech to be kept, but got %f", level)
+	}
+}
+
+func TestEchoCancellation(t *testing.T) {
+	const (
+		chunkLen = 160
+		delay    = 40
+		gain     = 0.3
+	)
+
+	rnd := rand.New(rand.NewSource(1))
+	ref := NewEchoReference()
+	farEnd := ref.Tap()(ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			chunk.Data[i] = float32(0.3 * rnd.NormFloat64())
+		}
+		return chunk, func() {}, nil
+	}))
+
+	played := make([]float32, delay)
+	var echo float64
+	r := EchoCancellation(ref)(ReaderFunc(func() (wave.Audio, func(), error) {
+		// Far-end audio is played while input is captured
+		far, _, err := farEnd.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		played = append(played, far.(*wave.Float32Interleaved).Data...)
+
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 8000})
+		for i := range chunk.Data {
+			chunk.Data[i] = gain * played[i]
+		}
+		played = played[chunkLen:]
+		echo = chunkRMS(chunk)
+		return chunk, func() {}, nil
+	}))
+
+	var residual float64
+	for i := 0; i < 200; i++ {
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		residual = chunkRMS(chunk)
+	}
+	if residual > echo*0.3 {
+		t.Fatalf("expected the echo to be reduced by 10dB from %f, but got %f", echo, residual)
+	}
+
+	t.Run("SamplingRateMismatch", func(t *testing.T) {
+		r := EchoCancellation(ref)(ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 1, SamplingRate: 16000})
+			for i := range chunk.Data {
+				chunk.Data[i] = 0.5
+			}
+			return chunk, func() {}, nil
+		}))
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := chunk.(*wave.Float32Interleaved).Data[0]; v != 0.5 {
+			t.Fatalf("expected the input to be passed through, but got %f", v)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package audio
+
+import (
+	"math"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	// resamplerZeroCrossings is the number of sinc zero crossings on each side of the filter.
+	resamplerZeroCrossings = 16
+	// resamplerPhases is the filter table's resolution per input sample.
+	resamplerPhases = 128
+	// resamplerBandwidth is the passband's ratio to the lower rate's Nyquist frequency, which leaves
+	// room for the filter's transition band.
+	resamplerBandwidth = 0.95
+)
+
+// NewResampler creates an audio transform that converts the sampling rate, e.g. from a pro audio interface's
+// 96kHz to the codecs' 48kHz. The signal goes through a windowed sinc low-pass filter to avoid aliasing when
+// downconverting. Chunks have the source's type, and their lengths follow the rate.
+func NewResampler(samplingRate int) TransformFunc {
+	return func(r Reader) Reader {
+		var s *resampler
+		return ReaderFunc(func() (wave.Audio, func(), error) {
+			for {
+				chunk, release, err := r.Read()
+				if err != nil {
+					return nil, func() {}, err
+				}
+
+				info := chunk.ChunkInfo()
+				if info.SamplingRate == samplingRate || info.SamplingRate <= 0 {
+					return chunk, release, nil
+				}
+				if s == nil || s.inRate != info.SamplingRate || s.channels() != info.Channels {
+					s = newResampler(info.SamplingRate, samplingRate, info.Channels)
+				}
+
+				resampled, err := s.process(chunk)
+				release()
+				if err != nil {
+					return nil, func() {}, err
+				}
+				if resampled.ChunkInfo().Len == 0 {
+					// Waiting for samples past the filter
+					continue
+				}
+				return resampled, func() {}, nil
+			}
+		})
+	}
+}
+
+type resampler struct {
+	inRate, outRate int
+	// ratio is the number of input samples per output sample.
+	ratio     float64
+	halfWidth float64
+	// table is the filter's right half, sampled at resamplerPhases per input sample.
+	table []float64
+	// history holds each channel's input samples, kept for the filter.
+	history [][]float64
+	// pos is the next output sample's position in history.
+	pos float64
+}
+
+func newResampler(inRate, outRate, channels int) *resampler {
+	ratio := float64(inRate) / float64(outRate)
+	// Cutoff frequency in cycles per input sample
+	cutoff := 0.5 * resamplerBandwidth * math.Min(1, 1/ratio)
+	halfWidth := resamplerZeroCrossings / (2 * cutoff)
+
+	table := make([]float64, int(math.Ceil(halfWidth*resamplerPhases))+2)
+	for i := range table {
+		x := float64(i) / resamplerPhases
+		if x >= halfWidth {
+			continue
+		}
+		sinc := 1.0
+		if x != 0 {
+			sinc = math.Sin(2*math.Pi*cutoff*x) / (2 * math.Pi * cutoff * x)
+		}
+		// Blackman window
+		t := x / halfWidth
+		window := 0.42 + 0.5*math.Cos(math.Pi*t) + 0.08*math.Cos(2*math.Pi*t)
+		table[i] = 2 * cutoff * sinc * window
+	}
+
+	// The first output sample is at the first input sample, which has zeros before it.
+	pad := int(math.Ceil(halfWidth))
+	history := make([][]float64, channels)
+	for ch := range history {
+		history[ch] = make([]float64, pad)
+	}
+	return &resampler{
+		inRate:    inRate,
+		outRate:   outRate,
+		ratio:     ratio,
+		halfWidth: halfWidth,
+		table:     table,
+		history:   history,
+		pos:       float64(pad),
+	}
+}
+
+func (s *resampler) channels() int {
+	return len(s.history)
+}
+
+// kernel returns the filter at x input samples from the center.
+func (s *resampler) kernel(x float64) float64 {
+	p := math.Abs(x) * resamplerPhases
+	i := int(p)
+	if i+1 >= len(s.table) {
+		return 0
+	}
+	f := p - float64(i)
+	return s.table[i] + (s.table[i+1]-s.table[i])*f
+}
+
+func (s *resampler) process(chunk wave.Audio) (wave.Audio, error) {
+	info := chunk.ChunkInfo()
+	for ch := range s.history {
+		for i := 0; i < info.Len; i++ {
+			s.history[ch] = append(s.history[ch], normalize(chunk.At(i, ch)))
+		}
+	}
+
+	available := float64(len(s.history[0]))
+	n := 0
+	if last := available - s.halfWidth - 1; last >= s.pos {
+		n = int((last-s.pos)/s.ratio) + 1
+	}
+
+	out := wave.NewFloat32Interleaved(wave.ChunkInfo{
+		Len:          n,
+		Channels:     info.Channels,
+		SamplingRate: s.outRate,
+	})
+	for i := 0; i < n; i++ {
+		center := s.pos + float64(i)*s.ratio
+		start := int(math.Ceil(center - s.halfWidth))
+		end := int(center + s.halfWidth)
+		for ch, history := range s.history {
+			var v float64
+			for j := start; j <= end; j++ {
+				v += history[j] * s.kernel(center-float64(j))
+			}
+			out.SetFloat32(i, ch, wave.Float32Sample(v))
+		}
+	}
+	s.pos += float64(n) * s.ratio
+
+	// Drop samples that are no longer in the filter.
+	if drop := int(s.pos-s.halfWidth) - 1; drop > 0 {
+		for ch := range s.history {
+			s.history[ch] = append(s.history[ch][:0], s.history[ch][drop:]...)
+		}
+		s.pos -= float64(drop)
+	}
+
+	t := wave.TypeOf(chunk)
+	if t == wave.TypeFloat32Interleaved || t == wave.TypeUnknown {
+		return out, nil
+	}
+	return wave.NewConverter(t, false).Convert(out)
+}
@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+// sineReader returns chunks of a sine wave at frequency, sampled at samplingRate.
+func sineReader(frequency float64, samplingRate, chunkLen int) Reader {
+	var n int
+	return ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk := wave.NewFloat32Interleaved(wave.ChunkInfo{Len: chunkLen, Channels: 2, SamplingRate: samplingRate})
+		for i := 0; i < chunkLen; i++ {
+			v := 0.5 * math.Sin(2*math.Pi*frequency*float64(n)/float64(samplingRate))
+			chunk.SetFloat32(i, 0, wave.Float32Sample(v))
+			chunk.SetFloat32(i, 1, wave.Float32Sample(-v))
+			n++
+		}
+		return chunk, func() {}, nil
+	})
+}
+
+// readSamples reads n samples of the first channel.
+func readSamples(t *testing.T, r Reader, n int) []float64 {
+	var samples []float64
+	for len(samples) < n {
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rate := chunk.ChunkInfo().SamplingRate; rate != 48000 {
+			t.Fatalf("expected 48000Hz, but got %d", rate)
+		}
+		for i := 0; i < chunk.ChunkInfo().Len; i++ {
+			samples = append(samples, normalize(chunk.At(i, 0)))
+		}
+	}
+	return samples[:n]
+}
+
+func TestResampler(t *testing.T) {
+	t.Run("Downsample", func(t *testing.T) {
+		samples := readSamples(t, NewResampler(48000)(sineReader(1000, 96000, 960)), 4800)
+		// Skip the filter's start
+		for i := 100; i < len(samples); i++ {
+			expected := 0.5 * math.Sin(2*math.Pi*1000*float64(i)/48000)
+			if math.Abs(samples[i]-expected) > 0.005 {
+				t.Fatalf("expected sample %d to be %f, but got %f", i, expected, samples[i])
+			}
+		}
+	})
+
+	t.Run("Upsample", func(t *testing.T) {
+		samples := readSamples(t, NewResampler(48000)(sineReader(1000, 44100, 441)), 4800)
+		for i := 100; i < len(samples); i++ {
+			expected := 0.5 * math.Sin(2*math.Pi*1000*float64(i)/48000)
+			if math.Abs(samples[i]-expected) > 0.005 {
+				t.Fatalf("expected sample %d to be %f, but got %f", i, expected, samples[i])
+			}
+		}
+	})
+
+	t.Run("AntiAliasing", func(t *testing.T) {
+		// 30kHz is above 48kHz's Nyquist frequency.
+		samples := readSamples(t, NewResampler(48000)(sineReader(30000, 96000, 960)), 4800)
+		var sum float64
+		for _, v := range samples[100:] {
+			sum += v * v
+		}
+		if rms := math.Sqrt(sum / float64(len(samples)-100)); rms > 0.005 {
+			t.Fatalf("expected the tone to be filtered out, but got rms %f", rms)
+		}
+	})
+
+	t.Run("Int16", func(t *testing.T) {
+		var n int
+		r := NewResampler(48000)(ReaderFunc(func() (wave.Audio, func(), error) {
+			chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 960, Channels: 1, SamplingRate: 96000})
+			for i := range chunk.Data {
+				chunk.Data[i] = int16(n)
+			}
+			n++
+			return chunk, func() {}, nil
+		}))
+		var total int
+		for i := 0; i < 10; i++ {
+			chunk, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := chunk.(*wave.Int16Interleaved); !ok {
+				t.Fatalf("expected *wave.Int16Interleaved, but got %T", chunk)
+			}
+			total += chunk.ChunkInfo().Len
+		}
+		// 10 chunks of 10ms, minus the filter delay
+		if total < 4800-100 || total > 4800 {
+			t.Fatalf("expected about 4800 samples, but got %d", total)
+		}
+	})
+}
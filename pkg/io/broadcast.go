@@ -17,9 +17,33 @@ const (
 var errEmptySource = fmt.Errorf("Source can't be nil")
 
 type broadcasterData struct {
-	data  interface{}
-	count uint32
-	err   error
+	data    interface{}
+	release func()
+	count   uint32
+	err     error
+	// refs counts the readers using data, plus 1 while it's in the ring. The source's release is called on data
+	// once it drops to 0.
+	refs int32
+}
+
+// retain adds a reader's reference to data, or returns false if it's already released.
+func (data *broadcasterData) retain() bool {
+	for {
+		refs := atomic.LoadInt32(&data.refs)
+		if refs <= 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&data.refs, refs, refs+1) {
+			return true
+		}
+	}
+}
+
+// unref drops a reference to data, and releases it on the last one.
+func (data *broadcasterData) unref() {
+	if atomic.AddInt32(&data.refs, -1) == 0 && data.release != nil {
+		data.release()
+	}
 }
 
 type broadcasterRing struct {
@@ -48,8 +72,12 @@ func (ring *broadcasterRing) acquire(count uint32) func(*broadcasterData) {
 	if atomic.CompareAndSwapUint64(&ring.state, state, state|maskReading) {
 		return func(data *broadcasterData) {
 			i := ring.index(count)
+			old, _ := ring.buffer[i].Load().(*broadcasterData)
 			ring.buffer[i].Store(data)
 			atomic.StoreUint64(&ring.state, uint64(count+1))
+			if old != nil {
+				old.unref()
+			}
 		}
 	}
 
@@ -124,21 +152,47 @@ func NewBroadcaster(source Reader, config *BroadcasterConfig) *Broadcaster {
 // copyFn is used to copy the data from the source to individual readers. Broadcaster uses a small ring
 // buffer, this means that slow readers might miss some data if they're really late and the data is no longer
 // in the ring buffer.
+//
+// Source data is released once it has left the ring buffer and every reader that read it has read the next
+// item, so readers must not use data after their next Read. Data isn't released if a reader stops reading.
 func (broadcaster *Broadcaster) NewReader(copyFn func(interface{}) interface{}) Reader {
 	currentCount := broadcaster.buffer.lastCount()
+	var last *broadcasterData
 
 	return ReaderFunc(func() (data interface{}, release func(), err error) {
-		currentCount++
-		if push := broadcaster.buffer.acquire(currentCount); push != nil {
-			data, _, err = broadcaster.source.Load().(Reader).Read()
-			push(&broadcasterData{
-				data:  data,
-				err:   err,
-				count: currentCount,
-			})
-		} else {
+		if last != nil {
+			last.unref()
+			last = nil
+		}
+
+		for {
+			currentCount++
+			if push := broadcaster.buffer.acquire(currentCount); push != nil {
+				var sourceRelease func()
+				data, sourceRelease, err = broadcaster.source.Load().(Reader).Read()
+				if err != nil {
+					sourceRelease = nil
+				}
+				last = &broadcasterData{
+					data:    data,
+					release: sourceRelease,
+					err:     err,
+					count:   currentCount,
+					refs:    2,
+				}
+				push(last)
+				break
+			}
+
 			ringData := broadcaster.buffer.get(currentCount)
+			if !ringData.retain() {
+				// The data left the ring buffer while being read, so read the next one
+				currentCount = ringData.count
+				continue
+			}
+			last = ringData
 			data, err, currentCount = ringData.data, ringData.err, ringData.count
+			break
 		}
 
 		data = copyFn(data)
@@ -148,3 +148,56 @@ func TestBroadcast(t *testing.T) {
 		})
 	}
 }
+
+func TestBroadcastRelease(t *testing.T) {
+	var n int
+	released := make(map[int]bool)
+	src := ReaderFunc(func() (interface{}, func(), error) {
+		frame := n
+		n++
+		return frame, func() {
+			if released[frame] {
+				t.Errorf("expected the frame %d to be released once", frame)
+			}
+			released[frame] = true
+		}, nil
+	})
+	broadcaster := NewBroadcaster(src, &BroadcasterConfig{BufferSize: 4})
+	a := broadcaster.NewReader(func(src interface{}) interface{} { return src })
+	b := broadcaster.NewReader(func(src interface{}) interface{} { return src })
+
+	for i := 0; i < 10; i++ {
+		for _, r := range []Reader{a, b} {
+			frame, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if frame.(int) != i {
+				t.Fatalf("expected the frame %d, but got %d", i, frame)
+			}
+		}
+		// Frames in the ring buffer are kept
+		if expected := i + 1 - 4; expected > 0 && len(released) != expected {
+			t.Fatalf("expected %d frames to be released, but got %d", expected, len(released))
+		}
+	}
+	for i := 0; i < 6; i++ {
+		if !released[i] {
+			t.Fatalf("expected the frame %d to be released", i)
+		}
+	}
+
+	// A reader's frame isn't released until it reads the next one
+	c := broadcaster.NewReader(func(src interface{}) interface{} { return src })
+	if _, _, err := c.Read(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, _, err := a.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if released[10] {
+		t.Fatal("expected the frame 10 to be kept by the reader")
+	}
+}
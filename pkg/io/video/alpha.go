@@ -0,0 +1,157 @@
+package video
+
+import (
+	"image"
+)
+
+// Premultiply converts frames with straight alpha, e.g. a matting model's *image.NRGBA, to *image.RGBA
+// whose colors are premultiplied by alpha. Transforms like Scale filter premultiplied colors, so
+// transparent region edges don't bleed into opaque ones. Other frames are kept.
+func Premultiply(r Reader) Reader {
+	var dst image.RGBA
+	return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		src, ok := img.(*image.NRGBA)
+		if !ok {
+			return img, release, nil
+		}
+
+		w, h := src.Rect.Dx(), src.Rect.Dy()
+		if len(dst.Pix) < 4*w*h {
+			dst.Pix = make([]uint8, 4*w*h)
+		}
+		dst.Stride = 4 * w
+		dst.Rect = src.Rect
+		parallelRows(h, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				s := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+				d := dst.Pix[y*dst.Stride:]
+				for i := 0; i < 4*w; i += 4 {
+					a := uint32(s[i+3])
+					d[i] = uint8((uint32(s[i])*a + 127) / 255)
+					d[i+1] = uint8((uint32(s[i+1])*a + 127) / 255)
+					d[i+2] = uint8((uint32(s[i+2])*a + 127) / 255)
+					d[i+3] = uint8(a)
+				}
+			}
+		})
+		release()
+		return &dst, func() {}, nil
+	}), r)
+}
+
+// Unpremultiply converts *image.RGBA frames to *image.NRGBA with straight alpha, whose colors ToI420
+// keeps in transparent regions. It's applied before the color encoder of a stream whose alpha AlphaPlane
+// sends, since receivers blend straight colors with alpha. Other frames are kept.
+func Unpremultiply(r Reader) Reader {
+	var dst image.NRGBA
+	return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		src, ok := img.(*image.RGBA)
+		if !ok {
+			return img, release, nil
+		}
+
+		w, h := src.Rect.Dx(), src.Rect.Dy()
+		if len(dst.Pix) < 4*w*h {
+			dst.Pix = make([]uint8, 4*w*h)
+		}
+		dst.Stride = 4 * w
+		dst.Rect = src.Rect
+		parallelRows(h, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				s := src.Pix[src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y):]
+				d := dst.Pix[y*dst.Stride:]
+				for i := 0; i < 4*w; i += 4 {
+					a := uint32(s[i+3])
+					switch a {
+					case 0xff:
+						copy(d[i:i+4], s[i:i+4])
+					case 0:
+						d[i], d[i+1], d[i+2], d[i+3] = 0, 0, 0, 0
+					default:
+						d[i] = uint8(min255((uint32(s[i])*255 + a/2) / a))
+						d[i+1] = uint8(min255((uint32(s[i+1])*255 + a/2) / a))
+						d[i+2] = uint8(min255((uint32(s[i+2])*255 + a/2) / a))
+						d[i+3] = uint8(a)
+					}
+				}
+			}
+		})
+		release()
+		return &dst, func() {}, nil
+	}), r)
+}
+
+// AlphaPlane converts frames to I420 whose luma is their alpha and whose chroma is neutral, which is the
+// VP9 and AV1 alpha side stream in WebM. Frames without alpha, e.g. *image.YCbCr, are opaque.
+func AlphaPlane(r Reader) Reader {
+	var dst *image.YCbCr
+	return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		defer release()
+
+		bounds := img.Bounds()
+		w, h := bounds.Dx(), bounds.Dy()
+		if dst == nil || dst.Rect.Dx() != w || dst.Rect.Dy() != h {
+			dst = image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio420)
+			for i := range dst.Cb {
+				dst.Cb[i], dst.Cr[i] = 0x80, 0x80
+			}
+		}
+
+		switch src := img.(type) {
+		case *image.RGBA:
+			copyAlpha(dst, src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y))
+		case *image.NRGBA:
+			copyAlpha(dst, src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y))
+		default:
+			if o, ok := img.(interface{ Opaque() bool }); ok && o.Opaque() {
+				for i := range dst.Y {
+					dst.Y[i] = 0xff
+				}
+				break
+			}
+			parallelRows(h, func(y0, y1 int) {
+				for y := y0; y < y1; y++ {
+					for x := 0; x < w; x++ {
+						_, _, _, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+						dst.Y[y*dst.YStride+x] = uint8(a >> 8)
+					}
+				}
+			})
+		}
+		cloned := *dst
+		return &cloned, func() {}, nil
+	}), r)
+}
+
+// copyAlpha copies the alpha of 4 byte pixels from offset in pix to dst's luma.
+func copyAlpha(dst *image.YCbCr, pix []uint8, stride, offset int) {
+	w := dst.Rect.Dx()
+	parallelRows(dst.Rect.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			s := pix[offset+y*stride:]
+			d := dst.Y[y*dst.YStride:]
+			for x := 0; x < w; x++ {
+				d[x] = s[4*x+3]
+			}
+		}
+	})
+}
+
+func min255(v uint32) uint32 {
+	if v > 255 {
+		return 255
+	}
+	return v
+}
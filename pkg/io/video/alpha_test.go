@@ -0,0 +1,100 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPremultiply(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 200, G: 100, B: 50, A: 128})
+	src.SetNRGBA(1, 0, color.NRGBA{R: 10, G: 20, B: 30, A: 255})
+
+	img, _, err := Premultiply(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		t.Fatalf("expected *image.RGBA, but got %T", img)
+	}
+	for x := 0; x < 2; x++ {
+		// image/color colors are the reference
+		r, g, b, a := src.At(x, 0).RGBA()
+		expected := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if c := rgba.RGBAAt(x, 0); !near(c.R, expected.R, 1) || !near(c.G, expected.G, 1) || !near(c.B, expected.B, 1) || c.A != expected.A {
+			t.Fatalf("expected %v at %d, but got %v", expected, x, c)
+		}
+	}
+
+	img, _, err = Unpremultiply(ReaderFunc(func() (image.Image, func(), error) {
+		return rgba, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("expected *image.NRGBA, but got %T", img)
+	}
+	for x := 0; x < 2; x++ {
+		expected, c := src.NRGBAAt(x, 0), nrgba.NRGBAAt(x, 0)
+		if !near(c.R, expected.R, 2) || !near(c.G, expected.G, 2) || !near(c.B, expected.B, 2) || c.A != expected.A {
+			t.Fatalf("expected %v back at %d, but got %v", expected, x, c)
+		}
+	}
+}
+
+func TestAlphaPlane(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	src.SetRGBA(2, 1, color.RGBA{R: 64, A: 64})
+	frames := []image.Image{src.SubImage(image.Rect(1, 1, 3, 3)), image.NewYCbCr(image.Rect(0, 0, 2, 2), image.YCbCrSubsampleRatio420)}
+	r := AlphaPlane(ReaderFunc(func() (image.Image, func(), error) {
+		img := frames[0]
+		frames = frames[1:]
+		return img, func() {}, nil
+	}))
+
+	img, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv := img.(*image.YCbCr)
+	if yuv.Rect.Dx() != 2 || yuv.Rect.Dy() != 2 || yuv.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+		t.Fatalf("expected I420 of 2x2, but got %v in %v", yuv.Rect, yuv.SubsampleRatio)
+	}
+	if a := yuv.YCbCrAt(1, 0); a.Y != 64 || a.Cb != 128 {
+		t.Fatalf("expected the alpha of 64 with the neutral chroma, but got %v", a)
+	}
+	if a := yuv.YCbCrAt(0, 1).Y; a != 0 {
+		t.Fatalf("expected the transparent pixel, but got %d", a)
+	}
+
+	img, _, err = r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := img.(*image.YCbCr).YCbCrAt(1, 1).Y; a != 255 {
+		t.Fatalf("expected the YCbCr frames to be opaque, but got %d", a)
+	}
+}
+
+func TestToI420KeepsStraightColors(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+3] = 255, 0
+	}
+	img, _, err := ToI420(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ey, ecb, ecr := color.RGBToYCbCr(255, 0, 0)
+	if c := img.(*image.YCbCr).YCbCrAt(0, 0); !near(c.Y, ey, 1) || !near(c.Cb, ecb, 1) || !near(c.Cr, ecr, 1) {
+		t.Fatalf("expected the straight red of the transparent pixels, but got %v", c)
+	}
+}
@@ -0,0 +1,218 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"time"
+)
+
+// FrameCondition is the state of video frames detected by Analyze.
+type FrameCondition int
+
+// FrameCondition values.
+const (
+	// FrameConditionNormal means the frames are neither black nor frozen.
+	FrameConditionNormal FrameCondition = iota
+	// FrameConditionBlack means the frames are fully black, e.g. the lens is covered or capture has failed.
+	FrameConditionBlack
+	// FrameConditionFrozen means the frames have kept the same content, e.g. the capture device is stuck.
+	FrameConditionFrozen
+)
+
+func (c FrameCondition) String() string {
+	switch c {
+	case FrameConditionNormal:
+		return "normal"
+	case FrameConditionBlack:
+		return "black"
+	case FrameConditionFrozen:
+		return "frozen"
+	default:
+		return "unknown"
+	}
+}
+
+// FrameAnalysis is the result of Analyze.
+type FrameAnalysis struct {
+	Condition FrameCondition
+	// Since is when the frames entered the condition.
+	Since time.Time
+	// BlackFrames and FrozenFrames are the total numbers of frames detected in each condition.
+	BlackFrames  uint64
+	FrozenFrames uint64
+}
+
+const (
+	defaultBlackThreshold = 32
+	defaultBlackDuration  = time.Second
+	defaultFreezeDuration = 3 * time.Second
+	// defaultAnalysisStep is the spacing of sampled pixels in both directions.
+	defaultAnalysisStep = 4
+	// blackPixelRatio is the largest share of bright pixels in a black frame, to tolerate noise and
+	// overlays like a timestamp.
+	blackPixelRatio = 0.01
+)
+
+type analyzeConfig struct {
+	blackThreshold  uint8
+	blackDuration   time.Duration
+	freezeDuration  time.Duration
+	freezeTolerance float64
+	step            int
+	now             func() time.Time
+}
+
+// AnalyzeOption configures Analyze.
+type AnalyzeOption func(*analyzeConfig)
+
+// WithBlackThreshold sets the maximum luma of pixels in black frames. The default is 32.
+func WithBlackThreshold(luma uint8) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.blackThreshold = luma
+	}
+}
+
+// WithBlackDuration sets how long frames have to be black before the condition is reported.
+// The default is 1 second.
+func WithBlackDuration(d time.Duration) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.blackDuration = d
+	}
+}
+
+// WithFreezeDuration sets how long frames have to keep the same content before the condition is reported.
+// The default is 3 seconds.
+func WithFreezeDuration(d time.Duration) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.freezeDuration = d
+	}
+}
+
+// WithFreezeTolerance sets the maximum mean absolute luma difference between frames with the same content.
+// The default is 0, which only detects identical frames. Use a small value if the source adds noise to
+// frozen frames.
+func WithFreezeTolerance(tolerance float64) AnalyzeOption {
+	return func(c *analyzeConfig) {
+		c.freezeTolerance = tolerance
+	}
+}
+
+// Analyze detects black and frozen frames, and calls onChange when the frame condition changes.
+// Only some pixels are sampled to keep the cost low. onChange is called in the reading goroutine,
+// so it shouldn't block.
+func Analyze(onChange func(FrameAnalysis), opts ...AnalyzeOption) TransformFunc {
+	c := analyzeConfig{
+		blackThreshold: defaultBlackThreshold,
+		blackDuration:  defaultBlackDuration,
+		freezeDuration: defaultFreezeDuration,
+		step:           defaultAnalysisStep,
+		now:            time.Now,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(r Reader) Reader {
+		var (
+			analysis   FrameAnalysis
+			samples    []uint8
+			prev       []uint8
+			prevBounds image.Rectangle
+			blackSince time.Time
+			lastChange time.Time
+		)
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			now := c.now()
+			if analysis.Since.IsZero() {
+				analysis.Since = now
+			}
+
+			samples = sampleLuma(samples[:0], img, c.step)
+			if isBlack(samples, c.blackThreshold) {
+				if blackSince.IsZero() {
+					blackSince = now
+				}
+			} else {
+				blackSince = time.Time{}
+			}
+			if lastChange.IsZero() || img.Bounds() != prevBounds || meanAbsDiff(samples, prev) > c.freezeTolerance {
+				lastChange = now
+			}
+			samples, prev = prev, samples
+			prevBounds = img.Bounds()
+
+			condition, since := FrameConditionNormal, now
+			switch {
+			case !blackSince.IsZero() && now.Sub(blackSince) >= c.blackDuration:
+				condition, since = FrameConditionBlack, blackSince
+				analysis.BlackFrames++
+			case now.Sub(lastChange) >= c.freezeDuration:
+				condition, since = FrameConditionFrozen, lastChange
+				analysis.FrozenFrames++
+			}
+			if condition != analysis.Condition {
+				analysis.Condition, analysis.Since = condition, since
+				onChange(analysis)
+			}
+
+			return img, release, nil
+		})
+	}
+}
+
+// sampleLuma appends the luma of pixels at every step in both directions to dst.
+func sampleLuma(dst []uint8, img image.Image, step int) []uint8 {
+	bounds := img.Bounds()
+	switch v := img.(type) {
+	case *image.YCbCr:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+			for x := bounds.Min.X; x < bounds.Max.X; x += step {
+				dst = append(dst, v.Y[v.YOffset(x, y)])
+			}
+		}
+	case *image.Gray:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+			for x := bounds.Min.X; x < bounds.Max.X; x += step {
+				dst = append(dst, v.Pix[v.PixOffset(x, y)])
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+			for x := bounds.Min.X; x < bounds.Max.X; x += step {
+				dst = append(dst, color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			}
+		}
+	}
+	return dst
+}
+
+func isBlack(samples []uint8, threshold uint8) bool {
+	var bright int
+	for _, s := range samples {
+		if s > threshold {
+			bright++
+		}
+	}
+	return float64(bright) <= float64(len(samples))*blackPixelRatio
+}
+
+// meanAbsDiff returns the mean absolute difference of a and b, sampled from frames of the same size.
+func meanAbsDiff(a, b []uint8) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	var sum int
+	for i := range a {
+		d := int(a[i]) - int(b[i])
+		if d < 0 {
+			d = -d
+		}
+		sum += d
+	}
+	return float64(sum) / float64(len(a))
+}
@@ -0,0 +1,101 @@
+package video
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	const frameInterval = 100 * time.Millisecond
+
+	newFrame := func(luma uint8) *image.YCbCr {
+		img := image.NewYCbCr(image.Rect(0, 0, 32, 32), image.YCbCrSubsampleRatio420)
+		for i := range img.Y {
+			img.Y[i] = luma
+		}
+		return img
+	}
+
+	var (
+		now    = time.Unix(0, 0)
+		frame  *image.YCbCr
+		events []FrameAnalysis
+	)
+	r := Analyze(
+		func(a FrameAnalysis) { events = append(events, a) },
+		WithBlackDuration(time.Second),
+		WithFreezeDuration(2*time.Second),
+		func(c *analyzeConfig) { c.now = func() time.Time { return now } },
+	)(ReaderFunc(func() (image.Image, func(), error) {
+		return frame, func() {}, nil
+	}))
+
+	read := func(n int, luma func(i int) uint8) {
+		for i := 0; i < n; i++ {
+			frame = newFrame(luma(i))
+			if _, _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(frameInterval)
+		}
+	}
+	moving := func(i int) uint8 { return uint8(64 + i%64) }
+	constant := func(luma uint8) func(int) uint8 {
+		return func(int) uint8 { return luma }
+	}
+
+	read(30, moving)
+	if len(events) != 0 {
+		t.Fatalf("expected no event from the moving frames, but got %+v", events)
+	}
+
+	// Black frames are detected after a second
+	blackStart := now
+	read(15, constant(0))
+	if len(events) != 1 {
+		t.Fatalf("expected an event, but got %+v", events)
+	}
+	if events[0].Condition != FrameConditionBlack || !events[0].Since.Equal(blackStart) {
+		t.Fatalf("expected black frames since %v, but got %+v", blackStart, events[0])
+	}
+	if events[0].BlackFrames != 1 {
+		t.Fatalf("expected 1 black frame, but got %d", events[0].BlackFrames)
+	}
+
+	read(1, moving)
+	if len(events) != 2 || events[1].Condition != FrameConditionNormal {
+		t.Fatalf("expected to recover from the black frames, but got %+v", events)
+	}
+
+	// Frozen frames are detected after 2 seconds
+	freezeStart := now
+	read(25, constant(100))
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events, but got %+v", events)
+	}
+	if events[2].Condition != FrameConditionFrozen || !events[2].Since.Equal(freezeStart) {
+		t.Fatalf("expected frozen frames since %v, but got %+v", freezeStart, events[2])
+	}
+
+	read(1, moving)
+	if len(events) != 4 || events[3].Condition != FrameConditionNormal {
+		t.Fatalf("expected to recover from the frozen frames, but got %+v", events)
+	}
+	if events[3].BlackFrames != 5 || events[3].FrozenFrames != 5 {
+		t.Fatalf("expected 5 black frames and 5 frozen frames, but got %+v", events[3])
+	}
+}
+
+func TestFrameConditionString(t *testing.T) {
+	for c, expected := range map[FrameCondition]string{
+		FrameConditionNormal: "normal",
+		FrameConditionBlack:  "black",
+		FrameConditionFrozen: "frozen",
+		FrameCondition(-1):   "unknown",
+	} {
+		if s := c.String(); s != expected {
+			t.Errorf("expected %s, but got %s", expected, s)
+		}
+	}
+}
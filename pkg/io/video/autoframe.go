@@ -0,0 +1,348 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// FaceDetector finds faces in frames passed by AutoFrame, e.g. with an external ML backend. The frame is only
+// valid during the call and must not be kept after DetectFaces returns.
+type FaceDetector interface {
+	// DetectFaces returns the bounds of the faces in img, in img's coordinates.
+	DetectFaces(img image.Image) []image.Rectangle
+}
+
+// FaceDetectorFunc adapts an ordinary function to FaceDetector.
+type FaceDetectorFunc func(img image.Image) []image.Rectangle
+
+// DetectFaces calls f(img).
+func (f FaceDetectorFunc) DetectFaces(img image.Image) []image.Rectangle {
+	return f(img)
+}
+
+const (
+	defaultAutoFrameInterval  = 5
+	defaultAutoFrameMaxZoom   = 2
+	defaultAutoFrameSmoothing = 0.1
+	// autoFrameFaceRatio is the face height relative to the framed region's height.
+	autoFrameFaceRatio = 1.0 / 3
+	// autoFrameHeadroom is how far the face sits above the framed region's center, relative to its height.
+	autoFrameHeadroom = 0.1
+	// autoFrameLostDetections is how many detections without a face it takes before zooming out.
+	autoFrameLostDetections = 6
+)
+
+type autoFrameConfig struct {
+	interval  int
+	maxZoom   float64
+	smoothing float64
+	scaler    Scaler
+}
+
+// AutoFrameOption configures AutoFrame.
+type AutoFrameOption func(*autoFrameConfig)
+
+// WithAutoFrameInterval sets how many frames pass between frames sent to the detector. The default is 5, i.e. 6
+// detections per second at 30 fps.
+func WithAutoFrameInterval(frames int) AutoFrameOption {
+	return func(c *autoFrameConfig) {
+		c.interval = frames
+	}
+}
+
+// WithAutoFrameMaxZoom sets the maximum zoom, which limits resolution loss on small faces. The default is 2.
+func WithAutoFrameMaxZoom(zoom float64) AutoFrameOption {
+	return func(c *autoFrameConfig) {
+		c.maxZoom = zoom
+	}
+}
+
+// WithAutoFrameSmoothing sets how far the framed region moves toward its target on each frame, as a fraction
+// of the distance. The default, 0.1, follows a face in about a second at 30 fps while smoothing out detection
+// jitter. 1 jumps straight to the target.
+func WithAutoFrameSmoothing(factor float64) AutoFrameOption {
+	return func(c *autoFrameConfig) {
+		c.smoothing = factor
+	}
+}
+
+// WithAutoFrameScaler sets the scaler for the framed region. The default is ScalerApproxBiLinear.
+func WithAutoFrameScaler(scaler Scaler) AutoFrameOption {
+	return func(c *autoFrameConfig) {
+		c.scaler = scaler
+	}
+}
+
+// AutoFrame keeps the dominant face centered by cropping and zooming, like the auto-framing of video conference
+// cameras. It frames the largest face and follows it until it's lost or another face is twice as large, and
+// zooms out when no face has been seen for a while. The framed region keeps the frame's aspect ratio and is
+// scaled back to the frame size, so the encoder isn't reconfigured.
+//
+// Detection runs on the reading goroutine at a reduced rate, like Detect. Frames must be RGBA or YCbCr, as with
+// Scale, and the scaled frame is only valid until the next frame is read.
+func AutoFrame(detector FaceDetector, opts ...AutoFrameOption) TransformFunc {
+	c := autoFrameConfig{
+		interval:  defaultAutoFrameInterval,
+		maxZoom:   defaultAutoFrameMaxZoom,
+		smoothing: defaultAutoFrameSmoothing,
+		scaler:    ScalerApproxBiLinear,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.interval < 1 {
+		c.interval = 1
+	}
+	if c.maxZoom < 1 {
+		c.maxZoom = 1
+	}
+	if c.smoothing <= 0 || c.smoothing > 1 {
+		c.smoothing = 1
+	}
+
+	return func(r Reader) Reader {
+		var (
+			frames  int
+			lost    int
+			face    image.Rectangle
+			bounds  image.Rectangle
+			current framing
+			target  framing
+
+			cropped image.Image
+			scaled  Reader
+		)
+		source := ReaderFunc(func() (image.Image, func(), error) {
+			return cropped, func() {}, nil
+		})
+
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			if b := img.Bounds(); b != bounds {
+				bounds = b
+				current = fullFraming(b)
+				target = current
+				face = image.Rectangle{}
+				scaled = Scale(b.Dx(), b.Dy(), c.scaler)(source)
+			}
+
+			if frames%c.interval == 0 {
+				if f, ok := dominantFace(detector.DetectFaces(img), face); ok {
+					face, lost = f, 0
+					target = faceFraming(f, bounds, c.maxZoom)
+				} else if lost++; lost >= autoFrameLostDetections {
+					face = image.Rectangle{}
+					target = fullFraming(bounds)
+				}
+			}
+			frames++
+			current = current.toward(target, c.smoothing)
+
+			rect := current.rect(bounds)
+			if rect == bounds {
+				return img, release, nil
+			}
+			src := img
+			// Crop a copy in Go memory, e.g. for DMABufImage.
+			if i, ok := src.(interface{ YCbCr() *image.YCbCr }); ok {
+				if yuv := i.YCbCr(); yuv != nil {
+					src = yuv
+				}
+			}
+			sub, ok := src.(interface {
+				SubImage(image.Rectangle) image.Image
+			})
+			if !ok {
+				release()
+				return nil, func() {}, errUnsupportedImageType
+			}
+			cropped = sub.SubImage(rect)
+			out, _, err := scaled.Read()
+			release()
+			return out, func() {}, err
+		})
+	}
+}
+
+// dominantFace returns the face to frame. A face overlapping the previous one is kept unless another face is
+// twice as large, so framing doesn't jump between faces of similar size.
+func dominantFace(faces []image.Rectangle, prev image.Rectangle) (image.Rectangle, bool) {
+	var largest, tracked image.Rectangle
+	for _, f := range faces {
+		if f.Empty() {
+			continue
+		}
+		if area(f) > area(largest) {
+			largest = f
+		}
+		if f.Overlaps(prev) && area(f) > area(tracked) {
+			tracked = f
+		}
+	}
+	if !tracked.Empty() && 2*area(tracked) >= area(largest) {
+		return tracked, true
+	}
+	return largest, !largest.Empty()
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// framing is a framed region by center and height. They're interpolated separately so zoom and pan stay smooth.
+type framing struct {
+	x, y, height float64
+}
+
+func fullFraming(bounds image.Rectangle) framing {
+	return framing{
+		x:      float64(bounds.Min.X+bounds.Max.X) / 2,
+		y:      float64(bounds.Min.Y+bounds.Max.Y) / 2,
+		height: float64(bounds.Dy()),
+	}
+}
+
+// faceFraming returns the framing of face, with the face a third of the height and a little above center.
+func faceFraming(face, bounds image.Rectangle, maxZoom float64) framing {
+	height := float64(face.Dy()) / autoFrameFaceRatio
+	height = math.Max(float64(bounds.Dy())/maxZoom, math.Min(float64(bounds.Dy()), height))
+	return framing{
+		x:      float64(face.Min.X+face.Max.X) / 2,
+		y:      float64(face.Min.Y+face.Max.Y)/2 + autoFrameHeadroom*height,
+		height: height,
+	}
+}
+
+func (f framing) toward(target framing, factor float64) framing {
+	return framing{
+		x:      f.x + (target.x-f.x)*factor,
+		y:      f.y + (target.y-f.y)*factor,
+		height: f.height + (target.height-f.height)*factor,
+	}
+}
+
+// rect returns f's region in bounds with bounds's aspect ratio. The region is shifted into bounds rather than
+// cut, so it isn't distorted at the edges.
+func (f framing) rect(bounds image.Rectangle) image.Rectangle {
+	h := int(math.Round(f.height))
+	if h >= bounds.Dy() {
+		return bounds
+	}
+	w := int(math.Round(f.height * float64(bounds.Dx()) / float64(bounds.Dy())))
+	x := int(math.Round(f.x - float64(w)/2))
+	y := int(math.Round(f.y - float64(h)/2))
+	x = clampInt(x, bounds.Min.X, bounds.Max.X-w)
+	y = clampInt(y, bounds.Min.Y, bounds.Max.Y-h)
+	// Align the origin to 2 pixels for 4:2:0 chroma
+	x, y = x&^1, y&^1
+	return image.Rect(x, y, x+w, y+h)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+const (
+	// skinDetectorCells is the number of grid cells across the frame width.
+	skinDetectorCells = 80
+	// skinDetectorMinCells is the minimum number of cells in a face.
+	skinDetectorMinCells = 12
+	// skinDetectorMinFill is the minimum share of skin cells within a face's bounds.
+	skinDetectorMinFill = 0.4
+)
+
+// skinDetector is a FaceDetector that finds blobs of skin tone.
+type skinDetector struct {
+	mask  []bool
+	queue []int
+}
+
+// NewSkinFaceDetector creates a FaceDetector that finds face-shaped blobs of skin tone. It's cheap and needs no
+// model, but it also picks up hands and skin-colored backgrounds, and misses faces in the dark or under colored
+// light. Use an ML backend if framing quality matters.
+func NewSkinFaceDetector() FaceDetector {
+	return &skinDetector{}
+}
+
+func (d *skinDetector) DetectFaces(img image.Image) []image.Rectangle {
+	b := img.Bounds()
+	step := b.Dx() / skinDetectorCells
+	if step < 1 {
+		step = 1
+	}
+	w, h := b.Dx()/step, b.Dy()/step
+	if cap(d.mask) < w*h {
+		d.mask = make([]bool, w*h)
+	}
+	d.mask = d.mask[:w*h]
+
+	ycbcr, _ := img.(*image.YCbCr)
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			x, y := b.Min.X+i*step+step/2, b.Min.Y+j*step+step/2
+			var cb, cr uint8
+			if ycbcr != nil {
+				ci := ycbcr.COffset(x, y)
+				cb, cr = ycbcr.Cb[ci], ycbcr.Cr[ci]
+			} else {
+				c := color.YCbCrModel.Convert(img.At(x, y)).(color.YCbCr)
+				cb, cr = c.Cb, c.Cr
+			}
+			d.mask[j*w+i] = isSkin(cb, cr)
+		}
+	}
+
+	var faces []image.Rectangle
+	for start := range d.mask {
+		if !d.mask[start] {
+			continue
+		}
+		// Flood fill the blob and clear it from the mask
+		d.mask[start] = false
+		d.queue = append(d.queue[:0], start)
+		cells := 0
+		blob := image.Rect(start%w, start/w, start%w+1, start/w+1)
+		for len(d.queue) > 0 {
+			k := d.queue[len(d.queue)-1]
+			d.queue = d.queue[:len(d.queue)-1]
+			cells++
+			i, j := k%w, k/w
+			blob = blob.Union(image.Rect(i, j, i+1, j+1))
+			for _, n := range [4][2]int{{i - 1, j}, {i + 1, j}, {i, j - 1}, {i, j + 1}} {
+				if n[0] < 0 || n[0] >= w || n[1] < 0 || n[1] >= h || !d.mask[n[1]*w+n[0]] {
+					continue
+				}
+				d.mask[n[1]*w+n[0]] = false
+				d.queue = append(d.queue, n[1]*w+n[0])
+			}
+		}
+
+		aspect := float64(blob.Dy()) / float64(blob.Dx())
+		if cells < skinDetectorMinCells || float64(cells) < skinDetectorMinFill*float64(area(blob)) ||
+			aspect < 0.8 || aspect > 2 {
+			continue
+		}
+		faces = append(faces, image.Rect(
+			b.Min.X+blob.Min.X*step, b.Min.Y+blob.Min.Y*step,
+			b.Min.X+blob.Max.X*step, b.Min.Y+blob.Max.Y*step,
+		))
+	}
+	return faces
+}
+
+// isSkin classifies skin tone chroma across most ethnicities, which stays stable as brightness changes.
+// Reference: D. Chai and K. N. Ngan, "Face segmentation using skin-color map in videophone applications", 1999.
+func isSkin(cb, cr uint8) bool {
+	return cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
@@ -0,0 +1,109 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestAutoFrame(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	draw.Draw(img, img.Rect, &image.Uniform{color.RGBA{128, 128, 128, 255}}, image.Point{}, draw.Src)
+	faceRect := image.Rect(240, 100, 280, 140)
+	draw.Draw(img, faceRect, &image.Uniform{color.RGBA{255, 0, 0, 255}}, image.Point{}, draw.Src)
+
+	faces := []image.Rectangle{faceRect}
+	detector := FaceDetectorFunc(func(image.Image) []image.Rectangle { return faces })
+	r := AutoFrame(detector, WithAutoFrameInterval(1), WithAutoFrameSmoothing(1))(ReaderFunc(func() (image.Image, func(), error) {
+		return img, func() {}, nil
+	}))
+
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds() != img.Rect {
+		t.Fatalf("expected the bounds %v, but got %v", img.Rect, out.Bounds())
+	}
+	// The 160x120 region is shifted into the frame at (160, 72) and zoomed by 2
+	if c := color.RGBAModel.Convert(out.At(200, 96)).(color.RGBA); c.R != 255 || c.G != 0 {
+		t.Fatalf("expected the face at the framed position, but got %v", c)
+	}
+	if c := color.RGBAModel.Convert(out.At(10, 10)).(color.RGBA); c.R != 128 {
+		t.Fatalf("expected the background at the corner, but got %v", c)
+	}
+
+	// Zoom out once the face is lost
+	faces = nil
+	for i := 0; i < autoFrameLostDetections; i++ {
+		if out, _, err = r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out != image.Image(img) {
+		t.Fatal("expected the full frame after the face is lost")
+	}
+}
+
+func TestDominantFace(t *testing.T) {
+	small := image.Rect(0, 0, 10, 10)
+	medium := image.Rect(100, 0, 114, 14)
+	large := image.Rect(200, 0, 230, 30)
+	larger := image.Rect(300, 0, 319, 19)
+
+	testCases := map[string]struct {
+		faces    []image.Rectangle
+		prev     image.Rectangle
+		expected image.Rectangle
+	}{
+		"Largest":       {faces: []image.Rectangle{small, medium}, expected: medium},
+		"Tracked":       {faces: []image.Rectangle{small, medium}, prev: image.Rect(2, 2, 12, 12), expected: small},
+		"TwiceAsLarge":  {faces: []image.Rectangle{small, large}, prev: image.Rect(2, 2, 12, 12), expected: large},
+		"TrackedMedium": {faces: []image.Rectangle{medium, larger}, prev: medium, expected: medium},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			face, ok := dominantFace(c.faces, c.prev)
+			if !ok || face != c.expected {
+				t.Fatalf("expected %v, but got %v", c.expected, face)
+			}
+		})
+	}
+	if _, ok := dominantFace(nil, small); ok {
+		t.Fatal("expected no face")
+	}
+}
+
+func TestSkinFaceDetector(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 320, 240))
+	draw.Draw(img, img.Rect, &image.Uniform{color.RGBA{60, 90, 160, 255}}, image.Point{}, draw.Src)
+	skin := &image.Uniform{color.RGBA{224, 172, 140, 255}}
+	face := image.Rect(100, 60, 160, 140)
+	draw.Draw(img, face, skin, image.Point{}, draw.Src)
+	// An arm, which isn't face-shaped
+	draw.Draw(img, image.Rect(200, 200, 320, 220), skin, image.Point{}, draw.Src)
+
+	faces := NewSkinFaceDetector().DetectFaces(img)
+	if len(faces) != 1 {
+		t.Fatalf("expected a face, but got %v", faces)
+	}
+	if inter := faces[0].Intersect(face); area(inter) < area(face)*3/4 {
+		t.Fatalf("expected the face around %v, but got %v", face, faces[0])
+	}
+
+	// YCbCr chroma is read directly
+	ycbcr := image.NewYCbCr(img.Rect, image.YCbCrSubsampleRatio420)
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 320; x++ {
+			c := color.YCbCrModel.Convert(img.At(x, y)).(color.YCbCr)
+			ycbcr.Y[ycbcr.YOffset(x, y)] = c.Y
+			ycbcr.Cb[ycbcr.COffset(x, y)] = c.Cb
+			ycbcr.Cr[ycbcr.COffset(x, y)] = c.Cr
+		}
+	}
+	if faces := NewSkinFaceDetector().DetectFaces(ycbcr); len(faces) != 1 {
+		t.Fatalf("expected a face, but got %v", faces)
+	}
+}
@@ -3,15 +3,24 @@ package video
 import (
 	"fmt"
 	"image"
+	"sync/atomic"
 
 	"github.com/pion/mediadevices/pkg/io"
 )
 
 var errEmptySource = fmt.Errorf("Source can't be nil")
 
-// Broadcaster is a specialized video broadcaster.
+// Broadcaster is a specialized video broadcaster. Its readers are MetadataReaders, which report the metadata
+// of the frames they read if the source is a MetadataReader.
 type Broadcaster struct {
 	ioBroadcaster *io.Broadcaster
+	source        atomic.Value
+}
+
+// broadcasterFrame is stored in the broadcaster to pass metadata along with the frame.
+type broadcasterFrame struct {
+	img      image.Image
+	metadata Metadata
 }
 
 type BroadcasterConfig struct {
@@ -27,11 +36,23 @@ func NewBroadcaster(source Reader, config *BroadcasterConfig) *Broadcaster {
 		coreConfig = config.Core
 	}
 
-	broadcaster := io.NewBroadcaster(io.ReaderFunc(func() (interface{}, func(), error) {
-		return source.Read()
-	}), coreConfig)
+	broadcaster := &Broadcaster{}
+	broadcaster.ioBroadcaster = io.NewBroadcaster(broadcaster.wrapSource(source), coreConfig)
+	if source != nil {
+		broadcaster.source.Store(source)
+	}
+	return broadcaster
+}
 
-	return &Broadcaster{broadcaster}
+func (broadcaster *Broadcaster) wrapSource(source Reader) io.Reader {
+	return io.ReaderFunc(func() (interface{}, func(), error) {
+		img, release, err := source.Read()
+		if err != nil {
+			return nil, release, err
+		}
+		metadata, _ := MetadataOf(source)
+		return broadcasterFrame{img: img, metadata: metadata}, release, nil
+	})
 }
 
 // NewReader creates a new reader. Each reader will retrieve the same data from the source.
@@ -44,33 +65,42 @@ func (broadcaster *Broadcaster) NewReader(copyFrame bool) Reader {
 	if copyFrame {
 		buffer := NewFrameBuffer(0)
 		copyFn = func(src interface{}) interface{} {
-			realSrc, _ := src.(image.Image)
-			buffer.StoreCopy(realSrc)
-			return buffer.Load()
+			frame, _ := src.(broadcasterFrame)
+			if frame.img == nil {
+				return frame
+			}
+			buffer.StoreCopy(frame.img)
+			frame.img = buffer.Load()
+			return frame
 		}
 	}
 
+	var last lastMetadata
 	reader := broadcaster.ioBroadcaster.NewReader(copyFn)
-	return ReaderFunc(func() (image.Image, func(), error) {
-		data, _, err := reader.Read()
-		img, _ := data.(image.Image)
-		return img, func() {}, err
-	})
+	return &metadataReader{
+		Reader: ReaderFunc(func() (image.Image, func(), error) {
+			data, _, err := reader.Read()
+			frame, _ := data.(broadcasterFrame)
+			last.store(frame.metadata)
+			return frame.img, func() {}, err
+		}),
+		metadata: last.load,
+	}
 }
 
 // ReplaceSource replaces the underlying source. This operation is thread safe.
 func (broadcaster *Broadcaster) ReplaceSource(source Reader) error {
-	return broadcaster.ioBroadcaster.ReplaceSource(io.ReaderFunc(func() (interface{}, func(), error) {
-		return source.Read()
-	}))
+	if source == nil {
+		return errEmptySource
+	}
+	if err := broadcaster.ioBroadcaster.ReplaceSource(broadcaster.wrapSource(source)); err != nil {
+		return err
+	}
+	broadcaster.source.Store(source)
+	return nil
 }
 
 // Source retrieves the underlying source. This operation is thread safe.
 func (broadcaster *Broadcaster) Source() Reader {
-	source := broadcaster.ioBroadcaster.Source()
-	return ReaderFunc(func() (image.Image, func(), error) {
-		data, _, err := source.Read()
-		img, _ := data.(image.Image)
-		return img, func() {}, err
-	})
+	return broadcaster.source.Load().(Reader)
 }
@@ -0,0 +1,219 @@
+package video
+
+import (
+	"fmt"
+	"image"
+)
+
+// subsampleFactors returns ratio's luma columns and rows per chroma sample.
+func subsampleFactors(ratio image.YCbCrSubsampleRatio) (int, int, error) {
+	switch ratio {
+	case image.YCbCrSubsampleRatio444:
+		return 1, 1, nil
+	case image.YCbCrSubsampleRatio422:
+		return 2, 1, nil
+	case image.YCbCrSubsampleRatio420:
+		return 2, 2, nil
+	case image.YCbCrSubsampleRatio440:
+		return 1, 2, nil
+	case image.YCbCrSubsampleRatio411:
+		return 4, 1, nil
+	case image.YCbCrSubsampleRatio410:
+		return 4, 2, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported subsample ratio: %s", ratio)
+}
+
+// Chroma converts frames to YCbCr in ratio, e.g. capture cards' 4:2:2 frames to encoders' 4:2:0.
+// Chroma is averaged over target sample areas when it's subsampled, and interpolated
+// linearly between sample centers when it's upsampled, so its geometry is kept with odd
+// sizes and cropped frames. Frames that aren't YCbCr are converted like ToI420. Frames are
+// converted into the transform's buffers, so frames shared by a Broadcaster's readers are kept.
+func Chroma(ratio image.YCbCrSubsampleRatio) TransformFunc {
+	return func(r Reader) Reader {
+		var yuv, dst image.YCbCr
+		var converter chromaConverter
+		return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			src, ok := asYCbCr(img)
+			if !ok {
+				imageToYCbCr(&yuv, img)
+				src = &yuv
+			}
+			if src.SubsampleRatio == ratio {
+				return src, release, nil
+			}
+			if err := converter.convert(&dst, src, ratio); err != nil {
+				release()
+				return nil, func() {}, err
+			}
+			// dst refers to the frame's luma
+			return &dst, release, nil
+		}), r)
+	}
+}
+
+// chromaConverter converts frame chroma, keeping its taps and row buffer for later frames of the same geometry.
+type chromaConverter struct {
+	key          chromaKey
+	xTaps, yTaps []taps
+	rows         []int32
+}
+
+// chromaKey is the geometry the taps were computed for.
+type chromaKey struct {
+	bounds     image.Rectangle
+	src, ratio image.YCbCrSubsampleRatio
+}
+
+// convert converts the chroma of src to ratio in dst, which is at the origin. The luma points to src's luma.
+func (c *chromaConverter) convert(dst, src *image.YCbCr, ratio image.YCbCrSubsampleRatio) error {
+	sfx, sfy, err := subsampleFactors(src.SubsampleRatio)
+	if err != nil {
+		return err
+	}
+	tfx, tfy, err := subsampleFactors(ratio)
+	if err != nil {
+		return err
+	}
+
+	bounds := src.Rect
+	w, h := bounds.Dx(), bounds.Dy()
+	// src chroma samples that cover bounds, and dst ones
+	scw := (bounds.Max.X-1)/sfx - bounds.Min.X/sfx + 1
+	sch := (bounds.Max.Y-1)/sfy - bounds.Min.Y/sfy + 1
+	tcw, tch := (w+tfx-1)/tfx, (h+tfy-1)/tfy
+	if key := (chromaKey{bounds, src.SubsampleRatio, ratio}); c.key != key || c.xTaps == nil {
+		c.key = key
+		c.xTaps = chromaTaps(bounds.Min.X, w, sfx, tfx, scw, tcw)
+		c.yTaps = chromaTaps(bounds.Min.Y, h, sfy, tfy, sch, tch)
+	}
+	xTaps, yTaps := c.xTaps, c.yTaps
+	if cap(c.rows) < sch*tcw {
+		c.rows = make([]int32, sch*tcw)
+	}
+	// Rows of src, resampled horizontally
+	rows := c.rows[:sch*tcw]
+
+	cSize := tcw * tch
+	if cap(dst.Cb) < 2*cSize {
+		dst.Cb = make([]uint8, 2*cSize)
+	}
+	dst.Cb, dst.Cr = dst.Cb[:cSize], dst.Cb[cSize:2*cSize]
+	dst.Y = src.Y[src.YOffset(bounds.Min.X, bounds.Min.Y):]
+	dst.YStride, dst.CStride = src.YStride, tcw
+	dst.SubsampleRatio = ratio
+	dst.Rect = image.Rect(0, 0, w, h)
+
+	c0 := src.COffset(bounds.Min.X, bounds.Min.Y)
+	for _, plane := range [2]struct{ src, dst []uint8 }{{src.Cb, dst.Cb}, {src.Cr, dst.Cr}} {
+		plane := plane
+		parallelRows(sch, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				s := plane.src[c0+y*src.CStride:]
+				for x, taps := range xTaps {
+					rows[y*tcw+x] = taps.apply(func(i int) int32 { return int32(s[i]) << 8 })
+				}
+			}
+		})
+		parallelRows(tch, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				taps := yTaps[y]
+				for x := 0; x < tcw; x++ {
+					v := taps.apply(func(i int) int32 { return rows[i*tcw+x] })
+					plane.dst[y*tcw+x] = uint8((v + 0x80) >> 8)
+				}
+			}
+		})
+	}
+	return nil
+}
+
+// taps are src samples weighted into a dst sample.
+type taps struct {
+	index  []int
+	weight []int32
+	total  int32
+}
+
+func (t *taps) add(i int, weight int32) {
+	if weight <= 0 {
+		return
+	}
+	t.index = append(t.index, i)
+	t.weight = append(t.weight, weight)
+	t.total += weight
+}
+
+func (t *taps) apply(sample func(i int) int32) int32 {
+	var sum int64
+	for k, i := range t.index {
+		sum += int64(sample(i)) * int64(t.weight[k])
+	}
+	return int32((sum + int64(t.total)/2) / int64(t.total))
+}
+
+// chromaTaps returns taps for dcount dst samples from scount src samples along an axis, where
+// luma is n pixels from min, and src and dst samples cover sf and tf pixels. src samples are
+// aligned to absolute coordinates like image.YCbCr, and dst ones to min.
+func chromaTaps(min, n, sf, tf, scount, dcount int) []taps {
+	// First src sample start relative to min
+	s0 := min/sf*sf - min
+	result := make([]taps, dcount)
+	for j := range result {
+		start, end := j*tf, (j+1)*tf
+		if end > n {
+			end = n
+		}
+		if tf >= sf {
+			// Averages src samples by their overlap with the dst sample
+			for i := (start - s0) / sf; i < scount && s0+i*sf < end; i++ {
+				from, to := s0+i*sf, s0+(i+1)*sf
+				if from < start {
+					from = start
+				}
+				if to > end {
+					to = end
+				}
+				result[j].add(i, int32(to-from))
+			}
+			continue
+		}
+
+		// Interpolates between src sample centers, which are clipped to luma at the edges
+		center := start + end
+		i := (start - s0) / sf
+		for i > 0 && sampleCenter(i, s0, sf, n) > center {
+			i--
+		}
+		for i+1 < scount && sampleCenter(i+1, s0, sf, n) <= center {
+			i++
+		}
+		c0 := sampleCenter(i, s0, sf, n)
+		if i+1 >= scount || center <= c0 {
+			result[j].add(i, 256)
+			continue
+		}
+		c1 := sampleCenter(i+1, s0, sf, n)
+		frac := int32((center - c0) * 256 / (c1 - c0))
+		result[j].add(i, 256-frac)
+		result[j].add(i+1, frac)
+	}
+	return result
+}
+
+// sampleCenter returns twice the center of sample i, which starts at s0+i*sf and is clipped to [0, n).
+func sampleCenter(i, s0, sf, n int) int {
+	from, to := s0+i*sf, s0+(i+1)*sf
+	if from < 0 {
+		from = 0
+	}
+	if to > n {
+		to = n
+	}
+	return from + to
+}
@@ -0,0 +1,118 @@
+package video
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestChroma(t *testing.T) {
+	// 3x3 4:2:2, whose last chroma column covers a single luma column
+	src := image.NewYCbCr(image.Rect(0, 0, 3, 3), image.YCbCrSubsampleRatio422)
+	copy(src.Cb, []uint8{
+		10, 200,
+		30, 100,
+		50, 0,
+	})
+	copy(src.Cr, src.Cb)
+	original := append([]uint8{}, src.Cb...)
+
+	cases := map[string]struct {
+		ratio    image.YCbCrSubsampleRatio
+		expected []uint8
+	}{
+		"420": {
+			ratio: image.YCbCrSubsampleRatio420,
+			expected: []uint8{
+				20, 150,
+				50, 0,
+			},
+		},
+		"444": {
+			ratio: image.YCbCrSubsampleRatio444,
+			expected: []uint8{
+				// 4:2:2 sample centers are between luma columns, except the last one
+				10, 73, 200,
+				30, 53, 100,
+				50, 33, 0,
+			},
+		},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			img, _, err := Chroma(c.ratio)(ReaderFunc(func() (image.Image, func(), error) {
+				return src, func() {}, nil
+			})).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			yuv, ok := img.(*image.YCbCr)
+			if !ok {
+				t.Fatalf("expected *image.YCbCr, but got %T", img)
+			}
+			if yuv.SubsampleRatio != c.ratio {
+				t.Fatalf("expected %s, but got %s", c.ratio, yuv.SubsampleRatio)
+			}
+			if !reflect.DeepEqual(yuv.Cb, c.expected) || !reflect.DeepEqual(yuv.Cr, c.expected) {
+				t.Fatalf("expected %v, but got %v, %v", c.expected, yuv.Cb, yuv.Cr)
+			}
+			if !reflect.DeepEqual(src.Cb, original) {
+				t.Fatalf("expected the source to be kept, but got %v", src.Cb)
+			}
+		})
+	}
+}
+
+func TestChromaSubImage(t *testing.T) {
+	// 4:2:0 chroma is aligned to absolute coordinates, so each pixel of the sub image from (1, 1) is
+	// covered by its own sample.
+	src := image.NewYCbCr(image.Rect(0, 0, 4, 4), image.YCbCrSubsampleRatio420)
+	copy(src.Cb, []uint8{
+		0, 100,
+		200, 40,
+	})
+	sub := src.SubImage(image.Rect(1, 1, 3, 3)).(*image.YCbCr)
+
+	img, _, err := Chroma(image.YCbCrSubsampleRatio444)(ReaderFunc(func() (image.Image, func(), error) {
+		return sub, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv := img.(*image.YCbCr)
+	if expected := image.Rect(0, 0, 2, 2); yuv.Rect != expected {
+		t.Fatalf("expected %v, but got %v", expected, yuv.Rect)
+	}
+	expected := []uint8{
+		0, 100,
+		200, 40,
+	}
+	if !reflect.DeepEqual(yuv.Cb, expected) {
+		t.Fatalf("expected %v, but got %v", expected, yuv.Cb)
+	}
+	if yuv.YOffset(0, 0) != 0 || &yuv.Y[0] != &src.Y[src.YOffset(1, 1)] {
+		t.Fatal("expected the luma of the source")
+	}
+}
+
+func TestToI420OddSize(t *testing.T) {
+	src := image.NewYCbCr(image.Rect(0, 0, 3, 1), image.YCbCrSubsampleRatio444)
+	copy(src.Cb, []uint8{10, 30, 90})
+	copy(src.Cr, []uint8{10, 30, 90})
+
+	img, _, err := ToI420(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv := img.(*image.YCbCr)
+	// The last column is kept in its own sample instead of dropped
+	if expected := []uint8{20, 90}; !reflect.DeepEqual(yuv.Cb, expected) {
+		t.Fatalf("expected %v, but got %v", expected, yuv.Cb)
+	}
+	if expected := []uint8{10, 30, 90}; !reflect.DeepEqual(src.Cb, expected) {
+		t.Fatalf("expected the source to be kept, but got %v", src.Cb)
+	}
+}
@@ -0,0 +1,393 @@
+package video
+
+import (
+	"image"
+	"image/draw"
+
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// ColorSpace is the matrix and range of frames' YCbCr values. The zero value is unknown.
+type ColorSpace struct {
+	// Matrix is prop.ColorMatrixBT601 or prop.ColorMatrixBT709.
+	Matrix string
+	// Range is prop.ColorRangeLimited or prop.ColorRangeFull.
+	Range string
+}
+
+var (
+	// ColorSpaceBT601 is the SD source colorspace.
+	ColorSpaceBT601 = ColorSpace{Matrix: prop.ColorMatrixBT601, Range: prop.ColorRangeLimited}
+	// ColorSpaceBT709 is the HD source colorspace.
+	ColorSpaceBT709 = ColorSpace{Matrix: prop.ColorMatrixBT709, Range: prop.ColorRangeLimited}
+	// ColorSpaceJPEG is image/color's colorspace, which ToI420 and ToRGBA convert frames in.
+	ColorSpaceJPEG = ColorSpace{Matrix: prop.ColorMatrixBT601, Range: prop.ColorRangeFull}
+)
+
+// ColorSpaceOf returns the properties' colorspace. The matrix defaults to BT.601 and the range to limited
+// if only one of them is known.
+func ColorSpaceOf(p prop.Video) ColorSpace {
+	cs := ColorSpace{Matrix: p.ColorMatrix, Range: p.ColorRange}
+	if cs.IsZero() {
+		return cs
+	}
+	if cs.Matrix == "" {
+		cs.Matrix = prop.ColorMatrixBT601
+	}
+	if cs.Range == "" {
+		cs.Range = prop.ColorRangeLimited
+	}
+	return cs
+}
+
+// IsZero reports whether the colorspace is unknown.
+func (cs ColorSpace) IsZero() bool {
+	return cs == ColorSpace{}
+}
+
+func (cs ColorSpace) String() string {
+	if cs.IsZero() {
+		return "unknown"
+	}
+	return cs.Matrix + "/" + cs.Range
+}
+
+// affine is a conversion between RGB and YCbCr in 16.16 fixed point, which maps v to m[:3]·v + m[3] per channel.
+type affine [3][4]int32
+
+type affineFloat [3][4]float64
+
+func (a affineFloat) fixed() affine {
+	var m affine
+	for i := range a {
+		for j := range a[i] {
+			v := a[i][j] * 65536
+			if j == 3 {
+				// Rounds results
+				v += 32768
+			}
+			if v < 0 {
+				m[i][j] = int32(v - 0.5)
+			} else {
+				m[i][j] = int32(v + 0.5)
+			}
+		}
+	}
+	return m
+}
+
+// then returns a conversion that applies a and then b.
+func (a affineFloat) then(b affineFloat) affineFloat {
+	var m affineFloat
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += b[i][k] * a[k][j]
+			}
+		}
+		m[i][3] += b[i][3]
+	}
+	return m
+}
+
+func (m *affine) apply(x, y, z uint8) (uint8, uint8, uint8) {
+	return clampFixed(m[0][0]*int32(x) + m[0][1]*int32(y) + m[0][2]*int32(z) + m[0][3]),
+		clampFixed(m[1][0]*int32(x) + m[1][1]*int32(y) + m[1][2]*int32(z) + m[1][3]),
+		clampFixed(m[2][0]*int32(x) + m[2][1]*int32(y) + m[2][2]*int32(z) + m[2][3])
+}
+
+func clampFixed(v int32) uint8 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 0xffffff:
+		return 0xff
+	}
+	return uint8(v >> 16)
+}
+
+// coefficients returns the matrix's Kr and Kb, and luma and chroma scales and offsets.
+func (cs ColorSpace) coefficients() (kr, kb, yScale, yOffset, cScale float64) {
+	kr, kb = 0.299, 0.114
+	if cs.Matrix == prop.ColorMatrixBT709 {
+		kr, kb = 0.2126, 0.0722
+	}
+	if cs.Range == prop.ColorRangeFull {
+		return kr, kb, 255, 0, 255
+	}
+	return kr, kb, 219, 16, 224
+}
+
+// fromRGB returns a conversion from RGB in 0-255 to YCbCr in cs.
+func (cs ColorSpace) fromRGB() affineFloat {
+	kr, kb, yScale, yOffset, cScale := cs.coefficients()
+	kg := 1 - kr - kb
+	// Y = Kr*R + Kg*G + Kb*B, Cb = (B-Y)/(2*(1-Kb)) and Cr = (R-Y)/(2*(1-Kr)) in 0-1
+	y := [3]float64{kr, kg, kb}
+	cb := [3]float64{-kr / (2 * (1 - kb)), -kg / (2 * (1 - kb)), 0.5}
+	cr := [3]float64{0.5, -kg / (2 * (1 - kr)), -kb / (2 * (1 - kr))}
+
+	var m affineFloat
+	for j := 0; j < 3; j++ {
+		m[0][j] = y[j] * yScale / 255
+		m[1][j] = cb[j] * cScale / 255
+		m[2][j] = cr[j] * cScale / 255
+	}
+	m[0][3], m[1][3], m[2][3] = yOffset, 128, 128
+	return m
+}
+
+// toRGB returns a conversion from YCbCr in cs to RGB in 0-255.
+func (cs ColorSpace) toRGB() affineFloat {
+	kr, kb, yScale, yOffset, cScale := cs.coefficients()
+	kg := 1 - kr - kb
+	ys, c := 255/yScale, 255/cScale
+	// R = Y + 2*(1-Kr)*Cr, B = Y + 2*(1-Kb)*Cb and G = (Y - Kr*R - Kb*B) / Kg
+	rCr := 2 * (1 - kr) * c
+	bCb := 2 * (1 - kb) * c
+	gCb := -kb * bCb / kg
+	gCr := -kr * rCr / kg
+
+	m := affineFloat{
+		{ys, 0, rCr},
+		{ys, gCb, gCr},
+		{ys, bCb, 0},
+	}
+	for i := 0; i < 3; i++ {
+		m[i][3] = -yOffset*m[i][0] - 128*(m[i][1]+m[i][2])
+	}
+	return m
+}
+
+// AssumeColorSpace sets cs in metadata of frames whose colorspace is unknown, e.g. from driver
+// properties.
+func AssumeColorSpace(cs ColorSpace) TransformFunc {
+	return func(r Reader) Reader {
+		var last lastMetadata
+		return &metadataReader{
+			Reader: ReaderFunc(func() (image.Image, func(), error) {
+				img, release, err := r.Read()
+				if err != nil {
+					return nil, func() {}, err
+				}
+
+				m, _ := MetadataOf(r)
+				if m.ColorSpace.IsZero() {
+					m.ColorSpace = cs
+				}
+				last.store(m)
+				return img, release, nil
+			}),
+			metadata: last.load,
+		}
+	}
+}
+
+// ConvertColorSpace converts YCbCr frames from colorspace from to colorspace to, e.g. a webcam's BT.601 frames
+// to BT.709 for an HD encoder. If from is zero, frames are converted from the colorspace in their
+// metadata, and frames whose colorspace is unknown are kept as if they were in to. Frames that aren't
+// YCbCr are kept, and YCbCr frames' metadata reports to.
+func ConvertColorSpace(from, to ColorSpace) TransformFunc {
+	return func(r Reader) Reader {
+		var last lastMetadata
+		var dst image.YCbCr
+		return &metadataReader{
+			Reader: ReaderFunc(func() (image.Image, func(), error) {
+				img, release, err := r.Read()
+				if err != nil {
+					return nil, func() {}, err
+				}
+
+				m, _ := MetadataOf(r)
+				src := from
+				if src.IsZero() {
+					src = m.ColorSpace
+				}
+				yuv, ok := asYCbCr(img)
+				if ok && !src.IsZero() && src != to {
+					convertYCbCr(&dst, yuv, src.toRGB().then(to.fromRGB()).fixed())
+					release()
+					img, release = &dst, func() {}
+				}
+				if ok {
+					m.ColorSpace = to
+				}
+				last.store(m)
+				return img, release, nil
+			}),
+			metadata: last.load,
+		}
+	}
+}
+
+// ToI420In converts frames to I420 in colorspace cs, where ToI420 converts RGB frames in
+// ColorSpaceJPEG. YCbCr frames are converted from the colorspace in their metadata if it's known, and
+// frame metadata reports cs.
+func ToI420In(cs ColorSpace) TransformFunc {
+	fromRGB := cs.fromRGB().fixed()
+	return func(r Reader) Reader {
+		var yuv image.YCbCr
+		converted := ConvertColorSpace(ColorSpace{}, cs)(KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			if _, ok := asYCbCr(img); ok {
+				return img, release, nil
+			}
+			rgbToYCbCr444(&yuv, img, &fromRGB)
+			release()
+			return &yuv, func() {}, nil
+		}), r))
+		return KeepMetadata(ToI420(converted), converted)
+	}
+}
+
+// ToRGBAFrom converts frames to RGBA, where YCbCr frames are converted from the colorspace in their
+// metadata, or from cs if it's unknown. ToRGBA converts them from ColorSpaceJPEG.
+func ToRGBAFrom(cs ColorSpace) TransformFunc {
+	return func(r Reader) Reader {
+		var dst image.RGBA
+		return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			yuv, ok := asYCbCr(img)
+			if !ok {
+				// RGBA frames aren't copied
+				imageToRGBA(&dst, img)
+				return &dst, release, nil
+			}
+			src := cs
+			if m, _ := MetadataOf(r); !m.ColorSpace.IsZero() {
+				src = m.ColorSpace
+			}
+			yCbCrToRGBA(&dst, yuv, src.toRGB().fixed())
+			release()
+			return &dst, func() {}, nil
+		}), r)
+	}
+}
+
+// asYCbCr returns img as *image.YCbCr, including images in device buffers that provide copies
+// in Go memory, e.g. DMABufImage.
+func asYCbCr(img image.Image) (*image.YCbCr, bool) {
+	if i, ok := img.(interface{ YCbCr() *image.YCbCr }); ok {
+		if yuv := i.YCbCr(); yuv != nil {
+			return yuv, true
+		}
+	}
+	yuv, ok := img.(*image.YCbCr)
+	return yuv, ok
+}
+
+// convertYCbCr converts src with m to dst in the same subsample ratio. Since the matrices mix luma and
+// chroma, each chroma sample is converted with the average luma of the pixels it covers.
+func convertYCbCr(dst, src *image.YCbCr, m affine) {
+	bounds := src.Rect
+	w, h := bounds.Dx(), bounds.Dy()
+	cw, ch := chromaSize(src.SubsampleRatio, bounds)
+	ySize, cSize := w*h, cw*ch
+	if cap(dst.Y) < ySize+2*cSize {
+		dst.Y = make([]uint8, ySize+2*cSize)
+	}
+	buf := dst.Y[:ySize+2*cSize]
+	dst.Y, dst.Cb, dst.Cr = buf[:ySize], buf[ySize:ySize+cSize], buf[ySize+cSize:]
+	dst.YStride, dst.CStride = w, cw
+	dst.SubsampleRatio = src.SubsampleRatio
+	dst.Rect = bounds
+
+	c0 := src.COffset(bounds.Min.X, bounds.Min.Y)
+	sums := make([]int, cSize)
+	counts := make([]int, cSize)
+	for yi := 0; yi < h; yi++ {
+		for xi := 0; xi < w; xi++ {
+			x, y := bounds.Min.X+xi, bounds.Min.Y+yi
+			ci := src.COffset(x, y)
+			luma := src.Y[src.YOffset(x, y)]
+			dst.Y[yi*w+xi], _, _ = m.apply(luma, src.Cb[ci], src.Cr[ci])
+
+			// Chroma sample index in dst
+			rel := ci - c0
+			i := rel/src.CStride*cw + rel%src.CStride
+			sums[i] += int(luma)
+			counts[i]++
+		}
+	}
+	for cy := 0; cy < ch; cy++ {
+		for cx := 0; cx < cw; cx++ {
+			i := cy*cw + cx
+			if counts[i] == 0 {
+				continue
+			}
+			si := c0 + cy*src.CStride + cx
+			_, dst.Cb[i], dst.Cr[i] = m.apply(uint8((sums[i]+counts[i]/2)/counts[i]), src.Cb[si], src.Cr[si])
+		}
+	}
+}
+
+// chromaSize returns the size of chroma planes that cover bounds.
+func chromaSize(ratio image.YCbCrSubsampleRatio, bounds image.Rectangle) (int, int) {
+	probe := image.YCbCr{SubsampleRatio: ratio, Rect: bounds, CStride: 1 << 20}
+	c0 := probe.COffset(bounds.Min.X, bounds.Min.Y)
+	cw := probe.COffset(bounds.Max.X-1, bounds.Min.Y) - c0 + 1
+	ch := (probe.COffset(bounds.Min.X, bounds.Max.Y-1)-c0)/probe.CStride + 1
+	return cw, ch
+}
+
+// rgbToYCbCr444 converts src to dst in I444 with m.
+func rgbToYCbCr444(dst *image.YCbCr, src image.Image, m *affine) {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(rgba, rgba.Rect, src, bounds.Min, draw.Src)
+	}
+
+	if cap(dst.Y) < 3*w*h {
+		dst.Y = make([]uint8, 3*w*h)
+	}
+	dst.Y = dst.Y[:3*w*h]
+	dst.Cb = dst.Y[w*h : 2*w*h]
+	dst.Cr = dst.Y[2*w*h : 3*w*h]
+	dst.Y = dst.Y[:w*h]
+	dst.YStride, dst.CStride = w, w
+	dst.SubsampleRatio = image.YCbCrSubsampleRatio444
+	dst.Rect = bounds
+
+	parallelRows(h, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			row := rgba.Pix[rgba.PixOffset(rgba.Rect.Min.X, rgba.Rect.Min.Y+y):]
+			for x := 0; x < w; x++ {
+				i := y*w + x
+				dst.Y[i], dst.Cb[i], dst.Cr[i] = m.apply(row[4*x], row[4*x+1], row[4*x+2])
+			}
+		}
+	})
+}
+
+// yCbCrToRGBA converts src to dst with m.
+func yCbCrToRGBA(dst *image.RGBA, src *image.YCbCr, m affine) {
+	bounds := src.Rect
+	w, h := bounds.Dx(), bounds.Dy()
+	if len(dst.Pix) < 4*w*h {
+		dst.Pix = make([]uint8, 4*w*h)
+	}
+	dst.Stride = 4 * w
+	dst.Rect = bounds
+
+	parallelRows(h, func(y0, y1 int) {
+		for yi := y0; yi < y1; yi++ {
+			for xi := 0; xi < w; xi++ {
+				x, y := bounds.Min.X+xi, bounds.Min.Y+yi
+				ci := src.COffset(x, y)
+				i := 4 * (yi*w + xi)
+				dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2] = m.apply(src.Y[src.YOffset(x, y)], src.Cb[ci], src.Cr[ci])
+				dst.Pix[i+3] = 0xff
+			}
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func near(a, b, tolerance uint8) bool {
+	return abs(int(a)-int(b)) <= int(tolerance)
+}
+
+func TestColorSpaceFromRGB(t *testing.T) {
+	cases := map[string]struct {
+		cs       ColorSpace
+		rgb      [3]uint8
+		expected [3]uint8
+	}{
+		"BT601White":   {ColorSpaceBT601, [3]uint8{255, 255, 255}, [3]uint8{235, 128, 128}},
+		"BT601Black":   {ColorSpaceBT601, [3]uint8{0, 0, 0}, [3]uint8{16, 128, 128}},
+		"BT601Red":     {ColorSpaceBT601, [3]uint8{255, 0, 0}, [3]uint8{81, 90, 240}},
+		"BT709Red":     {ColorSpaceBT709, [3]uint8{255, 0, 0}, [3]uint8{63, 102, 240}},
+		"BT709FullRed": {ColorSpace{"bt709", "full"}, [3]uint8{255, 0, 0}, [3]uint8{54, 99, 255}},
+	}
+	for name, c := range cases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			m := c.cs.fromRGB().fixed()
+			y, cb, cr := m.apply(c.rgb[0], c.rgb[1], c.rgb[2])
+			if !near(y, c.expected[0], 1) || !near(cb, c.expected[1], 1) || !near(cr, c.expected[2], 1) {
+				t.Fatalf("expected %v, but got %v", c.expected, [3]uint8{y, cb, cr})
+			}
+
+			m = c.cs.toRGB().fixed()
+			r, g, b := m.apply(y, cb, cr)
+			if !near(r, c.rgb[0], 2) || !near(g, c.rgb[1], 2) || !near(b, c.rgb[2], 2) {
+				t.Fatalf("expected %v back, but got %v", c.rgb, [3]uint8{r, g, b})
+			}
+		})
+	}
+
+	// The JPEG colorspace is image/color's
+	m := ColorSpaceJPEG.fromRGB().fixed()
+	for _, rgb := range [][3]uint8{{255, 0, 0}, {10, 200, 30}, {128, 128, 128}} {
+		y, cb, cr := m.apply(rgb[0], rgb[1], rgb[2])
+		ey, ecb, ecr := color.RGBToYCbCr(rgb[0], rgb[1], rgb[2])
+		if !near(y, ey, 1) || !near(cb, ecb, 1) || !near(cr, ecr, 1) {
+			t.Fatalf("expected %v for %v, but got %v", [3]uint8{ey, ecb, ecr}, rgb, [3]uint8{y, cb, cr})
+		}
+	}
+}
+
+func TestColorSpaceOf(t *testing.T) {
+	if cs := ColorSpaceOf(prop.Video{}); !cs.IsZero() {
+		t.Fatalf("expected the unknown colorspace, but got %v", cs)
+	}
+	if cs := ColorSpaceOf(prop.Video{ColorMatrix: prop.ColorMatrixBT709}); cs != ColorSpaceBT709 {
+		t.Fatalf("expected %v, but got %v", ColorSpaceBT709, cs)
+	}
+}
+
+func TestToI420In(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := 0; i < len(src.Pix); i += 4 {
+		src.Pix[i], src.Pix[i+3] = 255, 255
+	}
+	r := ToI420In(ColorSpaceBT709)(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	}))
+
+	img, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv, ok := img.(*image.YCbCr)
+	if !ok || yuv.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+		t.Fatalf("expected an I420 frame, but got %T", img)
+	}
+	if !near(yuv.Y[0], 63, 1) || !near(yuv.Cb[0], 102, 1) || !near(yuv.Cr[0], 240, 1) {
+		t.Fatalf("expected red in BT.709, but got %v", yuv.YCbCrAt(0, 0))
+	}
+	if m, _ := MetadataOf(r); m.ColorSpace != ColorSpaceBT709 {
+		t.Fatalf("expected the metadata to report %v, but got %v", ColorSpaceBT709, m.ColorSpace)
+	}
+}
+
+func TestConvertColorSpace(t *testing.T) {
+	// Green in BT.601 limited range, in 4:2:0 with an odd size
+	src := image.NewYCbCr(image.Rect(0, 0, 5, 3), image.YCbCrSubsampleRatio420)
+	m := ColorSpaceBT601.fromRGB().fixed()
+	y, cb, cr := m.apply(0, 255, 0)
+	for i := range src.Y {
+		src.Y[i] = y
+	}
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = cb, cr
+	}
+
+	frames := ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	})
+	converted := ConvertColorSpace(ColorSpaceBT601, ColorSpaceBT709)(frames)
+	img, _, err := converted.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv := img.(*image.YCbCr)
+	if yuv.Rect != src.Rect || len(yuv.Cb) != len(src.Cb) {
+		t.Fatalf("expected the size and the subsample ratio to be kept, but got %v with %d chroma samples", yuv.Rect, len(yuv.Cb))
+	}
+	m = ColorSpaceBT709.fromRGB().fixed()
+	ey, ecb, ecr := m.apply(0, 255, 0)
+	got := yuv.YCbCrAt(4, 2)
+	if !near(got.Y, ey, 2) || !near(got.Cb, ecb, 2) || !near(got.Cr, ecr, 2) {
+		t.Fatalf("expected %v, but got %v", [3]uint8{ey, ecb, ecr}, got)
+	}
+
+	// ToRGBAFrom reads converted frames' metadata
+	rgba, _, err := ToRGBAFrom(ColorSpaceBT601)(converted).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := rgba.(*image.RGBA).RGBAAt(4, 2); !near(c.R, 0, 3) || !near(c.G, 255, 3) || !near(c.B, 0, 3) {
+		t.Fatalf("expected green back, but got %v", c)
+	}
+}
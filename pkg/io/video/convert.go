@@ -13,6 +13,13 @@ func imageToYCbCr(dst *image.YCbCr, src image.Image) {
 		panic("dst can't be nil")
 	}
 
+	// Images in device buffers, e.g. DMABufImage, provide copies in Go memory.
+	if i, ok := src.(interface{ YCbCr() *image.YCbCr }); ok {
+		if img := i.YCbCr(); img != nil {
+			src = img
+		}
+	}
+
 	yuvImg, ok := src.(*image.YCbCr)
 	if ok {
 		*dst = *yuvImg
@@ -43,47 +50,65 @@ func imageToYCbCr(dst *image.YCbCr, src image.Image) {
 	switch s := src.(type) {
 	case *image.RGBA:
 		rgbaToI444(dst, s)
+	case *image.NRGBA:
+		// Straight colors are kept in transparent regions, see Unpremultiply.
+		rgbaToI444(dst, (*image.RGBA)(s))
 	default:
-		i := 0
-		for yi := 0; yi < dy; yi++ {
-			for xi := 0; xi < dx; xi++ {
-				// TODO: probably try to get the alpha value with something like
-				// https://en.wikipedia.org/wiki/Alpha_compositing
-				r, g, b, _ := src.At(xi, yi).RGBA()
-				yy, cb, cr := color.RGBToYCbCr(uint8(r/256), uint8(g/256), uint8(b/256))
-				dst.Y[i] = yy
-				dst.Cb[i] = cb
-				dst.Cr[i] = cr
-				i++
+		parallelRows(dy, func(y0, y1 int) {
+			i := dx * y0
+			for yi := y0; yi < y1; yi++ {
+				for xi := 0; xi < dx; xi++ {
+					// TODO: probably try to get the alpha value with something like
+					// https://en.wikipedia.org/wiki/Alpha_compositing
+					r, g, b, _ := src.At(bounds.Min.X+xi, bounds.Min.Y+yi).RGBA()
+					yy, cb, cr := color.RGBToYCbCr(uint8(r/256), uint8(g/256), uint8(b/256))
+					dst.Y[i] = yy
+					dst.Cb[i] = cb
+					dst.Cr[i] = cr
+					i++
+				}
 			}
-		}
+		})
 	}
 }
 
 // ToI420 converts r to a new reader that will output images in I420 format
 func ToI420(r Reader) Reader {
-	var yuvImg image.YCbCr
+	var yuvImg, chroma image.YCbCr
+	var converter chromaConverter
 	return ReaderFunc(func() (image.Image, func(), error) {
-		img, _, err := r.Read()
+		img, release, err := r.Read()
 		if err != nil {
 			return nil, func() {}, err
 		}
 
+		_, isYCbCr := asYCbCr(img)
 		imageToYCbCr(&yuvImg, img)
+		if yuvImg.SubsampleRatio == image.YCbCrSubsampleRatio420 {
+			// yuvImg points into the frame's planes
+			return &yuvImg, release, nil
+		}
 
-		// Covert pixel format to I420
-		switch yuvImg.SubsampleRatio {
-		case image.YCbCrSubsampleRatio444:
+		// Frames converted to I444 in the reader's buffer are subsampled in place. YCbCr frame
+		// chroma is converted into another buffer, since other readers may share the frames,
+		// and so are odd sizes, whose last chroma samples would be dropped in place.
+		bounds := yuvImg.Rect
+		if !isYCbCr && bounds.Dx()%2 == 0 && bounds.Dy()%2 == 0 {
+			release()
 			i444ToI420(&yuvImg)
-		case image.YCbCrSubsampleRatio422:
-			i422ToI420(&yuvImg)
-		case image.YCbCrSubsampleRatio420:
-		default:
+			yuvImg.SubsampleRatio = image.YCbCrSubsampleRatio420
+			return &yuvImg, func() {}, nil
+		}
+		if err := converter.convert(&chroma, &yuvImg, image.YCbCrSubsampleRatio420); err != nil {
+			release()
 			return nil, func() {}, fmt.Errorf("unsupported pixel format: %s", yuvImg.SubsampleRatio)
 		}
-
-		yuvImg.SubsampleRatio = image.YCbCrSubsampleRatio420
-		return &yuvImg, func() {}, nil
+		if !isYCbCr {
+			release()
+			return &chroma, func() {}, nil
+		}
+		// The luma of chroma points into the frame
+		return &chroma, release, nil
 	})
 }
 
@@ -114,17 +139,19 @@ func imageToRGBA(dst *image.RGBA, src image.Image) {
 		return
 	}
 
-	i := 0
-	for yi := 0; yi < dy; yi++ {
-		for xi := 0; xi < dx; xi++ {
-			r, g, b, a := src.At(xi, yi).RGBA()
-			dst.Pix[i+0] = uint8(r / 0x100)
-			dst.Pix[i+1] = uint8(g / 0x100)
-			dst.Pix[i+2] = uint8(b / 0x100)
-			dst.Pix[i+3] = uint8(a / 0x100)
-			i += 4
+	parallelRows(dy, func(y0, y1 int) {
+		i := 4 * dx * y0
+		for yi := y0; yi < y1; yi++ {
+			for xi := 0; xi < dx; xi++ {
+				r, g, b, a := src.At(bounds.Min.X+xi, bounds.Min.Y+yi).RGBA()
+				dst.Pix[i+0] = uint8(r / 0x100)
+				dst.Pix[i+1] = uint8(g / 0x100)
+				dst.Pix[i+2] = uint8(b / 0x100)
+				dst.Pix[i+3] = uint8(a / 0x100)
+				i += 4
+			}
 		}
-	}
+	})
 }
 
 // ToRGBA converts r to a new reader that will output images in RGBA format
@@ -33,28 +33,6 @@ void i444ToI420CGO(
   }
 }
 
-void i422ToI420CGO(
-    unsigned char* cb,
-    unsigned char* cr,
-    const int stride, const int h)
-{
-  int isrc = 0;
-  int idst = 0;
-  for (int y = 0; y < h / 2; y++)
-  {
-    for (int x = 0; x < stride; x++)
-    {
-      const uint8_t cb2 = ((uint16_t)cb[isrc] + (uint16_t)cb[isrc + stride]) / 2;
-      const uint8_t cr2 = ((uint16_t)cr[isrc] + (uint16_t)cr[isrc + stride]) / 2;
-      cb[idst] = cb2;
-      cr[idst] = cr2;
-      isrc++;
-      idst++;
-    }
-    isrc += stride;
-  }
-}
-
 void rgbToYCbCrCGO(
     unsigned char* y,
     unsigned char* cb,
@@ -144,14 +122,22 @@ void i444ToRGBACGO(
     const unsigned char* y,
     const unsigned char* cb,
     const unsigned char* cr,
-    const int stride, const int h)
+    const int w, const int h,
+    const int rgbStride, const int yStride, const int cStride)
 {
-  int i;
-  for (i = 0; i < stride * h; ++i)
+  int i, j;
+  for (j = 0; j < h; ++j)
   {
-    yCbCrToRGBCGO(rgb, rgb + 1, rgb + 2, y[i], cb[i], cr[i]);
-    rgb[3] = 0xFF;
-    rgb += 4;
+    unsigned char* d = rgb + j * rgbStride;
+    const unsigned char* sy = y + j * yStride;
+    const unsigned char* scb = cb + j * cStride;
+    const unsigned char* scr = cr + j * cStride;
+    for (i = 0; i < w; ++i)
+    {
+      yCbCrToRGBCGO(d, d + 1, d + 2, sy[i], scb[i], scr[i]);
+      d[3] = 0xFF;
+      d += 4;
+    }
   }
 }
 
@@ -160,12 +146,20 @@ void rgbaToI444(
     unsigned char* cb,
     unsigned char* cr,
     const unsigned char* rgb,
-    const int stride, const int h)
+    const int w, const int h,
+    const int yStride, const int cStride, const int rgbStride)
 {
-  int i;
-  for (i = 0; i < stride * h; ++i)
+  int i, j;
+  for (j = 0; j < h; ++j)
   {
-    rgbToYCbCrCGO(&y[i], &cb[i], &cr[i], rgb[0], rgb[1], rgb[2]);
-    rgb += 4;
+    unsigned char* dy = y + j * yStride;
+    unsigned char* dcb = cb + j * cStride;
+    unsigned char* dcr = cr + j * cStride;
+    const unsigned char* s = rgb + j * rgbStride;
+    for (i = 0; i < w; ++i)
+    {
+      rgbToYCbCrCGO(&dy[i], &dcb[i], &dcr[i], s[0], s[1], s[2]);
+      s += 4;
+    }
   }
 }
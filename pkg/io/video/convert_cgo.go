@@ -26,17 +26,6 @@ func i444ToI420(img *image.YCbCr) {
 	img.Cr = img.Cr[:cLen]
 }
 
-func i422ToI420(img *image.YCbCr) {
-	h := img.Rect.Dy()
-	C.i422ToI420CGO(
-		(*C.uchar)(&img.Cb[0]), (*C.uchar)(&img.Cr[0]),
-		C.int(img.CStride), C.int(h),
-	)
-	cLen := img.CStride * (h / 2)
-	img.Cb = img.Cb[:cLen]
-	img.Cr = img.Cr[:cLen]
-}
-
 func rgbToYCbCrCGO(y, cb, cr *uint8, r, g, b uint8) { // For testing
 	C.rgbToYCbCrCGO(
 		(*C.uchar)(y), (*C.uchar)(cb), (*C.uchar)(cr),
@@ -68,23 +57,31 @@ func repeatYCbCrToRGBCGO(n int, r, g, b *uint8, y, cb, cr uint8) { // For testin
 }
 
 func i444ToRGBA(dst *image.RGBA, src *image.YCbCr) {
-	C.i444ToRGBACGO(
-		(*C.uchar)(&dst.Pix[0]),
-		(*C.uchar)(&src.Y[0]),
-		(*C.uchar)(&src.Cb[0]),
-		(*C.uchar)(&src.Cr[0]),
-		C.int(src.Rect.Dx()),
-		C.int(src.Rect.Dy()),
-	)
+	d := rgbaPlane(dst)
+	y, cb, cr := yCbCrPlanes(src)
+	parallelRows(y.height, func(y0, y1 int) {
+		C.i444ToRGBACGO(
+			(*C.uchar)(&d.pix[d.stride*y0]),
+			(*C.uchar)(&y.pix[y.stride*y0]),
+			(*C.uchar)(&cb.pix[cb.stride*y0]),
+			(*C.uchar)(&cr.pix[cr.stride*y0]),
+			C.int(y.width), C.int(y1-y0),
+			C.int(d.stride), C.int(y.stride), C.int(cb.stride),
+		)
+	})
 }
 
 func rgbaToI444(dst *image.YCbCr, src *image.RGBA) {
-	C.rgbaToI444(
-		(*C.uchar)(&dst.Y[0]),
-		(*C.uchar)(&dst.Cb[0]),
-		(*C.uchar)(&dst.Cr[0]),
-		(*C.uchar)(&src.Pix[0]),
-		C.int(src.Rect.Dx()),
-		C.int(src.Rect.Dy()),
-	)
+	s := rgbaPlane(src)
+	y, cb, cr := yCbCrPlanes(dst)
+	parallelRows(y.height, func(y0, y1 int) {
+		C.rgbaToI444(
+			(*C.uchar)(&y.pix[y.stride*y0]),
+			(*C.uchar)(&cb.pix[cb.stride*y0]),
+			(*C.uchar)(&cr.pix[cr.stride*y0]),
+			(*C.uchar)(&s.pix[s.stride*y0]),
+			C.int(y.width), C.int(y1-y0),
+			C.int(y.stride), C.int(cb.stride), C.int(s.stride),
+		)
+	})
 }
@@ -3,11 +3,6 @@ void i444ToI420CGO(
     unsigned char* cr,
     const int stride, const int h);
 
-void i422ToI420CGO(
-    unsigned char* cb,
-    unsigned char* cr,
-    const int stride, const int h);
-
 void rgbToYCbCrCGO(
     unsigned char* y,
     unsigned char* cb,
@@ -47,11 +42,13 @@ void i444ToRGBACGO(
     const unsigned char* y,
     const unsigned char* cb,
     const unsigned char* cr,
-    const int stride, const int h);
+    const int w, const int h,
+    const int rgbStride, const int yStride, const int cStride);
 
 void rgbaToI444(
     unsigned char* y,
     unsigned char* cb,
     unsigned char* cr,
     const unsigned char* rgb,
-    const int stride, const int h);
+    const int w, const int h,
+    const int yStride, const int cStride, const int rgbStride);
@@ -35,55 +35,34 @@ func i444ToI420(img *image.YCbCr) {
 	img.Cr = img.Cr[:cLen]
 }
 
-func i422ToI420(img *image.YCbCr) {
-	h := img.Rect.Dy()
-	addrSrc := 0
-	addrDst := 0
-	for i := 0; i < h/2; i++ {
-		for j := 0; j < img.CStride; j++ {
-			cb := uint16(img.Cb[addrSrc]) + uint16(img.Cb[addrSrc+img.CStride])
-			cr := uint16(img.Cr[addrSrc]) + uint16(img.Cr[addrSrc+img.CStride])
-			img.Cb[addrDst] = uint8(cb / 2)
-			img.Cr[addrDst] = uint8(cr / 2)
-			addrDst++
-			addrSrc++
-		}
-		addrSrc += img.CStride
-	}
-	cLen := img.CStride * (h / 2)
-	img.Cb = img.Cb[:cLen]
-	img.Cr = img.Cr[:cLen]
-}
-
 func i444ToRGBA(dst *image.RGBA, src *image.YCbCr) {
-	dx := src.Rect.Dx()
-	dy := src.Rect.Dy()
-	i := 0
-	j := 0
-	for yi := 0; yi < dy; yi++ {
-		for xi := 0; xi < dx; xi++ {
-			r, g, b := color.YCbCrToRGB(src.Y[j], src.Cb[j], src.Cr[j])
-			dst.Pix[i+0] = uint8(r)
-			dst.Pix[i+1] = uint8(g)
-			dst.Pix[i+2] = uint8(b)
-			dst.Pix[i+3] = 0xff
-			i += 4
+	d := rgbaPlane(dst)
+	y, cb, cr := yCbCrPlanes(src)
+	parallelRows(y.height, func(y0, y1 int) {
+		for yi := y0; yi < y1; yi++ {
+			dRow, yRow, cbRow, crRow := d.row(yi), y.row(yi), cb.row(yi), cr.row(yi)
+			for xi := range yRow {
+				r, g, b := color.YCbCrToRGB(yRow[xi], cbRow[xi], crRow[xi])
+				dRow[4*xi+0] = uint8(r)
+				dRow[4*xi+1] = uint8(g)
+				dRow[4*xi+2] = uint8(b)
+				dRow[4*xi+3] = 0xff
+			}
 		}
-	}
+	})
 }
 
 func rgbaToI444(dst *image.YCbCr, src *image.RGBA) {
-	i := 0
-	addr := 0
-	dx := src.Rect.Dx()
-	dy := src.Rect.Dy()
-	for yi := 0; yi < dy; yi++ {
-		for xi := 0; xi < dx; xi++ {
-			dst.Y[i], dst.Cb[i], dst.Cr[i] = color.RGBToYCbCr(
-				src.Pix[addr+0], src.Pix[addr+1], src.Pix[addr+2],
-			)
-			addr += 4
-			i++
+	s := rgbaPlane(src)
+	y, cb, cr := yCbCrPlanes(dst)
+	parallelRows(y.height, func(y0, y1 int) {
+		for yi := y0; yi < y1; yi++ {
+			sRow, yRow, cbRow, crRow := s.row(yi), y.row(yi), cb.row(yi), cr.row(yi)
+			for xi := range yRow {
+				yRow[xi], cbRow[xi], crRow[xi] = color.RGBToYCbCr(
+					sRow[4*xi+0], sRow[4*xi+1], sRow[4*xi+2],
+				)
+			}
 		}
-	}
+	})
 }
@@ -229,11 +229,13 @@ func BenchmarkToI420(b *testing.B) {
 						return img, func() {}, nil
 					}))
 
+					b.ReportAllocs()
 					for i := 0; i < b.N; i++ {
-						_, _, err := r.Read()
+						_, release, err := r.Read()
 						if err != nil {
 							b.Fatalf("Unexpected error: %v", err)
 						}
+						release()
 					}
 				})
 			}
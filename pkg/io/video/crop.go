@@ -0,0 +1,45 @@
+package video
+
+import (
+	"image"
+
+	"golang.org/x/image/draw"
+)
+
+// Crop returns a video cropping transform, which cuts rect out of frames. Cropped frames' origin
+// is (0, 0). If rect is partially out of the frame, the intersection is used.
+//
+// Pixels are copied into a buffer that's reused for the next frame, so a frame is valid only until
+// the next frame is read.
+func Crop(rect image.Rectangle) TransformFunc {
+	return func(r Reader) Reader {
+		dst := &image.RGBA{}
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, _, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			sRect := rect.Intersect(img.Bounds())
+			w, h := sRect.Dx(), sRect.Dy()
+			if l := w * h * 4; cap(dst.Pix) < l {
+				dst.Pix = make([]uint8, l)
+			} else {
+				dst.Pix = dst.Pix[:l]
+			}
+			dst.Stride = w * 4
+			dst.Rect = image.Rect(0, 0, w, h)
+
+			if src, ok := img.(*image.RGBA); ok {
+				for y := 0; y < h; y++ {
+					i := src.PixOffset(sRect.Min.X, sRect.Min.Y+y)
+					copy(dst.Pix[y*dst.Stride:(y+1)*dst.Stride], src.Pix[i:i+dst.Stride])
+				}
+				return dst, func() {}, nil
+			}
+
+			draw.Draw(dst, dst.Rect, img, sRect.Min, draw.Src)
+			return dst, func() {}, nil
+		})
+	}
+}
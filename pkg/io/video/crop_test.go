@@ -0,0 +1,77 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCrop(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			src.SetRGBA(x, y, color.RGBA{R: uint8(x), G: uint8(y), A: 0xFF})
+		}
+	}
+	gray := image.NewGray(src.Rect)
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i)
+	}
+
+	testCases := map[string]struct {
+		src      image.Image
+		rect     image.Rectangle
+		expected image.Rectangle
+		at       func(x, y int) color.Color
+	}{
+		"RGBA": {
+			src:      src,
+			rect:     image.Rect(1, 2, 3, 4),
+			expected: image.Rect(0, 0, 2, 2),
+			at: func(x, y int) color.Color {
+				return color.RGBA{R: uint8(x + 1), G: uint8(y + 2), A: 0xFF}
+			},
+		},
+		"PartiallyOut": {
+			src:      src,
+			rect:     image.Rect(2, -1, 6, 1),
+			expected: image.Rect(0, 0, 2, 1),
+			at: func(x, y int) color.Color {
+				return color.RGBA{R: uint8(x + 2), G: uint8(y), A: 0xFF}
+			},
+		},
+		"Gray": {
+			src:      gray,
+			rect:     image.Rect(1, 1, 3, 3),
+			expected: image.Rect(0, 0, 2, 2),
+			at: func(x, y int) color.Color {
+				return color.RGBAModel.Convert(color.Gray{Y: uint8((y+1)*4 + x + 1)})
+			},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			r := Crop(c.rect)(ReaderFunc(func() (image.Image, func(), error) {
+				return c.src, func() {}, nil
+			}))
+			for i := 0; i < 2; i++ {
+				img, _, err := r.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if img.Bounds() != c.expected {
+					t.Fatalf("expected the bounds to be %v, but got %v", c.expected, img.Bounds())
+				}
+				for y := 0; y < c.expected.Dy(); y++ {
+					for x := 0; x < c.expected.Dx(); x++ {
+						expected := c.at(x, y)
+						if actual := color.RGBAModel.Convert(img.At(x, y)); actual != expected {
+							t.Fatalf("expected %v at (%d, %d), but got %v", expected, x, y, actual)
+						}
+					}
+				}
+			}
+		})
+	}
+}
@@ -58,3 +58,56 @@ func DetectChanges(interval time.Duration, fpsDiffTolerance float64, onChange fu
 		})
 	}
 }
+
+// Detector finds objects like barcodes in frames passed by Detect, and reports results through its own
+// callback. The frame is only valid during the call, so it must not be kept after Detect returns.
+type Detector interface {
+	Detect(img image.Image)
+}
+
+const defaultDetectInterval = 5
+
+type detectConfig struct {
+	interval int
+}
+
+// DetectOption configures Detect.
+type DetectOption func(*detectConfig)
+
+// WithDetectInterval sets how many frames are skipped between frames passed to the detector.
+// The default is 5, i.e. 6 frames per second for 30 fps video.
+func WithDetectInterval(frames int) DetectOption {
+	return func(c *detectConfig) {
+		c.interval = frames
+	}
+}
+
+// Detect passes frames to detector at a decimated rate without copying them. Detection runs in the
+// reading goroutine, so the interval should be long enough for the detector to keep up with the frame rate.
+func Detect(detector Detector, opts ...DetectOption) TransformFunc {
+	c := detectConfig{
+		interval: defaultDetectInterval,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.interval < 1 {
+		c.interval = 1
+	}
+
+	return func(r Reader) Reader {
+		var frames int
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			if frames%c.interval == 0 {
+				detector.Detect(img)
+			}
+			frames++
+			return img, release, nil
+		})
+	}
+}
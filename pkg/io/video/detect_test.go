@@ -204,3 +204,44 @@ func TestDetectChanges(t *testing.T) {
 		}
 	})
 }
+
+type detectorFunc func(image.Image)
+
+func (f detectorFunc) Detect(img image.Image) {
+	f(img)
+}
+
+func TestDetect(t *testing.T) {
+	var frames, released int
+	var src Reader
+	src = ReaderFunc(func() (image.Image, func(), error) {
+		frames++
+		return image.NewGray(image.Rect(0, 0, frames, 1)), func() { released++ }, nil
+	})
+
+	var detected []int
+	src = Detect(detectorFunc(func(img image.Image) {
+		detected = append(detected, img.Bounds().Dx())
+	}), WithDetectInterval(3))(src)
+
+	for i := 0; i < 7; i++ {
+		_, release, err := src.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	expected := []int{1, 4, 7}
+	if len(detected) != len(expected) {
+		t.Fatalf("expected the frames %v to be detected, but got %v", expected, detected)
+	}
+	for i := range expected {
+		if detected[i] != expected[i] {
+			t.Fatalf("expected the frames %v to be detected, but got %v", expected, detected)
+		}
+	}
+	if released != 7 {
+		t.Fatalf("expected all frames to be released, but got %d", released)
+	}
+}
@@ -0,0 +1,35 @@
+package video
+
+import (
+	"image"
+
+	"github.com/pion/mediadevices/pkg/frame"
+)
+
+// DMABufPlane is the layout of a frame plane in a DMA-BUF.
+type DMABufPlane struct {
+	// Offset is the plane's offset from the buffer start in bytes.
+	Offset int
+	// Pitch is the size of a plane line in bytes.
+	Pitch int
+}
+
+// DMABuf describes a frame stored in a DMA-BUF, e.g. a V4L2 capture buffer.
+type DMABuf struct {
+	// FD is the DMA-BUF's file descriptor, which the source owns.
+	FD     int
+	Format frame.Format
+	Width  int
+	Height int
+	Planes []DMABufPlane
+	// Size is the buffer size in bytes.
+	Size int
+}
+
+// DMABufImage is an image stored in a DMA-BUF. Hardware encoders import the buffer directly, and
+// pixels are copied into Go memory only when they're accessed, e.g. by a software encoder or a transform.
+// The source reuses the buffer, so it's valid only until the next frame is read from the source.
+type DMABufImage interface {
+	image.Image
+	ExportDMABuf() DMABuf
+}
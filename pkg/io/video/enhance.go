@@ -0,0 +1,286 @@
+package video
+
+import (
+	"image"
+	"math"
+)
+
+type enhanceKind int
+
+const (
+	enhanceAuto enhanceKind = iota
+	enhanceGamma
+	enhanceCLAHE
+)
+
+// EnhanceMode is how Enhance brightens frames.
+type EnhanceMode struct {
+	kind  enhanceKind
+	gamma float64
+}
+
+var (
+	// EnhanceAuto brightens dark frames with a gamma that lifts their mean luma toward middle gray, and leaves bright
+	// frames alone. The gamma is smoothed across frames so it doesn't flicker.
+	EnhanceAuto = EnhanceMode{kind: enhanceAuto}
+	// EnhanceCLAHE runs contrast limited adaptive histogram equalization (CLAHE) on tiles of the frame. It brings out
+	// detail in both dark and bright regions, e.g. a backlit face, without amplifying noise in flat regions.
+	EnhanceCLAHE = EnhanceMode{kind: enhanceCLAHE}
+)
+
+// EnhanceGamma brightens frames with a fixed gamma, mapping luma y in [0, 1] to y^(1/gamma). E.g. 2 lifts
+// shadows much more than highlights. A gamma of 1 or less leaves frames unchanged.
+func EnhanceGamma(gamma float64) EnhanceMode {
+	return EnhanceMode{kind: enhanceGamma, gamma: gamma}
+}
+
+const (
+	// enhanceAutoTarget is the mean luma EnhanceAuto lifts dark frames to.
+	enhanceAutoTarget   = 0.4
+	enhanceAutoMaxGamma = 3
+	// enhanceAutoSmoothing is the share of each EnhanceAuto gamma change applied per frame.
+	enhanceAutoSmoothing = 0.1
+	// enhanceAutoStep is the pixel step for sampling the mean luma.
+	enhanceAutoStep = 4
+	// enhanceTiles is the number of CLAHE tiles in each direction.
+	enhanceTiles = 8
+	// enhanceClipLimit caps each CLAHE histogram bin, as a multiple of the average count, which limits contrast.
+	enhanceClipLimit = 3
+)
+
+// Enhance brightens dark scenes by adjusting frame luma, which is cheaper than sensor gain and amplifies less
+// noise. Chroma is preserved: YCbCr chroma planes are kept, and RGBA colors are scaled with their luma, so colors
+// aren't washed out.
+//
+// Adjusted frames are copied to a buffer that's reused for the next frame, so frames shared by a Broadcaster's
+// readers aren't modified. Frames must be RGBA or YCbCr.
+func Enhance(mode EnhanceMode) TransformFunc {
+	return func(r Reader) Reader {
+		var (
+			buf    = NewFrameBuffer(0)
+			lut    [256]uint8
+			lutFor float64
+			gamma  float64
+			tiles  claheTiles
+			luma   []uint8
+		)
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			if i, ok := img.(interface{ YCbCr() *image.YCbCr }); ok {
+				if yuv := i.YCbCr(); yuv != nil {
+					img = yuv
+				}
+			}
+			switch img.(type) {
+			case *image.RGBA, *image.YCbCr:
+			default:
+				release()
+				return nil, func() {}, errUnsupportedImageType
+			}
+
+			var remap func(x, y int, v uint8) uint8
+			switch mode.kind {
+			case enhanceCLAHE:
+				remap = tiles.remap
+			default:
+				g := mode.gamma
+				if mode.kind == enhanceAuto {
+					target := autoGamma(meanLuma(img))
+					if gamma == 0 {
+						gamma = target
+					}
+					gamma += (target - gamma) * enhanceAutoSmoothing
+					g = gamma
+				}
+				if g < 1.01 {
+					return img, release, nil
+				}
+				if g != lutFor {
+					lut, lutFor = gammaLUT(g), g
+				}
+				remap = func(_, _ int, v uint8) uint8 { return lut[v] }
+			}
+
+			buf.StoreCopy(img)
+			release()
+			switch dst := buf.Load().(type) {
+			case *image.RGBA:
+				p := rgbaPlane(dst)
+				w := p.width / 4
+				if cap(luma) < w*p.height {
+					luma = make([]uint8, w*p.height)
+				}
+				luma = luma[:w*p.height]
+				for y := 0; y < p.height; y++ {
+					row := p.row(y)
+					for x := 0; x < w; x++ {
+						luma[y*w+x] = rgbLuma(row[4*x], row[4*x+1], row[4*x+2])
+					}
+				}
+				if mode.kind == enhanceCLAHE {
+					tiles.equalize(pixPlane(luma, w, 0, w, p.height))
+				}
+				for y := 0; y < p.height; y++ {
+					row := p.row(y)
+					for x := 0; x < w; x++ {
+						scaleRGB(row[4*x:4*x+3], luma[y*w+x], remap(x, y, luma[y*w+x]))
+					}
+				}
+			case *image.YCbCr:
+				p, _, _ := yCbCrPlanes(dst)
+				if mode.kind == enhanceCLAHE {
+					tiles.equalize(p)
+				}
+				for y := 0; y < p.height; y++ {
+					row := p.row(y)
+					for x := range row {
+						row[x] = remap(x, y, row[x])
+					}
+				}
+			}
+			return buf.Load(), func() {}, nil
+		})
+	}
+}
+
+// meanLuma returns the mean luma of the sampled pixels of img, from 0 to 1.
+func meanLuma(img image.Image) float64 {
+	var sum, n int
+	switch src := img.(type) {
+	case *image.YCbCr:
+		p, _, _ := yCbCrPlanes(src)
+		for y := 0; y < p.height; y += enhanceAutoStep {
+			row := p.row(y)
+			for x := 0; x < len(row); x += enhanceAutoStep {
+				sum += int(row[x])
+				n++
+			}
+		}
+	case *image.RGBA:
+		p := rgbaPlane(src)
+		for y := 0; y < p.height; y += enhanceAutoStep {
+			row := p.row(y)
+			for x := 0; x < len(row); x += 4 * enhanceAutoStep {
+				sum += int(rgbLuma(row[x], row[x+1], row[x+2]))
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return 1
+	}
+	return float64(sum) / float64(n) / 255
+}
+
+// autoGamma returns the gamma that maps mean to enhanceAutoTarget, or 1 for bright frames.
+func autoGamma(mean float64) float64 {
+	if mean >= enhanceAutoTarget {
+		return 1
+	}
+	// Black frames, e.g. from a covered lens, stay black
+	mean = math.Max(mean, 1.0/255)
+	return math.Min(enhanceAutoMaxGamma, math.Log(mean)/math.Log(enhanceAutoTarget))
+}
+
+func gammaLUT(gamma float64) [256]uint8 {
+	var lut [256]uint8
+	for v := range lut {
+		lut[v] = uint8(255*math.Pow(float64(v)/255, 1/gamma) + 0.5)
+	}
+	return lut
+}
+
+func rgbLuma(r, g, b uint8) uint8 {
+	return uint8((299*int(r) + 587*int(g) + 114*int(b) + 500) / 1000)
+}
+
+// scaleRGB scales rgb from luma from to luma to, keeping the channel ratios.
+func scaleRGB(rgb []uint8, from, to uint8) {
+	if from == 0 {
+		rgb[0], rgb[1], rgb[2] = to, to, to
+		return
+	}
+	for i, c := range rgb {
+		v := (int(c)*int(to) + int(from)/2) / int(from)
+		if v > 255 {
+			v = 255
+		}
+		rgb[i] = uint8(v)
+	}
+}
+
+// claheTiles holds per-tile CLAHE lookup tables. Lookups are interpolated between tile centers so tile edges
+// aren't visible.
+type claheTiles struct {
+	luts          [enhanceTiles * enhanceTiles][256]uint8
+	width, height int
+}
+
+// equalize builds the tile lookup tables for p.
+func (t *claheTiles) equalize(p plane) {
+	t.width = (p.width + enhanceTiles - 1) / enhanceTiles
+	t.height = (p.height + enhanceTiles - 1) / enhanceTiles
+	var hist [256]int
+	for ty := 0; ty < enhanceTiles; ty++ {
+		for tx := 0; tx < enhanceTiles; tx++ {
+			rect := image.Rect(tx*t.width, ty*t.height, (tx+1)*t.width, (ty+1)*t.height).
+				Intersect(image.Rect(0, 0, p.width, p.height))
+			lut := &t.luts[ty*enhanceTiles+tx]
+			n := rect.Dx() * rect.Dy()
+			if n == 0 {
+				for v := range lut {
+					lut[v] = uint8(v)
+				}
+				continue
+			}
+
+			hist = [256]int{}
+			for y := rect.Min.Y; y < rect.Max.Y; y++ {
+				for _, v := range p.row(y)[rect.Min.X:rect.Max.X] {
+					hist[v]++
+				}
+			}
+			limit := enhanceClipLimit * n / 256
+			if limit < 1 {
+				limit = 1
+			}
+			var excess int
+			for v, c := range hist {
+				if c > limit {
+					excess += c - limit
+					hist[v] = limit
+				}
+			}
+			// Redistribute the clipped counts across all bins
+			var cdf int
+			for v, c := range hist {
+				cdf += c
+				lut[v] = uint8((cdf + excess*(v+1)/256) * 255 / n)
+			}
+		}
+	}
+}
+
+// remap returns luma v at (x, y), bilinearly interpolated from the nearest tiles' lookup tables.
+func (t *claheTiles) remap(x, y int, v uint8) uint8 {
+	tileAt := func(pos, size int) (int, int, float64) {
+		f := (float64(pos)+0.5)/float64(size) - 0.5
+		i := int(math.Floor(f))
+		w := f - float64(i)
+		if i < 0 {
+			return 0, 0, 0
+		}
+		if i >= enhanceTiles-1 {
+			return enhanceTiles - 1, enhanceTiles - 1, 0
+		}
+		return i, i + 1, w
+	}
+	x0, x1, wx := tileAt(x, t.width)
+	y0, y1, wy := tileAt(y, t.height)
+	top := float64(t.luts[y0*enhanceTiles+x0][v])*(1-wx) + float64(t.luts[y0*enhanceTiles+x1][v])*wx
+	bottom := float64(t.luts[y1*enhanceTiles+x0][v])*(1-wx) + float64(t.luts[y1*enhanceTiles+x1][v])*wx
+	return uint8(top*(1-wy) + bottom*wy + 0.5)
+}
@@ -0,0 +1,143 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+)
+
+func TestEnhanceGamma(t *testing.T) {
+	src := image.NewYCbCr(image.Rect(0, 0, 32, 16), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = 64
+	}
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = 100, 150
+	}
+	r := Enhance(EnhanceGamma(2))(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	}))
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.YCbCr)
+	if src.Y[0] != 64 {
+		t.Fatal("expected the source frame to be kept")
+	}
+	// 255 * (64/255)^(1/2)
+	if dst.Y[0] != 128 {
+		t.Fatalf("expected the luma of 128, but got %d", dst.Y[0])
+	}
+	if dst.Cb[0] != 100 || dst.Cr[0] != 150 {
+		t.Fatalf("expected the chroma to be kept, but got (%d, %d)", dst.Cb[0], dst.Cr[0])
+	}
+}
+
+func TestEnhanceRGBA(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			src.SetRGBA(x, y, color.RGBA{60, 30, 15, 255})
+		}
+	}
+	r := Enhance(EnhanceGamma(2))(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	}))
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := out.(*image.RGBA).RGBAAt(8, 8)
+	if c.R <= 60 || c.A != 255 {
+		t.Fatalf("expected the brighter color, but got %v", c)
+	}
+	// Channel ratios are kept
+	if d := int(c.R) - 2*int(c.G); d < -2 || d > 2 {
+		t.Fatalf("expected the ratio of red to green to be kept, but got %v", c)
+	}
+	if d := int(c.G) - 2*int(c.B); d < -2 || d > 2 {
+		t.Fatalf("expected the ratio of green to blue to be kept, but got %v", c)
+	}
+}
+
+func TestEnhanceAuto(t *testing.T) {
+	testCases := map[string]struct {
+		luma     uint8
+		brighter bool
+	}{
+		"Dark":   {luma: 30, brighter: true},
+		"Bright": {luma: 150, brighter: false},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			src := image.NewYCbCr(image.Rect(0, 0, 32, 16), image.YCbCrSubsampleRatio420)
+			for i := range src.Y {
+				src.Y[i] = c.luma
+			}
+			r := Enhance(EnhanceAuto)(ReaderFunc(func() (image.Image, func(), error) {
+				return src, func() {}, nil
+			}))
+			out, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dst := out.(*image.YCbCr)
+			if !c.brighter {
+				if dst != src {
+					t.Fatal("expected the bright frame to be kept")
+				}
+				return
+			}
+			// Mean luma is lifted to about 40% of the range
+			if dst.Y[0] < 90 || dst.Y[0] > 115 {
+				t.Fatalf("expected the luma of about 102, but got %d", dst.Y[0])
+			}
+		})
+	}
+}
+
+func TestEnhanceCLAHE(t *testing.T) {
+	// A dark, low contrast texture from 20 to 40
+	src := image.NewYCbCr(image.Rect(0, 0, 128, 128), image.YCbCrSubsampleRatio420)
+	rnd := rand.New(rand.NewSource(1))
+	for i := range src.Y {
+		src.Y[i] = uint8(20 + rnd.Intn(21))
+	}
+	r := Enhance(EnhanceCLAHE)(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	}))
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.YCbCr)
+	lo, hi := 255, 0
+	var sum int
+	for _, v := range dst.Y {
+		if int(v) < lo {
+			lo = int(v)
+		}
+		if int(v) > hi {
+			hi = int(v)
+		}
+		sum += int(v)
+	}
+	if hi-lo < 60 {
+		t.Fatalf("expected the contrast to be stretched, but got from %d to %d", lo, hi)
+	}
+	if mean := sum / len(dst.Y); mean <= 40 {
+		t.Fatalf("expected the frame to be brightened, but got the mean of %d", mean)
+	}
+}
+
+func TestEnhanceUnsupported(t *testing.T) {
+	r := Enhance(EnhanceAuto)(ReaderFunc(func() (image.Image, func(), error) {
+		return image.NewGray(image.Rect(0, 0, 16, 16)), func() {}, nil
+	}))
+	if _, _, err := r.Read(); err != errUnsupportedImageType {
+		t.Fatalf("expected %v, but got %v", errUnsupportedImageType, err)
+	}
+}
@@ -0,0 +1,360 @@
+// +build gles
+
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+#include <GLES2/gl2.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define INPUT_YCBCR 0
+#define INPUT_RGBA 1
+
+#define OUTPUT_RGBA 0
+#define OUTPUT_I420 1
+
+typedef struct Renderer {
+  EGLDisplay display;
+  EGLContext context;
+  EGLSurface surface;
+
+  GLuint vertexBuffer;
+  GLuint compositeProgram;
+  GLuint packProgram;
+
+  // Input planes, or RGBA input in planes[0]
+  GLuint planes[3];
+  GLuint overlay;
+  int hasOverlay;
+  float overlayRect[4];
+
+  // Composited frame at output size
+  GLuint compositeTexture;
+  GLuint compositeFramebuffer;
+  int compositeWidth, compositeHeight;
+
+  // Packed I420 frame, whose RGBA pixels are 4 bytes of the planes
+  GLuint packTexture;
+  GLuint packFramebuffer;
+  int packWidth, packHeight;
+} Renderer;
+
+static const char *vertexShader = "attribute vec2 pos;\n"
+                                  "varying vec2 uv;\n"
+                                  "void main() {\n"
+                                  "  uv = pos * 0.5 + 0.5;\n"
+                                  "  gl_Position = vec4(pos, 0.0, 1.0);\n"
+                                  "}\n";
+
+// Texture and framebuffer rows are in image order, so pixels are read back from the first row.
+// The overlay is premultiplied like image package images.
+static const char *compositeShader =
+    "#ifdef GL_FRAGMENT_PRECISION_HIGH\n"
+    "precision highp float;\n"
+    "#else\n"
+    "precision mediump float;\n"
+    "#endif\n"
+    "varying vec2 uv;\n"
+    "uniform sampler2D planeY, planeCb, planeCr, overlay;\n"
+    "uniform int rgbaInput, hasOverlay;\n"
+    "uniform vec4 overlayRect;\n"
+    "vec3 toRGB(float y, float cb, float cr) {\n"
+    "  y = 1.16438 * (y - 0.0625);\n"
+    "  cb -= 0.5;\n"
+    "  cr -= 0.5;\n"
+    "  return clamp(vec3(y + 1.59603 * cr, y - 0.39176 * cb - 0.81297 * cr, y + 2.01723 * cb), 0.0, 1.0);\n"
+    "}\n"
+    "void main() {\n"
+    "  vec3 rgb;\n"
+    "  if (rgbaInput == 1) {\n"
+    "    rgb = texture2D(planeY, uv).rgb;\n"
+    "  } else {\n"
+    "    rgb = toRGB(texture2D(planeY, uv).r, texture2D(planeCb, uv).r, texture2D(planeCr, uv).r);\n"
+    "  }\n"
+    "  if (hasOverlay == 1 && uv.x >= overlayRect.x && uv.x < overlayRect.z && uv.y >= overlayRect.y &&\n"
+    "      uv.y < overlayRect.w) {\n"
+    "    vec4 o = texture2D(overlay, (uv - overlayRect.xy) / (overlayRect.zw - overlayRect.xy));\n"
+    "    rgb = rgb * (1.0 - o.a) + o.rgb;\n"
+    "  }\n"
+    "  gl_FragColor = vec4(rgb, 1.0);\n"
+    "}\n";
+
+// Each pack pass fragment is 4 bytes of the I420 planes in order, so the frame is read back
+// in a single call. Chroma is sampled at each 2x2 block's center, which the linear filter averages.
+static const char *packShader =
+    "#ifdef GL_FRAGMENT_PRECISION_HIGH\n"
+    "precision highp float;\n"
+    "#else\n"
+    "precision mediump float;\n"
+    "#endif\n"
+    "uniform sampler2D frame;\n"
+    "uniform vec2 size;\n"
+    "float luma(vec3 c) { return 0.0625 + dot(c, vec3(0.25679, 0.50413, 0.09791)); }\n"
+    "float cb(vec3 c) { return 0.5 + dot(c, vec3(-0.14822, -0.29099, 0.43922)); }\n"
+    "float cr(vec3 c) { return 0.5 + dot(c, vec3(0.43922, -0.36779, -0.07143)); }\n"
+    "vec3 at(float x, float y) { return texture2D(frame, vec2(x, y) / size).rgb; }\n"
+    "void main() {\n"
+    "  float px = floor(gl_FragCoord.x);\n"
+    "  float py = floor(gl_FragCoord.y);\n"
+    "  float w = size.x;\n"
+    "  float h = size.y;\n"
+    "  if (py < h) {\n"
+    "    float x = px * 4.0 + 0.5;\n"
+    "    float y = py + 0.5;\n"
+    "    gl_FragColor = vec4(luma(at(x, y)), luma(at(x + 1.0, y)), luma(at(x + 2.0, y)), luma(at(x + 3.0, y)));\n"
+    "    return;\n"
+    "  }\n"
+    "  float offset = ((py - h) * (w / 4.0) + px) * 4.0;\n"
+    "  float planeSize = w * h / 4.0;\n"
+    "  bool isCr = offset >= planeSize;\n"
+    "  if (isCr) {\n"
+    "    offset -= planeSize;\n"
+    "  }\n"
+    "  float row = floor(offset / (w / 2.0));\n"
+    "  float col = offset - row * (w / 2.0);\n"
+    "  float y = row * 2.0 + 1.0;\n"
+    "  vec4 v;\n"
+    "  for (int i = 0; i < 4; i++) {\n"
+    "    vec3 c = at((col + float(i)) * 2.0 + 1.0, y);\n"
+    "    v[i] = isCr ? cr(c) : cb(c);\n"
+    "  }\n"
+    "  gl_FragColor = v;\n"
+    "}\n";
+
+static const char *compileShader(GLuint *shader, GLenum type, const char *src) {
+  static char log[512];
+  GLint ok;
+  *shader = glCreateShader(type);
+  glShaderSource(*shader, 1, &src, NULL);
+  glCompileShader(*shader);
+  glGetShaderiv(*shader, GL_COMPILE_STATUS, &ok);
+  if (!ok) {
+    glGetShaderInfoLog(*shader, sizeof(log), NULL, log);
+    return log;
+  }
+  return NULL;
+}
+
+static const char *linkProgram(GLuint *program, const char *fragment) {
+  static char log[512];
+  GLuint vs, fs;
+  GLint ok;
+  const char *err;
+  if ((err = compileShader(&vs, GL_VERTEX_SHADER, vertexShader)) != NULL) {
+    return err;
+  }
+  if ((err = compileShader(&fs, GL_FRAGMENT_SHADER, fragment)) != NULL) {
+    glDeleteShader(vs);
+    return err;
+  }
+  *program = glCreateProgram();
+  glAttachShader(*program, vs);
+  glAttachShader(*program, fs);
+  glBindAttribLocation(*program, 0, "pos");
+  glLinkProgram(*program);
+  glDeleteShader(vs);
+  glDeleteShader(fs);
+  glGetProgramiv(*program, GL_LINK_STATUS, &ok);
+  if (!ok) {
+    glGetProgramInfoLog(*program, sizeof(log), NULL, log);
+    return log;
+  }
+  return NULL;
+}
+
+static GLuint newTexture(GLint filter) {
+  GLuint t;
+  glGenTextures(1, &t);
+  glBindTexture(GL_TEXTURE_2D, t);
+  glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_MIN_FILTER, filter);
+  glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_MAG_FILTER, filter);
+  glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_WRAP_S, GL_CLAMP_TO_EDGE);
+  glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_WRAP_T, GL_CLAMP_TO_EDGE);
+  return t;
+}
+
+// gles_init creates a headless context, on Mesa's surfaceless platform if it's available.
+static const char *gles_init(Renderer *r) {
+  memset(r, 0, sizeof(*r));
+
+  r->display = EGL_NO_DISPLAY;
+  PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+      (PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+#ifdef EGL_PLATFORM_SURFACELESS_MESA
+  if (getPlatformDisplay != NULL) {
+    r->display = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+  }
+#endif
+  if (r->display == EGL_NO_DISPLAY) {
+    r->display = eglGetDisplay(EGL_DEFAULT_DISPLAY);
+  }
+  if (r->display == EGL_NO_DISPLAY || !eglInitialize(r->display, NULL, NULL)) {
+    return "failed to initialize the EGL display";
+  }
+
+  const EGLint configAttribs[] = {EGL_RENDERABLE_TYPE, EGL_OPENGL_ES2_BIT, EGL_SURFACE_TYPE, EGL_PBUFFER_BIT,
+                                  EGL_RED_SIZE,        8,                  EGL_GREEN_SIZE,   8,
+                                  EGL_BLUE_SIZE,       8,                  EGL_ALPHA_SIZE,   8,
+                                  EGL_NONE};
+  EGLConfig config;
+  EGLint n;
+  if (!eglChooseConfig(r->display, configAttribs, &config, 1, &n) || n == 0) {
+    return "failed to choose the EGL config";
+  }
+  if (!eglBindAPI(EGL_OPENGL_ES_API)) {
+    return "failed to bind OpenGL ES";
+  }
+  const EGLint contextAttribs[] = {EGL_CONTEXT_CLIENT_VERSION, 2, EGL_NONE};
+  r->context = eglCreateContext(r->display, config, EGL_NO_CONTEXT, contextAttribs);
+  if (r->context == EGL_NO_CONTEXT) {
+    return "failed to create the EGL context";
+  }
+  // Frames are rendered to framebuffers, so the surface is only needed by drivers that don't
+  // support surfaceless contexts.
+  const EGLint surfaceAttribs[] = {EGL_WIDTH, 1, EGL_HEIGHT, 1, EGL_NONE};
+  r->surface = eglCreatePbufferSurface(r->display, config, surfaceAttribs);
+  if (!eglMakeCurrent(r->display, r->surface, r->surface, r->context)) {
+    return "failed to make the EGL context current";
+  }
+
+  const char *err;
+  if ((err = linkProgram(&r->compositeProgram, compositeShader)) != NULL) {
+    return err;
+  }
+  if ((err = linkProgram(&r->packProgram, packShader)) != NULL) {
+    return err;
+  }
+
+  const GLfloat quad[] = {-1, -1, 1, -1, -1, 1, 1, 1};
+  glGenBuffers(1, &r->vertexBuffer);
+  glBindBuffer(GL_ARRAY_BUFFER, r->vertexBuffer);
+  glBufferData(GL_ARRAY_BUFFER, sizeof(quad), quad, GL_STATIC_DRAW);
+
+  for (int i = 0; i < 3; i++) {
+    r->planes[i] = newTexture(GL_LINEAR);
+  }
+  r->overlay = newTexture(GL_LINEAR);
+  r->compositeTexture = newTexture(GL_LINEAR);
+  r->packTexture = newTexture(GL_NEAREST);
+  glGenFramebuffers(1, &r->compositeFramebuffer);
+  glGenFramebuffers(1, &r->packFramebuffer);
+  glPixelStorei(GL_UNPACK_ALIGNMENT, 1);
+  glPixelStorei(GL_PACK_ALIGNMENT, 1);
+  return NULL;
+}
+
+static void resizeTarget(GLuint texture, GLuint framebuffer, int *w, int *h, int width, int height) {
+  if (*w == width && *h == height) {
+    return;
+  }
+  glBindTexture(GL_TEXTURE_2D, texture);
+  glTexImage2D(GL_TEXTURE_2D, 0, GL_RGBA, width, height, 0, GL_RGBA, GL_UNSIGNED_BYTE, NULL);
+  glBindFramebuffer(GL_FRAMEBUFFER, framebuffer);
+  glFramebufferTexture2D(GL_FRAMEBUFFER, GL_COLOR_ATTACHMENT0, GL_TEXTURE_2D, texture, 0);
+  *w = width;
+  *h = height;
+}
+
+static void upload(GLuint texture, GLenum format, const uint8_t *data, int width, int height) {
+  glBindTexture(GL_TEXTURE_2D, texture);
+  glTexImage2D(GL_TEXTURE_2D, 0, format, width, height, 0, format, GL_UNSIGNED_BYTE, data);
+}
+
+static void gles_set_overlay(Renderer *r, const uint8_t *rgba, int width, int height, float x0, float y0, float x1,
+                             float y1) {
+  if (rgba == NULL) {
+    r->hasOverlay = 0;
+    return;
+  }
+  upload(r->overlay, GL_RGBA, rgba, width, height);
+  r->hasOverlay = 1;
+  r->overlayRect[0] = x0;
+  r->overlayRect[1] = y0;
+  r->overlayRect[2] = x1;
+  r->overlayRect[3] = y1;
+}
+
+static void bindSampler(GLuint program, const char *name, int unit, GLuint texture) {
+  glActiveTexture(GL_TEXTURE0 + unit);
+  glBindTexture(GL_TEXTURE_2D, texture);
+  glUniform1i(glGetUniformLocation(program, name), unit);
+}
+
+static void drawQuad() {
+  glEnableVertexAttribArray(0);
+  glVertexAttribPointer(0, 2, GL_FLOAT, GL_FALSE, 0, 0);
+  glDrawArrays(GL_TRIANGLE_STRIP, 0, 4);
+}
+
+// gles_render scales input to width x height, converts it, blends the overlay, and reads output back
+// to out, which is width * height * 4 bytes in RGBA or width * height * 3 / 2 bytes in I420.
+static const char *gles_render(Renderer *r, int input, const uint8_t *y, const uint8_t *cb, const uint8_t *cr,
+                               int inWidth, int inHeight, int chromaWidth, int chromaHeight, int output, int width,
+                               int height, uint8_t *out) {
+  glBindBuffer(GL_ARRAY_BUFFER, r->vertexBuffer);
+  if (input == INPUT_RGBA) {
+    upload(r->planes[0], GL_RGBA, y, inWidth, inHeight);
+  } else {
+    upload(r->planes[0], GL_LUMINANCE, y, inWidth, inHeight);
+    upload(r->planes[1], GL_LUMINANCE, cb, chromaWidth, chromaHeight);
+    upload(r->planes[2], GL_LUMINANCE, cr, chromaWidth, chromaHeight);
+  }
+
+  resizeTarget(r->compositeTexture, r->compositeFramebuffer, &r->compositeWidth, &r->compositeHeight, width,
+               height);
+  glBindFramebuffer(GL_FRAMEBUFFER, r->compositeFramebuffer);
+  if (glCheckFramebufferStatus(GL_FRAMEBUFFER) != GL_FRAMEBUFFER_COMPLETE) {
+    return "the framebuffer of the frame is incomplete";
+  }
+  glViewport(0, 0, width, height);
+  glUseProgram(r->compositeProgram);
+  bindSampler(r->compositeProgram, "planeY", 0, r->planes[0]);
+  bindSampler(r->compositeProgram, "planeCb", 1, r->planes[1]);
+  bindSampler(r->compositeProgram, "planeCr", 2, r->planes[2]);
+  bindSampler(r->compositeProgram, "overlay", 3, r->overlay);
+  glUniform1i(glGetUniformLocation(r->compositeProgram, "rgbaInput"), input == INPUT_RGBA);
+  glUniform1i(glGetUniformLocation(r->compositeProgram, "hasOverlay"), r->hasOverlay);
+  glUniform4fv(glGetUniformLocation(r->compositeProgram, "overlayRect"), 1, r->overlayRect);
+  drawQuad();
+
+  if (output == OUTPUT_RGBA) {
+    glReadPixels(0, 0, width, height, GL_RGBA, GL_UNSIGNED_BYTE, out);
+    return glGetError() == GL_NO_ERROR ? NULL : "failed to read the frame back";
+  }
+
+  resizeTarget(r->packTexture, r->packFramebuffer, &r->packWidth, &r->packHeight, width / 4, height * 3 / 2);
+  glBindFramebuffer(GL_FRAMEBUFFER, r->packFramebuffer);
+  if (glCheckFramebufferStatus(GL_FRAMEBUFFER) != GL_FRAMEBUFFER_COMPLETE) {
+    return "the framebuffer of the packed frame is incomplete";
+  }
+  glViewport(0, 0, width / 4, height * 3 / 2);
+  glUseProgram(r->packProgram);
+  bindSampler(r->packProgram, "frame", 0, r->compositeTexture);
+  glUniform2f(glGetUniformLocation(r->packProgram, "size"), (float)width, (float)height);
+  drawQuad();
+  glReadPixels(0, 0, width / 4, height * 3 / 2, GL_RGBA, GL_UNSIGNED_BYTE, out);
+  return glGetError() == GL_NO_ERROR ? NULL : "failed to read the frame back";
+}
+
+static void gles_destroy(Renderer *r) {
+  if (r->display == EGL_NO_DISPLAY) {
+    return;
+  }
+  if (r->context != EGL_NO_CONTEXT) {
+    glDeleteTextures(3, r->planes);
+    glDeleteTextures(1, &r->overlay);
+    glDeleteTextures(1, &r->compositeTexture);
+    glDeleteTextures(1, &r->packTexture);
+    glDeleteFramebuffers(1, &r->compositeFramebuffer);
+    glDeleteFramebuffers(1, &r->packFramebuffer);
+    glDeleteBuffers(1, &r->vertexBuffer);
+    glDeleteProgram(r->compositeProgram);
+    glDeleteProgram(r->packProgram);
+    eglMakeCurrent(r->display, EGL_NO_SURFACE, EGL_NO_SURFACE, EGL_NO_CONTEXT);
+    eglDestroyContext(r->display, r->context);
+  }
+  if (r->surface != EGL_NO_SURFACE) {
+    eglDestroySurface(r->display, r->surface);
+  }
+  eglTerminate(r->display);
+}
@@ -0,0 +1,110 @@
+// +build gles
+
+package gles
+
+// #cgo LDFLAGS: -lEGL -lGLESv2
+// #include "bridge.h"
+import "C"
+import (
+	"errors"
+	"runtime"
+	"unsafe"
+)
+
+// glBackend owns the context on a locked thread, since contexts are current on the thread they're made
+// current on.
+type glBackend struct {
+	renderer *C.Renderer
+	requests chan func()
+	done     chan struct{}
+}
+
+func newBackend() (backend, error) {
+	b := &glBackend{
+		renderer: (*C.Renderer)(C.calloc(1, C.sizeof_Renderer)),
+		requests: make(chan func()),
+		done:     make(chan struct{}),
+	}
+
+	initialized := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(b.done)
+
+		if err := C.gles_init(b.renderer); err != nil {
+			C.gles_destroy(b.renderer)
+			C.free(unsafe.Pointer(b.renderer))
+			initialized <- errors.New(C.GoString(err))
+			return
+		}
+		initialized <- nil
+
+		for request := range b.requests {
+			request()
+		}
+		C.gles_destroy(b.renderer)
+		C.free(unsafe.Pointer(b.renderer))
+	}()
+
+	if err := <-initialized; err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *glBackend) do(f func() *C.char) error {
+	errs := make(chan error)
+	b.requests <- func() {
+		if err := f(); err != nil {
+			errs <- errors.New(C.GoString(err))
+			return
+		}
+		errs <- nil
+	}
+	return <-errs
+}
+
+func (b *glBackend) setOverlay(o *overlay) error {
+	return b.do(func() *C.char {
+		if o == nil {
+			C.gles_set_overlay(b.renderer, nil, 0, 0, 0, 0, 0, 0)
+			return nil
+		}
+		C.gles_set_overlay(b.renderer, (*C.uint8_t)(unsafe.Pointer(&o.pix[0])), C.int(o.width), C.int(o.height),
+			C.float(o.rect[0]), C.float(o.rect[1]), C.float(o.rect[2]), C.float(o.rect[3]))
+		return nil
+	})
+}
+
+func (b *glBackend) render(in *input, format Format, width, height int, out []byte) error {
+	return b.do(func() *C.char {
+		// Textures are uploaded and the frame is read back within the calls, so C doesn't keep Go memory.
+		var planes [3]*C.uint8_t
+		for i, plane := range in.planes {
+			if len(plane) > 0 {
+				planes[i] = (*C.uint8_t)(unsafe.Pointer(&plane[0]))
+			}
+		}
+
+		cInput, cOutput := C.int(C.INPUT_YCBCR), C.int(C.OUTPUT_I420)
+		if in.rgba {
+			cInput = C.INPUT_RGBA
+		}
+		if format == FormatRGBA {
+			cOutput = C.OUTPUT_RGBA
+		}
+		if err := C.gles_render(b.renderer, cInput,
+			planes[0], planes[1], planes[2],
+			C.int(in.width), C.int(in.height), C.int(in.chromaWidth), C.int(in.chromaHeight),
+			cOutput, C.int(width), C.int(height), (*C.uint8_t)(unsafe.Pointer(&out[0]))); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (b *glBackend) close() {
+	close(b.requests)
+	<-b.done
+}
@@ -0,0 +1,90 @@
+// +build gles
+
+package gles
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestTransformer(t *testing.T) {
+	toI420, err := New(Options{Width: 16, Height: 8, Format: FormatI420})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer toI420.Close()
+
+	// Red on the left half, and blue on the right half
+	src := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			if x < 16 {
+				src.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				src.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	out, err := toI420.render(newInput(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv, ok := out.(*image.YCbCr)
+	if !ok || yuv.Rect.Dx() != 16 || yuv.Rect.Dy() != 8 || yuv.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+		t.Fatalf("expected an I420 frame of 16x8, but got %T of %v", out, out.Bounds())
+	}
+	for _, c := range []struct {
+		x, y         int
+		luma, cb, cr byte
+	}{
+		{0, 0, 81, 90, 240},
+		{15, 7, 41, 240, 110},
+	} {
+		got := yuv.YCbCrAt(c.x, c.y)
+		if abs(int(got.Y)-int(c.luma)) > 2 || abs(int(got.Cb)-int(c.cb)) > 2 || abs(int(got.Cr)-int(c.cr)) > 2 {
+			t.Fatalf("expected %v at (%d, %d), but got %v", []byte{c.luma, c.cb, c.cr}, c.x, c.y, got)
+		}
+	}
+
+	toRGBA, err := New(Options{Width: 16, Height: 8, Format: FormatRGBA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer toRGBA.Close()
+	overlay := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	overlay.Set(0, 0, color.RGBA{0, 255, 0, 255})
+	if err := toRGBA.SetOverlay(overlay, image.Rect(8, 0, 16, 4)); err != nil {
+		t.Fatal(err)
+	}
+	out, err = toRGBA.render(newInput(yuv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		x, y     int
+		expected color.RGBA
+	}{
+		{0, 0, color.RGBA{255, 0, 0, 255}},
+		{12, 2, color.RGBA{0, 255, 0, 255}},
+		{15, 7, color.RGBA{0, 0, 255, 255}},
+	} {
+		got := out.(*image.RGBA).RGBAAt(c.x, c.y)
+		if abs(int(got.R)-int(c.expected.R)) > 4 || abs(int(got.G)-int(c.expected.G)) > 4 ||
+			abs(int(got.B)-int(c.expected.B)) > 4 {
+			t.Fatalf("expected %v at (%d, %d), but got %v", c.expected, c.x, c.y, got)
+		}
+	}
+
+	toRGBA.Close()
+	if _, err := toRGBA.render(newInput(yuv)); err != errClosed {
+		t.Fatalf("expected %v after closing, but got %v", errClosed, err)
+	}
+}
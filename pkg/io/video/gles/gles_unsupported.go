@@ -0,0 +1,11 @@
+// +build !gles
+
+package gles
+
+import "errors"
+
+var errNotSupported = errors.New("gles transform requires the gles build tag")
+
+func newBackend() (backend, error) {
+	return nil, errNotSupported
+}
@@ -0,0 +1,22 @@
+// +build !gles
+
+package gles
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+func TestNotSupported(t *testing.T) {
+	if _, err := New(Options{}); err != errNotSupported {
+		t.Fatalf("expected %v, but got %v", errNotSupported, err)
+	}
+	if _, err := video.NewTransform("gles", json.RawMessage(`{"width": 640}`)); err == nil {
+		t.Fatal("expected the registered transform to fail without the build tag")
+	}
+	if _, err := video.NewTransform("gles", json.RawMessage(`{"format": "nv12"}`)); err == nil {
+		t.Fatal("expected an error with an unknown format")
+	}
+}
@@ -0,0 +1,325 @@
+// Package gles implements a video transform on the GPU with OpenGL ES 2.0. It scales frames, converts them
+// between YCbCr and RGBA, and blends an overlay in a single pass, so a pipeline of video.Scale, video.ToI420
+// and a drawn overlay costs one upload and one readback instead of several CPU passes.
+// The context is headless on EGL, and the backend is only built with the gles build tag. Without the tag, New
+// returns an error, so pipelines fall back to pkg/io/video transforms.
+package gles
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+var errClosed = errors.New("the transformer is closed")
+
+// Format is the format of frames the transform returns.
+type Format int
+
+const (
+	// FormatI420 returns frames as *image.YCbCr in I420, which encoders take without conversion.
+	FormatI420 Format = iota
+	// FormatRGBA returns frames as *image.RGBA.
+	FormatRGBA
+)
+
+// Options are transform options.
+type Options struct {
+	// Width and Height are the output size. If one of them is 0, it's computed from the other to keep
+	// the input aspect ratio, and if both are 0, frames keep their size. In FormatI420, width is
+	// rounded down to a multiple of 8 and height to a multiple of 2 to pack planes on the GPU.
+	Width, Height int
+	// Format is the output format.
+	Format Format
+	// Overlay is blended over frames in OverlayRect, which is in output coordinates.
+	// If OverlayRect is empty, the overlay is at the origin with its own size.
+	Overlay     image.Image
+	OverlayRect image.Rectangle
+}
+
+// input is a frame in contiguous planes that are uploaded to textures.
+type input struct {
+	rgba                      bool
+	planes                    [3][]byte
+	width, height             int
+	chromaWidth, chromaHeight int
+}
+
+// overlay is an overlay in premultiplied RGBA, and its rect in normalized output coordinates.
+type overlay struct {
+	pix           []byte
+	width, height int
+	rect          [4]float32
+}
+
+type backend interface {
+	setOverlay(o *overlay) error
+	// render renders in to out, which is width*height*4 bytes in FormatRGBA, or width*height*3/2 bytes in I420.
+	render(in *input, format Format, width, height int, out []byte) error
+	close()
+}
+
+// Transformer is a GPU transform. It's safe for concurrent use, and frames are rendered in order.
+type Transformer struct {
+	opts    Options
+	backend backend
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// New creates a Transformer with its own GPU context, which Close releases.
+func New(opts Options) (*Transformer, error) {
+	if opts.Width < 0 || opts.Height < 0 {
+		return nil, fmt.Errorf("invalid size %dx%d", opts.Width, opts.Height)
+	}
+	if opts.Format != FormatI420 && opts.Format != FormatRGBA {
+		return nil, fmt.Errorf("unknown format %d", opts.Format)
+	}
+
+	b, err := newBackend()
+	if err != nil {
+		return nil, err
+	}
+	t := &Transformer{opts: opts, backend: b}
+	if opts.Overlay != nil {
+		if err := t.SetOverlay(opts.Overlay, opts.OverlayRect); err != nil {
+			b.close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// SetOverlay replaces the overlay, which is removed if img is nil. rect is like Options.OverlayRect, and it's
+// relative to the output size set in the options. It's an error if the output keeps the frame
+// size.
+func (t *Transformer) SetOverlay(img image.Image, rect image.Rectangle) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return errClosed
+	}
+	if img == nil {
+		return t.backend.setOverlay(nil)
+	}
+
+	w, h := t.opts.Width, t.opts.Height
+	if w == 0 || h == 0 {
+		return fmt.Errorf("the overlay requires the size of the output")
+	}
+	w, h = outputSize(w, h, w, h, t.opts.Format)
+	if rect.Empty() {
+		rect = image.Rectangle{Max: img.Bounds().Size()}
+	}
+	return t.backend.setOverlay(newOverlay(img, rect, w, h))
+}
+
+// Transform returns the frame transform. Frames are copied back from the GPU, so readers can keep
+// them after the next frame.
+func (t *Transformer) Transform() video.TransformFunc {
+	return func(r video.Reader) video.Reader {
+		return video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			// Planes aren't copied if they're contiguous, so the frame is released after it's uploaded.
+			out, err := t.render(newInput(img))
+			release()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			return out, func() {}, nil
+		}), r)
+	}
+}
+
+func (t *Transformer) render(in *input) (image.Image, error) {
+	w, h := outputSize(t.opts.Width, t.opts.Height, in.width, in.height, t.opts.Format)
+	if w <= 0 || h <= 0 {
+		return nil, fmt.Errorf("the frames of %dx%d are too small for the output", in.width, in.height)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil, errClosed
+	}
+
+	if t.opts.Format == FormatRGBA {
+		out := image.NewRGBA(image.Rect(0, 0, w, h))
+		if err := t.backend.render(in, FormatRGBA, w, h, out.Pix); err != nil {
+			return nil, fmt.Errorf("failed to render the frame: %s", err)
+		}
+		return out, nil
+	}
+
+	buf := make([]byte, w*h*3/2)
+	if err := t.backend.render(in, FormatI420, w, h, buf); err != nil {
+		return nil, fmt.Errorf("failed to render the frame: %s", err)
+	}
+	ySize, cSize := w*h, w*h/4
+	return &image.YCbCr{
+		Y:              buf[:ySize],
+		Cb:             buf[ySize : ySize+cSize],
+		Cr:             buf[ySize+cSize:],
+		YStride:        w,
+		CStride:        w / 2,
+		SubsampleRatio: image.YCbCrSubsampleRatio420,
+		Rect:           image.Rect(0, 0, w, h),
+	}, nil
+}
+
+// Close releases the context. Transforms return an error after it's closed.
+func (t *Transformer) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	t.backend.close()
+	return nil
+}
+
+// outputSize returns the output size for inW x inH frames.
+func outputSize(width, height, inW, inH int, format Format) (int, int) {
+	switch {
+	case width == 0 && height == 0:
+		width, height = inW, inH
+	case width == 0:
+		width = inW * height / inH
+	case height == 0:
+		height = inH * width / inW
+	}
+	if format == FormatI420 {
+		width -= width % 8
+		height -= height % 2
+	}
+	return width, height
+}
+
+// newInput copies img's planes if they aren't contiguous. Frames that are neither YCbCr nor RGBA are
+// drawn in RGBA.
+func newInput(img image.Image) *input {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	if yuv, ok := img.(*image.YCbCr); ok {
+		in := &input{width: w, height: h}
+		in.planes[0] = packPlane(yuv.Y, yuv.YStride, yuv.YOffset(bounds.Min.X, bounds.Min.Y), w, h)
+		// Chroma rows and columns that cover bounds
+		c0 := yuv.COffset(bounds.Min.X, bounds.Min.Y)
+		in.chromaWidth = yuv.COffset(bounds.Max.X-1, bounds.Min.Y) - c0 + 1
+		in.chromaHeight = (yuv.COffset(bounds.Min.X, bounds.Max.Y-1)-c0)/yuv.CStride + 1
+		in.planes[1] = packPlane(yuv.Cb, yuv.CStride, c0, in.chromaWidth, in.chromaHeight)
+		in.planes[2] = packPlane(yuv.Cr, yuv.CStride, c0, in.chromaWidth, in.chromaHeight)
+		return in
+	}
+
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	}
+	return &input{
+		rgba:   true,
+		planes: [3][]byte{packPlane(rgba.Pix, rgba.Stride, rgba.PixOffset(rgba.Rect.Min.X, rgba.Rect.Min.Y), w*4, h)},
+		width:  w,
+		height: h,
+	}
+}
+
+// packPlane returns rows of width bytes from offset in pix, without copying if they're contiguous.
+func packPlane(pix []byte, stride, offset, width, height int) []byte {
+	if stride == width {
+		return pix[offset : offset+width*height]
+	}
+	packed := make([]byte, width*height)
+	for y := 0; y < height; y++ {
+		copy(packed[y*width:(y+1)*width], pix[offset+y*stride:])
+	}
+	return packed
+}
+
+func newOverlay(img image.Image, rect image.Rectangle, width, height int) *overlay {
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Rect, img, bounds.Min, draw.Src)
+	return &overlay{
+		pix:    rgba.Pix,
+		width:  rgba.Rect.Dx(),
+		height: rgba.Rect.Dy(),
+		rect: [4]float32{
+			float32(rect.Min.X) / float32(width),
+			float32(rect.Min.Y) / float32(height),
+			float32(rect.Max.X) / float32(width),
+			float32(rect.Max.Y) / float32(height),
+		},
+	}
+}
+
+type params struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Format string `json:"format"`
+	// Overlay is a PNG image path, placed at X and Y with its own size.
+	Overlay string `json:"overlay"`
+	X       int    `json:"x"`
+	Y       int    `json:"y"`
+}
+
+var formatsByName = map[string]Format{
+	"i420": FormatI420,
+	"rgba": FormatRGBA,
+}
+
+// init registers the transform as "gles", so pipelines configured in JSON can select it, e.g.
+// {"name": "gles", "params": {"width": 640, "format": "i420"}}. The context of transforms built from configs is kept for the process lifetime.
+func init() {
+	err := video.RegisterTransform("gles", params{Format: "i420"}, func(v interface{}) (video.TransformFunc, error) {
+		p := v.(params)
+		format, ok := formatsByName[strings.ToLower(p.Format)]
+		if !ok {
+			return nil, fmt.Errorf("unknown format %s", p.Format)
+		}
+		opts := Options{Width: p.Width, Height: p.Height, Format: format}
+		if p.Overlay != "" {
+			img, err := readPNG(p.Overlay)
+			if err != nil {
+				return nil, err
+			}
+			opts.Overlay = img
+			opts.OverlayRect = img.Bounds().Sub(img.Bounds().Min).Add(image.Pt(p.X, p.Y))
+		}
+		t, err := New(opts)
+		if err != nil {
+			return nil, err
+		}
+		return t.Transform(), nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the overlay: %s", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the overlay: %s", err)
+	}
+	return img, nil
+}
@@ -0,0 +1,54 @@
+package gles
+
+import (
+	"image"
+	"testing"
+)
+
+func TestOutputSize(t *testing.T) {
+	cases := []struct {
+		width, height, inW, inH int
+		format                  Format
+		expectedW, expectedH    int
+	}{
+		{0, 0, 640, 480, FormatRGBA, 640, 480},
+		{320, 0, 640, 480, FormatRGBA, 320, 240},
+		{0, 360, 1280, 720, FormatRGBA, 640, 360},
+		{0, 0, 642, 481, FormatI420, 640, 480},
+		{100, 0, 640, 480, FormatI420, 96, 74},
+	}
+	for _, c := range cases {
+		w, h := outputSize(c.width, c.height, c.inW, c.inH, c.format)
+		if w != c.expectedW || h != c.expectedH {
+			t.Fatalf("expected %dx%d for %+v, but got %dx%d", c.expectedW, c.expectedH, c, w, h)
+		}
+	}
+}
+
+func TestNewInput(t *testing.T) {
+	img := image.NewYCbCr(image.Rect(0, 0, 8, 4), image.YCbCrSubsampleRatio420)
+	for i := range img.Y {
+		img.Y[i] = byte(i)
+	}
+	for i := range img.Cb {
+		img.Cb[i] = byte(i)
+	}
+
+	in := newInput(img.SubImage(image.Rect(2, 2, 6, 4)))
+	if in.rgba || in.width != 4 || in.height != 2 || in.chromaWidth != 2 || in.chromaHeight != 1 {
+		t.Fatalf("expected a YCbCr input of 4x2 with the chroma of 2x1, but got %+v", in)
+	}
+	if expected := []byte{18, 19, 20, 21, 26, 27, 28, 29}; string(in.planes[0]) != string(expected) {
+		t.Fatalf("expected the luma %v, but got %v", expected, in.planes[0])
+	}
+	if expected := []byte{5, 6}; string(in.planes[1]) != string(expected) {
+		t.Fatalf("expected the chroma %v, but got %v", expected, in.planes[1])
+	}
+
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	gray.Pix[3] = 200
+	in = newInput(gray)
+	if !in.rgba || len(in.planes[0]) != 16 || in.planes[0][12] != 200 || in.planes[0][15] != 255 {
+		t.Fatalf("expected the gray image to be drawn in RGBA, but got %+v", in)
+	}
+}
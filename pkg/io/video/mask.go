@@ -0,0 +1,296 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"sync"
+)
+
+// MaskMode is how Mask hides regions.
+type MaskMode int
+
+// MaskMode values.
+const (
+	// MaskBlur blurs the regions, keeping the scene recognizable.
+	MaskBlur MaskMode = iota
+	// MaskPixelate replaces the regions with blocks of their average color.
+	MaskPixelate
+	// MaskSolid fills the regions with a color.
+	MaskSolid
+)
+
+func (m MaskMode) String() string {
+	switch m {
+	case MaskBlur:
+		return "blur"
+	case MaskPixelate:
+		return "pixelate"
+	case MaskSolid:
+		return "solid"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultMaskStrength = 16
+	// maskBlurPasses is the number of box blur passes, which together approximate a gaussian blur.
+	maskBlurPasses = 3
+)
+
+type maskConfig struct {
+	strength int
+	color    color.Color
+	padding  int
+}
+
+// MaskOption configures Mask.
+type MaskOption func(*maskConfig)
+
+// WithMaskStrength sets the MaskBlur radius and the MaskPixelate block size, in pixels. The default, 16, makes
+// a face about 100 pixels tall unrecognizable.
+func WithMaskStrength(pixels int) MaskOption {
+	return func(c *maskConfig) {
+		c.strength = pixels
+	}
+}
+
+// WithMaskColor sets the color of MaskSolid. The default is black.
+func WithMaskColor(c color.Color) MaskOption {
+	return func(mc *maskConfig) {
+		mc.color = c
+	}
+}
+
+// WithMaskPadding grows each region by pixels on every side, e.g. to cover objects that move between detector
+// updates. The default is 0.
+func WithMaskPadding(pixels int) MaskOption {
+	return func(c *maskConfig) {
+		c.padding = pixels
+	}
+}
+
+// MaskRegions is a set of regions for MaskDynamic that can be updated while frames are read, e.g. by a
+// detector.
+type MaskRegions struct {
+	mu      sync.Mutex
+	regions []image.Rectangle
+}
+
+// NewMaskRegions creates MaskRegions holding regions.
+func NewMaskRegions(regions ...image.Rectangle) *MaskRegions {
+	m := &MaskRegions{}
+	m.Set(regions)
+	return m
+}
+
+// Set replaces the regions, starting with the next frame.
+func (m *MaskRegions) Set(regions []image.Rectangle) {
+	copied := append([]image.Rectangle(nil), regions...)
+	m.mu.Lock()
+	m.regions = copied
+	m.mu.Unlock()
+}
+
+// current returns the current regions, which must not be modified.
+func (m *MaskRegions) current() []image.Rectangle {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.regions
+}
+
+// Detect returns a Detector that sets the regions to the faces detector finds, e.g. to blur passers-by. Pass it
+// to Detect before MaskDynamic.
+func (m *MaskRegions) Detect(detector FaceDetector) Detector {
+	return maskDetector{regions: m, detector: detector}
+}
+
+type maskDetector struct {
+	regions  *MaskRegions
+	detector FaceDetector
+}
+
+func (d maskDetector) Detect(img image.Image) {
+	d.regions.Set(d.detector.DetectFaces(img))
+}
+
+// Mask hides regions of each frame, e.g. a neighbor's windows in a surveillance camera's view, before the frames
+// are encoded or recorded. Regions are in frame coordinates. See MaskDynamic for how frames are handled.
+func Mask(regions []image.Rectangle, mode MaskMode, opts ...MaskOption) TransformFunc {
+	return MaskDynamic(NewMaskRegions(regions...), mode, opts...)
+}
+
+// MaskDynamic hides the current regions in each frame, like Mask. Frames with no region pass through unchanged;
+// the rest are copied to a buffer that's reused for the next frame, so frames shared by a Broadcaster's readers
+// aren't masked in place. Frames must be RGBA or YCbCr. Other formats fail rather than leak the regions.
+func MaskDynamic(regions *MaskRegions, mode MaskMode, opts ...MaskOption) TransformFunc {
+	c := maskConfig{
+		strength: defaultMaskStrength,
+		color:    color.Black,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.strength < 1 {
+		c.strength = 1
+	}
+
+	return func(r Reader) Reader {
+		buf := NewFrameBuffer(0)
+		var rects []image.Rectangle
+		var line []int
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			bounds := img.Bounds()
+			rects = rects[:0]
+			for _, region := range regions.current() {
+				region = region.Inset(-c.padding).Intersect(bounds)
+				if !region.Empty() {
+					rects = append(rects, region)
+				}
+			}
+			if len(rects) == 0 {
+				return img, release, nil
+			}
+
+			// Mask a copy in Go memory, e.g. for DMABufImage.
+			if i, ok := img.(interface{ YCbCr() *image.YCbCr }); ok {
+				if yuv := i.YCbCr(); yuv != nil {
+					img = yuv
+				}
+			}
+			switch img.(type) {
+			case *image.RGBA, *image.YCbCr:
+			default:
+				release()
+				return nil, func() {}, errUnsupportedImageType
+			}
+			buf.StoreCopy(img)
+			release()
+
+			switch dst := buf.Load().(type) {
+			case *image.RGBA:
+				p := rgbaPlane(dst)
+				r, g, b, a := c.color.RGBA()
+				value := []uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+				for _, rect := range rects {
+					line = maskPlane(p, 4, rect.Sub(bounds.Min), mode, c.strength, value, line)
+				}
+			case *image.YCbCr:
+				sx, sy, err := subsampleFactors(dst.SubsampleRatio)
+				if err != nil {
+					return nil, func() {}, err
+				}
+				y, cb, cr := yCbCrPlanes(dst)
+				yc := color.YCbCrModel.Convert(c.color).(color.YCbCr)
+				cStrength := c.strength / sx
+				if cStrength < 1 {
+					cStrength = 1
+				}
+				for _, rect := range rects {
+					line = maskPlane(y, 1, rect.Sub(bounds.Min), mode, c.strength, []uint8{yc.Y}, line)
+					// Chroma samples overlapping the region, relative to the first sample of the bounds
+					cRect := image.Rect(
+						rect.Min.X/sx-bounds.Min.X/sx, rect.Min.Y/sy-bounds.Min.Y/sy,
+						(rect.Max.X+sx-1)/sx-bounds.Min.X/sx, (rect.Max.Y+sy-1)/sy-bounds.Min.Y/sy,
+					).Intersect(image.Rect(0, 0, cb.width, cb.height))
+					line = maskPlane(cb, 1, cRect, mode, cStrength, []uint8{yc.Cb}, line)
+					line = maskPlane(cr, 1, cRect, mode, cStrength, []uint8{yc.Cr}, line)
+				}
+			}
+			return buf.Load(), func() {}, nil
+		})
+	}
+}
+
+// maskPlane masks rect of p, whose pixels are bpp bytes, and returns the line buffer for the next call.
+func maskPlane(p plane, bpp int, rect image.Rectangle, mode MaskMode, strength int, value []uint8, line []int) []int {
+	if rect.Empty() {
+		return line
+	}
+	switch mode {
+	case MaskSolid:
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			row := p.row(y)
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				copy(row[x*bpp:(x+1)*bpp], value)
+			}
+		}
+	case MaskPixelate:
+		pixelatePlane(p, bpp, rect, strength)
+	default:
+		for pass := 0; pass < maskBlurPasses; pass++ {
+			line = blurPlane(p, bpp, rect, strength, line)
+		}
+	}
+	return line
+}
+
+// pixelatePlane fills each block of size in rect with its average. Blocks are aligned to the plane, so they
+// don't flicker as the region moves.
+func pixelatePlane(p plane, bpp int, rect image.Rectangle, size int) {
+	var sum [4]int
+	for by := rect.Min.Y / size * size; by < rect.Max.Y; by += size {
+		for bx := rect.Min.X / size * size; bx < rect.Max.X; bx += size {
+			block := image.Rect(bx, by, bx+size, by+size).Intersect(rect)
+			sum = [4]int{}
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				row := p.row(y)
+				for x := block.Min.X; x < block.Max.X; x++ {
+					for ch := 0; ch < bpp; ch++ {
+						sum[ch] += int(row[x*bpp+ch])
+					}
+				}
+			}
+			n := block.Dx() * block.Dy()
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				row := p.row(y)
+				for x := block.Min.X; x < block.Max.X; x++ {
+					for ch := 0; ch < bpp; ch++ {
+						row[x*bpp+ch] = uint8((sum[ch] + n/2) / n)
+					}
+				}
+			}
+		}
+	}
+}
+
+// blurPlane blurs rect with a box of radius, first along rows and then columns. Only pixels inside rect are
+// sampled, so pixels outside the region aren't smeared into it.
+func blurPlane(p plane, bpp int, rect image.Rectangle, radius int, line []int) []int {
+	blurLine := func(n int, at func(i int) *uint8) {
+		// Prefix sums of the line
+		if cap(line) < n+1 {
+			line = make([]int, n+1)
+		}
+		line = line[:n+1]
+		for i := 0; i < n; i++ {
+			line[i+1] = line[i] + int(*at(i))
+		}
+		for i := 0; i < n; i++ {
+			lo, hi := i-radius, i+radius+1
+			if lo < 0 {
+				lo = 0
+			}
+			if hi > n {
+				hi = n
+			}
+			*at(i) = uint8((line[hi] - line[lo] + (hi-lo)/2) / (hi - lo))
+		}
+	}
+
+	for ch := 0; ch < bpp; ch++ {
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			row := p.row(y)
+			blurLine(rect.Dx(), func(i int) *uint8 { return &row[(rect.Min.X+i)*bpp+ch] })
+		}
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			blurLine(rect.Dy(), func(i int) *uint8 { return &p.pix[(rect.Min.Y+i)*p.stride+x*bpp+ch] })
+		}
+	}
+	return line
+}
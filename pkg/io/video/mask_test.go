@@ -0,0 +1,157 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	newRGBA := func() *image.RGBA {
+		img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+		for y := 0; y < 48; y++ {
+			for x := 0; x < 64; x++ {
+				// A 1-pixel checkerboard
+				v := uint8(255 * ((x + y) % 2))
+				img.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+			}
+		}
+		return img
+	}
+	region := image.Rect(16, 8, 48, 40)
+
+	testCases := map[string]struct {
+		mode  MaskMode
+		check func(t *testing.T, c color.RGBA)
+	}{
+		"Blur": {
+			mode: MaskBlur,
+			check: func(t *testing.T, c color.RGBA) {
+				if c.R < 96 || c.R > 160 {
+					t.Fatalf("expected the blurred gray, but got %v", c)
+				}
+			},
+		},
+		"Pixelate": {
+			mode: MaskPixelate,
+			check: func(t *testing.T, c color.RGBA) {
+				if c.R != 128 {
+					t.Fatalf("expected the average of the block, but got %v", c)
+				}
+			},
+		},
+		"Solid": {
+			mode: MaskSolid,
+			check: func(t *testing.T, c color.RGBA) {
+				if c != (color.RGBA{255, 0, 0, 255}) {
+					t.Fatalf("expected the color of the mask, but got %v", c)
+				}
+			},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			src := newRGBA()
+			r := Mask([]image.Rectangle{region}, c.mode, WithMaskColor(color.RGBA{255, 0, 0, 255}))(ReaderFunc(func() (image.Image, func(), error) {
+				return src, func() {}, nil
+			}))
+			out, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			dst := out.(*image.RGBA)
+			if dst == src {
+				t.Fatal("expected the frame to be copied")
+			}
+			if src.RGBAAt(20, 20) != newRGBA().RGBAAt(20, 20) {
+				t.Fatal("expected the source frame to be kept")
+			}
+			for y := region.Min.Y; y < region.Max.Y; y++ {
+				for x := region.Min.X; x < region.Max.X; x++ {
+					c.check(t, dst.RGBAAt(x, y))
+				}
+			}
+			if dst.RGBAAt(15, 8) != src.RGBAAt(15, 8) || dst.RGBAAt(48, 39) != src.RGBAAt(48, 39) {
+				t.Fatal("expected the pixels out of the region to be kept")
+			}
+		})
+	}
+}
+
+func TestMaskYCbCr(t *testing.T) {
+	src := image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio420)
+	for i := range src.Y {
+		src.Y[i] = 100
+	}
+	for i := range src.Cb {
+		src.Cb[i], src.Cr[i] = 128, 128
+	}
+	// A sub image at an odd offset
+	sub := src.SubImage(image.Rect(1, 1, 63, 47))
+
+	region := image.Rect(11, 11, 31, 21)
+	r := Mask([]image.Rectangle{region}, MaskSolid, WithMaskColor(color.YCbCr{Y: 16, Cb: 90, Cr: 240}))(ReaderFunc(func() (image.Image, func(), error) {
+		return sub, func() {}, nil
+	}))
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := out.(*image.YCbCr)
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			p := image.Pt(x, y)
+			if !p.In(dst.Rect) {
+				continue
+			}
+			c := dst.YCbCrAt(x, y)
+			if p.In(region) {
+				if c != (color.YCbCr{Y: 16, Cb: 90, Cr: 240}) {
+					t.Fatalf("expected the color of the mask at %v, but got %v", p, c)
+				}
+			} else if c.Y != 100 {
+				t.Fatalf("expected the luma out of the region to be kept at %v, but got %v", p, c)
+			}
+		}
+	}
+	if src.Y[src.YOffset(20, 15)] != 100 {
+		t.Fatal("expected the source frame to be kept")
+	}
+}
+
+func TestMaskDynamic(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 16, 16))
+	regions := NewMaskRegions()
+	detected := []image.Rectangle{image.Rect(4, 4, 8, 8)}
+	detector := regions.Detect(FaceDetectorFunc(func(image.Image) []image.Rectangle { return detected }))
+
+	var detect bool
+	r := MaskDynamic(regions, MaskBlur)(ReaderFunc(func() (image.Image, func(), error) {
+		if detect {
+			detector.Detect(src)
+		}
+		return src, func() {}, nil
+	}))
+
+	// Frames without a region pass through, even if they couldn't be masked
+	out, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != image.Image(src) {
+		t.Fatal("expected the frame without a region to be kept")
+	}
+
+	// Frames that can't be masked fail
+	detect = true
+	if _, _, err := r.Read(); err != errUnsupportedImageType {
+		t.Fatalf("expected %v, but got %v", errUnsupportedImageType, err)
+	}
+
+	// Regions outside the frame are ignored
+	detected = []image.Rectangle{image.Rect(20, 20, 30, 30)}
+	if out, _, err := r.Read(); err != nil || out != image.Image(src) {
+		t.Fatalf("expected the frame without a region to be kept, but got %v", err)
+	}
+}
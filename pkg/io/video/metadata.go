@@ -0,0 +1,150 @@
+package video
+
+import (
+	"image"
+	"sync"
+	"time"
+)
+
+// Metadata is information about a frame that's kept through transforms.
+type Metadata struct {
+	// CaptureTime is when the frame was captured from the source.
+	CaptureTime time.Time
+	// Sequence is the frame's number from the source, which increases monotonically from 0.
+	Sequence uint64
+	// Dropped is how many frames the source dropped before this one since it started, e.g. because
+	// device buffers were full. It's set by drivers that can detect dropped frames.
+	Dropped uint64
+	// ColorSpace is the YCbCr frame colorspace, which is zero if it's unknown. It's set by sources
+	// that know it, and by conversions like ToI420In.
+	ColorSpace ColorSpace
+	// Values is arbitrary metadata set by the source or transforms. Readers of the same frame share the map,
+	// so it must not be modified. Use With to set a value.
+	Values map[string]interface{}
+}
+
+// Value returns key's value.
+func (m Metadata) Value(key string) (interface{}, bool) {
+	v, ok := m.Values[key]
+	return v, ok
+}
+
+// With returns a copy of the metadata with key set to value.
+func (m Metadata) With(key string, value interface{}) Metadata {
+	values := make(map[string]interface{}, len(m.Values)+1)
+	for k, v := range m.Values {
+		values[k] = v
+	}
+	values[key] = value
+	m.Values = values
+	return m
+}
+
+// MetadataReader is a Reader that knows its frames' metadata. Since readers are pulled in the reading goroutine,
+// the metadata of the last frame read from the source belongs to the frame the transforms return.
+// That doesn't hold for transforms that delay frames.
+type MetadataReader interface {
+	Reader
+	// Metadata returns the metadata of the frame returned by the last Read.
+	Metadata() Metadata
+}
+
+// MetadataOf returns the metadata of the last frame read from r, if r is a MetadataReader.
+func MetadataOf(r Reader) (Metadata, bool) {
+	if mr, ok := r.(MetadataReader); ok {
+		return mr.Metadata(), true
+	}
+	return Metadata{}, false
+}
+
+type metadataReader struct {
+	Reader
+	metadata func() Metadata
+}
+
+func (r *metadataReader) Metadata() Metadata {
+	return r.metadata()
+}
+
+// NewMetadataReader creates a MetadataReader from r, whose metadata returns the metadata of the frame returned by
+// the last r.Read. It's for drivers that know their frames' capture times better than when they're read.
+func NewMetadataReader(r Reader, metadata func() Metadata) MetadataReader {
+	return &metadataReader{Reader: r, metadata: metadata}
+}
+
+// lastMetadata is the last frame's metadata, which other goroutines like the broadcaster's readers read.
+type lastMetadata struct {
+	mu       sync.Mutex
+	metadata Metadata
+}
+
+func (l *lastMetadata) store(m Metadata) {
+	l.mu.Lock()
+	l.metadata = m
+	l.mu.Unlock()
+}
+
+func (l *lastMetadata) load() Metadata {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.metadata
+}
+
+// KeepMetadata makes transformed, which reads frames from src, report src's metadata.
+// It returns transformed as is if it already knows the metadata, or if src doesn't.
+// Merge applies it to every transform.
+func KeepMetadata(transformed, src Reader) Reader {
+	if _, ok := transformed.(MetadataReader); ok {
+		return transformed
+	}
+	mr, ok := src.(MetadataReader)
+	if !ok {
+		return transformed
+	}
+	return &metadataReader{Reader: transformed, metadata: mr.Metadata}
+}
+
+// Stamp returns a MetadataReader that sets the capture time from now and the sequence number on frames read
+// from r. If r is a MetadataReader, its capture time, dropped frames and values are kept.
+func Stamp(r Reader, now func() time.Time) MetadataReader {
+	var last lastMetadata
+	var sequence uint64
+	return &metadataReader{
+		Reader: ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			m, _ := MetadataOf(r)
+			if m.CaptureTime.IsZero() {
+				m.CaptureTime = now()
+			}
+			m.Sequence = sequence
+			sequence++
+			last.store(m)
+			return img, release, nil
+		}),
+		metadata: last.load,
+	}
+}
+
+// Annotate sets key in the frames' metadata to the value fn computes from each frame.
+func Annotate(key string, fn func(img image.Image) interface{}) TransformFunc {
+	return func(r Reader) Reader {
+		var last lastMetadata
+		return &metadataReader{
+			Reader: ReaderFunc(func() (image.Image, func(), error) {
+				img, release, err := r.Read()
+				if err != nil {
+					return nil, func() {}, err
+				}
+
+				m, _ := MetadataOf(r)
+				last.store(m.With(key, fn(img)))
+				return img, release, nil
+			}),
+			metadata: last.load,
+		}
+	}
+}
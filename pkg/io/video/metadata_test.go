@@ -0,0 +1,112 @@
+package video
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	start := time.Unix(1600000000, 0)
+	now := start
+	var src Reader = ReaderFunc(func() (image.Image, func(), error) {
+		now = now.Add(time.Second)
+		return image.NewRGBA(image.Rect(0, 0, 4, 4)), func() {}, nil
+	})
+	stamped := Stamp(src, func() time.Time { return now })
+
+	// Drop every other frame
+	drop := func(r Reader) Reader {
+		return ReaderFunc(func() (image.Image, func(), error) {
+			r.Read()
+			return r.Read()
+		})
+	}
+	r := Merge(
+		drop,
+		Annotate("size", func(img image.Image) interface{} { return img.Bounds().Dx() }),
+		Scale(2, 2, nil),
+	)(stamped)
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+		m, ok := MetadataOf(r)
+		if !ok {
+			t.Fatal("expected the metadata to be kept through the transforms")
+		}
+		if expected := uint64(i*2 + 1); m.Sequence != expected {
+			t.Fatalf("expected sequence %d, but got %d", expected, m.Sequence)
+		}
+		if expected := start.Add(time.Duration(i*2+2) * time.Second); !m.CaptureTime.Equal(expected) {
+			t.Fatalf("expected capture time %v, but got %v", expected, m.CaptureTime)
+		}
+		if v, ok := m.Value("size"); !ok || v != 4 {
+			t.Fatalf("expected size to be 4, but got %v", v)
+		}
+	}
+}
+
+func TestMetadataWith(t *testing.T) {
+	m := Metadata{}.With("a", 1)
+	m2 := m.With("b", 2)
+	if _, ok := m.Value("b"); ok {
+		t.Fatal("expected With not to modify the original values")
+	}
+	if v, _ := m2.Value("a"); v != 1 {
+		t.Fatalf("expected a to be 1, but got %v", v)
+	}
+}
+
+func TestStampKeepsSourceMetadata(t *testing.T) {
+	captured := time.Unix(100, 0)
+	src := NewMetadataReader(ReaderFunc(func() (image.Image, func(), error) {
+		return image.NewGray(image.Rect(0, 0, 1, 1)), func() {}, nil
+	}), func() Metadata {
+		return Metadata{CaptureTime: captured}.With("exposure", 10)
+	})
+	r := Stamp(src, time.Now)
+	r.Read()
+	r.Read()
+	m := r.Metadata()
+	if !m.CaptureTime.Equal(captured) {
+		t.Fatalf("expected capture time from the source %v, but got %v", captured, m.CaptureTime)
+	}
+	if v, _ := m.Value("exposure"); v != 10 {
+		t.Fatalf("expected exposure from the source to be 10, but got %v", v)
+	}
+	if m.Sequence != 1 {
+		t.Fatalf("expected sequence 1, but got %d", m.Sequence)
+	}
+}
+
+func TestBroadcastMetadata(t *testing.T) {
+	var n int64
+	src := Stamp(ReaderFunc(func() (image.Image, func(), error) {
+		return image.NewGray(image.Rect(0, 0, 1, 1)), func() {}, nil
+	}), func() time.Time {
+		n++
+		return time.Unix(n, 0)
+	})
+
+	broadcaster := NewBroadcaster(src, nil)
+	fast := broadcaster.NewReader(false)
+	slow := broadcaster.NewReader(true)
+	fast.Read()
+	fast.Read()
+	if _, _, err := slow.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	if m, _ := MetadataOf(fast); m.Sequence != 1 {
+		t.Fatalf("expected sequence 1, but got %d", m.Sequence)
+	}
+	// The slow reader reads the buffered frame.
+	if m, _ := MetadataOf(slow); m.Sequence != 0 || m.CaptureTime.Unix() != 1 {
+		t.Fatalf("expected the metadata of the first frame, but got %+v", m)
+	}
+	if _, ok := MetadataOf(broadcaster.Source()); !ok {
+		t.Fatal("expected the source to be kept")
+	}
+}
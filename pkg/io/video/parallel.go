@@ -0,0 +1,70 @@
+package video
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minRowsPerWorker is the minimum number of rows a goroutine processes. Smaller frames use
+// fewer goroutines, since starting a goroutine costs more than converting a few rows.
+const minRowsPerWorker = 16
+
+// ConcurrencyOptions controls how many goroutines process a frame.
+type ConcurrencyOptions struct {
+	// Workers is the maximum number of goroutines converters use per frame, and the thread count of
+	// software encoders, i.e. vpx and x264, unless their parameters set it. 1 processes
+	// frames in the calling goroutine. If it's 0, GOMAXPROCS is used.
+	Workers int
+}
+
+var (
+	concurrencyOptionsMu sync.Mutex
+	concurrencyOptions   ConcurrencyOptions
+)
+
+// SetConcurrencyOptions configures video processing parallelism. Options apply to
+// frames processed and encoders created after this call.
+// Embedded systems may cap workers to leave cores for other tasks, and servers that process
+// a few large streams may raise them.
+func SetConcurrencyOptions(options ConcurrencyOptions) {
+	concurrencyOptionsMu.Lock()
+	defer concurrencyOptionsMu.Unlock()
+	concurrencyOptions = options
+}
+
+// Workers returns the maximum number of goroutines or threads per frame, as configured by
+// SetConcurrencyOptions.
+func Workers() int {
+	concurrencyOptionsMu.Lock()
+	workers := concurrencyOptions.Workers
+	concurrencyOptionsMu.Unlock()
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// parallelRows calls fn with row ranges [y0, y1) covering [0, height), in parallel on up to
+// Workers goroutines. fn must not write to rows outside its range.
+func parallelRows(height int, fn func(y0, y1 int)) {
+	workers := Workers()
+	if max := height / minRowsPerWorker; workers > max {
+		workers = max
+	}
+	if workers <= 1 {
+		fn(0, height)
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers - 1)
+	for i := 1; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			fn(height*i/workers, height*(i+1)/workers)
+		}(i)
+	}
+	fn(0, height/workers)
+	wg.Wait()
+}
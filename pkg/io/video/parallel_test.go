@@ -0,0 +1,99 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestWorkers(t *testing.T) {
+	defer SetConcurrencyOptions(ConcurrencyOptions{})
+
+	if n := Workers(); n != runtime.GOMAXPROCS(0) {
+		t.Fatalf("expected %d workers by default, but got %d", runtime.GOMAXPROCS(0), n)
+	}
+	SetConcurrencyOptions(ConcurrencyOptions{Workers: 3})
+	if n := Workers(); n != 3 {
+		t.Fatalf("expected 3 workers, but got %d", n)
+	}
+}
+
+func TestParallelRows(t *testing.T) {
+	defer SetConcurrencyOptions(ConcurrencyOptions{})
+
+	testCases := map[string]struct {
+		workers, height int
+		expected        int
+	}{
+		"Single": {
+			workers: 1, height: 1080, expected: 1,
+		},
+		"Parallel": {
+			workers: 4, height: 1080, expected: 4,
+		},
+		"SmallFrame": {
+			workers: 4, height: 40, expected: 2,
+		},
+		"Empty": {
+			workers: 4, height: 0, expected: 1,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			SetConcurrencyOptions(ConcurrencyOptions{Workers: c.workers})
+
+			var mu sync.Mutex
+			var calls int
+			rows := make([]int, c.height)
+			parallelRows(c.height, func(y0, y1 int) {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				for y := y0; y < y1; y++ {
+					rows[y]++
+				}
+			})
+			if calls != c.expected {
+				t.Fatalf("expected %d calls, but got %d", c.expected, calls)
+			}
+			for y, n := range rows {
+				if n != 1 {
+					t.Fatalf("expected row %d to be processed once, but got %d", y, n)
+				}
+			}
+		})
+	}
+}
+
+func TestParallelConvert(t *testing.T) {
+	defer SetConcurrencyOptions(ConcurrencyOptions{})
+
+	src := image.NewNRGBA(image.Rect(0, 0, 64, 100))
+	for i := range src.Pix {
+		src.Pix[i] = uint8(i)
+	}
+	convert := func(workers int) (*image.YCbCr, *image.RGBA) {
+		SetConcurrencyOptions(ConcurrencyOptions{Workers: workers})
+		var yuv image.YCbCr
+		imageToYCbCr(&yuv, src)
+		var rgba image.RGBA
+		imageToRGBA(&rgba, &yuv)
+		return &yuv, &rgba
+	}
+
+	expectedYUV, expectedRGBA := convert(1)
+	yuv, rgba := convert(4)
+	if !reflect.DeepEqual(expectedYUV, yuv) {
+		t.Fatal("expected the YCbCr images to be the same regardless of the workers")
+	}
+	if !reflect.DeepEqual(expectedRGBA, rgba) {
+		t.Fatal("expected the RGBA images to be the same regardless of the workers")
+	}
+	if c := rgba.At(10, 90).(color.RGBA); c.A != 0xff {
+		t.Fatalf("expected an opaque image, but got %v", c)
+	}
+}
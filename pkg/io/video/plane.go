@@ -0,0 +1,140 @@
+package video
+
+import (
+	"image"
+)
+
+// plane is a view of a frame plane's rows from its first pixel. Rows may be padded, e.g. by
+// drivers that align them, so they're stride bytes apart and only the first width bytes are pixels.
+type plane struct {
+	pix           []uint8
+	stride        int
+	width, height int
+}
+
+// pixPlane returns a plane of height rows of width bytes from offset in pix.
+func pixPlane(pix []uint8, stride, offset, width, height int) plane {
+	return plane{pix: pix[offset:], stride: stride, width: width, height: height}
+}
+
+// rgbaPlane returns img's pixel plane, whose rows are 4 bytes per pixel.
+func rgbaPlane(img *image.RGBA) plane {
+	bounds := img.Rect
+	return pixPlane(img.Pix, img.Stride, img.PixOffset(bounds.Min.X, bounds.Min.Y), 4*bounds.Dx(), bounds.Dy())
+}
+
+// yCbCrPlanes returns img's luma and chroma planes. Chroma planes cover img's bounds, e.g. their
+// last samples are kept at odd 4:2:0 sizes.
+func yCbCrPlanes(img *image.YCbCr) (y, cb, cr plane) {
+	bounds := img.Rect
+	cw, ch := chromaSize(img.SubsampleRatio, bounds)
+	c0 := img.COffset(bounds.Min.X, bounds.Min.Y)
+	y = pixPlane(img.Y, img.YStride, img.YOffset(bounds.Min.X, bounds.Min.Y), bounds.Dx(), bounds.Dy())
+	cb = pixPlane(img.Cb, img.CStride, c0, cw, ch)
+	cr = pixPlane(img.Cr, img.CStride, c0, cw, ch)
+	return y, cb, cr
+}
+
+// row returns row y's pixels.
+func (p plane) row(y int) []uint8 {
+	return p.pix[y*p.stride : y*p.stride+p.width]
+}
+
+// packed reports whether rows aren't padded.
+func (p plane) packed() bool {
+	return p.stride == p.width || p.height <= 1
+}
+
+// bytes returns a packed plane's pixels.
+func (p plane) bytes() []uint8 {
+	return p.pix[:p.width*p.height]
+}
+
+// copyTo copies rows to dst, which has the same size.
+func (p plane) copyTo(dst plane) {
+	if p.packed() && dst.packed() {
+		copy(dst.bytes(), p.bytes())
+		return
+	}
+	for y := 0; y < p.height; y++ {
+		copy(dst.row(y), p.row(y))
+	}
+}
+
+// gray returns the plane as *image.Gray at the origin, which x/image/draw scales like
+// *image.YCbCr planes.
+func (p plane) gray() *image.Gray {
+	return &image.Gray{Pix: p.pix, Stride: p.stride, Rect: image.Rect(0, 0, p.width, p.height)}
+}
+
+// Compact returns frames whose planes are tightly packed from the first pixel, i.e. strides are widths
+// and frames are at the origin, for consumers that take planes as contiguous buffers, e.g.
+// encoders and image encoders in C. Frames that are already packed are kept, and others, e.g.
+// frames with padded rows or Crop sub images, are copied into a buffer that's reused for the next
+// frame. *image.YCbCr, *image.RGBA, *image.NRGBA and *image.Gray are compacted, and other frames are kept.
+func Compact(r Reader) Reader {
+	var yuv image.YCbCr
+	var rgba image.RGBA
+	var gray image.Gray
+	return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		switch src := img.(type) {
+		case *image.RGBA:
+			pix, stride := compactPix(&rgba.Pix, rgbaPlane(src))
+			return &image.RGBA{Pix: pix, Stride: stride, Rect: image.Rect(0, 0, src.Rect.Dx(), src.Rect.Dy())}, release, nil
+		case *image.NRGBA:
+			pix, stride := compactPix(&rgba.Pix, rgbaPlane((*image.RGBA)(src)))
+			return &image.NRGBA{Pix: pix, Stride: stride, Rect: image.Rect(0, 0, src.Rect.Dx(), src.Rect.Dy())}, release, nil
+		case *image.Gray:
+			bounds := src.Rect
+			p := pixPlane(src.Pix, src.Stride, src.PixOffset(bounds.Min.X, bounds.Min.Y), bounds.Dx(), bounds.Dy())
+			pix, stride := compactPix(&gray.Pix, p)
+			return &image.Gray{Pix: pix, Stride: stride, Rect: image.Rect(0, 0, bounds.Dx(), bounds.Dy())}, release, nil
+		}
+
+		src, ok := asYCbCr(img)
+		if !ok {
+			return img, release, nil
+		}
+		y, cb, cr := yCbCrPlanes(src)
+		dst := image.YCbCr{
+			YStride:        y.width,
+			CStride:        cb.width,
+			SubsampleRatio: src.SubsampleRatio,
+			Rect:           image.Rect(0, 0, y.width, y.height),
+		}
+		if y.packed() && cb.packed() && cr.packed() {
+			dst.Y, dst.Cb, dst.Cr = y.bytes(), cb.bytes(), cr.bytes()
+			return &dst, release, nil
+		}
+
+		ySize, cSize := y.width*y.height, cb.width*cb.height
+		if cap(yuv.Y) < ySize+2*cSize {
+			yuv.Y = make([]uint8, ySize+2*cSize)
+		}
+		buf := yuv.Y[:ySize+2*cSize]
+		dst.Y, dst.Cb, dst.Cr = buf[:ySize], buf[ySize:ySize+cSize], buf[ySize+cSize:]
+		y.copyTo(pixPlane(dst.Y, dst.YStride, 0, y.width, y.height))
+		cb.copyTo(pixPlane(dst.Cb, dst.CStride, 0, cb.width, cb.height))
+		cr.copyTo(pixPlane(dst.Cr, dst.CStride, 0, cr.width, cr.height))
+		release()
+		return &dst, func() {}, nil
+	}), r)
+}
+
+// compactPix returns p's pixels and stride, which are copied to buf if rows are padded.
+func compactPix(buf *[]uint8, p plane) ([]uint8, int) {
+	if p.packed() {
+		return p.bytes(), p.width
+	}
+	if cap(*buf) < p.width*p.height {
+		*buf = make([]uint8, p.width*p.height)
+	}
+	pix := (*buf)[:p.width*p.height]
+	p.copyTo(pixPlane(pix, p.width, 0, p.width, p.height))
+	return pix, p.width
+}
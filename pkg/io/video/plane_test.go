@@ -0,0 +1,108 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// paddedYCbCr returns a w x h YCbCr in ratio, whose rows are padded by pad bytes on the right.
+func paddedYCbCr(w, h, pad int, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, w+pad, h), ratio).SubImage(image.Rect(0, 0, w, h)).(*image.YCbCr)
+	for i := range img.Y {
+		img.Y[i] = uint8(i)
+	}
+	for i := range img.Cb {
+		img.Cb[i], img.Cr[i] = uint8(100+i), uint8(200-i)
+	}
+	return img
+}
+
+func TestCompact(t *testing.T) {
+	src := paddedYCbCr(4, 2, 4, image.YCbCrSubsampleRatio420)
+	img, _, err := Compact(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yuv := img.(*image.YCbCr)
+	if yuv.YStride != 4 || yuv.CStride != 2 {
+		t.Fatalf("expected the strides of 4 and 2, but got %d and %d", yuv.YStride, yuv.CStride)
+	}
+	if expected := []uint8{0, 1, 2, 3, 8, 9, 10, 11}; !reflect.DeepEqual(yuv.Y, expected) {
+		t.Fatalf("expected %v, but got %v", expected, yuv.Y)
+	}
+	if expected := []uint8{100, 101}; !reflect.DeepEqual(yuv.Cb, expected) {
+		t.Fatalf("expected %v, but got %v", expected, yuv.Cb)
+	}
+
+	// Packed frames aren't copied
+	img, _, err = Compact(ReaderFunc(func() (image.Image, func(), error) {
+		return yuv, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &img.(*image.YCbCr).Y[0] != &yuv.Y[0] {
+		t.Fatal("expected the packed frame to be kept")
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 3, 3))
+	rgba.SetRGBA(2, 2, color.RGBA{R: 1, G: 2, B: 3, A: 4})
+	img, _, err = Compact(ReaderFunc(func() (image.Image, func(), error) {
+		return rgba.SubImage(image.Rect(1, 1, 3, 3)), func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := img.(*image.RGBA)
+	if sub.Rect != image.Rect(0, 0, 2, 2) || sub.Stride != 8 || len(sub.Pix) != 16 {
+		t.Fatalf("expected a packed frame of 2x2, but got %v with the stride of %d", sub.Rect, sub.Stride)
+	}
+	if c := sub.RGBAAt(1, 1); c != (color.RGBA{R: 1, G: 2, B: 3, A: 4}) {
+		t.Fatalf("expected the pixel of the sub image, but got %v", c)
+	}
+}
+
+func TestPaddedFrames(t *testing.T) {
+	src := paddedYCbCr(4, 2, 4, image.YCbCrSubsampleRatio444)
+	packed, _, err := Compact(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	})).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(img image.Image, transform TransformFunc) image.Image {
+		out, _, err := transform(ReaderFunc(func() (image.Image, func(), error) {
+			return img, func() {}, nil
+		})).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	// Padded frames are converted like packed ones
+	if expected, got := read(packed, ToRGBA), read(src, ToRGBA); !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+	scale := Scale(2, 2, ScalerNearestNeighbor)
+	expected := read(packed, scale).(*image.YCbCr)
+	got := read(src, scale).(*image.YCbCr)
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("expected %v, but got %v", expected, got)
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, 8, 2))
+	for i := range rgba.Pix {
+		rgba.Pix[i] = uint8(i * 3)
+	}
+	padded := rgba.SubImage(image.Rect(0, 0, 4, 2))
+	yuv := read(padded, ToI420).(*image.YCbCr)
+	if expected := read(read(padded, Compact), ToI420).(*image.YCbCr); !reflect.DeepEqual(expected.Y, yuv.Y) || !reflect.DeepEqual(expected.Cb, yuv.Cb) {
+		t.Fatalf("expected %v, but got %v", expected, yuv)
+	}
+}
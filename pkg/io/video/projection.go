@@ -0,0 +1,270 @@
+package video
+
+import (
+	"image"
+	"math"
+	"sync"
+)
+
+// FisheyeLayout is how fisheye images are arranged in a 360° camera's frames.
+type FisheyeLayout int
+
+// FisheyeLayout values.
+const (
+	// FisheyeSingle is one forward-looking fisheye image inscribed in the frame.
+	FisheyeSingle FisheyeLayout = iota
+	// FisheyeDual puts the front and back lens images side by side, each inscribed in half of the frame. Most
+	// consumer 360° cameras output this.
+	FisheyeDual
+)
+
+const (
+	defaultFisheyeFOV     = 180
+	defaultDualFisheyeFOV = 190
+)
+
+type fisheyeConfig struct {
+	fov float64
+}
+
+// FisheyeOption configures fisheye reprojection.
+type FisheyeOption func(*fisheyeConfig)
+
+// WithFisheyeFOV sets each fisheye image's field of view in degrees. The default is 180 for FisheyeSingle and
+// 190 for FisheyeDual, whose lenses overlap slightly.
+func WithFisheyeFOV(degrees float64) FisheyeOption {
+	return func(c *fisheyeConfig) {
+		c.fov = degrees
+	}
+}
+
+// Viewport is the direction and field of view of a FlatView. It can be changed while frames are read, e.g. by
+// viewers.
+type Viewport struct {
+	mu              sync.Mutex
+	yaw, pitch, fov float64
+	version         uint64
+}
+
+// NewViewport creates a Viewport. From the center of the front lens, yaw turns the view right and pitch turns it
+// up; fov is the horizontal field of view. All are in degrees.
+func NewViewport(yaw, pitch, fov float64) *Viewport {
+	v := &Viewport{}
+	v.Set(yaw, pitch, fov)
+	return v
+}
+
+// Set changes the view, starting with the next frame. pitch is clamped to ±90 and fov to (0, 180).
+func (v *Viewport) Set(yaw, pitch, fov float64) {
+	pitch = math.Max(-90, math.Min(90, pitch))
+	fov = math.Max(1, math.Min(179, fov))
+	v.mu.Lock()
+	v.yaw, v.pitch, v.fov = yaw, pitch, fov
+	v.version++
+	v.mu.Unlock()
+}
+
+// Get returns the current view.
+func (v *Viewport) Get() (yaw, pitch, fov float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.yaw, v.pitch, v.fov
+}
+
+func (v *Viewport) get() (yaw, pitch, fov float64, version uint64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.yaw, v.pitch, v.fov, v.version
+}
+
+// direction returns the ray direction for the output pixel at (x, y), in front lens coordinates where x points
+// right, y down and z forward. x and y are pixel centers in output pixels.
+type direction func(x, y float64) (float64, float64, float64)
+
+// Equirectangular reprojects fisheye frames in layout to width x height equirectangular frames, as 360° players
+// expect. The front is at the center, and 2:1 frames give square pixels. Directions the lenses don't cover are
+// black. See FlatView for how frames are handled.
+func Equirectangular(layout FisheyeLayout, width, height int, opts ...FisheyeOption) TransformFunc {
+	dir := func(x, y float64) (float64, float64, float64) {
+		lon := (x/float64(width) - 0.5) * 2 * math.Pi
+		lat := (0.5 - y/float64(height)) * math.Pi
+		return math.Cos(lat) * math.Sin(lon), -math.Sin(lat), math.Cos(lat) * math.Cos(lon)
+	}
+	return reproject(layout, width, height, opts, func() (direction, uint64) { return dir, 0 })
+}
+
+// FlatView reprojects fisheye frames in layout to width x height rectilinear frames looking along view, like a
+// normal camera pointed that way. Use it to stream a viewer-chosen framing from a 360° camera.
+//
+// Frames must be RGBA or YCbCr; YCbCr is reprojected to 4:2:0. Pixels are interpolated bilinearly into a buffer
+// that's reused for the next frame, so each frame is only valid until the next one is read.
+func FlatView(layout FisheyeLayout, view *Viewport, width, height int, opts ...FisheyeOption) TransformFunc {
+	return reproject(layout, width, height, opts, func() (direction, uint64) {
+		yaw, pitch, fov, version := view.get()
+		sinYaw, cosYaw := math.Sincos(yaw * math.Pi / 180)
+		sinPitch, cosPitch := math.Sincos(pitch * math.Pi / 180)
+		focal := float64(width) / 2 / math.Tan(fov*math.Pi/360)
+		return func(x, y float64) (float64, float64, float64) {
+			dx, dy, dz := x-float64(width)/2, y-float64(height)/2, focal
+			// Pitch around the x axis, then yaw around the y axis
+			dy, dz = dy*cosPitch-dz*sinPitch, dy*sinPitch+dz*cosPitch
+			dx, dz = dx*cosYaw+dz*sinYaw, -dx*sinYaw+dz*cosYaw
+			n := math.Sqrt(dx*dx + dy*dy + dz*dz)
+			return dx / n, dy / n, dz / n
+		}, version
+	})
+}
+
+// projectionMap holds the source coordinates of each sample of an output plane, in source plane samples.
+// Samples the lenses don't cover are NaN.
+type projectionMap []float32
+
+func reproject(layout FisheyeLayout, width, height int, opts []FisheyeOption, view func() (direction, uint64)) TransformFunc {
+	c := fisheyeConfig{fov: defaultFisheyeFOV}
+	if layout == FisheyeDual {
+		c.fov = defaultDualFisheyeFOV
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(r Reader) Reader {
+		var (
+			bounds    image.Rectangle
+			ratio     image.YCbCrSubsampleRatio
+			version   uint64
+			lumaMap   projectionMap
+			chromaMap projectionMap
+			rgba      *image.RGBA
+			yuv       *image.YCbCr
+		)
+		outRect := image.Rect(0, 0, width, height)
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			defer release()
+			if i, ok := img.(interface{ YCbCr() *image.YCbCr }); ok {
+				if y := i.YCbCr(); y != nil {
+					img = y
+				}
+			}
+
+			dir, v := view()
+			src, isYCbCr := img.(*image.YCbCr)
+			stale := img.Bounds() != bounds || v != version || lumaMap == nil
+			if isYCbCr && (chromaMap == nil || src.SubsampleRatio != ratio) {
+				stale = true
+			}
+			if stale {
+				bounds, version = img.Bounds(), v
+				lumaMap = newProjectionMap(layout, c.fov, dir, bounds, 1, 1, width, height, 1, 1)
+				chromaMap = nil
+				if isYCbCr {
+					ratio = src.SubsampleRatio
+					sx, sy, err := subsampleFactors(ratio)
+					if err != nil {
+						return nil, func() {}, err
+					}
+					chromaMap = newProjectionMap(layout, c.fov, dir, bounds, sx, sy, (width+1)/2, (height+1)/2, 2, 2)
+				}
+			}
+
+			switch src := img.(type) {
+			case *image.RGBA:
+				if rgba == nil {
+					rgba = image.NewRGBA(outRect)
+				}
+				samplePlane(rgbaPlane(rgba), rgbaPlane(src), 4, lumaMap, 0)
+				return rgba, func() {}, nil
+			case *image.YCbCr:
+				if yuv == nil {
+					yuv = image.NewYCbCr(outRect, image.YCbCrSubsampleRatio420)
+				}
+				dy, dcb, dcr := yCbCrPlanes(yuv)
+				sy, scb, scr := yCbCrPlanes(src)
+				samplePlane(dy, sy, 1, lumaMap, 16)
+				samplePlane(dcb, scb, 1, chromaMap, 128)
+				samplePlane(dcr, scr, 1, chromaMap, 128)
+				return yuv, func() {}, nil
+			default:
+				return nil, func() {}, errUnsupportedImageType
+			}
+		})
+	}
+}
+
+// newProjectionMap maps a width x height output plane, whose samples span dsx x dsy output pixels, onto a source
+// plane of bounds, whose samples span ssx x ssy source pixels.
+func newProjectionMap(layout FisheyeLayout, fov float64, dir direction, bounds image.Rectangle, ssx, ssy, width, height, dsx, dsy int) projectionMap {
+	halfFOV := fov * math.Pi / 360
+	lensWidth := float64(bounds.Dx())
+	if layout == FisheyeDual {
+		lensWidth /= 2
+	}
+	radius := math.Min(lensWidth, float64(bounds.Dy())) / 2
+
+	m := make(projectionMap, 2*width*height)
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			x, y, z := dir((float64(i)+0.5)*float64(dsx), (float64(j)+0.5)*float64(dsy))
+			cx := lensWidth / 2
+			if layout == FisheyeDual && z < 0 {
+				// The back lens faces backward, so turn it around the y axis
+				x, z = -x, -z
+				cx += lensWidth
+			}
+
+			k := 2 * (j*width + i)
+			// Equidistant projection: the distance from the center is proportional to the angle
+			theta := math.Acos(math.Max(-1, math.Min(1, z)))
+			if theta > halfFOV {
+				m[k], m[k+1] = float32(math.NaN()), float32(math.NaN())
+				continue
+			}
+			rxy := math.Hypot(x, y)
+			var px, py float64
+			if rxy > 0 {
+				r := radius * theta / halfFOV
+				px, py = r*x/rxy, r*y/rxy
+			}
+			// Coordinates in source samples, whose centers are at +0.5
+			m[k] = float32((cx+px)/float64(ssx) - 0.5)
+			m[k+1] = float32((float64(bounds.Dy())/2+py)/float64(ssy) - 0.5)
+		}
+	}
+	return m
+}
+
+// samplePlane bilinearly interpolates dst from src using m, byte by byte for pixels of bpp bytes. Samples the
+// lenses don't cover are filled with black.
+func samplePlane(dst, src plane, bpp int, m projectionMap, black uint8) {
+	w, h := src.width/bpp, src.height
+	for j := 0; j < dst.height; j++ {
+		row := dst.row(j)
+		for i := 0; i < dst.width/bpp; i++ {
+			k := 2 * (j*(dst.width/bpp) + i)
+			sx, sy := float64(m[k]), float64(m[k+1])
+			if math.IsNaN(sx) {
+				for b := 0; b < bpp; b++ {
+					row[i*bpp+b] = black
+				}
+				if bpp == 4 {
+					row[i*bpp+3] = 255
+				}
+				continue
+			}
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			fx, fy := sx-float64(x0), sy-float64(y0)
+			x0c, x1c := clampInt(x0, 0, w-1), clampInt(x0+1, 0, w-1)
+			y0c, y1c := clampInt(y0, 0, h-1), clampInt(y0+1, 0, h-1)
+			r0, r1 := src.row(y0c), src.row(y1c)
+			for b := 0; b < bpp; b++ {
+				top := float64(r0[x0c*bpp+b])*(1-fx) + float64(r0[x1c*bpp+b])*fx
+				bottom := float64(r1[x0c*bpp+b])*(1-fx) + float64(r1[x1c*bpp+b])*fx
+				row[i*bpp+b] = uint8(top*(1-fy) + bottom*fy + 0.5)
+			}
+		}
+	}
+}
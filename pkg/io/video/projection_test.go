@@ -0,0 +1,125 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestEquirectangular(t *testing.T) {
+	red := &image.Uniform{color.RGBA{255, 0, 0, 255}}
+	green := &image.Uniform{color.RGBA{0, 255, 0, 255}}
+	blue := &image.Uniform{color.RGBA{0, 0, 255, 255}}
+	black := color.RGBA{0, 0, 0, 255}
+
+	testCases := map[string]struct {
+		layout   FisheyeLayout
+		src      func() *image.RGBA
+		expected map[image.Point]color.RGBA
+	}{
+		"Single": {
+			layout: FisheyeSingle,
+			src: func() *image.RGBA {
+				img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+				draw.Draw(img, image.Rect(0, 0, 100, 200), red, image.Point{}, draw.Src)
+				draw.Draw(img, image.Rect(100, 0, 200, 200), blue, image.Point{}, draw.Src)
+				return img
+			},
+			expected: map[image.Point]color.RGBA{
+				// Left and right of the front, at ±45°
+				{150, 100}: {255, 0, 0, 255},
+				{250, 100}: {0, 0, 255, 255},
+				// The back isn't covered
+				{40, 100}:  black,
+				{360, 100}: black,
+			},
+		},
+		"Dual": {
+			layout: FisheyeDual,
+			src: func() *image.RGBA {
+				img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+				draw.Draw(img, image.Rect(0, 0, 200, 200), green, image.Point{}, draw.Src)
+				draw.Draw(img, image.Rect(200, 0, 400, 200), blue, image.Point{}, draw.Src)
+				return img
+			},
+			expected: map[image.Point]color.RGBA{
+				{200, 100}: {0, 255, 0, 255},
+				{120, 60}:  {0, 255, 0, 255},
+				{10, 100}:  {0, 0, 255, 255},
+				{390, 140}: {0, 0, 255, 255},
+			},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			src := c.src()
+			r := Equirectangular(c.layout, 400, 200)(ReaderFunc(func() (image.Image, func(), error) {
+				return src, func() {}, nil
+			}))
+			out, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Bounds() != image.Rect(0, 0, 400, 200) {
+				t.Fatalf("expected the bounds of 400x200, but got %v", out.Bounds())
+			}
+			for p, expected := range c.expected {
+				if got := out.(*image.RGBA).RGBAAt(p.X, p.Y); got != expected {
+					t.Errorf("expected %v at %v, but got %v", expected, p, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFlatView(t *testing.T) {
+	// Bright top half, dark bottom half
+	src := image.NewYCbCr(image.Rect(0, 0, 200, 200), image.YCbCrSubsampleRatio422)
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			luma := uint8(200)
+			if y >= 100 {
+				luma = 50
+			}
+			src.Y[src.YOffset(x, y)] = luma
+			src.Cb[src.COffset(x, y)], src.Cr[src.COffset(x, y)] = 100, 150
+		}
+	}
+
+	view := NewViewport(0, 45, 60)
+	r := FlatView(FisheyeSingle, view, 64, 48)(ReaderFunc(func() (image.Image, func(), error) {
+		return src, func() {}, nil
+	}))
+
+	read := func() *image.YCbCr {
+		out, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out.(*image.YCbCr)
+	}
+	out := read()
+	if out.SubsampleRatio != image.YCbCrSubsampleRatio420 || out.Rect != image.Rect(0, 0, 64, 48) {
+		t.Fatalf("expected 4:2:0 of 64x48, but got %v of %v", out.SubsampleRatio, out.Rect)
+	}
+	if c := out.YCbCrAt(32, 24); c != (color.YCbCr{200, 100, 150}) {
+		t.Fatalf("expected the top of the lens, but got %v", c)
+	}
+
+	// The view turns down from the next frame
+	view.Set(0, -45, 60)
+	if yaw, pitch, fov := view.Get(); yaw != 0 || pitch != -45 || fov != 60 {
+		t.Fatalf("expected the view of (0, -45, 60), but got (%v, %v, %v)", yaw, pitch, fov)
+	}
+	if c := read().YCbCrAt(32, 24); c.Y != 50 {
+		t.Fatalf("expected the bottom of the lens, but got %v", c)
+	}
+
+	// The back isn't covered
+	view.Set(180, 0, 60)
+	if c := read().YCbCrAt(32, 24); c != (color.YCbCr{16, 128, 128}) {
+		t.Fatalf("expected black, but got %v", c)
+	}
+}
@@ -0,0 +1,197 @@
+package video
+
+import (
+	"image"
+	"math"
+)
+
+// MaxPSNR is the PSNR of identical frames, which is otherwise infinite.
+const MaxPSNR = 100
+
+// PSNR returns the luma PSNR of b against a in dB. Frames are compared at the smaller one's size.
+func PSNR(a, b *image.YCbCr) float64 {
+	pa, pb := lumaPlanes(a, b)
+	var sse float64
+	for y := 0; y < pa.height; y++ {
+		ra, rb := pa.row(y), pb.row(y)
+		for x := range ra {
+			d := float64(ra[x]) - float64(rb[x])
+			sse += d * d
+		}
+	}
+	if sse == 0 {
+		return MaxPSNR
+	}
+	return math.Min(MaxPSNR, 10*math.Log10(255*255*float64(pa.width*pa.height)/sse))
+}
+
+// ssimBlock is the SSIM block size. Blocks don't overlap, to keep the cost low.
+const ssimBlock = 8
+
+// SSIM returns the mean SSIM of b's 8x8 luma blocks against a, which is 1 for identical frames.
+// Frames are compared at the smaller one's size.
+func SSIM(a, b *image.YCbCr) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+		n  = ssimBlock * ssimBlock
+	)
+	pa, pb := lumaPlanes(a, b)
+	var total float64
+	var blocks int
+	for by := 0; by+ssimBlock <= pa.height; by += ssimBlock {
+		for bx := 0; bx+ssimBlock <= pa.width; bx += ssimBlock {
+			var sa, sb, saa, sbb, sab float64
+			for y := by; y < by+ssimBlock; y++ {
+				ra, rb := pa.row(y)[bx:bx+ssimBlock], pb.row(y)[bx:bx+ssimBlock]
+				for x := range ra {
+					va, vb := float64(ra[x]), float64(rb[x])
+					sa, sb = sa+va, sb+vb
+					saa, sbb, sab = saa+va*va, sbb+vb*vb, sab+va*vb
+				}
+			}
+			ma, mb := sa/n, sb/n
+			va, vb, cov := saa/n-ma*ma, sbb/n-mb*mb, sab/n-ma*mb
+			total += (2*ma*mb + c1) * (2*cov + c2) / ((ma*ma + mb*mb + c1) * (va + vb + c2))
+			blocks++
+		}
+	}
+	if blocks == 0 {
+		return 1
+	}
+	return total / float64(blocks)
+}
+
+// lumaPlanes returns a's and b's luma planes at the smaller one's size.
+func lumaPlanes(a, b *image.YCbCr) (plane, plane) {
+	pa, _, _ := yCbCrPlanes(a)
+	pb, _, _ := yCbCrPlanes(b)
+	if pb.width < pa.width {
+		pa.width = pb.width
+	}
+	if pb.height < pa.height {
+		pa.height = pb.height
+	}
+	pb.width, pb.height = pa.width, pa.height
+	return pa, pb
+}
+
+// QualityStats is frame quality measured by Quality.
+type QualityStats struct {
+	// PSNR and SSIM are luma means over the frames in the window.
+	PSNR float64
+	SSIM float64
+	// MinPSNR is the worst frame's PSNR in the window.
+	MinPSNR float64
+	// Frames is the total number of measured frames.
+	Frames uint64
+}
+
+const defaultQualityWindow = 30
+
+type qualityConfig struct {
+	window int
+}
+
+// QualityOption configures Quality.
+type QualityOption func(*qualityConfig)
+
+// WithQualityWindow sets how many recent frames' scores are averaged. The default is 30.
+func WithQualityWindow(frames int) QualityOption {
+	return func(c *qualityConfig) {
+		c.window = frames
+	}
+}
+
+// Quality measures PSNR and SSIM of frames against reference's frames, e.g. an encoder's decoded
+// frames against the frames before the encoder, to quantify how much settings degrade them. A frame is read
+// from reference for each frame, so reference has to be in sync with the frames, e.g. a reader of the source's
+// Broadcaster. Frames of other sizes are scaled to the reference size, i.e. they're compared as
+// they're displayed. onStats is called with rolling scores for each frame in the reading goroutine, so it
+// shouldn't block. Frames are passed through.
+func Quality(reference Reader, onStats func(QualityStats), opts ...QualityOption) TransformFunc {
+	c := qualityConfig{
+		window: defaultQualityWindow,
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.window < 1 {
+		c.window = 1
+	}
+
+	return func(r Reader) Reader {
+		var (
+			stats        QualityStats
+			psnrs, ssims []float64
+			frame        image.Image
+			scaled       Reader
+			scaledSize   image.Point
+		)
+		ref := ToI420(reference)
+		src := ToI420(ReaderFunc(func() (image.Image, func(), error) {
+			return frame, func() {}, nil
+		}))
+		return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			refImg, releaseRef, err := ref.Read()
+			if err != nil {
+				release()
+				return nil, func() {}, err
+			}
+
+			frame = img
+			target := refImg.Bounds().Size()
+			measured := src
+			if img.Bounds().Size() != target {
+				if scaled == nil || scaledSize != target {
+					scaled = Merge(Scale(target.X, target.Y, ScalerBiLinear), ToI420)(ReaderFunc(func() (image.Image, func(), error) {
+						return frame, func() {}, nil
+					}))
+					scaledSize = target
+				}
+				measured = scaled
+			}
+			yuv, _, err := measured.Read()
+			if err != nil {
+				releaseRef()
+				release()
+				return nil, func() {}, err
+			}
+
+			a, b := refImg.(*image.YCbCr), yuv.(*image.YCbCr)
+			psnrs = appendWindow(psnrs, PSNR(a, b), c.window)
+			ssims = appendWindow(ssims, SSIM(a, b), c.window)
+			releaseRef()
+			stats.Frames++
+			stats.PSNR, stats.SSIM, stats.MinPSNR = mean(psnrs), mean(ssims), psnrs[0]
+			for _, p := range psnrs {
+				stats.MinPSNR = math.Min(stats.MinPSNR, p)
+			}
+			if onStats != nil {
+				onStats(stats)
+			}
+			return img, release, nil
+		}), r)
+	}
+}
+
+// appendWindow appends v to values, and keeps the last n values.
+func appendWindow(values []float64, v float64, n int) []float64 {
+	if len(values) >= n {
+		copy(values, values[len(values)-n+1:])
+		values = values[:n-1]
+	}
+	return append(values, v)
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
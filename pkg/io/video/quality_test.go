@@ -0,0 +1,96 @@
+package video
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestPSNR(t *testing.T) {
+	a := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	for i := range a.Y {
+		a.Y[i] = uint8(i % 200)
+	}
+	if p, s := PSNR(a, a), SSIM(a, a); p != MaxPSNR || math.Abs(s-1) > 1e-9 {
+		t.Fatalf("expected %d dB and SSIM of 1 for the identical frames, but got %f and %f", MaxPSNR, p, s)
+	}
+
+	// A difference of 1 in every pixel is 10*log10(255^2) dB
+	b := paddedYCbCr(16, 16, 8, image.YCbCrSubsampleRatio420)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			b.Y[b.YOffset(x, y)] = a.Y[a.YOffset(x, y)] + 1
+		}
+	}
+	if p, expected := PSNR(a, b), 20*math.Log10(255); math.Abs(p-expected) > 1e-9 {
+		t.Fatalf("expected %f dB, but got %f", expected, p)
+	}
+	if s := SSIM(a, b); s >= 1 || s < 0.9 {
+		t.Fatalf("expected SSIM slightly below 1, but got %f", s)
+	}
+}
+
+func TestQuality(t *testing.T) {
+	ref := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	for i := range ref.Y {
+		ref.Y[i] = uint8(4 * i)
+	}
+	// Frames are the reference, the reference with noise, and the reference at half size
+	noisy := image.NewYCbCr(ref.Rect, image.YCbCrSubsampleRatio420)
+	copy(noisy.Y, ref.Y)
+	for i := 0; i < len(noisy.Y); i += 3 {
+		noisy.Y[i] ^= 8
+	}
+	frames := []image.Image{ref, noisy}
+
+	var stats []QualityStats
+	var released int
+	r := Quality(ReaderFunc(func() (image.Image, func(), error) {
+		return ref, func() { released++ }, nil
+	}), func(s QualityStats) {
+		stats = append(stats, s)
+	}, WithQualityWindow(1))(ReaderFunc(func() (image.Image, func(), error) {
+		img := frames[0]
+		frames = frames[1:]
+		return img, func() {}, nil
+	}))
+	for i := 0; i < 2; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(stats) != 2 || stats[1].Frames != 2 {
+		t.Fatalf("expected the stats of 2 frames, but got %+v", stats)
+	}
+	if stats[0].PSNR != MaxPSNR || stats[1].PSNR >= MaxPSNR || stats[1].MinPSNR != stats[1].PSNR {
+		t.Fatalf("expected the window of the last frame, but got %+v", stats)
+	}
+	if released != 2 {
+		t.Fatalf("expected 2 reference frames to be released, but got %d", released)
+	}
+}
+
+func TestQualityScaled(t *testing.T) {
+	ref := image.NewYCbCr(image.Rect(0, 0, 32, 32), image.YCbCrSubsampleRatio420)
+	small := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+
+	var got QualityStats
+	r := Quality(ReaderFunc(func() (image.Image, func(), error) {
+		return ref, func() {}, nil
+	}), func(s QualityStats) {
+		got = s
+	})(ReaderFunc(func() (image.Image, func(), error) {
+		return small, func() {}, nil
+	}))
+	img, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img != small {
+		t.Fatal("expected the frame to be passed through")
+	}
+	// Flat frames are identical after scaling
+	if got.PSNR != MaxPSNR {
+		t.Fatalf("expected the scaled frame to be compared, but got %+v", got)
+	}
+}
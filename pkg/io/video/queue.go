@@ -0,0 +1,210 @@
+package video
+
+import (
+	"errors"
+	"image"
+	"runtime"
+	"sync/atomic"
+)
+
+// queueSpins is how many times the FrameQueue consumer yields before sleeping for the next frame. At high frame
+// rates the next frame usually arrives while it spins, which avoids wake-up latency.
+const queueSpins = 64
+
+// ErrQueueClosed is returned by FrameQueue.Read once the queue is closed and drained.
+var ErrQueueClosed = errors.New("video: frame queue is closed")
+
+// QueueStats is a snapshot of a FrameQueue.
+type QueueStats struct {
+	// Depth is the number of frames waiting in the queue.
+	Depth int
+	// Capacity is the most frames the queue can hold.
+	Capacity int
+	// Dropped is the number of frames dropped because the queue was full.
+	Dropped uint64
+}
+
+// queuedFrame is a slot in the FrameQueue ring.
+type queuedFrame struct {
+	img      image.Image
+	release  func()
+	err      error
+	metadata Metadata
+}
+
+// FrameQueue hands frames from the capture goroutine to the transform goroutine, e.g. to keep capturing at the
+// device frame rate while a frame is transformed or encoded. It's a lock-free single-producer single-consumer
+// ring: Run and Read must each be called from one goroutine, and frames pass without a lock or channel. The
+// consumer only sleeps when the queue is empty.
+type FrameQueue struct {
+	// head counts frames pushed by Run and tail counts frames popped by Read; both wrap around.
+	// IMPORTANT: dropped has to be at an offset of a multiple of 8, otherwise the atomic operations
+	//            will panic in 32 bits systems due to unalignment
+	head    uint32
+	_       [60]byte // keeps head and tail on separate cache lines
+	tail    uint32
+	_       [60]byte
+	dropped uint64
+	closed  uint32
+	done    uint32
+	waiting uint32
+
+	src   Reader
+	slots []queuedFrame
+	mask  uint32
+	wake  chan struct{}
+	err   error
+	last  lastMetadata
+}
+
+// NewFrameQueue creates a queue of frames from r that holds up to depth frames. depth is rounded up to a power
+// of 2, with a minimum of 1. Run reads the frames from r.
+func NewFrameQueue(r Reader, depth int) *FrameQueue {
+	n := 1
+	for n < depth {
+		n <<= 1
+	}
+	return &FrameQueue{
+		src:   r,
+		slots: make([]queuedFrame, n),
+		mask:  uint32(n - 1),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Run reads frames from the source and pushes them to the queue until the source returns an error or the queue
+// is closed. When the queue is full, the new frame is released and dropped, so the queue keeps the oldest frames
+// in order. Call it from the capture goroutine.
+func (q *FrameQueue) Run() {
+	defer func() {
+		atomic.StoreUint32(&q.done, 1)
+		q.notify()
+	}()
+
+	for atomic.LoadUint32(&q.closed) == 0 {
+		img, release, err := q.src.Read()
+		frame := queuedFrame{img: img, release: release, err: err}
+		if err == nil {
+			frame.metadata, _ = MetadataOf(q.src)
+		}
+
+		for !q.push(frame) {
+			if err == nil {
+				release()
+				atomic.AddUint64(&q.dropped, 1)
+				break
+			}
+			// Errors aren't dropped, since they end the stream
+			if atomic.LoadUint32(&q.closed) != 0 {
+				return
+			}
+			runtime.Gosched()
+		}
+		q.notify()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// push adds frame to the ring, or returns false if the ring is full.
+func (q *FrameQueue) push(frame queuedFrame) bool {
+	head := atomic.LoadUint32(&q.head)
+	if head-atomic.LoadUint32(&q.tail) > q.mask {
+		return false
+	}
+	q.slots[head&q.mask] = frame
+	atomic.StoreUint32(&q.head, head+1)
+	return true
+}
+
+// pop removes the oldest frame from the ring, or returns false if the ring is empty.
+func (q *FrameQueue) pop() (queuedFrame, bool) {
+	tail := atomic.LoadUint32(&q.tail)
+	if tail == atomic.LoadUint32(&q.head) {
+		return queuedFrame{}, false
+	}
+	frame := q.slots[tail&q.mask]
+	// Clear the references before handing the slot back to Run
+	q.slots[tail&q.mask] = queuedFrame{}
+	atomic.StoreUint32(&q.tail, tail+1)
+	return frame, true
+}
+
+// notify wakes Read if it's sleeping.
+func (q *FrameQueue) notify() {
+	if atomic.CompareAndSwapUint32(&q.waiting, 1, 0) {
+		select {
+		case q.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Read returns the oldest queued frame, waiting for one if the queue is empty. Once the source has returned an
+// error, Read returns that error, and once the queue is closed, it returns ErrQueueClosed after the frames pushed
+// before then.
+func (q *FrameQueue) Read() (image.Image, func(), error) {
+	if q.err != nil {
+		return nil, func() {}, q.err
+	}
+
+	for spins := 0; ; spins++ {
+		frame, ok := q.pop()
+		if !ok {
+			// Frames pushed before Run returned are read first
+			if atomic.LoadUint32(&q.done) != 0 {
+				if frame, ok = q.pop(); !ok {
+					q.err = ErrQueueClosed
+					return nil, func() {}, q.err
+				}
+			} else if spins < queueSpins {
+				runtime.Gosched()
+				continue
+			} else {
+				q.wait()
+				continue
+			}
+		}
+
+		if frame.err != nil {
+			q.err = frame.err
+			return nil, func() {}, frame.err
+		}
+		q.last.store(frame.metadata)
+		return frame.img, frame.release, nil
+	}
+}
+
+// wait sleeps until a frame is pushed or Run returns. waiting is set before the ring is checked again, so
+// either Run sees it after pushing, or the frame is found here.
+func (q *FrameQueue) wait() {
+	atomic.StoreUint32(&q.waiting, 1)
+	if atomic.LoadUint32(&q.tail) != atomic.LoadUint32(&q.head) || atomic.LoadUint32(&q.done) != 0 {
+		atomic.StoreUint32(&q.waiting, 0)
+		return
+	}
+	<-q.wake
+}
+
+// Metadata returns the metadata the frame returned by the last Read was read with.
+func (q *FrameQueue) Metadata() Metadata {
+	return q.last.load()
+}
+
+// Stats returns the number of queued and dropped frames. It's safe to call from any goroutine.
+func (q *FrameQueue) Stats() QueueStats {
+	// Load tail first so it can't pass head
+	tail := atomic.LoadUint32(&q.tail)
+	return QueueStats{
+		Depth:    int(atomic.LoadUint32(&q.head) - tail),
+		Capacity: len(q.slots),
+		Dropped:  atomic.LoadUint64(&q.dropped),
+	}
+}
+
+// Close stops Run after the frame it's reading. Read still returns the queued frames. It's safe to call from any
+// goroutine.
+func (q *FrameQueue) Close() {
+	atomic.StoreUint32(&q.closed, 1)
+}
@@ -0,0 +1,87 @@
+package video
+
+import (
+	"errors"
+	"image"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFrameQueue(t *testing.T) {
+	errEnd := errors.New("end")
+	const frames = 1000
+	var n, released int32
+	src := Stamp(ReaderFunc(func() (image.Image, func(), error) {
+		if atomic.AddInt32(&n, 1) > frames {
+			return nil, func() {}, errEnd
+		}
+		return image.NewGray(image.Rect(0, 0, 2, 2)), func() { atomic.AddInt32(&released, 1) }, nil
+	}), time.Now)
+
+	q := NewFrameQueue(src, 3)
+	go q.Run()
+
+	// Frames are read in order, and frames dropped by the full queue show up as gaps in the sequence numbers
+	var read int
+	next := uint64(0)
+	for {
+		_, release, err := q.Read()
+		if err == errEnd {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := q.Metadata(); m.Sequence < next {
+			t.Fatalf("expected the sequence after %d, but got %d", next, m.Sequence)
+		} else {
+			next = m.Sequence + 1
+		}
+		release()
+		read++
+		if read%10 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	stats := q.Stats()
+	if stats.Capacity != 4 || stats.Depth != 0 {
+		t.Fatalf("expected the empty queue of 4 frames, but got %+v", stats)
+	}
+	if uint64(read)+stats.Dropped != frames {
+		t.Fatalf("expected %d frames read or dropped, but got %d and %d", frames, read, stats.Dropped)
+	}
+	if released != frames {
+		t.Fatalf("expected %d frames released, but got %d", frames, released)
+	}
+	// The error is kept
+	if _, _, err := q.Read(); err != errEnd {
+		t.Fatalf("expected %v, but got %v", errEnd, err)
+	}
+}
+
+func TestFrameQueueClose(t *testing.T) {
+	frames := make(chan struct{})
+	src := ReaderFunc(func() (image.Image, func(), error) {
+		<-frames
+		return image.NewGray(image.Rect(0, 0, 2, 2)), func() {}, nil
+	})
+
+	q := NewFrameQueue(src, 4)
+	go q.Run()
+	frames <- struct{}{}
+	if _, _, err := q.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The frame read during Close comes before ErrQueueClosed
+	q.Close()
+	frames <- struct{}{}
+	if _, _, err := q.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := q.Read(); err != ErrQueueClosed {
+		t.Fatalf("expected %v, but got %v", ErrQueueClosed, err)
+	}
+}
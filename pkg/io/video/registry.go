@@ -0,0 +1,346 @@
+package video
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransformBuilder builds a transform from its parameters, which have the type of the defaults it was
+// registered with.
+type TransformBuilder func(params interface{}) (TransformFunc, error)
+
+type registeredTransform struct {
+	defaults interface{}
+	build    TransformBuilder
+}
+
+var (
+	transformsMu sync.Mutex
+	transforms   = make(map[string]registeredTransform)
+)
+
+// RegisterTransform registers a transform by name, so pipelines can be configured without recompiling,
+// see PipelineConfig. defaults is a struct of parameters with their default values, whose fields are decoded
+// from config JSON parameters, or nil if the transform has no parameters.
+func RegisterTransform(name string, defaults interface{}, build TransformBuilder) error {
+	if defaults != nil && reflect.TypeOf(defaults).Kind() != reflect.Struct {
+		return fmt.Errorf("the parameters of %s must be a struct", name)
+	}
+
+	transformsMu.Lock()
+	defer transformsMu.Unlock()
+	if _, ok := transforms[name]; ok {
+		return fmt.Errorf("transform %s is already registered", name)
+	}
+	transforms[name] = registeredTransform{defaults: defaults, build: build}
+	return nil
+}
+
+// RegisteredTransforms returns registered transform names in order.
+func RegisteredTransforms() []string {
+	transformsMu.Lock()
+	names := make([]string, 0, len(transforms))
+	for name := range transforms {
+		names = append(names, name)
+	}
+	transformsMu.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// NewTransform builds the transform registered by name with params in JSON. Unset parameters keep
+// their default values, and unknown ones are errors to catch config typos.
+func NewTransform(name string, params json.RawMessage) (TransformFunc, error) {
+	transformsMu.Lock()
+	t, ok := transforms[name]
+	transformsMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown transform %s", name)
+	}
+
+	var value interface{}
+	if t.defaults != nil {
+		v := reflect.New(reflect.TypeOf(t.defaults))
+		v.Elem().Set(reflect.ValueOf(t.defaults))
+		if len(bytes.TrimSpace(params)) > 0 {
+			dec := json.NewDecoder(bytes.NewReader(params))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(v.Interface()); err != nil {
+				return nil, fmt.Errorf("failed to decode the parameters of %s: %s", name, err)
+			}
+		}
+		value = v.Elem().Interface()
+	}
+
+	transform, err := t.build(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s: %s", name, err)
+	}
+	return transform, nil
+}
+
+// TransformConfig is a transform of a pipeline.
+type TransformConfig struct {
+	Name   string          `json:"name"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// PipelineConfig describes a pipeline's transforms in order, e.g.
+//
+//	{"transforms": [
+//		{"name": "crop", "params": {"x": 0, "y": 0, "width": 1280, "height": 720}},
+//		{"name": "scale", "params": {"width": 640, "height": -1, "scaler": "bilinear"}},
+//		{"name": "throttle", "params": {"rate": 15}}
+//	]}
+type PipelineConfig struct {
+	Transforms []TransformConfig `json:"transforms"`
+}
+
+// ParsePipelineConfig parses a pipeline in JSON, which is either a PipelineConfig or an array of its transforms.
+func ParsePipelineConfig(data []byte) (PipelineConfig, error) {
+	var c PipelineConfig
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &c.Transforms)
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&c)
+	}
+	if err != nil {
+		return PipelineConfig{}, fmt.Errorf("failed to parse the pipeline: %s", err)
+	}
+	return c, nil
+}
+
+// Build builds the pipeline's transforms, and merges them in order.
+func (c PipelineConfig) Build() (TransformFunc, error) {
+	fns := make([]TransformFunc, len(c.Transforms))
+	for i, t := range c.Transforms {
+		fn, err := NewTransform(t.Name, t.Params)
+		if err != nil {
+			return nil, fmt.Errorf("transform %d: %s", i, err)
+		}
+		fns[i] = fn
+	}
+	return Merge(fns...), nil
+}
+
+type cropParams struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type scaleParams struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Scaler is one of nearest, approxbilinear, bilinear and catmullrom.
+	Scaler string `json:"scaler"`
+}
+
+type throttleParams struct {
+	Rate float32 `json:"rate"`
+}
+
+type timelapseParams struct {
+	// Interval is in seconds.
+	Interval  float64 `json:"interval"`
+	Averaging bool    `json:"averaging"`
+}
+
+type chromaParams struct {
+	// Ratio is one of 444, 422, 420, 440, 411 and 410.
+	Ratio string `json:"ratio"`
+}
+
+type autoFrameParams struct {
+	Interval  int     `json:"interval"`
+	MaxZoom   float64 `json:"maxZoom"`
+	Smoothing float64 `json:"smoothing"`
+}
+
+type maskParams struct {
+	Regions []cropParams `json:"regions"`
+	// Mode is one of blur, pixelate and solid.
+	Mode     string `json:"mode"`
+	Strength int    `json:"strength"`
+}
+
+type enhanceParams struct {
+	// Mode is one of auto, gamma and clahe.
+	Mode  string  `json:"mode"`
+	Gamma float64 `json:"gamma"`
+}
+
+type reprojectParams struct {
+	// Layout is single or dual.
+	Layout  string  `json:"layout"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	LensFOV float64 `json:"lensFov"`
+	// Yaw, Pitch and FOV set the flatview direction.
+	Yaw   float64 `json:"yaw"`
+	Pitch float64 `json:"pitch"`
+	FOV   float64 `json:"fov"`
+}
+
+var fisheyeLayoutsByName = map[string]FisheyeLayout{
+	"single": FisheyeSingle,
+	"dual":   FisheyeDual,
+}
+
+var maskModesByName = map[string]MaskMode{
+	"blur":     MaskBlur,
+	"pixelate": MaskPixelate,
+	"solid":    MaskSolid,
+}
+
+var scalersByName = map[string]Scaler{
+	"nearest":        ScalerNearestNeighbor,
+	"approxbilinear": ScalerApproxBiLinear,
+	"bilinear":       ScalerBiLinear,
+	"catmullrom":     ScalerCatmullRom,
+}
+
+var subsampleRatiosByName = map[string]image.YCbCrSubsampleRatio{
+	"444": image.YCbCrSubsampleRatio444,
+	"422": image.YCbCrSubsampleRatio422,
+	"420": image.YCbCrSubsampleRatio420,
+	"440": image.YCbCrSubsampleRatio440,
+	"411": image.YCbCrSubsampleRatio411,
+	"410": image.YCbCrSubsampleRatio410,
+}
+
+func init() {
+	mustRegister := func(name string, defaults interface{}, build TransformBuilder) {
+		if err := RegisterTransform(name, defaults, build); err != nil {
+			panic(err)
+		}
+	}
+
+	mustRegister("crop", cropParams{}, func(params interface{}) (TransformFunc, error) {
+		p := params.(cropParams)
+		if p.Width <= 0 || p.Height <= 0 {
+			return nil, fmt.Errorf("invalid size %dx%d", p.Width, p.Height)
+		}
+		return Crop(image.Rect(p.X, p.Y, p.X+p.Width, p.Y+p.Height)), nil
+	})
+	mustRegister("scale", scaleParams{Scaler: "nearest"}, func(params interface{}) (TransformFunc, error) {
+		p := params.(scaleParams)
+		if p.Width <= 0 && p.Height <= 0 {
+			return nil, fmt.Errorf("invalid size %dx%d", p.Width, p.Height)
+		}
+		scaler, ok := scalersByName[strings.ToLower(p.Scaler)]
+		if !ok {
+			return nil, fmt.Errorf("unknown scaler %s", p.Scaler)
+		}
+		return Scale(p.Width, p.Height, scaler), nil
+	})
+	mustRegister("throttle", throttleParams{}, func(params interface{}) (TransformFunc, error) {
+		p := params.(throttleParams)
+		if p.Rate <= 0 {
+			return nil, fmt.Errorf("invalid rate %v", p.Rate)
+		}
+		return Throttle(p.Rate), nil
+	})
+	// Pipelines can't take external detectors, so they use the skin detector.
+	mustRegister("autoframe", autoFrameParams{
+		Interval:  defaultAutoFrameInterval,
+		MaxZoom:   defaultAutoFrameMaxZoom,
+		Smoothing: defaultAutoFrameSmoothing,
+	}, func(params interface{}) (TransformFunc, error) {
+		p := params.(autoFrameParams)
+		if p.MaxZoom < 1 {
+			return nil, fmt.Errorf("invalid max zoom %v", p.MaxZoom)
+		}
+		return AutoFrame(NewSkinFaceDetector(),
+			WithAutoFrameInterval(p.Interval),
+			WithAutoFrameMaxZoom(p.MaxZoom),
+			WithAutoFrameSmoothing(p.Smoothing),
+		), nil
+	})
+	mustRegister("mask", maskParams{Mode: "blur", Strength: defaultMaskStrength}, func(params interface{}) (TransformFunc, error) {
+		p := params.(maskParams)
+		mode, ok := maskModesByName[strings.ToLower(p.Mode)]
+		if !ok {
+			return nil, fmt.Errorf("unknown mask mode %s", p.Mode)
+		}
+		regions := make([]image.Rectangle, len(p.Regions))
+		for i, r := range p.Regions {
+			if r.Width <= 0 || r.Height <= 0 {
+				return nil, fmt.Errorf("invalid size %dx%d", r.Width, r.Height)
+			}
+			regions[i] = image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)
+		}
+		return Mask(regions, mode, WithMaskStrength(p.Strength)), nil
+	})
+	mustRegister("timelapse", timelapseParams{Interval: 60}, func(params interface{}) (TransformFunc, error) {
+		p := params.(timelapseParams)
+		if p.Interval <= 0 {
+			return nil, fmt.Errorf("invalid interval %v", p.Interval)
+		}
+		var opts []TimelapseOption
+		if p.Averaging {
+			opts = append(opts, WithTimelapseAveraging())
+		}
+		return Timelapse(time.Duration(p.Interval*float64(time.Second)), opts...), nil
+	})
+	mustRegister("enhance", enhanceParams{Mode: "auto", Gamma: 2}, func(params interface{}) (TransformFunc, error) {
+		p := params.(enhanceParams)
+		switch strings.ToLower(p.Mode) {
+		case "auto":
+			return Enhance(EnhanceAuto), nil
+		case "gamma":
+			if p.Gamma <= 0 {
+				return nil, fmt.Errorf("invalid gamma %v", p.Gamma)
+			}
+			return Enhance(EnhanceGamma(p.Gamma)), nil
+		case "clahe":
+			return Enhance(EnhanceCLAHE), nil
+		default:
+			return nil, fmt.Errorf("unknown enhance mode %s", p.Mode)
+		}
+	})
+	reproject := func(flat bool) TransformBuilder {
+		return func(params interface{}) (TransformFunc, error) {
+			p := params.(reprojectParams)
+			layout, ok := fisheyeLayoutsByName[strings.ToLower(p.Layout)]
+			if !ok {
+				return nil, fmt.Errorf("unknown fisheye layout %s", p.Layout)
+			}
+			if p.Width <= 0 || p.Height <= 0 {
+				return nil, fmt.Errorf("invalid size %dx%d", p.Width, p.Height)
+			}
+			var opts []FisheyeOption
+			if p.LensFOV > 0 {
+				opts = append(opts, WithFisheyeFOV(p.LensFOV))
+			}
+			if flat {
+				return FlatView(layout, NewViewport(p.Yaw, p.Pitch, p.FOV), p.Width, p.Height, opts...), nil
+			}
+			return Equirectangular(layout, p.Width, p.Height, opts...), nil
+		}
+	}
+	mustRegister("equirectangular", reprojectParams{Layout: "dual"}, reproject(false))
+	mustRegister("flatview", reprojectParams{Layout: "dual", FOV: 90}, reproject(true))
+	mustRegister("compact", nil, func(interface{}) (TransformFunc, error) {
+		return Compact, nil
+	})
+	mustRegister("chroma", chromaParams{Ratio: "420"}, func(params interface{}) (TransformFunc, error) {
+		p := params.(chromaParams)
+		ratio, ok := subsampleRatiosByName[p.Ratio]
+		if !ok {
+			return nil, fmt.Errorf("unknown subsample ratio %s", p.Ratio)
+		}
+		return Chroma(ratio), nil
+	})
+}
@@ -0,0 +1,99 @@
+package video
+
+import (
+	"encoding/json"
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipelineConfig(t *testing.T) {
+	c, err := ParsePipelineConfig([]byte(`{"transforms": [
+		{"name": "crop", "params": {"x": 2, "y": 2, "width": 8, "height": 4}},
+		{"name": "scale", "params": {"width": 4, "height": -1}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform, err := c.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := ReaderFunc(func() (image.Image, func(), error) {
+		return image.NewRGBA(image.Rect(0, 0, 16, 16)), func() {}, nil
+	})
+	img, _, err := transform(src).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := image.Rect(0, 0, 4, 2); img.Bounds() != expected {
+		t.Fatalf("expected %v, but got %v", expected, img.Bounds())
+	}
+
+	arr, err := ParsePipelineConfig([]byte(`[{"name": "throttle", "params": {"rate": 10}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(arr.Transforms) != 1 || arr.Transforms[0].Name != "throttle" {
+		t.Fatalf("expected the array of the transforms, but got %+v", arr)
+	}
+}
+
+func TestNewTransformErrors(t *testing.T) {
+	testCases := map[string]struct {
+		name   string
+		params string
+		err    string
+	}{
+		"Unknown":      {"blur", ``, "unknown transform"},
+		"UnknownParam": {"scale", `{"width": 4, "heigth": 2}`, "unknown field"},
+		"Invalid":      {"crop", `{"width": 0}`, "invalid size"},
+		"Scaler":       {"scale", `{"width": 4, "scaler": "cubic"}`, "unknown scaler"},
+		"Gamma":        {"enhance", `{"mode": "gamma", "gamma": 0}`, "invalid gamma"},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			_, err := NewTransform(testCase.name, json.RawMessage(testCase.params))
+			if err == nil || !strings.Contains(err.Error(), testCase.err) {
+				t.Fatalf("expected an error with %q, but got %v", testCase.err, err)
+			}
+		})
+	}
+}
+
+type testParams struct {
+	Gain  float64 `json:"gain"`
+	Label string  `json:"label"`
+}
+
+func TestRegisterTransform(t *testing.T) {
+	var got testParams
+	err := RegisterTransform("test-gain", testParams{Gain: 1, Label: "default"}, func(params interface{}) (TransformFunc, error) {
+		got = params.(testParams)
+		return func(r Reader) Reader { return r }, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterTransform("test-gain", nil, nil); err == nil {
+		t.Fatal("expected the duplicated name to fail")
+	}
+	if err := RegisterTransform("test-pointer", &testParams{}, nil); err == nil {
+		t.Fatal("expected the parameters which aren't a struct to fail")
+	}
+
+	if _, err := NewTransform("test-gain", json.RawMessage(`{"gain": 2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (testParams{Gain: 2, Label: "default"}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, but got %+v", expected, got)
+	}
+
+	names := RegisteredTransforms()
+	if strings.Join(names, ",") != "autoframe,chroma,compact,crop,enhance,equirectangular,flatview,mask,scale,test-gain,throttle,timelapse" {
+		t.Fatalf("expected the sorted names, but got %v", names)
+	}
+}
@@ -76,19 +76,6 @@ func Scale(width, height int, scaler Scaler) TransformFunc {
 			}
 		}
 
-		// fixedRect returns Rectangle of chroma plane
-		fixedRect := func(rect image.Rectangle, sr image.YCbCrSubsampleRatio) image.Rectangle {
-			switch sr {
-			case image.YCbCrSubsampleRatio444:
-			case image.YCbCrSubsampleRatio422:
-				rect.Max.X /= 2
-			case image.YCbCrSubsampleRatio420:
-				rect.Max.X /= 2
-				rect.Max.Y /= 2
-			}
-			return rect
-		}
-
 		src := &rgbLikeYCbCr{y: &image.Gray{}, cb: &image.Gray{}, cr: &image.Gray{}}
 		dst := &rgbLikeYCbCr{y: &image.Gray{}, cb: &image.Gray{}, cr: &image.Gray{}}
 
@@ -105,13 +92,9 @@ func Scale(width, height int, scaler Scaler) TransformFunc {
 				cacheScaler(rect, i1.Rect)
 			}
 
-			yDx := rect.Dx()
-			yDy := rect.Dy()
-			cRect := fixedRect(rect, i1.SubsampleRatio)
-			cDx := cRect.Dx()
-			cDy := cRect.Dx()
-			yLen := yDx * yDy
-			cLen := cDx * cDy
+			cw, ch := chromaSize(i1.SubsampleRatio, rect)
+			yLen := rect.Dx() * rect.Dy()
+			cLen := cw * ch
 			if len(imgDst.Y) < yLen {
 				if cap(imgDst.Y) < yLen {
 					imgDst.Y = make([]uint8, yLen)
@@ -130,9 +113,11 @@ func Scale(width, height int, scaler Scaler) TransformFunc {
 				}
 				imgDst.Cb = imgDst.Cb[:cLen]
 			}
-			*dst.y = image.Gray{Pix: imgDst.Y, Stride: imgDst.YStride, Rect: rect}
-			*dst.cb = image.Gray{Pix: imgDst.Cb, Stride: imgDst.CStride, Rect: cRect}
-			*dst.cr = image.Gray{Pix: imgDst.Cr, Stride: imgDst.CStride, Rect: cRect}
+			imgDst.YStride, imgDst.CStride = rect.Dx(), cw
+			imgDst.SubsampleRatio = i1.SubsampleRatio
+			imgDst.Rect = rect
+			y, cb, cr := yCbCrPlanes(imgDst)
+			dst.y, dst.cb, dst.cr = y.gray(), cb.gray(), cr.gray()
 		}
 		// ycbcrRealloc reallocs image.RGBA if needed
 		rgbaRealloc := func(i1 *image.RGBA) {
@@ -154,6 +139,8 @@ func Scale(width, height int, scaler Scaler) TransformFunc {
 				}
 				imgDst.Pix = imgDst.Pix[:l]
 			}
+			imgDst.Stride = 4 * rect.Dx()
+			imgDst.Rect = rect
 		}
 
 		return ReaderFunc(func() (image.Image, func(), error) {
@@ -161,6 +148,12 @@ func Scale(width, height int, scaler Scaler) TransformFunc {
 			if err != nil {
 				return nil, func() {}, err
 			}
+			// Scale a copy of the frame in Go memory, e.g. for DMABufImage.
+			if i, ok := img.(interface{ YCbCr() *image.YCbCr }); ok {
+				if yuv := i.YCbCr(); yuv != nil {
+					img = yuv
+				}
+			}
 
 			switch v := img.(type) {
 			case *image.RGBA:
@@ -173,14 +166,10 @@ func Scale(width, height int, scaler Scaler) TransformFunc {
 
 			case *image.YCbCr:
 				ycbcrRealloc(v)
-				// Scale each plane
-				*src.y = image.Gray{Pix: v.Y, Stride: v.YStride, Rect: v.Rect}
-				*src.cb = image.Gray{
-					Pix: v.Cb, Stride: v.CStride, Rect: fixedRect(v.Rect, v.SubsampleRatio),
-				}
-				*src.cr = image.Gray{
-					Pix: v.Cr, Stride: v.CStride, Rect: fixedRect(v.Rect, v.SubsampleRatio),
-				}
+				// Scale each plane from its first pixel, so row padding and sub image offsets
+				// are skipped
+				y, cb, cr := yCbCrPlanes(v)
+				src.y, src.cb, src.cr = y.gray(), cb.gray(), cr.gray()
 				scalerCached.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
 
 				cloned := *(imgScaled.(*image.YCbCr)) // clone metadata
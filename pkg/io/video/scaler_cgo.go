@@ -75,8 +75,8 @@ func (f *FastNearestNeighbor) Scale(dst draw.Image, dr image.Rectangle, src imag
 			d.Pix = d.Pix[:l]
 		}
 		C.fastNearestNeighbor(
-			(*C.uchar)(&d.Pix[dr.Min.X+d.Stride*dr.Min.Y]),
-			(*C.uchar)(&s.Pix[sr.Min.X+s.Stride*sr.Min.Y]),
+			(*C.uchar)(&d.Pix[d.PixOffset(dr.Min.X, dr.Min.Y)]),
+			(*C.uchar)(&s.Pix[s.PixOffset(sr.Min.X, sr.Min.Y)]),
 			4,
 			C.int(dr.Dx()), C.int(dr.Dy()), C.int(d.Stride),
 			C.int(sr.Dx()), C.int(sr.Dy()), C.int(s.Stride),
@@ -91,8 +91,8 @@ func (f *FastNearestNeighbor) Scale(dst draw.Image, dr image.Rectangle, src imag
 			d.Pix = d.Pix[:l]
 		}
 		C.fastNearestNeighbor(
-			(*C.uchar)(&d.Pix[dr.Min.X+d.Stride*dr.Min.Y]),
-			(*C.uchar)(&s.Pix[sr.Min.X+s.Stride*sr.Min.Y]),
+			(*C.uchar)(&d.Pix[d.PixOffset(dr.Min.X, dr.Min.Y)]),
+			(*C.uchar)(&s.Pix[s.PixOffset(sr.Min.X, sr.Min.Y)]),
 			1,
 			C.int(dr.Dx()), C.int(dr.Dy()), C.int(d.Stride),
 			C.int(sr.Dx()), C.int(sr.Dy()), C.int(s.Stride),
@@ -101,10 +101,9 @@ func (f *FastNearestNeighbor) Scale(dst draw.Image, dr image.Rectangle, src imag
 	case (*rgbLikeYCbCr):
 		d := dst.(*rgbLikeYCbCr)
 		f.Scale(d.y, dr, s.y, sr, op, opts)
-		dr2 := image.Rect(0, 0, d.cb.Stride, len(d.cb.Pix)/d.cb.Stride)
-		sr2 := image.Rect(0, 0, s.cb.Stride, len(s.cb.Pix)/s.cb.Stride)
-		f.Scale(d.cb, dr2, s.cb, sr2, op, opts)
-		f.Scale(d.cr, dr2, s.cr, sr2, op, opts)
+		// Chroma planes are scaled by their own sizes, which exclude row padding
+		f.Scale(d.cb, d.cb.Rect, s.cb, s.cb.Rect, op, opts)
+		f.Scale(d.cr, d.cr.Rect, s.cr, s.cr.Rect, op, opts)
 
 	default:
 		panic("unimplemented")
@@ -139,8 +138,8 @@ func (f *FastBoxSampling) Scale(dst draw.Image, dr image.Rectangle, src image.Im
 			*tmp = make([]uint32, l)
 		}
 		C.fastBoxSampling(
-			(*C.uchar)(&d.Pix[dr.Min.X+d.Stride*dr.Min.Y]),
-			(*C.uchar)(&s.Pix[sr.Min.X+s.Stride*sr.Min.Y]),
+			(*C.uchar)(&d.Pix[d.PixOffset(dr.Min.X, dr.Min.Y)]),
+			(*C.uchar)(&s.Pix[s.PixOffset(sr.Min.X, sr.Min.Y)]),
 			4,
 			C.int(dr.Dx()), C.int(dr.Dy()), C.int(d.Stride),
 			C.int(sr.Dx()), C.int(sr.Dy()), C.int(s.Stride),
@@ -159,8 +158,8 @@ func (f *FastBoxSampling) Scale(dst draw.Image, dr image.Rectangle, src image.Im
 			*tmp = make([]uint32, l)
 		}
 		C.fastBoxSampling(
-			(*C.uchar)(&d.Pix[dr.Min.X+d.Stride*dr.Min.Y]),
-			(*C.uchar)(&s.Pix[sr.Min.X+s.Stride*sr.Min.Y]),
+			(*C.uchar)(&d.Pix[d.PixOffset(dr.Min.X, dr.Min.Y)]),
+			(*C.uchar)(&s.Pix[s.PixOffset(sr.Min.X, sr.Min.Y)]),
 			1,
 			C.int(dr.Dx()), C.int(dr.Dy()), C.int(d.Stride),
 			C.int(sr.Dx()), C.int(sr.Dy()), C.int(s.Stride),
@@ -170,10 +169,9 @@ func (f *FastBoxSampling) Scale(dst draw.Image, dr image.Rectangle, src image.Im
 	case (*rgbLikeYCbCr):
 		d := dst.(*rgbLikeYCbCr)
 		f.Scale(d.y, dr, s.y, sr, op, opts)
-		dr2 := image.Rect(0, 0, d.cb.Stride, len(d.cb.Pix)/d.cb.Stride)
-		sr2 := image.Rect(0, 0, s.cb.Stride, len(s.cb.Pix)/s.cb.Stride)
-		f.Scale(d.cb, dr2, s.cb, sr2, op, opts)
-		f.Scale(d.cr, dr2, s.cr, sr2, op, opts)
+		// Chroma planes are scaled by their own sizes, which exclude row padding
+		f.Scale(d.cb, d.cb.Rect, s.cb, s.cb.Rect, op, opts)
+		f.Scale(d.cr, d.cr.Rect, s.cr, s.cr.Rect, op, opts)
 
 	default:
 		panic("unimplemented")
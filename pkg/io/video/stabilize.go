@@ -0,0 +1,260 @@
+package video
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+const (
+	// stabilizeWidth is the luma width that motion is estimated at.
+	stabilizeWidth = 320
+	// stabilizeBlock is the size of matched blocks, in estimated luma pixels.
+	stabilizeBlock = 16
+	// stabilizeSearch is the block search range, i.e. 32 pixels per frame at 1280x720.
+	stabilizeSearch = 8
+	// stabilizeMinContrast is the minimum mean absolute luma deviation for a block to be matched, since flat blocks
+	// like sky match anywhere.
+	stabilizeMinContrast = 4
+	// stabilizeMinBlocks is the minimum number of matched blocks. Below it, the frame is assumed not to move, e.g.
+	// at scene cuts or in the dark.
+	stabilizeMinBlocks = 4
+)
+
+// Stabilize removes shake from handheld and vehicle-mounted cameras. Each frame's global motion is estimated by
+// matching luma blocks against the previous frame, and frames are shifted along a smoothed camera path. strength,
+// from 0 to 1, is the share of shake removed per frame; e.g. 0.9 removes shake while following slow pans.
+// cropMargin is the share of width and height cropped from each side to make room for the shift, e.g. 0.1. The
+// cropped frames are scaled back to the frame size, so the encoder isn't reconfigured.
+//
+// Only translation is compensated, not rotation. Frames must be RGBA or YCbCr, as with Scale, and the scaled
+// frame is only valid until the next frame is read.
+func Stabilize(strength, cropMargin float64) TransformFunc {
+	strength = math.Max(0, math.Min(0.999, strength))
+	cropMargin = math.Max(0, math.Min(0.25, cropMargin))
+
+	return func(r Reader) Reader {
+		var (
+			bounds  image.Rectangle
+			est     motionEstimator
+			pathX   float64
+			pathY   float64
+			smoothX float64
+			smoothY float64
+			marginX int
+			marginY int
+			cropped image.Image
+			scaled  Reader
+		)
+		source := ReaderFunc(func() (image.Image, func(), error) {
+			return cropped, func() {}, nil
+		})
+
+		return ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+			src := img
+			if i, ok := src.(interface{ YCbCr() *image.YCbCr }); ok {
+				if yuv := i.YCbCr(); yuv != nil {
+					src = yuv
+				}
+			}
+			sub, ok := src.(interface {
+				SubImage(image.Rectangle) image.Image
+			})
+			if !ok {
+				release()
+				return nil, func() {}, errUnsupportedImageType
+			}
+
+			if b := img.Bounds(); b != bounds {
+				bounds = b
+				est = motionEstimator{}
+				pathX, pathY, smoothX, smoothY = 0, 0, 0, 0
+				marginX = int(cropMargin * float64(b.Dx()))
+				marginY = int(cropMargin * float64(b.Dy()))
+				scaled = Scale(b.Dx(), b.Dy(), ScalerApproxBiLinear)(source)
+			}
+
+			// The path tracks the content, which moves opposite to the camera
+			dx, dy := est.estimate(src)
+			pathX += dx
+			pathY += dy
+			smoothX += (pathX - smoothX) * (1 - strength)
+			smoothY += (pathY - smoothY) * (1 - strength)
+			// Follow the camera when the shift leaves the margin, e.g. on a fast pan
+			smoothX = math.Max(pathX-float64(marginX), math.Min(pathX+float64(marginX), smoothX))
+			smoothY = math.Max(pathY-float64(marginY), math.Min(pathY+float64(marginY), smoothY))
+
+			if marginX == 0 && marginY == 0 {
+				return img, release, nil
+			}
+			// The window follows the content along the smoothed path
+			x := bounds.Min.X + marginX + clampInt(int(math.Round(pathX-smoothX)), -marginX, marginX)
+			y := bounds.Min.Y + marginY + clampInt(int(math.Round(pathY-smoothY)), -marginY, marginY)
+			// Align the origin to 2 pixels for 4:2:0 chroma
+			x, y = x&^1, y&^1
+			cropped = sub.SubImage(image.Rect(x, y, x+bounds.Dx()-2*marginX, y+bounds.Dy()-2*marginY))
+			out, _, err := scaled.Read()
+			release()
+			return out, func() {}, err
+		})
+	}
+}
+
+// motionEstimator estimates global motion between frames on downsampled luma.
+type motionEstimator struct {
+	prev, cur []uint8
+	w, h      int
+	scale     int
+	dx, dy    []float64
+}
+
+// estimate returns how far the content of img moved since the previous frame, in img pixels. It's 0 for the
+// first frame.
+func (e *motionEstimator) estimate(img image.Image) (float64, float64) {
+	e.prev, e.cur = e.cur, e.prev
+	e.downsample(img)
+	if len(e.prev) != len(e.cur) {
+		return 0, 0
+	}
+
+	e.dx, e.dy = e.dx[:0], e.dy[:0]
+	margin := stabilizeSearch + 1
+	for by := margin; by+stabilizeBlock+margin <= e.h; by += stabilizeBlock {
+		for bx := margin; bx+stabilizeBlock+margin <= e.w; bx += stabilizeBlock {
+			if vx, vy, ok := e.matchBlock(bx, by); ok {
+				e.dx, e.dy = append(e.dx, vx), append(e.dy, vy)
+			}
+		}
+	}
+	if len(e.dx) < stabilizeMinBlocks {
+		return 0, 0
+	}
+	// The median ignores objects moving within the scene
+	return median(e.dx) * float64(e.scale), median(e.dy) * float64(e.scale)
+}
+
+// downsample averages img's luma over squares of scale pixels into cur.
+func (e *motionEstimator) downsample(img image.Image) {
+	b := img.Bounds()
+	e.scale = (b.Dx() + stabilizeWidth - 1) / stabilizeWidth
+	e.w, e.h = b.Dx()/e.scale, b.Dy()/e.scale
+	if cap(e.cur) < e.w*e.h {
+		e.cur = make([]uint8, e.w*e.h)
+	}
+	e.cur = e.cur[:e.w*e.h]
+
+	var luma func(x, y int) int
+	switch src := img.(type) {
+	case *image.YCbCr:
+		luma = func(x, y int) int { return int(src.Y[src.YOffset(x, y)]) }
+	case *image.RGBA:
+		luma = func(x, y int) int {
+			i := src.PixOffset(x, y)
+			return (299*int(src.Pix[i]) + 587*int(src.Pix[i+1]) + 114*int(src.Pix[i+2])) / 1000
+		}
+	default:
+		luma = func(x, y int) int { return int(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y) }
+	}
+
+	n := e.scale * e.scale
+	for j := 0; j < e.h; j++ {
+		for i := 0; i < e.w; i++ {
+			var sum int
+			for y := 0; y < e.scale; y++ {
+				for x := 0; x < e.scale; x++ {
+					sum += luma(b.Min.X+i*e.scale+x, b.Min.Y+j*e.scale+y)
+				}
+			}
+			e.cur[j*e.w+i] = uint8(sum / n)
+		}
+	}
+}
+
+// matchBlock returns the motion of cur's block at (bx, by) since prev. Fitting a parabola to the costs around
+// the best match refines it to sub-pixel precision.
+func (e *motionEstimator) matchBlock(bx, by int) (float64, float64, bool) {
+	var sum int
+	for y := 0; y < stabilizeBlock; y++ {
+		for x := 0; x < stabilizeBlock; x++ {
+			sum += int(e.cur[(by+y)*e.w+bx+x])
+		}
+	}
+	mean := sum / (stabilizeBlock * stabilizeBlock)
+	var deviation int
+	for y := 0; y < stabilizeBlock; y++ {
+		for x := 0; x < stabilizeBlock; x++ {
+			d := int(e.cur[(by+y)*e.w+bx+x]) - mean
+			if d < 0 {
+				d = -d
+			}
+			deviation += d
+		}
+	}
+	if deviation < stabilizeMinContrast*stabilizeBlock*stabilizeBlock {
+		return 0, 0, false
+	}
+
+	best, bestX, bestY := -1, 0, 0
+	for vy := -stabilizeSearch; vy <= stabilizeSearch; vy++ {
+		for vx := -stabilizeSearch; vx <= stabilizeSearch; vx++ {
+			// The content of this block in cur was at the block shifted by -v in prev
+			if cost := e.sad(bx, by, bx-vx, by-vy, best); best < 0 || cost < best {
+				best, bestX, bestY = cost, vx, vy
+			}
+		}
+	}
+
+	cost := func(vx, vy int) int { return e.sad(bx, by, bx-vx, by-vy, -1) }
+	fx, fy := float64(bestX), float64(bestY)
+	if bestX > -stabilizeSearch && bestX < stabilizeSearch {
+		fx += parabolaPeak(cost(bestX-1, bestY), best, cost(bestX+1, bestY))
+	}
+	if bestY > -stabilizeSearch && bestY < stabilizeSearch {
+		fy += parabolaPeak(cost(bestX, bestY-1), best, cost(bestX, bestY+1))
+	}
+	return fx, fy, true
+}
+
+// sad returns the sum of absolute differences between cur's block at (x0, y0) and prev's at (x1, y1). Unless
+// limit is negative, it stops once a row exceeds limit, since the block can no longer be the best match.
+func (e *motionEstimator) sad(x0, y0, x1, y1, limit int) int {
+	var sum int
+	for y := 0; y < stabilizeBlock; y++ {
+		a := e.cur[(y0+y)*e.w+x0:]
+		b := e.prev[(y1+y)*e.w+x1:]
+		for x := 0; x < stabilizeBlock; x++ {
+			d := int(a[x]) - int(b[x])
+			if d < 0 {
+				d = -d
+			}
+			sum += d
+		}
+		if limit >= 0 && sum > limit {
+			return sum
+		}
+	}
+	return sum
+}
+
+// parabolaPeak returns the offset of the minimum of the parabola through the costs at -1, 0 and 1.
+func parabolaPeak(left, center, right int) float64 {
+	d := left - 2*center + right
+	if d <= 0 {
+		return 0
+	}
+	return math.Max(-0.5, math.Min(0.5, float64(left-right)/float64(2*d)))
+}
+
+func median(v []float64) float64 {
+	sort.Float64s(v)
+	n := len(v)
+	if n%2 == 1 {
+		return v[n/2]
+	}
+	return (v[n/2-1] + v[n/2]) / 2
+}
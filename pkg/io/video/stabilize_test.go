@@ -0,0 +1,86 @@
+package video
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// testTexture returns luma textured in 2-pixel blocks, which the test frames are cut from.
+func testTexture(w, h int) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio420)
+	rnd := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y += 2 {
+		for x := 0; x < w; x += 2 {
+			v := uint8(rnd.Intn(256))
+			for i := 0; i < 4; i++ {
+				img.Y[img.YOffset(x+i%2, y+i/2)] = v
+			}
+		}
+	}
+	for i := range img.Cb {
+		img.Cb[i], img.Cr[i] = 128, 128
+	}
+	return img
+}
+
+func TestMotionEstimator(t *testing.T) {
+	texture := testTexture(800, 480)
+	frame := func(x, y int) image.Image {
+		return texture.SubImage(image.Rect(x, y, x+640, y+360))
+	}
+
+	var est motionEstimator
+	if dx, dy := est.estimate(frame(40, 40)); dx != 0 || dy != 0 {
+		t.Fatalf("expected no motion of the first frame, but got (%v, %v)", dx, dy)
+	}
+	// The camera moves left and down, so the content moves right and up
+	dx, dy := est.estimate(frame(30, 46))
+	if math.Abs(dx-10) > 0.5 || math.Abs(dy+6) > 0.5 {
+		t.Fatalf("expected the motion of (10, -6), but got (%v, %v)", dx, dy)
+	}
+}
+
+func TestStabilize(t *testing.T) {
+	texture := testTexture(400, 240)
+	rnd := rand.New(rand.NewSource(2))
+	var x, y int
+	src := ReaderFunc(func() (image.Image, func(), error) {
+		// ±8 pixels of shake
+		x, y = 40+rnd.Intn(17)-8, 30+rnd.Intn(17)-8
+		// A camera's frames all have the same bounds
+		img := image.NewYCbCr(image.Rect(0, 0, 320, 180), image.YCbCrSubsampleRatio420)
+		for j := 0; j < 180; j++ {
+			copy(img.Y[j*img.YStride:(j+1)*img.YStride], texture.Y[texture.YOffset(x, y+j):])
+		}
+		for i := range img.Cb {
+			img.Cb[i], img.Cr[i] = 128, 128
+		}
+		return img, func() {}, nil
+	})
+
+	var out motionEstimator
+	r := Stabilize(0.95, 0.1)(src)
+
+	var shake, residual float64
+	prevX, prevY := 0, 0
+	for i := 0; i < 40; i++ {
+		img, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if img.Bounds() != image.Rect(0, 0, 320, 180) {
+			t.Fatalf("expected the size of the frames, but got %v", img.Bounds())
+		}
+		dx, dy := out.estimate(img)
+		if i >= 10 {
+			shake += math.Hypot(float64(x-prevX), float64(y-prevY))
+			residual += math.Hypot(dx, dy)
+		}
+		prevX, prevY = x, y
+	}
+	if residual > shake/3 {
+		t.Fatalf("expected the shake to be removed, but got the motion of %v from %v", residual, shake)
+	}
+}
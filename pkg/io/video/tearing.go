@@ -0,0 +1,183 @@
+package video
+
+import (
+	"hash/crc32"
+	"image"
+	"image/color"
+)
+
+// Tearing is a torn frame detected by DetectTearing.
+type Tearing struct {
+	// Row is the first frame row that still has the previous frame's content.
+	Row int
+	// TornFrames is the total number of torn frames.
+	TornFrames uint64
+}
+
+type tearingConfig struct {
+	minRows int
+	drop    bool
+}
+
+// TearingOption configures DetectTearing.
+type TearingOption func(*tearingConfig)
+
+// WithTearingMinRows sets the minimum number of rows at a torn frame's bottom that are left from the
+// previous frame. The default is 1/16 of the height.
+func WithTearingMinRows(rows int) TearingOption {
+	return func(c *tearingConfig) {
+		c.minRows = rows
+	}
+}
+
+// WithDropTornFrames drops torn frames, so the reader returns the next frame instead.
+func WithDropTornFrames(drop bool) TearingOption {
+	return func(c *tearingConfig) {
+		c.drop = drop
+	}
+}
+
+// DetectTearing detects frames read while the device was writing them, e.g. from drivers' live buffers
+// on a slow USB bus, and calls onTorn for each. onTorn may be nil if torn frames are
+// only dropped.
+//
+// A torn frame's bottom is still the previous frame, so it's detected from rows identical to
+// the previous frame while the rows above them changed. Rows that were also identical in the previous
+// frame, e.g. letterbox black bars or a static overlay, are static content instead. Since sensor noise
+// changes every row of camera frames, rows are compared exactly by their checksums.
+// onTorn is called in the reading goroutine, so it shouldn't block.
+func DetectTearing(onTorn func(Tearing), opts ...TearingOption) TransformFunc {
+	var c tearingConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(r Reader) Reader {
+		var (
+			tearing    Tearing
+			rows, prev []rowChecksum
+			// changed is whether the previous frame's rows changed from the frame before it.
+			changed    []bool
+			prevBounds image.Rectangle
+			scratch    []uint8
+		)
+		return KeepMetadata(ReaderFunc(func() (image.Image, func(), error) {
+			for {
+				img, release, err := r.Read()
+				if err != nil {
+					return nil, func() {}, err
+				}
+
+				rows, scratch = rowChecksums(rows[:0], scratch, img)
+				torn := -1
+				if img.Bounds() == prevBounds {
+					minRows := c.minRows
+					if minRows <= 0 {
+						minRows = len(rows) / 16
+					}
+					torn = tornRow(rows, prev, changed, minRows)
+					for i := range rows {
+						changed[i] = rows[i].sum != prev[i].sum
+					}
+				} else {
+					// Rows of a size's first frame aren't known to change
+					changed = make([]bool, len(rows))
+				}
+				rows, prev = prev, rows
+				prevBounds = img.Bounds()
+
+				if torn < 0 {
+					return img, release, nil
+				}
+				tearing.Row = torn
+				tearing.TornFrames++
+				if onTorn != nil {
+					onTorn(tearing)
+				}
+				if !c.drop {
+					return img, release, nil
+				}
+				release()
+			}
+		}), r)
+	}
+}
+
+// rowChecksum is a row's checksum, and whether all its pixels are the same.
+type rowChecksum struct {
+	sum     uint32
+	uniform bool
+}
+
+// tornRow returns the first of the bottom rows that are identical to prev, or -1 if the frame isn't
+// torn. At least minRows have to be identical, and most of them have to have changed in the previous frame.
+// Uniform rows, e.g. black bars that appear after a scene change, aren't counted.
+func tornRow(rows, prev []rowChecksum, changed []bool, minRows int) int {
+	k := len(rows)
+	for k > 0 && rows[k-1].sum == prev[k-1].sum {
+		k--
+	}
+	// Frozen frames aren't torn
+	if k == 0 {
+		return -1
+	}
+	var stale, fresh int
+	for i := k; i < len(rows); i++ {
+		if rows[i].uniform {
+			continue
+		}
+		stale++
+		if changed[i] {
+			fresh++
+		}
+	}
+	if stale == 0 || stale < minRows || 2*fresh < stale {
+		return -1
+	}
+	return k
+}
+
+func checksumRow(row []uint8, bytesPerPixel int) rowChecksum {
+	uniform := true
+	for i := bytesPerPixel; i < len(row) && uniform; i++ {
+		uniform = row[i] == row[i-bytesPerPixel]
+	}
+	return rowChecksum{sum: crc32.ChecksumIEEE(row), uniform: uniform}
+}
+
+// rowChecksums appends checksums of img's luma or pixel rows to dst. scratch is a row buffer for
+// images that aren't in planes, which is returned for the next call.
+func rowChecksums(dst []rowChecksum, scratch []uint8, img image.Image) ([]rowChecksum, []uint8) {
+	var p plane
+	bytesPerPixel := 1
+	switch v := img.(type) {
+	case *image.RGBA:
+		p, bytesPerPixel = rgbaPlane(v), 4
+	case *image.NRGBA:
+		p, bytesPerPixel = rgbaPlane((*image.RGBA)(v)), 4
+	case *image.Gray:
+		bounds := v.Rect
+		p = pixPlane(v.Pix, v.Stride, v.PixOffset(bounds.Min.X, bounds.Min.Y), bounds.Dx(), bounds.Dy())
+	default:
+		yuv, ok := asYCbCr(img)
+		if !ok {
+			bounds := img.Bounds()
+			if cap(scratch) < bounds.Dx() {
+				scratch = make([]uint8, bounds.Dx())
+			}
+			row := scratch[:bounds.Dx()]
+			for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+				for x := range row {
+					row[x] = color.GrayModel.Convert(img.At(bounds.Min.X+x, y)).(color.Gray).Y
+				}
+				dst = append(dst, checksumRow(row, 1))
+			}
+			return dst, scratch
+		}
+		p, _, _ = yCbCrPlanes(yuv)
+	}
+	for y := 0; y < p.height; y++ {
+		dst = append(dst, checksumRow(p.row(y), bytesPerPixel))
+	}
+	return dst, scratch
+}
@@ -0,0 +1,69 @@
+package video
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+)
+
+// noisyFrame returns an 8x32 frame whose rows from static are the same for every frame.
+func noisyFrame(rng *rand.Rand, static int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 8, 32))
+	rng.Read(img.Pix[:static*img.Stride])
+	return img
+}
+
+func TestDetectTearing(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	frames := []*image.Gray{noisyFrame(rng, 32), noisyFrame(rng, 32), noisyFrame(rng, 32)}
+	// The device was writing the last frame, so its rows from 20 are still the previous frame
+	copy(frames[2].Pix[20*8:], frames[1].Pix[20*8:])
+	// Letterboxed frames whose black bars from 24 are identical
+	frames = append(frames, noisyFrame(rng, 24), noisyFrame(rng, 24), noisyFrame(rng, 24))
+
+	var detected []Tearing
+	r := DetectTearing(func(tearing Tearing) {
+		detected = append(detected, tearing)
+	})(ReaderFunc(func() (image.Image, func(), error) {
+		img := frames[0]
+		frames = frames[1:]
+		return img, func() {}, nil
+	}))
+	for i := 0; i < 6; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(detected) != 1 {
+		t.Fatalf("expected a torn frame, but got %v", detected)
+	}
+	if expected := (Tearing{Row: 20, TornFrames: 1}); detected[0] != expected {
+		t.Fatalf("expected %+v, but got %+v", expected, detected[0])
+	}
+}
+
+func TestDetectTearingDrop(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	frames := []*image.Gray{noisyFrame(rng, 32), noisyFrame(rng, 32), noisyFrame(rng, 32), noisyFrame(rng, 32)}
+	copy(frames[2].Pix[16*8:], frames[1].Pix[16*8:])
+	next := frames[3]
+
+	var released int
+	r := DetectTearing(nil, WithDropTornFrames(true))(ReaderFunc(func() (image.Image, func(), error) {
+		img := frames[0]
+		frames = frames[1:]
+		return img, func() { released++ }, nil
+	}))
+	for i := 0; i < 2; i++ {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	img, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img != next || released != 1 {
+		t.Fatalf("expected the torn frame to be dropped and released, but got %d releases", released)
+	}
+}
@@ -0,0 +1,174 @@
+package video
+
+import (
+	"image"
+	"time"
+)
+
+type timelapseConfig struct {
+	averaging bool
+}
+
+// TimelapseOption configures Timelapse.
+type TimelapseOption func(*timelapseConfig)
+
+// WithTimelapseAveraging emits the average of every frame captured in each interval instead of the last one.
+// This removes sensor noise in the dark and smoothly blurs moving objects like cars and clouds instead of making
+// them flicker. Frames must be RGBA or YCbCr.
+func WithTimelapseAveraging() TimelapseOption {
+	return func(c *timelapseConfig) {
+		c.averaging = true
+	}
+}
+
+// Timelapse emits one frame per interval from frames captured at full quality, e.g. to record a construction or
+// weather camera timelapse. It emits the first frame, then the first frame captured after each interval. The
+// interval is measured with the capture time in the frame metadata, see Stamp, or with read time for frames
+// without one, so Read blocks for the interval while frames are captured.
+//
+// Emitted frames keep the metadata of the last captured frame. Averaged frames are written to a buffer that's
+// reused for the next frame, so each is only valid until the next frame is read.
+func Timelapse(interval time.Duration, opts ...TimelapseOption) TransformFunc {
+	var c timelapseConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return func(r Reader) Reader {
+		var (
+			next   time.Time
+			buf    = NewFrameBuffer(0)
+			sums   []uint32
+			bounds image.Rectangle
+			frames uint32
+		)
+		return ReaderFunc(func() (image.Image, func(), error) {
+			for {
+				img, release, err := r.Read()
+				if err != nil {
+					return nil, func() {}, err
+				}
+				m, _ := MetadataOf(r)
+				now := m.CaptureTime
+				if now.IsZero() {
+					now = time.Now()
+				}
+				emit := next.IsZero() || !now.Before(next)
+				if emit {
+					next = next.Add(interval)
+					// Capture was paused, e.g. by source errors, so don't emit a burst of frames
+					if next.Before(now) {
+						next = now.Add(interval)
+					}
+				}
+
+				if !c.averaging {
+					if emit {
+						return img, release, nil
+					}
+					release()
+					continue
+				}
+
+				if i, ok := img.(interface{ YCbCr() *image.YCbCr }); ok {
+					if yuv := i.YCbCr(); yuv != nil {
+						img = yuv
+					}
+				}
+				planes, ok := framePlanes(img)
+				if !ok {
+					release()
+					return nil, func() {}, errUnsupportedImageType
+				}
+				if img.Bounds() != bounds || !sameLayout(img, buf.Load()) {
+					// Frames of a different size can't be averaged, so restart the interval
+					bounds, frames = img.Bounds(), 0
+					buf.StoreCopy(img)
+				}
+				sums = accumulatePlanes(sums, planes, frames == 0)
+				frames++
+				if !emit {
+					release()
+					continue
+				}
+
+				buf.StoreCopy(img)
+				release()
+				dst, _ := framePlanes(buf.Load())
+				averagePlanes(dst, sums, frames)
+				frames = 0
+				return buf.Load(), func() {}, nil
+			}
+		})
+	}
+}
+
+// framePlanes returns the planes of an RGBA or YCbCr frame.
+func framePlanes(img image.Image) ([]plane, bool) {
+	switch img := img.(type) {
+	case *image.RGBA:
+		return []plane{rgbaPlane(img)}, true
+	case *image.YCbCr:
+		y, cb, cr := yCbCrPlanes(img)
+		return []plane{y, cb, cr}, true
+	default:
+		return nil, false
+	}
+}
+
+// sameLayout reports whether a and b are the same frame type, with matching subsampling for YCbCr.
+func sameLayout(a, b image.Image) bool {
+	switch a := a.(type) {
+	case *image.RGBA:
+		_, ok := b.(*image.RGBA)
+		return ok
+	case *image.YCbCr:
+		y, ok := b.(*image.YCbCr)
+		return ok && y.SubsampleRatio == a.SubsampleRatio
+	default:
+		return false
+	}
+}
+
+// accumulatePlanes adds the samples of planes to sums, resetting sums first if reset is true.
+func accumulatePlanes(sums []uint32, planes []plane, reset bool) []uint32 {
+	var n int
+	for _, p := range planes {
+		n += p.width * p.height
+	}
+	if cap(sums) < n {
+		sums = make([]uint32, n)
+		reset = true
+	}
+	sums = sums[:n]
+	if reset {
+		for i := range sums {
+			sums[i] = 0
+		}
+	}
+
+	i := 0
+	for _, p := range planes {
+		for y := 0; y < p.height; y++ {
+			for _, v := range p.row(y) {
+				sums[i] += uint32(v)
+				i++
+			}
+		}
+	}
+	return sums
+}
+
+// averagePlanes writes the averages of n frames' sums to planes.
+func averagePlanes(planes []plane, sums []uint32, n uint32) {
+	i := 0
+	for _, p := range planes {
+		for y := 0; y < p.height; y++ {
+			row := p.row(y)
+			for x := range row {
+				row[x] = uint8((sums[i] + n/2) / n)
+				i++
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package video
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+// testTimelapseSource returns 1 fps camera frames whose luma is their sequence number.
+func testTimelapseSource() MetadataReader {
+	start := time.Unix(1000, 0)
+	var n int
+	now := start
+	src := ReaderFunc(func() (image.Image, func(), error) {
+		now = start.Add(time.Duration(n) * time.Second)
+		frame := image.NewYCbCr(image.Rect(0, 0, 16, 8), image.YCbCrSubsampleRatio420)
+		for i := range frame.Y {
+			frame.Y[i] = uint8(n)
+		}
+		n++
+		return frame, func() {}, nil
+	})
+	return Stamp(src, func() time.Time { return now })
+}
+
+func TestTimelapse(t *testing.T) {
+	src := testTimelapseSource()
+	r := KeepMetadata(Timelapse(10*time.Second)(src), src)
+
+	for i := 0; i < 3; i++ {
+		img, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := MetadataOf(r)
+		if m.Sequence != uint64(10*i) {
+			t.Fatalf("expected the frame %d, but got %d", 10*i, m.Sequence)
+		}
+		if y := img.(*image.YCbCr).Y[0]; y != uint8(10*i) {
+			t.Fatalf("expected the frame as is, but got the luma of %d", y)
+		}
+	}
+}
+
+func TestTimelapseAveraging(t *testing.T) {
+	src := testTimelapseSource()
+	r := KeepMetadata(Timelapse(10*time.Second, WithTimelapseAveraging())(src), src)
+
+	// The first frame, then the averages of frames 1 to 10 and 11 to 20
+	for i, expected := range []uint8{0, 6, 16} {
+		img, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := MetadataOf(r)
+		if m.Sequence != uint64(10*i) {
+			t.Fatalf("expected the metadata of the frame %d, but got %d", 10*i, m.Sequence)
+		}
+		yuv := img.(*image.YCbCr)
+		if yuv.Y[0] != expected || yuv.Y[len(yuv.Y)-1] != expected {
+			t.Fatalf("expected the average of %d, but got %d", expected, yuv.Y[0])
+		}
+	}
+}
+
+func TestTimelapseAveragingUnsupported(t *testing.T) {
+	r := Timelapse(time.Second, WithTimelapseAveraging())(ReaderFunc(func() (image.Image, func(), error) {
+		return image.NewGray(image.Rect(0, 0, 16, 16)), func() {}, nil
+	}))
+	if _, _, err := r.Read(); err != errUnsupportedImageType {
+		t.Fatalf("expected %v, but got %v", errUnsupportedImageType, err)
+	}
+}
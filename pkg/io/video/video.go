@@ -27,7 +27,7 @@ func (rf ReaderFunc) Read() (img image.Image, release func(), err error) {
 type TransformFunc func(r Reader) Reader
 
 // Merge merges transforms and produces a new TransformFunc that will execute
-// transforms in order
+// transforms in order. Frame metadata is kept through the transforms.
 func Merge(transforms ...TransformFunc) TransformFunc {
 	return func(r Reader) Reader {
 		for _, transform := range transforms {
@@ -35,7 +35,7 @@ func Merge(transforms ...TransformFunc) TransformFunc {
 				continue
 			}
 
-			r = transform(r)
+			r = KeepMetadata(transform(r), r)
 		}
 
 		return r
@@ -0,0 +1,416 @@
+package lossless
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	// flacBlockSize is the number of samples per channel in a frame, the libFLAC default.
+	flacBlockSize = 4096
+	// flacMaxFixedOrder is the highest fixed predictor order.
+	flacMaxFixedOrder = 4
+	// flacMaxPartitionOrder is the highest residual partition order.
+	flacMaxPartitionOrder = 8
+	// flacMaxRiceParameter is the largest 4-bit Rice parameter, since 15 is the escape code.
+	flacMaxRiceParameter = 14
+	flacMarker           = "fLaC"
+	flacStreamInfoSize   = 34
+)
+
+type flacWriter struct {
+	w       io.Writer
+	config  config
+	format  *format
+	pending []int32
+	block   [][]int32
+	frame   bitWriter
+
+	frameNumber  uint64
+	totalSamples uint64
+	minFrameSize int
+	maxFrameSize int
+	md5          hash.Hash
+	closed       bool
+}
+
+// NewFLACWriter creates a Writer that writes a FLAC file to w. Samples are encoded with fixed predictors, which
+// compress speech and music to roughly half to two thirds of WAV at little CPU cost. The total sample count and
+// the STREAMINFO MD5 aren't known while writing; Close fills them in if w is an io.WriteSeeker.
+func NewFLACWriter(w io.Writer, opts ...Option) (Writer, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &flacWriter{w: w, config: c, md5: md5.New()}, nil
+}
+
+func (w *flacWriter) Write(chunk wave.Audio) error {
+	if w.closed {
+		return errClosed
+	}
+	if w.format == nil {
+		f := newFormat(chunk.ChunkInfo(), w.config.bitDepth)
+		w.format = &f
+		w.block = make([][]int32, f.channels)
+		if _, err := w.w.Write(append([]byte(flacMarker), w.streamInfo()...)); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	w.pending, err = w.format.samples(w.pending, chunk)
+	if err != nil {
+		return err
+	}
+	frameSamples := flacBlockSize * w.format.channels
+	for len(w.pending) >= frameSamples {
+		if err := w.writeFrame(w.pending[:frameSamples]); err != nil {
+			return err
+		}
+		w.pending = w.pending[:copy(w.pending, w.pending[frameSamples:])]
+	}
+	return nil
+}
+
+// streamInfo returns the STREAMINFO metadata block, which is also the last one.
+// Reference: https://xiph.org/flac/format.html#metadata_block_streaminfo
+func (w *flacWriter) streamInfo() []byte {
+	f := w.format
+	b := make([]byte, 4+flacStreamInfoSize)
+	// Last-metadata-block flag and type 0
+	b[0] = 0x80
+	b[3] = flacStreamInfoSize
+
+	info := b[4:]
+	binary.BigEndian.PutUint16(info[0:], flacBlockSize)
+	binary.BigEndian.PutUint16(info[2:], flacBlockSize)
+	putUint24(info[4:], uint32(w.minFrameSize))
+	putUint24(info[7:], uint32(w.maxFrameSize))
+	// 20-bit sample rate, 3-bit channels - 1, 5-bit bit depth - 1 and 36-bit total samples
+	v := uint64(f.sampleRate)<<44 | uint64(f.channels-1)<<41 | uint64(f.bitDepth-1)<<36 | w.totalSamples&(1<<36-1)
+	binary.BigEndian.PutUint64(info[10:], v)
+	if w.closed {
+		copy(info[18:], w.md5.Sum(nil))
+	}
+	return b
+}
+
+// writeFrame writes a frame of interleaved samples.
+// Reference: https://xiph.org/flac/format.html#frame
+func (w *flacWriter) writeFrame(samples []int32) error {
+	channels := w.format.channels
+	n := len(samples) / channels
+	for ch := range w.block {
+		w.block[ch] = w.block[ch][:0]
+	}
+	bytesPerSample := w.format.bitDepth / 8
+	var raw [4]byte
+	for i, s := range samples {
+		ch := i % channels
+		w.block[ch] = append(w.block[ch], s)
+		binary.LittleEndian.PutUint32(raw[:], uint32(s))
+		w.md5.Write(raw[:bytesPerSample])
+	}
+
+	b := &w.frame
+	b.reset()
+	// Sync code, fixed block size, 16-bit block size at the end of the header, and the STREAMINFO sample rate
+	b.write(0xFFF8, 16)
+	b.write(0x70, 8)
+	sampleSize := uint64(4)
+	if w.format.bitDepth == 24 {
+		sampleSize = 6
+	}
+	// Independent channels
+	b.write(uint64(channels-1)<<4|sampleSize<<1, 8)
+	b.writeUTF8(w.frameNumber)
+	b.write(uint64(n-1), 16)
+	b.write(uint64(crc8(b.bytes())), 8)
+
+	for _, subframe := range w.block {
+		writeSubframe(b, subframe, w.format.bitDepth)
+	}
+	b.align()
+	b.write(uint64(crc16(b.bytes())), 16)
+
+	frame := b.bytes()
+	if w.minFrameSize == 0 || len(frame) < w.minFrameSize {
+		w.minFrameSize = len(frame)
+	}
+	if len(frame) > w.maxFrameSize {
+		w.maxFrameSize = len(frame)
+	}
+	w.frameNumber++
+	w.totalSamples += uint64(n)
+	_, err := w.w.Write(frame)
+	return err
+}
+
+// writeSubframe writes a channel's samples as whichever of a constant, fixed or verbatim subframe is smallest.
+// Reference: https://xiph.org/flac/format.html#subframe
+func writeSubframe(b *bitWriter, samples []int32, bits int) {
+	constant := true
+	for _, s := range samples[1:] {
+		if s != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		b.write(0x00, 8)
+		b.writeSigned(int64(samples[0]), bits)
+		return
+	}
+
+	bestOrder, bestPartitionOrder, bestCost := -1, 0, len(samples)*bits
+	var bestResiduals, residuals []int64
+	for order := 0; order <= flacMaxFixedOrder && order < len(samples); order++ {
+		residuals = fixedResiduals(residuals[:0], samples, order)
+		partitionOrder, cost := partitionResiduals(residuals, len(samples), order)
+		if cost += order * bits; cost < bestCost {
+			bestOrder, bestPartitionOrder, bestCost = order, partitionOrder, cost
+			bestResiduals, residuals = residuals, bestResiduals
+		}
+	}
+
+	if bestOrder < 0 {
+		b.write(0x02, 8)
+		for _, s := range samples {
+			b.writeSigned(int64(s), bits)
+		}
+		return
+	}
+	b.write(uint64(0x08|bestOrder)<<1, 8)
+	for _, s := range samples[:bestOrder] {
+		b.writeSigned(int64(s), bits)
+	}
+	// Residuals with 4-bit Rice parameters
+	b.write(0, 2)
+	b.write(uint64(bestPartitionOrder), 4)
+	for _, part := range partitions(bestResiduals, len(samples), bestOrder, bestPartitionOrder) {
+		k, _ := riceParameter(part)
+		b.write(uint64(k), 4)
+		for _, r := range part {
+			u := zigzag(r)
+			b.writeUnary(u >> uint(k))
+			b.write(u&(1<<uint(k)-1), k)
+		}
+	}
+}
+
+// fixedResiduals appends the residuals of the fixed predictor of order to dst.
+func fixedResiduals(dst []int64, samples []int32, order int) []int64 {
+	for i := order; i < len(samples); i++ {
+		x := func(j int) int64 { return int64(samples[i-j]) }
+		var r int64
+		switch order {
+		case 0:
+			r = x(0)
+		case 1:
+			r = x(0) - x(1)
+		case 2:
+			r = x(0) - 2*x(1) + x(2)
+		case 3:
+			r = x(0) - 3*x(1) + 3*x(2) - x(3)
+		case 4:
+			r = x(0) - 4*x(1) + 6*x(2) - 4*x(3) + x(4)
+		}
+		dst = append(dst, r)
+	}
+	return dst
+}
+
+// partitionResiduals returns the partition order that codes the residuals in the fewest bits, and that bit count.
+func partitionResiduals(residuals []int64, blockSize, predictorOrder int) (int, int) {
+	bestOrder, bestBits := 0, -1
+	for order := 0; order <= flacMaxPartitionOrder; order++ {
+		// Partitions are equal in size, and the first must hold more than the warm-up samples
+		if blockSize%(1<<uint(order)) != 0 || blockSize>>uint(order) <= predictorOrder {
+			break
+		}
+		// Coding method and partition order
+		bits := 6
+		for _, part := range partitions(residuals, blockSize, predictorOrder, order) {
+			_, n := riceParameter(part)
+			bits += 4 + n
+		}
+		if bestBits < 0 || bits < bestBits {
+			bestOrder, bestBits = order, bits
+		}
+	}
+	return bestOrder, bestBits
+}
+
+// partitions splits the residuals into partitionOrder partitions. The first partition excludes the warm-up
+// samples.
+func partitions(residuals []int64, blockSize, predictorOrder, partitionOrder int) [][]int64 {
+	size := blockSize >> uint(partitionOrder)
+	parts := make([][]int64, 1<<uint(partitionOrder))
+	start := 0
+	for i := range parts {
+		end := start + size
+		if i == 0 {
+			end -= predictorOrder
+		}
+		parts[i] = residuals[start:end]
+		start = end
+	}
+	return parts
+}
+
+// riceParameter returns the Rice parameter that codes the residuals in the fewest bits, and that bit count.
+func riceParameter(residuals []int64) (int, int) {
+	best, bestBits := 0, -1
+	for k := 0; k <= flacMaxRiceParameter; k++ {
+		// Unary quotients, stop bits and the k low bits
+		bits := len(residuals) * (k + 1)
+		for _, r := range residuals {
+			bits += int(zigzag(r) >> uint(k))
+		}
+		if bestBits < 0 || bits < bestBits {
+			best, bestBits = k, bits
+		}
+	}
+	return best, bestBits
+}
+
+// zigzag maps signed residuals to unsigned: 0, -1, 1, -2, 2...
+func zigzag(r int64) uint64 {
+	return uint64(r<<1 ^ r>>63)
+}
+
+func (w *flacWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	if w.format != nil && len(w.pending) > 0 {
+		if err := w.writeFrame(w.pending); err != nil {
+			w.closed = true
+			return err
+		}
+		w.pending = w.pending[:0]
+	}
+	w.closed = true
+
+	if s, ok := w.w.(io.WriteSeeker); ok && w.format != nil {
+		if _, err := s.Seek(int64(len(flacMarker)), io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := s.Write(w.streamInfo()); err != nil {
+			return err
+		}
+		if _, err := s.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	return closeWriter(w.w)
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v>>16), byte(v>>8), byte(v)
+}
+
+// bitWriter writes bits in big endian order.
+type bitWriter struct {
+	buf   []byte
+	cache uint64
+	n     uint
+}
+
+func (b *bitWriter) reset() {
+	b.buf, b.cache, b.n = b.buf[:0], 0, 0
+}
+
+// write writes the n low bits of v.
+func (b *bitWriter) write(v uint64, n int) {
+	for n > 0 {
+		m := n
+		if m > 32 {
+			m = 32
+		}
+		n -= m
+		b.cache = b.cache<<uint(m) | (v>>uint(n))&(1<<uint(m)-1)
+		b.n += uint(m)
+		for b.n >= 8 {
+			b.n -= 8
+			b.buf = append(b.buf, byte(b.cache>>b.n))
+		}
+	}
+}
+
+func (b *bitWriter) writeSigned(v int64, n int) {
+	b.write(uint64(v)&(1<<uint(n)-1), n)
+}
+
+// writeUnary writes v zeros and a one.
+func (b *bitWriter) writeUnary(v uint64) {
+	for ; v >= 32; v -= 32 {
+		b.write(0, 32)
+	}
+	b.write(1, int(v)+1)
+}
+
+// writeUTF8 writes v in the extended UTF-8 coding used for frame numbers.
+func (b *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		b.write(v, 8)
+		return
+	}
+	n := 2
+	for v >= 1<<uint(5*n+1) {
+		n++
+	}
+	b.write((0xFF00>>uint(n))&0xFF|v>>uint(6*(n-1)), 8)
+	for i := n - 2; i >= 0; i-- {
+		b.write(0x80|(v>>uint(6*i))&0x3F, 8)
+	}
+}
+
+// align zero-pads to a byte boundary.
+func (b *bitWriter) align() {
+	if b.n > 0 {
+		b.write(0, int(8-b.n))
+	}
+}
+
+// bytes returns the written bytes, excluding an incomplete last byte.
+func (b *bitWriter) bytes() []byte {
+	return b.buf
+}
+
+// crc8 is the frame header CRC-8, with polynomial x^8 + x^2 + x^1 + x^0.
+func crc8(data []byte) uint8 {
+	var crc uint8
+	for _, d := range data {
+		crc ^= d
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 is the frame CRC-16, with polynomial x^16 + x^15 + x^2 + x^0.
+func crc16(data []byte) uint16 {
+	var crc uint16
+	for _, d := range data {
+		crc ^= uint16(d) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,189 @@
+// Package lossless records raw track audio to WAV or FLAC files, e.g. to archive a live stream's audio for
+// editing later. Audio is read before it's encoded for streaming, so files keep the source quality rather than
+// the stream's.
+package lossless
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const defaultBitDepth = 24
+
+var (
+	errClosed        = errors.New("lossless: the writer is closed")
+	errBitDepth      = errors.New("lossless: the bit depth must be 16 or 24")
+	errFormatChanged = errors.New("lossless: the sample rate or the channels of the audio are changed")
+	errNotAudio      = errors.New("lossless: the track isn't an audio track")
+)
+
+// Writer writes audio chunks to a file.
+type Writer interface {
+	// Write writes the samples of chunk. The first chunk sets the file's sample rate and channels, and chunks in any
+	// other format are rejected.
+	Write(chunk wave.Audio) error
+	// Close finishes the file, and closes the underlying writer if it's an io.Closer.
+	Close() error
+}
+
+// Option configures a Writer.
+type Option func(*config)
+
+type config struct {
+	bitDepth int
+}
+
+// WithBitDepth sets the file's sample bit depth, 16 or 24. The default, 24, keeps the precision of Float32
+// samples. Samples are dithered when reduced to 16 bits.
+func WithBitDepth(bits int) Option {
+	return func(c *config) {
+		c.bitDepth = bits
+	}
+}
+
+func newConfig(opts []Option) (config, error) {
+	c := config{bitDepth: defaultBitDepth}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.bitDepth != 16 && c.bitDepth != 24 {
+		return config{}, errBitDepth
+	}
+	return c, nil
+}
+
+// format is a file's sample format, set by its first chunk.
+type format struct {
+	sampleRate int
+	channels   int
+	bitDepth   int
+	converter  *wave.Converter
+}
+
+func newFormat(info wave.ChunkInfo, bitDepth int) format {
+	typ := wave.TypeInt32Interleaved
+	if bitDepth == 16 {
+		typ = wave.TypeInt16Interleaved
+	}
+	return format{
+		sampleRate: info.SamplingRate,
+		channels:   info.Channels,
+		bitDepth:   bitDepth,
+		converter:  wave.NewConverter(typ, true),
+	}
+}
+
+// samples appends the interleaved samples of chunk at the bit depth to dst.
+func (f *format) samples(dst []int32, chunk wave.Audio) ([]int32, error) {
+	info := chunk.ChunkInfo()
+	if info.SamplingRate != f.sampleRate || info.Channels != f.channels {
+		return dst, errFormatChanged
+	}
+	converted, err := f.converter.Convert(chunk)
+	if err != nil {
+		return dst, err
+	}
+	switch c := converted.(type) {
+	case *wave.Int16Interleaved:
+		for _, s := range c.Data {
+			dst = append(dst, int32(s))
+		}
+	case *wave.Int32Interleaved:
+		shift := 32 - f.bitDepth
+		for _, s := range c.Data {
+			dst = append(dst, s>>shift)
+		}
+	}
+	return dst, nil
+}
+
+// Recorder writes a track's raw audio to a Writer.
+type Recorder struct {
+	w    Writer
+	done chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	err    error
+}
+
+// NewRecorder starts writing track's audio to w. Audio is read after the track's transforms, e.g. noise
+// suppression, and the track can be encoded for streaming at the same time.
+func NewRecorder(track mediadevices.Track, w Writer) (*Recorder, error) {
+	t, ok := track.(*mediadevices.AudioTrack)
+	if !ok {
+		return nil, errNotAudio
+	}
+
+	r := &Recorder{w: w, done: make(chan struct{})}
+	go r.run(t.NewReader(false))
+	return r, nil
+}
+
+func (r *Recorder) run(reader audio.Reader) {
+	defer close(r.done)
+	for {
+		chunk, release, err := reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				r.fail(err)
+			}
+			return
+		}
+
+		r.mu.Lock()
+		closed := r.closed
+		r.mu.Unlock()
+		if closed {
+			release()
+			return
+		}
+
+		err = r.w.Write(chunk)
+		release()
+		if err != nil {
+			r.fail(err)
+			return
+		}
+	}
+}
+
+func (r *Recorder) fail(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+// Close stops recording after the current chunk and finishes the file. It returns the error that stopped the
+// recording, if any.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return errClosed
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	<-r.done
+	err := r.w.Close()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	return err
+}
+
+// closeWriter closes w if it's an io.Closer.
+func closeWriter(w io.Writer) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
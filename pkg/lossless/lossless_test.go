@@ -0,0 +1,345 @@
+package lossless
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func testChunks(channels, n, size int) ([]wave.Audio, []int32) {
+	var chunks []wave.Audio
+	var samples []int32
+	for i := 0; i < n; i += size {
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: size, Channels: channels, SamplingRate: 48000})
+		for j := 0; j < size; j++ {
+			for ch := 0; ch < channels; ch++ {
+				// A ramp, a silent channel, and noise in the low bits
+				v := int16((i + j) * 7 % 20000)
+				switch ch {
+				case 1:
+					v = 0
+				case 2:
+					v = int16((i + j) * 7919 % 65536)
+				}
+				chunk.SetInt16(j, ch, wave.Int16Sample(v))
+				samples = append(samples, int32(v))
+			}
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, samples
+}
+
+func tempFile(t *testing.T, name string) (*os.File, func()) {
+	dir, err := ioutil.TempDir("", "lossless")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return f, func() { os.RemoveAll(dir) }
+}
+
+func TestWAVWriter(t *testing.T) {
+	f, cleanup := tempFile(t, "test.wav")
+	defer cleanup()
+
+	chunks, samples := testChunks(2, 960, 480)
+	w, err := NewWAVWriter(f, WithBitDepth(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, chunk := range chunks {
+		if err := w.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Write(wave.NewInt16Interleaved(wave.ChunkInfo{Len: 1, Channels: 1, SamplingRate: 48000})); err != errFormatChanged {
+		t.Fatalf("expected %v, but got %v", errFormatChanged, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(chunks[0]); err != errClosed {
+		t.Fatalf("expected %v, but got %v", errClosed, err)
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := len(samples) * 2
+	if len(b) != wavHeaderSize+size {
+		t.Fatalf("expected %d bytes, but got %d", wavHeaderSize+size, len(b))
+	}
+	if riffSize := binary.LittleEndian.Uint32(b[4:]); riffSize != uint32(len(b)-8) {
+		t.Errorf("expected the RIFF size %d, but got %d", len(b)-8, riffSize)
+	}
+	if channels := binary.LittleEndian.Uint16(b[22:]); channels != 2 {
+		t.Errorf("expected 2 channels, but got %d", channels)
+	}
+	if dataSize := binary.LittleEndian.Uint32(b[40:]); dataSize != uint32(size) {
+		t.Errorf("expected the data size %d, but got %d", size, dataSize)
+	}
+	for i, s := range samples {
+		if v := int16(binary.LittleEndian.Uint16(b[wavHeaderSize+2*i:])); int32(v) != s {
+			t.Fatalf("expected the sample %d to be %d, but got %d", i, s, v)
+		}
+	}
+}
+
+func TestWAVWriterStream(t *testing.T) {
+	// Sizes are unknown without an io.WriteSeeker
+	var buf bytes.Buffer
+	w, err := NewWAVWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks, samples := testChunks(1, 3, 3)
+	if err := w.Write(chunks[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	if len(b) != wavHeaderSize+len(samples)*3 {
+		t.Fatalf("expected %d bytes, but got %d", wavHeaderSize+len(samples)*3, len(b))
+	}
+	if bits := binary.LittleEndian.Uint16(b[34:]); bits != 24 {
+		t.Errorf("expected 24 bits, but got %d", bits)
+	}
+	if dataSize := binary.LittleEndian.Uint32(b[40:]); dataSize != wavUnknownSize {
+		t.Errorf("expected the unknown data size, but got %d", dataSize)
+	}
+}
+
+func TestFLACWriter(t *testing.T) {
+	testCases := map[string]struct {
+		channels, n, size, bitDepth int
+	}{
+		"Stereo16": {channels: 2, n: 20160, size: 480, bitDepth: 16},
+		"Mono24":   {channels: 1, n: 5000, size: 1000, bitDepth: 24},
+		"Channels": {channels: 3, n: 4096, size: 512, bitDepth: 16},
+		"Short":    {channels: 2, n: 1, size: 1, bitDepth: 16},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			f, cleanup := tempFile(t, "test.flac")
+			defer cleanup()
+
+			chunks, samples := testChunks(c.channels, c.n, c.size)
+			w, err := NewFLACWriter(f, WithBitDepth(c.bitDepth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, chunk := range chunks {
+				if c.bitDepth == 24 {
+					// Int32 samples that keep the low bits of 24-bit audio
+					converted, err := wave.NewConverter(wave.TypeInt32Interleaved, false).Convert(chunk)
+					if err != nil {
+						t.Fatal(err)
+					}
+					chunk = converted
+				}
+				if err := w.Write(chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if c.bitDepth == 24 {
+				for i := range samples {
+					samples[i] <<= 8
+				}
+			}
+
+			b, err := ioutil.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, decoded := decodeFLAC(t, b)
+			if info.channels != c.channels || info.bitDepth != c.bitDepth || info.sampleRate != 48000 {
+				t.Errorf("expected %d channels of %d bits at 48000Hz, but got %+v", c.channels, c.bitDepth, info)
+			}
+			if info.totalSamples != uint64(len(samples)/c.channels) {
+				t.Errorf("expected %d samples, but got %d", len(samples)/c.channels, info.totalSamples)
+			}
+			if !reflect.DeepEqual(samples, decoded) {
+				t.Fatal("expected the decoded samples to be the written ones")
+			}
+
+			h := md5.New()
+			var raw [4]byte
+			for _, s := range samples {
+				binary.LittleEndian.PutUint32(raw[:], uint32(s))
+				h.Write(raw[:c.bitDepth/8])
+			}
+			if !bytes.Equal(info.md5, h.Sum(nil)) {
+				t.Errorf("expected the MD5 %x, but got %x", h.Sum(nil), info.md5)
+			}
+			if n := len(samples) / c.channels; n >= 4*flacBlockSize && len(b) > len(samples)*c.bitDepth/8*2/3 {
+				t.Errorf("expected the samples to be compressed, but got %d bytes", len(b))
+			}
+		})
+	}
+}
+
+type flacInfo struct {
+	sampleRate, channels, bitDepth int
+	totalSamples                   uint64
+	md5                            []byte
+}
+
+type bitReader struct {
+	t   *testing.T
+	b   []byte
+	pos int
+}
+
+func (r *bitReader) read(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		if r.pos/8 >= len(r.b) {
+			r.t.Fatal("unexpected end of the frame")
+		}
+		v = v<<1 | uint64(r.b[r.pos/8]>>(7-uint(r.pos%8))&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) readSigned(n int) int64 {
+	return int64(r.read(n)<<(64-uint(n))) >> (64 - uint(n))
+}
+
+// decodeFLAC decodes the subset of FLAC that flacWriter writes.
+func decodeFLAC(t *testing.T, b []byte) (flacInfo, []int32) {
+	if string(b[:4]) != flacMarker || b[4] != 0x80 {
+		t.Fatal("expected the marker and the last STREAMINFO")
+	}
+	r := &bitReader{t: t, b: b[8 : 8+flacStreamInfoSize]}
+	r.read(16 + 16 + 24 + 24)
+	info := flacInfo{
+		sampleRate:   int(r.read(20)),
+		channels:     int(r.read(3)) + 1,
+		bitDepth:     int(r.read(5)) + 1,
+		totalSamples: r.read(36),
+		md5:          b[8+flacStreamInfoSize-16 : 8+flacStreamInfoSize],
+	}
+
+	var samples []int32
+	r = &bitReader{t: t, b: b, pos: (8 + flacStreamInfoSize) * 8}
+	for frame := uint64(0); r.pos/8 < len(b); frame++ {
+		start := r.pos / 8
+		if sync := r.read(16); sync != 0xFFF8 {
+			t.Fatalf("expected the sync code, but got %x", sync)
+		}
+		r.read(8)
+		if channels := int(r.read(4)) + 1; channels != info.channels {
+			t.Fatalf("expected %d channels, but got %d", info.channels, channels)
+		}
+		r.read(4)
+		// Frame numbers in these tests fit in under 2 bytes
+		if n := r.read(8); n&0x80 != 0 {
+			n = (n&0x1F)<<6 | r.read(8)&0x3F
+			if n != frame {
+				t.Fatalf("expected the frame %d, but got %d", frame, n)
+			}
+		} else if n != frame {
+			t.Fatalf("expected the frame %d, but got %d", frame, n)
+		}
+		blockSize := int(r.read(16)) + 1
+		if crc := uint8(r.read(8)); crc != crc8(b[start:r.pos/8-1]) {
+			t.Fatal("wrong CRC-8 of the frame header")
+		}
+
+		channels := make([][]int32, info.channels)
+		for ch := range channels {
+			channels[ch] = decodeSubframe(r, blockSize, info.bitDepth)
+		}
+		if r.pos%8 != 0 {
+			r.read(8 - r.pos%8)
+		}
+		end := r.pos / 8
+		if crc := uint16(r.read(16)); crc != crc16(b[start:end]) {
+			t.Fatal("wrong CRC-16 of the frame")
+		}
+		for i := 0; i < blockSize; i++ {
+			for ch := range channels {
+				samples = append(samples, channels[ch][i])
+			}
+		}
+	}
+	return info, samples
+}
+
+func decodeSubframe(r *bitReader, blockSize, bits int) []int32 {
+	header := r.read(8)
+	typ := header >> 1 & 0x3F
+	samples := make([]int32, 0, blockSize)
+	switch {
+	case typ == 0:
+		v := int32(r.readSigned(bits))
+		for i := 0; i < blockSize; i++ {
+			samples = append(samples, v)
+		}
+	case typ == 1:
+		for i := 0; i < blockSize; i++ {
+			samples = append(samples, int32(r.readSigned(bits)))
+		}
+	case typ&0x38 == 0x08:
+		order := int(typ & 0x07)
+		for i := 0; i < order; i++ {
+			samples = append(samples, int32(r.readSigned(bits)))
+		}
+		if method := r.read(2); method != 0 {
+			r.t.Fatalf("expected the 4-bit Rice parameters, but got %d", method)
+		}
+		partitionOrder := r.read(4)
+		for p := 0; p < 1<<partitionOrder; p++ {
+			n := blockSize >> partitionOrder
+			if p == 0 {
+				n -= order
+			}
+			k := r.read(4)
+			for i := 0; i < n; i++ {
+				var q uint64
+				for r.read(1) == 0 {
+					q++
+				}
+				u := q<<k | r.read(int(k))
+				residual := int64(u>>1) ^ -int64(u&1)
+				x := func(j int) int64 { return int64(samples[len(samples)-j]) }
+				var prediction int64
+				switch order {
+				case 1:
+					prediction = x(1)
+				case 2:
+					prediction = 2*x(1) - x(2)
+				case 3:
+					prediction = 3*x(1) - 3*x(2) + x(3)
+				case 4:
+					prediction = 4*x(1) - 6*x(2) + 4*x(3) - x(4)
+				}
+				samples = append(samples, int32(prediction+residual))
+			}
+		}
+	default:
+		r.t.Fatalf("unexpected subframe type %x", typ)
+	}
+	return samples
+}
@@ -0,0 +1,116 @@
+package lossless
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	wavHeaderSize = 44
+	// wavFormatPCM is WAVE_FORMAT_PCM, which most players also accept for 24 bits.
+	wavFormatPCM = 1
+	// wavUnknownSize is the chunk size of an unfinished WAV file. Players read such files to the end.
+	wavUnknownSize = 0xFFFFFFFF
+)
+
+type wavWriter struct {
+	w      io.Writer
+	config config
+	format *format
+	size   int64
+	buf    []byte
+	values []int32
+	closed bool
+}
+
+// NewWAVWriter creates a Writer that writes a PCM WAV file to w. The header sizes are left unknown while writing,
+// so the file can be streamed or read during recording; Close fills them in if w is an io.WriteSeeker.
+func NewWAVWriter(w io.Writer, opts ...Option) (Writer, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &wavWriter{w: w, config: c}, nil
+}
+
+func (w *wavWriter) Write(chunk wave.Audio) error {
+	if w.closed {
+		return errClosed
+	}
+	if w.format == nil {
+		f := newFormat(chunk.ChunkInfo(), w.config.bitDepth)
+		w.format = &f
+		if _, err := w.w.Write(w.header(wavUnknownSize)); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	w.values, err = w.format.samples(w.values[:0], chunk)
+	if err != nil {
+		return err
+	}
+	bytesPerSample := w.format.bitDepth / 8
+	w.buf = w.buf[:0]
+	for _, v := range w.values {
+		for i := 0; i < bytesPerSample; i++ {
+			w.buf = append(w.buf, byte(v>>(8*i)))
+		}
+	}
+	n, err := w.w.Write(w.buf)
+	w.size += int64(n)
+	return err
+}
+
+// header returns the RIFF header for size bytes of data.
+func (w *wavWriter) header(size uint32) []byte {
+	f := w.format
+	blockAlign := f.channels * f.bitDepth / 8
+	riffSize := size
+	if size != wavUnknownSize {
+		// The data chunk is padded to an even size
+		riffSize = size + size%2 + wavHeaderSize - 8
+	}
+
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:], riffSize)
+	copy(h[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(h[16:], 16)
+	binary.LittleEndian.PutUint16(h[20:], wavFormatPCM)
+	binary.LittleEndian.PutUint16(h[22:], uint16(f.channels))
+	binary.LittleEndian.PutUint32(h[24:], uint32(f.sampleRate))
+	binary.LittleEndian.PutUint32(h[28:], uint32(f.sampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:], uint16(f.bitDepth))
+	copy(h[36:], "data")
+	binary.LittleEndian.PutUint32(h[40:], size)
+	return h
+}
+
+func (w *wavWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	if s, ok := w.w.(io.WriteSeeker); ok && w.format != nil && w.size < wavUnknownSize-wavHeaderSize {
+		if w.size%2 == 1 {
+			if _, err := s.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+		if _, err := s.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := s.Write(w.header(uint32(w.size))); err != nil {
+			return err
+		}
+		if _, err := s.Seek(0, io.SeekEnd); err != nil {
+			return err
+		}
+	}
+	return closeWriter(w.w)
+}
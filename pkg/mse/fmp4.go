@@ -0,0 +1,396 @@
+package mse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	// timescale is the video RTP timestamp clock rate, which frame durations are in.
+	timescale = 90000
+	// defaultDuration is the duration of frames with unknown duration, which is 30fps.
+	defaultDuration = timescale / 30
+
+	sampleFlagsKey   = 0x02000000
+	sampleFlagsDelta = 0x01010000
+)
+
+// identityMatrix is the movie and track header transformation matrix.
+var identityMatrix = []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000}
+
+// fragment is a frame's movie fragment.
+type fragment struct {
+	data     []byte
+	keyFrame bool
+}
+
+// muxer fragments encoded frames into fragmented MP4 for Media Source Extensions, where each frame is a movie
+// fragment, so frames are played as soon as they arrive.
+// Reference: ISO/IEC 14496-12, ISO/IEC 14496-15 and VP Codec ISO Media File Format Binding
+type muxer struct {
+	mimeType string
+
+	init   []byte
+	codecs string
+	sps    []byte
+	pps    []byte
+	vp9    vp9Header
+
+	sequence  uint32
+	decodeAt  uint64
+	lastTime  time.Time
+	lastDelta uint32
+}
+
+func newMuxer(mimeType string) (*muxer, error) {
+	switch {
+	case strings.EqualFold(mimeType, webrtc.MimeTypeH264), strings.EqualFold(mimeType, webrtc.MimeTypeVP9):
+	default:
+		return nil, fmt.Errorf("mse: %s isn't supported", mimeType)
+	}
+	return &muxer{mimeType: mimeType}, nil
+}
+
+// contentType returns the SourceBuffer MIME type.
+func (m *muxer) contentType() string {
+	return fmt.Sprintf(`video/mp4; codecs="%s"`, m.codecs)
+}
+
+// write fragments an encoded frame. The init segment is updated when stream parameters change, which
+// the second result tells. Frames before the first keyframe are dropped, which returns nil.
+func (m *muxer) write(data []byte, samples uint32, captureTime time.Time) (*fragment, bool, error) {
+	var sample []byte
+	var keyFrame, changed bool
+	if strings.EqualFold(m.mimeType, webrtc.MimeTypeH264) {
+		var err error
+		if sample, keyFrame, changed, err = m.h264Sample(data); err != nil {
+			return nil, false, err
+		}
+	} else {
+		sample = data
+		keyFrame = codec.IsKeyFrame(m.mimeType, data)
+		if keyFrame {
+			if h, ok := parseVP9KeyFrame(data); ok && h != m.vp9 {
+				m.vp9 = h
+				m.init = m.vp9Init()
+				changed = true
+			}
+		}
+	}
+	if m.init == nil || len(sample) == 0 {
+		return nil, false, nil
+	}
+
+	duration := m.duration(samples, captureTime)
+	f := &fragment{data: m.fragment(sample, keyFrame, duration), keyFrame: keyFrame}
+	m.decodeAt += uint64(duration)
+	return f, changed, nil
+}
+
+// h264Sample converts an Annex B frame into a sample's length prefixed NAL units. Parameter sets are
+// moved to the init segment.
+func (m *muxer) h264Sample(data []byte) ([]byte, bool, bool, error) {
+	var sample []byte
+	var sps, pps []byte
+	keyFrame := false
+	for _, nalu := range splitNALUs(data) {
+		if len(nalu) == 0 {
+			continue
+		}
+		switch nalu[0] & 0x1f {
+		case 7:
+			sps = nalu
+			continue
+		case 8:
+			pps = nalu
+			continue
+		case 9:
+			// Access unit delimiters aren't allowed in samples
+			continue
+		case 5:
+			keyFrame = true
+		}
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(nalu)))
+		sample = append(sample, length[:]...)
+		sample = append(sample, nalu...)
+	}
+
+	changed := false
+	if sps != nil && pps != nil && (!bytes.Equal(sps, m.sps) || !bytes.Equal(pps, m.pps)) {
+		width, height, err := parseSPS(sps)
+		if err != nil {
+			return nil, false, false, err
+		}
+		m.sps = append([]byte(nil), sps...)
+		m.pps = append([]byte(nil), pps...)
+		m.init = m.h264Init(width, height)
+		changed = true
+	}
+	return sample, keyFrame, changed, nil
+}
+
+// duration returns the frame's duration, which is the encoder's samples if it's known, or the capture time
+// interval. The interval is from the previous frame, since the next frame isn't known yet.
+func (m *muxer) duration(samples uint32, captureTime time.Time) uint32 {
+	delta := samples
+	if delta == 0 && !captureTime.IsZero() && !m.lastTime.IsZero() {
+		if d := captureTime.Sub(m.lastTime); d > 0 && d < time.Second {
+			delta = uint32(d * timescale / time.Second)
+		}
+	}
+	m.lastTime = captureTime
+	if delta == 0 {
+		delta = m.lastDelta
+	}
+	if delta == 0 {
+		delta = defaultDuration
+	}
+	m.lastDelta = delta
+	return delta
+}
+
+func (m *muxer) h264Init(width, height int) []byte {
+	m.codecs = fmt.Sprintf("avc1.%02x%02x%02x", m.sps[1], m.sps[2], m.sps[3])
+	return initSegment("avc1", width, height, func(b *boxWriter) {
+		b.box("avcC", func() {
+			b.u8(1)
+			b.write(m.sps[1:4])
+			// NAL units are prefixed by 4 bytes
+			b.u8(0xfc | 3)
+			b.u8(0xe0 | 1)
+			b.u16(uint16(len(m.sps)))
+			b.write(m.sps)
+			b.u8(1)
+			b.u16(uint16(len(m.pps)))
+			b.write(m.pps)
+		})
+	})
+}
+
+func (m *muxer) vp9Init() []byte {
+	// The level isn't known from frames, so it's level 4.1, which covers 1080p at 60fps
+	const level = 41
+	m.codecs = fmt.Sprintf("vp09.%02d.%02d.%02d", m.vp9.profile, level, m.vp9.bitDepth)
+	return initSegment("vp09", m.vp9.width, m.vp9.height, func(b *boxWriter) {
+		b.fullBox("vpcC", 1, 0, func() {
+			b.u8(uint8(m.vp9.profile))
+			b.u8(level)
+			// 4:2:0 subsampling colocated with luma, and limited range
+			b.u8(uint8(m.vp9.bitDepth)<<4 | 1<<1)
+			// Colour primaries, transfer characteristics and matrix coefficients are unspecified
+			b.u8(2)
+			b.u8(2)
+			b.u8(2)
+			b.u16(0)
+		})
+	})
+}
+
+// initSegment returns ftyp and moov for a video track with format's sample entry.
+func initSegment(format string, width, height int, config func(*boxWriter)) []byte {
+	b := &boxWriter{}
+	b.box("ftyp", func() {
+		b.writeString("iso5")
+		b.u32(512)
+		b.writeString("iso5iso6mp41")
+	})
+	b.box("moov", func() {
+		b.fullBox("mvhd", 0, 0, func() {
+			b.u32(0) // creation_time
+			b.u32(0) // modification_time
+			b.u32(1000)
+			b.u32(0) // duration
+			b.u32(0x00010000)
+			b.u16(0x0100)
+			b.zeros(10)
+			b.u32s(identityMatrix)
+			b.zeros(24)
+			b.u32(2) // next_track_ID
+		})
+		b.box("trak", func() {
+			// Track is enabled and in the movie
+			b.fullBox("tkhd", 0, 3, func() {
+				b.u32(0)
+				b.u32(0)
+				b.u32(1) // track_ID
+				b.u32(0)
+				b.u32(0) // duration
+				b.zeros(16)
+				b.u32s(identityMatrix)
+				b.u32(uint32(width) << 16)
+				b.u32(uint32(height) << 16)
+			})
+			b.box("mdia", func() {
+				b.fullBox("mdhd", 0, 0, func() {
+					b.u32(0)
+					b.u32(0)
+					b.u32(timescale)
+					b.u32(0)
+					b.u16(0x55c4) // und
+					b.u16(0)
+				})
+				b.fullBox("hdlr", 0, 0, func() {
+					b.u32(0)
+					b.writeString("vide")
+					b.zeros(12)
+					b.writeString("VideoHandler\x00")
+				})
+				b.box("minf", func() {
+					b.fullBox("vmhd", 0, 1, func() {
+						b.zeros(8)
+					})
+					b.box("dinf", func() {
+						b.fullBox("dref", 0, 0, func() {
+							b.u32(1)
+							// Samples are in the same file
+							b.fullBox("url ", 0, 1, func() {})
+						})
+					})
+					b.box("stbl", func() {
+						b.fullBox("stsd", 0, 0, func() {
+							b.u32(1)
+							b.box(format, func() {
+								b.zeros(6)
+								b.u16(1) // data_reference_index
+								b.zeros(16)
+								b.u16(uint16(width))
+								b.u16(uint16(height))
+								b.u32(0x00480000)
+								b.u32(0x00480000)
+								b.u32(0)
+								b.u16(1) // frame_count
+								b.zeros(32)
+								b.u16(0x0018)
+								b.u16(0xffff)
+								config(b)
+							})
+						})
+						// Samples are in the fragments
+						b.fullBox("stts", 0, 0, func() { b.u32(0) })
+						b.fullBox("stsc", 0, 0, func() { b.u32(0) })
+						b.fullBox("stsz", 0, 0, func() { b.u32(0); b.u32(0) })
+						b.fullBox("stco", 0, 0, func() { b.u32(0) })
+					})
+				})
+			})
+		})
+		b.box("mvex", func() {
+			b.fullBox("trex", 0, 0, func() {
+				b.u32(1) // track_ID
+				b.u32(1) // default_sample_description_index
+				b.u32(0)
+				b.u32(0)
+				b.u32(0)
+			})
+		})
+	})
+	return b.buf.Bytes()
+}
+
+// fragment returns a sample's moof and mdat.
+func (m *muxer) fragment(sample []byte, keyFrame bool, duration uint32) []byte {
+	m.sequence++
+	flags := uint32(sampleFlagsDelta)
+	if keyFrame {
+		flags = sampleFlagsKey
+	}
+
+	b := &boxWriter{}
+	var dataOffset int
+	b.box("moof", func() {
+		b.fullBox("mfhd", 0, 0, func() {
+			b.u32(m.sequence)
+		})
+		b.box("traf", func() {
+			// default-base-is-moof
+			b.fullBox("tfhd", 0, 0x020000, func() {
+				b.u32(1)
+			})
+			b.fullBox("tfdt", 1, 0, func() {
+				b.u64(m.decodeAt)
+			})
+			// data-offset, sample-duration, sample-size and sample-flags
+			b.fullBox("trun", 0, 0x000701, func() {
+				b.u32(1)
+				dataOffset = b.buf.Len()
+				b.u32(0)
+				b.u32(duration)
+				b.u32(uint32(len(sample)))
+				b.u32(flags)
+			})
+		})
+	})
+	// Data offset is from moof to the sample in mdat
+	binary.BigEndian.PutUint32(b.buf.Bytes()[dataOffset:], uint32(b.buf.Len()+8))
+	b.box("mdat", func() {
+		b.write(sample)
+	})
+	return b.buf.Bytes()
+}
+
+// boxWriter writes nested boxes, whose sizes are filled after their contents.
+type boxWriter struct {
+	buf bytes.Buffer
+}
+
+func (b *boxWriter) box(typ string, content func()) {
+	start := b.buf.Len()
+	b.u32(0)
+	b.writeString(typ)
+	content()
+	binary.BigEndian.PutUint32(b.buf.Bytes()[start:], uint32(b.buf.Len()-start))
+}
+
+func (b *boxWriter) fullBox(typ string, version uint8, flags uint32, content func()) {
+	b.box(typ, func() {
+		b.u32(uint32(version)<<24 | flags)
+		content()
+	})
+}
+
+func (b *boxWriter) u8(v uint8) {
+	b.buf.WriteByte(v)
+}
+
+func (b *boxWriter) u16(v uint16) {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], v)
+	b.buf.Write(buf[:])
+}
+
+func (b *boxWriter) u32(v uint32) {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	b.buf.Write(buf[:])
+}
+
+func (b *boxWriter) u32s(vs []uint32) {
+	for _, v := range vs {
+		b.u32(v)
+	}
+}
+
+func (b *boxWriter) u64(v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	b.buf.Write(buf[:])
+}
+
+func (b *boxWriter) zeros(n int) {
+	b.buf.Write(make([]byte, n))
+}
+
+func (b *boxWriter) write(p []byte) {
+	b.buf.Write(p)
+}
+
+func (b *boxWriter) writeString(s string) {
+	b.buf.WriteString(s)
+}
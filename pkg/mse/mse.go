@@ -0,0 +1,309 @@
+// Package mse streams a video track to browsers over WebSocket for Media Source Extensions, so the local
+// pipeline can be previewed without a WebRTC connection. Encoded H.264 or VP9 frames are fragmented into
+// fragmented MP4, one frame per fragment, and pushed to each WebSocket.
+//
+// Each WebSocket receives a text message with the SourceBuffer MIME type, e.g.
+// `video/mp4; codecs="avc1.42e01f"`, followed by the binary init segment and binary fragments. The
+// MIME type and init segment are sent again when stream parameters change, e.g. resolution.
+// Fragments start at a keyframe, and late clients skip fragments until the next keyframe, so players
+// seek to the end of the buffered range to keep latency low. Streamer serves such a player page to other
+// requests.
+package mse
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/internal/logging"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// maxQueued is how many messages are queued for a client before it's considered late.
+	maxQueued = 256
+	// maxCached is how many fragments since the last keyframe are cached for new clients.
+	// Fragments aren't cached until the next keyframe if keyframes are farther apart.
+	maxCached = maxQueued - 2
+)
+
+var logger = logging.NewLogger("mediadevices/mse")
+
+var errClosed = errors.New("mse: streamer has been closed")
+
+// message is a WebSocket message to clients.
+type message struct {
+	text bool
+	data []byte
+}
+
+type client struct {
+	messages chan message
+	// late is set when the queue is full, and fragments are skipped until the next keyframe, where the client
+	// starts again from the init segment.
+	late bool
+}
+
+// start queues the MIME type, init segment and fragments from a keyframe.
+func (c *client) start(contentType string, init []byte, fragments ...[]byte) {
+	c.late = !c.send(message{text: true, data: []byte(contentType)}) || !c.send(message{data: init})
+	for _, f := range fragments {
+		if c.late {
+			return
+		}
+		c.late = !c.send(message{data: f})
+	}
+}
+
+// send queues msg, and returns false if the queue is full.
+func (c *client) send(msg message) bool {
+	select {
+	case c.messages <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Streamer encodes a video track once, and streams fragments to WebSocket clients.
+type Streamer struct {
+	reader mediadevices.EncodedReadCloser
+	muxer  *muxer
+	done   chan struct{}
+
+	mu          sync.Mutex
+	contentType string
+	init        []byte
+	cached      [][]byte
+	caching     bool
+	clients     map[*client]struct{}
+	closed      bool
+}
+
+// NewStreamer starts encoding track in mimeType, which is webrtc.MimeTypeH264 or webrtc.MimeTypeVP9. Frames
+// are encoded while the streamer is open even if there's no client, so clients start at the last keyframe.
+func NewStreamer(track mediadevices.Track, mimeType string) (*Streamer, error) {
+	m, err := newMuxer(mimeType)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := track.NewEncodedReader(mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Streamer{
+		reader:  reader,
+		muxer:   m,
+		done:    make(chan struct{}),
+		clients: make(map[*client]struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+func (s *Streamer) run() {
+	defer close(s.done)
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closed = true
+		for c := range s.clients {
+			close(c.messages)
+			delete(s.clients, c)
+		}
+	}()
+
+	for {
+		buffer, release, err := s.reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				logger.Debugf("failed to read the encoded frames: %s", err)
+			}
+			return
+		}
+		f, changed, err := s.muxer.write(buffer.Data, buffer.Samples, buffer.CaptureTime)
+		release()
+		if err != nil {
+			logger.Debugf("failed to fragment the frame: %s", err)
+			continue
+		}
+		if f != nil {
+			s.broadcast(f, changed)
+		}
+	}
+}
+
+func (s *Streamer) broadcast(f *fragment, changed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if changed {
+		s.contentType = s.muxer.contentType()
+		s.init = s.muxer.init
+		for c := range s.clients {
+			c.late = true
+		}
+	}
+	if f.keyFrame {
+		s.cached = s.cached[:0]
+		s.caching = true
+	}
+	if s.caching {
+		if len(s.cached) < maxCached {
+			s.cached = append(s.cached, f.data)
+		} else {
+			s.cached = s.cached[:0]
+			s.caching = false
+		}
+	}
+
+	for c := range s.clients {
+		switch {
+		case !c.late:
+			c.late = !c.send(message{data: f.data})
+		case f.keyFrame:
+			c.start(s.contentType, s.init, f.data)
+		}
+	}
+}
+
+// ServeHTTP streams to WebSocket requests, and serves the player page to others. Any origin is accepted,
+// so it's meant for trusted networks, or for handlers that check requests first.
+func (s *Streamer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, playerHTML)
+		return
+	}
+	server := websocket.Server{
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: s.serve,
+	}
+	server.ServeHTTP(w, r)
+}
+
+func (s *Streamer) serve(ws *websocket.Conn) {
+	defer ws.Close()
+	c := &client{messages: make(chan message, maxQueued)}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	if s.init != nil && s.caching {
+		c.start(s.contentType, s.init, s.cached...)
+	} else {
+		// The client waits for the next keyframe
+		c.late = true
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	// Clients don't send anything, so reading tells when they disconnect
+	disconnected := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, ws)
+		close(disconnected)
+	}()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				return
+			}
+			var err error
+			if msg.text {
+				err = websocket.Message.Send(ws, string(msg.data))
+			} else {
+				err = websocket.Message.Send(ws, msg.data)
+			}
+			if err != nil {
+				return
+			}
+		case <-disconnected:
+			return
+		}
+	}
+}
+
+// Close stops encoding the track, and disconnects clients.
+func (s *Streamer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	err := s.reader.Close()
+	<-s.done
+	return err
+}
+
+// playerHTML is a page that plays the stream at the same URL.
+const playerHTML = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>mediadevices</title></head>
+<body style="margin:0;background:#000">
+<video id="video" autoplay muted playsinline style="width:100%;height:100vh"></video>
+<script>
+const video = document.getElementById('video');
+const source = new MediaSource();
+video.src = URL.createObjectURL(source);
+source.addEventListener('sourceopen', () => {
+  const ws = new WebSocket(location.href.replace(/^http/, 'ws'));
+  ws.binaryType = 'arraybuffer';
+  const queue = [];
+  let buffer;
+  const append = () => {
+    while (buffer && !buffer.updating && queue.length > 0) {
+      const data = queue.shift();
+      if (typeof data === 'string') {
+        buffer.changeType(data);
+      } else {
+        buffer.appendBuffer(data);
+      }
+    }
+  };
+  ws.onmessage = (e) => {
+    if (buffer || typeof e.data !== 'string') {
+      queue.push(e.data);
+      append();
+      return;
+    }
+    buffer = source.addSourceBuffer(e.data);
+    buffer.addEventListener('updateend', () => {
+      const ranges = buffer.buffered;
+      if (ranges.length > 0) {
+        const end = ranges.end(ranges.length - 1);
+        if (end - video.currentTime > 1) {
+          video.currentTime = end - 0.1;
+        }
+        if (end - ranges.start(0) > 30) {
+          buffer.remove(0, end - 10);
+          return;
+        }
+      }
+      append();
+    });
+  };
+});
+</script>
+</body>
+</html>
+`
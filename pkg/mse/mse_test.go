@@ -0,0 +1,322 @@
+package mse
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/webrtc/v3"
+	"golang.org/x/net/websocket"
+)
+
+// bitWriter writes Exp-Golomb coded syntax elements for test parameter sets.
+type bitWriter struct {
+	buf []byte
+	n   int
+}
+
+func (w *bitWriter) u(n int, v uint32) {
+	for i := n - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte(v>>uint(i)&1) << (7 - uint(w.n%8))
+		w.n++
+	}
+}
+
+func (w *bitWriter) ue(v uint32) {
+	bits := 0
+	for x := v + 1; x > 1; x >>= 1 {
+		bits++
+	}
+	w.u(bits, 0)
+	w.u(bits+1, v+1)
+}
+
+// testSPS returns a 1920x1080 baseline SPS, which is cropped from 1088 lines.
+func testSPS() []byte {
+	w := &bitWriter{}
+	w.u(8, 0x67)
+	w.u(8, 66)
+	w.u(8, 0xc0)
+	w.u(8, 40)
+	w.ue(0) // seq_parameter_set_id
+	w.ue(0) // log2_max_frame_num_minus4
+	w.ue(0) // pic_order_cnt_type
+	w.ue(0) // log2_max_pic_order_cnt_lsb_minus4
+	w.ue(1) // max_num_ref_frames
+	w.u(1, 0)
+	w.ue(119)
+	w.ue(67)
+	w.u(1, 1) // frame_mbs_only_flag
+	w.u(1, 1) // direct_8x8_inference_flag
+	w.u(1, 1) // frame_cropping_flag
+	w.ue(0)
+	w.ue(0)
+	w.ue(0)
+	w.ue(4)
+	w.u(1, 0) // vui_parameters_present_flag
+	w.u(1, 1) // rbsp_stop_one_bit
+	return w.buf
+}
+
+func TestParseSPS(t *testing.T) {
+	width, height, err := parseSPS(testSPS())
+	if err != nil {
+		t.Fatalf("failed to parse the SPS: %s", err)
+	}
+	if width != 1920 || height != 1080 {
+		t.Fatalf("expected 1920x1080, but got %dx%d", width, height)
+	}
+	if _, _, err := parseSPS(testSPS()[:6]); err != errInvalidSPS {
+		t.Fatalf("expected %v for the truncated SPS, but got %v", errInvalidSPS, err)
+	}
+}
+
+func TestParseVP9KeyFrame(t *testing.T) {
+	w := &bitWriter{}
+	w.u(2, 2) // frame_marker
+	w.u(2, 0) // profile
+	w.u(1, 0) // show_existing_frame
+	w.u(1, 0) // frame_type
+	w.u(1, 1) // show_frame
+	w.u(1, 0) // error_resilient_mode
+	w.u(24, 0x498342)
+	w.u(3, 1) // color_space
+	w.u(1, 0) // color_range
+	w.u(16, 639)
+	w.u(16, 479)
+
+	h, ok := parseVP9KeyFrame(w.buf)
+	if !ok {
+		t.Fatal("failed to parse the keyframe")
+	}
+	expected := vp9Header{profile: 0, bitDepth: 8, width: 640, height: 480}
+	if h != expected {
+		t.Fatalf("expected %+v, but got %+v", expected, h)
+	}
+	if _, ok := parseVP9KeyFrame([]byte{0x86}); ok {
+		t.Fatal("expected the inter frame not to be parsed")
+	}
+}
+
+// boxes returns types and contents of data's top level boxes.
+func boxes(t *testing.T, data []byte) ([]string, map[string][]byte) {
+	var types []string
+	contents := make(map[string][]byte)
+	for len(data) > 0 {
+		if len(data) < 8 {
+			t.Fatalf("expected a box header, but got %v", data)
+		}
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 8 || size > len(data) {
+			t.Fatalf("invalid size of a box: %d", size)
+		}
+		typ := string(data[4:8])
+		types = append(types, typ)
+		contents[typ] = data[8:size]
+		data = data[size:]
+	}
+	return types, contents
+}
+
+func find(t *testing.T, data []byte, path ...string) []byte {
+	for _, typ := range path {
+		_, contents := boxes(t, data)
+		var ok bool
+		if data, ok = contents[typ]; !ok {
+			t.Fatalf("expected %s in %v", typ, path)
+		}
+	}
+	return data
+}
+
+func annexB(nalus ...[]byte) []byte {
+	var data []byte
+	for _, nalu := range nalus {
+		data = append(data, 0, 0, 0, 1)
+		data = append(data, nalu...)
+	}
+	return data
+}
+
+func TestMuxerH264(t *testing.T) {
+	m, err := newMuxer(webrtc.MimeTypeH264)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pps := []byte{0x68, 0xce, 0x3c, 0x80}
+	idr := []byte{0x65, 0x88, 0x84, 0x00}
+	nonIDR := []byte{0x41, 0x9a, 0x02}
+
+	if f, _, err := m.write(annexB(nonIDR), 3000, time.Time{}); f != nil || err != nil {
+		t.Fatalf("expected the frames before the first keyframe to be dropped, but got %v, %v", f, err)
+	}
+
+	f, changed, err := m.write(annexB([]byte{0x09, 0xf0}, testSPS(), pps, idr), 3000, time.Time{})
+	if err != nil {
+		t.Fatalf("failed to write the keyframe: %s", err)
+	}
+	if !changed || !f.keyFrame {
+		t.Fatalf("expected a keyframe with a new init segment, but got %v, %v", f.keyFrame, changed)
+	}
+	if ct := m.contentType(); ct != `video/mp4; codecs="avc1.42c028"` {
+		t.Fatalf("unexpected content type: %s", ct)
+	}
+
+	types, _ := boxes(t, m.init)
+	if strings.Join(types, ",") != "ftyp,moov" {
+		t.Fatalf("expected ftyp and moov, but got %v", types)
+	}
+	entry := find(t, m.init, "moov", "trak", "mdia", "minf", "stbl")
+	// stsd is a full box with the entry count
+	stsd := find(t, entry, "stsd")[8:]
+	avc1 := find(t, stsd, "avc1")
+	if width, height := binary.BigEndian.Uint16(avc1[24:]), binary.BigEndian.Uint16(avc1[26:]); width != 1920 || height != 1080 {
+		t.Fatalf("expected 1920x1080 in the sample entry, but got %dx%d", width, height)
+	}
+	avcC := find(t, avc1[78:], "avcC")
+	if !bytes.Contains(avcC, testSPS()) || !bytes.Contains(avcC, pps) {
+		t.Fatal("expected the parameter sets in avcC")
+	}
+
+	checkFragment := func(f *fragment, decodeAt uint64, sample []byte) {
+		types, contents := boxes(t, f.data)
+		if strings.Join(types, ",") != "moof,mdat" {
+			t.Fatalf("expected moof and mdat, but got %v", types)
+		}
+		if !bytes.Equal(contents["mdat"], sample) {
+			t.Fatalf("expected the sample %v, but got %v", sample, contents["mdat"])
+		}
+		tfdt := find(t, f.data, "moof", "traf", "tfdt")
+		if v := binary.BigEndian.Uint64(tfdt[4:]); v != decodeAt {
+			t.Fatalf("expected the decode time %d, but got %d", decodeAt, v)
+		}
+		trun := find(t, f.data, "moof", "traf", "trun")
+		offset := binary.BigEndian.Uint32(trun[8:])
+		if !bytes.Equal(f.data[offset:], sample) {
+			t.Fatalf("expected the data offset %d to point to the sample", offset)
+		}
+	}
+	checkFragment(f, 0, []byte{0, 0, 0, 4, 0x65, 0x88, 0x84, 0x00})
+
+	f, changed, err = m.write(annexB(nonIDR), 0, time.Time{})
+	if err != nil || changed || f.keyFrame {
+		t.Fatalf("expected a delta frame, but got %v, %v, %v", f.keyFrame, changed, err)
+	}
+	checkFragment(f, 3000, []byte{0, 0, 0, 3, 0x41, 0x9a, 0x02})
+}
+
+type mockReader struct {
+	buffers chan mediadevices.EncodedBuffer
+	closed  chan struct{}
+}
+
+func (r *mockReader) Read() (mediadevices.EncodedBuffer, func(), error) {
+	select {
+	case b := <-r.buffers:
+		return b, func() {}, nil
+	case <-r.closed:
+		return mediadevices.EncodedBuffer{}, func() {}, io.EOF
+	}
+}
+
+func (r *mockReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+type mockTrack struct {
+	mediadevices.Track
+	reader *mockReader
+}
+
+func (track *mockTrack) NewEncodedReader(codecName string) (mediadevices.EncodedReadCloser, error) {
+	if codecName != webrtc.MimeTypeH264 {
+		return nil, errors.New("not supported")
+	}
+	return track.reader, nil
+}
+
+func TestStreamer(t *testing.T) {
+	reader := &mockReader{buffers: make(chan mediadevices.EncodedBuffer), closed: make(chan struct{})}
+	s, err := NewStreamer(&mockTrack{reader: reader}, webrtc.MimeTypeH264)
+	if err != nil {
+		t.Fatalf("failed to create the streamer: %s", err)
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.Contains(string(page), "MediaSource") {
+		t.Fatal("expected the player page")
+	}
+
+	dial := func() *websocket.Conn {
+		ws, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1), "", server.URL)
+		if err != nil {
+			t.Fatalf("failed to connect: %s", err)
+		}
+		ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+		return ws
+	}
+	receive := func(ws *websocket.Conn, prefix string) {
+		var msg []byte
+		if err := websocket.Message.Receive(ws, &msg); err != nil {
+			t.Fatalf("failed to receive: %s", err)
+		}
+		if !strings.HasPrefix(string(msg), prefix) && !bytes.Equal(msg[4:8], []byte(prefix)) {
+			t.Fatalf("expected %s, but got %q", prefix, msg)
+		}
+	}
+
+	ws1 := dial()
+	defer ws1.Close()
+	// Wait until the client is added
+	for {
+		s.mu.Lock()
+		n := len(s.clients)
+		s.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	reader.buffers <- mediadevices.EncodedBuffer{Data: annexB(testSPS(), []byte{0x68, 0xce, 0x3c, 0x80}, []byte{0x65, 0x88}), Samples: 3000}
+	reader.buffers <- mediadevices.EncodedBuffer{Data: annexB([]byte{0x41, 0x9a}), Samples: 3000}
+	for _, prefix := range []string{"video/mp4", "ftyp", "moof", "moof"} {
+		receive(ws1, prefix)
+	}
+
+	// The new client starts at the cached keyframe
+	ws2 := dial()
+	defer ws2.Close()
+	for _, prefix := range []string{"video/mp4", "ftyp", "moof", "moof"} {
+		receive(ws2, prefix)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("failed to close the streamer: %s", err)
+	}
+	var msg []byte
+	if err := websocket.Message.Receive(ws1, &msg); err == nil {
+		t.Fatal("expected the clients to be disconnected")
+	}
+	if err := s.Close(); err != errClosed {
+		t.Fatalf("expected %v, but got %v", errClosed, err)
+	}
+}
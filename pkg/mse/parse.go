@@ -0,0 +1,235 @@
+package mse
+
+import (
+	"errors"
+)
+
+var errInvalidSPS = errors.New("mse: invalid sequence parameter set")
+
+// splitNALUs returns Annex B data's NAL units without start codes.
+func splitNALUs(data []byte) [][]byte {
+	var nalus [][]byte
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+		if start >= 0 {
+			nalus = append(nalus, trimZeros(data[start:i]))
+		}
+		i += 2
+		start = i + 1
+	}
+	if start >= 0 && start < len(data) {
+		nalus = append(nalus, data[start:])
+	}
+	return nalus
+}
+
+// trimZeros removes trailing zeros, which are the first byte of 4 byte start codes.
+func trimZeros(nalu []byte) []byte {
+	for len(nalu) > 0 && nalu[len(nalu)-1] == 0 {
+		nalu = nalu[:len(nalu)-1]
+	}
+	return nalu
+}
+
+// unescape removes emulation prevention bytes from a NAL unit.
+func unescape(nalu []byte) []byte {
+	rbsp := make([]byte, 0, len(nalu))
+	zeros := 0
+	for _, b := range nalu {
+		if zeros >= 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
+
+// bitReader reads Exp-Golomb coded syntax elements. Reading past the end sets err.
+type bitReader struct {
+	buf []byte
+	pos int
+	err bool
+}
+
+func (r *bitReader) u(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if r.pos >= 8*len(r.buf) {
+			r.err = true
+			return 0
+		}
+		v = v<<1 | uint32(r.buf[r.pos/8]>>(7-uint(r.pos%8))&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) ue() uint32 {
+	zeros := 0
+	for r.u(1) == 0 && !r.err {
+		zeros++
+		if zeros > 31 {
+			r.err = true
+			return 0
+		}
+	}
+	return 1<<uint(zeros) - 1 + r.u(zeros)
+}
+
+func (r *bitReader) se() int32 {
+	v := r.ue()
+	if v&1 == 1 {
+		return int32(v/2 + 1)
+	}
+	return -int32(v / 2)
+}
+
+// parseSPS returns an H.264 SPS's cropped picture size.
+// Reference: ITU-T H.264, 7.3.2.1.1 Sequence parameter set data syntax
+func parseSPS(sps []byte) (width, height int, err error) {
+	if len(sps) < 4 {
+		return 0, 0, errInvalidSPS
+	}
+	r := &bitReader{buf: unescape(sps)[1:]}
+	profile := r.u(8)
+	r.u(16) // constraint_set_flags, level_idc
+	r.ue()  // seq_parameter_set_id
+
+	chromaFormat := uint32(1)
+	separateColourPlane := false
+	switch profile {
+	case 100, 110, 122, 244, 44, 83, 86, 118, 128, 138, 139, 134, 135:
+		chromaFormat = r.ue()
+		if chromaFormat == 3 {
+			separateColourPlane = r.u(1) == 1
+		}
+		r.ue() // bit_depth_luma_minus8
+		r.ue() // bit_depth_chroma_minus8
+		r.u(1) // qpprime_y_zero_transform_bypass_flag
+		if r.u(1) == 1 {
+			lists := 8
+			if chromaFormat == 3 {
+				lists = 12
+			}
+			for i := 0; i < lists; i++ {
+				if r.u(1) == 0 {
+					continue
+				}
+				size := 16
+				if i >= 6 {
+					size = 64
+				}
+				last, next := int32(8), int32(8)
+				for j := 0; j < size && !r.err; j++ {
+					if next != 0 {
+						next = (last + r.se() + 256) % 256
+					}
+					if next != 0 {
+						last = next
+					}
+				}
+			}
+		}
+	}
+
+	r.ue() // log2_max_frame_num_minus4
+	switch r.ue() {
+	case 0:
+		r.ue() // log2_max_pic_order_cnt_lsb_minus4
+	case 1:
+		r.u(1) // delta_pic_order_always_zero_flag
+		r.se() // offset_for_non_ref_pic
+		r.se() // offset_for_top_to_bottom_field
+		cycle := r.ue()
+		for i := uint32(0); i < cycle && !r.err; i++ {
+			r.se()
+		}
+	}
+	r.ue() // max_num_ref_frames
+	r.u(1) // gaps_in_frame_num_value_allowed_flag
+	widthInMBs := int(r.ue()) + 1
+	heightInMapUnits := int(r.ue()) + 1
+	frameMBsOnly := int(r.u(1))
+	if frameMBsOnly == 0 {
+		r.u(1) // mb_adaptive_frame_field_flag
+	}
+	r.u(1) // direct_8x8_inference_flag
+
+	width = widthInMBs * 16
+	height = (2 - frameMBsOnly) * heightInMapUnits * 16
+	if r.u(1) == 1 {
+		cropX, cropY := 1, 2-frameMBsOnly
+		if chromaFormat != 0 && !separateColourPlane {
+			if chromaFormat != 3 {
+				cropX = 2
+			}
+			if chromaFormat == 1 {
+				cropY *= 2
+			}
+		}
+		left, right, top, bottom := int(r.ue()), int(r.ue()), int(r.ue()), int(r.ue())
+		width -= cropX * (left + right)
+		height -= cropY * (top + bottom)
+	}
+	if r.err || width <= 0 || height <= 0 {
+		return 0, 0, errInvalidSPS
+	}
+	return width, height, nil
+}
+
+// vp9Header is VP9 keyframe information for the sample entry.
+type vp9Header struct {
+	profile  int
+	bitDepth int
+	width    int
+	height   int
+}
+
+// parseVP9KeyFrame parses a VP9 keyframe's uncompressed header.
+// Reference: VP9 Bitstream Specification, 6.2 Uncompressed header syntax
+func parseVP9KeyFrame(data []byte) (vp9Header, bool) {
+	r := &bitReader{buf: data}
+	if r.u(2) != 2 {
+		return vp9Header{}, false
+	}
+	low := r.u(1)
+	h := vp9Header{profile: int(r.u(1)<<1 | low), bitDepth: 8}
+	if h.profile == 3 {
+		r.u(1) // reserved_zero
+	}
+	if r.u(1) == 1 || r.u(1) != 0 {
+		// show_existing_frame, or frame_type isn't KEY_FRAME
+		return vp9Header{}, false
+	}
+	r.u(2) // show_frame, error_resilient_mode
+	if r.u(24) != 0x498342 {
+		return vp9Header{}, false
+	}
+	if h.profile >= 2 {
+		h.bitDepth = 10
+		if r.u(1) == 1 {
+			h.bitDepth = 12
+		}
+	}
+	if r.u(3) != 7 {
+		// color_space isn't CS_RGB
+		r.u(1) // color_range
+		if h.profile == 1 || h.profile == 3 {
+			r.u(3) // subsampling_x, subsampling_y, reserved_zero
+		}
+	} else if h.profile == 1 || h.profile == 3 {
+		r.u(1) // reserved_zero
+	}
+	h.width = int(r.u(16)) + 1
+	h.height = int(r.u(16)) + 1
+	return h, !r.err
+}
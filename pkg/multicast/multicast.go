@@ -0,0 +1,275 @@
+// Package multicast sends mediadevices tracks as multicast RTP, and announces sessions by SAP, so
+// AV-over-IP network receivers, e.g. VLC and GStreamer, can play them without a WebRTC peer.
+// Reference: https://tools.ietf.org/html/rfc2974
+package multicast
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	defaultTTL              = 16
+	defaultMTU              = 1200
+	defaultAnnounceInterval = 5 * time.Second
+	defaultSessionName      = "mediadevices"
+)
+
+var (
+	errEmptyAddress = errors.New("multicast: address can't be empty")
+	errNoCodec      = errors.New("multicast: codec can't be nil")
+	errClosed       = errors.New("multicast: sender has been closed")
+)
+
+// Config configures how the sender sends the track and announces the session.
+type Config struct {
+	// Address is the RTP multicast group and port, e.g. "239.255.0.1:5004".
+	Address string
+	// Codec is the codec the track is encoded with, e.g. the encoder params' RTPCodec. Its payload type,
+	// clock rate, channels and fmtp line are announced in the SDP.
+	Codec *codec.RTPCodec
+	// TTL is packet time to live, or IPv6 hop limit. The default is 16.
+	TTL int
+	// Interface is the network interface to send packets on. The system default interface is used if
+	// it's nil.
+	Interface *net.Interface
+	// MTU is the maximum RTP packet size. The default is 1200.
+	MTU int
+	// SessionName is the session name in the SDP, which receivers show in playlists.
+	SessionName string
+	// SAPAddress is where announcements are sent. The default is 224.2.127.254:9875 for IPv4, and
+	// the SAP address for the group's scope for IPv6, e.g. [ff0e::2:7ffe]:9875. Announcements are
+	// disabled if it's "-".
+	SAPAddress string
+	// AnnounceInterval is the interval between announcements. The default is 5 seconds, which is shorter
+	// than SAP recommends, for small lab networks.
+	AnnounceInterval time.Duration
+}
+
+// Sender sends a track to a multicast group, and announces it until Close is called.
+type Sender struct {
+	config Config
+	conn   *net.UDPConn
+	sap    *net.UDPConn
+	reader mediadevices.RTPReadCloser
+	sdp    string
+
+	mu      sync.Mutex
+	closed  bool
+	closing chan struct{}
+	wg      sync.WaitGroup
+}
+
+// Send starts sending track to config's multicast group. The returned Sender owns track's RTP reader,
+// but not the track.
+func Send(config Config, track mediadevices.Track) (*Sender, error) {
+	if config.Address == "" {
+		return nil, errEmptyAddress
+	}
+	if config.Codec == nil {
+		return nil, errNoCodec
+	}
+	if config.TTL == 0 {
+		config.TTL = defaultTTL
+	}
+	if config.MTU == 0 {
+		config.MTU = defaultMTU
+	}
+	if config.SessionName == "" {
+		config.SessionName = defaultSessionName
+	}
+	if config.AnnounceInterval == 0 {
+		config.AnnounceInterval = defaultAnnounceInterval
+	}
+
+	group, err := net.ResolveUDPAddr("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %s", config.Address, err)
+	}
+	conn, err := dial(group, config)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sender{
+		config:  config,
+		conn:    conn,
+		closing: make(chan struct{}),
+	}
+	origin := conn.LocalAddr().(*net.UDPAddr).IP
+	s.sdp = sessionDescription(config, group, origin, rand.Uint32())
+
+	if config.SAPAddress != "-" {
+		sapAddr, err := resolveSAPAddress(config.SAPAddress, group.IP)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if s.sap, err = dial(sapAddr, config); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	s.reader, err = track.NewRTPReader(config.Codec.MimeType, rand.Uint32(), config.MTU)
+	if err != nil {
+		s.closeConns()
+		return nil, err
+	}
+
+	s.wg.Add(1)
+	go s.send()
+	if s.sap != nil {
+		s.wg.Add(1)
+		go s.announce(origin)
+	}
+	return s, nil
+}
+
+// dial connects to addr, and sets multicast packet TTL and interface.
+func dial(addr *net.UDPAddr, config Config) (*net.UDPConn, error) {
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %s", addr, err)
+	}
+
+	if addr.IP.To4() != nil {
+		p := ipv4.NewPacketConn(conn)
+		err = p.SetMulticastTTL(config.TTL)
+		if err == nil && config.Interface != nil {
+			err = p.SetMulticastInterface(config.Interface)
+		}
+	} else {
+		p := ipv6.NewPacketConn(conn)
+		err = p.SetMulticastHopLimit(config.TTL)
+		if err == nil && config.Interface != nil {
+			err = p.SetMulticastInterface(config.Interface)
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to set the multicast options: %s", err)
+	}
+	return conn, nil
+}
+
+func (s *Sender) send() {
+	defer s.wg.Done()
+	buff := make([]byte, s.config.MTU)
+	for {
+		pkts, release, err := s.reader.Read()
+		if err != nil {
+			return
+		}
+		for _, pkt := range pkts {
+			n, err := pkt.MarshalTo(buff)
+			if err != nil {
+				continue
+			}
+			// UDP errors are transient, e.g. no route to the group while the network is changing
+			s.conn.Write(buff[:n])
+		}
+		release()
+	}
+}
+
+func (s *Sender) announce(origin net.IP) {
+	defer s.wg.Done()
+	announcement := sapPacket(origin, s.sdp, false)
+	ticker := time.NewTicker(s.config.AnnounceInterval)
+	defer ticker.Stop()
+	for {
+		s.sap.Write(announcement)
+		select {
+		case <-s.closing:
+			// Let receivers remove the session at once instead of waiting for the timeout
+			s.sap.Write(sapPacket(origin, s.sdp, true))
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SDP returns the announced session description, e.g. to save as a .sdp file for receivers
+// that don't listen to SAP.
+func (s *Sender) SDP() string {
+	return s.sdp
+}
+
+// Close stops sending the track, and announces the session's deletion.
+func (s *Sender) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return errClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.closing)
+	err := s.reader.Close()
+	s.wg.Wait()
+	s.closeConns()
+	return err
+}
+
+func (s *Sender) closeConns() {
+	s.conn.Close()
+	if s.sap != nil {
+		s.sap.Close()
+	}
+}
+
+// sessionDescription returns the session's SDP.
+// Reference: https://tools.ietf.org/html/rfc4566
+func sessionDescription(config Config, group *net.UDPAddr, origin net.IP, sessionID uint32) string {
+	network, connection := "IP4", fmt.Sprintf("%s/%d", group.IP, config.TTL)
+	if group.IP.To4() == nil {
+		network, connection = "IP6", group.IP.String()
+	}
+	originNetwork := "IP4"
+	if origin.To4() == nil {
+		originNetwork = "IP6"
+	}
+
+	c := config.Codec
+	media, name := splitMimeType(c.MimeType)
+	encoding := fmt.Sprintf("%s/%d", name, c.ClockRate)
+	if c.Channels > 0 {
+		encoding += fmt.Sprintf("/%d", c.Channels)
+	}
+
+	lines := []string{
+		"v=0",
+		fmt.Sprintf("o=- %d 1 IN %s %s", sessionID, originNetwork, origin),
+		"s=" + config.SessionName,
+		fmt.Sprintf("c=IN %s %s", network, connection),
+		"t=0 0",
+		fmt.Sprintf("m=%s %d RTP/AVP %d", media, group.Port, c.PayloadType),
+		fmt.Sprintf("a=rtpmap:%d %s", c.PayloadType, encoding),
+	}
+	if c.SDPFmtpLine != "" {
+		lines = append(lines, fmt.Sprintf("a=fmtp:%d %s", c.PayloadType, c.SDPFmtpLine))
+	}
+	lines = append(lines, "a=sendonly")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// splitMimeType splits "video/H264" into "video" and "H264".
+func splitMimeType(mimeType string) (string, string) {
+	i := strings.Index(mimeType, "/")
+	if i < 0 {
+		return "video", mimeType
+	}
+	return strings.ToLower(mimeType[:i]), mimeType[i+1:]
+}
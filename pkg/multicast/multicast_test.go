@@ -0,0 +1,177 @@
+package multicast
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+type mockReader struct {
+	pkts   chan *rtp.Packet
+	closed chan struct{}
+}
+
+func (r *mockReader) Read() ([]*rtp.Packet, func(), error) {
+	select {
+	case pkt := <-r.pkts:
+		return []*rtp.Packet{pkt}, func() {}, nil
+	case <-r.closed:
+		return nil, func() {}, io.EOF
+	}
+}
+
+func (r *mockReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+type mockTrack struct {
+	reader   *mockReader
+	codecReq string
+}
+
+func (track *mockTrack) ID() string                { return "mock" }
+func (track *mockTrack) StreamID() string          { return "mock" }
+func (track *mockTrack) Close() error              { return nil }
+func (track *mockTrack) Kind() webrtc.RTPCodecType { return webrtc.RTPCodecTypeVideo }
+func (track *mockTrack) OnEnded(func(error))       {}
+
+func (track *mockTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	return webrtc.RTPCodecParameters{}, errors.New("not supported")
+}
+
+func (track *mockTrack) Unbind(ctx webrtc.TrackLocalContext) error {
+	return nil
+}
+
+func (track *mockTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (mediadevices.RTPReadCloser, error) {
+	track.codecReq = codecName
+	return track.reader, nil
+}
+
+func (track *mockTrack) NewEncodedReader(string) (mediadevices.EncodedReadCloser, error) {
+	return nil, nil
+}
+
+func (track *mockTrack) NewEncodedIOReader(string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (track *mockTrack) Stats() mediadevices.TrackStats {
+	return mediadevices.TrackStats{}
+}
+
+func listen(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestSend(t *testing.T) {
+	rtpConn := listen(t)
+	defer rtpConn.Close()
+	sapConn := listen(t)
+	defer sapConn.Close()
+
+	track := &mockTrack{reader: &mockReader{pkts: make(chan *rtp.Packet, 1), closed: make(chan struct{})}}
+	s, err := Send(Config{
+		Address:     rtpConn.LocalAddr().String(),
+		Codec:       codec.NewRTPH264Codec(90000),
+		SessionName: "camera",
+		SAPAddress:  sapConn.LocalAddr().String(),
+	}, track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if track.codecReq != "video/H264" {
+		t.Fatalf("expected the reader of video/H264, but got %s", track.codecReq)
+	}
+
+	sdp := s.SDP()
+	for _, line := range []string{
+		"s=camera\r\n",
+		"c=IN IP4 127.0.0.1/16\r\n",
+		"m=video " + strings.Split(rtpConn.LocalAddr().String(), ":")[1] + " RTP/AVP 125\r\n",
+		"a=rtpmap:125 H264/90000\r\n",
+		"a=fmtp:125 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f\r\n",
+	} {
+		if !strings.Contains(sdp, line) {
+			t.Fatalf("expected %q in the SDP, but got %q", line, sdp)
+		}
+	}
+
+	buff := make([]byte, 1500)
+	n, err := sapConn.Read(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	announcement := sapPacket(net.IPv4(127, 0, 0, 1), sdp, false)
+	if !bytes.Equal(buff[:n], announcement) {
+		t.Fatalf("expected the announcement %v, but got %v", announcement, buff[:n])
+	}
+	if !bytes.HasSuffix(buff[:n], []byte("application/sdp\x00"+sdp)) {
+		t.Fatal("expected the SDP in the announcement")
+	}
+
+	track.reader.pkts <- &rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: 125, SequenceNumber: 7}, Payload: []byte{1, 2, 3}}
+	n, err = rtpConn.Read(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pkt rtp.Packet
+	if err := pkt.Unmarshal(buff[:n]); err != nil {
+		t.Fatal(err)
+	}
+	if pkt.SequenceNumber != 7 || !bytes.Equal(pkt.Payload, []byte{1, 2, 3}) {
+		t.Fatalf("expected the packet to be sent, but got %v", pkt)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	n, err = sapConn.Read(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buff[0] != 0x24 {
+		t.Fatalf("expected the deletion, but got the header %x", buff[0])
+	}
+	if err := s.Close(); err != errClosed {
+		t.Fatalf("expected %v, but got %v", errClosed, err)
+	}
+}
+
+func TestResolveSAPAddress(t *testing.T) {
+	testCases := map[string]struct {
+		group    string
+		expected string
+	}{
+		"IPv4":            {group: "239.255.0.1", expected: "224.2.127.254:9875"},
+		"IPv6SiteLocal":   {group: "ff05::1", expected: "[ff05::2:7ffe]:9875"},
+		"IPv6GlobalScope": {group: "ff3e::1234", expected: "[ff0e::2:7ffe]:9875"},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			addr, err := resolveSAPAddress("", net.ParseIP(tc.group))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if addr.String() != tc.expected {
+				t.Fatalf("expected %s, but got %s", tc.expected, addr)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package multicast
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"net"
+)
+
+const (
+	sapPort        = 9875
+	sapVersion     = 1
+	sapPayloadType = "application/sdp"
+	// sapAddressIPv4 is the IPv4 global scope SAP address.
+	sapAddressIPv4 = "224.2.127.254"
+)
+
+// resolveSAPAddress returns address, or the SAP address for group's scope if address is empty.
+// Reference: https://tools.ietf.org/html/rfc2974#section-3
+func resolveSAPAddress(address string, group net.IP) (*net.UDPAddr, error) {
+	if address == "" {
+		if group.To4() != nil {
+			return &net.UDPAddr{IP: net.ParseIP(sapAddressIPv4), Port: sapPort}, nil
+		}
+		// FF0X:0:0:0:0:0:2:7FFE, where X is group's scope
+		ip := net.ParseIP("ff00::2:7ffe")
+		ip[1] = group[1] & 0x0f
+		return &net.UDPAddr{IP: ip, Port: sapPort}, nil
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %s", address, err)
+	}
+	return addr, nil
+}
+
+// sapPacket returns an announcement of sdp, or its deletion.
+func sapPacket(origin net.IP, sdp string, deletion bool) []byte {
+	header := byte(sapVersion << 5)
+	source := origin.To4()
+	if source == nil {
+		// A: originating source is IPv6
+		header |= 1 << 4
+		source = origin.To16()
+	}
+	if deletion {
+		// T: message type is deletion
+		header |= 1 << 2
+	}
+
+	// Message identifier hash changes whenever the SDP changes
+	h := fnv.New32a()
+	h.Write([]byte(sdp))
+	hash := h.Sum32()
+
+	pkt := make([]byte, 4, 4+len(source)+len(sapPayloadType)+1+len(sdp))
+	pkt[0] = header
+	// pkt[1] is authentication data length, which isn't used
+	binary.BigEndian.PutUint16(pkt[2:], uint16(hash^hash>>16))
+	pkt = append(pkt, source...)
+	pkt = append(pkt, sapPayloadType...)
+	pkt = append(pkt, 0)
+	pkt = append(pkt, sdp...)
+	return pkt
+}
@@ -0,0 +1,184 @@
+package onvif
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	discoveryAddress = "239.255.255.250"
+	discoveryPort    = 3702
+
+	actionProbe        = nsDiscovery + "/Probe"
+	actionProbeMatches = nsDiscovery + "/ProbeMatches"
+	actionHello        = nsDiscovery + "/Hello"
+	actionBye          = nsDiscovery + "/Bye"
+	addressAnonymous   = nsAddress + "/role/anonymous"
+	addressDiscovery   = "urn:schemas-xmlsoap-org:ws:2005:04:discovery"
+
+	deviceTypes = "dn:NetworkVideoTransmitter tds:Device"
+	maxDatagram = 1 << 16
+)
+
+var errDiscoveryClosed = errors.New("onvif: discovery has been closed")
+
+// Discovery answers WS-Discovery probes from local network clients, so NVR software finds
+// the device without its address.
+// Reference: https://www.onvif.org/specs/core/ONVIF-Core-Specification.pdf
+type Discovery struct {
+	device   Device
+	httpPort int
+	endpoint string
+	conn     *net.UDPConn
+	group    *net.UDPAddr
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// NewDiscovery starts answering probes for device, whose Handler is served at httpPort. Answers give
+// device service addresses on the interface each probe came from. ifi is the
+// interface to join the multicast group on, or the default interface if it's nil.
+func NewDiscovery(device Device, httpPort int, ifi *net.Interface) (*Discovery, error) {
+	group := &net.UDPAddr{IP: net.ParseIP(discoveryAddress), Port: discoveryPort}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: discoveryPort})
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on the port of WS-Discovery: %s", err)
+	}
+	if err := ipv4.NewPacketConn(conn).JoinGroup(ifi, group); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to join the group of WS-Discovery: %s", err)
+	}
+
+	d := &Discovery{
+		device:   device,
+		httpPort: httpPort,
+		endpoint: "urn:uuid:" + uuid.New().String(),
+		conn:     conn,
+		group:    group,
+		done:     make(chan struct{}),
+	}
+	d.announce(actionHello, "d:Hello")
+	go d.serve()
+	return d, nil
+}
+
+func (d *Discovery) serve() {
+	defer close(d.done)
+	buff := make([]byte, maxDatagram)
+	for {
+		n, addr, err := d.conn.ReadFromUDP(buff)
+		if err != nil {
+			return
+		}
+		res, ok := d.probeMatches(buff[:n], addr)
+		if !ok {
+			continue
+		}
+		d.conn.WriteToUDP(res, addr)
+	}
+}
+
+// probe is a client WS-Discovery message.
+type probe struct {
+	Header struct {
+		MessageID string `xml:"MessageID"`
+		Action    string `xml:"Action"`
+	} `xml:"Header"`
+	Body struct {
+		Probe struct {
+			Types string `xml:"Types"`
+		} `xml:"Probe"`
+	} `xml:"Body"`
+}
+
+// probeMatches returns the answer to msg from addr, or false if msg isn't a probe for the device.
+func (d *Discovery) probeMatches(msg []byte, addr *net.UDPAddr) ([]byte, bool) {
+	var p probe
+	if err := xml.Unmarshal(msg, &p); err != nil || strings.TrimSpace(p.Header.Action) != actionProbe {
+		return nil, false
+	}
+	if !matchTypes(p.Body.Probe.Types) {
+		return nil, false
+	}
+
+	header := fmt.Sprintf(`<wsa:MessageID>urn:uuid:%s</wsa:MessageID><wsa:RelatesTo>%s</wsa:RelatesTo>`+
+		`<wsa:To>%s</wsa:To><wsa:Action>%s</wsa:Action>`,
+		uuid.New(), escape(strings.TrimSpace(p.Header.MessageID)), addressAnonymous, actionProbeMatches)
+	body := fmt.Sprintf(`<d:ProbeMatches><d:ProbeMatch>%s</d:ProbeMatch></d:ProbeMatches>`,
+		d.endpointXML(d.xaddr(addr)))
+	return soapMessage(header, body), true
+}
+
+// matchTypes reports whether the device is one of types. Probes without types match any device.
+func matchTypes(types string) bool {
+	fields := strings.Fields(types)
+	if len(fields) == 0 {
+		return true
+	}
+	for _, t := range fields {
+		// Clients declare prefixes, so only local names are compared
+		name := t[strings.LastIndex(t, ":")+1:]
+		if name == "NetworkVideoTransmitter" || name == "Device" {
+			return true
+		}
+	}
+	return false
+}
+
+// xaddr returns the device service URL at the address of the interface that routes to addr.
+func (d *Discovery) xaddr(addr *net.UDPAddr) string {
+	host := "0.0.0.0"
+	if conn, err := net.DialUDP("udp4", nil, addr); err == nil {
+		host = conn.LocalAddr().(*net.UDPAddr).IP.String()
+		conn.Close()
+	}
+	return serviceURL(net.JoinHostPort(host, strconv.Itoa(d.httpPort)), DeviceServicePath)
+}
+
+func (d *Discovery) endpointXML(xaddr string) string {
+	var scopeItems []string
+	for _, scope := range scopes(d.device) {
+		scopeItems = append(scopeItems, escape(scope))
+	}
+	s := fmt.Sprintf(`<wsa:EndpointReference><wsa:Address>%s</wsa:Address></wsa:EndpointReference>`+
+		`<d:Types>%s</d:Types><d:Scopes>%s</d:Scopes>`, d.endpoint, deviceTypes, strings.Join(scopeItems, " "))
+	if xaddr != "" {
+		s += fmt.Sprintf(`<d:XAddrs>%s</d:XAddrs>`, escape(xaddr))
+	}
+	// Metadata, e.g. scopes, doesn't change while the device is on the network
+	return s + `<d:MetadataVersion>1</d:MetadataVersion>`
+}
+
+// announce sends Hello or Bye to the multicast group.
+func (d *Discovery) announce(action, element string) {
+	header := fmt.Sprintf(`<wsa:MessageID>urn:uuid:%s</wsa:MessageID><wsa:To>%s</wsa:To><wsa:Action>%s</wsa:Action>`,
+		uuid.New(), addressDiscovery, action)
+	body := fmt.Sprintf(`<%s>%s</%s>`, element, d.endpointXML(""), element)
+	d.conn.WriteToUDP(soapMessage(header, body), d.group)
+}
+
+// Close announces that the device leaves the network, and stops answering probes.
+func (d *Discovery) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return errDiscoveryClosed
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	d.announce(actionBye, "d:Bye")
+	err := d.conn.Close()
+	<-d.done
+	return err
+}
@@ -0,0 +1,252 @@
+// Package onvif implements the minimal ONVIF Profile S subset NVR software needs to find and record
+// a camera: device WS-Discovery, device information, and media profiles whose stream URIs
+// point to an RTSP server, e.g. pkg/rtsp. There's no authentication, so it's only for trusted networks.
+// Reference: https://www.onvif.org/profiles/profile-s/
+package onvif
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DeviceServicePath is the device service path, which Discovery announces.
+	DeviceServicePath = "/onvif/device_service"
+	// MediaServicePath is the media service path.
+	MediaServicePath = "/onvif/media_service"
+
+	defaultRTSPPort = 8554
+	maxRequestSize  = 1 << 16
+)
+
+// Device describes the camera to clients.
+type Device struct {
+	Manufacturer    string
+	Model           string
+	FirmwareVersion string
+	SerialNumber    string
+	HardwareID      string
+	// Name is the device name in scopes, which NVR software shows in camera lists.
+	Name string
+	// RTSPPort is the profiles' RTSP server port. The default is 8554.
+	RTSPPort int
+	Profiles []Profile
+}
+
+// Profile is a device media profile, which is an RTSP server stream.
+type Profile struct {
+	// Token identifies the profile, e.g. "main".
+	Token string
+	Name  string
+	// Path is the stream path in the RTSP server, e.g. "camera".
+	Path string
+	// Encoding is the ONVIF video encoding, "H264", "JPEG" or "MPEG4".
+	Encoding  string
+	Width     int
+	Height    int
+	FrameRate int
+	// BitRate is video bit rate in kbps.
+	BitRate int
+}
+
+// Handler serves Device's device and media services. The zero value isn't usable, use NewHandler.
+type Handler struct {
+	device Device
+}
+
+// NewHandler creates a Handler for device. It serves both DeviceServicePath and MediaServicePath, since
+// clients send requests to the service address in the capabilities.
+func NewHandler(device Device) *Handler {
+	if device.RTSPPort == 0 {
+		device.RTSPPort = defaultRTSPPort
+	}
+	return &Handler{device: device}
+}
+
+// envelope is a request's SOAP message, whose body is a service action.
+type envelope struct {
+	Body struct {
+		Action struct {
+			XMLName      xml.Name
+			ProfileToken string `xml:"ProfileToken"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var env envelope
+	if err := xml.Unmarshal(b, &env); err != nil {
+		writeFault(w, http.StatusBadRequest, "env:Sender", "ter:WellFormed")
+		return
+	}
+
+	action := env.Body.Action
+	body, ok := h.respond(action.XMLName.Local, action.ProfileToken, r.Host)
+	if !ok {
+		writeFault(w, http.StatusInternalServerError, "env:Receiver", "ter:ActionNotSupported")
+		return
+	}
+	if body == "" {
+		writeFault(w, http.StatusBadRequest, "env:Sender", "ter:NoProfile")
+		return
+	}
+	writeEnvelope(w, http.StatusOK, body)
+}
+
+// respond returns the response body for action, or false if the action isn't supported. The body is empty
+// if the profile isn't found.
+func (h *Handler) respond(action, profileToken, host string) (string, bool) {
+	switch action {
+	case "GetDeviceInformation":
+		d := h.device
+		return fmt.Sprintf(`<tds:GetDeviceInformationResponse><tds:Manufacturer>%s</tds:Manufacturer>`+
+			`<tds:Model>%s</tds:Model><tds:FirmwareVersion>%s</tds:FirmwareVersion>`+
+			`<tds:SerialNumber>%s</tds:SerialNumber><tds:HardwareId>%s</tds:HardwareId>`+
+			`</tds:GetDeviceInformationResponse>`,
+			escape(d.Manufacturer), escape(d.Model), escape(d.FirmwareVersion), escape(d.SerialNumber),
+			escape(d.HardwareID)), true
+	case "GetSystemDateAndTime":
+		now := time.Now().UTC()
+		return fmt.Sprintf(`<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime>`+
+			`<tt:DateTimeType>NTP</tt:DateTimeType><tt:DaylightSavings>false</tt:DaylightSavings>`+
+			`<tt:UTCDateTime><tt:Time><tt:Hour>%d</tt:Hour><tt:Minute>%d</tt:Minute><tt:Second>%d</tt:Second></tt:Time>`+
+			`<tt:Date><tt:Year>%d</tt:Year><tt:Month>%d</tt:Month><tt:Day>%d</tt:Day></tt:Date></tt:UTCDateTime>`+
+			`</tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse>`,
+			now.Hour(), now.Minute(), now.Second(), now.Year(), now.Month(), now.Day()), true
+	case "GetCapabilities":
+		return fmt.Sprintf(`<tds:GetCapabilitiesResponse><tds:Capabilities>`+
+			`<tt:Device><tt:XAddr>%s</tt:XAddr></tt:Device>`+
+			`<tt:Media><tt:XAddr>%s</tt:XAddr><tt:StreamingCapabilities><tt:RTPMulticast>false</tt:RTPMulticast>`+
+			`<tt:RTP_TCP>true</tt:RTP_TCP><tt:RTP_RTSP_TCP>true</tt:RTP_RTSP_TCP></tt:StreamingCapabilities></tt:Media>`+
+			`</tds:Capabilities></tds:GetCapabilitiesResponse>`,
+			serviceURL(host, DeviceServicePath), serviceURL(host, MediaServicePath)), true
+	case "GetServices":
+		return fmt.Sprintf(`<tds:GetServicesResponse>`+
+			`<tds:Service><tds:Namespace>%s</tds:Namespace><tds:XAddr>%s</tds:XAddr>`+
+			`<tds:Version><tt:Major>2</tt:Major><tt:Minor>0</tt:Minor></tds:Version></tds:Service>`+
+			`<tds:Service><tds:Namespace>%s</tds:Namespace><tds:XAddr>%s</tds:XAddr>`+
+			`<tds:Version><tt:Major>2</tt:Major><tt:Minor>0</tt:Minor></tds:Version></tds:Service>`+
+			`</tds:GetServicesResponse>`,
+			nsDevice, serviceURL(host, DeviceServicePath), nsMedia, serviceURL(host, MediaServicePath)), true
+	case "GetScopes":
+		var b strings.Builder
+		b.WriteString(`<tds:GetScopesResponse>`)
+		for _, scope := range scopes(h.device) {
+			fmt.Fprintf(&b, `<tds:Scopes><tt:ScopeDef>Fixed</tt:ScopeDef><tt:ScopeItem>%s</tt:ScopeItem></tds:Scopes>`,
+				escape(scope))
+		}
+		b.WriteString(`</tds:GetScopesResponse>`)
+		return b.String(), true
+	case "GetVideoSources":
+		var b strings.Builder
+		b.WriteString(`<trt:GetVideoSourcesResponse>`)
+		for _, p := range h.device.Profiles {
+			fmt.Fprintf(&b, `<trt:VideoSources token="%s"><tt:Framerate>%d</tt:Framerate>`+
+				`<tt:Resolution><tt:Width>%d</tt:Width><tt:Height>%d</tt:Height></tt:Resolution></trt:VideoSources>`,
+				escape(p.Token), p.FrameRate, p.Width, p.Height)
+		}
+		b.WriteString(`</trt:GetVideoSourcesResponse>`)
+		return b.String(), true
+	case "GetProfiles":
+		var b strings.Builder
+		b.WriteString(`<trt:GetProfilesResponse>`)
+		for _, p := range h.device.Profiles {
+			b.WriteString(profileXML("trt:Profiles", p))
+		}
+		b.WriteString(`</trt:GetProfilesResponse>`)
+		return b.String(), true
+	case "GetProfile":
+		p, ok := h.profile(profileToken)
+		if !ok {
+			return "", true
+		}
+		return `<trt:GetProfileResponse>` + profileXML("trt:Profile", p) + `</trt:GetProfileResponse>`, true
+	case "GetStreamUri":
+		p, ok := h.profile(profileToken)
+		if !ok {
+			return "", true
+		}
+		return fmt.Sprintf(`<trt:GetStreamUriResponse><trt:MediaUri><tt:Uri>%s</tt:Uri>`+
+			`<tt:InvalidAfterConnect>false</tt:InvalidAfterConnect><tt:InvalidAfterReboot>false</tt:InvalidAfterReboot>`+
+			`<tt:Timeout>PT0S</tt:Timeout></trt:MediaUri></trt:GetStreamUriResponse>`,
+			escape(h.streamURI(host, p))), true
+	default:
+		return "", false
+	}
+}
+
+func (h *Handler) profile(token string) (Profile, bool) {
+	for _, p := range h.device.Profiles {
+		if p.Token == token {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// streamURI returns p's RTSP URL at the host the client connected to, since the device may have
+// several addresses.
+func (h *Handler) streamURI(host string, p Profile) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	return fmt.Sprintf("rtsp://%s/%s", net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(h.device.RTSPPort)),
+		strings.Trim(p.Path, "/"))
+}
+
+func profileXML(element string, p Profile) string {
+	token := escape(p.Token)
+	return fmt.Sprintf(`<%s token="%s" fixed="true"><tt:Name>%s</tt:Name>`+
+		`<tt:VideoSourceConfiguration token="%s"><tt:Name>%s</tt:Name><tt:UseCount>1</tt:UseCount>`+
+		`<tt:SourceToken>%s</tt:SourceToken><tt:Bounds x="0" y="0" width="%d" height="%d"></tt:Bounds>`+
+		`</tt:VideoSourceConfiguration>`+
+		`<tt:VideoEncoderConfiguration token="%s"><tt:Name>%s</tt:Name><tt:UseCount>1</tt:UseCount>`+
+		`<tt:Encoding>%s</tt:Encoding><tt:Resolution><tt:Width>%d</tt:Width><tt:Height>%d</tt:Height></tt:Resolution>`+
+		`<tt:Quality>5</tt:Quality><tt:RateControl><tt:FrameRateLimit>%d</tt:FrameRateLimit>`+
+		`<tt:EncodingInterval>1</tt:EncodingInterval><tt:BitrateLimit>%d</tt:BitrateLimit></tt:RateControl>`+
+		`<tt:SessionTimeout>PT60S</tt:SessionTimeout></tt:VideoEncoderConfiguration></%s>`,
+		element, token, escape(p.Name),
+		token, escape(p.Name), token, p.Width, p.Height,
+		token, escape(p.Name), escape(p.Encoding), p.Width, p.Height, p.FrameRate, p.BitRate,
+		element)
+}
+
+// scopes returns device scopes, which Discovery announces as well.
+func scopes(d Device) []string {
+	s := []string{
+		"onvif://www.onvif.org/type/video_encoder",
+		"onvif://www.onvif.org/Profile/Streaming",
+	}
+	if d.Name != "" {
+		s = append(s, "onvif://www.onvif.org/name/"+scopeValue(d.Name))
+	}
+	if d.HardwareID != "" {
+		s = append(s, "onvif://www.onvif.org/hardware/"+scopeValue(d.HardwareID))
+	}
+	return s
+}
+
+// scopeValue encodes spaces, which separate scopes.
+func scopeValue(s string) string {
+	return strings.Replace(s, " ", "%20", -1)
+}
+
+func serviceURL(host, path string) string {
+	return "http://" + host + path
+}
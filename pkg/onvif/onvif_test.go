@@ -0,0 +1,117 @@
+package onvif
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testDevice = Device{
+	Manufacturer: "Pion",
+	Model:        "mediadevices",
+	Name:         "Front Door",
+	RTSPPort:     8554,
+	Profiles: []Profile{
+		{Token: "main", Name: "Main", Path: "camera", Encoding: "H264", Width: 1280, Height: 720, FrameRate: 30, BitRate: 2000},
+	},
+}
+
+func soapRequest(action string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:trt="http://www.onvif.org/ver10/media/wsdl">` +
+		`<s:Body>` + action + `</s:Body></s:Envelope>`
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(testDevice)
+	testCases := map[string]struct {
+		action   string
+		status   int
+		expected []string
+	}{
+		"GetDeviceInformation": {
+			action:   `<GetDeviceInformation xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
+			status:   http.StatusOK,
+			expected: []string{"<tds:Manufacturer>Pion</tds:Manufacturer>"},
+		},
+		"GetCapabilities": {
+			action:   `<GetCapabilities xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
+			status:   http.StatusOK,
+			expected: []string{"<tt:XAddr>http://192.168.1.10:8080/onvif/media_service</tt:XAddr>"},
+		},
+		"GetScopes": {
+			action:   `<GetScopes xmlns="http://www.onvif.org/ver10/device/wsdl"/>`,
+			status:   http.StatusOK,
+			expected: []string{"onvif://www.onvif.org/name/Front%20Door"},
+		},
+		"GetProfiles": {
+			action:   `<trt:GetProfiles/>`,
+			status:   http.StatusOK,
+			expected: []string{`<trt:Profiles token="main"`, "<tt:Encoding>H264</tt:Encoding>", "<tt:Width>1280</tt:Width>"},
+		},
+		"GetStreamUri": {
+			action:   `<trt:GetStreamUri><trt:ProfileToken>main</trt:ProfileToken></trt:GetStreamUri>`,
+			status:   http.StatusOK,
+			expected: []string{"<tt:Uri>rtsp://192.168.1.10:8554/camera</tt:Uri>"},
+		},
+		"NoProfile": {
+			action:   `<trt:GetStreamUri><trt:ProfileToken>sub</trt:ProfileToken></trt:GetStreamUri>`,
+			status:   http.StatusBadRequest,
+			expected: []string{"ter:NoProfile"},
+		},
+		"ActionNotSupported": {
+			action:   `<trt:GetSnapshotUri><trt:ProfileToken>main</trt:ProfileToken></trt:GetSnapshotUri>`,
+			status:   http.StatusInternalServerError,
+			expected: []string{"ter:ActionNotSupported"},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://192.168.1.10:8080"+MediaServicePath, strings.NewReader(soapRequest(tc.action)))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tc.status {
+				t.Fatalf("expected %d, but got %d", tc.status, w.Code)
+			}
+			body, _ := ioutil.ReadAll(w.Body)
+			for _, s := range tc.expected {
+				if !strings.Contains(string(body), s) {
+					t.Fatalf("expected %q in the response, but got %s", s, body)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeMatches(t *testing.T) {
+	d := &Discovery{device: testDevice, httpPort: 8080, endpoint: "urn:uuid:device"}
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 3702}
+
+	msg := `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://schemas.xmlsoap.org/ws/2004/08/addressing" ` +
+		`xmlns:d="http://schemas.xmlsoap.org/ws/2005/04/discovery" xmlns:dn="http://www.onvif.org/ver10/network/wsdl">` +
+		`<s:Header><a:MessageID>uuid:probe</a:MessageID><a:Action>http://schemas.xmlsoap.org/ws/2005/04/discovery/Probe</a:Action></s:Header>` +
+		`<s:Body><d:Probe><d:Types>%s</d:Types></d:Probe></s:Body></s:Envelope>`
+
+	res, ok := d.probeMatches([]byte(strings.Replace(msg, "%s", "dn:NetworkVideoTransmitter", 1)), addr)
+	if !ok {
+		t.Fatal("expected the probe to match")
+	}
+	for _, s := range []string{
+		"<wsa:RelatesTo>uuid:probe</wsa:RelatesTo>",
+		"<wsa:Address>urn:uuid:device</wsa:Address>",
+		"<d:XAddrs>http://127.0.0.1:8080/onvif/device_service</d:XAddrs>",
+	} {
+		if !strings.Contains(string(res), s) {
+			t.Fatalf("expected %q in the answer, but got %s", s, res)
+		}
+	}
+
+	if _, ok := d.probeMatches([]byte(strings.Replace(msg, "%s", "dp0:NetworkVideoDisplay", 1)), addr); ok {
+		t.Fatal("expected the probe of another type not to match")
+	}
+}
@@ -0,0 +1,53 @@
+package onvif
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
+
+const (
+	nsEnvelope  = "http://www.w3.org/2003/05/soap-envelope"
+	nsDevice    = "http://www.onvif.org/ver10/device/wsdl"
+	nsMedia     = "http://www.onvif.org/ver10/media/wsdl"
+	nsSchema    = "http://www.onvif.org/ver10/schema"
+	nsError     = "http://www.onvif.org/ver10/error"
+	nsAddress   = "http://schemas.xmlsoap.org/ws/2004/08/addressing"
+	nsDiscovery = "http://schemas.xmlsoap.org/ws/2005/04/discovery"
+	nsNetwork   = "http://www.onvif.org/ver10/network/wsdl"
+)
+
+// envelopeHeader declares all prefixes the responses use.
+var envelopeHeader = fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>`+
+	`<env:Envelope xmlns:env="%s" xmlns:tds="%s" xmlns:trt="%s" xmlns:tt="%s" xmlns:ter="%s" `+
+	`xmlns:wsa="%s" xmlns:d="%s" xmlns:dn="%s">`,
+	nsEnvelope, nsDevice, nsMedia, nsSchema, nsError, nsAddress, nsDiscovery, nsNetwork)
+
+// soapMessage returns a SOAP envelope of header and body.
+func soapMessage(header, body string) []byte {
+	var b bytes.Buffer
+	b.WriteString(envelopeHeader)
+	if header != "" {
+		b.WriteString("<env:Header>" + header + "</env:Header>")
+	}
+	b.WriteString("<env:Body>" + body + "</env:Body></env:Envelope>")
+	return b.Bytes()
+}
+
+func writeEnvelope(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(soapMessage("", body))
+}
+
+func writeFault(w http.ResponseWriter, status int, code, subcode string) {
+	writeEnvelope(w, status, fmt.Sprintf(`<env:Fault><env:Code><env:Value>%s</env:Value>`+
+		`<env:Subcode><env:Value>%s</env:Value></env:Subcode></env:Code></env:Fault>`, code, subcode))
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,21 @@
+package overuse
+
+import (
+	"errors"
+)
+
+var errCPUUsageUnsupported = errors.New("overuse: system cpu usage is not supported on this platform")
+
+// CPUMonitor measures CPU usage.
+type CPUMonitor interface {
+	// Usage returns the CPU usage since the last call, in the range [0, 1].
+	Usage() (float64, error)
+}
+
+// CPUMonitorFunc is a proxy type for CPUMonitor
+type CPUMonitorFunc func() (float64, error)
+
+// Usage implements CPUMonitor
+func (f CPUMonitorFunc) Usage() (float64, error) {
+	return f()
+}
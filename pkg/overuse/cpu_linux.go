@@ -0,0 +1,82 @@
+package overuse
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// procStatFields is the number of fields from user to steal
+const procStatFields = 8
+
+var errInvalidProcStat = errors.New("overuse: invalid /proc/stat")
+
+type systemCPUMonitor struct {
+	path  string
+	idle  uint64
+	total uint64
+}
+
+// NewSystemCPUMonitor creates a CPUMonitor that measures the usage of all CPUs in the system. On Linux,
+// usage is read from /proc/stat.
+func NewSystemCPUMonitor() CPUMonitor {
+	return &systemCPUMonitor{path: "/proc/stat"}
+}
+
+func (m *systemCPUMonitor) Usage() (float64, error) {
+	idle, total, err := m.read()
+	if err != nil {
+		return 0, err
+	}
+
+	deltaIdle := idle - m.idle
+	deltaTotal := total - m.total
+	m.idle, m.total = idle, total
+	if deltaTotal == 0 {
+		return 0, nil
+	}
+
+	return 1 - float64(deltaIdle)/float64(deltaTotal), nil
+}
+
+// read parses the aggregated cpu line, "cpu user nice system idle iowait irq softirq steal ..."
+func (m *systemCPUMonitor) read() (idle, total uint64, err error) {
+	f, err := os.Open(m.path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return 0, 0, errInvalidProcStat
+	}
+
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, errInvalidProcStat
+	}
+
+	// guest and guest_nice are already included in user and nice
+	values := fields[1:]
+	if len(values) > procStatFields {
+		values = values[:procStatFields]
+	}
+
+	for i, field := range values {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, errInvalidProcStat
+		}
+
+		total += v
+		// idle and iowait
+		if i == 3 || i == 4 {
+			idle += v
+		}
+	}
+
+	return idle, total, nil
+}
@@ -0,0 +1,45 @@
+package overuse
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSystemCPUMonitor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "stat")
+	write := func(stat string) {
+		if err := ioutil.WriteFile(path, []byte(stat), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &systemCPUMonitor{path: path}
+	write("cpu  100 0 100 700 100 0 0 0 50 0\ncpu0 100 0 100 700 100 0 0 0 50 0\n")
+	if _, err := m.Usage(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 300 busy ticks and 100 idle ticks since the last measurement
+	write("cpu  300 0 200 750 150 0 0 0 80 0\ncpu0 300 0 200 750 150 0 0 0 80 0\n")
+	usage, err := m.Usage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(usage-0.75) > 1e-9 {
+		t.Fatalf("expected usage to be 0.75, but got %f", usage)
+	}
+
+	write("intr 0\n")
+	if _, err := m.Usage(); err != errInvalidProcStat {
+		t.Fatalf("expected %v, but got %v", errInvalidProcStat, err)
+	}
+}
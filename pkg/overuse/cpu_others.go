@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package overuse
+
+type systemCPUMonitor struct{}
+
+// NewSystemCPUMonitor creates a CPUMonitor that measures the usage of all CPUs in the system. It's only
+// supported on Linux; on other platforms the monitor returns an error.
+func NewSystemCPUMonitor() CPUMonitor {
+	return &systemCPUMonitor{}
+}
+
+func (m *systemCPUMonitor) Usage() (float64, error) {
+	return 0, errCPUUsageUnsupported
+}
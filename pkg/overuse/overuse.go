@@ -0,0 +1,209 @@
+// Package overuse detects when the capture and encoding pipeline can't keep up with the source, similar to
+// libwebrtc's overuse detector. The detector measures each frame's capture-to-encode latency and the system
+// CPU usage, and emits signals to adapt the stream's quality.
+package overuse
+
+import (
+	"time"
+)
+
+// Signal is an adaptation request emitted by Detector.
+type Signal int
+
+// Signal values.
+const (
+	// SignalNone means the current quality should be kept.
+	SignalNone Signal = iota
+	// SignalAdaptDown means the pipeline is overused, and the quality should be lowered.
+	SignalAdaptDown
+	// SignalAdaptUp means the pipeline has enough headroom to raise the quality.
+	SignalAdaptUp
+)
+
+func (s Signal) String() string {
+	switch s {
+	case SignalNone:
+		return "none"
+	case SignalAdaptDown:
+		return "adapt-down"
+	case SignalAdaptUp:
+		return "adapt-up"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	defaultOveruseThreshold     = 0.85
+	defaultUnderuseThreshold    = 0.4
+	defaultCPUOveruseThreshold  = 0.9
+	defaultCPUUnderuseThreshold = 0.6
+	defaultOveruseFrames        = 10
+	defaultUnderuseFrames       = 60
+	defaultCPUSampleInterval    = time.Second
+	usageSmoothing              = 0.1
+)
+
+// Detector measures a capture and encoding pipeline's usage. Detector isn't safe for concurrent use.
+type Detector struct {
+	budget               time.Duration
+	overuseThreshold     float64
+	underuseThreshold    float64
+	cpuOveruseThreshold  float64
+	cpuUnderuseThreshold float64
+	overuseFrames        int
+	underuseFrames       int
+	cpu                  CPUMonitor
+	cpuSampleInterval    time.Duration
+	now                  func() time.Time
+
+	capturedAt   time.Time
+	usage        float64
+	samples      int
+	cpuUsage     float64
+	cpuAvailable bool
+	cpuSampledAt time.Time
+}
+
+// Option configures Detector.
+type Option func(*Detector)
+
+// WithThresholds sets the thresholds for capture-to-encode latency over the frame budget. The hysteresis
+// between overuse and underuse has to be wider than one adaptation step to prevent oscillation. The defaults
+// are 0.85 and 0.4.
+func WithThresholds(overuse, underuse float64) Option {
+	return func(d *Detector) {
+		d.overuseThreshold = overuse
+		d.underuseThreshold = underuse
+	}
+}
+
+// WithCPUThresholds sets the system CPU usage thresholds, in the range [0, 1]. The defaults are 0.9 and 0.6.
+func WithCPUThresholds(overuse, underuse float64) Option {
+	return func(d *Detector) {
+		d.cpuOveruseThreshold = overuse
+		d.cpuUnderuseThreshold = underuse
+	}
+}
+
+// WithFrames sets how many frames are measured before emitting a signal. Since raising the quality is
+// more disruptive than lowering it, underuse should be measured longer. The defaults are 10 and 60.
+func WithFrames(overuse, underuse int) Option {
+	return func(d *Detector) {
+		d.overuseFrames = overuse
+		d.underuseFrames = underuse
+	}
+}
+
+// WithCPUMonitor replaces the system CPU monitor. Setting nil disables CPU measurement.
+func WithCPUMonitor(m CPUMonitor) Option {
+	return func(d *Detector) {
+		d.cpu = m
+	}
+}
+
+// WithTimeSource replaces the detector's time source, which defaults to time.Now.
+func WithTimeSource(now func() time.Time) Option {
+	return func(d *Detector) {
+		d.now = now
+	}
+}
+
+// NewDetector creates a Detector that allows budget for capturing and encoding one frame.
+func NewDetector(budget time.Duration, opts ...Option) *Detector {
+	d := &Detector{
+		budget:               budget,
+		overuseThreshold:     defaultOveruseThreshold,
+		underuseThreshold:    defaultUnderuseThreshold,
+		cpuOveruseThreshold:  defaultCPUOveruseThreshold,
+		cpuUnderuseThreshold: defaultCPUUnderuseThreshold,
+		overuseFrames:        defaultOveruseFrames,
+		underuseFrames:       defaultUnderuseFrames,
+		cpu:                  NewSystemCPUMonitor(),
+		cpuSampleInterval:    defaultCPUSampleInterval,
+		now:                  time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// SetBudget changes the per-frame time budget, e.g. after some frames are dropped, and restarts the measurement.
+func (d *Detector) SetBudget(budget time.Duration) {
+	d.budget = budget
+	d.Reset()
+}
+
+// Reset restarts the measurement. It should be called once the pipeline has been adapted.
+func (d *Detector) Reset() {
+	d.samples = 0
+	d.capturedAt = time.Time{}
+}
+
+// Usage returns the smoothed capture-to-encode latency over the frame budget.
+func (d *Detector) Usage() float64 {
+	return d.usage
+}
+
+// FrameCaptured marks that a frame has been captured from the source.
+func (d *Detector) FrameCaptured() {
+	d.capturedAt = d.now()
+}
+
+// FrameEncoded marks that the last captured frame has been encoded, and returns an adaptation signal. After
+// a signal other than SignalNone, the measurement restarts.
+func (d *Detector) FrameEncoded() Signal {
+	if d.capturedAt.IsZero() {
+		return SignalNone
+	}
+
+	now := d.now()
+	usage := float64(now.Sub(d.capturedAt)) / float64(d.budget)
+	d.capturedAt = time.Time{}
+
+	if d.samples == 0 {
+		d.usage = usage
+	} else {
+		d.usage += (usage - d.usage) * usageSmoothing
+	}
+	d.samples++
+
+	d.sampleCPU(now)
+
+	signal := SignalNone
+	switch {
+	case d.samples >= d.overuseFrames && d.overused():
+		signal = SignalAdaptDown
+	case d.samples >= d.underuseFrames && d.underused():
+		signal = SignalAdaptUp
+	}
+
+	if signal != SignalNone {
+		d.Reset()
+	}
+	return signal
+}
+
+func (d *Detector) overused() bool {
+	return d.usage > d.overuseThreshold || (d.cpuAvailable && d.cpuUsage > d.cpuOveruseThreshold)
+}
+
+func (d *Detector) underused() bool {
+	return d.usage < d.underuseThreshold && (!d.cpuAvailable || d.cpuUsage < d.cpuUnderuseThreshold)
+}
+
+func (d *Detector) sampleCPU(now time.Time) {
+	if d.cpu == nil || now.Sub(d.cpuSampledAt) < d.cpuSampleInterval {
+		return
+	}
+	d.cpuSampledAt = now
+
+	usage, err := d.cpu.Usage()
+	d.cpuAvailable = err == nil
+	if err == nil {
+		d.cpuUsage = usage
+	}
+}
@@ -0,0 +1,100 @@
+package overuse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDetector(t *testing.T) {
+	testCases := map[string]struct {
+		encodeTime time.Duration
+		cpu        CPUMonitor
+		expected   Signal
+	}{
+		"Overuse": {
+			encodeTime: 30 * time.Millisecond,
+			expected:   SignalAdaptDown,
+		},
+		"Underuse": {
+			encodeTime: 5 * time.Millisecond,
+			expected:   SignalAdaptUp,
+		},
+		"Normal": {
+			encodeTime: 20 * time.Millisecond,
+			expected:   SignalNone,
+		},
+		"CPUOveruse": {
+			encodeTime: 5 * time.Millisecond,
+			cpu:        CPUMonitorFunc(func() (float64, error) { return 0.95, nil }),
+			expected:   SignalAdaptDown,
+		},
+		"CPUBusy": {
+			encodeTime: 5 * time.Millisecond,
+			cpu:        CPUMonitorFunc(func() (float64, error) { return 0.7, nil }),
+			expected:   SignalNone,
+		},
+		"CPUUnavailable": {
+			encodeTime: 5 * time.Millisecond,
+			cpu:        CPUMonitorFunc(func() (float64, error) { return 0, errors.New("unavailable") }),
+			expected:   SignalAdaptUp,
+		},
+	}
+
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(0, 0)
+			d := NewDetector(33*time.Millisecond,
+				WithTimeSource(func() time.Time { return now }),
+				WithCPUMonitor(testCase.cpu),
+			)
+
+			signal := SignalNone
+			for i := 0; i < defaultUnderuseFrames && signal == SignalNone; i++ {
+				d.FrameCaptured()
+				now = now.Add(testCase.encodeTime)
+				signal = d.FrameEncoded()
+			}
+
+			if signal != testCase.expected {
+				t.Fatalf("expected %s, but got %s", testCase.expected, signal)
+			}
+		})
+	}
+}
+
+func TestDetectorReset(t *testing.T) {
+	now := time.Unix(0, 0)
+	d := NewDetector(10*time.Millisecond,
+		WithTimeSource(func() time.Time { return now }),
+		WithCPUMonitor(nil),
+		WithFrames(2, 2),
+	)
+
+	encode := func() Signal {
+		d.FrameCaptured()
+		now = now.Add(20 * time.Millisecond)
+		return d.FrameEncoded()
+	}
+
+	if signal := encode(); signal != SignalNone {
+		t.Fatalf("expected no signal before enough frames, but got %s", signal)
+	}
+	if signal := encode(); signal != SignalAdaptDown {
+		t.Fatalf("expected %s, but got %s", SignalAdaptDown, signal)
+	}
+
+	// The measurement should restart after a signal
+	d.SetBudget(100 * time.Millisecond)
+	if signal := encode(); signal != SignalNone {
+		t.Fatalf("expected no signal after reset, but got %s", signal)
+	}
+	if signal := encode(); signal != SignalAdaptUp {
+		t.Fatalf("expected %s, but got %s", SignalAdaptUp, signal)
+	}
+
+	if signal := d.FrameEncoded(); signal != SignalNone {
+		t.Fatalf("expected no signal without a captured frame, but got %s", signal)
+	}
+}
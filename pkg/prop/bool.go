@@ -35,3 +35,11 @@ func (b Bool) Compare(o bool) (float64, bool) {
 	dist, _ := BoolExact(b).Compare(o)
 	return dist, true
 }
+
+// Value implements BoolConstraint.
+func (b Bool) Value() bool { return bool(b) }
+
+// String implements Stringify
+func (b Bool) String() string {
+	return fmt.Sprintf("%t (ideal)", b)
+}
@@ -76,3 +76,31 @@ func (f FrameFormatOneOf) String() string {
 
 	return fmt.Sprintf("%s (one of values)", strings.Join(opts, ","))
 }
+
+// FrameFormatPreferred specifies frame formats in order of preference.
+// Any value may be selected, but earlier formats take priority over later ones,
+// and all of them take priority over unlisted formats.
+type FrameFormatPreferred []frame.Format
+
+// Compare implements FrameFormatConstraint.
+func (f FrameFormatPreferred) Compare(a frame.Format) (float64, bool) {
+	for i, ff := range f {
+		if ff == a {
+			return float64(i) / float64(len(f)), true
+		}
+	}
+	return 1.0, true
+}
+
+// Value implements FrameFormatConstraint.
+func (FrameFormatPreferred) Value() (frame.Format, bool) { return "", false }
+
+// String implements Stringify
+func (f FrameFormatPreferred) String() string {
+	var opts []string
+	for _, v := range f {
+		opts = append(opts, fmt.Sprint(v))
+	}
+
+	return fmt.Sprintf("%s (in order of preference)", strings.Join(opts, ","))
+}
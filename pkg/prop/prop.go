@@ -145,6 +145,7 @@ func (p *MediaConstraints) FitnessDistance(o Media) (float64, bool) {
 	cmps.add(p.Width, o.Width)
 	cmps.add(p.Height, o.Height)
 	cmps.add(p.FrameFormat, o.FrameFormat)
+	cmps.add(p.DisplaySurface, o.DisplaySurface)
 	cmps.add(p.SampleRate, o.SampleRate)
 	cmps.add(p.Latency, o.Latency)
 	cmps.add(p.ChannelCount, o.ChannelCount)
@@ -225,9 +226,14 @@ func (c *comparisons) fitnessDistance() (float64, bool) {
 
 // VideoConstraints represents a video's constraints
 type VideoConstraints struct {
-	Width, Height IntConstraint
-	FrameRate     FloatConstraint
-	FrameFormat   FrameFormatConstraint
+	Width, Height  IntConstraint
+	FrameRate      FloatConstraint
+	FrameFormat    FrameFormatConstraint
+	DisplaySurface StringConstraint
+	// ColorMatrix and ColorRange don't select properties, since few drivers report them. They declare the
+	// colorspace of sources that don't report it.
+	ColorMatrix StringConstraint
+	ColorRange  StringConstraint
 }
 
 // Video represents a video's constraints
@@ -235,8 +241,40 @@ type Video struct {
 	Width, Height int
 	FrameRate     float32
 	FrameFormat   frame.Format
+	// DisplaySurface is the captured screen's kind, which is empty for other video devices.
+	DisplaySurface string
+	// ColorMatrix and ColorRange are the YCbCr frame colorspace, which are empty if they're unknown.
+	ColorMatrix string
+	ColorRange  string
 }
 
+// Screen display surfaces, see https://w3c.github.io/mediacapture-screen-share/#displaycapturesurfacetype
+const (
+	// DisplaySurfaceMonitor is a whole display
+	DisplaySurfaceMonitor = "monitor"
+	// DisplaySurfaceWindow is a single window
+	DisplaySurfaceWindow = "window"
+	// DisplaySurfaceApplication is all of an application's windows
+	DisplaySurfaceApplication = "application"
+)
+
+// YCbCr frame color matrices
+const (
+	// ColorMatrixBT601 is ITU-R BT.601, which SD sources and image/color use
+	ColorMatrixBT601 = "bt601"
+	// ColorMatrixBT709 is ITU-R BT.709, which HD sources use
+	ColorMatrixBT709 = "bt709"
+)
+
+// YCbCr frame color ranges
+const (
+	// ColorRangeLimited is 16-235 for luma and 16-240 for chroma, which decoders assume
+	// by default
+	ColorRangeLimited = "limited"
+	// ColorRangeFull is 0-255, which JPEG and image/color use
+	ColorRangeFull = "full"
+)
+
 // AudioConstraints represents an audio's constraints
 type AudioConstraints struct {
 	ChannelCount  IntConstraint
@@ -246,6 +284,11 @@ type AudioConstraints struct {
 	IsBigEndian   BoolConstraint
 	IsFloat       BoolConstraint
 	IsInterleaved BoolConstraint
+	// EchoCancellation, NoiseSuppression and AutoGainControl enable input processing like
+	// browsers. Transforms in pkg/io/audio do it unless the driver processes input itself.
+	EchoCancellation BoolConstraint
+	NoiseSuppression BoolConstraint
+	AutoGainControl  BoolConstraint
 }
 
 // Audio represents an audio's constraints
@@ -257,4 +300,8 @@ type Audio struct {
 	IsBigEndian   bool
 	IsFloat       bool
 	IsInterleaved bool
+	// EchoCancellation, NoiseSuppression and AutoGainControl are true if the driver processes input itself.
+	EchoCancellation bool
+	NoiseSuppression bool
+	AutoGainControl  bool
 }
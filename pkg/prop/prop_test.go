@@ -103,6 +103,33 @@ func TestCompareMatch(t *testing.T) {
 			}},
 			false,
 		},
+		"FrameFormatPreferredUnlisted": {
+			MediaConstraints{VideoConstraints: VideoConstraints{
+				FrameFormat: FrameFormatPreferred{frame.FormatYUYV, frame.FormatMJPEG},
+			}},
+			Media{Video: Video{
+				FrameFormat: frame.FormatI420,
+			}},
+			true,
+		},
+		"DisplaySurfaceExactUnmatch": {
+			MediaConstraints{VideoConstraints: VideoConstraints{
+				DisplaySurface: StringExact(DisplaySurfaceWindow),
+			}},
+			Media{Video: Video{
+				DisplaySurface: DisplaySurfaceMonitor,
+			}},
+			false,
+		},
+		"DisplaySurfaceExactMatch": {
+			MediaConstraints{VideoConstraints: VideoConstraints{
+				DisplaySurface: StringExact(DisplaySurfaceMonitor),
+			}},
+			Media{Video: Video{
+				DisplaySurface: DisplaySurfaceMonitor,
+			}},
+			true,
+		},
 		"DurationExactUnmatch": {
 			MediaConstraints{AudioConstraints: AudioConstraints{
 				Latency: DurationExact(time.Second),
@@ -170,6 +197,26 @@ func TestCompareMatch(t *testing.T) {
 	}
 }
 
+func TestFrameFormatPreferred(t *testing.T) {
+	preferred := FrameFormatPreferred{frame.FormatYUYV, frame.FormatNV12, frame.FormatMJPEG}
+	formats := []frame.Format{frame.FormatYUYV, frame.FormatNV12, frame.FormatMJPEG, frame.FormatI420}
+
+	prev := -1.0
+	for _, format := range formats {
+		d, ok := preferred.Compare(format)
+		if !ok {
+			t.Fatalf("expected %s to be allowed", format)
+		}
+		if d <= prev {
+			t.Fatalf("expected the distance of %s to be larger than %f, but got %f", format, prev, d)
+		}
+		prev = d
+	}
+	if d, _ := preferred.Compare(frame.FormatYUYV); d != 0 {
+		t.Fatalf("expected the distance of the most preferred format to be 0, but got %f", d)
+	}
+}
+
 func TestMergeWithZero(t *testing.T) {
 	a := Media{
 		Video: Video{
@@ -284,6 +331,32 @@ func TestMergeConstraintsWithSameField(t *testing.T) {
 	}
 }
 
+func TestMergeConstraintsColorSpace(t *testing.T) {
+	a := Media{
+		Video: Video{
+			Width: 30,
+		},
+	}
+
+	b := MediaConstraints{
+		VideoConstraints: VideoConstraints{
+			ColorMatrix: StringExact(ColorMatrixBT709),
+			ColorRange:  StringExact(ColorRangeFull),
+		},
+	}
+
+	// Colorspace doesn't select properties, since few drivers report it
+	if _, ok := b.FitnessDistance(a); !ok {
+		t.Fatal("expected the properties without the colorspace to match")
+	}
+
+	a.MergeConstraints(b)
+
+	if a.ColorMatrix != ColorMatrixBT709 || a.ColorRange != ColorRangeFull {
+		t.Errorf("expected the colorspace to be bt709/full, but got %s/%s", a.ColorMatrix, a.ColorRange)
+	}
+}
+
 func TestMergeConstraintsNested(t *testing.T) {
 	type constraints struct {
 		Media
@@ -354,6 +427,14 @@ func TestString(t *testing.T) {
 		})
 	})
 
+	t.Run("PreferredValues", func(t *testing.T) {
+		t.Log("\n", &MediaConstraints{
+			VideoConstraints: VideoConstraints{
+				FrameFormat: FrameFormatPreferred{frame.FormatYUYV, frame.FormatMJPEG},
+			},
+		})
+	})
+
 	t.Run("RangedValues", func(t *testing.T) {
 		t.Log("\n", &MediaConstraints{
 			VideoConstraints: VideoConstraints{
@@ -0,0 +1,131 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+)
+
+// bitMatrix is a 2D array of bits, where true is a dark pixel or module.
+type bitMatrix struct {
+	width, height int
+	bits          []bool
+}
+
+func newBitMatrix(width, height int) *bitMatrix {
+	return &bitMatrix{
+		width:  width,
+		height: height,
+		bits:   make([]bool, width*height),
+	}
+}
+
+func (m *bitMatrix) in(x, y int) bool {
+	return x >= 0 && y >= 0 && x < m.width && y < m.height
+}
+
+// get returns false outside the matrix.
+func (m *bitMatrix) get(x, y int) bool {
+	return m.in(x, y) && m.bits[y*m.width+x]
+}
+
+func (m *bitMatrix) set(x, y int, v bool) {
+	m.bits[y*m.width+x] = v
+}
+
+func (m *bitMatrix) setRegion(x, y, width, height int) {
+	for j := y; j < y+height; j++ {
+		for i := x; i < x+width; i++ {
+			m.set(i, j, true)
+		}
+	}
+}
+
+const (
+	binarizeBlockSize = 8
+	// minDynamicRange is the luma range below which a block is considered uniform.
+	minDynamicRange = 24
+)
+
+// binarize converts the image to dark and light pixels with per-block local thresholds, which hold up
+// against uneven lighting in camera images.
+func binarize(img image.Image) *bitMatrix {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	luma := make([]uint8, width*height)
+	switch v := img.(type) {
+	case *image.YCbCr:
+		for y := 0; y < height; y++ {
+			offset := v.YOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(luma[y*width:(y+1)*width], v.Y[offset:offset+width])
+		}
+	case *image.Gray:
+		for y := 0; y < height; y++ {
+			offset := v.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(luma[y*width:(y+1)*width], v.Pix[offset:offset+width])
+		}
+	default:
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				luma[y*width+x] = color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+			}
+		}
+	}
+
+	// Estimate the black point of each block. A uniform block is assumed to be background unless its
+	// neighbors are darker.
+	bw := (width + binarizeBlockSize - 1) / binarizeBlockSize
+	bh := (height + binarizeBlockSize - 1) / binarizeBlockSize
+	blackPoints := make([]int, bw*bh)
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			sum, count, min, max := 0, 0, 255, 0
+			for y := by * binarizeBlockSize; y < (by+1)*binarizeBlockSize && y < height; y++ {
+				for x := bx * binarizeBlockSize; x < (bx+1)*binarizeBlockSize && x < width; x++ {
+					l := int(luma[y*width+x])
+					sum += l
+					count++
+					if l < min {
+						min = l
+					}
+					if l > max {
+						max = l
+					}
+				}
+			}
+			average := sum / count
+			if max-min <= minDynamicRange {
+				average = min / 2
+				if bx > 0 && by > 0 {
+					neighbors := (blackPoints[(by-1)*bw+bx] + 2*blackPoints[by*bw+bx-1] + blackPoints[(by-1)*bw+bx-1]) / 4
+					if min < neighbors {
+						average = neighbors
+					}
+				}
+			}
+			blackPoints[by*bw+bx] = average
+		}
+	}
+
+	// Each block's threshold is the average black point of the surrounding 5x5 blocks.
+	m := newBitMatrix(width, height)
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			sum, count := 0, 0
+			for ny := by - 2; ny <= by+2; ny++ {
+				for nx := bx - 2; nx <= bx+2; nx++ {
+					if nx >= 0 && ny >= 0 && nx < bw && ny < bh {
+						sum += blackPoints[ny*bw+nx]
+						count++
+					}
+				}
+			}
+			threshold := sum / count
+			for y := by * binarizeBlockSize; y < (by+1)*binarizeBlockSize && y < height; y++ {
+				for x := bx * binarizeBlockSize; x < (bx+1)*binarizeBlockSize && x < width; x++ {
+					m.set(x, y, int(luma[y*width+x]) <= threshold)
+				}
+			}
+		}
+	}
+	return m
+}
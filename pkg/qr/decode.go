@@ -0,0 +1,287 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	mathbits "math/bits"
+	"strings"
+)
+
+var (
+	errFormat   = errors.New("failed to read format information")
+	errTooShort = errors.New("data is too short")
+)
+
+// sample reads modules at their centers through the transform.
+func (m *bitMatrix) sample(version int, t transform) *bitMatrix {
+	dim := dimension(version)
+	modules := newBitMatrix(dim, dim)
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; x++ {
+			p := t.apply(float64(x)+0.5, float64(y)+0.5)
+			modules.set(x, y, m.get(int(p.x), int(p.y)))
+		}
+	}
+	return modules
+}
+
+// readFormat returns the error correction level and mask pattern from the two copies of format
+// information around the finder patterns.
+func (m *bitMatrix) readFormat() (int, int, error) {
+	dim := m.width
+	var first, second int
+	copyBit := func(bits *int, x, y int) {
+		*bits <<= 1
+		if m.get(x, y) {
+			*bits |= 1
+		}
+	}
+	for x := 0; x <= 5; x++ {
+		copyBit(&first, x, 8)
+	}
+	copyBit(&first, 7, 8)
+	copyBit(&first, 8, 8)
+	copyBit(&first, 8, 7)
+	for y := 5; y >= 0; y-- {
+		copyBit(&first, 8, y)
+	}
+	for y := dim - 1; y >= dim-7; y-- {
+		copyBit(&second, 8, y)
+	}
+	for x := dim - 8; x < dim; x++ {
+		copyBit(&second, x, 8)
+	}
+
+	// Up to 3 bit errors can be corrected.
+	best, bestDistance := 0, 4
+	for format := 0; format < 32; format++ {
+		code := formatCode(format)
+		for _, bits := range []int{first, second} {
+			if d := mathbits.OnesCount(uint(code ^ bits)); d < bestDistance {
+				best, bestDistance = format, d
+			}
+		}
+	}
+	if bestDistance > 3 {
+		return 0, 0, errFormat
+	}
+	return levelFromFormat[best>>3], best & 7, nil
+}
+
+// readCodewords reads and unmasks codewords from the data modules.
+func (m *bitMatrix) readCodewords(version, mask int) []uint8 {
+	var codewords []uint8
+	var current uint8
+	var n int
+	forEachDataModule(functionPatterns(version), func(x, y int) {
+		current <<= 1
+		if m.get(x, y) != masked(mask, x, y) {
+			current |= 1
+		}
+		n++
+		if n%8 == 0 {
+			codewords = append(codewords, current)
+			current = 0
+		}
+	})
+	return codewords
+}
+
+// correctBlocks deinterleaves codewords into blocks, corrects them and returns the data codewords.
+func correctBlocks(codewords []uint8, ec ecBlocks) ([]uint8, error) {
+	nblocks := ec.blocks1 + ec.blocks2
+	blocks := make([][]uint8, nblocks)
+	dataLen := func(b int) int {
+		if b < ec.blocks1 {
+			return ec.data1
+		}
+		return ec.data2
+	}
+	maxData := ec.data1
+	if ec.blocks2 > 0 {
+		maxData = ec.data2
+	}
+
+	var i int
+	for j := 0; j < maxData; j++ {
+		for b := range blocks {
+			if j < dataLen(b) {
+				blocks[b] = append(blocks[b], codewords[i])
+				i++
+			}
+		}
+	}
+	for j := 0; j < ec.ecPerBlock; j++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[i])
+			i++
+		}
+	}
+
+	var data []uint8
+	for b, block := range blocks {
+		if err := correctErrors(block, ec.ecPerBlock); err != nil {
+			return nil, err
+		}
+		data = append(data, block[:dataLen(b)]...)
+	}
+	return data, nil
+}
+
+// bitReader reads bits starting from each byte's most significant bit.
+type bitReader struct {
+	data []uint8
+	pos  int
+}
+
+func (r *bitReader) available() int {
+	return len(r.data)*8 - r.pos
+}
+
+func (r *bitReader) read(n int) (int, error) {
+	if n > r.available() {
+		return 0, errTooShort
+	}
+	var v int
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(r.data[r.pos/8]>>uint(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v, nil
+}
+
+// Segment modes
+const (
+	modeTerminator   = 0x0
+	modeNumeric      = 0x1
+	modeAlphanumeric = 0x2
+	modeByte         = 0x4
+	modeECI          = 0x7
+)
+
+const alphanumericChars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// characterCountBits returns the length of mode's character count.
+func characterCountBits(mode, version int) int {
+	i := 0
+	switch {
+	case version >= 27:
+		i = 2
+	case version >= 10:
+		i = 1
+	}
+	switch mode {
+	case modeNumeric:
+		return [...]int{10, 12, 14}[i]
+	case modeAlphanumeric:
+		return [...]int{9, 11, 13}[i]
+	default:
+		return [...]int{8, 16, 16}[i]
+	}
+}
+
+// decodeSegments decodes data in numeric, alphanumeric and byte modes. ECI designators are skipped, and
+// bytes are returned as is.
+func decodeSegments(data []uint8, version int) (string, error) {
+	r := &bitReader{data: data}
+	var b strings.Builder
+	for r.available() >= 4 {
+		mode, _ := r.read(4)
+		if mode == modeTerminator {
+			break
+		}
+		if mode == modeECI {
+			designator, err := r.read(8)
+			if err != nil {
+				return "", err
+			}
+			// The designator takes 1 to 3 bytes, indicated by its leading bits.
+			switch {
+			case designator&0x80 == 0:
+			case designator&0xc0 == 0x80:
+				_, err = r.read(8)
+			default:
+				_, err = r.read(16)
+			}
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		if mode != modeNumeric && mode != modeAlphanumeric && mode != modeByte {
+			return "", fmt.Errorf("unsupported mode: %d", mode)
+		}
+		count, err := r.read(characterCountBits(mode, version))
+		if err != nil {
+			return "", err
+		}
+		switch mode {
+		case modeNumeric:
+			for ; count >= 3; count -= 3 {
+				v, err := r.read(10)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&b, "%03d", v)
+			}
+			if count == 2 {
+				v, err := r.read(7)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&b, "%02d", v)
+			} else if count == 1 {
+				v, err := r.read(4)
+				if err != nil {
+					return "", err
+				}
+				fmt.Fprintf(&b, "%d", v)
+			}
+		case modeAlphanumeric:
+			for ; count >= 2; count -= 2 {
+				v, err := r.read(11)
+				if err != nil {
+					return "", err
+				}
+				if v >= 45*45 {
+					return "", errors.New("invalid alphanumeric characters")
+				}
+				b.WriteByte(alphanumericChars[v/45])
+				b.WriteByte(alphanumericChars[v%45])
+			}
+			if count == 1 {
+				v, err := r.read(6)
+				if err != nil {
+					return "", err
+				}
+				if v >= 45 {
+					return "", errors.New("invalid alphanumeric character")
+				}
+				b.WriteByte(alphanumericChars[v])
+			}
+		case modeByte:
+			for ; count > 0; count-- {
+				v, err := r.read(8)
+				if err != nil {
+					return "", err
+				}
+				b.WriteByte(byte(v))
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// decode decodes modules sampled from the code.
+func (m *bitMatrix) decode(version int) (string, error) {
+	level, mask, err := m.readFormat()
+	if err != nil {
+		return "", err
+	}
+	data, err := correctBlocks(m.readCodewords(version, mask), versionBlocks[version-1][level])
+	if err != nil {
+		return "", err
+	}
+	return decodeSegments(data, version)
+}
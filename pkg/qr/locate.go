@@ -0,0 +1,379 @@
+package qr
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+var errNotFound = errors.New("no QR code found")
+
+type point struct {
+	x, y float64
+}
+
+func distance(a, b point) float64 {
+	return math.Hypot(a.x-b.x, a.y-b.y)
+}
+
+// pattern is a finder or alignment pattern candidate.
+type pattern struct {
+	point
+	moduleSize float64
+	count      int
+}
+
+// matchRatio reports whether run lengths match ratio in modules, within half a module each.
+func matchRatio(counts []int, ratio []int) bool {
+	var total, modules int
+	for i := range counts {
+		if counts[i] == 0 {
+			return false
+		}
+		total += counts[i]
+		modules += ratio[i]
+	}
+	moduleSize := float64(total) / float64(modules)
+	for i := range counts {
+		if math.Abs(float64(counts[i])-moduleSize*float64(ratio[i])) >= moduleSize*float64(ratio[i])/2 {
+			return false
+		}
+	}
+	return true
+}
+
+// Run ratios across the patterns, alternating dark and light from the dark center run.
+var (
+	finderRatio    = []int{1, 1, 3, 1, 1}
+	alignmentRatio = []int{1, 1, 1}
+)
+
+// crossCheck counts the runs around the center run at (x, y) along (dx, dy), alternating dark and light
+// from the center, and checks them against ratio. It returns the pattern's center as an offset along
+// the line, and the runs' total length.
+func (m *bitMatrix) crossCheck(x, y, dx, dy int, ratio []int, maxCount int) (float64, int, bool) {
+	n := len(ratio)
+	counts := make([]int, n)
+	center := n / 2
+	// Backward from the center
+	p := 0
+	for i := center; i >= 0; i-- {
+		dark := (center-i)%2 == 0
+		for m.in(x-p*dx, y-p*dy) && m.get(x-p*dx, y-p*dy) == dark && counts[i] <= maxCount {
+			counts[i]++
+			p++
+		}
+		if counts[i] == 0 || counts[i] > maxCount {
+			return 0, 0, false
+		}
+	}
+	// Forward from the center
+	p = 1
+	for i := center; i < n; i++ {
+		dark := (i-center)%2 == 0
+		for m.in(x+p*dx, y+p*dy) && m.get(x+p*dx, y+p*dy) == dark && counts[i] <= maxCount {
+			counts[i]++
+			p++
+		}
+		if (i != center && counts[i] == 0) || counts[i] > maxCount {
+			return 0, 0, false
+		}
+	}
+	if !matchRatio(counts, ratio) {
+		return 0, 0, false
+	}
+
+	var total, after int
+	for i, c := range counts {
+		total += c
+		if i > center {
+			after += c
+		}
+	}
+	return float64(p-after) - float64(counts[center])/2, total, true
+}
+
+// runs returns the row's run lengths, and whether the first run is dark.
+func (m *bitMatrix) runs(y int) ([]int, bool) {
+	var runs []int
+	run := 0
+	for x := 0; x < m.width; x++ {
+		if x > 0 && m.get(x, y) != m.get(x-1, y) {
+			runs = append(runs, run)
+			run = 0
+		}
+		run++
+	}
+	return append(runs, run), m.get(0, y)
+}
+
+// findPatterns scans rows in [minY, maxY) and [minX, maxX) for the patterns' center runs, and returns
+// the candidates confirmed in both directions.
+func (m *bitMatrix) findPatterns(ratio []int, minX, minY, maxX, maxY int) []pattern {
+	var modules int
+	for _, r := range ratio {
+		modules += r
+	}
+	n := len(ratio)
+	// The center run is dark, so the first run is dark if the center is at an even index.
+	firstDark := (n/2)%2 == 0
+	if minX < 0 {
+		minX = 0
+	}
+	if minY < 0 {
+		minY = 0
+	}
+	if maxX > m.width {
+		maxX = m.width
+	}
+	if maxY > m.height {
+		maxY = m.height
+	}
+
+	var patterns []pattern
+	for y := minY; y < maxY; y++ {
+		runs, dark := m.runs(y)
+		start := 0
+		for i := 0; i+n <= len(runs); i, start, dark = i+1, start+runs[i], !dark {
+			window := runs[i : i+n]
+			total := sum(window)
+			if dark != firstDark || start < minX || start+total > maxX || !matchRatio(window, ratio) {
+				continue
+			}
+
+			maxCount := window[n/2] * 2
+			cx := int(float64(start+sum(window[:n/2])) + float64(window[n/2])/2)
+			t, vTotal, ok := m.crossCheck(cx, y, 0, 1, ratio, maxCount)
+			if !ok || 5*abs(vTotal-total) >= 2*total {
+				continue
+			}
+			centerY := float64(y) + t
+			cy := int(centerY)
+			t, hTotal, ok := m.crossCheck(cx, cy, 1, 0, ratio, maxCount)
+			if !ok || 5*abs(hTotal-total) >= 2*total {
+				continue
+			}
+			centerX := float64(cx) + t
+			// Refine the vertical center at the refined column.
+			if t, _, ok := m.crossCheck(int(centerX), cy, 0, 1, ratio, maxCount); ok {
+				centerY = float64(cy) + t
+			}
+			c := pattern{
+				point:      point{centerX, centerY},
+				moduleSize: float64(hTotal+vTotal) / float64(2*modules),
+				count:      1,
+			}
+			patterns = mergePattern(patterns, c)
+		}
+	}
+	return patterns
+}
+
+// mergePattern merges the candidate into one found at the same position in earlier rows.
+func mergePattern(patterns []pattern, c pattern) []pattern {
+	for i := range patterns {
+		p := &patterns[i]
+		if math.Abs(p.x-c.x) <= p.moduleSize && math.Abs(p.y-c.y) <= p.moduleSize &&
+			math.Abs(p.moduleSize-c.moduleSize) <= math.Max(1, p.moduleSize/2) {
+			n := float64(p.count)
+			p.x = (p.x*n + c.x) / (n + 1)
+			p.y = (p.y*n + c.y) / (n + 1)
+			p.moduleSize = (p.moduleSize*n + c.moduleSize) / (n + 1)
+			p.count++
+			return patterns
+		}
+	}
+	return append(patterns, c)
+}
+
+// maxFinderCandidates limits how many finder pattern candidates are tried.
+const maxFinderCandidates = 8
+
+// selectFinders selects the three finder patterns that form a right isosceles triangle, and returns them
+// as top left, top right and bottom left.
+func selectFinders(patterns []pattern) ([3]pattern, error) {
+	var confirmed []pattern
+	for _, p := range patterns {
+		if p.count >= 2 {
+			confirmed = append(confirmed, p)
+		}
+	}
+	sort.Slice(confirmed, func(i, j int) bool {
+		return confirmed[i].count > confirmed[j].count
+	})
+	if len(confirmed) > maxFinderCandidates {
+		confirmed = confirmed[:maxFinderCandidates]
+	}
+
+	var best [3]pattern
+	bestScore := math.Inf(1)
+	for i := 0; i < len(confirmed); i++ {
+		for j := i + 1; j < len(confirmed); j++ {
+			for k := j + 1; k < len(confirmed); k++ {
+				a, b, c := confirmed[i], confirmed[j], confirmed[k]
+				minSize := math.Min(a.moduleSize, math.Min(b.moduleSize, c.moduleSize))
+				maxSize := math.Max(a.moduleSize, math.Max(b.moduleSize, c.moduleSize))
+				if maxSize > minSize*1.4 {
+					continue
+				}
+				// The top left pattern is at the right angle, opposite the longest side.
+				ab, bc, ca := distance(a.point, b.point), distance(b.point, c.point), distance(c.point, a.point)
+				switch {
+				case bc >= ab && bc >= ca:
+				case ca >= ab && ca >= bc:
+					a, b, c = b, c, a
+					ab, bc, ca = bc, ca, ab
+				default:
+					a, b, c = c, a, b
+					ab, bc, ca = ca, ab, bc
+				}
+				legs := (ab + ca) / 2
+				score := math.Abs(ab-ca)/legs + math.Abs(bc-legs*math.Sqrt2)/legs
+				if score > 0.3 || score >= bestScore {
+					continue
+				}
+				// Order the others clockwise in image coordinates, where y points down.
+				if (b.x-a.x)*(c.y-a.y)-(b.y-a.y)*(c.x-a.x) < 0 {
+					b, c = c, b
+				}
+				best, bestScore = [3]pattern{a, b, c}, score
+			}
+		}
+	}
+	if math.IsInf(bestScore, 1) {
+		return best, errNotFound
+	}
+	return best, nil
+}
+
+// estimateVersion estimates the version from the distances between finder patterns, and returns the version
+// and module size.
+func estimateVersion(finders [3]pattern) (int, float64, error) {
+	tl, tr, bl := finders[0].point, finders[1].point, finders[2].point
+	// Rotation stretches the runs across finder patterns in rows and columns.
+	d := distance(tl, tr)
+	stretch := math.Max(math.Abs(tr.x-tl.x), math.Abs(tr.y-tl.y)) / d
+	moduleSize := stretch * (finders[0].moduleSize + finders[1].moduleSize + finders[2].moduleSize) / 3
+	modules := (d + distance(tl, bl)) / (2 * moduleSize)
+	version := int(math.Round((modules + 7 - 17) / 4))
+	if version < 1 || version > maxVersion {
+		return 0, 0, errors.New("unsupported version")
+	}
+	return version, moduleSize, nil
+}
+
+// findAlignment finds the bottom right alignment pattern around its estimated position.
+func (m *bitMatrix) findAlignment(estimated point, moduleSize float64) (point, bool) {
+	r := int(moduleSize * 5)
+	patterns := m.findPatterns(alignmentRatio,
+		int(estimated.x)-r, int(estimated.y)-r, int(estimated.x)+r, int(estimated.y)+r)
+	var found point
+	nearest := math.Inf(1)
+	for _, p := range patterns {
+		if math.Abs(p.moduleSize-moduleSize) > moduleSize/2 {
+			continue
+		}
+		if d := distance(p.point, estimated); d < nearest {
+			found, nearest = p.point, d
+		}
+	}
+	return found, !math.IsInf(nearest, 1)
+}
+
+// transform is a projective transform as a 3x3 row-major matrix.
+type transform [9]float64
+
+func (t transform) apply(x, y float64) point {
+	w := t[6]*x + t[7]*y + t[8]
+	return point{(t[0]*x + t[1]*y + t[2]) / w, (t[3]*x + t[4]*y + t[5]) / w}
+}
+
+func (t transform) mul(o transform) transform {
+	var r transform
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i*3+j] += t[i*3+k] * o[k*3+j]
+			}
+		}
+	}
+	return r
+}
+
+// The adjugate is the inverse transform, since the matrix's scale doesn't matter.
+func (t transform) adjugate() transform {
+	return transform{
+		t[4]*t[8] - t[5]*t[7], t[2]*t[7] - t[1]*t[8], t[1]*t[5] - t[2]*t[4],
+		t[5]*t[6] - t[3]*t[8], t[0]*t[8] - t[2]*t[6], t[2]*t[3] - t[0]*t[5],
+		t[3]*t[7] - t[4]*t[6], t[1]*t[6] - t[0]*t[7], t[0]*t[4] - t[1]*t[3],
+	}
+}
+
+// squareToQuad maps (0, 0), (1, 0), (1, 1) and (0, 1) to points.
+func squareToQuad(p [4]point) transform {
+	dx3 := p[0].x - p[1].x + p[2].x - p[3].x
+	dy3 := p[0].y - p[1].y + p[2].y - p[3].y
+	if dx3 == 0 && dy3 == 0 {
+		return transform{
+			p[1].x - p[0].x, p[2].x - p[1].x, p[0].x,
+			p[1].y - p[0].y, p[2].y - p[1].y, p[0].y,
+			0, 0, 1,
+		}
+	}
+	dx1, dx2 := p[1].x-p[2].x, p[3].x-p[2].x
+	dy1, dy2 := p[1].y-p[2].y, p[3].y-p[2].y
+	denominator := dx1*dy2 - dx2*dy1
+	g := (dx3*dy2 - dx2*dy3) / denominator
+	h := (dx1*dy3 - dx3*dy1) / denominator
+	return transform{
+		p[1].x - p[0].x + g*p[1].x, p[3].x - p[0].x + h*p[3].x, p[0].x,
+		p[1].y - p[0].y + g*p[1].y, p[3].y - p[0].y + h*p[3].y, p[0].y,
+		g, h, 1,
+	}
+}
+
+// quadToQuad maps src's points to dst's.
+func quadToQuad(src, dst [4]point) transform {
+	return squareToQuad(dst).mul(squareToQuad(src).adjugate())
+}
+
+// locate finds the code in the binarized image, and returns the version and the transform from module
+// coordinates to image coordinates.
+func (m *bitMatrix) locate() (int, transform, error) {
+	finders, err := selectFinders(m.findPatterns(finderRatio, 0, 0, m.width, m.height))
+	if err != nil {
+		return 0, transform{}, err
+	}
+	version, moduleSize, err := estimateVersion(finders)
+	if err != nil {
+		return 0, transform{}, err
+	}
+
+	dim := float64(dimension(version))
+	tl, tr, bl := finders[0].point, finders[1].point, finders[2].point
+	src := [4]point{{3.5, 3.5}, {dim - 3.5, 3.5}, {dim - 3.5, dim - 3.5}, {3.5, dim - 3.5}}
+	dst := [4]point{tl, tr, {tr.x + bl.x - tl.x, tr.y + bl.y - tl.y}, bl}
+	if version > 1 {
+		// The alignment pattern corrects perspective distortion.
+		affine := quadToQuad(src, dst)
+		if p, ok := m.findAlignment(affine.apply(dim-6.5, dim-6.5), moduleSize); ok {
+			src[2], dst[2] = point{dim - 6.5, dim - 6.5}, p
+		}
+	}
+	return version, quadToQuad(src, dst), nil
+}
+
+func sum(values []int) int {
+	var s int
+	for _, v := range values {
+		s += v
+	}
+	return s
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
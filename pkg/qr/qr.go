@@ -0,0 +1,69 @@
+// Package qr detects and decodes QR codes in video frames. The detector implements video.Detector, so
+// codes can be detected in the pipeline with video.Detect without copying frames:
+//
+//	track.Transform(video.Detect(qr.NewDetector(func(c qr.Code) {
+//	  fmt.Println(c.Content)
+//	})))
+//
+// Model 2 codes up to version 10 (57x57 modules) are supported, which covers typical uses like URLs and
+// tickets. Kanji mode and mirrored codes aren't supported.
+// Reference: ISO/IEC 18004
+package qr
+
+import (
+	"image"
+	"math"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// Code is a QR code found in an image.
+type Code struct {
+	// Content is the decoded data. Byte mode bytes are stored as is, and are usually UTF-8.
+	Content string
+	// Version is the code's size, from 1 (21x21 modules) to 10 (57x57 modules).
+	Version int
+	// Corners are the code's corners in the image, clockwise from the code's top left corner.
+	Corners [4]image.Point
+}
+
+// Scan finds a QR code in the image and decodes it. Only one code is returned if the image has several.
+func Scan(img image.Image) (Code, error) {
+	m := binarize(img)
+	version, t, err := m.locate()
+	if err != nil {
+		return Code{}, err
+	}
+	content, err := m.sample(version, t).decode(version)
+	if err != nil {
+		return Code{}, err
+	}
+
+	code := Code{
+		Content: content,
+		Version: version,
+	}
+	dim := float64(dimension(version))
+	min := img.Bounds().Min
+	for i, c := range [4]point{{0, 0}, {dim, 0}, {dim, dim}, {0, dim}} {
+		p := t.apply(c.x, c.y)
+		code.Corners[i] = image.Pt(min.X+int(math.Round(p.x)), min.Y+int(math.Round(p.y)))
+	}
+	return code, nil
+}
+
+type detector struct {
+	onDetect func(Code)
+}
+
+// NewDetector creates a video.Detector that calls onDetect with the code found in each frame. onDetect is
+// called for every frame with a code, so it's up to the caller to ignore repeats.
+func NewDetector(onDetect func(Code)) video.Detector {
+	return &detector{onDetect: onDetect}
+}
+
+func (d *detector) Detect(img image.Image) {
+	if code, err := Scan(img); err == nil {
+		d.onDetect(code)
+	}
+}
@@ -0,0 +1,358 @@
+package qr
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// rsEncode returns data's error correction codewords.
+func rsEncode(data []uint8, nsym int) []uint8 {
+	// Generator polynomial in descending degree order
+	gen := []uint8{1}
+	for i := 0; i < nsym; i++ {
+		next := make([]uint8, len(gen)+1)
+		for j, c := range gen {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfPow(i))
+		}
+		gen = next
+	}
+	rem := make([]uint8, nsym)
+	for _, d := range data {
+		factor := d ^ rem[0]
+		copy(rem, rem[1:])
+		rem[nsym-1] = 0
+		for j := range rem {
+			rem[j] ^= gfMul(gen[j+1], factor)
+		}
+	}
+	return rem
+}
+
+// encode builds the code's modules with byte mode data.
+func encode(t *testing.T, content string, version, level, mask int) *bitMatrix {
+	ec := versionBlocks[version-1][level]
+	capacity := ec.blocks1*ec.data1 + ec.blocks2*ec.data2
+
+	var bits []bool
+	appendBits := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, v>>uint(i)&1 == 1)
+		}
+	}
+	appendBits(modeByte, 4)
+	appendBits(len(content), characterCountBits(modeByte, version))
+	for i := 0; i < len(content); i++ {
+		appendBits(int(content[i]), 8)
+	}
+	if len(bits)+4 > capacity*8 {
+		t.Fatalf("content is too long for version %d", version)
+	}
+	appendBits(modeTerminator, 4)
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	data := make([]uint8, capacity)
+	for i := 0; i < len(bits)/8; i++ {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				data[i] |= 1 << uint(7-j)
+			}
+		}
+	}
+	for i, pad := len(bits)/8, 0; i < capacity; i, pad = i+1, pad+1 {
+		data[i] = [2]uint8{0xec, 0x11}[pad%2]
+	}
+
+	// Split into blocks and interleave
+	var blocks, ecs [][]uint8
+	for b, offset := 0, 0; b < ec.blocks1+ec.blocks2; b++ {
+		n := ec.data1
+		if b >= ec.blocks1 {
+			n = ec.data2
+		}
+		blocks = append(blocks, data[offset:offset+n])
+		ecs = append(ecs, rsEncode(data[offset:offset+n], ec.ecPerBlock))
+		offset += n
+	}
+	var codewords []uint8
+	for j := 0; j < ec.data2 || j < ec.data1; j++ {
+		for _, block := range blocks {
+			if j < len(block) {
+				codewords = append(codewords, block[j])
+			}
+		}
+	}
+	for j := 0; j < ec.ecPerBlock; j++ {
+		for _, e := range ecs {
+			codewords = append(codewords, e[j])
+		}
+	}
+
+	dim := dimension(version)
+	m := newBitMatrix(dim, dim)
+	finder := func(x0, y0 int) {
+		for y := 0; y < 7; y++ {
+			for x := 0; x < 7; x++ {
+				ring := x == 0 || y == 0 || x == 6 || y == 6
+				m.set(x0+x, y0+y, ring || (x >= 2 && x <= 4 && y >= 2 && y <= 4))
+			}
+		}
+	}
+	finder(0, 0)
+	finder(dim-7, 0)
+	finder(0, dim-7)
+	for i := 8; i < dim-8; i++ {
+		m.set(i, 6, i%2 == 0)
+		m.set(6, i, i%2 == 0)
+	}
+	positions := alignmentPositions(version)
+	for i, cy := range positions {
+		for j, cx := range positions {
+			last := len(positions) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for y := -2; y <= 2; y++ {
+				for x := -2; x <= 2; x++ {
+					m.set(cx+x, cy+y, x == -2 || x == 2 || y == -2 || y == 2 || (x == 0 && y == 0))
+				}
+			}
+		}
+	}
+
+	format := formatCode([4]int{1, 0, 3, 2}[level]<<3 | mask)
+	bit := func(i int) bool { return format>>uint(14-i)&1 == 1 }
+	first := [][2]int{{0, 8}, {1, 8}, {2, 8}, {3, 8}, {4, 8}, {5, 8}, {7, 8}, {8, 8}, {8, 7}, {8, 5}, {8, 4}, {8, 3}, {8, 2}, {8, 1}, {8, 0}}
+	for i, p := range first {
+		m.set(p[0], p[1], bit(i))
+	}
+	for i := 0; i < 7; i++ {
+		m.set(8, dim-1-i, bit(i))
+	}
+	for i := 7; i < 15; i++ {
+		m.set(dim-15+i, 8, bit(i))
+	}
+	m.set(8, dim-8, true)
+
+	if version >= 7 {
+		info := bchCode(version, 12, 0x1f25)
+		for i := 0; i < 18; i++ {
+			v := info>>uint(i)&1 == 1
+			m.set(i/3, dim-11+i%3, v)
+			m.set(dim-11+i%3, i/3, v)
+		}
+	}
+
+	n := 0
+	forEachDataModule(functionPatterns(version), func(x, y int) {
+		var v bool
+		if n/8 < len(codewords) {
+			v = codewords[n/8]>>uint(7-n%8)&1 == 1
+		}
+		m.set(x, y, v != masked(mask, x, y))
+		n++
+	})
+	return m
+}
+
+// render draws the modules rotated by angle around the center, with a quiet zone.
+func render(m *bitMatrix, scale int, angle float64) *image.Gray {
+	size := (m.width + 8) * scale
+	if angle != 0 {
+		// Room for the rotated corners
+		size = size * 3 / 2
+	}
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	center := float64(size) / 2
+	sin, cos := math.Sincos(angle)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-center, float64(y)+0.5-center
+			mx := int(math.Floor((cos*dx+sin*dy)/float64(scale) + float64(m.width)/2))
+			my := int(math.Floor((-sin*dx+cos*dy)/float64(scale) + float64(m.width)/2))
+			c := uint8(230)
+			if m.get(mx, my) {
+				c = 20
+			}
+			img.SetGray(x, y, color.Gray{Y: c})
+		}
+	}
+	return img
+}
+
+func TestReedSolomon(t *testing.T) {
+	// HELLO WORLD in version 1-Q
+	data := []uint8{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236}
+	expected := []uint8{168, 72, 22, 82, 217, 54, 156, 0, 46, 15, 180, 122, 16}
+	ec := rsEncode(data, len(expected))
+	for i := range expected {
+		if ec[i] != expected[i] {
+			t.Fatalf("expected %v, but got %v", expected, ec)
+		}
+	}
+
+	decoded, err := decodeSegments(data, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "HELLO WORLD" {
+		t.Fatalf("expected HELLO WORLD, but got %s", decoded)
+	}
+
+	block := append(append([]uint8{}, data...), ec...)
+	for _, i := range []int{0, 3, 7, 12, 20, 25} {
+		block[i] ^= 0x5a
+	}
+	if err := correctErrors(block, len(ec)); err != nil {
+		t.Fatal(err)
+	}
+	for i := range data {
+		if block[i] != data[i] {
+			t.Fatalf("expected %v, but got %v", data, block[:len(data)])
+		}
+	}
+
+	block[1] ^= 1
+	block[2] ^= 1
+	block[4] ^= 1
+	block[5] ^= 1
+	block[6] ^= 1
+	block[8] ^= 1
+	block[9] ^= 1
+	if err := correctErrors(block, len(ec)); err == nil {
+		t.Fatal("expected to fail with 7 errors")
+	}
+}
+
+func TestFormatCode(t *testing.T) {
+	// Level L and mask 4
+	if code := formatCode(1<<3 | 4); code != 0x662f {
+		t.Fatalf("expected %015b, but got %015b", 0x662f, code)
+	}
+	// Version 7
+	if code := bchCode(7, 12, 0x1f25); code != 0x07c94 {
+		t.Fatalf("expected %018b, but got %018b", 0x07c94, code)
+	}
+}
+
+func TestVersionBlocks(t *testing.T) {
+	for version := 1; version <= maxVersion; version++ {
+		var modules int
+		forEachDataModule(functionPatterns(version), func(x, y int) {
+			modules++
+		})
+		for level, ec := range versionBlocks[version-1] {
+			total := (ec.blocks1+ec.blocks2)*ec.ecPerBlock + ec.blocks1*ec.data1 + ec.blocks2*ec.data2
+			if total != modules/8 {
+				t.Errorf("version %d level %d: expected %d codewords, but got %d", version, level, modules/8, total)
+			}
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	const content = "https://github.com/pion/mediadevices"
+
+	testCases := map[string]struct {
+		version, level, mask int
+		scale                int
+		angle                float64
+	}{
+		"Version3": {
+			version: 3, level: levelM, mask: 2, scale: 4,
+		},
+		"Rotated": {
+			version: 3, level: levelL, mask: 5, scale: 6, angle: math.Pi / 6,
+		},
+		"UpsideDown": {
+			version: 5, level: levelH, mask: 0, scale: 5, angle: math.Pi,
+		},
+		"Version7": {
+			version: 7, level: levelQ, mask: 7, scale: 3, angle: -0.1,
+		},
+		"Version10": {
+			version: 10, level: levelH, mask: 3, scale: 3,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			m := encode(t, content, c.version, c.level, c.mask)
+			code, err := Scan(render(m, c.scale, c.angle))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code.Content != content {
+				t.Fatalf("expected %s, but got %s", content, code.Content)
+			}
+			if code.Version != c.version {
+				t.Fatalf("expected version %d, but got %d", c.version, code.Version)
+			}
+		})
+	}
+
+	t.Run("Perspective", func(t *testing.T) {
+		m := encode(t, content, 5, levelM, 6)
+		dim := float64(m.width)
+		// Tilted away from the camera at the bottom right corner
+		toModule := quadToQuad(
+			[4]point{{0, 0}, {dim, 0}, {dim, dim}, {0, dim}},
+			[4]point{{30, 20}, {250, 35}, {215, 215}, {40, 240}},
+		).adjugate()
+		img := image.NewGray(image.Rect(0, 0, 280, 280))
+		for y := 0; y < 280; y++ {
+			for x := 0; x < 280; x++ {
+				p := toModule.apply(float64(x)+0.5, float64(y)+0.5)
+				c := uint8(230)
+				if m.get(int(math.Floor(p.x)), int(math.Floor(p.y))) {
+					c = 20
+				}
+				img.SetGray(x, y, color.Gray{Y: c})
+			}
+		}
+		code, err := Scan(img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code.Content != content {
+			t.Fatalf("expected %s, but got %s", content, code.Content)
+		}
+	})
+
+	t.Run("Corners", func(t *testing.T) {
+		code, err := Scan(render(encode(t, content, 3, levelM, 1), 4, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 4 quiet zone modules, and 29 modules for version 3
+		expected := [4]image.Point{{16, 16}, {132, 16}, {132, 132}, {16, 132}}
+		for i := range expected {
+			if d := code.Corners[i].Sub(expected[i]); d.X < -2 || d.X > 2 || d.Y < -2 || d.Y > 2 {
+				t.Fatalf("expected corners %v, but got %v", expected, code.Corners)
+			}
+		}
+	})
+
+	t.Run("NoCode", func(t *testing.T) {
+		img := image.NewGray(image.Rect(0, 0, 160, 120))
+		if _, err := Scan(img); err == nil {
+			t.Fatal("expected an error from an empty image")
+		}
+	})
+}
+
+func TestDetector(t *testing.T) {
+	img := render(encode(t, "hello", 1, levelM, 4), 4, 0)
+	var detected []Code
+	d := NewDetector(func(c Code) {
+		detected = append(detected, c)
+	})
+	d.Detect(img)
+	d.Detect(image.NewGray(img.Rect))
+	if len(detected) != 1 || detected[0].Content != "hello" {
+		t.Fatalf("expected to detect hello once, but got %v", detected)
+	}
+}
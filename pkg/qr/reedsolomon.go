@@ -0,0 +1,153 @@
+package qr
+
+import "errors"
+
+var errTooManyErrors = errors.New("too many errors to correct")
+
+// GF(256) with the primitive polynomial x^8 + x^4 + x^3 + x^2 + 1.
+var gfExp, gfLog = func() (exp [512]uint8, log [256]uint8) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = uint8(x)
+		log[x] = uint8(i)
+		x <<= 1
+		if x >= 256 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(exp); i++ {
+		exp[i] = exp[i-255]
+	}
+	return
+}()
+
+func gfMul(a, b uint8) uint8 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b uint8) uint8 {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfPow returns alpha^n.
+func gfPow(n int) uint8 {
+	n %= 255
+	if n < 0 {
+		n += 255
+	}
+	return gfExp[n]
+}
+
+// polyEval evaluates the polynomial, with coefficients in ascending degree order, at x.
+func polyEval(p []uint8, x uint8) uint8 {
+	var y uint8
+	for i := len(p) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ p[i]
+	}
+	return y
+}
+
+// correctErrors corrects the block's errors in place. The block is the data codewords followed by
+// nsym error correction codewords, whose generator polynomial has the roots alpha^0 to alpha^(nsym-1).
+func correctErrors(block []uint8, nsym int) error {
+	// The first codeword is the highest degree coefficient.
+	n := len(block)
+	syndromes := make([]uint8, nsym)
+	var hasError bool
+	for i := range syndromes {
+		x := gfPow(i)
+		var s uint8
+		for _, c := range block {
+			s = gfMul(s, x) ^ c
+		}
+		syndromes[i] = s
+		if s != 0 {
+			hasError = true
+		}
+	}
+	if !hasError {
+		return nil
+	}
+
+	// Berlekamp-Massey algorithm to find the error locator polynomial.
+	locator := []uint8{1}
+	prev := []uint8{1}
+	var l int
+	shift, prevDiscrepancy := 1, uint8(1)
+	for i := 0; i < nsym; i++ {
+		d := syndromes[i]
+		for j := 1; j <= l && j < len(locator); j++ {
+			d ^= gfMul(locator[j], syndromes[i-j])
+		}
+		if d == 0 {
+			shift++
+			continue
+		}
+		scale := gfDiv(d, prevDiscrepancy)
+		next := make([]uint8, max(len(locator), len(prev)+shift))
+		copy(next, locator)
+		for j, c := range prev {
+			next[j+shift] ^= gfMul(scale, c)
+		}
+		if 2*l <= i {
+			prev = locator
+			l = i + 1 - l
+			prevDiscrepancy = d
+			shift = 1
+		} else {
+			shift++
+		}
+		locator = next
+	}
+	if 2*l > nsym {
+		return errTooManyErrors
+	}
+
+	// Chien search for error positions, counted from the end of the block.
+	var positions []int
+	for p := 0; p < n; p++ {
+		if polyEval(locator, gfPow(-p)) == 0 {
+			positions = append(positions, p)
+		}
+	}
+	if len(positions) != l {
+		return errTooManyErrors
+	}
+
+	// Forney algorithm for error magnitudes.
+	evaluator := make([]uint8, nsym)
+	for i, s := range syndromes {
+		for j, c := range locator {
+			if i+j < nsym {
+				evaluator[i+j] ^= gfMul(s, c)
+			}
+		}
+	}
+	derivative := make([]uint8, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+	for _, p := range positions {
+		xInv := gfPow(-p)
+		denominator := polyEval(derivative, xInv)
+		if denominator == 0 {
+			return errTooManyErrors
+		}
+		magnitude := gfMul(gfPow(p), gfDiv(polyEval(evaluator, xInv), denominator))
+		block[n-1-p] ^= magnitude
+	}
+	return nil
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
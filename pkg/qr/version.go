@@ -0,0 +1,157 @@
+package qr
+
+import (
+	mathbits "math/bits"
+)
+
+// Error correction levels, in versionBlocks order.
+const (
+	levelL = iota
+	levelM
+	levelQ
+	levelH
+)
+
+// levelFromFormat maps the format information's 2 bits to the error correction level.
+var levelFromFormat = [4]int{levelM, levelL, levelH, levelQ}
+
+// ecBlocks is the block structure for a version and error correction level: the error correction codewords
+// per block, and the block counts and data codewords of both groups.
+type ecBlocks struct {
+	ecPerBlock     int
+	blocks1, data1 int
+	blocks2, data2 int
+}
+
+// versionBlocks is indexed by version - 1 and error correction level. ISO/IEC 18004 Table 9.
+var versionBlocks = [...][4]ecBlocks{
+	{{7, 1, 19, 0, 0}, {10, 1, 16, 0, 0}, {13, 1, 13, 0, 0}, {17, 1, 9, 0, 0}},
+	{{10, 1, 34, 0, 0}, {16, 1, 28, 0, 0}, {22, 1, 22, 0, 0}, {28, 1, 16, 0, 0}},
+	{{15, 1, 55, 0, 0}, {26, 1, 44, 0, 0}, {18, 2, 17, 0, 0}, {22, 2, 13, 0, 0}},
+	{{20, 1, 80, 0, 0}, {18, 2, 32, 0, 0}, {26, 2, 24, 0, 0}, {16, 4, 9, 0, 0}},
+	{{26, 1, 108, 0, 0}, {24, 2, 43, 0, 0}, {18, 2, 15, 2, 16}, {22, 2, 11, 2, 12}},
+	{{18, 2, 68, 0, 0}, {16, 4, 27, 0, 0}, {24, 4, 19, 0, 0}, {28, 4, 15, 0, 0}},
+	{{20, 2, 78, 0, 0}, {18, 4, 31, 0, 0}, {18, 2, 14, 4, 15}, {26, 4, 13, 1, 14}},
+	{{24, 2, 97, 0, 0}, {22, 2, 38, 2, 39}, {22, 4, 18, 2, 19}, {26, 4, 14, 2, 15}},
+	{{30, 2, 116, 0, 0}, {22, 3, 36, 2, 37}, {20, 4, 16, 4, 17}, {24, 4, 12, 4, 13}},
+	{{18, 2, 68, 2, 69}, {26, 4, 43, 1, 44}, {24, 6, 19, 2, 20}, {28, 6, 15, 2, 16}},
+}
+
+// maxVersion is the largest supported version.
+const maxVersion = len(versionBlocks)
+
+func dimension(version int) int {
+	return 17 + 4*version
+}
+
+// alignmentPositions returns the alignment pattern centers' coordinates in both directions.
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	n := version/7 + 2
+	last := dimension(version) - 7
+	step := (version*4 + n*2 + 1) / (n*2 - 2) * 2
+	positions := make([]int, n)
+	positions[0] = 6
+	for i := n - 1; i > 0; i-- {
+		positions[i] = last - (n-1-i)*step
+	}
+	return positions
+}
+
+// functionPatterns returns the modules that don't store data: finder patterns with their separators and
+// format information, timing patterns, alignment patterns and version information.
+func functionPatterns(version int) *bitMatrix {
+	dim := dimension(version)
+	m := newBitMatrix(dim, dim)
+	m.setRegion(0, 0, 9, 9)
+	m.setRegion(dim-8, 0, 8, 9)
+	m.setRegion(0, dim-8, 9, 8)
+	m.setRegion(6, 0, 1, dim)
+	m.setRegion(0, 6, dim, 1)
+
+	positions := alignmentPositions(version)
+	for i, y := range positions {
+		for j, x := range positions {
+			last := len(positions) - 1
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				// Overlapped with the finder patterns
+				continue
+			}
+			m.setRegion(x-2, y-2, 5, 5)
+		}
+	}
+
+	if version >= 7 {
+		m.setRegion(0, dim-11, 6, 3)
+		m.setRegion(dim-11, 0, 3, 6)
+	}
+	return m
+}
+
+// forEachDataModule calls fn at the data modules in bit order, which zigzags up and down 2-module-wide
+// columns from the bottom right corner.
+func forEachDataModule(function *bitMatrix, fn func(x, y int)) {
+	dim := function.width
+	up := true
+	for right := dim - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// Skip the vertical timing pattern
+			right = 5
+		}
+		for i := 0; i < dim; i++ {
+			y := i
+			if up {
+				y = dim - 1 - i
+			}
+			for x := right; x > right-2; x-- {
+				if !function.get(x, y) {
+					fn(x, y)
+				}
+			}
+		}
+		up = !up
+	}
+}
+
+// masked reports whether the mask pattern inverts the module at (x, y).
+func masked(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (y/2+x/3)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+const (
+	formatGenerator = 0x537
+	formatMask      = 0x5412
+)
+
+// formatCode returns the format information's 15 bits with BCH code.
+func formatCode(format int) int {
+	return bchCode(format, 10, formatGenerator) ^ formatMask
+}
+
+// bchCode appends the remainder of data divided by generator, whose degree is bits.
+func bchCode(data, bits, generator int) int {
+	rem := data << uint(bits)
+	for rem>>uint(bits) != 0 {
+		rem ^= generator << uint(mathbits.Len(uint(rem))-mathbits.Len(uint(generator)))
+	}
+	return data<<uint(bits) | rem
+}
@@ -0,0 +1,144 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+	"golang.org/x/image/draw"
+)
+
+// IndexSuffix is appended to a segment's path to get its index path.
+const IndexSuffix = ".index"
+
+const thumbnailQuality = 75
+
+// IndexEntry is a keyframe in a segment's index, which players seek to.
+type IndexEntry struct {
+	// Offset is the keyframe's position in the segment in bytes.
+	Offset int64 `json:"offset"`
+	// Time is the keyframe's capture time.
+	Time time.Time `json:"time"`
+	// Thumbnail is a JPEG thumbnail of the video at the keyframe, if any.
+	Thumbnail []byte `json:"thumbnail,omitempty"`
+}
+
+// WithIndex writes a keyframe index next to each segment, see IndexSuffix, so recordings can be
+// scrubbed without reading them. thumbnail returns index entry images, e.g. Thumbnailer.Thumbnail,
+// or nil to index only offsets.
+func WithIndex(thumbnail func() image.Image) Option {
+	return func(r *Recorder) error {
+		r.indexed = true
+		r.thumbnail = thumbnail
+		return nil
+	}
+}
+
+// ReadIndex reads entries of an index written by WithIndex.
+func ReadIndex(r io.Reader) ([]IndexEntry, error) {
+	var entries []IndexEntry
+	dec := json.NewDecoder(r)
+	for dec.More() {
+		var e IndexEntry
+		if err := dec.Decode(&e); err != nil {
+			return entries, fmt.Errorf("failed to read the index: %s", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// indexWriter writes entries as JSON lines, so an interrupted recording's index is readable.
+type indexWriter struct {
+	file io.WriteCloser
+	w    *bufio.Writer
+	enc  *json.Encoder
+}
+
+func newIndexWriter(file io.WriteCloser) *indexWriter {
+	w := bufio.NewWriter(file)
+	return &indexWriter{file: file, w: w, enc: json.NewEncoder(w)}
+}
+
+func (w *indexWriter) add(offset int64, t time.Time, thumbnail func() image.Image) error {
+	e := IndexEntry{Offset: offset, Time: t}
+	if thumbnail != nil {
+		if img := thumbnail(); img != nil {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+				return fmt.Errorf("failed to encode the thumbnail: %s", err)
+			}
+			e.Thumbnail = buf.Bytes()
+		}
+	}
+	if err := w.enc.Encode(e); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *indexWriter) close() error {
+	err := w.w.Flush()
+	if errClose := w.file.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
+
+// Thumbnailer keeps a thumbnail of a video's last frame.
+type Thumbnailer struct {
+	width int
+
+	mu  sync.Mutex
+	img *image.RGBA
+}
+
+// NewThumbnailer creates a Thumbnailer whose thumbnails are width pixels wide with the frames' aspect ratio.
+func NewThumbnailer(width int) *Thumbnailer {
+	return &Thumbnailer{width: width}
+}
+
+// Transform returns a transform that scales each frame to the thumbnail, e.g. for the recorded track.
+// Frames are passed through.
+func (t *Thumbnailer) Transform() video.TransformFunc {
+	return func(r video.Reader) video.Reader {
+		return video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+			img, release, err := r.Read()
+			if err != nil {
+				return nil, func() {}, err
+			}
+
+			bounds := img.Bounds()
+			if bounds.Dx() > 0 && bounds.Dy() > 0 {
+				height := bounds.Dy() * t.width / bounds.Dx()
+				if height < 1 {
+					height = 1
+				}
+				thumbnail := image.NewRGBA(image.Rect(0, 0, t.width, height))
+				draw.ApproxBiLinear.Scale(thumbnail, thumbnail.Rect, img, bounds, draw.Src, nil)
+
+				t.mu.Lock()
+				t.img = thumbnail
+				t.mu.Unlock()
+			}
+			return img, release, nil
+		}), r)
+	}
+}
+
+// Thumbnail returns the last frame's thumbnail, or nil before the first frame.
+func (t *Thumbnailer) Thumbnail() image.Image {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.img == nil {
+		return nil
+	}
+	return t.img
+}
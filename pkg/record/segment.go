@@ -0,0 +1,383 @@
+// Package record writes a track's encoded frames to a recording's segment files. Segments are
+// rotated at keyframes by duration or size, so each segment plays from its start, and the
+// oldest segments are pruned to bound disk usage of long-running recordings.
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+)
+
+// DefaultNameTemplate is the segment name if WithNameTemplate doesn't set it.
+const DefaultNameTemplate = `{{.Start.Format "20060102-150405"}}-{{.Index}}`
+
+var errClosed = errors.New("record: the recorder is closed")
+
+// Segment is a recording's segment file.
+type Segment struct {
+	// Index is the segment's number in the recording, from 0.
+	Index int
+	// Path is the segment file's path.
+	Path string
+	// Start is the first frame's capture time, and End is the next segment's first frame's, or the
+	// recording's last frame's.
+	Start, End time.Time
+	// Size is how many bytes were written to the segment.
+	Size int64
+	// IndexPath is the segment's keyframe index path, or empty without WithIndex.
+	IndexPath string
+	// TimestampsPath is the path of the segment's frame timestamps, or empty without WithTimestamps.
+	TimestampsPath string
+}
+
+// Option configures NewRecorder.
+type Option func(*Recorder) error
+
+// WithSegmentDuration sets maximum segment duration. The default is 10 minutes, and 0 disables it.
+func WithSegmentDuration(d time.Duration) Option {
+	return func(r *Recorder) error {
+		r.maxDuration = d
+		return nil
+	}
+}
+
+// WithSegmentSize sets maximum segment size in bytes. It's disabled by default.
+func WithSegmentSize(size int64) Option {
+	return func(r *Recorder) error {
+		r.maxSize = size
+		return nil
+	}
+}
+
+// WithNameTemplate sets segment file names, as a text/template executed with Segment, e.g.
+// `{{.Start.UTC.Format "2006/01/02/150405"}}.h264`. Directories in the name are created.
+func WithNameTemplate(name string) Option {
+	return func(r *Recorder) error {
+		tmpl, err := template.New("segment").Parse(name)
+		if err != nil {
+			return fmt.Errorf("failed to parse the name template: %s", err)
+		}
+		r.name = tmpl
+		return nil
+	}
+}
+
+// WithMaxDiskUsage prunes the oldest segments in the recording's directory after each segment, so
+// segments total up to size in bytes. Segments left in the directory by previous recordings are
+// pruned too, so only recordings should use the directory.
+func WithMaxDiskUsage(size int64) Option {
+	return func(r *Recorder) error {
+		r.maxDiskUsage = size
+		return nil
+	}
+}
+
+// WithOpener sets a function that creates segment files, e.g. to write a container header or to encrypt
+// them with encrypt.NewWriter. The default creates files with os.Create.
+func WithOpener(open func(path string) (io.WriteCloser, error)) Option {
+	return func(r *Recorder) error {
+		r.open = open
+		return nil
+	}
+}
+
+// OnSegmentComplete sets a handler that's called after each segment is closed, e.g. to upload it.
+// It's called in Write's or Close's goroutine.
+func OnSegmentComplete(handler func(Segment)) Option {
+	return func(r *Recorder) error {
+		r.onComplete = handler
+		return nil
+	}
+}
+
+// Recorder writes encoded frames to rotated segment files in a directory.
+type Recorder struct {
+	dir          string
+	mimeType     string
+	maxDuration  time.Duration
+	maxSize      int64
+	maxDiskUsage int64
+	name         *template.Template
+	open         func(path string) (io.WriteCloser, error)
+	onComplete   func(Segment)
+	indexed      bool
+	thumbnail    func() image.Image
+	timestamped  bool
+
+	mu      sync.Mutex
+	index   int
+	segment *Segment
+	file    io.WriteCloser
+	idx     *indexWriter
+	ts      *timestampsWriter
+	closed  bool
+}
+
+// NewRecorder creates a Recorder that writes mimeType frames to dir. Frames are written as they are,
+// e.g. H.264 frames in Annex B, so WithOpener writes containers.
+func NewRecorder(dir string, mimeType string, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		dir:         dir,
+		mimeType:    mimeType,
+		maxDuration: 10 * time.Minute,
+		open: func(path string) (io.WriteCloser, error) {
+			return os.Create(path)
+		},
+	}
+	if err := WithNameTemplate(DefaultNameTemplate)(r); err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the directory: %s", err)
+	}
+	return r, nil
+}
+
+// Write writes an encoded frame captured at captureTime. A new segment is started at a keyframe when the current
+// one is longer than segment duration or larger than segment size. Frames before the first keyframe
+// are dropped, since they can't be decoded.
+func (r *Recorder) Write(data []byte, captureTime time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return errClosed
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	keyFrame := codec.IsKeyFrame(r.mimeType, data)
+	if keyFrame && (r.segment == nil || r.full(captureTime)) {
+		if err := r.complete(captureTime); err != nil {
+			return err
+		}
+		if err := r.start(captureTime); err != nil {
+			return err
+		}
+	}
+	if r.segment == nil {
+		return nil
+	}
+	if keyFrame && r.idx != nil {
+		if err := r.idx.add(r.segment.Size, captureTime, r.thumbnail); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if fw, ok := r.file.(FrameWriter); ok {
+		err = fw.WriteFrame(data, captureTime)
+		r.segment.Size += int64(len(data))
+	} else {
+		var n int
+		n, err = r.file.Write(data)
+		r.segment.Size += int64(n)
+	}
+	r.segment.End = captureTime
+	if err == nil && r.ts != nil {
+		err = r.ts.add(captureTime)
+	}
+	return err
+}
+
+// WriteFrom writes frames read from src until it returns an error, e.g. from a track's encoded reader. Recording
+// encoders may delay frames, e.g. x264 with B-frames or TwoPass, and return them before io.EOF once src is
+// closed, so every frame is written before WriteFrom returns. It returns nil on io.EOF.
+func (r *Recorder) WriteFrom(src mediadevices.EncodedReadCloser) error {
+	for {
+		buffer, release, err := src.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		err = r.Write(buffer.Data, buffer.CaptureTime)
+		release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *Recorder) full(now time.Time) bool {
+	return (r.maxDuration > 0 && now.Sub(r.segment.Start) >= r.maxDuration) ||
+		(r.maxSize > 0 && r.segment.Size >= r.maxSize)
+}
+
+func (r *Recorder) start(now time.Time) error {
+	segment := &Segment{Index: r.index, Start: now, End: now}
+	var name bytes.Buffer
+	if err := r.name.Execute(&name, segment); err != nil {
+		return fmt.Errorf("failed to name the segment: %s", err)
+	}
+	segment.Path = filepath.Join(r.dir, name.String())
+	if err := os.MkdirAll(filepath.Dir(segment.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create the directory of the segment: %s", err)
+	}
+
+	file, err := r.open(segment.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create the segment: %s", err)
+	}
+	if r.indexed {
+		segment.IndexPath = segment.Path + IndexSuffix
+		idxFile, err := r.open(segment.IndexPath)
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("failed to create the index: %s", err)
+		}
+		r.idx = newIndexWriter(idxFile)
+	}
+	if r.timestamped {
+		segment.TimestampsPath = segment.Path + TimestampsSuffix
+		tsFile, err := r.open(segment.TimestampsPath)
+		if err == nil {
+			if r.ts, err = newTimestampsWriter(tsFile, now); err != nil {
+				tsFile.Close()
+			}
+		}
+		if err != nil {
+			file.Close()
+			if r.idx != nil {
+				r.idx.close()
+				r.idx = nil
+			}
+			return fmt.Errorf("failed to create the timestamps: %s", err)
+		}
+	}
+	r.index++
+	r.segment, r.file = segment, file
+	return nil
+}
+
+// complete closes the current segment, which ends at end.
+func (r *Recorder) complete(end time.Time) error {
+	if r.segment == nil {
+		return nil
+	}
+	segment := *r.segment
+	if end.After(segment.End) {
+		segment.End = end
+	}
+	err := r.file.Close()
+	if r.idx != nil {
+		if errIdx := r.idx.close(); err == nil {
+			err = errIdx
+		}
+	}
+	if r.ts != nil {
+		if errTs := r.ts.close(); err == nil {
+			err = errTs
+		}
+	}
+	r.segment, r.file, r.idx, r.ts = nil, nil, nil, nil
+	if err != nil {
+		return fmt.Errorf("failed to close the segment: %s", err)
+	}
+
+	if r.maxDiskUsage > 0 {
+		if err := prune(r.dir, r.maxDiskUsage, segment.Path); err != nil {
+			return err
+		}
+	}
+	if r.onComplete != nil {
+		r.onComplete(segment)
+	}
+	return nil
+}
+
+// Close closes the current segment.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.complete(time.Time{})
+}
+
+type segmentFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// sidecarSuffixes are the suffixes of files written next to segments.
+var sidecarSuffixes = []string{IndexSuffix, TimestampsSuffix}
+
+// prune removes the oldest files in dir, except keep, until they fit in maxSize. A segment's index and timestamps
+// are removed with it.
+func prune(dir string, maxSize int64, keep string) error {
+	var files []segmentFile
+	var total int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		total += info.Size()
+		for _, suffix := range sidecarSuffixes {
+			if strings.HasSuffix(path, suffix) {
+				return nil
+			}
+		}
+		size := info.Size()
+		for _, suffix := range sidecarSuffixes {
+			if sidecar, err := os.Stat(path + suffix); err == nil {
+				size += sidecar.Size()
+			}
+		}
+		files = append(files, segmentFile{path: path, size: size, modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the segments: %s", err)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].modTime.Equal(files[j].modTime) {
+			return files[i].path < files[j].path
+		}
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files {
+		if total <= maxSize {
+			break
+		}
+		if f.path == keep {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			return fmt.Errorf("failed to remove the segment: %s", err)
+		}
+		for _, suffix := range sidecarSuffixes {
+			if err := os.Remove(f.path + suffix); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s of the segment: %s", suffix, err)
+			}
+		}
+		total -= f.size
+	}
+	return nil
+}
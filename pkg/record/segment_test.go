@@ -0,0 +1,349 @@
+package record
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/webrtc/v3"
+)
+
+var (
+	testStart  = time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+	keyFrame   = []byte{0x00, 1, 2, 3}
+	interFrame = []byte{0x01, 4, 5}
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRecorderRotation(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	var completed []Segment
+	r, err := NewRecorder(dir, webrtc.MimeTypeVP8,
+		WithSegmentDuration(2*time.Second),
+		WithSegmentSize(12),
+		WithNameTemplate(`{{.Start.Format "150405"}}/{{.Index}}.vp8`),
+		OnSegmentComplete(func(s Segment) { completed = append(completed, s) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Frames every 500ms with a keyframe every second
+	for i := 0; i < 12; i++ {
+		frame := interFrame
+		if i%2 == 0 {
+			frame = keyFrame
+		}
+		if i == 0 {
+			// Frames before the first keyframe are dropped
+			if err := r.Write(interFrame, testStart); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if i == 9 {
+			// Large frames make the segment full before its duration
+			frame = append(append([]byte{}, interFrame...), make([]byte, 10)...)
+		}
+		if err := r.Write(frame, testStart.Add(time.Duration(i)*500*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Write(keyFrame, testStart); err != errClosed {
+		t.Fatalf("expected %v, but got %v", errClosed, err)
+	}
+
+	// Rotated by duration at 2s and 4s, and by size at 5s
+	expected := []struct {
+		start, end time.Duration
+		size       int64
+	}{
+		{0, 2 * time.Second, 14},
+		{2 * time.Second, 4 * time.Second, 14},
+		{4 * time.Second, 5 * time.Second, 17},
+		{5 * time.Second, 5500 * time.Millisecond, 7},
+	}
+	if len(completed) != len(expected) {
+		t.Fatalf("expected %d segments, but got %+v", len(expected), completed)
+	}
+	for i, e := range expected {
+		s := completed[i]
+		if s.Index != i || !s.Start.Equal(testStart.Add(e.start)) || !s.End.Equal(testStart.Add(e.end)) || s.Size != e.size {
+			t.Fatalf("expected the segment %d from %v to %v of %d bytes, but got %+v", i, e.start, e.end, e.size, s)
+		}
+		data, err := ioutil.ReadFile(s.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != s.Size || data[0] != keyFrame[0] {
+			t.Fatalf("expected the segment %d to start at a keyframe with %d bytes, but got %v", i, s.Size, data)
+		}
+	}
+	if completed[1].Path != filepath.Join(dir, "120002", "1.vp8") {
+		t.Fatalf("expected the name from the template, but got %s", completed[1].Path)
+	}
+}
+
+func TestRecorderMaxDiskUsage(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// A segment left by a previous recording
+	old := filepath.Join(dir, "old")
+	if err := ioutil.WriteFile(old, make([]byte, 8), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(old, testStart, testStart); err != nil {
+		t.Fatal(err)
+	}
+
+	var completed []Segment
+	r, err := NewRecorder(dir, webrtc.MimeTypeVP8,
+		WithSegmentDuration(time.Second),
+		WithMaxDiskUsage(10),
+		OnSegmentComplete(func(s Segment) { completed = append(completed, s) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if err := r.Write(keyFrame, testStart.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatal("expected the old segment to be pruned")
+	}
+	var total int64
+	for _, s := range completed[len(completed)-2:] {
+		info, err := os.Stat(s.Path)
+		if err != nil {
+			t.Fatalf("expected the last segments to be kept, but got %v", err)
+		}
+		total += info.Size()
+	}
+	if total != 8 {
+		t.Fatalf("expected 8 bytes of the last 2 segments, but got %d", total)
+	}
+	if _, err := os.Stat(completed[0].Path); !os.IsNotExist(err) {
+		t.Fatal("expected the oldest segment to be pruned")
+	}
+}
+
+func TestRecorderIndex(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	thumbnailer := NewThumbnailer(8)
+	var n int
+	src := thumbnailer.Transform()(video.ReaderFunc(func() (image.Image, func(), error) {
+		n++
+		img := image.NewGray(image.Rect(0, 0, 32, 16))
+		for i := range img.Pix {
+			img.Pix[i] = uint8(n * 50)
+		}
+		return img, func() {}, nil
+	}))
+	if thumbnailer.Thumbnail() != nil {
+		t.Fatal("expected no thumbnail before the first frame")
+	}
+
+	var completed []Segment
+	r, err := NewRecorder(dir, webrtc.MimeTypeVP8,
+		WithIndex(thumbnailer.Thumbnail),
+		OnSegmentComplete(func(s Segment) { completed = append(completed, s) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range [][]byte{keyFrame, interFrame, keyFrame} {
+		if _, _, err := src.Read(); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Write(frame, testStart.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(completed[0].IndexPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	entries, err := ReadIndex(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Offset != 0 || entries[1].Offset != 7 ||
+		!entries[1].Time.Equal(testStart.Add(2*time.Second)) {
+		t.Fatalf("expected the keyframes at 0 and 7, but got %+v", entries)
+	}
+
+	thumbnail, err := jpeg.Decode(bytes.NewReader(entries[1].Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if thumbnail.Bounds().Dx() != 8 || thumbnail.Bounds().Dy() != 4 {
+		t.Fatalf("expected the thumbnail of 8x4, but got %v", thumbnail.Bounds())
+	}
+	if r, _, _, _ := thumbnail.At(4, 2).RGBA(); r>>8 < 140 || r>>8 > 160 {
+		t.Fatalf("expected the thumbnail of the third frame, but got %d", r>>8)
+	}
+}
+
+func TestRecorderTimestamps(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	var completed []Segment
+	r, err := NewRecorder(dir, webrtc.MimeTypeVP8,
+		WithTimestamps(),
+		OnSegmentComplete(func(s Segment) { completed = append(completed, s) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A screen capture that only delivers frames on damage
+	for _, d := range []time.Duration{0, 33333 * time.Microsecond, 5 * time.Second, 5010 * time.Millisecond} {
+		if err := r.Write(keyFrame, testStart.Add(d)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(completed[0].TimestampsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "# timestamp format v2\n0\n33.333\n5000\n5010\n"; string(b) != expected {
+		t.Fatalf("expected the timestamps %q, but got %q", expected, b)
+	}
+}
+
+// testFrameWriter is a container that records frame capture times.
+type testFrameWriter struct {
+	bytes.Buffer
+	times []time.Time
+}
+
+func (w *testFrameWriter) WriteFrame(data []byte, captureTime time.Time) error {
+	w.times = append(w.times, captureTime)
+	_, err := w.Write(data)
+	return err
+}
+
+func (w *testFrameWriter) Close() error { return nil }
+
+func TestRecorderFrameWriter(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	w := &testFrameWriter{}
+	r, err := NewRecorder(dir, webrtc.MimeTypeVP8, WithOpener(func(string) (io.WriteCloser, error) { return w, nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, frame := range [][]byte{keyFrame, interFrame} {
+		if err := r.Write(frame, testStart.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(w.times) != 2 || !w.times[1].Equal(testStart.Add(time.Second)) || w.Len() != 7 {
+		t.Fatalf("expected the frames to be written with their capture times, but got %v", w.times)
+	}
+}
+
+type testEncodedReader struct {
+	buffers  []mediadevices.EncodedBuffer
+	released int
+}
+
+func (r *testEncodedReader) Read() (mediadevices.EncodedBuffer, func(), error) {
+	if len(r.buffers) == 0 {
+		return mediadevices.EncodedBuffer{}, func() {}, io.EOF
+	}
+	buffer := r.buffers[0]
+	r.buffers = r.buffers[1:]
+	return buffer, func() { r.released++ }, nil
+}
+
+func (r *testEncodedReader) Close() error { return nil }
+
+func TestRecorderWriteFrom(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	w := &testFrameWriter{}
+	r, err := NewRecorder(dir, webrtc.MimeTypeVP8, WithOpener(func(string) (io.WriteCloser, error) { return w, nil }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &testEncodedReader{buffers: []mediadevices.EncodedBuffer{
+		{Data: keyFrame, CaptureTime: testStart},
+		{Data: interFrame, CaptureTime: testStart.Add(2 * time.Second)},
+		{Data: interFrame, CaptureTime: testStart.Add(time.Second)},
+	}}
+	if err := r.WriteFrom(src); err != nil {
+		t.Fatalf("expected no error at the end of the frames, but got %v", err)
+	}
+	if len(w.times) != 3 || !w.times[2].Equal(testStart.Add(time.Second)) || src.released != 3 {
+		t.Fatalf("expected the frames to be written with their capture times, but got %v", w.times)
+	}
+}
+
+func TestPruneIndex(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	for i, name := range []string{"a", "b"} {
+		path := filepath.Join(dir, name)
+		for _, p := range []string{path, path + IndexSuffix} {
+			if err := ioutil.WriteFile(p, make([]byte, 4), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mod := testStart.Add(time.Duration(i) * time.Second)
+			if err := os.Chtimes(p, mod, mod); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := prune(dir, 10, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"a", "a" + IndexSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, p)); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be pruned", p)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b"+IndexSuffix)); err != nil {
+		t.Fatalf("expected the index of the kept segment, but got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package record
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"time"
+)
+
+// TimestampsSuffix is appended to a segment's path to get the path of its timestamps.
+const TimestampsSuffix = ".timestamps.txt"
+
+// FrameWriter is a segment file that takes frame capture times, e.g. a container that writes sample timestamps.
+// WithOpener files that implement it get WriteFrame instead of Write, so variable frame rate recordings, e.g. of
+// screen captures that only deliver frames on damage, play back at the real frame times.
+type FrameWriter interface {
+	io.WriteCloser
+	// WriteFrame writes an encoded frame captured at captureTime.
+	WriteFrame(data []byte, captureTime time.Time) error
+}
+
+// WithTimestamps writes frame timestamps next to each segment, see TimestampsSuffix. Raw frame segments have no
+// timestamps of their own, so this lets them be muxed at a variable frame rate, e.g. with
+// mkvmerge --timestamps 0:segment.h264.timestamps.txt segment.h264. The format is mkvmerge timestamp format v2:
+// milliseconds of each frame since the segment start.
+func WithTimestamps() Option {
+	return func(r *Recorder) error {
+		r.timestamped = true
+		return nil
+	}
+}
+
+// timestampsWriter writes timestamp lines and flushes after each frame, so an interrupted recording's timestamps
+// are still readable.
+type timestampsWriter struct {
+	file  io.WriteCloser
+	w     *bufio.Writer
+	start time.Time
+	line  []byte
+}
+
+func newTimestampsWriter(file io.WriteCloser, start time.Time) (*timestampsWriter, error) {
+	w := &timestampsWriter{file: file, w: bufio.NewWriter(file), start: start}
+	if _, err := w.w.WriteString("# timestamp format v2\n"); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *timestampsWriter) add(t time.Time) error {
+	ms := float64(t.Sub(w.start)) / float64(time.Millisecond)
+	w.line = append(strconv.AppendFloat(w.line[:0], ms, 'f', -1, 64), '\n')
+	if _, err := w.w.Write(w.line); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *timestampsWriter) close() error {
+	err := w.w.Flush()
+	if errClose := w.file.Close(); err == nil {
+		err = errClose
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/internal/logging"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const defaultJPEGQuality = 80
+
+var logger = logging.NewLogger("mediadevices/remote")
+
+// Agent serves this machine's devices to clients. Each connection records a device.
+type Agent struct {
+	drivers []driver.Driver
+	// driverMu serializes driver calls, since drivers aren't safe for concurrent use and connections
+	// query and open them concurrently.
+	driverMu sync.Mutex
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	closed    bool
+}
+
+// NewAgent creates an Agent of drivers. If drivers is empty, the driver manager's video and audio
+// recorders are served, which are queried when each client connects.
+func NewAgent(drivers ...driver.Driver) *Agent {
+	return &Agent{
+		drivers:   drivers,
+		listeners: make(map[net.Listener]struct{}),
+	}
+}
+
+func (a *Agent) devices() []driver.Driver {
+	if len(a.drivers) > 0 {
+		return a.drivers
+	}
+	m := driver.GetManager()
+	return append(m.Query(driver.FilterVideoRecorder()), m.Query(driver.FilterAudioRecorder())...)
+}
+
+// Serve serves clients from l until the agent is closed.
+func (a *Agent) Serve(l net.Listener) error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		l.Close()
+		return errClosed
+	}
+	a.listeners[l] = struct{}{}
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		delete(a.listeners, l)
+		a.mu.Unlock()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := a.ServeConn(conn); err != nil {
+				logger.Debugf("failed to serve %s: %s", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// Close stops listeners. Devices being recorded are closed when their clients disconnect.
+func (a *Agent) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	for l := range a.listeners {
+		l.Close()
+	}
+	return nil
+}
+
+// ServeConn serves a client on conn, e.g. a connection accepted on another transport, until it disconnects.
+// conn is closed when it returns.
+func (a *Agent) ServeConn(conn net.Conn) error {
+	defer conn.Close()
+	w := bufio.NewWriter(conn)
+
+	drivers := a.devices()
+	h := hello{Version: protocolVersion}
+	a.driverMu.Lock()
+	for _, d := range drivers {
+		h.Devices = append(h.Devices, Device{
+			Label:      d.Info().Label,
+			DeviceType: d.Info().DeviceType,
+			Properties: properties(d),
+		})
+	}
+	a.driverMu.Unlock()
+	if err := writeJSON(w, msgHello, h); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	var req open
+	if err := readJSON(conn, msgOpen, &req); err != nil {
+		return err
+	}
+	var d driver.Driver
+	for _, candidate := range drivers {
+		if candidate.Info().Label == req.Label {
+			d = candidate
+		}
+	}
+	if d == nil {
+		writeMessage(conn, msgError, []byte(errDeviceNotFound.Error()))
+		return errDeviceNotFound
+	}
+
+	a.driverMu.Lock()
+	err := d.Open()
+	a.driverMu.Unlock()
+	if err != nil {
+		err = fmt.Errorf("failed to open %s: %s", req.Label, err)
+		writeMessage(conn, msgError, []byte(err.Error()))
+		return err
+	}
+	var closeOnce sync.Once
+	closeDevice := func() {
+		closeOnce.Do(func() {
+			a.driverMu.Lock()
+			d.Close()
+			a.driverMu.Unlock()
+		})
+	}
+	defer closeDevice()
+
+	// The client stops recording by disconnecting, which reading the connection notices
+	disconnected := make(chan struct{})
+	go func() {
+		var b [1]byte
+		conn.Read(b[:])
+		close(disconnected)
+		// Unblock the device reader
+		closeDevice()
+	}()
+
+	switch recorder := d.(type) {
+	case driver.VideoRecorder:
+		err = a.sendVideo(w, recorder, req, disconnected)
+	case driver.AudioRecorder:
+		err = a.sendAudio(w, recorder, req, disconnected)
+	}
+	select {
+	case <-disconnected:
+		return nil
+	default:
+	}
+	if err != nil {
+		writeMessage(conn, msgError, []byte(err.Error()))
+	}
+	return err
+}
+
+func (a *Agent) sendVideo(w *bufio.Writer, recorder driver.VideoRecorder, req open, disconnected <-chan struct{}) error {
+	r, err := recorder.VideoRecord(req.Media)
+	if err != nil {
+		return err
+	}
+	if req.Quality <= 0 {
+		req.Quality = defaultJPEGQuality
+	}
+	i420 := video.KeepMetadata(video.ToI420(r), r)
+
+	var buff bytes.Buffer
+	for {
+		img, release, err := i420.Read()
+		if err != nil {
+			return err
+		}
+		metadata, _ := video.MetadataOf(i420)
+		if metadata.CaptureTime.IsZero() {
+			metadata.CaptureTime = time.Now()
+		}
+
+		buff.Reset()
+		err = encodeVideo(&buff, img.(*image.YCbCr), metadata, req.Compression, req.Quality)
+		release()
+		if err != nil {
+			return err
+		}
+		if err := a.write(w, msgVideo, buff.Bytes(), disconnected); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Agent) sendAudio(w *bufio.Writer, recorder driver.AudioRecorder, req open, disconnected <-chan struct{}) error {
+	r, err := recorder.AudioRecord(req.Media)
+	if err != nil {
+		return err
+	}
+	converter := wave.NewConverter(wave.TypeFloat32Interleaved, false)
+
+	var buff bytes.Buffer
+	for {
+		chunk, release, err := r.Read()
+		if err != nil {
+			return err
+		}
+		// The chunk is read after all its samples are captured
+		captureTime := time.Now().Add(-audioDuration(chunk))
+
+		if t := wave.TypeOf(chunk); t != wave.TypeInt16Interleaved && t != wave.TypeFloat32Interleaved {
+			chunk, err = converter.Convert(chunk)
+		}
+		if err == nil {
+			buff.Reset()
+			err = encodeAudio(&buff, chunk, captureTime)
+		}
+		release()
+		if err != nil {
+			return err
+		}
+		if err := a.write(w, msgAudio, buff.Bytes(), disconnected); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Agent) write(w *bufio.Writer, typ byte, payload []byte, disconnected <-chan struct{}) error {
+	select {
+	case <-disconnected:
+		return errClosed
+	default:
+	}
+	if err := writeMessage(w, typ, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// properties returns a copy of d's properties, and opens d to query them if it's closed, like GetUserMedia
+// does. Properties are copied since some adapters return their own slice.
+func properties(d driver.Driver) []prop.Media {
+	if d.Status() == driver.StateClosed {
+		if err := d.Open(); err != nil {
+			logger.Debugf("failed to open %s: %s", d.Info().Label, err)
+			return nil
+		}
+		defer d.Close()
+	}
+	return append([]prop.Media(nil), d.Properties()...)
+}
+
+func audioDuration(chunk wave.Audio) time.Duration {
+	info := chunk.ChunkInfo()
+	if info.SamplingRate <= 0 {
+		return 0
+	}
+	return time.Duration(info.Len) * time.Second / time.Duration(info.SamplingRate)
+}
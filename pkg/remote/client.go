@@ -0,0 +1,279 @@
+package remote
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"net"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+var errNotOpened = errors.New("remote: device is not opened")
+
+// Client records an agent's devices.
+type Client struct {
+	address     string
+	dial        func() (net.Conn, error)
+	compression Compression
+	quality     int
+	devices     []Device
+}
+
+// ClientOption configures Client.
+type ClientOption func(*Client)
+
+// WithCompression sets frame compression. The default is CompressionNone.
+func WithCompression(compression Compression) ClientOption {
+	return func(c *Client) {
+		c.compression = compression
+	}
+}
+
+// WithJPEGQuality sets CompressionJPEG quality in [1, 100]. The default is 80.
+func WithJPEGQuality(quality int) ClientOption {
+	return func(c *Client) {
+		c.quality = quality
+	}
+}
+
+// WithDialer replaces how the client connects to the agent, e.g. through a tunnel.
+func WithDialer(dial func() (net.Conn, error)) ClientOption {
+	return func(c *Client) {
+		c.dial = dial
+	}
+}
+
+// Dial connects to the agent at address on network, e.g. "tcp" or "unix", and gets its devices. Each recording
+// connects to the agent again.
+func Dial(network, address string, opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		address: address,
+		dial: func() (net.Conn, error) {
+			return net.Dial(network, address)
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	conn, h, err := c.connect()
+	if err != nil {
+		return nil, err
+	}
+	conn.Close()
+	c.devices = h.Devices
+	return c, nil
+}
+
+func (c *Client) connect() (net.Conn, hello, error) {
+	conn, err := c.dial()
+	if err != nil {
+		return nil, hello{}, fmt.Errorf("failed to connect to %s: %s", c.address, err)
+	}
+	var h hello
+	if err := readJSON(conn, msgHello, &h); err != nil {
+		conn.Close()
+		return nil, hello{}, err
+	}
+	if h.Version != protocolVersion {
+		conn.Close()
+		return nil, hello{}, errUnsupportedVersion
+	}
+	return conn, h, nil
+}
+
+// Devices returns the agent's devices at the time the client connected.
+func (c *Client) Devices() []Device {
+	return c.devices
+}
+
+// Register registers the agent's devices to the driver manager, with labels prefixed by the agent's
+// address, e.g. "10.0.0.2:7000/video0". Frames are I420, and audio chunks are Float32Interleaved unless
+// they're Int16Interleaved. The returned function unregisters them.
+func (c *Client) Register() (func(), error) {
+	var adapters []driver.Adapter
+	unregister := func() {
+		for _, a := range adapters {
+			driver.GetManager().Unregister(a)
+		}
+	}
+
+	for _, d := range c.devices {
+		base := &adapter{client: c, device: d}
+		var a driver.Adapter
+		switch d.DeviceType {
+		case driver.Microphone:
+			a = &microphone{base}
+		default:
+			a = &camera{base}
+		}
+		info := driver.Info{Label: c.address + "/" + d.Label, DeviceType: d.DeviceType}
+		if err := driver.GetManager().Register(a, info); err != nil {
+			unregister()
+			return nil, err
+		}
+		adapters = append(adapters, a)
+	}
+	return unregister, nil
+}
+
+// adapter is an agent device, which connects to the agent when it's opened.
+type adapter struct {
+	client *Client
+	device Device
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (a *adapter) Open() error {
+	conn, _, err := a.client.connect()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.conn = conn
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *adapter) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+// Properties returns the device's properties in the formats the client receives.
+func (a *adapter) Properties() []prop.Media {
+	properties := make([]prop.Media, len(a.device.Properties))
+	for i, p := range a.device.Properties {
+		if a.device.DeviceType == driver.Microphone {
+			if p.IsInterleaved && !p.IsFloat && p.SampleSize == 2 {
+				p.IsBigEndian = false
+			} else {
+				p.IsFloat, p.SampleSize, p.IsInterleaved, p.IsBigEndian = true, 4, true, false
+			}
+		} else {
+			p.FrameFormat = frame.FormatI420
+		}
+		properties[i] = p
+	}
+	return properties
+}
+
+// record asks the agent to record the device with the agent's properties that p was converted from.
+func (a *adapter) record(p prop.Media) (net.Conn, error) {
+	a.mu.Lock()
+	conn := a.conn
+	a.mu.Unlock()
+	if conn == nil {
+		return nil, errNotOpened
+	}
+
+	media := p
+	for i, converted := range a.Properties() {
+		if converted.Video == p.Video && converted.Audio == p.Audio {
+			media = a.device.Properties[i]
+			break
+		}
+	}
+	req := open{
+		Label:       a.device.Label,
+		Media:       media,
+		Compression: a.client.compression,
+		Quality:     a.client.quality,
+	}
+	if err := writeJSON(conn, msgOpen, req); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+type camera struct {
+	*adapter
+}
+
+func (c *camera) VideoRecord(p prop.Media) (video.Reader, error) {
+	conn, err := c.record(p)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	var buff []byte
+	var metadata video.Metadata
+	reader := video.ReaderFunc(func() (image.Image, func(), error) {
+		var typ byte
+		var err error
+		typ, buff, err = readMessage(r, buff)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		switch typ {
+		case msgVideo:
+		case msgError:
+			return nil, func() {}, fmt.Errorf("remote: %s", buff)
+		default:
+			return nil, func() {}, errUnexpectedMessage
+		}
+
+		// Raw frames refer to the buffer, which the next read reuses
+		img, m, err := decodeVideo(buff)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		metadata = m
+		return img, func() {}, nil
+	})
+	return video.NewMetadataReader(reader, func() video.Metadata {
+		return metadata
+	}), nil
+}
+
+type microphone struct {
+	*adapter
+}
+
+func (m *microphone) AudioRecord(p prop.Media) (audio.Reader, error) {
+	conn, err := m.record(p)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	var buff []byte
+	return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		var typ byte
+		var err error
+		typ, buff, err = readMessage(r, buff)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		switch typ {
+		case msgAudio:
+		case msgError:
+			return nil, func() {}, fmt.Errorf("remote: %s", buff)
+		default:
+			return nil, func() {}, errUnexpectedMessage
+		}
+
+		chunk, _, err := decodeAudio(buff)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return chunk, func() {}, nil
+	}), nil
+}
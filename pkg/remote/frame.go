@@ -0,0 +1,202 @@
+package remote
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const (
+	videoHeaderSize = 29
+	audioHeaderSize = 19
+)
+
+var (
+	errInvalidFrame    = errors.New("remote: invalid frame")
+	errUnsupportedType = errors.New("remote: unsupported audio type")
+)
+
+// encodeVideo writes an I420 frame message to buff. The header is capture time in Unix nanoseconds,
+// which is zero if it's unknown, sequence, compression, width and height.
+func encodeVideo(buff *bytes.Buffer, img *image.YCbCr, metadata video.Metadata, compression Compression, quality int) error {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	var header [videoHeaderSize]byte
+	binary.BigEndian.PutUint64(header[0:], uint64(unixNano(metadata.CaptureTime)))
+	binary.BigEndian.PutUint64(header[8:], metadata.Sequence)
+	header[16] = byte(compression)
+	binary.BigEndian.PutUint32(header[17:], uint32(width))
+	binary.BigEndian.PutUint32(header[21:], uint32(height))
+	buff.Write(header[:])
+
+	switch compression {
+	case CompressionJPEG:
+		return jpeg.Encode(buff, img, &jpeg.Options{Quality: quality})
+	case CompressionDeflate:
+		w, err := flate.NewWriter(buff, flate.BestSpeed)
+		if err != nil {
+			return err
+		}
+		if err := writePlanes(w, img); err != nil {
+			return err
+		}
+		return w.Close()
+	default:
+		return writePlanes(buff, img)
+	}
+}
+
+// writePlanes writes img's planes without stride padding.
+func writePlanes(w io.Writer, img *image.YCbCr) error {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	cw, ch := (width+1)/2, (height+1)/2
+	for y := 0; y < height; y++ {
+		i := img.YOffset(img.Rect.Min.X, img.Rect.Min.Y+y)
+		if _, err := w.Write(img.Y[i : i+width]); err != nil {
+			return err
+		}
+	}
+	for _, plane := range [][]byte{img.Cb, img.Cr} {
+		for y := 0; y < ch; y++ {
+			i := img.COffset(img.Rect.Min.X, img.Rect.Min.Y+2*y)
+			if _, err := w.Write(plane[i : i+cw]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeVideo decodes a frame payload into an I420 image.
+func decodeVideo(payload []byte) (image.Image, video.Metadata, error) {
+	if len(payload) < videoHeaderSize {
+		return nil, video.Metadata{}, errInvalidFrame
+	}
+	metadata := video.Metadata{
+		CaptureTime: fromUnixNano(int64(binary.BigEndian.Uint64(payload[0:]))),
+		Sequence:    binary.BigEndian.Uint64(payload[8:]),
+	}
+	compression := Compression(payload[16])
+	width := int(binary.BigEndian.Uint32(payload[17:]))
+	height := int(binary.BigEndian.Uint32(payload[21:]))
+	data := payload[videoHeaderSize:]
+
+	switch compression {
+	case CompressionJPEG:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		return img, metadata, err
+	case CompressionDeflate:
+		var err error
+		if data, err = ioutil.ReadAll(flate.NewReader(bytes.NewReader(data))); err != nil {
+			return nil, metadata, err
+		}
+	case CompressionNone:
+	default:
+		return nil, metadata, errInvalidFrame
+	}
+
+	cw, ch := (width+1)/2, (height+1)/2
+	ySize, cSize := width*height, cw*ch
+	if width <= 0 || height <= 0 || len(data) != ySize+2*cSize {
+		return nil, metadata, errInvalidFrame
+	}
+	return &image.YCbCr{
+		Y:              data[:ySize],
+		Cb:             data[ySize : ySize+cSize],
+		Cr:             data[ySize+cSize:],
+		YStride:        width,
+		CStride:        cw,
+		SubsampleRatio: image.YCbCrSubsampleRatio420,
+		Rect:           image.Rect(0, 0, width, height),
+	}, metadata, nil
+}
+
+// encodeAudio writes an audio chunk message, which is Int16Interleaved or Float32Interleaved, to buff. The
+// header is capture time, type, channels, sampling rate and length.
+func encodeAudio(buff *bytes.Buffer, chunk wave.Audio, captureTime time.Time) error {
+	info := chunk.ChunkInfo()
+	var header [audioHeaderSize]byte
+	binary.BigEndian.PutUint64(header[0:], uint64(unixNano(captureTime)))
+	header[8] = byte(wave.TypeOf(chunk))
+	binary.BigEndian.PutUint16(header[9:], uint16(info.Channels))
+	binary.BigEndian.PutUint32(header[11:], uint32(info.SamplingRate))
+	binary.BigEndian.PutUint32(header[15:], uint32(info.Len))
+	buff.Write(header[:])
+
+	var b [4]byte
+	switch c := chunk.(type) {
+	case *wave.Int16Interleaved:
+		for _, s := range c.Data {
+			binary.BigEndian.PutUint16(b[:], uint16(s))
+			buff.Write(b[:2])
+		}
+	case *wave.Float32Interleaved:
+		for _, s := range c.Data {
+			binary.BigEndian.PutUint32(b[:], math.Float32bits(s))
+			buff.Write(b[:])
+		}
+	default:
+		return errUnsupportedType
+	}
+	return nil
+}
+
+func decodeAudio(payload []byte) (wave.Audio, time.Time, error) {
+	if len(payload) < audioHeaderSize {
+		return nil, time.Time{}, errInvalidFrame
+	}
+	captureTime := fromUnixNano(int64(binary.BigEndian.Uint64(payload[0:])))
+	info := wave.ChunkInfo{
+		Channels:     int(binary.BigEndian.Uint16(payload[9:])),
+		SamplingRate: int(binary.BigEndian.Uint32(payload[11:])),
+		Len:          int(binary.BigEndian.Uint32(payload[15:])),
+	}
+	data := payload[audioHeaderSize:]
+	n := info.Len * info.Channels
+
+	switch wave.Type(payload[8]) {
+	case wave.TypeInt16Interleaved:
+		if len(data) != 2*n {
+			return nil, captureTime, errInvalidFrame
+		}
+		chunk := wave.NewInt16Interleaved(info)
+		for i := range chunk.Data {
+			chunk.Data[i] = int16(binary.BigEndian.Uint16(data[2*i:]))
+		}
+		return chunk, captureTime, nil
+	case wave.TypeFloat32Interleaved:
+		if len(data) != 4*n {
+			return nil, captureTime, errInvalidFrame
+		}
+		chunk := wave.NewFloat32Interleaved(info)
+		for i := range chunk.Data {
+			chunk.Data[i] = math.Float32frombits(binary.BigEndian.Uint32(data[4*i:]))
+		}
+		return chunk, captureTime, nil
+	default:
+		return nil, captureTime, errUnsupportedType
+	}
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(nsec int64) time.Time {
+	if nsec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nsec)
+}
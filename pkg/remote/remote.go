@@ -0,0 +1,142 @@
+// Package remote captures a machine's devices for another machine, e.g. a lightweight agent on an edge device
+// captures frames, and a server runs transforms and encoders. Agent serves the agent's drivers,
+// and Client registers them as the server's drivers, so the same readers and GetUserMedia are used on
+// both ends.
+//
+// The protocol runs on any reliable stream, e.g. TCP or a Unix socket. Each message is a 1 byte type, the payload
+// length as a big endian uint32, and the payload. The agent sends hello with devices when the client
+// connects, the client sends open with the device and properties to record, and the agent sends frames or
+// audio chunks until the client disconnects, or error if the device fails.
+package remote
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// protocolVersion is increased when messages change incompatibly.
+const protocolVersion = 1
+
+const (
+	msgHello byte = iota + 1
+	msgOpen
+	msgVideo
+	msgAudio
+	msgError
+
+	// maxMessageSize is the maximum payload size, which is enough for a raw 8K I420 frame.
+	maxMessageSize = 64 << 20
+)
+
+var (
+	errMessageTooLarge    = errors.New("remote: message too large")
+	errUnexpectedMessage  = errors.New("remote: unexpected message")
+	errDeviceNotFound     = errors.New("remote: device not found")
+	errUnsupportedVersion = errors.New("remote: unsupported protocol version")
+	errClosed             = errors.New("remote: connection has been closed")
+)
+
+// Compression is how frames are compressed on the wire.
+type Compression int
+
+// Compression values.
+const (
+	// CompressionNone sends raw I420 frames, which is for local networks or Unix sockets.
+	CompressionNone Compression = iota
+	// CompressionDeflate compresses I420 frames losslessly, which saves about half of camera frame bandwidth,
+	// and more for screens.
+	CompressionDeflate
+	// CompressionJPEG compresses frames into JPEG, which is lossy but uses a tenth of the bandwidth.
+	CompressionJPEG
+)
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionDeflate:
+		return "deflate"
+	case CompressionJPEG:
+		return "jpeg"
+	default:
+		return "unknown"
+	}
+}
+
+// Device is an agent device.
+type Device struct {
+	Label      string
+	DeviceType driver.DeviceType
+	Properties []prop.Media
+}
+
+type hello struct {
+	Version int
+	Devices []Device
+}
+
+type open struct {
+	Label       string
+	Media       prop.Media
+	Compression Compression
+	Quality     int
+}
+
+func writeMessage(w io.Writer, typ byte, payload []byte) error {
+	var header [5]byte
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func writeJSON(w io.Writer, typ byte, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeMessage(w, typ, payload)
+}
+
+// readMessage reads the next message into buff, which is grown if it's too small.
+func readMessage(r io.Reader, buff []byte) (byte, []byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, buff, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return 0, buff, errMessageTooLarge
+	}
+	if cap(buff) < int(size) {
+		buff = make([]byte, size)
+	}
+	buff = buff[:size]
+	if _, err := io.ReadFull(r, buff); err != nil {
+		return 0, buff, err
+	}
+	return header[0], buff, nil
+}
+
+func readJSON(r io.Reader, typ byte, v interface{}) error {
+	t, payload, err := readMessage(r, nil)
+	if err != nil {
+		return err
+	}
+	if t == msgError {
+		return fmt.Errorf("remote: %s", payload)
+	}
+	if t != typ {
+		return errUnexpectedMessage
+	}
+	return json.Unmarshal(payload, v)
+}
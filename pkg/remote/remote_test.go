@@ -0,0 +1,184 @@
+package remote
+
+import (
+	"bytes"
+	"image"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/driver/drivertest"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func queryLabel(t *testing.T, label string) driver.Driver {
+	drivers := driver.GetManager().Query(func(d driver.Driver) bool { return d.Info().Label == label })
+	if len(drivers) != 1 {
+		t.Fatalf("expected a driver of %s, but got %d", label, len(drivers))
+	}
+	return drivers[0]
+}
+
+func TestRemote(t *testing.T) {
+	start := time.Unix(1000, 0)
+	clock := drivertest.NewClock(start)
+	unregisterCamera, err := drivertest.NewCamera(clock, drivertest.WithCameraProperties(prop.Media{
+		Video: prop.Video{Width: 64, Height: 48, FrameFormat: frame.FormatYUYV, FrameRate: 30},
+	})).Register("agent-camera")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterCamera()
+	unregisterMicrophone, err := drivertest.NewMicrophone(clock).Register("agent-microphone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unregisterMicrophone()
+
+	cameraDriver := queryLabel(t, "agent-camera")
+	agent := NewAgent(cameraDriver, queryLabel(t, "agent-microphone"))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go agent.Serve(l)
+	defer agent.Close()
+
+	for _, compression := range []Compression{CompressionNone, CompressionDeflate, CompressionJPEG} {
+		compression := compression
+		t.Run(compression.String(), func(t *testing.T) {
+			client, err := Dial("tcp", l.Addr().String(), WithCompression(compression))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(client.Devices()); n != 2 {
+				t.Fatalf("expected 2 devices, but got %d", n)
+			}
+			unregister, err := client.Register()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer unregister()
+
+			d := queryLabel(t, l.Addr().String()+"/agent-camera")
+			if err := d.Open(); err != nil {
+				t.Fatal(err)
+			}
+			p := d.Properties()[0]
+			if p.FrameFormat != frame.FormatI420 || p.Width != 64 {
+				t.Fatalf("expected the I420 property, but got %v", p)
+			}
+			r, err := d.(driver.VideoRecorder).VideoRecord(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, _, err := r.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			yuv, ok := img.(*image.YCbCr)
+			if !ok || yuv.Rect.Dx() != 64 || yuv.Rect.Dy() != 48 {
+				t.Fatalf("expected a 64x48 YCbCr frame, but got %T %v", img, img.Bounds())
+			}
+			// The first frame's luma is 0
+			if y := yuv.Y[yuv.YOffset(10, 10)]; y > 2 {
+				t.Fatalf("expected the luma of the first frame, but got %d", y)
+			}
+			metadata, _ := video.MetadataOf(r)
+			if !metadata.CaptureTime.Equal(start) {
+				t.Fatalf("expected the capture time %v, but got %v", start, metadata.CaptureTime)
+			}
+
+			if err := d.Close(); err != nil {
+				t.Fatal(err)
+			}
+			status := func() driver.State {
+				agent.driverMu.Lock()
+				defer agent.driverMu.Unlock()
+				return cameraDriver.Status()
+			}
+			for deadline := time.Now().Add(5 * time.Second); status() != driver.StateClosed; {
+				if time.Now().After(deadline) {
+					t.Fatalf("expected the agent to close the camera, but got %s", status())
+				}
+				time.Sleep(time.Millisecond)
+			}
+		})
+	}
+
+	t.Run("Audio", func(t *testing.T) {
+		client, err := Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		unregister, err := client.Register()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unregister()
+
+		d := queryLabel(t, l.Addr().String()+"/agent-microphone")
+		if err := d.Open(); err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		r, err := d.(driver.AudioRecorder).AudioRecord(d.Properties()[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The agent starts recording asynchronously, so the clock is advanced until the chunk is delivered
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case <-done:
+					return
+				case <-time.After(time.Millisecond):
+					clock.Advance(20 * time.Millisecond)
+				}
+			}
+		}()
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := chunk.ChunkInfo()
+		if _, ok := chunk.(*wave.Float32Interleaved); !ok || info.Len != 960 || info.SamplingRate != 48000 {
+			t.Fatalf("expected 960 samples of float32, but got %T %v", chunk, info)
+		}
+	})
+}
+
+func TestEncodeAudio(t *testing.T) {
+	chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 3, Channels: 2, SamplingRate: 16000})
+	copy(chunk.Data, []int16{1, -2, 3, -4, 5, -32768})
+	captureTime := time.Unix(10, 5)
+
+	var buff bytes.Buffer
+	if err := encodeAudio(&buff, chunk, captureTime); err != nil {
+		t.Fatal(err)
+	}
+	decoded, decodedTime, err := decodeAudio(buff.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decodedTime.Equal(captureTime) {
+		t.Fatalf("expected %v, but got %v", captureTime, decodedTime)
+	}
+	i16, ok := decoded.(*wave.Int16Interleaved)
+	if !ok || i16.Size != chunk.Size {
+		t.Fatalf("expected an Int16Interleaved chunk, but got %T", decoded)
+	}
+	for i, s := range chunk.Data {
+		if i16.Data[i] != s {
+			t.Fatalf("expected %v, but got %v", chunk.Data, i16.Data)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package rtsp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pion/mediadevices"
+)
+
+const trackIDPrefix = "trackID="
+
+// conn is a client's RTSP connection. The client's sessions end when it's closed.
+type conn struct {
+	server *Server
+	nc     net.Conn
+	r      *bufio.Reader
+
+	// writeMu serializes responses and interleaved packets
+	writeMu  sync.Mutex
+	sessions map[string]*session
+}
+
+// session is a set of media a client sets up to play together.
+type session struct {
+	id         string
+	path       string
+	media      []Media
+	transports map[int]*transport
+
+	// done is closed to stop readers, and is nil until the session is played.
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// transport is how a media's packets are sent to the client.
+type transport struct {
+	// channel is the RTP packets' interleaved channel, which is used if udp is nil.
+	channel int
+	udp     *net.UDPConn
+	rtcp    *net.UDPConn
+}
+
+func (c *conn) serve() {
+	defer func() {
+		c.nc.Close()
+		for _, s := range c.sessions {
+			c.teardown(s)
+		}
+	}()
+
+	for {
+		req, err := readRequest(c.r)
+		if err != nil {
+			return
+		}
+		res := c.handle(req)
+		if err := c.write(res.marshal(req.header.Get("CSeq"))); err != nil {
+			return
+		}
+	}
+}
+
+func (c *conn) write(b []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.nc.Write(b)
+	return err
+}
+
+func (c *conn) handle(req *request) *response {
+	switch req.method {
+	case "OPTIONS":
+		res := newResponse(statusOK)
+		res.header.Set("Public", "OPTIONS, DESCRIBE, SETUP, PLAY, TEARDOWN, GET_PARAMETER, SET_PARAMETER")
+		return res
+	case "DESCRIBE":
+		return c.describe(req)
+	case "SETUP":
+		return c.setup(req)
+	case "PLAY":
+		return c.play(req)
+	case "TEARDOWN":
+		s, res := c.session(req)
+		if s != nil {
+			c.teardown(s)
+			delete(c.sessions, s.id)
+		}
+		return res
+	case "GET_PARAMETER", "SET_PARAMETER":
+		// Clients send them as keep-alives
+		_, res := c.session(req)
+		return res
+	default:
+		return newResponse(statusMethodNotAllowed)
+	}
+}
+
+// session returns the Session header's session. Keep-alives without the header are answered.
+func (c *conn) session(req *request) (*session, *response) {
+	id := strings.SplitN(req.header.Get("Session"), ";", 2)[0]
+	if id == "" {
+		return nil, newResponse(statusOK)
+	}
+	s, ok := c.sessions[id]
+	if !ok {
+		return nil, newResponse(statusSessionNotFound)
+	}
+	res := newResponse(statusOK)
+	res.header.Set("Session", s.id)
+	return s, res
+}
+
+// parsePath returns the stream path, and the media index if the URL has a media control.
+func parsePath(rawURL string) (string, int, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", 0, false
+	}
+	path := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(path, trackIDPrefix)
+	if i < 0 {
+		return path, -1, true
+	}
+	index, err := strconv.Atoi(path[i+len(trackIDPrefix):])
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.TrimSuffix(path[:i], "/"), index, true
+}
+
+func (c *conn) describe(req *request) *response {
+	path, _, ok := parsePath(req.url)
+	if !ok {
+		return newResponse(statusBadRequest)
+	}
+	media, ok := c.server.stream(path)
+	if !ok {
+		return newResponse(statusNotFound)
+	}
+
+	host := c.nc.LocalAddr().(*net.TCPAddr).IP
+	res := newResponse(statusOK)
+	res.header.Set("Content-Type", "application/sdp")
+	res.header.Set("Content-Base", strings.TrimSuffix(req.url, "/")+"/")
+	res.body = []byte(sessionDescription(path, host, media))
+	return res
+}
+
+func (c *conn) setup(req *request) *response {
+	path, index, ok := parsePath(req.url)
+	if !ok {
+		return newResponse(statusBadRequest)
+	}
+	media, ok := c.server.stream(path)
+	if !ok {
+		return newResponse(statusNotFound)
+	}
+	if index < 0 {
+		// The aggregate URL sets up the only media
+		index = 0
+	}
+	if index >= len(media) {
+		return newResponse(statusNotFound)
+	}
+
+	spec, err := parseTransport(req.header.Get("Transport"))
+	if err != nil {
+		return newResponse(statusUnsupportedTransport)
+	}
+
+	s, res := c.session(req)
+	if res.status != statusOK {
+		return res
+	}
+	if s == nil {
+		s = &session{
+			id:         strconv.FormatUint(uint64(rand.Uint32())<<32|uint64(rand.Uint32()), 16),
+			path:       path,
+			media:      media,
+			transports: make(map[int]*transport),
+		}
+		c.sessions[s.id] = s
+	} else if s.path != path {
+		return newResponse(statusBadRequest)
+	}
+	if old, ok := s.transports[index]; ok {
+		old.close()
+	}
+
+	var header string
+	t := &transport{channel: spec.interleaved[0]}
+	if spec.tcp {
+		header = fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", spec.interleaved[0], spec.interleaved[1])
+	} else {
+		if t.udp, t.rtcp, err = c.dialUDP(spec.clientPort); err != nil {
+			return newResponse(statusInternalServerError)
+		}
+		header = fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d;server_port=%d-%d",
+			spec.clientPort[0], spec.clientPort[1],
+			t.udp.LocalAddr().(*net.UDPAddr).Port, t.rtcp.LocalAddr().(*net.UDPAddr).Port)
+	}
+	s.transports[index] = t
+
+	res.header.Set("Transport", header)
+	res.header.Set("Session", fmt.Sprintf("%s;timeout=%d", s.id, sessionTimeout))
+	return res
+}
+
+// dialUDP connects to the client's RTP and RTCP ports from the RTSP connection's interface.
+func (c *conn) dialUDP(ports [2]int) (*net.UDPConn, *net.UDPConn, error) {
+	local := c.nc.LocalAddr().(*net.TCPAddr).IP
+	remote := c.nc.RemoteAddr().(*net.TCPAddr).IP
+	udp, err := net.DialUDP("udp", &net.UDPAddr{IP: local}, &net.UDPAddr{IP: remote, Port: ports[0]})
+	if err != nil {
+		return nil, nil, err
+	}
+	rtcp, err := net.DialUDP("udp", &net.UDPAddr{IP: local}, &net.UDPAddr{IP: remote, Port: ports[1]})
+	if err != nil {
+		udp.Close()
+		return nil, nil, err
+	}
+	return udp, rtcp, nil
+}
+
+func (c *conn) play(req *request) *response {
+	s, res := c.session(req)
+	if s == nil {
+		if res.status == statusOK {
+			return newResponse(statusSessionNotFound)
+		}
+		return res
+	}
+
+	if s.done != nil {
+		// Already playing, e.g. the client resumes without pausing
+		return res
+	}
+
+	var indexes []int
+	var readers []mediadevices.RTPReadCloser
+	for index := range s.transports {
+		m := s.media[index]
+		reader, err := m.Track.NewRTPReader(m.Codec.MimeType, rand.Uint32(), c.server.mtu)
+		if err != nil {
+			for _, r := range readers {
+				r.Close()
+			}
+			return newResponse(statusInternalServerError)
+		}
+		indexes = append(indexes, index)
+		readers = append(readers, reader)
+	}
+
+	s.done = make(chan struct{})
+	for i, reader := range readers {
+		s.wg.Add(1)
+		go c.send(s, s.done, reader, s.transports[indexes[i]])
+	}
+
+	res.header.Set("Range", "npt=0.000-")
+	return res
+}
+
+// send sends reader's packets until the session is torn down. The reader is closed by the same goroutine,
+// like the track does for peer connections.
+func (c *conn) send(s *session, done <-chan struct{}, reader mediadevices.RTPReadCloser, t *transport) {
+	defer s.wg.Done()
+	defer reader.Close()
+	buff := make([]byte, 4+c.server.mtu)
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		pkts, release, err := reader.Read()
+		if err != nil {
+			return
+		}
+		for _, pkt := range pkts {
+			n, err := pkt.MarshalTo(buff[4:])
+			if err != nil {
+				continue
+			}
+			if t.udp != nil {
+				// UDP errors are transient, e.g. the client hasn't opened the port yet
+				t.udp.Write(buff[4 : 4+n])
+				continue
+			}
+			buff[0] = interleavedMagic
+			buff[1] = byte(t.channel)
+			binary.BigEndian.PutUint16(buff[2:], uint16(n))
+			if err := c.write(buff[:4+n]); err != nil {
+				release()
+				return
+			}
+		}
+		release()
+	}
+}
+
+func (c *conn) teardown(s *session) {
+	if s.done != nil {
+		close(s.done)
+		s.done = nil
+	}
+	s.wg.Wait()
+	for _, t := range s.transports {
+		t.close()
+	}
+}
+
+func (t *transport) close() {
+	if t.udp != nil {
+		t.udp.Close()
+		t.rtcp.Close()
+	}
+}
@@ -0,0 +1,201 @@
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+const (
+	rtspVersion = "RTSP/1.0"
+	// interleavedMagic starts RTP and RTCP packets interleaved in the TCP connection.
+	interleavedMagic = '$'
+	maxContentLength = 1 << 16
+)
+
+var errInvalidRequest = errors.New("rtsp: invalid request")
+
+// request is an RTSP request.
+// Reference: https://tools.ietf.org/html/rfc2326#section-6
+type request struct {
+	method string
+	url    string
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// readRequest reads the next request from r. Interleaved client packets, e.g. RTCP receiver
+// reports, are skipped.
+func readRequest(r *bufio.Reader) (*request, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != interleavedMagic {
+			break
+		}
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		if _, err := r.Discard(int(header[2])<<8 | int(header[3])); err != nil {
+			return nil, err
+		}
+	}
+
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(line, " ")
+	if len(parts) != 3 || parts[2] != rtspVersion {
+		return nil, errInvalidRequest
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	req := &request{method: parts[0], url: parts[1], header: header}
+
+	if s := header.Get("Content-Length"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > maxContentLength {
+			return nil, errInvalidRequest
+		}
+		req.body = make([]byte, n)
+		if _, err := io.ReadFull(r, req.body); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// response is an RTSP response.
+type response struct {
+	status int
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+func newResponse(status int) *response {
+	return &response{status: status, header: make(textproto.MIMEHeader)}
+}
+
+func (res *response) marshal(cseq string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %d %s\r\n", rtspVersion, res.status, statusText(res.status))
+	fmt.Fprintf(&b, "CSeq: %s\r\n", cseq)
+	for key, values := range res.header {
+		for _, value := range values {
+			fmt.Fprintf(&b, "%s: %s\r\n", key, value)
+		}
+	}
+	if len(res.body) > 0 {
+		fmt.Fprintf(&b, "Content-Length: %d\r\n", len(res.body))
+	}
+	b.WriteString("\r\n")
+	b.Write(res.body)
+	return b.Bytes()
+}
+
+const (
+	statusOK                   = 200
+	statusBadRequest           = 400
+	statusNotFound             = 404
+	statusMethodNotAllowed     = 405
+	statusSessionNotFound      = 454
+	statusMethodNotValid       = 455
+	statusUnsupportedTransport = 461
+	statusInternalServerError  = 500
+)
+
+func statusText(status int) string {
+	switch status {
+	case statusOK:
+		return "OK"
+	case statusBadRequest:
+		return "Bad Request"
+	case statusNotFound:
+		return "Not Found"
+	case statusMethodNotAllowed:
+		return "Method Not Allowed"
+	case statusSessionNotFound:
+		return "Session Not Found"
+	case statusMethodNotValid:
+		return "Method Not Valid in This State"
+	case statusUnsupportedTransport:
+		return "Unsupported Transport"
+	default:
+		return "Internal Server Error"
+	}
+}
+
+// transportSpec is SETUP's Transport header.
+// Reference: https://tools.ietf.org/html/rfc2326#section-12.39
+type transportSpec struct {
+	tcp         bool
+	interleaved [2]int
+	clientPort  [2]int
+}
+
+func parseTransport(header string) (transportSpec, error) {
+	// The client may offer several transports in order of preference
+	for _, spec := range strings.Split(header, ",") {
+		params := strings.Split(strings.TrimSpace(spec), ";")
+		var t transportSpec
+		var ok bool
+		switch strings.ToUpper(params[0]) {
+		case "RTP/AVP", "RTP/AVP/UDP":
+		case "RTP/AVP/TCP":
+			t.tcp = true
+		default:
+			continue
+		}
+
+		multicast := false
+		for _, param := range params[1:] {
+			kv := strings.SplitN(param, "=", 2)
+			switch strings.ToLower(kv[0]) {
+			case "multicast":
+				multicast = true
+			case "interleaved":
+				if len(kv) == 2 {
+					t.interleaved, ok = parseRange(kv[1])
+				}
+			case "client_port":
+				if len(kv) == 2 {
+					t.clientPort, ok = parseRange(kv[1])
+				}
+			}
+		}
+		if multicast || (!ok && !t.tcp) {
+			continue
+		}
+		return t, nil
+	}
+	return transportSpec{}, errInvalidRequest
+}
+
+// parseRange parses "a-b", or "a" as "a-(a+1)".
+func parseRange(s string) ([2]int, bool) {
+	parts := strings.SplitN(s, "-", 2)
+	a, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return [2]int{}, false
+	}
+	b := a + 1
+	if len(parts) == 2 {
+		if b, err = strconv.Atoi(parts[1]); err != nil {
+			return [2]int{}, false
+		}
+	}
+	return [2]int{a, b}, true
+}
@@ -0,0 +1,283 @@
+package rtsp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3"
+)
+
+type mockReader struct {
+	seq    uint16
+	closed chan struct{}
+}
+
+func (r *mockReader) Read() ([]*rtp.Packet, func(), error) {
+	select {
+	case <-r.closed:
+		return nil, func() {}, io.EOF
+	case <-time.After(time.Millisecond):
+	}
+	r.seq++
+	return []*rtp.Packet{{Header: rtp.Header{Version: 2, PayloadType: 125, SequenceNumber: r.seq}, Payload: []byte{1}}}, func() {}, nil
+}
+
+func (r *mockReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+type mockTrack struct {
+	readers chan *mockReader
+}
+
+func (track *mockTrack) ID() string                { return "mock" }
+func (track *mockTrack) StreamID() string          { return "mock" }
+func (track *mockTrack) Close() error              { return nil }
+func (track *mockTrack) Kind() webrtc.RTPCodecType { return webrtc.RTPCodecTypeVideo }
+func (track *mockTrack) OnEnded(func(error))       {}
+
+func (track *mockTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	return webrtc.RTPCodecParameters{}, errors.New("not supported")
+}
+
+func (track *mockTrack) Unbind(ctx webrtc.TrackLocalContext) error {
+	return nil
+}
+
+func (track *mockTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (mediadevices.RTPReadCloser, error) {
+	r := &mockReader{closed: make(chan struct{})}
+	track.readers <- r
+	return r, nil
+}
+
+func (track *mockTrack) NewEncodedReader(string) (mediadevices.EncodedReadCloser, error) {
+	return nil, nil
+}
+
+func (track *mockTrack) NewEncodedIOReader(string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (track *mockTrack) Stats() mediadevices.TrackStats {
+	return mediadevices.TrackStats{}
+}
+
+type client struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	cseq int
+}
+
+func (c *client) do(method, url string, header ...string) (int, map[string]string, string) {
+	c.cseq++
+	fmt.Fprintf(c.conn, "%s %s RTSP/1.0\r\nCSeq: %d\r\n%s\r\n", method, url, c.cseq, strings.Join(append(header, ""), "\r\n"))
+
+	res, err := readResponse(c.r)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if res.header["Cseq"] != fmt.Sprint(c.cseq) {
+		c.t.Fatalf("expected CSeq %d, but got %s", c.cseq, res.header["Cseq"])
+	}
+	return res.status, res.header, res.body
+}
+
+type clientResponse struct {
+	status int
+	header map[string]string
+	body   string
+}
+
+func readResponse(r *bufio.Reader) (*clientResponse, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != interleavedMagic {
+			break
+		}
+		var header [4]byte
+		io.ReadFull(r, header[:])
+		r.Discard(int(binary.BigEndian.Uint16(header[2:])))
+	}
+
+	res := &clientResponse{header: make(map[string]string)}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fmt.Sscanf(line, "RTSP/1.0 %d", &res.status)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		kv := strings.SplitN(line, ": ", 2)
+		res.header[strings.Title(strings.ToLower(kv[0]))] = kv[1]
+	}
+	var n int
+	fmt.Sscan(res.header["Content-Length"], &n)
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	res.body = string(body)
+	return res, nil
+}
+
+func startServer(t *testing.T) (*Server, *mockTrack, string) {
+	track := &mockTrack{readers: make(chan *mockReader, 2)}
+	s := NewServer()
+	if err := s.Handle("/camera", Media{Track: track, Codec: codec.NewRTPH264Codec(90000)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Handle("camera", Media{Track: track, Codec: codec.NewRTPH264Codec(90000)}); err != errStreamExists {
+		t.Fatalf("expected %v, but got %v", errStreamExists, err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	return s, track, "rtsp://" + l.Addr().String() + "/camera"
+}
+
+func dialClient(t *testing.T, url string) *client {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(strings.SplitN(url, "/camera", 2)[0], "rtsp://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return &client{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func TestServerInterleaved(t *testing.T) {
+	s, track, url := startServer(t)
+	defer s.Close()
+	c := dialClient(t, url)
+	defer c.conn.Close()
+
+	if status, header, _ := c.do("OPTIONS", url); status != statusOK || !strings.Contains(header["Public"], "DESCRIBE") {
+		t.Fatalf("expected the methods, but got %d %v", status, header)
+	}
+	if status, _, _ := c.do("DESCRIBE", url+"2"); status != statusNotFound {
+		t.Fatalf("expected %d, but got %d", statusNotFound, status)
+	}
+
+	status, header, body := c.do("DESCRIBE", url)
+	if status != statusOK || header["Content-Base"] != url+"/" {
+		t.Fatalf("expected the description, but got %d %v", status, header)
+	}
+	for _, line := range []string{"m=video 0 RTP/AVP 125\r\n", "a=rtpmap:125 H264/90000\r\n", "a=control:trackID=0\r\n"} {
+		if !strings.Contains(body, line) {
+			t.Fatalf("expected %q in the SDP, but got %q", line, body)
+		}
+	}
+
+	status, header, _ = c.do("SETUP", url+"/trackID=0", "Transport: RTP/AVP/TCP;unicast;interleaved=0-1")
+	if status != statusOK || header["Transport"] != "RTP/AVP/TCP;unicast;interleaved=0-1" {
+		t.Fatalf("expected the interleaved transport, but got %d %v", status, header)
+	}
+	session := strings.SplitN(header["Session"], ";", 2)[0]
+	if status, _, _ := c.do("PLAY", url, "Session: "+session); status != statusOK {
+		t.Fatalf("expected to play, but got %d", status)
+	}
+
+	var header4 [4]byte
+	if _, err := io.ReadFull(c.r, header4[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header4[0] != interleavedMagic || header4[1] != 0 {
+		t.Fatalf("expected an interleaved packet of the channel 0, but got %v", header4)
+	}
+	pkt := make([]byte, binary.BigEndian.Uint16(header4[2:]))
+	io.ReadFull(c.r, pkt)
+	var p rtp.Packet
+	if err := p.Unmarshal(pkt); err != nil || p.PayloadType != 125 {
+		t.Fatalf("expected an RTP packet, but got %v", err)
+	}
+
+	if status, _, _ := c.do("TEARDOWN", url, "Session: "+session); status != statusOK {
+		t.Fatalf("expected to tear down, but got %d", status)
+	}
+	r := <-track.readers
+	select {
+	case <-r.closed:
+	default:
+		t.Fatal("expected the reader to be closed")
+	}
+	if status, _, _ := c.do("PLAY", url, "Session: "+session); status != statusSessionNotFound {
+		t.Fatalf("expected %d, but got %d", statusSessionNotFound, status)
+	}
+}
+
+func TestServerUDP(t *testing.T) {
+	s, track, url := startServer(t)
+	defer s.Close()
+	c := dialClient(t, url)
+	defer c.conn.Close()
+
+	rtpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rtpConn.Close()
+	rtpConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	port := rtpConn.LocalAddr().(*net.UDPAddr).Port
+
+	status, header, _ := c.do("SETUP", url, fmt.Sprintf("Transport: RTP/AVP;multicast,RTP/AVP;unicast;client_port=%d-%d", port, port+1))
+	if status != statusOK || !strings.HasPrefix(header["Transport"], fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d;server_port=", port, port+1)) {
+		t.Fatalf("expected the UDP transport, but got %d %v", status, header)
+	}
+	session := strings.SplitN(header["Session"], ";", 2)[0]
+	if status, _, _ := c.do("PLAY", url, "Session: "+session); status != statusOK {
+		t.Fatalf("expected to play, but got %d", status)
+	}
+
+	buff := make([]byte, 1500)
+	n, err := rtpConn.Read(buff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p rtp.Packet
+	if err := p.Unmarshal(buff[:n]); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session ends when the client disconnects
+	c.conn.Close()
+	r := <-track.readers
+	select {
+	case <-r.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the reader to be closed")
+	}
+}
+
+func TestParseTransport(t *testing.T) {
+	if _, err := parseTransport("RTP/AVP;multicast;port=5000-5001"); err == nil {
+		t.Fatal("expected the multicast transport to be unsupported")
+	}
+	spec, err := parseTransport("RTP/AVP/TCP;unicast;interleaved=2-3")
+	if err != nil || !spec.tcp || spec.interleaved != [2]int{2, 3} {
+		t.Fatalf("expected the interleaved channels 2-3, but got %v %v", spec, err)
+	}
+}
@@ -0,0 +1,235 @@
+// Package rtsp serves mediadevices tracks over RTSP, so players and NVR software, e.g. VLC and
+// IP camera network video recorders, can pull them without a WebRTC peer. Each client gets its own
+// RTP reader of the track, like a peer connection, so its stream starts with a keyframe.
+// Reference: https://tools.ietf.org/html/rfc2326
+package rtsp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/codec"
+)
+
+const (
+	defaultMTU = 1200
+	// sessionTimeout is the timeout in seconds told to clients. Sessions are actually kept
+	// until the connection is closed, but clients send keep-alives within it.
+	sessionTimeout = 60
+)
+
+var (
+	errEmptyMedia   = errors.New("rtsp: stream needs at least one media")
+	errStreamExists = errors.New("rtsp: stream already exists")
+	errClosed       = errors.New("rtsp: server has been closed")
+)
+
+// Media is a stream's track.
+type Media struct {
+	Track mediadevices.Track
+	// Codec is the codec the track is encoded with, e.g. the encoder params' RTPCodec. It's described
+	// in DESCRIBE's SDP.
+	Codec *codec.RTPCodec
+}
+
+// Server serves tracks' streams. The zero value isn't usable, use NewServer.
+type Server struct {
+	mtu int
+
+	mu        sync.Mutex
+	streams   map[string][]Media
+	listeners map[net.Listener]struct{}
+	conns     map[*conn]struct{}
+	closed    bool
+}
+
+// ServerOption configures Server.
+type ServerOption func(*Server)
+
+// WithMTU sets maximum RTP packet size. The default is 1200.
+func WithMTU(mtu int) ServerOption {
+	return func(s *Server) {
+		s.mtu = mtu
+	}
+}
+
+// NewServer creates a Server without streams.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		mtu:       defaultMTU,
+		streams:   make(map[string][]Media),
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[*conn]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Handle serves media at path, e.g. "camera" for rtsp://host:8554/camera. The server doesn't own the tracks.
+func (s *Server) Handle(path string, media ...Media) error {
+	if len(media) == 0 {
+		return errEmptyMedia
+	}
+	path = strings.Trim(path, "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.streams[path]; ok {
+		return errStreamExists
+	}
+	s.streams[path] = media
+	return nil
+}
+
+// Remove stops serving the stream at path. Clients playing it keep playing until they disconnect.
+func (s *Server) Remove(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, strings.Trim(path, "/"))
+}
+
+// Paths returns stream paths.
+func (s *Server) Paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	paths := make([]string, 0, len(s.streams))
+	for path := range s.streams {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func (s *Server) stream(path string) ([]Media, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	media, ok := s.streams[path]
+	return media, ok
+}
+
+// ListenAndServe listens on the TCP address, e.g. ":8554", and serves clients.
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve serves clients from l until the server is closed. l is closed when it returns.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return errClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	for {
+		nc, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return errClosed
+			}
+			return err
+		}
+
+		c := &conn{
+			server:   s,
+			nc:       nc,
+			r:        bufio.NewReader(nc),
+			sessions: make(map[string]*session),
+		}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		go func() {
+			c.serve()
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops listeners and disconnects clients.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	s.closed = true
+	for l := range s.listeners {
+		l.Close()
+	}
+	for c := range s.conns {
+		c.nc.Close()
+	}
+	return nil
+}
+
+// sessionDescription returns DESCRIBE's SDP, whose media are set up by control URLs "trackID=<index>".
+// Reference: https://tools.ietf.org/html/rfc2326#appendix-C
+func sessionDescription(path string, host net.IP, media []Media) string {
+	network := "IP4"
+	if host.To4() == nil {
+		network = "IP6"
+	}
+
+	lines := []string{
+		"v=0",
+		fmt.Sprintf("o=- %d 1 IN %s %s", rand.Uint32(), network, host),
+		"s=" + path,
+		fmt.Sprintf("c=IN %s %s", network, host),
+		"t=0 0",
+		"a=control:*",
+	}
+	for i, m := range media {
+		c := m.Codec
+		kind, name := splitMimeType(c.MimeType)
+		encoding := fmt.Sprintf("%s/%d", name, c.ClockRate)
+		if c.Channels > 0 {
+			encoding += fmt.Sprintf("/%d", c.Channels)
+		}
+		lines = append(lines,
+			fmt.Sprintf("m=%s 0 RTP/AVP %d", kind, c.PayloadType),
+			fmt.Sprintf("a=rtpmap:%d %s", c.PayloadType, encoding),
+		)
+		if c.SDPFmtpLine != "" {
+			lines = append(lines, fmt.Sprintf("a=fmtp:%d %s", c.PayloadType, c.SDPFmtpLine))
+		}
+		lines = append(lines, fmt.Sprintf("a=control:trackID=%d", i))
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// splitMimeType splits "video/H264" into "video" and "H264".
+func splitMimeType(mimeType string) (string, string) {
+	i := strings.Index(mimeType, "/")
+	if i < 0 {
+		return "video", mimeType
+	}
+	return strings.ToLower(mimeType[:i]), mimeType[i+1:]
+}
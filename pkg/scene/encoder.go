@@ -0,0 +1,190 @@
+package scene
+
+import (
+	"image"
+	"sync"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+const (
+	defaultThreshold       = 0.4
+	defaultStaticThreshold = 0.02
+	defaultMinInterval     = 10
+)
+
+// Option configures NewEncoderBuilder.
+type Option func(*encoderBuilder)
+
+// WithThreshold sets the minimum luma histogram distance between consecutive frames that counts as a scene
+// change, between 0 and 1. The default is 0.4. Lower thresholds also put keyframes on fades and fast pans.
+func WithThreshold(threshold float64) Option {
+	return func(b *encoderBuilder) {
+		b.threshold = threshold
+	}
+}
+
+// WithMinInterval sets the minimum number of frames between keyframes forced by scene changes, so flashes and
+// flickering sources don't turn every frame into a keyframe. The default is 10.
+func WithMinInterval(frames int) Option {
+	return func(b *encoderBuilder) {
+		b.minInterval = frames
+	}
+}
+
+// WithKeyFrameInterval forces a keyframe interval frames after the last one if the scene has changed since, or
+// after static frames if it hasn't. A scene is static while histogram distances stay below WithStaticThreshold.
+// Disable the encoder's own periodic keyframes, or set its interval longer than static, so keyframes are placed
+// here.
+func WithKeyFrameInterval(interval, static int) Option {
+	return func(b *encoderBuilder) {
+		b.interval = interval
+		b.staticInterval = static
+	}
+}
+
+// WithStaticThreshold sets the maximum luma histogram distance between consecutive frames of a static scene.
+// The default is 0.02, which tolerates a moving cursor and camera noise.
+func WithStaticThreshold(threshold float64) Option {
+	return func(b *encoderBuilder) {
+		b.staticThreshold = threshold
+	}
+}
+
+type encoderBuilder struct {
+	codec.VideoEncoderBuilder
+	threshold       float64
+	staticThreshold float64
+	minInterval     int
+	interval        int
+	staticInterval  int
+}
+
+// NewEncoderBuilder wraps builder to force keyframes on scene changes, and, if WithKeyFrameInterval is set, to
+// place periodic keyframes based on motion. Only some pixels are sampled to keep it cheap.
+func NewEncoderBuilder(builder codec.VideoEncoderBuilder, opts ...Option) codec.VideoEncoderBuilder {
+	b := &encoderBuilder{
+		VideoEncoderBuilder: builder,
+		threshold:           defaultThreshold,
+		staticThreshold:     defaultStaticThreshold,
+		minInterval:         defaultMinInterval,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *encoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	e := &encoder{
+		builder:  b,
+		mimeType: b.RTPCodec().MimeType,
+		r:        r,
+	}
+
+	// Read looks one frame ahead so keyframes can be forced before the frame is encoded, since encoders can't be
+	// controlled while they're reading.
+	ahead := video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+		e.mu.Lock()
+		img, release := e.next, e.release
+		e.next, e.release = nil, nil
+		e.mu.Unlock()
+		if img == nil {
+			return r.Read()
+		}
+		return img, release, nil
+	}), r)
+
+	rc, err := b.VideoEncoderBuilder.BuildVideoEncoder(ahead, p)
+	if err != nil {
+		return nil, err
+	}
+	e.ReadCloser = rc
+
+	return e, nil
+}
+
+type encoder struct {
+	codec.EncoderWrapper
+	builder  *encoderBuilder
+	mimeType string
+	r        video.Reader
+
+	mu      sync.Mutex
+	next    image.Image
+	release func()
+
+	prev       histogram
+	prevBounds image.Rectangle
+	hasPrev    bool
+	// sinceKeyFrame is how many frames the next frame is from the last keyframe.
+	sinceKeyFrame int
+	moving        bool
+}
+
+func (e *encoder) Read() ([]byte, func(), error) {
+	img, release, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	if e.place(img) {
+		if err := e.ReadCloser.ForceKeyFrame(); err != nil {
+			release()
+			return nil, func() {}, err
+		}
+	}
+
+	e.mu.Lock()
+	e.next, e.release = img, release
+	e.mu.Unlock()
+
+	b, encodedRelease, err := e.ReadCloser.Read()
+	if err != nil {
+		return b, encodedRelease, err
+	}
+	if len(b) > 0 {
+		if codec.IsKeyFrame(e.mimeType, b) {
+			e.sinceKeyFrame, e.moving = 1, false
+		} else {
+			e.sinceKeyFrame++
+		}
+	}
+	return b, encodedRelease, nil
+}
+
+// place reports whether img should be encoded as a keyframe.
+func (e *encoder) place(img image.Image) bool {
+	h := newHistogram(img, sampleStep)
+	d, bounds := 0.0, img.Bounds()
+	if e.hasPrev && bounds == e.prevBounds {
+		d = h.distance(&e.prev)
+	}
+	cut := e.hasPrev && (bounds != e.prevBounds || d >= e.builder.threshold)
+	e.prev, e.prevBounds, e.hasPrev = h, bounds, true
+
+	if d >= e.builder.staticThreshold {
+		e.moving = true
+	}
+	if cut && e.sinceKeyFrame >= e.builder.minInterval {
+		return true
+	}
+	if e.builder.interval <= 0 {
+		return false
+	}
+	if e.moving {
+		return e.sinceKeyFrame >= e.builder.interval
+	}
+	return e.builder.staticInterval > 0 && e.sinceKeyFrame >= e.builder.staticInterval
+}
+
+func (e *encoder) Close() error {
+	e.mu.Lock()
+	if e.release != nil {
+		e.release()
+	}
+	e.next, e.release = nil, nil
+	e.mu.Unlock()
+	return e.ReadCloser.Close()
+}
@@ -0,0 +1,76 @@
+// Package scene places video keyframes based on frame content. It detects scene changes from the distance between
+// luma histograms of consecutive frames and forces a keyframe at each cut, so the new scene isn't predicted from
+// the old one. With WithKeyFrameInterval it also controls periodic keyframes, stretching the interval while the
+// scene is static, e.g. a slide in a screen share, to spend bitrate on frames that change.
+package scene
+
+import (
+	"image"
+	"image/color"
+)
+
+const (
+	histogramBins = 64
+	// sampleStep is the distance between sampled pixels in both directions.
+	sampleStep = 4
+)
+
+// histogram is a normalized luma histogram of a frame.
+type histogram [histogramBins]float64
+
+// newHistogram samples the luma of img every step pixels in both directions.
+func newHistogram(img image.Image, step int) histogram {
+	var (
+		h      histogram
+		counts [histogramBins]int
+		n      int
+	)
+	add := func(luma uint8) {
+		counts[int(luma)*histogramBins/256]++
+		n++
+	}
+
+	bounds := img.Bounds()
+	switch v := img.(type) {
+	case *image.YCbCr:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+			for x := bounds.Min.X; x < bounds.Max.X; x += step {
+				add(v.Y[v.YOffset(x, y)])
+			}
+		}
+	case *image.Gray:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+			for x := bounds.Min.X; x < bounds.Max.X; x += step {
+				add(v.Pix[v.PixOffset(x, y)])
+			}
+		}
+	default:
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += step {
+			for x := bounds.Min.X; x < bounds.Max.X; x += step {
+				add(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+			}
+		}
+	}
+
+	if n == 0 {
+		return h
+	}
+	for i, c := range counts {
+		h[i] = float64(c) / float64(n)
+	}
+	return h
+}
+
+// distance returns the total variation distance between the histograms: 0 for identical luma distributions, and
+// 1 for frames with no luma in common.
+func (h *histogram) distance(other *histogram) float64 {
+	var d float64
+	for i := range h {
+		diff := h[i] - other[i]
+		if diff < 0 {
+			diff = -diff
+		}
+		d += diff
+	}
+	return d / 2
+}
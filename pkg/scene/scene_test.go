@@ -0,0 +1,161 @@
+package scene
+
+import (
+	"image"
+	"io"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// testImage returns a frame of the given luma. Moved frames have white rows 4 to 7, a quarter of the sampled
+// pixels.
+func testImage(luma uint8, moved bool) image.Image {
+	img := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	for i := range img.Y {
+		img.Y[i] = luma
+	}
+	if moved {
+		for i := 4 * img.YStride; i < 8*img.YStride; i++ {
+			img.Y[i] = 255
+		}
+	}
+	return img
+}
+
+func TestHistogramDistance(t *testing.T) {
+	dark, bright := newHistogram(testImage(16, false), sampleStep), newHistogram(testImage(235, false), sampleStep)
+	if d := dark.distance(&dark); d != 0 {
+		t.Fatalf("expected the distance of the same frames to be 0, but got %f", d)
+	}
+	if d := dark.distance(&bright); d != 1 {
+		t.Fatalf("expected the distance of the frames without common luma to be 1, but got %f", d)
+	}
+
+	half := testImage(16, false).(*image.YCbCr)
+	for i := 0; i < len(half.Y)/2; i++ {
+		half.Y[i] = 235
+	}
+	h := newHistogram(half, sampleStep)
+	if d := dark.distance(&h); d != 0.5 {
+		t.Fatalf("expected the distance of the half changed frame to be 0.5, but got %f", d)
+	}
+}
+
+// testEncoderBuilder encodes frames to VP8 frames whose second byte is the frame's luma. The first frame and
+// forced frames are keyframes.
+type testEncoderBuilder struct{}
+
+func (b *testEncoderBuilder) RTPCodec() *codec.RTPCodec {
+	return codec.NewRTPVP8Codec(90000)
+}
+
+func (b *testEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	return &testEncoder{r: r, keyFrame: true}, nil
+}
+
+type testEncoder struct {
+	r        video.Reader
+	keyFrame bool
+}
+
+func (e *testEncoder) Read() ([]byte, func(), error) {
+	img, release, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	defer release()
+
+	var frameType byte = 1
+	if e.keyFrame {
+		frameType = 0
+		e.keyFrame = false
+	}
+	return []byte{frameType, img.(*image.YCbCr).Y[0]}, func() {}, nil
+}
+
+func (e *testEncoder) Close() error { return nil }
+
+func (e *testEncoder) SetBitRate(int) error { return nil }
+
+func (e *testEncoder) ForceKeyFrame() error {
+	e.keyFrame = true
+	return nil
+}
+
+func TestEncoderBuilder(t *testing.T) {
+	testCases := map[string]struct {
+		opts  []Option
+		lumas []uint8
+		// moving is set if the rows change on every frame
+		moving    bool
+		keyFrames []int
+	}{
+		"Cut": {
+			lumas:     []uint8{16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 235, 235, 235},
+			keyFrames: []int{0, 12},
+		},
+		"CutInMinInterval": {
+			opts:      []Option{WithMinInterval(4)},
+			lumas:     []uint8{16, 16, 235, 235, 235, 16, 16, 16},
+			keyFrames: []int{0, 5},
+		},
+		"StaticScene": {
+			opts:      []Option{WithKeyFrameInterval(3, 6)},
+			lumas:     []uint8{16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16, 16},
+			keyFrames: []int{0, 6, 12},
+		},
+		"MovingScene": {
+			opts:      []Option{WithKeyFrameInterval(3, 6)},
+			lumas:     []uint8{16, 16, 16, 16, 16, 16, 16, 16, 16},
+			moving:    true,
+			keyFrames: []int{0, 3, 6},
+		},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			n := 0
+			r := video.ReaderFunc(func() (image.Image, func(), error) {
+				if n == len(testCase.lumas) {
+					return nil, func() {}, io.EOF
+				}
+				n++
+				return testImage(testCase.lumas[n-1], testCase.moving && n%2 == 0), func() {}, nil
+			})
+			e, err := NewEncoderBuilder(&testEncoderBuilder{}, testCase.opts...).BuildVideoEncoder(r, prop.Media{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer e.Close()
+
+			var keyFrames []int
+			for i := range testCase.lumas {
+				b, _, err := e.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if b[1] != testCase.lumas[i] {
+					t.Fatalf("expected the frame %d to have the luma %d, but got %d", i, testCase.lumas[i], b[1])
+				}
+				if b[0]&0x01 == 0 {
+					keyFrames = append(keyFrames, i)
+				}
+			}
+			if _, _, err := e.Read(); err != io.EOF {
+				t.Fatalf("expected EOF, but got %v", err)
+			}
+
+			if len(keyFrames) != len(testCase.keyFrames) {
+				t.Fatalf("expected the keyframes %v, but got %v", testCase.keyFrames, keyFrames)
+			}
+			for i := range keyFrames {
+				if keyFrames[i] != testCase.keyFrames[i] {
+					t.Fatalf("expected the keyframes %v, but got %v", testCase.keyFrames, keyFrames)
+				}
+			}
+		})
+	}
+}
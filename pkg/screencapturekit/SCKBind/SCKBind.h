@@ -0,0 +1,89 @@
+// MIT License
+//
+// Copyright (c) 2019-2020 Pion
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+#pragma once
+
+#include <stddef.h>
+#include <stdint.h>
+
+#define MAX_CONTENTS                     256
+#define MAX_CONTENT_NAME_CHARS           128
+
+typedef const char* STATUS;
+static STATUS STATUS_OK                       = (STATUS) NULL;
+static STATUS STATUS_NULL_ARG                 = (STATUS) "One of the arguments was null";
+static STATUS STATUS_UNAVAILABLE              = (STATUS) "ScreenCaptureKit requires macOS 13 or later";
+static STATUS STATUS_PERMISSION_DENIED        = (STATUS) "Failed to get shareable content, screen recording permission may be denied";
+static STATUS STATUS_CONTENT_NOT_FOUND        = (STATUS) "Failed to find the content";
+static STATUS STATUS_STREAM_START_FAILED      = (STATUS) "Failed to start stream";
+
+typedef enum SCKBindContentType {
+    SCKBindContentTypeDisplay,
+    SCKBindContentTypeApplication,
+    SCKBindContentTypeWindow,
+} SCKBindContentType;
+
+// SCKBindContent is a shareable content. id is a display ID for displays, a process ID for applications,
+// and a window ID for windows. name is a bundle identifier for applications, and a title for windows.
+typedef struct {
+    SCKBindContentType type;
+    uint32_t id;
+    char name[MAX_CONTENT_NAME_CHARS + 1];
+    int width, height;
+} SCKBindContent, *PSCKBindContent;
+
+typedef struct {
+    SCKBindContentType type;
+    uint32_t id;
+
+    // Applications are given by bundle identifier
+    const char **ppExcludedApplications;
+    int numExcludedApplications;
+    const uint32_t *pExcludedWindows;
+    int numExcludedWindows;
+
+    int width, height;
+    int frameRate;
+    int showsCursor;
+
+    int capturesAudio;
+    int sampleRate;
+    int channelCount;
+} SCKBindStreamConfig;
+
+// SCKBindVideoCallback receives a frame in RGBA format. Frames are only delivered when the content changes.
+typedef void (*SCKBindVideoCallback)(void *userData, void *buf, int width, int height);
+// SCKBindAudioCallback receives non-interleaved float32 samples.
+typedef void (*SCKBindAudioCallback)(void *userData, void *buf, int samples, int channels);
+
+typedef struct SCKBindStream SCKBindStream, *PSCKBindStream;
+
+int SCKBindAvailable();
+
+// SCKBindContents returns a list of shareable contents. The result array is pointing to a static
+// memory. The caller is expected to not hold on to the address for a long time and make a copy.
+// Everytime this function gets called, the array will be overwritten and the memory will be reused.
+STATUS SCKBindContents(PSCKBindContent*, int*);
+
+STATUS SCKBindStreamOpen(SCKBindStreamConfig, SCKBindVideoCallback, SCKBindAudioCallback, void*, PSCKBindStream*);
+STATUS SCKBindStreamClose(PSCKBindStream*);
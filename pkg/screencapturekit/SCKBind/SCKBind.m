@@ -0,0 +1,430 @@
+// MIT License
+//
+// Copyright (c) 2019-2020 Pion
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+// Naming Convention (let "name" as an actual variable name):
+//   - mName: "name" is a member of an Objective C object
+//   - pName: "name" is a C pointer
+//   - refName: "name" is an Objective C object reference
+
+#import <Foundation/Foundation.h>
+#import <CoreMedia/CoreMedia.h>
+#import <CoreVideo/CoreVideo.h>
+#import <ScreenCaptureKit/ScreenCaptureKit.h>
+#import "SCKBind.h"
+#include <string.h>
+
+#define CHK(condition, status) \
+    do { \
+        if(!(condition)) { \
+            retStatus = status; \
+            goto cleanup; \
+        } \
+    } while(0)
+
+API_AVAILABLE(macos(13.0))
+@interface StreamOutput : NSObject<SCStreamOutput>
+
+@property (readonly) SCKBindVideoCallback mVideoCallback;
+@property (readonly) SCKBindAudioCallback mAudioCallback;
+@property (readonly) void *mPUserData;
+
+@end
+
+@implementation StreamOutput
+
+- (id) init: (SCKBindVideoCallback) videoCallback
+withAudioCallback: (SCKBindAudioCallback) audioCallback
+ withUserData: (void*) pUserData {
+    self = [super init];
+    _mVideoCallback = videoCallback;
+    _mAudioCallback = audioCallback;
+    _mPUserData = pUserData;
+
+    return self;
+}
+
+- (void)stream:(SCStream *)stream
+didOutputSampleBuffer:(CMSampleBufferRef)sampleBuffer
+        ofType:(SCStreamOutputType)type {
+    if (!CMSampleBufferIsValid(sampleBuffer) || !CMSampleBufferDataIsReady(sampleBuffer)) {
+        return;
+    }
+
+    switch (type) {
+        case SCStreamOutputTypeScreen:
+            [self onVideo: sampleBuffer];
+            break;
+        case SCStreamOutputTypeAudio:
+            [self onAudio: sampleBuffer];
+            break;
+    }
+}
+
+- (void)onVideo:(CMSampleBufferRef)sampleBuffer {
+    // Idle frames, which are sent when the content hasn't been changed, don't have images
+    CFArrayRef attachments = CMSampleBufferGetSampleAttachmentsArray(sampleBuffer, false);
+    if (attachments == NULL || CFArrayGetCount(attachments) == 0) {
+        return;
+    }
+    NSDictionary *refAttachment = (NSDictionary *) CFArrayGetValueAtIndex(attachments, 0);
+    NSNumber *refStatus = refAttachment[SCStreamFrameInfoStatus];
+    if (refStatus == nil || refStatus.integerValue != SCFrameStatusComplete) {
+        return;
+    }
+
+    CVImageBufferRef imageBuffer = CMSampleBufferGetImageBuffer(sampleBuffer);
+    if (imageBuffer == NULL) {
+        return;
+    }
+
+    imageBuffer = CVBufferRetain(imageBuffer);
+    CVReturn ret = CVPixelBufferLockBaseAddress(imageBuffer, kCVPixelBufferLock_ReadOnly);
+    if (ret != kCVReturnSuccess) {
+        CVBufferRelease(imageBuffer);
+        return;
+    }
+
+    int width = (int) CVPixelBufferGetWidth(imageBuffer);
+    int height = (int) CVPixelBufferGetHeight(imageBuffer);
+    size_t stride = CVPixelBufferGetBytesPerRow(imageBuffer);
+    uint8_t *pSrc = CVPixelBufferGetBaseAddress(imageBuffer);
+    uint8_t *pDst = malloc(width * height * 4);
+
+    // BGRA to RGBA
+    for (int y = 0; y < height; y++) {
+        uint8_t *pSrcRow = pSrc + y * stride;
+        uint8_t *pDstRow = pDst + y * width * 4;
+        for (int x = 0; x < width * 4; x += 4) {
+            pDstRow[x + 0] = pSrcRow[x + 2];
+            pDstRow[x + 1] = pSrcRow[x + 1];
+            pDstRow[x + 2] = pSrcRow[x + 0];
+            pDstRow[x + 3] = 0xFF;
+        }
+    }
+
+    CVPixelBufferUnlockBaseAddress(imageBuffer, kCVPixelBufferLock_ReadOnly);
+    CVBufferRelease(imageBuffer);
+
+    _mVideoCallback(_mPUserData, pDst, width, height);
+    free(pDst);
+}
+
+- (void)onAudio:(CMSampleBufferRef)sampleBuffer {
+    CMFormatDescriptionRef format = CMSampleBufferGetFormatDescription(sampleBuffer);
+    const AudioStreamBasicDescription *pDesc = CMAudioFormatDescriptionGetStreamBasicDescription(format);
+    if (pDesc == NULL || pDesc->mFormatID != kAudioFormatLinearPCM ||
+        !(pDesc->mFormatFlags & kAudioFormatFlagIsFloat) ||
+        !(pDesc->mFormatFlags & kAudioFormatFlagIsNonInterleaved)) {
+        return;
+    }
+
+    size_t bufferListSize;
+    CMSampleBufferGetAudioBufferListWithRetainedBlockBuffer(
+        sampleBuffer, &bufferListSize, NULL, 0, NULL, NULL, 0, NULL);
+    AudioBufferList *pBufferList = malloc(bufferListSize);
+    CMBlockBufferRef blockBuffer = NULL;
+    OSStatus status = CMSampleBufferGetAudioBufferListWithRetainedBlockBuffer(
+        sampleBuffer, NULL, pBufferList, bufferListSize, NULL, NULL,
+        kCMSampleBufferFlag_AudioBufferList_Assure16ByteAlignment, &blockBuffer);
+    if (status != noErr) {
+        free(pBufferList);
+        return;
+    }
+
+    int channels = (int) pBufferList->mNumberBuffers;
+    int samples = (int) CMSampleBufferGetNumSamples(sampleBuffer);
+    float *pDst = malloc(sizeof(float) * samples * channels);
+    for (int ch = 0; ch < channels; ch++) {
+        memcpy(pDst + ch * samples, pBufferList->mBuffers[ch].mData, sizeof(float) * samples);
+    }
+
+    CFRelease(blockBuffer);
+    free(pBufferList);
+
+    _mAudioCallback(_mPUserData, pDst, samples, channels);
+    free(pDst);
+}
+
+@end
+
+struct SCKBindStream {
+    id refStream;
+    id refOutput;
+};
+
+int SCKBindAvailable() {
+    if (@available(macOS 13.0, *)) {
+        return 1;
+    }
+    return 0;
+}
+
+API_AVAILABLE(macos(13.0))
+static SCShareableContent* getShareableContent() {
+    __block SCShareableContent *refContent = nil;
+    dispatch_semaphore_t sem = dispatch_semaphore_create(0);
+    [SCShareableContent getShareableContentExcludingDesktopWindows: YES
+                                               onScreenWindowsOnly: YES
+                                                 completionHandler: ^(SCShareableContent *content, NSError *error) {
+        if (error == nil) {
+            refContent = [content retain];
+        }
+        dispatch_semaphore_signal(sem);
+    }];
+    dispatch_semaphore_wait(sem, DISPATCH_TIME_FOREVER);
+    dispatch_release(sem);
+    return [refContent autorelease];
+}
+
+static void copyName(char *pDst, NSString *refName) {
+    const char *pName = refName != nil ? refName.UTF8String : "";
+    strncpy(pDst, pName, MAX_CONTENT_NAME_CHARS);
+    pDst[MAX_CONTENT_NAME_CHARS] = '\0';
+}
+
+STATUS SCKBindContents(PSCKBindContent *ppContents, int *pLen) {
+    static SCKBindContent contents[MAX_CONTENTS];
+    STATUS retStatus = STATUS_OK;
+    NSAutoreleasePool *refPool = [[NSAutoreleasePool alloc] init];
+    CHK(ppContents != NULL && pLen != NULL, STATUS_NULL_ARG);
+    CHK(SCKBindAvailable(), STATUS_UNAVAILABLE);
+
+    if (@available(macOS 13.0, *)) {
+        SCShareableContent *refContent = getShareableContent();
+        CHK(refContent != nil, STATUS_PERMISSION_DENIED);
+
+        int i = 0;
+        PSCKBindContent pContent;
+        for (SCDisplay *refDisplay in refContent.displays) {
+            if (i >= MAX_CONTENTS) {
+                break;
+            }
+            pContent = contents + i++;
+            pContent->type = SCKBindContentTypeDisplay;
+            pContent->id = refDisplay.displayID;
+            copyName(pContent->name, [NSString stringWithFormat: @"%u", refDisplay.displayID]);
+            pContent->width = (int) refDisplay.width;
+            pContent->height = (int) refDisplay.height;
+        }
+
+        for (SCRunningApplication *refApp in refContent.applications) {
+            if (i >= MAX_CONTENTS) {
+                break;
+            }
+            pContent = contents + i++;
+            pContent->type = SCKBindContentTypeApplication;
+            pContent->id = (uint32_t) refApp.processID;
+            copyName(pContent->name, refApp.bundleIdentifier);
+            pContent->width = 0;
+            pContent->height = 0;
+        }
+
+        for (SCWindow *refWindow in refContent.windows) {
+            if (i >= MAX_CONTENTS) {
+                break;
+            }
+            pContent = contents + i++;
+            pContent->type = SCKBindContentTypeWindow;
+            pContent->id = refWindow.windowID;
+            copyName(pContent->name, refWindow.title);
+            pContent->width = (int) refWindow.frame.size.width;
+            pContent->height = (int) refWindow.frame.size.height;
+        }
+
+        *ppContents = contents;
+        *pLen = i;
+    }
+
+cleanup:
+    [refPool drain];
+    return retStatus;
+}
+
+API_AVAILABLE(macos(13.0))
+static SCContentFilter* newContentFilter(SCShareableContent *refContent, SCKBindStreamConfig config) {
+    NSMutableArray *refExcludedWindows = [NSMutableArray array];
+    NSMutableArray *refExcludedApps = [NSMutableArray array];
+    for (SCWindow *refWindow in refContent.windows) {
+        for (int i = 0; i < config.numExcludedWindows; i++) {
+            if (refWindow.windowID == config.pExcludedWindows[i]) {
+                [refExcludedWindows addObject: refWindow];
+            }
+        }
+    }
+    for (SCRunningApplication *refApp in refContent.applications) {
+        for (int i = 0; i < config.numExcludedApplications; i++) {
+            if ([refApp.bundleIdentifier isEqualToString: [NSString stringWithUTF8String: config.ppExcludedApplications[i]]]) {
+                [refExcludedApps addObject: refApp];
+            }
+        }
+    }
+
+    switch (config.type) {
+        case SCKBindContentTypeDisplay:
+            // Exclude the windows of the excluded applications along with the excluded windows
+            for (SCWindow *refWindow in refContent.windows) {
+                if ([refExcludedApps containsObject: refWindow.owningApplication] &&
+                    ![refExcludedWindows containsObject: refWindow]) {
+                    [refExcludedWindows addObject: refWindow];
+                }
+            }
+            for (SCDisplay *refDisplay in refContent.displays) {
+                if (refDisplay.displayID == config.id) {
+                    return [[SCContentFilter alloc] initWithDisplay: refDisplay
+                                                   excludingWindows: refExcludedWindows];
+                }
+            }
+            break;
+        case SCKBindContentTypeApplication: {
+            // Applications are captured on the main display
+            SCDisplay *refMainDisplay = nil;
+            for (SCDisplay *refDisplay in refContent.displays) {
+                if (refDisplay.displayID == CGMainDisplayID()) {
+                    refMainDisplay = refDisplay;
+                }
+            }
+            for (SCRunningApplication *refApp in refContent.applications) {
+                if (refMainDisplay != nil && (uint32_t) refApp.processID == config.id) {
+                    return [[SCContentFilter alloc] initWithDisplay: refMainDisplay
+                                              includingApplications: @[refApp]
+                                                   exceptingWindows: refExcludedWindows];
+                }
+            }
+            break;
+        }
+        case SCKBindContentTypeWindow:
+            for (SCWindow *refWindow in refContent.windows) {
+                if (refWindow.windowID == config.id) {
+                    return [[SCContentFilter alloc] initWithDesktopIndependentWindow: refWindow];
+                }
+            }
+            break;
+    }
+    return nil;
+}
+
+STATUS SCKBindStreamOpen(SCKBindStreamConfig config,
+                         SCKBindVideoCallback videoCallback,
+                         SCKBindAudioCallback audioCallback,
+                         void *pUserData,
+                         PSCKBindStream *ppStream) {
+    STATUS retStatus = STATUS_OK;
+    NSAutoreleasePool *refPool = [[NSAutoreleasePool alloc] init];
+    CHK(ppStream != NULL && videoCallback != NULL && audioCallback != NULL, STATUS_NULL_ARG);
+    CHK(SCKBindAvailable(), STATUS_UNAVAILABLE);
+
+    if (@available(macOS 13.0, *)) {
+        SCShareableContent *refContent = getShareableContent();
+        CHK(refContent != nil, STATUS_PERMISSION_DENIED);
+
+        SCContentFilter *refFilter = newContentFilter(refContent, config);
+        CHK(refFilter != nil, STATUS_CONTENT_NOT_FOUND);
+        [refFilter autorelease];
+
+        SCStreamConfiguration *refConfig = [[[SCStreamConfiguration alloc] init] autorelease];
+        if (config.width > 0 && config.height > 0) {
+            refConfig.width = config.width;
+            refConfig.height = config.height;
+        }
+        if (config.frameRate > 0) {
+            refConfig.minimumFrameInterval = CMTimeMake(1, config.frameRate);
+        }
+        refConfig.pixelFormat = kCVPixelFormatType_32BGRA;
+        refConfig.showsCursor = config.showsCursor ? YES : NO;
+        refConfig.queueDepth = 3;
+        if (config.capturesAudio) {
+            refConfig.capturesAudio = YES;
+            refConfig.excludesCurrentProcessAudio = YES;
+            refConfig.sampleRate = config.sampleRate;
+            refConfig.channelCount = config.channelCount;
+        }
+
+        StreamOutput *refOutput = [[StreamOutput alloc] init: videoCallback
+                                            withAudioCallback: audioCallback
+                                                 withUserData: pUserData];
+        SCStream *refStream = [[SCStream alloc] initWithFilter: refFilter
+                                                 configuration: refConfig
+                                                      delegate: nil];
+
+        dispatch_queue_t queue = dispatch_queue_create("screenCaptureQueue", DISPATCH_QUEUE_SERIAL);
+        NSError *refErr = nil;
+        BOOL ok = [refStream addStreamOutput: refOutput type: SCStreamOutputTypeScreen sampleHandlerQueue: queue error: &refErr];
+        if (ok && config.capturesAudio) {
+            ok = [refStream addStreamOutput: refOutput type: SCStreamOutputTypeAudio sampleHandlerQueue: queue error: &refErr];
+        }
+        dispatch_release(queue);
+
+        __block BOOL started = NO;
+        if (ok) {
+            dispatch_semaphore_t sem = dispatch_semaphore_create(0);
+            [refStream startCaptureWithCompletionHandler: ^(NSError *error) {
+                started = error == nil;
+                dispatch_semaphore_signal(sem);
+            }];
+            dispatch_semaphore_wait(sem, DISPATCH_TIME_FOREVER);
+            dispatch_release(sem);
+        }
+
+        if (!started) {
+            [refStream release];
+            [refOutput release];
+            CHK(NO, STATUS_STREAM_START_FAILED);
+        }
+
+        PSCKBindStream pStream = malloc(sizeof(SCKBindStream));
+        pStream->refStream = refStream;
+        pStream->refOutput = refOutput;
+        *ppStream = pStream;
+    }
+
+cleanup:
+    [refPool drain];
+    return retStatus;
+}
+
+STATUS SCKBindStreamClose(PSCKBindStream *ppStream) {
+    STATUS retStatus = STATUS_OK;
+    CHK(ppStream != NULL, STATUS_NULL_ARG);
+    CHK(*ppStream != NULL, STATUS_OK);
+
+    if (@available(macOS 13.0, *)) {
+        PSCKBindStream pStream = *ppStream;
+        SCStream *refStream = pStream->refStream;
+
+        // Wait until the stream is stopped, so that the callbacks are not called after closing
+        dispatch_semaphore_t sem = dispatch_semaphore_create(0);
+        [refStream stopCaptureWithCompletionHandler: ^(NSError *error) {
+            dispatch_semaphore_signal(sem);
+        }];
+        dispatch_semaphore_wait(sem, DISPATCH_TIME_FOREVER);
+        dispatch_release(sem);
+
+        [refStream release];
+        [pStream->refOutput release];
+        free(pStream);
+        *ppStream = NULL;
+    }
+
+cleanup:
+    return retStatus;
+}
@@ -0,0 +1,79 @@
+package screencapturekit
+
+import "C"
+import (
+	"image"
+	"sync"
+	"unsafe"
+)
+
+var mu sync.Mutex
+var nextID handleID
+
+type handle struct {
+	onVideo func(*image.RGBA)
+	onAudio func(Audio)
+}
+
+var handles = make(map[handleID]handle)
+
+type handleID int
+
+//export onVideo
+func onVideo(userData unsafe.Pointer, buf unsafe.Pointer, width, height C.int) {
+	h, ok := lookup(handleID(*(*C.int)(userData)))
+	if ok {
+		img := &image.RGBA{
+			Pix:    C.GoBytes(buf, width*height*4),
+			Stride: int(width) * 4,
+			Rect:   image.Rect(0, 0, int(width), int(height)),
+		}
+		h.onVideo(img)
+	}
+}
+
+//export onAudio
+func onAudio(userData unsafe.Pointer, buf unsafe.Pointer, samples, channels C.int) {
+	h, ok := lookup(handleID(*(*C.int)(userData)))
+	if ok {
+		n := int(samples) * int(channels)
+		src := (*[1 << 28]float32)(buf)[:n:n]
+		a := Audio{
+			Data:     make([][]float32, channels),
+			Channels: int(channels),
+		}
+		for ch := range a.Data {
+			a.Data[ch] = make([]float32, samples)
+			copy(a.Data[ch], src[ch*int(samples):])
+		}
+		h.onAudio(a)
+	}
+}
+
+func register(onVideo func(*image.RGBA), onAudio func(Audio)) handleID {
+	mu.Lock()
+	defer mu.Unlock()
+
+	nextID++
+	for _, ok := handles[nextID]; ok; _, ok = handles[nextID] {
+		nextID++
+	}
+	handles[nextID] = handle{onVideo: onVideo, onAudio: onAudio}
+
+	return nextID
+}
+
+func lookup(i handleID) (h handle, ok bool) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	h, ok = handles[i]
+	return
+}
+
+func unregister(i handleID) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	delete(handles, i)
+}
@@ -0,0 +1,259 @@
+// Package screencapturekit provides a ScreenCaptureKit binding for Go. ScreenCaptureKit is available on
+// macOS 13 or later, and building this package requires the macOS 13 SDK.
+package screencapturekit
+
+// #cgo CFLAGS: -x objective-c
+// #cgo LDFLAGS: -framework Foundation -framework CoreMedia -framework CoreVideo -framework CoreGraphics -weak_framework ScreenCaptureKit
+// #include <stdlib.h>
+// #include "SCKBind/SCKBind.h"
+// #include "SCKBind/SCKBind.m"
+// extern void onVideo(void*, void*, int, int);
+// extern void onAudio(void*, void*, int, int);
+// void onVideoBridge(void *userData, void *buf, int width, int height) {
+// 	onVideo(userData, buf, width, height);
+// }
+// void onAudioBridge(void *userData, void *buf, int samples, int channels) {
+// 	onAudio(userData, buf, samples, channels);
+// }
+import "C"
+import (
+	"fmt"
+	"image"
+	"io"
+	"sync"
+	"unsafe"
+)
+
+// ContentType is a kind of shareable content
+type ContentType C.SCKBindContentType
+
+const (
+	Display     = ContentType(C.SCKBindContentTypeDisplay)
+	Application = ContentType(C.SCKBindContentTypeApplication)
+	Window      = ContentType(C.SCKBindContentTypeWindow)
+)
+
+// Content is shareable content that a Stream can capture
+type Content struct {
+	Type ContentType
+	// ID is a display ID for displays, a process ID for applications, and a window ID for windows
+	ID uint32
+	// Name is a bundle identifier for applications, and a title for windows
+	Name   string
+	Width  int
+	Height int
+}
+
+// Available reports whether ScreenCaptureKit can be used on the running system
+func Available() bool {
+	return C.SCKBindAvailable() != 0
+}
+
+// Contents queries the shareable contents. Screen recording permission is required.
+func Contents() ([]Content, error) {
+	var cContentsPtr C.PSCKBindContent
+	var cContentsLen C.int
+
+	status := C.SCKBindContents(&cContentsPtr, &cContentsLen)
+	if status != nil {
+		return nil, fmt.Errorf("%s", C.GoString(status))
+	}
+
+	// https://github.com/golang/go/wiki/cgo#turning-c-arrays-into-go-slices
+	cContents := (*[1 << 28]C.SCKBindContent)(unsafe.Pointer(cContentsPtr))[:cContentsLen:cContentsLen]
+	contents := make([]Content, cContentsLen)
+
+	for i := range contents {
+		contents[i] = Content{
+			Type:   ContentType(cContents[i]._type),
+			ID:     uint32(cContents[i].id),
+			Name:   C.GoString(&cContents[i].name[0]),
+			Width:  int(cContents[i].width),
+			Height: int(cContents[i].height),
+		}
+	}
+
+	return contents, nil
+}
+
+// Config configures a Stream
+type Config struct {
+	// Content is the content to capture
+	Content Content
+	// ExcludedApplications are bundle identifiers of applications to hide from the display
+	ExcludedApplications []string
+	// ExcludedWindows are IDs of windows to hide from the display or application
+	ExcludedWindows []uint32
+
+	// Frame size. If it's 0, the content's size is used.
+	Width, Height int
+	// FrameRate is the maximum frame rate. Frames are only delivered when the content changes.
+	FrameRate   int
+	ShowsCursor bool
+
+	// CapturesAudio captures system audio, excluding the current process's audio.
+	CapturesAudio bool
+	SampleRate    int
+	ChannelCount  int
+}
+
+// Audio is a chunk of non-interleaved float32 samples
+type Audio struct {
+	Data     [][]float32
+	Channels int
+}
+
+// Stream captures a shareable content
+type Stream struct {
+	cStream C.PSCKBindStream
+	cID     *C.int
+	videoCh chan *image.RGBA
+	audioCh chan Audio
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Open starts capturing with config. As soon as it returns successfully, data starts flowing.
+func Open(config Config) (*Stream, error) {
+	s := &Stream{
+		videoCh: make(chan *image.RGBA, 1),
+		audioCh: make(chan Audio, 8),
+	}
+
+	cConfig := C.SCKBindStreamConfig{
+		_type:         C.SCKBindContentType(config.Content.Type),
+		id:            C.uint32_t(config.Content.ID),
+		width:         C.int(config.Width),
+		height:        C.int(config.Height),
+		frameRate:     C.int(config.FrameRate),
+		showsCursor:   cBool(config.ShowsCursor),
+		capturesAudio: cBool(config.CapturesAudio),
+		sampleRate:    C.int(config.SampleRate),
+		channelCount:  C.int(config.ChannelCount),
+	}
+
+	// The arrays are allocated in C, since C can't hold Go pointers
+	if n := len(config.ExcludedApplications); n > 0 {
+		apps := (*[1 << 20]*C.char)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof((*C.char)(nil)))))[:n:n]
+		for i, app := range config.ExcludedApplications {
+			apps[i] = C.CString(app)
+		}
+		defer func() {
+			for _, app := range apps {
+				C.free(unsafe.Pointer(app))
+			}
+			C.free(unsafe.Pointer(&apps[0]))
+		}()
+		cConfig.ppExcludedApplications = &apps[0]
+		cConfig.numExcludedApplications = C.int(n)
+	}
+	if n := len(config.ExcludedWindows); n > 0 {
+		windows := (*[1 << 20]C.uint32_t)(C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(C.uint32_t(0)))))[:n:n]
+		for i, window := range config.ExcludedWindows {
+			windows[i] = C.uint32_t(window)
+		}
+		defer C.free(unsafe.Pointer(&windows[0]))
+		cConfig.pExcludedWindows = &windows[0]
+		cConfig.numExcludedWindows = C.int(n)
+	}
+
+	// The handle is passed as C memory, since the callbacks are called after Open returns
+	s.cID = (*C.int)(C.malloc(C.size_t(unsafe.Sizeof(C.int(0)))))
+	*s.cID = C.int(register(s.onVideo, s.onAudio))
+	status := C.SCKBindStreamOpen(
+		cConfig,
+		C.SCKBindVideoCallback(unsafe.Pointer(C.onVideoBridge)),
+		C.SCKBindAudioCallback(unsafe.Pointer(C.onAudioBridge)),
+		unsafe.Pointer(s.cID),
+		&s.cStream,
+	)
+	if status != nil {
+		unregister(handleID(*s.cID))
+		C.free(unsafe.Pointer(s.cID))
+		return nil, fmt.Errorf("%s", C.GoString(status))
+	}
+
+	return s, nil
+}
+
+func cBool(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (s *Stream) onVideo(img *image.RGBA) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	// Keep only the latest frame, since frames aren't delivered periodically
+	select {
+	case s.videoCh <- img:
+	default:
+		select {
+		case <-s.videoCh:
+		default:
+		}
+		s.videoCh <- img
+	}
+}
+
+func (s *Stream) onAudio(a Audio) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+
+	// Drop the oldest chunk for a slow reader, since blocking the capture queue stalls the video too
+	select {
+	case s.audioCh <- a:
+	default:
+		select {
+		case <-s.audioCh:
+		default:
+		}
+		s.audioCh <- a
+	}
+}
+
+// ReadVideo returns the next frame, which is delivered when the content changes
+func (s *Stream) ReadVideo() (*image.RGBA, error) {
+	img, ok := <-s.videoCh
+	if !ok {
+		return nil, io.EOF
+	}
+	return img, nil
+}
+
+// ReadAudio returns the next chunk of captured system audio
+func (s *Stream) ReadAudio() (Audio, error) {
+	a, ok := <-s.audioCh
+	if !ok {
+		return Audio{}, io.EOF
+	}
+	return a, nil
+}
+
+// Close stops capturing, and no more data flows
+func (s *Stream) Close() error {
+	status := C.SCKBindStreamClose(&s.cStream)
+	unregister(handleID(*s.cID))
+	C.free(unsafe.Pointer(s.cID))
+
+	s.mu.Lock()
+	s.closed = true
+	close(s.videoCh)
+	close(s.audioCh)
+	s.mu.Unlock()
+
+	if status != nil {
+		return fmt.Errorf("%s", C.GoString(status))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package screencapturekit
+
+import (
+	"image"
+	"testing"
+)
+
+func TestStreamDelivery(t *testing.T) {
+	s := &Stream{
+		videoCh: make(chan *image.RGBA, 1),
+		audioCh: make(chan Audio, 2),
+	}
+
+	// Only the latest frame is kept for the reader
+	first, second := image.NewRGBA(image.Rect(0, 0, 1, 1)), image.NewRGBA(image.Rect(0, 0, 1, 1))
+	s.onVideo(first)
+	s.onVideo(second)
+	img, err := s.ReadVideo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img != second {
+		t.Fatal("expected the latest frame")
+	}
+
+	// The oldest chunk is dropped for a slow reader
+	for i := 1; i <= 3; i++ {
+		s.onAudio(Audio{Channels: i})
+	}
+	for _, expected := range []int{2, 3} {
+		a, err := s.ReadAudio()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Channels != expected {
+			t.Fatalf("expected the chunk %d, but got %d", expected, a.Channels)
+		}
+	}
+}
+
+func TestHandles(t *testing.T) {
+	var delivered *image.RGBA
+	id := register(func(img *image.RGBA) { delivered = img }, func(Audio) {})
+	h, ok := lookup(id)
+	if !ok {
+		t.Fatal("expected the handle to be registered")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	h.onVideo(img)
+	if delivered != img {
+		t.Fatal("expected the frame to be delivered to the stream of the handle")
+	}
+
+	unregister(id)
+	if _, ok := lookup(id); ok {
+		t.Fatal("expected the handle to be unregistered")
+	}
+}
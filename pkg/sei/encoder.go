@@ -0,0 +1,91 @@
+package sei
+
+import (
+	"image"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+// Frame is an encoded frame that messages are inserted into.
+type Frame struct {
+	// Metadata of the raw frame, e.g. values from video.Annotate.
+	video.Metadata
+	// KeyFrame is set for keyframes, e.g. to repeat HDR metadata on each one.
+	KeyFrame bool
+}
+
+// MessageFunc returns the messages for a frame. It runs on the encoder's reading goroutine, so it shouldn't
+// block.
+type MessageFunc func(Frame) []Message
+
+type encoderBuilder struct {
+	codec.VideoEncoderBuilder
+	messages MessageFunc
+}
+
+// NewEncoderBuilder wraps builder to insert each frame's messages into its encoded output. Raw frames are matched
+// to encoded frames with codec.PendingFrames, so messages land on the right frame. Codecs other than H.264 and
+// H.265 are passed through unchanged.
+func NewEncoderBuilder(builder codec.VideoEncoderBuilder, messages MessageFunc) codec.VideoEncoderBuilder {
+	return &encoderBuilder{VideoEncoderBuilder: builder, messages: messages}
+}
+
+func (b *encoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	e := &encoder{
+		mimeType: b.RTPCodec().MimeType,
+		messages: b.messages,
+	}
+
+	tracked := video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		m, _ := video.MetadataOf(r)
+		e.pending.Push(m, m)
+		return img, release, nil
+	}), r)
+
+	rc, err := b.VideoEncoderBuilder.BuildVideoEncoder(tracked, p)
+	if err != nil {
+		return nil, err
+	}
+	e.ReadCloser = rc
+
+	return e, nil
+}
+
+type encoder struct {
+	codec.EncoderWrapper
+	mimeType string
+	messages MessageFunc
+
+	pending codec.PendingFrames
+}
+
+func (e *encoder) Read() ([]byte, func(), error) {
+	b, release, err := e.ReadCloser.Read()
+	if err != nil {
+		return b, release, err
+	}
+	m, _ := e.pending.Pop(e.ReadCloser, b)
+	if len(b) == 0 {
+		return b, release, nil
+	}
+
+	f := Frame{KeyFrame: codec.IsKeyFrame(e.mimeType, b)}
+	f.Metadata, _ = m.(video.Metadata)
+	messages := e.messages(f)
+	if len(messages) == 0 || !Supported(e.mimeType) {
+		return b, release, nil
+	}
+
+	inserted := Insert(e.mimeType, b, messages...)
+	if len(inserted) == len(b) {
+		return b, release, nil
+	}
+	release()
+	return inserted, func() {}, nil
+}
@@ -0,0 +1,316 @@
+// Package sei inserts SEI (supplemental enhancement information) messages into H.264 and H.265 frames, so
+// per-frame metadata such as capture time, custom user data or HDR metadata travels inside the video stream to
+// downstream systems. Decoders ignore messages they don't understand.
+package sei
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/webrtc/v3"
+)
+
+// SEI payload types.
+// Reference: ITU-T H.264, D.1 SEI payload syntax, and ITU-T H.265, D.2.1 General SEI message syntax
+const (
+	TypeUserDataRegistered   = 4
+	TypeUserDataUnregistered = 5
+	TypeMasteringDisplay     = 137
+	TypeContentLightLevel    = 144
+)
+
+const (
+	naluTypeH264SEI       = 6
+	naluTypeH265PrefixSEI = 39
+	naluTypeH265SuffixSEI = 40
+)
+
+// TimestampUUID identifies the user data of Timestamp.
+var TimestampUUID = [16]byte{0x6d, 0x65, 0x64, 0x69, 0x61, 0x64, 0x65, 0x76, 0x69, 0x63, 0x65, 0x73, 0x2d, 0x74, 0x73, 0x00}
+
+// Message is an SEI message.
+type Message struct {
+	// Type is the payloadType, e.g. TypeUserDataUnregistered.
+	Type int
+	// Payload is the message body without emulation prevention bytes.
+	Payload []byte
+}
+
+// UserDataUnregistered returns a message carrying data, identified by id, e.g. an application UUID.
+func UserDataUnregistered(id [16]byte, data []byte) Message {
+	payload := make([]byte, 0, len(id)+len(data))
+	payload = append(payload, id[:]...)
+	return Message{Type: TypeUserDataUnregistered, Payload: append(payload, data...)}
+}
+
+// UserData returns the identifier and data of a TypeUserDataUnregistered message, or false for other messages.
+func (m Message) UserData() ([16]byte, []byte, bool) {
+	var id [16]byte
+	if m.Type != TypeUserDataUnregistered || len(m.Payload) < len(id) {
+		return id, nil, false
+	}
+	copy(id[:], m.Payload)
+	return id, m.Payload[len(id):], true
+}
+
+// Timestamp returns TimestampUUID user data holding t as big endian int64 Unix nanoseconds, e.g. a capture time
+// from a clock synchronized across cameras.
+func Timestamp(t time.Time) Message {
+	var nsec [8]byte
+	binary.BigEndian.PutUint64(nsec[:], uint64(t.UnixNano()))
+	return UserDataUnregistered(TimestampUUID, nsec[:])
+}
+
+// ParseTimestamp returns the time in a Timestamp message, or false for other messages.
+func ParseTimestamp(m Message) (time.Time, bool) {
+	id, data, ok := m.UserData()
+	if !ok || id != TimestampUUID || len(data) != 8 {
+		return time.Time{}, false
+	}
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data))), true
+}
+
+// MasteringDisplay is the mastering display colour volume of HDR content, as in SMPTE ST 2086.
+type MasteringDisplay struct {
+	// Primaries are the x and y chromaticity coordinates of the green, blue and red primaries, and WhitePoint
+	// those of the white point, in increments of 0.00002.
+	Primaries  [3][2]uint16
+	WhitePoint [2]uint16
+	// MaxLuminance and MinLuminance are the display luminance in units of 0.0001 cd/m2.
+	MaxLuminance uint32
+	MinLuminance uint32
+}
+
+// Message returns a TypeMasteringDisplay message.
+func (d MasteringDisplay) Message() Message {
+	payload := make([]byte, 0, 24)
+	for _, p := range d.Primaries {
+		payload = appendUint16(payload, p[0])
+		payload = appendUint16(payload, p[1])
+	}
+	payload = appendUint16(payload, d.WhitePoint[0])
+	payload = appendUint16(payload, d.WhitePoint[1])
+	payload = appendUint32(payload, d.MaxLuminance)
+	payload = appendUint32(payload, d.MinLuminance)
+	return Message{Type: TypeMasteringDisplay, Payload: payload}
+}
+
+// ContentLightLevel is the light level of HDR content, as in CTA-861.3.
+type ContentLightLevel struct {
+	// MaxContentLightLevel and MaxFrameAverageLightLevel are in cd/m2.
+	MaxContentLightLevel      uint16
+	MaxFrameAverageLightLevel uint16
+}
+
+// Message returns a TypeContentLightLevel message.
+func (l ContentLightLevel) Message() Message {
+	payload := appendUint16(make([]byte, 0, 4), l.MaxContentLightLevel)
+	return Message{Type: TypeContentLightLevel, Payload: appendUint16(payload, l.MaxFrameAverageLightLevel)}
+}
+
+func appendUint16(b []byte, v uint16) []byte {
+	return append(b, byte(v>>8), byte(v))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func isH265(mimeType string) bool {
+	return strings.EqualFold(mimeType, codec.MimeTypeH265)
+}
+
+// Supported reports whether messages can be inserted into mimeType frames.
+func Supported(mimeType string) bool {
+	return strings.EqualFold(mimeType, webrtc.MimeTypeH264) || isH265(mimeType)
+}
+
+// Insert returns the Annex B frame with an SEI NAL unit of messages placed before the first picture, i.e. after
+// the access unit delimiter and parameter sets. The frame is returned unchanged if it has no picture or mimeType
+// isn't supported.
+func Insert(mimeType string, frame []byte, messages ...Message) []byte {
+	if len(messages) == 0 || !Supported(mimeType) {
+		return frame
+	}
+	h265 := isH265(mimeType)
+	at := -1
+	for _, nalu := range nalUnits(frame) {
+		if isVCL(h265, frame[nalu.header]) {
+			at = nalu.start
+			break
+		}
+	}
+	if at < 0 {
+		return frame
+	}
+
+	sei := seiNALU(h265, messages)
+	out := make([]byte, 0, len(frame)+len(sei)+4)
+	out = append(out, frame[:at]...)
+	out = append(out, 0, 0, 0, 1)
+	out = append(out, sei...)
+	return append(out, frame[at:]...)
+}
+
+// Parse returns the SEI messages in an Annex B frame of mimeType.
+func Parse(mimeType string, frame []byte) []Message {
+	if !Supported(mimeType) {
+		return nil
+	}
+	h265 := isH265(mimeType)
+	var messages []Message
+	for _, nalu := range nalUnits(frame) {
+		data := frame[nalu.header:nalu.end]
+		headerSize := 1
+		if h265 {
+			headerSize = 2
+		}
+		if len(data) <= headerSize || !isSEI(h265, data[0]) {
+			continue
+		}
+		messages = append(messages, parseMessages(removeEmulationPrevention(data[headerSize:]))...)
+	}
+	return messages
+}
+
+func isVCL(h265 bool, header byte) bool {
+	if h265 {
+		return header>>1&0x3f < 32
+	}
+	t := header & 0x1f
+	return t >= 1 && t <= 5
+}
+
+func isSEI(h265 bool, header byte) bool {
+	if h265 {
+		t := header >> 1 & 0x3f
+		return t == naluTypeH265PrefixSEI || t == naluTypeH265SuffixSEI
+	}
+	return header&0x1f == naluTypeH264SEI
+}
+
+// seiNALU returns an SEI NAL unit of messages without a start code.
+func seiNALU(h265 bool, messages []Message) []byte {
+	var rbsp []byte
+	for _, m := range messages {
+		rbsp = appendVariable(rbsp, m.Type)
+		rbsp = appendVariable(rbsp, len(m.Payload))
+		rbsp = append(rbsp, m.Payload...)
+	}
+	// rbsp_trailing_bits
+	rbsp = append(rbsp, 0x80)
+
+	header := []byte{naluTypeH264SEI}
+	if h265 {
+		// nuh_layer_id is 0 and nuh_temporal_id_plus1 is 1
+		header = []byte{naluTypeH265PrefixSEI << 1, 1}
+	}
+	return append(header, emulationPrevention(rbsp)...)
+}
+
+// appendVariable appends a payload type or size, coded as 0xff bytes followed by the remainder.
+func appendVariable(b []byte, v int) []byte {
+	for ; v >= 0xff; v -= 0xff {
+		b = append(b, 0xff)
+	}
+	return append(b, byte(v))
+}
+
+func parseMessages(rbsp []byte) []Message {
+	var messages []Message
+	readVariable := func() (int, bool) {
+		v := 0
+		for len(rbsp) > 0 {
+			b := rbsp[0]
+			rbsp = rbsp[1:]
+			v += int(b)
+			if b != 0xff {
+				return v, true
+			}
+		}
+		return 0, false
+	}
+	// The messages continue until rbsp_trailing_bits
+	for len(rbsp) > 1 || (len(rbsp) == 1 && rbsp[0] != 0x80) {
+		typ, ok := readVariable()
+		if !ok {
+			break
+		}
+		size, ok := readVariable()
+		if !ok || size > len(rbsp) {
+			break
+		}
+		messages = append(messages, Message{Type: typ, Payload: append([]byte(nil), rbsp[:size]...)})
+		rbsp = rbsp[size:]
+	}
+	return messages
+}
+
+// nalUnit is the position of a NAL unit in an Annex B frame: start is the start code, header the first byte of
+// the unit, and end its end without trailing zeros.
+type nalUnit struct {
+	start, header, end int
+}
+
+func nalUnits(frame []byte) []nalUnit {
+	var nalus []nalUnit
+	for i := 0; i+2 < len(frame); i++ {
+		if frame[i] != 0 || frame[i+1] != 0 || frame[i+2] != 1 {
+			continue
+		}
+		start := i
+		if start > 0 && frame[start-1] == 0 {
+			// 4 bytes start code
+			start--
+		}
+		if n := len(nalus); n > 0 {
+			nalus[n-1].end = len(bytes.TrimRight(frame[:start], "\x00"))
+		}
+		if i+3 < len(frame) {
+			nalus = append(nalus, nalUnit{start: start, header: i + 3, end: len(frame)})
+		}
+		i += 2
+	}
+	return nalus
+}
+
+// emulationPrevention inserts 0x03 after two zero bytes followed by a byte up to 0x03, so the payload can't
+// contain a start code.
+func emulationPrevention(rbsp []byte) []byte {
+	out := make([]byte, 0, len(rbsp)+len(rbsp)/2)
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros == 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+func removeEmulationPrevention(ebsp []byte) []byte {
+	out := make([]byte, 0, len(ebsp))
+	zeros := 0
+	for _, b := range ebsp {
+		if zeros == 2 && b == 3 {
+			zeros = 0
+			continue
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
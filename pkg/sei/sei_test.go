@@ -0,0 +1,253 @@
+package sei
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+func TestInsert(t *testing.T) {
+	id := [16]byte{1, 2, 3}
+	// The payload is over 255 bytes and has zeros to escape
+	data := make([]byte, 300)
+	data[299] = 1
+	messages := []Message{
+		UserDataUnregistered(id, data),
+		ContentLightLevel{MaxContentLightLevel: 1000, MaxFrameAverageLightLevel: 400}.Message(),
+	}
+
+	testCases := map[string]struct {
+		mimeType string
+		frame    []byte
+		at       int
+		sei      byte
+	}{
+		"H264": {
+			mimeType: webrtc.MimeTypeH264,
+			// AUD, SPS, IDR
+			frame: []byte{0, 0, 0, 1, 0x09, 0xf0, 0, 0, 0, 1, 0x67, 0x42, 0, 0, 1, 0x65, 0x88, 0x84},
+			at:    12,
+			sei:   0x06,
+		},
+		"H265": {
+			mimeType: codec.MimeTypeH265,
+			// VPS, IDR_W_RADL
+			frame: []byte{0, 0, 0, 1, 0x40, 0x01, 0x0c, 0, 0, 0, 1, 0x26, 0x01, 0xaf},
+			at:    7,
+			sei:   0x4e,
+		},
+	}
+	for name, testCase := range testCases {
+		testCase := testCase
+		t.Run(name, func(t *testing.T) {
+			inserted := Insert(testCase.mimeType, testCase.frame, messages...)
+			if !bytes.Equal(inserted[:testCase.at], testCase.frame[:testCase.at]) {
+				t.Fatalf("expected the frame to keep the NAL units before the picture, but got %v", inserted[:testCase.at])
+			}
+			if !bytes.Equal(inserted[testCase.at:testCase.at+5], []byte{0, 0, 0, 1, testCase.sei}) {
+				t.Fatalf("expected an SEI NAL unit before the picture, but got %v", inserted[testCase.at:testCase.at+5])
+			}
+			if !bytes.HasSuffix(inserted, testCase.frame[testCase.at:]) {
+				t.Fatal("expected the picture after the SEI NAL unit")
+			}
+			if bytes.Contains(inserted[testCase.at+4:len(inserted)-len(testCase.frame)+testCase.at], []byte{0, 0, 0}) {
+				t.Fatal("expected the SEI NAL unit to be escaped")
+			}
+
+			parsed := Parse(testCase.mimeType, inserted)
+			if len(parsed) != len(messages) {
+				t.Fatalf("expected %d messages, but got %d", len(messages), len(parsed))
+			}
+			for i, m := range parsed {
+				if m.Type != messages[i].Type || !bytes.Equal(m.Payload, messages[i].Payload) {
+					t.Errorf("expected %v, but got %v", messages[i], m)
+				}
+			}
+			parsedID, parsedData, ok := parsed[0].UserData()
+			if !ok || parsedID != id || !bytes.Equal(parsedData, data) {
+				t.Errorf("expected the user data of %v, but got %v", id, parsedID)
+			}
+		})
+	}
+
+	vp8 := []byte{0x10, 0x02, 0x00}
+	if out := Insert(webrtc.MimeTypeVP8, vp8, messages...); !bytes.Equal(out, vp8) {
+		t.Fatal("expected the VP8 frame to be left as it is")
+	}
+	params := []byte{0, 0, 0, 1, 0x67, 0x42, 0, 0, 1, 0x68, 0xce}
+	if out := Insert(webrtc.MimeTypeH264, params, messages...); !bytes.Equal(out, params) {
+		t.Fatal("expected the frame without a picture to be left as it is")
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	now := time.Unix(1600000000, 123456789)
+	parsed, ok := ParseTimestamp(Timestamp(now))
+	if !ok || !parsed.Equal(now) {
+		t.Fatalf("expected %v, but got %v", now, parsed)
+	}
+	if _, ok := ParseTimestamp(UserDataUnregistered([16]byte{}, make([]byte, 8))); ok {
+		t.Fatal("expected the user data of the other UUID not to be a timestamp")
+	}
+}
+
+func TestMasteringDisplay(t *testing.T) {
+	m := MasteringDisplay{
+		Primaries:    [3][2]uint16{{8500, 39850}, {6550, 2300}, {35400, 14600}},
+		WhitePoint:   [2]uint16{15635, 16450},
+		MaxLuminance: 10000000,
+		MinLuminance: 50,
+	}.Message()
+	expected := []byte{
+		0x21, 0x34, 0x9b, 0xaa, 0x19, 0x96, 0x08, 0xfc, 0x8a, 0x48, 0x39, 0x08,
+		0x3d, 0x13, 0x40, 0x42, 0x00, 0x98, 0x96, 0x80, 0x00, 0x00, 0x00, 0x32,
+	}
+	if m.Type != TypeMasteringDisplay || !bytes.Equal(m.Payload, expected) {
+		t.Fatalf("expected %v, but got %v", expected, m.Payload)
+	}
+}
+
+// testEncoderBuilder encodes each frame to an IDR NAL unit holding its first luma sample, and skips frames whose
+// first luma sample is skip.
+type testEncoderBuilder struct {
+	skip uint8
+}
+
+func (b *testEncoderBuilder) RTPCodec() *codec.RTPCodec {
+	return codec.NewRTPH264Codec(90000)
+}
+
+func (b *testEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	return &testEncoder{r: r, skip: b.skip}, nil
+}
+
+type testEncoder struct {
+	r    video.Reader
+	skip uint8
+}
+
+func (e *testEncoder) Read() ([]byte, func(), error) {
+	img, release, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	defer release()
+	y := img.(*image.YCbCr).Y[0]
+	if y == e.skip {
+		return nil, func() {}, nil
+	}
+	return []byte{0, 0, 0, 1, 0x65, y}, func() {}, nil
+}
+
+func (e *testEncoder) Close() error { return nil }
+
+func (e *testEncoder) SetBitRate(int) error { return nil }
+
+func (e *testEncoder) ForceKeyFrame() error { return nil }
+
+func TestEncoderBuilder(t *testing.T) {
+	base := time.Unix(1600000000, 0)
+	n := 0
+	r := video.NewMetadataReader(video.ReaderFunc(func() (image.Image, func(), error) {
+		if n == 3 {
+			return nil, func() {}, io.EOF
+		}
+		n++
+		img := image.NewYCbCr(image.Rect(0, 0, 2, 2), image.YCbCrSubsampleRatio420)
+		img.Y[0] = uint8(n)
+		return img, func() {}, nil
+	}), func() video.Metadata {
+		return video.Metadata{CaptureTime: base.Add(time.Duration(n) * time.Second)}
+	})
+
+	builder := NewEncoderBuilder(&testEncoderBuilder{}, func(f Frame) []Message {
+		if !f.KeyFrame {
+			t.Error("expected the IDR frames to be keyframes")
+		}
+		if f.CaptureTime.Equal(base.Add(2 * time.Second)) {
+			// No message for the second frame
+			return nil
+		}
+		return []Message{Timestamp(f.CaptureTime)}
+	})
+	e, err := builder.BuildVideoEncoder(r, prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		b, _, err := e.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b[len(b)-1] != uint8(i) {
+			t.Fatalf("expected the frame %d, but got %d", i, b[len(b)-1])
+		}
+		messages := Parse(webrtc.MimeTypeH264, b)
+		if i == 2 {
+			if len(messages) != 0 {
+				t.Fatalf("expected no message in the frame 2, but got %v", messages)
+			}
+			continue
+		}
+		if len(messages) != 1 {
+			t.Fatalf("expected a message in the frame %d, but got %v", i, messages)
+		}
+		captureTime, ok := ParseTimestamp(messages[0])
+		if expected := base.Add(time.Duration(i) * time.Second); !ok || !captureTime.Equal(expected) {
+			t.Fatalf("expected the capture time %v of the frame %d, but got %v", expected, i, captureTime)
+		}
+	}
+	if _, _, err := e.Read(); err != io.EOF {
+		t.Fatalf("expected EOF, but got %v", err)
+	}
+}
+
+func TestEncoderBuilderSkip(t *testing.T) {
+	base := time.Unix(1600000000, 0)
+	n := 0
+	r := video.NewMetadataReader(video.ReaderFunc(func() (image.Image, func(), error) {
+		n++
+		img := image.NewYCbCr(image.Rect(0, 0, 2, 2), image.YCbCrSubsampleRatio420)
+		img.Y[0] = uint8(n)
+		return img, func() {}, nil
+	}), func() video.Metadata {
+		return video.Metadata{Sequence: uint64(n), CaptureTime: base.Add(time.Duration(n) * time.Second)}
+	})
+
+	builder := NewEncoderBuilder(&testEncoderBuilder{skip: 2}, func(f Frame) []Message {
+		return []Message{Timestamp(f.CaptureTime)}
+	})
+	e, err := builder.BuildVideoEncoder(r, prop.Media{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		b, _, err := e.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			if len(b) != 0 {
+				t.Fatalf("expected the frame 2 to be skipped, but got %v", b)
+			}
+			continue
+		}
+		messages := Parse(webrtc.MimeTypeH264, b)
+		if len(messages) != 1 {
+			t.Fatalf("expected a message in the frame %d, but got %v", i, messages)
+		}
+		captureTime, _ := ParseTimestamp(messages[0])
+		if expected := base.Add(time.Duration(i) * time.Second); !captureTime.Equal(expected) {
+			t.Fatalf("expected the capture time %v of the frame %d, but got %v", expected, i, captureTime)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!windows
+
+package shm
+
+import (
+	"errors"
+)
+
+var errUnsupported = errors.New("shm: shared memory isn't supported on this platform")
+
+func createSegment(name string, size int) (*segment, error) {
+	return nil, errUnsupported
+}
+
+func openSegment(name string) (*segment, error) {
+	return nil, errUnsupported
+}
+
+func removeSegment(name string) error {
+	return errUnsupported
+}
@@ -0,0 +1,70 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package shm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// segmentPath returns name's segment path, which is shm_open("/name") on Linux.
+func segmentPath(name string) string {
+	if runtime.GOOS == "linux" {
+		return filepath.Join("/dev/shm", name)
+	}
+	return filepath.Join(os.TempDir(), name)
+}
+
+func createSegment(name string, size int) (*segment, error) {
+	path := segmentPath(name)
+	// The segment is replaced instead of truncated, since truncating memory that readers mapped crashes them
+	os.Remove(path)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %s", path, err)
+	}
+	defer file.Close()
+	if err := file.Truncate(int64(size)); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to allocate %s: %s", path, err)
+	}
+	return mmap(file, size, syscall.PROT_READ|syscall.PROT_WRITE)
+}
+
+func openSegment(name string) (*segment, error) {
+	path := segmentPath(name)
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %s", path, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < headerSize {
+		return nil, errInvalidSegment
+	}
+	return mmap(file, int(info.Size()), syscall.PROT_READ)
+}
+
+func mmap(file *os.File, size, prot int) (*segment, error) {
+	mem, err := syscall.Mmap(int(file.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map %s: %s", file.Name(), err)
+	}
+	return &segment{
+		mem: mem,
+		unmap: func() error {
+			return syscall.Munmap(mem)
+		},
+	}, nil
+}
+
+func removeSegment(name string) error {
+	return os.Remove(segmentPath(name))
+}
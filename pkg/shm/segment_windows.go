@@ -0,0 +1,101 @@
+package shm
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+const errorAlreadyExists syscall.Errno = 183
+
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procCreateFileMappingW = kernel32.NewProc("CreateFileMappingW")
+	procVirtualQuery       = kernel32.NewProc("VirtualQuery")
+)
+
+// memoryBasicInformation is MEMORY_BASIC_INFORMATION, whose RegionSize is the mapped view size.
+type memoryBasicInformation struct {
+	BaseAddress       uintptr
+	AllocationBase    uintptr
+	AllocationProtect uint32
+	PartitionID       uint16
+	RegionSize        uintptr
+	State             uint32
+	Protect           uint32
+	Type              uint32
+}
+
+// createFileMapping creates name's file mapping in the session namespace, or opens it with its own size if
+// it exists, which the second result tells. syscall.CreateFileMapping doesn't return ERROR_ALREADY_EXISTS.
+func createFileMapping(name string, size int) (syscall.Handle, bool, error) {
+	mappingName, err := syscall.UTF16PtrFromString(`Local\` + name)
+	if err != nil {
+		return 0, false, err
+	}
+	h, _, err := procCreateFileMappingW.Call(uintptr(syscall.InvalidHandle), 0, syscall.PAGE_READWRITE,
+		uintptr(uint64(size)>>32), uintptr(uint32(size)), uintptr(unsafe.Pointer(mappingName)))
+	if h == 0 {
+		return 0, false, err
+	}
+	return syscall.Handle(h), err == errorAlreadyExists, nil
+}
+
+func createSegment(name string, size int) (*segment, error) {
+	h, exists, err := createFileMapping(name, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %s", name, err)
+	}
+	if exists {
+		// An existing mapping's size can't be changed, and it's removed when the last handle is closed
+		syscall.CloseHandle(h)
+		return nil, fmt.Errorf("failed to create %s: it's still opened", name)
+	}
+	return mapView(h, syscall.FILE_MAP_WRITE, size)
+}
+
+func openSegment(name string) (*segment, error) {
+	h, exists, err := createFileMapping(name, headerSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %s", name, err)
+	}
+	if !exists {
+		syscall.CloseHandle(h)
+		return nil, fmt.Errorf("failed to open %s: it doesn't exist", name)
+	}
+	return mapView(h, syscall.FILE_MAP_READ, 0)
+}
+
+// mapView maps a view of size bytes of h, or the whole mapping if size is 0.
+func mapView(h syscall.Handle, access uint32, size int) (*segment, error) {
+	addr, err := syscall.MapViewOfFile(h, access, 0, 0, uintptr(size))
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, fmt.Errorf("failed to map the view: %s", err)
+	}
+	if size == 0 {
+		var info memoryBasicInformation
+		procVirtualQuery.Call(addr, uintptr(unsafe.Pointer(&info)), unsafe.Sizeof(info))
+		size = int(info.RegionSize)
+	}
+
+	var mem []byte
+	header := (*reflect.SliceHeader)(unsafe.Pointer(&mem))
+	header.Data = addr
+	header.Len = size
+	header.Cap = size
+	return &segment{
+		mem: mem,
+		unmap: func() error {
+			err := syscall.UnmapViewOfFile(addr)
+			syscall.CloseHandle(h)
+			return err
+		},
+	}, nil
+}
+
+// removeSegment does nothing, since the mapping is removed when the last handle is closed.
+func removeSegment(name string) error {
+	return nil
+}
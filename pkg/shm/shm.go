@@ -0,0 +1,127 @@
+// Package shm exchanges raw frames and audio chunks with other processes on the same machine through
+// shared memory, e.g. an OBS plugin or a machine learning sidecar that isn't written in Go. Frames are written
+// to and read from shared memory directly, without sockets or copies through the kernel.
+//
+// A segment is created by a Sink, which is the only writer, and opened by any number of readers. On Linux, it's
+// POSIX shared memory named name, i.e. shm_open("/name"), which is /dev/shm/name. On other Unix platforms,
+// it's a file named name in the temporary directory, and on Windows, it's the file mapping "Local\name".
+//
+// The segment is a 64 byte header followed by a ring of slots. Fields are in native byte order:
+//
+//	offset  size  header
+//	0       4     magic, 0x4d53444d
+//	4       4     version, 1
+//	8       4     slot count
+//	12      4     slot payload capacity, rounded up to 64 bytes for the next slot
+//	16      8     published frame count
+//	24      4     1 if the writer has been closed
+//
+//	offset  size  slot
+//	0       8     sequence lock, 2n+1 while frame n is written, then 2n+2
+//	8       8     capture time in Unix nanoseconds, or 0 if it's unknown
+//	16      4     payload size
+//	20      4     format as a little endian fourcc: "I420", "RGBA", "S16I" or "F32I"
+//	24      4     frame width, or audio chunk channels
+//	28      4     frame height, or audio chunk sampling rate
+//	64            payload, i.e. tightly packed planes or interleaved samples
+//
+// Frame n is written to slot n modulo the slot count. The writer marks the slot odd, writes the
+// frame, marks it even, and then increases the published frame count. Readers copy the frame's payload,
+// and drop it if the sequence lock changed meanwhile, since the writer never waits for readers.
+package shm
+
+import (
+	"errors"
+	"sync/atomic"
+	"unsafe"
+)
+
+const (
+	magic   = 0x4d53444d
+	version = 1
+
+	headerSize     = 64
+	slotHeaderSize = 64
+
+	offsetMagic     = 0
+	offsetVersion   = 4
+	offsetSlots     = 8
+	offsetSlotSize  = 12
+	offsetPublished = 16
+	offsetClosed    = 24
+
+	offsetSeq    = 0
+	offsetTime   = 8
+	offsetSize   = 16
+	offsetFormat = 20
+	offsetWidth  = 24
+	offsetHeight = 28
+)
+
+var (
+	formatI420 = fourcc("I420")
+	formatRGBA = fourcc("RGBA")
+	formatS16I = fourcc("S16I")
+	formatF32I = fourcc("F32I")
+)
+
+var (
+	errInvalidSegment   = errors.New("shm: invalid segment")
+	errFrameTooLarge    = errors.New("shm: frame is larger than the slots")
+	errUnexpectedFormat = errors.New("shm: unexpected format")
+	errClosed           = errors.New("shm: segment has been closed")
+)
+
+func fourcc(s string) uint32 {
+	return uint32(s[0]) | uint32(s[1])<<8 | uint32(s[2])<<16 | uint32(s[3])<<24
+}
+
+// segment is mapped shared memory.
+type segment struct {
+	mem   []byte
+	unmap func() error
+}
+
+func (s *segment) uint32At(offset int) *uint32 {
+	return (*uint32)(unsafe.Pointer(&s.mem[offset]))
+}
+
+func (s *segment) uint64At(offset int) *uint64 {
+	return (*uint64)(unsafe.Pointer(&s.mem[offset]))
+}
+
+// layout is the ring geometry, which is fixed when the segment is created.
+type layout struct {
+	slots    int
+	slotSize int
+}
+
+func (l layout) stride() int {
+	return slotHeaderSize + l.slotSize
+}
+
+func (l layout) size() int {
+	return headerSize + l.slots*l.stride()
+}
+
+func (l layout) slot(n uint64) int {
+	return headerSize + int(n%uint64(l.slots))*l.stride()
+}
+
+// readLayout validates seg's header and returns its layout.
+func readLayout(seg *segment) (layout, error) {
+	if len(seg.mem) < headerSize {
+		return layout{}, errInvalidSegment
+	}
+	if atomic.LoadUint32(seg.uint32At(offsetMagic)) != magic || *seg.uint32At(offsetVersion) != version {
+		return layout{}, errInvalidSegment
+	}
+	l := layout{
+		slots:    int(*seg.uint32At(offsetSlots)),
+		slotSize: int(*seg.uint32At(offsetSlotSize)),
+	}
+	if l.slots <= 0 || l.slotSize <= 0 || l.slotSize%64 != 0 || len(seg.mem) < l.size() {
+		return layout{}, errInvalidSegment
+	}
+	return l, nil
+}
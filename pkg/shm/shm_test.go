@@ -0,0 +1,158 @@
+package shm
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+func segmentName(t *testing.T) string {
+	return fmt.Sprintf("mediadevices-test-%d-%s", os.Getpid(), t.Name())
+}
+
+func TestVideo(t *testing.T) {
+	name := segmentName(t)
+	sink, err := Create(name, 4*4*4)
+	if err != nil {
+		t.Fatalf("failed to create the sink: %s", err)
+	}
+	source, err := Open(name)
+	if err != nil {
+		t.Fatalf("failed to open the source: %s", err)
+	}
+	defer source.Close()
+	r := source.VideoReader()
+
+	i420 := image.NewYCbCr(image.Rect(0, 0, 4, 2), image.YCbCrSubsampleRatio420)
+	for i := range i420.Y {
+		i420.Y[i] = byte(i)
+	}
+	i420.Cb[0], i420.Cb[1], i420.Cr[0], i420.Cr[1] = 10, 11, 12, 13
+	captureTime := time.Unix(100, 5)
+	if err := sink.WriteVideo(i420, captureTime); err != nil {
+		t.Fatalf("failed to write the frame: %s", err)
+	}
+
+	img, _, err := r.Read()
+	if err != nil {
+		t.Fatalf("failed to read the frame: %s", err)
+	}
+	yuv, ok := img.(*image.YCbCr)
+	if !ok {
+		t.Fatalf("expected *image.YCbCr, but got %T", img)
+	}
+	if !reflect.DeepEqual(yuv.Y, i420.Y) || !reflect.DeepEqual(yuv.Cb, i420.Cb) || !reflect.DeepEqual(yuv.Cr, i420.Cr) {
+		t.Fatalf("expected %v, but got %v", i420, yuv)
+	}
+	metadata, _ := video.MetadataOf(r)
+	if !metadata.CaptureTime.Equal(captureTime) || metadata.Sequence != 0 {
+		t.Fatalf("expected the capture time %v of the frame 0, but got %v of %d",
+			captureTime, metadata.CaptureTime, metadata.Sequence)
+	}
+
+	// Other formats are written as RGBA
+	gray := image.NewGray(image.Rect(0, 0, 2, 2))
+	gray.Pix = []byte{0, 50, 100, 255}
+	if err := sink.WriteVideo(gray, time.Time{}); err != nil {
+		t.Fatalf("failed to write the frame: %s", err)
+	}
+	img, _, err = r.Read()
+	if err != nil {
+		t.Fatalf("failed to read the frame: %s", err)
+	}
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		t.Fatalf("expected *image.RGBA, but got %T", img)
+	}
+	expected := []byte{0, 0, 0, 255, 50, 50, 50, 255, 100, 100, 100, 255, 255, 255, 255, 255}
+	if !reflect.DeepEqual(rgba.Pix, expected) {
+		t.Fatalf("expected %v, but got %v", expected, rgba.Pix)
+	}
+
+	if err := sink.WriteVideo(image.NewRGBA(image.Rect(0, 0, 8, 8)), time.Time{}); err != errFrameTooLarge {
+		t.Fatalf("expected %v, but got %v", errFrameTooLarge, err)
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("failed to close the sink: %s", err)
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Fatalf("expected io.EOF after the sink is closed, but got %v", err)
+	}
+}
+
+func TestDrop(t *testing.T) {
+	name := segmentName(t)
+	sink, err := Create(name, 4, WithSlots(2))
+	if err != nil {
+		t.Fatalf("failed to create the sink: %s", err)
+	}
+	defer sink.Close()
+	source, err := Open(name)
+	if err != nil {
+		t.Fatalf("failed to open the source: %s", err)
+	}
+	defer source.Close()
+	r := source.VideoReader()
+
+	// A reader lapped by the writer skips to the latest frame
+	for i := 0; i < 5; i++ {
+		if err := sink.WriteVideo(image.NewRGBA(image.Rect(0, 0, 1, 1)), time.Unix(int64(i+1), 0)); err != nil {
+			t.Fatalf("failed to write the frame: %s", err)
+		}
+	}
+	if _, _, err := r.Read(); err != nil {
+		t.Fatalf("failed to read the frame: %s", err)
+	}
+	metadata, _ := video.MetadataOf(r)
+	if metadata.Sequence != 4 {
+		t.Fatalf("expected the frame 4, but got %d", metadata.Sequence)
+	}
+}
+
+func TestAudio(t *testing.T) {
+	name := segmentName(t)
+	sink, err := Create(name, 64)
+	if err != nil {
+		t.Fatalf("failed to create the sink: %s", err)
+	}
+	defer sink.Close()
+	source, err := Open(name)
+	if err != nil {
+		t.Fatalf("failed to open the source: %s", err)
+	}
+	defer source.Close()
+	r := source.AudioReader()
+
+	info := wave.ChunkInfo{Len: 3, Channels: 2, SamplingRate: 48000}
+	int16Chunk := wave.NewInt16Interleaved(info)
+	copy(int16Chunk.Data, []int16{1, -2, 3, -4, 5, -32768})
+	float32Chunk := wave.NewFloat32Interleaved(info)
+	copy(float32Chunk.Data, []float32{0.5, -0.25, 1, -1, 0, 0.125})
+
+	for _, chunk := range []wave.Audio{int16Chunk, float32Chunk} {
+		if err := sink.WriteAudio(chunk, time.Now()); err != nil {
+			t.Fatalf("failed to write the chunk: %s", err)
+		}
+		read, _, err := r.Read()
+		if err != nil {
+			t.Fatalf("failed to read the chunk: %s", err)
+		}
+		if !reflect.DeepEqual(read, chunk) {
+			t.Fatalf("expected %v, but got %v", chunk, read)
+		}
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	if _, err := Open(segmentName(t)); err == nil {
+		t.Fatalf("expected an error for the segment which doesn't exist")
+	}
+}
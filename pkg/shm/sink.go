@@ -0,0 +1,191 @@
+package shm
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const defaultSlots = 4
+
+// Sink writes frames or audio chunks to a segment for other processes.
+type Sink struct {
+	name   string
+	seg    *segment
+	layout layout
+
+	mu        sync.Mutex
+	published uint64
+	rgba      *image.RGBA
+	closed    bool
+}
+
+// SinkOption configures Sink.
+type SinkOption func(*Sink)
+
+// WithSlots sets the ring's slot count. Readers that are behind by more than the slots drop
+// frames. The default is 4.
+func WithSlots(slots int) SinkOption {
+	return func(s *Sink) {
+		s.layout.slots = slots
+	}
+}
+
+// Create creates a segment named name, whose slots hold payloads up to slotSize bytes, e.g. width*height*3/2
+// for I420 frames or width*height*4 for RGBA frames. On Unix, an existing segment with the same name is
+// replaced, and its readers keep reading the old one until they reopen it. On Windows, it fails while the existing
+// one is still open.
+func Create(name string, slotSize int, opts ...SinkOption) (*Sink, error) {
+	s := &Sink{
+		name:   name,
+		layout: layout{slots: defaultSlots, slotSize: (slotSize + 63) &^ 63},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.layout.slots <= 0 || s.layout.slotSize <= 0 {
+		return nil, errInvalidSegment
+	}
+
+	seg, err := createSegment(name, s.layout.size())
+	if err != nil {
+		return nil, err
+	}
+	s.seg = seg
+	*seg.uint32At(offsetVersion) = version
+	*seg.uint32At(offsetSlots) = uint32(s.layout.slots)
+	*seg.uint32At(offsetSlotSize) = uint32(s.layout.slotSize)
+	// Readers don't accept the segment until the header is complete
+	atomic.StoreUint32(seg.uint32At(offsetMagic), magic)
+	return s, nil
+}
+
+// WriteVideo publishes img, which was captured at captureTime. 4:2:0 YCbCr frames are written as I420, and
+// others are written as RGBA.
+func (s *Sink) WriteVideo(img image.Image, captureTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+
+	rect := img.Bounds()
+	width, height := rect.Dx(), rect.Dy()
+	if yuv, ok := img.(*image.YCbCr); ok && yuv.SubsampleRatio == image.YCbCrSubsampleRatio420 {
+		cw, ch := (width+1)/2, (height+1)/2
+		return s.publish(formatI420, width, height, captureTime, width*height+2*cw*ch, func(payload []byte) {
+			i := 0
+			for y := rect.Min.Y; y < rect.Max.Y; y++ {
+				offset := yuv.YOffset(rect.Min.X, y)
+				i += copy(payload[i:], yuv.Y[offset:offset+width])
+			}
+			for _, plane := range [][]byte{yuv.Cb, yuv.Cr} {
+				for y := 0; y < ch; y++ {
+					offset := yuv.COffset(rect.Min.X, rect.Min.Y+2*y)
+					i += copy(payload[i:], plane[offset:offset+cw])
+				}
+			}
+		})
+	}
+
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		if s.rgba == nil || s.rgba.Rect != rect {
+			s.rgba = image.NewRGBA(rect)
+		}
+		draw.Draw(s.rgba, rect, img, rect.Min, draw.Src)
+		rgba = s.rgba
+	}
+	return s.publish(formatRGBA, width, height, captureTime, 4*width*height, func(payload []byte) {
+		i := 0
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			offset := rgba.PixOffset(rect.Min.X, y)
+			i += copy(payload[i:], rgba.Pix[offset:offset+4*width])
+		}
+	})
+}
+
+// WriteAudio publishes chunk, whose first sample was captured at captureTime. Chunks that aren't
+// Int16Interleaved or Float32Interleaved are converted to Float32Interleaved.
+func (s *Sink) WriteAudio(chunk wave.Audio, captureTime time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+
+	info := chunk.ChunkInfo()
+	if c, ok := chunk.(*wave.Int16Interleaved); ok {
+		return s.publish(formatS16I, info.Channels, info.SamplingRate, captureTime, 2*len(c.Data), func(payload []byte) {
+			for i, v := range c.Data {
+				*(*int16)(unsafe.Pointer(&payload[2*i])) = v
+			}
+		})
+	}
+
+	c, ok := chunk.(*wave.Float32Interleaved)
+	if !ok {
+		converted, err := wave.NewConverter(wave.TypeFloat32Interleaved, false).Convert(chunk)
+		if err != nil {
+			return err
+		}
+		c = converted.(*wave.Float32Interleaved)
+	}
+	return s.publish(formatF32I, info.Channels, info.SamplingRate, captureTime, 4*len(c.Data), func(payload []byte) {
+		for i, v := range c.Data {
+			*(*uint32)(unsafe.Pointer(&payload[4*i])) = math.Float32bits(v)
+		}
+	})
+}
+
+// publish writes the next frame with write, which fills a payload of size bytes.
+func (s *Sink) publish(format uint32, width, height int, captureTime time.Time, size int, write func([]byte)) error {
+	if size > s.layout.slotSize {
+		return errFrameTooLarge
+	}
+
+	n := s.published
+	slot := s.layout.slot(n)
+	seq := s.seg.uint64At(slot + offsetSeq)
+	atomic.StoreUint64(seq, 2*n+1)
+
+	var nsec int64
+	if !captureTime.IsZero() {
+		nsec = captureTime.UnixNano()
+	}
+	*s.seg.uint64At(slot + offsetTime) = uint64(nsec)
+	*s.seg.uint32At(slot + offsetSize) = uint32(size)
+	*s.seg.uint32At(slot + offsetFormat) = format
+	*s.seg.uint32At(slot + offsetWidth) = uint32(width)
+	*s.seg.uint32At(slot + offsetHeight) = uint32(height)
+	payload := slot + slotHeaderSize
+	write(s.seg.mem[payload : payload+size])
+
+	atomic.StoreUint64(seq, 2*n+2)
+	s.published = n + 1
+	atomic.StoreUint64(s.seg.uint64At(offsetPublished), s.published)
+	return nil
+}
+
+// Close marks the segment closed, so readers return io.EOF after the last frame, and removes it.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	s.closed = true
+
+	atomic.StoreUint32(s.seg.uint32At(offsetClosed), 1)
+	err := s.seg.unmap()
+	if removeErr := removeSegment(s.name); err == nil {
+		err = removeErr
+	}
+	return err
+}
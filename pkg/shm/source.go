@@ -0,0 +1,264 @@
+package shm
+
+import (
+	"image"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+const defaultPollInterval = time.Millisecond
+
+// Source reads frames or audio chunks another process writes to a segment.
+type Source struct {
+	seg          *segment
+	layout       layout
+	pollInterval time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// SourceOption configures Source.
+type SourceOption func(*Source)
+
+// WithPollInterval sets how often readers check for the next frame while they wait for it, since the writer
+// doesn't notify them. The default is 1ms.
+func WithPollInterval(d time.Duration) SourceOption {
+	return func(s *Source) {
+		s.pollInterval = d
+	}
+}
+
+// Open opens the segment named name, which a Sink or another process following the same layout created.
+func Open(name string, opts ...SourceOption) (*Source, error) {
+	seg, err := openSegment(name)
+	if err != nil {
+		return nil, err
+	}
+	l, err := readLayout(seg)
+	if err != nil {
+		seg.unmap()
+		return nil, err
+	}
+
+	s := &Source{
+		seg:          seg,
+		layout:       l,
+		pollInterval: defaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Close unmaps the segment. Readers return an error after it's closed.
+func (s *Source) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+	s.closed = true
+	return s.seg.unmap()
+}
+
+// frame is a frame copied from a slot.
+type frame struct {
+	sequence    uint64
+	captureTime time.Time
+	format      uint32
+	width       int
+	height      int
+	payload     []byte
+}
+
+// cursor is a reader's position in the ring.
+type cursor struct {
+	source *Source
+	next   uint64
+	buff   []byte
+}
+
+// newCursor starts at the next frame, so readers don't return stale frames.
+func (s *Source) newCursor() *cursor {
+	return &cursor{
+		source: s,
+		next:   atomic.LoadUint64(s.seg.uint64At(offsetPublished)),
+	}
+}
+
+// read waits for the next frame, and copies it into the cursor's buffer, which the next read reuses.
+func (c *cursor) read() (frame, error) {
+	for {
+		f, ok, err := c.tryRead()
+		if ok || err != nil {
+			return f, err
+		}
+		time.Sleep(c.source.pollInterval)
+	}
+}
+
+func (c *cursor) tryRead() (frame, bool, error) {
+	s := c.source
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return frame{}, false, errClosed
+	}
+
+	published := atomic.LoadUint64(s.seg.uint64At(offsetPublished))
+	if published == c.next {
+		if atomic.LoadUint32(s.seg.uint32At(offsetClosed)) != 0 {
+			return frame{}, false, io.EOF
+		}
+		return frame{}, false, nil
+	}
+	if published-c.next > uint64(s.layout.slots) {
+		// The writer lapped the reader, so skip to the latest frame
+		c.next = published - 1
+	}
+
+	n := c.next
+	slot := s.layout.slot(n)
+	seq := s.seg.uint64At(slot + offsetSeq)
+	if atomic.LoadUint64(seq) != 2*n+2 {
+		c.next = published - 1
+		return frame{}, false, nil
+	}
+	f := frame{
+		sequence: n,
+		format:   *s.seg.uint32At(slot + offsetFormat),
+		width:    int(*s.seg.uint32At(slot + offsetWidth)),
+		height:   int(*s.seg.uint32At(slot + offsetHeight)),
+	}
+	if nsec := int64(*s.seg.uint64At(slot + offsetTime)); nsec != 0 {
+		f.captureTime = time.Unix(0, nsec)
+	}
+	size := int(*s.seg.uint32At(slot + offsetSize))
+	if size > s.layout.slotSize {
+		return frame{}, false, errInvalidSegment
+	}
+	if cap(c.buff) < size {
+		c.buff = make([]byte, size)
+	}
+	c.buff = c.buff[:size]
+	payload := slot + slotHeaderSize
+	copy(c.buff, s.seg.mem[payload:payload+size])
+	if atomic.LoadUint64(seq) != 2*n+2 {
+		// The slot was overwritten while it was copied
+		c.next = n + 1
+		return frame{}, false, nil
+	}
+	c.next = n + 1
+	f.payload = c.buff
+	return f, true, nil
+}
+
+// VideoReader returns a reader of frames written after it's created. Frames are *image.YCbCr
+// for I420 or *image.RGBA, which refer to the reader's buffer until the next Read. The reader is a
+// video.MetadataReader with the writer's capture times and sequences.
+func (s *Source) VideoReader() video.Reader {
+	c := s.newCursor()
+	var metadata video.Metadata
+	r := video.ReaderFunc(func() (image.Image, func(), error) {
+		f, err := c.read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		img, err := decodeVideo(f)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		metadata = video.Metadata{CaptureTime: f.captureTime, Sequence: f.sequence}
+		return img, func() {}, nil
+	})
+	return video.NewMetadataReader(r, func() video.Metadata {
+		return metadata
+	})
+}
+
+func decodeVideo(f frame) (image.Image, error) {
+	w, h := f.width, f.height
+	rect := image.Rect(0, 0, w, h)
+	switch f.format {
+	case formatI420:
+		cw, ch := (w+1)/2, (h+1)/2
+		ySize, cSize := w*h, cw*ch
+		if len(f.payload) != ySize+2*cSize {
+			return nil, errInvalidSegment
+		}
+		return &image.YCbCr{
+			Y:              f.payload[:ySize],
+			Cb:             f.payload[ySize : ySize+cSize],
+			Cr:             f.payload[ySize+cSize:],
+			YStride:        w,
+			CStride:        cw,
+			SubsampleRatio: image.YCbCrSubsampleRatio420,
+			Rect:           rect,
+		}, nil
+	case formatRGBA:
+		if len(f.payload) != 4*w*h {
+			return nil, errInvalidSegment
+		}
+		return &image.RGBA{Pix: f.payload, Stride: 4 * w, Rect: rect}, nil
+	default:
+		return nil, errUnexpectedFormat
+	}
+}
+
+// AudioReader returns a reader of audio chunks written after it's created. Chunks are
+// Int16Interleaved or Float32Interleaved as they're written.
+func (s *Source) AudioReader() audio.Reader {
+	c := s.newCursor()
+	return audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		f, err := c.read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		chunk, err := decodeAudio(f)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		return chunk, func() {}, nil
+	})
+}
+
+func decodeAudio(f frame) (wave.Audio, error) {
+	info := wave.ChunkInfo{Channels: f.width, SamplingRate: f.height}
+	if info.Channels <= 0 {
+		return nil, errInvalidSegment
+	}
+	switch f.format {
+	case formatS16I:
+		if len(f.payload)%(2*info.Channels) != 0 {
+			return nil, errInvalidSegment
+		}
+		info.Len = len(f.payload) / (2 * info.Channels)
+		chunk := wave.NewInt16Interleaved(info)
+		for i := range chunk.Data {
+			chunk.Data[i] = *(*int16)(unsafe.Pointer(&f.payload[2*i]))
+		}
+		return chunk, nil
+	case formatF32I:
+		if len(f.payload)%(4*info.Channels) != 0 {
+			return nil, errInvalidSegment
+		}
+		info.Len = len(f.payload) / (4 * info.Channels)
+		chunk := wave.NewFloat32Interleaved(info)
+		for i := range chunk.Data {
+			chunk.Data[i] = math.Float32frombits(*(*uint32)(unsafe.Pointer(&f.payload[4*i])))
+		}
+		return chunk, nil
+	default:
+		return nil, errUnexpectedFormat
+	}
+}
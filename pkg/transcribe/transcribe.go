@@ -0,0 +1,242 @@
+// Package transcribe taps track audio for speech recognition engines, e.g. whisper.cpp or a cloud speech-to-text
+// service. Audio is read before it's encoded for streaming, and framed as 16kHz mono PCM, which most engines
+// take. Frame and transcript times can follow the track's MediaClock, so captions line up with the video.
+package transcribe
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/mediadevices/pkg/wave/mixer"
+)
+
+// SampleRate is the sample rate of every Frame.
+const SampleRate = 16000
+
+const defaultFrameDuration = 20 * time.Millisecond
+
+var (
+	errClosed        = errors.New("transcribe: the transcriber is closed")
+	errNotAudio      = errors.New("transcribe: the track isn't an audio track")
+	errFrameDuration = errors.New("transcribe: the frame duration must be a multiple of 1ms")
+)
+
+// Frame is a chunk of a track's audio as 16kHz mono PCM.
+type Frame struct {
+	Samples []int16
+	// Time is when the first sample was captured: the elapsed time of the WithClock MediaClock, which matches the
+	// track's RTP timestamps, or the time since the tap started without a clock. Times are counted in samples, so
+	// read jitter doesn't affect them.
+	Time time.Duration
+}
+
+// Duration returns how long the samples of f last.
+func (f Frame) Duration() time.Duration {
+	return time.Duration(len(f.Samples)) * time.Second / SampleRate
+}
+
+// Transcript is text recognized from frames.
+type Transcript struct {
+	Text string
+	// Start and End bound the speech of the text, on the same timeline as Frame.Time.
+	Start, End time.Duration
+	// Final is false for a partial result, which a later transcript with the same Start replaces.
+	Final bool
+}
+
+// Recognizer is a speech recognition engine.
+type Recognizer interface {
+	// Recognize takes a frame and returns any transcripts recognized so far. Engines that recognize in the background
+	// return the transcripts received since the last call.
+	Recognize(frame Frame) ([]Transcript, error)
+	// Flush returns transcripts for frames not yet recognized, at the end of the audio.
+	Flush() ([]Transcript, error)
+}
+
+// Option configures a Tap.
+type Option func(*config)
+
+type config struct {
+	frameDuration time.Duration
+	clock         *mediadevices.MediaClock
+}
+
+// WithFrameDuration sets the frame duration. The default is 20ms, which most streaming engines and voice
+// activity detectors accept. Engines that work on longer windows, e.g. whisper.cpp, buffer frames themselves.
+func WithFrameDuration(d time.Duration) Option {
+	return func(c *config) {
+		c.frameDuration = d
+	}
+}
+
+// WithClock sets the clock for frame times. Pass the MediaClock of the track's CodecSelector, so times line up
+// with the RTP timestamps of this and the other tracks on that clock.
+func WithClock(clock *mediadevices.MediaClock) Option {
+	return func(c *config) {
+		c.clock = clock
+	}
+}
+
+// Tap reads a track's audio as frames of 16kHz mono PCM.
+type Tap struct {
+	reader  audio.Reader
+	clock   *mediadevices.MediaClock
+	started bool
+	start   time.Duration
+	samples int64
+}
+
+// NewTap creates a Tap of track. Audio is read after the track's transforms, e.g. noise suppression, and the
+// track can be encoded for streaming at the same time.
+func NewTap(track mediadevices.Track, opts ...Option) (*Tap, error) {
+	t, ok := track.(*mediadevices.AudioTrack)
+	if !ok {
+		return nil, errNotAudio
+	}
+
+	c := config{frameDuration: defaultFrameDuration}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.frameDuration < time.Millisecond || c.frameDuration%time.Millisecond != 0 {
+		return nil, errFrameDuration
+	}
+
+	tap := &Tap{clock: c.clock}
+	source := t.NewReader(false)
+	first := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		chunk, release, err := source.Read()
+		if err == nil && !tap.started {
+			tap.started = true
+			if tap.clock != nil {
+				// The first chunk took its own duration to capture, so it started before now
+				info := chunk.ChunkInfo()
+				tap.start = tap.clock.Elapsed()
+				if info.SamplingRate > 0 {
+					tap.start -= time.Duration(int64(info.Len) * int64(time.Second) / int64(info.SamplingRate))
+				}
+			}
+		}
+		return chunk, release, err
+	})
+
+	rMix := audio.NewChannelMixer(1, &mixer.MonoMixer{})
+	rResample := audio.NewResampler(SampleRate)
+	rConv := audio.NewConverter(true, wave.TypeInt16Interleaved)
+	rBuf := audio.NewBuffer(int(int64(SampleRate) * int64(c.frameDuration) / int64(time.Second)))
+	tap.reader = rBuf(rConv(rResample(rMix(first))))
+	return tap, nil
+}
+
+// Read reads the next frame. It returns io.EOF once the track has ended.
+func (t *Tap) Read() (Frame, error) {
+	chunk, _, err := t.reader.Read()
+	if err != nil {
+		return Frame{}, err
+	}
+	b, ok := chunk.(*wave.Int16Interleaved)
+	if !ok {
+		return Frame{}, errors.New("transcribe: unknown type of audio buffer")
+	}
+
+	frame := Frame{
+		Samples: b.Data,
+		Time:    t.start + time.Duration(t.samples*int64(time.Second)/SampleRate),
+	}
+	t.samples += int64(len(b.Data))
+	return frame, nil
+}
+
+// Transcriber feeds a track's audio to a Recognizer.
+type Transcriber struct {
+	recognizer Recognizer
+	handler    func(Transcript)
+	done       chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	err    error
+}
+
+// NewTranscriber starts recognizing track's audio with recognizer and passes the transcripts to handler.
+// handler runs on the transcriber's goroutine, so no frames are read while it blocks. See NewTap for the options.
+func NewTranscriber(track mediadevices.Track, recognizer Recognizer, handler func(Transcript), opts ...Option) (*Transcriber, error) {
+	tap, err := NewTap(track, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transcriber{
+		recognizer: recognizer,
+		handler:    handler,
+		done:       make(chan struct{}),
+	}
+	go t.run(tap)
+	return t, nil
+}
+
+func (t *Transcriber) run(tap *Tap) {
+	defer close(t.done)
+	for {
+		frame, err := tap.Read()
+		if err != nil {
+			if err != io.EOF {
+				t.fail(err)
+			}
+			return
+		}
+
+		t.mu.Lock()
+		closed := t.closed
+		t.mu.Unlock()
+		if closed {
+			return
+		}
+
+		transcripts, err := t.recognizer.Recognize(frame)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		t.emit(transcripts)
+	}
+}
+
+func (t *Transcriber) emit(transcripts []Transcript) {
+	for _, transcript := range transcripts {
+		t.handler(transcript)
+	}
+}
+
+func (t *Transcriber) fail(err error) {
+	t.mu.Lock()
+	t.err = err
+	t.mu.Unlock()
+}
+
+// Close stops recognition after the current frame and passes the recognizer's flushed transcripts to the
+// handler. It returns the error that stopped recognition, if any. It doesn't close the recognizer.
+func (t *Transcriber) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return errClosed
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	<-t.done
+	transcripts, err := t.recognizer.Flush()
+	t.emit(transcripts)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package transcribe
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+type testAudioSource struct {
+	info wave.ChunkInfo
+}
+
+func (s *testAudioSource) Read() (wave.Audio, func(), error) {
+	chunk := wave.NewFloat32Interleaved(s.info)
+	for i := range chunk.Data {
+		chunk.Data[i] = 0.5
+	}
+	return chunk, func() {}, nil
+}
+
+func (s *testAudioSource) ID() string   { return "mic" }
+func (s *testAudioSource) Close() error { return nil }
+
+func TestTap(t *testing.T) {
+	testCases := map[string]wave.ChunkInfo{
+		"Stereo48k": {Len: 480, Channels: 2, SamplingRate: 48000},
+		"Mono16k":   {Len: 160, Channels: 1, SamplingRate: 16000},
+	}
+	for name, info := range testCases {
+		info := info
+		t.Run(name, func(t *testing.T) {
+			track := mediadevices.NewAudioTrack(&testAudioSource{info: info}, nil)
+			defer track.Close()
+
+			tap, err := NewTap(track, WithFrameDuration(30*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 5; i++ {
+				frame, err := tap.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(frame.Samples) != 480 {
+					t.Fatalf("expected 480 samples, but got %d", len(frame.Samples))
+				}
+				if expected := time.Duration(i) * 30 * time.Millisecond; frame.Time != expected {
+					t.Fatalf("expected the frame %d at %v, but got %v", i, expected, frame.Time)
+				}
+				if frame.Duration() != 30*time.Millisecond {
+					t.Fatalf("expected the duration of 30ms, but got %v", frame.Duration())
+				}
+			}
+		})
+	}
+}
+
+func TestTapClock(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := mediadevices.NewMediaClock(mediadevices.WithTimeSource(func() time.Time { return now }))
+	clock.Now()
+	now = now.Add(time.Second)
+
+	track := mediadevices.NewAudioTrack(&testAudioSource{info: wave.ChunkInfo{Len: 160, Channels: 1, SamplingRate: 16000}}, nil)
+	defer track.Close()
+	tap, err := NewTap(track, WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		frame, err := tap.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The first 10ms chunk was captured before the read
+		if expected := time.Second - 10*time.Millisecond + time.Duration(i)*20*time.Millisecond; frame.Time != expected {
+			t.Fatalf("expected the frame %d at %v, but got %v", i, expected, frame.Time)
+		}
+	}
+}
+
+func TestNewTapError(t *testing.T) {
+	track := mediadevices.NewAudioTrack(&testAudioSource{info: wave.ChunkInfo{Len: 160, Channels: 1, SamplingRate: 16000}}, nil)
+	defer track.Close()
+	if _, err := NewTap(track, WithFrameDuration(time.Microsecond)); err != errFrameDuration {
+		t.Fatalf("expected %v, but got %v", errFrameDuration, err)
+	}
+}
+
+// testRecognizer recognizes one word every 5 frames.
+type testRecognizer struct {
+	mu     sync.Mutex
+	frames []Frame
+}
+
+func (r *testRecognizer) Recognize(frame Frame) ([]Transcript, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = append(r.frames, frame)
+	if len(r.frames) < 5 {
+		return nil, nil
+	}
+	return r.flush(), nil
+}
+
+func (r *testRecognizer) Flush() ([]Transcript, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.frames) == 0 {
+		return nil, nil
+	}
+	transcript := r.flush()
+	transcript[0].Text = "flushed"
+	return transcript, nil
+}
+
+func (r *testRecognizer) flush() []Transcript {
+	last := r.frames[len(r.frames)-1]
+	transcript := Transcript{
+		Text:  "word",
+		Start: r.frames[0].Time,
+		End:   last.Time + last.Duration(),
+		Final: true,
+	}
+	r.frames = r.frames[:0]
+	return []Transcript{transcript}
+}
+
+func TestTranscriber(t *testing.T) {
+	track := mediadevices.NewAudioTrack(&testAudioSource{info: wave.ChunkInfo{Len: 960, Channels: 2, SamplingRate: 48000}}, nil)
+	defer track.Close()
+
+	var mu sync.Mutex
+	var transcripts []Transcript
+	received := make(chan struct{}, 100)
+	transcriber, err := NewTranscriber(track, &testRecognizer{}, func(transcript Transcript) {
+		mu.Lock()
+		transcripts = append(transcripts, transcript)
+		mu.Unlock()
+		received <- struct{}{}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("expected the transcripts")
+		}
+	}
+	if err := transcriber.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := transcriber.Close(); err != errClosed {
+		t.Fatalf("expected %v, but got %v", errClosed, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, transcript := range transcripts {
+		if transcript.Text == "flushed" {
+			if i != len(transcripts)-1 {
+				t.Fatal("expected the flushed transcript to be the last one")
+			}
+			continue
+		}
+		if expected := time.Duration(i) * 100 * time.Millisecond; transcript.Start != expected || transcript.End != expected+100*time.Millisecond {
+			t.Fatalf("expected the transcript %d from %v to %v, but got %+v", i, expected, expected+100*time.Millisecond, transcript)
+		}
+	}
+}
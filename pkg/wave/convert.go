@@ -0,0 +1,186 @@
+package wave
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+)
+
+var errUnsupportedType = errors.New("unsupported audio type")
+
+// Type combines an Audio's sample type and channel layout.
+type Type int
+
+// Type values.
+const (
+	TypeUnknown Type = iota
+	TypeInt16Interleaved
+	TypeInt16NonInterleaved
+	TypeInt32Interleaved
+	TypeInt32NonInterleaved
+	TypeFloat32Interleaved
+	TypeFloat32NonInterleaved
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeInt16Interleaved:
+		return "Int16Interleaved"
+	case TypeInt16NonInterleaved:
+		return "Int16NonInterleaved"
+	case TypeInt32Interleaved:
+		return "Int32Interleaved"
+	case TypeInt32NonInterleaved:
+		return "Int32NonInterleaved"
+	case TypeFloat32Interleaved:
+		return "Float32Interleaved"
+	case TypeFloat32NonInterleaved:
+		return "Float32NonInterleaved"
+	default:
+		return "Unknown"
+	}
+}
+
+// TypeOf returns a's type.
+func TypeOf(a Audio) Type {
+	switch a.(type) {
+	case *Int16Interleaved:
+		return TypeInt16Interleaved
+	case *Int16NonInterleaved:
+		return TypeInt16NonInterleaved
+	case *Int32Interleaved:
+		return TypeInt32Interleaved
+	case *Int32NonInterleaved:
+		return TypeInt32NonInterleaved
+	case *Float32Interleaved:
+		return TypeFloat32Interleaved
+	case *Float32NonInterleaved:
+		return TypeFloat32NonInterleaved
+	default:
+		return TypeUnknown
+	}
+}
+
+// New creates an Audio of type t with size.
+func (t Type) New(size ChunkInfo) (EditableAudio, error) {
+	switch t {
+	case TypeInt16Interleaved:
+		return NewInt16Interleaved(size), nil
+	case TypeInt16NonInterleaved:
+		return NewInt16NonInterleaved(size), nil
+	case TypeInt32Interleaved:
+		return NewInt32Interleaved(size), nil
+	case TypeInt32NonInterleaved:
+		return NewInt32NonInterleaved(size), nil
+	case TypeFloat32Interleaved:
+		return NewFloat32Interleaved(size), nil
+	case TypeFloat32NonInterleaved:
+		return NewFloat32NonInterleaved(size), nil
+	default:
+		return nil, errUnsupportedType
+	}
+}
+
+// bits returns the samples' bit depth. Float32 has 24 bits of precision.
+func (t Type) bits() int {
+	switch t {
+	case TypeInt16Interleaved, TypeInt16NonInterleaved:
+		return 16
+	case TypeFloat32Interleaved, TypeFloat32NonInterleaved:
+		return 24
+	default:
+		return 32
+	}
+}
+
+// Converter converts Audio between sample types and layouts. Samples are scaled so the integer types' full scale
+// matches Float32's [-1, 1].
+type Converter struct {
+	typ    Type
+	dither bool
+	rand   *rand.Rand
+}
+
+// NewConverter creates a Converter into t. If dither is true, TPDF (triangular probability density function)
+// dither of 1 LSB is added when bit depth is reduced to Int16, so quantization error becomes a constant
+// noise floor instead of distortion correlated with the signal.
+func NewConverter(t Type, dither bool) *Converter {
+	return &Converter{
+		typ:    t,
+		dither: dither,
+		rand:   rand.New(rand.NewSource(1)),
+	}
+}
+
+// Convert returns src converted into the Converter's type. src is returned as is if it already has the type.
+// Convert isn't safe for concurrent use by multiple goroutines when dither is enabled.
+func (c *Converter) Convert(src Audio) (Audio, error) {
+	srcType := TypeOf(src)
+	if srcType == c.typ {
+		return src, nil
+	}
+	if srcType == TypeUnknown {
+		return nil, errUnsupportedType
+	}
+
+	info := src.ChunkInfo()
+	dst, err := c.typ.New(info)
+	if err != nil {
+		return nil, err
+	}
+
+	dither := c.dither && c.typ.bits() < srcType.bits()
+	var set func(i, ch int, v float64)
+	switch d := dst.(type) {
+	case *Int16Interleaved:
+		set = func(i, ch int, v float64) { d.SetInt16(i, ch, Int16Sample(c.quantize(v, math.MaxInt16, dither))) }
+	case *Int16NonInterleaved:
+		set = func(i, ch int, v float64) { d.SetInt16(i, ch, Int16Sample(c.quantize(v, math.MaxInt16, dither))) }
+	case *Int32Interleaved:
+		set = func(i, ch int, v float64) { d.SetInt32(i, ch, Int32Sample(c.quantize(v, math.MaxInt32, false))) }
+	case *Int32NonInterleaved:
+		set = func(i, ch int, v float64) { d.SetInt32(i, ch, Int32Sample(c.quantize(v, math.MaxInt32, false))) }
+	case *Float32Interleaved:
+		set = func(i, ch int, v float64) { d.SetFloat32(i, ch, Float32Sample(v)) }
+	case *Float32NonInterleaved:
+		set = func(i, ch int, v float64) { d.SetFloat32(i, ch, Float32Sample(v)) }
+	}
+
+	for i := 0; i < info.Len; i++ {
+		for ch := 0; ch < info.Channels; ch++ {
+			set(i, ch, toFloat(src.At(i, ch)))
+		}
+	}
+	return dst, nil
+}
+
+// quantize scales v in [-1, 1] to an integer in [-max-1, max], with rounding.
+func (c *Converter) quantize(v float64, max int64, dither bool) int64 {
+	v *= float64(max + 1)
+	if dither {
+		// The sum of two uniform random values has a triangular distribution in (-1, 1).
+		v += c.rand.Float64() - c.rand.Float64()
+	}
+	v = math.Round(v)
+	if v > float64(max) {
+		return max
+	}
+	if v < float64(-max-1) {
+		return -max - 1
+	}
+	return int64(v)
+}
+
+// toFloat returns the sample scaled to [-1, 1].
+func toFloat(s Sample) float64 {
+	switch v := s.(type) {
+	case Int16Sample:
+		return float64(v) / -math.MinInt16
+	case Int32Sample:
+		return float64(v) / -math.MinInt32
+	case Float32Sample:
+		return float64(v)
+	default:
+		return float64(s.Int()) / -math.MinInt32
+	}
+}
@@ -0,0 +1,116 @@
+package wave
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConverter(t *testing.T) {
+	t.Run("Layout", func(t *testing.T) {
+		in := &Int16Interleaved{
+			Data: []int16{1, -5, 2, -6, 3, -7},
+			Size: ChunkInfo{3, 2, 48000},
+		}
+		out, err := NewConverter(TypeInt16NonInterleaved, true).Convert(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := &Int16NonInterleaved{
+			Data: [][]int16{{1, 2, 3}, {-5, -6, -7}},
+			Size: ChunkInfo{3, 2, 48000},
+		}
+		if !reflect.DeepEqual(expected, out) {
+			t.Fatalf("expected %v, but got %v", expected, out)
+		}
+	})
+
+	t.Run("SameType", func(t *testing.T) {
+		in := NewFloat32Interleaved(ChunkInfo{4, 1, 48000})
+		out, err := NewConverter(TypeFloat32Interleaved, false).Convert(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != Audio(in) {
+			t.Fatal("expected the same type to be returned as is")
+		}
+	})
+
+	t.Run("Scale", func(t *testing.T) {
+		in := &Float32Interleaved{
+			Data: []float32{0, 0.5, -1, 1.5},
+			Size: ChunkInfo{4, 1, 48000},
+		}
+		testCases := map[Type]Audio{
+			TypeInt16Interleaved: &Int16Interleaved{
+				Data: []int16{0, 16384, -32768, 32767},
+				Size: ChunkInfo{4, 1, 48000},
+			},
+			TypeInt32Interleaved: &Int32Interleaved{
+				Data: []int32{0, 1 << 30, -1 << 31, 1<<31 - 1},
+				Size: ChunkInfo{4, 1, 48000},
+			},
+		}
+		for typ, expected := range testCases {
+			out, err := NewConverter(typ, false).Convert(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expected, out) {
+				t.Errorf("%s: expected %v, but got %v", typ, expected, out)
+			}
+
+			// Back to Float32, without the clipped sample
+			back, err := NewConverter(TypeFloat32Interleaved, false).Convert(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data := back.(*Float32Interleaved).Data; data[0] != 0 || data[1] != 0.5 || data[2] != -1 {
+				t.Errorf("%s: expected [0 0.5 -1 ...], but got %v", typ, data)
+			}
+		}
+	})
+
+	t.Run("Int32ToInt16", func(t *testing.T) {
+		in := &Int32Interleaved{
+			Data: []int32{0x12340000, -0x12340000},
+			Size: ChunkInfo{2, 1, 48000},
+		}
+		out, err := NewConverter(TypeInt16Interleaved, false).Convert(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data := out.(*Int16Interleaved).Data; data[0] != 0x1234 || data[1] != -0x1234 {
+			t.Fatalf("expected [%d %d], but got %v", 0x1234, -0x1234, data)
+		}
+	})
+
+	t.Run("Dither", func(t *testing.T) {
+		// A quarter of an Int16 LSB is lost without dither.
+		const n = 10000
+		in := NewFloat32Interleaved(ChunkInfo{n, 1, 48000})
+		for i := range in.Data {
+			in.Data[i] = 0.25 / 32768
+		}
+
+		for _, dither := range []bool{false, true} {
+			out, err := NewConverter(TypeInt16Interleaved, dither).Convert(in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum int
+			for _, v := range out.(*Int16Interleaved).Data {
+				if v < -1 || v > 1 {
+					t.Fatalf("expected the dither within 1 LSB, but got %d", v)
+				}
+				sum += int(v)
+			}
+			mean := float64(sum) / n
+			if !dither && mean != 0 {
+				t.Errorf("expected the mean 0 without the dither, but got %f", mean)
+			}
+			if dither && (mean < 0.2 || mean > 0.3) {
+				t.Errorf("expected the mean around 0.25 with the dither, but got %f", mean)
+			}
+		}
+	})
+}
@@ -47,6 +47,10 @@ func init() {
 	decoderBuilders := []DecoderBuilderFunc{
 		newInt16InterleavedDecoder,
 		newInt16NonInterleavedDecoder,
+		newInt32InterleavedDecoder,
+		newInt32NonInterleavedDecoder,
+		newInt24InterleavedDecoder,
+		newInt24NonInterleavedDecoder,
 		newFloat32InterleavedDecoder,
 		newFloat32NonInterleavedDecoder,
 	}
@@ -72,6 +76,15 @@ func (f DecoderFunc) Decode(endian binary.ByteOrder, chunk []byte, channels int)
 	return f(endian, chunk, channels)
 }
 
+// pooledDecoderFunc is the type of the NewDecoder decoders, which decode into an Audio from a Pool, see
+// Pool.Decode. A nil pool allocates the Audio.
+type pooledDecoderFunc func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error)
+
+func (f pooledDecoderFunc) Decode(endian binary.ByteOrder, chunk []byte, channels int) (Audio, error) {
+	a, _, err := f(nil, endian, chunk, channels)
+	return a, err
+}
+
 // DecoderBuilder builds raw audio decoder
 type DecoderBuilder interface {
 	// NewDecoder creates a new decoder for specified format
@@ -134,14 +147,15 @@ func newInt16InterleavedDecoder() (Decoder, Format) {
 		Interleaved: true,
 	}
 
-	decoder := DecoderFunc(func(endian binary.ByteOrder, chunk []byte, channels int) (Audio, error) {
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
 		sampleSize := format.SampleSize
 		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
 		if err != nil {
-			return nil, err
+			return nil, func() {}, err
 		}
 
-		container := NewInt16Interleaved(chunkInfo)
+		a, release, _ := pool.Get(TypeInt16Interleaved, chunkInfo)
+		container := a.(*Int16Interleaved)
 
 		if endian == hostEndian {
 			data := container.Data
@@ -150,7 +164,7 @@ func newInt16InterleavedDecoder() (Decoder, Format) {
 			n := len(chunk)
 			hdr.Len, hdr.Cap = n, n
 			copy(dst, chunk)
-			return container, nil
+			return container, release, nil
 		}
 
 		sampleLen := sampleSize * channels
@@ -164,7 +178,7 @@ func newInt16InterleavedDecoder() (Decoder, Format) {
 			i++
 		}
 
-		return container, nil
+		return container, release, nil
 
 	})
 
@@ -178,14 +192,15 @@ func newInt16NonInterleavedDecoder() (Decoder, Format) {
 		Interleaved: false,
 	}
 
-	decoder := DecoderFunc(func(endian binary.ByteOrder, chunk []byte, channels int) (Audio, error) {
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
 		sampleSize := format.SampleSize
 		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
 		if err != nil {
-			return nil, err
+			return nil, func() {}, err
 		}
 
-		container := NewInt16NonInterleaved(chunkInfo)
+		a, release, _ := pool.Get(TypeInt16NonInterleaved, chunkInfo)
+		container := a.(*Int16NonInterleaved)
 		chunkLen := len(chunk) / channels
 
 		if endian == hostEndian {
@@ -197,7 +212,7 @@ func newInt16NonInterleavedDecoder() (Decoder, Format) {
 				offset := ch * chunkLen
 				copy(dst, chunk[offset:offset+chunkLen])
 			}
-			return container, nil
+			return container, release, nil
 		}
 
 		for ch := 0; ch < channels; ch++ {
@@ -209,7 +224,170 @@ func newInt16NonInterleavedDecoder() (Decoder, Format) {
 			}
 		}
 
-		return container, nil
+		return container, release, nil
+	})
+
+	return decoder, format
+}
+
+func newInt32InterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  4,
+		IsFloat:     false,
+		Interleaved: true,
+	}
+
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
+		sampleSize := format.SampleSize
+		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		a, release, _ := pool.Get(TypeInt32Interleaved, chunkInfo)
+		container := a.(*Int32Interleaved)
+
+		if endian == hostEndian {
+			data := container.Data
+			dst := *(*[]byte)(unsafe.Pointer(&data))
+			hdr := (*reflect.SliceHeader)(unsafe.Pointer(&dst))
+			n := len(chunk)
+			hdr.Len, hdr.Cap = n, n
+			copy(dst, chunk)
+			return container, release, nil
+		}
+
+		sampleLen := sampleSize * channels
+		var i int
+		for offset := 0; offset+sampleLen <= len(chunk); offset += sampleLen {
+			for ch := 0; ch < channels; ch++ {
+				flatOffset := offset + ch*sampleSize
+				sample := endian.Uint32(chunk[flatOffset : flatOffset+sampleSize])
+				container.SetInt32(i, ch, Int32Sample(sample))
+			}
+			i++
+		}
+
+		return container, release, nil
+
+	})
+
+	return decoder, format
+}
+
+func newInt32NonInterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  4,
+		IsFloat:     false,
+		Interleaved: false,
+	}
+
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
+		sampleSize := format.SampleSize
+		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		a, release, _ := pool.Get(TypeInt32NonInterleaved, chunkInfo)
+		container := a.(*Int32NonInterleaved)
+		chunkLen := len(chunk) / channels
+
+		if endian == hostEndian {
+			for ch := 0; ch < channels; ch++ {
+				data := container.Data[ch]
+				dst := *(*[]byte)(unsafe.Pointer(&data))
+				hdr := (*reflect.SliceHeader)(unsafe.Pointer(&dst))
+				hdr.Len, hdr.Cap = chunkLen, chunkLen
+				offset := ch * chunkLen
+				copy(dst, chunk[offset:offset+chunkLen])
+			}
+			return container, release, nil
+		}
+
+		for ch := 0; ch < channels; ch++ {
+			offset := ch * chunkLen
+			for i := 0; i < chunkInfo.Len; i++ {
+				flatOffset := offset + i*sampleSize
+				sample := endian.Uint32(chunk[flatOffset : flatOffset+sampleSize])
+				container.SetInt32(i, ch, Int32Sample(sample))
+			}
+		}
+
+		return container, release, nil
+	})
+
+	return decoder, format
+}
+
+// int24 returns a packed 24-bit sample as the most significant bits of a 32-bit sample.
+func int24(endian binary.ByteOrder, b []byte) Int32Sample {
+	if endian == binary.BigEndian {
+		return Int32Sample(int32(uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8))
+	}
+	return Int32Sample(int32(uint32(b[2])<<24 | uint32(b[1])<<16 | uint32(b[0])<<8))
+}
+
+// newInt24InterleavedDecoder decodes packed 24-bit samples (S24_3LE/S24_3BE) to Int32Interleaved.
+func newInt24InterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  3,
+		IsFloat:     false,
+		Interleaved: true,
+	}
+
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
+		sampleSize := format.SampleSize
+		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		a, release, _ := pool.Get(TypeInt32Interleaved, chunkInfo)
+		container := a.(*Int32Interleaved)
+		sampleLen := sampleSize * channels
+		var i int
+		for offset := 0; offset+sampleLen <= len(chunk); offset += sampleLen {
+			for ch := 0; ch < channels; ch++ {
+				flatOffset := offset + ch*sampleSize
+				container.SetInt32(i, ch, int24(endian, chunk[flatOffset:flatOffset+sampleSize]))
+			}
+			i++
+		}
+
+		return container, release, nil
+	})
+
+	return decoder, format
+}
+
+// newInt24NonInterleavedDecoder decodes packed 24-bit samples (S24_3LE/S24_3BE) to Int32NonInterleaved.
+func newInt24NonInterleavedDecoder() (Decoder, Format) {
+	format := &RawFormat{
+		SampleSize:  3,
+		IsFloat:     false,
+		Interleaved: false,
+	}
+
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
+		sampleSize := format.SampleSize
+		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		a, release, _ := pool.Get(TypeInt32NonInterleaved, chunkInfo)
+		container := a.(*Int32NonInterleaved)
+		chunkLen := len(chunk) / channels
+		for ch := 0; ch < channels; ch++ {
+			offset := ch * chunkLen
+			for i := 0; i < chunkInfo.Len; i++ {
+				flatOffset := offset + i*sampleSize
+				container.SetInt32(i, ch, int24(endian, chunk[flatOffset:flatOffset+sampleSize]))
+			}
+		}
+
+		return container, release, nil
 	})
 
 	return decoder, format
@@ -222,14 +400,15 @@ func newFloat32InterleavedDecoder() (Decoder, Format) {
 		Interleaved: true,
 	}
 
-	decoder := DecoderFunc(func(endian binary.ByteOrder, chunk []byte, channels int) (Audio, error) {
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
 		sampleSize := format.SampleSize
 		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
 		if err != nil {
-			return nil, err
+			return nil, func() {}, err
 		}
 
-		container := NewFloat32Interleaved(chunkInfo)
+		a, release, _ := pool.Get(TypeFloat32Interleaved, chunkInfo)
+		container := a.(*Float32Interleaved)
 
 		if endian == hostEndian {
 			data := container.Data
@@ -238,7 +417,7 @@ func newFloat32InterleavedDecoder() (Decoder, Format) {
 			n := len(chunk)
 			hdr.Len, hdr.Cap = n, n
 			copy(dst, chunk)
-			return container, nil
+			return container, release, nil
 		}
 
 		sampleLen := sampleSize * channels
@@ -253,7 +432,7 @@ func newFloat32InterleavedDecoder() (Decoder, Format) {
 			i++
 		}
 
-		return container, nil
+		return container, release, nil
 	})
 
 	return decoder, format
@@ -266,14 +445,15 @@ func newFloat32NonInterleavedDecoder() (Decoder, Format) {
 		Interleaved: false,
 	}
 
-	decoder := DecoderFunc(func(endian binary.ByteOrder, chunk []byte, channels int) (Audio, error) {
+	decoder := pooledDecoderFunc(func(pool *Pool, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
 		sampleSize := format.SampleSize
 		chunkInfo, err := calculateChunkInfo(chunk, channels, sampleSize)
 		if err != nil {
-			return nil, err
+			return nil, func() {}, err
 		}
 
-		container := NewFloat32NonInterleaved(chunkInfo)
+		a, release, _ := pool.Get(TypeFloat32NonInterleaved, chunkInfo)
+		container := a.(*Float32NonInterleaved)
 		chunkLen := len(chunk) / channels
 
 		if endian == hostEndian {
@@ -285,7 +465,7 @@ func newFloat32NonInterleavedDecoder() (Decoder, Format) {
 				offset := ch * chunkLen
 				copy(dst, chunk[offset:offset+chunkLen])
 			}
-			return container, nil
+			return container, release, nil
 		}
 
 		for ch := 0; ch < channels; ch++ {
@@ -298,7 +478,7 @@ func newFloat32NonInterleavedDecoder() (Decoder, Format) {
 			}
 		}
 
-		return container, nil
+		return container, release, nil
 	})
 
 	return decoder, format
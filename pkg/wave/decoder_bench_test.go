@@ -37,3 +37,23 @@ func BenchmarkDecoder(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkPoolDecode(b *testing.B) {
+	var pool Pool
+	// The size is a multiple of every format's sample size
+	chunk := make([]byte, 960)
+	for format, decoder := range registeredDecoders {
+		decoder := decoder
+
+		b.Run(format, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, release, err := pool.Decode(decoder, hostEndian, chunk, 2)
+				if err != nil {
+					b.Fatal(err)
+				}
+				release()
+			}
+		})
+	}
+}
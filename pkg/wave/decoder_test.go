@@ -115,6 +115,21 @@ func TestNewDecoder(t *testing.T) {
 			IsFloat:     true,
 			Interleaved: true,
 		},
+		{
+			SampleSize:  4,
+			IsFloat:     false,
+			Interleaved: false,
+		},
+		{
+			SampleSize:  4,
+			IsFloat:     false,
+			Interleaved: true,
+		},
+		{
+			SampleSize:  3,
+			IsFloat:     false,
+			Interleaved: true,
+		},
 	}
 
 	for _, rawFormat := range rawFormats {
@@ -180,6 +195,82 @@ func TestDecodeInt16Interleaved(t *testing.T) {
 	})
 }
 
+func TestDecodeInt32Interleaved(t *testing.T) {
+	raw := []byte{
+		// 32 bits per channel
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+	}
+	decoder, _ := newInt32InterleavedDecoder()
+
+	for name, endian := range map[string]binary.ByteOrder{
+		"BigEndian":    binary.BigEndian,
+		"LittleEndian": binary.LittleEndian,
+	} {
+		endian := endian
+		t.Run(name, func(t *testing.T) {
+			expected := &Int32Interleaved{
+				Data: []int32{
+					int32(endian.Uint32([]byte{0x01, 0x02, 0x03, 0x04})),
+					int32(endian.Uint32([]byte{0x05, 0x06, 0x07, 0x08})),
+				},
+				Size: ChunkInfo{
+					Len:      1,
+					Channels: 2,
+				},
+			}
+			actual, err := decoder.Decode(endian, raw, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(expected, actual) {
+				t.Errorf("Wrong decode result,\nexpected:\n%+v\ngot:\n%+v", expected, actual)
+			}
+		})
+	}
+}
+
+func TestDecodeInt24(t *testing.T) {
+	raw := []byte{
+		// 24 bits per channel
+		0x01, 0x02, 0x03, 0xfd, 0xfe, 0xff,
+	}
+	t.Run("Interleaved", func(t *testing.T) {
+		decoder, _ := newInt24InterleavedDecoder()
+		expected := &Int32Interleaved{
+			Data: []int32{0x03020100, -0x00010300},
+			Size: ChunkInfo{
+				Len:      1,
+				Channels: 2,
+			},
+		}
+		actual, err := decoder.Decode(binary.LittleEndian, raw, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Wrong decode result,\nexpected:\n%+v\ngot:\n%+v", expected, actual)
+		}
+	})
+	t.Run("NonInterleaved", func(t *testing.T) {
+		decoder, _ := newInt24NonInterleavedDecoder()
+		expected := &Int32NonInterleaved{
+			Data: [][]int32{{0x01020300}, {-0x02010100}},
+			Size: ChunkInfo{
+				Len:      1,
+				Channels: 2,
+			},
+		}
+		actual, err := decoder.Decode(binary.BigEndian, raw, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("Wrong decode result,\nexpected:\n%+v\ngot:\n%+v", expected, actual)
+		}
+	})
+}
+
 func TestDecodeInt16NonInterleaved(t *testing.T) {
 	raw := []byte{
 		// 16 bits per channel
@@ -52,7 +52,7 @@ func TestInt16(t *testing.T) {
 	}
 }
 
-func TestInt32SubAudio(t *testing.T) {
+func TestInt16SubAudio(t *testing.T) {
 	t.Run("Interleaved", func(t *testing.T) {
 		in := &Int16Interleaved{
 			Data: []int16{
@@ -0,0 +1,100 @@
+package wave
+
+// Int32Sample is a 32-bits signed integer audio sample.
+type Int32Sample int32
+
+func (s Int32Sample) Int() int64 {
+	return int64(s)
+}
+
+// Int32Interleaved multi-channel interlaced Audio.
+type Int32Interleaved struct {
+	Data []int32
+	Size ChunkInfo
+}
+
+// ChunkInfo returns audio chunk size.
+func (a *Int32Interleaved) ChunkInfo() ChunkInfo {
+	return a.Size
+}
+
+func (a *Int32Interleaved) SampleFormat() SampleFormat {
+	return Int32SampleFormat
+}
+
+func (a *Int32Interleaved) At(i, ch int) Sample {
+	return Int32Sample(a.Data[i*a.Size.Channels+ch])
+}
+
+func (a *Int32Interleaved) Set(i, ch int, s Sample) {
+	a.Data[i*a.Size.Channels+ch] = int32(Int32SampleFormat.Convert(s).(Int32Sample))
+}
+
+func (a *Int32Interleaved) SetInt32(i, ch int, s Int32Sample) {
+	a.Data[i*a.Size.Channels+ch] = int32(s)
+}
+
+// SubAudio returns part of the original audio sharing the buffer.
+func (a *Int32Interleaved) SubAudio(offsetSamples, nSamples int) *Int32Interleaved {
+	ret := *a
+	offset := offsetSamples * a.Size.Channels
+	n := nSamples * a.Size.Channels
+	ret.Data = ret.Data[offset : offset+n]
+	ret.Size.Len = nSamples
+	return &ret
+}
+
+func NewInt32Interleaved(size ChunkInfo) *Int32Interleaved {
+	return &Int32Interleaved{
+		Data: make([]int32, size.Channels*size.Len),
+		Size: size,
+	}
+}
+
+// Int32NonInterleaved multi-channel interlaced Audio.
+type Int32NonInterleaved struct {
+	Data [][]int32
+	Size ChunkInfo
+}
+
+// ChunkInfo returns audio chunk size.
+func (a *Int32NonInterleaved) ChunkInfo() ChunkInfo {
+	return a.Size
+}
+
+func (a *Int32NonInterleaved) SampleFormat() SampleFormat {
+	return Int32SampleFormat
+}
+
+func (a *Int32NonInterleaved) At(i, ch int) Sample {
+	return Int32Sample(a.Data[ch][i])
+}
+
+func (a *Int32NonInterleaved) Set(i, ch int, s Sample) {
+	a.Data[ch][i] = int32(Int32SampleFormat.Convert(s).(Int32Sample))
+}
+
+func (a *Int32NonInterleaved) SetInt32(i, ch int, s Int32Sample) {
+	a.Data[ch][i] = int32(s)
+}
+
+// SubAudio returns part of the original audio sharing the buffer.
+func (a *Int32NonInterleaved) SubAudio(offsetSamples, nSamples int) *Int32NonInterleaved {
+	ret := *a
+	for i := range a.Data {
+		ret.Data[i] = ret.Data[i][offsetSamples : offsetSamples+nSamples]
+	}
+	ret.Size.Len = nSamples
+	return &ret
+}
+
+func NewInt32NonInterleaved(size ChunkInfo) *Int32NonInterleaved {
+	d := make([][]int32, size.Channels)
+	for i := 0; i < size.Channels; i++ {
+		d[i] = make([]int32, size.Len)
+	}
+	return &Int32NonInterleaved{
+		Data: d,
+		Size: size,
+	}
+}
@@ -40,3 +40,37 @@ func (m *MonoMixer) Mix(dst wave.Audio, src wave.Audio) error {
 	}
 	return nil
 }
+
+// ChannelSelector picks the source's channels by 0-based index, e.g. {2, 3} picks the third and
+// fourth inputs of a multichannel interface as stereo.
+type ChannelSelector struct {
+	Channels []int
+}
+
+func (m *ChannelSelector) Mix(dst wave.Audio, src wave.Audio) error {
+	if dst.ChunkInfo().Len != src.ChunkInfo().Len {
+		return errors.New("buffer size mismatch")
+	}
+	if dst.ChunkInfo().Channels != len(m.Channels) {
+		return errors.New("channel count mismatch")
+	}
+	dstSetter, ok := dst.(wave.EditableAudio)
+	if !ok {
+		return errors.New("destination buffer is not settable")
+	}
+
+	channels := src.ChunkInfo().Channels
+	for _, ch := range m.Channels {
+		if ch < 0 || ch >= channels {
+			return errors.New("selected channel is out of range")
+		}
+	}
+
+	n := src.ChunkInfo().Len
+	for i := 0; i < n; i++ {
+		for dstCh, srcCh := range m.Channels {
+			dstSetter.Set(i, dstCh, src.At(i, srcCh))
+		}
+	}
+	return nil
+}
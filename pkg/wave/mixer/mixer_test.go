@@ -78,3 +78,31 @@ func TestMonoMixer(t *testing.T) {
 		})
 	}
 }
+
+func TestChannelSelector(t *testing.T) {
+	src := &wave.Int32Interleaved{
+		Size: wave.ChunkInfo{
+			Len:      2,
+			Channels: 4,
+		},
+		Data: []int32{
+			0, 1, 2, 3,
+			4, 5, 6, 7,
+		},
+	}
+	dst := wave.NewInt32Interleaved(wave.ChunkInfo{Len: 2, Channels: 2})
+
+	m := &ChannelSelector{Channels: []int{3, 1}}
+	if err := m.Mix(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	expected := []int32{3, 1, 7, 5}
+	if !reflect.DeepEqual(expected, dst.Data) {
+		t.Errorf("Mix result is wrong\nexpected: %v\ngot: %v", expected, dst.Data)
+	}
+
+	m = &ChannelSelector{Channels: []int{4, 0}}
+	if err := m.Mix(dst, src); err == nil {
+		t.Error("expected an error for the channel out of range")
+	}
+}
@@ -0,0 +1,117 @@
+package wave
+
+import (
+	"encoding/binary"
+	"sync"
+)
+
+// Pool reuses Audio chunks and their samples once they're released, so sources and transforms don't allocate
+// samples for every chunk of a stream. Each Audio comes with a release function that must be called exactly
+// once, and the Audio must not be used after that. The zero value is ready to use, and it's safe for concurrent
+// use.
+type Pool struct {
+	pool sync.Pool
+}
+
+type pooledAudio struct {
+	audio   EditableAudio
+	release func()
+}
+
+// Get returns an Audio of t with size, like t.New, and a function that releases it to p. Reused Audio isn't
+// cleared. If p is nil, the Audio is allocated and the function does nothing.
+func (p *Pool) Get(t Type, size ChunkInfo) (EditableAudio, func(), error) {
+	if p == nil {
+		a, err := t.New(size)
+		return a, func() {}, err
+	}
+
+	a, _ := p.pool.Get().(*pooledAudio)
+	if a == nil {
+		a = &pooledAudio{}
+		a.release = func() { p.pool.Put(a) }
+	}
+	if !reuse(a.audio, t, size) {
+		audio, err := t.New(size)
+		if err != nil {
+			return nil, func() {}, err
+		}
+		a.audio = audio
+	}
+	return a.audio, a.release, nil
+}
+
+// Decode decodes chunk with d into an Audio from p, and returns a function that releases it. If d isn't one of
+// the NewDecoder decoders, the Audio is allocated.
+func (p *Pool) Decode(d Decoder, endian binary.ByteOrder, chunk []byte, channels int) (Audio, func(), error) {
+	if d, ok := d.(pooledDecoderFunc); ok {
+		return d(p, endian, chunk, channels)
+	}
+	a, err := d.Decode(endian, chunk, channels)
+	return a, func() {}, err
+}
+
+// reuse resizes audio to size if it's a t with enough capacity.
+func reuse(audio EditableAudio, t Type, size ChunkInfo) bool {
+	if audio == nil || TypeOf(audio) != t {
+		return false
+	}
+
+	n := size.Channels * size.Len
+	switch a := audio.(type) {
+	case *Int16Interleaved:
+		if cap(a.Data) < n {
+			return false
+		}
+		a.Data, a.Size = a.Data[:n], size
+	case *Int32Interleaved:
+		if cap(a.Data) < n {
+			return false
+		}
+		a.Data, a.Size = a.Data[:n], size
+	case *Float32Interleaved:
+		if cap(a.Data) < n {
+			return false
+		}
+		a.Data, a.Size = a.Data[:n], size
+	case *Int16NonInterleaved:
+		if cap(a.Data) < size.Channels {
+			return false
+		}
+		a.Data = a.Data[:size.Channels]
+		for ch := range a.Data {
+			if cap(a.Data[ch]) < size.Len {
+				return false
+			}
+			a.Data[ch] = a.Data[ch][:size.Len]
+		}
+		a.Size = size
+	case *Int32NonInterleaved:
+		if cap(a.Data) < size.Channels {
+			return false
+		}
+		a.Data = a.Data[:size.Channels]
+		for ch := range a.Data {
+			if cap(a.Data[ch]) < size.Len {
+				return false
+			}
+			a.Data[ch] = a.Data[ch][:size.Len]
+		}
+		a.Size = size
+	case *Float32NonInterleaved:
+		if cap(a.Data) < size.Channels {
+			return false
+		}
+		a.Data = a.Data[:size.Channels]
+		for ch := range a.Data {
+			if cap(a.Data[ch]) < size.Len {
+				return false
+			}
+			a.Data[ch] = a.Data[ch][:size.Len]
+		}
+		a.Size = size
+	default:
+		return false
+	}
+	return true
+}
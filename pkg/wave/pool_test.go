@@ -0,0 +1,54 @@
+package wave
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestPoolDecode(t *testing.T) {
+	var pool Pool
+	// Sizes are multiples of every format's sample size, and the floats aren't NaN so they compare equal
+	chunks := make([][]byte, 3)
+	for i, n := range []int{96, 48, 144} {
+		chunks[i] = make([]byte, n)
+		for j := range chunks[i] {
+			chunks[i][j] = byte(i*n+j*37) & 0x3f
+		}
+	}
+	for format, decoder := range registeredDecoders {
+		// Chunks are decoded into the released Audio, which still holds the previous chunk's samples
+		for _, chunk := range chunks {
+			expected, err := decoder.Decode(binary.LittleEndian, chunk, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, release, err := pool.Decode(decoder, binary.LittleEndian, chunk, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(expected, decoded) {
+				t.Fatalf("%s: expected %+v, but got %+v", format, expected, decoded)
+			}
+			release()
+		}
+	}
+}
+
+func TestPoolGet(t *testing.T) {
+	var pool Pool
+	for _, size := range []ChunkInfo{{Len: 4, Channels: 2}, {Len: 2, Channels: 1}, {Len: 8, Channels: 2}} {
+		for _, typ := range []Type{TypeInt16Interleaved, TypeInt16NonInterleaved, TypeFloat32Interleaved} {
+			a, release, err := pool.Get(typ, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if TypeOf(a) != typ || a.ChunkInfo() != size {
+				t.Fatalf("expected %v of %+v, but got %v of %+v", typ, size, TypeOf(a), a.ChunkInfo())
+			}
+			// Every sample is accessible
+			a.Set(size.Len-1, size.Channels-1, Int16Sample(1))
+			release()
+		}
+	}
+}
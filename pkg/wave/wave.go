@@ -47,6 +47,12 @@ var (
 		}
 		return Int16Sample(s.Int() >> 16)
 	})
+	Int32SampleFormat = SampleFormatFunc(func(s Sample) Sample {
+		if _, ok := s.(Int32Sample); ok {
+			return s
+		}
+		return Int32Sample(s.Int())
+	})
 	Float32SampleFormat = SampleFormatFunc(func(s Sample) Sample {
 		if _, ok := s.(Float32Sample); ok {
 			return s
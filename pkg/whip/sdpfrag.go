@@ -0,0 +1,129 @@
+package whip
+
+import (
+	"strings"
+)
+
+const sdpLineBreak = "\r\n"
+
+// sdpMedia holds the parts of a media section needed to build and apply ICE fragments
+type sdpMedia struct {
+	mLine      string
+	mid        string
+	candidates []string
+}
+
+// sdpICE holds only the ICE attributes of a session description
+type sdpICE struct {
+	ufrag, pwd string
+	options    string
+	medias     []sdpMedia
+}
+
+func splitSDPLines(sdp string) []string {
+	lines := strings.Split(strings.ReplaceAll(sdp, sdpLineBreak, "\n"), "\n")
+	result := lines[:0]
+	for _, line := range lines {
+		if line != "" {
+			result = append(result, line)
+		}
+	}
+	return result
+}
+
+func parseSDPICE(sdp string) sdpICE {
+	var ice sdpICE
+	var media *sdpMedia
+
+	for _, line := range splitSDPLines(sdp) {
+		switch {
+		case strings.HasPrefix(line, "m="):
+			ice.medias = append(ice.medias, sdpMedia{mLine: line})
+			media = &ice.medias[len(ice.medias)-1]
+		case strings.HasPrefix(line, "a=ice-ufrag:"):
+			if ice.ufrag == "" {
+				ice.ufrag = strings.TrimPrefix(line, "a=ice-ufrag:")
+			}
+		case strings.HasPrefix(line, "a=ice-pwd:"):
+			if ice.pwd == "" {
+				ice.pwd = strings.TrimPrefix(line, "a=ice-pwd:")
+			}
+		case strings.HasPrefix(line, "a=ice-options:"):
+			if ice.options == "" {
+				ice.options = strings.TrimPrefix(line, "a=ice-options:")
+			}
+		case strings.HasPrefix(line, "a=mid:") && media != nil:
+			media.mid = strings.TrimPrefix(line, "a=mid:")
+		case strings.HasPrefix(line, "a=candidate:") && media != nil:
+			media.candidates = append(media.candidates, line)
+		}
+	}
+
+	return ice
+}
+
+// sdpFragment extracts the ICE credentials and candidates from sdp in the trickle-ice-sdpfrag format.
+// Reference: https://datatracker.ietf.org/doc/html/rfc8840#section-9
+func sdpFragment(sdp string) string {
+	ice := parseSDPICE(sdp)
+
+	var lines []string
+	if ice.options != "" {
+		lines = append(lines, "a=ice-options:"+ice.options)
+	}
+	lines = append(lines, "a=ice-ufrag:"+ice.ufrag, "a=ice-pwd:"+ice.pwd)
+	for _, media := range ice.medias {
+		lines = append(lines, media.mLine, "a=mid:"+media.mid)
+		lines = append(lines, media.candidates...)
+		lines = append(lines, "a=end-of-candidates")
+	}
+
+	return strings.Join(lines, sdpLineBreak) + sdpLineBreak
+}
+
+// applySDPFragment replaces the ICE credentials and candidates in sdp with the ones found in fragment.
+// The candidates of a media section are only replaced when fragment contains a matching mid.
+func applySDPFragment(sdp, fragment string) string {
+	ice := parseSDPICE(fragment)
+	candidatesByMid := make(map[string][]string)
+	for _, media := range ice.medias {
+		if len(media.candidates) > 0 {
+			candidatesByMid[media.mid] = media.candidates
+		}
+	}
+
+	var result []string
+	var pending []string
+	var currentMid string
+	flush := func() {
+		if candidates, ok := candidatesByMid[currentMid]; ok {
+			result = append(result, candidates...)
+		} else {
+			result = append(result, pending...)
+		}
+		pending = nil
+	}
+
+	for _, line := range splitSDPLines(sdp) {
+		switch {
+		case strings.HasPrefix(line, "m="):
+			flush()
+			currentMid = ""
+			result = append(result, line)
+		case strings.HasPrefix(line, "a=mid:"):
+			currentMid = strings.TrimPrefix(line, "a=mid:")
+			result = append(result, line)
+		case strings.HasPrefix(line, "a=ice-ufrag:") && ice.ufrag != "":
+			result = append(result, "a=ice-ufrag:"+ice.ufrag)
+		case strings.HasPrefix(line, "a=ice-pwd:") && ice.pwd != "":
+			result = append(result, "a=ice-pwd:"+ice.pwd)
+		case strings.HasPrefix(line, "a=candidate:"):
+			pending = append(pending, line)
+		default:
+			result = append(result, line)
+		}
+	}
+	flush()
+
+	return strings.Join(result, sdpLineBreak) + sdpLineBreak
+}
@@ -0,0 +1,319 @@
+// Package whip implements a WHIP (WebRTC-HTTP Ingestion Protocol) client that publishes
+// mediadevices tracks to any WHIP capable media server.
+// Reference: https://datatracker.ietf.org/doc/draft-ietf-wish-whip/
+package whip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	mimeTypeSDP     = "application/sdp"
+	mimeTypeSDPFrag = "application/trickle-ice-sdpfrag"
+)
+
+var (
+	errEmptyEndpoint   = errors.New("whip: endpoint can't be empty")
+	errMissingLocation = errors.New("whip: server didn't return the resource location")
+	errClosed          = errors.New("whip: publisher has been closed")
+)
+
+// Config configures how the publisher connects to the WHIP endpoint
+type Config struct {
+	// Endpoint is the WHIP endpoint URL that accepts the SDP offer.
+	Endpoint string
+	// Token is an optional bearer token that's sent in the Authorization header.
+	Token string
+	// WebRTC configures the underlying peer connection, e.g. ICE servers.
+	WebRTC webrtc.Configuration
+	// Codec tells the peer connection which codecs are available. When it's nil,
+	// pion/webrtc's default codecs are registered instead.
+	Codec *mediadevices.CodecSelector
+	// HTTPClient is used to make the WHIP requests. The default value is http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Publisher is an active WHIP session sending tracks to a media server
+type Publisher struct {
+	config      Config
+	pc          *webrtc.PeerConnection
+	resourceURL string
+	etag        string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Publish creates a new peer connection that sends tracks, and negotiates the session with the
+// WHIP endpoint. The returned Publisher owns the peer connection, but not the tracks.
+func Publish(config Config, tracks ...mediadevices.Track) (*Publisher, error) {
+	if config.Endpoint == "" {
+		return nil, errEmptyEndpoint
+	}
+
+	if config.HTTPClient == nil {
+		config.HTTPClient = http.DefaultClient
+	}
+
+	mediaEngine := webrtc.MediaEngine{}
+	if config.Codec != nil {
+		config.Codec.Populate(&mediaEngine)
+	} else if err := mediaEngine.RegisterDefaultCodecs(); err != nil {
+		return nil, err
+	}
+
+	api := webrtc.NewAPI(webrtc.WithMediaEngine(&mediaEngine))
+	pc, err := api.NewPeerConnection(config.WebRTC)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, track := range tracks {
+		_, err = pc.AddTransceiverFromTrack(track,
+			webrtc.RtpTransceiverInit{
+				Direction: webrtc.RTPTransceiverDirectionSendonly,
+			},
+		)
+		if err != nil {
+			pc.Close()
+			return nil, err
+		}
+	}
+
+	p := &Publisher{
+		config: config,
+		pc:     pc,
+	}
+
+	if err := p.negotiate(); err != nil {
+		pc.Close()
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// PeerConnection returns the underlying peer connection
+func (p *Publisher) PeerConnection() *webrtc.PeerConnection {
+	return p.pc
+}
+
+// ResourceURL returns the URL of this session's resource on the WHIP server
+func (p *Publisher) ResourceURL() string {
+	return p.resourceURL
+}
+
+// negotiate sends a full offer to the endpoint, and applies the answer from the server
+func (p *Publisher) negotiate() error {
+	offer, err := p.createOffer(nil)
+	if err != nil {
+		return err
+	}
+
+	resp, body, err := p.do(http.MethodPost, p.config.Endpoint, mimeTypeSDP, []byte(offer.SDP))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("whip: unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return errMissingLocation
+	}
+
+	// The session was created on the server, so it's deleted if it can't be used. Without a resolved URL there's
+	// nothing to send the DELETE to, so the error is returned as is.
+	resourceURL, err := resolveURL(p.config.Endpoint, location)
+	if err != nil {
+		return fmt.Errorf("whip: invalid location %q: %s", location, err)
+	}
+	p.resourceURL = resourceURL
+	p.etag = resp.Header.Get("ETag")
+
+	err = p.pc.SetRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  string(body),
+	})
+	if err != nil {
+		return p.discard(resourceURL, err)
+	}
+	return nil
+}
+
+// discard deletes the resource of a session whose negotiation failed with err after the resource was created,
+// and returns err along with any deletion error.
+func (p *Publisher) discard(resourceURL string, err error) error {
+	resp, body, deleteErr := p.do(http.MethodDelete, resourceURL, "", nil)
+	if deleteErr != nil {
+		return fmt.Errorf("%s\n\nwhip: failed to delete the resource: %s", err, deleteErr)
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s\n\nwhip: unexpected status code %d: %s", err, resp.StatusCode, body)
+	}
+	return err
+}
+
+// Restart performs an ICE restart by sending the new ICE credentials to the resource URL.
+// Reference: https://datatracker.ietf.org/doc/html/draft-ietf-wish-whip#section-4.1
+func (p *Publisher) Restart() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errClosed
+	}
+
+	offer, err := p.createOffer(&webrtc.OfferOptions{ICERestart: true})
+	if err != nil {
+		return err
+	}
+
+	resp, body, err := p.do(http.MethodPatch, p.resourceURL, mimeTypeSDPFrag, []byte(sdpFragment(offer.SDP)))
+	if err != nil {
+		return p.rollback(err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return p.rollback(fmt.Errorf("whip: ice restart failed with status code %d: %s", resp.StatusCode, body))
+	}
+
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		p.etag = etag
+	}
+
+	remote := p.pc.RemoteDescription()
+	if remote == nil {
+		return errors.New("whip: missing remote description")
+	}
+
+	return p.pc.SetRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  applySDPFragment(remote.SDP, string(body)),
+	})
+}
+
+// rollback returns the peer connection to stable after the server rejected a restart offer, so Restart can be
+// retried. It returns err along with any error from restoring the answer.
+func (p *Publisher) rollback(err error) error {
+	// pion can't roll back a local offer, so the offer is settled with the previous answer instead
+	remote := p.pc.RemoteDescription()
+	if remote == nil {
+		return err
+	}
+	restoreErr := p.pc.SetRemoteDescription(webrtc.SessionDescription{
+		Type: webrtc.SDPTypeAnswer,
+		SDP:  remote.SDP,
+	})
+	if restoreErr != nil {
+		return fmt.Errorf("%s\n\nwhip: failed to restore the previous answer: %s", err, restoreErr)
+	}
+	return err
+}
+
+// Close terminates the WHIP session on the server, and closes the peer connection
+func (p *Publisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	var errs []string
+	if p.resourceURL != "" {
+		resp, body, err := p.do(http.MethodDelete, p.resourceURL, "", nil)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else if resp.StatusCode/100 != 2 {
+			errs = append(errs, fmt.Sprintf("whip: unexpected status code %d: %s", resp.StatusCode, body))
+		}
+	}
+
+	if err := p.pc.Close(); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n\n"))
+	}
+	return nil
+}
+
+// createOffer creates an offer and blocks until ICE gathering is complete. WHIP only
+// exchanges one message per negotiation, so candidates are not trickled.
+func (p *Publisher) createOffer(options *webrtc.OfferOptions) (webrtc.SessionDescription, error) {
+	offer, err := p.pc.CreateOffer(options)
+	if err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+
+	gatherComplete := webrtc.GatheringCompletePromise(p.pc)
+	if err := p.pc.SetLocalDescription(offer); err != nil {
+		return webrtc.SessionDescription{}, err
+	}
+	<-gatherComplete
+
+	return *p.pc.LocalDescription(), nil
+}
+
+func (p *Publisher) do(method, rawURL, contentType string, payload []byte) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, rawURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if p.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.Token)
+	}
+
+	if method == http.MethodPatch && p.etag != "" {
+		req.Header.Set("If-Match", p.etag)
+	}
+
+	resp, err := p.config.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return resp, body, nil
+}
+
+// resolveURL resolves location relative to the endpoint since servers are allowed to
+// return a relative resource location.
+func resolveURL(endpoint, location string) (string, error) {
+	base, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(location)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
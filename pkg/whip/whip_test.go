@@ -0,0 +1,319 @@
+package whip
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pion/mediadevices"
+	"github.com/pion/webrtc/v3"
+)
+
+type mockTrack struct {
+	kind webrtc.RTPCodecType
+
+	mu    sync.Mutex
+	bound bool
+}
+
+func (track *mockTrack) ID() string                { return "mock" }
+func (track *mockTrack) StreamID() string          { return "mock" }
+func (track *mockTrack) Close() error              { return nil }
+func (track *mockTrack) Kind() webrtc.RTPCodecType { return track.kind }
+func (track *mockTrack) OnEnded(func(error))       {}
+
+func (track *mockTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
+	track.mu.Lock()
+	defer track.mu.Unlock()
+	track.bound = true
+	return ctx.CodecParameters()[0], nil
+}
+
+func (track *mockTrack) Unbind(ctx webrtc.TrackLocalContext) error {
+	return nil
+}
+
+func (track *mockTrack) NewRTPReader(string, uint32, int) (mediadevices.RTPReadCloser, error) {
+	return nil, nil
+}
+
+func (track *mockTrack) NewEncodedReader(string) (mediadevices.EncodedReadCloser, error) {
+	return nil, nil
+}
+
+func (track *mockTrack) NewEncodedIOReader(string) (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (track *mockTrack) Stats() mediadevices.TrackStats {
+	return mediadevices.TrackStats{}
+}
+
+func (track *mockTrack) isBound() bool {
+	track.mu.Lock()
+	defer track.mu.Unlock()
+	return track.bound
+}
+
+type mockServer struct {
+	t     *testing.T
+	token string
+	pc    *webrtc.PeerConnection
+	// answer replaces the SDP answer if it's not empty.
+	answer string
+	// location replaces the resource location if it's not empty.
+	location string
+	// failPatches is the number of ICE restarts to reject before taking them.
+	failPatches int
+
+	mu      sync.Mutex
+	methods []string
+}
+
+func (s *mockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.methods = append(s.methods, r.Method)
+	s.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if r.URL.Path != "/whip" || r.Header.Get("Content-Type") != mimeTypeSDP {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		s.pc = pc
+
+		err = pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: string(body)})
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		answer, err := pc.CreateAnswer(nil)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		gatherComplete := webrtc.GatheringCompletePromise(pc)
+		if err := pc.SetLocalDescription(answer); err != nil {
+			s.t.Fatal(err)
+		}
+		<-gatherComplete
+
+		w.Header().Set("Content-Type", mimeTypeSDP)
+		location := "resource/1"
+		if s.location != "" {
+			location = s.location
+		}
+		w.Header().Set("Location", location)
+		w.Header().Set("ETag", "\"1\"")
+		w.WriteHeader(http.StatusCreated)
+		if s.answer != "" {
+			w.Write([]byte(s.answer))
+			return
+		}
+		w.Write([]byte(pc.LocalDescription().SDP))
+	case http.MethodPatch:
+		if r.URL.Path != "/resource/1" || r.Header.Get("If-Match") != "\"1\"" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		if !strings.Contains(string(body), "a=ice-ufrag:") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.failPatches > 0 {
+			s.failPatches--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", mimeTypeSDPFrag)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("a=ice-ufrag:newufrag\r\na=ice-pwd:newpasswordnewpasswordnewpassword\r\n"))
+	case http.MethodDelete:
+		if r.URL.Path != "/resource/1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.pc.Close()
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestPublish(t *testing.T) {
+	handler := &mockServer{t: t, token: "secret"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	track := &mockTrack{kind: webrtc.RTPCodecTypeVideo}
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		_, err := Publish(Config{Endpoint: server.URL + "/whip", Token: "wrong"}, track)
+		if err == nil {
+			t.Fatal("expected to get an error with a wrong token")
+		}
+	})
+
+	p, err := Publish(Config{Endpoint: server.URL + "/whip", Token: "secret"}, track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := server.URL + "/resource/1"; p.ResourceURL() != expected {
+		t.Fatalf("expected resource url to be %s, but got %s", expected, p.ResourceURL())
+	}
+
+	if !track.isBound() {
+		t.Fatal("expected the track to be bound after the negotiation")
+	}
+
+	if err := p.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	if sdp := p.PeerConnection().RemoteDescription().SDP; !strings.Contains(sdp, "a=ice-ufrag:newufrag") {
+		t.Fatalf("expected the remote description to use the new ice credentials:\n%s", sdp)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Restart(); err != errClosed {
+		t.Fatalf("expected %v after close, but got %v", errClosed, err)
+	}
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	expected := []string{http.MethodPost, http.MethodPost, http.MethodPatch, http.MethodDelete}
+	if strings.Join(handler.methods, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected requests %v, but got %v", expected, handler.methods)
+	}
+}
+
+func TestPublishInvalidAnswer(t *testing.T) {
+	handler := &mockServer{t: t, token: "secret", answer: "invalid"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	track := &mockTrack{kind: webrtc.RTPCodecTypeVideo}
+	if _, err := Publish(Config{Endpoint: server.URL + "/whip", Token: "secret"}, track); err == nil {
+		t.Fatal("expected to get an error with an invalid answer")
+	}
+
+	// The resource created by the offer is deleted
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	expected := []string{http.MethodPost, http.MethodDelete}
+	if strings.Join(handler.methods, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected requests %v, but got %v", expected, handler.methods)
+	}
+}
+
+func TestPublishInvalidLocation(t *testing.T) {
+	handler := &mockServer{t: t, token: "secret", location: "resource/%zz"}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	track := &mockTrack{kind: webrtc.RTPCodecTypeVideo}
+	if _, err := Publish(Config{Endpoint: server.URL + "/whip", Token: "secret"}, track); err == nil {
+		t.Fatal("expected to get an error with an invalid location")
+	}
+
+	// The location can't be resolved, so no DELETE is sent
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	expected := []string{http.MethodPost}
+	if strings.Join(handler.methods, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected requests %v, but got %v", expected, handler.methods)
+	}
+}
+
+func TestRestartRollback(t *testing.T) {
+	handler := &mockServer{t: t, token: "secret", failPatches: 1}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	track := &mockTrack{kind: webrtc.RTPCodecTypeVideo}
+	p, err := Publish(Config{Endpoint: server.URL + "/whip", Token: "secret"}, track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if err := p.Restart(); err == nil {
+		t.Fatal("expected to get an error when the server rejects the restart")
+	}
+	if state := p.PeerConnection().SignalingState(); state != webrtc.SignalingStateStable {
+		t.Fatalf("expected the offer to be rolled back, but got the signaling state %s", state)
+	}
+
+	if err := p.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	if sdp := p.PeerConnection().RemoteDescription().SDP; !strings.Contains(sdp, "a=ice-ufrag:newufrag") {
+		t.Fatalf("expected the remote description to use the new ice credentials:\n%s", sdp)
+	}
+}
+
+func TestApplySDPFragment(t *testing.T) {
+	sdp := "v=0\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\n" +
+		"a=mid:0\r\n" +
+		"a=ice-ufrag:old\r\n" +
+		"a=ice-pwd:oldpwd\r\n" +
+		"a=candidate:1 1 udp 1 10.0.0.1 5000 typ host\r\n" +
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\n" +
+		"a=mid:1\r\n" +
+		"a=ice-ufrag:old\r\n" +
+		"a=ice-pwd:oldpwd\r\n" +
+		"a=candidate:1 1 udp 1 10.0.0.1 5001 typ host\r\n"
+
+	fragment := "a=ice-ufrag:new\r\n" +
+		"a=ice-pwd:newpwd\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\n" +
+		"a=mid:0\r\n" +
+		"a=candidate:2 1 udp 1 10.0.0.2 6000 typ host\r\n"
+
+	expected := "v=0\r\n" +
+		"m=video 9 UDP/TLS/RTP/SAVPF 96\r\n" +
+		"a=mid:0\r\n" +
+		"a=ice-ufrag:new\r\n" +
+		"a=ice-pwd:newpwd\r\n" +
+		"a=candidate:2 1 udp 1 10.0.0.2 6000 typ host\r\n" +
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\n" +
+		"a=mid:1\r\n" +
+		"a=ice-ufrag:new\r\n" +
+		"a=ice-pwd:newpwd\r\n" +
+		"a=candidate:1 1 udp 1 10.0.0.1 5001 typ host\r\n"
+
+	if actual := applySDPFragment(sdp, fragment); actual != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	frag := sdpFragment(sdp)
+	if !strings.Contains(frag, "a=ice-ufrag:old\r\na=ice-pwd:oldpwd\r\nm=video") {
+		t.Fatalf("unexpected fragment:\n%s", frag)
+	}
+}
@@ -0,0 +1,143 @@
+package mediadevices
+
+import (
+	"errors"
+	"image"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+	"github.com/pion/mediadevices/pkg/wave/mixer"
+)
+
+var errUnsupportedSampleFormat = errors.New("unsupported sample format")
+
+// echoReference is audio played by Players, which is cancelled from tracks with EchoCancellation.
+var echoReference = audio.NewEchoReference()
+
+// Player renders audio or video to an output device, e.g. a remote track's decoded samples, so full-duplex
+// applications like intercoms can be built together with the captured tracks.
+type Player struct {
+	d driver.Driver
+}
+
+// NewPlayer starts rendering r to the output device that best fits the constraints. Output devices are
+// registered by drivers like pkg/driver/speaker, and constraints are applied the same way as in
+// GetUserMedia, e.g. DeviceID selects a device from EnumerateDevices. r's samples are resampled, mixed
+// and converted into the device's format. Playback ends when r returns an error or the Player is closed.
+func NewPlayer(r audio.Reader, constraints MediaOption) (*Player, error) {
+	var c MediaTrackConstraints
+	if constraints != nil {
+		constraints(&c)
+	}
+
+	d, c, err := selectBestDriver(driver.FilterAudioPlayer(), c)
+	if err != nil {
+		return nil, err
+	}
+
+	p := c.selectedMedia
+	t, err := audioType(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.Configure(c.DriverOptions); err != nil {
+		return nil, err
+	}
+	if err := d.Open(); err != nil {
+		return nil, err
+	}
+
+	rResample := audio.NewResampler(p.SampleRate)
+	rMix := audio.NewChannelMixer(p.ChannelCount, &mixer.MonoMixer{})
+	rConv := audio.NewConverter(false, t)
+	rEcho := echoReference.Tap()
+	if err := d.(driver.AudioPlayer).AudioPlay(p, rConv(rEcho(rMix(rResample(r))))); err != nil {
+		return nil, err
+	}
+	return &Player{d: d}, nil
+}
+
+// NewVideoPlayer starts rendering r to the video output device that best fits the constraints, e.g. a virtual
+// camera registered by pkg/driver/virtualcam, so other applications can use the processed video.
+// r's images are converted into I420 and scaled to the device's size if needed. Playback ends when r
+// returns an error or the Player is closed.
+func NewVideoPlayer(r video.Reader, constraints MediaOption) (*Player, error) {
+	var c MediaTrackConstraints
+	if constraints != nil {
+		constraints(&c)
+	}
+
+	d, c, err := selectBestDriver(driver.FilterVideoPlayer(), c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.Configure(c.DriverOptions); err != nil {
+		return nil, err
+	}
+	if err := d.Open(); err != nil {
+		return nil, err
+	}
+
+	p := c.selectedMedia
+	if err := d.(driver.VideoPlayer).VideoPlay(p, fitSize(p.Width, p.Height, video.ToI420(r))); err != nil {
+		return nil, err
+	}
+	return &Player{d: d}, nil
+}
+
+// Close stops the playback and closes the output device.
+func (p *Player) Close() error {
+	return p.d.Close()
+}
+
+// audioType returns the type of chunks in p's format.
+func audioType(p prop.Media) (wave.Type, error) {
+	switch {
+	case p.IsFloat && p.SampleSize == 4 && p.IsInterleaved:
+		return wave.TypeFloat32Interleaved, nil
+	case p.IsFloat && p.SampleSize == 4:
+		return wave.TypeFloat32NonInterleaved, nil
+	case !p.IsFloat && p.SampleSize == 4 && p.IsInterleaved:
+		return wave.TypeInt32Interleaved, nil
+	case !p.IsFloat && p.SampleSize == 4:
+		return wave.TypeInt32NonInterleaved, nil
+	case !p.IsFloat && p.SampleSize == 2 && p.IsInterleaved:
+		return wave.TypeInt16Interleaved, nil
+	case !p.IsFloat && p.SampleSize == 2:
+		return wave.TypeInt16NonInterleaved, nil
+	default:
+		return wave.TypeUnknown, errUnsupportedSampleFormat
+	}
+}
+
+// fitSize scales r's images that aren't width x height.
+func fitSize(width, height int, r video.Reader) video.Reader {
+	if width <= 0 || height <= 0 {
+		return r
+	}
+
+	var img image.Image
+	scaled := video.Scale(width, height, nil)(video.ReaderFunc(func() (image.Image, func(), error) {
+		return img, func() {}, nil
+	}))
+	return video.ReaderFunc(func() (image.Image, func(), error) {
+		var release func()
+		var err error
+		img, release, err = r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+		if bounds := img.Bounds(); bounds.Dx() == width && bounds.Dy() == height {
+			return img, release, nil
+		}
+
+		// The scaled image is a copy
+		defer release()
+		return scaled.Read()
+	})
+}
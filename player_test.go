@@ -0,0 +1,181 @@
+package mediadevices
+
+import (
+	"image"
+	"io"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/frame"
+	"github.com/pion/mediadevices/pkg/io/audio"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/mediadevices/pkg/wave"
+)
+
+type playerAdapterMock struct {
+	played chan audio.Reader
+	closed bool
+}
+
+func (a *playerAdapterMock) Open() error  { return nil }
+func (a *playerAdapterMock) Close() error { a.closed = true; return nil }
+func (a *playerAdapterMock) Properties() []prop.Media {
+	return []prop.Media{{
+		Audio: prop.Audio{
+			ChannelCount:  2,
+			SampleRate:    48000,
+			SampleSize:    4,
+			IsFloat:       true,
+			IsInterleaved: true,
+		},
+	}}
+}
+
+func (a *playerAdapterMock) AudioPlay(p prop.Media, r audio.Reader) error {
+	a.played <- r
+	return nil
+}
+
+func TestPlayer(t *testing.T) {
+	a := &playerAdapterMock{played: make(chan audio.Reader, 1)}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "speaker", DeviceType: driver.Speaker}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	var found bool
+	for _, info := range EnumerateDevices() {
+		if info.Label == "speaker" {
+			found = info.Kind == AudioOutput
+		}
+	}
+	if !found {
+		t.Fatal("expected the speaker to be enumerated as AudioOutput")
+	}
+
+	// Mono Int16 at 24kHz, e.g. decoded from a remote track
+	var n int
+	src := audio.ReaderFunc(func() (wave.Audio, func(), error) {
+		if n == 10 {
+			return nil, func() {}, io.EOF
+		}
+		n++
+		chunk := wave.NewInt16Interleaved(wave.ChunkInfo{Len: 480, Channels: 1, SamplingRate: 24000})
+		for i := range chunk.Data {
+			chunk.Data[i] = 16384
+		}
+		return chunk, func() {}, nil
+	})
+
+	p, err := NewPlayer(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-a.played
+	for i := 0; i < 5; i++ {
+		chunk, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, ok := chunk.(*wave.Float32Interleaved)
+		if !ok {
+			t.Fatalf("expected *wave.Float32Interleaved, but got %T", chunk)
+		}
+		if info := f.ChunkInfo(); info.Channels != 2 || info.SamplingRate != 48000 {
+			t.Fatalf("expected stereo at 48kHz, but got %v", info)
+		}
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !a.closed {
+		t.Fatal("expected the device to be closed")
+	}
+}
+
+type videoPlayerAdapterMock struct {
+	played chan video.Reader
+	closed bool
+}
+
+func (a *videoPlayerAdapterMock) Open() error  { return nil }
+func (a *videoPlayerAdapterMock) Close() error { a.closed = true; return nil }
+func (a *videoPlayerAdapterMock) Properties() []prop.Media {
+	return []prop.Media{{
+		Video: prop.Video{
+			Width:       640,
+			Height:      480,
+			FrameFormat: frame.FormatYUYV,
+		},
+	}}
+}
+
+func (a *videoPlayerAdapterMock) VideoPlay(p prop.Media, r video.Reader) error {
+	a.played <- r
+	return nil
+}
+
+func TestVideoPlayer(t *testing.T) {
+	a := &videoPlayerAdapterMock{played: make(chan video.Reader, 1)}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "virtualcam", DeviceType: driver.VirtualCamera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	var found bool
+	for _, info := range EnumerateDevices() {
+		if info.Label == "virtualcam" {
+			found = info.Kind == VideoOutput
+		}
+	}
+	if !found {
+		t.Fatal("expected the virtual camera to be enumerated as VideoOutput")
+	}
+
+	sizes := []image.Point{{640, 480}, {320, 240}}
+	var n int
+	src := video.ReaderFunc(func() (image.Image, func(), error) {
+		if n == len(sizes) {
+			return nil, func() {}, io.EOF
+		}
+		img := image.NewRGBA(image.Rectangle{Max: sizes[n]})
+		n++
+		return img, func() {}, nil
+	})
+
+	p, err := NewVideoPlayer(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := <-a.played
+	for range sizes {
+		img, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		yuv, ok := img.(*image.YCbCr)
+		if !ok {
+			t.Fatalf("expected *image.YCbCr, but got %T", img)
+		}
+		if yuv.Rect.Dx() != 640 || yuv.Rect.Dy() != 480 {
+			t.Fatalf("expected 640x480, but got %v", yuv.Rect)
+		}
+		if yuv.SubsampleRatio != image.YCbCrSubsampleRatio420 {
+			t.Fatalf("expected I420, but got %v", yuv.SubsampleRatio)
+		}
+	}
+	if _, _, err := r.Read(); err != io.EOF {
+		t.Fatalf("expected %v, but got %v", io.EOF, err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !a.closed {
+		t.Fatal("expected the device to be closed")
+	}
+}
@@ -0,0 +1,155 @@
+package mediadevices
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+)
+
+var (
+	errPreEventBufferClosed = errors.New("pre-event buffer is closed")
+	errNotEncodableTrack    = errors.New("the track doesn't provide its codec")
+)
+
+// encodedReaderTrack is implemented by tracks that report their encoded readers' codec.
+type encodedReaderTrack interface {
+	newEncodedReader(codecNames ...string) (EncodedReadCloser, *codec.RTPCodec, error)
+}
+
+type preEventFrame struct {
+	buffer   EncodedBuffer
+	time     time.Time
+	keyFrame bool
+}
+
+// PreEventBuffer keeps a track's last encoded frames in memory, so media before an event, e.g.
+// motion or speech, is saved when the event fires, like dashcams do.
+type PreEventBuffer struct {
+	codec    *codec.RTPCodec
+	duration time.Duration
+
+	mu     sync.Mutex
+	frames []preEventFrame
+	err    error
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPreEventBuffer starts encoding track with codecName, and keeps the last duration of frames. The buffer
+// starts at a keyframe, so it keeps up to a keyframe interval more than duration.
+func NewPreEventBuffer(track Track, codecName string, duration time.Duration) (*PreEventBuffer, error) {
+	t, ok := track.(encodedReaderTrack)
+	if !ok {
+		return nil, errNotEncodableTrack
+	}
+	reader, selectedCodec, err := t.newEncodedReader(codecName)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &PreEventBuffer{
+		codec:    selectedCodec,
+		duration: duration,
+		closed:   make(chan struct{}),
+	}
+	resources.addCloser(b)
+	stopped := resources.start("pre-event buffer of " + track.ID())
+	go func() {
+		defer stopped()
+		b.run(reader)
+	}()
+	return b, nil
+}
+
+func (b *PreEventBuffer) run(reader EncodedReadCloser) {
+	defer reader.Close()
+	mimeType := b.codec.MimeType
+	for {
+		buffer, release, err := reader.Read()
+		if err != nil {
+			b.mu.Lock()
+			b.err = err
+			b.mu.Unlock()
+			return
+		}
+		// Data may refer to encoder memory, which the next frame reuses.
+		buffer.Data = append([]byte{}, buffer.Data...)
+		release()
+
+		select {
+		case <-b.closed:
+			return
+		default:
+		}
+
+		if len(buffer.Data) == 0 {
+			continue
+		}
+		frame := preEventFrame{buffer: buffer, time: buffer.CaptureTime, keyFrame: codec.IsKeyFrame(mimeType, buffer.Data)}
+		if frame.time.IsZero() {
+			frame.time = time.Now()
+		}
+		b.push(frame)
+	}
+}
+
+func (b *PreEventBuffer) push(frame preEventFrame) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.frames = append(b.frames, frame)
+	// Trim to the last keyframe that's old enough, so the buffer covers duration and starts decodable.
+	cutoff := frame.time.Add(-b.duration)
+	for i := len(b.frames) - 1; i > 0; i-- {
+		if b.frames[i].keyFrame && !b.frames[i].time.After(cutoff) {
+			b.frames = append(b.frames[:0], b.frames[i:]...)
+			break
+		}
+	}
+}
+
+// Codec returns the buffered frames' codec.
+func (b *PreEventBuffer) Codec() *codec.RTPCodec {
+	return b.codec
+}
+
+// Flush calls write with buffered frames in order from the oldest keyframe, e.g. to write them to a file.
+// Frames keep being buffered, so frames after the event are read from another track reader.
+// It returns the error that stopped buffering, if any, after writing the frames.
+func (b *PreEventBuffer) Flush(write func(EncodedBuffer) error) error {
+	b.mu.Lock()
+	frames := make([]preEventFrame, len(b.frames))
+	copy(frames, b.frames)
+	bufferErr := b.err
+	b.mu.Unlock()
+
+	started := false
+	for _, frame := range frames {
+		if !started && !frame.keyFrame {
+			continue
+		}
+		started = true
+		if err := write(frame.buffer); err != nil {
+			return err
+		}
+	}
+	return bufferErr
+}
+
+// Close stops buffering. The encoder is closed after it returns the frame being read.
+func (b *PreEventBuffer) Close() error {
+	b.closeOnce.Do(func() {
+		resources.removeCloser(b)
+		close(b.closed)
+		b.mu.Lock()
+		b.frames = nil
+		if b.err == nil {
+			b.err = errPreEventBufferClosed
+		}
+		b.mu.Unlock()
+	})
+	return nil
+}
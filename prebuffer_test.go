@@ -0,0 +1,105 @@
+package mediadevices
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+func TestPreEventBufferTrim(t *testing.T) {
+	b := &PreEventBuffer{codec: codec.NewRTPVP8Codec(90000), duration: time.Second, closed: make(chan struct{})}
+	start := time.Unix(1, 0)
+	// Keyframes are every 4 frames at 4fps
+	for i := 0; i < 14; i++ {
+		b.push(preEventFrame{
+			buffer:   EncodedBuffer{Data: []byte{byte(i)}},
+			time:     start.Add(time.Duration(i) * 250 * time.Millisecond),
+			keyFrame: i%4 == 0,
+		})
+	}
+
+	var flushed []byte
+	if err := b.Flush(func(buffer EncodedBuffer) error {
+		flushed = append(flushed, buffer.Data[0])
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// The last second starts at frame 9, so the buffer starts at keyframe 8.
+	if len(flushed) != 6 || flushed[0] != 8 || flushed[5] != 13 {
+		t.Fatalf("expected the frames from 8 to 13, but got %v", flushed)
+	}
+}
+
+type testVP8Encoder struct {
+	r video.Reader
+	n int
+}
+
+func (e *testVP8Encoder) Read() ([]byte, func(), error) {
+	if _, _, err := e.r.Read(); err != nil {
+		return nil, func() {}, err
+	}
+	// Keyframes are every 3 frames
+	frameType := byte(1)
+	if e.n%3 == 0 {
+		frameType = 0
+	}
+	e.n++
+	return []byte{frameType, byte(e.n)}, func() {}, nil
+}
+
+func (e *testVP8Encoder) Close() error         { return nil }
+func (e *testVP8Encoder) SetBitRate(int) error { return nil }
+func (e *testVP8Encoder) ForceKeyFrame() error { return nil }
+
+type testVP8EncoderBuilder struct{}
+
+func (b *testVP8EncoderBuilder) RTPCodec() *codec.RTPCodec { return codec.NewRTPVP8Codec(90000) }
+
+func (b *testVP8EncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	return &testVP8Encoder{r: r}, nil
+}
+
+func TestPreEventBuffer(t *testing.T) {
+	selector := NewCodecSelector(WithVideoEncoders(&testVP8EncoderBuilder{}))
+	img := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	track := NewVideoTrack(&testVideoSource{img: img}, selector)
+	defer track.Close()
+
+	b, err := NewPreEventBuffer(track, webrtc.MimeTypeVP8, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if b.Codec().MimeType != webrtc.MimeTypeVP8 {
+		t.Fatalf("expected %s, but got %s", webrtc.MimeTypeVP8, b.Codec().MimeType)
+	}
+
+	var flushed []EncodedBuffer
+	for i := 0; i < 100 && len(flushed) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if err := b.Flush(func(buffer EncodedBuffer) error {
+			flushed = append(flushed, buffer)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(flushed) == 0 {
+		t.Fatal("expected the frames to be buffered")
+	}
+	if flushed[0].Data[0] != 0 {
+		t.Fatalf("expected the flushed frames to start at a keyframe, but got %v", flushed[0].Data)
+	}
+
+	b.Close()
+	if err := b.Flush(func(EncodedBuffer) error { return nil }); err != errPreEventBufferClosed {
+		t.Fatalf("expected %v, but got %v", errPreEventBufferClosed, err)
+	}
+}
@@ -0,0 +1,96 @@
+package mediadevices
+
+import (
+	"fmt"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+// PreflightResult is the status of the device GetUserMedia would open for a media kind.
+type PreflightResult struct {
+	Kind MediaDeviceType
+	// Found is whether a device fits the constraints. If it's false, other fields are empty.
+	Found  bool
+	Device MediaDeviceInfo
+	// Permission is whether the OS allows the application to use the device. If it's driver.PermissionPrompt,
+	// GetUserMedia will make the OS ask the user.
+	Permission driver.Permission
+	// Busy is whether another application or a track uses the device, so it can't be opened now.
+	Busy bool
+}
+
+// Preflight checks devices GetUserMedia would open for the constraints without recording,
+// e.g. to guide users through permissions in an onboarding flow before the first call. It returns
+// video and audio results in this order, for each kind in the constraints.
+//
+// Devices that aren't permitted yet aren't opened to query their properties, since opening them makes the OS
+// prompt the user. If no permitted device fits the constraints, the result is the highest priority device
+// that isn't permitted. Permission and exclusive use are only known if the driver is a driver.Prober,
+// e.g. cameras on Linux, macOS and Windows.
+func Preflight(constraints MediaStreamConstraints) ([]PreflightResult, error) {
+	var results []PreflightResult
+	if constraints.Video != nil {
+		var c MediaTrackConstraints
+		constraints.Video(&c)
+		result, err := preflight(VideoInput, driver.FilterVideoRecorder(), c)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if constraints.Audio != nil {
+		var c MediaTrackConstraints
+		constraints.Audio(&c)
+		result, err := preflight(AudioInput, driver.FilterAudioRecorder(), c)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func preflight(kind MediaDeviceType, filter driver.FilterFn, constraints MediaTrackConstraints) (PreflightResult, error) {
+	result := PreflightResult{Kind: kind}
+
+	probes := make(map[driver.Driver]driver.Probe)
+	var blocked driver.Driver
+	for _, d := range driver.GetManager().Query(filter) {
+		p, err := d.Probe()
+		if err != nil {
+			return result, fmt.Errorf("failed to probe %s: %s", d.Info().Label, err)
+		}
+		probes[d] = p
+		if !permitted(p.Permission) {
+			if blocked == nil || d.Info().Priority > blocked.Info().Priority {
+				blocked = d
+			}
+		}
+	}
+
+	probed := driver.FilterFn(func(d driver.Driver) bool {
+		p, ok := probes[d]
+		return ok && permitted(p.Permission)
+	})
+	d, _, err := selectBestDriver(driver.FilterAnd(filter, probed), constraints)
+	if err != nil {
+		if blocked == nil {
+			// No device fits the constraints
+			return result, nil
+		}
+		d = blocked
+	}
+
+	info, _ := deviceInfo(d)
+	p := probes[d]
+	result.Found = true
+	result.Device = info
+	result.Permission = p.Permission
+	result.Busy = p.Busy || d.Status() == driver.StateRunning
+	return result, nil
+}
+
+// permitted reports whether a device with permission can be opened without prompting the user.
+func permitted(p driver.Permission) bool {
+	return p == driver.PermissionGranted || p == driver.PermissionUnknown
+}
@@ -0,0 +1,69 @@
+package mediadevices
+
+import (
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/prop"
+)
+
+type proberCameraMock struct {
+	screenAdapterMock
+	probe  driver.Probe
+	opened int
+}
+
+func (a *proberCameraMock) Open() error {
+	a.opened++
+	return a.screenAdapterMock.Open()
+}
+
+func (a *proberCameraMock) Probe() (driver.Probe, error) {
+	return a.probe, nil
+}
+
+func TestPreflight(t *testing.T) {
+	granted := &proberCameraMock{probe: driver.Probe{Permission: driver.PermissionGranted, Busy: true}}
+	denied := &proberCameraMock{probe: driver.Probe{Permission: driver.PermissionDenied}}
+	for label, a := range map[string]*proberCameraMock{"granted": granted, "denied": denied} {
+		if err := RegisterDriverAdapter(a, driver.Info{Label: label, DeviceType: driver.Camera, Priority: driver.PriorityHigh}); err != nil {
+			t.Fatal(err)
+		}
+		defer driver.GetManager().Unregister(a)
+	}
+
+	results, err := Preflight(MediaStreamConstraints{
+		Video: func(c *MediaTrackConstraints) {},
+		Audio: func(c *MediaTrackConstraints) {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Kind != VideoInput || results[1].Kind != AudioInput {
+		t.Fatalf("expected the results of the video and the audio, but got %+v", results)
+	}
+	video := results[0]
+	if !video.Found || video.Device.Label != "granted" || video.Permission != driver.PermissionGranted || !video.Busy {
+		t.Fatalf("expected the busy camera, but got %+v", video)
+	}
+	// audiotest's microphone doesn't know its permission
+	if audio := results[1]; !audio.Found || audio.Permission != driver.PermissionUnknown {
+		t.Fatalf("expected the microphone of the unknown permission, but got %+v", audio)
+	}
+
+	// No permitted camera has the size
+	results, err = Preflight(MediaStreamConstraints{
+		Video: func(c *MediaTrackConstraints) {
+			c.Width = prop.IntExact(12345)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Found || results[0].Device.Label != "denied" || results[0].Permission != driver.PermissionDenied {
+		t.Fatalf("expected the denied camera, but got %+v", results)
+	}
+	if denied.opened != 0 {
+		t.Fatal("expected the denied camera not to be opened")
+	}
+}
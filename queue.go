@@ -0,0 +1,116 @@
+package mediadevices
+
+import (
+	"image"
+	"sync/atomic"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// captureQueue sits between a video track's capture and its transforms. When SetCaptureQueue enables it, frames
+// pass through a video.FrameQueue; otherwise the source is read directly. The queue is switched in Read, on the
+// transform goroutine, so only one goroutine reads the source at a time.
+type captureQueue struct {
+	src  video.Reader
+	name string
+	// depth is the depth set by SetCaptureQueue, and closed is set once the track is closed.
+	depth  int32
+	closed uint32
+	// queue is the running queue, used for stats and closing the track, and current is the one Read uses.
+	queue   atomic.Value
+	current *video.FrameQueue
+	running int32
+}
+
+// queueHolder wraps the value in captureQueue.queue, since atomic.Value can't store nil.
+type queueHolder struct {
+	queue *video.FrameQueue
+}
+
+func newCaptureQueue(src video.Reader, name string) *captureQueue {
+	c := &captureQueue{src: src, name: name}
+	c.queue.Store(queueHolder{})
+	return c
+}
+
+func (c *captureQueue) Read() (image.Image, func(), error) {
+	for {
+		depth := atomic.LoadInt32(&c.depth)
+		if c.current != nil && depth != c.running {
+			// Drain the queue until it's stopped, then start the new one
+			c.current.Close()
+		}
+		if c.current == nil && depth > 0 && atomic.LoadUint32(&c.closed) == 0 {
+			c.start(int(depth))
+		}
+		if c.current == nil {
+			return c.src.Read()
+		}
+
+		img, release, err := c.current.Read()
+		if err == video.ErrQueueClosed {
+			c.current = nil
+			c.queue.Store(queueHolder{})
+			continue
+		}
+		return img, release, err
+	}
+}
+
+// start runs a new queue's capture on its own goroutine.
+func (c *captureQueue) start(depth int) {
+	q := video.NewFrameQueue(c.src, depth)
+	c.current, c.running = q, int32(depth)
+	c.queue.Store(queueHolder{queue: q})
+
+	stopped := resources.start(c.name)
+	go func() {
+		defer stopped()
+		q.Run()
+	}()
+}
+
+func (c *captureQueue) Metadata() video.Metadata {
+	if c.current != nil {
+		return c.current.Metadata()
+	}
+	m, _ := video.MetadataOf(c.src)
+	return m
+}
+
+func (c *captureQueue) stats() video.QueueStats {
+	if q := c.queue.Load().(queueHolder).queue; q != nil {
+		return q.Stats()
+	}
+	return video.QueueStats{}
+}
+
+// close stops the queue's capture, which returns once the source is closed.
+func (c *captureQueue) close() {
+	atomic.StoreUint32(&c.closed, 1)
+	if q := c.queue.Load().(queueHolder).queue; q != nil {
+		q.Close()
+	}
+}
+
+// SetCaptureQueue runs the track's capture on its own goroutine, handing frames to the transforms through a
+// lock-free queue of depth frames, see video.FrameQueue. The source keeps being read at its frame rate while a
+// frame is transformed or encoded, which reduces jitter at high frame rates on slow cores, e.g. ARM boards. When
+// the queue is full, new frames are dropped and counted in DropStats.Buffer; Stats reports the queue depth. It
+// takes effect from the next frame. 0 disables the queue, which is the default. Only use it for sources paced by
+// a device, since the transforms no longer pace the capture, and queued frames keep their old size after
+// Reconfigure.
+func (track *VideoTrack) SetCaptureQueue(depth int) {
+	if depth < 0 {
+		depth = 0
+	}
+	atomic.StoreInt32(&track.capture.depth, int32(depth))
+}
+
+// Stats returns the number of frames read from the source, the frames dropped at each pipeline stage, and the
+// capture queue state.
+func (track *VideoTrack) Stats() TrackStats {
+	stats := track.baseTrack.Stats()
+	stats.Queue = track.capture.stats()
+	return stats
+}
@@ -0,0 +1,63 @@
+package mediadevices
+
+import (
+	"sync/atomic"
+
+	"github.com/pion/mediadevices/pkg/codec"
+)
+
+// Values for recoveryOptions. They stay unset until the option is set on the track.
+const (
+	recoveryUnset int32 = iota
+	recoveryDisabled
+	recoveryEnabled
+)
+
+// recoveryOptions control how a video track's encoders help receivers recover from losses. They're set by
+// SetIntraRefresh and SetLongTermReference, and applied to each encoder on its reading goroutine.
+type recoveryOptions struct {
+	intraRefresh      int32
+	longTermReference int32
+}
+
+func recoveryOption(enabled bool) int32 {
+	if enabled {
+		return recoveryEnabled
+	}
+	return recoveryDisabled
+}
+
+// SetIntraRefresh enables or disables periodic intra refresh on the track's encoders that implement
+// codec.IntraRefresher, e.g. x264. It overrides the encoder params and takes effect before the next frame.
+func (track *VideoTrack) SetIntraRefresh(enabled bool) {
+	atomic.StoreInt32(&track.recovery.intraRefresh, recoveryOption(enabled))
+}
+
+// SetLongTermReference enables or disables long-term reference frames on the track's encoders that implement
+// codec.LongTermReferencer, e.g. openh264. It overrides the encoder params and takes effect before the next frame.
+func (track *VideoTrack) SetLongTermReference(enabled bool) {
+	atomic.StoreInt32(&track.recovery.longTermReference, recoveryOption(enabled))
+}
+
+// apply sets the options that changed since applied on encoder, and returns the options now in effect.
+func (o *recoveryOptions) apply(encoder codec.ReadCloser, applied recoveryOptions) recoveryOptions {
+	current := recoveryOptions{
+		intraRefresh:      atomic.LoadInt32(&o.intraRefresh),
+		longTermReference: atomic.LoadInt32(&o.longTermReference),
+	}
+	if current.intraRefresh != applied.intraRefresh && current.intraRefresh != recoveryUnset {
+		if r, ok := encoder.(codec.IntraRefresher); ok {
+			if err := r.SetIntraRefresh(current.intraRefresh == recoveryEnabled); err != nil {
+				logger.Debugf("failed to set the intra refresh of the encoder: %s", err)
+			}
+		}
+	}
+	if current.longTermReference != applied.longTermReference && current.longTermReference != recoveryUnset {
+		if r, ok := encoder.(codec.LongTermReferencer); ok {
+			if err := r.SetLongTermReference(current.longTermReference == recoveryEnabled); err != nil {
+				logger.Debugf("failed to set the long-term reference of the encoder: %s", err)
+			}
+		}
+	}
+	return current
+}
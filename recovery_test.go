@@ -0,0 +1,88 @@
+package mediadevices
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+// testRecoveryEncoder is a testKeyFrameEncoder that records the recovery options set on it.
+type testRecoveryEncoder struct {
+	testKeyFrameEncoder
+	intraRefresh      []bool
+	longTermReference []bool
+}
+
+func (e *testRecoveryEncoder) SetIntraRefresh(enabled bool) error {
+	e.intraRefresh = append(e.intraRefresh, enabled)
+	return nil
+}
+
+func (e *testRecoveryEncoder) SetLongTermReference(enabled bool) error {
+	e.longTermReference = append(e.longTermReference, enabled)
+	return nil
+}
+
+type testRecoveryEncoderBuilder struct {
+	testKeyFrameEncoderBuilder
+	encoders []*testRecoveryEncoder
+}
+
+func (b *testRecoveryEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	e := &testRecoveryEncoder{testKeyFrameEncoder: testKeyFrameEncoder{r: r}}
+	b.encoders = append(b.encoders, e)
+	return e, nil
+}
+
+func TestVideoTrackRecoveryOptions(t *testing.T) {
+	builder := &testRecoveryEncoderBuilder{}
+	track := NewVideoTrack(&closableVideoSource{id: "camera", img: testLumaImage(32, 24, 1)},
+		NewCodecSelector(WithVideoEncoders(builder))).(*VideoTrack)
+	defer track.Close()
+
+	r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	read := func() {
+		if _, _, err := r.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read()
+	e := builder.encoders[0]
+	if len(e.intraRefresh) != 0 || len(e.longTermReference) != 0 {
+		t.Fatal("expected the params of the encoder to be kept until the options are set")
+	}
+
+	track.SetIntraRefresh(true)
+	read()
+	read()
+	track.SetLongTermReference(true)
+	track.SetIntraRefresh(false)
+	read()
+	if expected := []bool{true, false}; !reflect.DeepEqual(e.intraRefresh, expected) {
+		t.Fatalf("expected the intra refresh to be set to %v, but got %v", expected, e.intraRefresh)
+	}
+	if expected := []bool{true}; !reflect.DeepEqual(e.longTermReference, expected) {
+		t.Fatalf("expected the long-term reference to be set to %v, but got %v", expected, e.longTermReference)
+	}
+
+	// Options are applied again to rebuilt encoders
+	atomic.AddUint32(&track.resets, 1)
+	read()
+	if len(builder.encoders) != 2 {
+		t.Fatalf("expected the encoder to be rebuilt, but got %d encoders", len(builder.encoders))
+	}
+	rebuilt := builder.encoders[1]
+	if !reflect.DeepEqual(rebuilt.intraRefresh, []bool{false}) || !reflect.DeepEqual(rebuilt.longTermReference, []bool{true}) {
+		t.Fatalf("expected the options to be applied to the rebuilt encoder, but got %v and %v", rebuilt.intraRefresh, rebuilt.longTermReference)
+	}
+}
@@ -0,0 +1,319 @@
+package mediadevices
+
+import (
+	"image"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// sourceSwitch reads a video track whose source can be replaced while the track is read. New source
+// frames are scaled to the track's first frame size, so encoders are kept.
+type sourceSwitch struct {
+	mu     sync.Mutex
+	source Source
+	reader video.Reader
+	// raw reads the source before scaling.
+	raw video.Reader
+	// last is the reader of the last frame, whose metadata is returned. It's nil for transition frames.
+	last video.Reader
+	size image.Point
+	// dropped counts frames dropped by replaced sources, which is added to the current source's.
+	dropped     uint64
+	lastDropped uint64
+
+	transition Transition
+	// pending is the new source's first frame while it warms up.
+	pending <-chan warmUpFrame
+	// lastImage is the source's last frame, and held is its copy, which the transition shows.
+	// lastRelease drops the switch's reference to lastImage, so it isn't reused before it's copied.
+	lastImage   image.Image
+	lastRelease func()
+	held        *image.YCbCr
+	// fade is how many crossfade frames remain.
+	fade     int
+	lastRead time.Time
+	interval time.Duration
+
+	generation uint32
+}
+
+func newSourceSwitch(source Source, reader video.Reader) *sourceSwitch {
+	return &sourceSwitch{source: source, reader: reader, raw: reader, last: reader, interval: defaultTransitionInterval}
+}
+
+func (s *sourceSwitch) current() (Source, video.Reader, uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source, s.reader, atomic.LoadUint32(&s.generation)
+}
+
+// switches returns how many times the source was replaced.
+func (s *sourceSwitch) switches() uint32 {
+	return atomic.LoadUint32(&s.generation)
+}
+
+func (s *sourceSwitch) Read() (image.Image, func(), error) {
+	for {
+		s.mu.Lock()
+		r, pending, generation := s.reader, s.pending, s.switches()
+		s.mu.Unlock()
+
+		var img image.Image
+		var release func()
+		var err error
+		if pending != nil {
+			frame, held := s.waitWarmUp(pending)
+			if held != nil {
+				return s.transitionFrame(held), func() {}, nil
+			}
+			img, release, err = frame.img, frame.release, frame.err
+		} else {
+			img, release, err = r.Read()
+		}
+		if err != nil {
+			if s.switches() != generation {
+				// The source was closed because it was replaced, so read the frame from the new one.
+				continue
+			}
+			return nil, func() {}, err
+		}
+
+		s.mu.Lock()
+		if s.switches() != generation {
+			s.mu.Unlock()
+			release()
+			continue
+		}
+		if pending != nil {
+			s.pending = nil
+		}
+		img, release = s.delivered(r, img, release)
+		s.mu.Unlock()
+		return img, release, nil
+	}
+}
+
+// waitWarmUp waits for the new source's first frame, or returns the replaced source's last frame when
+// a transition frame has to be shown.
+func (s *sourceSwitch) waitWarmUp(pending <-chan warmUpFrame) (warmUpFrame, *image.YCbCr) {
+	s.mu.Lock()
+	mode, interval, held := s.transition.Mode, s.interval, s.held
+	s.mu.Unlock()
+	if mode == TransitionNone || held == nil {
+		return <-pending, nil
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case frame := <-pending:
+		return frame, nil
+	case <-timer.C:
+		return warmUpFrame{}, held
+	}
+}
+
+// transitionFrame returns the frame shown instead of held while the new source warms up.
+func (s *sourceSwitch) transitionFrame(held *image.YCbCr) image.Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.last = nil
+	if s.transition.Mode == TransitionBlack {
+		return blackI420(held.Rect.Size())
+	}
+	return held
+}
+
+// delivered records img read from r, and returns the crossfade frame if one is in progress.
+func (s *sourceSwitch) delivered(r video.Reader, img image.Image, release func()) (image.Image, func()) {
+	s.releaseLast()
+	release, s.lastRelease = retainFrame(release)
+	s.last, s.lastImage = r, img
+	if s.size == (image.Point{}) {
+		s.size = img.Bounds().Size()
+	}
+
+	now := time.Now()
+	if !s.lastRead.IsZero() {
+		if d := now.Sub(s.lastRead); d > 0 && d < time.Second {
+			s.interval = d
+		}
+	}
+	s.lastRead = now
+
+	if s.fade <= 0 || s.held == nil {
+		return img, release
+	}
+	frames := s.crossfadeFrames()
+	alpha := float64(frames-s.fade+1) / float64(frames+1)
+	s.fade--
+	faded := crossfade(s.held, img, alpha)
+	release()
+	return faded, func() {}
+}
+
+// retainFrame shares a frame between its reader and the switch, which keeps the last frame for the transition.
+// release is called once both have released it.
+func retainFrame(release func()) (reader, last func()) {
+	refs := int32(2)
+	unref := func() {
+		if atomic.AddInt32(&refs, -1) == 0 {
+			release()
+		}
+	}
+	var once sync.Once
+	return func() { once.Do(unref) }, unref
+}
+
+// releaseLast drops the switch's reference to the last frame.
+func (s *sourceSwitch) releaseLast() {
+	if s.lastRelease != nil {
+		s.lastRelease()
+	}
+	s.lastImage, s.lastRelease = nil, nil
+}
+
+func (s *sourceSwitch) crossfadeFrames() int {
+	if s.transition.Frames > 0 {
+		return s.transition.Frames
+	}
+	return defaultCrossfadeFrames
+}
+
+func (s *sourceSwitch) Metadata() video.Metadata {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, _ := video.MetadataOf(s.last)
+	s.lastDropped = m.Dropped
+	m.Dropped += s.dropped
+	return m
+}
+
+// replace switches to source's reader r, and closes the replaced source. source may be nil if the
+// track doesn't own the reader.
+func (s *sourceSwitch) replace(source Source, r video.Reader) error {
+	s.mu.Lock()
+	s.raw = r
+	if s.size != (image.Point{}) {
+		r = fitSize(s.size.X, s.size.Y, r)
+	}
+	old := s.source
+	s.source, s.reader = source, r
+	s.dropped += s.lastDropped
+	s.lastDropped = 0
+
+	// Copy the last frame before releasing it and closing the replaced source, since its buffer may be reused,
+	// e.g. by frame.Pool.
+	if s.transition.Mode != TransitionNone && s.lastImage != nil && s.pending == nil {
+		s.held = copyI420(s.lastImage)
+	}
+	s.releaseLast()
+	s.fade = 0
+	if s.transition.Mode == TransitionCrossfade {
+		s.fade = s.crossfadeFrames()
+	}
+	if s.transition.Mode != TransitionNone || s.transition.WarmUp > 0 {
+		s.pending = warmUp(r, s.transition.WarmUp)
+	} else {
+		s.pending = nil
+	}
+	s.lastRead = time.Time{}
+	atomic.AddUint32(&s.generation, 1)
+	s.mu.Unlock()
+
+	if old == nil || old == source {
+		return nil
+	}
+	return old.Close()
+}
+
+// currentSize returns the size frames are scaled to, or zero before the first frame.
+func (s *sourceSwitch) currentSize() image.Point {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// resize changes the size frames are scaled to.
+func (s *sourceSwitch) resize(size image.Point) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.size = size
+	s.reader = fitSize(size.X, size.Y, s.raw)
+}
+
+func (s *sourceSwitch) close() error {
+	s.mu.Lock()
+	s.releaseLast()
+	s.mu.Unlock()
+	source, _, _ := s.current()
+	if source == nil {
+		return nil
+	}
+	return source.Close()
+}
+
+// ReplaceSource switches the track's source to r, e.g. from camera to screen, while the track is
+// read. The track's encoders and RTP senders are kept, and each encoder's next frame is a keyframe.
+// r's frames are scaled to the track size if they differ. If r is a Source, e.g. a VideoSource,
+// it's closed with the track. The replaced source is closed, but the track ID is kept.
+func (track *VideoTrack) ReplaceSource(r video.Reader) error {
+	source, _ := r.(Source)
+	err := track.switcher.replace(source, r)
+	track.setDriver(nil, nil, MediaTrackConstraints{})
+	return err
+}
+
+// ReplaceDriver switches the track's source to video driver d, like ReplaceSource.
+// d's property is selected by constraints like GetUserMedia.
+func (track *VideoTrack) ReplaceDriver(d driver.Driver, constraints MediaOption) error {
+	recorder, ok := d.(driver.VideoRecorder)
+	if !ok {
+		return errInvalidDriverType
+	}
+
+	var c MediaTrackConstraints
+	if constraints != nil {
+		constraints(&c)
+	}
+	_, c, err := selectBestDriver(driver.FilterID(d.ID()), c)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Configure(c.DriverOptions); err != nil {
+		return err
+	}
+	if err := d.Open(); err != nil {
+		return err
+	}
+	if err := d.SetControls(c.Controls); err != nil {
+		d.Close()
+		return err
+	}
+	reader, err := recordVideo(recorder, c.selectedMedia)
+	if err != nil {
+		closeDriver(d)
+		return err
+	}
+	err = track.switcher.replace(d, reader)
+	track.setDriver(d, recorder, c)
+	return err
+}
+
+// Reconfigure changes the track resolution to width x height while it's being read, e.g. after ReplaceDriver
+// picked a new resolution (otherwise its frames are scaled to the old size). Source frames of another size are
+// scaled. Encoders implementing codec.Resetter are reset in place and others are rebuilt, so RTP senders are
+// kept, and each encoder's next frame is a keyframe with the new codec configuration.
+func (track *VideoTrack) Reconfigure(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errInvalidResolution
+	}
+	track.switcher.resize(image.Pt(width, height))
+	atomic.AddUint32(&track.reconfigs, 1)
+	return nil
+}
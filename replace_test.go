@@ -0,0 +1,294 @@
+package mediadevices
+
+import (
+	"errors"
+	"image"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+type closableVideoSource struct {
+	id  string
+	img image.Image
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *closableVideoSource) Read() (image.Image, func(), error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, func() {}, io.EOF
+	}
+	return s.img, func() {}, nil
+}
+
+func (s *closableVideoSource) ID() string { return s.id }
+
+func (s *closableVideoSource) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *closableVideoSource) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// testKeyFrameEncoder encodes a frame to its size, the first luma sample, and whether it's a keyframe.
+type testKeyFrameEncoder struct {
+	r         video.Reader
+	keyFrame  bool
+	keyFrames int
+	closes    int
+}
+
+func (e *testKeyFrameEncoder) Read() ([]byte, func(), error) {
+	img, _, err := e.r.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	var key byte
+	if e.keyFrame {
+		key, e.keyFrame = 1, false
+	}
+	bounds := img.Bounds()
+	return []byte{byte(bounds.Dx()), byte(bounds.Dy()), img.(*image.YCbCr).Y[0], key}, func() {}, nil
+}
+
+func (e *testKeyFrameEncoder) ForceKeyFrame() error {
+	e.keyFrame = true
+	e.keyFrames++
+	return nil
+}
+
+func (e *testKeyFrameEncoder) Close() error {
+	e.closes++
+	return nil
+}
+
+func (e *testKeyFrameEncoder) SetBitRate(int) error { return nil }
+
+type testKeyFrameEncoderBuilder struct{}
+
+func (b *testKeyFrameEncoderBuilder) RTPCodec() *codec.RTPCodec { return codec.NewRTPVP8Codec(90000) }
+
+func (b *testKeyFrameEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	return &testKeyFrameEncoder{r: r}, nil
+}
+
+func testLumaImage(width, height int, luma uint8) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, width, height), image.YCbCrSubsampleRatio420)
+	for i := range img.Y {
+		img.Y[i] = luma
+	}
+	for i := range img.Cb {
+		img.Cb[i], img.Cr[i] = 128, 128
+	}
+	return img
+}
+
+func TestVideoTrackReplaceSource(t *testing.T) {
+	camera := &closableVideoSource{id: "camera", img: testLumaImage(64, 48, 1)}
+	screen := &closableVideoSource{id: "screen", img: testLumaImage(32, 24, 200)}
+
+	track := NewVideoTrack(camera, NewCodecSelector(WithVideoEncoders(&testKeyFrameEncoderBuilder{}))).(*VideoTrack)
+	ended := make(chan error, 1)
+	track.OnEnded(func(err error) { ended <- err })
+
+	r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	buf, _, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Data[2] != 1 {
+		t.Fatalf("expected the frame from the camera, but got luma %d", buf.Data[2])
+	}
+
+	if err := track.ReplaceSource(screen); err != nil {
+		t.Fatal(err)
+	}
+	if !camera.isClosed() {
+		t.Fatal("expected the replaced source to be closed")
+	}
+	if track.ID() != "camera" {
+		t.Fatalf("expected the ID of the track to be kept, but got %s", track.ID())
+	}
+
+	var keyFrames int
+	for i := 0; ; i++ {
+		if i == 10 {
+			t.Fatal("expected the frames from the new source")
+		}
+		buf, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyFrames += int(buf.Data[3])
+		if buf.Data[2] != 200 {
+			continue
+		}
+		if buf.Data[0] != 64 || buf.Data[1] != 48 {
+			t.Fatalf("expected the frame to be scaled to 64x48, but got %dx%d", buf.Data[0], buf.Data[1])
+		}
+		break
+	}
+	if keyFrames != 1 {
+		t.Fatalf("expected a keyframe at the switch, but got %d", keyFrames)
+	}
+
+	select {
+	case err := <-ended:
+		t.Fatalf("expected the track to be kept, but it ended with %v", err)
+	default:
+	}
+
+	if err := track.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !screen.isClosed() {
+		t.Fatal("expected the current source to be closed with the track")
+	}
+}
+
+// testResetEncoder is a testKeyFrameEncoder that implements codec.Resetter.
+type testResetEncoder struct {
+	testKeyFrameEncoder
+	resets []prop.Media
+}
+
+func (e *testResetEncoder) Reset(p prop.Media) error {
+	e.resets = append(e.resets, p)
+	e.keyFrame = true
+	return nil
+}
+
+type testResetEncoderBuilder struct {
+	testKeyFrameEncoderBuilder
+	resettable bool
+	encoders   []codec.ReadCloser
+	// failRebuild makes every build after the first one fail.
+	failRebuild bool
+}
+
+func (b *testResetEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	if b.failRebuild && len(b.encoders) > 0 {
+		return nil, errRebuild
+	}
+	// Each encoder starts with a keyframe
+	var e codec.ReadCloser = &testKeyFrameEncoder{r: r, keyFrame: true}
+	if b.resettable {
+		e = &testResetEncoder{testKeyFrameEncoder: testKeyFrameEncoder{r: r, keyFrame: true}}
+	}
+	b.encoders = append(b.encoders, e)
+	return e, nil
+}
+
+func TestVideoTrackReconfigure(t *testing.T) {
+	for name, resettable := range map[string]bool{"Reset": true, "Rebuild": false} {
+		resettable := resettable
+		t.Run(name, func(t *testing.T) {
+			camera := &closableVideoSource{id: "camera", img: testLumaImage(64, 48, 1)}
+			builder := &testResetEncoderBuilder{resettable: resettable}
+			track := NewVideoTrack(camera, NewCodecSelector(WithVideoEncoders(builder))).(*VideoTrack)
+			defer track.Close()
+
+			r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			if _, _, err := r.Read(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := track.Reconfigure(0, 24); err != errInvalidResolution {
+				t.Fatalf("expected %v, but got %v", errInvalidResolution, err)
+			}
+			if err := track.ReplaceSource(&closableVideoSource{id: "screen", img: testLumaImage(32, 24, 200)}); err != nil {
+				t.Fatal(err)
+			}
+			if err := track.Reconfigure(32, 24); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; ; i++ {
+				if i == 10 {
+					t.Fatal("expected the frames of the new resolution")
+				}
+				buf, _, err := r.Read()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if buf.Data[2] != 200 || buf.Data[0] != 32 {
+					continue
+				}
+				if buf.Data[1] != 24 || buf.Data[3] != 1 {
+					t.Fatalf("expected a 32x24 keyframe, but got %dx%d, key %d", buf.Data[0], buf.Data[1], buf.Data[3])
+				}
+				break
+			}
+
+			if resettable {
+				if len(builder.encoders) != 1 {
+					t.Fatalf("expected the encoder to be kept, but got %d encoders", len(builder.encoders))
+				}
+				resets := builder.encoders[0].(*testResetEncoder).resets
+				if len(resets) != 1 || resets[0].Width != 32 || resets[0].Height != 24 {
+					t.Fatalf("expected a reset to 32x24, but got %v", resets)
+				}
+			} else if len(builder.encoders) != 2 {
+				t.Fatalf("expected the encoder to be rebuilt, but got %d encoders", len(builder.encoders))
+			}
+		})
+	}
+}
+
+var errRebuild = errors.New("failed to rebuild")
+
+func TestVideoTrackReconfigureRebuildError(t *testing.T) {
+	camera := &closableVideoSource{id: "camera", img: testLumaImage(64, 48, 1)}
+	builder := &testResetEncoderBuilder{failRebuild: true}
+	track := NewVideoTrack(camera, NewCodecSelector(WithVideoEncoders(builder))).(*VideoTrack)
+	defer track.Close()
+
+	r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := track.Reconfigure(32, 24); err != nil {
+		t.Fatal(err)
+	}
+	// The error sticks, and the closed encoder isn't read again
+	for i := 0; i < 2; i++ {
+		if _, _, err := r.Read(); err == nil || !strings.Contains(err.Error(), errRebuild.Error()) {
+			t.Fatalf("expected %v, but got %v", errRebuild, err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if closes := builder.encoders[0].(*testKeyFrameEncoder).closes; closes != 1 {
+		t.Fatalf("expected the encoder to be closed once, but it was closed %d times", closes)
+	}
+}
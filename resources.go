@@ -0,0 +1,300 @@
+package mediadevices
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+const defaultJoinTimeout = 5 * time.Second
+
+// ResourceOptions configures how package resources are tracked, see Resources.
+type ResourceOptions struct {
+	// Debug records where each track, goroutine and encoded buffer is created, and tracks encoded buffers
+	// that aren't released. It takes a stack trace for each, so it's meant for finding leaks.
+	Debug bool
+	// JoinTimeout is how long Close waits for package goroutines to stop. If it's 0, 5 seconds
+	// is used.
+	JoinTimeout time.Duration
+}
+
+var (
+	resourceOptionsMu sync.Mutex
+	resourceOptions   ResourceOptions
+)
+
+// SetResourceOptions configures resource tracking. Debug mode applies to resources
+// created after this call.
+func SetResourceOptions(options ResourceOptions) {
+	resourceOptionsMu.Lock()
+	resourceOptions = options
+	resourceOptionsMu.Unlock()
+}
+
+func getResourceOptions() ResourceOptions {
+	resourceOptionsMu.Lock()
+	defer resourceOptionsMu.Unlock()
+	return resourceOptions
+}
+
+// Resource is a package resource that's open or running.
+type Resource struct {
+	// Name identifies the resource, e.g. a track or driver ID, or what a goroutine does.
+	Name string
+	// State is the driver state, or empty.
+	State string
+	// Stack is where the resource was created in debug mode, or empty.
+	Stack string
+}
+
+// ResourceReport lists package resources that are open or running.
+type ResourceReport struct {
+	Tracks     []Resource
+	Drivers    []Resource
+	Goroutines []Resource
+	// Buffers are encoded buffers that aren't released. They're only tracked in debug mode.
+	Buffers []Resource
+}
+
+// Empty reports whether no resource is left.
+func (r ResourceReport) Empty() bool {
+	return len(r.Tracks) == 0 && len(r.Drivers) == 0 && len(r.Goroutines) == 0 && len(r.Buffers) == 0
+}
+
+func (r ResourceReport) String() string {
+	var b strings.Builder
+	write := func(kind string, resources []Resource) {
+		for _, res := range resources {
+			fmt.Fprintf(&b, "%s %s", kind, res.Name)
+			if res.State != "" {
+				fmt.Fprintf(&b, " (%s)", res.State)
+			}
+			b.WriteString("\n")
+			if res.Stack != "" {
+				b.WriteString(res.Stack)
+			}
+		}
+	}
+	write("track", r.Tracks)
+	write("driver", r.Drivers)
+	write("goroutine", r.Goroutines)
+	write("buffer", r.Buffers)
+	return b.String()
+}
+
+// resourceRegistry tracks the package's tracks, goroutines and buffers.
+type resourceRegistry struct {
+	mu     sync.Mutex
+	next   uint64
+	tracks map[Track]Resource
+	// closers are other package objects that run goroutines, e.g. Watchdog.
+	closers    map[io.Closer]struct{}
+	goroutines map[uint64]Resource
+	buffers    map[uint64]Resource
+	// stopped is signaled when a goroutine stops, for join.
+	stopped *sync.Cond
+}
+
+var resources = newResourceRegistry()
+
+func newResourceRegistry() *resourceRegistry {
+	r := &resourceRegistry{
+		tracks:     make(map[Track]Resource),
+		closers:    make(map[io.Closer]struct{}),
+		goroutines: make(map[uint64]Resource),
+		buffers:    make(map[uint64]Resource),
+	}
+	r.stopped = sync.NewCond(&r.mu)
+	return r
+}
+
+func newResource(name string) Resource {
+	res := Resource{Name: name}
+	if getResourceOptions().Debug {
+		res.Stack = string(debug.Stack())
+	}
+	return res
+}
+
+func (r *resourceRegistry) addTrack(track Track) {
+	res := newResource(track.ID())
+	r.mu.Lock()
+	r.tracks[track] = res
+	r.mu.Unlock()
+}
+
+func (r *resourceRegistry) removeTrack(track Track) {
+	r.mu.Lock()
+	delete(r.tracks, track)
+	r.mu.Unlock()
+}
+
+func (r *resourceRegistry) addCloser(c io.Closer) {
+	r.mu.Lock()
+	r.closers[c] = struct{}{}
+	r.mu.Unlock()
+}
+
+func (r *resourceRegistry) removeCloser(c io.Closer) {
+	r.mu.Lock()
+	delete(r.closers, c)
+	r.mu.Unlock()
+}
+
+// start records a goroutine named name, and returns a function the goroutine calls when it stops.
+func (r *resourceRegistry) start(name string) func() {
+	res := newResource(name)
+	r.mu.Lock()
+	id := r.next
+	r.next++
+	r.goroutines[id] = res
+	r.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.goroutines, id)
+			r.mu.Unlock()
+			r.stopped.Broadcast()
+		})
+	}
+}
+
+// trackBuffer records release's encoded buffer until it's released in debug mode.
+func (r *resourceRegistry) trackBuffer(name string, release func()) func() {
+	if !getResourceOptions().Debug {
+		return release
+	}
+
+	res := newResource(name)
+	r.mu.Lock()
+	id := r.next
+	r.next++
+	r.buffers[id] = res
+	r.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.buffers, id)
+			r.mu.Unlock()
+		})
+		release()
+	}
+}
+
+// join waits until the goroutines stop or timeout passes.
+func (r *resourceRegistry) join(timeout time.Duration) {
+	timer := time.AfterFunc(timeout, func() {
+		r.mu.Lock()
+		r.stopped.Broadcast()
+		r.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	deadline := time.Now().Add(timeout)
+	r.mu.Lock()
+	for len(r.goroutines) > 0 && time.Now().Before(deadline) {
+		r.stopped.Wait()
+	}
+	r.mu.Unlock()
+}
+
+func sortedResources(m map[uint64]Resource) []Resource {
+	ids := make([]uint64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	list := make([]Resource, len(ids))
+	for i, id := range ids {
+		list[i] = m[id]
+	}
+	return list
+}
+
+func (r *resourceRegistry) report() ResourceReport {
+	var report ResourceReport
+	r.mu.Lock()
+	for _, res := range r.tracks {
+		report.Tracks = append(report.Tracks, res)
+	}
+	report.Goroutines = sortedResources(r.goroutines)
+	report.Buffers = sortedResources(r.buffers)
+	r.mu.Unlock()
+	sort.Slice(report.Tracks, func(i, j int) bool { return report.Tracks[i].Name < report.Tracks[j].Name })
+
+	for _, d := range driver.GetManager().Query(func(driver.Driver) bool { return true }) {
+		if state := d.Status(); state != driver.StateClosed {
+			report.Drivers = append(report.Drivers, Resource{Name: d.ID(), State: string(state)})
+		}
+	}
+	return report
+}
+
+func (r *resourceRegistry) open() ([]io.Closer, []Track) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	closers := make([]io.Closer, 0, len(r.closers))
+	for c := range r.closers {
+		closers = append(closers, c)
+	}
+	tracks := make([]Track, 0, len(r.tracks))
+	for track := range r.tracks {
+		tracks = append(tracks, track)
+	}
+	return closers, tracks
+}
+
+// Resources returns tracks that aren't closed, open drivers, running package goroutines,
+// and in debug mode, encoded buffers that aren't released.
+func Resources() ResourceReport {
+	return resources.report()
+}
+
+// Close stops the package: it closes watchdogs, pre-event buffers, all tracks and open drivers, and waits
+// for package goroutines to stop, see ResourceOptions.JoinTimeout. It returns resources that are left, e.g.
+// goroutines blocked by a peer connection or encoded buffers that aren't released.
+func Close() (ResourceReport, error) {
+	var errs []string
+	closers, tracks := resources.open()
+	for _, c := range closers {
+		c.Close()
+		resources.removeCloser(c)
+	}
+	for _, track := range tracks {
+		if err := track.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("track %s: %s", track.ID(), err))
+		}
+		// Tracks that fail to close are still stopped.
+		resources.removeTrack(track)
+	}
+	for _, d := range driver.GetManager().Query(func(driver.Driver) bool { return true }) {
+		if d.Status() == driver.StateClosed {
+			continue
+		}
+		if err := d.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("driver %s: %s", d.ID(), err))
+		}
+	}
+
+	timeout := getResourceOptions().JoinTimeout
+	if timeout <= 0 {
+		timeout = defaultJoinTimeout
+	}
+	resources.join(timeout)
+
+	report := resources.report()
+	if len(errs) > 0 {
+		return report, fmt.Errorf("failed to close the resources: %s", strings.Join(errs, "; "))
+	}
+	return report, nil
+}
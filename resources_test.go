@@ -0,0 +1,136 @@
+package mediadevices
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+func hasResource(resources []Resource, name string) bool {
+	for _, res := range resources {
+		if strings.Contains(res.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClose(t *testing.T) {
+	SetResourceOptions(ResourceOptions{JoinTimeout: time.Second})
+	defer SetResourceOptions(ResourceOptions{})
+
+	a := &screenAdapterMock{}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "close", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	d := driver.GetManager().Query(func(d driver.Driver) bool { return d.Info().Label == "close" })[0]
+	track, err := newTrackFromDriver(d, MediaTrackConstraints{selectedMedia: photoMedia(64, 32)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatchdog()
+	defer w.Close()
+	w.Watch(track)
+
+	report := Resources()
+	if !hasResource(report.Tracks, track.ID()) || !hasResource(report.Drivers, d.ID()) ||
+		!hasResource(report.Goroutines, "watchdog of "+track.ID()) {
+		t.Fatalf("expected the track, the driver and the watchdog to be reported, but got:\n%s", report)
+	}
+
+	report, err = Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasResource(report.Tracks, track.ID()) || hasResource(report.Drivers, d.ID()) ||
+		hasResource(report.Goroutines, "watchdog of "+track.ID()) {
+		t.Fatalf("expected the resources to be closed, but got:\n%s", report)
+	}
+	if d.Status() != driver.StateClosed {
+		t.Fatalf("expected the driver to be closed, but got %s", d.Status())
+	}
+}
+
+type failingCameraMock struct {
+	screenAdapterMock
+}
+
+func (a *failingCameraMock) VideoRecord(p prop.Media) (video.Reader, error) {
+	return nil, errors.New("busy")
+}
+
+func TestNewTrackFromDriverFailure(t *testing.T) {
+	a := &failingCameraMock{}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "failing", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	d := driver.GetManager().Query(func(d driver.Driver) bool { return d.Info().Label == "failing" })[0]
+	if _, err := newTrackFromDriver(d, MediaTrackConstraints{selectedMedia: photoMedia(64, 32)}, nil); err == nil {
+		t.Fatal("expected the track to fail")
+	}
+	if d.Status() != driver.StateClosed {
+		t.Fatalf("expected the driver to be closed after the failure, but got %s", d.Status())
+	}
+}
+
+func TestResourcesDebugBuffers(t *testing.T) {
+	SetResourceOptions(ResourceOptions{Debug: true})
+	defer SetResourceOptions(ResourceOptions{})
+
+	selector := NewCodecSelector(WithVideoEncoders(&testBitRateEncoderBuilder{}))
+	img := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	track := NewVideoTrack(&testVideoSource{img: img}, selector)
+	defer track.Close()
+
+	r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	_, release, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := Resources()
+	if len(report.Buffers) != 1 || !strings.Contains(report.Buffers[0].Stack, "TestResourcesDebugBuffers") {
+		t.Fatalf("expected the unreleased buffer with its stack, but got %+v", report.Buffers)
+	}
+	if len(report.Tracks) == 0 || report.Tracks[0].Stack == "" {
+		t.Fatal("expected the stack of the track in the debug mode")
+	}
+	release()
+	release()
+	if n := len(Resources().Buffers); n != 0 {
+		t.Fatalf("expected the buffer to be released, but got %d", n)
+	}
+}
+
+func TestMediaStreamClose(t *testing.T) {
+	img := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	track := NewVideoTrack(&testVideoSource{img: img}, nil)
+	stream, err := NewMediaStream(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(stream.GetTracks()); n != 0 {
+		t.Fatalf("expected the tracks to be removed, but got %d", n)
+	}
+	if hasResource(Resources().Tracks, track.ID()) {
+		t.Fatal("expected the track to be closed")
+	}
+}
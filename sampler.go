@@ -3,22 +3,136 @@ package mediadevices
 import (
 	"math"
 	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
 )
 
 type samplerFunc func() uint32
 
-// newVideoSampler creates a video sampler that uses the actual video frame rate and
-// the codec's clock rate to come up with a duration for each sample.
-func newVideoSampler(clockRate uint32) samplerFunc {
+// TimestampSource is the time encoded buffers' RTP timestamps are generated from.
+type TimestampSource int
+
+const (
+	// TimestampDefault uses TimestampCapture for video, and TimestampNominal for audio. Audio is
+	// resampled to follow the CodecSelector's MediaClock if any, see newClockAudioReader.
+	TimestampDefault TimestampSource = iota
+	// TimestampCapture uses frame capture times, e.g. kernel timestamps, which aren't
+	// affected by transform and encoder jitter. Frames without a capture time use the clock.
+	// Audio chunks don't have capture times, so it's the same as TimestampDefault for audio.
+	TimestampCapture
+	// TimestampClock uses the clock's time when each buffer is encoded. It's the wall clock unless the
+	// CodecSelector's MediaClock has another time source, e.g. a PTP clock.
+	TimestampClock
+	// TimestampNominal uses nominal buffer durations, i.e. the video frame interval or the audio
+	// codec latency. Timestamps are evenly spaced, but they drift from the device clock.
+	TimestampNominal
+)
+
+func (s TimestampSource) String() string {
+	switch s {
+	case TimestampCapture:
+		return "capture"
+	case TimestampClock:
+		return "clock"
+	case TimestampNominal:
+		return "nominal"
+	default:
+		return "default"
+	}
+}
+
+// presentationClock derives the presentation and decoding times of a track's encoded frames from their capture
+// times. They're relative to the track's first captured frame, so they carry on when the encoder is rebuilt.
+type presentationClock struct {
+	first time.Time
+}
+
+// times returns the capture time, PTS and DTS of the frame returned by encoder's last Read. Unless encoder is a
+// codec.FrameReporter, that's the last frame it read, which was captured at captured.
+func (c *presentationClock) times(encoder codec.ReadCloser, captured time.Time) (time.Time, time.Duration, time.Duration) {
+	var reordered time.Duration
+	if r, ok := encoder.(codec.FrameReporter); ok {
+		if f, ok := r.EncodedFrame(); ok {
+			captured, reordered = f.Metadata.CaptureTime, f.PTS-f.DTS
+		}
+	}
+	if captured.IsZero() {
+		return captured, 0, 0
+	}
+	if c.first.IsZero() {
+		c.first = captured
+	}
+	pts := captured.Sub(c.first)
+	return captured, pts, pts - reordered
+}
+
+// newVideoSampler creates source's video sampler. clock is used when capture time is unknown.
+func newVideoSampler(source TimestampSource, clock *MediaClock, clockRate uint32, frameRate float32, captureTime func() time.Time) samplerFunc {
+	switch {
+	case source == TimestampClock:
+		return newCaptureVideoSampler(clock, clockRate, func() time.Time { return time.Time{} })
+	case source == TimestampNominal && frameRate > 0:
+		return newNominalSampler(float64(clockRate) / float64(frameRate))
+	default:
+		return newCaptureVideoSampler(clock, clockRate, captureTime)
+	}
+}
+
+// newSourceAudioSampler creates source's audio sampler. clock may be nil unless the CodecSelector has one.
+// Audio that follows the clock has nominal durations, since newClockAudioReader resamples it.
+func newSourceAudioSampler(source TimestampSource, clock *MediaClock, clockRate uint32, latency time.Duration) samplerFunc {
+	switch {
+	case source == TimestampClock:
+		if clock == nil {
+			clock = NewMediaClock()
+		}
+		return newCaptureVideoSampler(clock, clockRate, func() time.Time { return time.Time{} })
+	default:
+		return newAudioSampler(clockRate, latency)
+	}
+}
+
+// newNominalSampler creates a sampler of a fixed duration in samples, which may be fractional, e.g. 1501.5 for
+// 59.94 fps at 90kHz. Fractions are accumulated, so timestamps don't drift from the nominal rate.
+func newNominalSampler(samples float64) samplerFunc {
+	var total float64
+	var sent uint64
+	return samplerFunc(func() uint32 {
+		total += samples
+		n := uint64(math.Round(total)) - sent
+		sent += n
+		return uint32(n)
+	})
+}
+
+// newCaptureVideoSampler creates a video sampler that uses the actual video frame rate and
+// the codec's clock rate to come up with a duration for each sample. Durations are measured between the
+// frames' capture times instead of their encode times, so timestamps aren't affected by transform and
+// encoder jitter. Capture times have to come from clock's time source; see clockCaptureTimes for driver
+// timestamps. If the capture time is unknown, the clock's time is used instead.
+//
+// Frame intervals may vary, e.g. for screen captures that only deliver frames on damage, so samples are counted
+// from the start instead of rounding each interval, which would drift from the clock.
+func newCaptureVideoSampler(clock *MediaClock, clockRate uint32, captureTime func() time.Time) samplerFunc {
 	clockRateFloat := float64(clockRate)
-	lastTimestamp := time.Now()
+	start := clock.Now()
+	lastTimestamp := start
+	var sent uint64
 
 	return samplerFunc(func() uint32 {
-		now := time.Now()
-		duration := now.Sub(lastTimestamp).Seconds()
-		samples := uint32(math.Round(clockRateFloat * duration))
+		now := captureTime()
+		if now.IsZero() {
+			now = clock.Now()
+		}
+		if now.Before(lastTimestamp) {
+			// Captured before the sampler was created, e.g. a prepared encoder's first frame
+			return 0
+		}
 		lastTimestamp = now
-		return samples
+		total := uint64(math.Round(clockRateFloat * now.Sub(start).Seconds()))
+		samples := total - sent
+		sent = total
+		return uint32(samples)
 	})
 }
 
@@ -0,0 +1,133 @@
+package mediadevices
+
+import (
+	"image"
+	"sync/atomic"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// DropStats counts frames dropped at each stage of a track's pipeline.
+type DropStats struct {
+	// Driver counts frames the driver or device dropped before they were read, e.g. because device buffers
+	// were full. It's only known for drivers that detect it, e.g. V4L2 cameras.
+	Driver uint64
+	// Buffer counts frames dropped by the track's transforms, e.g. video.Throttle, and by a full capture queue, see
+	// VideoTrack.SetCaptureQueue.
+	Buffer uint64
+	// Encoder counts frames that weren't encoded because the encoder was still busy with the previous frame,
+	// or that degradation preference dropped. All of the track's encoders are summed.
+	Encoder uint64
+	// Packetize counts encoded frames that didn't produce any RTP packets, e.g. empty frames from encoders
+	// that skipped them to keep bit rate.
+	Packetize uint64
+}
+
+// TrackStats is a track's statistics.
+type TrackStats struct {
+	// Frames is how many frames were read from the source. For audio tracks, it counts chunks.
+	Frames  uint64
+	Dropped DropStats
+	// Queue is the state of a video track's capture queue, or zero while it's disabled.
+	Queue video.QueueStats
+}
+
+// trackCounters counts a track's frames. Reader goroutines update the counters.
+type trackCounters struct {
+	frames    uint64
+	driver    uint64
+	buffer    uint64
+	encoder   uint64
+	packetize uint64
+
+	// nextBuffered is the sequence number of the next frame after transforms if none is dropped.
+	nextBuffered uint64
+}
+
+func (c *trackCounters) stats() TrackStats {
+	return TrackStats{
+		Frames: atomic.LoadUint64(&c.frames),
+		Dropped: DropStats{
+			Driver:    atomic.LoadUint64(&c.driver),
+			Buffer:    atomic.LoadUint64(&c.buffer),
+			Encoder:   atomic.LoadUint64(&c.encoder),
+			Packetize: atomic.LoadUint64(&c.packetize),
+		},
+	}
+}
+
+// countSource counts frames read from r, and driver-dropped frames reported in
+// metadata.
+func (c *trackCounters) countSource(r video.Reader) video.Reader {
+	return video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := r.Read()
+		if err != nil {
+			return nil, func() {}, err
+		}
+
+		atomic.AddUint64(&c.frames, 1)
+		if m, ok := video.MetadataOf(r); ok {
+			atomic.StoreUint64(&c.driver, m.Dropped)
+		}
+		return img, release, nil
+	}), r)
+}
+
+// bufferCounter counts frames the track's transforms dropped from gaps in sequence
+// numbers. Reader is the transformed source, which is replaced when the track is transformed again.
+type bufferCounter struct {
+	video.Reader
+	counters *trackCounters
+}
+
+func (b *bufferCounter) Read() (image.Image, func(), error) {
+	img, release, err := b.Reader.Read()
+	if err != nil {
+		return nil, func() {}, err
+	}
+
+	if m, ok := video.MetadataOf(b.Reader); ok {
+		next := atomic.SwapUint64(&b.counters.nextBuffered, m.Sequence+1)
+		if m.Sequence > next {
+			atomic.AddUint64(&b.counters.buffer, m.Sequence-next)
+		}
+	}
+	return img, release, nil
+}
+
+func (b *bufferCounter) Metadata() video.Metadata {
+	m, _ := video.MetadataOf(b.Reader)
+	return m
+}
+
+// encoderCounter counts frames an encoder didn't encode. Frames the broadcaster's reader
+// missed are counted from sequence number gaps, minus frames the transforms dropped in
+// the meantime, and frames read but not delivered to the encoder were dropped by degradation.
+type encoderCounter struct {
+	counters   *trackCounters
+	started    bool
+	last       uint64
+	lastBuffer uint64
+	pending    uint64
+}
+
+// read records a frame read from the broadcaster.
+func (e *encoderCounter) read(sequence uint64) {
+	buffer := atomic.LoadUint64(&e.counters.buffer)
+	if e.started && sequence > e.last+1 {
+		gap := sequence - e.last - 1
+		if dropped := buffer - e.lastBuffer; dropped < gap {
+			atomic.AddUint64(&e.counters.encoder, gap-dropped)
+		}
+	}
+	e.started, e.last, e.lastBuffer = true, sequence, buffer
+	e.pending++
+}
+
+// delivered records that the last frame read from the broadcaster was delivered to the encoder.
+func (e *encoderCounter) delivered() {
+	if e.pending > 1 {
+		atomic.AddUint64(&e.counters.encoder, e.pending-1)
+	}
+	e.pending = 0
+}
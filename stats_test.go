@@ -0,0 +1,125 @@
+package mediadevices
+
+import (
+	"image"
+	"testing"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+// droppingVideoSource is a source that reports driver-dropped frames.
+type droppingVideoSource struct {
+	testVideoSource
+	dropped uint64
+}
+
+func (s *droppingVideoSource) Read() (image.Image, func(), error) {
+	s.dropped += 2
+	return s.testVideoSource.Read()
+}
+
+func (s *droppingVideoSource) Metadata() video.Metadata {
+	return video.Metadata{Dropped: s.dropped}
+}
+
+func TestTrackStats(t *testing.T) {
+	source := &droppingVideoSource{
+		testVideoSource: testVideoSource{img: image.NewRGBA(image.Rect(0, 0, 4, 4))},
+	}
+	track := NewVideoTrack(source, nil).(*VideoTrack)
+	defer track.Close()
+
+	// Drops every other frame
+	track.Transform(func(r video.Reader) video.Reader {
+		return video.ReaderFunc(func() (image.Image, func(), error) {
+			if _, _, err := r.Read(); err != nil {
+				return nil, func() {}, err
+			}
+			return r.Read()
+		})
+	})
+	reader := track.NewReader(false)
+	for i := 0; i < 3; i++ {
+		if _, _, err := reader.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	track.packetized(nil)
+
+	expected := TrackStats{
+		Frames: 6,
+		Dropped: DropStats{
+			Driver:    12,
+			Buffer:    3,
+			Packetize: 1,
+		},
+	}
+	if stats := track.Stats(); stats != expected {
+		t.Fatalf("expected %+v, but got %+v", expected, stats)
+	}
+}
+
+func TestEncoderCounter(t *testing.T) {
+	counters := &trackCounters{}
+	e := &encoderCounter{counters: counters}
+
+	e.read(0)
+	e.delivered()
+	// 1 is dropped by transforms, and 2 is missed by the encoder.
+	counters.buffer++
+	e.read(3)
+	e.delivered()
+	// 4 is dropped by degradation.
+	e.read(4)
+	e.read(5)
+	e.delivered()
+
+	if n := counters.stats().Dropped.Encoder; n != 2 {
+		t.Fatalf("expected 2 frames dropped by the encoder, but got %d", n)
+	}
+}
+
+// pacedVideoSource returns a frame each time a value is sent to frames.
+type pacedVideoSource struct {
+	testVideoSource
+	frames chan struct{}
+}
+
+func (s *pacedVideoSource) Read() (image.Image, func(), error) {
+	<-s.frames
+	return s.testVideoSource.Read()
+}
+
+func TestTrackStatsQueue(t *testing.T) {
+	source := &pacedVideoSource{
+		testVideoSource: testVideoSource{img: image.NewRGBA(image.Rect(0, 0, 4, 4))},
+		frames:          make(chan struct{}, 10),
+	}
+	track := NewVideoTrack(source, nil).(*VideoTrack)
+	defer track.Close()
+
+	track.SetCaptureQueue(3)
+	reader := track.NewReader(false)
+	for i := 0; i < 3; i++ {
+		source.frames <- struct{}{}
+		if _, _, err := reader.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := track.Stats(); stats.Queue.Capacity != 4 || stats.Queue.Dropped != 0 {
+		t.Fatalf("expected the queue of 4 frames without the drops, but got %+v", stats.Queue)
+	}
+
+	// The frame the queue already read comes before the source is read directly
+	track.SetCaptureQueue(0)
+	for i := 0; i < 2; i++ {
+		source.frames <- struct{}{}
+		if _, _, err := reader.Read(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected := TrackStats{Frames: 5}
+	if stats := track.Stats(); stats != expected {
+		t.Fatalf("expected %+v, but got %+v", expected, stats)
+	}
+}
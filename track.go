@@ -7,12 +7,16 @@ import (
 	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/pion/mediadevices/pkg/codec"
 	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/fec"
 	"github.com/pion/mediadevices/pkg/io/audio"
 	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
 	"github.com/pion/mediadevices/pkg/wave"
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3"
@@ -25,6 +29,7 @@ const (
 var (
 	errInvalidDriverType      = errors.New("invalid driver type")
 	errNotFoundPeerConnection = errors.New("failed to find given peer connection")
+	errInvalidResolution      = errors.New("invalid resolution")
 )
 
 // Source is a generic representation of a media source
@@ -69,6 +74,9 @@ type Track interface {
 	NewEncodedReader(codecName string) (EncodedReadCloser, error)
 	// NewEncodedReader creates a new Go standard io.ReadCloser that reads the encoded data in codecName format
 	NewEncodedIOReader(codecName string) (io.ReadCloser, error)
+	// Stats returns how many frames were read from the source and dropped at each pipeline stage,
+	// to tell where frames go missing.
+	Stats() TrackStats
 }
 
 type baseTrack struct {
@@ -80,6 +88,9 @@ type baseTrack struct {
 	kind                  MediaDeviceType
 	selector              *CodecSelector
 	activePeerConnections map[string]chan<- chan<- struct{}
+	counters              *trackCounters
+	// bitRate is the encoder bit rate set by BandwidthAllocator, or 0 if it's not allocated.
+	bitRate int32
 }
 
 func newBaseTrack(source Source, kind MediaDeviceType, selector *CodecSelector) *baseTrack {
@@ -88,9 +99,41 @@ func newBaseTrack(source Source, kind MediaDeviceType, selector *CodecSelector)
 		kind:                  kind,
 		selector:              selector,
 		activePeerConnections: make(map[string]chan<- chan<- struct{}),
+		counters:              &trackCounters{},
 	}
 }
 
+// Stats returns how many frames were read from the source and dropped at each pipeline
+// stage.
+func (track *baseTrack) Stats() TrackStats {
+	return track.counters.stats()
+}
+
+// packetized counts an encoded frame that didn't produce any packets as dropped.
+func (track *baseTrack) packetized(pkts []*rtp.Packet) {
+	if len(pkts) == 0 {
+		atomic.AddUint64(&track.counters.packetize, 1)
+	}
+}
+
+// setBitRate sets the track's encoder bit rate, which is applied before the next frame is encoded.
+func (track *baseTrack) setBitRate(bps int) {
+	atomic.StoreInt32(&track.bitRate, int32(bps))
+}
+
+// applyBitRate sets encoder's bit rate if it changed from applied, and returns encoder's bit rate.
+// It's called in the goroutine that reads encoder, since encoders aren't required to be thread safe.
+func (track *baseTrack) applyBitRate(encoder codec.ReadCloser, applied int) int {
+	bps := int(atomic.LoadInt32(&track.bitRate))
+	if bps == 0 || bps == applied {
+		return applied
+	}
+	if err := encoder.SetBitRate(bps); err != nil {
+		logger.Debugf("failed to set the bit rate of the encoder: %s", err)
+	}
+	return bps
+}
+
 // Kind returns track's kind
 func (track *baseTrack) Kind() webrtc.RTPCodecType {
 	switch track.kind {
@@ -143,6 +186,30 @@ func (track *baseTrack) onError(err error) {
 	}
 }
 
+// negotiatedTrack is a track whose RTP readers only send redundancy codecs the remote negotiated.
+type negotiatedTrack interface {
+	newRTPReader(codecName string, ssrc uint32, mtu int, negotiated []webrtc.RTPCodecParameters) (RTPReadCloser, error)
+}
+
+// newNegotiatedRTPReader creates track's RTP reader for the codecs the remote negotiated.
+func newNegotiatedRTPReader(track Track, codecName string, ssrc uint32, negotiated []webrtc.RTPCodecParameters) (RTPReadCloser, error) {
+	if t, ok := track.(negotiatedTrack); ok {
+		return t.newRTPReader(codecName, ssrc, rtpOutboundMTU, negotiated)
+	}
+	return track.NewRTPReader(codecName, ssrc, rtpOutboundMTU)
+}
+
+// newFECEncoder creates c's FEC encoder. If negotiated isn't nil, FEC is limited to its redundancy codecs,
+// and packets fall back to plain packetization without RED.
+func newFECEncoder(c *codec.RTPCodec, mtu int, negotiated []webrtc.RTPCodecParameters) (fec.Encoder, int) {
+	if negotiated != nil && c.FEC.Mode != codec.FECModeNone {
+		limited := *c
+		limited.FEC = c.NegotiatedFEC(negotiated)
+		c = &limited
+	}
+	return fec.NewEncoder(c, mtu)
+}
+
 func (track *baseTrack) bind(ctx webrtc.TrackLocalContext, specializedTrack Track) (webrtc.RTPCodecParameters, error) {
 	track.mu.Lock()
 	defer track.mu.Unlock()
@@ -156,7 +223,7 @@ func (track *baseTrack) bind(ctx webrtc.TrackLocalContext, specializedTrack Trac
 	var errReasons []string
 	for _, wantedCodec := range ctx.CodecParameters() {
 		logger.Debugf("trying to build %s rtp reader", wantedCodec.MimeType)
-		encodedReader, err = specializedTrack.NewRTPReader(wantedCodec.MimeType, uint32(ctx.SSRC()), rtpOutboundMTU)
+		encodedReader, err = newNegotiatedRTPReader(specializedTrack, wantedCodec.MimeType, uint32(ctx.SSRC()), ctx.CodecParameters())
 		if err == nil {
 			selectedCodec = wantedCodec
 			break
@@ -169,9 +236,11 @@ func (track *baseTrack) bind(ctx webrtc.TrackLocalContext, specializedTrack Trac
 		return webrtc.RTPCodecParameters{}, errors.New(strings.Join(errReasons, "\n\n"))
 	}
 
+	stopped := resources.start("rtp writer of " + specializedTrack.ID())
 	go func() {
 		var doneCh chan<- struct{}
 		writer := ctx.WriteStream()
+		defer stopped()
 		defer func() {
 			encodedReader.Close()
 
@@ -234,24 +303,87 @@ func (track *baseTrack) removeActivePeerConnection(id string) (chan<- chan<- str
 }
 
 func newTrackFromDriver(d driver.Driver, constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
+	if err := d.Configure(constraints.DriverOptions); err != nil {
+		return nil, err
+	}
 	if err := d.Open(); err != nil {
+		if info, ok := deviceInfo(d); ok {
+			if denied := deniedError(info.Kind, d); denied != nil {
+				return nil, denied
+			}
+		}
+		return nil, err
+	}
+	if err := d.SetControls(constraints.Controls); err != nil {
+		d.Close()
 		return nil, err
 	}
 
+	var track Track
+	var err error
 	switch recorder := d.(type) {
 	case driver.VideoRecorder:
-		return newVideoTrackFromDriver(d, recorder, constraints, selector)
+		track, err = newVideoTrackFromDriver(d, recorder, constraints, selector)
 	case driver.AudioRecorder:
-		return newAudioTrackFromDriver(d, recorder, constraints, selector)
+		track, err = newAudioTrackFromDriver(d, recorder, constraints, selector)
 	default:
 		panic(errInvalidDriverType)
 	}
+	if err != nil {
+		// No track owns the driver yet, so it would be left open.
+		closeDriver(d)
+		return nil, err
+	}
+	return track, nil
+}
+
+// closeDriver closes d unless it's closed, e.g. by a VideoRecord failure.
+func closeDriver(d driver.Driver) {
+	if d.Status() != driver.StateClosed {
+		d.Close()
+	}
+}
+
+// recordVideo starts recording with properties p. The properties' colorspace is set in frame metadata,
+// so frames are converted from it.
+func recordVideo(recorder driver.VideoRecorder, p prop.Media) (video.Reader, error) {
+	reader, err := recorder.VideoRecord(p)
+	if err != nil {
+		return nil, err
+	}
+	if cs := video.ColorSpaceOf(p.Video); !cs.IsZero() {
+		reader = video.AssumeColorSpace(cs)(reader)
+	}
+	return reader, nil
 }
 
 // VideoTrack is a specific track type that contains video source which allows multiple readers to access, and manipulate.
 type VideoTrack struct {
 	*baseTrack
 	*video.Broadcaster
+	degradationPreference int32
+	latency               *latencyTracer
+	switcher              *sourceSwitch
+	capture               *captureQueue
+	// resets is incremented to rebuild encoders, see Watchdog.
+	resets uint32
+	// reconfigs is incremented to reset encoders for a new resolution, see Reconfigure.
+	reconfigs uint32
+	recovery  recoveryOptions
+
+	// reopen reopens the track's driver, and placeholder blocks reads while it's reopened.
+	reopenMu    sync.Mutex
+	reopen      func() error
+	placeholder *blockedReader
+
+	preparedMu sync.Mutex
+	prepared   []preparedEncoder
+}
+
+// preparedEncoder is an unused encoded reader built by VideoTrack.Prepare.
+type preparedEncoder struct {
+	reader EncodedReadCloser
+	codec  *codec.RTPCodec
 }
 
 // NewVideoTrack constructs a new VideoTrack
@@ -261,37 +393,113 @@ func NewVideoTrack(source VideoSource, selector *CodecSelector) Track {
 
 func newVideoTrackFromReader(source Source, reader video.Reader, selector *CodecSelector) Track {
 	base := newBaseTrack(source, VideoInput, selector)
-	wrappedReader := video.ReaderFunc(func() (img image.Image, release func(), err error) {
-		img, _, err = reader.Read()
+	switcher := newSourceSwitch(source, reader)
+	wrappedReader := video.KeepMetadata(video.ReaderFunc(func() (img image.Image, release func(), err error) {
+		img, release, err = switcher.Read()
 		if err != nil {
 			base.onError(err)
 		}
-		return img, func() {}, err
-	})
+		return img, release, err
+	}), switcher)
+
+	// Capture times come from the shared clock if any, so samplers can use them as RTP timestamps.
+	now := time.Now
+	if selector != nil && selector.clock != nil {
+		now = selector.clock.Now
+		wrappedReader = clockCaptureTimes(wrappedReader, selector.clock, time.Now)
+	}
 
+	// Frames dropped by the transforms are counted after all of them, see Transform. The transforms read from the
+	// capture queue, which sits in front of them.
+	capture := newCaptureQueue(base.counters.countSource(video.Stamp(wrappedReader, now)), "capture queue of "+source.ID())
+	counted := &bufferCounter{
+		Reader:   capture,
+		counters: base.counters,
+	}
 	// TODO: Allow users to configure broadcaster
-	broadcaster := video.NewBroadcaster(wrappedReader, nil)
+	broadcaster := video.NewBroadcaster(counted, nil)
 
-	return &VideoTrack{
+	track := &VideoTrack{
 		baseTrack:   base,
 		Broadcaster: broadcaster,
+		latency:     newLatencyTracer(now),
+		switcher:    switcher,
+		capture:     capture,
 	}
+	resources.addTrack(track)
+	return track
 }
 
 // newVideoTrackFromDriver is an internal video track creation from driver
 func newVideoTrackFromDriver(d driver.Driver, recorder driver.VideoRecorder, constraints MediaTrackConstraints, selector *CodecSelector) (Track, error) {
-	reader, err := recorder.VideoRecord(constraints.selectedMedia)
+	reader, err := recordVideo(recorder, constraints.selectedMedia)
 	if err != nil {
 		return nil, err
 	}
 
-	return newVideoTrackFromReader(d, reader, selector), nil
+	track := newVideoTrackFromReader(d, reader, selector).(*VideoTrack)
+	track.setDriver(d, recorder, constraints)
+	if selector != nil {
+		for _, codecName := range selector.preparedVideoCodecs {
+			if err := track.Prepare(codecName); err != nil {
+				logger.Debugf("failed to prepare %s encoder: %s", codecName, err)
+			}
+		}
+	}
+	return track, nil
 }
 
 // Transform transforms the underlying source by applying the given fns in serial order
 func (track *VideoTrack) Transform(fns ...video.TransformFunc) {
 	src := track.Broadcaster.Source()
-	track.Broadcaster.ReplaceSource(video.Merge(fns...)(src))
+	counter, ok := src.(*bufferCounter)
+	if !ok {
+		track.Broadcaster.ReplaceSource(video.Merge(fns...)(src))
+		return
+	}
+	track.Broadcaster.ReplaceSource(&bufferCounter{
+		Reader:   video.Merge(fns...)(counter.Reader),
+		counters: counter.counters,
+	})
+}
+
+// OnFrameAnalysis detects the track's black and frozen frames, and calls handler when the frame condition
+// changes. It's useful to detect failed capture on unattended cameras. See video.Analyze for the options.
+func (track *VideoTrack) OnFrameAnalysis(handler func(video.FrameAnalysis), opts ...video.AnalyzeOption) {
+	track.Transform(video.Analyze(handler, opts...))
+}
+
+// ApplyControls changes low level camera properties, e.g. turns on the torch. Nil controls
+// are kept as they are. It returns driver.ErrControlUnsupported unless the track is from a driver that
+// supports all of the controls.
+func (track *VideoTrack) ApplyControls(c driver.Controls) error {
+	source, _, _ := track.switcher.current()
+	d, ok := source.(driver.Driver)
+	if !ok {
+		if c.Empty() {
+			return nil
+		}
+		return driver.ErrControlUnsupported
+	}
+	return d.SetControls(c)
+}
+
+// SetDegradationPreference sets how the track is degraded when an encoder can't keep up with the source.
+// Each encoder's usage is measured separately, so the track's readers may be degraded differently.
+// Adaptation is disabled by default.
+func (track *VideoTrack) SetDegradationPreference(preference DegradationPreference) {
+	atomic.StoreInt32(&track.degradationPreference, int32(preference))
+}
+
+// DegradationPreference returns the track's current degradation preference.
+func (track *VideoTrack) DegradationPreference() DegradationPreference {
+	return DegradationPreference(atomic.LoadInt32(&track.degradationPreference))
+}
+
+// LatencyStats returns the latency distribution of recent frames, from capture to RTP packets. Frames
+// from all of the track's readers are included.
+func (track *VideoTrack) LatencyStats() LatencyStats {
+	return track.latency.stats()
 }
 
 func (track *VideoTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
@@ -302,30 +510,215 @@ func (track *VideoTrack) Unbind(ctx webrtc.TrackLocalContext) error {
 	return track.unbind(ctx)
 }
 
+// Prepare builds codecName's encoder ahead of time, so encoder initialization doesn't delay the first frame.
+// If the encoder implements codec.Preparer, its codec configuration is sent with the first frame.
+// The codec's next NewRTPReader, NewEncodedReader or NewEncodedIOReader uses the prepared encoder.
+func (track *VideoTrack) Prepare(codecName string) error {
+	reader, selectedCodec, err := track.buildEncodedReader(true, codecName)
+	if err != nil {
+		return err
+	}
+
+	track.preparedMu.Lock()
+	track.prepared = append(track.prepared, preparedEncoder{reader: reader, codec: selectedCodec})
+	track.preparedMu.Unlock()
+	return nil
+}
+
+// takePrepared returns the prepared encoder matching one of codecNames the same way CodecSelector does,
+// and removes it from the prepared encoders.
+func (track *VideoTrack) takePrepared(codecNames ...string) (EncodedReadCloser, *codec.RTPCodec) {
+	track.preparedMu.Lock()
+	defer track.preparedMu.Unlock()
+
+	for _, codecName := range codecNames {
+		codecNameLower := strings.ToLower(codecName)
+		for i, p := range track.prepared {
+			if strings.HasSuffix(strings.ToLower(p.codec.MimeType), codecNameLower) {
+				track.prepared = append(track.prepared[:i], track.prepared[i+1:]...)
+				return p.reader, p.codec
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Close closes the unused prepared encoders and the track's source.
+func (track *VideoTrack) Close() error {
+	track.preparedMu.Lock()
+	for _, p := range track.prepared {
+		p.reader.Close()
+	}
+	track.prepared = nil
+	track.preparedMu.Unlock()
+
+	track.capture.close()
+	err := track.switcher.close()
+	track.releasePlaceholder()
+	resources.removeTrack(track)
+	return err
+}
+
 func (track *VideoTrack) newEncodedReader(codecNames ...string) (EncodedReadCloser, *codec.RTPCodec, error) {
-	reader := track.NewReader(false)
+	if reader, selectedCodec := track.takePrepared(codecNames...); reader != nil {
+		return reader, selectedCodec, nil
+	}
+	return track.buildEncodedReader(false, codecNames...)
+}
+
+func (track *VideoTrack) buildEncodedReader(prepare bool, codecNames ...string) (EncodedReadCloser, *codec.RTPCodec, error) {
 	inputProp, err := detectCurrentVideoProp(track.Broadcaster)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	encodedReader, selectedCodec, err := track.selector.selectVideoCodecByNames(reader, inputProp, codecNames...)
+	degradation := newDegradationController(track.DegradationPreference, inputProp, track.selector.overuseOpts...)
+	source := track.NewReader(false)
+	drops := &encoderCounter{counters: track.counters}
+	reader := degradation.wrap(video.ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := source.Read()
+		if err == nil {
+			metadata, _ := video.MetadataOf(source)
+			drops.read(metadata.Sequence)
+		}
+		return img, release, err
+	}))
+	// Capture time, and when the last frame was delivered to the encoder
+	var captured, input time.Time
+	traced := video.ReaderFunc(func() (image.Image, func(), error) {
+		img, release, err := reader.Read()
+		if err == nil {
+			drops.delivered()
+		}
+		metadata, _ := video.MetadataOf(source)
+		captured, input = metadata.CaptureTime, track.latency.now()
+		return img, release, err
+	})
+	// The encoder reads from encoderInput, which is replaced when the encoder is reset for a new resolution.
+	// Frames of other sizes, e.g. those read before the reset, are scaled to the encoder size. Source metadata is
+	// kept so encoders know the capture times, see codec.FrameClock.
+	var encoderInput video.Reader
+	encoderReader := video.KeepMetadata(video.ReaderFunc(func() (image.Image, func(), error) {
+		return encoderInput.Read()
+	}), source)
+	encoderProp := func() prop.Media {
+		p := inputProp
+		p.Width, p.Height = degradation.resolution()
+		encoderInput = fitSize(p.Width, p.Height, traced)
+		return p
+	}
+	buildEncoder := func(codecNames ...string) (codec.ReadCloser, *codec.RTPCodec, error) {
+		return track.selector.selectVideoCodecByNames(encoderReader, encoderProp(), codecNames...)
+	}
+
+	encodedReader, selectedCodec, err := buildEncoder(codecNames...)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	sample := newVideoSampler(selectedCodec.ClockRate)
+	// config is sent before the first frame
+	var config []byte
+	if p, ok := encodedReader.(codec.Preparer); ok && prepare {
+		if config, err = p.Prepare(); err != nil {
+			encodedReader.Close()
+			return nil, nil, err
+		}
+	}
+
+	// Encoders read frames synchronously, so the last frame read from source is the encoded one.
+	clock := track.selector.clock
+	if clock == nil {
+		clock = NewMediaClock()
+	}
+	sample := newVideoSampler(track.selector.timestamps, clock, selectedCodec.ClockRate, inputProp.FrameRate, func() time.Time {
+		metadata, _ := video.MetadataOf(source)
+		return metadata.CaptureTime
+	})
 
+	// rebuild closes the encoder and builds a new one; reset resets it in place if it's a codec.Resetter.
+	var rebuild, reset bool
+	// rebuildErr is set when a rebuild fails. encodedReader is nil then, since the old encoder is closed.
+	var rebuildErr error
+	var bitRate int
+	var recovery recoveryOptions
+	var presentation presentationClock
+	switches := track.switcher.switches()
+	resets := atomic.LoadUint32(&track.resets)
+	reconfigs := atomic.LoadUint32(&track.reconfigs)
 	return &encodedReadCloserImpl{
 		readFn: func() (EncodedBuffer, func(), error) {
+			if rebuildErr != nil {
+				return EncodedBuffer{}, func() {}, rebuildErr
+			}
+			if n := track.switcher.switches(); n != switches {
+				// Decoders can't continue the stream from the replaced source.
+				switches = n
+				if err := encodedReader.ForceKeyFrame(); err != nil {
+					logger.Debugf("failed to force a keyframe after the source was replaced: %s", err)
+				}
+			}
+			if n := atomic.LoadUint32(&track.resets); n != resets {
+				resets = n
+				rebuild = true
+			}
+			if n := atomic.LoadUint32(&track.reconfigs); n != reconfigs {
+				reconfigs = n
+				size := track.switcher.currentSize()
+				inputProp.Width, inputProp.Height = size.X, size.Y
+				degradation.resize(size.X, size.Y)
+				reset = true
+			}
+			if reset && !rebuild {
+				if resetter, ok := encodedReader.(codec.Resetter); ok {
+					if err := resetter.Reset(encoderProp()); err != nil {
+						logger.Debugf("failed to reset the encoder, so it's rebuilt: %s", err)
+						rebuild = true
+					}
+				} else {
+					rebuild = true
+				}
+			}
+			reset = false
+			if rebuild {
+				// Rebuild before reading the next frame, since the last buffer may point into the encoder's memory.
+				rebuild = false
+				encodedReader.Close()
+
+				rebuilt, _, err := buildEncoder(selectedCodec.MimeType)
+				if err != nil {
+					// Don't read or close the closed encoder again
+					encodedReader, rebuildErr = nil, err
+					return EncodedBuffer{}, func() {}, err
+				}
+				encodedReader = rebuilt
+				bitRate = 0
+				recovery = recoveryOptions{}
+			}
+			bitRate = track.applyBitRate(encodedReader, bitRate)
+			recovery = track.recovery.apply(encodedReader, recovery)
+
 			data, release, err := encodedReader.Read()
+			if err == nil && config != nil {
+				data = append(config, data...)
+				config = nil
+			}
 			buffer := EncodedBuffer{
 				Data:    data,
 				Samples: sample(),
 			}
+			buffer.CaptureTime, buffer.PTS, buffer.DTS = presentation.times(encodedReader, captured)
+			if err == nil {
+				track.latency.encoded(buffer.CaptureTime, input, track.latency.now())
+				reset = degradation.onEncoded()
+			}
 			return buffer, release, err
 		},
-		closeFn: encodedReader.Close,
+		closeFn: func() error {
+			if encodedReader == nil {
+				return nil
+			}
+			return encodedReader.Close()
+		},
 	}, selectedCodec, nil
 }
 
@@ -343,11 +736,20 @@ func (track *VideoTrack) NewEncodedIOReader(codecName string) (io.ReadCloser, er
 }
 
 func (track *VideoTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (RTPReadCloser, error) {
+	return track.newRTPReader(codecName, ssrc, mtu, nil)
+}
+
+func (track *VideoTrack) newRTPReader(codecName string, ssrc uint32, mtu int, negotiated []webrtc.RTPCodecParameters) (RTPReadCloser, error) {
 	encodedReader, selectedCodec, err := track.newEncodedReader(codecName)
 	if err != nil {
 		return nil, err
 	}
 
+	if selectedCodec.MTU > 0 {
+		mtu = selectedCodec.MTU
+	}
+
+	fecEncoder, mtu := newFECEncoder(selectedCodec, mtu, negotiated)
 	packetizer := rtp.NewPacketizer(mtu, uint8(selectedCodec.PayloadType), ssrc, selectedCodec.Payloader, rtp.NewRandomSequencer(), selectedCodec.ClockRate)
 
 	return &rtpReadCloserImpl{
@@ -360,8 +762,19 @@ func (track *VideoTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (R
 			}
 			defer release()
 
-			pkts := packetizer.Packetize(encoded.Data, encoded.Samples)
-			return pkts, release, err
+			encodedAt := track.latency.now()
+			// A video sample's duration is the interval since the previous frame, so advance the timestamp before
+			// packetizing. Otherwise each frame would carry the previous frame's capture time, which matters when
+			// intervals vary, e.g. for screen captures that only deliver frames on damage.
+			packetizer.SkipSamples(encoded.Samples)
+			pkts := packetizer.Packetize(encoded.Data, 0)
+			if fecEncoder != nil {
+				pkts = fecEncoder.Encode(pkts)
+			}
+			track.packetized(pkts)
+			track.latency.packetized(encoded.CaptureTime, encodedAt, track.latency.now())
+			// The packetizer copies the payloads, so the encoded frame is already released
+			return pkts, func() {}, err
 		},
 		closeFn: encodedReader.Close,
 	}, nil
@@ -382,20 +795,30 @@ func NewAudioTrack(source AudioSource, selector *CodecSelector) Track {
 func newAudioTrackFromReader(source Source, reader audio.Reader, selector *CodecSelector) Track {
 	base := newBaseTrack(source, AudioInput, selector)
 	wrappedReader := audio.ReaderFunc(func() (chunk wave.Audio, release func(), err error) {
-		chunk, _, err = reader.Read()
+		chunk, release, err = reader.Read()
 		if err != nil {
 			base.onError(err)
+		} else {
+			atomic.AddUint64(&base.counters.frames, 1)
 		}
-		return chunk, func() {}, err
+		return chunk, release, err
 	})
 
 	// TODO: Allow users to configure broadcaster
 	broadcaster := audio.NewBroadcaster(wrappedReader, nil)
 
-	return &AudioTrack{
+	track := &AudioTrack{
 		baseTrack:   base,
 		Broadcaster: broadcaster,
 	}
+	resources.addTrack(track)
+	return track
+}
+
+// Close closes the track's source.
+func (track *AudioTrack) Close() error {
+	resources.removeTrack(track)
+	return track.baseTrack.Source.Close()
 }
 
 // newAudioTrackFromDriver is an internal audio track creation from driver
@@ -405,15 +828,45 @@ func newAudioTrackFromDriver(d driver.Driver, recorder driver.AudioRecorder, con
 		return nil, err
 	}
 
+	reader = audio.Merge(audioProcessing(constraints)...)(reader)
 	return newAudioTrackFromReader(d, reader, selector), nil
 }
 
+// audioProcessing returns transforms enabled by constraints, in order: echo cancellation,
+// noise suppression, auto gain control. Processing the driver does itself is skipped.
+// Echo of audio played by NewPlayer is cancelled.
+func audioProcessing(constraints MediaTrackConstraints) []audio.TransformFunc {
+	enabled := func(c prop.BoolConstraint, native bool) bool {
+		return c != nil && c.Value() && !native
+	}
+
+	p := constraints.selectedMedia
+	var fns []audio.TransformFunc
+	if enabled(constraints.EchoCancellation, p.EchoCancellation) {
+		fns = append(fns, audio.EchoCancellation(echoReference))
+	}
+	if enabled(constraints.NoiseSuppression, p.NoiseSuppression) {
+		fns = append(fns, audio.NoiseSuppression())
+	}
+	if enabled(constraints.AutoGainControl, p.AutoGainControl) {
+		fns = append(fns, audio.AutoGainControl())
+	}
+	return fns
+}
+
 // Transform transforms the underlying source by applying the given fns in serial order
 func (track *AudioTrack) Transform(fns ...audio.TransformFunc) {
 	src := track.Broadcaster.Source()
 	track.Broadcaster.ReplaceSource(audio.Merge(fns...)(src))
 }
 
+// OnAudioAnalysis detects the track's sustained clipping, DC offset and dead microphone, and calls handler when
+// the input's condition changes. It's useful to prompt users to fix their input device.
+// See audio.Analyze for the options.
+func (track *AudioTrack) OnAudioAnalysis(handler func(audio.Analysis), opts ...audio.AnalyzeOption) {
+	track.Transform(audio.Analyze(handler, opts...))
+}
+
 func (track *AudioTrack) Bind(ctx webrtc.TrackLocalContext) (webrtc.RTPCodecParameters, error) {
 	return track.bind(ctx, track)
 }
@@ -423,21 +876,29 @@ func (track *AudioTrack) Unbind(ctx webrtc.TrackLocalContext) error {
 }
 
 func (track *AudioTrack) newEncodedReader(codecNames ...string) (EncodedReadCloser, *codec.RTPCodec, error) {
-	reader := track.NewReader(false)
+	var reader audio.Reader = track.NewReader(false)
 	inputProp, err := detectCurrentAudioProp(track.Broadcaster)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	stop := func() {}
+	if clock, source := track.selector.clock, track.selector.timestamps; clock != nil && source != TimestampNominal && source != TimestampClock {
+		reader, stop = newClockAudioReader(reader, clock, inputProp.SampleRate)
+	}
+
 	encodedReader, selectedCodec, err := track.selector.selectAudioCodecByNames(reader, inputProp, codecNames...)
 	if err != nil {
+		stop()
 		return nil, nil, err
 	}
 
-	sample := newAudioSampler(selectedCodec.ClockRate, selectedCodec.Latency)
+	sample := newSourceAudioSampler(track.selector.timestamps, track.selector.clock, selectedCodec.ClockRate, selectedCodec.Latency)
 
+	var bitRate int
 	return &encodedReadCloserImpl{
 		readFn: func() (EncodedBuffer, func(), error) {
+			bitRate = track.applyBitRate(encodedReader, bitRate)
 			data, release, err := encodedReader.Read()
 			buffer := EncodedBuffer{
 				Data:    data,
@@ -445,7 +906,10 @@ func (track *AudioTrack) newEncodedReader(codecNames ...string) (EncodedReadClos
 			}
 			return buffer, release, err
 		},
-		closeFn: encodedReader.Close,
+		closeFn: func() error {
+			stop()
+			return encodedReader.Close()
+		},
 	}, selectedCodec, nil
 }
 
@@ -463,11 +927,20 @@ func (track *AudioTrack) NewEncodedIOReader(codecName string) (io.ReadCloser, er
 }
 
 func (track *AudioTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (RTPReadCloser, error) {
+	return track.newRTPReader(codecName, ssrc, mtu, nil)
+}
+
+func (track *AudioTrack) newRTPReader(codecName string, ssrc uint32, mtu int, negotiated []webrtc.RTPCodecParameters) (RTPReadCloser, error) {
 	encodedReader, selectedCodec, err := track.newEncodedReader(codecName)
 	if err != nil {
 		return nil, err
 	}
 
+	if selectedCodec.MTU > 0 {
+		mtu = selectedCodec.MTU
+	}
+
+	fecEncoder, mtu := newFECEncoder(selectedCodec, mtu, negotiated)
 	packetizer := rtp.NewPacketizer(mtu, uint8(selectedCodec.PayloadType), ssrc, selectedCodec.Payloader, rtp.NewRandomSequencer(), selectedCodec.ClockRate)
 
 	return &rtpReadCloserImpl{
@@ -481,7 +954,11 @@ func (track *AudioTrack) NewRTPReader(codecName string, ssrc uint32, mtu int) (R
 			defer release()
 
 			pkts := packetizer.Packetize(encoded.Data, encoded.Samples)
-			return pkts, release, err
+			if fecEncoder != nil {
+				pkts = fecEncoder.Encode(pkts)
+			}
+			track.packetized(pkts)
+			return pkts, func() {}, err
 		},
 		closeFn: encodedReader.Close,
 	}, nil
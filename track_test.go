@@ -1,9 +1,18 @@
 package mediadevices
 
 import (
+	"bytes"
 	"errors"
+	"image"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/pion/mediadevices/pkg/codec"
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
 )
 
 func TestOnEnded(t *testing.T) {
@@ -53,3 +62,319 @@ func TestOnEnded(t *testing.T) {
 		}
 	})
 }
+
+type testVideoSource struct {
+	img image.Image
+}
+
+func (s *testVideoSource) Read() (image.Image, func(), error) { return s.img, func() {}, nil }
+func (s *testVideoSource) ID() string                         { return "test" }
+func (s *testVideoSource) Close() error                       { return nil }
+
+type testPreparedEncoder struct {
+	r        video.Reader
+	prepared bool
+}
+
+func (e *testPreparedEncoder) Read() ([]byte, func(), error) {
+	if _, _, err := e.r.Read(); err != nil {
+		return nil, func() {}, err
+	}
+	return []byte{0xff}, func() {}, nil
+}
+
+func (e *testPreparedEncoder) Prepare() ([]byte, error) {
+	e.prepared = true
+	return []byte{0x01, 0x02}, nil
+}
+
+func (e *testPreparedEncoder) Close() error         { return nil }
+func (e *testPreparedEncoder) SetBitRate(int) error { return nil }
+func (e *testPreparedEncoder) ForceKeyFrame() error { return nil }
+
+type testPreparedEncoderBuilder struct {
+	encoders []*testPreparedEncoder
+}
+
+func (b *testPreparedEncoderBuilder) RTPCodec() *codec.RTPCodec { return codec.NewRTPVP8Codec(90000) }
+
+func (b *testPreparedEncoderBuilder) BuildVideoEncoder(r video.Reader, p prop.Media) (codec.ReadCloser, error) {
+	e := &testPreparedEncoder{r: r}
+	b.encoders = append(b.encoders, e)
+	return e, nil
+}
+
+func TestVideoTrackPrepare(t *testing.T) {
+	builder := &testPreparedEncoderBuilder{}
+	source := &testVideoSource{
+		img: image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio420),
+	}
+	track := NewVideoTrack(source, NewCodecSelector(WithVideoEncoders(builder))).(*VideoTrack)
+	defer track.Close()
+
+	if err := track.Prepare("vp8"); err != nil {
+		t.Fatal(err)
+	}
+	if len(builder.encoders) != 1 || !builder.encoders[0].prepared {
+		t.Fatal("expected the encoder to be built and prepared")
+	}
+
+	r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if len(builder.encoders) != 1 {
+		t.Fatalf("expected the prepared encoder to be used, but got %d encoders", len(builder.encoders))
+	}
+
+	for i, expected := range [][]byte{{0x01, 0x02, 0xff}, {0xff}} {
+		buf, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf.Data, expected) {
+			t.Fatalf("expected frame %d to be %v, but got %v", i, expected, buf.Data)
+		}
+	}
+
+	// The prepared encoder is only used once
+	r2, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r2.Close()
+	if len(builder.encoders) != 2 || builder.encoders[1].prepared {
+		t.Fatal("expected a new encoder to be built without preparation")
+	}
+}
+
+func TestVideoTrackOnFrameAnalysis(t *testing.T) {
+	source := &testVideoSource{
+		img: image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio420),
+	}
+	track := NewVideoTrack(source, NewCodecSelector()).(*VideoTrack)
+	defer track.Close()
+
+	analyzed := make(chan video.FrameAnalysis, 1)
+	track.OnFrameAnalysis(func(a video.FrameAnalysis) {
+		analyzed <- a
+	}, video.WithBlackDuration(0))
+
+	if _, _, err := track.NewReader(false).Read(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case a := <-analyzed:
+		if a.Condition != video.FrameConditionBlack {
+			t.Fatalf("expected %s, but got %s", video.FrameConditionBlack, a.Condition)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+type controllerAdapterMock struct {
+	screenAdapterMock
+	controls []driver.Controls
+}
+
+func (a *controllerAdapterMock) SetControls(c driver.Controls) error {
+	a.controls = append(a.controls, c)
+	return nil
+}
+
+func TestVideoTrackApplyControls(t *testing.T) {
+	a := &controllerAdapterMock{}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "controller", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	torch := true
+	d := driver.GetManager().Query(func(d driver.Driver) bool { return d.Info().Label == "controller" })[0]
+	c := MediaTrackConstraints{
+		Controls:      driver.Controls{Torch: &torch},
+		selectedMedia: prop.Media{Video: prop.Video{Width: 64, Height: 32}},
+	}
+	track, err := newTrackFromDriver(d, c, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer track.Close()
+	if len(a.controls) != 1 || a.controls[0].Torch == nil || !*a.controls[0].Torch {
+		t.Fatalf("expected the torch to be turned on by the constraints, but got %v", a.controls)
+	}
+
+	torch = false
+	if err := track.(*VideoTrack).ApplyControls(driver.Controls{Torch: &torch}); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.controls) != 2 || *a.controls[1].Torch {
+		t.Fatalf("expected the torch to be turned off, but got %v", a.controls)
+	}
+
+	// Tracks from sources other than drivers don't have controls.
+	source := NewVideoTrack(&testVideoSource{img: image.NewRGBA(image.Rect(0, 0, 4, 4))}, nil).(*VideoTrack)
+	defer source.Close()
+	if err := source.ApplyControls(driver.Controls{Torch: &torch}); err != driver.ErrControlUnsupported {
+		t.Fatalf("expected %v, but got %v", driver.ErrControlUnsupported, err)
+	}
+}
+
+// testVFRSource is a screen capture that only delivers frames on damage. Each frame is captured after the
+// clock's next interval.
+type testVFRSource struct {
+	testVideoSource
+	mu        sync.Mutex
+	now       time.Time
+	intervals []time.Duration
+	frames    int
+}
+
+func (s *testVFRSource) Read() (image.Image, func(), error) {
+	s.mu.Lock()
+	s.now = s.now.Add(s.intervals[s.frames%len(s.intervals)])
+	s.frames++
+	s.mu.Unlock()
+	return s.img, func() {}, nil
+}
+
+func (s *testVFRSource) time() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+func TestVideoTrackVariableFrameRate(t *testing.T) {
+	source := &testVFRSource{
+		testVideoSource: testVideoSource{img: image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio420)},
+		now:             time.Unix(1000, 0),
+		intervals:       []time.Duration{33 * time.Millisecond, 2 * time.Second, 10 * time.Millisecond},
+	}
+	clock := NewMediaClock(WithTimeSource(source.time))
+	selector := NewCodecSelector(WithVideoEncoders(&testPreparedEncoderBuilder{}), WithMediaClock(clock))
+	track := NewVideoTrack(source, selector).(*VideoTrack)
+	defer track.Close()
+
+	r, err := track.NewRTPReader("vp8", 1, 1200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var last uint32
+	for i := 0; i < 6; i++ {
+		pkts, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 {
+			// Each frame has its own capture time, not the previous frame's
+			source.mu.Lock()
+			interval := source.intervals[(source.frames-1)%len(source.intervals)]
+			source.mu.Unlock()
+			if expected := uint32(interval * 90000 / time.Second); pkts[0].Timestamp-last != expected {
+				t.Fatalf("expected the frame %d %d samples after the previous one, but got %d", i, expected, pkts[0].Timestamp-last)
+			}
+		}
+		last = pkts[0].Timestamp
+	}
+}
+
+func TestAudioProcessing(t *testing.T) {
+	testCases := map[string]struct {
+		constraints prop.AudioConstraints
+		native      prop.Audio
+		expected    int
+	}{
+		"Unspecified": {
+			expected: 0,
+		},
+		"All": {
+			constraints: prop.AudioConstraints{
+				EchoCancellation: prop.BoolExact(true),
+				NoiseSuppression: prop.Bool(true),
+				AutoGainControl:  prop.Bool(true),
+			},
+			expected: 3,
+		},
+		"Disabled": {
+			constraints: prop.AudioConstraints{
+				EchoCancellation: prop.Bool(false),
+				AutoGainControl:  prop.Bool(true),
+			},
+			expected: 1,
+		},
+		"Native": {
+			constraints: prop.AudioConstraints{
+				EchoCancellation: prop.Bool(true),
+				NoiseSuppression: prop.Bool(true),
+			},
+			native:   prop.Audio{EchoCancellation: true},
+			expected: 1,
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			constraints := MediaTrackConstraints{
+				MediaConstraints: prop.MediaConstraints{AudioConstraints: c.constraints},
+				selectedMedia:    prop.Media{Audio: c.native},
+			}
+			if fns := audioProcessing(constraints); len(fns) != c.expected {
+				t.Fatalf("expected %d transforms, but got %d", c.expected, len(fns))
+			}
+		})
+	}
+}
+
+// testFECEncoderBuilder is a testPreparedEncoderBuilder whose codec sends ULPFEC.
+type testFECEncoderBuilder struct {
+	testPreparedEncoderBuilder
+}
+
+func (b *testFECEncoderBuilder) RTPCodec() *codec.RTPCodec {
+	c := codec.NewRTPVP8Codec(90000)
+	c.SetPacketization(codec.PacketizationParams{FEC: codec.FECParams{Mode: codec.FECModeULPFEC}})
+	return c
+}
+
+func TestVideoTrackNegotiatedFEC(t *testing.T) {
+	vp8 := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8, ClockRate: 90000},
+		PayloadType:        96,
+	}
+	red := webrtc.RTPCodecParameters{
+		RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: codec.MimeTypeVideoRED, ClockRate: 90000},
+		PayloadType:        110,
+	}
+	for name, c := range map[string]struct {
+		negotiated  []webrtc.RTPCodecParameters
+		payloadType uint8
+	}{
+		"WithoutRED": {[]webrtc.RTPCodecParameters{vp8}, uint8(codec.NewRTPVP8Codec(90000).PayloadType)},
+		"WithRED":    {[]webrtc.RTPCodecParameters{vp8, red}, 110},
+	} {
+		source := &testVideoSource{
+			img: image.NewYCbCr(image.Rect(0, 0, 64, 48), image.YCbCrSubsampleRatio420),
+		}
+		track := NewVideoTrack(source, NewCodecSelector(WithVideoEncoders(&testFECEncoderBuilder{}))).(*VideoTrack)
+
+		r, err := newNegotiatedRTPReader(track, "vp8", 1, c.negotiated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkts, _, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, pkt := range pkts {
+			if pkt.PayloadType != c.payloadType {
+				t.Fatalf("%s: expected the payload type %d, but got %d", name, c.payloadType, pkt.PayloadType)
+			}
+		}
+		r.Close()
+		track.Close()
+	}
+}
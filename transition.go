@@ -0,0 +1,139 @@
+package mediadevices
+
+import (
+	"image"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/io/video"
+)
+
+const (
+	defaultCrossfadeFrames = 15
+	// defaultTransitionInterval is the transition frame interval until the track's frame rate is known.
+	defaultTransitionInterval = time.Second / 30
+)
+
+// TransitionMode is what a video track shows while VideoTrack.ReplaceSource's new source warms up.
+type TransitionMode int
+
+const (
+	// TransitionNone shows new source frames as soon as they're read. Video pauses until then.
+	TransitionNone TransitionMode = iota
+	// TransitionHold repeats the replaced source's last frame.
+	TransitionHold
+	// TransitionBlack shows black frames.
+	TransitionBlack
+	// TransitionCrossfade repeats the replaced source's last frame, and fades it into the new source.
+	TransitionCrossfade
+)
+
+// Transition is how a video track switches to a new source from VideoTrack.ReplaceSource and
+// VideoTrack.ReplaceDriver.
+type Transition struct {
+	Mode TransitionMode
+	// WarmUp is how many of the new source's first frames are skipped, e.g. dark or green frames
+	// some cameras capture while exposure is adjusted.
+	WarmUp int
+	// Frames is the crossfade length in frames. It's 15 if it's 0.
+	Frames int
+}
+
+// SetTransition sets how the track switches to a new source from ReplaceSource and ReplaceDriver. Transition
+// frames are sent at the replaced source's frame rate, so viewers see neither frozen
+// video nor a new device's garbage frames while it warms up. It's TransitionNone by default.
+func (track *VideoTrack) SetTransition(t Transition) {
+	track.switcher.mu.Lock()
+	track.switcher.transition = t
+	track.switcher.mu.Unlock()
+}
+
+// warmUpFrame is a new source's first frame after warm-up.
+type warmUpFrame struct {
+	img     image.Image
+	release func()
+	err     error
+}
+
+// warmUp reads r's frames in background until skipped frames are read, since the first frame
+// may take a while, e.g. while a camera is opened.
+func warmUp(r video.Reader, skip int) <-chan warmUpFrame {
+	ch := make(chan warmUpFrame, 1)
+	stopped := resources.start("warm-up of a replaced source")
+	go func() {
+		defer stopped()
+		for i := 0; ; i++ {
+			img, release, err := r.Read()
+			if err != nil || i >= skip {
+				ch <- warmUpFrame{img: img, release: release, err: err}
+				return
+			}
+			release()
+		}
+	}()
+	return ch
+}
+
+// toI420 returns img in I420. The pixels may be shared with img.
+func toI420(img image.Image) (*image.YCbCr, bool) {
+	converted, _, err := video.ToI420(video.ReaderFunc(func() (image.Image, func(), error) {
+		return img, func() {}, nil
+	})).Read()
+	if err != nil {
+		return nil, false
+	}
+	yuv, ok := converted.(*image.YCbCr)
+	return yuv, ok
+}
+
+// copyI420 returns a copy of img in I420, which is kept after the source reuses img's buffer.
+func copyI420(img image.Image) *image.YCbCr {
+	src, ok := toI420(img)
+	if !ok {
+		return nil
+	}
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio420)
+	for y := 0; y < h; y++ {
+		i := src.YOffset(src.Rect.Min.X, src.Rect.Min.Y+y)
+		copy(dst.Y[y*dst.YStride:y*dst.YStride+w], src.Y[i:i+w])
+	}
+	cw, ch := (w+1)/2, (h+1)/2
+	for y := 0; y < ch; y++ {
+		i := src.COffset(src.Rect.Min.X, src.Rect.Min.Y+2*y)
+		copy(dst.Cb[y*dst.CStride:y*dst.CStride+cw], src.Cb[i:i+cw])
+		copy(dst.Cr[y*dst.CStride:y*dst.CStride+cw], src.Cr[i:i+cw])
+	}
+	return dst
+}
+
+// blackI420 returns a black frame of size.
+func blackI420(size image.Point) *image.YCbCr {
+	img := image.NewYCbCr(image.Rect(0, 0, size.X, size.Y), image.YCbCrSubsampleRatio420)
+	for i := range img.Y {
+		img.Y[i] = 16
+	}
+	for i := range img.Cb {
+		img.Cb[i], img.Cr[i] = 128, 128
+	}
+	return img
+}
+
+// crossfade returns a new frame showing to with weight alpha over from, or to if they can't be blended.
+func crossfade(from *image.YCbCr, to image.Image, alpha float64) image.Image {
+	next := copyI420(to)
+	if next == nil || next.Rect.Size() != from.Rect.Size() {
+		return to
+	}
+
+	w := int(alpha*256 + 0.5)
+	blend := func(dst, src []uint8) {
+		for i := range dst {
+			dst[i] = uint8((int(src[i])*(256-w) + int(dst[i])*w) >> 8)
+		}
+	}
+	blend(next.Y, from.Y)
+	blend(next.Cb, from.Cb)
+	blend(next.Cr, from.Cr)
+	return next
+}
@@ -0,0 +1,157 @@
+package mediadevices
+
+import (
+	"image"
+	"testing"
+)
+
+// gatedVideoSource returns frames after gate is closed, and repeats the last one.
+type gatedVideoSource struct {
+	gate   chan struct{}
+	frames []image.Image
+}
+
+func (s *gatedVideoSource) Read() (image.Image, func(), error) {
+	<-s.gate
+	img := s.frames[0]
+	if len(s.frames) > 1 {
+		s.frames = s.frames[1:]
+	}
+	return img, func() {}, nil
+}
+
+func readLuma(t *testing.T, s *sourceSwitch) uint8 {
+	img, _, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img.(*image.YCbCr).Y[0]
+}
+
+func TestSourceSwitchTransition(t *testing.T) {
+	testCases := map[string]struct {
+		transition Transition
+		// warmUp is frame luma while the new source warms up.
+		warmUp   uint8
+		expected []uint8
+	}{
+		"Hold": {
+			transition: Transition{Mode: TransitionHold},
+			warmUp:     1,
+			expected:   []uint8{100, 200},
+		},
+		"Black": {
+			transition: Transition{Mode: TransitionBlack},
+			warmUp:     16,
+			expected:   []uint8{100, 200},
+		},
+		// The held frame is faded into new source frames by 1/4, 2/4 and 3/4.
+		"Crossfade": {
+			transition: Transition{Mode: TransitionCrossfade, Frames: 3},
+			warmUp:     1,
+			expected:   []uint8{25, 100, 150, 200},
+		},
+		"SkipWarmUp": {
+			transition: Transition{Mode: TransitionHold, WarmUp: 1},
+			warmUp:     1,
+			expected:   []uint8{200, 200},
+		},
+	}
+	for name, c := range testCases {
+		c := c
+		t.Run(name, func(t *testing.T) {
+			camera := &closableVideoSource{id: "camera", img: testLumaImage(8, 8, 1)}
+			s := newSourceSwitch(camera, camera)
+			s.transition = c.transition
+			if luma := readLuma(t, s); luma != 1 {
+				t.Fatalf("expected the frame of the camera, but got luma %d", luma)
+			}
+
+			screen := &gatedVideoSource{
+				gate:   make(chan struct{}),
+				frames: []image.Image{testLumaImage(8, 8, 100), testLumaImage(8, 8, 200)},
+			}
+			if err := s.replace(nil, screen); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 3; i++ {
+				if luma := readLuma(t, s); luma != c.warmUp {
+					t.Fatalf("expected luma %d while the source warms up, but got %d", c.warmUp, luma)
+				}
+				if m := s.Metadata(); !m.CaptureTime.IsZero() {
+					t.Fatal("expected the frames of the transition not to have the capture time")
+				}
+			}
+
+			close(screen.gate)
+			for i, expected := range c.expected {
+				if luma := readLuma(t, s); luma != expected {
+					t.Fatalf("expected luma %d of frame %d, but got %d", expected, i, luma)
+				}
+			}
+		})
+	}
+}
+
+func TestSourceSwitchNoTransition(t *testing.T) {
+	camera := &closableVideoSource{id: "camera", img: testLumaImage(8, 8, 1)}
+	s := newSourceSwitch(camera, camera)
+	readLuma(t, s)
+
+	screen := &gatedVideoSource{gate: make(chan struct{}), frames: []image.Image{testLumaImage(8, 8, 200)}}
+	if err := s.replace(nil, screen); err != nil {
+		t.Fatal(err)
+	}
+	if s.pending != nil {
+		t.Fatal("expected the new source to be read directly without the transition")
+	}
+	close(screen.gate)
+	if luma := readLuma(t, s); luma != 200 {
+		t.Fatalf("expected the frame of the new source, but got luma %d", luma)
+	}
+}
+
+// reusingVideoSource overwrites its frame on release, like a frame.Pool reusing the buffer.
+type reusingVideoSource struct {
+	img      *image.YCbCr
+	released int
+}
+
+func (s *reusingVideoSource) Read() (image.Image, func(), error) {
+	return s.img, func() {
+		s.released++
+		for i := range s.img.Y {
+			s.img.Y[i] = 0
+		}
+	}, nil
+}
+
+func TestSourceSwitchHoldReleased(t *testing.T) {
+	camera := &reusingVideoSource{img: testLumaImage(8, 8, 100)}
+	s := newSourceSwitch(nil, camera)
+	s.transition = Transition{Mode: TransitionHold}
+	img, release, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if luma := img.(*image.YCbCr).Y[0]; luma != 100 {
+		t.Fatalf("expected the frame of the camera, but got luma %d", luma)
+	}
+	// The reader is done with the frame, but the switch holds it until it's copied
+	release()
+	if camera.released != 0 {
+		t.Fatal("expected the last frame to be kept by the switch")
+	}
+
+	screen := &gatedVideoSource{gate: make(chan struct{}), frames: []image.Image{testLumaImage(8, 8, 200)}}
+	if err := s.replace(nil, screen); err != nil {
+		t.Fatal(err)
+	}
+	if camera.released != 1 {
+		t.Fatalf("expected the last frame to be released once after the replace, but got %d", camera.released)
+	}
+	if luma := readLuma(t, s); luma != 100 {
+		t.Fatalf("expected the held frame of the camera, but got luma %d", luma)
+	}
+	close(screen.gate)
+}
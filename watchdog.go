@@ -0,0 +1,285 @@
+package mediadevices
+
+import (
+	"errors"
+	"image"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+)
+
+const defaultStallTimeout = 3 * time.Second
+
+var (
+	errRecoveryUnsupported = errors.New("the track doesn't support the recovery")
+	errNoDriverToReopen    = errors.New("the source of the track isn't a driver")
+	errRecoveryPending     = errors.New("the source is being reopened")
+)
+
+// RecoveryAction is what Watchdog does when a track's frames stall.
+type RecoveryAction int
+
+const (
+	// RecoveryResetEncoder rebuilds the track's encoders, e.g. when a hardware encoder hangs.
+	RecoveryResetEncoder RecoveryAction = iota
+	// RecoveryReopenDriver closes the track's driver and reopens it with the same constraints, e.g. when
+	// a camera is unplugged and plugged in again. The track's encoders and RTP senders are kept.
+	RecoveryReopenDriver
+	// RecoveryNone only calls the OnStall handler.
+	RecoveryNone
+)
+
+func (a RecoveryAction) String() string {
+	switch a {
+	case RecoveryResetEncoder:
+		return "ResetEncoder"
+	case RecoveryReopenDriver:
+		return "ReopenDriver"
+	default:
+		return "None"
+	}
+}
+
+// StallEvent is passed to the OnStall handler after each recovery of a stalled track.
+type StallEvent struct {
+	Track Track
+	// Stalled is how long the track hasn't read a frame.
+	Stalled time.Duration
+	// Attempt is the stall's recovery count, from 0.
+	Attempt int
+	Action  RecoveryAction
+	// Err is the recovery error, if any.
+	Err error
+}
+
+// WatchdogOption configures NewWatchdog.
+type WatchdogOption func(*Watchdog)
+
+// WithStallTimeout sets how long a track doesn't read a frame before it's recovered. The default is 3 seconds.
+func WithStallTimeout(d time.Duration) WatchdogOption {
+	return func(w *Watchdog) {
+		w.timeout = d
+	}
+}
+
+// WithRecovery sets actions that are tried in order at each stall timeout while the track is stalled.
+// The last action is repeated. The default is RecoveryResetEncoder then RecoveryReopenDriver.
+func WithRecovery(actions ...RecoveryAction) WatchdogOption {
+	return func(w *Watchdog) {
+		w.actions = actions
+	}
+}
+
+// OnStall sets a handler that's called after each recovery, e.g. to restart the application after
+// recoveries fail. It's called in the watchdog's goroutine.
+func OnStall(handler func(StallEvent)) WatchdogOption {
+	return func(w *Watchdog) {
+		w.onStall = handler
+	}
+}
+
+// Watchdog monitors tracks' frame cadence and recovers stalled ones, so unattended
+// captures heal themselves. Since tracks read frames only while they're read, it should only watch
+// tracks that are read continuously, e.g. sent to a peer or recorded.
+type Watchdog struct {
+	timeout time.Duration
+	actions []RecoveryAction
+	onStall func(StallEvent)
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewWatchdog creates a Watchdog.
+func NewWatchdog(opts ...WatchdogOption) *Watchdog {
+	w := &Watchdog{
+		timeout: defaultStallTimeout,
+		actions: []RecoveryAction{RecoveryResetEncoder, RecoveryReopenDriver},
+		closed:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if len(w.actions) == 0 {
+		w.actions = []RecoveryAction{RecoveryNone}
+	}
+	resources.addCloser(w)
+	return w
+}
+
+// Watch starts watching track. The track is watched after it reads its first frame, until the returned function
+// is called, the track ends or the watchdog is closed.
+func (w *Watchdog) Watch(track Track) func() {
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+	ended := make(chan struct{})
+	var endOnce sync.Once
+	track.OnEnded(func(error) {
+		endOnce.Do(func() { close(ended) })
+	})
+
+	w.wg.Add(1)
+	stopped := resources.start("watchdog of " + track.ID())
+	go func() {
+		defer w.wg.Done()
+		defer stopped()
+		ticker := time.NewTicker(w.timeout / 4)
+		defer ticker.Stop()
+
+		frames := track.Stats().Frames
+		last := time.Now()
+		attempt := 0
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ended:
+				return
+			case <-w.closed:
+				return
+			case now := <-ticker.C:
+				if n := track.Stats().Frames; n != frames || n == 0 {
+					frames, last, attempt = n, now, 0
+					continue
+				}
+				if stalled := now.Sub(last); stalled >= w.timeout*time.Duration(attempt+1) {
+					w.recover(track, stalled, attempt)
+					attempt++
+				}
+			}
+		}
+	}()
+
+	return func() {
+		stopOnce.Do(func() { close(stop) })
+	}
+}
+
+func (w *Watchdog) recover(track Track, stalled time.Duration, attempt int) {
+	action := w.actions[len(w.actions)-1]
+	if attempt < len(w.actions) {
+		action = w.actions[attempt]
+	}
+
+	var err error
+	r, ok := track.(recoverableTrack)
+	switch {
+	case action == RecoveryNone:
+	case !ok:
+		err = errRecoveryUnsupported
+	case action == RecoveryResetEncoder:
+		r.resetEncoders()
+	case action == RecoveryReopenDriver:
+		err = r.reopenDriver()
+	}
+	if err != nil {
+		logger.Debugf("failed to recover the stalled track %s: %s", track.ID(), err)
+	}
+
+	if w.onStall != nil {
+		w.onStall(StallEvent{Track: track, Stalled: stalled, Attempt: attempt, Action: action, Err: err})
+	}
+}
+
+// Close stops watching tracks.
+func (w *Watchdog) Close() error {
+	w.closeOnce.Do(func() { close(w.closed) })
+	w.wg.Wait()
+	resources.removeCloser(w)
+	return nil
+}
+
+// recoverableTrack is implemented by tracks Watchdog can recover.
+type recoverableTrack interface {
+	resetEncoders()
+	reopenDriver() error
+}
+
+func (track *VideoTrack) resetEncoders() {
+	atomic.AddUint32(&track.resets, 1)
+}
+
+func (track *VideoTrack) reopenDriver() error {
+	track.reopenMu.Lock()
+	defer track.reopenMu.Unlock()
+	if track.reopen == nil {
+		return errNoDriverToReopen
+	}
+	return track.reopen()
+}
+
+// setDriver records how to reopen d with c, or nothing if d is nil. It's called after the source is replaced,
+// so reads blocked by a failed reopen continue from the new source.
+func (track *VideoTrack) setDriver(d driver.Driver, recorder driver.VideoRecorder, c MediaTrackConstraints) {
+	var reopen func() error
+	if d != nil {
+		reopen = func() error {
+			if track.placeholder == nil {
+				// Reads stalled in the driver are moved to the placeholder before it's closed, so
+				// their errors don't end the track.
+				track.placeholder = newBlockedReader()
+				if err := track.switcher.replace(nil, track.placeholder); err != nil {
+					logger.Debugf("failed to close the stalled driver: %s", err)
+				}
+			}
+			if err := d.Configure(c.DriverOptions); err != nil {
+				return err
+			}
+			if err := d.Open(); err != nil {
+				return err
+			}
+			if err := d.SetControls(c.Controls); err != nil {
+				d.Close()
+				return err
+			}
+			reader, err := recordVideo(recorder, c.selectedMedia)
+			if err != nil {
+				closeDriver(d)
+				return err
+			}
+			if err := track.switcher.replace(d, reader); err != nil {
+				return err
+			}
+			track.placeholder.release()
+			track.placeholder = nil
+			return nil
+		}
+	}
+
+	track.reopenMu.Lock()
+	track.reopen = reopen
+	track.reopenMu.Unlock()
+	track.releasePlaceholder()
+}
+
+// releasePlaceholder ends reads blocked by a failed reopen.
+func (track *VideoTrack) releasePlaceholder() {
+	track.reopenMu.Lock()
+	if track.placeholder != nil {
+		track.placeholder.release()
+		track.placeholder = nil
+	}
+	track.reopenMu.Unlock()
+}
+
+// blockedReader blocks reads until it's released, since the switch reads from the new source after that.
+type blockedReader struct {
+	released chan struct{}
+	once     sync.Once
+}
+
+func newBlockedReader() *blockedReader {
+	return &blockedReader{released: make(chan struct{})}
+}
+
+func (r *blockedReader) Read() (image.Image, func(), error) {
+	<-r.released
+	return nil, func() {}, errRecoveryPending
+}
+
+func (r *blockedReader) release() {
+	r.once.Do(func() { close(r.released) })
+}
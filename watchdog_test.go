@@ -0,0 +1,134 @@
+package mediadevices
+
+import (
+	"image"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/mediadevices/pkg/driver"
+	"github.com/pion/mediadevices/pkg/io/video"
+	"github.com/pion/mediadevices/pkg/prop"
+	"github.com/pion/webrtc/v3"
+)
+
+// stallingCameraMock stalls after its first frames until it's reopened.
+type stallingCameraMock struct {
+	screenAdapterMock
+	opened int32
+}
+
+func (a *stallingCameraMock) Open() error {
+	atomic.AddInt32(&a.opened, 1)
+	return a.screenAdapterMock.Open()
+}
+
+func (a *stallingCameraMock) VideoRecord(p prop.Media) (video.Reader, error) {
+	img := image.NewRGBA(image.Rect(0, 0, p.Width, p.Height))
+	done, stall := a.done, atomic.LoadInt32(&a.opened) == 1
+	var n int
+	return video.ReaderFunc(func() (image.Image, func(), error) {
+		n++
+		if stall && n > 3 {
+			<-done
+		}
+		select {
+		case <-done:
+			return nil, func() {}, io.EOF
+		default:
+		}
+		time.Sleep(time.Millisecond)
+		return img, func() {}, nil
+	}), nil
+}
+
+func TestWatchdogReopenDriver(t *testing.T) {
+	a := &stallingCameraMock{}
+	if err := RegisterDriverAdapter(a, driver.Info{Label: "stalling", DeviceType: driver.Camera}); err != nil {
+		t.Fatal(err)
+	}
+	defer driver.GetManager().Unregister(a)
+
+	d := driver.GetManager().Query(func(d driver.Driver) bool { return d.Info().Label == "stalling" })[0]
+	track, err := newTrackFromDriver(d, MediaTrackConstraints{selectedMedia: photoMedia(64, 32)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer track.Close()
+
+	ended := make(chan error, 1)
+	track.OnEnded(func(err error) { ended <- err })
+	go func() {
+		r := track.(*VideoTrack).NewReader(false)
+		for {
+			if _, _, err := r.Read(); err != nil {
+				return
+			}
+		}
+	}()
+
+	events := make(chan StallEvent, 10)
+	w := NewWatchdog(WithStallTimeout(40*time.Millisecond), WithRecovery(RecoveryReopenDriver), OnStall(func(e StallEvent) {
+		events <- e
+	}))
+	defer w.Close()
+	w.Watch(track)
+
+	select {
+	case e := <-events:
+		if e.Action != RecoveryReopenDriver || e.Err != nil || e.Attempt != 0 {
+			t.Fatalf("expected the driver to be reopened, but got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the stall")
+	}
+
+	frames := track.Stats().Frames
+	time.Sleep(50 * time.Millisecond)
+	if n := track.Stats().Frames; n <= frames {
+		t.Fatalf("expected the frames to flow after the driver was reopened, but got %d then %d", frames, n)
+	}
+	if opened := atomic.LoadInt32(&a.opened); opened != 2 {
+		t.Fatalf("expected the driver to be opened twice, but got %d", opened)
+	}
+	select {
+	case err := <-ended:
+		t.Fatalf("expected the track to be kept, but it ended with %v", err)
+	default:
+	}
+}
+
+func TestWatchdogResetEncoder(t *testing.T) {
+	builder := &testBitRateEncoderBuilder{}
+	selector := NewCodecSelector(WithVideoEncoders(builder))
+	img := image.NewYCbCr(image.Rect(0, 0, 16, 16), image.YCbCrSubsampleRatio420)
+	track := NewVideoTrack(&testVideoSource{img: img}, selector)
+	defer track.Close()
+
+	r, err := track.NewEncodedReader(webrtc.MimeTypeVP8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewWatchdog()
+	w.recover(track, time.Second, 0)
+	if _, _, err := r.Read(); err != nil {
+		t.Fatal(err)
+	}
+	if len(builder.encoders) != 2 {
+		t.Fatalf("expected the encoder to be rebuilt, but got %d encoders", len(builder.encoders))
+	}
+
+	// The source isn't a driver
+	var got StallEvent
+	w = NewWatchdog(WithRecovery(RecoveryReopenDriver), OnStall(func(e StallEvent) { got = e }))
+	w.recover(track, time.Second, 3)
+	if got.Err != errNoDriverToReopen {
+		t.Fatalf("expected %v, but got %v", errNoDriverToReopen, got.Err)
+	}
+}